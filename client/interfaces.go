@@ -5,6 +5,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/pkg/sftp"
@@ -186,6 +187,7 @@ type InstanceServer interface {
 	DeleteInstanceConsoleLog(instanceName string, args *InstanceConsoleLogArgs) (err error)
 
 	GetInstanceFile(instanceName string, path string) (content io.ReadCloser, resp *InstanceFileResponse, err error)
+	GetInstanceFileTar(instanceName string, path string) (content io.ReadCloser, err error)
 	CreateInstanceFile(instanceName string, path string, args InstanceFileArgs) (err error)
 	DeleteInstanceFile(instanceName string, path string) (err error)
 
@@ -196,6 +198,7 @@ type InstanceServer interface {
 	GetInstanceSnapshots(instanceName string) (snapshots []api.InstanceSnapshot, err error)
 	GetInstanceSnapshot(instanceName string, name string) (snapshot *api.InstanceSnapshot, ETag string, err error)
 	CreateInstanceSnapshot(instanceName string, snapshot api.InstanceSnapshotsPost) (op Operation, err error)
+	RetryInstanceScheduledSnapshot(instanceName string) (op Operation, err error)
 	CopyInstanceSnapshot(source InstanceServer, instanceName string, snapshot api.InstanceSnapshot, args *InstanceSnapshotCopyArgs) (op RemoteOperation, err error)
 	RenameInstanceSnapshot(instanceName string, name string, instance api.InstanceSnapshotPost) (op Operation, err error)
 	MigrateInstanceSnapshot(instanceName string, name string, instance api.InstanceSnapshotPost) (op Operation, err error)
@@ -214,6 +217,10 @@ type InstanceServer interface {
 	GetInstanceState(name string) (state *api.InstanceState, ETag string, err error)
 	UpdateInstanceState(name string, state api.InstanceStatePut, ETag string) (op Operation, err error)
 
+	GetInstanceCloudInit(name string) (cloudInit *api.InstanceCloudInit, err error)
+	GetInstanceCloudInitWait(name string, timeout int) (cloudInit *api.InstanceCloudInit, err error)
+	GetInstanceCloudInitNetworkConfig(name string) (networkConfig *api.InstanceCloudInitNetworkConfig, err error)
+
 	GetInstanceLogfiles(name string) (logfiles []string, err error)
 	GetInstanceLogfile(name string, filename string) (content io.ReadCloser, err error)
 	DeleteInstanceLogfile(name string, filename string) (err error)
@@ -248,6 +255,7 @@ type InstanceServer interface {
 	GetNetworks() (networks []api.Network, err error)
 	GetNetwork(name string) (network *api.Network, ETag string, err error)
 	GetNetworkLeases(name string) (leases []api.NetworkLease, err error)
+	GetNetworkStaticAddresses(name string) (addresses []api.NetworkStaticAddress, err error)
 	GetNetworkState(name string) (state *api.NetworkState, err error)
 	CreateNetwork(network api.NetworksPost) (err error)
 	UpdateNetwork(name string, network api.NetworkPut, ETag string) (err error)
@@ -291,6 +299,9 @@ type InstanceServer interface {
 	// Network allocations functions ("network_allocations" API extension)
 	GetNetworkAllocations(allProjects bool) (allocations []api.NetworkAllocations, err error)
 
+	// Uplink network allocations functions ("network_uplink_allocations" API extension)
+	GetNetworkUplinkAllocations(networkName string) (allocations []api.NetworkUplinkAllocation, err error)
+
 	// Network zone functions ("network_dns" API extension)
 	GetNetworkZoneNames() (names []string, err error)
 	GetNetworkZones() (zones []api.NetworkZone, err error)
@@ -397,9 +408,18 @@ type InstanceServer interface {
 	// Storage volume ISO import function ("custom_volume_iso" API extension)
 	CreateStoragePoolVolumeFromISO(pool string, args StoragePoolVolumeBackupArgs) (op Operation, err error)
 
+	// Storage volume initial content function ("storage_volume_initial_content" API extension)
+	CreateStoragePoolVolumeFromContent(pool string, volume api.StorageVolumesPost, content io.Reader) (op Operation, err error)
+
+	// Storage volume SFTP functions ("custom_volume_sftp" API extension)
+	GetStoragePoolVolumeFileSFTPConn(pool string, volType string, volName string) (net.Conn, error)
+	GetStoragePoolVolumeFileSFTP(pool string, volType string, volName string) (*sftp.Client, error)
+
 	// Cluster functions ("cluster" API extensions)
 	GetCluster() (cluster *api.Cluster, ETag string, err error)
 	UpdateCluster(cluster api.ClusterPut, ETag string) (op Operation, err error)
+	GetClusterDatabase() (database *api.ClusterDatabase, err error)
+	ClusterDatabaseAction(req api.ClusterDatabasePost) (op Operation, err error)
 	DeleteClusterMember(name string, force bool) (err error)
 	GetClusterMemberNames() (names []string, err error)
 	GetClusterMembers() (members []api.ClusterMember, err error)
@@ -407,6 +427,7 @@ type InstanceServer interface {
 	UpdateClusterMember(name string, member api.ClusterMemberPut, ETag string) (err error)
 	RenameClusterMember(name string, member api.ClusterMemberPost) (err error)
 	CreateClusterMember(member api.ClusterMembersPost) (op Operation, err error)
+	ValidateClusterMemberJoin(member api.ClusterMemberJoinValidationPost) (validation *api.ClusterMemberJoinValidation, err error)
 	UpdateClusterCertificate(certs api.ClusterCertificatePut, ETag string) (err error)
 	GetClusterMemberState(name string) (*api.ClusterMemberState, string, error)
 	UpdateClusterMemberState(name string, state api.ClusterMemberStatePost) (op Operation, err error)
@@ -450,6 +471,22 @@ type InstanceServer interface {
 	DeleteIdentityProviderGroup(identityProviderGroupName string) error
 	GetPermissions(args GetPermissionsArgs) (permissions []api.Permission, err error)
 	GetPermissionsInfo(args GetPermissionsArgs) (permissions []api.PermissionInfo, err error)
+	GetEntitlements() (entitlements []api.EntityTypeEntitlements, err error)
+	GetEntitlementsFull() (entitlements []api.EntityTypeEntitlementsFull, err error)
+	GetAuthAuditEntries(args GetAuthAuditEntriesArgs) (entries []api.AuthAuditEntry, err error)
+	GetAuthModelOpenFGA() (model *api.AuthModelOpenFGA, err error)
+	GetAuthModelRego() (model *api.AuthModelRego, err error)
+	PreviewAuthGroup(group api.AuthGroupsPost) (permissions []api.Permission, err error)
+	GetAuthGroupDiff(groupName string, againstGroupName string) (diff *api.AuthGroupDiff, err error)
+	ImportAuthGroups(groups api.AuthGroupsImport) (op Operation, err error)
+	DeleteAuthGroups(args DeleteAuthGroupsArgs) (deleted *api.AuthGroupsDeleted, err error)
+	GetAuthRoleNames() (roleNames []string, err error)
+	GetAuthRoles() (roles []api.AuthRole, err error)
+	GetAuthRole(roleName string) (role *api.AuthRole, ETag string, err error)
+	CreateAuthRole(role api.AuthRolesPost) error
+	UpdateAuthRole(roleName string, rolePut api.AuthRolePut, ETag string) error
+	DeleteAuthRole(roleName string) error
+	GetSelfPermissions(entityURL string) (permissions []api.Permission, err error)
 
 	// Internal functions (for internal use)
 	RawQuery(method string, path string, data any, queryETag string) (resp *api.Response, ETag string, err error)
@@ -524,6 +561,24 @@ type ImageFileRequest struct {
 	// Path retriever for image delta downloads
 	// If set, it must return the path to the image file or an empty string if not available
 	DeltaSourceRetriever func(fingerprint string, file string) string
+
+	// Compression algorithm of the image tarballs (none, gzip, zstd or xz)
+	// As the tarballs may be recompressed, the downloaded files aren't checked against the image fingerprint.
+	//
+	// API extension: image_export_compression
+	Compression string
+
+	// Compression level of the compression algorithm (nil for the default level)
+	//
+	// API extension: image_export_compression
+	CompressionLevel *int
+
+	// Fingerprints of the previous versions of the image that may be used as the source of a delta download
+	// The server sends a binary delta against one of them if it has it, which is then applied to the file returned
+	// by DeltaSourceRetriever. The full image is downloaded if no delta can be used.
+	//
+	// API extension: image_export_delta
+	DeltaSourceFingerprints []string
 }
 
 // The ImageFileResponse struct is used as the response for image downloads.
@@ -539,6 +594,16 @@ type ImageFileResponse struct {
 
 	// Size of the rootfs file
 	RootfsSize int64
+
+	// Fingerprint of the image that the files were reconstructed from (empty if the full image was downloaded)
+	//
+	// API extension: image_export_delta
+	DeltaSource string
+
+	// Size of the downloaded deltas
+	//
+	// API extension: image_export_delta
+	DeltaSize int64
 }
 
 // The ImageCopyArgs struct is used to pass additional options during image copy.
@@ -635,6 +700,18 @@ type InstanceCopyArgs struct {
 
 	// API extension: instance_allow_inconsistent_copy
 	AllowInconsistent bool
+
+	// API extension: instance_copy_exclusions
+	// Only copy the most recent snapshots (local copy only, 0 for all)
+	SnapshotsLimit int
+
+	// API extension: instance_copy_exclusions
+	// Devices of the source instance that shouldn't be copied
+	SkipDevices []string
+
+	// API extension: instance_copy_exclusions
+	// Custom volumes in the target project to use for disk devices
+	DeviceVolumes map[string]string
 }
 
 // The InstanceSnapshotCopyArgs struct is used to pass additional options during instance copy.
@@ -700,7 +777,7 @@ type InstanceFileArgs struct {
 	// File permissions
 	Mode int
 
-	// File type (file or directory)
+	// File type (file, symlink, directory or tar to unpack a tarball into the directory)
 	Type string
 
 	// File write mode (overwrite or append)
@@ -735,4 +812,41 @@ type GetPermissionsArgs struct {
 	// If the project name is specified, only permissions for resources in the given project will be returned and server
 	// level permissions will not be returned.
 	ProjectName string
+
+	// Search is a case insensitive string that the name of the entity of each returned permission, or the name of its
+	// project or parent entities, must contain.
+	Search string
+
+	// Limit is the maximum number of permissions to return. If zero, all permissions are returned.
+	Limit int
+
+	// Offset is the number of permissions to skip.
+	Offset int
+}
+
+// GetAuthAuditEntriesArgs contains optional arguments for filtering and paginating the auth audit trail.
+type GetAuthAuditEntriesArgs struct {
+	// Since only returns the entries made at or after this date, if set.
+	Since time.Time
+
+	// ObjectType only returns the entries of objects of this type, if set.
+	ObjectType string
+
+	// Limit is the maximum number of entries to return. If zero, all entries are returned.
+	Limit int
+
+	// Offset is the number of entries to skip.
+	Offset int
+}
+
+// DeleteAuthGroupsArgs contains the arguments for deleting the groups matching a filter.
+type DeleteAuthGroupsArgs struct {
+	// Filter is the collection filter matching the groups to delete.
+	Filter string
+
+	// DryRun only returns the groups that would be deleted, without deleting them.
+	DryRun bool
+
+	// Force applies the change even if it removes the last server administrator.
+	Force bool
 }
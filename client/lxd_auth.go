@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/canonical/lxd/shared/api"
 )
@@ -403,6 +405,11 @@ func (r *ProtocolLXD) GetPermissions(args GetPermissionsArgs) ([]api.Permission,
 		u = u.WithQuery("entity-type", args.EntityType)
 	}
 
+	u, err = r.permissionsPaginationURL(u, args)
+	if err != nil {
+		return nil, err
+	}
+
 	var permissions []api.Permission
 	_, err = r.queryStruct(http.MethodGet, u.String(), nil, "", &permissions)
 	if err != nil {
@@ -412,6 +419,32 @@ func (r *ProtocolLXD) GetPermissions(args GetPermissionsArgs) ([]api.Permission,
 	return permissions, nil
 }
 
+// permissionsPaginationURL adds the search and pagination query parameters of the given arguments to the URL.
+func (r *ProtocolLXD) permissionsPaginationURL(u *api.URL, args GetPermissionsArgs) (*api.URL, error) {
+	if args.Search == "" && args.Limit == 0 && args.Offset == 0 {
+		return u, nil
+	}
+
+	err := r.CheckExtension("auth_permissions_pagination")
+	if err != nil {
+		return nil, err
+	}
+
+	if args.Search != "" {
+		u = u.WithQuery("search", args.Search)
+	}
+
+	if args.Limit > 0 {
+		u = u.WithQuery("limit", strconv.Itoa(args.Limit))
+	}
+
+	if args.Offset > 0 {
+		u = u.WithQuery("offset", strconv.Itoa(args.Offset))
+	}
+
+	return u, nil
+}
+
 // GetPermissionsInfo returns all permissions available on the server and includes the groups that are assigned each permission.
 func (r *ProtocolLXD) GetPermissionsInfo(args GetPermissionsArgs) ([]api.PermissionInfo, error) {
 	err := r.CheckExtension("access_management")
@@ -428,6 +461,11 @@ func (r *ProtocolLXD) GetPermissionsInfo(args GetPermissionsArgs) ([]api.Permiss
 		u = u.WithQuery("entity-type", args.EntityType)
 	}
 
+	u, err = r.permissionsPaginationURL(u, args)
+	if err != nil {
+		return nil, err
+	}
+
 	var permissions []api.PermissionInfo
 	_, err = r.queryStruct(http.MethodGet, u.String(), nil, "", &permissions)
 	if err != nil {
@@ -436,3 +474,292 @@ func (r *ProtocolLXD) GetPermissionsInfo(args GetPermissionsArgs) ([]api.Permiss
 
 	return permissions, nil
 }
+
+// GetEntitlements returns the entitlements that can be granted on each entity type.
+func (r *ProtocolLXD) GetEntitlements() ([]api.EntityTypeEntitlements, error) {
+	err := r.CheckExtension("auth_entitlements")
+	if err != nil {
+		return nil, err
+	}
+
+	var entitlements []api.EntityTypeEntitlements
+	_, err = r.queryStruct(http.MethodGet, api.NewURL().Path("auth", "entitlements").String(), nil, "", &entitlements)
+	if err != nil {
+		return nil, err
+	}
+
+	return entitlements, nil
+}
+
+// GetEntitlementsFull returns the entitlements that can be granted on each entity type, with a description of each
+// entitlement.
+func (r *ProtocolLXD) GetEntitlementsFull() ([]api.EntityTypeEntitlementsFull, error) {
+	err := r.CheckExtension("auth_entitlements_info")
+	if err != nil {
+		return nil, err
+	}
+
+	var entitlements []api.EntityTypeEntitlementsFull
+	_, err = r.queryStruct(http.MethodGet, api.NewURL().Path("auth", "entitlements").WithQuery("recursion", "1").String(), nil, "", &entitlements)
+	if err != nil {
+		return nil, err
+	}
+
+	return entitlements, nil
+}
+
+// GetAuthAuditEntries returns the entries of the audit trail of authorization changes, from the oldest to the most
+// recent.
+func (r *ProtocolLXD) GetAuthAuditEntries(args GetAuthAuditEntriesArgs) ([]api.AuthAuditEntry, error) {
+	err := r.CheckExtension("auth_audit")
+	if err != nil {
+		return nil, err
+	}
+
+	u := api.NewURL().Path("auth", "audit")
+	if !args.Since.IsZero() {
+		u = u.WithQuery("since", args.Since.UTC().Format(time.RFC3339))
+	}
+
+	if args.ObjectType != "" {
+		u = u.WithQuery("object-type", args.ObjectType)
+	}
+
+	if args.Limit > 0 {
+		u = u.WithQuery("limit", strconv.Itoa(args.Limit))
+	}
+
+	if args.Offset > 0 {
+		u = u.WithQuery("offset", strconv.Itoa(args.Offset))
+	}
+
+	var entries []api.AuthAuditEntry
+	_, err = r.queryStruct(http.MethodGet, u.String(), nil, "", &entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// GetAuthModelOpenFGA returns the authorization model as an OpenFGA model and relationship tuples.
+func (r *ProtocolLXD) GetAuthModelOpenFGA() (*api.AuthModelOpenFGA, error) {
+	err := r.CheckExtension("auth_model_export")
+	if err != nil {
+		return nil, err
+	}
+
+	model := api.AuthModelOpenFGA{}
+	_, err = r.queryStruct(http.MethodGet, api.NewURL().Path("auth", "model").WithQuery("format", api.AuthModelFormatOpenFGA).String(), nil, "", &model)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model, nil
+}
+
+// GetAuthModelRego returns the authorization model as a Rego policy and data document.
+func (r *ProtocolLXD) GetAuthModelRego() (*api.AuthModelRego, error) {
+	err := r.CheckExtension("auth_model_export")
+	if err != nil {
+		return nil, err
+	}
+
+	model := api.AuthModelRego{}
+	_, err = r.queryStruct(http.MethodGet, api.NewURL().Path("auth", "model").WithQuery("format", api.AuthModelFormatRego).String(), nil, "", &model)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model, nil
+}
+
+// PreviewAuthGroup returns the permissions that a group would grant if it were created, without creating it.
+func (r *ProtocolLXD) PreviewAuthGroup(group api.AuthGroupsPost) ([]api.Permission, error) {
+	err := r.CheckExtension("auth_groups_preview")
+	if err != nil {
+		return nil, err
+	}
+
+	var permissions []api.Permission
+	_, err = r.queryStruct(http.MethodPost, api.NewURL().Path("auth", "groups", "preview").String(), group, "", &permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+// GetAuthGroupDiff returns the differences between the group with the given name and another group.
+func (r *ProtocolLXD) GetAuthGroupDiff(groupName string, againstGroupName string) (*api.AuthGroupDiff, error) {
+	err := r.CheckExtension("auth_group_diff")
+	if err != nil {
+		return nil, err
+	}
+
+	diff := api.AuthGroupDiff{}
+	_, err = r.queryStruct(http.MethodGet, api.NewURL().Path("auth", "groups", groupName, "diff").WithQuery("against", againstGroupName).String(), nil, "", &diff)
+	if err != nil {
+		return nil, err
+	}
+
+	return &diff, nil
+}
+
+// ImportAuthGroups creates many groups in a background operation.
+func (r *ProtocolLXD) ImportAuthGroups(groups api.AuthGroupsImport) (Operation, error) {
+	err := r.CheckExtension("auth_groups_import")
+	if err != nil {
+		return nil, err
+	}
+
+	op, _, err := r.queryOperation(http.MethodPost, api.NewURL().Path("auth", "groups", "import").String(), groups, "", true)
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+// DeleteAuthGroups deletes the groups matching a filter, or only lists them if args.DryRun is set.
+func (r *ProtocolLXD) DeleteAuthGroups(args DeleteAuthGroupsArgs) (*api.AuthGroupsDeleted, error) {
+	err := r.CheckExtension("auth_groups_bulk_delete")
+	if err != nil {
+		return nil, err
+	}
+
+	u := api.NewURL().Path("auth", "groups").WithQuery("filter", args.Filter)
+	if args.DryRun {
+		u = u.WithQuery("dry_run", "1")
+	} else {
+		u = u.WithQuery("confirm", "1")
+	}
+
+	if args.Force {
+		u = u.WithQuery("force", "1")
+	}
+
+	deleted := api.AuthGroupsDeleted{}
+	_, err = r.queryStruct(http.MethodDelete, u.String(), nil, "", &deleted)
+	if err != nil {
+		return nil, err
+	}
+
+	return &deleted, nil
+}
+
+// GetAuthRoleNames returns a slice of all role names.
+func (r *ProtocolLXD) GetAuthRoleNames() ([]string, error) {
+	err := r.CheckExtension("auth_roles")
+	if err != nil {
+		return nil, err
+	}
+
+	urls := []string{}
+	baseURL := "auth/roles"
+	_, err = r.queryStruct(http.MethodGet, baseURL, nil, "", &urls)
+	if err != nil {
+		return nil, err
+	}
+
+	return urlsToResourceNames(baseURL, urls...)
+}
+
+// GetAuthRoles returns a list of all roles.
+func (r *ProtocolLXD) GetAuthRoles() ([]api.AuthRole, error) {
+	err := r.CheckExtension("auth_roles")
+	if err != nil {
+		return nil, err
+	}
+
+	var roles []api.AuthRole
+	_, err = r.queryStruct(http.MethodGet, api.NewURL().Path("auth", "roles").WithQuery("recursion", "1").String(), nil, "", &roles)
+	if err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+// GetAuthRole returns a single role by its name.
+func (r *ProtocolLXD) GetAuthRole(roleName string) (*api.AuthRole, string, error) {
+	err := r.CheckExtension("auth_roles")
+	if err != nil {
+		return nil, "", err
+	}
+
+	role := api.AuthRole{}
+	etag, err := r.queryStruct(http.MethodGet, api.NewURL().Path("auth", "roles", roleName).String(), nil, "", &role)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &role, etag, nil
+}
+
+// CreateAuthRole creates a new role.
+func (r *ProtocolLXD) CreateAuthRole(role api.AuthRolesPost) error {
+	err := r.CheckExtension("auth_roles")
+	if err != nil {
+		return err
+	}
+
+	_, _, err = r.query(http.MethodPost, api.NewURL().Path("auth", "roles").String(), role, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateAuthRole replaces the editable fields of the role with the given name.
+func (r *ProtocolLXD) UpdateAuthRole(roleName string, rolePut api.AuthRolePut, ETag string) error {
+	err := r.CheckExtension("auth_roles")
+	if err != nil {
+		return err
+	}
+
+	_, _, err = r.query(http.MethodPut, api.NewURL().Path("auth", "roles", roleName).String(), rolePut, ETag)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteAuthRole deletes the role with the given name.
+func (r *ProtocolLXD) DeleteAuthRole(roleName string) error {
+	err := r.CheckExtension("auth_roles")
+	if err != nil {
+		return err
+	}
+
+	_, _, err = r.query(http.MethodDelete, api.NewURL().Path("auth", "roles", roleName).String(), nil, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetSelfPermissions returns the effective permissions of the caller. If entityURL is not empty, only the permissions
+// that apply to the entity with this URL are returned.
+func (r *ProtocolLXD) GetSelfPermissions(entityURL string) ([]api.Permission, error) {
+	err := r.CheckExtension("auth_self_permissions")
+	if err != nil {
+		return nil, err
+	}
+
+	u := api.NewURL().Path("auth", "self", "permissions")
+	if entityURL != "" {
+		u = u.WithQuery("entity", entityURL)
+	}
+
+	var permissions []api.Permission
+	_, err = r.queryStruct(http.MethodGet, u.String(), nil, "", &permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
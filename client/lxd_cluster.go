@@ -46,6 +46,37 @@ func (r *ProtocolLXD) UpdateCluster(cluster api.ClusterPut, ETag string) (Operat
 	return op, nil
 }
 
+// GetClusterDatabase returns the state of the global database.
+func (r *ProtocolLXD) GetClusterDatabase() (*api.ClusterDatabase, error) {
+	err := r.CheckExtension("cluster_database")
+	if err != nil {
+		return nil, err
+	}
+
+	database := api.ClusterDatabase{}
+	_, err = r.queryStruct("GET", "/cluster/database", nil, "", &database)
+	if err != nil {
+		return nil, err
+	}
+
+	return &database, nil
+}
+
+// ClusterDatabaseAction runs a maintenance action (vacuum or checkpoint) on the global database.
+func (r *ProtocolLXD) ClusterDatabaseAction(req api.ClusterDatabasePost) (Operation, error) {
+	err := r.CheckExtension("cluster_database")
+	if err != nil {
+		return nil, err
+	}
+
+	op, _, err := r.queryOperation("POST", "/cluster/database", req, "", true)
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
 // DeleteClusterMember makes the given member leave the cluster (gracefully or not,
 // depending on the force flag).
 func (r *ProtocolLXD) DeleteClusterMember(name string, force bool) error {
@@ -171,6 +202,22 @@ func (r *ProtocolLXD) CreateClusterMember(member api.ClusterMembersPost) (Operat
 	return op, nil
 }
 
+// ValidateClusterMemberJoin checks whether a server can join the cluster without making any change to the cluster.
+func (r *ProtocolLXD) ValidateClusterMemberJoin(member api.ClusterMemberJoinValidationPost) (*api.ClusterMemberJoinValidation, error) {
+	err := r.CheckExtension("cluster_join_validation")
+	if err != nil {
+		return nil, err
+	}
+
+	validation := api.ClusterMemberJoinValidation{}
+	_, err = r.queryStruct("POST", "/cluster/members?validate-only=true", member, "", &validation)
+	if err != nil {
+		return nil, err
+	}
+
+	return &validation, nil
+}
+
 // UpdateClusterCertificate updates the cluster certificate for every node in the cluster.
 func (r *ProtocolLXD) UpdateClusterCertificate(certs api.ClusterCertificatePut, ETag string) error {
 	err := r.CheckExtension("clustering_update_cert")
@@ -9,6 +9,8 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"os/exec"
+	"strconv"
 	"strings"
 	"time"
 
@@ -133,6 +135,25 @@ func (r *ProtocolLXD) GetPrivateImageFile(fingerprint string, secret string, req
 		return nil, err
 	}
 
+	if req.Compression != "" || req.CompressionLevel != nil {
+		err = r.CheckExtension("image_export_compression")
+		if err != nil {
+			return nil, err
+		}
+
+		uri, err = setQueryParam(uri, "compression", req.Compression)
+		if err != nil {
+			return nil, err
+		}
+
+		if req.CompressionLevel != nil {
+			uri, err = setQueryParam(uri, "compression_level", strconv.Itoa(*req.CompressionLevel))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	// Attempt to download from host
 	if secret == "" && shared.PathExists("/dev/lxd/sock") && os.Geteuid() == 0 {
 		unixURI := fmt.Sprintf("http://unix.socket%s", uri)
@@ -163,9 +184,192 @@ func (r *ProtocolLXD) GetPrivateImageFile(fingerprint string, secret string, req
 	httpTransport.ResponseHeaderTimeout = 30 * time.Second
 	httpClient.Transport = httpTransport
 
+	// Attempt to download deltas against a previous version of the image (requires xdelta3)
+	if len(req.DeltaSourceFingerprints) > 0 && req.DeltaSourceRetriever != nil && req.Compression == "" && req.CompressionLevel == nil && r.HasExtension("image_export_delta") {
+		_, err := exec.LookPath("xdelta3")
+		if err == nil {
+			resp, err := lxdDownloadImageDelta(fingerprint, uri, r.httpUserAgent, r.DoHTTP, req)
+			if err == nil {
+				return resp, nil
+			}
+
+			// Fallback to a full download, which rewrites the targets from their start.
+			for _, target := range []io.WriteSeeker{req.MetaFile, req.RootfsFile} {
+				if target == nil {
+					continue
+				}
+
+				_, err := target.Seek(0, io.SeekStart)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
 	return lxdDownloadImage(fingerprint, uri, r.httpUserAgent, r.DoHTTP, req)
 }
 
+// lxdDownloadImageDelta downloads binary deltas of the image files against one of the delta source fingerprints of
+// the request, and applies them to the files returned by the DeltaSourceRetriever of the request. The reconstructed
+// files are checked against the image fingerprint before being written to the targets. The full image is written to
+// the targets instead if the server doesn't have any of the delta sources.
+func lxdDownloadImageDelta(fingerprint string, uri string, userAgent string, do func(*http.Request) (*http.Response, error), req ImageFileRequest) (*ImageFileResponse, error) {
+	uri, err := setQueryParam(uri, "delta_sources", strings.Join(req.DeltaSourceFingerprints, ","))
+	if err != nil {
+		return nil, err
+	}
+
+	// Temporary files are removed once done.
+	var tempFiles []*os.File
+	defer func() {
+		for _, file := range tempFiles {
+			_ = file.Close()
+			_ = os.Remove(file.Name())
+		}
+	}()
+
+	createTemp := func() (*os.File, error) {
+		file, err := os.CreateTemp("", "lxc_image_")
+		if err != nil {
+			return nil, err
+		}
+
+		tempFiles = append(tempFiles, file)
+
+		return file, nil
+	}
+
+	copyTemp := func(target io.Writer, file *os.File) (int64, error) {
+		_, err := file.Seek(0, io.SeekStart)
+		if err != nil {
+			return -1, err
+		}
+
+		return io.Copy(target, file)
+	}
+
+	// Download into temporary files so that the targets are only written once the image is reconstructed.
+	var metaFile *os.File
+	var rootfsFile *os.File
+	deltaReq := req
+
+	if req.MetaFile != nil {
+		metaFile, err = createTemp()
+		if err != nil {
+			return nil, err
+		}
+
+		deltaReq.MetaFile = metaFile
+	}
+
+	if req.RootfsFile != nil {
+		rootfsFile, err = createTemp()
+		if err != nil {
+			return nil, err
+		}
+
+		deltaReq.RootfsFile = rootfsFile
+	}
+
+	resp, err := lxdDownloadImage(fingerprint, uri, userAgent, do, deltaReq)
+	if err != nil {
+		return nil, err
+	}
+
+	// The server sent the full image, which has already been checked against the fingerprint.
+	if resp.DeltaSource == "" {
+		_, err = copyTemp(req.MetaFile, metaFile)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.RootfsSize > 0 {
+			_, err = copyTemp(req.RootfsFile, rootfsFile)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return resp, nil
+	}
+
+	if !shared.ValueInSlice(resp.DeltaSource, req.DeltaSourceFingerprints) {
+		return nil, fmt.Errorf("Unexpected delta source %q", resp.DeltaSource)
+	}
+
+	resp.DeltaSize = resp.MetaSize + resp.RootfsSize
+
+	// Apply the deltas
+	patch := func(file string, delta *os.File) (*os.File, error) {
+		srcPath := req.DeltaSourceRetriever(resp.DeltaSource, file)
+		if srcPath == "" {
+			return nil, fmt.Errorf("The %s file of delta source %q isn't available", file, resp.DeltaSource)
+		}
+
+		patchedFile, err := createTemp()
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = shared.RunCommand("xdelta3", "-f", "-d", "-s", srcPath, delta.Name(), patchedFile.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		return patchedFile, nil
+	}
+
+	patchedMeta, err := patch("metadata", metaFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var patchedRootfs *os.File
+	if resp.RootfsSize > 0 {
+		patchedRootfs, err = patch("rootfs", rootfsFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Check the hash of the reconstructed image
+	sha256 := sha256.New()
+
+	resp.MetaSize, err = copyTemp(sha256, patchedMeta)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.RootfsSize = 0
+	if patchedRootfs != nil {
+		resp.RootfsSize, err = copyTemp(sha256, patchedRootfs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hash := fmt.Sprintf("%x", sha256.Sum(nil))
+	if !strings.HasPrefix(hash, fingerprint) {
+		return nil, fmt.Errorf("Reconstructed image fingerprint doesn't match. Got %s expected %s", hash, fingerprint)
+	}
+
+	// Write the reconstructed image to the targets
+	_, err = copyTemp(req.MetaFile, patchedMeta)
+	if err != nil {
+		return nil, err
+	}
+
+	if patchedRootfs != nil {
+		_, err = copyTemp(req.RootfsFile, patchedRootfs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
 func lxdDownloadImage(fingerprint string, uri string, userAgent string, do func(*http.Request) (*http.Response, error), req ImageFileRequest) (*ImageFileResponse, error) {
 	// Prepare the response
 	resp := ImageFileResponse{}
@@ -196,6 +400,9 @@ func lxdDownloadImage(fingerprint string, uri string, userAgent string, do func(
 		}
 	}
 
+	// Deltas are checked once applied.
+	resp.DeltaSource = response.Header.Get("X-LXD-Delta-Source")
+
 	ctype, ctypeParams, err := mime.ParseMediaType(response.Header.Get("Content-Type"))
 	if err != nil {
 		ctype = "application/octet-stream"
@@ -274,7 +481,7 @@ func lxdDownloadImage(fingerprint string, uri string, userAgent string, do func(
 
 		// Check the hash
 		hash := fmt.Sprintf("%x", sha256.Sum(nil))
-		if !strings.HasPrefix(hash, fingerprint) {
+		if req.Compression == "" && resp.DeltaSource == "" && !strings.HasPrefix(hash, fingerprint) {
 			return nil, fmt.Errorf("Image fingerprint doesn't match. Got %s expected %s", hash, fingerprint)
 		}
 
@@ -302,7 +509,7 @@ func lxdDownloadImage(fingerprint string, uri string, userAgent string, do func(
 
 	// Check the hash
 	hash := fmt.Sprintf("%x", sha256.Sum(nil))
-	if !strings.HasPrefix(hash, fingerprint) {
+	if req.Compression == "" && resp.DeltaSource == "" && !strings.HasPrefix(hash, fingerprint) {
 		return nil, fmt.Errorf("Image fingerprint doesn't match. Got %s expected %s", hash, fingerprint)
 	}
 
@@ -393,6 +600,13 @@ func (r *ProtocolLXD) CreateImage(image api.ImagesPost, args *ImageCreateArgs) (
 		}
 	}
 
+	if image.CompressionLevel != nil {
+		err := r.CheckExtension("image_export_compression")
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Send the JSON based request
 	if args == nil {
 		op, _, err := r.queryOperation("POST", "/images", image, "", true)
@@ -860,6 +860,12 @@ func (r *ProtocolLXD) CopyInstance(source InstanceServer, instance api.Instance,
 			}
 		}
 
+		if args.SnapshotsLimit != 0 || len(args.SkipDevices) > 0 || len(args.DeviceVolumes) > 0 {
+			if !r.HasExtension("instance_copy_exclusions") {
+				return nil, fmt.Errorf("The target server is missing the required \"instance_copy_exclusions\" API extension")
+			}
+		}
+
 		// Allow overriding the target name
 		if args.Name != "" {
 			req.Name = args.Name
@@ -870,6 +876,9 @@ func (r *ProtocolLXD) CopyInstance(source InstanceServer, instance api.Instance,
 		req.Source.ContainerOnly = args.InstanceOnly // For legacy servers.
 		req.Source.Refresh = args.Refresh
 		req.Source.AllowInconsistent = args.AllowInconsistent
+		req.Source.SnapshotsLimit = args.SnapshotsLimit
+		req.Source.SkipDevices = args.SkipDevices
+		req.Source.DeviceVolumes = args.DeviceVolumes
 	}
 
 	if req.Source.Live {
@@ -922,6 +931,10 @@ func (r *ProtocolLXD) CopyInstance(source InstanceServer, instance api.Instance,
 		return &rop, nil
 	}
 
+	if req.Source.SnapshotsLimit != 0 {
+		return nil, fmt.Errorf("Limiting the number of snapshots is only supported when copying on the same server")
+	}
+
 	// Source request
 	sourceReq := api.InstancePost{
 		Migration:         true,
@@ -1525,6 +1538,53 @@ func (r *ProtocolLXD) GetInstanceFile(instanceName string, filePath string) (io.
 	return resp.Body, &fileResp, err
 }
 
+// GetInstanceFileTar retrieves the provided directory from the instance as a tarball.
+func (r *ProtocolLXD) GetInstanceFileTar(instanceName string, dirPath string) (io.ReadCloser, error) {
+	err := r.CheckExtension("instance_file_tar")
+	if err != nil {
+		return nil, err
+	}
+
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prepare the HTTP request
+	requestURL, err := shared.URLEncode(
+		fmt.Sprintf("%s/1.0%s/%s/files", r.httpBaseURL.String(), path, url.PathEscape(instanceName)),
+		map[string]string{"path": dirPath, "format": "tar", "recursive": "true"})
+	if err != nil {
+		return nil, err
+	}
+
+	requestURL, err = r.setQueryAttributes(requestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send the request
+	resp, err := r.DoHTTP(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the return value for a cleaner error
+	if resp.StatusCode != http.StatusOK {
+		_, _, err := lxdParseResponse(resp)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resp.Body, nil
+}
+
 // CreateInstanceFile tells LXD to create a file in the instance.
 func (r *ProtocolLXD) CreateInstanceFile(instanceName string, filePath string, args InstanceFileArgs) error {
 	if args.Type == "directory" {
@@ -1548,6 +1608,13 @@ func (r *ProtocolLXD) CreateInstanceFile(instanceName string, filePath string, a
 		}
 	}
 
+	if args.Type == "tar" {
+		err := r.CheckExtension("instance_file_tar")
+		if err != nil {
+			return err
+		}
+	}
+
 	var requestURL string
 
 	if r.IsAgent() {
@@ -1823,6 +1890,27 @@ func (r *ProtocolLXD) CreateInstanceSnapshot(instanceName string, snapshot api.I
 	return op, nil
 }
 
+// RetryInstanceScheduledSnapshot immediately takes the scheduled snapshot of the instance, outside of its schedule.
+func (r *ProtocolLXD) RetryInstanceScheduledSnapshot(instanceName string) (Operation, error) {
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.CheckExtension("instance_scheduled_snapshots_retry")
+	if err != nil {
+		return nil, err
+	}
+
+	// Send the request
+	op, _, err := r.queryOperation("POST", fmt.Sprintf("%s/%s/snapshots?scheduled=retry", path, url.PathEscape(instanceName)), nil, "", true)
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
 // CopyInstanceSnapshot copies a snapshot from a remote server into a new instance. Additional options can be passed using InstanceCopyArgs.
 func (r *ProtocolLXD) CopyInstanceSnapshot(source InstanceServer, instanceName string, snapshot api.InstanceSnapshot, args *InstanceSnapshotCopyArgs) (RemoteOperation, error) {
 	// Backward compatibility (with broken Name field)
@@ -2212,6 +2300,84 @@ func (r *ProtocolLXD) GetInstanceState(name string) (*api.InstanceState, string,
 	return &state, etag, nil
 }
 
+// GetInstanceCloudInit returns the cloud-init status of the instance.
+func (r *ProtocolLXD) GetInstanceCloudInit(name string) (*api.InstanceCloudInit, error) {
+	err := r.CheckExtension("instance_cloud_init_status")
+	if err != nil {
+		return nil, err
+	}
+
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	cloudInit := api.InstanceCloudInit{}
+
+	// Fetch the raw value
+	_, err = r.queryStruct("GET", fmt.Sprintf("%s/%s/cloud-init", path, url.PathEscape(name)), nil, "", &cloudInit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cloudInit, nil
+}
+
+// GetInstanceCloudInitWait returns the cloud-init status of the instance once cloud-init has finished or the timeout is hit.
+// A timeout of -1 waits indefinitely.
+func (r *ProtocolLXD) GetInstanceCloudInitWait(name string, timeout int) (*api.InstanceCloudInit, error) {
+	err := r.CheckExtension("instance_cloud_init_status")
+	if err != nil {
+		return nil, err
+	}
+
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unset the response header timeout so that the request does not time out.
+	transport, err := r.getUnderlyingHTTPTransport()
+	if err != nil {
+		return nil, err
+	}
+
+	transport.ResponseHeaderTimeout = 0
+
+	cloudInit := api.InstanceCloudInit{}
+
+	// Fetch the raw value
+	_, err = r.queryStruct("GET", fmt.Sprintf("%s/%s/cloud-init?wait=true&timeout=%d", path, url.PathEscape(name), timeout), nil, "", &cloudInit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cloudInit, nil
+}
+
+// GetInstanceCloudInitNetworkConfig returns the cloud-init network configuration generated from the NIC devices of the instance.
+func (r *ProtocolLXD) GetInstanceCloudInitNetworkConfig(name string) (*api.InstanceCloudInitNetworkConfig, error) {
+	err := r.CheckExtension("cloud_init_network_config_auto")
+	if err != nil {
+		return nil, err
+	}
+
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	networkConfig := api.InstanceCloudInitNetworkConfig{}
+
+	// Fetch the raw value
+	_, err = r.queryStruct("GET", fmt.Sprintf("%s/%s/cloud-init/network-config", path, url.PathEscape(name)), nil, "", &networkConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &networkConfig, nil
+}
+
 // UpdateInstanceState updates the instance to match the requested state.
 func (r *ProtocolLXD) UpdateInstanceState(name string, state api.InstanceStatePut, ETag string) (Operation, error) {
 	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
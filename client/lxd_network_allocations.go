@@ -25,3 +25,22 @@ func (r *ProtocolLXD) GetNetworkAllocations(allProjects bool) ([]api.NetworkAllo
 
 	return netAllocations, nil
 }
+
+// GetNetworkUplinkAllocations returns the addresses of an uplink network that are used by OVN networks and by their
+// network forwards and load balancers.
+func (r *ProtocolLXD) GetNetworkUplinkAllocations(networkName string) ([]api.NetworkUplinkAllocation, error) {
+	err := r.CheckExtension("network_uplink_allocations")
+	if err != nil {
+		return nil, err
+	}
+
+	allocations := []api.NetworkUplinkAllocation{}
+
+	// Fetch the raw value.
+	_, err = r.queryStruct("GET", api.NewURL().Path("networks", networkName, "allocations").String(), nil, "", &allocations)
+	if err != nil {
+		return nil, err
+	}
+
+	return allocations, nil
+}
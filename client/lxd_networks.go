@@ -80,6 +80,24 @@ func (r *ProtocolLXD) GetNetworkLeases(name string) ([]api.NetworkLease, error)
 	return leases, nil
 }
 
+// GetNetworkStaticAddresses returns the static IP addresses of the instance NICs connected to the network.
+func (r *ProtocolLXD) GetNetworkStaticAddresses(name string) ([]api.NetworkStaticAddress, error) {
+	err := r.CheckExtension("network_static_addresses")
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := []api.NetworkStaticAddress{}
+
+	// Fetch the raw value
+	_, err = r.queryStruct("GET", fmt.Sprintf("/networks/%s/static-addresses", url.PathEscape(name)), nil, "", &addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	return addresses, nil
+}
+
 // GetNetworkState returns metrics and information on the running network.
 func (r *ProtocolLXD) GetNetworkState(name string) (*api.NetworkState, error) {
 	err := r.CheckExtension("network_state")
@@ -1,12 +1,17 @@
 package lxd
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
 
+	"github.com/pkg/sftp"
+
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/cancel"
@@ -209,6 +214,44 @@ func (r *ProtocolLXD) GetStoragePoolVolumeState(pool string, volType string, nam
 	return &state, nil
 }
 
+// GetStoragePoolVolumeFileSFTPConn returns a connection to the volume's SFTP endpoint.
+func (r *ProtocolLXD) GetStoragePoolVolumeFileSFTPConn(pool string, volType string, volName string) (net.Conn, error) {
+	err := r.CheckExtension("custom_volume_sftp")
+	if err != nil {
+		return nil, err
+	}
+
+	apiURL := api.NewURL()
+	apiURL.URL = r.httpBaseURL // Preload the URL with the client base URL.
+	apiURL.Path("1.0", "storage-pools", pool, "volumes", volType, volName, "sftp")
+	r.setURLQueryAttributes(&apiURL.URL)
+
+	return r.rawSFTPConn(&apiURL.URL)
+}
+
+// GetStoragePoolVolumeFileSFTP returns an SFTP connection to the volume.
+func (r *ProtocolLXD) GetStoragePoolVolumeFileSFTP(pool string, volType string, volName string) (*sftp.Client, error) {
+	conn, err := r.GetStoragePoolVolumeFileSFTPConn(pool, volType, volName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get a SFTP client.
+	client, err := sftp.NewClientPipe(conn, conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	go func() {
+		// Wait for the client to be done before closing the connection.
+		_ = client.Wait()
+		_ = conn.Close()
+	}()
+
+	return client, nil
+}
+
 // CreateStoragePoolVolume defines a new storage volume.
 func (r *ProtocolLXD) CreateStoragePoolVolume(pool string, volume api.StorageVolumesPost) error {
 	err := r.CheckExtension("storage")
@@ -1064,6 +1107,108 @@ func (r *ProtocolLXD) CreateStoragePoolVolumeFromISO(pool string, args StoragePo
 	return &op, nil
 }
 
+// CreateStoragePoolVolumeFromContent creates a custom filesystem volume populated with the content of a tarball.
+// If content is nil, the tarball is fetched by the server from the source URL of the volume.
+func (r *ProtocolLXD) CreateStoragePoolVolumeFromContent(pool string, volume api.StorageVolumesPost, content io.Reader) (Operation, error) {
+	err := r.CheckExtension("storage_volume_initial_content")
+	if err != nil {
+		return nil, err
+	}
+
+	path := fmt.Sprintf("/storage-pools/%s/volumes/custom", url.PathEscape(pool))
+
+	if content == nil {
+		if volume.Source.Type == "" {
+			volume.Source.Type = "url"
+		}
+
+		op, _, err := r.queryOperation("POST", path, volume, "", true)
+		if err != nil {
+			return nil, err
+		}
+
+		return op, nil
+	}
+
+	if volume.Source.Type == "" {
+		volume.Source.Type = "upload"
+	}
+
+	pr, pw := io.Pipe()
+	w := multipart.NewWriter(pw)
+
+	go func() {
+		var ioErr error
+		defer func() {
+			cerr := w.Close()
+			if ioErr == nil && cerr != nil {
+				ioErr = cerr
+			}
+
+			_ = pw.CloseWithError(ioErr)
+		}()
+
+		fw, ioErr := w.CreateFormField("request")
+		if ioErr != nil {
+			return
+		}
+
+		ioErr = json.NewEncoder(fw).Encode(volume)
+		if ioErr != nil {
+			return
+		}
+
+		fw, ioErr = w.CreateFormFile("content", volume.Name)
+		if ioErr != nil {
+			return
+		}
+
+		_, ioErr = io.Copy(fw, content)
+	}()
+
+	// Prepare the HTTP request.
+	reqURL, err := r.setQueryAttributes(fmt.Sprintf("%s/1.0%s", r.httpBaseURL.String(), path))
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", reqURL, pr)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	// Send the request.
+	resp, err := r.DoHTTP(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	// Handle errors.
+	response, _, err := lxdParseResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get to the operation.
+	respOperation, err := response.MetadataAsOperation()
+	if err != nil {
+		return nil, err
+	}
+
+	// Setup an Operation wrapper.
+	op := operation{
+		Operation: *respOperation,
+		r:         r,
+		chActive:  make(chan bool),
+	}
+
+	return &op, nil
+}
+
 // CreateStoragePoolVolumeFromBackup creates a custom volume from a backup file.
 func (r *ProtocolLXD) CreateStoragePoolVolumeFromBackup(pool string, args StoragePoolVolumeBackupArgs) (Operation, error) {
 	err := r.CheckExtension("custom_volume_backup")
@@ -62,6 +62,10 @@ func (r *ProtocolSimpleStreams) GetImageFile(fingerprint string, req ImageFileRe
 		return nil, fmt.Errorf("No file requested")
 	}
 
+	if req.Compression != "" || req.CompressionLevel != nil {
+		return nil, fmt.Errorf("Image compression isn't supported by the simplestreams protocol")
+	}
+
 	// Attempt to download from host
 	if shared.PathExists("/dev/lxd/sock") && os.Geteuid() == 0 {
 		unixURI := fmt.Sprintf("http://unix.socket/1.0/images/%s/export", url.PathEscape(fingerprint))
@@ -136,6 +136,10 @@ func (c *cmdAction) Command(action string) *cobra.Command {
 		cmd.Flags().IntVar(&c.flagTimeout, "timeout", -1, i18n.G("Time to wait for the instance to shutdown cleanly")+"``")
 	}
 
+	if action == "pause" {
+		cmd.Flags().IntVar(&c.flagTimeout, "timeout", -1, i18n.G("Time after which the instance is automatically resumed")+"``")
+	}
+
 	return cmd
 }
 
@@ -233,6 +237,10 @@ func (c *cmdAction) doAction(action string, conf *config.Config, nameArg string)
 		return fmt.Errorf(i18n.G("Must supply instance name for: ")+"\"%s\"", nameArg)
 	}
 
+	if action == "freeze" && c.flagTimeout > 0 && !d.HasExtension("instance_freeze_timeout") {
+		return fmt.Errorf(i18n.G("The server doesn't support pausing instances with a timeout"))
+	}
+
 	if action == "start" {
 		current, _, err := d.GetInstance(name)
 		if err != nil {
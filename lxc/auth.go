@@ -221,6 +221,8 @@ func (c *cmdGroupEdit) helpTemplate() string {
 ### - entity_type: project
 ###   url: /1.0/projects/default
 ###   entitlement: can_view
+### parents:
+### - viewers
 ### identities:
 ### - authentication_method: oidc
 ###   type: OIDC client
@@ -232,7 +234,7 @@ func (c *cmdGroupEdit) helpTemplate() string {
 ### - sales
 ### - operations
 ###
-### Note that all group information is shown but only the description and permissions can be modified`)
+### Note that all group information is shown but only the description, permissions and parents can be modified`)
 }
 
 func (c *cmdGroupEdit) run(cmd *cobra.Command, args []string) error {
@@ -545,7 +547,7 @@ func (c *cmdGroupPermissionAdd) run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	permission, err := parsePermissionArgs(args)
+	permission, err := parsePermissionArgs(args, c.global.flagProject)
 	if err != nil {
 		return err
 	}
@@ -604,7 +606,7 @@ func (c *cmdGroupPermissionRemove) run(cmd *cobra.Command, args []string) error
 		return err
 	}
 
-	permission, err := parsePermissionArgs(args)
+	permission, err := parsePermissionArgs(args, c.global.flagProject)
 	if err != nil {
 		return err
 	}
@@ -634,8 +636,9 @@ func (c *cmdGroupPermissionRemove) run(cmd *cobra.Command, args []string) error
 
 // parsePermissionArgs parses the `<entity_type> [<entity_name>] <entitlement> [<key>=<value>...]` arguments of
 // `lxc auth group permission add/remove` and returns an api.Permission that can be appended/removed from the list of
-// permissions belonging to a group.
-func parsePermissionArgs(args []string) (*api.Permission, error) {
+// permissions belonging to a group. The given project (from the --project flag) is used for entities that require a
+// project, unless a `project=<project_name>` argument is supplied.
+func parsePermissionArgs(args []string, flagProject string) (*api.Permission, error) {
 	entityType := entity.Type(args[1])
 	err := entityType.Validate()
 	if err != nil {
@@ -683,10 +686,17 @@ func parsePermissionArgs(args []string) (*api.Permission, error) {
 		pathArgs = []string{authenticationMethod, identifier}
 	}
 
-	projectName, ok := kv["project"]
 	requiresProject, _ := entityType.RequiresProject()
+	projectName, ok := kv["project"]
+	if requiresProject && ok && flagProject != "" && projectName != flagProject {
+		return nil, fmt.Errorf("The project argument %q conflicts with the --project flag %q", projectName, flagProject)
+	} else if requiresProject && !ok && flagProject != "" {
+		projectName = flagProject
+		ok = true
+	}
+
 	if requiresProject && !ok {
-		return nil, fmt.Errorf("Entities of type %q require a supplementary project argument `project=<project_name>`", entityType)
+		return nil, fmt.Errorf("Entities of type %q require a project, use the --project flag or a supplementary project argument `project=<project_name>`", entityType)
 	}
 
 	if entityType == entity.TypeStorageVolume {
@@ -797,8 +807,18 @@ func (c *cmdIdentityList) run(cmd *cobra.Command, args []string) error {
 		delimiter = ","
 	}
 
+	const layout = "2006/01/02 15:04 MST"
+
 	for _, identity := range identities {
-		data = append(data, []string{identity.AuthenticationMethod, identity.Type, identity.Name, identity.Identifier, strings.Join(identity.Groups, delimiter)})
+		disabled := ""
+		if identity.DisabledAt != nil {
+			disabled = identity.DisabledAt.Local().Format(layout)
+			if identity.DisabledBy != "" {
+				disabled = fmt.Sprintf(i18n.G("%s by %s"), disabled, identity.DisabledBy)
+			}
+		}
+
+		data = append(data, []string{identity.AuthenticationMethod, identity.Type, identity.Name, identity.Identifier, strings.Join(identity.Groups, delimiter), disabled})
 	}
 
 	sort.Sort(cli.SortColumnsNaturally(data))
@@ -809,6 +829,7 @@ func (c *cmdIdentityList) run(cmd *cobra.Command, args []string) error {
 		i18n.G("NAME"),
 		i18n.G("IDENTIFIER"),
 		i18n.G("GROUPS"),
+		i18n.G("DISABLED"),
 	}
 
 	return cli.RenderTable(c.flagFormat, header, data, identities)
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePermissionArgs(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		flagProject string
+		url         string
+		wantErr     bool
+	}{
+		{"Project argument", []string{"g", "instance", "c1", "can_exec", "project=p1"}, "", "/1.0/instances/c1?project=p1", false},
+		{"Project flag", []string{"g", "instance", "c1", "can_exec"}, "p1", "/1.0/instances/c1?project=p1", false},
+		{"Conflicting project", []string{"g", "instance", "c1", "can_exec", "project=p1"}, "p2", "", true},
+		{"Missing project", []string{"g", "instance", "c1", "can_exec"}, "", "", true},
+		{"Project flag ignored for projects", []string{"g", "project", "foo", "can_view"}, "foo", "/1.0/projects/foo", false},
+		{"Project flag ignored for the server", []string{"g", "server", "admin"}, "foo", "/1.0", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			permission, err := parsePermissionArgs(test.args, test.flagProject)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.url, permission.EntityReference)
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/canonical/lxd/shared/api"
+	cli "github.com/canonical/lxd/shared/cmd"
+	"github.com/canonical/lxd/shared/i18n"
+)
+
+type cmdCloudInit struct {
+	global *cmdGlobal
+}
+
+func (c *cmdCloudInit) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("cloud-init")
+	cmd.Short = i18n.G("Inspect cloud-init in instances")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Inspect cloud-init in instances`))
+
+	// Status
+	cloudInitStatusCmd := cmdCloudInitStatus{global: c.global, cloudInit: c}
+	cmd.AddCommand(cloudInitStatusCmd.Command())
+
+	// Network config
+	cloudInitNetworkConfigCmd := cmdCloudInitNetworkConfig{global: c.global, cloudInit: c}
+	cmd.AddCommand(cloudInitNetworkConfigCmd.Command())
+
+	// Workaround for subcommand usage errors. See: https://github.com/spf13/cobra/issues/706
+	cmd.Args = cobra.NoArgs
+	cmd.Run = func(cmd *cobra.Command, args []string) { _ = cmd.Usage() }
+	return cmd
+}
+
+// Status.
+type cmdCloudInitStatus struct {
+	global    *cmdGlobal
+	cloudInit *cmdCloudInit
+
+	flagWait    bool
+	flagTimeout int
+}
+
+func (c *cmdCloudInitStatus) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("status", i18n.G("[<remote>:]<instance>"))
+	cmd.Short = i18n.G("Show the cloud-init status of an instance")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show the cloud-init status of an instance
+
+The status is one of not-available, not-started, running, done or error.
+An error is returned if cloud-init reported errors.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc cloud-init status c1 --wait --timeout=300
+    Wait up to 5 minutes for cloud-init to finish in instance c1`))
+
+	cmd.Flags().BoolVarP(&c.flagWait, "wait", "w", false, i18n.G("Wait for cloud-init to finish"))
+	cmd.Flags().IntVarP(&c.flagTimeout, "timeout", "t", -1, i18n.G("Number of seconds to wait, -1 to wait indefinitely")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdCloudInitStatus) Run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing instance name"))
+	}
+
+	// Get the status
+	var cloudInit *api.InstanceCloudInit
+	if c.flagWait {
+		cloudInit, err = resource.server.GetInstanceCloudInitWait(resource.name, c.flagTimeout)
+	} else {
+		cloudInit, err = resource.server.GetInstanceCloudInit(resource.name)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Render as YAML
+	data, err := yaml.Marshal(&cloudInit)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", data)
+
+	if cloudInit.Status == api.InstanceCloudInitStatusError {
+		return fmt.Errorf(i18n.G("Cloud-init reported errors"))
+	}
+
+	return nil
+}
+
+// Network config.
+type cmdCloudInitNetworkConfig struct {
+	global    *cmdGlobal
+	cloudInit *cmdCloudInit
+}
+
+func (c *cmdCloudInitNetworkConfig) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("network-config", i18n.G("[<remote>:]<instance>"))
+	cmd.Short = i18n.G("Show the generated cloud-init network configuration of an instance")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show the generated cloud-init network configuration of an instance
+
+This is the configuration LXD generates from the NIC devices of the instance
+and provides to it when cloud-init.network-config is set to auto.`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdCloudInitNetworkConfig) Run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing instance name"))
+	}
+
+	// Get the generated configuration
+	networkConfig, err := resource.server.GetInstanceCloudInitNetworkConfig(resource.name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", networkConfig.NetworkConfig)
+
+	return nil
+}
@@ -834,6 +834,11 @@ func (c *cmdConfigShow) Run(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return err
 		}
+
+		instPut, ok := brief.(*api.InstancePut)
+		if ok {
+			data = c.markImmutableKeys(data, instPut.Config)
+		}
 	}
 
 	fmt.Printf("%s", data)
@@ -841,6 +846,36 @@ func (c *cmdConfigShow) Run(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// markImmutableKeys adds a comment to the lines of the YAML instance config holding immutable keys.
+func (c *cmdConfigShow) markImmutableKeys(data []byte, config map[string]string) []byte {
+	keys := shared.SplitNTrimSpace(config["volatile.immutable_keys"], ",", -1, true)
+	if len(keys) == 0 {
+		return data
+	}
+
+	lines := strings.Split(string(data), "\n")
+	inConfig := false
+	for i, line := range lines {
+		if !strings.HasPrefix(line, " ") {
+			inConfig = line == "config:"
+			continue
+		}
+
+		if !inConfig {
+			continue
+		}
+
+		for _, key := range keys {
+			if strings.HasPrefix(line, "  "+key+":") {
+				lines[i] = line + " # " + i18n.G("immutable")
+				break
+			}
+		}
+	}
+
+	return []byte(strings.Join(lines, "\n"))
+}
+
 // Unset.
 type cmdConfigUnset struct {
 	global    *cmdGlobal
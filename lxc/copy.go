@@ -31,6 +31,9 @@ type cmdCopy struct {
 	flagTargetProject     string
 	flagRefresh           bool
 	flagAllowInconsistent bool
+	flagSnapshotsLimit    int
+	flagSkipDevice        []string
+	flagDeviceVolume      []string
 }
 
 func (c *cmdCopy) Command() *cobra.Command {
@@ -63,6 +66,9 @@ The pull transfer mode is the default as it is compatible with all LXD versions.
 	cmd.Flags().BoolVar(&c.flagNoProfiles, "no-profiles", false, i18n.G("Create the instance with no profiles applied"))
 	cmd.Flags().BoolVar(&c.flagRefresh, "refresh", false, i18n.G("Perform an incremental copy"))
 	cmd.Flags().BoolVar(&c.flagAllowInconsistent, "allow-inconsistent", false, i18n.G("Ignore copy errors for volatile files"))
+	cmd.Flags().IntVar(&c.flagSnapshotsLimit, "snapshots-limit", 0, i18n.G("Only copy the most recent snapshots")+"``")
+	cmd.Flags().StringArrayVar(&c.flagSkipDevice, "skip-device", nil, i18n.G("Device of the source instance not to copy")+"``")
+	cmd.Flags().StringArrayVar(&c.flagDeviceVolume, "device-volume", nil, i18n.G("Custom volume in the target project to use for a disk device (DEVICE=VOLUME)")+"``")
 
 	return cmd
 }
@@ -147,6 +153,16 @@ func (c *cmdCopy) copyInstance(conf *config.Config, sourceResource string, destR
 		return err
 	}
 
+	deviceVolumes := map[string]string{}
+	for _, entry := range c.flagDeviceVolume {
+		devName, volName, found := strings.Cut(entry, "=")
+		if !found || devName == "" || volName == "" {
+			return fmt.Errorf(i18n.G("Bad device volume pair: %s"), entry)
+		}
+
+		deviceVolumes[devName] = volName
+	}
+
 	var op lxd.RemoteOperation
 	var writable api.InstancePut
 	var start bool
@@ -167,6 +183,10 @@ func (c *cmdCopy) copyInstance(conf *config.Config, sourceResource string, destR
 			return fmt.Errorf(i18n.G("--refresh can only be used with instances"))
 		}
 
+		if c.flagSnapshotsLimit != 0 || len(c.flagSkipDevice) > 0 || len(deviceVolumes) > 0 {
+			return fmt.Errorf(i18n.G("--snapshots-limit, --skip-device and --device-volume can only be used with instances"))
+		}
+
 		// Copy of a snapshot into a new instance
 		srcFields := strings.SplitN(sourceName, shared.SnapshotDelimiter, 2)
 		entry, _, err := source.GetInstanceSnapshot(srcFields[0], srcFields[1])
@@ -251,6 +271,9 @@ func (c *cmdCopy) copyInstance(conf *config.Config, sourceResource string, destR
 			Mode:              mode,
 			Refresh:           c.flagRefresh,
 			AllowInconsistent: c.flagAllowInconsistent,
+			SnapshotsLimit:    c.flagSnapshotsLimit,
+			SkipDevices:       c.flagSkipDevice,
+			DeviceVolumes:     deviceVolumes,
 		}
 
 		// Copy of an instance into a new instance
@@ -480,7 +480,9 @@ type cmdImageExport struct {
 	global *cmdGlobal
 	image  *cmdImage
 
-	flagVM bool
+	flagVM               bool
+	flagCompression      string
+	flagCompressionLevel int
 }
 
 func (c *cmdImageExport) Command() *cobra.Command {
@@ -490,9 +492,14 @@ func (c *cmdImageExport) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Export and download images
 
-The output target is optional and defaults to the working directory.`))
+The output target is optional and defaults to the working directory.
+
+With --compression, the image tarballs are recompressed by the server with the given algorithm,
+and the extension of the downloaded files matches it. Recompressed files don't match the image fingerprint.`))
 
 	cmd.Flags().BoolVar(&c.flagVM, "vm", false, i18n.G("Query virtual machine images"))
+	cmd.Flags().StringVar(&c.flagCompression, "compression", "", i18n.G("Compression algorithm of the image tarballs (none, gzip, zstd or xz)")+"``")
+	cmd.Flags().IntVar(&c.flagCompressionLevel, "compression-level", -1, i18n.G("Compression level of the compression algorithm")+"``")
 	cmd.RunE = c.Run
 
 	return cmd
@@ -563,6 +570,11 @@ func (c *cmdImageExport) Run(cmd *cobra.Command, args []string) error {
 		MetaFile:        io.WriteSeeker(dest),
 		RootfsFile:      io.WriteSeeker(destRootfs),
 		ProgressHandler: progress.UpdateProgress,
+		Compression:     c.flagCompression,
+	}
+
+	if c.flagCompressionLevel >= 0 {
+		req.CompressionLevel = &c.flagCompressionLevel
 	}
 
 	// Download the image
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -36,10 +37,18 @@ func (c *cmdImageAlias) Command() *cobra.Command {
 	imageAliasListCmd := cmdImageAliasList{global: c.global, image: c.image, imageAlias: c}
 	cmd.AddCommand(imageAliasListCmd.Command())
 
+	// Pin
+	imageAliasPinCmd := cmdImageAliasPin{global: c.global, image: c.image, imageAlias: c}
+	cmd.AddCommand(imageAliasPinCmd.Command())
+
 	// Rename
 	imageAliasRenameCmd := cmdImageAliasRename{global: c.global, image: c.image, imageAlias: c}
 	cmd.AddCommand(imageAliasRenameCmd.Command())
 
+	// Unpin
+	imageAliasUnpinCmd := cmdImageAliasUnpin{global: c.global, image: c.image, imageAlias: c}
+	cmd.AddCommand(imageAliasUnpinCmd.Command())
+
 	// Workaround for subcommand usage errors. See: https://github.com/spf13/cobra/issues/706
 	cmd.Args = cobra.NoArgs
 	cmd.Run = func(cmd *cobra.Command, args []string) { _ = cmd.Usage() }
@@ -51,6 +60,9 @@ type cmdImageAliasCreate struct {
 	global     *cmdGlobal
 	image      *cmdImage
 	imageAlias *cmdImageAlias
+
+	flagAutoUpdate string
+	flagPin        bool
 }
 
 func (c *cmdImageAliasCreate) Command() *cobra.Command {
@@ -59,6 +71,8 @@ func (c *cmdImageAliasCreate) Command() *cobra.Command {
 	cmd.Short = i18n.G("Create aliases for existing images")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Create aliases for existing images`))
+	cmd.Flags().StringVar(&c.flagAutoUpdate, "auto-update", "", i18n.G("Whether the alias follows updates of its image (true or false, defaults to the auto-update setting of the image)")+"``")
+	cmd.Flags().BoolVar(&c.flagPin, "pin", false, i18n.G("Pin the alias to the image, so that it doesn't follow updates of the image"))
 
 	cmd.RunE = c.Run
 
@@ -89,9 +103,32 @@ func (c *cmdImageAliasCreate) Run(cmd *cobra.Command, args []string) error {
 	alias.Name = resource.name
 	alias.Target = args[1]
 
+	alias.AutoUpdate, err = imageAliasParseAutoUpdate(c.flagAutoUpdate)
+	if err != nil {
+		return err
+	}
+
+	if c.flagPin {
+		alias.PinnedFingerprint = args[1]
+	}
+
 	return resource.server.CreateImageAlias(alias)
 }
 
+// imageAliasParseAutoUpdate parses the value of the --auto-update flag, which is nil if the flag isn't set.
+func imageAliasParseAutoUpdate(value string) (*bool, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	autoUpdate, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf(i18n.G("Invalid value for --auto-update %q: %w"), value, err)
+	}
+
+	return &autoUpdate, nil
+}
+
 // Delete.
 type cmdImageAliasDelete struct {
 	global     *cmdGlobal
@@ -225,7 +262,12 @@ func (c *cmdImageAliasList) Run(cmd *cobra.Command, args []string) error {
 			alias.Type = "container"
 		}
 
-		data = append(data, []string{alias.Name, alias.Target[0:12], strings.ToUpper(alias.Type), alias.Description})
+		pinned := i18n.G("no")
+		if alias.PinnedFingerprint != "" {
+			pinned = i18n.G("yes")
+		}
+
+		data = append(data, []string{alias.Name, alias.Target[0:12], strings.ToUpper(alias.Type), pinned, alias.Description})
 	}
 
 	sort.Sort(cli.StringList(data))
@@ -234,12 +276,69 @@ func (c *cmdImageAliasList) Run(cmd *cobra.Command, args []string) error {
 		i18n.G("ALIAS"),
 		i18n.G("FINGERPRINT"),
 		i18n.G("TYPE"),
+		i18n.G("PINNED"),
 		i18n.G("DESCRIPTION"),
 	}
 
 	return cli.RenderTable(c.flagFormat, header, data, aliases)
 }
 
+// Pin.
+type cmdImageAliasPin struct {
+	global     *cmdGlobal
+	image      *cmdImage
+	imageAlias *cmdImageAlias
+}
+
+func (c *cmdImageAliasPin) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("pin", i18n.G("[<remote>:]<alias> [<fingerprint>]"))
+	cmd.Short = i18n.G("Pin aliases to images")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Pin aliases to images
+
+A pinned alias doesn't follow updates of its image. The alias is pinned to the image that it
+targets, unless another image is given, in which case the alias is moved to that image.`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdImageAliasPin) Run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	exit, err := c.global.CheckArgs(cmd, args, 1, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Alias name missing"))
+	}
+
+	alias, etag, err := resource.server.GetImageAlias(resource.name)
+	if err != nil {
+		return err
+	}
+
+	if len(args) > 1 {
+		alias.Target = args[1]
+	}
+
+	// Pin the alias
+	alias.PinnedFingerprint = alias.Target
+
+	return resource.server.UpdateImageAlias(resource.name, alias.ImageAliasesEntryPut, etag)
+}
+
 // Rename.
 type cmdImageAliasRename struct {
 	global     *cmdGlobal
@@ -282,3 +381,66 @@ func (c *cmdImageAliasRename) Run(cmd *cobra.Command, args []string) error {
 	// Rename the alias
 	return resource.server.RenameImageAlias(resource.name, api.ImageAliasesEntryPost{Name: args[1]})
 }
+
+// Unpin.
+type cmdImageAliasUnpin struct {
+	global     *cmdGlobal
+	image      *cmdImage
+	imageAlias *cmdImageAlias
+
+	flagAutoUpdate string
+}
+
+func (c *cmdImageAliasUnpin) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("unpin", i18n.G("[<remote>:]<alias>"))
+	cmd.Short = i18n.G("Unpin aliases from images")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Unpin aliases from images
+
+Images that are kept for pinned aliases no longer get updated. To make an unpinned alias
+follow updates again, set --auto-update to true.`))
+	cmd.Flags().StringVar(&c.flagAutoUpdate, "auto-update", "", i18n.G("Whether the alias follows updates of its image (true or false, defaults to the current setting of the alias)")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdImageAliasUnpin) Run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Alias name missing"))
+	}
+
+	autoUpdate, err := imageAliasParseAutoUpdate(c.flagAutoUpdate)
+	if err != nil {
+		return err
+	}
+
+	alias, etag, err := resource.server.GetImageAlias(resource.name)
+	if err != nil {
+		return err
+	}
+
+	// Unpin the alias
+	alias.PinnedFingerprint = ""
+	if autoUpdate != nil {
+		alias.AutoUpdate = autoUpdate
+	}
+
+	return resource.server.UpdateImageAlias(resource.name, alias.ImageAliasesEntryPut, etag)
+}
@@ -513,6 +513,27 @@ func (c *cmdInfo) instanceInfo(d lxd.InstanceServer, remote config.Remote, name
 			fmt.Print(diskInfo)
 		}
 
+		// Disk I/O
+		diskIOInfo := ""
+		for entry, disk := range inst.State.Disk {
+			if disk.ReadOperations == 0 && disk.WriteOperations == 0 {
+				continue
+			}
+
+			diskIOInfo += fmt.Sprintf("    %s:\n", entry)
+			diskIOInfo += fmt.Sprintf("      %s: %s\n", i18n.G("Bytes read"), units.GetByteSizeStringIEC(disk.ReadBytes, 2))
+			diskIOInfo += fmt.Sprintf("      %s: %s\n", i18n.G("Bytes written"), units.GetByteSizeStringIEC(disk.WrittenBytes, 2))
+			diskIOInfo += fmt.Sprintf("      %s: %d\n", i18n.G("Read operations"), disk.ReadOperations)
+			diskIOInfo += fmt.Sprintf("      %s: %d\n", i18n.G("Write operations"), disk.WriteOperations)
+			diskIOInfo += fmt.Sprintf("      %s: %d\n", i18n.G("Read operations per second"), disk.ReadOperationsPerSecond)
+			diskIOInfo += fmt.Sprintf("      %s: %d\n", i18n.G("Write operations per second"), disk.WriteOperationsPerSecond)
+		}
+
+		if diskIOInfo != "" {
+			fmt.Printf("  %s\n", i18n.G("Disk I/O:"))
+			fmt.Print(diskIOInfo)
+		}
+
 		// CPU usage
 		cpuInfo := ""
 		if inst.State.CPU.Usage != 0 {
@@ -566,6 +587,10 @@ func (c *cmdInfo) instanceInfo(d lxd.InstanceServer, remote config.Remote, name
 					networkInfo += fmt.Sprintf("      %s: %d\n", i18n.G("MTU"), net.Mtu)
 				}
 
+				if net.Queues != 0 {
+					networkInfo += fmt.Sprintf("      %s: %d\n", i18n.G("Queues"), net.Queues)
+				}
+
 				networkInfo += fmt.Sprintf("      %s: %s\n", i18n.G("Bytes received"), units.GetByteSizeString(net.Counters.BytesReceived, 2))
 				networkInfo += fmt.Sprintf("      %s: %s\n", i18n.G("Bytes sent"), units.GetByteSizeString(net.Counters.BytesSent, 2))
 				networkInfo += fmt.Sprintf("      %s: %d\n", i18n.G("Packets received"), net.Counters.PacketsReceived)
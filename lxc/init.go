@@ -33,6 +33,8 @@ type cmdInit struct {
 	flagNoProfiles bool
 	flagEmpty      bool
 	flagVM         bool
+
+	flagApplyManifest bool
 }
 
 func (c *cmdInit) Command() *cobra.Command {
@@ -57,6 +59,7 @@ lxc init ubuntu:22.04 u1 < config.yaml
 	cmd.Flags().BoolVar(&c.flagNoProfiles, "no-profiles", false, i18n.G("Create the instance with no profiles applied"))
 	cmd.Flags().BoolVar(&c.flagEmpty, "empty", false, i18n.G("Create an empty instance"))
 	cmd.Flags().BoolVar(&c.flagVM, "vm", false, i18n.G("Create a virtual machine"))
+	cmd.Flags().BoolVar(&c.flagApplyManifest, "apply-manifest", false, i18n.G("Apply the instance configuration embedded in the image"))
 
 	return cmd
 }
@@ -325,6 +328,7 @@ func (c *cmdInit) create(conf *config.Config, args []string) (lxd.InstanceServer
 	}
 
 	req.Devices = devicesMap
+	req.Source.ApplyManifest = c.flagApplyManifest
 
 	var opInfo api.Operation
 	if !c.flagEmpty {
@@ -114,6 +114,10 @@ For help with any of those, simply call them with --help.`))
 	aliasCmd := cmdAlias{global: &globalCmd}
 	app.AddCommand(aliasCmd.Command())
 
+	// cloud-init sub-command
+	cloudInitCmd := cmdCloudInit{global: &globalCmd}
+	app.AddCommand(cloudInitCmd.Command())
+
 	// cluster sub-command
 	clusterCmd := cmdCluster{global: &globalCmd}
 	app.AddCommand(clusterCmd.Command())
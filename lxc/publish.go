@@ -20,10 +20,13 @@ type cmdPublish struct {
 
 	flagAliases              []string
 	flagCompressionAlgorithm string
+	flagCompressionLevel     int
 	flagExpiresAt            string
 	flagMakePublic           bool
 	flagForce                bool
 	flagReuse                bool
+	flagIncludeManifest      bool
+	flagManifestExcludeUser  bool
 }
 
 func (c *cmdPublish) Command() *cobra.Command {
@@ -38,8 +41,11 @@ func (c *cmdPublish) Command() *cobra.Command {
 	cmd.Flags().StringArrayVar(&c.flagAliases, "alias", nil, i18n.G("New alias to define at target")+"``")
 	cmd.Flags().BoolVarP(&c.flagForce, "force", "f", false, i18n.G("Stop the instance if currently running"))
 	cmd.Flags().StringVar(&c.flagCompressionAlgorithm, "compression", "", i18n.G("Compression algorithm to use (`none` for uncompressed)"))
+	cmd.Flags().IntVar(&c.flagCompressionLevel, "compression-level", -1, i18n.G("Compression level to use (gzip, zstd or xz only)")+"``")
 	cmd.Flags().StringVar(&c.flagExpiresAt, "expire", "", i18n.G("Image expiration date (format: rfc3339)")+"``")
 	cmd.Flags().BoolVar(&c.flagReuse, "reuse", false, i18n.G("If the image alias already exists, delete and create a new one"))
+	cmd.Flags().BoolVar(&c.flagIncludeManifest, "include-manifest", false, i18n.G("Embed the instance configuration and devices in the image"))
+	cmd.Flags().BoolVar(&c.flagManifestExcludeUser, "manifest-exclude-user", false, i18n.G("Leave user.* keys out of the embedded instance configuration"))
 
 	return cmd
 }
@@ -206,10 +212,16 @@ func (c *cmdPublish) Run(cmd *cobra.Command, args []string) error {
 			Name: cName,
 		},
 		CompressionAlgorithm: c.flagCompressionAlgorithm,
+		IncludeManifest:      c.flagIncludeManifest,
+		ManifestExcludeUser:  c.flagManifestExcludeUser,
 	}
 
 	req.Properties = properties
 
+	if c.flagCompressionLevel >= 0 {
+		req.CompressionLevel = &c.flagCompressionLevel
+	}
+
 	if shared.IsSnapshot(cName) {
 		req.Source.Type = "snapshot"
 	} else if !s.HasExtension("instances") {
@@ -80,8 +80,11 @@ func (c *cmdRebuild) rebuild(conf *config.Config, args []string) error {
 		return err
 	}
 
+	// Let the server stop the running instance and start it again, if it supports it.
+	serverForce := c.flagForce && d.HasExtension("instances_rebuild_force")
+
 	// If the instance is running, stop it first.
-	if c.flagForce && current.StatusCode == api.Running {
+	if c.flagForce && current.StatusCode == api.Running && !serverForce {
 		req := api.InstanceStatePut{
 			Action: "stop",
 			Force:  true,
@@ -113,6 +116,7 @@ func (c *cmdRebuild) rebuild(conf *config.Config, args []string) error {
 	// Base request
 	req := api.InstanceRebuildPost{
 		Source: api.InstanceSource{},
+		Force:  serverForce,
 	}
 
 	if !c.flagEmpty {
@@ -174,7 +178,7 @@ func (c *cmdRebuild) rebuild(conf *config.Config, args []string) error {
 	}
 
 	// If the instance was stopped, start it back up.
-	if c.flagForce && current.StatusCode == api.Running {
+	if c.flagForce && current.StatusCode == api.Running && !serverForce {
 		req := api.InstanceStatePut{
 			Action: "start",
 		}
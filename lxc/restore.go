@@ -14,7 +14,8 @@ import (
 type cmdRestore struct {
 	global *cmdGlobal
 
-	flagStateful bool
+	flagStateful           bool
+	flagOverrideProtection bool
 }
 
 func (c *cmdRestore) Command() *cobra.Command {
@@ -24,7 +25,10 @@ func (c *cmdRestore) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Restore instances from snapshots
 
-If --stateful is passed, then the running state will be restored too.`))
+If --stateful is passed, then the running state will be restored too.
+
+If --override-protection is passed, the snapshot is restored even if the instance
+has security.protection.force set.`))
 	cmd.Example = cli.FormatSection("", i18n.G(
 		`lxc snapshot u1 snap0
     Create the snapshot.
@@ -34,6 +38,7 @@ lxc restore u1 snap0
 
 	cmd.RunE = c.Run
 	cmd.Flags().BoolVar(&c.flagStateful, "stateful", false, i18n.G("Whether or not to restore the instance's running state from snapshot (if available)"))
+	cmd.Flags().BoolVar(&c.flagOverrideProtection, "override-protection", false, i18n.G("Restore the snapshot even if the instance is protected"))
 
 	return cmd
 }
@@ -65,8 +70,9 @@ func (c *cmdRestore) Run(cmd *cobra.Command, args []string) error {
 	}
 
 	req := api.InstancePut{
-		Restore:  snapname,
-		Stateful: c.flagStateful,
+		Restore:            snapname,
+		Stateful:           c.flagStateful,
+		OverrideProtection: c.flagOverrideProtection,
 	}
 
 	// Restore the snapshot
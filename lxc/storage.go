@@ -472,6 +472,7 @@ func (c *cmdStorageInfo) Run(cmd *cobra.Command, args []string) error {
 	descriptionstring := i18n.G("description")
 	totalspacestring := i18n.G("total space")
 	spaceusedstring := i18n.G("space used")
+	sessionsstring := i18n.G("sessions")
 
 	// Initialize the usedby map
 	poolusedby[usedbystring] = make(map[string][]string)
@@ -554,6 +555,15 @@ func (c *cmdStorageInfo) Run(cmd *cobra.Command, args []string) error {
 		poolinfo[infostring][spaceusedstring] = units.GetByteSizeStringIEC(int64(res.Space.Used), 2)
 	}
 
+	// Build up the sessions map
+	if len(res.Sessions) > 0 {
+		poolinfo[sessionsstring] = map[string]string{}
+
+		for _, session := range res.Sessions {
+			poolinfo[sessionsstring][session.Portal] = session.State
+		}
+	}
+
 	poolinfodata, err := yaml.Marshal(poolinfo)
 	if err != nil {
 		return err
@@ -75,7 +75,7 @@ func eventsSocket(d *Daemon, r *http.Request, w http.ResponseWriter) error {
 	}
 
 	// As we don't know which project we are in, subscribe to events from all projects.
-	listener, err := d.events.AddListener("", true, nil, listenerConnection, strings.Split(typeStr, ","), nil, nil, nil)
+	listener, err := d.events.AddListener("", true, nil, listenerConnection, strings.Split(typeStr, ","), nil, nil, nil, "")
 	if err != nil {
 		return err
 	}
@@ -126,8 +126,8 @@ func eventsProcess(event api.Event) {
 		return
 	}
 
-	// Only care about device additions, we don't try to handle remove.
-	if e.Action != "added" {
+	// Only care about device additions and removals.
+	if e.Action != "added" && e.Action != "removed" {
 		return
 	}
 
@@ -137,7 +137,19 @@ func eventsProcess(event api.Event) {
 	}
 
 	// And only for path based devices.
-	if e.Config["path"] == "" {
+	if e.Config["path"] == "" || e.Config["path"] == "/" {
+		return
+	}
+
+	// Unmount removed shares. The virtio-fs device is already gone, so perform a lazy unmount.
+	if e.Action == "removed" {
+		_, err = shared.RunCommand("umount", "-l", e.Config["path"])
+		if err != nil {
+			logger.Infof("Failed to unmount hotplug %q", e.Config["path"])
+			return
+		}
+
+		logger.Infof("Unmounted hotplug %q", e.Config["path"])
 		return
 	}
 
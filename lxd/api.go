@@ -116,6 +116,10 @@ func restServer(d *Daemon) *http.Server {
 		d.oidcVerifier.Logout(w, r)
 	})
 
+	// Health checks for load balancers (unauthenticated).
+	mux.HandleFunc("/healthz", healthHandler(d, false))
+	mux.HandleFunc("/readyz", healthHandler(d, true))
+
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
@@ -363,6 +367,12 @@ type lxdHTTPServer struct {
 }
 
 func (s *lxdHTTPServer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	// The health endpoints report on the daemon startup themselves rather than waiting for it.
+	if req.URL.Path == "/healthz" || req.URL.Path == "/readyz" {
+		s.r.ServeHTTP(rw, req)
+		return
+	}
+
 	if !strings.HasPrefix(req.URL.Path, "/internal") {
 		<-s.d.setupChan
 
@@ -43,6 +43,7 @@ var api10 = []APIEndpoint{
 	certificateCmd,
 	certificatesCmd,
 	clusterCmd,
+	clusterDatabaseCmd,
 	clusterGroupCmd,
 	clusterGroupsCmd,
 	clusterNodeCmd,
@@ -53,11 +54,16 @@ var api10 = []APIEndpoint{
 	instanceBackupExportCmd,
 	instanceBackupsCmd,
 	instanceCmd,
+	instanceCloudInitCmd,
+	instanceCloudInitNetworkConfigCmd,
 	instanceConsoleCmd,
+	instanceDeviceCmd,
 	instanceExecCmd,
 	instanceFileCmd,
 	instanceExecOutputCmd,
 	instanceExecOutputsCmd,
+	instanceExecRecordingCmd,
+	instanceExecRecordingsCmd,
 	instanceLogCmd,
 	instanceLogsCmd,
 	instanceMetadataCmd,
@@ -82,10 +88,12 @@ var api10 = []APIEndpoint{
 	networkLeasesCmd,
 	networksCmd,
 	networkStateCmd,
+	networkStaticAddressesCmd,
 	networkACLCmd,
 	networkACLsCmd,
 	networkACLLogCmd,
 	networkAllocationsCmd,
+	networkUplinkAllocationsCmd,
 	networkForwardCmd,
 	networkForwardsCmd,
 	networkLoadBalancerCmd,
@@ -121,17 +129,36 @@ var api10 = []APIEndpoint{
 	storagePoolVolumeTypeCustomBackupCmd,
 	storagePoolVolumeTypeCustomBackupExportCmd,
 	storagePoolVolumeTypeStateCmd,
+	storagePoolVolumeTypeSFTPCmd,
 	warningsCmd,
+	warningSuppressionsCmd,
+	warningSuppressionCmd,
 	warningCmd,
 	metricsCmd,
 	identitiesCmd,
 	identitiesByAuthenticationMethodCmd,
 	identityCmd,
+	identitySelfPermissionsCmd,
 	authGroupsCmd,
+	authGroupsPreviewCmd,
+	authGroupsImportCmd,
 	authGroupCmd,
+	authGroupDiffCmd,
+	authRolesCmd,
+	authRoleCmd,
+	authAuditCmd,
+	authModelCmd,
+	snapshotRetentionPoliciesCmd,
+	snapshotRetentionPolicyCmd,
+	snapshotRetentionPolicySimulateCmd,
+	snapshotRetentionPolicyApplyCmd,
+	instancePoolsCmd,
+	instancePoolCmd,
+	instancePoolAcquireCmd,
 	identityProviderGroupsCmd,
 	identityProviderGroupCmd,
 	permissionsCmd,
+	entitlementsCmd,
 }
 
 // swagger:operation GET /1.0?public server server_get_untrusted
@@ -220,7 +247,7 @@ func api10Get(d *Daemon, r *http.Request) response.Response {
 	// Get the authentication methods.
 	authMethods := []string{api.AuthenticationMethodTLS}
 
-	oidcIssuer, oidcClientID, _, _ := s.GlobalConfig.OIDCServer()
+	oidcIssuer, oidcClientID, _ := s.GlobalConfig.OIDCServer()
 	if oidcIssuer != "" && oidcClientID != "" {
 		authMethods = append(authMethods, api.AuthenticationMethodOIDC)
 	}
@@ -799,6 +826,7 @@ func doAPI10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 	acmeDomainChanged := false
 	acmeCAURLChanged := false
 	oidcChanged := false
+	oidcClaimsChanged := false
 	syslogSocketChanged := false
 
 	for key := range clusterChanged {
@@ -853,8 +881,16 @@ func doAPI10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 			acmeCAURLChanged = true
 		case "acme.domain":
 			acmeDomainChanged = true
-		case "oidc.issuer", "oidc.client.id", "oidc.audience", "oidc.groups.claim":
+		case "oidc.issuer", "oidc.client.id", "oidc.audience":
 			oidcChanged = true
+		case "oidc.groups.claim", "oidc.name.claim", "oidc.email.claim":
+			oidcChanged = true
+			oidcClaimsChanged = true
+		case "core.admin_groups_max_identities":
+			err := checkAuthGroupsBroadAdminAccess(s.ShutdownCtx, s)
+			if err != nil {
+				logger.Warn("Failed checking authorization groups for broad administrative access", logger.Ctx{"err": err})
+			}
 		}
 	}
 
@@ -997,7 +1033,8 @@ func doAPI10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 	}
 
 	if oidcChanged {
-		oidcIssuer, oidcClientID, oidcAudience, oidcGroupsClaim := clusterConfig.OIDCServer()
+		oidcIssuer, oidcClientID, oidcAudience := clusterConfig.OIDCServer()
+		oidcGroupsClaim, oidcNameClaim, oidcEmailClaim := clusterConfig.OIDCClaims()
 
 		if oidcIssuer == "" || oidcClientID == "" {
 			d.oidcVerifier = nil
@@ -1008,11 +1045,16 @@ func doAPI10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 				return util.HTTPClient("", d.proxy)
 			}
 
-			d.oidcVerifier, err = oidc.NewVerifier(oidcIssuer, oidcClientID, oidcAudience, s.ServerCert, d.identityCache, httpClientFunc, &oidc.Opts{GroupsClaim: oidcGroupsClaim})
+			d.oidcVerifier, err = oidc.NewVerifier(oidcIssuer, oidcClientID, oidcAudience, s.ServerCert, d.identityCache, httpClientFunc, &oidc.Opts{GroupsClaim: oidcGroupsClaim, NameClaim: oidcNameClaim, EmailClaim: oidcEmailClaim, LoginHook: d.handleOIDCAuthenticationResult})
 			if err != nil {
 				return fmt.Errorf("Failed creating verifier: %w", err)
 			}
 		}
+
+		// Identities authenticated so far were resolved using the previous claims, so reload them.
+		if oidcClaimsChanged {
+			updateIdentityCache(d)
+		}
 	}
 
 	if syslogSocketChanged {
@@ -6,6 +6,7 @@ import (
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -81,7 +82,7 @@ var clusterNodesCmd = APIEndpoint{
 	Path: "cluster/members",
 
 	Get:  APIEndpointAction{Handler: clusterNodesGet, AccessHandler: allowPermission(entity.TypeServer, auth.EntitlementCanView)},
-	Post: APIEndpointAction{Handler: clusterNodesPost, AccessHandler: allowPermission(entity.TypeServer, auth.EntitlementCanEdit)},
+	Post: APIEndpointAction{Handler: clusterNodesPost, AccessHandler: clusterNodesPostAccessHandler, AllowUntrusted: true},
 }
 
 var clusterNodeCmd = APIEndpoint{
@@ -542,6 +543,13 @@ func clusterPutJoin(d *Daemon, r *http.Request, req api.ClusterPut) response.Res
 		UserAgent:     version.UserAgent,
 	}
 
+	// Check that this server can join before any change is made to the cluster, so that a join that would fail
+	// doesn't leave a half-joined member behind.
+	resp := clusterPutJoinValidate(s, req, localHTTPSAddress, args)
+	if resp != nil {
+		return resp
+	}
+
 	// Asynchronously join the cluster.
 	run := func(op *operations.Operation) error {
 		logger.Debug("Running cluster join operation")
@@ -845,6 +853,49 @@ func clusterPutJoin(d *Daemon, r *http.Request, req api.ClusterPut) response.Res
 	return operations.OperationResponse(op)
 }
 
+// clusterPutJoinValidate asks the cluster to validate the join of this server. It returns an error response listing
+// all the failed checks if the server can't join, or nil otherwise. Validation is skipped if the join isn't done
+// with a join token or if the cluster doesn't support it.
+func clusterPutJoinValidate(s *state.State, req api.ClusterPut, localHTTPSAddress string, args *lxd.ConnectionArgs) response.Response {
+	_, err := shared.JoinTokenDecode(req.ClusterPassword)
+	if err != nil {
+		return nil
+	}
+
+	client, err := lxd.ConnectLXD(fmt.Sprintf("https://%s", req.ClusterAddress), args)
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Failed to connect to cluster member %q: %w", req.ClusterAddress, err))
+	}
+
+	if !client.HasExtension("cluster_join_validation") {
+		return nil
+	}
+
+	validation, err := client.ValidateClusterMemberJoin(api.ClusterMemberJoinValidationPost{
+		JoinToken:     req.ClusterPassword,
+		ServerName:    req.ServerName,
+		ServerAddress: localHTTPSAddress,
+		Schema:        cluster.SchemaVersion,
+		APIExtensions: version.APIExtensionsCount(),
+		Time:          time.Now(),
+		MemberConfig:  req.MemberConfig,
+	})
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Failed to validate cluster join: %w", err))
+	}
+
+	if len(validation.Failures) == 0 {
+		return nil
+	}
+
+	messages := make([]string, 0, len(validation.Failures))
+	for _, failure := range validation.Failures {
+		messages = append(messages, failure.Message)
+	}
+
+	return response.ErrorResponseMetadata(http.StatusBadRequest, fmt.Sprintf("Cluster join validation failed: %s", strings.Join(messages, "; ")), validation)
+}
+
 // clusterPutDisableMu is used to prevent the LXD process from being replaced/stopped during removal from the
 // cluster until such time as the request that initiated the removal has finished. This allows for self removal
 // from the cluster when not the leader.
@@ -1259,6 +1310,24 @@ func clusterNodesGet(d *Daemon, r *http.Request) response.Response {
 
 var clusterNodesPostMu sync.Mutex // Used to prevent races when creating cluster join tokens.
 
+// clusterJoinMaxTimeSkew is the maximum clock difference allowed between a joining server and the cluster.
+const clusterJoinMaxTimeSkew = 30 * time.Second
+
+// clusterNodesPostAccessHandler lets untrusted servers validate a join, as they authenticate with their join token
+// which is checked by the handler. Requesting a join token requires permission to edit the server.
+func clusterNodesPostAccessHandler(d *Daemon, r *http.Request) response.Response {
+	if shared.IsTrue(request.QueryParam(r, "validate-only")) {
+		return response.EmptySyncResponse
+	}
+
+	resp := allowAuthenticated(d, r)
+	if resp != response.EmptySyncResponse {
+		return resp
+	}
+
+	return allowPermission(entity.TypeServer, auth.EntitlementCanEdit)(d, r)
+}
+
 // swagger:operation POST /1.0/cluster/members cluster cluster_members_post
 //
 //	Request a join token
@@ -1287,6 +1356,10 @@ var clusterNodesPostMu sync.Mutex // Used to prevent races when creating cluster
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
 func clusterNodesPost(d *Daemon, r *http.Request) response.Response {
+	if shared.IsTrue(request.QueryParam(r, "validate-only")) {
+		return clusterNodesPostValidate(d, r)
+	}
+
 	s := d.State()
 
 	req := api.ClusterMembersPost{}
@@ -1407,6 +1480,289 @@ func clusterNodesPost(d *Daemon, r *http.Request) response.Response {
 	return operations.OperationResponse(op)
 }
 
+// swagger:operation POST /1.0/cluster/members?validate-only=true cluster cluster_members_post_validate
+//
+//	Validate a cluster join
+//
+//	Checks whether a server can join the cluster without making any change to the cluster.
+//	The joining server authenticates using its join token, which is not consumed by the request.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: body
+//	    name: cluster
+//	    description: Cluster join validation request
+//	    required: true
+//	    schema:
+//	      $ref: "#/definitions/ClusterMemberJoinValidationPost"
+//	responses:
+//	  "200":
+//	    description: Cluster join validation
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          $ref: "#/definitions/ClusterMemberJoinValidation"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func clusterNodesPostValidate(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	req := api.ClusterMemberJoinValidationPost{}
+
+	// Parse the request.
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if !s.ServerClustered {
+		return response.BadRequest(fmt.Errorf("This server is not clustered"))
+	}
+
+	// Callers that are allowed to add cluster members themselves don't need a join token.
+	resp := allowAuthenticated(d, r)
+	if resp == response.EmptySyncResponse {
+		resp = allowPermission(entity.TypeServer, auth.EntitlementCanEdit)(d, r)
+	}
+
+	trusted := resp == response.EmptySyncResponse
+	if req.JoinToken != "" {
+		joinToken, err := shared.JoinTokenDecode(req.JoinToken)
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Invalid cluster join token: %w", err))
+		}
+
+		if req.ServerName == "" {
+			req.ServerName = joinToken.ServerName
+		}
+
+		if req.ServerName != joinToken.ServerName {
+			return response.Forbidden(fmt.Errorf("Cluster join token was issued for a different server name"))
+		}
+
+		// Only look the token up, it is consumed when the server joins.
+		joinOp, err := clusterMemberJoinTokenFind(s, r, api.ProjectDefaultName, joinToken)
+		if err != nil {
+			return response.InternalError(fmt.Errorf("Failed during search for join token operation: %w", err))
+		}
+
+		if joinOp == nil {
+			return response.Forbidden(fmt.Errorf("No matching cluster join operation found"))
+		}
+
+		if clusterMemberJoinTokenExpired(s, joinOp) {
+			return response.Forbidden(fmt.Errorf("Token has expired"))
+		}
+	} else if !trusted {
+		return resp
+	}
+
+	remoteHost, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remoteHost = r.RemoteAddr
+	}
+
+	validation, err := clusterValidateJoin(s, req, trusted, remoteHost)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, validation)
+}
+
+// clusterValidateJoin checks whether the server described by the request can join the cluster, without making any
+// change to the cluster. All the checks are performed and every failure is reported, rather than stopping at the
+// first one. Untrusted callers (authenticated by a join token) are not told about the existing members, and the cluster
+// only connects back to them at the address that they sent the request from (given as remoteHost).
+func clusterValidateJoin(s *state.State, req api.ClusterMemberJoinValidationPost, trusted bool, remoteHost string) (*api.ClusterMemberJoinValidation, error) {
+	validation := &api.ClusterMemberJoinValidation{
+		Failures: []api.ClusterMemberJoinValidationFailure{},
+	}
+
+	addFailure := func(check string, format string, args ...any) {
+		validation.Failures = append(validation.Failures, api.ClusterMemberJoinValidationFailure{
+			Check:   check,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if req.ServerName == "" {
+		addFailure("name", "Member name must not be empty")
+	}
+
+	address := ""
+	if req.ServerAddress == "" {
+		addFailure("address", "Member address must not be empty")
+	} else {
+		address = util.CanonicalNetworkAddress(req.ServerAddress, shared.HTTPSDefaultPort)
+	}
+
+	// Check versions and name and address collisions against every existing member.
+	var members []db.NodeInfo
+	err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		var err error
+		members, err = tx.GetNodes(ctx)
+		if err != nil {
+			return fmt.Errorf("Failed getting cluster members: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	validation.Failures = append(validation.Failures, clusterValidateJoinMembers(members, req, address, trusted)...)
+
+	// Check the clocks are close enough. The clocks aren't compared if the time of the joining server isn't given.
+	if !req.Time.IsZero() {
+		skew := time.Since(req.Time)
+		if skew < 0 {
+			skew = -skew
+		}
+
+		if skew > clusterJoinMaxTimeSkew {
+			addFailure("time", "The clock of the joining server differs from the cluster by %s (maximum allowed is %s)", skew.Round(time.Second), clusterJoinMaxTimeSkew)
+		}
+	}
+
+	// Check that the node-specific config of all storage pools and networks is provided.
+	memberConfig, err := clusterGetMemberConfig(s.DB.Cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range memberConfig {
+		found := false
+		for _, reqKey := range req.MemberConfig {
+			if reqKey.Entity == key.Entity && reqKey.Name == key.Name && reqKey.Key == key.Key {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			missingKey := key
+			validation.Failures = append(validation.Failures, api.ClusterMemberJoinValidationFailure{
+				Check:        "member_config",
+				Message:      fmt.Sprintf("Missing member config %s", key.Description),
+				MemberConfig: &missingKey,
+			})
+		}
+	}
+
+	// Check that the cluster can reach the joining server. The other way round has been checked by the joining
+	// server reaching the cluster.
+	if address != "" {
+		failure := clusterValidateJoinConnectivity(address, trusted, remoteHost)
+		if failure != nil {
+			validation.Failures = append(validation.Failures, *failure)
+		}
+	}
+
+	return validation, nil
+}
+
+// clusterValidateJoinMembers returns the failures of the checks of the joining server against the existing cluster
+// members. The failures only name the members and their versions if the caller is trusted.
+func clusterValidateJoinMembers(members []db.NodeInfo, req api.ClusterMemberJoinValidationPost, address string, trusted bool) []api.ClusterMemberJoinValidationFailure {
+	failures := []api.ClusterMemberJoinValidationFailure{}
+	addFailure := func(check string, format string, args ...any) {
+		failures = append(failures, api.ClusterMemberJoinValidationFailure{
+			Check:   check,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	versionMismatch := false
+	for _, member := range members {
+		if req.ServerName != "" && member.Name == req.ServerName {
+			addFailure("name", "The cluster already has a member with name %q", req.ServerName)
+		}
+
+		if address != "" && member.Address == address {
+			addFailure("address", "The cluster already has a member with address %q", address)
+		}
+
+		if member.Schema == req.Schema && member.APIExtensions == req.APIExtensions {
+			continue
+		}
+
+		if !trusted {
+			versionMismatch = true
+			continue
+		}
+
+		if member.Schema != req.Schema {
+			addFailure("version", "Cluster member %q has DB schema %d but the joining server has %d", member.Name, member.Schema, req.Schema)
+		}
+
+		if member.APIExtensions != req.APIExtensions {
+			addFailure("version", "Cluster member %q has %d API extensions but the joining server has %d", member.Name, member.APIExtensions, req.APIExtensions)
+		}
+	}
+
+	if versionMismatch {
+		addFailure("version", "The version of the joining server doesn't match the version of the cluster")
+	}
+
+	return failures
+}
+
+// clusterValidateJoinConnectivity checks that the cluster can connect to the joining server at the given address, and
+// returns a failure if it can't. Untrusted callers could otherwise use the cluster to probe arbitrary addresses, so the
+// address is only checked for them if it is the address that the request was sent from, and the reason of a failure to
+// connect is only logged.
+func clusterValidateJoinConnectivity(address string, trusted bool, remoteHost string) *api.ClusterMemberJoinValidationFailure {
+	if !trusted {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil
+		}
+
+		hostIP := net.ParseIP(host)
+		remoteIP := net.ParseIP(remoteHost)
+		if hostIP == nil || remoteIP == nil || !hostIP.Equal(remoteIP) {
+			logger.Debug("Skipping connectivity check of joining server with address differing from the request", logger.Ctx{"address": address, "remote": remoteHost})
+			return nil
+		}
+	}
+
+	conn, err := net.DialTimeout("tcp", address, 5*time.Second)
+	if err != nil {
+		logger.Warn("Failed to connect to joining server", logger.Ctx{"address": address, "err": err})
+		return &api.ClusterMemberJoinValidationFailure{
+			Check:   "connectivity",
+			Message: fmt.Sprintf("The cluster failed to connect to the joining server at %q", address),
+		}
+	}
+
+	_ = conn.Close()
+	return nil
+}
+
 // swagger:operation GET /1.0/cluster/members/{name} cluster cluster_member_get
 //
 //	Get the cluster member
@@ -3735,7 +4091,7 @@ func clusterGroupsGet(d *Daemon, r *http.Request) response.Response {
 
 	var result any
 
-	err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+	err := s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
 		var err error
 
 		if recursion {
@@ -3828,7 +4184,7 @@ func clusterGroupGet(d *Daemon, r *http.Request) response.Response {
 
 	var group *dbCluster.ClusterGroup
 
-	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
 		// Get the cluster group.
 		group, err = dbCluster.GetClusterGroup(ctx, tx.Tx(), name)
 		if err != nil {
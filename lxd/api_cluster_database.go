@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/canonical/lxd/lxd/auth"
+	"github.com/canonical/lxd/lxd/cluster"
+	"github.com/canonical/lxd/lxd/db"
+	"github.com/canonical/lxd/lxd/db/operationtype"
+	"github.com/canonical/lxd/lxd/db/query"
+	"github.com/canonical/lxd/lxd/operations"
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/lxd/state"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
+	"github.com/canonical/lxd/shared/logger"
+)
+
+var clusterDatabaseCmd = APIEndpoint{
+	Path: "cluster/database",
+
+	Get:  APIEndpointAction{Handler: clusterDatabaseGet, AccessHandler: allowPermission(entity.TypeServer, auth.EntitlementCanEdit)},
+	Post: APIEndpointAction{Handler: clusterDatabasePost, AccessHandler: allowPermission(entity.TypeServer, auth.EntitlementCanEdit)},
+}
+
+// raftSegmentRegexp matches the names of the open and closed raft log segments in the global database directory.
+var raftSegmentRegexp = regexp.MustCompile(`^(open-[0-9]+|[0-9]{16}-[0-9]{16})$`)
+
+// clusterDatabaseDiskUsage returns the total size of the files in the given global database directory and the size
+// of its raft log segments.
+func clusterDatabaseDiskUsage(dir string) (diskSize int64, walSize int64, err error) {
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		diskSize += info.Size()
+
+		if raftSegmentRegexp.MatchString(entry.Name()) {
+			walSize += info.Size()
+		}
+
+		return nil
+	})
+	if err != nil {
+		return -1, -1, fmt.Errorf("Failed to get disk usage of %q: %w", dir, err)
+	}
+
+	return diskSize, walSize, nil
+}
+
+// swagger:operation GET /1.0/cluster/database cluster cluster_database_get
+//
+//	Get the global database state
+//
+//	Gets the size of the global database on disk, its page utilization and the number of rows of each table.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    description: Global database state
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          $ref: "#/definitions/ClusterDatabase"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func clusterDatabaseGet(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	var err error
+	database := api.ClusterDatabase{}
+
+	database.DiskSize, database.WALSize, err = clusterDatabaseDiskUsage(s.OS.GlobalDatabaseDir())
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		pragmas := map[string]*int64{
+			"page_size":      &database.PageSize,
+			"page_count":     &database.PageCount,
+			"freelist_count": &database.FreePageCount,
+		}
+
+		for pragma, dest := range pragmas {
+			err := tx.Tx().QueryRowContext(ctx, "PRAGMA "+pragma).Scan(dest)
+			if err != nil {
+				return fmt.Errorf("Failed to get database %s: %w", pragma, err)
+			}
+		}
+
+		database.Tables, err = query.CountAll(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if database.PageCount > 0 {
+		database.PageUtilization = float64(database.PageCount-database.FreePageCount) / float64(database.PageCount)
+	}
+
+	return response.SyncResponse(true, database)
+}
+
+// swagger:operation POST /1.0/cluster/database cluster cluster_database_post
+//
+//	Run maintenance on the global database
+//
+//	Runs an online vacuum or checkpoint of the global database on the leader.
+//	This is refused while any cluster member is offline.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: body
+//	    name: database
+//	    description: Database action
+//	    required: true
+//	    schema:
+//	      $ref: "#/definitions/ClusterDatabasePost"
+//	responses:
+//	  "202":
+//	    $ref: "#/responses/Operation"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func clusterDatabasePost(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	req := api.ClusterDatabasePost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if req.Action != "vacuum" && req.Action != "checkpoint" {
+		return response.BadRequest(fmt.Errorf("Invalid action %q", req.Action))
+	}
+
+	// Run the maintenance on the leader, which is the member applying changes to the database.
+	if s.ServerClustered {
+		leader, err := d.gateway.LeaderAddress()
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		if leader != s.LocalConfig.ClusterAddress() {
+			client, err := cluster.Connect(leader, s.Endpoints.NetworkCert(), s.ServerCert(), r, false)
+			if err != nil {
+				return response.SmartError(err)
+			}
+
+			return response.ForwardedResponse(client, r)
+		}
+
+		// Refuse to run while members are offline, as they would fall behind while the database is rewritten.
+		var offlineMembers []string
+		err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+			members, err := tx.GetNodes(ctx)
+			if err != nil {
+				return fmt.Errorf("Failed getting cluster members: %w", err)
+			}
+
+			for _, member := range members {
+				if member.IsOffline(s.GlobalConfig.OfflineThreshold()) {
+					offlineMembers = append(offlineMembers, member.Name)
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		if len(offlineMembers) > 0 {
+			return response.BadRequest(fmt.Errorf("Cannot run database %s while cluster members are offline: %s", req.Action, strings.Join(offlineMembers, ", ")))
+		}
+	}
+
+	run := func(op *operations.Operation) error {
+		return clusterDatabaseMaintenance(s, op, req.Action)
+	}
+
+	op, err := operations.OperationCreate(s, "", operations.OperationClassTask, operationtype.ClusterDatabaseMaintenance, nil, nil, run, nil, nil, r)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// clusterDatabaseMaintenance runs the given maintenance action on the global database, reporting progress through
+// the metadata of the given operation.
+func clusterDatabaseMaintenance(s *state.State, op *operations.Operation, action string) error {
+	steps := []string{"PRAGMA wal_checkpoint(TRUNCATE)"}
+	if action == "vacuum" {
+		steps = append([]string{"VACUUM"}, steps...)
+	}
+
+	diskSizeBefore, _, err := clusterDatabaseDiskUsage(s.OS.GlobalDatabaseDir())
+	if err != nil {
+		return err
+	}
+
+	for i, step := range steps {
+		_ = op.UpdateMetadata(map[string]any{"progress": fmt.Sprintf("Running %q (%d/%d)", step, i+1, len(steps))})
+
+		_, err := s.DB.Cluster.DB().ExecContext(s.ShutdownCtx, step)
+		if err != nil {
+			return fmt.Errorf("Failed running %q on the global database: %w", step, err)
+		}
+	}
+
+	diskSizeAfter, _, err := clusterDatabaseDiskUsage(s.OS.GlobalDatabaseDir())
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Ran global database maintenance", logger.Ctx{"action": action, "sizeBefore": diskSizeBefore, "sizeAfter": diskSizeAfter})
+
+	_ = op.UpdateMetadata(map[string]any{
+		"progress":         "Done",
+		"disk_size_before": diskSizeBefore,
+		"disk_size_after":  diskSizeAfter,
+	})
+
+	return nil
+}
@@ -2,12 +2,14 @@ package main
 
 import (
 	"fmt"
+	"net"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/canonical/lxd/client"
+	"github.com/canonical/lxd/lxd/db"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 )
@@ -78,6 +80,64 @@ func TestCluster_RenameNode(t *testing.T) {
 	require.NoError(t, err)
 }
 
+// Only trusted callers are told about the members that don't match the joining server.
+func TestCluster_ValidateJoinMembers(t *testing.T) {
+	members := []db.NodeInfo{
+		{Name: "node1", Address: "10.0.0.1:8443", Schema: 70, APIExtensions: 400},
+		{Name: "node2", Address: "10.0.0.2:8443", Schema: 71, APIExtensions: 401},
+	}
+
+	req := api.ClusterMemberJoinValidationPost{ServerName: "node3", Schema: 70, APIExtensions: 400}
+
+	failures := clusterValidateJoinMembers(members, req, "10.0.0.3:8443", true)
+	require.Len(t, failures, 2)
+	assert.Contains(t, failures[0].Message, "node2")
+	assert.Contains(t, failures[1].Message, "401")
+
+	failures = clusterValidateJoinMembers(members, req, "10.0.0.3:8443", false)
+	require.Len(t, failures, 1)
+	assert.Equal(t, "version", failures[0].Check)
+	assert.NotContains(t, failures[0].Message, "node2")
+	assert.NotContains(t, failures[0].Message, "71")
+
+	req.ServerName = "node1"
+	failures = clusterValidateJoinMembers(members[:1], req, "10.0.0.1:8443", false)
+	require.Len(t, failures, 2)
+	assert.Equal(t, "name", failures[0].Check)
+	assert.Equal(t, "address", failures[1].Check)
+}
+
+// The cluster only connects to the joining server of an untrusted caller at the address that the request came from,
+// and doesn't return the reason of a failure to connect.
+func TestCluster_ValidateJoinConnectivity(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+
+	address := listener.Addr().String()
+	assert.Nil(t, clusterValidateJoinConnectivity(address, true, ""))
+	assert.Nil(t, clusterValidateJoinConnectivity(address, false, "127.0.0.1"))
+
+	// Get an address that nothing listens on.
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddress := closed.Addr().String()
+	require.NoError(t, closed.Close())
+
+	failure := clusterValidateJoinConnectivity(closedAddress, true, "")
+	require.NotNil(t, failure)
+	assert.Equal(t, "connectivity", failure.Check)
+	assert.NotContains(t, failure.Message, "refused")
+
+	failure = clusterValidateJoinConnectivity(closedAddress, false, "127.0.0.1")
+	require.NotNil(t, failure)
+	assert.NotContains(t, failure.Message, "refused")
+
+	// Addresses other than the one of the caller aren't checked.
+	assert.Nil(t, clusterValidateJoinConnectivity(closedAddress, false, "192.0.2.1"))
+	assert.Nil(t, clusterValidateJoinConnectivity("not an address", false, "127.0.0.1"))
+}
+
 // Test helper for cluster-related APIs.
 type clusterFixture struct {
 	t       *testing.T
@@ -57,12 +57,14 @@ var apiInternal = []APIEndpoint{
 	internalGarbageCollectorCmd,
 	internalImageOptimizeCmd,
 	internalImageRefreshCmd,
+	internalImageTransferCmd,
 	internalRAFTSnapshotCmd,
 	internalReadyCmd,
 	internalShutdownCmd,
 	internalSQLCmd,
 	internalWarningCreateCmd,
 	internalIdentityCacheRefreshCmd,
+	internalAuthRebuildEntityURLsCmd,
 }
 
 var internalShutdownCmd = APIEndpoint{
@@ -126,6 +128,12 @@ var internalImageOptimizeCmd = APIEndpoint{
 	Post: APIEndpointAction{Handler: internalOptimizeImage, AccessHandler: allowPermission(entity.TypeServer, auth.EntitlementCanEdit)},
 }
 
+var internalImageTransferCmd = APIEndpoint{
+	Path: "image-transfer",
+
+	Post: APIEndpointAction{Handler: internalTransferImage, AccessHandler: allowPermission(entity.TypeServer, auth.EntitlementCanEdit)},
+}
+
 var internalWarningCreateCmd = APIEndpoint{
 	Path: "testing/warnings",
 
@@ -144,11 +152,38 @@ var internalIdentityCacheRefreshCmd = APIEndpoint{
 	Post: APIEndpointAction{Handler: internalIdentityCacheRefresh, AccessHandler: allowPermission(entity.TypeServer, auth.EntitlementCanEdit)},
 }
 
+var internalAuthRebuildEntityURLsCmd = APIEndpoint{
+	Path: "auth/rebuild-entity-urls",
+
+	Post: APIEndpointAction{Handler: internalAuthRebuildEntityURLs, AccessHandler: allowPermission(entity.TypeServer, auth.EntitlementCanEdit)},
+}
+
 type internalImageOptimizePost struct {
 	Image api.Image `json:"image" yaml:"image"`
 	Pool  string    `json:"pool"  yaml:"pool"`
 }
 
+type internalImageTransferPost struct {
+	Fingerprint string `json:"fingerprint" yaml:"fingerprint"`
+	Project     string `json:"project"     yaml:"project"`
+	Address     string `json:"address"     yaml:"address"`
+}
+
+// internalAuthPermission identifies a permission in the response of internalAuthRebuildEntityURLs.
+type internalAuthPermission struct {
+	ID          int    `json:"id"                     yaml:"id"`
+	Entitlement string `json:"entitlement"            yaml:"entitlement"`
+	EntityType  string `json:"entity_type"            yaml:"entity_type"`
+	EntityID    int    `json:"entity_id"              yaml:"entity_id"`
+	Error       string `json:"error,omitempty"        yaml:"error,omitempty"`
+}
+
+type internalAuthRebuildEntityURLsResult struct {
+	Resolved  int                      `json:"resolved"  yaml:"resolved"`
+	Removed   []internalAuthPermission `json:"removed"   yaml:"removed"`
+	Unfixable []internalAuthPermission `json:"unfixable" yaml:"unfixable"`
+}
+
 type internalWarningCreatePost struct {
 	Location   string      `json:"location"    yaml:"location"`
 	Project    string      `json:"project"     yaml:"project"`
@@ -221,6 +256,56 @@ func internalOptimizeImage(d *Daemon, r *http.Request) response.Response {
 	return response.EmptySyncResponse
 }
 
+// internalTransferImage transfers an image from another cluster member to this member, as a delta against a previous
+// version of the image if possible. It is used to distribute updated images to the cluster members.
+func internalTransferImage(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	req := &internalImageTransferPost{}
+
+	// Parse the request.
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	unlock, err := imageOperationLock(req.Fingerprint)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	defer unlock()
+
+	var nodeAddresses []string
+	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		nodeAddresses, err = tx.GetNodesWithImage(ctx, req.Fingerprint)
+
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Nothing to do if the image is already available on this member.
+	if shared.ValueInSlice(s.LocalConfig.ClusterAddress(), nodeAddresses) {
+		return response.EmptySyncResponse
+	}
+
+	err = instanceImageTransfer(s, r, req.Project, req.Fingerprint, req.Address)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		return tx.AddImageToLocalNode(ctx, req.Project, req.Fingerprint)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
 func internalRefreshImage(d *Daemon, r *http.Request) response.Response {
 	s := d.State()
 
@@ -1091,6 +1176,57 @@ func internalBGPState(d *Daemon, r *http.Request) response.Response {
 	return response.SyncResponse(true, s.BGP.Debug())
 }
 
+// internalAuthRebuildEntityURLs recomputes the entity URLs of all permissions, removing the permissions of entities
+// that no longer exist and reporting those that can't be repaired. It is meant as a recovery tool after bulk imports or
+// database migrations.
+func internalAuthRebuildEntityURLs(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	var rebuild *cluster.PermissionEntityURLsRebuild
+	err := s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		var err error
+		rebuild, err = cluster.RebuildPermissionEntityURLs(ctx, tx.Tx())
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	result := internalAuthRebuildEntityURLsResult{
+		Resolved:  rebuild.Resolved,
+		Removed:   make([]internalAuthPermission, 0, len(rebuild.Removed)),
+		Unfixable: make([]internalAuthPermission, 0, len(rebuild.Unfixable)),
+	}
+
+	for _, p := range rebuild.Removed {
+		logger.Info("Removed permission of entity that no longer exists", logger.Ctx{"id": p.ID, "entitlement": p.Entitlement, "entityType": p.EntityType, "entityID": p.EntityID})
+		result.Removed = append(result.Removed, internalAuthPermission{
+			ID:          p.ID,
+			Entitlement: string(p.Entitlement),
+			EntityType:  string(p.EntityType),
+			EntityID:    p.EntityID,
+		})
+	}
+
+	for _, p := range rebuild.Unfixable {
+		logger.Warn("Failed resolving permission entity URL", logger.Ctx{"id": p.ID, "entitlement": p.Entitlement, "entityType": p.EntityType, "entityID": p.EntityID, "err": p.Err})
+		result.Unfixable = append(result.Unfixable, internalAuthPermission{
+			ID:          p.ID,
+			Entitlement: p.Entitlement,
+			EntityType:  p.EntityType,
+			EntityID:    p.EntityID,
+			Error:       p.Err.Error(),
+		})
+	}
+
+	// The identity cache holds the permissions of each group, so it must be updated when they change.
+	if len(rebuild.Removed) > 0 {
+		notifyIdentityCacheRefresh(s)
+	}
+
+	return response.SyncResponse(true, result)
+}
+
 func internalIdentityCacheRefresh(d *Daemon, r *http.Request) response.Response {
 	logger.Debug("Received identity cache update notification - refreshing cache")
 	d.State().UpdateIdentityCache()
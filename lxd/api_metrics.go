@@ -22,6 +22,7 @@ import (
 	"github.com/canonical/lxd/lxd/request"
 	"github.com/canonical/lxd/lxd/response"
 	"github.com/canonical/lxd/lxd/state"
+	storagePools "github.com/canonical/lxd/lxd/storage"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/entity"
@@ -39,7 +40,7 @@ var metricsCacheLock sync.Mutex
 var metricsCmd = APIEndpoint{
 	Path: "metrics",
 
-	Get: APIEndpointAction{Handler: metricsGet, AccessHandler: allowMetrics, AllowUntrusted: true},
+	Get: APIEndpointAction{Handler: metricsGet, AccessHandler: allowMetrics, AllowUntrusted: true, NoStaleReads: true},
 }
 
 func allowMetrics(d *Daemon, r *http.Request) response.Response {
@@ -120,7 +121,19 @@ func metricsGet(d *Daemon, r *http.Request) response.Response {
 		}
 
 		// Add internal metrics.
-		metricSet.Merge(internalMetrics(ctx, s.StartTime, tx))
+		metricSet.Merge(internalMetrics(ctx, s.StartTime, s.OS.GlobalDatabaseDir(), tx))
+		metricSet.AddSamples(metrics.EventsDroppedTotal, metrics.Sample{Value: float64(s.Events.Dropped())})
+		metricSet.AddSamples(metrics.ImageDeltaHitsTotal, metrics.Sample{Value: float64(imageDeltaHits.Load())})
+		metricSet.AddSamples(metrics.ImageDeltaMissesTotal, metrics.Sample{Value: float64(imageDeltaMisses.Load())})
+		metricSet.AddSamples(metrics.ImageDeltaSavedBytesTotal, metrics.Sample{Value: float64(imageDeltaSavedBytes.Load())})
+
+		// Add instance pool metrics.
+		poolMetrics, err := instancePoolMetrics(ctx, tx, s.ServerName, projectNames)
+		if err != nil {
+			logger.Warn("Failed to get instance pool metrics", logger.Ctx{"err": err})
+		} else {
+			metricSet.Merge(poolMetrics)
+		}
 
 		return nil
 	})
@@ -352,7 +365,7 @@ func getFilteredMetrics(s *state.State, r *http.Request, compress bool, metricSe
 	return response.SyncResponsePlain(true, compress, metricSet.String())
 }
 
-func internalMetrics(ctx context.Context, daemonStartTime time.Time, tx *db.ClusterTx) *metrics.MetricSet {
+func internalMetrics(ctx context.Context, daemonStartTime time.Time, globalDatabaseDir string, tx *db.ClusterTx) *metrics.MetricSet {
 	out := metrics.NewMetricSet(nil)
 
 	warnings, err := dbCluster.GetWarnings(ctx, tx.Tx())
@@ -371,6 +384,19 @@ func internalMetrics(ctx context.Context, daemonStartTime time.Time, tx *db.Clus
 		out.AddSamples(metrics.OperationsTotal, metrics.Sample{Value: float64(len(operations))})
 	}
 
+	databaseSize, _, err := clusterDatabaseDiskUsage(globalDatabaseDir)
+	if err != nil {
+		logger.Warn("Failed to get global database size", logger.Ctx{"err": err})
+	} else {
+		// Size of the global database on disk
+		out.AddSamples(metrics.DatabaseSizeBytes, metrics.Sample{Value: float64(databaseSize)})
+	}
+
+	// Instance volume creations waiting for a slot on each storage pool
+	for poolName, depth := range storagePools.CreateQueueDepths() {
+		out.AddSamples(metrics.StoragePoolCreateQueued, metrics.Sample{Labels: map[string]string{"pool": poolName}, Value: float64(depth)})
+	}
+
 	// Daemon uptime
 	out.AddSamples(metrics.UptimeSeconds, metrics.Sample{Value: time.Since(daemonStartTime).Seconds()})
 
@@ -13,10 +13,14 @@ import (
 
 	"github.com/gorilla/mux"
 
+	"github.com/canonical/lxd/client"
 	"github.com/canonical/lxd/lxd/auth"
+	lxdCluster "github.com/canonical/lxd/lxd/cluster"
 	"github.com/canonical/lxd/lxd/db"
 	"github.com/canonical/lxd/lxd/db/cluster"
 	"github.com/canonical/lxd/lxd/db/operationtype"
+	"github.com/canonical/lxd/lxd/instance"
+	"github.com/canonical/lxd/lxd/instance/instancetype"
 	"github.com/canonical/lxd/lxd/lifecycle"
 	"github.com/canonical/lxd/lxd/network"
 	"github.com/canonical/lxd/lxd/operations"
@@ -147,7 +151,7 @@ func projectsGet(d *Daemon, r *http.Request) response.Response {
 	}
 
 	var result any
-	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
 		projects, err := cluster.GetProjects(ctx, tx.Tx())
 		if err != nil {
 			return err
@@ -414,7 +418,7 @@ func projectGet(d *Daemon, r *http.Request) response.Response {
 
 	// Get the database entry
 	var project *api.Project
-	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
 		dbProject, err := cluster.GetProject(ctx, tx.Tx(), name)
 		if err != nil {
 			return err
@@ -477,6 +481,19 @@ func projectPut(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	if isClusterNotification(r) {
+		// In this case the ProjectPut request payload contains information about the old project, since
+		// the new one has already been saved in the database.
+		old := api.ProjectPut{}
+		err := json.NewDecoder(r.Body).Decode(&old)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		err = doProjectDeviceDefaultsUpdate(s, name, old)
+		return response.SmartError(err)
+	}
+
 	// Get the current data
 	var project *api.Project
 	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
@@ -742,9 +759,107 @@ func projectChange(s *state.State, project *api.Project, req api.ProjectPut) res
 		return response.SmartError(err)
 	}
 
+	// Apply changes to the device defaults to the instances of the project on all cluster members.
+	if shared.StringPrefixInSlice(instancetype.ProjectDeviceDefaultPrefix, configChanged) {
+		err = doProjectDeviceDefaultsUpdate(s, project.Name, project.ProjectPut)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		// Notify all other nodes. If a node is down, it will be ignored.
+		notifier, err := lxdCluster.NewNotifier(s, s.Endpoints.NetworkCert(), s.ServerCert(), lxdCluster.NotifyAlive)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		err = notifier(func(client lxd.InstanceServer) error {
+			return client.UpdateProject(project.Name, project.ProjectPut, "")
+		})
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
 	return response.EmptySyncResponse
 }
 
+// doProjectDeviceDefaultsUpdate applies changes to the device defaults of the project to its instances on this
+// cluster member, including live updates of running instances where the changed device keys support it.
+func doProjectDeviceDefaultsUpdate(s *state.State, projectName string, old api.ProjectPut) error {
+	var p *api.Project
+	var insts map[int]db.InstanceArgs
+	err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		dbProject, err := cluster.GetProject(ctx, tx.Tx(), projectName)
+		if err != nil {
+			return err
+		}
+
+		p, err = dbProject.ToAPI(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		dbInstances, err := cluster.GetInstances(ctx, tx.Tx(), cluster.InstanceFilter{Project: &projectName})
+		if err != nil {
+			return err
+		}
+
+		insts, err = tx.InstancesToInstanceArgs(ctx, true, dbInstances...)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to fetch instances of project %q: %w", projectName, err)
+	}
+
+	// As the project has already been updated in the database by this point, load the instances with the old
+	// config so that updating them will detect the changes and apply them.
+	p.Config = old.Config
+
+	failures := map[string]error{}
+	for _, args := range insts {
+		if args.Node != "" && args.Node != s.ServerName {
+			continue // This instance does not belong to this member, skip.
+		}
+
+		inst, err := instance.Load(s, args, *p)
+		if err != nil {
+			failures[args.Name] = err
+			continue
+		}
+
+		// Update will internally load the new project config and detect the changes to apply.
+		err = inst.Update(db.InstanceArgs{
+			Architecture: inst.Architecture(),
+			Config:       inst.LocalConfig(),
+			Description:  inst.Description(),
+			Devices:      inst.LocalDevices(),
+			Ephemeral:    inst.IsEphemeral(),
+			Profiles:     inst.Profiles(),
+			Project:      inst.Project().Name,
+			Type:         inst.Type(),
+			Snapshot:     inst.IsSnapshot(),
+		}, true)
+		if err != nil {
+			failures[args.Name] = err
+		}
+	}
+
+	if len(failures) != 0 {
+		msg := "The following instances failed to update (project change still saved):\n"
+		for instName, err := range failures {
+			msg += fmt.Sprintf(" - Instance: %s: %v\n", instName, err)
+		}
+
+		return fmt.Errorf("%s", msg)
+	}
+
+	return nil
+}
+
 // swagger:operation POST /1.0/projects/{name} projects project_post
 //
 //	Rename the project
@@ -971,7 +1086,7 @@ func projectStateGet(d *Daemon, r *http.Request) response.Response {
 	state := api.ProjectState{}
 
 	// Get current limits and usage.
-	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
 		result, err := projecthelpers.GetCurrentAllocations(s.GlobalConfig.Dump(), ctx, tx, name)
 		if err != nil {
 			return err
@@ -1070,6 +1185,14 @@ func projectValidateConfig(s *state.State, config map[string]string) error {
 		//  type: string
 		//  shortdesc: Compression algorithm to use for backups
 		"backups.compression_algorithm": validate.IsCompressionAlgorithm,
+		// lxdmeta:generate(entities=project; group=specific; key=exec.record)
+		// When enabled, exec sessions into all instances of the project are recorded, regardless of
+		// the {config:option}`instance-miscellaneous:exec.record` setting of the instances.
+		// ---
+		//  type: bool
+		//  defaultdesc: `false`
+		//  shortdesc: Whether to record exec sessions into the instances of the project
+		"exec.record": validate.Optional(validate.IsBool),
 		// lxdmeta:generate(entities=project; group=features; key=features.profiles)
 		//
 		// ---
@@ -1433,6 +1556,30 @@ func projectValidateConfig(s *state.State, config map[string]string) error {
 			continue
 		}
 
+		// lxdmeta:generate(entities=project; group=specific; key=default.device.<device>.<key>)
+		// Set the device configuration key `<key>` on each device of the instances in this project whose type or
+		// name is `<device>`. The value set by profiles or by the instance is overridden, unless
+		// `default.device.<device>.overridable` is set to `true`, in which case the value only applies to devices
+		// that do not set the key.
+		// ---
+		//  type: string
+		//  shortdesc: Device configuration enforced on the instances in this project
+		if strings.HasPrefix(key, instancetype.ProjectDeviceDefaultPrefix) {
+			_, deviceKey, err := instancetype.ParseProjectDeviceDefaultKey(key)
+			if err != nil {
+				return fmt.Errorf("Invalid project configuration key %q: %w", k, err)
+			}
+
+			if deviceKey == "overridable" {
+				err = validate.Optional(validate.IsBool)(v)
+				if err != nil {
+					return fmt.Errorf("Invalid project configuration key %q value: %w", k, err)
+				}
+			}
+
+			continue
+		}
+
 		// Then validate.
 		validator, ok := projectConfigKeys[key]
 		if !ok {
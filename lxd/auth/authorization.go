@@ -12,7 +12,7 @@ import (
 )
 
 const (
-	// DriverTLS is the default TLS authorization driver. It is not compatible with OIDC authentication.
+	// DriverTLS is the default TLS authorization driver. OIDC identities are only authorized against their groups.
 	DriverTLS string = "tls"
 )
 
@@ -41,6 +41,7 @@ type Authorizer interface {
 
 	CheckPermission(ctx context.Context, r *http.Request, entityURL *api.URL, entitlement Entitlement) error
 	GetPermissionChecker(ctx context.Context, r *http.Request, entitlement Entitlement, entityType entity.Type) (PermissionChecker, error)
+	GetPermissions(ctx context.Context, r *http.Request, entityURL *api.URL) ([]api.Permission, error)
 
 	AddProject(ctx context.Context, projectID int64, projectName string) error
 	DeleteProject(ctx context.Context, projectID int64, projectName string) error
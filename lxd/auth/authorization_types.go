@@ -2,8 +2,6 @@ package auth
 
 import (
 	"fmt"
-
-	"github.com/canonical/lxd/shared"
 )
 
 // Entitlement represents a permission that can be applied to an entity.
@@ -19,6 +17,9 @@ const (
 	// EntitlementCanDelete is the `can_delete` Entitlement. It applies to most entity types.
 	EntitlementCanDelete Entitlement = "can_delete"
 
+	// EntitlementCanDisable is the `can_disable` Entitlement. It applies to entity.TypeIdentity.
+	EntitlementCanDisable Entitlement = "can_disable"
+
 	// EntitlementServerAdmin is the `admin` Entitlement. It applies to entity.TypeServer.
 	EntitlementServerAdmin Entitlement = "admin"
 
@@ -58,6 +59,9 @@ const (
 	// EntitlementCanDeleteGroups is the `can_delete_groups` Entitlement. It applies to entity.TypeServer.
 	EntitlementCanDeleteGroups Entitlement = "can_delete_groups"
 
+	// EntitlementCanViewAuthAudit is the `can_view_auth_audit` Entitlement. It applies to entity.TypeServer.
+	EntitlementCanViewAuthAudit Entitlement = "can_view_auth_audit"
+
 	// EntitlementStoragePoolManager is the `storage_pool_manager` Entitlement. It applies to entity.TypeServer.
 	EntitlementStoragePoolManager Entitlement = "storage_pool_manager"
 
@@ -88,6 +92,9 @@ const (
 	// EntitlementCanOverrideClusterTargetRestriction is the `can_override_cluster_target_restriction` Entitlement. It applies to entity.TypeServer.
 	EntitlementCanOverrideClusterTargetRestriction Entitlement = "can_override_cluster_target_restriction"
 
+	// EntitlementCanOverrideImmutable is the `can_override_immutable` Entitlement. It applies to entity.TypeServer.
+	EntitlementCanOverrideImmutable Entitlement = "can_override_immutable"
+
 	// EntitlementCanViewPrivilegedEvents is the `can_view_privileged_events` Entitlement. It applies to entity.TypeServer.
 	EntitlementCanViewPrivilegedEvents Entitlement = "can_view_privileged_events"
 
@@ -97,6 +104,9 @@ const (
 	// EntitlementCanViewMetrics is the `can_view_metrics` Entitlement. It applies to entity.TypeServer.
 	EntitlementCanViewMetrics Entitlement = "can_view_metrics"
 
+	// EntitlementCanManageWarningSuppressions is the `can_manage_warning_suppressions` Entitlement. It applies to entity.TypeServer.
+	EntitlementCanManageWarningSuppressions Entitlement = "can_manage_warning_suppressions"
+
 	// EntitlementCanViewWarnings is the `can_view_warnings` Entitlement. It applies to entity.TypeServer.
 	EntitlementCanViewWarnings Entitlement = "can_view_warnings"
 
@@ -259,12 +269,18 @@ const (
 	// EntitlementCanUpdateState is the `can_update_state` Entitlement. It applies to entity.TypeInstance.
 	EntitlementCanUpdateState Entitlement = "can_update_state"
 
-	// EntitlementCanConnectSFTP is the `can_connect_sftp` Entitlement. It applies to entity.TypeInstance.
+	// EntitlementCanConnectSFTP is the `can_connect_sftp` Entitlement. It applies to entity.TypeInstance and entity.TypeStorageVolume.
 	EntitlementCanConnectSFTP Entitlement = "can_connect_sftp"
 
+	// EntitlementCanConnectSFTPReadOnly is the `can_connect_sftp_read_only` Entitlement. It applies to entity.TypeStorageVolume.
+	EntitlementCanConnectSFTPReadOnly Entitlement = "can_connect_sftp_read_only"
+
 	// EntitlementCanAccessFiles is the `can_access_files` Entitlement. It applies to entity.TypeInstance.
 	EntitlementCanAccessFiles Entitlement = "can_access_files"
 
+	// EntitlementCanEditFiles is the `can_edit_files` Entitlement. It applies to entity.TypeInstance.
+	EntitlementCanEditFiles Entitlement = "can_edit_files"
+
 	// EntitlementCanAccessConsole is the `can_access_console` Entitlement. It applies to entity.TypeInstance.
 	EntitlementCanAccessConsole Entitlement = "can_access_console"
 
@@ -278,103 +294,15 @@ const (
 	EntitlementCanManageBackups Entitlement = "can_manage_backups"
 )
 
-var allEntitlements = []Entitlement{
-	EntitlementCanView,
-	EntitlementCanEdit,
-	EntitlementCanDelete,
-	EntitlementServerAdmin,
-	EntitlementServerViewer,
-	EntitlementCanViewConfiguration,
-	EntitlementPermissionManager,
-	EntitlementCanViewPermissions,
-	EntitlementCanCreateIdentities,
-	EntitlementCanViewIdentities,
-	EntitlementCanEditIdentities,
-	EntitlementCanDeleteIdentities,
-	EntitlementCanCreateGroups,
-	EntitlementCanViewGroups,
-	EntitlementCanEditGroups,
-	EntitlementCanDeleteGroups,
-	EntitlementStoragePoolManager,
-	EntitlementCanCreateStoragePools,
-	EntitlementCanEditStoragePools,
-	EntitlementCanDeleteStoragePools,
-	EntitlementProjectManager,
-	EntitlementCanCreateProjects,
-	EntitlementCanViewProjects,
-	EntitlementCanEditProjects,
-	EntitlementCanDeleteProjects,
-	EntitlementCanOverrideClusterTargetRestriction,
-	EntitlementCanViewPrivilegedEvents,
-	EntitlementCanViewResources,
-	EntitlementCanViewMetrics,
-	EntitlementCanViewWarnings,
-	EntitlementProjectOperator,
-	EntitlementProjectViewer,
-	EntitlementImageManager,
-	EntitlementCanCreateImages,
-	EntitlementCanViewImages,
-	EntitlementCanEditImages,
-	EntitlementCanDeleteImages,
-	EntitlementImageAliasManager,
-	EntitlementCanCreateImageAliases,
-	EntitlementCanViewImageAliases,
-	EntitlementCanEditImageAliases,
-	EntitlementCanDeleteImageAliases,
-	EntitlementInstanceManager,
-	EntitlementCanCreateInstances,
-	EntitlementCanViewInstances,
-	EntitlementCanEditInstances,
-	EntitlementCanDeleteInstances,
-	EntitlementCanOperateInstances,
-	EntitlementNetworkManager,
-	EntitlementCanCreateNetworks,
-	EntitlementCanViewNetworks,
-	EntitlementCanEditNetworks,
-	EntitlementCanDeleteNetworks,
-	EntitlementNetworkACLManager,
-	EntitlementCanCreateNetworkACLs,
-	EntitlementCanViewNetworkACLs,
-	EntitlementCanEditNetworkACLs,
-	EntitlementCanDeleteNetworkACLs,
-	EntitlementNetworkZoneManager,
-	EntitlementCanCreateNetworkZones,
-	EntitlementCanViewNetworkZones,
-	EntitlementCanEditNetworkZones,
-	EntitlementCanDeleteNetworkZones,
-	EntitlementProfileManager,
-	EntitlementCanCreateProfiles,
-	EntitlementCanViewProfiles,
-	EntitlementCanEditProfiles,
-	EntitlementCanDeleteProfiles,
-	EntitlementStorageVolumeManager,
-	EntitlementCanCreateStorageVolumes,
-	EntitlementCanViewStorageVolumes,
-	EntitlementCanEditStorageVolumes,
-	EntitlementCanDeleteStorageVolumes,
-	EntitlementStorageBucketManager,
-	EntitlementCanCreateStorageBuckets,
-	EntitlementCanViewStorageBuckets,
-	EntitlementCanEditStorageBuckets,
-	EntitlementCanDeleteStorageBuckets,
-	EntitlementCanViewOperations,
-	EntitlementCanViewEvents,
-	EntitlementInstanceUser,
-	EntitlementInstanceOperator,
-	EntitlementCanUpdateState,
-	EntitlementCanConnectSFTP,
-	EntitlementCanAccessFiles,
-	EntitlementCanAccessConsole,
-	EntitlementCanExec,
-	EntitlementCanManageSnapshots,
-	EntitlementCanManageBackups,
-}
-
 // Validate returns an error if the Entitlement is not recognised.
 func Validate(e Entitlement) error {
-	if !shared.ValueInSlice(e, allEntitlements) {
-		return fmt.Errorf("Entitlement %q not defined", e)
+	for _, definitions := range entitlementDefinitions {
+		for _, definition := range definitions {
+			if definition.Entitlement == e {
+				return nil
+			}
+		}
 	}
 
-	return nil
+	return fmt.Errorf("Entitlement %q not defined", e)
 }
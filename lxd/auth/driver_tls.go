@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"sort"
 
 	"github.com/canonical/lxd/lxd/identity"
 	"github.com/canonical/lxd/lxd/request"
@@ -40,7 +41,27 @@ func (t *tls) CheckPermission(ctx context.Context, r *http.Request, entityURL *a
 	}
 
 	authenticationProtocol := details.authenticationProtocol()
-	if authenticationProtocol != api.AuthenticationMethodTLS {
+	if authenticationProtocol == api.AuthenticationMethodOIDC {
+		groupPermissions, isRestricted, err := t.oidcGroupPermissions(details.username())
+		if err != nil {
+			return err
+		}
+
+		if !isRestricted {
+			return nil
+		}
+
+		entityType, _, _, _, err := entity.ParseURL(entityURL.URL)
+		if err != nil {
+			return fmt.Errorf("Failed to parse entity URL: %w", err)
+		}
+
+		if t.groupPermissionsGrant(groupPermissions, entitlement, entityType, entityURL) {
+			return nil
+		}
+
+		return api.StatusErrorf(http.StatusForbidden, "User does not have entitlement %q on %q", entitlement, entityURL.String())
+	} else if authenticationProtocol != api.AuthenticationMethodTLS {
 		t.logger.Warn("Authentication protocol is not compatible with authorization driver", logger.Ctx{"protocol": authenticationProtocol})
 		// Return nil. If the server has been configured with an authentication method but no associated authorization driver,
 		// the default is to give these authenticated users admin privileges.
@@ -78,6 +99,13 @@ func (t *tls) CheckPermission(ctx context.Context, r *http.Request, entityURL *a
 		projectName = pathArgs[0]
 	}
 
+	// Permissions granted to the groups of the identity apply in addition to the restrictions below, as long as they
+	// are within the projects that the identity is confined to.
+	groupPermissions := confinedGroupPermissions(t.identities.GetGroupPermissions(id.Groups), id.Projects)
+	if t.groupPermissionsGrant(groupPermissions, entitlement, entityType, entityURL) {
+		return nil
+	}
+
 	// Check server level object types
 	switch entityType {
 	case entity.TypeServer:
@@ -91,6 +119,9 @@ func (t *tls) CheckPermission(ctx context.Context, r *http.Request, entityURL *a
 			return nil
 		}
 
+		return api.StatusErrorf(http.StatusForbidden, "Certificate is restricted")
+	case entity.TypeAuthGroup:
+		// Groups are not part of a project, so restricted identities cannot be granted access to them.
 		return api.StatusErrorf(http.StatusForbidden, "Certificate is restricted")
 	}
 
@@ -120,7 +151,20 @@ func (t *tls) GetPermissionChecker(ctx context.Context, r *http.Request, entitle
 	}
 
 	authenticationProtocol := details.authenticationProtocol()
-	if authenticationProtocol != api.AuthenticationMethodTLS {
+	if authenticationProtocol == api.AuthenticationMethodOIDC {
+		groupPermissions, isRestricted, err := t.oidcGroupPermissions(details.username())
+		if err != nil {
+			return nil, err
+		}
+
+		if !isRestricted {
+			return allowFunc(true), nil
+		}
+
+		return func(entityURL *api.URL) bool {
+			return t.groupPermissionsGrant(groupPermissions, entitlement, entityType, entityURL)
+		}, nil
+	} else if authenticationProtocol != api.AuthenticationMethodTLS {
 		t.logger.Warn("Authentication protocol is not compatible with authorization driver", logger.Ctx{"protocol": authenticationProtocol})
 		// Allow all. If the server has been configured with an authentication method but no associated authorization driver,
 		// the default is to give these authenticated users admin privileges.
@@ -149,6 +193,13 @@ func (t *tls) GetPermissionChecker(ctx context.Context, r *http.Request, entitle
 		return nil, api.StatusErrorf(http.StatusForbidden, "Certificate is restricted")
 	}
 
+	// Permissions granted to the groups of the identity apply in addition to the restrictions below, as long as they
+	// are within the projects that the identity is confined to.
+	groupPermissions := confinedGroupPermissions(t.identities.GetGroupPermissions(id.Groups), id.Projects)
+	groupPermissionChecker := func(entityURL *api.URL) bool {
+		return t.groupPermissionsGrant(groupPermissions, entitlement, entityType, entityURL)
+	}
+
 	// Check server level object types
 	switch entityType {
 	case entity.TypeServer:
@@ -156,17 +207,21 @@ func (t *tls) GetPermissionChecker(ctx context.Context, r *http.Request, entitle
 			return allowFunc(true), nil
 		}
 
-		return allowFunc(false), nil
+		return groupPermissionChecker, nil
 	case entity.TypeStoragePool, entity.TypeCertificate:
 		if entitlement == EntitlementCanView {
 			return allowFunc(true), nil
 		}
 
-		return allowFunc(false), nil
+		return groupPermissionChecker, nil
+	case entity.TypeAuthGroup:
+		return groupPermissionChecker, nil
 	}
 
-	// Error if user does not have access to the project (unless we're getting projects, where we want to filter the results).
-	if !shared.ValueInSlice(details.projectName, id.Projects) && entityType != entity.TypeProject {
+	// Error if user does not have access to the project (unless we're getting projects, where we want to filter the
+	// results, or the groups of the identity grant the entitlement on some entities of the type).
+	hasProjectAccess := shared.ValueInSlice(details.projectName, id.Projects)
+	if !hasProjectAccess && entityType != entity.TypeProject && !groupPermissionsGrantType(groupPermissions, entitlement, entityType) {
 		return nil, api.StatusErrorf(http.StatusForbidden, "User does not have permissions for project %q", details.projectName)
 	}
 
@@ -193,10 +248,173 @@ func (t *tls) GetPermissionChecker(ctx context.Context, r *http.Request, entitle
 
 		// If an effective project has been set in the request context. We expect all entities to be in that project.
 		if effectiveProject != "" {
-			return project == effectiveProject
+			if (hasProjectAccess || eType == entity.TypeProject) && project == effectiveProject {
+				return true
+			}
+		} else if shared.ValueInSlice(project, id.Projects) {
+			// Otherwise, check if the project is in the list of allowed projects for the entity.
+			return true
 		}
 
-		// Otherwise, check if the project is in the list of allowed projects for the entity.
-		return shared.ValueInSlice(project, id.Projects)
+		return groupPermissionChecker(entityURL)
 	}, nil
 }
+
+// GetPermissions returns the effective permissions of the caller, sorted by entity type, entity URL and entitlement.
+// Callers that aren't restricted are granted the admin entitlement on the server. Restricted TLS identities are granted
+// the union of the permissions of their groups that are within their projects, and OIDC identities that are a member
+// of any group the union of the permissions of their effective groups. If an entity URL is given, only the permissions
+// that apply to that entity are returned.
+func (t *tls) GetPermissions(ctx context.Context, r *http.Request, entityURL *api.URL) ([]api.Permission, error) {
+	adminPermissions := []api.Permission{{
+		EntityType:      string(entity.TypeServer),
+		EntityReference: entity.ServerURL().String(),
+		Entitlement:     string(EntitlementServerAdmin),
+	}}
+
+	details, err := t.requestDetails(r)
+	if err != nil {
+		return nil, api.StatusErrorf(http.StatusForbidden, "Failed to extract request details: %v", err)
+	}
+
+	authenticationProtocol := details.authenticationProtocol()
+	if details.isInternalOrUnix() || details.isPKI || (authenticationProtocol != api.AuthenticationMethodTLS && authenticationProtocol != api.AuthenticationMethodOIDC) {
+		return adminPermissions, nil
+	}
+
+	var groupPermissions map[string][]api.Permission
+	if authenticationProtocol == api.AuthenticationMethodOIDC {
+		var isRestricted bool
+		groupPermissions, isRestricted, err = t.oidcGroupPermissions(details.username())
+		if err != nil {
+			return nil, err
+		}
+
+		if !isRestricted {
+			return adminPermissions, nil
+		}
+	} else {
+		username := details.username()
+		id, err := t.identities.Get(api.AuthenticationMethodTLS, username)
+		if err != nil {
+			return nil, fmt.Errorf("Failed loading certificate for %q: %w", username, err)
+		}
+
+		isRestricted, err := identity.IsRestrictedIdentityType(id.IdentityType)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to check restricted status of identity: %w", err)
+		}
+
+		if !isRestricted {
+			return adminPermissions, nil
+		}
+
+		groupPermissions = confinedGroupPermissions(t.identities.GetGroupPermissions(id.Groups), id.Projects)
+	}
+
+	var entityType entity.Type
+	if entityURL != nil {
+		entityType, _, _, _, err = entity.ParseURL(entityURL.URL)
+		if err != nil {
+			return nil, api.StatusErrorf(http.StatusBadRequest, "Failed to parse entity URL: %v", err)
+		}
+	}
+
+	permissions := []api.Permission{}
+	for _, permissions := range groupPermissions {
+		for _, permission := range permissions {
+			if entityURL != nil && !PermissionGrants(permission, Entitlement(permission.Entitlement), entityType, entityURL) {
+				continue
+			}
+
+			if !shared.ValueInSlice(permission, permissions) {
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+
+	sort.Slice(permissions, func(i, j int) bool {
+		if permissions[i].EntityType != permissions[j].EntityType {
+			return permissions[i].EntityType < permissions[j].EntityType
+		}
+
+		if permissions[i].EntityReference != permissions[j].EntityReference {
+			return permissions[i].EntityReference < permissions[j].EntityReference
+		}
+
+		return permissions[i].Entitlement < permissions[j].Entitlement
+	})
+
+	return permissions, nil
+}
+
+// oidcGroupPermissions returns a map of group name to the permissions of each of the effective groups of the OIDC
+// identity with the given email address. These are the groups that the identity is a direct member of and the groups
+// mapped to the identity provider groups that it last authenticated with. OIDC identities that aren't a member of any
+// group aren't restricted, which is reported by the returned bool.
+func (t *tls) oidcGroupPermissions(username string) (map[string][]api.Permission, bool, error) {
+	id, err := t.identities.Get(api.AuthenticationMethodOIDC, username)
+	if err != nil {
+		return nil, false, fmt.Errorf("Failed loading OIDC identity %q: %w", username, err)
+	}
+
+	effectiveGroups := t.identities.GetEffectiveGroups(api.AuthenticationMethodOIDC, username, id.Groups)
+	if len(effectiveGroups) == 0 {
+		return nil, false, nil
+	}
+
+	groupNames := make([]string, 0, len(effectiveGroups))
+	for _, effectiveGroup := range effectiveGroups {
+		groupNames = append(groupNames, effectiveGroup.Group)
+	}
+
+	return t.identities.GetGroupPermissions(groupNames), true, nil
+}
+
+// groupPermissionsGrant returns whether any of the given group permissions grant the Entitlement on the entity with
+// the given URL, directly or through a broader entitlement (see PermissionGrantsImplied). Each group that grants the
+// Entitlement is marked as used in the identity cache.
+func (t *tls) groupPermissionsGrant(groupPermissions map[string][]api.Permission, entitlement Entitlement, entityType entity.Type, entityURL *api.URL) bool {
+	granted := false
+	for groupName, permissions := range groupPermissions {
+		for _, permission := range permissions {
+			if PermissionGrantsImplied(permission, entitlement, entityType, entityURL) {
+				t.identities.MarkGroupUsed(groupName)
+				granted = true
+				break
+			}
+		}
+	}
+
+	return granted
+}
+
+// confinedGroupPermissions returns the given group permissions without the permissions that are outside of the given
+// projects. Restricted identities are confined to their projects, so the permissions granted to them by their groups
+// outside of their projects are ignored.
+func confinedGroupPermissions(groupPermissions map[string][]api.Permission, projects []string) map[string][]api.Permission {
+	confined := make(map[string][]api.Permission, len(groupPermissions))
+	for groupName, permissions := range groupPermissions {
+		for _, permission := range permissions {
+			if PermissionInProjects(permission, projects) {
+				confined[groupName] = append(confined[groupName], permission)
+			}
+		}
+	}
+
+	return confined
+}
+
+// groupPermissionsGrantType returns whether any of the given group permissions grant the Entitlement on entities of
+// the given entity.Type.
+func groupPermissionsGrantType(groupPermissions map[string][]api.Permission, entitlement Entitlement, entityType entity.Type) bool {
+	for _, permissions := range groupPermissions {
+		for _, permission := range permissions {
+			if Entitlement(permission.Entitlement) == entitlement && entity.Type(permission.EntityType) == entityType {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/lxd/lxd/identity"
+	"github.com/canonical/lxd/lxd/request"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
+	"github.com/canonical/lxd/shared/logger"
+)
+
+// newTestTLSAuthorizer returns a TLS authorizer whose identity cache contains an unrestricted and a restricted client
+// certificate. The restricted certificate has access to the default project and is a member of group "g1". The cache
+// also contains an OIDC identity that isn't a member of any group, one that is a direct member of group "g2", and one
+// that is a member of group "g2" through the identity provider group "idp".
+func newTestTLSAuthorizer(t *testing.T, groupPermissions map[string][]api.Permission) Authorizer {
+	// Make sure that the server is not in PKI mode.
+	t.Setenv("LXD_DIR", t.TempDir())
+
+	identityCache := &identity.Cache{}
+	err := identityCache.ReplaceAll([]identity.CacheEntry{
+		{
+			Identifier:           "unrestricted",
+			AuthenticationMethod: api.AuthenticationMethodTLS,
+			IdentityType:         api.IdentityTypeCertificateClientUnrestricted,
+			Certificate:          &x509.Certificate{},
+		},
+		{
+			Identifier:           "restricted",
+			AuthenticationMethod: api.AuthenticationMethodTLS,
+			IdentityType:         api.IdentityTypeCertificateClientRestricted,
+			Projects:             []string{"default"},
+			Groups:               []string{"g1"},
+			Certificate:          &x509.Certificate{},
+		},
+		{
+			Identifier:           "ungrouped@example.com",
+			AuthenticationMethod: api.AuthenticationMethodOIDC,
+			IdentityType:         api.IdentityTypeOIDCClient,
+		},
+		{
+			Identifier:           "direct@example.com",
+			AuthenticationMethod: api.AuthenticationMethodOIDC,
+			IdentityType:         api.IdentityTypeOIDCClient,
+			Groups:               []string{"g2"},
+		},
+		{
+			Identifier:             "idp@example.com",
+			AuthenticationMethod:   api.AuthenticationMethodOIDC,
+			IdentityType:           api.IdentityTypeOIDCClient,
+			IdentityProviderGroups: []string{"idp"},
+		},
+	}, map[string][]string{"idp": {"g2"}}, groupPermissions)
+	require.NoError(t, err)
+
+	authorizer, err := LoadAuthorizer(context.Background(), DriverTLS, logger.Log, identityCache)
+	require.NoError(t, err)
+
+	return authorizer
+}
+
+// newTestTLSRequest returns a request to the given URL made by the client certificate with the given fingerprint.
+func newTestTLSRequest(fingerprint string, u *api.URL) *http.Request {
+	r := &http.Request{URL: &u.URL}
+	r = r.WithContext(context.WithValue(r.Context(), request.CtxProtocol, api.AuthenticationMethodTLS))
+	return r.WithContext(context.WithValue(r.Context(), request.CtxUsername, fingerprint))
+}
+
+// newTestOIDCRequest returns a request to the given URL made by the OIDC identity with the given email address.
+func newTestOIDCRequest(email string, u *api.URL) *http.Request {
+	r := &http.Request{URL: &u.URL}
+	r = r.WithContext(context.WithValue(r.Context(), request.CtxProtocol, api.AuthenticationMethodOIDC))
+	return r.WithContext(context.WithValue(r.Context(), request.CtxUsername, email))
+}
+
+func TestTLSCheckPermission(t *testing.T) {
+	groupPermissions := map[string][]api.Permission{
+		"g1": {
+			{EntityType: string(entity.TypeStoragePool), EntityReference: entity.StoragePoolURL("pool1").String(), Entitlement: string(EntitlementCanEdit)},
+			{EntityType: string(entity.TypeInstance), EntityReference: entity.InstanceURL("p1", "c1").String(), Entitlement: string(EntitlementCanView)},
+			{EntityType: string(entity.TypeStorageVolume), EntityReference: entity.StoragePoolURL("pool1").String(), Entitlement: string(EntitlementCanView)},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		fingerprint string
+		entityURL   *api.URL
+		entitlement Entitlement
+		allowed     bool
+	}{
+		{"Unrestricted", "unrestricted", entity.StoragePoolURL("pool2"), EntitlementCanEdit, true},
+		{"Restricted in own project", "restricted", entity.InstanceURL("default", "c1"), EntitlementCanEdit, true},
+		{"Restricted in other project", "restricted", entity.InstanceURL("p1", "c3"), EntitlementCanView, false},
+		{"Restricted server view", "restricted", entity.ServerURL(), EntitlementCanView, true},
+		{"Restricted server edit", "restricted", entity.ServerURL(), EntitlementCanEdit, false},
+		{"Group permissions on pools are ignored", "restricted", entity.StoragePoolURL("pool1"), EntitlementCanEdit, false},
+		{"Group permissions in other projects are ignored", "restricted", entity.InstanceURL("p1", "c1"), EntitlementCanView, false},
+		{"Group subtree permissions on pools are ignored", "restricted", entity.StorageVolumeURL("p1", "", "pool1", "custom", "vol1"), EntitlementCanView, false},
+	}
+
+	authorizer := newTestTLSAuthorizer(t, groupPermissions)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := authorizer.CheckPermission(context.Background(), newTestTLSRequest(test.fingerprint, test.entityURL), test.entityURL, test.entitlement)
+			if test.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, api.StatusErrorCheck(err, http.StatusForbidden), "Expected forbidden error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestTLSGetPermissionChecker(t *testing.T) {
+	groupPermissions := map[string][]api.Permission{
+		"g1": {
+			{EntityType: string(entity.TypeInstance), EntityReference: entity.InstanceURL("p1", "c1").String(), Entitlement: string(EntitlementCanView)},
+		},
+	}
+
+	authorizer := newTestTLSAuthorizer(t, groupPermissions)
+
+	// Group permissions outside of the projects of the identity are ignored.
+	r := newTestTLSRequest("restricted", api.NewURL().Path("1.0", "instances").Project("p1"))
+	_, err := authorizer.GetPermissionChecker(context.Background(), r, EntitlementCanView, entity.TypeInstance)
+	assert.True(t, api.StatusErrorCheck(err, http.StatusForbidden), "Expected forbidden error, got: %v", err)
+
+	r = newTestTLSRequest("restricted", api.NewURL().Path("1.0", "instances"))
+	checker, err := authorizer.GetPermissionChecker(context.Background(), r, EntitlementCanView, entity.TypeInstance)
+	require.NoError(t, err)
+	assert.True(t, checker(entity.InstanceURL("default", "c2")))
+	assert.False(t, checker(entity.InstanceURL("p1", "c1")))
+}
+
+func TestOIDCCheckPermission(t *testing.T) {
+	groupPermissions := map[string][]api.Permission{
+		"g2": {
+			{EntityType: string(entity.TypeStorageVolume), EntityReference: entity.StoragePoolURL("pool1").String(), Entitlement: string(EntitlementCanView)},
+			{EntityType: string(entity.TypeProject), EntityReference: entity.ProjectURL("p1").String(), Entitlement: string(EntitlementCanEditInstances)},
+			{EntityType: string(entity.TypeInstance), EntityReference: entity.InstanceURL("p2", "c1").String(), Entitlement: string(EntitlementCanEditFiles)},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		email       string
+		entityURL   *api.URL
+		entitlement Entitlement
+		allowed     bool
+	}{
+		{"Not a member of any group", "ungrouped@example.com", entity.ServerURL(), EntitlementCanEdit, true},
+		{"Subtree permission", "direct@example.com", entity.StorageVolumeURL("p3", "", "pool1", "custom", "vol1"), EntitlementCanView, true},
+		{"Subtree permission on other pool", "direct@example.com", entity.StorageVolumeURL("p3", "", "pool2", "custom", "vol1"), EntitlementCanView, false},
+		{"Project entitlement", "direct@example.com", entity.InstanceURL("p1", "c2"), EntitlementCanEdit, true},
+		{"Project entitlement implies view", "direct@example.com", entity.InstanceURL("p1", "c2"), EntitlementCanView, true},
+		{"Project entitlement in other project", "direct@example.com", entity.InstanceURL("p2", "c2"), EntitlementCanEdit, false},
+		{"Implied entitlement", "direct@example.com", entity.InstanceURL("p2", "c1"), EntitlementCanAccessFiles, true},
+		{"Not implied entitlement", "direct@example.com", entity.InstanceURL("p2", "c1"), EntitlementCanExec, false},
+		{"Server", "direct@example.com", entity.ServerURL(), EntitlementCanEdit, false},
+		{"Identity provider group", "idp@example.com", entity.InstanceURL("p1", "c2"), EntitlementCanEdit, true},
+		{"Identity provider group in other project", "idp@example.com", entity.InstanceURL("p2", "c2"), EntitlementCanEdit, false},
+	}
+
+	authorizer := newTestTLSAuthorizer(t, groupPermissions)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := authorizer.CheckPermission(context.Background(), newTestOIDCRequest(test.email, test.entityURL), test.entityURL, test.entitlement)
+			if test.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, api.StatusErrorCheck(err, http.StatusForbidden), "Expected forbidden error, got: %v", err)
+			}
+		})
+	}
+
+	// The server admin entitlement grants everything.
+	authorizer = newTestTLSAuthorizer(t, map[string][]api.Permission{
+		"g2": {{EntityType: string(entity.TypeServer), EntityReference: entity.ServerURL().String(), Entitlement: string(EntitlementServerAdmin)}},
+	})
+
+	err := authorizer.CheckPermission(context.Background(), newTestOIDCRequest("direct@example.com", entity.ProjectURL("p1")), entity.ProjectURL("p1"), EntitlementCanDelete)
+	assert.NoError(t, err)
+}
+
+func TestOIDCGetPermissionChecker(t *testing.T) {
+	groupPermissions := map[string][]api.Permission{
+		"g2": {
+			{EntityType: string(entity.TypeStorageVolume), EntityReference: entity.StoragePoolURL("pool1").String(), Entitlement: string(EntitlementCanView)},
+		},
+	}
+
+	authorizer := newTestTLSAuthorizer(t, groupPermissions)
+
+	r := newTestOIDCRequest("direct@example.com", api.NewURL().Path("1.0", "storage-pools", "pool1", "volumes"))
+	checker, err := authorizer.GetPermissionChecker(context.Background(), r, EntitlementCanView, entity.TypeStorageVolume)
+	require.NoError(t, err)
+	assert.True(t, checker(entity.StorageVolumeURL("default", "", "pool1", "custom", "vol1")))
+	assert.False(t, checker(entity.StorageVolumeURL("default", "", "pool2", "custom", "vol1")))
+
+	r = newTestOIDCRequest("ungrouped@example.com", api.NewURL().Path("1.0", "storage-pools", "pool1", "volumes"))
+	checker, err = authorizer.GetPermissionChecker(context.Background(), r, EntitlementCanView, entity.TypeStorageVolume)
+	require.NoError(t, err)
+	assert.True(t, checker(entity.StorageVolumeURL("default", "", "pool2", "custom", "vol1")))
+}
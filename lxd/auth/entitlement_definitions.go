@@ -0,0 +1,529 @@
+package auth
+
+import (
+	"fmt"
+
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/entity"
+)
+
+// EntitlementCategory groups entitlements by the kind of access that they grant.
+type EntitlementCategory string
+
+const (
+	// EntitlementCategoryView is the category of entitlements that grant read-only access.
+	EntitlementCategoryView EntitlementCategory = "view"
+
+	// EntitlementCategoryEdit is the category of entitlements that grant permission to create, modify, delete, or
+	// interact with entities.
+	EntitlementCategoryEdit EntitlementCategory = "edit"
+
+	// EntitlementCategoryAdmin is the category of entitlements that grant full control over an entity and everything
+	// within it.
+	EntitlementCategoryAdmin EntitlementCategory = "admin"
+
+	// EntitlementCategoryDelegate is the category of entitlements that grant permission to manage the access of other
+	// identities.
+	EntitlementCategoryDelegate EntitlementCategory = "delegate"
+)
+
+// EntitlementDefinition describes an Entitlement that can be granted on entities of an entity type.
+type EntitlementDefinition struct {
+	// Entitlement is the name of the entitlement.
+	Entitlement Entitlement
+
+	// Description is a human readable description of what the entitlement grants.
+	Description string
+
+	// Category is the kind of access the entitlement grants.
+	Category EntitlementCategory
+
+	// ImpliedBy lists the broader entitlements on the same entity that include this entitlement.
+	ImpliedBy []Entitlement
+}
+
+// entityTypesWithoutEntitlements lists the entity types that entitlements cannot be granted on. Access to these
+// entities is derived from entitlements on other entities.
+var entityTypesWithoutEntitlements = []entity.Type{
+	entity.TypeContainer,
+	entity.TypeCertificate,
+	entity.TypeInstanceBackup,
+	entity.TypeInstanceSnapshot,
+	entity.TypeNode,
+	entity.TypeOperation,
+	entity.TypeStorageVolumeBackup,
+	entity.TypeStorageVolumeSnapshot,
+	entity.TypeWarning,
+	entity.TypeClusterGroup,
+}
+
+// commonEntitlementDefinitions returns the definitions of EntitlementCanView, EntitlementCanEdit, and
+// EntitlementCanDelete for an entity described by the given noun.
+func commonEntitlementDefinitions(noun string) []EntitlementDefinition {
+	return []EntitlementDefinition{
+		{
+			Entitlement: EntitlementCanView,
+			Description: "Grants permission to view the " + noun + ".",
+			Category:    EntitlementCategoryView,
+			ImpliedBy:   []Entitlement{EntitlementCanEdit},
+		},
+		{
+			Entitlement: EntitlementCanEdit,
+			Description: "Grants permission to edit the " + noun + ".",
+			Category:    EntitlementCategoryEdit,
+		},
+		{
+			Entitlement: EntitlementCanDelete,
+			Description: "Grants permission to delete the " + noun + ".",
+			Category:    EntitlementCategoryEdit,
+		},
+	}
+}
+
+// projectResourceEntitlementDefinitions returns the definitions of the entitlements that grant access to a kind of
+// resource within a project. The given nouns are the singular and plural of the resource.
+func projectResourceEntitlementDefinitions(noun string, nouns string, manager Entitlement, create Entitlement, view Entitlement, edit Entitlement, del Entitlement) []EntitlementDefinition {
+	return []EntitlementDefinition{
+		{
+			Entitlement: manager,
+			Description: "Grants permission to create, view, edit, and delete all " + nouns + " within the project.",
+			Category:    EntitlementCategoryAdmin,
+			ImpliedBy:   []Entitlement{EntitlementProjectOperator},
+		},
+		{
+			Entitlement: create,
+			Description: "Grants permission to create " + nouns + " within the project.",
+			Category:    EntitlementCategoryEdit,
+			ImpliedBy:   []Entitlement{EntitlementProjectOperator, manager},
+		},
+		{
+			Entitlement: view,
+			Description: "Grants permission to view all " + nouns + " within the project.",
+			Category:    EntitlementCategoryView,
+			ImpliedBy:   []Entitlement{EntitlementProjectOperator, EntitlementProjectViewer, manager},
+		},
+		{
+			Entitlement: edit,
+			Description: "Grants permission to edit any " + noun + " within the project.",
+			Category:    EntitlementCategoryEdit,
+			ImpliedBy:   []Entitlement{EntitlementProjectOperator, manager},
+		},
+		{
+			Entitlement: del,
+			Description: "Grants permission to delete any " + noun + " within the project.",
+			Category:    EntitlementCategoryEdit,
+			ImpliedBy:   []Entitlement{EntitlementProjectOperator, manager},
+		},
+	}
+}
+
+// entitlementDefinitions is a map of entity type to the definitions of the entitlements that can be granted on
+// entities of that type, in the order they are listed by the API. It is the single source of truth for which
+// entitlements exist and where they apply.
+var entitlementDefinitions = func() map[entity.Type][]EntitlementDefinition {
+	definitions := map[entity.Type][]EntitlementDefinition{
+		entity.TypeImage:                 commonEntitlementDefinitions("image"),
+		entity.TypeProfile:               commonEntitlementDefinitions("profile"),
+		entity.TypeNetwork:               commonEntitlementDefinitions("network"),
+		entity.TypeNetworkACL:            commonEntitlementDefinitions("network ACL"),
+		entity.TypeStoragePool:           commonEntitlementDefinitions("storage pool"),
+		entity.TypeStorageBucket:         commonEntitlementDefinitions("storage bucket"),
+		entity.TypeImageAlias:            commonEntitlementDefinitions("image alias"),
+		entity.TypeNetworkZone:           commonEntitlementDefinitions("network zone"),
+		entity.TypeAuthGroup:             commonEntitlementDefinitions("group"),
+		entity.TypeIdentityProviderGroup: commonEntitlementDefinitions("identity provider group"),
+	}
+
+	definitions[entity.TypeIdentity] = append(commonEntitlementDefinitions("identity"),
+		EntitlementDefinition{
+			Entitlement: EntitlementCanDisable,
+			Description: "Grants permission to disable and enable the identity.",
+			Category:    EntitlementCategoryDelegate,
+		},
+	)
+
+	definitions[entity.TypeStorageVolume] = append(commonEntitlementDefinitions("storage volume"),
+		EntitlementDefinition{
+			Entitlement: EntitlementCanManageBackups,
+			Description: "Grants permission to create, view, and delete backups of the storage volume.",
+			Category:    EntitlementCategoryEdit,
+		},
+		EntitlementDefinition{
+			Entitlement: EntitlementCanManageSnapshots,
+			Description: "Grants permission to create, view, edit, restore, and delete snapshots of the storage volume.",
+			Category:    EntitlementCategoryEdit,
+		},
+		EntitlementDefinition{
+			Entitlement: EntitlementCanConnectSFTP,
+			Description: "Grants permission to read and write the files of the storage volume over SFTP.",
+			Category:    EntitlementCategoryEdit,
+		},
+		EntitlementDefinition{
+			Entitlement: EntitlementCanConnectSFTPReadOnly,
+			Description: "Grants permission to read the files of the storage volume over SFTP.",
+			Category:    EntitlementCategoryView,
+			ImpliedBy:   []Entitlement{EntitlementCanConnectSFTP},
+		},
+	)
+
+	instanceInteraction := []Entitlement{EntitlementInstanceUser, EntitlementInstanceOperator}
+	definitions[entity.TypeInstance] = []EntitlementDefinition{
+		{
+			Entitlement: EntitlementCanView,
+			Description: "Grants permission to view the instance and its state.",
+			Category:    EntitlementCategoryView,
+			ImpliedBy:   []Entitlement{EntitlementCanEdit, EntitlementInstanceUser, EntitlementInstanceOperator},
+		},
+		{
+			Entitlement: EntitlementCanEdit,
+			Description: "Grants permission to edit the configuration of the instance.",
+			Category:    EntitlementCategoryEdit,
+		},
+		{
+			Entitlement: EntitlementCanDelete,
+			Description: "Grants permission to delete the instance.",
+			Category:    EntitlementCategoryEdit,
+		},
+		{
+			Entitlement: EntitlementInstanceUser,
+			Description: "Grants permission to view the instance, access its files, and start a terminal session or console.",
+			Category:    EntitlementCategoryEdit,
+		},
+		{
+			Entitlement: EntitlementInstanceOperator,
+			Description: "Grants permission to view the instance, change its state, access its files, start a terminal session or console, and manage its snapshots and backups.",
+			Category:    EntitlementCategoryEdit,
+		},
+		{
+			Entitlement: EntitlementCanUpdateState,
+			Description: "Grants permission to start, stop, freeze, and restart the instance.",
+			Category:    EntitlementCategoryEdit,
+			ImpliedBy:   []Entitlement{EntitlementInstanceOperator},
+		},
+		{
+			Entitlement: EntitlementCanConnectSFTP,
+			Description: "Grants permission to access the files of the instance over SFTP.",
+			Category:    EntitlementCategoryEdit,
+			ImpliedBy:   instanceInteraction,
+		},
+		{
+			Entitlement: EntitlementCanAccessFiles,
+			Description: "Grants permission to read the files of the instance.",
+			Category:    EntitlementCategoryView,
+			ImpliedBy:   []Entitlement{EntitlementCanEditFiles, EntitlementInstanceUser, EntitlementInstanceOperator},
+		},
+		{
+			Entitlement: EntitlementCanEditFiles,
+			Description: "Grants permission to read and write the files of the instance.",
+			Category:    EntitlementCategoryEdit,
+			ImpliedBy:   instanceInteraction,
+		},
+		{
+			Entitlement: EntitlementCanAccessConsole,
+			Description: "Grants permission to access the console of the instance.",
+			Category:    EntitlementCategoryEdit,
+			ImpliedBy:   instanceInteraction,
+		},
+		{
+			Entitlement: EntitlementCanExec,
+			Description: "Grants permission to execute commands in the instance.",
+			Category:    EntitlementCategoryEdit,
+			ImpliedBy:   instanceInteraction,
+		},
+		{
+			Entitlement: EntitlementCanManageBackups,
+			Description: "Grants permission to create, view, and delete backups of the instance.",
+			Category:    EntitlementCategoryEdit,
+			ImpliedBy:   []Entitlement{EntitlementInstanceOperator},
+		},
+		{
+			Entitlement: EntitlementCanManageSnapshots,
+			Description: "Grants permission to create, view, edit, restore, and delete snapshots of the instance.",
+			Category:    EntitlementCategoryEdit,
+			ImpliedBy:   []Entitlement{EntitlementInstanceOperator},
+		},
+	}
+
+	project := []EntitlementDefinition{
+		{
+			Entitlement: EntitlementCanView,
+			Description: "Grants permission to view the project.",
+			Category:    EntitlementCategoryView,
+			ImpliedBy:   []Entitlement{EntitlementCanEdit, EntitlementProjectOperator, EntitlementProjectViewer},
+		},
+		{
+			Entitlement: EntitlementCanEdit,
+			Description: "Grants permission to edit the configuration of the project.",
+			Category:    EntitlementCategoryEdit,
+		},
+		{
+			Entitlement: EntitlementCanDelete,
+			Description: "Grants permission to delete the project.",
+			Category:    EntitlementCategoryEdit,
+		},
+		{
+			Entitlement: EntitlementProjectOperator,
+			Description: "Grants permission to create, view, edit, and delete all resources within the project, but not to edit or delete the project itself.",
+			Category:    EntitlementCategoryAdmin,
+		},
+		{
+			Entitlement: EntitlementProjectViewer,
+			Description: "Grants permission to view all resources within the project.",
+			Category:    EntitlementCategoryView,
+		},
+	}
+
+	project = append(project, projectResourceEntitlementDefinitions("image", "images", EntitlementImageManager, EntitlementCanCreateImages, EntitlementCanViewImages, EntitlementCanEditImages, EntitlementCanDeleteImages)...)
+	project = append(project, projectResourceEntitlementDefinitions("image alias", "image aliases", EntitlementImageAliasManager, EntitlementCanCreateImageAliases, EntitlementCanViewImageAliases, EntitlementCanEditImageAliases, EntitlementCanDeleteImageAliases)...)
+	project = append(project, projectResourceEntitlementDefinitions("instance", "instances", EntitlementInstanceManager, EntitlementCanCreateInstances, EntitlementCanViewInstances, EntitlementCanEditInstances, EntitlementCanDeleteInstances)...)
+	project = append(project, EntitlementDefinition{
+		Entitlement: EntitlementCanOperateInstances,
+		Description: "Grants permission to view any instance within the project, change its state, access its files, start a terminal session or console, and manage its snapshots and backups.",
+		Category:    EntitlementCategoryEdit,
+		ImpliedBy:   []Entitlement{EntitlementProjectOperator, EntitlementInstanceManager},
+	})
+
+	project = append(project, projectResourceEntitlementDefinitions("network", "networks", EntitlementNetworkManager, EntitlementCanCreateNetworks, EntitlementCanViewNetworks, EntitlementCanEditNetworks, EntitlementCanDeleteNetworks)...)
+	project = append(project, projectResourceEntitlementDefinitions("network ACL", "network ACLs", EntitlementNetworkACLManager, EntitlementCanCreateNetworkACLs, EntitlementCanViewNetworkACLs, EntitlementCanEditNetworkACLs, EntitlementCanDeleteNetworkACLs)...)
+	project = append(project, projectResourceEntitlementDefinitions("network zone", "network zones", EntitlementNetworkZoneManager, EntitlementCanCreateNetworkZones, EntitlementCanViewNetworkZones, EntitlementCanEditNetworkZones, EntitlementCanDeleteNetworkZones)...)
+	project = append(project, projectResourceEntitlementDefinitions("profile", "profiles", EntitlementProfileManager, EntitlementCanCreateProfiles, EntitlementCanViewProfiles, EntitlementCanEditProfiles, EntitlementCanDeleteProfiles)...)
+	project = append(project, projectResourceEntitlementDefinitions("storage volume", "storage volumes", EntitlementStorageVolumeManager, EntitlementCanCreateStorageVolumes, EntitlementCanViewStorageVolumes, EntitlementCanEditStorageVolumes, EntitlementCanDeleteStorageVolumes)...)
+	project = append(project, projectResourceEntitlementDefinitions("storage bucket", "storage buckets", EntitlementStorageBucketManager, EntitlementCanCreateStorageBuckets, EntitlementCanViewStorageBuckets, EntitlementCanEditStorageBuckets, EntitlementCanDeleteStorageBuckets)...)
+	project = append(project,
+		EntitlementDefinition{
+			Entitlement: EntitlementCanViewOperations,
+			Description: "Grants permission to view all operations relating to the project.",
+			Category:    EntitlementCategoryView,
+			ImpliedBy:   []Entitlement{EntitlementProjectOperator, EntitlementProjectViewer},
+		},
+		EntitlementDefinition{
+			Entitlement: EntitlementCanViewEvents,
+			Description: "Grants permission to view events relating to the project.",
+			Category:    EntitlementCategoryView,
+			ImpliedBy:   []Entitlement{EntitlementProjectOperator, EntitlementProjectViewer},
+		},
+	)
+
+	definitions[entity.TypeProject] = project
+
+	admin := []Entitlement{EntitlementServerAdmin}
+	adminOrViewer := []Entitlement{EntitlementServerAdmin, EntitlementServerViewer}
+	permissionManager := []Entitlement{EntitlementServerAdmin, EntitlementPermissionManager}
+	storagePoolManager := []Entitlement{EntitlementServerAdmin, EntitlementStoragePoolManager}
+	projectManager := []Entitlement{EntitlementServerAdmin, EntitlementProjectManager}
+	definitions[entity.TypeServer] = []EntitlementDefinition{
+		{
+			Entitlement: EntitlementCanView,
+			Description: "Grants permission to view the server.",
+			Category:    EntitlementCategoryView,
+			ImpliedBy:   []Entitlement{EntitlementServerAdmin, EntitlementServerViewer, EntitlementCanEdit},
+		},
+		{
+			Entitlement: EntitlementCanEdit,
+			Description: "Grants permission to edit the server configuration and cluster members.",
+			Category:    EntitlementCategoryEdit,
+			ImpliedBy:   admin,
+		},
+		{
+			Entitlement: EntitlementServerAdmin,
+			Description: "Grants full access to LXD, including every project and every resource within it.",
+			Category:    EntitlementCategoryAdmin,
+		},
+		{
+			Entitlement: EntitlementServerViewer,
+			Description: "Grants permission to view the server, its configuration, resources, metrics, warnings, and privileged events.",
+			Category:    EntitlementCategoryView,
+			ImpliedBy:   admin,
+		},
+		{
+			Entitlement: EntitlementCanViewConfiguration,
+			Description: "Grants permission to view the server configuration.",
+			Category:    EntitlementCategoryView,
+			ImpliedBy:   []Entitlement{EntitlementServerAdmin, EntitlementServerViewer, EntitlementCanEdit},
+		},
+		{
+			Entitlement: EntitlementPermissionManager,
+			Description: "Grants permission to view permissions, and to create, view, edit, and delete identities and groups.",
+			Category:    EntitlementCategoryDelegate,
+			ImpliedBy:   admin,
+		},
+		{
+			Entitlement: EntitlementCanViewPermissions,
+			Description: "Grants permission to view the permissions that can be granted.",
+			Category:    EntitlementCategoryView,
+			ImpliedBy:   permissionManager,
+		},
+		{
+			Entitlement: EntitlementCanCreateIdentities,
+			Description: "Grants permission to create identities.",
+			Category:    EntitlementCategoryDelegate,
+			ImpliedBy:   permissionManager,
+		},
+		{
+			Entitlement: EntitlementCanViewIdentities,
+			Description: "Grants permission to view all identities.",
+			Category:    EntitlementCategoryView,
+			ImpliedBy:   permissionManager,
+		},
+		{
+			Entitlement: EntitlementCanEditIdentities,
+			Description: "Grants permission to edit any identity, including the groups it is a member of.",
+			Category:    EntitlementCategoryDelegate,
+			ImpliedBy:   permissionManager,
+		},
+		{
+			Entitlement: EntitlementCanDeleteIdentities,
+			Description: "Grants permission to delete any identity.",
+			Category:    EntitlementCategoryDelegate,
+			ImpliedBy:   permissionManager,
+		},
+		{
+			Entitlement: EntitlementCanCreateGroups,
+			Description: "Grants permission to create groups.",
+			Category:    EntitlementCategoryDelegate,
+			ImpliedBy:   permissionManager,
+		},
+		{
+			Entitlement: EntitlementCanViewGroups,
+			Description: "Grants permission to view all groups.",
+			Category:    EntitlementCategoryView,
+			ImpliedBy:   permissionManager,
+		},
+		{
+			Entitlement: EntitlementCanEditGroups,
+			Description: "Grants permission to edit any group, including the permissions it grants.",
+			Category:    EntitlementCategoryDelegate,
+			ImpliedBy:   permissionManager,
+		},
+		{
+			Entitlement: EntitlementCanDeleteGroups,
+			Description: "Grants permission to delete any group.",
+			Category:    EntitlementCategoryDelegate,
+			ImpliedBy:   permissionManager,
+		},
+		{
+			Entitlement: EntitlementCanViewAuthAudit,
+			Description: "Grants permission to view and export the audit trail of changes to groups, identities, identity provider groups, and roles.",
+			Category:    EntitlementCategoryView,
+			ImpliedBy:   permissionManager,
+		},
+		{
+			Entitlement: EntitlementStoragePoolManager,
+			Description: "Grants permission to create, edit, and delete all storage pools.",
+			Category:    EntitlementCategoryAdmin,
+			ImpliedBy:   admin,
+		},
+		{
+			Entitlement: EntitlementCanCreateStoragePools,
+			Description: "Grants permission to create storage pools.",
+			Category:    EntitlementCategoryEdit,
+			ImpliedBy:   storagePoolManager,
+		},
+		{
+			Entitlement: EntitlementCanEditStoragePools,
+			Description: "Grants permission to edit any storage pool.",
+			Category:    EntitlementCategoryEdit,
+			ImpliedBy:   storagePoolManager,
+		},
+		{
+			Entitlement: EntitlementCanDeleteStoragePools,
+			Description: "Grants permission to delete any storage pool.",
+			Category:    EntitlementCategoryEdit,
+			ImpliedBy:   storagePoolManager,
+		},
+		{
+			Entitlement: EntitlementProjectManager,
+			Description: "Grants permission to create, view, edit, and delete all projects.",
+			Category:    EntitlementCategoryAdmin,
+			ImpliedBy:   admin,
+		},
+		{
+			Entitlement: EntitlementCanCreateProjects,
+			Description: "Grants permission to create projects.",
+			Category:    EntitlementCategoryEdit,
+			ImpliedBy:   projectManager,
+		},
+		{
+			Entitlement: EntitlementCanViewProjects,
+			Description: "Grants permission to view all projects.",
+			Category:    EntitlementCategoryView,
+			ImpliedBy:   projectManager,
+		},
+		{
+			Entitlement: EntitlementCanEditProjects,
+			Description: "Grants permission to edit any project.",
+			Category:    EntitlementCategoryEdit,
+			ImpliedBy:   projectManager,
+		},
+		{
+			Entitlement: EntitlementCanDeleteProjects,
+			Description: "Grants permission to delete any project.",
+			Category:    EntitlementCategoryEdit,
+			ImpliedBy:   projectManager,
+		},
+		{
+			Entitlement: EntitlementCanOverrideClusterTargetRestriction,
+			Description: "Grants permission to target a specific cluster member when the project restricts it.",
+			Category:    EntitlementCategoryAdmin,
+			ImpliedBy:   admin,
+		},
+		{
+			Entitlement: EntitlementCanOverrideImmutable,
+			Description: "Grants permission to change the immutable configuration keys of instances.",
+			Category:    EntitlementCategoryAdmin,
+			ImpliedBy:   admin,
+		},
+		{
+			Entitlement: EntitlementCanViewPrivilegedEvents,
+			Description: "Grants permission to view privileged events, such as logging events.",
+			Category:    EntitlementCategoryView,
+			ImpliedBy:   adminOrViewer,
+		},
+		{
+			Entitlement: EntitlementCanViewResources,
+			Description: "Grants permission to view the hardware resources of the server.",
+			Category:    EntitlementCategoryView,
+			ImpliedBy:   adminOrViewer,
+		},
+		{
+			Entitlement: EntitlementCanViewMetrics,
+			Description: "Grants permission to view the metrics of the server and of all projects.",
+			Category:    EntitlementCategoryView,
+			ImpliedBy:   adminOrViewer,
+		},
+		{
+			Entitlement: EntitlementCanManageWarningSuppressions,
+			Description: "Grants permission to create, view, and delete warning suppressions.",
+			Category:    EntitlementCategoryAdmin,
+			ImpliedBy:   admin,
+		},
+		{
+			Entitlement: EntitlementCanViewWarnings,
+			Description: "Grants permission to view all warnings.",
+			Category:    EntitlementCategoryView,
+			ImpliedBy:   adminOrViewer,
+		},
+	}
+
+	return definitions
+}()
+
+// EntitlementDefinitionsByEntityType returns the definitions of the entitlements that can be granted on entities of
+// the given entity.Type.
+func EntitlementDefinitionsByEntityType(entityType entity.Type) ([]EntitlementDefinition, error) {
+	err := entityType.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("Entity type %q is not valid: %w", entityType, err)
+	}
+
+	if shared.ValueInSlice(entityType, entityTypesWithoutEntitlements) {
+		return []EntitlementDefinition{}, nil
+	}
+
+	definitions, ok := entitlementDefinitions[entityType]
+	if !ok {
+		return nil, fmt.Errorf("Missing entitlements definition for entity type %q", entityType)
+	}
+
+	return append([]EntitlementDefinition{}, definitions...), nil
+}
@@ -0,0 +1,338 @@
+package auth
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
+)
+
+// openFGAMemberRelation is the relation of the members of groups and identity provider groups in the OpenFGA model.
+const openFGAMemberRelation = "member"
+
+// regoPolicy is the Rego module of the Open Policy Agent export. It mirrors how the permissions of groups are granted
+// to their members (see PermissionGrants).
+const regoPolicy = `package lxd.authz
+
+import rego.v1
+
+# The input is the request to authorize, for example:
+#
+# {
+#   "identity": "oidc/jane@example.com",
+#   "identity_provider_groups": ["operators"],
+#   "entitlement": "can_exec",
+#   "entity_type": "instance",
+#   "entity_url": "/1.0/instances/c1?project=default",
+#   "location": "member01"
+# }
+#
+# The entity URL must be canonical. The location is the cluster member that the entity is located on, if any.
+
+default allow := false
+
+# Enabled groups that the identity is a member of, directly or through its identity provider groups.
+direct_groups contains name if {
+	some name, group in data.lxd.groups
+	group.enabled
+	input.identity in group.identities
+}
+
+direct_groups contains name if {
+	some name, group in data.lxd.groups
+	group.enabled
+	some idp_group in input.identity_provider_groups
+	idp_group in group.identity_provider_groups
+}
+
+# Groups inherit the permissions of their ancestors.
+groups := graph.reachable(data.lxd.parents, direct_groups)
+
+allow if {
+	some name in groups
+	group := data.lxd.groups[name]
+	group.enabled
+	some permission in group.permissions
+	permission.entitlement == input.entitlement
+	permission.entity_type == input.entity_type
+	grants(permission)
+}
+
+grants(permission) if {
+	not permission.subtree
+	permission.url == input.entity_url
+}
+
+grants(permission) if {
+	permission.subtree
+	not permission.location
+	startswith(split(input.entity_url, "?")[0], concat("", [permission.url, "/"]))
+}
+
+grants(permission) if {
+	permission.subtree
+	permission.location == input.location
+}
+`
+
+// ExportOpenFGA returns the given groups as an OpenFGA authorization model and relationship tuples. The roles granted
+// to the groups are expanded into the entitlements of the roles, and disabled and template groups only keep their
+// parents so that their enabled children still inherit the permissions of their ancestors. The membership of identity
+// provider groups is not part of the export, as it is given by the identity provider when an identity authenticates.
+func ExportOpenFGA(groups []api.AuthGroup, roles []api.AuthRole) (*api.AuthModelOpenFGA, error) {
+	model, err := openFGAModel()
+	if err != nil {
+		return nil, err
+	}
+
+	rolesByName := make(map[string]api.AuthRole, len(roles))
+	for _, role := range roles {
+		rolesByName[role.Name] = role
+	}
+
+	export := &api.AuthModelOpenFGA{Model: model, Tuples: []api.AuthModelOpenFGATuple{}}
+	seen := make(map[api.AuthModelOpenFGATuple]bool)
+	addTuple := func(user string, relation string, object string) {
+		tuple := api.AuthModelOpenFGATuple{User: user, Relation: relation, Object: object}
+		if seen[tuple] {
+			return
+		}
+
+		seen[tuple] = true
+		export.Tuples = append(export.Tuples, tuple)
+	}
+
+	for _, group := range groups {
+		groupObject := openFGAObject(entity.TypeAuthGroup, entity.AuthGroupURL(group.Name).String())
+		groupMembers := groupObject + "#" + openFGAMemberRelation
+
+		// Members of a group are members of its parents.
+		for _, parent := range group.Parents {
+			addTuple(groupMembers, openFGAMemberRelation, openFGAObject(entity.TypeAuthGroup, entity.AuthGroupURL(parent).String()))
+		}
+
+		if !exportGroupGrants(group) {
+			continue
+		}
+
+		for _, identity := range group.Identities {
+			addTuple(openFGAObject(entity.TypeIdentity, entity.IdentityURL(identity.AuthenticationMethod, identity.Identifier).String()), openFGAMemberRelation, groupObject)
+		}
+
+		for _, idpGroup := range group.IdentityProviderGroups {
+			addTuple(openFGAObject(entity.TypeIdentityProviderGroup, entity.IdentityProviderGroupURL(idpGroup).String())+"#"+openFGAMemberRelation, openFGAMemberRelation, groupObject)
+		}
+
+		permissions, err := exportGroupPermissions(group, rolesByName)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, permission := range permissions {
+			referenceType, _, err := exportPermissionReference(permission)
+			if err != nil {
+				return nil, err
+			}
+
+			// Subtree permissions are granted on the parent entity, whose children have a relation to it.
+			if referenceType != entity.Type(permission.EntityType) {
+				addTuple(groupMembers, openFGASubtreeRelation(entity.Type(permission.EntityType), Entitlement(permission.Entitlement)), openFGAObject(referenceType, permission.EntityReference))
+				continue
+			}
+
+			addTuple(groupMembers, permission.Entitlement, openFGAObject(referenceType, permission.EntityReference))
+		}
+	}
+
+	return export, nil
+}
+
+// ExportRego returns the given groups as an Open Policy Agent policy and data document. The roles granted to the
+// groups are expanded into the entitlements of the roles.
+func ExportRego(groups []api.AuthGroup, roles []api.AuthRole) (*api.AuthModelRego, error) {
+	rolesByName := make(map[string]api.AuthRole, len(roles))
+	for _, role := range roles {
+		rolesByName[role.Name] = role
+	}
+
+	data := api.AuthModelRegoData{
+		Groups:  make(map[string]api.AuthModelRegoGroup, len(groups)),
+		Parents: make(map[string][]string, len(groups)),
+	}
+
+	for _, group := range groups {
+		regoGroup := api.AuthModelRegoGroup{
+			Enabled:                exportGroupGrants(group),
+			Identities:             make([]string, 0, len(group.Identities)),
+			IdentityProviderGroups: append([]string{}, group.IdentityProviderGroups...),
+			Permissions:            []api.AuthModelRegoPermission{},
+		}
+
+		for _, identity := range group.Identities {
+			regoGroup.Identities = append(regoGroup.Identities, identity.AuthenticationMethod+"/"+identity.Identifier)
+		}
+
+		permissions, err := exportGroupPermissions(group, rolesByName)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, permission := range permissions {
+			referenceType, pathArgs, err := exportPermissionReference(permission)
+			if err != nil {
+				return nil, err
+			}
+
+			regoPermission := api.AuthModelRegoPermission{Permission: permission}
+			if referenceType != entity.Type(permission.EntityType) {
+				regoPermission.Subtree = true
+
+				// Instances are not children of cluster members in the API, so subtree permissions on a cluster
+				// member are matched against the location of the instance instead.
+				if referenceType == entity.TypeNode && len(pathArgs) > 0 {
+					regoPermission.Location = pathArgs[0]
+				}
+			}
+
+			if !shared.ValueInSlice(regoPermission, regoGroup.Permissions) {
+				regoGroup.Permissions = append(regoGroup.Permissions, regoPermission)
+			}
+		}
+
+		data.Groups[group.Name] = regoGroup
+		data.Parents[group.Name] = append([]string{}, group.Parents...)
+	}
+
+	return &api.AuthModelRego{Policy: regoPolicy, Data: api.AuthModelRegoDocument{LXD: data}}, nil
+}
+
+// openFGAModel returns the OpenFGA authorization model in the OpenFGA DSL. Each entity type that entitlements can be
+// granted on has a relation per entitlement, which can be granted to the members of groups. Entitlements that can be
+// granted on all children of a parent entity are also granted through a relation on the parent entity.
+func openFGAModel() (string, error) {
+	var b strings.Builder
+	b.WriteString("model\n  schema 1.1\n")
+
+	for _, entityType := range entity.Types() {
+		definitions, err := EntitlementDefinitionsByEntityType(entityType)
+		if err != nil {
+			return "", err
+		}
+
+		var relations []string
+		switch entityType {
+		case entity.TypeAuthGroup:
+			relations = append(relations, fmt.Sprintf("define %s: [%s, %s#%s, %s#%s]", openFGAMemberRelation, entity.TypeIdentity, entity.TypeIdentityProviderGroup, openFGAMemberRelation, entity.TypeAuthGroup, openFGAMemberRelation))
+		case entity.TypeIdentityProviderGroup:
+			relations = append(relations, fmt.Sprintf("define %s: [%s]", openFGAMemberRelation, entity.TypeIdentity))
+		}
+
+		parentEntitlements := SubtreeEntitlementsByEntityType(entityType)
+		parentTypes := make([]string, 0, len(parentEntitlements))
+		for parentType := range parentEntitlements {
+			parentTypes = append(parentTypes, string(parentType))
+		}
+
+		sort.Strings(parentTypes)
+		for _, parentType := range parentTypes {
+			relations = append(relations, fmt.Sprintf("define %s: [%s]", parentType, parentType))
+		}
+
+		for _, definition := range definitions {
+			relation := fmt.Sprintf("define %s: [%s#%s]", definition.Entitlement, entity.TypeAuthGroup, openFGAMemberRelation)
+			for _, parentType := range parentTypes {
+				if shared.ValueInSlice(definition.Entitlement, parentEntitlements[entity.Type(parentType)]) {
+					relation += fmt.Sprintf(" or %s from %s", openFGASubtreeRelation(entityType, definition.Entitlement), parentType)
+				}
+			}
+
+			relations = append(relations, relation)
+		}
+
+		for _, childType := range entity.Types() {
+			for _, entitlement := range SubtreeEntitlementsByEntityType(childType)[entityType] {
+				relations = append(relations, fmt.Sprintf("define %s: [%s#%s]", openFGASubtreeRelation(childType, entitlement), entity.TypeAuthGroup, openFGAMemberRelation))
+			}
+		}
+
+		// Identities are the users of the model, so their type is defined even if it has no relations.
+		if len(relations) == 0 && entityType != entity.TypeIdentity {
+			continue
+		}
+
+		b.WriteString("\ntype " + string(entityType) + "\n")
+		if len(relations) > 0 {
+			b.WriteString("  relations\n")
+		}
+
+		for _, relation := range relations {
+			b.WriteString("    " + relation + "\n")
+		}
+	}
+
+	return b.String(), nil
+}
+
+// openFGAObject returns the OpenFGA object of the entity with the given type and URL.
+func openFGAObject(entityType entity.Type, entityURL string) string {
+	return string(entityType) + ":" + entityURL
+}
+
+// openFGASubtreeRelation returns the relation on a parent entity that grants the Entitlement on all of its children of
+// the given entity.Type.
+func openFGASubtreeRelation(entityType entity.Type, entitlement Entitlement) string {
+	return string(entityType) + "_" + string(entitlement)
+}
+
+// exportGroupGrants returns whether the permissions of the group are granted. Disabled and template groups grant
+// nothing.
+func exportGroupGrants(group api.AuthGroup) bool {
+	return (group.Enabled == nil || *group.Enabled) && (group.Template == nil || !*group.Template)
+}
+
+// exportGroupPermissions returns the permissions of the group, followed by the permissions granted by its roles.
+// Permissions whose entity couldn't be resolved grant nothing and are left out.
+func exportGroupPermissions(group api.AuthGroup, roles map[string]api.AuthRole) ([]api.Permission, error) {
+	permissions := make([]api.Permission, 0, len(group.Permissions))
+	for _, permission := range group.Permissions {
+		if permission.Error == "" {
+			permissions = append(permissions, permission)
+		}
+	}
+
+	for _, groupRole := range group.Roles {
+		role, ok := roles[groupRole.Role]
+		if !ok {
+			return nil, fmt.Errorf("Role %q of group %q not found", groupRole.Role, group.Name)
+		}
+
+		for _, entitlement := range role.Entitlements {
+			permissions = append(permissions, api.Permission{
+				EntityType:      role.EntityType,
+				EntityReference: groupRole.EntityReference,
+				Entitlement:     entitlement,
+			})
+		}
+	}
+
+	return permissions, nil
+}
+
+// exportPermissionReference returns the entity type and path arguments of the entity referenced by the permission.
+func exportPermissionReference(permission api.Permission) (entity.Type, []string, error) {
+	u, err := url.Parse(permission.EntityReference)
+	if err != nil {
+		return "", nil, fmt.Errorf("Failed to parse entity reference %q: %w", permission.EntityReference, err)
+	}
+
+	referenceType, _, _, pathArgs, err := entity.ParseURL(*u)
+	if err != nil {
+		return "", nil, fmt.Errorf("Failed to parse entity reference %q: %w", permission.EntityReference, err)
+	}
+
+	return referenceType, pathArgs, nil
+}
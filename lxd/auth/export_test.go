@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/lxd/shared/api"
+)
+
+// exportTestGroups returns groups covering direct, role, subtree and dangling permissions, parents, and disabled
+// groups.
+func exportTestGroups() ([]api.AuthGroup, []api.AuthRole) {
+	enabled := true
+	disabled := false
+
+	roles := []api.AuthRole{{
+		AuthRolesPost: api.AuthRolesPost{
+			Name: "instance-operator",
+			AuthRolePut: api.AuthRolePut{
+				EntityType:   "instance",
+				Entitlements: []string{"can_exec", "can_update_state"},
+			},
+		},
+	}}
+
+	groups := []api.AuthGroup{
+		{
+			AuthGroupsPost: api.AuthGroupsPost{
+				AuthGroupPost: api.AuthGroupPost{Name: "viewers"},
+				AuthGroupPut: api.AuthGroupPut{
+					Enabled:     &enabled,
+					Permissions: []api.Permission{{EntityType: "server", EntityReference: "/1.0", Entitlement: "viewer"}},
+				},
+			},
+		},
+		{
+			AuthGroupsPost: api.AuthGroupsPost{
+				AuthGroupPost: api.AuthGroupPost{Name: "operators"},
+				AuthGroupPut: api.AuthGroupPut{
+					Enabled: &enabled,
+					Parents: []string{"viewers"},
+					Permissions: []api.Permission{
+						{EntityType: "instance", EntityReference: "/1.0/instances/c1?project=default", Entitlement: "can_exec"},
+						{EntityType: "instance", EntityReference: "/1.0/cluster/members/member01", Entitlement: "can_view"},
+						{EntityType: "storage_volume", EntityReference: "/1.0/storage-pools/default", Entitlement: "can_view"},
+						{EntityType: "instance", Entitlement: "can_view", Error: "Entity URL missing for permission with entity type \"instance\" and entity ID `42`"},
+					},
+					Roles: []api.AuthGroupRole{{Role: "instance-operator", EntityReference: "/1.0/instances/c1?project=default"}},
+				},
+			},
+			Identities:             []api.Identity{{AuthenticationMethod: "oidc", Identifier: "jane@example.com"}},
+			IdentityProviderGroups: []string{"ops"},
+		},
+		{
+			AuthGroupsPost: api.AuthGroupsPost{
+				AuthGroupPost: api.AuthGroupPost{Name: "disabled"},
+				AuthGroupPut: api.AuthGroupPut{
+					Enabled:     &disabled,
+					Parents:     []string{"viewers"},
+					Permissions: []api.Permission{{EntityType: "server", EntityReference: "/1.0", Entitlement: "admin"}},
+				},
+			},
+			Identities: []api.Identity{{AuthenticationMethod: "oidc", Identifier: "joe@example.com"}},
+		},
+	}
+
+	return groups, roles
+}
+
+func TestExportOpenFGA(t *testing.T) {
+	groups, roles := exportTestGroups()
+
+	export, err := ExportOpenFGA(groups, roles)
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(export.Model, "model\n  schema 1.1\n"))
+	assert.Contains(t, export.Model, "\ntype group\n  relations\n    define member: [identity, identity_provider_group#member, group#member]\n")
+	assert.Contains(t, export.Model, "\ntype identity_provider_group\n  relations\n    define member: [identity]\n")
+	assert.Contains(t, export.Model, "    define node: [node]\n")
+	assert.Contains(t, export.Model, "    define can_view: [group#member] or instance_can_view from node\n")
+	assert.Contains(t, export.Model, "    define can_delete: [group#member]\n")
+	assert.Contains(t, export.Model, "\ntype node\n  relations\n    define instance_can_view: [group#member]\n")
+	assert.Contains(t, export.Model, "    define storage_volume_can_view: [group#member]\n")
+	assert.NotContains(t, export.Model, "type container")
+
+	assert.ElementsMatch(t, []api.AuthModelOpenFGATuple{
+		{User: "group:/1.0/auth/groups/viewers#member", Relation: "viewer", Object: "server:/1.0"},
+		{User: "group:/1.0/auth/groups/operators#member", Relation: "member", Object: "group:/1.0/auth/groups/viewers"},
+		{User: "identity:/1.0/auth/identities/oidc/jane@example.com", Relation: "member", Object: "group:/1.0/auth/groups/operators"},
+		{User: "identity_provider_group:/1.0/auth/identity-provider-groups/ops#member", Relation: "member", Object: "group:/1.0/auth/groups/operators"},
+		{User: "group:/1.0/auth/groups/operators#member", Relation: "can_exec", Object: "instance:/1.0/instances/c1?project=default"},
+		{User: "group:/1.0/auth/groups/operators#member", Relation: "instance_can_view", Object: "node:/1.0/cluster/members/member01"},
+		{User: "group:/1.0/auth/groups/operators#member", Relation: "storage_volume_can_view", Object: "storage_pool:/1.0/storage-pools/default"},
+		{User: "group:/1.0/auth/groups/operators#member", Relation: "can_update_state", Object: "instance:/1.0/instances/c1?project=default"},
+		{User: "group:/1.0/auth/groups/disabled#member", Relation: "member", Object: "group:/1.0/auth/groups/viewers"},
+	}, export.Tuples)
+
+	// Groups can't be exported if one of their roles is missing.
+	_, err = ExportOpenFGA(groups, nil)
+	assert.Error(t, err)
+}
+
+func TestExportRego(t *testing.T) {
+	groups, roles := exportTestGroups()
+
+	export, err := ExportRego(groups, roles)
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(export.Policy, "package lxd.authz\n"))
+
+	data := export.Data.LXD
+	assert.Equal(t, map[string][]string{"viewers": {}, "operators": {"viewers"}, "disabled": {"viewers"}}, data.Parents)
+	require.Len(t, data.Groups, 3)
+
+	operators := data.Groups["operators"]
+	assert.True(t, operators.Enabled)
+	assert.Equal(t, []string{"oidc/jane@example.com"}, operators.Identities)
+	assert.Equal(t, []string{"ops"}, operators.IdentityProviderGroups)
+	assert.Equal(t, []api.AuthModelRegoPermission{
+		{Permission: api.Permission{EntityType: "instance", EntityReference: "/1.0/instances/c1?project=default", Entitlement: "can_exec"}},
+		{Permission: api.Permission{EntityType: "instance", EntityReference: "/1.0/cluster/members/member01", Entitlement: "can_view"}, Subtree: true, Location: "member01"},
+		{Permission: api.Permission{EntityType: "storage_volume", EntityReference: "/1.0/storage-pools/default", Entitlement: "can_view"}, Subtree: true},
+		{Permission: api.Permission{EntityType: "instance", EntityReference: "/1.0/instances/c1?project=default", Entitlement: "can_update_state"}},
+	}, operators.Permissions)
+
+	// Disabled groups are exported with their permissions, which the policy doesn't grant.
+	assert.False(t, data.Groups["disabled"].Enabled)
+	assert.Len(t, data.Groups["disabled"].Permissions, 1)
+}
+
+func TestExportTemplateGroups(t *testing.T) {
+	template := true
+	groups := []api.AuthGroup{{
+		AuthGroupsPost: api.AuthGroupsPost{
+			AuthGroupPost: api.AuthGroupPost{Name: "reference"},
+			AuthGroupPut: api.AuthGroupPut{
+				Template:    &template,
+				Parents:     []string{"viewers"},
+				Permissions: []api.Permission{{EntityType: "server", EntityReference: "/1.0", Entitlement: "admin"}},
+			},
+		},
+	}}
+
+	// Template groups grant nothing, but keep their parents.
+	openFGA, err := ExportOpenFGA(groups, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []api.AuthModelOpenFGATuple{
+		{User: "group:/1.0/auth/groups/reference#member", Relation: "member", Object: "group:/1.0/auth/groups/viewers"},
+	}, openFGA.Tuples)
+
+	rego, err := ExportRego(groups, nil)
+	require.NoError(t, err)
+	assert.False(t, rego.Data.LXD.Groups["reference"].Enabled)
+}
@@ -0,0 +1,75 @@
+package oidc
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// claimPathSeparator separates the claim names of a claim path.
+const claimPathSeparator = "."
+
+// ValidateClaimPath checks that the given claim path is well formed. A claim path is a dot separated list of claim
+// names that is used to find a claim nested within JSON objects of the token claims (e.g. "resource_access.lxd.roles").
+// An empty path is valid and means that the claim is not configured.
+func ValidateClaimPath(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	for _, name := range strings.Split(path, claimPathSeparator) {
+		if name == "" {
+			return fmt.Errorf("Invalid claim path %q: Claim names must not be empty", path)
+		}
+
+		if strings.IndexFunc(name, unicode.IsSpace) >= 0 {
+			return fmt.Errorf("Invalid claim path %q: Claim names must not contain whitespace", path)
+		}
+	}
+
+	return nil
+}
+
+// isNestedClaimPath returns whether the given claim path refers to a claim nested within a JSON object.
+func isNestedClaimPath(path string) bool {
+	return strings.Contains(path, claimPathSeparator)
+}
+
+// claimAtPath returns the value of the claim found by following the given claim path through the claims, and whether
+// it was found. Claim names may contain dots themselves (e.g. namespaced claims such as "https://example.com/groups"),
+// so a claim whose name is the whole path takes precedence over a nested claim.
+func claimAtPath(claims map[string]any, path string) (any, bool) {
+	value, ok := claims[path]
+	if ok {
+		return value, true
+	}
+
+	names := strings.Split(path, claimPathSeparator)
+
+	value = claims
+	for _, name := range names {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+
+		value, ok = object[name]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+// stringClaimAtPath returns the string value of the claim found by following the given claim path through the
+// claims. An empty string is returned if the claim is not found or is not a string.
+func stringClaimAtPath(claims map[string]any, path string) string {
+	value, ok := claimAtPath(claims, path)
+	if !ok {
+		return ""
+	}
+
+	valueStr, _ := value.(string)
+	return valueStr
+}
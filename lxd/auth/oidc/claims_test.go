@@ -0,0 +1,76 @@
+package oidc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateClaimPath(t *testing.T) {
+	for _, path := range []string{"", "groups", "resource_access.lxd.roles", "https://example.com/groups"} {
+		assert.NoError(t, ValidateClaimPath(path), path)
+	}
+
+	for _, path := range []string{".groups", "groups.", "resource_access..roles", "resource access.roles", "."} {
+		assert.Error(t, ValidateClaimPath(path), path)
+	}
+}
+
+func TestClaimAtPath(t *testing.T) {
+	claims := map[string]any{
+		"name": "Jane Doe",
+		"resource_access": map[string]any{
+			"lxd": map[string]any{
+				"roles": []any{"admins", "viewers"},
+			},
+		},
+	}
+
+	value, ok := claimAtPath(claims, "resource_access.lxd.roles")
+	assert.True(t, ok)
+	assert.Equal(t, []any{"admins", "viewers"}, value)
+
+	_, ok = claimAtPath(claims, "resource_access.other.roles")
+	assert.False(t, ok)
+
+	_, ok = claimAtPath(claims, "name.first")
+	assert.False(t, ok)
+
+	assert.Equal(t, "Jane Doe", stringClaimAtPath(claims, "name"))
+	assert.Equal(t, "", stringClaimAtPath(claims, "resource_access.lxd"))
+}
+
+func TestGetGroupsFromClaims(t *testing.T) {
+	verifier := &Verifier{groupsClaim: "resource_access.lxd.roles"}
+	claims := map[string]any{
+		"resource_access": map[string]any{
+			"lxd": map[string]any{
+				"roles": []any{"admins", 1, "viewers"},
+			},
+		},
+	}
+
+	assert.Equal(t, []string{"admins", "viewers"}, verifier.getGroupsFromClaims(claims))
+}
+
+func TestClaimAtPathNamespaced(t *testing.T) {
+	claims := map[string]any{
+		"https://example.com/groups": []any{"admins"},
+		"https://example": map[string]any{
+			"com/roles": []any{"viewers"},
+		},
+	}
+
+	// A claim whose name contains dots is found by its name.
+	value, ok := claimAtPath(claims, "https://example.com/groups")
+	assert.True(t, ok)
+	assert.Equal(t, []any{"admins"}, value)
+
+	// Otherwise the claim path is followed.
+	value, ok = claimAtPath(claims, "https://example.com/roles")
+	assert.True(t, ok)
+	assert.Equal(t, []any{"viewers"}, value)
+
+	verifier := &Verifier{groupsClaim: "https://example.com/groups"}
+	assert.Equal(t, []string{"admins"}, verifier.getGroupsFromClaims(claims))
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/sha512"
 	"errors"
 	"fmt"
+	"html"
 	"io"
 	"net/http"
 	"strings"
@@ -41,6 +42,21 @@ const (
 	defaultConfigExpiryInterval = 5 * time.Minute
 )
 
+// loginRefusedPage is shown to users whose login through the callback is refused by the login hook.
+const loginRefusedPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Login refused</title>
+</head>
+<body>
+<h1>Login refused</h1>
+<p>%s</p>
+<p><a href="/ui/login/">Back to the login page</a></p>
+</body>
+</html>
+`
+
 // Verifier holds all information needed to verify an access token offline.
 type Verifier struct {
 	accessTokenVerifier op.AccessTokenVerifier
@@ -51,8 +67,11 @@ type Verifier struct {
 	issuer         string
 	audience       string
 	groupsClaim    string
+	nameClaim      string
+	emailClaim     string
 	clusterCert    func() *shared.CertInfo
 	httpClientFunc func() (*http.Client, error)
+	loginHook      func(r *http.Request, result *AuthenticationResult) error
 
 	// host is used for setting a valid callback URL when setting the relyingParty.
 	// When creating the relyingParty, the OIDC library performs discovery (e.g. it calls the /well-known/oidc-configuration endpoint).
@@ -149,10 +168,17 @@ func (o *Verifier) authenticateAccessToken(ctx context.Context, accessToken stri
 
 	id, err := o.identityCache.GetByOIDCSubject(claims.Subject)
 	if err == nil {
+		// The identifier of a known identity doesn't change, but its name is kept up to date if a custom name
+		// claim is present in the access token.
+		name := id.Name
+		if o.nameClaim != "" && stringClaimAtPath(claims.Claims, o.nameClaim) != "" {
+			name = stringClaimAtPath(claims.Claims, o.nameClaim)
+		}
+
 		return &AuthenticationResult{
 			IdentityType:           api.IdentityTypeOIDCClient,
 			Email:                  id.Identifier,
-			Name:                   id.Name,
+			Name:                   name,
 			Subject:                claims.Subject,
 			IdentityProviderGroups: o.getGroupsFromClaims(claims.Claims),
 		}, nil
@@ -165,14 +191,15 @@ func (o *Verifier) authenticateAccessToken(ctx context.Context, accessToken stri
 		return nil, AuthError{Err: fmt.Errorf("Failed to call user info endpoint with given access token: %w", err)}
 	}
 
-	if userInfo.Email == "" {
+	email, name := o.getIdentityFromClaims(userInfo.Email, userInfo.Name, claims.Claims, userInfo.Claims)
+	if email == "" {
 		return nil, AuthError{Err: fmt.Errorf("Could not get email address of oidc user with subject %q", claims.Subject)}
 	}
 
 	return &AuthenticationResult{
 		IdentityType:           api.IdentityTypeOIDCClient,
-		Email:                  userInfo.Email,
-		Name:                   userInfo.Name,
+		Email:                  email,
+		Name:                   name,
 		Subject:                claims.Subject,
 		IdentityProviderGroups: o.getGroupsFromClaims(claims.Claims),
 	}, nil
@@ -187,13 +214,7 @@ func (o *Verifier) authenticateIDToken(ctx context.Context, w http.ResponseWrite
 		// Try to verify the ID token.
 		claims, err = rp.VerifyIDToken[*oidc.IDTokenClaims](ctx, idToken, o.relyingParty.IDTokenVerifier())
 		if err == nil {
-			return &AuthenticationResult{
-				IdentityType:           api.IdentityTypeOIDCClient,
-				Subject:                claims.Subject,
-				Email:                  claims.Email,
-				Name:                   claims.Name,
-				IdentityProviderGroups: o.getGroupsFromClaims(claims.Claims),
-			}, nil
+			return o.authenticationResultFromIDTokenClaims(claims), nil
 		}
 	}
 
@@ -231,23 +252,55 @@ func (o *Verifier) authenticateIDToken(ctx context.Context, w http.ResponseWrite
 		return nil, AuthError{fmt.Errorf("Failed to update login cookies: %w", err)}
 	}
 
+	return o.authenticationResultFromIDTokenClaims(claims), nil
+}
+
+// authenticationResultFromIDTokenClaims returns the AuthenticationResult for the given verified ID token claims.
+func (o *Verifier) authenticationResultFromIDTokenClaims(claims *oidc.IDTokenClaims) *AuthenticationResult {
+	email, name := o.getIdentityFromClaims(claims.Email, claims.Name, claims.Claims)
 	return &AuthenticationResult{
 		IdentityType:           api.IdentityTypeOIDCClient,
 		Subject:                claims.Subject,
-		Email:                  claims.Email,
-		Name:                   claims.Name,
+		Email:                  email,
+		Name:                   name,
 		IdentityProviderGroups: o.getGroupsFromClaims(claims.Claims),
-	}, nil
+	}
+}
+
+// getIdentityFromClaims returns the email address and name of the identity. If custom claims are configured for
+// either of them, they are looked up in each of the given token claims in turn instead of using the standard values.
+func (o *Verifier) getIdentityFromClaims(email string, name string, claimSets ...map[string]any) (string, string) {
+	lookup := func(claimPath string) string {
+		for _, claims := range claimSets {
+			value := stringClaimAtPath(claims, claimPath)
+			if value != "" {
+				return value
+			}
+		}
+
+		logger.Warn("OIDC custom claim not found", logger.Ctx{"claim_name": claimPath})
+		return ""
+	}
+
+	if o.emailClaim != "" {
+		email = lookup(o.emailClaim)
+	}
+
+	if o.nameClaim != "" {
+		name = lookup(o.nameClaim)
+	}
+
+	return email, name
 }
 
 // getGroupsFromClaims attempts to get the configured groups claim from the token claims and warns if it is not present
-// or is not a valid type. The custom claims are an unmarshalled JSON object.
+// or is not a valid type. The custom claims are an unmarshalled JSON object, in which the groups claim may be nested.
 func (o *Verifier) getGroupsFromClaims(customClaims map[string]any) []string {
 	if o.groupsClaim == "" {
 		return nil
 	}
 
-	groupsClaimAny, ok := customClaims[o.groupsClaim]
+	groupsClaimAny, ok := claimAtPath(customClaims, o.groupsClaim)
 	if !ok {
 		logger.Warn("OIDC groups custom claim not found", logger.Ctx{"claim_name": o.groupsClaim})
 		return nil
@@ -256,6 +309,7 @@ func (o *Verifier) getGroupsFromClaims(customClaims map[string]any) []string {
 	groupsArr, ok := groupsClaimAny.([]any)
 	if !ok {
 		logger.Warn("Unexpected type for OIDC groups custom claim", logger.Ctx{"claim_name": o.groupsClaim, "claim_value": groupsClaimAny})
+		return nil
 	}
 
 	groups := make([]string, 0, len(groupsArr))
@@ -263,6 +317,7 @@ func (o *Verifier) getGroupsFromClaims(customClaims map[string]any) []string {
 		groupName, ok := groupNameAny.(string)
 		if !ok {
 			logger.Warn("Unexpected type for OIDC groups custom claim", logger.Ctx{"claim_name": o.groupsClaim, "claim_value": groupsClaimAny})
+			continue
 		}
 
 		groups = append(groups, groupName)
@@ -303,6 +358,23 @@ func (o *Verifier) Callback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	handler := rp.CodeExchangeHandler(func(w http.ResponseWriter, r *http.Request, tokens *oidc.Tokens[*oidc.IDTokenClaims], state string, rp rp.RelyingParty) {
+		if o.loginHook != nil {
+			result := o.authenticationResultFromIDTokenClaims(tokens.IDTokenClaims)
+			err := o.loginHook(r, result)
+			if err != nil {
+				// Explain to the user why their login was refused, rather than rendering an API error.
+				if api.StatusErrorCheck(err, http.StatusForbidden) {
+					w.Header().Set("Content-Type", "text/html; charset=utf-8")
+					w.WriteHeader(http.StatusForbidden)
+					_, _ = fmt.Fprintf(w, loginRefusedPage, html.EscapeString(err.Error()))
+					return
+				}
+
+				_ = response.ErrorResponse(http.StatusInternalServerError, fmt.Errorf("Login failed: %w", err).Error()).Render(w)
+				return
+			}
+		}
+
 		sessionID := uuid.New()
 		secureCookie, err := o.secureCookieFromSession(sessionID)
 		if err != nil {
@@ -424,7 +496,8 @@ func (o *Verifier) setRelyingParty(host string) error {
 	}
 
 	oidcScopes := []string{oidc.ScopeOpenID, oidc.ScopeOfflineAccess, oidc.ScopeEmail, oidc.ScopeProfile}
-	if o.groupsClaim != "" {
+	// Nested claims can't be requested as a scope, the identity provider must be configured to include them.
+	if o.groupsClaim != "" && !isNestedClaimPath(o.groupsClaim) {
 		oidcScopes = append(oidcScopes, o.groupsClaim)
 	}
 
@@ -614,14 +687,23 @@ func (o *Verifier) secureCookieFromSession(sessionID uuid.UUID) (*securecookie.S
 // Opts contains optional configurable fields for the Verifier.
 type Opts struct {
 	GroupsClaim string
+	NameClaim   string
+	EmailClaim  string
+
+	// LoginHook is called with the result of each login through the callback. The login is refused if it returns an
+	// error. If the error is a forbidden api.StatusError, its message is shown to the user.
+	LoginHook func(r *http.Request, result *AuthenticationResult) error
 }
 
 // NewVerifier returns a Verifier.
 func NewVerifier(issuer string, clientID string, audience string, clusterCert func() *shared.CertInfo, identityCache *identity.Cache, httpClientFunc func() (*http.Client, error), options *Opts) (*Verifier, error) {
 	opts := &Opts{}
 
-	if options != nil && options.GroupsClaim != "" {
+	if options != nil {
 		opts.GroupsClaim = options.GroupsClaim
+		opts.NameClaim = options.NameClaim
+		opts.EmailClaim = options.EmailClaim
+		opts.LoginHook = options.LoginHook
 	}
 
 	verifier := &Verifier{
@@ -630,9 +712,12 @@ func NewVerifier(issuer string, clientID string, audience string, clusterCert fu
 		audience:             audience,
 		identityCache:        identityCache,
 		groupsClaim:          opts.GroupsClaim,
+		nameClaim:            opts.NameClaim,
+		emailClaim:           opts.EmailClaim,
 		clusterCert:          clusterCert,
 		configExpiryInterval: defaultConfigExpiryInterval,
 		httpClientFunc:       httpClientFunc,
+		loginHook:            opts.LoginHook,
 	}
 
 	return verifier, nil
@@ -3,6 +3,8 @@ package auth
 import (
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
@@ -37,148 +39,238 @@ func ValidateEntitlement(entityType entity.Type, entitlement Entitlement) error
 	return nil
 }
 
-// EntitlementsByEntityType returns a list of available Entitlement for the entity.Type.
-func EntitlementsByEntityType(entityType entity.Type) ([]Entitlement, error) {
-	err := entityType.Validate()
-	if err != nil {
-		return nil, fmt.Errorf("Entity type %q is not valid: %w", entityType, err)
-	}
-
-	// Some entity types do not have entitlements
-	if shared.ValueInSlice(entityType, []entity.Type{
-		entity.TypeContainer,
-		entity.TypeCertificate,
-		entity.TypeInstanceBackup,
-		entity.TypeInstanceSnapshot,
-		entity.TypeNode,
-		entity.TypeOperation,
-		entity.TypeStorageVolumeBackup,
-		entity.TypeStorageVolumeSnapshot,
-		entity.TypeWarning,
-		entity.TypeClusterGroup,
-	}) {
-		return []Entitlement{}, nil
-	}
-
-	// With the exception of entity types in the list below. All entity types have EntitlementCanView,
-	// EntitlementCanEdit, and EntitlementCanDelete.
-	if !shared.ValueInSlice(entityType, []entity.Type{entity.TypeStorageVolume, entity.TypeInstance, entity.TypeProject, entity.TypeServer}) {
-		return []Entitlement{EntitlementCanView, EntitlementCanEdit, EntitlementCanDelete}, nil
-	}
-
-	switch entityType {
-	case entity.TypeStorageVolume:
-		return []Entitlement{
+// subtreeEntitlements is a map of entity type, to the entity type of its parent, to the entitlements that may be
+// granted on all entities of that type that are children of a given parent entity (a subtree permission).
+// Entitlements that should only ever be granted on individual entities are omitted. For example, granting
+// EntitlementCanDelete over a storage pool would allow the deletion of any volume that is created in the pool later on,
+// including instance root volumes.
+//
+// Instances are not children of cluster members in the API. Instead, a subtree permission on the instances of a cluster
+// member applies to the instances that are located on that member, and is resolved into a permission on each of those
+// instances when the identity cache is loaded.
+var subtreeEntitlements = map[entity.Type]map[entity.Type][]Entitlement{
+	entity.TypeInstance: {
+		entity.TypeNode: {
 			EntitlementCanView,
 			EntitlementCanEdit,
-			EntitlementCanDelete,
-			EntitlementCanManageBackups,
-			EntitlementCanManageSnapshots,
-		}, nil
-	case entity.TypeInstance:
-		return []Entitlement{
-			EntitlementCanView,
-			EntitlementCanEdit,
-			EntitlementCanDelete,
 			EntitlementInstanceUser,
 			EntitlementInstanceOperator,
 			EntitlementCanUpdateState,
 			EntitlementCanConnectSFTP,
 			EntitlementCanAccessFiles,
+			EntitlementCanEditFiles,
 			EntitlementCanAccessConsole,
 			EntitlementCanExec,
 			EntitlementCanManageBackups,
 			EntitlementCanManageSnapshots,
-		}, nil
-	case entity.TypeProject:
-		return []Entitlement{
-			EntitlementCanView,
-			EntitlementCanEdit,
-			EntitlementCanDelete,
-			EntitlementProjectOperator,
-			EntitlementProjectViewer,
-			EntitlementImageManager,
-			EntitlementCanCreateImages,
-			EntitlementCanViewImages,
-			EntitlementCanEditImages,
-			EntitlementCanDeleteImages,
-			EntitlementImageAliasManager,
-			EntitlementCanCreateImageAliases,
-			EntitlementCanViewImageAliases,
-			EntitlementCanEditImageAliases,
-			EntitlementCanDeleteImageAliases,
-			EntitlementInstanceManager,
-			EntitlementCanCreateInstances,
-			EntitlementCanViewInstances,
-			EntitlementCanEditInstances,
-			EntitlementCanDeleteInstances,
-			EntitlementCanOperateInstances,
-			EntitlementNetworkManager,
-			EntitlementCanCreateNetworks,
-			EntitlementCanViewNetworks,
-			EntitlementCanEditNetworks,
-			EntitlementCanDeleteNetworks,
-			EntitlementNetworkACLManager,
-			EntitlementCanCreateNetworkACLs,
-			EntitlementCanViewNetworkACLs,
-			EntitlementCanEditNetworkACLs,
-			EntitlementCanDeleteNetworkACLs,
-			EntitlementNetworkZoneManager,
-			EntitlementCanCreateNetworkZones,
-			EntitlementCanViewNetworkZones,
-			EntitlementCanEditNetworkZones,
-			EntitlementCanDeleteNetworkZones,
-			EntitlementProfileManager,
-			EntitlementCanCreateProfiles,
-			EntitlementCanViewProfiles,
-			EntitlementCanEditProfiles,
-			EntitlementCanDeleteProfiles,
-			EntitlementStorageVolumeManager,
-			EntitlementCanCreateStorageVolumes,
-			EntitlementCanViewStorageVolumes,
-			EntitlementCanEditStorageVolumes,
-			EntitlementCanDeleteStorageVolumes,
-			EntitlementStorageBucketManager,
-			EntitlementCanCreateStorageBuckets,
-			EntitlementCanViewStorageBuckets,
-			EntitlementCanEditStorageBuckets,
-			EntitlementCanDeleteStorageBuckets,
-			EntitlementCanViewOperations,
-			EntitlementCanViewEvents,
-		}, nil
-	case entity.TypeServer:
-		return []Entitlement{
+		},
+	},
+	entity.TypeStorageVolume: {
+		entity.TypeStoragePool: {
 			EntitlementCanView,
 			EntitlementCanEdit,
-			EntitlementServerAdmin,
-			EntitlementServerViewer,
-			EntitlementCanViewConfiguration,
-			EntitlementPermissionManager,
-			EntitlementCanViewPermissions,
-			EntitlementCanCreateIdentities,
-			EntitlementCanViewIdentities,
-			EntitlementCanEditIdentities,
-			EntitlementCanDeleteIdentities,
-			EntitlementCanCreateGroups,
-			EntitlementCanViewGroups,
-			EntitlementCanEditGroups,
-			EntitlementCanDeleteGroups,
-			EntitlementStoragePoolManager,
-			EntitlementCanCreateStoragePools,
-			EntitlementCanEditStoragePools,
-			EntitlementCanDeleteStoragePools,
-			EntitlementProjectManager,
-			EntitlementCanCreateProjects,
-			EntitlementCanViewProjects,
-			EntitlementCanEditProjects,
-			EntitlementCanDeleteProjects,
-			EntitlementCanOverrideClusterTargetRestriction,
-			EntitlementCanViewPrivilegedEvents,
-			EntitlementCanViewResources,
-			EntitlementCanViewMetrics,
-			EntitlementCanViewWarnings,
-		}, nil
-	}
-
-	return nil, fmt.Errorf("Missing entitlements definition for entity type %q", entityType)
+			EntitlementCanManageBackups,
+			EntitlementCanManageSnapshots,
+			EntitlementCanConnectSFTP,
+			EntitlementCanConnectSFTPReadOnly,
+		},
+	},
+}
+
+// projectEntitlements is a map of entity.Type to the entitlements on entities of that type that are granted on all the
+// entities of the type within a project by an entitlement on the project.
+var projectEntitlements = func() map[entity.Type]map[Entitlement][]Entitlement {
+	resource := func(view Entitlement, edit Entitlement, del Entitlement) map[Entitlement][]Entitlement {
+		return map[Entitlement][]Entitlement{
+			EntitlementCanView:   {view},
+			EntitlementCanEdit:   {edit},
+			EntitlementCanDelete: {del},
+		}
+	}
+
+	projectEntitlements := map[entity.Type]map[Entitlement][]Entitlement{
+		entity.TypeImage:         resource(EntitlementCanViewImages, EntitlementCanEditImages, EntitlementCanDeleteImages),
+		entity.TypeImageAlias:    resource(EntitlementCanViewImageAliases, EntitlementCanEditImageAliases, EntitlementCanDeleteImageAliases),
+		entity.TypeInstance:      resource(EntitlementCanViewInstances, EntitlementCanEditInstances, EntitlementCanDeleteInstances),
+		entity.TypeNetwork:       resource(EntitlementCanViewNetworks, EntitlementCanEditNetworks, EntitlementCanDeleteNetworks),
+		entity.TypeNetworkACL:    resource(EntitlementCanViewNetworkACLs, EntitlementCanEditNetworkACLs, EntitlementCanDeleteNetworkACLs),
+		entity.TypeNetworkZone:   resource(EntitlementCanViewNetworkZones, EntitlementCanEditNetworkZones, EntitlementCanDeleteNetworkZones),
+		entity.TypeProfile:       resource(EntitlementCanViewProfiles, EntitlementCanEditProfiles, EntitlementCanDeleteProfiles),
+		entity.TypeStorageVolume: resource(EntitlementCanViewStorageVolumes, EntitlementCanEditStorageVolumes, EntitlementCanDeleteStorageVolumes),
+		entity.TypeStorageBucket: resource(EntitlementCanViewStorageBuckets, EntitlementCanEditStorageBuckets, EntitlementCanDeleteStorageBuckets),
+	}
+
+	// Operating instances covers viewing them and everything that the instance operator entitlement grants.
+	instanceOperator := []Entitlement{EntitlementCanView, EntitlementCanUpdateState, EntitlementCanConnectSFTP, EntitlementCanAccessFiles, EntitlementCanEditFiles, EntitlementCanAccessConsole, EntitlementCanExec, EntitlementCanManageBackups, EntitlementCanManageSnapshots, EntitlementInstanceOperator}
+	for _, entitlement := range instanceOperator {
+		projectEntitlements[entity.TypeInstance][entitlement] = append(projectEntitlements[entity.TypeInstance][entitlement], EntitlementCanOperateInstances)
+	}
+
+	return projectEntitlements
+}()
+
+// ValidateSubtreeEntitlement returns an error if the given Entitlement cannot be granted on all entities of the given
+// entity.Type that are children of an entity of the parent entity.Type.
+func ValidateSubtreeEntitlement(parentEntityType entity.Type, entityType entity.Type, entitlement Entitlement) error {
+	parentEntitlements, ok := subtreeEntitlements[entityType]
+	if !ok {
+		return fmt.Errorf("Subtree permissions cannot be granted against entities of type %q", entityType)
+	}
+
+	entitlements, ok := parentEntitlements[parentEntityType]
+	if !ok {
+		return fmt.Errorf("Entities of type %q are not children of entities of type %q", entityType, parentEntityType)
+	}
+
+	if !shared.ValueInSlice(entitlement, entitlements) {
+		return fmt.Errorf("Entitlement %q cannot be granted on all entities of type %q within an entity of type %q", entitlement, entityType, parentEntityType)
+	}
+
+	return nil
+}
+
+// SubtreeEntitlementsByEntityType returns a map of parent entity.Type to the list of Entitlement that can be granted on
+// all entities of the given entity.Type that are children of an entity of the parent entity.Type.
+func SubtreeEntitlementsByEntityType(entityType entity.Type) map[entity.Type][]Entitlement {
+	result := make(map[entity.Type][]Entitlement, len(subtreeEntitlements[entityType]))
+	for parentEntityType, entitlements := range subtreeEntitlements[entityType] {
+		result[parentEntityType] = append([]Entitlement{}, entitlements...)
+	}
+
+	return result
+}
+
+// PermissionGrants returns whether the given permission grants the Entitlement on the entity with the given URL. The
+// entity reference of the permission is expected to have been resolved to a canonical entity URL. If the permission
+// is a subtree permission (its entity type differs from the type of its entity reference), the entity URL must be a
+// child of the entity reference.
+func PermissionGrants(permission api.Permission, entitlement Entitlement, entityType entity.Type, entityURL *api.URL) bool {
+	if Entitlement(permission.Entitlement) != entitlement || entity.Type(permission.EntityType) != entityType {
+		return false
+	}
+
+	referenceURL, err := url.Parse(permission.EntityReference)
+	if err != nil {
+		return false
+	}
+
+	referenceEntityType, referenceProject, referenceLocation, _, err := entity.ParseURL(*referenceURL)
+	if err != nil {
+		return false
+	}
+
+	// Subtree permissions apply to all child entities, regardless of their project or location. The parent entities
+	// that subtree permissions can reference (see subtreeEntitlements) aren't part of a project, and neither are their
+	// children in the URL hierarchy, e.g. the storage volumes of a pool in all projects are at the path of the pool.
+	if referenceEntityType != entityType {
+		return strings.HasPrefix(entityURL.URL.Path, referenceURL.Path+"/")
+	}
+
+	_, projectName, location, _, err := entity.ParseURL(entityURL.URL)
+	if err != nil {
+		return false
+	}
+
+	return entityURL.URL.Path == referenceURL.Path && projectName == referenceProject && location == referenceLocation
+}
+
+// PermissionGrantsImplied returns whether the given permission grants the Entitlement on the entity with the given URL,
+// either directly (see PermissionGrants) or through a broader entitlement. Broader entitlements are the entitlements on
+// the same entity that imply the Entitlement (see EntitlementDefinition.ImpliedBy), the entitlements on the project of
+// the entity that apply to all entities of its type within the project, and the admin entitlement on the server.
+func PermissionGrantsImplied(permission api.Permission, entitlement Entitlement, entityType entity.Type, entityURL *api.URL) bool {
+	permissionEntityType := entity.Type(permission.EntityType)
+	if permissionEntityType == entity.TypeServer && Entitlement(permission.Entitlement) == EntitlementServerAdmin {
+		return true
+	}
+
+	implying := implyingEntitlements(entityType, entitlement)
+	for _, entityEntitlement := range implying {
+		if PermissionGrants(permission, entityEntitlement, entityType, entityURL) {
+			return true
+		}
+	}
+
+	if permissionEntityType != entity.TypeProject || projectEntitlements[entityType] == nil {
+		return false
+	}
+
+	_, projectName, _, _, err := entity.ParseURL(entityURL.URL)
+	if err != nil || projectName == "" {
+		return false
+	}
+
+	projectURL := entity.ProjectURL(projectName)
+	for _, entityEntitlement := range implying {
+		for _, projectEntitlement := range projectEntitlements[entityType][entityEntitlement] {
+			for _, projectImplying := range implyingEntitlements(entity.TypeProject, projectEntitlement) {
+				if PermissionGrants(permission, projectImplying, entity.TypeProject, projectURL) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// implyingEntitlements returns the given Entitlement followed by the entitlements on entities of the given entity.Type
+// that imply it, either directly or through other entitlements.
+func implyingEntitlements(entityType entity.Type, entitlement Entitlement) []Entitlement {
+	definitions, err := EntitlementDefinitionsByEntityType(entityType)
+	if err != nil {
+		return []Entitlement{entitlement}
+	}
+
+	impliedBy := make(map[Entitlement][]Entitlement, len(definitions))
+	for _, definition := range definitions {
+		impliedBy[definition.Entitlement] = definition.ImpliedBy
+	}
+
+	implying := []Entitlement{entitlement}
+	for i := 0; i < len(implying); i++ {
+		for _, broader := range impliedBy[implying[i]] {
+			if !shared.ValueInSlice(broader, implying) {
+				implying = append(implying, broader)
+			}
+		}
+	}
+
+	return implying
+}
+
+// PermissionInProjects returns whether the entity referenced by the permission is one of the given projects, or is part
+// of one of them. Entities that aren't part of a project, such as the server, storage pools, cluster members or groups,
+// aren't in any project, so subtree permissions referencing them can't be confined to projects either.
+func PermissionInProjects(permission api.Permission, projects []string) bool {
+	referenceURL, err := url.Parse(permission.EntityReference)
+	if err != nil {
+		return false
+	}
+
+	referenceEntityType, referenceProject, _, pathArgs, err := entity.ParseURL(*referenceURL)
+	if err != nil {
+		return false
+	}
+
+	if referenceEntityType == entity.TypeProject {
+		referenceProject = pathArgs[0]
+	}
+
+	return referenceProject != "" && shared.ValueInSlice(referenceProject, projects)
+}
+
+// EntitlementsByEntityType returns a list of available Entitlement for the entity.Type.
+func EntitlementsByEntityType(entityType entity.Type) ([]Entitlement, error) {
+	definitions, err := EntitlementDefinitionsByEntityType(entityType)
+	if err != nil {
+		return nil, err
+	}
+
+	entitlements := make([]Entitlement, 0, len(definitions))
+	for _, definition := range definitions {
+		entitlements = append(entitlements, definition.Entitlement)
+	}
+
+	return entitlements, nil
 }
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
+)
+
+func TestPermissionInProjects(t *testing.T) {
+	projects := []string{"default", "foo"}
+
+	tests := []struct {
+		name       string
+		permission api.Permission
+		want       bool
+	}{
+		{"project", api.Permission{EntityType: "project", EntityReference: "/1.0/projects/foo", Entitlement: "operator"}, true},
+		{"other project", api.Permission{EntityType: "project", EntityReference: "/1.0/projects/bar", Entitlement: "operator"}, false},
+		{"instance", api.Permission{EntityType: "instance", EntityReference: "/1.0/instances/c1?project=foo", Entitlement: "can_exec"}, true},
+		{"instance in default project", api.Permission{EntityType: "instance", EntityReference: "/1.0/instances/c1", Entitlement: "can_exec"}, true},
+		{"instance in other project", api.Permission{EntityType: "instance", EntityReference: "/1.0/instances/c1?project=bar", Entitlement: "can_exec"}, false},
+		{"project subtree", api.Permission{EntityType: "instance", EntityReference: "/1.0/projects/foo", Entitlement: "can_view"}, true},
+		{"server", api.Permission{EntityType: "server", EntityReference: "/1.0", Entitlement: "viewer"}, false},
+		{"group", api.Permission{EntityType: "group", EntityReference: "/1.0/auth/groups/admins", Entitlement: "can_view"}, false},
+		{"storage pool subtree", api.Permission{EntityType: "storage_volume", EntityReference: "/1.0/storage-pools/default", Entitlement: "can_view"}, false},
+		{"invalid reference", api.Permission{EntityType: "instance", EntityReference: "/2.0/instances/c1", Entitlement: "can_exec"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, PermissionInProjects(tt.permission, projects))
+		})
+	}
+}
+
+func TestPermissionGrants(t *testing.T) {
+	instance := api.Permission{EntityType: "instance", EntityReference: "/1.0/instances/c1?project=foo", Entitlement: "can_exec"}
+	poolVolumes := api.Permission{EntityType: "storage_volume", EntityReference: "/1.0/storage-pools/default", Entitlement: "can_view"}
+
+	tests := []struct {
+		name        string
+		permission  api.Permission
+		entitlement Entitlement
+		entityType  entity.Type
+		entityURL   *api.URL
+		want        bool
+	}{
+		{"instance", instance, EntitlementCanExec, entity.TypeInstance, entity.InstanceURL("foo", "c1"), true},
+		{"instance in other project", instance, EntitlementCanExec, entity.TypeInstance, entity.InstanceURL("bar", "c1"), false},
+		{"other instance", instance, EntitlementCanExec, entity.TypeInstance, entity.InstanceURL("foo", "c2"), false},
+		{"other entitlement", instance, EntitlementCanView, entity.TypeInstance, entity.InstanceURL("foo", "c1"), false},
+		{"volume in pool", poolVolumes, EntitlementCanView, entity.TypeStorageVolume, entity.StorageVolumeURL("default", "", "default", "custom", "vol1"), true},
+		// Storage pools aren't part of a project, so the permission applies to the volumes of the pool in all projects.
+		{"volume in pool in other project", poolVolumes, EntitlementCanView, entity.TypeStorageVolume, entity.StorageVolumeURL("foo", "", "default", "custom", "vol1"), true},
+		{"volume in pool on cluster member", poolVolumes, EntitlementCanView, entity.TypeStorageVolume, entity.StorageVolumeURL("foo", "node1", "default", "custom", "vol1"), true},
+		{"volume in pool with same prefix", poolVolumes, EntitlementCanView, entity.TypeStorageVolume, entity.StorageVolumeURL("default", "", "default2", "custom", "vol1"), false},
+		{"other entitlement on volume in pool", poolVolumes, EntitlementCanEdit, entity.TypeStorageVolume, entity.StorageVolumeURL("default", "", "default", "custom", "vol1"), false},
+		{"pool", poolVolumes, EntitlementCanView, entity.TypeStoragePool, entity.StoragePoolURL("default"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, PermissionGrants(tt.permission, tt.entitlement, tt.entityType, tt.entityURL))
+		})
+	}
+}
+
+func TestPermissionGrantsImplied(t *testing.T) {
+	instanceEditFiles := api.Permission{EntityType: "instance", EntityReference: "/1.0/instances/c1?project=foo", Entitlement: "can_edit_files"}
+	projectEditInstances := api.Permission{EntityType: "project", EntityReference: "/1.0/projects/foo", Entitlement: "can_edit_instances"}
+	projectOperator := api.Permission{EntityType: "project", EntityReference: "/1.0/projects/foo", Entitlement: "operator"}
+	serverAdmin := api.Permission{EntityType: "server", EntityReference: "/1.0", Entitlement: "admin"}
+
+	tests := []struct {
+		name        string
+		permission  api.Permission
+		entitlement Entitlement
+		entityType  entity.Type
+		entityURL   *api.URL
+		want        bool
+	}{
+		{"same entitlement", instanceEditFiles, EntitlementCanEditFiles, entity.TypeInstance, entity.InstanceURL("foo", "c1"), true},
+		{"implied entitlement", instanceEditFiles, EntitlementCanAccessFiles, entity.TypeInstance, entity.InstanceURL("foo", "c1"), true},
+		{"implied entitlement on other instance", instanceEditFiles, EntitlementCanAccessFiles, entity.TypeInstance, entity.InstanceURL("foo", "c2"), false},
+		{"not implied entitlement", instanceEditFiles, EntitlementCanExec, entity.TypeInstance, entity.InstanceURL("foo", "c1"), false},
+		{"project entitlement", projectEditInstances, EntitlementCanEdit, entity.TypeInstance, entity.InstanceURL("foo", "c1"), true},
+		{"project entitlement implying view", projectEditInstances, EntitlementCanView, entity.TypeInstance, entity.InstanceURL("foo", "c1"), true},
+		{"project entitlement not granting delete", projectEditInstances, EntitlementCanDelete, entity.TypeInstance, entity.InstanceURL("foo", "c1"), false},
+		{"project entitlement in other project", projectEditInstances, EntitlementCanEdit, entity.TypeInstance, entity.InstanceURL("bar", "c1"), false},
+		{"project entitlement on other entity type", projectEditInstances, EntitlementCanEdit, entity.TypeProfile, entity.ProfileURL("foo", "default"), false},
+		{"project operator", projectOperator, EntitlementCanDelete, entity.TypeStorageVolume, entity.StorageVolumeURL("foo", "", "default", "custom", "vol1"), true},
+		{"project operator on pool", projectOperator, EntitlementCanView, entity.TypeStoragePool, entity.StoragePoolURL("default"), false},
+		{"server admin", serverAdmin, EntitlementCanDelete, entity.TypeProject, entity.ProjectURL("foo"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, PermissionGrantsImplied(tt.permission, tt.entitlement, tt.entityType, tt.entityURL))
+		})
+	}
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/canonical/lxd/lxd/auth"
+	"github.com/canonical/lxd/lxd/cluster"
+	"github.com/canonical/lxd/lxd/db"
+	dbCluster "github.com/canonical/lxd/lxd/db/cluster"
+	"github.com/canonical/lxd/lxd/request"
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/lxd/state"
+	"github.com/canonical/lxd/lxd/task"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
+	"github.com/canonical/lxd/shared/logger"
+)
+
+var authAuditCmd = APIEndpoint{
+	Name: "auth_audit",
+	Path: "auth/audit",
+	Get: APIEndpointAction{
+		Handler:       getAuthAudit,
+		AccessHandler: allowPermission(entity.TypeServer, auth.EntitlementCanViewAuthAudit),
+	},
+}
+
+// authAuditObjectTypes are the types of objects whose changes are recorded in the audit trail.
+var authAuditObjectTypes = []string{
+	api.AuthAuditObjectTypeGroup,
+	api.AuthAuditObjectTypeIdentity,
+	api.AuthAuditObjectTypeIdentityProviderGroup,
+	api.AuthAuditObjectTypeRole,
+}
+
+// swagger:operation GET /1.0/auth/audit auth_audit auth_audit_get
+//
+//	Get the audit trail of authorization changes
+//
+//	Returns the entries of the audit trail of changes to groups, identities, identity provider groups and roles,
+//	ordered from the oldest to the most recent. With `format=jsonl`, the entries are exported as JSON lines instead.
+//
+//	---
+//	produces:
+//	  - application/json
+//	  - application/jsonl
+//	parameters:
+//	  - in: query
+//	    name: since
+//	    description: Only return the entries made at or after this date (RFC 3339)
+//	    type: string
+//	    example: 2024-03-23T00:00:00Z
+//	  - in: query
+//	    name: object-type
+//	    description: Only return the entries of objects of this type (group, identity, identity_provider_group, or role)
+//	    type: string
+//	    example: group
+//	  - in: query
+//	    name: limit
+//	    description: Maximum number of entries to return
+//	    type: integer
+//	    example: 100
+//	  - in: query
+//	    name: offset
+//	    description: Number of entries to skip
+//	    type: integer
+//	    example: 0
+//	  - in: query
+//	    name: format
+//	    description: Set to `jsonl` to export the entries as JSON lines
+//	    type: string
+//	    example: jsonl
+//	responses:
+//	  "200":
+//	    description: API endpoints
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of audit entries
+//	          items:
+//	            $ref: "#/definitions/AuthAuditEntry"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func getAuthAudit(d *Daemon, r *http.Request) response.Response {
+	filter := dbCluster.AuthAuditEntryFilter{
+		ObjectType: request.QueryParam(r, "object-type"),
+	}
+
+	if filter.ObjectType != "" && !shared.ValueInSlice(filter.ObjectType, authAuditObjectTypes) {
+		return response.BadRequest(fmt.Errorf("Invalid `object-type` query parameter %q", filter.ObjectType))
+	}
+
+	since := request.QueryParam(r, "since")
+	if since != "" {
+		var err error
+		filter.Since, err = time.Parse(time.RFC3339, since)
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Invalid `since` query parameter %q: %w", since, err))
+		}
+	}
+
+	limit, err := permissionsQueryInt(r, "limit")
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	offset, err := permissionsQueryInt(r, "offset")
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	format := request.QueryParam(r, "format")
+	if format != "" && format != "jsonl" {
+		return response.BadRequest(fmt.Errorf("Invalid `format` query parameter %q", format))
+	}
+
+	var entries []dbCluster.AuthAuditEntry
+	err = d.State().DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		entries, err = dbCluster.GetAuthAuditEntries(ctx, tx.Tx(), filter, limit, offset)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	apiEntries := make([]api.AuthAuditEntry, 0, len(entries))
+	for _, entry := range entries {
+		apiEntries = append(apiEntries, entry.ToAPI())
+	}
+
+	if format != "jsonl" {
+		return response.SyncResponse(true, apiEntries)
+	}
+
+	return response.ManualResponse(func(w http.ResponseWriter) error {
+		w.Header().Set("Content-Type", "application/jsonl")
+		w.Header().Set("Content-Disposition", "attachment; filename=auth-audit.jsonl")
+		w.WriteHeader(http.StatusOK)
+
+		encoder := json.NewEncoder(w)
+		for _, entry := range apiEntries {
+			err := encoder.Encode(entry)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// authAuditRecord appends an entry recording a change of the authorization configuration to the audit trail. It must
+// be called in the transaction making the change, so that the change fails if it can't be recorded. The before and
+// after states of the object are nil if the object didn't exist before or after the change.
+func authAuditRecord(ctx context.Context, tx *sql.Tx, requestor *api.EventLifecycleRequestor, objectType string, objectName string, action string, before any, after any) error {
+	entry := dbCluster.AuthAuditEntry{
+		Date:          time.Now(),
+		Actor:         requestor.Username,
+		ActorProtocol: requestor.Protocol,
+		ObjectType:    objectType,
+		ObjectName:    objectName,
+		Action:        action,
+	}
+
+	var err error
+	entry.Before, err = authAuditState(before)
+	if err != nil {
+		return err
+	}
+
+	entry.After, err = authAuditState(after)
+	if err != nil {
+		return err
+	}
+
+	_, err = dbCluster.CreateAuthAuditEntry(ctx, tx, entry)
+	if err != nil {
+		return fmt.Errorf("Failed recording the change in the auth audit trail: %w", err)
+	}
+
+	return nil
+}
+
+// authAuditState returns the JSON encoding of the state of an object, or an empty string if the object is nil.
+func authAuditState(object any) (string, error) {
+	b, err := json.Marshal(object)
+	if err != nil {
+		return "", fmt.Errorf("Failed encoding the state of the object for the auth audit trail: %w", err)
+	}
+
+	if bytes.Equal(b, []byte("null")) {
+		return "", nil
+	}
+
+	return string(b), nil
+}
+
+// authAuditNotFound returns whether the error reports that the object doesn't exist.
+func authAuditNotFound(err error) bool {
+	return api.StatusErrorCheck(err, http.StatusNotFound) || errors.Is(err, sql.ErrNoRows)
+}
+
+// authAuditGroupState returns the state of the group with the given name, or nil if it doesn't exist.
+func authAuditGroupState(ctx context.Context, tx *sql.Tx, groupName string) (*api.AuthGroup, error) {
+	group, err := dbCluster.GetAuthGroup(ctx, tx, groupName)
+	if err != nil {
+		if authAuditNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return group.ToAPI(ctx, tx)
+}
+
+// authAuditIdentityName returns the object name of an identity in the audit trail.
+func authAuditIdentityName(authenticationMethod string, identifier string) string {
+	return authenticationMethod + "/" + identifier
+}
+
+// authAuditIdentityState returns the state of the identity, or nil if it doesn't exist.
+func authAuditIdentityState(ctx context.Context, tx *sql.Tx, authenticationMethod string, identifier string) (*api.IdentityInfo, error) {
+	id, err := dbCluster.GetIdentity(ctx, tx, dbCluster.AuthMethod(authenticationMethod), identifier)
+	if err != nil {
+		if authAuditNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return id.ToAPIInfo(ctx, tx)
+}
+
+// authAuditIdentityUpdated records the update of the identity, whose state before the update is given.
+func authAuditIdentityUpdated(ctx context.Context, tx *sql.Tx, requestor *api.EventLifecycleRequestor, id *dbCluster.Identity, before *api.IdentityInfo) error {
+	after, err := authAuditIdentityState(ctx, tx, string(id.AuthMethod), id.Identifier)
+	if err != nil {
+		return err
+	}
+
+	return authAuditRecord(ctx, tx, requestor, api.AuthAuditObjectTypeIdentity, authAuditIdentityName(string(id.AuthMethod), id.Identifier), api.AuthAuditActionUpdated, before, after)
+}
+
+// authAuditIdentityProviderGroupState returns the state of the identity provider group with the given name, or nil if
+// it doesn't exist.
+func authAuditIdentityProviderGroupState(ctx context.Context, tx *sql.Tx, idpGroupName string) (*api.IdentityProviderGroup, error) {
+	idpGroup, err := dbCluster.GetIdentityProviderGroup(ctx, tx, idpGroupName)
+	if err != nil {
+		if authAuditNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return idpGroup.ToAPI(ctx, tx)
+}
+
+// authAuditRoleState returns the state of the role with the given name, or nil if it doesn't exist.
+func authAuditRoleState(ctx context.Context, tx *sql.Tx, roleName string) (*api.AuthRole, error) {
+	role, err := dbCluster.GetAuthRole(ctx, tx, roleName)
+	if err != nil {
+		if authAuditNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	groupNamesByRoleID, err := dbCluster.GetAllAuthGroupNamesByRoleIDs(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	apiRole := authRoleToAPI(*role, groupNamesByRoleID[role.ID])
+	return &apiRole, nil
+}
+
+// pruneAuthAuditTask deletes the entries of the audit trail that are older than core.auth_audit_retention. It only
+// runs on the leader, as the audit trail is shared by all cluster members.
+func pruneAuthAuditTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		leader, err := d.gateway.LeaderAddress()
+		if err != nil && !errors.Is(err, cluster.ErrNodeIsNotClustered) {
+			logger.Error("Failed to get leader cluster member address", logger.Ctx{"err": err})
+			return
+		}
+
+		if err == nil && d.State().LocalConfig.ClusterAddress() != leader {
+			return
+		}
+
+		err = pruneAuthAudit(ctx, d.State())
+		if err != nil {
+			logger.Warn("Failed pruning the auth audit trail", logger.Ctx{"err": err})
+		}
+	}
+
+	return f, task.Daily()
+}
+
+// pruneAuthAudit deletes the entries of the audit trail that are older than core.auth_audit_retention.
+func pruneAuthAudit(ctx context.Context, s *state.State) error {
+	now := time.Now()
+	expiry, err := shared.GetExpiry(now, s.GlobalConfig.AuthAuditRetention())
+	if err != nil {
+		return err
+	}
+
+	if !expiry.After(now) {
+		return nil
+	}
+
+	cutoff := now.Add(-expiry.Sub(now))
+
+	var deleted int64
+	err = s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+		deleted, err = dbCluster.DeleteAuthAuditEntriesBefore(ctx, tx.Tx(), cutoff)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		logger.Info("Pruned the auth audit trail", logger.Ctx{"deleted": deleted, "before": cutoff})
+	}
+
+	return nil
+}
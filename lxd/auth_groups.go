@@ -4,26 +4,35 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 
-	"github.com/canonical/lxd/client"
 	"github.com/canonical/lxd/lxd/auth"
-	"github.com/canonical/lxd/lxd/cluster"
+	clusterConfig "github.com/canonical/lxd/lxd/cluster/config"
 	"github.com/canonical/lxd/lxd/db"
 	dbCluster "github.com/canonical/lxd/lxd/db/cluster"
+	"github.com/canonical/lxd/lxd/db/operationtype"
+	"github.com/canonical/lxd/lxd/db/query"
+	"github.com/canonical/lxd/lxd/db/warningtype"
 	"github.com/canonical/lxd/lxd/lifecycle"
+	"github.com/canonical/lxd/lxd/operations"
 	"github.com/canonical/lxd/lxd/request"
 	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/lxd/state"
+	"github.com/canonical/lxd/lxd/task"
 	"github.com/canonical/lxd/lxd/util"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/entity"
+	"github.com/canonical/lxd/shared/filter"
+	"github.com/canonical/lxd/shared/logger"
 )
 
 var authGroupsCmd = APIEndpoint{
@@ -37,6 +46,37 @@ var authGroupsCmd = APIEndpoint{
 		Handler:       createAuthGroup,
 		AccessHandler: allowPermission(entity.TypeServer, auth.EntitlementCanCreateGroups),
 	},
+	Delete: APIEndpointAction{
+		Handler:       deleteAuthGroups,
+		AccessHandler: allowAuthenticated,
+	},
+}
+
+var authGroupsPreviewCmd = APIEndpoint{
+	Name: "auth_groups_preview",
+	Path: "auth/groups/preview",
+	Post: APIEndpointAction{
+		Handler:       previewAuthGroup,
+		AccessHandler: allowPermission(entity.TypeServer, auth.EntitlementCanCreateGroups),
+	},
+}
+
+var authGroupsImportCmd = APIEndpoint{
+	Name: "auth_groups_import",
+	Path: "auth/groups/import",
+	Post: APIEndpointAction{
+		Handler:       importAuthGroups,
+		AccessHandler: allowPermission(entity.TypeServer, auth.EntitlementCanCreateGroups),
+	},
+}
+
+var authGroupDiffCmd = APIEndpoint{
+	Name: "auth_group_diff",
+	Path: "auth/groups/{groupName}/diff",
+	Get: APIEndpointAction{
+		Handler:       getAuthGroupDiff,
+		AccessHandler: allowPermission(entity.TypeAuthGroup, auth.EntitlementCanView, "groupName"),
+	},
 }
 
 var authGroupCmd = APIEndpoint{
@@ -77,6 +117,11 @@ func validateGroupName(name string) error {
 		return api.StatusErrorf(http.StatusBadRequest, "Group name cannot contain a colon")
 	}
 
+	// The names are reserved for the group preview and import endpoints.
+	if name == "preview" || name == "import" {
+		return api.StatusErrorf(http.StatusBadRequest, "Group name cannot be %q", name)
+	}
+
 	return nil
 }
 
@@ -89,6 +134,12 @@ func validateGroupName(name string) error {
 //	---
 //	produces:
 //	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: filter
+//	    description: Collection filter
+//	    type: string
+//	    example: default
 //	responses:
 //	  "200":
 //	    description: API endpoints
@@ -123,6 +174,98 @@ func validateGroupName(name string) error {
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
 
+// swagger:operation GET /1.0/auth/groups?count=1 auth_groups auth_groups_get_count
+//
+//	Get the number of groups
+//
+//	Returns the number of authorization groups that the caller can view.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: filter
+//	    description: Collection filter
+//	    type: string
+//	    example: default
+//	responses:
+//	  "200":
+//	    description: API endpoints
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: integer
+//	          description: Number of groups
+//	          example: 2
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+
+// swagger:operation GET /1.0/auth/groups?summary=1 auth_groups auth_groups_get_summary
+//
+//	Get a summary of the groups
+//
+//	Returns the name, description, number of permissions and broadest permission scope of each authorization group.
+//	This is cheaper than a recursive request, as the entities that the permissions apply to aren't resolved.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: filter
+//	    description: Collection filter
+//	    type: string
+//	    example: default
+//	responses:
+//	  "200":
+//	    description: API endpoints
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of group summaries
+//	          items:
+//	            $ref: "#/definitions/AuthGroupSummary"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+
 // swagger:operation GET /1.0/auth/groups?recursion=1 auth_groups auth_groups_get_recursion1
 //
 //	Get the groups
@@ -132,6 +275,17 @@ func validateGroupName(name string) error {
 //	---
 //	produces:
 //	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: filter
+//	    description: Collection filter
+//	    type: string
+//	    example: default
+//	  - in: query
+//	    name: identity_filter
+//	    description: Filter applied to the identities of each group
+//	    type: string
+//	    example: authentication_method eq tls
 //	responses:
 //	  "200":
 //	    description: API endpoints
@@ -162,68 +316,78 @@ func validateGroupName(name string) error {
 //	    $ref: "#/responses/InternalServerError"
 func getAuthGroups(d *Daemon, r *http.Request) response.Response {
 	recursion := request.QueryParam(r, "recursion")
+	count := request.QueryParam(r, "count") == "1"
+	summary := request.QueryParam(r, "summary") == "1"
 	s := d.State()
 
-	hasPermission, err := s.Authorizer.GetPermissionChecker(r.Context(), r, auth.EntitlementCanViewGroups, entity.TypeAuthGroup)
+	if summary && recursion == "1" {
+		return response.BadRequest(fmt.Errorf("The summary of groups cannot be combined with recursion"))
+	}
+
+	// Parse filter value.
+	clauses, err := filter.Parse(request.QueryParam(r, "filter"), filter.QueryOperatorSet())
+	if err != nil {
+		return response.BadRequest(fmt.Errorf("Failed to filter groups: %w", err))
+	}
+
+	identityClauses, err := filter.Parse(request.QueryParam(r, "identity_filter"), filter.QueryOperatorSet())
+	if err != nil {
+		return response.BadRequest(fmt.Errorf("Failed to filter group identities: %w", err))
+	}
+
+	hasPermission, err := authGroupPermissionChecker(s, r, auth.EntitlementCanViewGroups, auth.EntitlementCanView)
 	if err != nil {
 		return response.SmartError(fmt.Errorf("Failed to get a permission checker: %w", err))
 	}
 
 	var groups []dbCluster.AuthGroup
-	groupsPermissions := make(map[int][]dbCluster.Permission)
-	groupsIdentities := make(map[int][]dbCluster.Identity)
-	groupsIdentityProviderGroups := make(map[int][]dbCluster.IdentityProviderGroup)
-	entityURLs := make(map[entity.Type]map[int]*api.URL)
+	var groupsPermissions map[int][]dbCluster.Permission
+	details := authGroupsDetails{names: make(map[int]string)}
 	err = d.db.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
 		allGroups, err := dbCluster.GetAuthGroups(ctx, tx.Tx())
 		if err != nil {
 			return err
 		}
 
+		details.enabled, err = dbCluster.GetAllAuthGroupsEnabled(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
 		groups = make([]dbCluster.AuthGroup, 0, len(groups))
 		for _, group := range allGroups {
-			if hasPermission(entity.AuthGroupURL(group.Name)) {
-				groups = append(groups, group)
-			}
-		}
+			details.names[group.ID] = group.Name
 
-		if len(groups) == 0 {
-			return nil
-		}
+			if !hasPermission(entity.AuthGroupURL(group.Name)) {
+				continue
+			}
 
-		if recursion == "1" {
-			// If recursing, we need all identities for all groups, all IDP groups for all groups,
-			// all permissions for all groups, and finally the URLs that those permissions apply to.
-			groupsIdentities, err = dbCluster.GetAllIdentitiesByAuthGroupIDs(ctx, tx.Tx())
+			match, err := authGroupMatchesFilter(group, details.enabled[group.ID], clauses)
 			if err != nil {
 				return err
 			}
 
-			groupsIdentityProviderGroups, err = dbCluster.GetAllIdentityProviderGroupsByGroupIDs(ctx, tx.Tx())
-			if err != nil {
-				return err
+			if match {
+				groups = append(groups, group)
 			}
+		}
 
+		if len(groups) == 0 || count {
+			return nil
+		}
+
+		if summary {
+			// Only the permissions themselves are needed to summarise the groups, not the URLs of their entities.
 			groupsPermissions, err = dbCluster.GetAllPermissionsByAuthGroupIDs(ctx, tx.Tx())
 			if err != nil {
 				return err
 			}
 
-			// allGroupPermissions is a de-duplicated slice of permissions.
-			var allGroupPermissions []dbCluster.Permission
-			for _, groupPermissions := range groupsPermissions {
-				for _, permission := range groupPermissions {
-					if !shared.ValueInSlice(permission, allGroupPermissions) {
-						allGroupPermissions = append(allGroupPermissions, permission)
-					}
-				}
-			}
+			return nil
+		}
 
-			// EntityURLs is a map of entity type, to entity ID, to api.URL.
-			entityURLs, err = dbCluster.GetPermissionEntityURLs(ctx, tx.Tx(), allGroupPermissions)
-			if err != nil {
-				return err
-			}
+		if recursion == "1" {
+			return details.load(ctx, tx.Tx())
 		}
 
 		return nil
@@ -232,72 +396,215 @@ func getAuthGroups(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	if count {
+		return response.SyncResponse(true, len(groups))
+	}
+
 	if recursion == "1" {
-		apiGroups := make([]api.AuthGroup, 0, len(groups))
+		apiGroups, err := details.toAPI(groups, identityClauses)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.SyncResponse(true, apiGroups)
+	}
+
+	if summary {
+		summaries := make([]api.AuthGroupSummary, 0, len(groups))
 		for _, group := range groups {
-			var apiPermissions []api.Permission
-
-			// The group may not have any permissions.
-			permissions, ok := groupsPermissions[group.ID]
-			if ok {
-				apiPermissions = make([]api.Permission, 0, len(permissions))
-				for _, permission := range permissions {
-					// Expect to find any permissions in the entity URL map by its entity type and entity ID.
-					entityIDToURL, ok := entityURLs[entity.Type(permission.EntityType)]
-					if !ok {
-						return response.InternalError(fmt.Errorf("Entity URLs missing for permissions with entity type %q", permission.EntityType))
-					}
+			summaries = append(summaries, api.AuthGroupSummary{
+				Name:            group.Name,
+				Description:     group.Description,
+				PermissionCount: len(groupsPermissions[group.ID]),
+				Scope:           authGroupPermissionScope(groupsPermissions[group.ID]),
+			})
+		}
 
-					apiURL, ok := entityIDToURL[permission.EntityID]
-					if !ok {
-						return response.InternalError(fmt.Errorf("Entity URL missing for permission with entity type %q and entity ID `%d`", permission.EntityType, permission.EntityID))
-					}
+		return response.SyncResponse(true, summaries)
+	}
 
-					apiPermissions = append(apiPermissions, api.Permission{
-						EntityType:      string(permission.EntityType),
-						EntityReference: apiURL.String(),
-						Entitlement:     string(permission.Entitlement),
-					})
-				}
-			}
+	groupURLs := make([]string, 0, len(groups))
+	for _, group := range groups {
+		groupURLs = append(groupURLs, entity.AuthGroupURL(group.Name).String())
+	}
+
+	return response.SyncResponse(true, groupURLs)
+}
+
+// authGroupsDetails holds the data that groups are converted to api.AuthGroup from, for all groups. The names and
+// enabled states of the groups are set by the caller, as they are needed to select the groups, and the rest is set by
+// load.
+type authGroupsDetails struct {
+	names                  map[int]string
+	enabled                map[int]bool
+	templates              map[int]bool
+	permissions            map[int][]dbCluster.Permission
+	identities             map[int][]dbCluster.Identity
+	identityProviderGroups map[int][]dbCluster.IdentityProviderGroup
+	lastUsedAt             map[int]time.Time
+	parentIDs              map[int][]int
+	roles                  map[int][]dbCluster.AuthGroupRole
+	roleEntityURLs         map[entity.Type]map[int]*api.URL
+	entityURLs             map[entity.Type]map[int]*api.URL
+}
+
+// load gets the template states, identities, IDP groups, permissions, parents and roles of all groups, and the URLs
+// of the entities that the permissions and roles apply to.
+func (g *authGroupsDetails) load(ctx context.Context, tx *sql.Tx) error {
+	var err error
+	g.templates, err = dbCluster.GetAllAuthGroupsTemplate(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	g.identities, err = dbCluster.GetAllIdentitiesByAuthGroupIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
 
-			apiIdentities := make([]api.Identity, 0, len(groupsIdentities[group.ID]))
-			for _, identity := range groupsIdentities[group.ID] {
-				apiIdentities = append(apiIdentities, api.Identity{
-					AuthenticationMethod: string(identity.AuthMethod),
-					Type:                 string(identity.Type),
-					Identifier:           identity.Identifier,
-					Name:                 identity.Name,
-				})
+	g.identityProviderGroups, err = dbCluster.GetAllIdentityProviderGroupsByGroupIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	g.permissions, err = dbCluster.GetAllPermissionsByAuthGroupIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	g.lastUsedAt, err = dbCluster.GetAllAuthGroupsLastUsedAt(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	g.parentIDs, err = dbCluster.GetAllAuthGroupParentIDsByGroupIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	g.roles, err = dbCluster.GetAllAuthGroupRolesByGroupIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	var allGroupRoles []dbCluster.AuthGroupRole
+	for _, groupRoles := range g.roles {
+		allGroupRoles = append(allGroupRoles, groupRoles...)
+	}
+
+	g.roleEntityURLs, err = dbCluster.GetAuthGroupRoleEntityURLs(ctx, tx, allGroupRoles)
+	if err != nil {
+		return err
+	}
+
+	// allGroupPermissions is a de-duplicated slice of permissions.
+	var allGroupPermissions []dbCluster.Permission
+	for _, groupPermissions := range g.permissions {
+		for _, permission := range groupPermissions {
+			if !shared.ValueInSlice(permission, allGroupPermissions) {
+				allGroupPermissions = append(allGroupPermissions, permission)
 			}
+		}
+	}
+
+	// EntityURLs is a map of entity type, to entity ID, to api.URL.
+	g.entityURLs, err = dbCluster.GetPermissionEntityURLs(ctx, tx, allGroupPermissions)
+	if err != nil {
+		return err
+	}
 
-			idpGroups := make([]string, 0, len(groupsIdentityProviderGroups[group.ID]))
-			for _, idpGroup := range groupsIdentityProviderGroups[group.ID] {
-				idpGroups = append(idpGroups, idpGroup.Name)
+	return nil
+}
+
+// toAPI converts the given groups to api.AuthGroup. The identities of each group are filtered by the given clauses.
+func (g *authGroupsDetails) toAPI(groups []dbCluster.AuthGroup, identityClauses *filter.ClauseSet) ([]api.AuthGroup, error) {
+	// Convert the permissions of all groups, as groups inherit the permissions of their ancestors.
+	groupsAPIPermissions := make(map[int][]api.Permission, len(g.permissions))
+	for groupID, permissions := range g.permissions {
+		apiPermissions := make([]api.Permission, 0, len(permissions))
+		for _, permission := range permissions {
+			// Expect to find any permissions in the entity URL map by its entity type and entity ID. A permission
+			// whose entity can't be resolved is reported with an error rather than failing the whole listing, so
+			// that a single dangling permission doesn't hide all groups.
+			apiURL, ok := g.entityURLs[entity.Type(permission.EntityType)][permission.EntityID]
+			if !ok {
+				apiPermission := permission.ToAPI(&api.URL{})
+				apiPermission.Error = fmt.Sprintf("Entity URL missing for permission with entity type %q and entity ID `%d`", permission.EntityType, permission.EntityID)
+				apiPermissions = append(apiPermissions, apiPermission)
+				continue
 			}
 
-			apiGroups = append(apiGroups, api.AuthGroup{
-				AuthGroupsPost: api.AuthGroupsPost{
-					AuthGroupPost: api.AuthGroupPost{Name: group.Name},
-					AuthGroupPut: api.AuthGroupPut{
-						Description: group.Description,
-						Permissions: apiPermissions,
-					},
-				},
-				Identities:             apiIdentities,
-				IdentityProviderGroups: idpGroups,
-			})
+			apiPermissions = append(apiPermissions, permission.ToAPI(apiURL))
 		}
 
-		return response.SyncResponse(true, apiGroups)
+		groupsAPIPermissions[groupID] = apiPermissions
 	}
 
-	groupURLs := make([]string, 0, len(groups))
+	apiGroups := make([]api.AuthGroup, 0, len(groups))
 	for _, group := range groups {
-		groupURLs = append(groupURLs, entity.AuthGroupURL(group.Name).String())
+		// The group may not have any permissions.
+		apiPermissions := groupsAPIPermissions[group.ID]
+
+		parents := make([]string, 0, len(g.parentIDs[group.ID]))
+		for _, parentID := range g.parentIDs[group.ID] {
+			parents = append(parents, g.names[parentID])
+		}
+
+		sort.Strings(parents)
+
+		inheritedPermissions := []api.AuthGroupInheritedPermission{}
+		for _, ancestorID := range dbCluster.AuthGroupAncestorIDs(g.parentIDs, group.ID) {
+			if ancestorID == group.ID {
+				continue
+			}
+
+			for _, permission := range groupsAPIPermissions[ancestorID] {
+				inheritedPermissions = append(inheritedPermissions, api.AuthGroupInheritedPermission{Permission: permission, Group: g.names[ancestorID]})
+			}
+		}
+
+		apiIdentities := make([]api.Identity, 0, len(g.identities[group.ID]))
+		for _, identity := range g.identities[group.ID] {
+			apiIdentities = append(apiIdentities, api.Identity{
+				AuthenticationMethod: string(identity.AuthMethod),
+				Type:                 string(identity.Type),
+				Identifier:           identity.Identifier,
+				Name:                 identity.Name,
+			})
+		}
+
+		apiIdentities, err := filterAuthGroupIdentities(apiIdentities, identityClauses)
+		if err != nil {
+			return nil, err
+		}
+
+		idpGroups := make([]string, 0, len(g.identityProviderGroups[group.ID]))
+		for _, idpGroup := range g.identityProviderGroups[group.ID] {
+			idpGroups = append(idpGroups, idpGroup.Name)
+		}
+
+		enabled := g.enabled[group.ID]
+		template := g.templates[group.ID]
+		apiGroups = append(apiGroups, api.AuthGroup{
+			AuthGroupsPost: api.AuthGroupsPost{
+				AuthGroupPost: api.AuthGroupPost{Name: group.Name},
+				AuthGroupPut: api.AuthGroupPut{
+					Description: group.Description,
+					Permissions: apiPermissions,
+					Parents:     parents,
+					Roles:       dbCluster.AuthGroupRolesToAPI(g.roles[group.ID], g.roleEntityURLs),
+					Enabled:     &enabled,
+					Template:    &template,
+				},
+			},
+			Identities:             apiIdentities,
+			IdentityProviderGroups: idpGroups,
+			LastUsedAt:             g.lastUsedAt[group.ID],
+			InheritedPermissions:   inheritedPermissions,
+		})
 	}
 
-	return response.SyncResponse(true, groupURLs)
+	return apiGroups, nil
 }
 
 // swagger:operation POST /1.0/auth/groups auth_groups auth_groups_post
@@ -305,6 +612,9 @@ func getAuthGroups(d *Daemon, r *http.Request) response.Response {
 //	Create a new authorization group
 //
 //	Creates a new authorization group.
+//	If the request has a `Prefer: return=representation` header, the created group is returned.
+//	If the request has a `Prefer: handling=lenient` header or the `if_not_exists` parameter is set, creating a group
+//	that already exists with the same definition succeeds and returns the existing group.
 //
 //	---
 //	consumes:
@@ -312,19 +622,49 @@ func getAuthGroups(d *Daemon, r *http.Request) response.Response {
 //	produces:
 //	  - application/json
 //	parameters:
+//	  - in: query
+//	    name: if_not_exists
+//	    description: Succeed if the group already exists with the same definition
+//	    type: integer
+//	    example: 1
 //	  - in: body
 //	    name: group
 //	    description: Group request
 //	    required: true
 //	    schema:
 //	      $ref: "#/definitions/AuthGroupsPost"
+//	  - in: header
+//	    name: Prefer
+//	    description: Set to `return=representation` to return the created group, or to `handling=lenient` to succeed if the group already exists with the same definition
+//	    type: string
+//	    example: return=representation
 //	responses:
 //	  "200":
-//	    $ref: "#/responses/EmptySyncResponse"
+//	    description: Empty sync response, or the created or existing group if requested
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          $ref: "#/definitions/AuthGroup"
 //	  "400":
 //	    $ref: "#/responses/BadRequest"
 //	  "403":
 //	    $ref: "#/responses/Forbidden"
+//	  "409":
+//	    description: A group with the same name already exists (with a different definition if `if_not_exists` is set)
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
 func createAuthGroup(d *Daemon, r *http.Request) response.Response {
@@ -344,111 +684,263 @@ func createAuthGroup(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	s := d.State()
+
+	err = authGroupPermissionsQuotaCheck(s, len(group.Permissions))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	l := authGroupLogger(r, "create", group.Name, group.Permissions)
+
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 	defer cancel()
 
-	s := d.State()
-	err = s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
-		groupID, err := dbCluster.CreateAuthGroup(ctx, tx.Tx(), dbCluster.AuthGroup{
-			Name:        group.Name,
-			Description: group.Description,
-		})
+	// Creating a group that already exists with the same definition succeeds when requested, so that clients
+	// repeatedly applying a desired state don't have to check whether the group exists first.
+	if request.PreferLenientHandling(r) || request.QueryParam(r, "if_not_exists") == "1" {
+		existingGroup, err := authGroupMatchingDefinition(ctx, s, group, l)
 		if err != nil {
-			return err
+			return authGroupTxError(ctx, err)
+		}
+
+		if existingGroup != nil {
+			l.Debug("Group already exists with the same definition")
+			return response.SyncResponseLocation(true, *existingGroup, entity.AuthGroupURL(group.Name).String())
 		}
+	}
+
+	requestor := request.CreateRequestor(r)
 
-		permissionIDs, err := upsertPermissions(ctx, tx.Tx(), group.Permissions)
+	var apiGroup *api.AuthGroup
+	var maybeCreated bool
+	err = s.DB.Cluster.TransactionRetry(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+		maybeCreated = query.IsRetryAfterLeadershipLost(ctx)
+		err := authGroupCaseConflictCheck(ctx, s, tx.Tx(), group.Name, "")
 		if err != nil {
 			return err
 		}
 
-		err = dbCluster.SetAuthGroupPermissions(ctx, tx.Tx(), int(groupID), permissionIDs)
+		err = createAuthGroupTx(ctx, tx.Tx(), group, l)
 		if err != nil {
 			return err
 		}
 
-		return nil
+		// Get the stored group in the same transaction so that it can't be modified before it is recorded and
+		// returned.
+		apiGroup, err = authAuditGroupState(ctx, tx.Tx(), group.Name)
+		if err != nil {
+			return err
+		}
+
+		return authAuditRecord(ctx, tx.Tx(), requestor, api.AuthAuditObjectTypeGroup, group.Name, api.AuthAuditActionCreated, nil, apiGroup)
 	})
+
+	// If leadership was lost while committing a previous attempt, the group may have been created by that attempt
+	// rather than by someone else.
+	if maybeCreated && api.StatusErrorCheck(err, http.StatusConflict) {
+		existingGroup, matchErr := authGroupMatchingDefinition(ctx, s, group, l)
+		if matchErr == nil && existingGroup != nil {
+			apiGroup = existingGroup
+			err = nil
+		}
+	}
+
 	if err != nil {
-		return response.SmartError(err)
+		l.Warn("Failed creating group", logger.Ctx{"err": err})
+		return authGroupTxError(ctx, err)
 	}
 
+	l.Debug("Created group")
+
 	// Send a lifecycle event for the group creation
-	lc := lifecycle.AuthGroupCreated.Event(group.Name, request.CreateRequestor(r), nil)
+	lc := lifecycle.AuthGroupCreated.Event(group.Name, requestor, nil)
 	s.Events.SendLifecycle(api.ProjectDefaultName, lc)
 
+	if request.PreferRepresentation(r) {
+		return response.SyncResponseLocation(true, *apiGroup, entity.AuthGroupURL(group.Name).String())
+	}
+
 	return response.SyncResponseLocation(true, nil, entity.AuthGroupURL(group.Name).String())
 }
 
-// swagger:operation GET /1.0/auth/groups/{groupName} auth_groups auth_group_get
-//
-//	Get the authorization group
-//
-//	Gets a specific authorization group.
-//
-//	---
-//	produces:
-//	  - application/json
-//	responses:
-//	  "200":
-//	    schema:
-//	      type: object
-//	      description: Sync response
-//	      properties:
-//	        type:
-//	          type: string
-//	          description: Response type
-//	          example: sync
-//	        status:
-//	          type: string
-//	          description: Status description
-//	          example: Success
-//	        status_code:
-//	          type: integer
-//	          description: Status code
-//	          example: 200
-//	        metadata:
-//	          $ref: "#/definitions/AuthGroup"
-//	  "403":
-//	    $ref: "#/responses/Forbidden"
-//	  "500":
-//	    $ref: "#/responses/InternalServerError"
-func getAuthGroup(d *Daemon, r *http.Request) response.Response {
-	groupName, err := url.PathUnescape(mux.Vars(r)["groupName"])
+// createAuthGroupTx creates the given group along with its permissions, parents, roles, enabled and template states.
+// The logger is used to report permission references that fail to resolve.
+func createAuthGroupTx(ctx context.Context, tx *sql.Tx, group api.AuthGroupsPost, l logger.Logger) error {
+	groupID, err := dbCluster.CreateAuthGroup(ctx, tx, dbCluster.AuthGroup{
+		Name:        group.Name,
+		Description: group.Description,
+	})
 	if err != nil {
-		return response.SmartError(err)
+		return err
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
-	defer cancel()
+	permissionIDs, err := upsertPermissions(ctx, tx, group.Permissions, l)
+	if err != nil {
+		return err
+	}
 
-	var apiGroup *api.AuthGroup
-	s := d.State()
-	err = s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
-		group, err := dbCluster.GetAuthGroup(ctx, tx.Tx(), groupName)
+	err = dbCluster.SetAuthGroupPermissions(ctx, tx, int(groupID), permissionIDs)
+	if err != nil {
+		return err
+	}
+
+	parentIDs, err := authGroupParentIDs(ctx, tx, int(groupID), group.Name, group.Parents)
+	if err != nil {
+		return err
+	}
+
+	err = dbCluster.SetAuthGroupParents(ctx, tx, int(groupID), parentIDs)
+	if err != nil {
+		return err
+	}
+
+	roles, err := authGroupRoles(ctx, tx, group.Roles)
+	if err != nil {
+		return err
+	}
+
+	err = dbCluster.SetAuthGroupRoles(ctx, tx, int(groupID), roles)
+	if err != nil {
+		return err
+	}
+
+	// Groups are enabled unless requested otherwise.
+	if group.Enabled != nil && !*group.Enabled {
+		err = dbCluster.SetAuthGroupEnabled(ctx, tx, int(groupID), false)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Groups aren't templates unless requested otherwise.
+	if group.Template != nil && *group.Template {
+		return dbCluster.SetAuthGroupTemplate(ctx, tx, int(groupID), true)
+	}
+
+	return nil
+}
+
+// authGroupMatchingDefinition returns the group with the name of the given group creation request if it exists and
+// has the same definition, or nil if it doesn't exist. An api.StatusError with http.StatusConflict is returned if the
+// group exists with a different definition. The requested group is created under a temporary name in a transaction
+// that is rolled back, so that both definitions are compared as returned by ToAPI.
+func authGroupMatchingDefinition(ctx context.Context, s *state.State, group api.AuthGroupsPost, l logger.Logger) (*api.AuthGroup, error) {
+	var existingGroup *api.AuthGroup
+	var matches bool
+	err := s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+		dbGroup, err := dbCluster.GetAuthGroup(ctx, tx.Tx(), group.Name)
 		if err != nil {
+			if api.StatusErrorCheck(err, http.StatusNotFound) {
+				return nil
+			}
+
 			return err
 		}
 
-		apiGroup, err = group.ToAPI(ctx, tx.Tx())
+		existingGroup, err = dbGroup.ToAPI(ctx, tx.Tx())
 		if err != nil {
 			return err
 		}
 
-		return nil
+		// Group names cannot contain a forward slash, so the temporary name cannot conflict with an existing group.
+		requested := group
+		requested.Name = group.Name + "/requested"
+		err = createAuthGroupTx(ctx, tx.Tx(), requested, l)
+		if err != nil {
+			return err
+		}
+
+		dbRequestedGroup, err := dbCluster.GetAuthGroup(ctx, tx.Tx(), requested.Name)
+		if err != nil {
+			return err
+		}
+
+		requestedGroup, err := dbRequestedGroup.ToAPI(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		matches = authGroupDefinitionsEqual(*existingGroup, *requestedGroup)
+
+		// Roll back the transaction so that the requested group isn't persisted.
+		return errAuthGroupPreview
 	})
-	if err != nil {
-		return response.SmartError(err)
+	if err != nil && !errors.Is(err, errAuthGroupPreview) {
+		return nil, err
+	}
+
+	if existingGroup != nil && !matches {
+		return nil, api.StatusErrorf(http.StatusConflict, "Group %q already exists with a different definition", group.Name)
 	}
 
-	return response.SyncResponseETag(true, *apiGroup, *apiGroup)
+	return existingGroup, nil
 }
 
-// swagger:operation PUT /1.0/auth/groups/{groupName} auth_groups auth_group_put
+// authGroupDefinitionsEqual returns whether the given groups have the same description, permissions, parents, roles,
+// enabled and template states. Lists are compared as sets, so ordering and duplicates are not significant.
+func authGroupDefinitionsEqual(a api.AuthGroup, b api.AuthGroup) bool {
+	// Groups are enabled and aren't templates unless stated otherwise.
+	aEnabled := a.Enabled == nil || *a.Enabled
+	bEnabled := b.Enabled == nil || *b.Enabled
+	aTemplate := a.Template != nil && *a.Template
+	bTemplate := b.Template != nil && *b.Template
+	if a.Description != b.Description || aEnabled != bEnabled || aTemplate != bTemplate {
+		return false
+	}
+
+	return authGroupSameElements(a.Permissions, b.Permissions) && authGroupSameElements(a.Parents, b.Parents) && authGroupSameElements(a.Roles, b.Roles)
+}
+
+// authGroupSameElements returns whether the given slices contain the same elements, regardless of ordering and
+// duplicates.
+func authGroupSameElements[T comparable](a []T, b []T) bool {
+	for _, element := range a {
+		if !shared.ValueInSlice(element, b) {
+			return false
+		}
+	}
+
+	for _, element := range b {
+		if !shared.ValueInSlice(element, a) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// setAuthGroupTemplate sets whether the group with the given ID and name is a template. A group can only become a
+// template if it has no members, as template groups can't have members.
+func setAuthGroupTemplate(ctx context.Context, tx *sql.Tx, groupID int, groupName string, template bool) error {
+	if template {
+		identities, err := dbCluster.GetIdentitiesByAuthGroupID(ctx, tx, groupID)
+		if err != nil {
+			return err
+		}
+
+		idpGroups, err := dbCluster.GetIdentityProviderGroupsByGroupID(ctx, tx, groupID)
+		if err != nil {
+			return err
+		}
+
+		if len(identities) > 0 || len(idpGroups) > 0 {
+			return api.StatusErrorf(http.StatusBadRequest, "Group %q cannot be a template as it has members", groupName)
+		}
+	}
+
+	return dbCluster.SetAuthGroupTemplate(ctx, tx, groupID, template)
+}
+
+// errAuthGroupPreview is returned from the transaction of a group preview to roll it back.
+var errAuthGroupPreview = errors.New("Group preview")
+
+// swagger:operation POST /1.0/auth/groups/preview auth_groups auth_groups_preview_post
 //
-//	Update the authorization group
+//	Preview a new authorization group
 //
-//	Replaces the editable fields of an authorization group
+//	Validates a group creation request and returns the permissions that the group would grant, without creating it.
+//	These include the permissions granted by the roles of the group and the permissions inherited from its parents.
 //
 //	---
 //	consumes:
@@ -458,91 +950,131 @@ func getAuthGroup(d *Daemon, r *http.Request) response.Response {
 //	parameters:
 //	  - in: body
 //	    name: group
-//	    description: Update request
+//	    description: Group request
+//	    required: true
 //	    schema:
-//	      $ref: "#/definitions/AuthGroupPut"
+//	      $ref: "#/definitions/AuthGroupsPost"
 //	responses:
 //	  "200":
-//	    $ref: "#/responses/EmptySyncResponse"
+//	    description: Permissions that the group would grant
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of permissions
+//	          items:
+//	            $ref: "#/definitions/Permission"
 //	  "400":
 //	    $ref: "#/responses/BadRequest"
 //	  "403":
 //	    $ref: "#/responses/Forbidden"
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
-func updateAuthGroup(d *Daemon, r *http.Request) response.Response {
-	groupName, err := url.PathUnescape(mux.Vars(r)["groupName"])
+func previewAuthGroup(d *Daemon, r *http.Request) response.Response {
+	var group api.AuthGroupsPost
+	err := json.NewDecoder(r.Body).Decode(&group)
+	if err != nil {
+		return response.BadRequest(fmt.Errorf("Invalid request body: %w", err))
+	}
+
+	err = validateGroupName(group.Name)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
-	var groupPut api.AuthGroupPut
-	err = json.NewDecoder(r.Body).Decode(&groupPut)
+	err = validatePermissions(group.Permissions)
 	if err != nil {
-		return response.BadRequest(fmt.Errorf("Invalid request body: %w", err))
+		return response.SmartError(err)
 	}
 
-	err = validatePermissions(groupPut.Permissions)
+	s := d.State()
+
+	err = authGroupPermissionsQuotaCheck(s, len(group.Permissions))
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	l := authGroupLogger(r, "preview", group.Name, group.Permissions)
+
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 	defer cancel()
 
-	s := d.State()
+	var permissions []api.Permission
 	err = s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
-		group, err := dbCluster.GetAuthGroup(ctx, tx.Tx(), groupName)
+		err := authGroupCaseConflictCheck(ctx, s, tx.Tx(), group.Name, "")
 		if err != nil {
 			return err
 		}
 
-		apiGroup, err := group.ToAPI(ctx, tx.Tx())
+		// Create the group as it would be created, so that its permissions are resolved in the same way as those of
+		// existing groups.
+		err = createAuthGroupTx(ctx, tx.Tx(), group, l)
 		if err != nil {
 			return err
 		}
 
-		err = util.EtagCheck(r, *apiGroup)
+		groupPermissions, err := getAuthGroupsPermissions(ctx, tx.Tx())
 		if err != nil {
 			return err
 		}
 
-		err = dbCluster.UpdateAuthGroup(ctx, tx.Tx(), groupName, dbCluster.AuthGroup{
-			Name:        groupName,
-			Description: groupPut.Description,
-		})
-		if err != nil {
-			return err
+		permissions = groupPermissions[group.Name]
+
+		// Roll back the transaction so that nothing is persisted.
+		return errAuthGroupPreview
+	})
+	if err != nil && !errors.Is(err, errAuthGroupPreview) {
+		return authGroupTxError(ctx, err)
+	}
+
+	// Remove the permissions that are granted more than once, for example by a role and by a parent.
+	uniquePermissions := make([]api.Permission, 0, len(permissions))
+	for _, permission := range permissions {
+		if !shared.ValueInSlice(permission, uniquePermissions) {
+			uniquePermissions = append(uniquePermissions, permission)
 		}
+	}
 
-		permissionIDs, err := upsertPermissions(ctx, tx.Tx(), groupPut.Permissions)
-		if err != nil {
-			return err
+	sort.Slice(uniquePermissions, func(i, j int) bool {
+		if uniquePermissions[i].EntityReference != uniquePermissions[j].EntityReference {
+			return uniquePermissions[i].EntityReference < uniquePermissions[j].EntityReference
 		}
 
-		err = dbCluster.SetAuthGroupPermissions(ctx, tx.Tx(), group.ID, permissionIDs)
-		if err != nil {
-			return err
+		if uniquePermissions[i].EntityType != uniquePermissions[j].EntityType {
+			return uniquePermissions[i].EntityType < uniquePermissions[j].EntityType
 		}
 
-		return nil
+		return uniquePermissions[i].Entitlement < uniquePermissions[j].Entitlement
 	})
-	if err != nil {
-		return response.SmartError(err)
-	}
 
-	// Send a lifecycle event for the group update
-	lc := lifecycle.AuthGroupUpdated.Event(groupName, request.CreateRequestor(r), nil)
-	s.Events.SendLifecycle(api.ProjectDefaultName, lc)
-
-	return response.EmptySyncResponse
+	return response.SyncResponse(true, uniquePermissions)
 }
 
-// swagger:operation PATCH /1.0/auth/groups/{groupName} auth_groups auth_group_patch
+// swagger:operation POST /1.0/auth/groups/import auth_groups auth_groups_import_post
 //
-//	Partially update the authorization group
+//	Import groups
 //
-//	Updates the editable fields of an authorization group
+//	Creates many authorization groups in a background operation. The groups are created in order, each in its own
+//	transaction, so that large imports aren't bound by the timeout of a single transaction. The parents of a group
+//	must either exist or come before it in the request.
+//
+//	The operation metadata reports the number of groups created so far (`groups_created`) out of the total
+//	(`groups_total`), along with their names (`created`). If a group fails to be created, the operation fails and the
+//	groups created before it are kept.
 //
 //	---
 //	consumes:
@@ -551,101 +1083,422 @@ func updateAuthGroup(d *Daemon, r *http.Request) response.Response {
 //	  - application/json
 //	parameters:
 //	  - in: body
-//	    name: group
-//	    description: Update request
+//	    name: groups
+//	    description: Groups to create
+//	    required: true
 //	    schema:
-//	      $ref: "#/definitions/AuthGroupPut"
+//	      $ref: "#/definitions/AuthGroupsImport"
 //	responses:
-//	  "200":
-//	    $ref: "#/responses/EmptySyncResponse"
+//	  "202":
+//	    $ref: "#/responses/Operation"
 //	  "400":
 //	    $ref: "#/responses/BadRequest"
 //	  "403":
 //	    $ref: "#/responses/Forbidden"
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
-func patchAuthGroup(d *Daemon, r *http.Request) response.Response {
-	groupName, err := url.PathUnescape(mux.Vars(r)["groupName"])
-	if err != nil {
-		return response.SmartError(err)
-	}
-
-	var groupPut api.AuthGroupPut
-	err = json.NewDecoder(r.Body).Decode(&groupPut)
+func importAuthGroups(d *Daemon, r *http.Request) response.Response {
+	var req api.AuthGroupsImport
+	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		return response.BadRequest(fmt.Errorf("Invalid request body: %w", err))
 	}
 
-	err = validatePermissions(groupPut.Permissions)
-	if err != nil {
-		return response.SmartError(err)
+	if len(req.Groups) == 0 {
+		return response.BadRequest(fmt.Errorf("No groups to import"))
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
-	defer cancel()
-
 	s := d.State()
-	err = s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
-		group, err := dbCluster.GetAuthGroup(ctx, tx.Tx(), groupName)
+
+	// Validate all the groups before creating any of them, so that invalid requests fail without side effects.
+	names := make(map[string]bool, len(req.Groups))
+	loggers := make([]logger.Logger, 0, len(req.Groups))
+	for _, group := range req.Groups {
+		err = validateGroupName(group.Name)
 		if err != nil {
-			return err
+			return response.SmartError(fmt.Errorf("Invalid group %q: %w", group.Name, err))
 		}
 
-		apiGroup, err := group.ToAPI(ctx, tx.Tx())
-		if err != nil {
-			return err
+		if names[group.Name] {
+			return response.BadRequest(fmt.Errorf("Group %q is given more than once", group.Name))
 		}
 
-		err = util.EtagCheck(r, *apiGroup)
+		names[group.Name] = true
+
+		err = validatePermissions(group.Permissions)
 		if err != nil {
-			return err
+			return response.SmartError(fmt.Errorf("Invalid group %q: %w", group.Name, err))
 		}
 
-		if groupPut.Description != "" {
-			err = dbCluster.UpdateAuthGroup(ctx, tx.Tx(), groupName, dbCluster.AuthGroup{
-				Name:        groupName,
-				Description: groupPut.Description,
-			})
-			if err != nil {
-				return err
-			}
+		err = authGroupPermissionsQuotaCheck(s, len(group.Permissions))
+		if err != nil {
+			return response.SmartError(fmt.Errorf("Invalid group %q: %w", group.Name, err))
 		}
 
-		newPermissions := make([]api.Permission, 0, len(groupPut.Permissions))
-		for _, permission := range groupPut.Permissions {
-			if !shared.ValueInSlice(permission, apiGroup.Permissions) {
-				newPermissions = append(newPermissions, permission)
+		loggers = append(loggers, authGroupLogger(r, "import", group.Name, group.Permissions))
+	}
+
+	requestor := request.CreateRequestor(r)
+
+	metadata := map[string]any{
+		"groups_created": 0,
+		"groups_total":   len(req.Groups),
+		"created":        []string{},
+	}
+
+	run := func(op *operations.Operation) error {
+		created := make([]string, 0, len(req.Groups))
+		for i, group := range req.Groups {
+			l := loggers[i]
+
+			err := authGroupImportOne(s, requestor, group, l)
+			if err != nil {
+				l.Warn("Failed importing group", logger.Ctx{"err": err, "created": len(created), "total": len(req.Groups)})
+				return fmt.Errorf("Failed creating group %q (%d of %d groups created): %w", group.Name, len(created), len(req.Groups), err)
+			}
+
+			l.Debug("Imported group")
+
+			lc := lifecycle.AuthGroupCreated.Event(group.Name, requestor, nil)
+			s.Events.SendLifecycle(api.ProjectDefaultName, lc)
+
+			created = append(created, group.Name)
+			metadata["groups_created"] = len(created)
+			metadata["created"] = created
+
+			err = op.UpdateMetadata(metadata)
+			if err != nil {
+				l.Warn("Failed updating import progress", logger.Ctx{"err": err})
 			}
 		}
 
-		permissionIDs, err := upsertPermissions(ctx, tx.Tx(), newPermissions)
+		return nil
+	}
+
+	resources := map[string][]api.URL{}
+	resources["auth_groups"] = make([]api.URL, 0, len(req.Groups))
+	for _, group := range req.Groups {
+		resources["auth_groups"] = append(resources["auth_groups"], *entity.AuthGroupURL(group.Name))
+	}
+
+	op, err := operations.OperationCreate(s, "", operations.OperationClassTask, operationtype.AuthGroupsImport, resources, metadata, run, nil, nil, r)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// authGroupImportOne creates a group of an import in its own transaction, with the same checks and audit record as a
+// group created on its own.
+func authGroupImportOne(s *state.State, requestor *api.EventLifecycleRequestor, group api.AuthGroupsPost, l logger.Logger) error {
+	ctx, cancel := context.WithTimeout(s.ShutdownCtx, 10*time.Second)
+	defer cancel()
+
+	return s.DB.Cluster.TransactionRetry(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+		err := authGroupCaseConflictCheck(ctx, s, tx.Tx(), group.Name, "")
 		if err != nil {
 			return err
 		}
 
-		err = dbCluster.SetAuthGroupPermissions(ctx, tx.Tx(), group.ID, permissionIDs)
+		err = createAuthGroupTx(ctx, tx.Tx(), group, l)
+		if err != nil {
+			return err
+		}
+
+		apiGroup, err := authAuditGroupState(ctx, tx.Tx(), group.Name)
+		if err != nil {
+			return err
+		}
+
+		return authAuditRecord(ctx, tx.Tx(), requestor, api.AuthAuditObjectTypeGroup, group.Name, api.AuthAuditActionCreated, nil, apiGroup)
+	})
+}
+
+// swagger:operation GET /1.0/auth/groups/{groupName} auth_groups auth_group_get
+//
+//	Get the authorization group
+//
+//	Gets a specific authorization group.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: identity_filter
+//	    description: Filter applied to the identities of the group
+//	    type: string
+//	    example: name eq svc-.*
+//	responses:
+//	  "200":
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          $ref: "#/definitions/AuthGroup"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func getAuthGroup(d *Daemon, r *http.Request) response.Response {
+	groupName, err := url.PathUnescape(mux.Vars(r)["groupName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	identityClauses, err := filter.Parse(request.QueryParam(r, "identity_filter"), filter.QueryOperatorSet())
+	if err != nil {
+		return response.BadRequest(fmt.Errorf("Failed to filter group identities: %w", err))
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var apiGroup *api.AuthGroup
+	s := d.State()
+	err = s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+		name, err := authGroupName(ctx, s, tx.Tx(), groupName)
+		if err != nil {
+			return err
+		}
+
+		group, err := dbCluster.GetAuthGroup(ctx, tx.Tx(), name)
+		if err != nil {
+			return err
+		}
+
+		apiGroup, err = group.ToAPI(ctx, tx.Tx())
 		if err != nil {
 			return err
 		}
 
 		return nil
 	})
+	if err != nil {
+		return authGroupTxError(ctx, err)
+	}
+
+	// The ETag is computed from the unfiltered group so that it can be used for updating the group.
+	etag := *apiGroup
+	apiGroup.Identities, err = filterAuthGroupIdentities(apiGroup.Identities, identityClauses)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
-	// Send a lifecycle event for the group update
-	lc := lifecycle.AuthGroupUpdated.Event(groupName, request.CreateRequestor(r), nil)
-	s.Events.SendLifecycle(api.ProjectDefaultName, lc)
+	return response.SyncResponseETag(true, *apiGroup, etag)
+}
 
-	return response.EmptySyncResponse
+// swagger:operation GET /1.0/auth/groups/{groupName}/diff auth_groups auth_group_diff_get
+//
+//	Compare the authorization group with another group
+//
+//	Returns the permissions that only the group has, that only the other group has, and that both groups have, along
+//	with the differences between their descriptions, identities and identity provider groups. Permissions are compared
+//	on their canonical entity references, and don't include inherited permissions nor the permissions of roles.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: against
+//	    description: Name of the group to compare against
+//	    type: string
+//	    example: operators-old
+//	responses:
+//	  "200":
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          $ref: "#/definitions/AuthGroupDiff"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func getAuthGroupDiff(d *Daemon, r *http.Request) response.Response {
+	groupName, err := url.PathUnescape(mux.Vars(r)["groupName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	against := request.QueryParam(r, "against")
+	if against == "" {
+		return response.BadRequest(fmt.Errorf("The `against` query parameter is required"))
+	}
+
+	s := d.State()
+
+	// The group that is compared against must be visible too.
+	err = s.Authorizer.CheckPermission(r.Context(), r, entity.AuthGroupURL(against), auth.EntitlementCanView)
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusForbidden) {
+			return response.NotFound(fmt.Errorf("Authorization group %q not found", against))
+		}
+
+		return response.SmartError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var apiGroup *api.AuthGroup
+	var apiAgainst *api.AuthGroup
+	err = s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+		group, err := dbCluster.GetAuthGroup(ctx, tx.Tx(), groupName)
+		if err != nil {
+			return err
+		}
+
+		apiGroup, err = group.ToAPI(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		againstGroup, err := dbCluster.GetAuthGroup(ctx, tx.Tx(), against)
+		if err != nil {
+			return err
+		}
+
+		apiAgainst, err = againstGroup.ToAPI(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return authGroupTxError(ctx, err)
+	}
+
+	return response.SyncResponse(true, authGroupDiff(*apiGroup, *apiAgainst))
 }
 
-// swagger:operation POST /1.0/auth/groups/{groupName} auth_groups auth_group_post
+// authGroupDiff returns the difference between two groups. Permissions are compared on their canonical entity
+// references, identities on their authentication method and identifier.
+func authGroupDiff(group api.AuthGroup, against api.AuthGroup) api.AuthGroupDiff {
+	diff := api.AuthGroupDiff{
+		Group:   group.Name,
+		Against: against.Name,
+	}
+
+	if group.Description != against.Description {
+		diff.Description = &api.AuthGroupDiffDescription{
+			Group:   group.Description,
+			Against: against.Description,
+		}
+	}
+
+	normalizePermissions := func(permissions []api.Permission) []api.Permission {
+		normalized := make([]api.Permission, 0, len(permissions))
+		for _, permission := range permissions {
+			entityURL, err := canonicalEntityReference(permission.EntityReference)
+			if err == nil {
+				permission.EntityReference = entityURL.String()
+			}
+
+			normalized = append(normalized, permission)
+		}
+
+		return normalized
+	}
+
+	diff.Permissions.OnlyInGroup, diff.Permissions.OnlyInAgainst, diff.Permissions.InBoth = authGroupSetDifference(normalizePermissions(group.Permissions), normalizePermissions(against.Permissions), func(permission api.Permission) string {
+		return permission.EntityReference + " " + permission.EntityType + " " + permission.Entitlement
+	})
+
+	diff.Identities.OnlyInGroup, diff.Identities.OnlyInAgainst, diff.Identities.InBoth = authGroupSetDifference(group.Identities, against.Identities, func(identity api.Identity) string {
+		return identity.AuthenticationMethod + "/" + identity.Identifier
+	})
+
+	diff.IdentityProviderGroups.OnlyInGroup, diff.IdentityProviderGroups.OnlyInAgainst, diff.IdentityProviderGroups.InBoth = authGroupSetDifference(group.IdentityProviderGroups, against.IdentityProviderGroups, func(name string) string {
+		return name
+	})
+
+	return diff
+}
+
+// authGroupSetDifference returns the elements that are only in a, only in b, and in both, compared on the given key and
+// sorted by it. Duplicates are removed.
+func authGroupSetDifference[T any](a []T, b []T, key func(T) string) (onlyInA []T, onlyInB []T, inBoth []T) {
+	index := func(elements []T) map[string]T {
+		m := make(map[string]T, len(elements))
+		for _, element := range elements {
+			m[key(element)] = element
+		}
+
+		return m
+	}
+
+	sorted := func(m map[string]T) []T {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		elements := make([]T, 0, len(keys))
+		for _, k := range keys {
+			elements = append(elements, m[k])
+		}
+
+		return elements
+	}
+
+	indexA := index(a)
+	indexB := index(b)
+	both := make(map[string]T)
+	for k, element := range indexA {
+		_, ok := indexB[k]
+		if ok {
+			both[k] = element
+			delete(indexA, k)
+			delete(indexB, k)
+		}
+	}
+
+	return sorted(indexA), sorted(indexB), sorted(both)
+}
+
+// swagger:operation PUT /1.0/auth/groups/{groupName} auth_groups auth_group_put
 //
-//	Rename the authorization group
+//	Update the authorization group
 //
-//	Renames the authorization group
+//	Replaces the editable fields of an authorization group
 //
 //	---
 //	consumes:
@@ -653,11 +1506,16 @@ func patchAuthGroup(d *Daemon, r *http.Request) response.Response {
 //	produces:
 //	  - application/json
 //	parameters:
+//	  - in: query
+//	    name: force
+//	    description: Apply the change even if it removes the last server administrator
+//	    type: integer
+//	    example: 1
 //	  - in: body
 //	    name: group
 //	    description: Update request
 //	    schema:
-//	      $ref: "#/definitions/AuthGroupPost"
+//	      $ref: "#/definitions/AuthGroupPut"
 //	responses:
 //	  "200":
 //	    $ref: "#/responses/EmptySyncResponse"
@@ -665,131 +1523,1201 @@ func patchAuthGroup(d *Daemon, r *http.Request) response.Response {
 //	    $ref: "#/responses/BadRequest"
 //	  "403":
 //	    $ref: "#/responses/Forbidden"
+//	  "409":
+//	    description: The permissions of the group differ from the given permissions base
+//	    schema:
+//	      $ref: "#/definitions/AuthGroupPermissionsConflict"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
+//	  "428":
+//	    description: The If-Match header is required by core.etag_required_for_auth but was not set
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
-func renameAuthGroup(d *Daemon, r *http.Request) response.Response {
+func updateAuthGroup(d *Daemon, r *http.Request) response.Response {
 	groupName, err := url.PathUnescape(mux.Vars(r)["groupName"])
 	if err != nil {
 		return response.SmartError(err)
 	}
 
-	var groupPost api.AuthGroupPost
-	err = json.NewDecoder(r.Body).Decode(&groupPost)
-	if err != nil {
-		return response.BadRequest(fmt.Errorf("Invalid request body: %w", err))
+	err = authGroupEtagRequiredCheck(d.State(), r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	var groupPut api.AuthGroupPut
+	err = json.NewDecoder(r.Body).Decode(&groupPut)
+	if err != nil {
+		return response.BadRequest(fmt.Errorf("Invalid request body: %w", err))
+	}
+
+	err = validatePermissions(groupPut.Permissions)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	s := d.State()
+
+	err = authGroupPermissionsQuotaCheck(s, len(groupPut.Permissions))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	l := authGroupLogger(r, "update", groupName, groupPut.Permissions)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	force := request.QueryParam(r, "force") == "1"
+
+	var conflict *api.AuthGroupPermissionsConflict
+	err = s.DB.Cluster.TransactionRetry(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+		group, err := dbCluster.GetAuthGroup(ctx, tx.Tx(), groupName)
+		if err != nil {
+			return err
+		}
+
+		adminBefore := false
+		if !force {
+			adminBefore, err = authServerAdminExists(ctx, tx.Tx())
+			if err != nil {
+				return err
+			}
+		}
+
+		apiGroup, err := group.ToAPI(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		err = util.EtagCheck(r, *apiGroup)
+		if err != nil {
+			return err
+		}
+
+		err = authGroupDelegationCheck(s, r, apiGroup, groupPut.Permissions, groupPut.Parents, groupPut.Roles)
+		if err != nil {
+			return err
+		}
+
+		// If the client told us which permissions it based its update on, refuse to apply the update if the
+		// permissions of the group have since changed.
+		if groupPut.PermissionsBase != nil {
+			conflict = authGroupPermissionsConflict(groupPut.PermissionsBase, apiGroup.Permissions, groupPut.Permissions)
+			if conflict != nil {
+				return api.StatusErrorf(http.StatusConflict, "Permissions of group %q have changed since the given permissions base", groupName)
+			}
+		}
+
+		err = dbCluster.UpdateAuthGroup(ctx, tx.Tx(), groupName, dbCluster.AuthGroup{
+			Name:        groupName,
+			Description: groupPut.Description,
+		})
+		if err != nil {
+			return err
+		}
+
+		permissionIDs, err := upsertPermissions(ctx, tx.Tx(), groupPut.Permissions, l)
+		if err != nil {
+			return err
+		}
+
+		err = dbCluster.SetAuthGroupPermissions(ctx, tx.Tx(), group.ID, permissionIDs)
+		if err != nil {
+			return err
+		}
+
+		parentIDs, err := authGroupParentIDs(ctx, tx.Tx(), group.ID, groupName, groupPut.Parents)
+		if err != nil {
+			return err
+		}
+
+		err = dbCluster.SetAuthGroupParents(ctx, tx.Tx(), group.ID, parentIDs)
+		if err != nil {
+			return err
+		}
+
+		roles, err := authGroupRoles(ctx, tx.Tx(), groupPut.Roles)
+		if err != nil {
+			return err
+		}
+
+		err = dbCluster.SetAuthGroupRoles(ctx, tx.Tx(), group.ID, roles)
+		if err != nil {
+			return err
+		}
+
+		if groupPut.Enabled != nil {
+			err = dbCluster.SetAuthGroupEnabled(ctx, tx.Tx(), group.ID, *groupPut.Enabled)
+			if err != nil {
+				return err
+			}
+		}
+
+		if groupPut.Template != nil {
+			err = setAuthGroupTemplate(ctx, tx.Tx(), group.ID, groupName, *groupPut.Template)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = authGroupLockoutCheck(ctx, tx.Tx(), adminBefore)
+		if err != nil {
+			return err
+		}
+
+		after, err := authAuditGroupState(ctx, tx.Tx(), groupName)
+		if err != nil {
+			return err
+		}
+
+		return authAuditRecord(ctx, tx.Tx(), request.CreateRequestor(r), api.AuthAuditObjectTypeGroup, groupName, api.AuthAuditActionUpdated, apiGroup, after)
+	})
+	if err != nil {
+		l.Warn("Failed updating group", logger.Ctx{"err": err})
+
+		if conflict != nil {
+			return response.ErrorResponseMetadata(http.StatusConflict, err.Error(), conflict)
+		}
+
+		return authGroupTxError(ctx, err)
+	}
+
+	l.Debug("Updated group")
+
+	// The identity cache holds the permissions of each group, so it must be updated when they change.
+	notifyIdentityCacheRefresh(s)
+
+	// Send a lifecycle event for the group update
+	lc := lifecycle.AuthGroupUpdated.Event(groupName, request.CreateRequestor(r), nil)
+	s.Events.SendLifecycle(api.ProjectDefaultName, lc)
+
+	return response.EmptySyncResponse
+}
+
+// authGroupPermissionsConflict compares the permissions that a client based its update on with the current
+// permissions of a group. If they differ, it returns a three-way diff of the base, current, and requested permissions.
+// Otherwise it returns nil. Permissions are compared as sets, so ordering and duplicates are not significant.
+func authGroupPermissionsConflict(base []api.Permission, current []api.Permission, requested []api.Permission) *api.AuthGroupPermissionsConflict {
+	// difference returns the permissions in a that are not in b.
+	difference := func(a []api.Permission, b []api.Permission) []api.Permission {
+		diff := []api.Permission{}
+		for _, permission := range a {
+			if !shared.ValueInSlice(permission, b) && !shared.ValueInSlice(permission, diff) {
+				diff = append(diff, permission)
+			}
+		}
+
+		return diff
+	}
+
+	currentAdded := difference(current, base)
+	currentRemoved := difference(base, current)
+	if len(currentAdded) == 0 && len(currentRemoved) == 0 {
+		return nil
+	}
+
+	return &api.AuthGroupPermissionsConflict{
+		Base:             base,
+		Current:          current,
+		Requested:        requested,
+		CurrentAdded:     currentAdded,
+		CurrentRemoved:   currentRemoved,
+		RequestedAdded:   difference(requested, base),
+		RequestedRemoved: difference(base, requested),
+	}
+}
+
+// swagger:operation PATCH /1.0/auth/groups/{groupName} auth_groups auth_group_patch
+//
+//	Partially update the authorization group
+//
+//	Updates the editable fields of an authorization group
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: force
+//	    description: Apply the change even if it removes the last server administrator
+//	    type: integer
+//	    example: 1
+//	  - in: body
+//	    name: group
+//	    description: Update request
+//	    schema:
+//	      $ref: "#/definitions/AuthGroupPut"
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "409":
+//	    description: The permissions of the group differ from the given permissions base
+//	    schema:
+//	      $ref: "#/definitions/AuthGroupPermissionsConflict"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
+//	  "428":
+//	    description: The If-Match header is required by core.etag_required_for_auth but was not set
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func patchAuthGroup(d *Daemon, r *http.Request) response.Response {
+	groupName, err := url.PathUnescape(mux.Vars(r)["groupName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = authGroupEtagRequiredCheck(d.State(), r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	var groupPut api.AuthGroupPut
+	err = json.NewDecoder(r.Body).Decode(&groupPut)
+	if err != nil {
+		return response.BadRequest(fmt.Errorf("Invalid request body: %w", err))
+	}
+
+	err = validatePermissions(groupPut.Permissions)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	l := authGroupLogger(r, "patch", groupName, groupPut.Permissions)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	force := request.QueryParam(r, "force") == "1"
+
+	s := d.State()
+	var conflict *api.AuthGroupPermissionsConflict
+	err = s.DB.Cluster.TransactionRetry(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+		group, err := dbCluster.GetAuthGroup(ctx, tx.Tx(), groupName)
+		if err != nil {
+			return err
+		}
+
+		apiGroup, err := group.ToAPI(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		err = util.EtagCheck(r, *apiGroup)
+		if err != nil {
+			return err
+		}
+
+		err = authGroupDelegationCheck(s, r, apiGroup, groupPut.Permissions, groupPut.Parents, groupPut.Roles)
+		if err != nil {
+			return err
+		}
+
+		// If the client told us which permissions it based its update on, refuse to apply the update if the
+		// permissions of the group have since changed.
+		if groupPut.PermissionsBase != nil {
+			conflict = authGroupPermissionsConflict(groupPut.PermissionsBase, apiGroup.Permissions, groupPut.Permissions)
+			if conflict != nil {
+				return api.StatusErrorf(http.StatusConflict, "Permissions of group %q have changed since the given permissions base", groupName)
+			}
+		}
+
+		adminBefore := false
+		if !force {
+			adminBefore, err = authServerAdminExists(ctx, tx.Tx())
+			if err != nil {
+				return err
+			}
+		}
+
+		if groupPut.Description != "" {
+			err = dbCluster.UpdateAuthGroup(ctx, tx.Tx(), groupName, dbCluster.AuthGroup{
+				Name:        groupName,
+				Description: groupPut.Description,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		newPermissions := make([]api.Permission, 0, len(groupPut.Permissions))
+		for _, permission := range groupPut.Permissions {
+			if !shared.ValueInSlice(permission, apiGroup.Permissions) {
+				newPermissions = append(newPermissions, permission)
+			}
+		}
+
+		err = authGroupPermissionsQuotaCheck(s, len(newPermissions))
+		if err != nil {
+			return err
+		}
+
+		permissionIDs, err := upsertPermissions(ctx, tx.Tx(), newPermissions, l)
+		if err != nil {
+			return err
+		}
+
+		err = dbCluster.SetAuthGroupPermissions(ctx, tx.Tx(), group.ID, permissionIDs)
+		if err != nil {
+			return err
+		}
+
+		if groupPut.Parents != nil {
+			parentIDs, err := authGroupParentIDs(ctx, tx.Tx(), group.ID, groupName, groupPut.Parents)
+			if err != nil {
+				return err
+			}
+
+			err = dbCluster.SetAuthGroupParents(ctx, tx.Tx(), group.ID, parentIDs)
+			if err != nil {
+				return err
+			}
+		}
+
+		if groupPut.Roles != nil {
+			roles, err := authGroupRoles(ctx, tx.Tx(), groupPut.Roles)
+			if err != nil {
+				return err
+			}
+
+			err = dbCluster.SetAuthGroupRoles(ctx, tx.Tx(), group.ID, roles)
+			if err != nil {
+				return err
+			}
+		}
+
+		if groupPut.Enabled != nil {
+			err = dbCluster.SetAuthGroupEnabled(ctx, tx.Tx(), group.ID, *groupPut.Enabled)
+			if err != nil {
+				return err
+			}
+		}
+
+		if groupPut.Template != nil {
+			err = setAuthGroupTemplate(ctx, tx.Tx(), group.ID, groupName, *groupPut.Template)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = authGroupLockoutCheck(ctx, tx.Tx(), adminBefore)
+		if err != nil {
+			return err
+		}
+
+		after, err := authAuditGroupState(ctx, tx.Tx(), groupName)
+		if err != nil {
+			return err
+		}
+
+		return authAuditRecord(ctx, tx.Tx(), request.CreateRequestor(r), api.AuthAuditObjectTypeGroup, groupName, api.AuthAuditActionUpdated, apiGroup, after)
+	})
+	if err != nil {
+		l.Warn("Failed patching group", logger.Ctx{"err": err})
+
+		if conflict != nil {
+			return response.ErrorResponseMetadata(http.StatusConflict, err.Error(), conflict)
+		}
+
+		return authGroupTxError(ctx, err)
+	}
+
+	l.Debug("Patched group")
+
+	// The identity cache holds the permissions of each group, so it must be updated when they change.
+	notifyIdentityCacheRefresh(s)
+
+	// Send a lifecycle event for the group update
+	lc := lifecycle.AuthGroupUpdated.Event(groupName, request.CreateRequestor(r), nil)
+	s.Events.SendLifecycle(api.ProjectDefaultName, lc)
+
+	return response.EmptySyncResponse
+}
+
+// swagger:operation POST /1.0/auth/groups/{groupName} auth_groups auth_group_post
+//
+//	Rename the authorization group
+//
+//	Renames the authorization group
+//
+//	The change is applied to the identity cache of other cluster members on a best-effort basis. If a member cannot be
+//	notified, the request still succeeds, a warning is raised and the member is notified again in the background.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: body
+//	    name: group
+//	    description: Update request
+//	    schema:
+//	      $ref: "#/definitions/AuthGroupPost"
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func renameAuthGroup(d *Daemon, r *http.Request) response.Response {
+	groupName, err := url.PathUnescape(mux.Vars(r)["groupName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	var groupPost api.AuthGroupPost
+	err = json.NewDecoder(r.Body).Decode(&groupPost)
+	if err != nil {
+		return response.BadRequest(fmt.Errorf("Invalid request body: %w", err))
+	}
+
+	err = validateGroupName(groupPost.Name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	l := authGroupLogger(r, "rename", groupName, nil).AddContext(logger.Ctx{"newName": groupPost.Name})
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	s := d.State()
+	err = s.DB.Cluster.TransactionRetry(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+		before, err := authAuditGroupState(ctx, tx.Tx(), groupName)
+		if err != nil {
+			return err
+		}
+
+		err = authGroupCaseConflictCheck(ctx, s, tx.Tx(), groupPost.Name, groupName)
+		if err != nil {
+			return err
+		}
+
+		// If leadership was lost while committing a previous attempt, the group may already have been renamed.
+		if before == nil && query.IsRetryAfterLeadershipLost(ctx) {
+			_, err = dbCluster.GetAuthGroup(ctx, tx.Tx(), groupPost.Name)
+			if err == nil {
+				return nil
+			}
+		}
+
+		err = dbCluster.RenameAuthGroup(ctx, tx.Tx(), groupName, groupPost.Name)
+		if err != nil {
+			return err
+		}
+
+		after, err := authAuditGroupState(ctx, tx.Tx(), groupPost.Name)
+		if err != nil {
+			return err
+		}
+
+		return authAuditRecord(ctx, tx.Tx(), request.CreateRequestor(r), api.AuthAuditObjectTypeGroup, groupPost.Name, api.AuthAuditActionRenamed, before, after)
+	})
+	if err != nil {
+		l.Warn("Failed renaming group", logger.Ctx{"err": err})
+		return authGroupTxError(ctx, err)
+	}
+
+	l.Debug("Renamed group")
+
+	// When a group is renamed we need to update the list of group names associated with each identity in the cache.
+	// When a group is created, no identities are a member of it yet, so the cache doesn't need to be updated.
+	notifyIdentityCacheRefresh(s)
+
+	// Send a lifecycle event for the group rename
+	lc := lifecycle.AuthGroupRenamed.Event(groupPost.Name, request.CreateRequestor(r), map[string]any{"old_name": groupName})
+	s.Events.SendLifecycle(api.ProjectDefaultName, lc)
+
+	return response.SyncResponseLocation(true, nil, entity.AuthGroupURL(groupPost.Name).String())
+}
+
+// authGroupMatchesFilter returns whether the group matches the given filter. Filters are matched against the name,
+// description and enabled state of the group only.
+func authGroupMatchesFilter(group dbCluster.AuthGroup, enabled bool, clauses *filter.ClauseSet) (bool, error) {
+	match, err := filter.Match(api.AuthGroup{
+		AuthGroupsPost: api.AuthGroupsPost{
+			AuthGroupPost: api.AuthGroupPost{Name: group.Name},
+			AuthGroupPut:  api.AuthGroupPut{Description: group.Description, Enabled: &enabled},
+		},
+	}, *clauses)
+	if err != nil {
+		return false, api.StatusErrorf(http.StatusBadRequest, "Failed to filter groups: %w", err)
+	}
+
+	return match, nil
+}
+
+// swagger:operation DELETE /1.0/auth/groups auth_groups auth_groups_delete
+//
+//	Delete the authorization groups matching a filter
+//
+//	Deletes all authorization groups matching the filter in a single transaction. Groups that match the filter but
+//	that the caller isn't allowed to delete are skipped and reported. The request must either set `confirm` to delete
+//	the groups, or `dry_run` to only return the groups that would be deleted.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: filter
+//	    description: Collection filter matching the groups to delete
+//	    type: string
+//	    example: name eq project-foo-.*
+//	  - in: query
+//	    name: confirm
+//	    description: Confirm the deletion of the matching groups
+//	    type: integer
+//	    example: 1
+//	  - in: query
+//	    name: dry_run
+//	    description: Return the groups that would be deleted without deleting them
+//	    type: integer
+//	    example: 1
+//	  - in: query
+//	    name: force
+//	    description: Apply the change even if it removes the last server administrator
+//	    type: integer
+//	    example: 1
+//	responses:
+//	  "200":
+//	    description: Deleted groups
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          $ref: "#/definitions/AuthGroupsDeleted"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func deleteAuthGroups(d *Daemon, r *http.Request) response.Response {
+	dryRun := request.QueryParam(r, "dry_run") == "1"
+	if !dryRun && request.QueryParam(r, "confirm") != "1" {
+		return response.BadRequest(fmt.Errorf("Deleting the groups matching a filter requires the confirm parameter (or dry_run to list them)"))
+	}
+
+	filterStr := request.QueryParam(r, "filter")
+	if filterStr == "" {
+		return response.BadRequest(fmt.Errorf("A filter is required to delete groups"))
+	}
+
+	clauses, err := filter.Parse(filterStr, filter.QueryOperatorSet())
+	if err != nil {
+		return response.BadRequest(fmt.Errorf("Failed to filter groups: %w", err))
+	}
+
+	force := request.QueryParam(r, "force") == "1"
+	s := d.State()
+
+	canView, err := authGroupPermissionChecker(s, r, auth.EntitlementCanViewGroups, auth.EntitlementCanView)
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Failed to get a permission checker: %w", err))
+	}
+
+	canDelete, err := authGroupPermissionChecker(s, r, auth.EntitlementCanDeleteGroups, auth.EntitlementCanDelete)
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Failed to get a permission checker: %w", err))
+	}
+
+	l := authGroupLogger(r, "bulk_delete", "", nil)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	var result api.AuthGroupsDeleted
+	err = s.DB.Cluster.TransactionRetry(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+		result = api.AuthGroupsDeleted{Deleted: []string{}, Skipped: []string{}}
+
+		groups, err := dbCluster.GetAuthGroups(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		groupsEnabled, err := dbCluster.GetAllAuthGroupsEnabled(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		for _, group := range groups {
+			groupURL := entity.AuthGroupURL(group.Name)
+
+			// Groups that the caller can't see are neither deleted nor reported.
+			if !canDelete(groupURL) && !canView(groupURL) {
+				continue
+			}
+
+			match, err := authGroupMatchesFilter(group, groupsEnabled[group.ID], clauses)
+			if err != nil {
+				return err
+			}
+
+			if !match {
+				continue
+			}
+
+			if !canDelete(groupURL) {
+				result.Skipped = append(result.Skipped, group.Name)
+				continue
+			}
+
+			result.Deleted = append(result.Deleted, group.Name)
+		}
+
+		if dryRun || len(result.Deleted) == 0 {
+			return nil
+		}
+
+		adminBefore := false
+		if !force {
+			adminBefore, err = authServerAdminExists(ctx, tx.Tx())
+			if err != nil {
+				return err
+			}
+		}
+
+		before := make(map[string]*api.AuthGroup, len(result.Deleted))
+		for _, groupName := range result.Deleted {
+			before[groupName], err = authAuditGroupState(ctx, tx.Tx(), groupName)
+			if err != nil {
+				return err
+			}
+
+			err = dbCluster.DeleteAuthGroup(ctx, tx.Tx(), groupName)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = authGroupLockoutCheck(ctx, tx.Tx(), adminBefore)
+		if err != nil {
+			return err
+		}
+
+		requestor := request.CreateRequestor(r)
+		for _, groupName := range result.Deleted {
+			err = authAuditRecord(ctx, tx.Tx(), requestor, api.AuthAuditObjectTypeGroup, groupName, api.AuthAuditActionDeleted, before[groupName], nil)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		l.Warn("Failed deleting groups", logger.Ctx{"filter": filterStr, "err": err})
+		return authGroupTxError(ctx, err)
+	}
+
+	if dryRun || len(result.Deleted) == 0 {
+		return response.SyncResponse(true, result)
+	}
+
+	l.Debug("Deleted groups", logger.Ctx{"filter": filterStr, "deleted": result.Deleted, "skipped": result.Skipped})
+
+	// The identity cache is refreshed once for all the deleted groups.
+	notifyIdentityCacheRefresh(s)
+
+	for _, groupName := range result.Deleted {
+		lc := lifecycle.AuthGroupDeleted.Event(groupName, request.CreateRequestor(r), nil)
+		s.Events.SendLifecycle(api.ProjectDefaultName, lc)
+	}
+
+	return response.SyncResponse(true, result)
+}
+
+// swagger:operation DELETE /1.0/auth/groups/{groupName} auth_groups auth_group_delete
+//
+//	Delete the authorization group
+//
+//	Deletes the authorization group
+//
+//	The change is applied to the identity cache of other cluster members on a best-effort basis. If a member cannot be
+//	notified, the request still succeeds, a warning is raised and the member is notified again in the background.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: force
+//	    description: Apply the change even if it removes the last server administrator
+//	    type: integer
+//	    example: 1
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func deleteAuthGroup(d *Daemon, r *http.Request) response.Response {
+	groupName, err := url.PathUnescape(mux.Vars(r)["groupName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	l := authGroupLogger(r, "delete", groupName, nil)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	force := request.QueryParam(r, "force") == "1"
+
+	s := d.State()
+	err = s.DB.Cluster.TransactionRetry(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+		var err error
+		adminBefore := false
+		if !force {
+			adminBefore, err = authServerAdminExists(ctx, tx.Tx())
+			if err != nil {
+				return err
+			}
+		}
+
+		before, err := authAuditGroupState(ctx, tx.Tx(), groupName)
+		if err != nil {
+			return err
+		}
+
+		err = dbCluster.DeleteAuthGroup(ctx, tx.Tx(), groupName)
+		if err != nil {
+			// If leadership was lost while committing a previous attempt, the group may already have been deleted.
+			if query.IsRetryAfterLeadershipLost(ctx) && api.StatusErrorCheck(err, http.StatusNotFound) {
+				return nil
+			}
+
+			return err
+		}
+
+		err = authGroupLockoutCheck(ctx, tx.Tx(), adminBefore)
+		if err != nil {
+			return err
+		}
+
+		return authAuditRecord(ctx, tx.Tx(), request.CreateRequestor(r), api.AuthAuditObjectTypeGroup, groupName, api.AuthAuditActionDeleted, before, nil)
+	})
+	if err != nil {
+		l.Warn("Failed deleting group", logger.Ctx{"err": err})
+		return authGroupTxError(ctx, err)
+	}
+
+	l.Debug("Deleted group")
+
+	// When a group is deleted we need to remove it from the list of groups names associated with each identity in the cache.
+	// (When a group is created, nobody is a member of it yet, so the cache doesn't need to be updated).
+	notifyIdentityCacheRefresh(s)
+
+	// Send a lifecycle event for the group deletion
+	lc := lifecycle.AuthGroupDeleted.Event(groupName, request.CreateRequestor(r), nil)
+	s.Events.SendLifecycle(api.ProjectDefaultName, lc)
+
+	return response.EmptySyncResponse
+}
+
+// authServerAdminExists returns whether any remote identity has the admin entitlement on the server. This is the case
+// if there is an enabled unrestricted client certificate, or if an enabled group grants the admin entitlement on the
+// server (directly, through one of its roles or through one of its ancestors) to an enabled identity or an identity
+// provider group.
+func authServerAdminExists(ctx context.Context, tx *sql.Tx) (bool, error) {
+	disabled, err := dbCluster.GetAllIdentitiesDisabled(ctx, tx)
+	if err != nil {
+		return false, err
+	}
+
+	unrestrictedType := dbCluster.IdentityType(api.IdentityTypeCertificateClientUnrestricted)
+	unrestricted, err := dbCluster.GetIdentitys(ctx, tx, dbCluster.IdentityFilter{Type: &unrestrictedType})
+	if err != nil {
+		return false, err
+	}
+
+	for _, id := range unrestricted {
+		_, ok := disabled[id.ID]
+		if !ok {
+			return true, nil
+		}
+	}
+
+	groups, err := dbCluster.GetAuthGroups(ctx, tx)
+	if err != nil {
+		return false, err
+	}
+
+	groupPermissions, err := getAuthGroupsPermissions(ctx, tx)
+	if err != nil {
+		return false, err
+	}
+
+	identitiesByGroupID, err := dbCluster.GetAllIdentitiesByAuthGroupIDs(ctx, tx)
+	if err != nil {
+		return false, err
+	}
+
+	idpGroupsByGroupID, err := dbCluster.GetAllIdentityProviderGroupsByGroupIDs(ctx, tx)
+	if err != nil {
+		return false, err
+	}
+
+	for _, group := range groups {
+		enabledIdentities := 0
+		for _, id := range identitiesByGroupID[group.ID] {
+			_, ok := disabled[id.ID]
+			if !ok {
+				enabledIdentities++
+			}
+		}
+
+		if enabledIdentities == 0 && len(idpGroupsByGroupID[group.ID]) == 0 {
+			continue
+		}
+
+		for _, permission := range groupPermissions[group.Name] {
+			if permission.EntityType == string(entity.TypeServer) && permission.Entitlement == string(auth.EntitlementServerAdmin) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// authGroupLockoutCheck returns a forbidden error if no remote identity has the admin entitlement on the server
+// anymore, although one had it before the change to the groups or identities (as given by adminBefore). This prevents
+// a change from locking all remote clients out of the server.
+func authGroupLockoutCheck(ctx context.Context, tx *sql.Tx, adminBefore bool) error {
+	if !adminBefore {
+		return nil
+	}
+
+	adminAfter, err := authServerAdminExists(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	if !adminAfter {
+		return api.StatusErrorf(http.StatusForbidden, "The change would leave no enabled identity with the admin entitlement on the server, locking all remote clients out (use force to apply it anyway)")
+	}
+
+	return nil
+}
+
+// authGroupPermissionChecker returns a permission checker for groups. If the caller has the given entitlement on the
+// server, it allows all groups. Otherwise it only allows the groups on which the caller has the given group
+// entitlement, so that the management of specific groups can be delegated.
+func authGroupPermissionChecker(s *state.State, r *http.Request, serverEntitlement auth.Entitlement, groupEntitlement auth.Entitlement) (auth.PermissionChecker, error) {
+	err := s.Authorizer.CheckPermission(r.Context(), r, entity.ServerURL(), serverEntitlement)
+	if err == nil {
+		return func(*api.URL) bool { return true }, nil
+	} else if !api.StatusErrorCheck(err, http.StatusForbidden) {
+		return nil, err
+	}
+
+	return s.Authorizer.GetPermissionChecker(r.Context(), r, groupEntitlement, entity.TypeAuthGroup)
+}
+
+// authGroupDelegationCheck returns a forbidden error if a caller that can edit the group, but doesn't have the
+// can_edit_groups entitlement on the server, requests a change granting more access than it has itself. Such callers
+// can only add permissions that they have, can only add parents that they can edit, and cannot change the roles of
+// the group. Parents and roles are left unchanged if nil.
+func authGroupDelegationCheck(s *state.State, r *http.Request, current *api.AuthGroup, permissions []api.Permission, parents []string, roles []api.AuthGroupRole) error {
+	err := s.Authorizer.CheckPermission(r.Context(), r, entity.ServerURL(), auth.EntitlementCanEditGroups)
+	if err == nil {
+		return nil
+	} else if !api.StatusErrorCheck(err, http.StatusForbidden) {
+		return err
+	}
+
+	for _, permission := range permissions {
+		if shared.ValueInSlice(permission, current.Permissions) {
+			continue
+		}
+
+		entityURL, err := url.Parse(permission.EntityReference)
+		if err != nil {
+			return api.StatusErrorf(http.StatusBadRequest, "Failed to parse entity reference %q: %v", permission.EntityReference, err)
+		}
+
+		err = s.Authorizer.CheckPermission(r.Context(), r, &api.URL{URL: *entityURL}, auth.Entitlement(permission.Entitlement))
+		if err != nil {
+			return api.StatusErrorf(http.StatusForbidden, "Cannot grant the %q entitlement on %q without having it", permission.Entitlement, permission.EntityReference)
+		}
+	}
+
+	for _, parent := range parents {
+		if shared.ValueInSlice(parent, current.Parents) {
+			continue
+		}
+
+		err = s.Authorizer.CheckPermission(r.Context(), r, entity.AuthGroupURL(parent), auth.EntitlementCanEdit)
+		if err != nil {
+			return api.StatusErrorf(http.StatusForbidden, "Cannot add group %q as a parent without permission to edit it", parent)
+		}
+	}
+
+	if roles != nil {
+		changed := len(roles) != len(current.Roles)
+		for _, role := range roles {
+			if !shared.ValueInSlice(role, current.Roles) {
+				changed = true
+			}
+		}
+
+		if changed {
+			return api.StatusErrorf(http.StatusForbidden, "Changing the roles of a group requires the %q entitlement on the server", auth.EntitlementCanEditGroups)
+		}
+	}
+
+	return nil
+}
+
+// authGroupEtagRequiredCheck returns an error if core.etag_required_for_auth is enabled and the request does not set
+// the If-Match header. This prevents unconditional updates from overwriting concurrent changes to a group.
+func authGroupEtagRequiredCheck(s *state.State, r *http.Request) error {
+	if s.GlobalConfig.EtagRequiredForAuth() && r.Header.Get("If-Match") == "" {
+		return api.StatusErrorf(http.StatusPreconditionRequired, "The If-Match header is required to update authorization groups")
 	}
 
-	err = validateGroupName(groupPost.Name)
+	return nil
+}
+
+// authGroupPermissionsQuotaCheck returns an error if a group with the given number of permissions would exceed
+// core.auth_groups_max_permissions. Groups with very many permissions slow down every listing that includes them.
+func authGroupPermissionsQuotaCheck(s *state.State, count int) error {
+	maxPermissions := s.GlobalConfig.AuthGroupsMaxPermissions()
+	if maxPermissions > 0 && int64(count) > maxPermissions {
+		return api.StatusErrorf(http.StatusBadRequest, "Group would have %d permissions, which exceeds the maximum of %d (consider granting the entitlements on a parent entity or through a role)", count, maxPermissions)
+	}
+
+	return nil
+}
+
+// authGroupName returns the name under which the group with the given name is stored. When group names are
+// case-insensitive and there is no group with the exact name, the name of a group whose name only differs in case is
+// returned. Otherwise the given name is returned as is.
+func authGroupName(ctx context.Context, s *state.State, tx *sql.Tx, name string) (string, error) {
+	if !s.GlobalConfig.AuthCaseInsensitiveGroupNames() {
+		return name, nil
+	}
+
+	names, err := dbCluster.GetAuthGroupNamesFold(ctx, tx, name)
 	if err != nil {
-		return response.SmartError(err)
+		return "", err
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
-	defer cancel()
+	if len(names) == 0 || shared.ValueInSlice(name, names) {
+		return name, nil
+	}
 
-	s := d.State()
-	err = s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
-		err = dbCluster.RenameAuthGroup(ctx, tx.Tx(), groupName, groupPost.Name)
-		if err != nil {
-			return err
-		}
+	return names[0], nil
+}
 
+// authGroupCaseConflictCheck returns an api.StatusError with http.StatusConflict if group names are case-insensitive
+// and a group other than the current one has a name that only differs in case from the given name. The current group
+// is the group being renamed, if any, so that a group can be renamed to a different case of its own name. Groups with
+// the exact name are left to be reported by the database.
+func authGroupCaseConflictCheck(ctx context.Context, s *state.State, tx *sql.Tx, name string, current string) error {
+	if !s.GlobalConfig.AuthCaseInsensitiveGroupNames() {
 		return nil
-	})
-	if err != nil {
-		return response.SmartError(err)
 	}
 
-	// Notify other cluster members to update their identity cache.
-	notifier, err := cluster.NewNotifier(s, s.Endpoints.NetworkCert(), s.ServerCert(), cluster.NotifyAlive)
+	names, err := dbCluster.GetAuthGroupNamesFold(ctx, tx, name)
 	if err != nil {
-		return response.SmartError(err)
+		return err
 	}
 
-	err = notifier(func(client lxd.InstanceServer) error {
-		_, _, err := client.RawQuery(http.MethodPost, "/internal/identity-cache-refresh", nil, "")
-		return err
-	})
-	if err != nil {
-		return response.SmartError(err)
+	for _, groupName := range names {
+		if groupName != name && groupName != current {
+			return api.StatusErrorf(http.StatusConflict, "Group %q already exists as %q (group names are case-insensitive)", name, groupName)
+		}
 	}
 
-	// When a group is renamed we need to update the list of group names associated with each identity in the cache.
-	// When a group is otherwise modified, the name is unchanged, so the cache doesn't need to be updated.
-	// When a group is created, no identities are a member of it yet, so the cache doesn't need to be updated.
-	s.UpdateIdentityCache()
+	return nil
+}
 
-	// Send a lifecycle event for the group rename
-	lc := lifecycle.AuthGroupRenamed.Event(groupPost.Name, request.CreateRequestor(r), map[string]any{"old_name": groupName})
-	s.Events.SendLifecycle(api.ProjectDefaultName, lc)
+// authGroupTxRetryAfter is how long clients are asked to wait before retrying a group request whose database
+// transaction timed out.
+const authGroupTxRetryAfter = 5 * time.Second
 
-	return response.SyncResponseLocation(true, nil, entity.AuthGroupURL(groupPost.Name).String())
+// authGroupTxError returns the response for an error returned by the database transaction of a group request.
+// If the transaction exceeded the deadline of the request, for example on a busy cluster, a 503 with a Retry-After
+// header is returned so that clients back off instead of retrying immediately.
+func authGroupTxError(ctx context.Context, err error) response.Response {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return response.UnavailableRetryAfter(fmt.Errorf("Timed out waiting for the database: %w", err), authGroupTxRetryAfter)
+	}
+
+	return response.SmartError(err)
 }
 
-// swagger:operation DELETE /1.0/auth/groups/{groupName} auth_groups auth_group_delete
-//
-//	Delete the authorization group
-//
-//	Deletes the authorization group
-//
-//	---
-//	produces:
-//	  - application/json
-//	responses:
-//	  "200":
-//	    $ref: "#/responses/EmptySyncResponse"
-//	  "400":
-//	    $ref: "#/responses/BadRequest"
-//	  "403":
-//	    $ref: "#/responses/Forbidden"
-//	  "500":
-//	    $ref: "#/responses/InternalServerError"
-func deleteAuthGroup(d *Daemon, r *http.Request) response.Response {
-	groupName, err := url.PathUnescape(mux.Vars(r)["groupName"])
-	if err != nil {
-		return response.SmartError(err)
+// authGroupLogger returns a logger for a request changing a group, with the operation, the group name, the identity
+// of the requestor and, if the request sets permissions, their count as context.
+func authGroupLogger(r *http.Request, operation string, groupName string, permissions []api.Permission) logger.Logger {
+	ctx := logger.Ctx{"operation": operation, "group": groupName}
+
+	requestor := request.CreateRequestor(r)
+	ctx["username"] = requestor.Username
+	ctx["protocol"] = requestor.Protocol
+	ctx["address"] = requestor.Address
+
+	if permissions != nil {
+		ctx["permissions"] = len(permissions)
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
-	defer cancel()
+	return logger.AddContext(ctx)
+}
 
-	s := d.State()
-	err = s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
-		return dbCluster.DeleteAuthGroup(ctx, tx.Tx(), groupName)
-	})
-	if err != nil {
-		return response.SmartError(err)
+// authGroupPermissionScope returns the broadest scope of the given permissions: "server" if any of them applies to
+// the server, otherwise "project" if any of them applies to a project, otherwise "entity" if there are any, and
+// "none" otherwise.
+func authGroupPermissionScope(permissions []dbCluster.Permission) string {
+	scope := "none"
+	for _, permission := range permissions {
+		switch entity.Type(permission.EntityType) {
+		case entity.TypeServer:
+			return "server"
+		case entity.TypeProject:
+			scope = "project"
+		default:
+			if scope == "none" {
+				scope = "entity"
+			}
+		}
+	}
+
+	return scope
+}
+
+// filterAuthGroupIdentities returns the identities of a group that match the given filter clauses.
+func filterAuthGroupIdentities(identities []api.Identity, clauses *filter.ClauseSet) ([]api.Identity, error) {
+	if len(clauses.Clauses) == 0 {
+		return identities, nil
+	}
+
+	filtered := make([]api.Identity, 0, len(identities))
+	for _, identity := range identities {
+		match, err := filter.Match(identity, *clauses)
+		if err != nil {
+			return nil, api.StatusErrorf(http.StatusBadRequest, "Failed to filter group identities: %w", err)
+		}
+
+		if match {
+			filtered = append(filtered, identity)
+		}
 	}
 
-	// Notify other cluster members to update their identity cache.
-	notifier, err := cluster.NewNotifier(s, s.Endpoints.NetworkCert(), s.ServerCert(), cluster.NotifyAlive)
+	return filtered, nil
+}
+
+// authGroupParentIDs returns the IDs of the groups with the given parent names of the group with the given ID. It
+// returns an error if a parent does not exist or if the parents would make the group an ancestor of itself.
+func authGroupParentIDs(ctx context.Context, tx *sql.Tx, groupID int, groupName string, parentNames []string) ([]int, error) {
+	parentIDs := make([]int, 0, len(parentNames))
+	for _, parentName := range parentNames {
+		parentID, err := dbCluster.GetAuthGroupID(ctx, tx, parentName)
+		if err != nil {
+			if api.StatusErrorCheck(err, http.StatusNotFound) {
+				return nil, api.StatusErrorf(http.StatusBadRequest, "Parent group %q not found", parentName)
+			}
+
+			return nil, err
+		}
+
+		if !shared.ValueInSlice(int(parentID), parentIDs) {
+			parentIDs = append(parentIDs, int(parentID))
+		}
+	}
+
+	// Check the hierarchy for cycles as it would be with the new parents of the group.
+	parentIDsByGroupID, err := dbCluster.GetAllAuthGroupParentIDsByGroupIDs(ctx, tx)
 	if err != nil {
-		return response.SmartError(err)
+		return nil, err
 	}
 
-	err = notifier(func(client lxd.InstanceServer) error {
-		_, _, err := client.RawQuery(http.MethodPost, "/internal/identity-cache-refresh", nil, "")
-		return err
-	})
+	parentIDsByGroupID[groupID] = parentIDs
+	if shared.ValueInSlice(groupID, dbCluster.AuthGroupAncestorIDs(parentIDsByGroupID, groupID)) {
+		return nil, api.StatusErrorf(http.StatusBadRequest, "Group %q cannot inherit from itself or its descendants", groupName)
+	}
+
+	return parentIDs, nil
+}
+
+// authGroupRoles resolves the given roles of a group to the IDs of the roles and of the entities that they are granted
+// on. It returns an error if a role does not exist or if the referenced entity does not have the entity type of the role.
+func authGroupRoles(ctx context.Context, tx *sql.Tx, apiRoles []api.AuthGroupRole) ([]dbCluster.AuthGroupRole, error) {
+	entityReferences := make(map[*api.URL]*dbCluster.EntityRef, len(apiRoles))
+	roleURLs := make([]*api.URL, 0, len(apiRoles))
+	roles := make([]dbCluster.AuthGroupRole, 0, len(apiRoles))
+	for _, apiRole := range apiRoles {
+		role, err := dbCluster.GetAuthRole(ctx, tx, apiRole.Role)
+		if err != nil {
+			if api.StatusErrorCheck(err, http.StatusNotFound) {
+				return nil, api.StatusErrorf(http.StatusBadRequest, "Role %q not found", apiRole.Role)
+			}
+
+			return nil, err
+		}
+
+		u, err := url.Parse(apiRole.EntityReference)
+		if err != nil {
+			return nil, api.StatusErrorf(http.StatusBadRequest, "Failed to parse entity reference %q of role %q: %v", apiRole.EntityReference, apiRole.Role, err)
+		}
+
+		apiURL := &api.URL{URL: *u}
+		entityReferences[apiURL] = &dbCluster.EntityRef{}
+		roleURLs = append(roleURLs, apiURL)
+		roles = append(roles, dbCluster.AuthGroupRole{RoleID: role.ID, RoleName: role.Name, EntityType: role.EntityType})
+	}
+
+	err := dbCluster.PopulateEntityReferencesFromURLs(ctx, tx, entityReferences)
 	if err != nil {
-		return response.SmartError(err)
+		return nil, err
 	}
 
-	// When a group is deleted we need to remove it from the list of groups names associated with each identity in the cache.
-	// (When a group is created, nobody is a member of it yet, so the cache doesn't need to be updated).
-	s.UpdateIdentityCache()
+	for i, apiURL := range roleURLs {
+		entityRef := entityReferences[apiURL]
+		if entityRef.EntityType != roles[i].EntityType {
+			return nil, api.StatusErrorf(http.StatusBadRequest, "Role %q can only be granted on entities of type %q, not on %q", roles[i].RoleName, roles[i].EntityType, apiRoles[i].EntityReference)
+		}
 
-	// Send a lifecycle event for the group deletion
-	lc := lifecycle.AuthGroupDeleted.Event(groupName, request.CreateRequestor(r), nil)
-	s.Events.SendLifecycle(api.ProjectDefaultName, lc)
+		roles[i].EntityID = entityRef.EntityID
+	}
 
-	return response.EmptySyncResponse
+	return roles, nil
 }
 
 // validatePermissions checks that a) the entity type exists, b) the entitlement exists, c) then entity type matches the
-// entity reference (URL), and d) that the entitlement is valid for the entity type.
+// entity reference (URL), and d) that the entitlement is valid for the entity type. If the entity type does not match
+// the entity reference, the permission is a subtree permission and the entitlement must be valid for all child
+// entities of the entity type within the referenced entity. The entity reference of each permission is rewritten to
+// its canonical form, so that equivalent references compare equal.
 func validatePermissions(permissions []api.Permission) error {
-	for _, permission := range permissions {
+	for i, permission := range permissions {
 		entityType := entity.Type(permission.EntityType)
 		err := entityType.Validate()
 		if err != nil {
@@ -802,18 +2730,33 @@ func validatePermissions(permissions []api.Permission) error {
 			return api.StatusErrorf(http.StatusBadRequest, "Failed to validate entitlement for permission with entity reference %q and entitlement %q: %v", permission.EntityReference, permission.Entitlement, err)
 		}
 
-		u, err := url.Parse(permission.EntityReference)
+		canonicalURL, err := canonicalEntityReference(permission.EntityReference)
 		if err != nil {
 			return api.StatusErrorf(http.StatusBadRequest, "Failed to parse permission with entity reference %q and entitlement %q: %v", permission.EntityReference, permission.Entitlement, err)
 		}
 
-		referenceEntityType, _, _, _, err := entity.ParseURL(*u)
+		permissions[i].EntityReference = canonicalURL.String()
+
+		referenceEntityType, _, location, _, err := entity.ParseURL(canonicalURL.URL)
 		if err != nil {
 			return api.StatusErrorf(http.StatusBadRequest, "Failed to parse permission with entity reference %q and entitlement %q: %v", permission.EntityReference, permission.Entitlement, err)
 		}
 
+		// The location of an instance can change, so it cannot be part of the entity reference. Permissions on the
+		// instances of a cluster member are granted by referencing the cluster member instead.
+		if referenceEntityType == entity.TypeInstance && location != "" {
+			return api.StatusErrorf(http.StatusBadRequest, "Failed to validate permission with entity reference %q and entitlement %q: Instance references cannot have a target, use a permission with entity type %q on the cluster member instead", permission.EntityReference, permission.Entitlement, entity.TypeInstance)
+		}
+
+		// If the entity type does not correspond to the entity reference, the permission is a subtree permission that
+		// applies to all child entities of the entity type within the referenced entity.
 		if entityType != referenceEntityType {
-			return api.StatusErrorf(http.StatusBadRequest, "Failed to parse permission with entity reference %q and entitlement %q: Entity type does not correspond to entity reference", permission.EntityReference, permission.Entitlement)
+			err = auth.ValidateSubtreeEntitlement(referenceEntityType, entityType, entitlement)
+			if err != nil {
+				return api.StatusErrorf(http.StatusBadRequest, "Failed to validate subtree permission with entity reference %q and entitlement %q: %v", permission.EntityReference, permission.Entitlement, err)
+			}
+
+			continue
 		}
 
 		err = auth.ValidateEntitlement(entityType, entitlement)
@@ -825,39 +2768,77 @@ func validatePermissions(permissions []api.Permission) error {
 	return nil
 }
 
+// canonicalEntityReference parses the entity reference of a permission and returns its canonical URL.
+func canonicalEntityReference(entityReference string) (*api.URL, error) {
+	u, err := url.Parse(entityReference)
+	if err != nil {
+		return nil, err
+	}
+
+	return entity.CanonicalURL(*u)
+}
+
 // upsertPermissions resolves the URLs of each permission to an entity ID and checks if the permission already
 // exists (it may be assigned to another group already). If the permission does not already exist, it is created.
 // A slice of permission IDs is returned that can be used to associate these permissions to a group.
-func upsertPermissions(ctx context.Context, tx *sql.Tx, permissions []api.Permission) ([]int, error) {
+// The entity references that don't resolve are logged with the given logger.
+func upsertPermissions(ctx context.Context, tx *sql.Tx, permissions []api.Permission, l logger.Logger) ([]int, error) {
 	entityReferences := make(map[*api.URL]*dbCluster.EntityRef, len(permissions))
 	permissionToURL := make(map[api.Permission]*api.URL, len(permissions))
+	canonicalURLs := make(map[string]*api.URL, len(permissions))
 	for _, permission := range permissions {
-		u, err := url.Parse(permission.EntityReference)
+		// Equivalent entity references resolve to the same canonical URL, so that they result in a single permission.
+		canonicalURL, err := canonicalEntityReference(permission.EntityReference)
 		if err != nil {
-			return nil, fmt.Errorf("Failed to parse permission entity reference: %w", err)
+			return nil, api.StatusErrorf(http.StatusBadRequest, "Failed to parse permission entity reference %q: %v", permission.EntityReference, err)
 		}
 
-		apiURL := &api.URL{URL: *u}
-		entityReferences[apiURL] = &dbCluster.EntityRef{}
+		apiURL, ok := canonicalURLs[canonicalURL.String()]
+		if !ok {
+			apiURL = canonicalURL
+			canonicalURLs[canonicalURL.String()] = apiURL
+			entityReferences[apiURL] = &dbCluster.EntityRef{}
+		}
+
+		permission.EntityReference = apiURL.String()
 		permissionToURL[permission] = apiURL
 	}
 
+	// Check that all entity references resolve before writing any permission, so that the error names the references
+	// that don't (for example an instance in another project).
 	err := dbCluster.PopulateEntityReferencesFromURLs(ctx, tx, entityReferences)
 	if err != nil {
+		var unresolved []string
+		for apiURL, entityRef := range entityReferences {
+			if entityRef == nil || (entityRef.EntityID == 0 && entityRef.EntityType != dbCluster.EntityType(entity.TypeServer)) {
+				unresolved = append(unresolved, apiURL.String())
+			}
+		}
+
+		sort.Strings(unresolved)
+		l.Warn("Failed resolving permission entity references", logger.Ctx{"references": unresolved, "err": err})
+
 		return nil, err
 	}
 
 	var permissionIDs []int
 	for permission, apiURL := range permissionToURL {
 		entitlement := auth.Entitlement(permission.Entitlement)
-		entityType := dbCluster.EntityType(permission.EntityType)
 		entityRef, ok := entityReferences[apiURL]
 		if !ok {
-			return nil, fmt.Errorf("Missing entity ID for permission with URL %q", permission.EntityReference)
+			return nil, api.StatusErrorf(http.StatusNotFound, "No entity found for permission entity reference %q", permission.EntityReference)
+		}
+
+		// The entity type of the permission is always that of the referenced entity. For subtree permissions, the
+		// entity type of the child entities that the entitlement applies to is stored separately.
+		entityType := entityRef.EntityType
+		var subtreeEntityType entity.Type
+		if dbCluster.EntityType(permission.EntityType) != entityType {
+			subtreeEntityType = entity.Type(permission.EntityType)
 		}
 
 		// Get the permission, if one is found, append its ID to the slice.
-		existingPermission, err := dbCluster.GetPermission(ctx, tx, entitlement, entityType, entityRef.EntityID)
+		existingPermission, err := dbCluster.GetPermission(ctx, tx, entitlement, entityType, entityRef.EntityID, subtreeEntityType)
 		if err == nil {
 			permissionIDs = append(permissionIDs, existingPermission.ID)
 			continue
@@ -866,7 +2847,7 @@ func upsertPermissions(ctx context.Context, tx *sql.Tx, permissions []api.Permis
 		}
 
 		// Generated "create" methods call cluster.GetPermission again to check if it exists. We already know that it doesn't exist, so create it directly.
-		res, err := tx.ExecContext(ctx, `INSERT INTO permissions (entitlement, entity_type, entity_id) VALUES (?, ?, ?)`, entitlement, entityType, entityRef.EntityID)
+		res, err := tx.ExecContext(ctx, `INSERT INTO permissions (entitlement, entity_type, entity_id, subtree_entity_type) VALUES (?, ?, ?, ?)`, entitlement, entityType, entityRef.EntityID, subtreeEntityType)
 		if err != nil {
 			return nil, fmt.Errorf("Failed to insert new permission: %w", err)
 		}
@@ -881,3 +2862,164 @@ func upsertPermissions(ctx context.Context, tx *sql.Tx, permissions []api.Permis
 
 	return permissionIDs, nil
 }
+
+// updateAuthGroupsLastUsedTask records when the permissions of each group last granted access to a request. The
+// authorizer only marks groups as used in the identity cache, so that authorized requests don't cause database writes.
+// The last used time of each group is then written to the database at most once per minute.
+func updateAuthGroupsLastUsedTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		groupsUsed := d.identityCache.TakeGroupsUsed()
+		if len(groupsUsed) == 0 {
+			return
+		}
+
+		err := d.db.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+			return dbCluster.UpdateAuthGroupsLastUsedAt(ctx, tx.Tx(), groupsUsed)
+		})
+		if err != nil {
+			logger.Warn("Failed updating last used time of groups", logger.Ctx{"err": err})
+		}
+	}
+
+	return f, task.Every(time.Minute)
+}
+
+// checkAuthGroupsBroadAdminAccess raises a warning for each group that grants the admin or can_edit entitlement on
+// the server, directly or through one of its ancestors, to more than core.admin_groups_max_identities identities or
+// to any identity provider group. Disabled and template groups grant nothing. The warnings of groups that no longer
+// do so are resolved.
+func checkAuthGroupsBroadAdminAccess(ctx context.Context, s *state.State) error {
+	warningType := warningtype.AuthGroupBroadAdminAccess
+
+	return s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+		config, err := clusterConfig.Load(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		groups, err := dbCluster.GetAuthGroups(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		permissionsByGroupID, err := dbCluster.GetAllPermissionsByAuthGroupIDs(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		parentIDsByGroupID, err := dbCluster.GetAllAuthGroupParentIDsByGroupIDs(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		identitiesByGroupID, err := dbCluster.GetAllIdentitiesByAuthGroupIDs(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		idpGroupsByGroupID, err := dbCluster.GetAllIdentityProviderGroupsByGroupIDs(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		roles, err := dbCluster.GetAuthRoles(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		roleEntitlements := make(map[int][]auth.Entitlement, len(roles))
+		for _, role := range roles {
+			roleEntitlements[role.ID] = role.Entitlements
+		}
+
+		rolesByGroupID, err := dbCluster.GetAllAuthGroupRolesByGroupIDs(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		groupsEnabled, err := dbCluster.GetAllAuthGroupsEnabled(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		groupsTemplate, err := dbCluster.GetAllAuthGroupsTemplate(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		// grantsAdmin returns whether the group with the given ID has the admin or can_edit entitlement on the server,
+		// either directly or through one of its roles.
+		grantsAdmin := func(groupID int) bool {
+			if !groupsEnabled[groupID] || groupsTemplate[groupID] {
+				return false
+			}
+
+			for _, role := range rolesByGroupID[groupID] {
+				if role.EntityType != dbCluster.EntityType(entity.TypeServer) {
+					continue
+				}
+
+				for _, entitlement := range roleEntitlements[role.RoleID] {
+					if entitlement == auth.EntitlementServerAdmin || entitlement == auth.EntitlementCanEdit {
+						return true
+					}
+				}
+			}
+
+			for _, permission := range permissionsByGroupID[groupID] {
+				if permission.EntityType != dbCluster.EntityType(entity.TypeServer) || permission.SubtreeEntityType != "" {
+					continue
+				}
+
+				if permission.Entitlement == auth.EntitlementServerAdmin || permission.Entitlement == auth.EntitlementCanEdit {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		broadGroupIDs := make(map[int]bool)
+		for _, group := range groups {
+			if !groupsEnabled[group.ID] {
+				continue
+			}
+
+			admin := grantsAdmin(group.ID)
+			for _, ancestorID := range dbCluster.AuthGroupAncestorIDs(parentIDsByGroupID, group.ID) {
+				admin = admin || grantsAdmin(ancestorID)
+			}
+
+			identities := len(identitiesByGroupID[group.ID])
+			idpGroups := len(idpGroupsByGroupID[group.ID])
+			if !admin || (int64(identities) <= config.AdminGroupsMaxIdentities() && idpGroups == 0) {
+				continue
+			}
+
+			broadGroupIDs[group.ID] = true
+			err = tx.UpsertWarning(ctx, "", "", entity.TypeAuthGroup, group.ID, warningType, fmt.Sprintf("Group %q grants administrative access on the server to %d identities and %d identity provider groups", group.Name, identities, idpGroups))
+			if err != nil {
+				return err
+			}
+		}
+
+		// Resolve the warnings of groups that no longer grant administrative access broadly, or no longer exist.
+		warnings, err := dbCluster.GetWarnings(ctx, tx.Tx(), dbCluster.WarningFilter{TypeCode: &warningType})
+		if err != nil {
+			return err
+		}
+
+		for _, w := range warnings {
+			if broadGroupIDs[w.EntityID] || w.Status == warningtype.StatusResolved {
+				continue
+			}
+
+			err = tx.UpdateWarningStatus(w.UUID, warningtype.StatusResolved)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/auth"
+	"github.com/canonical/lxd/lxd/db"
+	dbCluster "github.com/canonical/lxd/lxd/db/cluster"
+	"github.com/canonical/lxd/lxd/request"
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
+	"github.com/canonical/lxd/shared/filter"
+)
+
+var authModelCmd = APIEndpoint{
+	Name: "auth_model",
+	Path: "auth/model",
+	Get: APIEndpointAction{
+		Handler:       getAuthModel,
+		AccessHandler: allowAuthenticated,
+	},
+}
+
+// swagger:operation GET /1.0/auth/model auth_model auth_model_get
+//
+//	Export the authorization model
+//
+//	Returns the groups that the caller can view, along with their permissions, parents, members and identity
+//	provider group mappings, in the representation of an external policy engine. Roles are expanded into their
+//	entitlements.
+//
+//	With `format=openfga` (the default), an OpenFGA authorization model in the OpenFGA DSL is returned along with
+//	the relationship tuples of the groups. Disabled and template groups only keep their parents. The membership of
+//	identity provider groups is not returned, as it is given by the identity provider.
+//
+//	With `format=rego`, a Rego policy is returned along with the data document that it is evaluated against.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: format
+//	    description: Policy representation (openfga or rego)
+//	    type: string
+//	    example: openfga
+//	responses:
+//	  "200":
+//	    description: Authorization model
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          $ref: "#/definitions/AuthModelOpenFGA"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func getAuthModel(d *Daemon, r *http.Request) response.Response {
+	format := request.QueryParam(r, "format")
+	if format == "" {
+		format = api.AuthModelFormatOpenFGA
+	}
+
+	if format != api.AuthModelFormatOpenFGA && format != api.AuthModelFormatRego {
+		return response.BadRequest(fmt.Errorf("Invalid `format` query parameter %q", format))
+	}
+
+	s := d.State()
+	hasPermission, err := authGroupPermissionChecker(s, r, auth.EntitlementCanViewGroups, auth.EntitlementCanView)
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Failed to get a permission checker: %w", err))
+	}
+
+	var groups []dbCluster.AuthGroup
+	var roles []api.AuthRole
+	details := authGroupsDetails{names: make(map[int]string)}
+	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		allGroups, err := dbCluster.GetAuthGroups(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		details.enabled, err = dbCluster.GetAllAuthGroupsEnabled(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		for _, group := range allGroups {
+			details.names[group.ID] = group.Name
+			if hasPermission(entity.AuthGroupURL(group.Name)) {
+				groups = append(groups, group)
+			}
+		}
+
+		dbRoles, err := dbCluster.GetAuthRoles(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		// The roles are only needed to expand the roles of the groups into their entitlements.
+		for _, role := range dbRoles {
+			roles = append(roles, authRoleToAPI(role, nil))
+		}
+
+		return details.load(ctx, tx.Tx())
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	apiGroups, err := details.toAPI(groups, &filter.ClauseSet{})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if format == api.AuthModelFormatRego {
+		export, err := auth.ExportRego(apiGroups, roles)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.SyncResponse(true, export)
+	}
+
+	export, err := auth.ExportOpenFGA(apiGroups, roles)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, export)
+}
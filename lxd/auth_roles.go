@@ -0,0 +1,536 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/canonical/lxd/lxd/auth"
+	"github.com/canonical/lxd/lxd/db"
+	dbCluster "github.com/canonical/lxd/lxd/db/cluster"
+	"github.com/canonical/lxd/lxd/lifecycle"
+	"github.com/canonical/lxd/lxd/request"
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/lxd/util"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
+	"github.com/canonical/lxd/shared/version"
+)
+
+var authRolesCmd = APIEndpoint{
+	Name: "auth_roles",
+	Path: "auth/roles",
+	Get: APIEndpointAction{
+		Handler:       getAuthRoles,
+		AccessHandler: allowPermission(entity.TypeServer, auth.EntitlementCanViewGroups),
+	},
+	Post: APIEndpointAction{
+		Handler:       createAuthRole,
+		AccessHandler: allowPermission(entity.TypeServer, auth.EntitlementCanCreateGroups),
+	},
+}
+
+var authRoleCmd = APIEndpoint{
+	Name: "auth_role",
+	Path: "auth/roles/{roleName}",
+	Get: APIEndpointAction{
+		Handler:       getAuthRole,
+		AccessHandler: allowPermission(entity.TypeServer, auth.EntitlementCanViewGroups),
+	},
+	Put: APIEndpointAction{
+		Handler:       updateAuthRole,
+		AccessHandler: allowPermission(entity.TypeServer, auth.EntitlementCanEditGroups),
+	},
+	Delete: APIEndpointAction{
+		Handler:       deleteAuthRole,
+		AccessHandler: allowPermission(entity.TypeServer, auth.EntitlementCanDeleteGroups),
+	},
+}
+
+// authRoleURL returns the URL of the role with the given name.
+func authRoleURL(roleName string) *api.URL {
+	return api.NewURL().Path(version.APIVersion, "auth", "roles", roleName)
+}
+
+// validateAuthRole checks the name of a role and that each of its entitlements can be granted on entities of its
+// entity type.
+func validateAuthRole(name string, role api.AuthRolePut) error {
+	if name == "" {
+		return api.StatusErrorf(http.StatusBadRequest, "Role name cannot be empty")
+	}
+
+	if strings.Contains(name, "/") {
+		return api.StatusErrorf(http.StatusBadRequest, "Role name cannot contain a forward slash")
+	}
+
+	entityType := entity.Type(role.EntityType)
+	err := entityType.Validate()
+	if err != nil {
+		return api.StatusErrorf(http.StatusBadRequest, "Invalid entity type of role %q: %v", name, err)
+	}
+
+	if len(role.Entitlements) == 0 {
+		return api.StatusErrorf(http.StatusBadRequest, "Role %q must have at least one entitlement", name)
+	}
+
+	for _, entitlement := range role.Entitlements {
+		err = auth.ValidateEntitlement(entityType, auth.Entitlement(entitlement))
+		if err != nil {
+			return api.StatusErrorf(http.StatusBadRequest, "Invalid entitlement %q of role %q: %v", entitlement, name, err)
+		}
+	}
+
+	return nil
+}
+
+// authRoleToAPI converts a role to an api.AuthRole, given the names of the groups that the role is granted to.
+func authRoleToAPI(role dbCluster.AuthRole, groupNames []string) api.AuthRole {
+	entitlements := make([]string, 0, len(role.Entitlements))
+	for _, entitlement := range role.Entitlements {
+		entitlements = append(entitlements, string(entitlement))
+	}
+
+	usedBy := make([]string, 0, len(groupNames))
+	for _, groupName := range groupNames {
+		usedBy = append(usedBy, entity.AuthGroupURL(groupName).String())
+	}
+
+	return api.AuthRole{
+		AuthRolesPost: api.AuthRolesPost{
+			Name: role.Name,
+			AuthRolePut: api.AuthRolePut{
+				Description:  role.Description,
+				EntityType:   string(role.EntityType),
+				Entitlements: entitlements,
+			},
+		},
+		UsedBy: usedBy,
+	}
+}
+
+// swagger:operation GET /1.0/auth/roles auth_roles auth_roles_get
+//
+//	Get the roles
+//
+//	Returns a list of roles (URLs).
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    description: API endpoints
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of endpoints
+//	          items:
+//	            type: string
+//	          example: |-
+//	            [
+//	              "/1.0/auth/roles/project-operator",
+//	              "/1.0/auth/roles/instance-user"
+//	            ]
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+
+// swagger:operation GET /1.0/auth/roles?recursion=1 auth_roles auth_roles_get_recursion1
+//
+//	Get the roles
+//
+//	Returns a list of roles.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    description: API endpoints
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of roles
+//	          items:
+//	            $ref: "#/definitions/AuthRole"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func getAuthRoles(d *Daemon, r *http.Request) response.Response {
+	recursion := r.URL.Query().Get("recursion")
+	s := d.State()
+
+	var roles []dbCluster.AuthRole
+	var groupNamesByRoleID map[int][]string
+	err := s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		var err error
+		roles, err = dbCluster.GetAuthRoles(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		if recursion == "1" {
+			groupNamesByRoleID, err = dbCluster.GetAllAuthGroupNamesByRoleIDs(ctx, tx.Tx())
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if recursion == "1" {
+		apiRoles := make([]api.AuthRole, 0, len(roles))
+		for _, role := range roles {
+			apiRoles = append(apiRoles, authRoleToAPI(role, groupNamesByRoleID[role.ID]))
+		}
+
+		return response.SyncResponse(true, apiRoles)
+	}
+
+	roleURLs := make([]string, 0, len(roles))
+	for _, role := range roles {
+		roleURLs = append(roleURLs, authRoleURL(role.Name).String())
+	}
+
+	return response.SyncResponse(true, roleURLs)
+}
+
+// swagger:operation POST /1.0/auth/roles auth_roles auth_roles_post
+//
+//	Create a new role
+//
+//	Creates a new role.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: body
+//	    name: role
+//	    description: Role request
+//	    required: true
+//	    schema:
+//	      $ref: "#/definitions/AuthRolesPost"
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func createAuthRole(d *Daemon, r *http.Request) response.Response {
+	var role api.AuthRolesPost
+	err := json.NewDecoder(r.Body).Decode(&role)
+	if err != nil {
+		return response.BadRequest(fmt.Errorf("Invalid request body: %w", err))
+	}
+
+	err = validateAuthRole(role.Name, role.AuthRolePut)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	entitlements := make([]auth.Entitlement, 0, len(role.Entitlements))
+	for _, entitlement := range role.Entitlements {
+		entitlements = append(entitlements, auth.Entitlement(entitlement))
+	}
+
+	s := d.State()
+	err = s.DB.Cluster.TransactionRetry(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		_, err := dbCluster.CreateAuthRole(ctx, tx.Tx(), dbCluster.AuthRole{
+			Name:         role.Name,
+			Description:  role.Description,
+			EntityType:   dbCluster.EntityType(role.EntityType),
+			Entitlements: entitlements,
+		})
+		if err != nil {
+			return err
+		}
+
+		after, err := authAuditRoleState(ctx, tx.Tx(), role.Name)
+		if err != nil {
+			return err
+		}
+
+		return authAuditRecord(ctx, tx.Tx(), request.CreateRequestor(r), api.AuthAuditObjectTypeRole, role.Name, api.AuthAuditActionCreated, nil, after)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	lc := lifecycle.AuthRoleCreated.Event(role.Name, request.CreateRequestor(r), nil)
+	s.Events.SendLifecycle(api.ProjectDefaultName, lc)
+
+	return response.SyncResponseLocation(true, nil, authRoleURL(role.Name).String())
+}
+
+// swagger:operation GET /1.0/auth/roles/{roleName} auth_roles auth_role_get
+//
+//	Get the role
+//
+//	Gets a specific role.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          $ref: "#/definitions/AuthRole"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func getAuthRole(d *Daemon, r *http.Request) response.Response {
+	roleName, err := url.PathUnescape(mux.Vars(r)["roleName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	var apiRole api.AuthRole
+	s := d.State()
+	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		role, err := dbCluster.GetAuthRole(ctx, tx.Tx(), roleName)
+		if err != nil {
+			return err
+		}
+
+		groupNamesByRoleID, err := dbCluster.GetAllAuthGroupNamesByRoleIDs(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		apiRole = authRoleToAPI(*role, groupNamesByRoleID[role.ID])
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseETag(true, apiRole, apiRole.AuthRolePut)
+}
+
+// swagger:operation PUT /1.0/auth/roles/{roleName} auth_roles auth_role_put
+//
+//	Update the role
+//
+//	Replaces the editable fields of a role.
+//	The change applies to all groups that the role is granted to.
+//	The entity type of a role cannot be changed while the role is granted to a group.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: body
+//	    name: role
+//	    description: Update request
+//	    schema:
+//	      $ref: "#/definitions/AuthRolePut"
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func updateAuthRole(d *Daemon, r *http.Request) response.Response {
+	roleName, err := url.PathUnescape(mux.Vars(r)["roleName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	var rolePut api.AuthRolePut
+	err = json.NewDecoder(r.Body).Decode(&rolePut)
+	if err != nil {
+		return response.BadRequest(fmt.Errorf("Invalid request body: %w", err))
+	}
+
+	err = validateAuthRole(roleName, rolePut)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	entitlements := make([]auth.Entitlement, 0, len(rolePut.Entitlements))
+	for _, entitlement := range rolePut.Entitlements {
+		entitlements = append(entitlements, auth.Entitlement(entitlement))
+	}
+
+	s := d.State()
+	err = s.DB.Cluster.TransactionRetry(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		role, err := dbCluster.GetAuthRole(ctx, tx.Tx(), roleName)
+		if err != nil {
+			return err
+		}
+
+		groupNamesByRoleID, err := dbCluster.GetAllAuthGroupNamesByRoleIDs(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		before := authRoleToAPI(*role, groupNamesByRoleID[role.ID])
+		err = util.EtagCheck(r, before.AuthRolePut)
+		if err != nil {
+			return err
+		}
+
+		// Groups reference the entity that the role is granted on, which must have the entity type of the role.
+		if string(role.EntityType) != rolePut.EntityType && len(groupNamesByRoleID[role.ID]) > 0 {
+			return api.StatusErrorf(http.StatusBadRequest, "Cannot change the entity type of role %q while it is granted to groups: %s", roleName, strings.Join(groupNamesByRoleID[role.ID], ", "))
+		}
+
+		err = dbCluster.UpdateAuthRole(ctx, tx.Tx(), role.ID, dbCluster.AuthRole{
+			Name:         roleName,
+			Description:  rolePut.Description,
+			EntityType:   dbCluster.EntityType(rolePut.EntityType),
+			Entitlements: entitlements,
+		})
+		if err != nil {
+			return err
+		}
+
+		after, err := authAuditRoleState(ctx, tx.Tx(), roleName)
+		if err != nil {
+			return err
+		}
+
+		return authAuditRecord(ctx, tx.Tx(), request.CreateRequestor(r), api.AuthAuditObjectTypeRole, roleName, api.AuthAuditActionUpdated, before, after)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// The identity cache holds the permissions that roles expand to, so it must be updated when they change.
+	notifyIdentityCacheRefresh(s)
+
+	lc := lifecycle.AuthRoleUpdated.Event(roleName, request.CreateRequestor(r), nil)
+	s.Events.SendLifecycle(api.ProjectDefaultName, lc)
+
+	return response.EmptySyncResponse
+}
+
+// swagger:operation DELETE /1.0/auth/roles/{roleName} auth_roles auth_role_delete
+//
+//	Delete the role
+//
+//	Deletes the role. A role cannot be deleted while it is granted to a group.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func deleteAuthRole(d *Daemon, r *http.Request) response.Response {
+	roleName, err := url.PathUnescape(mux.Vars(r)["roleName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	s := d.State()
+	err = s.DB.Cluster.TransactionRetry(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		role, err := dbCluster.GetAuthRole(ctx, tx.Tx(), roleName)
+		if err != nil {
+			return err
+		}
+
+		groupNamesByRoleID, err := dbCluster.GetAllAuthGroupNamesByRoleIDs(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		if len(groupNamesByRoleID[role.ID]) > 0 {
+			return api.StatusErrorf(http.StatusBadRequest, "Role %q is granted to groups: %s", roleName, strings.Join(groupNamesByRoleID[role.ID], ", "))
+		}
+
+		err = dbCluster.DeleteAuthRole(ctx, tx.Tx(), roleName)
+		if err != nil {
+			return err
+		}
+
+		before := authRoleToAPI(*role, nil)
+		return authAuditRecord(ctx, tx.Tx(), request.CreateRequestor(r), api.AuthAuditObjectTypeRole, roleName, api.AuthAuditActionDeleted, before, nil)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	lc := lifecycle.AuthRoleDeleted.Event(roleName, request.CreateRequestor(r), nil)
+	s.Events.SendLifecycle(api.ProjectDefaultName, lc)
+
+	return response.EmptySyncResponse
+}
@@ -15,7 +15,6 @@ import (
 
 	"github.com/gorilla/mux"
 
-	"github.com/canonical/lxd/client"
 	"github.com/canonical/lxd/lxd/auth"
 	"github.com/canonical/lxd/lxd/certificate"
 	"github.com/canonical/lxd/lxd/cluster"
@@ -145,7 +144,7 @@ func certificatesGet(d *Daemon, r *http.Request) response.Response {
 		var certResponses []api.Certificate
 		var baseCerts []dbCluster.Certificate
 		var err error
-		err = d.State().DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		err = d.State().DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
 			baseCerts, err = dbCluster.GetCertificates(ctx, tx.Tx())
 			if err != nil {
 				return err
@@ -187,15 +186,14 @@ func certificatesGet(d *Daemon, r *http.Request) response.Response {
 	return response.SyncResponse(true, body)
 }
 
-// clusterMemberJoinTokenValid searches for cluster join token that matches the join token provided.
-// Returns matching operation if found and cancels the operation, otherwise returns nil.
-func clusterMemberJoinTokenValid(s *state.State, r *http.Request, projectName string, joinToken *api.ClusterMemberJoinToken) (*api.Operation, error) {
+// clusterMemberJoinTokenFind searches for cluster join token that matches the join token provided.
+// Returns matching operation if found without cancelling it, otherwise returns nil.
+func clusterMemberJoinTokenFind(s *state.State, r *http.Request, projectName string, joinToken *api.ClusterMemberJoinToken) (*api.Operation, error) {
 	ops, err := operationsGetByType(s, r, projectName, operationtype.ClusterJoinToken)
 	if err != nil {
 		return nil, fmt.Errorf("Failed getting cluster join token operations: %w", err)
 	}
 
-	var foundOp *api.Operation
 	for _, op := range ops {
 		if op.StatusCode != api.Running {
 			continue // Tokens are single use, so if cancelled but not deleted yet its not available.
@@ -216,11 +214,40 @@ func clusterMemberJoinTokenValid(s *state.State, r *http.Request, projectName st
 		}
 
 		if opServerName == joinToken.ServerName && opSecret == joinToken.Secret {
-			foundOp = op
-			break
+			return op, nil
 		}
 	}
 
+	return nil, nil
+}
+
+// clusterMemberJoinTokenExpired returns whether the cluster join token operation has expired.
+func clusterMemberJoinTokenExpired(s *state.State, op *api.Operation) bool {
+	expiresAt, ok := op.Metadata["expiresAt"]
+	if !ok {
+		return false
+	}
+
+	var expiry time.Time
+
+	// Depending on whether it's a local operation or not, expiry will either be a time.Time or a string.
+	if s.ServerName == op.Location {
+		expiry, _ = expiresAt.(time.Time)
+	} else {
+		expiry, _ = time.Parse(time.RFC3339Nano, expiresAt.(string))
+	}
+
+	return time.Now().After(expiry)
+}
+
+// clusterMemberJoinTokenValid searches for cluster join token that matches the join token provided.
+// Returns matching operation if found and cancels the operation, otherwise returns nil.
+func clusterMemberJoinTokenValid(s *state.State, r *http.Request, projectName string, joinToken *api.ClusterMemberJoinToken) (*api.Operation, error) {
+	foundOp, err := clusterMemberJoinTokenFind(s, r, projectName, joinToken)
+	if err != nil {
+		return nil, err
+	}
+
 	if foundOp != nil {
 		// Token is single-use, so cancel it now.
 		err = operationCancel(s, r, projectName, foundOp)
@@ -228,21 +255,9 @@ func clusterMemberJoinTokenValid(s *state.State, r *http.Request, projectName st
 			return nil, fmt.Errorf("Failed to cancel operation %q: %w", foundOp.ID, err)
 		}
 
-		expiresAt, ok := foundOp.Metadata["expiresAt"]
-		if ok {
-			var expiry time.Time
-
-			// Depending on whether it's a local operation or not, expiry will either be a time.Time or a string.
-			if s.ServerName == foundOp.Location {
-				expiry, _ = expiresAt.(time.Time)
-			} else {
-				expiry, _ = time.Parse(time.RFC3339Nano, expiresAt.(string))
-			}
-
-			// Check if token has expired.
-			if time.Now().After(expiry) {
-				return nil, api.StatusErrorf(http.StatusForbidden, "Token has expired")
-			}
+		// Check if token has expired.
+		if clusterMemberJoinTokenExpired(s, foundOp) {
+			return nil, api.StatusErrorf(http.StatusForbidden, "Token has expired")
 		}
 
 		return foundOp, nil
@@ -637,10 +652,7 @@ func certificatesPost(d *Daemon, r *http.Request) response.Response {
 		},
 	}
 
-	err = notifier(func(client lxd.InstanceServer) error {
-		_, _, err := client.RawQuery(http.MethodPost, "/internal/identity-cache-refresh", nil, "")
-		return err
-	})
+	err = notifier(identityCacheRefreshMember)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -702,7 +714,7 @@ func certificateGet(d *Daemon, r *http.Request) response.Response {
 	}
 
 	var cert *api.Certificate
-	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
 		dbCertInfo, err := dbCluster.GetCertificateByFingerprintPrefix(ctx, tx.Tx(), fingerprint)
 		if err != nil {
 			return err
@@ -971,10 +983,7 @@ func doCertificateUpdate(d *Daemon, dbInfo api.Certificate, req api.CertificateP
 		return response.SmartError(err)
 	}
 
-	err = notifier(func(client lxd.InstanceServer) error {
-		_, _, err := client.RawQuery(http.MethodPost, "/internal/identity-cache-refresh", nil, "")
-		return err
-	})
+	err = notifier(identityCacheRefreshMember)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -982,6 +991,12 @@ func doCertificateUpdate(d *Daemon, dbInfo api.Certificate, req api.CertificateP
 	// Reload the identity cache.
 	s.UpdateIdentityCache()
 
+	// The projects of the certificate may have changed, which changes the permissions that its groups can grant.
+	err = checkAuthGroupsConfinedProjects(s.ShutdownCtx, s)
+	if err != nil {
+		logger.Warn("Failed checking authorization groups for permissions outside of the projects of restricted identities", logger.Ctx{"err": err})
+	}
+
 	s.Events.SendLifecycle(api.ProjectDefaultName, lifecycle.CertificateUpdated.Event(dbInfo.Fingerprint, request.CreateRequestor(r), nil))
 
 	return response.EmptySyncResponse
@@ -1082,10 +1097,7 @@ func certificateDelete(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
-	err = notifier(func(client lxd.InstanceServer) error {
-		_, _, err := client.RawQuery(http.MethodPost, "/internal/identity-cache-refresh", nil, "")
-		return err
-	})
+	err = notifier(identityCacheRefreshMember)
 	if err != nil {
 		return response.SmartError(err)
 	}
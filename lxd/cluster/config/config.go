@@ -13,8 +13,10 @@ import (
 	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/scrypt"
 
+	"github.com/canonical/lxd/lxd/auth/oidc"
 	"github.com/canonical/lxd/lxd/config"
 	"github.com/canonical/lxd/lxd/db"
+	"github.com/canonical/lxd/lxd/oci"
 	scriptletLoad "github.com/canonical/lxd/lxd/scriptlet/load"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/validate"
@@ -83,6 +85,17 @@ func (c *Config) TrustPassword() string {
 	return c.m.GetString("core.trust_password")
 }
 
+// AuthRequireGroupMembership returns whether fine-grained identities must be a member of at least one group to
+// authenticate.
+func (c *Config) AuthRequireGroupMembership() bool {
+	return c.m.GetBool("auth.require_group_membership")
+}
+
+// AuthCaseInsensitiveGroupNames returns whether group names are unique and looked up regardless of their case.
+func (c *Config) AuthCaseInsensitiveGroupNames() bool {
+	return c.m.GetBool("auth.case_insensitive_group_names")
+}
+
 // TrustCACertificates returns whether client certificates are checked
 // against a CA.
 func (c *Config) TrustCACertificates() bool {
@@ -168,11 +181,22 @@ func (c *Config) ImagesDefaultArchitecture() string {
 	return c.m.GetString("images.default_architecture")
 }
 
+// ImagesOCICredentials returns the credentials of the given OCI registry host, if any.
+func (c *Config) ImagesOCICredentials(registry string) string {
+	credentials, _ := oci.ParseCredentials(c.m.GetString("images.oci.credentials"))
+	return credentials[registry]
+}
+
 // ImagesCompressionAlgorithm returns the compression algorithm to use for images.
 func (c *Config) ImagesCompressionAlgorithm() string {
 	return c.m.GetString("images.compression_algorithm")
 }
 
+// ImagesDeltaTransfer returns whether images are transferred as binary deltas against a previous version when possible.
+func (c *Config) ImagesDeltaTransfer() bool {
+	return c.m.GetBool("images.delta_transfer")
+}
+
 // ImagesAutoUpdateCached returns whether or not to auto update cached images.
 func (c *Config) ImagesAutoUpdateCached() bool {
 	return c.m.GetBool("images.auto_update_cached")
@@ -188,6 +212,13 @@ func (c *Config) ImagesRemoteCacheExpiryDays() int64 {
 	return c.m.GetInt64("images.remote_cache_expiry")
 }
 
+// InstancesFilesystemFreezeTimeout returns how long an instance file system may stay frozen before being thawed
+// automatically. A zero duration means that frozen file systems are never thawed automatically.
+func (c *Config) InstancesFilesystemFreezeTimeout() time.Duration {
+	n := c.m.GetInt64("instances.filesystem_freeze.timeout")
+	return time.Duration(n) * time.Second
+}
+
 // InstancesNICHostname returns hostname mode to use for instance NICs.
 func (c *Config) InstancesNICHostname() string {
 	return c.m.GetString("instances.nic.host_name")
@@ -203,6 +234,12 @@ func (c *Config) InstancesMigrationStateful() bool {
 	return c.m.GetBool("instances.migration.stateful")
 }
 
+// InstancesMigrationTransferRetries returns the number of times that a failed filesystem transfer of an instance
+// migration is retried over a new connection.
+func (c *Config) InstancesMigrationTransferRetries() int64 {
+	return c.m.GetInt64("instances.migration.transfer_retries")
+}
+
 // LokiServer returns all the Loki settings needed to connect to a server.
 func (c *Config) LokiServer() (apiURL string, authUsername string, authPassword string, apiCACert string, instance string, logLevel string, labels []string, types []string) {
 	if c.m.GetString("loki.types") != "" {
@@ -232,8 +269,46 @@ func (c *Config) RemoteTokenExpiry() string {
 }
 
 // OIDCServer returns all the OpenID Connect settings needed to connect to a server.
-func (c *Config) OIDCServer() (issuer string, clientID string, audience string, groupsClaim string) {
-	return c.m.GetString("oidc.issuer"), c.m.GetString("oidc.client.id"), c.m.GetString("oidc.audience"), c.m.GetString("oidc.groups.claim")
+func (c *Config) OIDCServer() (issuer string, clientID string, audience string) {
+	return c.m.GetString("oidc.issuer"), c.m.GetString("oidc.client.id"), c.m.GetString("oidc.audience")
+}
+
+// OIDCClaims returns the paths of the OpenID Connect claims used to get the groups, name and email address of identities.
+func (c *Config) OIDCClaims() (groupsClaim string, nameClaim string, emailClaim string) {
+	return c.m.GetString("oidc.groups.claim"), c.m.GetString("oidc.name.claim"), c.m.GetString("oidc.email.claim")
+}
+
+// AdminGroupsMaxIdentities returns the maximum number of identities that a group granting administrative access on
+// the server may have before a warning is raised.
+func (c *Config) AdminGroupsMaxIdentities() int64 {
+	return c.m.GetInt64("core.admin_groups_max_identities")
+}
+
+// AuthGroupsMaxPermissions returns the maximum number of permissions that an authorization group can have, or zero if
+// there is no limit.
+func (c *Config) AuthGroupsMaxPermissions() int64 {
+	return c.m.GetInt64("core.auth_groups_max_permissions")
+}
+
+// EventsAggregationThreshold returns the number of lifecycle events of each action that bulk operations send before
+// aggregating them, or zero if they are never aggregated.
+func (c *Config) EventsAggregationThreshold() int64 {
+	return c.m.GetInt64("core.events_aggregation_threshold")
+}
+
+// AuthAuditRetention returns how long the entries of the audit trail of authorization configuration changes are kept.
+func (c *Config) AuthAuditRetention() string {
+	return c.m.GetString("core.auth_audit_retention")
+}
+
+// EtagRequiredForAuth returns whether requests updating authorization groups must set the If-Match header.
+func (c *Config) EtagRequiredForAuth() bool {
+	return c.m.GetBool("core.etag_required_for_auth")
+}
+
+// HealthCheckStoragePools returns whether the health endpoints check that the local storage pools are available.
+func (c *Config) HealthCheckStoragePools() bool {
+	return c.m.GetBool("core.health_check_storage_pools")
 }
 
 // ClusterHealingThreshold returns the configured healing threshold, i.e. the
@@ -331,6 +406,29 @@ var ConfigSchema = config.Schema{
 	//  shortdesc: Agree to ACME terms of service
 	"acme.agree_tos": {Type: config.Bool, Default: "false"},
 
+	// lxdmeta:generate(entities=server; group=miscellaneous; key=auth.case_insensitive_group_names)
+	// If enabled, group names are unique regardless of their case, so that a group can't be created or renamed to a name
+	// that only differs in case from the name of another group, and groups are looked up regardless of the case of their
+	// name. Enabling this doesn't change groups whose names already only differ in case.
+	// ---
+	//  type: bool
+	//  scope: global
+	//  defaultdesc: `false`
+	//  shortdesc: Whether group names are case-insensitive
+	"auth.case_insensitive_group_names": {Type: config.Bool, Default: "false"},
+
+	// lxdmeta:generate(entities=server; group=miscellaneous; key=auth.require_group_membership)
+	// If enabled, OIDC identities that are not a member of any group, either directly or through the identity provider
+	// groups that they authenticate with, are refused when they log in. They are still added on their first login, so
+	// that an administrator can add them to a group. A daily warning lists the identities that are not a member of any
+	// group.
+	// ---
+	//  type: bool
+	//  scope: global
+	//  defaultdesc: `false`
+	//  shortdesc: Whether identities must be a member of a group to log in
+	"auth.require_group_membership": {Type: config.Bool, Default: "false"},
+
 	// lxdmeta:generate(entities=server; group=miscellaneous; key=backups.compression_algorithm)
 	// Possible values are `bzip2`, `gzip`, `lzma`, `xz`, or `none`.
 	// ---
@@ -397,6 +495,68 @@ var ConfigSchema = config.Schema{
 	//  shortdesc: Number of database stand-by members
 	"cluster.max_standby": {Type: config.Int64, Default: "2", Validator: maxStandByValidator},
 
+	// lxdmeta:generate(entities=server; group=core; key=core.admin_groups_max_identities)
+	// A warning is raised for each authorization group that grants the `admin` or `can_edit` entitlement on the
+	// server to more than this number of identities, or to any identity provider group.
+	// ---
+	//  type: integer
+	//  scope: global
+	//  defaultdesc: `10`
+	//  shortdesc: Maximum number of identities of a group with administrative access before a warning is raised
+	"core.admin_groups_max_identities": {Type: config.Int64, Default: "10"},
+
+	// lxdmeta:generate(entities=server; group=core; key=core.auth_audit_retention)
+	// Specify the retention as an expiry expression, for example `90d` or `1y`. Entries of the audit trail of
+	// authorization configuration changes are deleted once they are older than this.
+	// Set this option to an empty value to keep the entries forever.
+	// ---
+	//  type: string
+	//  scope: global
+	//  defaultdesc: `1y`
+	//  shortdesc: How long to keep the audit trail of authorization changes
+	"core.auth_audit_retention": {Type: config.String, Default: "1y", Validator: expiryValidator},
+
+	// lxdmeta:generate(entities=server; group=core; key=core.auth_groups_max_permissions)
+	// Requests creating or updating an authorization group are rejected if the group would have more permissions than
+	// this number. Consider granting the entitlement on a parent entity or through a role instead of on many entities.
+	// Set this option to `0` to disable the limit.
+	// ---
+	//  type: integer
+	//  scope: global
+	//  defaultdesc: `1000`
+	//  shortdesc: Maximum number of permissions of an authorization group
+	"core.auth_groups_max_permissions": {Type: config.Int64, Default: "1000"},
+
+	// lxdmeta:generate(entities=server; group=core; key=core.etag_required_for_auth)
+	// If enabled, requests updating authorization groups must set the `If-Match` HTTP header and are rejected with
+	// `428 Precondition Required` otherwise. `If-Match: *` can be used to only require that the group exists.
+	// ---
+	//  type: bool
+	//  scope: global
+	//  defaultdesc: `false`
+	//  shortdesc: Whether updates of authorization groups require an `If-Match` header
+	"core.etag_required_for_auth": {Type: config.Bool, Default: "false"},
+
+	// lxdmeta:generate(entities=server; group=core; key=core.events_aggregation_threshold)
+	// Bulk operations, like changing the state of all instances of a project, send the lifecycle events of each action
+	// individually up to this number of events. The remaining events of the action are aggregated into a single lifecycle
+	// event once the operation is done. Set this option to `0` to disable the aggregation.
+	// ---
+	//  type: integer
+	//  scope: global
+	//  defaultdesc: `100`
+	//  shortdesc: Number of lifecycle events of a bulk operation before they are aggregated
+	"core.events_aggregation_threshold": {Type: config.Int64, Default: "100", Validator: validate.IsUint32},
+
+	// lxdmeta:generate(entities=server; group=core; key=core.health_check_storage_pools)
+	// If enabled, the `/healthz` and `/readyz` endpoints also check that the storage pools of the server are mounted.
+	// ---
+	//  type: bool
+	//  scope: global
+	//  defaultdesc: `false`
+	//  shortdesc: Whether the health endpoints check the local storage pools
+	"core.health_check_storage_pools": {Type: config.Bool, Default: "false"},
+
 	// lxdmeta:generate(entities=server; group=core; key=core.metrics_authentication)
 	//
 	// ---
@@ -448,12 +608,12 @@ var ConfigSchema = config.Schema{
 	"core.https_allowed_credentials": {Type: config.Bool, Default: "false"},
 
 	// lxdmeta:generate(entities=server; group=core; key=core.https_trusted_proxy)
-	// Specify a comma-separated list of IP addresses of trusted servers that provide the client's address through the proxy connection header.
+	// Specify a comma-separated list of IP addresses or subnets in CIDR notation of trusted servers that provide the client's address through the proxy connection header (PROXY protocol) or through the `X-Forwarded-For` or `X-Real-IP` HTTP headers.
 	// ---
 	//  type: string
 	//  scope: global
 	//  shortdesc: Trusted servers to provide the client's address
-	"core.https_trusted_proxy": {},
+	"core.https_trusted_proxy": {Validator: validate.Optional(validate.IsListOf(TrustedProxyValidator))},
 
 	// lxdmeta:generate(entities=server; group=core; key=core.proxy_http)
 	// If this option is not specified, LXD falls back to the `HTTP_PROXY` environment variable (if set).
@@ -544,6 +704,17 @@ var ConfigSchema = config.Schema{
 	//  shortdesc: Compression algorithm to use for new images
 	"images.compression_algorithm": {Default: "gzip", Validator: validate.IsCompressionAlgorithm},
 
+	// lxdmeta:generate(entities=server; group=images; key=images.delta_transfer)
+	// When an image is downloaded from a LXD server or transferred between cluster members, and a previous version of
+	// the image from the same source is available locally, only a binary delta against that version is transferred.
+	// The full image is transferred if no delta can be used. This requires `xdelta3` on both ends.
+	// ---
+	//  type: bool
+	//  scope: global
+	//  defaultdesc: `true`
+	//  shortdesc: Whether to transfer image updates as binary deltas
+	"images.delta_transfer": {Type: config.Bool, Default: "true"},
+
 	// lxdmeta:generate(entities=server; group=images; key=images.default_architecture)
 	//
 	// ---
@@ -551,6 +722,15 @@ var ConfigSchema = config.Schema{
 	//  shortdesc: Default architecture to use in a mixed-architecture cluster
 	"images.default_architecture": {Validator: validate.Optional(validate.IsArchitecture)},
 
+	// lxdmeta:generate(entities=server; group=images; key=images.oci.credentials)
+	// Specify a comma-separated list of `<registry>=<username>:<password>` entries, which are used when importing
+	// images from OCI registries. Docker Hub can be referred to as `docker.io`.
+	// ---
+	//  type: string
+	//  scope: global
+	//  shortdesc: Credentials of OCI registries
+	"images.oci.credentials": {Hidden: true, Validator: validateOCICredentials},
+
 	// lxdmeta:generate(entities=server; group=images; key=images.remote_cache_expiry)
 	// Specify the number of days after which the unused cached image expires.
 	// ---
@@ -560,6 +740,17 @@ var ConfigSchema = config.Schema{
 	//  shortdesc: When an unused cached remote image is flushed
 	"images.remote_cache_expiry": {Type: config.Int64, Default: "10"},
 
+	// lxdmeta:generate(entities=server; group=miscellaneous; key=instances.filesystem_freeze.timeout)
+	// When LXD freezes the file system of an instance (for example, to take a consistent snapshot) and the file
+	// system is still frozen after this number of seconds, LXD thaws it and raises a warning.
+	// Set this option to `0` to never thaw frozen file systems automatically.
+	// ---
+	//  type: integer
+	//  scope: global
+	//  defaultdesc: `300`
+	//  shortdesc: Maximum time during which an instance file system may stay frozen
+	"instances.filesystem_freeze.timeout": {Type: config.Int64, Default: "300", Validator: validate.Optional(validate.IsUint32)},
+
 	// lxdmeta:generate(entities=server; group=miscellaneous; key=instances.nic.host_name)
 	// Possible values are `random` and `mac`.
 	//
@@ -589,6 +780,17 @@ var ConfigSchema = config.Schema{
 	//  shortdesc: Whether to set `migration.stateful` to `true` for the instances
 	"instances.migration.stateful": {Type: config.Bool, Default: "false"},
 
+	// lxdmeta:generate(entities=server; group=miscellaneous; key=instances.migration.transfer_retries)
+	// When the connection used to transfer the file systems of a migrating instance fails, the transfer is resumed
+	// over a new connection up to this number of times. Partially transferred data is kept on the target.
+	// Set this option on both the source and the target server.
+	// ---
+	//  type: integer
+	//  scope: global
+	//  defaultdesc: `0`
+	//  shortdesc: Number of times to retry a failed migration transfer
+	"instances.migration.transfer_retries": {Type: config.Int64, Default: "0", Validator: validate.Optional(validate.IsUint8)},
+
 	// lxdmeta:generate(entities=server; group=loki; key=loki.auth.username)
 	//
 	// ---
@@ -701,11 +903,30 @@ var ConfigSchema = config.Schema{
 	// Configure a corresponding custom claim in your identity provider and
 	// add organization level groups to it. These can be mapped to LXD groups
 	// for automatic access control.
+	// Claims nested in JSON objects can be specified with a dot separated path (for example, `resource_access.lxd.roles`).
 	// ---
 	//  type: string
 	//  scope: global
-	//  shortdesc: Expected audience value for the application
-	"oidc.groups.claim": {},
+	//  shortdesc: Claim containing the identity provider groups of identities
+	"oidc.groups.claim": {Validator: oidc.ValidateClaimPath},
+
+	// lxdmeta:generate(entities=server; group=oidc; key=oidc.name.claim)
+	// Specify a custom claim to use as the name of identities instead of the standard `name` claim.
+	// Claims nested in JSON objects can be specified with a dot separated path.
+	// ---
+	//  type: string
+	//  scope: global
+	//  shortdesc: Claim containing the name of identities
+	"oidc.name.claim": {Validator: oidc.ValidateClaimPath},
+
+	// lxdmeta:generate(entities=server; group=oidc; key=oidc.email.claim)
+	// Specify a custom claim to use as the email address of identities instead of the standard `email` claim.
+	// Claims nested in JSON objects can be specified with a dot separated path.
+	// ---
+	//  type: string
+	//  scope: global
+	//  shortdesc: Claim containing the email address of identities
+	"oidc.email.claim": {Validator: oidc.ValidateClaimPath},
 	// OVN networking global keys.
 
 	// lxdmeta:generate(entities=server; group=miscellaneous; key=network.ovn.integration_bridge)
@@ -762,6 +983,20 @@ func expiryValidator(value string) error {
 	return nil
 }
 
+// TrustedProxyValidator checks that an entry of core.https_trusted_proxy is an IP address or subnet.
+func TrustedProxyValidator(value string) error {
+	if validate.IsNetworkAddress(value) == nil {
+		return nil
+	}
+
+	err := validate.IsNetworkAddressCIDR(value)
+	if err != nil {
+		return fmt.Errorf("Not an IP address or subnet %q", value)
+	}
+
+	return nil
+}
+
 func logLevelValidator(value string) error {
 	if value == "" {
 		return nil
@@ -835,6 +1070,11 @@ func maxStandByValidator(value string) error {
 	return nil
 }
 
+func validateOCICredentials(value string) error {
+	_, err := oci.ParseCredentials(value)
+	return err
+}
+
 func passwordSetter(value string) (string, error) {
 	// Nothing to do on unset
 	if value == "" {
@@ -550,15 +550,7 @@ func (g *Gateway) Sync() {
 		return
 	}
 
-	client, err := g.getClient()
-	if err != nil {
-		logger.Warnf("Failed to get client: %v", err)
-		return
-	}
-
-	defer func() { _ = client.Close() }()
-
-	files, err := client.Dump(context.Background(), "db.bin")
+	files, err := g.dump()
 	if err != nil {
 		// Just log a warning, since this is not fatal.
 		logger.Warnf("Failed get database dump: %v", err)
@@ -575,6 +567,44 @@ func (g *Gateway) Sync() {
 	}
 }
 
+// DumpReplica returns the content of the copy of the global database held by the dqlite node of this member. It
+// returns ErrNotReplica if this member doesn't hold a copy of the database, or if it's the leader (whose reads are
+// never stale).
+func (g *Gateway) DumpReplica() ([]client.File, error) {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	if g.server == nil || !shared.ValueInSlice(g.info.Role, []db.RaftRole{db.RaftVoter, db.RaftStandBy}) {
+		return nil, ErrNotReplica
+	}
+
+	isLeader, err := g.isLeader()
+	if err != nil {
+		return nil, err
+	}
+
+	if isLeader {
+		return nil, ErrNotReplica
+	}
+
+	return g.dump()
+}
+
+// ErrNotReplica signals that a member doesn't hold a copy of the global database that can be read from.
+var ErrNotReplica = fmt.Errorf("Not a database replica")
+
+// dump returns the files of the global database held by the dqlite node of this member.
+func (g *Gateway) dump() ([]client.File, error) {
+	client, err := g.getClient()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get client: %w", err)
+	}
+
+	defer func() { _ = client.Close() }()
+
+	return client.Dump(context.Background(), "db.bin")
+}
+
 func (g *Gateway) getClient() (*client.Client, error) {
 	return client.New(context.Background(), g.bindAddress)
 }
@@ -3,6 +3,8 @@ package cluster
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -28,6 +30,48 @@ const (
 	NotifyTryAll                       // Attempt to notify all nodes regardless of state.
 )
 
+// notifyMaxConcurrency is the maximum number of cluster members that a Notifier contacts at the same time.
+var notifyMaxConcurrency = 10
+
+// NotifyError is returned by a Notifier when one or more cluster members could not be notified.
+type NotifyError struct {
+	// Errors maps the name of each cluster member that could not be notified to the reason why.
+	Errors map[string]error
+}
+
+// Error returns the errors of all members that could not be notified, sorted by member name.
+func (e *NotifyError) Error() string {
+	if len(e.Errors) == 1 {
+		for _, err := range e.Errors {
+			return err.Error()
+		}
+	}
+
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	msgs := make([]string, 0, len(names))
+	for _, name := range names {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", name, e.Errors[name]))
+	}
+
+	return fmt.Sprintf("Failed to notify %d cluster members: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the underlying errors so that they can be inspected with errors.Is and errors.As.
+func (e *NotifyError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
 // NewNotifier builds a Notifier that can be used to notify other peers using
 // the given policy.
 func NewNotifier(state *state.State, networkCert *shared.CertInfo, serverCert *shared.CertInfo, policy NotifierPolicy) (Notifier, error) {
@@ -59,7 +103,7 @@ func NewNotifier(state *state.State, networkCert *shared.CertInfo, serverCert *s
 		return nil, err
 	}
 
-	peers := []string{}
+	peers := []db.NodeInfo{}
 	for _, member := range members {
 		if member.Address == localClusterAddress || member.Address == "0.0.0.0" {
 			continue // Exclude ourselves
@@ -81,17 +125,24 @@ func NewNotifier(state *state.State, networkCert *shared.CertInfo, serverCert *s
 			}
 		}
 
-		peers = append(peers, member.Address)
+		peers = append(peers, member)
 	}
 
 	notifier := func(hook func(lxd.InstanceServer) error) error {
 		errs := make([]error, len(peers))
 		wg := sync.WaitGroup{}
 		wg.Add(len(peers))
-		for i, address := range peers {
-			logger.Debugf("Notify node %s of state changes", address)
+
+		// Limit the number of members that are contacted at the same time.
+		sem := make(chan struct{}, notifyMaxConcurrency)
+		for i, member := range peers {
+			logger.Debugf("Notify node %s of state changes", member.Address)
 			go func(i int, address string) {
 				defer wg.Done()
+
+				sem <- struct{}{}
+				defer func() { <-sem }()
+
 				client, err := Connect(address, networkCert, serverCert, nil, true)
 				if err != nil {
 					errs[i] = fmt.Errorf("failed to connect to peer %s: %w", address, err)
@@ -102,21 +153,27 @@ func NewNotifier(state *state.State, networkCert *shared.CertInfo, serverCert *s
 				if err != nil {
 					errs[i] = fmt.Errorf("failed to notify peer %s: %w", address, err)
 				}
-			}(i, address)
+			}(i, member.Address)
 		}
 
 		wg.Wait()
-		// TODO: aggregate all errors?
+
+		notifyErr := &NotifyError{Errors: map[string]error{}}
 		for i, err := range errs {
 			if err != nil {
 				if shared.IsConnectionError(err) && policy == NotifyAlive {
-					logger.Warnf("Could not notify node %s", peers[i])
+					logger.Warnf("Could not notify node %s", peers[i].Address)
 					continue
 				}
 
-				return err
+				notifyErr.Errors[peers[i].Name] = err
 			}
 		}
+
+		if len(notifyErr.Errors) > 0 {
+			return notifyErr
+		}
+
 		return nil
 	}
 
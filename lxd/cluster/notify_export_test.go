@@ -0,0 +1,9 @@
+package cluster
+
+// SetNotifyMaxConcurrency changes the maximum number of cluster members that a Notifier contacts at the same time,
+// and returns a function that restores the previous value.
+func SetNotifyMaxConcurrency(n int) func() {
+	previous := notifyMaxConcurrency
+	notifyMaxConcurrency = n
+	return func() { notifyMaxConcurrency = previous }
+}
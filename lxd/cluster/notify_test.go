@@ -2,9 +2,12 @@ package cluster_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -135,6 +138,120 @@ func TestNewNotify_NotifyAlive(t *testing.T) {
 	assert.Equal(t, 1, i)
 }
 
+// A slow cluster member does not delay the notification of the other members.
+func TestNewNotify_SlowMember(t *testing.T) {
+	state, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	cert := shared.TestingKeyPair()
+
+	f := notifyFixtures{t: t, state: state}
+	defer f.Nodes(cert, 3)()
+
+	f.LoadLocalConfig()
+
+	slowAddress := f.Address(1)
+
+	notifier, err := cluster.NewNotifier(state, cert, cert, cluster.NotifyAll)
+	require.NoError(t, err)
+
+	// The slow member only completes once the other member has been notified, which can only happen if members
+	// are notified concurrently.
+	fastNotified := make(chan struct{})
+	hook := func(client lxd.InstanceServer) error {
+		server, _, err := client.GetServer()
+		if err != nil {
+			return err
+		}
+
+		if server.Config["cluster.https_address"].(string) != slowAddress {
+			close(fastNotified)
+			return nil
+		}
+
+		select {
+		case <-fastNotified:
+			return nil
+		case <-time.After(5 * time.Second):
+			return fmt.Errorf("Other member was not notified while waiting for slow member")
+		}
+	}
+
+	assert.NoError(t, notifier(hook))
+}
+
+// The number of members that are notified at the same time is bounded.
+func TestNewNotify_MaxConcurrency(t *testing.T) {
+	state, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	defer cluster.SetNotifyMaxConcurrency(1)()
+
+	cert := shared.TestingKeyPair()
+
+	f := notifyFixtures{t: t, state: state}
+	defer f.Nodes(cert, 4)()
+
+	f.LoadLocalConfig()
+
+	notifier, err := cluster.NewNotifier(state, cert, cert, cluster.NotifyAll)
+	require.NoError(t, err)
+
+	var running, maxRunning, calls int32
+	hook := func(client lxd.InstanceServer) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+
+		for {
+			current := atomic.LoadInt32(&maxRunning)
+			if n <= current || atomic.CompareAndSwapInt32(&maxRunning, current, n) {
+				break
+			}
+		}
+
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}
+
+	assert.NoError(t, notifier(hook))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&maxRunning))
+}
+
+// The errors of all members that could not be notified are returned, keyed by member name.
+func TestNewNotify_AggregateErrors(t *testing.T) {
+	state, cleanup := state.NewTestState(t)
+	defer cleanup()
+
+	cert := shared.TestingKeyPair()
+
+	f := notifyFixtures{t: t, state: state}
+	defer f.Nodes(cert, 4)()
+
+	f.LoadLocalConfig()
+
+	notifier, err := cluster.NewNotifier(state, cert, cert, cluster.NotifyAll)
+	require.NoError(t, err)
+
+	hook := func(client lxd.InstanceServer) error {
+		return api.StatusErrorf(http.StatusConflict, "Boom")
+	}
+
+	err = notifier(hook)
+	require.Error(t, err)
+
+	var notifyErr *cluster.NotifyError
+	require.True(t, errors.As(err, &notifyErr))
+	assert.Len(t, notifyErr.Errors, 3)
+	for _, name := range []string{"1", "2", "3"} {
+		assert.Contains(t, notifyErr.Errors, name)
+	}
+
+	assert.True(t, api.StatusErrorCheck(err, http.StatusConflict))
+	assert.Regexp(t, "Failed to notify 3 cluster members: 1: .+; 2: .+; 3: .+", err.Error())
+}
+
 // Helper for setting fixtures for Notify tests.
 type notifyFixtures struct {
 	t       *testing.T
@@ -195,6 +312,19 @@ func (h *notifyFixtures) Nodes(cert *shared.CertInfo, n int) func() {
 	return cleanup
 }
 
+// Populate state.LocalConfig after nodes have been created.
+func (h *notifyFixtures) LoadLocalConfig() {
+	var err error
+	var nodeConfig *node.Config
+	err = h.state.DB.Node.Transaction(context.TODO(), func(ctx context.Context, tx *db.NodeTx) error {
+		nodeConfig, err = node.ConfigLoad(ctx, tx)
+		return err
+	})
+	require.NoError(h.t, err)
+
+	h.state.LocalConfig = nodeConfig
+}
+
 // Return the network address of the i-th node.
 func (h *notifyFixtures) Address(i int) string {
 	var address string
@@ -25,7 +25,6 @@ import (
 	liblxc "github.com/lxc/go-lxc"
 	"golang.org/x/sys/unix"
 
-	"github.com/canonical/lxd/client"
 	"github.com/canonical/lxd/lxd/acme"
 	"github.com/canonical/lxd/lxd/apparmor"
 	"github.com/canonical/lxd/lxd/auth"
@@ -80,6 +79,7 @@ type Daemon struct {
 	identityCache *identity.Cache
 	os            *sys.OS
 	db            *db.DB
+	dbReplica     *db.Replica // Local copy of the global database used to serve stale reads.
 	firewall      firewall.Firewall
 	maas          *maas.Controller
 	bgp           *bgp.Server
@@ -99,6 +99,9 @@ type Daemon struct {
 	taskPruneImages      *task.Task
 	taskClusterHeartbeat *task.Task
 
+	// Task replenishing the instance pools, reset to run it right away when a pool needs instances
+	taskInstancePools *task.Task
+
 	// Stores startup time of daemon
 	startTime time.Time
 
@@ -234,6 +237,10 @@ type APIEndpointAction struct {
 	Handler        func(d *Daemon, r *http.Request) response.Response
 	AccessHandler  func(d *Daemon, r *http.Request) response.Response
 	AllowUntrusted bool
+
+	// NoStaleReads prevents GET requests from being served from the local copy of the global database, for
+	// endpoints whose reads have side effects or must see the latest state (see Daemon.staleReadRequest).
+	NoStaleReads bool
 }
 
 // allowAuthenticated is an AccessHandler which allows only authenticated requests. This should be used in conjunction
@@ -381,6 +388,11 @@ func (d *Daemon) Authenticate(w http.ResponseWriter, r *http.Request) (trusted b
 		for _, i := range r.TLS.PeerCertificates {
 			trusted, username := util.CheckTrustState(*i, d.identityCache.X509Certificates(api.IdentityTypeCertificateMetrics), d.endpoints.NetworkCert(), trustCACertificates)
 			if trusted {
+				err := d.identityCache.CheckEnabled(api.AuthenticationMethodTLS, username)
+				if err != nil {
+					return false, "", "", nil, err
+				}
+
 				return true, username, api.AuthenticationMethodTLS, nil, nil
 			}
 		}
@@ -389,6 +401,12 @@ func (d *Daemon) Authenticate(w http.ResponseWriter, r *http.Request) (trusted b
 	for _, i := range r.TLS.PeerCertificates {
 		trusted, username := util.CheckTrustState(*i, d.identityCache.X509Certificates(api.IdentityTypeCertificateClientRestricted, api.IdentityTypeCertificateClientUnrestricted), d.endpoints.NetworkCert(), trustCACertificates)
 		if trusted {
+			// Reject disabled identities before any authorization takes place.
+			err := d.identityCache.CheckEnabled(api.AuthenticationMethodTLS, username)
+			if err != nil {
+				return false, "", "", nil, err
+			}
+
 			return true, username, api.AuthenticationMethodTLS, nil, nil
 		}
 	}
@@ -397,18 +415,47 @@ func (d *Daemon) Authenticate(w http.ResponseWriter, r *http.Request) (trusted b
 	return false, "", "", nil, nil
 }
 
+// identityProviderGroupsEqual returns whether the given slices contain the same identity provider groups in any order.
+func identityProviderGroupsEqual(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for _, group := range a {
+		if !shared.ValueInSlice(group, b) {
+			return false
+		}
+	}
+
+	for _, group := range b {
+		if !shared.ValueInSlice(group, a) {
+			return false
+		}
+	}
+
+	return true
+}
+
 // handleOIDCAuthenticationResult checks the identity cache for the OIDC identity by their email address. If no identity
-// is found, an identity is added with that email. If an identity is found but the OIDC subject is different to the
-// expected value, the identity is updated with the new subject.
+// is found, an identity is added with that email. If an identity is found but the OIDC subject or identity provider
+// groups are different to the expected values, the identity is updated with the new values. A forbidden error is returned
+// if the identity is disabled, or if auth.require_group_membership is enabled and the identity is not a member of any group.
 func (d *Daemon) handleOIDCAuthenticationResult(r *http.Request, result *oidc.AuthenticationResult) error {
 	var action lifecycle.IdentityAction
 
+	// The identity is created or updated on behalf of the user authenticating.
+	requestor := &api.EventLifecycleRequestor{
+		Username: result.Email,
+		Protocol: api.AuthenticationMethodOIDC,
+		Address:  r.RemoteAddr,
+	}
+
 	id, err := d.identityCache.Get(api.AuthenticationMethodOIDC, result.Email)
 	if err != nil && !api.StatusErrorCheck(err, http.StatusNotFound) {
 		return fmt.Errorf("Failed getting OIDC identity from cache: %w", err)
 	} else if err != nil {
 		// Identity not found. Add it to the database and refresh the identity cache.
-		idMetadata := dbCluster.OIDCMetadata{Subject: result.Subject}
+		idMetadata := dbCluster.OIDCMetadata{Subject: result.Subject, IdentityProviderGroups: result.IdentityProviderGroups}
 		b, err := json.Marshal(idMetadata)
 		if err != nil {
 			return fmt.Errorf("Failed to marshal OIDC identity metadata: %w", err)
@@ -422,30 +469,54 @@ func (d *Daemon) handleOIDCAuthenticationResult(r *http.Request, result *oidc.Au
 				Name:       result.Name,
 				Metadata:   string(b),
 			})
-			return err
+			if err != nil {
+				return err
+			}
+
+			after, err := authAuditIdentityState(ctx, tx.Tx(), api.AuthenticationMethodOIDC, result.Email)
+			if err != nil {
+				return err
+			}
+
+			return authAuditRecord(ctx, tx.Tx(), requestor, api.AuthAuditObjectTypeIdentity, authAuditIdentityName(api.AuthenticationMethodOIDC, result.Email), api.AuthAuditActionCreated, nil, after)
 		})
 		if err != nil {
 			return fmt.Errorf("Failed to add new OIDC identity to database: %w", err)
 		}
 
 		action = lifecycle.IdentityCreated
-	} else if id.Subject != result.Subject || id.Name != result.Name {
-		// The OIDC subject of the user with this email address has changed (this should be rare). Replace the
-		// subject in the identity metadata and refresh the cache.
-		idMetadata := dbCluster.OIDCMetadata{Subject: result.Subject}
+	} else if id.Subject != result.Subject || id.Name != result.Name || !identityProviderGroupsEqual(id.IdentityProviderGroups, result.IdentityProviderGroups) {
+		// The OIDC subject of the user with this email address has changed (this should be rare), or the user
+		// authenticated with different identity provider groups. Replace the identity metadata and refresh the cache.
+		idMetadata := dbCluster.OIDCMetadata{Subject: result.Subject, IdentityProviderGroups: result.IdentityProviderGroups}
 		b, err := json.Marshal(idMetadata)
 		if err != nil {
 			return fmt.Errorf("Failed to marshal OIDC identity metadata: %w", err)
 		}
 
 		err = d.db.Cluster.Transaction(d.shutdownCtx, func(ctx context.Context, tx *db.ClusterTx) error {
-			return dbCluster.UpdateIdentity(ctx, tx.Tx(), api.AuthenticationMethodOIDC, result.Email, dbCluster.Identity{
+			before, err := authAuditIdentityState(ctx, tx.Tx(), api.AuthenticationMethodOIDC, result.Email)
+			if err != nil {
+				return err
+			}
+
+			err = dbCluster.UpdateIdentity(ctx, tx.Tx(), api.AuthenticationMethodOIDC, result.Email, dbCluster.Identity{
 				AuthMethod: api.AuthenticationMethodOIDC,
 				Type:       api.IdentityTypeOIDCClient,
 				Identifier: result.Email,
 				Name:       result.Name,
 				Metadata:   string(b),
 			})
+			if err != nil {
+				return err
+			}
+
+			after, err := authAuditIdentityState(ctx, tx.Tx(), api.AuthenticationMethodOIDC, result.Email)
+			if err != nil {
+				return err
+			}
+
+			return authAuditRecord(ctx, tx.Tx(), requestor, api.AuthAuditObjectTypeIdentity, authAuditIdentityName(api.AuthenticationMethodOIDC, result.Email), api.AuthAuditActionUpdated, before, after)
 		})
 		if err != nil {
 			return fmt.Errorf("Failed to update OIDC identity information: %w", err)
@@ -462,10 +533,7 @@ func (d *Daemon) handleOIDCAuthenticationResult(r *http.Request, result *oidc.Au
 			return fmt.Errorf("Failed to notify cluster members of new or updated OIDC identity: %w", err)
 		}
 
-		err = notifier(func(client lxd.InstanceServer) error {
-			_, _, err := client.RawQuery(http.MethodPost, "/internal/identity-cache-refresh", nil, "")
-			return err
-		})
+		err = notifier(identityCacheRefreshMember)
 		if err != nil {
 			return fmt.Errorf("Failed to notify cluster members of new or updated OIDC identity: %w", err)
 		}
@@ -476,6 +544,25 @@ func (d *Daemon) handleOIDCAuthenticationResult(r *http.Request, result *oidc.Au
 		s.UpdateIdentityCache()
 	}
 
+	// Reject disabled identities before any authorization takes place.
+	err = d.identityCache.CheckEnabled(api.AuthenticationMethodOIDC, result.Email)
+	if err != nil {
+		return err
+	}
+
+	// Refuse identities that are not a member of any group if required, rather than letting them authenticate without
+	// being allowed to do anything. The identity is kept so that an administrator can add it to a group.
+	if d.globalConfig.AuthRequireGroupMembership() {
+		id, err := d.identityCache.Get(api.AuthenticationMethodOIDC, result.Email)
+		if err != nil {
+			return fmt.Errorf("Failed getting OIDC identity from cache: %w", err)
+		}
+
+		if len(d.identityCache.GetEffectiveGroups(api.AuthenticationMethodOIDC, result.Email, id.Groups)) == 0 {
+			return api.StatusErrorf(http.StatusForbidden, "The identity %q is not a member of any group. An administrator must add it to a group before it can log in", result.Email)
+		}
+	}
+
 	return nil
 }
 
@@ -547,6 +634,10 @@ func (d *Daemon) createCmd(restAPI *mux.Router, version string, c APIEndpoint) {
 	route := restAPI.HandleFunc(uri, func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
+		// Use the address of the client for requests relayed by a trusted proxy.
+		proxyAddress := r.RemoteAddr
+		r.RemoteAddr = util.ProxyClientAddress(r, d.endpoints.NetworkTrustedProxies())
+
 		if !(r.RemoteAddr == "@" && version == "internal") {
 			// Block public API requests until we're done with basic
 			// initialization tasks, such setting up the cluster database.
@@ -590,6 +681,10 @@ func (d *Daemon) createCmd(restAPI *mux.Router, version string, c APIEndpoint) {
 		}
 
 		logCtx := logger.Ctx{"method": r.Method, "url": r.URL.RequestURI(), "ip": r.RemoteAddr, "protocol": protocol}
+		if proxyAddress != r.RemoteAddr {
+			logCtx["proxy"] = proxyAddress
+		}
+
 		if protocol == "cluster" {
 			logCtx["fingerprint"] = username
 		} else {
@@ -710,6 +805,15 @@ func (d *Daemon) createCmd(restAPI *mux.Router, version string, c APIEndpoint) {
 			return action.Handler(d, r)
 		}
 
+		// Serve GET requests from the local copy of the global database if the client accepts stale data.
+		if r.Method == "GET" && version == "1.0" && trusted {
+			r, err = d.staleReadRequest(w, r, c.Get)
+			if err != nil {
+				_ = response.SmartError(err).Render(w)
+				return
+			}
+		}
+
 		switch r.Method {
 		case "GET":
 			resp = handleRequest(c.Get)
@@ -1245,6 +1349,11 @@ func (d *Daemon) init() error {
 		return fmt.Errorf("Failed to initialize global database: %w", err)
 	}
 
+	d.dbReplica, err = db.NewReplica(d.os.GlobalDatabaseReplicaDir())
+	if err != nil {
+		return err
+	}
+
 	d.firewall = firewall.New()
 	logger.Info("Firewall loaded driver", logger.Ctx{"driver": d.firewall})
 
@@ -1410,7 +1519,8 @@ func (d *Daemon) init() error {
 	maasAPIURL, maasAPIKey = d.globalConfig.MAASController()
 	d.gateway.HeartbeatOfflineThreshold = d.globalConfig.OfflineThreshold()
 	lokiURL, lokiUsername, lokiPassword, lokiCACert, lokiInstance, lokiLoglevel, lokiLabels, lokiTypes := d.globalConfig.LokiServer()
-	oidcIssuer, oidcClientID, oidcAudience, oidcGroupsClaim := d.globalConfig.OIDCServer()
+	oidcIssuer, oidcClientID, oidcAudience := d.globalConfig.OIDCServer()
+	oidcGroupsClaim, oidcNameClaim, oidcEmailClaim := d.globalConfig.OIDCClaims()
 	syslogSocketEnabled := d.localConfig.SyslogSocket()
 	instancePlacementScriptlet := d.globalConfig.InstancesPlacementScriptlet()
 
@@ -1438,7 +1548,7 @@ func (d *Daemon) init() error {
 			return util.HTTPClient("", d.proxy)
 		}
 
-		d.oidcVerifier, err = oidc.NewVerifier(oidcIssuer, oidcClientID, oidcAudience, d.serverCert, d.identityCache, httpClientFunc, &oidc.Opts{GroupsClaim: oidcGroupsClaim})
+		d.oidcVerifier, err = oidc.NewVerifier(oidcIssuer, oidcClientID, oidcAudience, d.serverCert, d.identityCache, httpClientFunc, &oidc.Opts{GroupsClaim: oidcGroupsClaim, NameClaim: oidcNameClaim, EmailClaim: oidcEmailClaim, LoginHook: d.handleOIDCAuthenticationResult})
 		if err != nil {
 			return err
 		}
@@ -1585,6 +1695,16 @@ func (d *Daemon) init() error {
 		// Read the trusted identities
 		updateIdentityCache(d)
 
+		err = checkAuthGroupsBroadAdminAccess(d.shutdownCtx, d.State())
+		if err != nil {
+			logger.Warn("Failed checking authorization groups for broad administrative access", logger.Ctx{"err": err})
+		}
+
+		err = checkAuthGroupsConfinedProjects(d.shutdownCtx, d.State())
+		if err != nil {
+			logger.Warn("Failed checking authorization groups for permissions outside of the projects of restricted identities", logger.Ctx{"err": err})
+		}
+
 		// Connect to MAAS
 		if maasAPIURL != "" {
 			go func() {
@@ -1683,6 +1803,12 @@ func (d *Daemon) init() error {
 		// Prune expired instance snapshots and take snapshot of instances (minutely check of configurable cron expression)
 		d.tasks.Add(pruneExpiredAndAutoCreateInstanceSnapshotsTask(d))
 
+		// Start and stop instances according to their boot schedule (minutely check of configurable cron expression)
+		d.tasks.Add(autoStartStopInstancesTask(d))
+
+		// Create and delete instances so that instance pools have as many available instances as their size (minutely)
+		d.taskInstancePools = d.tasks.Add(autoReplenishInstancePoolsTask(d))
+
 		// Prune expired custom volume snapshots and take snapshots of custom volumes (minutely check of configurable cron expression)
 		d.tasks.Add(pruneExpiredAndAutoCreateCustomVolumeSnapshotsTask(d))
 
@@ -1694,6 +1820,21 @@ func (d *Daemon) init() error {
 
 		// Remove expired tokens (hourly)
 		d.tasks.Add(autoRemoveExpiredTokensTask(d))
+
+		// Record when groups were last used (minutely)
+		d.tasks.Add(updateAuthGroupsLastUsedTask(d))
+
+		// Warn about identities that are not a member of any group (daily)
+		d.tasks.Add(checkIdentitiesWithoutGroupsTask(d))
+
+		// Prune the auth audit trail past its retention period (daily)
+		d.tasks.Add(pruneAuthAuditTask(d))
+
+		// Rotate the console history of virtual machines (minutely)
+		d.tasks.Add(rotateConsoleHistoryTask(d))
+
+		// Pause instances using unreachable storage pools and resume them on recovery (every 10s)
+		d.tasks.Add(storagePoolHealthTask(d))
 	}
 
 	// Start all background tasks
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"time"
 
 	"github.com/canonical/lxd/client"
@@ -47,6 +48,72 @@ type ImageDownloadArgs struct {
 	SourceProjectName string
 }
 
+// Counters of the image transfers to this member that offered delta sources.
+var (
+	imageDeltaHits       atomic.Uint64
+	imageDeltaMisses     atomic.Uint64
+	imageDeltaSavedBytes atomic.Uint64
+)
+
+// imageDeltaSourcePath returns the path of the given file ("metadata" or "rootfs") of a local image, or an empty string
+// if it isn't available. It is the DeltaSourceRetriever of image downloads.
+func imageDeltaSourcePath(fingerprint string, file string) string {
+	path := shared.VarPath("images", fingerprint)
+	if file != "metadata" {
+		path += "." + file
+	}
+
+	if shared.PathExists(path) {
+		return path
+	}
+
+	return ""
+}
+
+// imageDeltaSources returns the fingerprints of the previous versions of the image that are available locally and
+// that it can be transferred as a delta against, if enabled. See GetImageDeltaSourcesFingerprints.
+func imageDeltaSources(s *state.State, projectName string, fingerprint string, server string, alias string) []string {
+	if !s.GlobalConfig.ImagesDeltaTransfer() {
+		return nil
+	}
+
+	var fingerprints []string
+	err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		var err error
+		fingerprints, err = tx.GetImageDeltaSourcesFingerprints(ctx, projectName, fingerprint, server, alias)
+
+		return err
+	})
+	if err != nil {
+		logger.Warn("Failed getting image delta sources", logger.Ctx{"err": err, "project": projectName, "fingerprint": fingerprint})
+		return nil
+	}
+
+	sources := make([]string, 0, len(fingerprints))
+	for _, source := range fingerprints {
+		if imageDeltaSourcePath(source, "metadata") != "" {
+			sources = append(sources, source)
+		}
+	}
+
+	return sources
+}
+
+// imageDeltaRecord records the outcome of an image transfer that offered delta sources in the metrics.
+func imageDeltaRecord(resp *lxd.ImageFileResponse) {
+	if resp.DeltaSource == "" {
+		imageDeltaMisses.Add(1)
+		return
+	}
+
+	imageDeltaHits.Add(1)
+
+	size := resp.MetaSize + resp.RootfsSize
+	if size > resp.DeltaSize {
+		imageDeltaSavedBytes.Add(uint64(size - resp.DeltaSize))
+	}
+}
+
 // imageOperationLock acquires a lock for operating on an image and returns the unlock function.
 func imageOperationLock(fingerprint string) (locking.UnlockFunc, error) {
 	l := logger.AddContext(logger.Ctx{"fingerprint": fingerprint})
@@ -389,18 +456,17 @@ func ImageDownload(r *http.Request, s *state.State, op *operations.Operation, ar
 		// Download the image
 		var resp *lxd.ImageFileResponse
 		request := lxd.ImageFileRequest{
-			MetaFile:        io.WriteSeeker(dest),
-			RootfsFile:      io.WriteSeeker(destRootfs),
-			ProgressHandler: progress,
-			Canceler:        canceler,
-			DeltaSourceRetriever: func(fingerprint string, file string) string {
-				path := shared.VarPath("images", fmt.Sprintf("%s.%s", fingerprint, file))
-				if shared.PathExists(path) {
-					return path
-				}
+			MetaFile:             io.WriteSeeker(dest),
+			RootfsFile:           io.WriteSeeker(destRootfs),
+			ProgressHandler:      progress,
+			Canceler:             canceler,
+			DeltaSourceRetriever: imageDeltaSourcePath,
+		}
 
-				return ""
-			},
+		// Previous versions of the image from the same source can be used as delta sources (simplestreams
+		// servers advertise their own deltas).
+		if protocol == "lxd" {
+			request.DeltaSourceFingerprints = imageDeltaSources(s, args.ProjectName, fp, args.Server, alias)
 		}
 
 		if args.Secret != "" {
@@ -413,6 +479,10 @@ func ImageDownload(r *http.Request, s *state.State, op *operations.Operation, ar
 			return nil, err
 		}
 
+		if len(request.DeltaSourceFingerprints) > 0 {
+			imageDeltaRecord(resp)
+		}
+
 		// Truncate down to size
 		if resp.RootfsSize > 0 {
 			err = destRootfs.Truncate(resp.RootfsSize)
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/canonical/lxd/lxd/cluster"
+	"github.com/canonical/lxd/lxd/request"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+)
+
+// databaseReplicaRefreshInterval is the minimum interval between two refreshes of the local copy of the global
+// database.
+const databaseReplicaRefreshInterval = time.Second
+
+// staleReadRequest returns the request to serve for a GET request to the given endpoint action.
+//
+// If the client accepts stale data (see request.HeaderAllowStale) and the local copy of the global database is within
+// the accepted staleness, the returned request carries the copy in its context, so that the cluster database
+// transactions made with the request context don't go through the leader, and the staleness of the copy is set in the
+// response headers. Otherwise the request is returned as is and is served from the global database.
+//
+// The local copy is only kept up to date while requests use it, so the first requests accepting stale data on a member
+// are served from the global database.
+func (d *Daemon) staleReadRequest(w http.ResponseWriter, r *http.Request, action APIEndpointAction) (*http.Request, error) {
+	header := r.Header.Get(request.HeaderAllowStale)
+	if header == "" {
+		return r, nil
+	}
+
+	maxStaleness, err := time.ParseDuration(header)
+	if err != nil || maxStaleness < 0 {
+		return nil, api.StatusErrorf(http.StatusBadRequest, "Invalid %q header %q", request.HeaderAllowStale, header)
+	}
+
+	if action.NoStaleReads || d.dbReplica == nil || !d.serverClustered {
+		return r, nil
+	}
+
+	go d.refreshDatabaseReplica()
+
+	staleness, err := d.dbReplica.Staleness(maxStaleness)
+	if err != nil {
+		return r, nil
+	}
+
+	w.Header().Set(request.HeaderStaleness, staleness.Round(time.Millisecond).String())
+
+	return r.WithContext(context.WithValue(r.Context(), request.CtxDatabaseReplica, d.dbReplica)), nil
+}
+
+// refreshDatabaseReplica refreshes the local copy of the global database, unless it was refreshed recently. Members
+// that don't hold a copy of the global database, or that are the leader, have nothing to refresh.
+func (d *Daemon) refreshDatabaseReplica() {
+	err := d.dbReplica.Refresh(databaseReplicaRefreshInterval, d.db.Cluster.GetNodeID(), d.gateway.DumpReplica)
+	if err != nil && !errors.Is(err, cluster.ErrNotReplica) {
+		logger.Warn("Failed refreshing local copy of the global database", logger.Ctx{"err": err})
+	}
+}
@@ -0,0 +1,181 @@
+package cluster
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/canonical/lxd/lxd/db/query"
+	"github.com/canonical/lxd/shared/api"
+)
+
+// AuthAuditEntry is the database representation of an api.AuthAuditEntry. The before and after states of the changed
+// object are JSON encoded, and empty if the object didn't exist before or after the change.
+type AuthAuditEntry struct {
+	ID            int64
+	Date          time.Time
+	Actor         string
+	ActorProtocol string
+	ObjectType    string
+	ObjectName    string
+	Action        string
+	Before        string
+	After         string
+	PreviousHash  string
+	Hash          string
+}
+
+// AuthAuditEntryFilter filters the entries returned by GetAuthAuditEntries. Zero values match all entries.
+type AuthAuditEntryFilter struct {
+	Since      time.Time
+	ObjectType string
+}
+
+// ToAPI converts the AuthAuditEntry to an api.AuthAuditEntry.
+func (e AuthAuditEntry) ToAPI() api.AuthAuditEntry {
+	entry := api.AuthAuditEntry{
+		ID:            e.ID,
+		Date:          e.Date,
+		Actor:         e.Actor,
+		ActorProtocol: e.ActorProtocol,
+		ObjectType:    e.ObjectType,
+		ObjectName:    e.ObjectName,
+		Action:        e.Action,
+		PreviousHash:  e.PreviousHash,
+		Hash:          e.Hash,
+	}
+
+	if e.Before != "" {
+		entry.Before = json.RawMessage(e.Before)
+	}
+
+	if e.After != "" {
+		entry.After = json.RawMessage(e.After)
+	}
+
+	return entry
+}
+
+// authAuditEntryHash returns the hash of the entry, which covers the hash of the previous entry. The fields are
+// separated by null bytes, which can't appear in any of them.
+func authAuditEntryHash(entry AuthAuditEntry) string {
+	fields := []string{
+		entry.PreviousHash,
+		entry.Date.UTC().Format(time.RFC3339),
+		entry.Actor,
+		entry.ActorProtocol,
+		entry.ObjectType,
+		entry.ObjectName,
+		entry.Action,
+		entry.Before,
+		entry.After,
+	}
+
+	hash := sha256.New()
+	for _, field := range fields {
+		_, _ = hash.Write([]byte(field))
+		_, _ = hash.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// CreateAuthAuditEntry appends the entry to the audit trail and returns its ID. The date of the entry is truncated to
+// the second, and its hash is chained to the hash of the last entry.
+func CreateAuthAuditEntry(ctx context.Context, tx *sql.Tx, entry AuthAuditEntry) (int64, error) {
+	err := tx.QueryRowContext(ctx, "SELECT hash FROM auth_audit ORDER BY id DESC LIMIT 1").Scan(&entry.PreviousHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return -1, fmt.Errorf("Failed to get the last auth audit entry: %w", err)
+	}
+
+	entry.Date = entry.Date.UTC().Truncate(time.Second)
+	entry.Hash = authAuditEntryHash(entry)
+
+	res, err := tx.ExecContext(ctx, `
+INSERT INTO auth_audit (date, actor, actor_protocol, object_type, object_name, action, before, after, previous_hash, hash)
+VALUES (?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?)`,
+		entry.Date, entry.Actor, entry.ActorProtocol, entry.ObjectType, entry.ObjectName, entry.Action, entry.Before, entry.After, entry.PreviousHash, entry.Hash)
+	if err != nil {
+		return -1, fmt.Errorf("Failed to create auth audit entry: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return -1, fmt.Errorf("Failed to get ID of auth audit entry: %w", err)
+	}
+
+	return id, nil
+}
+
+// GetAuthAuditEntries returns the entries matching the filter, ordered by ID. At most limit entries are returned if
+// limit is positive, after skipping offset entries.
+func GetAuthAuditEntries(ctx context.Context, tx *sql.Tx, filter AuthAuditEntryFilter, limit int, offset int) ([]AuthAuditEntry, error) {
+	var where []string
+	var args []any
+	if !filter.Since.IsZero() {
+		where = append(where, "date >= ?")
+		args = append(args, filter.Since.UTC())
+	}
+
+	if filter.ObjectType != "" {
+		where = append(where, "object_type = ?")
+		args = append(args, filter.ObjectType)
+	}
+
+	stmt := `
+SELECT id, date, actor, actor_protocol, object_type, object_name, action, IFNULL(before, ''), IFNULL(after, ''), previous_hash, hash
+FROM auth_audit`
+	if len(where) > 0 {
+		stmt += "\nWHERE " + strings.Join(where, " AND ")
+	}
+
+	stmt += "\nORDER BY id"
+	if limit > 0 {
+		stmt += "\nLIMIT ? OFFSET ?"
+		args = append(args, limit, offset)
+	} else if offset > 0 {
+		stmt += "\nLIMIT -1 OFFSET ?"
+		args = append(args, offset)
+	}
+
+	var entries []AuthAuditEntry
+	dest := func(scan func(dest ...any) error) error {
+		e := AuthAuditEntry{}
+		err := scan(&e.ID, &e.Date, &e.Actor, &e.ActorProtocol, &e.ObjectType, &e.ObjectName, &e.Action, &e.Before, &e.After, &e.PreviousHash, &e.Hash)
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, e)
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, stmt, dest, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get auth audit entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// DeleteAuthAuditEntriesBefore deletes the entries made before the given date and returns how many were deleted.
+func DeleteAuthAuditEntriesBefore(ctx context.Context, tx *sql.Tx, date time.Time) (int64, error) {
+	res, err := tx.ExecContext(ctx, "DELETE FROM auth_audit WHERE date < ?", date.UTC())
+	if err != nil {
+		return -1, fmt.Errorf("Failed to delete auth audit entries: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return -1, fmt.Errorf("Failed to get the number of deleted auth audit entries: %w", err)
+	}
+
+	return n, nil
+}
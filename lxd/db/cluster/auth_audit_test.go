@@ -0,0 +1,84 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthAuditEntries(t *testing.T) {
+	schema := Schema()
+	db, err := schema.ExerciseUpdate(SchemaVersion, nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now()
+	entries := []AuthAuditEntry{
+		{Date: now.Add(-48 * time.Hour), Actor: "admin", ActorProtocol: "tls", ObjectType: "group", ObjectName: "g1", Action: "created", After: `{"name":"g1"}`},
+		{Date: now.Add(-time.Hour), Actor: "admin", ActorProtocol: "tls", ObjectType: "group", ObjectName: "g1", Action: "deleted", Before: `{"name":"g1"}`},
+		{Date: now, Actor: "jane@example.com", ActorProtocol: "oidc", ObjectType: "role", ObjectName: "r1", Action: "created", After: `{"name":"r1"}`},
+	}
+
+	for _, entry := range entries {
+		_, err = CreateAuthAuditEntry(ctx, tx, entry)
+		require.NoError(t, err)
+	}
+
+	// Entries are chained by their hashes.
+	all, err := GetAuthAuditEntries(ctx, tx, AuthAuditEntryFilter{}, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	assert.Empty(t, all[0].PreviousHash)
+	for i, entry := range all {
+		assert.Equal(t, authAuditEntryHash(entry), entry.Hash)
+		if i > 0 {
+			assert.Equal(t, all[i-1].Hash, entry.PreviousHash)
+		}
+	}
+
+	assert.Empty(t, all[0].Before)
+	assert.Equal(t, `{"name":"g1"}`, all[0].After)
+	assert.Equal(t, `{"name":"g1"}`, all[1].Before)
+	assert.Empty(t, all[1].After)
+
+	// Entries can be filtered and paginated.
+	filtered, err := GetAuthAuditEntries(ctx, tx, AuthAuditEntryFilter{ObjectType: "group"}, 0, 0)
+	require.NoError(t, err)
+	assert.Len(t, filtered, 2)
+
+	filtered, err = GetAuthAuditEntries(ctx, tx, AuthAuditEntryFilter{Since: now.Add(-2 * time.Hour)}, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, filtered, 2)
+	assert.Equal(t, all[1].ID, filtered[0].ID)
+
+	filtered, err = GetAuthAuditEntries(ctx, tx, AuthAuditEntryFilter{}, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, filtered, 1)
+	assert.Equal(t, all[1].ID, filtered[0].ID)
+
+	filtered, err = GetAuthAuditEntries(ctx, tx, AuthAuditEntryFilter{}, 0, 2)
+	require.NoError(t, err)
+	require.Len(t, filtered, 1)
+	assert.Equal(t, all[2].ID, filtered[0].ID)
+
+	// Entries can't be modified.
+	_, err = tx.Exec("UPDATE auth_audit SET actor = 'someone' WHERE id = ?", all[0].ID)
+	assert.Error(t, err)
+
+	// Entries past the retention period can be deleted.
+	n, err := DeleteAuthAuditEntriesBefore(ctx, tx, now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	all, err = GetAuthAuditEntries(ctx, tx, AuthAuditEntryFilter{}, 0, 0)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+}
@@ -3,7 +3,11 @@ package cluster
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/canonical/lxd/lxd/db/query"
 	"github.com/canonical/lxd/shared/api"
@@ -55,36 +59,63 @@ func (g *AuthGroup) ToAPI(ctx context.Context, tx *sql.Tx) (*api.AuthGroup, erro
 		},
 	}
 
-	permissions, err := GetPermissionsByAuthGroupID(ctx, tx, g.ID)
+	var err error
+	group.Permissions, err = getAuthGroupAPIPermissions(ctx, tx, g.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	entityURLs, err := GetPermissionEntityURLs(ctx, tx, permissions)
+	parents, err := GetAuthGroupParentsByGroupID(ctx, tx, g.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	apiPermissions := make([]api.Permission, 0, len(permissions))
-	for _, p := range permissions {
-		entityURLs, ok := entityURLs[entity.Type(p.EntityType)]
-		if !ok {
-			return nil, fmt.Errorf("Entity URLs missing for permissions with entity type %q", p.EntityType)
+	group.Parents = make([]string, 0, len(parents))
+	for _, parent := range parents {
+		group.Parents = append(group.Parents, parent.Name)
+	}
+
+	roles, err := GetAuthGroupRolesByGroupID(ctx, tx, g.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	roleEntityURLs, err := GetAuthGroupRoleEntityURLs(ctx, tx, roles)
+	if err != nil {
+		return nil, err
+	}
+
+	group.Roles = AuthGroupRolesToAPI(roles, roleEntityURLs)
+
+	parentIDsByGroupID, err := GetAllAuthGroupParentIDsByGroupIDs(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	group.InheritedPermissions = []api.AuthGroupInheritedPermission{}
+	for _, ancestorID := range AuthGroupAncestorIDs(parentIDsByGroupID, g.ID) {
+		if ancestorID == g.ID {
+			continue
 		}
 
-		u, ok := entityURLs[p.EntityID]
-		if !ok {
-			return nil, fmt.Errorf("Entity URL missing for permission with entity type %q and entity ID `%d`", p.EntityType, p.EntityID)
+		ancestors, err := GetAuthGroups(ctx, tx, AuthGroupFilter{ID: &ancestorID})
+		if err != nil {
+			return nil, err
 		}
 
-		apiPermissions = append(apiPermissions, api.Permission{
-			EntityType:      string(p.EntityType),
-			EntityReference: u.String(),
-			Entitlement:     string(p.Entitlement),
-		})
-	}
+		if len(ancestors) != 1 {
+			return nil, fmt.Errorf("Failed to get ancestor group with ID `%d` of group %q", ancestorID, g.Name)
+		}
 
-	group.Permissions = apiPermissions
+		ancestorPermissions, err := getAuthGroupAPIPermissions(ctx, tx, ancestorID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, permission := range ancestorPermissions {
+			group.InheritedPermissions = append(group.InheritedPermissions, api.AuthGroupInheritedPermission{Permission: permission, Group: ancestors[0].Name})
+		}
+	}
 
 	identities, err := GetIdentitiesByAuthGroupID(ctx, tx, g.ID)
 	if err != nil {
@@ -110,9 +141,240 @@ func (g *AuthGroup) ToAPI(ctx context.Context, tx *sql.Tx) (*api.AuthGroup, erro
 		group.IdentityProviderGroups = append(group.IdentityProviderGroups, idpGroup.Name)
 	}
 
+	group.LastUsedAt, err = GetAuthGroupLastUsedAt(ctx, tx, g.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled, err := GetAuthGroupEnabled(ctx, tx, g.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	group.Enabled = &enabled
+
+	template, err := GetAuthGroupTemplate(ctx, tx, g.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	group.Template = &template
+
 	return group, nil
 }
 
+// getAuthGroupAPIPermissions returns the permissions of the group with the given ID, converted to api.Permission.
+func getAuthGroupAPIPermissions(ctx context.Context, tx *sql.Tx, groupID int) ([]api.Permission, error) {
+	permissions, err := GetPermissionsByAuthGroupID(ctx, tx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	entityURLs, err := GetPermissionEntityURLs(ctx, tx, permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	apiPermissions := make([]api.Permission, 0, len(permissions))
+	for _, p := range permissions {
+		entityURLs, ok := entityURLs[entity.Type(p.EntityType)]
+		if !ok {
+			return nil, fmt.Errorf("Entity URLs missing for permissions with entity type %q", p.EntityType)
+		}
+
+		u, ok := entityURLs[p.EntityID]
+		if !ok {
+			return nil, fmt.Errorf("Entity URL missing for permission with entity type %q and entity ID `%d`", p.EntityType, p.EntityID)
+		}
+
+		apiPermissions = append(apiPermissions, p.ToAPI(u))
+	}
+
+	return apiPermissions, nil
+}
+
+// GetAuthGroupLastUsedAt returns the last time that a permission of the group with the given ID granted access to a
+// request. The zero time is returned if the group has never been used.
+func GetAuthGroupLastUsedAt(ctx context.Context, tx *sql.Tx, groupID int) (time.Time, error) {
+	var lastUsedAt sql.NullTime
+	err := tx.QueryRowContext(ctx, "SELECT last_used_at FROM auth_groups WHERE id = ?", groupID).Scan(&lastUsedAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Failed to get last used time of the group with ID `%d`: %w", groupID, err)
+	}
+
+	return lastUsedAt.Time, nil
+}
+
+// GetAllAuthGroupsLastUsedAt returns a map of group IDs to the last time that a permission of the group with that ID
+// granted access to a request. Groups that have never been used are omitted.
+func GetAllAuthGroupsLastUsedAt(ctx context.Context, tx *sql.Tx) (map[int]time.Time, error) {
+	result := make(map[int]time.Time)
+	dest := func(scan func(dest ...any) error) error {
+		var groupID int
+		var lastUsedAt time.Time
+		err := scan(&groupID, &lastUsedAt)
+		if err != nil {
+			return err
+		}
+
+		result[groupID] = lastUsedAt
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, "SELECT id, last_used_at FROM auth_groups WHERE last_used_at IS NOT NULL", dest)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get last used time of all groups: %w", err)
+	}
+
+	return result, nil
+}
+
+// UpdateAuthGroupsLastUsedAt sets the last used time of the groups with the given names, unless a later time has
+// already been recorded (for example by another cluster member).
+func UpdateAuthGroupsLastUsedAt(ctx context.Context, tx *sql.Tx, lastUsedAt map[string]time.Time) error {
+	for groupName, usedAt := range lastUsedAt {
+		_, err := tx.ExecContext(ctx, "UPDATE auth_groups SET last_used_at = ? WHERE name = ? AND (last_used_at IS NULL OR last_used_at < ?)", usedAt, groupName, usedAt)
+		if err != nil {
+			return fmt.Errorf("Failed to update last used time of group %q: %w", groupName, err)
+		}
+	}
+
+	return nil
+}
+
+// GetAuthGroupEnabled returns whether the group with the given ID is enabled. The permissions of a disabled group
+// aren't granted to its members.
+func GetAuthGroupEnabled(ctx context.Context, tx *sql.Tx, groupID int) (bool, error) {
+	var enabled bool
+	err := tx.QueryRowContext(ctx, "SELECT enabled FROM auth_groups WHERE id = ?", groupID).Scan(&enabled)
+	if err != nil {
+		return false, fmt.Errorf("Failed to get enabled state of the group with ID `%d`: %w", groupID, err)
+	}
+
+	return enabled, nil
+}
+
+// GetAllAuthGroupsEnabled returns a map of group IDs to whether the group with that ID is enabled.
+func GetAllAuthGroupsEnabled(ctx context.Context, tx *sql.Tx) (map[int]bool, error) {
+	result := make(map[int]bool)
+	dest := func(scan func(dest ...any) error) error {
+		var groupID int
+		var enabled bool
+		err := scan(&groupID, &enabled)
+		if err != nil {
+			return err
+		}
+
+		result[groupID] = enabled
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, "SELECT id, enabled FROM auth_groups", dest)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get enabled state of all groups: %w", err)
+	}
+
+	return result, nil
+}
+
+// SetAuthGroupEnabled enables or disables the group with the given ID.
+func SetAuthGroupEnabled(ctx context.Context, tx *sql.Tx, groupID int, enabled bool) error {
+	_, err := tx.ExecContext(ctx, "UPDATE auth_groups SET enabled = ? WHERE id = ?", enabled, groupID)
+	if err != nil {
+		return fmt.Errorf("Failed to set enabled state of the group with ID `%d`: %w", groupID, err)
+	}
+
+	return nil
+}
+
+// GetAuthGroupTemplate returns whether the group with the given ID is a template. Template groups can't have members
+// and their permissions aren't granted.
+func GetAuthGroupTemplate(ctx context.Context, tx *sql.Tx, groupID int) (bool, error) {
+	var template bool
+	err := tx.QueryRowContext(ctx, "SELECT template FROM auth_groups WHERE id = ?", groupID).Scan(&template)
+	if err != nil {
+		return false, fmt.Errorf("Failed to get template state of the group with ID `%d`: %w", groupID, err)
+	}
+
+	return template, nil
+}
+
+// GetAllAuthGroupsTemplate returns a map of group IDs to whether the group with that ID is a template.
+func GetAllAuthGroupsTemplate(ctx context.Context, tx *sql.Tx) (map[int]bool, error) {
+	result := make(map[int]bool)
+	dest := func(scan func(dest ...any) error) error {
+		var groupID int
+		var template bool
+		err := scan(&groupID, &template)
+		if err != nil {
+			return err
+		}
+
+		result[groupID] = template
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, "SELECT id, template FROM auth_groups", dest)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get template state of all groups: %w", err)
+	}
+
+	return result, nil
+}
+
+// SetAuthGroupTemplate sets whether the group with the given ID is a template.
+func SetAuthGroupTemplate(ctx context.Context, tx *sql.Tx, groupID int, template bool) error {
+	_, err := tx.ExecContext(ctx, "UPDATE auth_groups SET template = ? WHERE id = ?", template, groupID)
+	if err != nil {
+		return fmt.Errorf("Failed to set template state of the group with ID `%d`: %w", groupID, err)
+	}
+
+	return nil
+}
+
+// GetAuthGroupNamesFold returns the names of the groups whose name is equal to the given name under Unicode case
+// folding, sorted by name.
+func GetAuthGroupNamesFold(ctx context.Context, tx *sql.Tx, name string) ([]string, error) {
+	names, err := query.SelectStrings(ctx, tx, "SELECT name FROM auth_groups ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get group names: %w", err)
+	}
+
+	var matches []string
+	for _, groupName := range names {
+		if strings.EqualFold(groupName, name) {
+			matches = append(matches, groupName)
+		}
+	}
+
+	return matches, nil
+}
+
+// checkAuthGroupsNotTemplates returns an api.StatusError with http.StatusBadRequest if any of the groups with the
+// given names is a template, as template groups can't have members.
+func checkAuthGroupsNotTemplates(ctx context.Context, tx *sql.Tx, groupNames []string) error {
+	for _, groupName := range groupNames {
+		var template bool
+		err := tx.QueryRowContext(ctx, "SELECT template FROM auth_groups WHERE name = ?", groupName).Scan(&template)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+
+			return fmt.Errorf("Failed to get template state of group %q: %w", groupName, err)
+		}
+
+		if template {
+			return api.StatusErrorf(http.StatusBadRequest, "Group %q is a template and cannot have members", groupName)
+		}
+	}
+
+	return nil
+}
+
 // GetIdentitiesByAuthGroupID returns the identities that are members of the group with the given ID.
 func GetIdentitiesByAuthGroupID(ctx context.Context, tx *sql.Tx, groupID int) ([]Identity, error) {
 	stmt := `
@@ -239,7 +501,7 @@ WHERE auth_groups_permissions.auth_group_id = ?`, permissionColumns())
 	var result []Permission
 	dest := func(scan func(dest ...any) error) error {
 		p := Permission{}
-		err := scan(&p.ID, &p.Entitlement, &p.EntityType, &p.EntityID)
+		err := scan(&p.ID, &p.Entitlement, &p.EntityType, &p.EntityID, &p.SubtreeEntityType)
 		if err != nil {
 			return err
 		}
@@ -267,7 +529,7 @@ JOIN auth_groups_permissions ON permissions.id = auth_groups_permissions.permiss
 	dest := func(scan func(dest ...any) error) error {
 		var groupID int
 		p := Permission{}
-		err := scan(&groupID, &p.ID, &p.Entitlement, &p.EntityType, &p.EntityID)
+		err := scan(&groupID, &p.ID, &p.Entitlement, &p.EntityType, &p.EntityID, &p.SubtreeEntityType)
 		if err != nil {
 			return err
 		}
@@ -305,3 +567,99 @@ func SetAuthGroupPermissions(ctx context.Context, tx *sql.Tx, groupID int, permi
 
 	return nil
 }
+
+// GetAuthGroupParentsByGroupID returns the groups that are direct parents of the group with the given ID.
+func GetAuthGroupParentsByGroupID(ctx context.Context, tx *sql.Tx, groupID int) ([]AuthGroup, error) {
+	stmt := `
+SELECT auth_groups.id, auth_groups.name, auth_groups.description 
+FROM auth_groups 
+JOIN auth_groups_parents ON auth_groups.id = auth_groups_parents.parent_auth_group_id 
+WHERE auth_groups_parents.auth_group_id = ? 
+ORDER BY auth_groups.name`
+
+	var result []AuthGroup
+	dest := func(scan func(dest ...any) error) error {
+		g := AuthGroup{}
+		err := scan(&g.ID, &g.Name, &g.Description)
+		if err != nil {
+			return err
+		}
+
+		result = append(result, g)
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, stmt, dest, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get parents of the group with ID `%d`: %w", groupID, err)
+	}
+
+	return result, nil
+}
+
+// GetAllAuthGroupParentIDsByGroupIDs returns a map of group IDs to the IDs of the direct parents of the group with
+// that ID.
+func GetAllAuthGroupParentIDsByGroupIDs(ctx context.Context, tx *sql.Tx) (map[int][]int, error) {
+	result := make(map[int][]int)
+	dest := func(scan func(dest ...any) error) error {
+		var groupID int
+		var parentID int
+		err := scan(&groupID, &parentID)
+		if err != nil {
+			return err
+		}
+
+		result[groupID] = append(result[groupID], parentID)
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, "SELECT auth_group_id, parent_auth_group_id FROM auth_groups_parents ORDER BY id", dest)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get parents of all groups: %w", err)
+	}
+
+	return result, nil
+}
+
+// SetAuthGroupParents deletes all parents of the group with the given ID from the `auth_groups_parents` table. Then
+// it inserts a new row for each given parent group ID. Callers must check that the parents do not introduce a cycle
+// (see AuthGroupAncestorIDs).
+func SetAuthGroupParents(ctx context.Context, tx *sql.Tx, groupID int, parentIDs []int) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM auth_groups_parents WHERE auth_group_id = ?`, groupID)
+	if err != nil {
+		return fmt.Errorf("Failed to delete existing parents of group with ID `%d`: %w", groupID, err)
+	}
+
+	for _, parentID := range parentIDs {
+		_, err := tx.ExecContext(ctx, `INSERT INTO auth_groups_parents (auth_group_id, parent_auth_group_id) VALUES (?, ?);`, groupID, parentID)
+		if err != nil {
+			return fmt.Errorf("Failed to write group parents: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// AuthGroupAncestorIDs returns the IDs of the transitive parents of the group with the given ID, nearest first,
+// given a map of group IDs to the IDs of their direct parents. Each ancestor is returned once. If the parents form a
+// cycle through the group, the group itself is included in the result.
+func AuthGroupAncestorIDs(parentIDsByGroupID map[int][]int, groupID int) []int {
+	var ancestorIDs []int
+	seen := map[int]bool{}
+	queue := append([]int{}, parentIDsByGroupID[groupID]...)
+	for len(queue) > 0 {
+		ancestorID := queue[0]
+		queue = queue[1:]
+		if seen[ancestorID] {
+			continue
+		}
+
+		seen[ancestorID] = true
+		ancestorIDs = append(ancestorIDs, ancestorID)
+		queue = append(queue, parentIDsByGroupID[ancestorID]...)
+	}
+
+	return ancestorIDs
+}
@@ -0,0 +1,38 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAuthGroupNamesFold(t *testing.T) {
+	schema := Schema()
+	db, err := schema.ExerciseUpdate(SchemaVersion, nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	defer func() { _ = tx.Rollback() }()
+
+	for _, name := range []string{"admins", "Admins", "operators"} {
+		_, err = tx.Exec("INSERT INTO auth_groups (name, description) VALUES (?, '')", name)
+		require.NoError(t, err)
+	}
+
+	names, err := GetAuthGroupNamesFold(ctx, tx, "ADMINS")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Admins", "admins"}, names)
+
+	names, err = GetAuthGroupNamesFold(ctx, tx, "Operators")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"operators"}, names)
+
+	names, err = GetAuthGroupNamesFold(ctx, tx, "viewers")
+	require.NoError(t, err)
+	assert.Empty(t, names)
+}
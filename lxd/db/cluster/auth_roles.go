@@ -0,0 +1,302 @@
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/auth"
+	"github.com/canonical/lxd/lxd/db/query"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
+)
+
+// AuthRole is the database representation of an api.AuthRole. A role is a named bundle of entitlements on entities of
+// a single entity type. Granting a role to a group on an entity grants all entitlements of the role on that entity.
+type AuthRole struct {
+	ID           int
+	Name         string
+	Description  string
+	EntityType   EntityType
+	Entitlements []auth.Entitlement
+}
+
+// AuthGroupRole is a role that is granted to a group on the entity with the given ID. The entity has the entity type
+// of the role.
+type AuthGroupRole struct {
+	RoleID     int
+	RoleName   string
+	EntityType EntityType
+	EntityID   int
+}
+
+// GetAuthRoles returns all roles, ordered by name.
+func GetAuthRoles(ctx context.Context, tx *sql.Tx) ([]AuthRole, error) {
+	return getAuthRoles(ctx, tx, "")
+}
+
+// GetAuthRole returns the role with the given name.
+func GetAuthRole(ctx context.Context, tx *sql.Tx, name string) (*AuthRole, error) {
+	roles, err := getAuthRoles(ctx, tx, "WHERE auth_roles.name = ?", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(roles) == 0 {
+		return nil, api.StatusErrorf(http.StatusNotFound, "Role not found")
+	}
+
+	return &roles[0], nil
+}
+
+// getAuthRoles returns the roles matching the given WHERE clause, along with their entitlements.
+func getAuthRoles(ctx context.Context, tx *sql.Tx, where string, args ...any) ([]AuthRole, error) {
+	stmt := `
+SELECT auth_roles.id, auth_roles.name, auth_roles.description, auth_roles.entity_type
+FROM auth_roles
+` + where + `
+ORDER BY auth_roles.name`
+
+	var roles []AuthRole
+	dest := func(scan func(dest ...any) error) error {
+		r := AuthRole{}
+		err := scan(&r.ID, &r.Name, &r.Description, &r.EntityType)
+		if err != nil {
+			return err
+		}
+
+		roles = append(roles, r)
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, stmt, dest, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get roles: %w", err)
+	}
+
+	if len(roles) == 0 {
+		return roles, nil
+	}
+
+	entitlementsByRoleID := make(map[int][]auth.Entitlement)
+	dest = func(scan func(dest ...any) error) error {
+		var roleID int
+		var entitlement auth.Entitlement
+		err := scan(&roleID, &entitlement)
+		if err != nil {
+			return err
+		}
+
+		entitlementsByRoleID[roleID] = append(entitlementsByRoleID[roleID], entitlement)
+
+		return nil
+	}
+
+	err = query.Scan(ctx, tx, "SELECT auth_role_id, entitlement FROM auth_roles_entitlements ORDER BY entitlement", dest)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get role entitlements: %w", err)
+	}
+
+	for i := range roles {
+		roles[i].Entitlements = entitlementsByRoleID[roles[i].ID]
+	}
+
+	return roles, nil
+}
+
+// CreateAuthRole creates a new role with the given entitlements and returns its ID.
+func CreateAuthRole(ctx context.Context, tx *sql.Tx, role AuthRole) (int64, error) {
+	_, err := GetAuthRole(ctx, tx, role.Name)
+	if err == nil {
+		return -1, api.StatusErrorf(http.StatusConflict, "A role with name %q already exists", role.Name)
+	} else if !api.StatusErrorCheck(err, http.StatusNotFound) {
+		return -1, err
+	}
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO auth_roles (name, description, entity_type) VALUES (?, ?, ?)`, role.Name, role.Description, role.EntityType)
+	if err != nil {
+		return -1, fmt.Errorf("Failed to create role %q: %w", role.Name, err)
+	}
+
+	roleID, err := res.LastInsertId()
+	if err != nil {
+		return -1, fmt.Errorf("Failed to get ID of role %q: %w", role.Name, err)
+	}
+
+	err = setAuthRoleEntitlements(ctx, tx, int(roleID), role.Entitlements)
+	if err != nil {
+		return -1, err
+	}
+
+	return roleID, nil
+}
+
+// UpdateAuthRole updates the description, entity type and entitlements of the role with the given ID.
+func UpdateAuthRole(ctx context.Context, tx *sql.Tx, roleID int, role AuthRole) error {
+	_, err := tx.ExecContext(ctx, `UPDATE auth_roles SET description = ?, entity_type = ? WHERE id = ?`, role.Description, role.EntityType, roleID)
+	if err != nil {
+		return fmt.Errorf("Failed to update role %q: %w", role.Name, err)
+	}
+
+	return setAuthRoleEntitlements(ctx, tx, roleID, role.Entitlements)
+}
+
+// setAuthRoleEntitlements replaces the entitlements of the role with the given ID.
+func setAuthRoleEntitlements(ctx context.Context, tx *sql.Tx, roleID int, entitlements []auth.Entitlement) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM auth_roles_entitlements WHERE auth_role_id = ?`, roleID)
+	if err != nil {
+		return fmt.Errorf("Failed to delete existing entitlements of role with ID `%d`: %w", roleID, err)
+	}
+
+	for _, entitlement := range entitlements {
+		_, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO auth_roles_entitlements (auth_role_id, entitlement) VALUES (?, ?)`, roleID, entitlement)
+		if err != nil {
+			return fmt.Errorf("Failed to write role entitlements: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// DeleteAuthRole deletes the role with the given name.
+func DeleteAuthRole(ctx context.Context, tx *sql.Tx, name string) error {
+	res, err := tx.ExecContext(ctx, `DELETE FROM auth_roles WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("Failed to delete role %q: %w", name, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("Failed to get affected rows to delete role %q: %w", name, err)
+	}
+
+	if n == 0 {
+		return api.StatusErrorf(http.StatusNotFound, "Role not found")
+	}
+
+	return nil
+}
+
+// GetAllAuthGroupNamesByRoleIDs returns a map of role IDs to the names of the groups that the role is granted to.
+func GetAllAuthGroupNamesByRoleIDs(ctx context.Context, tx *sql.Tx) (map[int][]string, error) {
+	stmt := `
+SELECT DISTINCT auth_groups_roles.auth_role_id, auth_groups.name
+FROM auth_groups_roles
+JOIN auth_groups ON auth_groups.id = auth_groups_roles.auth_group_id
+ORDER BY auth_groups.name`
+
+	result := make(map[int][]string)
+	dest := func(scan func(dest ...any) error) error {
+		var roleID int
+		var groupName string
+		err := scan(&roleID, &groupName)
+		if err != nil {
+			return err
+		}
+
+		result[roleID] = append(result[roleID], groupName)
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, stmt, dest)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get groups of all roles: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetAuthGroupRolesByGroupID returns the roles that are granted to the group with the given ID.
+func GetAuthGroupRolesByGroupID(ctx context.Context, tx *sql.Tx, groupID int) ([]AuthGroupRole, error) {
+	groupRoles, err := getAuthGroupRoles(ctx, tx, "WHERE auth_groups_roles.auth_group_id = ?", groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupRoles[groupID], nil
+}
+
+// GetAllAuthGroupRolesByGroupIDs returns a map of group IDs to the roles that are granted to the group with that ID.
+func GetAllAuthGroupRolesByGroupIDs(ctx context.Context, tx *sql.Tx) (map[int][]AuthGroupRole, error) {
+	return getAuthGroupRoles(ctx, tx, "")
+}
+
+// getAuthGroupRoles returns a map of group IDs to the roles granted to the group, for the grants matching the given
+// WHERE clause.
+func getAuthGroupRoles(ctx context.Context, tx *sql.Tx, where string, args ...any) (map[int][]AuthGroupRole, error) {
+	stmt := `
+SELECT auth_groups_roles.auth_group_id, auth_roles.id, auth_roles.name, auth_roles.entity_type, auth_groups_roles.entity_id
+FROM auth_groups_roles
+JOIN auth_roles ON auth_roles.id = auth_groups_roles.auth_role_id
+` + where + `
+ORDER BY auth_roles.name, auth_groups_roles.entity_id`
+
+	result := make(map[int][]AuthGroupRole)
+	dest := func(scan func(dest ...any) error) error {
+		var groupID int
+		r := AuthGroupRole{}
+		err := scan(&groupID, &r.RoleID, &r.RoleName, &r.EntityType, &r.EntityID)
+		if err != nil {
+			return err
+		}
+
+		result[groupID] = append(result[groupID], r)
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, stmt, dest, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get group roles: %w", err)
+	}
+
+	return result, nil
+}
+
+// SetAuthGroupRoles deletes all roles of the group with the given ID from the `auth_groups_roles` table. Then it
+// inserts a new row for each given role.
+func SetAuthGroupRoles(ctx context.Context, tx *sql.Tx, groupID int, roles []AuthGroupRole) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM auth_groups_roles WHERE auth_group_id = ?`, groupID)
+	if err != nil {
+		return fmt.Errorf("Failed to delete existing roles of group with ID `%d`: %w", groupID, err)
+	}
+
+	for _, role := range roles {
+		_, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO auth_groups_roles (auth_group_id, auth_role_id, entity_id) VALUES (?, ?, ?)`, groupID, role.RoleID, role.EntityID)
+		if err != nil {
+			return fmt.Errorf("Failed to write group roles: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetAuthGroupRoleEntityURLs returns a map of entity type, to entity ID, to api.URL for the entities that the given
+// roles are granted on. Entities that no longer exist are omitted.
+func GetAuthGroupRoleEntityURLs(ctx context.Context, tx *sql.Tx, roles []AuthGroupRole) (map[entity.Type]map[int]*api.URL, error) {
+	permissions := make([]Permission, 0, len(roles))
+	for _, role := range roles {
+		permissions = append(permissions, Permission{EntityType: role.EntityType, EntityID: role.EntityID})
+	}
+
+	return GetPermissionEntityURLs(ctx, tx, permissions)
+}
+
+// AuthGroupRolesToAPI converts the given roles of a group to api.AuthGroupRole, given the URLs of the entities that
+// they are granted on (see GetAuthGroupRoleEntityURLs). Roles granted on entities that no longer exist are omitted.
+func AuthGroupRolesToAPI(roles []AuthGroupRole, entityURLs map[entity.Type]map[int]*api.URL) []api.AuthGroupRole {
+	apiRoles := make([]api.AuthGroupRole, 0, len(roles))
+	for _, role := range roles {
+		u, ok := entityURLs[entity.Type(role.EntityType)][role.EntityID]
+		if !ok {
+			continue
+		}
+
+		apiRoles = append(apiRoles, api.AuthGroupRole{Role: role.RoleName, EntityReference: u.String()})
+	}
+
+	return apiRoles
+}
@@ -8,8 +8,10 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 
+	"github.com/canonical/lxd/lxd/db/query"
 	"github.com/canonical/lxd/lxd/instance/instancetype"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
@@ -560,6 +562,22 @@ var entityStatementsByID = map[entity.Type]string{
 	entity.TypeIdentity:              identityEntityByID,
 }
 
+// entityStatementMaxIDs is the maximum number of entity IDs bound to a single statement when querying for entities
+// by their IDs. This is kept below the default limit on the number of variables in an SQLite statement.
+const entityStatementMaxIDs = 999
+
+// entityStatementByIDs returns the statement which queries for all URL information for the entities of the given type
+// with any of the given number of IDs. The statement is derived from the one in entityStatementsByID, which compares
+// the entity ID with its only argument.
+func entityStatementByIDs(entityType entity.Type, n int) (string, error) {
+	stmt, ok := entityStatementsByID[entityType]
+	if !ok {
+		return "", fmt.Errorf("No statement found for entity type %q", entityType)
+	}
+
+	return strings.Replace(stmt, "= ?", "IN "+query.Params(n), 1), nil
+}
+
 // entityStatementsByProjectName is a map of entity type to the statement which queries for all URL information for all entities of that type within a given project.
 var entityStatementsByProjectName = map[entity.Type]string{
 	entity.TypeContainer:             containerEntitiesByProjectName,
@@ -640,6 +658,55 @@ func GetEntityURL(ctx context.Context, tx *sql.Tx, entityType entity.Type, entit
 	return entityRef.getURL()
 }
 
+// GetEntityURLsByIDs returns a map of entity ID to *api.URL for the entities of the given type with the given IDs.
+// The URLs are resolved with a single query, or with one query per chunk of entityStatementMaxIDs IDs. IDs that do not
+// refer to an existing entity are omitted from the result.
+func GetEntityURLsByIDs(ctx context.Context, tx *sql.Tx, entityType entity.Type, entityIDs []int) (map[int]*api.URL, error) {
+	result := make(map[int]*api.URL, len(entityIDs))
+	if entityType == entity.TypeServer {
+		for _, entityID := range entityIDs {
+			result[entityID] = entity.ServerURL()
+		}
+
+		return result, nil
+	}
+
+	for start := 0; start < len(entityIDs); start += entityStatementMaxIDs {
+		end := min(start+entityStatementMaxIDs, len(entityIDs))
+
+		stmt, err := entityStatementByIDs(entityType, end-start)
+		if err != nil {
+			return nil, fmt.Errorf("Could not get entity URLs: %w", err)
+		}
+
+		args := make([]any, 0, end-start)
+		for _, entityID := range entityIDs[start:end] {
+			args = append(args, entityID)
+		}
+
+		err = query.Scan(ctx, tx, stmt, func(scan func(dest ...any) error) error {
+			entityRef := &EntityRef{}
+			err := entityRef.scan(scan)
+			if err != nil {
+				return err
+			}
+
+			u, err := entityRef.getURL()
+			if err != nil {
+				return err
+			}
+
+			result[entityRef.EntityID] = u
+			return nil
+		}, args...)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to perform entity URL query: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
 // GetEntityURLs accepts a project name and a variadic of entity types and returns a map of entity.Type to map of entity ID, to *api.URL.
 // This method combines the above queries into a single query using the UNION operator. If no entity types are given, this function will
 // return URLs for all entity types. If no project name is given, this function will return URLs for all projects. This may result in
@@ -734,6 +801,131 @@ func GetEntityURLs(ctx context.Context, tx *sql.Tx, projectName string, entityTy
 	return result, nil
 }
 
+// ListableEntityTypes returns the entity types whose entities can be listed with GetEntityURLsPage, sorted by name.
+// If a project name is given, only the entity types whose entities can be part of a project are returned.
+func ListableEntityTypes(projectName string) []entity.Type {
+	statements := entityStatementsAll
+	if projectName != "" {
+		statements = entityStatementsByProjectName
+	}
+
+	entityTypes := make([]entity.Type, 0, len(statements)+1)
+	for entityType := range statements {
+		entityTypes = append(entityTypes, entityType)
+	}
+
+	if projectName == "" {
+		entityTypes = append(entityTypes, entity.TypeServer)
+	}
+
+	sort.Slice(entityTypes, func(i, j int) bool {
+		return entityTypes[i] < entityTypes[j]
+	})
+
+	return entityTypes
+}
+
+// entitiesStatement returns the statement and arguments that query for the URL information of all entities of the
+// given type. If a project name is given, only the entities in that project are returned. If a search string is given,
+// only the entities whose project name or path arguments contain it (case insensitive) are returned.
+func entitiesStatement(entityType entity.Type, projectName string, search string) (string, []any, error) {
+	var stmt string
+	var args []any
+	var ok bool
+	if projectName == "" {
+		stmt, ok = entityStatementsAll[entityType]
+	} else {
+		stmt, ok = entityStatementsByProjectName[entityType]
+		args = append(args, projectName)
+	}
+
+	if !ok {
+		return "", nil, fmt.Errorf("No statement found for entity type %q", entityType)
+	}
+
+	// Name the columns of the statement so that the entities can be filtered, counted and ordered.
+	stmt = `WITH entities (entity_type, entity_id, project_name, location, path_args) AS (` + stmt + `)
+SELECT entity_type, entity_id, project_name, location, path_args FROM entities`
+	if search != "" {
+		stmt += ` WHERE instr(lower(project_name || ' ' || path_args), lower(?)) > 0`
+		args = append(args, search)
+	}
+
+	return stmt, args, nil
+}
+
+// CountEntities returns the number of entities of the given type. If a project name is given, only the entities in that
+// project are counted. If a search string is given, only the entities whose project name or path arguments contain it
+// (case insensitive) are counted. The server has neither, so it is only counted if there is no search string.
+func CountEntities(ctx context.Context, tx *sql.Tx, entityType entity.Type, projectName string, search string) (int, error) {
+	if entityType == entity.TypeServer {
+		if search != "" {
+			return 0, nil
+		}
+
+		return 1, nil
+	}
+
+	stmt, args, err := entitiesStatement(entityType, projectName, search)
+	if err != nil {
+		return 0, fmt.Errorf("Could not count entities: %w", err)
+	}
+
+	var count int
+	err = tx.QueryRowContext(ctx, `SELECT count(*) FROM (`+stmt+`)`, args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to count entities of type %q: %w", entityType, err)
+	}
+
+	return count, nil
+}
+
+// GetEntityURLsPage returns the IDs of the entities of the given type that match the given project name and search
+// string (see CountEntities), and a map of entity ID to *api.URL. The IDs are ordered by the project name, location and
+// path arguments of the entities, so that they can be paginated. At most limit IDs are returned after skipping offset
+// IDs. If the limit is negative, all IDs after the offset are returned.
+func GetEntityURLsPage(ctx context.Context, tx *sql.Tx, entityType entity.Type, projectName string, search string, limit int, offset int) ([]int, map[int]*api.URL, error) {
+	if entityType == entity.TypeServer {
+		if search != "" || offset > 0 || limit == 0 {
+			return nil, map[int]*api.URL{}, nil
+		}
+
+		return []int{0}, map[int]*api.URL{0: entity.ServerURL()}, nil
+	}
+
+	stmt, args, err := entitiesStatement(entityType, projectName, search)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not get entity URLs: %w", err)
+	}
+
+	stmt += ` ORDER BY project_name, location, path_args, entity_id LIMIT ? OFFSET ?`
+	args = append(args, limit, offset)
+
+	var entityIDs []int
+	entityURLs := make(map[int]*api.URL)
+	err = query.Scan(ctx, tx, stmt, func(scan func(dest ...any) error) error {
+		entityRef := &EntityRef{}
+		err := entityRef.scan(scan)
+		if err != nil {
+			return err
+		}
+
+		u, err := entityRef.getURL()
+		if err != nil {
+			return err
+		}
+
+		entityIDs = append(entityIDs, entityRef.EntityID)
+		entityURLs[entityRef.EntityID] = u
+		return nil
+	}, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to perform entity URL query: %w", err)
+	}
+
+	return entityIDs, entityURLs, nil
+}
+
 /*
 The following queries return the ID of an entity by the information contained in its unique URL in a common format.
 These queries are not used in isolation, they are used together as part of a larger UNION query.
@@ -1043,7 +1235,8 @@ var entityIDFromURLStatements = map[entity.Type]string{
 }
 
 // PopulateEntityReferencesFromURLs populates the values in the given map with entity references corresponding to the api.URL keys.
-// It will return an error if any of the given URLs do not correspond to a LXD entity.
+// It will return an error if any of the given URLs do not correspond to a LXD entity. If some URLs are valid but the
+// entities that they reference do not exist, the error is a http.StatusNotFound api.StatusError listing those URLs.
 func PopulateEntityReferencesFromURLs(ctx context.Context, tx *sql.Tx, entityURLMap map[*api.URL]*EntityRef) error {
 	// If the input list is empty, nothing to do.
 	if len(entityURLMap) == 0 {
@@ -1061,7 +1254,7 @@ func PopulateEntityReferencesFromURLs(ctx context.Context, tx *sql.Tx, entityURL
 		// Parse the URL to get the majority of the fields of the EntityRef for that URL.
 		entityType, projectName, location, pathArgs, err := entity.ParseURL(entityURL.URL)
 		if err != nil {
-			return fmt.Errorf("Failed to get entity IDs from URLs: %w", err)
+			return api.StatusErrorf(http.StatusBadRequest, "Failed to get entity ID from URL %q: %v", entityURL.String(), err)
 		}
 
 		// Populate the result map.
@@ -1132,11 +1325,25 @@ func PopulateEntityReferencesFromURLs(ctx context.Context, tx *sql.Tx, entityURL
 	}
 
 	// Check that all given URLs have been resolved to an ID.
-	for u, ref := range entityURLMap {
-		if ref.EntityID == 0 && ref.EntityType != EntityType(entity.TypeServer) {
-			return fmt.Errorf("Failed to find entity ID for URL %q", u.String())
+	var notFound []string
+	for _, u := range entityURLs {
+		ref := entityURLMap[u]
+		if ref.EntityID != 0 || ref.EntityType == EntityType(entity.TypeServer) {
+			continue
+		}
+
+		// Name the project for project specific entities, as it is the default project if not set in the URL.
+		if ref.ProjectName != "" {
+			notFound = append(notFound, fmt.Sprintf("%q (project %q)", u.String(), ref.ProjectName))
+		} else {
+			notFound = append(notFound, fmt.Sprintf("%q", u.String()))
 		}
 	}
 
+	if len(notFound) > 0 {
+		sort.Strings(notFound)
+		return api.StatusErrorf(http.StatusNotFound, "No entity found for %s", strings.Join(notFound, ", "))
+	}
+
 	return nil
 }
@@ -1,11 +1,15 @@
 package cluster
 
 import (
+	"context"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/lxd/lxd/auth"
+	"github.com/canonical/lxd/shared/entity"
 )
 
 func TestEntityStatementValidity(t *testing.T) {
@@ -23,11 +27,26 @@ func TestEntityStatementValidity(t *testing.T) {
 		assert.NoErrorf(t, err, "Entity statements %q (by ID): %v", entityType, err)
 	}
 
+	for entityType, stmt := range entityStatementsByID {
+		assert.Equalf(t, 1, strings.Count(stmt, "= ?"), "Entity statement %q (by ID) must compare the entity ID with its only argument", entityType)
+		stmt, err := entityStatementByIDs(entityType, 3)
+		require.NoError(t, err)
+		_, err = db.Prepare(stmt)
+		assert.NoErrorf(t, err, "Entity statements %q (by IDs): %v", entityType, err)
+	}
+
 	for entityType, stmt := range entityStatementsByProjectName {
 		_, err := db.Prepare(stmt)
 		assert.NoErrorf(t, err, "Entity statements %q (by project): %v", entityType, err)
 	}
 
+	for entityType := range entityStatementsByProjectName {
+		stmt, _, err := entitiesStatement(entityType, "default", "foo")
+		require.NoError(t, err)
+		_, err = db.Prepare(stmt)
+		assert.NoErrorf(t, err, "Entity statements %q (filtered): %v", entityType, err)
+	}
+
 	for outerEntityType, outerStmt := range entityStatementsByProjectName {
 		for middleEntityType, middleStmt := range entityStatementsByID {
 			for innerEntityType, innerStmt := range entityStatementsAll {
@@ -47,3 +66,94 @@ func TestEntityStatementValidity(t *testing.T) {
 		}
 	}
 }
+
+func TestGetEntityURLsPage(t *testing.T) {
+	schema := Schema()
+	db, err := schema.ExerciseUpdate(SchemaVersion, nil)
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO projects (id, name, description) VALUES (1, 'default', ''), (2, 'foo', '')")
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO profiles (id, name, description, project_id) VALUES (1, 'p2', '', 1), (2, 'p1', '', 1), (3, 'p3', '', 2), (4, 'other', '', 2)")
+	require.NoError(t, err)
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	defer func() { _ = tx.Rollback() }()
+
+	ctx := context.Background()
+	count, err := CountEntities(ctx, tx, entity.TypeProfile, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+
+	count, err = CountEntities(ctx, tx, entity.TypeProfile, "foo", "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	count, err = CountEntities(ctx, tx, entity.TypeProfile, "", "P")
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	// Entities are ordered by project, then by name.
+	entityIDs, entityURLs, err := GetEntityURLsPage(ctx, tx, entity.TypeProfile, "", "", -1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 1, 4, 3}, entityIDs)
+	assert.Equal(t, entity.ProfileURL("default", "p1").String(), entityURLs[2].String())
+
+	entityIDs, _, err = GetEntityURLsPage(ctx, tx, entity.TypeProfile, "", "", 2, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 4}, entityIDs)
+
+	entityIDs, _, err = GetEntityURLsPage(ctx, tx, entity.TypeProfile, "foo", "p", -1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []int{3}, entityIDs)
+
+	// The server has no name that can be searched for.
+	entityIDs, _, err = GetEntityURLsPage(ctx, tx, entity.TypeServer, "", "", -1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0}, entityIDs)
+
+	entityIDs, _, err = GetEntityURLsPage(ctx, tx, entity.TypeServer, "", "1.0", -1, 0)
+	require.NoError(t, err)
+	assert.Empty(t, entityIDs)
+
+	_, _, err = GetEntityURLsPage(ctx, tx, entity.TypeStoragePool, "foo", "", -1, 0)
+	assert.Error(t, err)
+}
+
+func TestGetAuthGroupNamesByEntityIDs(t *testing.T) {
+	schema := Schema()
+	db, err := schema.ExerciseUpdate(SchemaVersion, nil)
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO projects (id, name, description) VALUES (1, 'default', '')")
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO auth_groups (id, name, description) VALUES (1, 'g2', ''), (2, 'g1', '')")
+	require.NoError(t, err)
+
+	_, err = db.Exec(`INSERT INTO permissions (id, entitlement, entity_type, entity_id, subtree_entity_type) VALUES 
+(1, 'can_view', ?, 1, ''), 
+(2, 'can_edit', ?, 1, ''), 
+(3, 'can_view', ?, 2, ''), 
+(4, 'can_view', ?, 1, 'storage_volume')`, EntityType(entity.TypeProject), EntityType(entity.TypeProject), EntityType(entity.TypeProject), EntityType(entity.TypeProject))
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO auth_groups_permissions (auth_group_id, permission_id) VALUES (1, 1), (2, 1), (2, 2), (1, 3), (1, 4)")
+	require.NoError(t, err)
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	defer func() { _ = tx.Rollback() }()
+
+	// Only the permissions on the given entities are returned, without subtree permissions.
+	groupNames, err := GetAuthGroupNamesByEntityIDs(context.Background(), tx, entity.TypeProject, []int{1})
+	require.NoError(t, err)
+	assert.Equal(t, map[int]map[auth.Entitlement][]string{
+		1: {
+			auth.EntitlementCanView: {"g1", "g2"},
+			auth.EntitlementCanEdit: {"g1"},
+		},
+	}, groupNames)
+}
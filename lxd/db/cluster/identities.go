@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/canonical/lxd/lxd/certificate"
 	"github.com/canonical/lxd/lxd/db/query"
@@ -273,6 +274,9 @@ func (i Identity) X509() (*x509.Certificate, error) {
 // OIDCMetadata contains metadata for OIDC identities.
 type OIDCMetadata struct {
 	Subject string `json:"subject"`
+
+	// IdentityProviderGroups are the identity provider groups that the identity last authenticated with.
+	IdentityProviderGroups []string `json:"identity_provider_groups,omitempty"`
 }
 
 // Subject returns OIDC subject from the identity metadata. The AuthMethod of the Identity must be api.AuthenticationMethodOIDC.
@@ -290,6 +294,22 @@ func (i Identity) Subject() (string, error) {
 	return metadata.Subject, nil
 }
 
+// IdentityProviderGroups returns the identity provider groups that the identity last authenticated with from the
+// identity metadata. The AuthMethod of the Identity must be api.AuthenticationMethodOIDC.
+func (i Identity) IdentityProviderGroups() ([]string, error) {
+	if i.AuthMethod != api.AuthenticationMethodOIDC {
+		return nil, fmt.Errorf("Cannot extract identity provider groups from identity: Identity has authentication method %q (%q required)", i.AuthMethod, api.AuthenticationMethodOIDC)
+	}
+
+	var metadata OIDCMetadata
+	err := json.Unmarshal([]byte(i.Metadata), &metadata)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal identity provider groups metadata: %w", err)
+	}
+
+	return metadata.IdentityProviderGroups, nil
+}
+
 // ToAPIInfo converts an Identity to an api.IdentityInfo, executing database queries as necessary.
 func (i *Identity) ToAPIInfo(ctx context.Context, tx *sql.Tx) (*api.IdentityInfo, error) {
 	groups, err := GetAuthGroupsByIdentityID(ctx, tx, i.ID)
@@ -302,7 +322,12 @@ func (i *Identity) ToAPIInfo(ctx context.Context, tx *sql.Tx) (*api.IdentityInfo
 		groupNames = append(groupNames, group.Name)
 	}
 
-	return &api.IdentityInfo{
+	disabled, err := GetIdentityDisabled(ctx, tx, i.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	apiIdentityInfo := &api.IdentityInfo{
 		Identity: api.Identity{
 			AuthenticationMethod: string(i.AuthMethod),
 			Type:                 string(i.Type),
@@ -312,7 +337,16 @@ func (i *Identity) ToAPIInfo(ctx context.Context, tx *sql.Tx) (*api.IdentityInfo
 		IdentityPut: api.IdentityPut{
 			Groups: groupNames,
 		},
-	}, nil
+	}
+
+	enabled := disabled == nil
+	apiIdentityInfo.Enabled = &enabled
+	if disabled != nil {
+		apiIdentityInfo.DisabledAt = &disabled.At
+		apiIdentityInfo.DisabledBy = disabled.By
+	}
+
+	return apiIdentityInfo, nil
 }
 
 // GetAuthGroupsByIdentityID returns a slice of groups that the identity with the given ID is a member of.
@@ -415,6 +449,11 @@ func SetIdentityAuthGroups(ctx context.Context, tx *sql.Tx, identityID int, grou
 		return nil
 	}
 
+	err = checkAuthGroupsNotTemplates(ctx, tx, groupNames)
+	if err != nil {
+		return err
+	}
+
 	args := []any{identityID}
 	var builder strings.Builder
 	builder.WriteString(`
@@ -449,3 +488,70 @@ WHERE auth_groups.name IN (
 
 	return nil
 }
+
+// IdentityDisabled records when and by whom an identity was disabled.
+type IdentityDisabled struct {
+	At time.Time
+	By string
+}
+
+// GetIdentityDisabled returns when and by whom the identity with the given ID was disabled, or nil if it is enabled.
+func GetIdentityDisabled(ctx context.Context, tx *sql.Tx, identityID int) (*IdentityDisabled, error) {
+	var enabled bool
+	var disabledAt sql.NullTime
+	var disabledBy sql.NullString
+	err := tx.QueryRowContext(ctx, "SELECT enabled, disabled_at, disabled_by FROM identities WHERE id = ?", identityID).Scan(&enabled, &disabledAt, &disabledBy)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get enabled state of the identity with ID `%d`: %w", identityID, err)
+	}
+
+	if enabled {
+		return nil, nil
+	}
+
+	return &IdentityDisabled{At: disabledAt.Time, By: disabledBy.String}, nil
+}
+
+// GetAllIdentitiesDisabled returns a map of identity IDs to when and by whom the identity was disabled. Enabled
+// identities are not included.
+func GetAllIdentitiesDisabled(ctx context.Context, tx *sql.Tx) (map[int]IdentityDisabled, error) {
+	result := make(map[int]IdentityDisabled)
+	dest := func(scan func(dest ...any) error) error {
+		var identityID int
+		var disabledAt sql.NullTime
+		var disabledBy sql.NullString
+		err := scan(&identityID, &disabledAt, &disabledBy)
+		if err != nil {
+			return err
+		}
+
+		result[identityID] = IdentityDisabled{At: disabledAt.Time, By: disabledBy.String}
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, "SELECT id, disabled_at, disabled_by FROM identities WHERE enabled = 0", dest)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get disabled identities: %w", err)
+	}
+
+	return result, nil
+}
+
+// SetIdentityEnabled enables or disables the identity with the given ID. When disabling an identity that is enabled,
+// the current time and the given username are recorded. Disabling an identity that is already disabled keeps the
+// original record.
+func SetIdentityEnabled(ctx context.Context, tx *sql.Tx, identityID int, enabled bool, username string) error {
+	var err error
+	if enabled {
+		_, err = tx.ExecContext(ctx, "UPDATE identities SET enabled = 1, disabled_at = NULL, disabled_by = NULL WHERE id = ?", identityID)
+	} else {
+		_, err = tx.ExecContext(ctx, "UPDATE identities SET enabled = 0, disabled_at = ?, disabled_by = ? WHERE id = ? AND enabled = 1", time.Now().UTC(), username, identityID)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Failed to set enabled state of the identity with ID `%d`: %w", identityID, err)
+	}
+
+	return nil
+}
@@ -107,6 +107,11 @@ func SetIdentityProviderGroupMapping(ctx context.Context, tx *sql.Tx, identityPr
 		return nil
 	}
 
+	err = checkAuthGroupsNotTemplates(ctx, tx, groupNames)
+	if err != nil {
+		return err
+	}
+
 	args := []any{identityProviderGroupID}
 	var builder strings.Builder
 	builder.WriteString(`
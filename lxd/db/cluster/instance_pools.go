@@ -0,0 +1,231 @@
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/db/query"
+	"github.com/canonical/lxd/shared/api"
+)
+
+// InstancePool is the database representation of an api.InstancePool. The template of the pool is stored as JSON.
+type InstancePool struct {
+	ID          int
+	Name        string
+	Project     string
+	Description string
+	Size        int
+	NamePattern string
+	Template    api.InstancePoolTemplate
+}
+
+// ToAPI converts the pool to an api.InstancePool. The UsedBy field isn't populated.
+func (p InstancePool) ToAPI() api.InstancePool {
+	return api.InstancePool{
+		Name:        p.Name,
+		Project:     p.Project,
+		Description: p.Description,
+		Size:        p.Size,
+		NamePattern: p.NamePattern,
+		Template:    p.Template,
+	}
+}
+
+// InstancePoolInstance is an instance that is available in an instance pool.
+type InstancePoolInstance struct {
+	InstanceID int
+	Name       string
+	Node       string
+}
+
+// GetInstancePools returns the pools of the given project ordered by name.
+func GetInstancePools(ctx context.Context, tx *sql.Tx, project string) ([]InstancePool, error) {
+	return getInstancePools(ctx, tx, "WHERE projects.name = ?", project)
+}
+
+// GetAllInstancePools returns the pools of all projects.
+func GetAllInstancePools(ctx context.Context, tx *sql.Tx) ([]InstancePool, error) {
+	return getInstancePools(ctx, tx, "")
+}
+
+// GetInstancePool returns the pool with the given name in the given project.
+func GetInstancePool(ctx context.Context, tx *sql.Tx, project string, name string) (*InstancePool, error) {
+	pools, err := getInstancePools(ctx, tx, "WHERE projects.name = ? AND instance_pools.name = ?", project, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pools) == 0 {
+		return nil, api.StatusErrorf(http.StatusNotFound, "Instance pool not found")
+	}
+
+	return &pools[0], nil
+}
+
+// getInstancePools returns the pools matching the given WHERE clause.
+func getInstancePools(ctx context.Context, tx *sql.Tx, where string, args ...any) ([]InstancePool, error) {
+	stmt := `
+SELECT instance_pools.id, instance_pools.name, projects.name, instance_pools.description, instance_pools.size, instance_pools.name_pattern, instance_pools.template
+FROM instance_pools
+JOIN projects ON projects.id = instance_pools.project_id
+` + where + `
+ORDER BY projects.name, instance_pools.name`
+
+	var pools []InstancePool
+	dest := func(scan func(dest ...any) error) error {
+		p := InstancePool{}
+		var template string
+		err := scan(&p.ID, &p.Name, &p.Project, &p.Description, &p.Size, &p.NamePattern, &template)
+		if err != nil {
+			return err
+		}
+
+		err = json.Unmarshal([]byte(template), &p.Template)
+		if err != nil {
+			return fmt.Errorf("Failed to parse template of instance pool %q: %w", p.Name, err)
+		}
+
+		pools = append(pools, p)
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, stmt, dest, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get instance pools: %w", err)
+	}
+
+	return pools, nil
+}
+
+// CreateInstancePool creates a new pool and returns its ID.
+func CreateInstancePool(ctx context.Context, tx *sql.Tx, pool InstancePool) (int64, error) {
+	_, err := GetInstancePool(ctx, tx, pool.Project, pool.Name)
+	if err == nil {
+		return -1, api.StatusErrorf(http.StatusConflict, "An instance pool with name %q already exists", pool.Name)
+	} else if !api.StatusErrorCheck(err, http.StatusNotFound) {
+		return -1, err
+	}
+
+	projectID, err := GetProjectID(ctx, tx, pool.Project)
+	if err != nil {
+		return -1, err
+	}
+
+	template, err := json.Marshal(pool.Template)
+	if err != nil {
+		return -1, fmt.Errorf("Failed to encode template of instance pool %q: %w", pool.Name, err)
+	}
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO instance_pools (name, project_id, description, size, name_pattern, template) VALUES (?, ?, ?, ?, ?, ?)`, pool.Name, projectID, pool.Description, pool.Size, pool.NamePattern, string(template))
+	if err != nil {
+		return -1, fmt.Errorf("Failed to create instance pool %q: %w", pool.Name, err)
+	}
+
+	poolID, err := res.LastInsertId()
+	if err != nil {
+		return -1, fmt.Errorf("Failed to get ID of instance pool %q: %w", pool.Name, err)
+	}
+
+	return poolID, nil
+}
+
+// UpdateInstancePool updates the description, size, name pattern and template of the pool with the given ID.
+func UpdateInstancePool(ctx context.Context, tx *sql.Tx, poolID int, pool InstancePool) error {
+	template, err := json.Marshal(pool.Template)
+	if err != nil {
+		return fmt.Errorf("Failed to encode template of instance pool %q: %w", pool.Name, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE instance_pools SET description = ?, size = ?, name_pattern = ?, template = ? WHERE id = ?`, pool.Description, pool.Size, pool.NamePattern, string(template), poolID)
+	if err != nil {
+		return fmt.Errorf("Failed to update instance pool %q: %w", pool.Name, err)
+	}
+
+	return nil
+}
+
+// DeleteInstancePool deletes the pool with the given ID. The instances that were available in the pool are kept but
+// no longer belong to it.
+func DeleteInstancePool(ctx context.Context, tx *sql.Tx, poolID int) error {
+	res, err := tx.ExecContext(ctx, `DELETE FROM instance_pools WHERE id = ?`, poolID)
+	if err != nil {
+		return fmt.Errorf("Failed to delete instance pool: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("Failed to get affected rows to delete instance pool: %w", err)
+	}
+
+	if n == 0 {
+		return api.StatusErrorf(http.StatusNotFound, "Instance pool not found")
+	}
+
+	return nil
+}
+
+// GetInstancePoolInstances returns the instances that are available in the pool with the given ID, ordered by
+// instance ID so that the oldest instances are acquired first.
+func GetInstancePoolInstances(ctx context.Context, tx *sql.Tx, poolID int) ([]InstancePoolInstance, error) {
+	stmt := `
+SELECT instances.id, instances.name, nodes.name
+FROM instance_pools_instances
+JOIN instances ON instances.id = instance_pools_instances.instance_id
+JOIN nodes ON nodes.id = instances.node_id
+WHERE instance_pools_instances.instance_pool_id = ?
+ORDER BY instances.id`
+
+	var instances []InstancePoolInstance
+	dest := func(scan func(dest ...any) error) error {
+		inst := InstancePoolInstance{}
+		err := scan(&inst.InstanceID, &inst.Name, &inst.Node)
+		if err != nil {
+			return err
+		}
+
+		instances = append(instances, inst)
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, stmt, dest, poolID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get instances of instance pool: %w", err)
+	}
+
+	return instances, nil
+}
+
+// AddInstancePoolInstance makes the instance with the given ID available in the pool with the given ID.
+func AddInstancePoolInstance(ctx context.Context, tx *sql.Tx, poolID int, instanceID int) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO instance_pools_instances (instance_pool_id, instance_id) VALUES (?, ?)`, poolID, instanceID)
+	if err != nil {
+		return fmt.Errorf("Failed to add instance to instance pool: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveInstancePoolInstance removes the instance with the given ID from its pool. A not found error is returned if
+// the instance isn't available in a pool, for example because it was acquired concurrently.
+func RemoveInstancePoolInstance(ctx context.Context, tx *sql.Tx, instanceID int) error {
+	res, err := tx.ExecContext(ctx, `DELETE FROM instance_pools_instances WHERE instance_id = ?`, instanceID)
+	if err != nil {
+		return fmt.Errorf("Failed to remove instance from instance pool: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("Failed to get affected rows to remove instance from instance pool: %w", err)
+	}
+
+	if n == 0 {
+		return api.StatusErrorf(http.StatusNotFound, "Instance isn't available in an instance pool")
+	}
+
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/canonical/lxd/lxd/auth"
 	"github.com/canonical/lxd/lxd/db/query"
+	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/entity"
 )
@@ -21,64 +22,76 @@ import (
 //go:generate mapper stmt -e permission objects-by-EntityType
 //go:generate mapper stmt -e permission objects-by-EntityType-and-EntityID
 //go:generate mapper stmt -e permission objects-by-EntityType-and-EntityID-and-Entitlement
+//go:generate mapper stmt -e permission objects-by-EntityType-and-EntityID-and-Entitlement-and-SubtreeEntityType
 //
 //go:generate mapper method -i -e permission GetMany
 //go:generate mapper method -i -e permission GetOne
 
 // Permission is the database representation of an api.Permission.
+//
+// EntityType and EntityID always refer to the entity that is referenced by the permission. If SubtreeEntityType is
+// set, the permission is a subtree permission and the entitlement applies to all child entities of that type of the
+// referenced entity (e.g. all storage volumes in a storage pool).
 type Permission struct {
-	ID          int
-	Entitlement auth.Entitlement `db:"primary=true"`
-	EntityType  EntityType       `db:"primary=true"`
-	EntityID    int              `db:"primary=true"`
+	ID                int
+	Entitlement       auth.Entitlement `db:"primary=true"`
+	EntityType        EntityType       `db:"primary=true"`
+	EntityID          int              `db:"primary=true"`
+	SubtreeEntityType entity.Type      `db:"primary=true"`
 }
 
 // PermissionFilter contains the fields upon which a Permission may be filtered.
 type PermissionFilter struct {
-	ID          *int
-	Entitlement *auth.Entitlement
-	EntityType  *EntityType
-	EntityID    *int
+	ID                *int
+	Entitlement       *auth.Entitlement
+	EntityType        *EntityType
+	EntityID          *int
+	SubtreeEntityType *entity.Type
+}
+
+// ToAPI converts the Permission to an api.Permission, given the URL of the entity that it references.
+func (p Permission) ToAPI(entityURL *api.URL) api.Permission {
+	entityType := entity.Type(p.EntityType)
+	if p.SubtreeEntityType != "" {
+		entityType = p.SubtreeEntityType
+	}
+
+	return api.Permission{
+		EntityType:      string(entityType),
+		EntityReference: entityURL.String(),
+		Entitlement:     string(p.Entitlement),
+	}
 }
 
 // GetPermissionEntityURLs accepts a slice of Permission and returns a map of entity.Type, to entity ID, to api.URL.
 // The returned map contains the URL of the entity of each given permission. It is used for populating api.Permission.
 func GetPermissionEntityURLs(ctx context.Context, tx *sql.Tx, permissions []Permission) (map[entity.Type]map[int]*api.URL, error) {
-	// To make as few calls as possible, categorize the permissions by entity type.
-	permissionsByEntityType := map[EntityType][]Permission{}
+	// To make as few calls as possible, collect the distinct entity IDs of the permissions by entity type.
+	entityIDsByEntityType := map[entity.Type][]int{}
+	seen := map[entity.Type]map[int]bool{}
 	for _, permission := range permissions {
-		permissionsByEntityType[permission.EntityType] = append(permissionsByEntityType[permission.EntityType], permission)
-	}
-
-	// For each entity type, if there is only on permission for the entity type, we'll get the URL by its entity type and ID.
-	// If there are multiple permissions for the entity type, append the entity type to a list for later use.
-	entityURLs := make(map[entity.Type]map[int]*api.URL)
-	var entityTypes []entity.Type
-	for entityType, permissions := range permissionsByEntityType {
-		if len(permissions) > 1 {
-			entityTypes = append(entityTypes, entity.Type(entityType))
-			continue
+		entityType := entity.Type(permission.EntityType)
+		if seen[entityType] == nil {
+			seen[entityType] = make(map[int]bool)
 		}
 
-		u, err := GetEntityURL(ctx, tx, entity.Type(entityType), permissions[0].EntityID)
-		if err != nil {
-			return nil, err
+		if seen[entityType][permission.EntityID] {
+			continue
 		}
 
-		entityURLs[entity.Type(entityType)] = make(map[int]*api.URL)
-		entityURLs[entity.Type(entityType)][permissions[0].EntityID] = u
+		seen[entityType][permission.EntityID] = true
+		entityIDsByEntityType[entityType] = append(entityIDsByEntityType[entityType], permission.EntityID)
 	}
 
-	// If there are any entity types with multiple permissions, get all URLs for those entities.
-	if len(entityTypes) > 0 {
-		entityURLsAll, err := GetEntityURLs(ctx, tx, "", entityTypes...)
+	// Resolve the URLs of all entities of each entity type at once.
+	entityURLs := make(map[entity.Type]map[int]*api.URL, len(entityIDsByEntityType))
+	for entityType, entityIDs := range entityIDsByEntityType {
+		urls, err := GetEntityURLsByIDs(ctx, tx, entityType, entityIDs)
 		if err != nil {
 			return nil, err
 		}
 
-		for k, v := range entityURLsAll {
-			entityURLs[k] = v
-		}
+		entityURLs[entityType] = urls
 	}
 
 	return entityURLs, nil
@@ -111,3 +124,174 @@ JOIN auth_groups_permissions ON auth_groups.id = auth_groups_permissions.auth_gr
 
 	return result, nil
 }
+
+// GetAuthGroupNamesByEntityIDs returns a map of entity ID, to Entitlement, to the names of the groups that have been
+// granted the Entitlement on the entity of the given type with that ID. Only the permissions on the entities with the
+// given IDs are loaded. Subtree permissions are not included.
+func GetAuthGroupNamesByEntityIDs(ctx context.Context, tx *sql.Tx, entityType entity.Type, entityIDs []int) (map[int]map[auth.Entitlement][]string, error) {
+	result := make(map[int]map[auth.Entitlement][]string)
+	for start := 0; start < len(entityIDs); start += entityStatementMaxIDs {
+		end := min(start+entityStatementMaxIDs, len(entityIDs))
+
+		stmt := fmt.Sprintf(`
+SELECT permissions.entity_id, permissions.entitlement, auth_groups.name 
+FROM permissions 
+JOIN auth_groups_permissions ON permissions.id = auth_groups_permissions.permission_id 
+JOIN auth_groups ON auth_groups_permissions.auth_group_id = auth_groups.id 
+WHERE permissions.entity_type = ? AND permissions.subtree_entity_type = '' AND permissions.entity_id IN %s 
+ORDER BY auth_groups.name`, query.Params(end-start))
+
+		args := make([]any, 0, end-start+1)
+		args = append(args, EntityType(entityType))
+		for _, entityID := range entityIDs[start:end] {
+			args = append(args, entityID)
+		}
+
+		dest := func(scan func(dest ...any) error) error {
+			var entityID int
+			var entitlement auth.Entitlement
+			var groupName string
+			err := scan(&entityID, &entitlement, &groupName)
+			if err != nil {
+				return err
+			}
+
+			if result[entityID] == nil {
+				result[entityID] = make(map[auth.Entitlement][]string)
+			}
+
+			result[entityID][entitlement] = append(result[entityID][entitlement], groupName)
+			return nil
+		}
+
+		err := query.Scan(ctx, tx, stmt, dest, args...)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get groups by permission for entities of type %q: %w", entityType, err)
+		}
+	}
+
+	return result, nil
+}
+
+// UnfixablePermission is a permission whose entity URL cannot be computed nor repaired.
+type UnfixablePermission struct {
+	ID          int
+	Entitlement string
+	EntityType  string
+	EntityID    int
+	Err         error
+}
+
+// PermissionEntityURLsRebuild is the outcome of RebuildPermissionEntityURLs.
+type PermissionEntityURLsRebuild struct {
+	// Resolved is the number of permissions whose entity URL was computed successfully.
+	Resolved int
+
+	// Removed contains the permissions that were deleted because their entity no longer exists.
+	Removed []Permission
+
+	// Unfixable contains the permissions whose entity URL could not be computed for any other reason.
+	Unfixable []UnfixablePermission
+}
+
+// RebuildPermissionEntityURLs recomputes the entity URLs of all permissions. Permissions referencing an entity that
+// no longer exists can never grant access again, so they are deleted. Any other permission whose URL cannot be computed
+// (e.g. because of an unknown entity type) is left untouched and reported so that it can be investigated.
+func RebuildPermissionEntityURLs(ctx context.Context, tx *sql.Tx) (*PermissionEntityURLsRebuild, error) {
+	result := &PermissionEntityURLsRebuild{}
+
+	// Scan the entity types separately so that a single unknown entity type doesn't prevent reading all permissions.
+	var permissions []Permission
+	dest := func(scan func(dest ...any) error) error {
+		var p Permission
+		var entityType int64
+		err := scan(&p.ID, &p.Entitlement, &entityType, &p.EntityID, &p.SubtreeEntityType)
+		if err != nil {
+			return err
+		}
+
+		err = p.EntityType.Scan(entityType)
+		if err != nil {
+			result.Unfixable = append(result.Unfixable, UnfixablePermission{
+				ID:          p.ID,
+				Entitlement: string(p.Entitlement),
+				EntityType:  fmt.Sprintf("%d", entityType),
+				EntityID:    p.EntityID,
+				Err:         err,
+			})
+
+			return nil
+		}
+
+		permissions = append(permissions, p)
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, "SELECT id, entitlement, entity_type, entity_id, subtree_entity_type FROM permissions ORDER BY id", dest)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get permissions: %w", err)
+	}
+
+	// Resolve the URLs of each entity type separately so that failures can be attributed to the affected permissions.
+	permissionsByEntityType := map[entity.Type][]Permission{}
+	for _, p := range permissions {
+		entityType := entity.Type(p.EntityType)
+		permissionsByEntityType[entityType] = append(permissionsByEntityType[entityType], p)
+	}
+
+	var removedIDs []any
+	for entityType, entityPermissions := range permissionsByEntityType {
+		entityIDs := make([]int, 0, len(entityPermissions))
+		for _, p := range entityPermissions {
+			if !shared.ValueInSlice(p.EntityID, entityIDs) {
+				entityIDs = append(entityIDs, p.EntityID)
+			}
+		}
+
+		entityURLs, err := GetEntityURLsByIDs(ctx, tx, entityType, entityIDs)
+		for _, p := range entityPermissions {
+			unfixable := UnfixablePermission{
+				ID:          p.ID,
+				Entitlement: string(p.Entitlement),
+				EntityType:  string(entityType),
+				EntityID:    p.EntityID,
+				Err:         err,
+			}
+
+			if err != nil {
+				result.Unfixable = append(result.Unfixable, unfixable)
+				continue
+			}
+
+			u, ok := entityURLs[p.EntityID]
+			if !ok {
+				result.Removed = append(result.Removed, p)
+				removedIDs = append(removedIDs, p.ID)
+				continue
+			}
+
+			// Check that the URL refers back to the entity type of the permission.
+			urlEntityType, _, _, _, err := entity.ParseURL(u.URL)
+			if err == nil && urlEntityType != entityType {
+				err = fmt.Errorf("Entity URL %q refers to entity type %q", u.String(), urlEntityType)
+			}
+
+			if err != nil {
+				unfixable.Err = err
+				result.Unfixable = append(result.Unfixable, unfixable)
+				continue
+			}
+
+			result.Resolved++
+		}
+	}
+
+	if len(removedIDs) > 0 {
+		_, err = tx.ExecContext(ctx, "DELETE FROM permissions WHERE id IN "+query.Params(len(removedIDs)), removedIDs...)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to delete permissions of entities that no longer exist: %w", err)
+		}
+	}
+
+	return result, nil
+}
@@ -7,6 +7,7 @@ import (
 	"database/sql"
 
 	"github.com/canonical/lxd/lxd/auth"
+	"github.com/canonical/lxd/shared/entity"
 )
 
 // PermissionGenerated is an interface of generated methods for Permission.
@@ -17,5 +18,5 @@ type PermissionGenerated interface {
 
 	// GetPermission returns the permission with the given key.
 	// generator: permission GetOne
-	GetPermission(ctx context.Context, tx *sql.Tx, entitlement auth.Entitlement, entityType EntityType, entityID int) (*Permission, error)
+	GetPermission(ctx context.Context, tx *sql.Tx, entitlement auth.Entitlement, entityType EntityType, entityID int, subtreeEntityType entity.Type) (*Permission, error)
 }
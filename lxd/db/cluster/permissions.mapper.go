@@ -14,48 +14,56 @@ import (
 	"github.com/canonical/lxd/lxd/auth"
 	"github.com/canonical/lxd/lxd/db/query"
 	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
 )
 
 var _ = api.ServerEnvironment{}
 
 var permissionObjects = RegisterStmt(`
-SELECT permissions.id, permissions.entitlement, permissions.entity_type, permissions.entity_id
+SELECT permissions.id, permissions.entitlement, permissions.entity_type, permissions.entity_id, permissions.subtree_entity_type
   FROM permissions
-  ORDER BY permissions.entitlement, permissions.entity_type, permissions.entity_id
+  ORDER BY permissions.entitlement, permissions.entity_type, permissions.entity_id, permissions.subtree_entity_type
 `)
 
 var permissionObjectsByID = RegisterStmt(`
-SELECT permissions.id, permissions.entitlement, permissions.entity_type, permissions.entity_id
+SELECT permissions.id, permissions.entitlement, permissions.entity_type, permissions.entity_id, permissions.subtree_entity_type
   FROM permissions
   WHERE ( permissions.id = ? )
-  ORDER BY permissions.entitlement, permissions.entity_type, permissions.entity_id
+  ORDER BY permissions.entitlement, permissions.entity_type, permissions.entity_id, permissions.subtree_entity_type
 `)
 
 var permissionObjectsByEntityType = RegisterStmt(`
-SELECT permissions.id, permissions.entitlement, permissions.entity_type, permissions.entity_id
+SELECT permissions.id, permissions.entitlement, permissions.entity_type, permissions.entity_id, permissions.subtree_entity_type
   FROM permissions
   WHERE ( permissions.entity_type = ? )
-  ORDER BY permissions.entitlement, permissions.entity_type, permissions.entity_id
+  ORDER BY permissions.entitlement, permissions.entity_type, permissions.entity_id, permissions.subtree_entity_type
 `)
 
 var permissionObjectsByEntityTypeAndEntityID = RegisterStmt(`
-SELECT permissions.id, permissions.entitlement, permissions.entity_type, permissions.entity_id
+SELECT permissions.id, permissions.entitlement, permissions.entity_type, permissions.entity_id, permissions.subtree_entity_type
   FROM permissions
   WHERE ( permissions.entity_type = ? AND permissions.entity_id = ? )
-  ORDER BY permissions.entitlement, permissions.entity_type, permissions.entity_id
+  ORDER BY permissions.entitlement, permissions.entity_type, permissions.entity_id, permissions.subtree_entity_type
 `)
 
 var permissionObjectsByEntityTypeAndEntityIDAndEntitlement = RegisterStmt(`
-SELECT permissions.id, permissions.entitlement, permissions.entity_type, permissions.entity_id
+SELECT permissions.id, permissions.entitlement, permissions.entity_type, permissions.entity_id, permissions.subtree_entity_type
   FROM permissions
   WHERE ( permissions.entity_type = ? AND permissions.entity_id = ? AND permissions.entitlement = ? )
-  ORDER BY permissions.entitlement, permissions.entity_type, permissions.entity_id
+  ORDER BY permissions.entitlement, permissions.entity_type, permissions.entity_id, permissions.subtree_entity_type
+`)
+
+var permissionObjectsByEntityTypeAndEntityIDAndEntitlementAndSubtreeEntityType = RegisterStmt(`
+SELECT permissions.id, permissions.entitlement, permissions.entity_type, permissions.entity_id, permissions.subtree_entity_type
+  FROM permissions
+  WHERE ( permissions.entity_type = ? AND permissions.entity_id = ? AND permissions.entitlement = ? AND permissions.subtree_entity_type = ? )
+  ORDER BY permissions.entitlement, permissions.entity_type, permissions.entity_id, permissions.subtree_entity_type
 `)
 
 // permissionColumns returns a string of column names to be used with a SELECT statement for the entity.
 // Use this function when building statements to retrieve database entries matching the Permission entity.
 func permissionColumns() string {
-	return "permissions.id, permissions.entitlement, permissions.entity_type, permissions.entity_id"
+	return "permissions.id, permissions.entitlement, permissions.entity_type, permissions.entity_id, permissions.subtree_entity_type"
 }
 
 // getPermissions can be used to run handwritten sql.Stmts to return a slice of objects.
@@ -64,7 +72,7 @@ func getPermissions(ctx context.Context, stmt *sql.Stmt, args ...any) ([]Permiss
 
 	dest := func(scan func(dest ...any) error) error {
 		p := Permission{}
-		err := scan(&p.ID, &p.Entitlement, &p.EntityType, &p.EntityID)
+		err := scan(&p.ID, &p.Entitlement, &p.EntityType, &p.EntityID, &p.SubtreeEntityType)
 		if err != nil {
 			return err
 		}
@@ -88,7 +96,7 @@ func getPermissionsRaw(ctx context.Context, tx *sql.Tx, sql string, args ...any)
 
 	dest := func(scan func(dest ...any) error) error {
 		p := Permission{}
-		err := scan(&p.ID, &p.Entitlement, &p.EntityType, &p.EntityID)
+		err := scan(&p.ID, &p.Entitlement, &p.EntityType, &p.EntityID, &p.SubtreeEntityType)
 		if err != nil {
 			return err
 		}
@@ -127,7 +135,31 @@ func GetPermissions(ctx context.Context, tx *sql.Tx, filters ...PermissionFilter
 	}
 
 	for i, filter := range filters {
-		if filter.EntityType != nil && filter.EntityID != nil && filter.Entitlement != nil && filter.ID == nil {
+		if filter.EntityType != nil && filter.EntityID != nil && filter.Entitlement != nil && filter.SubtreeEntityType != nil && filter.ID == nil {
+			args = append(args, []any{filter.EntityType, filter.EntityID, filter.Entitlement, filter.SubtreeEntityType}...)
+			if len(filters) == 1 {
+				sqlStmt, err = Stmt(tx, permissionObjectsByEntityTypeAndEntityIDAndEntitlementAndSubtreeEntityType)
+				if err != nil {
+					return nil, fmt.Errorf("Failed to get \"permissionObjectsByEntityTypeAndEntityIDAndEntitlementAndSubtreeEntityType\" prepared statement: %w", err)
+				}
+
+				break
+			}
+
+			query, err := StmtString(permissionObjectsByEntityTypeAndEntityIDAndEntitlementAndSubtreeEntityType)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to get \"permissionObjects\" prepared statement: %w", err)
+			}
+
+			parts := strings.SplitN(query, "ORDER BY", 2)
+			if i == 0 {
+				copy(queryParts[:], parts)
+				continue
+			}
+
+			_, where, _ := strings.Cut(parts[0], "WHERE")
+			queryParts[0] += "OR" + where
+		} else if filter.EntityType != nil && filter.EntityID != nil && filter.Entitlement != nil && filter.ID == nil && filter.SubtreeEntityType == nil {
 			args = append(args, []any{filter.EntityType, filter.EntityID, filter.Entitlement}...)
 			if len(filters) == 1 {
 				sqlStmt, err = Stmt(tx, permissionObjectsByEntityTypeAndEntityIDAndEntitlement)
@@ -151,7 +183,7 @@ func GetPermissions(ctx context.Context, tx *sql.Tx, filters ...PermissionFilter
 
 			_, where, _ := strings.Cut(parts[0], "WHERE")
 			queryParts[0] += "OR" + where
-		} else if filter.EntityType != nil && filter.EntityID != nil && filter.ID == nil && filter.Entitlement == nil {
+		} else if filter.EntityType != nil && filter.EntityID != nil && filter.ID == nil && filter.Entitlement == nil && filter.SubtreeEntityType == nil {
 			args = append(args, []any{filter.EntityType, filter.EntityID}...)
 			if len(filters) == 1 {
 				sqlStmt, err = Stmt(tx, permissionObjectsByEntityTypeAndEntityID)
@@ -175,7 +207,7 @@ func GetPermissions(ctx context.Context, tx *sql.Tx, filters ...PermissionFilter
 
 			_, where, _ := strings.Cut(parts[0], "WHERE")
 			queryParts[0] += "OR" + where
-		} else if filter.ID != nil && filter.Entitlement == nil && filter.EntityType == nil && filter.EntityID == nil {
+		} else if filter.ID != nil && filter.Entitlement == nil && filter.EntityType == nil && filter.EntityID == nil && filter.SubtreeEntityType == nil {
 			args = append(args, []any{filter.ID}...)
 			if len(filters) == 1 {
 				sqlStmt, err = Stmt(tx, permissionObjectsByID)
@@ -199,7 +231,7 @@ func GetPermissions(ctx context.Context, tx *sql.Tx, filters ...PermissionFilter
 
 			_, where, _ := strings.Cut(parts[0], "WHERE")
 			queryParts[0] += "OR" + where
-		} else if filter.EntityType != nil && filter.ID == nil && filter.Entitlement == nil && filter.EntityID == nil {
+		} else if filter.EntityType != nil && filter.ID == nil && filter.Entitlement == nil && filter.EntityID == nil && filter.SubtreeEntityType == nil {
 			args = append(args, []any{filter.EntityType}...)
 			if len(filters) == 1 {
 				sqlStmt, err = Stmt(tx, permissionObjectsByEntityType)
@@ -223,7 +255,7 @@ func GetPermissions(ctx context.Context, tx *sql.Tx, filters ...PermissionFilter
 
 			_, where, _ := strings.Cut(parts[0], "WHERE")
 			queryParts[0] += "OR" + where
-		} else if filter.ID == nil && filter.Entitlement == nil && filter.EntityType == nil && filter.EntityID == nil {
+		} else if filter.ID == nil && filter.Entitlement == nil && filter.EntityType == nil && filter.EntityID == nil && filter.SubtreeEntityType == nil {
 			return nil, fmt.Errorf("Cannot filter on empty PermissionFilter")
 		} else {
 			return nil, fmt.Errorf("No statement exists for the given Filter")
@@ -247,11 +279,12 @@ func GetPermissions(ctx context.Context, tx *sql.Tx, filters ...PermissionFilter
 
 // GetPermission returns the permission with the given key.
 // generator: permission GetOne
-func GetPermission(ctx context.Context, tx *sql.Tx, entitlement auth.Entitlement, entityType EntityType, entityID int) (*Permission, error) {
+func GetPermission(ctx context.Context, tx *sql.Tx, entitlement auth.Entitlement, entityType EntityType, entityID int, subtreeEntityType entity.Type) (*Permission, error) {
 	filter := PermissionFilter{}
 	filter.Entitlement = &entitlement
 	filter.EntityType = &entityType
 	filter.EntityID = &entityID
+	filter.SubtreeEntityType = &subtreeEntityType
 
 	objects, err := GetPermissions(ctx, tx, filter)
 	if err != nil {
@@ -0,0 +1,58 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/lxd/lxd/auth"
+	"github.com/canonical/lxd/shared/entity"
+)
+
+func BenchmarkGetPermissionEntityURLs(b *testing.B) {
+	schema := Schema()
+	db, err := schema.ExerciseUpdate(SchemaVersion, nil)
+	require.NoError(b, err)
+
+	_, err = db.Exec("INSERT INTO projects (name, description) VALUES ('default', '')")
+	require.NoError(b, err)
+
+	// Create 10k permissions referencing profiles, auth groups, projects and the server.
+	var permissions []Permission
+	for i := 1; i <= 5000; i++ {
+		_, err = db.Exec("INSERT INTO profiles (id, name, description, project_id) VALUES (?, ?, '', 1)", i, fmt.Sprintf("profile%d", i))
+		require.NoError(b, err)
+		permissions = append(permissions, Permission{Entitlement: auth.EntitlementCanView, EntityType: EntityType(entity.TypeProfile), EntityID: i})
+	}
+
+	for i := 1; i <= 4000; i++ {
+		_, err = db.Exec("INSERT INTO auth_groups (id, name, description) VALUES (?, ?, '')", i, fmt.Sprintf("group%d", i))
+		require.NoError(b, err)
+		permissions = append(permissions, Permission{Entitlement: auth.EntitlementCanView, EntityType: EntityType(entity.TypeAuthGroup), EntityID: i})
+	}
+
+	for i := 1; i <= 999; i++ {
+		permissions = append(permissions, Permission{Entitlement: auth.EntitlementCanEdit, EntityType: EntityType(entity.TypeProfile), EntityID: i})
+	}
+
+	permissions = append(permissions, Permission{Entitlement: auth.EntitlementServerAdmin, EntityType: EntityType(entity.TypeServer)})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tx, err := db.Begin()
+		require.NoError(b, err)
+
+		entityURLs, err := GetPermissionEntityURLs(context.Background(), tx, permissions)
+		require.NoError(b, err)
+		require.Len(b, entityURLs[entity.TypeProfile], 5000)
+		require.Len(b, entityURLs[entity.TypeAuthGroup], 4000)
+		require.Len(b, entityURLs[entity.TypeServer], 1)
+
+		require.NoError(b, tx.Rollback())
+	}
+
+	// Resolving the URLs of 10k permissions is expected to take well under 100ms.
+	b.ReportMetric(float64(b.Elapsed().Milliseconds())/float64(b.N), "ms/op")
+}
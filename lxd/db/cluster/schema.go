@@ -6,10 +6,33 @@ package cluster
 // modify the database schema, please add a new schema update to update.go
 // and the run 'make update-schema'.
 const freshSchema = `
+CREATE TABLE auth_audit (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    date DATETIME NOT NULL,
+    actor TEXT NOT NULL,
+    actor_protocol TEXT NOT NULL,
+    object_type TEXT NOT NULL,
+    object_name TEXT NOT NULL,
+    action TEXT NOT NULL,
+    before TEXT,
+    after TEXT,
+    previous_hash TEXT NOT NULL,
+    hash TEXT NOT NULL
+);
+CREATE INDEX auth_audit_date_idx ON auth_audit (date);
+CREATE TRIGGER auth_audit_update
+    BEFORE UPDATE ON auth_audit
+    BEGIN
+        SELECT RAISE(ABORT,
+    'Auth audit entries cannot be modified');
+    END;
 CREATE TABLE auth_groups (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
     name TEXT NOT NULL,
     description TEXT NOT NULL,
+    last_used_at DATETIME,
+    enabled INTEGER NOT NULL DEFAULT 1,
+    template INTEGER NOT NULL DEFAULT 0,
     UNIQUE (name)
 );
 CREATE TABLE auth_groups_identity_provider_groups (
@@ -20,6 +43,14 @@ CREATE TABLE auth_groups_identity_provider_groups (
     FOREIGN KEY (identity_provider_group_id) REFERENCES identity_provider_groups (id) ON DELETE CASCADE,
     UNIQUE (auth_group_id, identity_provider_group_id)
 );
+CREATE TABLE auth_groups_parents (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    auth_group_id INTEGER NOT NULL,
+    parent_auth_group_id INTEGER NOT NULL,
+    FOREIGN KEY (auth_group_id) REFERENCES auth_groups (id) ON DELETE CASCADE,
+    FOREIGN KEY (parent_auth_group_id) REFERENCES auth_groups (id) ON DELETE CASCADE,
+    UNIQUE (auth_group_id, parent_auth_group_id)
+);
 CREATE TABLE auth_groups_permissions (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
     auth_group_id INTEGER NOT NULL,
@@ -28,6 +59,29 @@ CREATE TABLE auth_groups_permissions (
     FOREIGN KEY (permission_id) REFERENCES permissions (id) ON DELETE CASCADE,
     UNIQUE (auth_group_id, permission_id)
 );
+CREATE TABLE auth_groups_roles (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    auth_group_id INTEGER NOT NULL,
+    auth_role_id INTEGER NOT NULL,
+    entity_id INTEGER NOT NULL,
+    FOREIGN KEY (auth_group_id) REFERENCES auth_groups (id) ON DELETE CASCADE,
+    FOREIGN KEY (auth_role_id) REFERENCES auth_roles (id) ON DELETE CASCADE,
+    UNIQUE (auth_group_id, auth_role_id, entity_id)
+);
+CREATE TABLE auth_roles (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL,
+    entity_type INTEGER NOT NULL,
+    UNIQUE (name)
+);
+CREATE TABLE auth_roles_entitlements (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    auth_role_id INTEGER NOT NULL,
+    entitlement TEXT NOT NULL,
+    FOREIGN KEY (auth_role_id) REFERENCES auth_roles (id) ON DELETE CASCADE,
+    UNIQUE (auth_role_id, entitlement)
+);
 CREATE TABLE "cluster_groups" (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
     name TEXT NOT NULL,
@@ -50,6 +104,9 @@ CREATE TABLE identities (
     first_seen_date DATETIME NOT NULL DEFAULT "0001-01-01T00:00:00Z",
     last_seen_date DATETIME NOT NULL DEFAULT "0001-01-01T00:00:00Z",
     updated_date DATETIME NOT NULL DEFAULT "0001-01-01T00:00:00Z",
+    enabled INTEGER NOT NULL DEFAULT 1,
+    disabled_at DATETIME,
+    disabled_by TEXT,
     UNIQUE (auth_method, identifier),
     UNIQUE (type, identifier)
 );
@@ -98,6 +155,8 @@ CREATE TABLE "images_aliases" (
     image_id INTEGER NOT NULL,
     description TEXT NOT NULL,
     project_id INTEGER NOT NULL,
+    auto_update INTEGER,
+    pinned_fingerprint TEXT NOT NULL DEFAULT '',
     UNIQUE (project_id, name),
     FOREIGN KEY (image_id) REFERENCES "images" (id) ON DELETE CASCADE,
     FOREIGN KEY (project_id) REFERENCES "projects" (id) ON DELETE CASCADE
@@ -127,6 +186,14 @@ CREATE TABLE "images_properties" (
     value TEXT,
     FOREIGN KEY (image_id) REFERENCES "images" (id) ON DELETE CASCADE
 );
+CREATE TABLE images_shared_projects (
+    image_id INTEGER NOT NULL,
+    project_id INTEGER NOT NULL,
+    FOREIGN KEY (image_id) REFERENCES images (id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+    UNIQUE (image_id, project_id)
+);
+CREATE INDEX images_shared_projects_project_id_idx ON images_shared_projects (project_id);
 CREATE TABLE "images_source" (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
     image_id INTEGER NOT NULL,
@@ -136,6 +203,25 @@ CREATE TABLE "images_source" (
     alias TEXT NOT NULL,
     FOREIGN KEY (image_id) REFERENCES "images" (id) ON DELETE CASCADE
 );
+CREATE TABLE instance_pools (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name TEXT NOT NULL,
+    project_id INTEGER NOT NULL,
+    description TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    name_pattern TEXT NOT NULL,
+    template TEXT NOT NULL,
+    UNIQUE (project_id, name),
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
+);
+CREATE TABLE instance_pools_instances (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    instance_pool_id INTEGER NOT NULL,
+    instance_id INTEGER NOT NULL,
+    UNIQUE (instance_id),
+    FOREIGN KEY (instance_pool_id) REFERENCES instance_pools (id) ON DELETE CASCADE,
+    FOREIGN KEY (instance_id) REFERENCES instances (id) ON DELETE CASCADE
+);
 CREATE TABLE "instances" (
     id INTEGER primary key AUTOINCREMENT NOT NULL,
     node_id INTEGER NOT NULL,
@@ -349,6 +435,19 @@ CREATE TABLE "networks_peers_config" (
 	FOREIGN KEY (network_peer_id) REFERENCES "networks_peers" (id) ON DELETE CASCADE
 );
 CREATE UNIQUE INDEX networks_unique_network_id_node_id_key ON "networks_config" (network_id, IFNULL(node_id, -1), key);
+CREATE TABLE networks_uplink_allocations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    uplink_network_id INTEGER NOT NULL,
+    network_id INTEGER,
+    address TEXT NOT NULL,
+    type TEXT NOT NULL,
+    used_by TEXT NOT NULL,
+    allocated_at DATETIME NOT NULL,
+    released_at DATETIME,
+    FOREIGN KEY (uplink_network_id) REFERENCES "networks" (id) ON DELETE CASCADE,
+    FOREIGN KEY (network_id) REFERENCES "networks" (id) ON DELETE SET NULL
+);
+CREATE UNIQUE INDEX networks_uplink_allocations_unique_uplink_network_id_address ON networks_uplink_allocations (uplink_network_id, address) WHERE released_at IS NULL;
 CREATE TABLE "networks_zones" (
 	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
 	project_id INTEGER NOT NULL,
@@ -433,12 +532,13 @@ CREATE TABLE "operations" (
     FOREIGN KEY (node_id) REFERENCES "nodes" (id) ON DELETE CASCADE,
     FOREIGN KEY (project_id) REFERENCES "projects" (id) ON DELETE CASCADE
 );
-CREATE TABLE permissions (
+CREATE TABLE "permissions" (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
     entitlement TEXT NOT NULL,
     entity_type TEXT NOT NULL,
     entity_id INTEGER NOT NULL,
-    UNIQUE (entitlement, entity_type, entity_id)
+    subtree_entity_type TEXT NOT NULL DEFAULT '',
+    UNIQUE (entitlement, entity_type, entity_id, subtree_entity_type)
 );
 CREATE TABLE "profiles" (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
@@ -487,6 +587,16 @@ CREATE TABLE "projects_config" (
     FOREIGN KEY (project_id) REFERENCES "projects" (id) ON DELETE CASCADE,
     UNIQUE (project_id, key)
 );
+CREATE TABLE snapshot_retention_policies (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name TEXT NOT NULL,
+    project_id INTEGER,
+    description TEXT NOT NULL,
+    rules TEXT NOT NULL,
+    pending_rules TEXT,
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX snapshot_retention_policies_unique_project_id_name ON snapshot_retention_policies (IFNULL(project_id, -1), name);
 CREATE TABLE "storage_buckets" (
 	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
 	name TEXT NOT NULL,
@@ -662,11 +772,22 @@ CREATE TABLE "warnings" (
 	updated_date DATETIME,
 	last_message TEXT NOT NULL,
 	count INTEGER NOT NULL,
+	suppressed INTEGER NOT NULL DEFAULT 0,
 	UNIQUE (uuid),
 	FOREIGN KEY (node_id) REFERENCES "nodes"(id) ON DELETE CASCADE,
 	FOREIGN KEY (project_id) REFERENCES "projects" (id) ON DELETE CASCADE
 );
 CREATE UNIQUE INDEX warnings_unique_node_id_project_id_entity_type_code_entity_id_type_code ON warnings(IFNULL(node_id, -1), IFNULL(project_id, -1), entity_type_code, entity_id, type_code);
+CREATE TABLE warnings_suppressions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    type_code INTEGER,
+    entity_type_code INTEGER,
+    entity_id INTEGER,
+    message_pattern TEXT NOT NULL,
+    description TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME
+);
 
-INSERT INTO schema (version, updated_at) VALUES (72, strftime("%s"))
+INSERT INTO schema (version, updated_at) VALUES (87, strftime("%s"))
 `
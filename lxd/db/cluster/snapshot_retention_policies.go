@@ -0,0 +1,219 @@
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/db/query"
+	"github.com/canonical/lxd/shared/api"
+)
+
+// SnapshotRetentionPolicy is the database representation of an api.SnapshotRetentionPolicy. The rules of the policy
+// are stored as JSON.
+type SnapshotRetentionPolicy struct {
+	ID          int
+	Name        string
+	Project     string // Empty for a server-wide policy.
+	Description string
+	Rules       api.SnapshotRetentionRules
+
+	// PendingRules are the rules that replace Rules once they are applied. They are nil if there are none.
+	PendingRules *api.SnapshotRetentionRules
+}
+
+// ToAPI converts the policy to an api.SnapshotRetentionPolicy. The UsedBy field isn't populated.
+func (p SnapshotRetentionPolicy) ToAPI() api.SnapshotRetentionPolicy {
+	return api.SnapshotRetentionPolicy{
+		Name:         p.Name,
+		Description:  p.Description,
+		Project:      p.Project,
+		Rules:        p.Rules,
+		PendingRules: p.PendingRules,
+	}
+}
+
+// GetSnapshotRetentionPolicies returns the policies of the given project, or the server-wide policies if the project
+// is empty, ordered by name.
+func GetSnapshotRetentionPolicies(ctx context.Context, tx *sql.Tx, project string) ([]SnapshotRetentionPolicy, error) {
+	if project == "" {
+		return getSnapshotRetentionPolicies(ctx, tx, "WHERE snapshot_retention_policies.project_id IS NULL")
+	}
+
+	return getSnapshotRetentionPolicies(ctx, tx, "WHERE projects.name = ?", project)
+}
+
+// GetAllSnapshotRetentionPolicies returns the policies of all projects and the server-wide policies.
+func GetAllSnapshotRetentionPolicies(ctx context.Context, tx *sql.Tx) ([]SnapshotRetentionPolicy, error) {
+	return getSnapshotRetentionPolicies(ctx, tx, "")
+}
+
+// GetSnapshotRetentionPolicy returns the policy with the given name in the given project, or the server-wide policy
+// with the given name if the project is empty.
+func GetSnapshotRetentionPolicy(ctx context.Context, tx *sql.Tx, project string, name string) (*SnapshotRetentionPolicy, error) {
+	var policies []SnapshotRetentionPolicy
+	var err error
+	if project == "" {
+		policies, err = getSnapshotRetentionPolicies(ctx, tx, "WHERE snapshot_retention_policies.project_id IS NULL AND snapshot_retention_policies.name = ?", name)
+	} else {
+		policies, err = getSnapshotRetentionPolicies(ctx, tx, "WHERE projects.name = ? AND snapshot_retention_policies.name = ?", project, name)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(policies) == 0 {
+		return nil, api.StatusErrorf(http.StatusNotFound, "Snapshot retention policy not found")
+	}
+
+	return &policies[0], nil
+}
+
+// GetEffectiveSnapshotRetentionPolicy returns the policy that an instance or custom volume in the given project uses
+// when its `snapshots.retention.policy` is set to the given name. That is the policy with that name in the project if
+// there is one, and the server-wide policy with that name otherwise.
+func GetEffectiveSnapshotRetentionPolicy(ctx context.Context, tx *sql.Tx, project string, name string) (*SnapshotRetentionPolicy, error) {
+	policy, err := GetSnapshotRetentionPolicy(ctx, tx, project, name)
+	if err == nil || !api.StatusErrorCheck(err, http.StatusNotFound) {
+		return policy, err
+	}
+
+	return GetSnapshotRetentionPolicy(ctx, tx, "", name)
+}
+
+// getSnapshotRetentionPolicies returns the policies matching the given WHERE clause.
+func getSnapshotRetentionPolicies(ctx context.Context, tx *sql.Tx, where string, args ...any) ([]SnapshotRetentionPolicy, error) {
+	stmt := `
+SELECT snapshot_retention_policies.id, snapshot_retention_policies.name, IFNULL(projects.name, ''), snapshot_retention_policies.description, snapshot_retention_policies.rules, snapshot_retention_policies.pending_rules
+FROM snapshot_retention_policies
+LEFT JOIN projects ON projects.id = snapshot_retention_policies.project_id
+` + where + `
+ORDER BY projects.name, snapshot_retention_policies.name`
+
+	var policies []SnapshotRetentionPolicy
+	dest := func(scan func(dest ...any) error) error {
+		p := SnapshotRetentionPolicy{}
+		var rules string
+		var pendingRules sql.NullString
+		err := scan(&p.ID, &p.Name, &p.Project, &p.Description, &rules, &pendingRules)
+		if err != nil {
+			return err
+		}
+
+		err = json.Unmarshal([]byte(rules), &p.Rules)
+		if err != nil {
+			return fmt.Errorf("Failed to parse rules of snapshot retention policy %q: %w", p.Name, err)
+		}
+
+		if pendingRules.Valid {
+			p.PendingRules = &api.SnapshotRetentionRules{}
+			err = json.Unmarshal([]byte(pendingRules.String), p.PendingRules)
+			if err != nil {
+				return fmt.Errorf("Failed to parse pending rules of snapshot retention policy %q: %w", p.Name, err)
+			}
+		}
+
+		policies = append(policies, p)
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, stmt, dest, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get snapshot retention policies: %w", err)
+	}
+
+	return policies, nil
+}
+
+// CreateSnapshotRetentionPolicy creates a new policy and returns its ID.
+func CreateSnapshotRetentionPolicy(ctx context.Context, tx *sql.Tx, policy SnapshotRetentionPolicy) (int64, error) {
+	_, err := GetSnapshotRetentionPolicy(ctx, tx, policy.Project, policy.Name)
+	if err == nil {
+		return -1, api.StatusErrorf(http.StatusConflict, "A snapshot retention policy with name %q already exists", policy.Name)
+	} else if !api.StatusErrorCheck(err, http.StatusNotFound) {
+		return -1, err
+	}
+
+	var projectID any
+	if policy.Project != "" {
+		projectID, err = GetProjectID(ctx, tx, policy.Project)
+		if err != nil {
+			return -1, err
+		}
+	}
+
+	rules, pendingRules, err := snapshotRetentionPolicyRulesToJSON(policy)
+	if err != nil {
+		return -1, err
+	}
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO snapshot_retention_policies (name, project_id, description, rules, pending_rules) VALUES (?, ?, ?, ?, ?)`, policy.Name, projectID, policy.Description, rules, pendingRules)
+	if err != nil {
+		return -1, fmt.Errorf("Failed to create snapshot retention policy %q: %w", policy.Name, err)
+	}
+
+	policyID, err := res.LastInsertId()
+	if err != nil {
+		return -1, fmt.Errorf("Failed to get ID of snapshot retention policy %q: %w", policy.Name, err)
+	}
+
+	return policyID, nil
+}
+
+// UpdateSnapshotRetentionPolicy updates the description, rules and pending rules of the policy with the given ID.
+func UpdateSnapshotRetentionPolicy(ctx context.Context, tx *sql.Tx, policyID int, policy SnapshotRetentionPolicy) error {
+	rules, pendingRules, err := snapshotRetentionPolicyRulesToJSON(policy)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE snapshot_retention_policies SET description = ?, rules = ?, pending_rules = ? WHERE id = ?`, policy.Description, rules, pendingRules, policyID)
+	if err != nil {
+		return fmt.Errorf("Failed to update snapshot retention policy %q: %w", policy.Name, err)
+	}
+
+	return nil
+}
+
+// DeleteSnapshotRetentionPolicy deletes the policy with the given ID.
+func DeleteSnapshotRetentionPolicy(ctx context.Context, tx *sql.Tx, policyID int) error {
+	res, err := tx.ExecContext(ctx, `DELETE FROM snapshot_retention_policies WHERE id = ?`, policyID)
+	if err != nil {
+		return fmt.Errorf("Failed to delete snapshot retention policy: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("Failed to get affected rows to delete snapshot retention policy: %w", err)
+	}
+
+	if n == 0 {
+		return api.StatusErrorf(http.StatusNotFound, "Snapshot retention policy not found")
+	}
+
+	return nil
+}
+
+// snapshotRetentionPolicyRulesToJSON returns the rules and pending rules of the policy as JSON. The pending rules are
+// nil if the policy has none.
+func snapshotRetentionPolicyRulesToJSON(policy SnapshotRetentionPolicy) (string, any, error) {
+	rules, err := json.Marshal(policy.Rules)
+	if err != nil {
+		return "", nil, fmt.Errorf("Failed to encode rules of snapshot retention policy %q: %w", policy.Name, err)
+	}
+
+	if policy.PendingRules == nil {
+		return string(rules), nil, nil
+	}
+
+	pendingRules, err := json.Marshal(policy.PendingRules)
+	if err != nil {
+		return "", nil, fmt.Errorf("Failed to encode pending rules of snapshot retention policy %q: %w", policy.Name, err)
+	}
+
+	return string(rules), string(pendingRules), nil
+}
@@ -5,6 +5,7 @@ package cluster
 import (
 	"database/sql"
 	"fmt"
+	"sync"
 )
 
 // RegisterStmt register a SQL statement.
@@ -41,6 +42,18 @@ var stmts = map[int]string{} // Statement code to statement SQL text.
 // PreparedStmts is a placeholder for transitioning to package-scoped transaction functions.
 var PreparedStmts = map[int]*sql.Stmt{}
 
+// replicaTxs holds the transactions made against a copy of the database rather than the database that the
+// PreparedStmts were prepared on.
+var replicaTxs sync.Map
+
+// TrackReplicaTx records that the given transaction is made against a copy of the database, so that Stmt prepares the
+// statements within the transaction. The returned function must be called once the transaction is done.
+func TrackReplicaTx(tx *sql.Tx) func() {
+	replicaTxs.Store(tx, struct{}{})
+
+	return func() { replicaTxs.Delete(tx) }
+}
+
 // Stmt prepares the in-memory prepared statement for the transaction.
 func Stmt(tx *sql.Tx, code int) (*sql.Stmt, error) {
 	stmt, ok := PreparedStmts[code]
@@ -48,6 +61,12 @@ func Stmt(tx *sql.Tx, code int) (*sql.Stmt, error) {
 		return nil, fmt.Errorf("No prepared statement registered with code %d", code)
 	}
 
+	// Statements prepared on the cluster database can't be used on a copy of it.
+	_, isReplica := replicaTxs.Load(tx)
+	if isReplica {
+		return tx.Prepare(stmts[code])
+	}
+
 	return tx.Stmt(stmt), nil
 }
 
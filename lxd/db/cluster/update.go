@@ -109,6 +109,345 @@ var updates = map[int]schema.Update{
 	70: updateFromV69,
 	71: updateFromV70,
 	72: updateFromV71,
+	73: updateFromV72,
+	74: updateFromV73,
+	75: updateFromV74,
+	76: updateFromV75,
+	77: updateFromV76,
+	78: updateFromV77,
+	79: updateFromV78,
+	80: updateFromV79,
+	81: updateFromV80,
+	82: updateFromV81,
+	83: updateFromV82,
+	84: updateFromV83,
+	85: updateFromV84,
+	86: updateFromV85,
+	87: updateFromV86,
+}
+
+// updateFromV86 adds a table for the projects an image is shared with. Those projects can use the image read-only.
+func updateFromV86(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+CREATE TABLE images_shared_projects (
+    image_id INTEGER NOT NULL,
+    project_id INTEGER NOT NULL,
+    FOREIGN KEY (image_id) REFERENCES images (id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+    UNIQUE (image_id, project_id)
+);
+CREATE INDEX images_shared_projects_project_id_idx ON images_shared_projects (project_id);
+`)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// updateFromV85 adds a template column to the auth_groups table. Template groups are only meant to be copied, so they
+// can't have members and their permissions aren't granted.
+func updateFromV85(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `ALTER TABLE auth_groups ADD COLUMN template INTEGER NOT NULL DEFAULT 0;`)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// updateFromV84 adds columns to the images_aliases table to override the auto_update setting of the image of an
+// alias, and to pin an alias to the fingerprint of an image. A NULL auto_update follows the setting of the image.
+func updateFromV84(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+ALTER TABLE images_aliases ADD COLUMN auto_update INTEGER;
+ALTER TABLE images_aliases ADD COLUMN pinned_fingerprint TEXT NOT NULL DEFAULT '';
+`)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// updateFromV83 adds a table for the audit trail of authorization configuration changes. Entries are append-only:
+// they can be deleted once they exceed the retention period, but never updated.
+func updateFromV83(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+CREATE TABLE auth_audit (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    date DATETIME NOT NULL,
+    actor TEXT NOT NULL,
+    actor_protocol TEXT NOT NULL,
+    object_type TEXT NOT NULL,
+    object_name TEXT NOT NULL,
+    action TEXT NOT NULL,
+    before TEXT,
+    after TEXT,
+    previous_hash TEXT NOT NULL,
+    hash TEXT NOT NULL
+);
+CREATE INDEX auth_audit_date_idx ON auth_audit (date);
+CREATE TRIGGER auth_audit_update
+    BEFORE UPDATE ON auth_audit
+    BEGIN
+        SELECT RAISE(ABORT, 'Auth audit entries cannot be modified');
+    END;
+`)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// updateFromV82 grants the can_edit_files entitlement wherever can_access_files is granted. The latter used to allow
+// both reading and writing the files of an instance, but now only allows reading them.
+func updateFromV82(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+INSERT OR IGNORE INTO permissions (entitlement, entity_type, entity_id, subtree_entity_type)
+    SELECT 'can_edit_files', entity_type, entity_id, subtree_entity_type FROM permissions WHERE entitlement = 'can_access_files';
+INSERT OR IGNORE INTO auth_groups_permissions (auth_group_id, permission_id)
+    SELECT auth_groups_permissions.auth_group_id, new_permissions.id
+    FROM auth_groups_permissions
+    JOIN permissions AS old_permissions ON auth_groups_permissions.permission_id = old_permissions.id
+    JOIN permissions AS new_permissions ON new_permissions.entity_type = old_permissions.entity_type
+        AND new_permissions.entity_id = old_permissions.entity_id
+        AND new_permissions.subtree_entity_type = old_permissions.subtree_entity_type
+    WHERE old_permissions.entitlement = 'can_access_files' AND new_permissions.entitlement = 'can_edit_files';
+INSERT OR IGNORE INTO auth_roles_entitlements (auth_role_id, entitlement)
+    SELECT auth_role_id, 'can_edit_files' FROM auth_roles_entitlements WHERE entitlement = 'can_access_files';
+`)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// updateFromV81 adds columns to the identities table for disabling an identity without deleting it, along with when
+// and by whom it was disabled.
+func updateFromV81(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+ALTER TABLE identities ADD COLUMN enabled INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE identities ADD COLUMN disabled_at DATETIME;
+ALTER TABLE identities ADD COLUMN disabled_by TEXT;
+`)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// updateFromV80 adds tables for instance pools and for the instances that are available in them. Instances that are
+// acquired from a pool are removed from the second table, as are deleted instances.
+func updateFromV80(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+CREATE TABLE instance_pools (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name TEXT NOT NULL,
+    project_id INTEGER NOT NULL,
+    description TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    name_pattern TEXT NOT NULL,
+    template TEXT NOT NULL,
+    UNIQUE (project_id, name),
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
+);
+CREATE TABLE instance_pools_instances (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    instance_pool_id INTEGER NOT NULL,
+    instance_id INTEGER NOT NULL,
+    UNIQUE (instance_id),
+    FOREIGN KEY (instance_pool_id) REFERENCES instance_pools (id) ON DELETE CASCADE,
+    FOREIGN KEY (instance_id) REFERENCES instances (id) ON DELETE CASCADE
+);
+`)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// updateFromV79 adds an enabled column to the auth_groups table. The permissions of a disabled group aren't granted,
+// but the group and its members are kept so that it can be enabled again.
+func updateFromV79(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `ALTER TABLE auth_groups ADD COLUMN enabled INTEGER NOT NULL DEFAULT 1;`)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// updateFromV78 adds a table for snapshot retention policies, which are either server-wide or belong to a project.
+func updateFromV78(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+CREATE TABLE snapshot_retention_policies (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name TEXT NOT NULL,
+    project_id INTEGER,
+    description TEXT NOT NULL,
+    rules TEXT NOT NULL,
+    pending_rules TEXT,
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX snapshot_retention_policies_unique_project_id_name ON snapshot_retention_policies (IFNULL(project_id, -1), name);
+`)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// updateFromV77 adds tables for auth roles, which are named bundles of entitlements on an entity type, and for the
+// roles that are granted to auth groups on a specific entity.
+func updateFromV77(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+CREATE TABLE auth_roles (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL,
+    entity_type INTEGER NOT NULL,
+    UNIQUE (name)
+);
+CREATE TABLE auth_roles_entitlements (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    auth_role_id INTEGER NOT NULL,
+    entitlement TEXT NOT NULL,
+    FOREIGN KEY (auth_role_id) REFERENCES auth_roles (id) ON DELETE CASCADE,
+    UNIQUE (auth_role_id, entitlement)
+);
+CREATE TABLE auth_groups_roles (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    auth_group_id INTEGER NOT NULL,
+    auth_role_id INTEGER NOT NULL,
+    entity_id INTEGER NOT NULL,
+    FOREIGN KEY (auth_group_id) REFERENCES auth_groups (id) ON DELETE CASCADE,
+    FOREIGN KEY (auth_role_id) REFERENCES auth_roles (id) ON DELETE CASCADE,
+    UNIQUE (auth_group_id, auth_role_id, entity_id)
+);
+`)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// updateFromV76 adds a table of warning suppression rules, and a suppressed column to the warnings table to tag the
+// warnings that were acknowledged on creation because they matched a rule.
+func updateFromV76(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+ALTER TABLE warnings ADD COLUMN suppressed INTEGER NOT NULL DEFAULT 0;
+CREATE TABLE warnings_suppressions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    type_code INTEGER,
+    entity_type_code INTEGER,
+    entity_id INTEGER,
+    message_pattern TEXT NOT NULL,
+    description TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME
+);
+`)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// updateFromV75 adds a table recording the parents of auth groups. A group inherits the permissions of its parents.
+func updateFromV75(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+CREATE TABLE auth_groups_parents (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    auth_group_id INTEGER NOT NULL,
+    parent_auth_group_id INTEGER NOT NULL,
+    FOREIGN KEY (auth_group_id) REFERENCES auth_groups (id) ON DELETE CASCADE,
+    FOREIGN KEY (parent_auth_group_id) REFERENCES auth_groups (id) ON DELETE CASCADE,
+    UNIQUE (auth_group_id, parent_auth_group_id)
+);
+`)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// updateFromV74 adds a last_used_at column to the auth_groups table, recording when a permission of the group last
+// granted access to a request.
+func updateFromV74(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `ALTER TABLE auth_groups ADD COLUMN last_used_at DATETIME;`)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// updateFromV73 adds a table recording the addresses of uplink networks that are automatically allocated to network
+// forwards and load balancers of OVN networks. Released allocations are kept as history, so the address must only be
+// unique among the allocations that have not been released.
+func updateFromV73(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+CREATE TABLE networks_uplink_allocations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    uplink_network_id INTEGER NOT NULL,
+    network_id INTEGER,
+    address TEXT NOT NULL,
+    type TEXT NOT NULL,
+    used_by TEXT NOT NULL,
+    allocated_at DATETIME NOT NULL,
+    released_at DATETIME,
+    FOREIGN KEY (uplink_network_id) REFERENCES "networks" (id) ON DELETE CASCADE,
+    FOREIGN KEY (network_id) REFERENCES "networks" (id) ON DELETE SET NULL
+);
+CREATE UNIQUE INDEX networks_uplink_allocations_unique_uplink_network_id_address ON networks_uplink_allocations (uplink_network_id, address) WHERE released_at IS NULL;
+`)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// updateFromV72 adds a subtree_entity_type column to the permissions table. When set, the entitlement of the
+// permission applies to all child entities of that type of the referenced entity (e.g. all storage volumes in a
+// storage pool). The unique constraint must include the new column, so the table is recreated.
+func updateFromV72(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+CREATE TABLE permissions_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    entitlement TEXT NOT NULL,
+    entity_type TEXT NOT NULL,
+    entity_id INTEGER NOT NULL,
+    subtree_entity_type TEXT NOT NULL DEFAULT '',
+    UNIQUE (entitlement, entity_type, entity_id, subtree_entity_type)
+);
+
+INSERT INTO permissions_new (id, entitlement, entity_type, entity_id)
+    SELECT id, entitlement, entity_type, entity_id FROM permissions;
+
+PRAGMA foreign_keys = OFF;
+PRAGMA legacy_alter_table = ON;
+
+DROP TABLE permissions;
+ALTER TABLE permissions_new RENAME TO permissions;
+
+PRAGMA foreign_keys = ON;
+PRAGMA legacy_alter_table = OFF;
+`)
+	if err != nil {
+		return err
+	}
+
+	return nil
 }
 
 func updateFromV71(ctx context.Context, tx *sql.Tx) error {
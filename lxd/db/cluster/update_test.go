@@ -12,10 +12,12 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/canonical/lxd/lxd/auth"
 	"github.com/canonical/lxd/lxd/db/cluster"
 	"github.com/canonical/lxd/lxd/db/query"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
 	"github.com/canonical/lxd/shared/osarch"
 )
 
@@ -839,3 +841,106 @@ INSERT INTO certificates_projects (certificate_id, project_id) VALUES (1, 4);
 	require.NoError(t, err)
 	assert.Equal(t, c2, metadata.Certificate)
 }
+
+func TestUpdateFromV72(t *testing.T) {
+	schema := cluster.Schema()
+	db, err := schema.ExerciseUpdate(73, func(db *sql.DB) {
+		_, err := db.Exec(`
+INSERT INTO auth_groups (name, description) VALUES ('g1', '');
+INSERT INTO permissions (entitlement, entity_type, entity_id) VALUES ('can_view', 3, 1);
+INSERT INTO permissions (entitlement, entity_type, entity_id) VALUES ('can_edit', 3, 1);
+INSERT INTO auth_groups_permissions (auth_group_id, permission_id) VALUES (1, 1);
+INSERT INTO auth_groups_permissions (auth_group_id, permission_id) VALUES (1, 2);
+`)
+		require.NoError(t, err)
+	})
+	require.NoError(t, err)
+
+	// Existing permissions aren't subtree permissions, and keep their IDs and group memberships.
+	var count int
+	err = db.QueryRow(`SELECT count(*) FROM permissions WHERE subtree_entity_type = ''`).Scan(&count)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	err = db.QueryRow(`SELECT count(*) FROM auth_groups_permissions JOIN permissions ON permissions.id = auth_groups_permissions.permission_id`).Scan(&count)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// Looking up an existing permission as an ordinary permission with the values written by Go finds the migrated
+	// row rather than allowing a duplicate to be created.
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	defer func() { _ = tx.Rollback() }()
+
+	var id int
+	err = tx.QueryRow(`SELECT id FROM permissions WHERE entity_type = ? AND entity_id = ? AND entitlement = ? AND subtree_entity_type = ?`, cluster.EntityType(entity.TypeProject), 1, auth.EntitlementCanView, entity.Type("")).Scan(&id)
+	require.NoError(t, err)
+	assert.Equal(t, 1, id)
+
+	_, err = tx.Exec(`INSERT INTO permissions (entitlement, entity_type, entity_id, subtree_entity_type) VALUES (?, ?, ?, ?)`, auth.EntitlementCanView, cluster.EntityType(entity.TypeProject), 1, entity.Type(""))
+	assert.Error(t, err)
+
+	// Subtree permissions of the same entity are distinct permissions.
+	_, err = tx.Exec(`INSERT INTO permissions (entitlement, entity_type, entity_id, subtree_entity_type) VALUES (?, ?, ?, ?)`, auth.EntitlementCanView, cluster.EntityType(entity.TypeProject), 1, entity.TypeInstance)
+	assert.NoError(t, err)
+}
+
+func TestUpdateFromV82(t *testing.T) {
+	schema := cluster.Schema()
+	db, err := schema.ExerciseUpdate(83, func(db *sql.DB) {
+		_, err := db.Exec(`
+INSERT INTO auth_groups (name, description) VALUES ('g1', '');
+INSERT INTO auth_groups (name, description) VALUES ('g2', '');
+INSERT INTO permissions (entitlement, entity_type, entity_id) VALUES ('can_access_files', 20, 1);
+INSERT INTO permissions (entitlement, entity_type, entity_id, subtree_entity_type) VALUES ('can_access_files', 20, 2, 'storage_volume');
+INSERT INTO permissions (entitlement, entity_type, entity_id) VALUES ('can_exec', 20, 1);
+INSERT INTO auth_groups_permissions (auth_group_id, permission_id) VALUES (1, 1);
+INSERT INTO auth_groups_permissions (auth_group_id, permission_id) VALUES (2, 1);
+INSERT INTO auth_groups_permissions (auth_group_id, permission_id) VALUES (2, 2);
+INSERT INTO auth_groups_permissions (auth_group_id, permission_id) VALUES (1, 3);
+INSERT INTO auth_roles (name, description, entity_type) VALUES ('r1', '', 20);
+INSERT INTO auth_roles (name, description, entity_type) VALUES ('r2', '', 20);
+INSERT INTO auth_roles_entitlements (auth_role_id, entitlement) VALUES (1, 'can_access_files');
+INSERT INTO auth_roles_entitlements (auth_role_id, entitlement) VALUES (2, 'can_exec');
+`)
+		require.NoError(t, err)
+	})
+	require.NoError(t, err)
+
+	getGroupPermissions := func(groupName string) []string {
+		rows, err := db.Query(`
+SELECT permissions.entitlement || ':' || permissions.entity_id || ':' || permissions.subtree_entity_type
+FROM auth_groups_permissions
+JOIN permissions ON auth_groups_permissions.permission_id = permissions.id
+JOIN auth_groups ON auth_groups_permissions.auth_group_id = auth_groups.id
+WHERE auth_groups.name = ?`, groupName)
+		require.NoError(t, err)
+		defer func() { _ = rows.Close() }()
+
+		var permissions []string
+		for rows.Next() {
+			var permission string
+			require.NoError(t, rows.Scan(&permission))
+			permissions = append(permissions, permission)
+		}
+
+		require.NoError(t, rows.Err())
+		return permissions
+	}
+
+	assert.ElementsMatch(t, []string{"can_access_files:1:", "can_edit_files:1:", "can_exec:1:"}, getGroupPermissions("g1"))
+	assert.ElementsMatch(t, []string{"can_access_files:1:", "can_edit_files:1:", "can_access_files:2:storage_volume", "can_edit_files:2:storage_volume"}, getGroupPermissions("g2"))
+
+	var count int
+	err = db.QueryRow(`SELECT count(*) FROM permissions WHERE entitlement = 'can_edit_files'`).Scan(&count)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	err = db.QueryRow(`SELECT count(*) FROM auth_roles_entitlements WHERE auth_role_id = 1 AND entitlement = 'can_edit_files'`).Scan(&count)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	err = db.QueryRow(`SELECT count(*) FROM auth_roles_entitlements WHERE auth_role_id = 2 AND entitlement = 'can_edit_files'`).Scan(&count)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
@@ -47,6 +47,7 @@ type Warning struct {
 	UpdatedDate   time.Time
 	LastMessage   string
 	Count         int
+	Suppressed    bool
 }
 
 // WarningFilter specifies potential query parameter fields.
@@ -78,5 +79,6 @@ func (w Warning) ToAPI() api.Warning {
 		LastSeenAt:  w.LastSeenDate,
 		LastMessage: w.LastMessage,
 		Severity:    warningtype.Severities[typeCode.Severity()],
+		Suppressed:  w.Suppressed,
 	}
 }
@@ -326,12 +326,31 @@ func (c *Cluster) GetNodeID() int64 {
 //
 // If EnterExclusive has been called before, calling Transaction will block
 // until ExitExclusive has been called as well to release the lock.
+//
+// If the context carries a database Replica, the transaction is run read-only
+// against the local copy of the database instead.
 func (c *Cluster) Transaction(ctx context.Context, f func(context.Context, *ClusterTx) error) error {
+	replica := replicaFromContext(ctx)
+	if replica != nil {
+		return replica.transaction(ctx, f)
+	}
+
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	return c.transaction(ctx, f)
 }
 
+// TransactionRetry is like Transaction, but the whole transaction is retried with exponential backoff if it fails
+// because the dqlite leader changed, until the given context is done.
+//
+// The given function must be idempotent, as it may be run more than once. If leadership was lost while committing,
+// the previous attempt may have been applied, see query.IsRetryAfterLeadershipLost.
+func (c *Cluster) TransactionRetry(ctx context.Context, f func(context.Context, *ClusterTx) error) error {
+	return query.RetryBackoff(ctx, func(ctx context.Context) error {
+		return c.Transaction(ctx, f)
+	})
+}
+
 // EnterExclusive acquires a lock on the cluster db, so any successive call to
 // Transaction will block until ExitExclusive has been called.
 func (c *Cluster) EnterExclusive() error {
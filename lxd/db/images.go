@@ -121,18 +121,23 @@ func (c *ClusterTx) imageFill(ctx context.Context, id int, image *api.Image, cre
 
 	image.Properties = properties
 
-	q := "SELECT name, description FROM images_aliases WHERE image_id=?"
+	q := "SELECT name, description, auto_update, pinned_fingerprint FROM images_aliases WHERE image_id=?"
 
 	// Get the aliases
 	aliases := []api.ImageAlias{}
 	err = query.Scan(ctx, c.tx, q, func(scan func(dest ...any) error) error {
 		alias := api.ImageAlias{}
+		var autoUpdate sql.NullBool
 
-		err := scan(&alias.Name, &alias.Description)
+		err := scan(&alias.Name, &alias.Description, &autoUpdate, &alias.PinnedFingerprint)
 		if err != nil {
 			return err
 		}
 
+		if autoUpdate.Valid {
+			alias.AutoUpdate = &autoUpdate.Bool
+		}
+
 		aliases = append(aliases, alias)
 		return nil
 	}, id)
@@ -142,6 +147,20 @@ func (c *ClusterTx) imageFill(ctx context.Context, id int, image *api.Image, cre
 
 	image.Aliases = aliases
 
+	// Get the projects the image is shared with
+	q = `
+SELECT projects.name FROM projects
+	JOIN images_shared_projects ON images_shared_projects.project_id = projects.id
+WHERE images_shared_projects.image_id = ?
+ORDER BY projects.name
+`
+	sharedWith, err := query.SelectStrings(ctx, c.tx, q, id)
+	if err != nil {
+		return err
+	}
+
+	image.SharedWith = sharedWith
+
 	_, source, err := c.GetImageSource(ctx, id)
 	if err == nil {
 		image.UpdateSource = &source
@@ -178,6 +197,118 @@ WHERE images_profiles.image_id = ? AND projects.name = ?
 	return nil
 }
 
+// UpdateImageSharedProjects sets the projects that the image with the given ID is shared with.
+func (c *ClusterTx) UpdateImageSharedProjects(ctx context.Context, id int, projectNames []string) error {
+	_, err := c.tx.ExecContext(ctx, "DELETE FROM images_shared_projects WHERE image_id=?", id)
+	if err != nil {
+		return err
+	}
+
+	for _, projectName := range projectNames {
+		result, err := c.tx.ExecContext(ctx, "INSERT INTO images_shared_projects (image_id, project_id) SELECT ?, id FROM projects WHERE name = ?", id, projectName)
+		if err != nil {
+			return err
+		}
+
+		n, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if n == 0 {
+			return api.StatusErrorf(http.StatusNotFound, "Project %q not found", projectName)
+		}
+	}
+
+	return nil
+}
+
+// GetSharedImagesFingerprints returns the fingerprints of the images of other projects that are shared with the given
+// project, mapped to the name of the project that owns them. If the same image is shared from several projects, the
+// project that was created first is used.
+func (c *ClusterTx) GetSharedImagesFingerprints(ctx context.Context, projectName string, fingerprintPrefix string) (map[string]string, error) {
+	q := `
+SELECT images.fingerprint, projects.name
+  FROM images
+  JOIN projects ON projects.id = images.project_id
+  JOIN images_shared_projects ON images_shared_projects.image_id = images.id
+ WHERE images_shared_projects.project_id = (SELECT id FROM projects WHERE name = ?) AND images.fingerprint LIKE ?
+ ORDER BY projects.id DESC
+`
+	enabled, err := cluster.ProjectHasImages(ctx, c.tx, projectName)
+	if err != nil {
+		return nil, fmt.Errorf("Check if project has images: %w", err)
+	}
+
+	if !enabled {
+		projectName = "default"
+	}
+
+	images := map[string]string{}
+	err = query.Scan(ctx, c.tx, q, func(scan func(dest ...any) error) error {
+		var fingerprint, owner string
+
+		err := scan(&fingerprint, &owner)
+		if err != nil {
+			return err
+		}
+
+		images[fingerprint] = owner
+		return nil
+	}, projectName, fingerprintPrefix+"%")
+	if err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
+// GetSharedImageByFingerprintPrefix returns the image matching the fingerprint prefix among the images of other
+// projects that are shared with the given project. The origin and project of the returned image are set, and its
+// profiles are left empty as they belong to the project that owns it.
+func (c *ClusterTx) GetSharedImageByFingerprintPrefix(ctx context.Context, projectName string, fingerprintPrefix string) (int, *api.Image, error) {
+	if fingerprintPrefix == "" {
+		return -1, nil, errors.New("No fingerprint prefix specified for the image")
+	}
+
+	images, err := c.GetSharedImagesFingerprints(ctx, projectName, fingerprintPrefix)
+	if err != nil {
+		return -1, nil, fmt.Errorf("Failed to fetch shared images: %w", err)
+	}
+
+	if len(images) == 0 {
+		return -1, nil, api.StatusErrorf(http.StatusNotFound, "Image not found")
+	} else if len(images) > 1 {
+		return -1, nil, fmt.Errorf("More than one image matches")
+	}
+
+	for fingerprint, owner := range images {
+		id, image, err := c.GetImageByFingerprintPrefix(ctx, fingerprint, cluster.ImageFilter{Project: &owner})
+		if err != nil {
+			return -1, nil, err
+		}
+
+		image.Origin = api.ImageOriginShared
+		image.Project = owner
+		image.Profiles = []string{}
+
+		return id, image, nil
+	}
+
+	return -1, nil, api.StatusErrorf(http.StatusNotFound, "Image not found")
+}
+
+// GetProjectUsableImage returns the image of the project matching the fingerprint prefix, or the image shared with the
+// project from another project if the project has none.
+func (c *ClusterTx) GetProjectUsableImage(ctx context.Context, projectName string, fingerprintPrefix string) (int, *api.Image, error) {
+	id, image, err := c.GetImageByFingerprintPrefix(ctx, fingerprintPrefix, cluster.ImageFilter{Project: &projectName})
+	if err == nil || !api.StatusErrorCheck(err, http.StatusNotFound) {
+		return id, image, err
+	}
+
+	return c.GetSharedImageByFingerprintPrefix(ctx, projectName, fingerprintPrefix)
+}
+
 // GetImagesFingerprints returns the names of all images (optionally only the public ones).
 func (c *ClusterTx) GetImagesFingerprints(ctx context.Context, projectName string, publicOnly bool) ([]string, error) {
 	q := `
@@ -209,6 +340,37 @@ SELECT fingerprint
 	return fingerprints, nil
 }
 
+// GetImageDeltaSourcesFingerprints returns the fingerprints of the images of the project that come from the given
+// source server and alias, or from the same source as the image with the given fingerprint, from the most recently
+// uploaded. They are the previous versions of the image that it can be transferred as a delta against.
+func (c *ClusterTx) GetImageDeltaSourcesFingerprints(ctx context.Context, projectName string, fingerprint string, server string, alias string) ([]string, error) {
+	q := `
+SELECT images.fingerprint
+  FROM images
+  JOIN projects ON projects.id = images.project_id
+  JOIN images_source ON images_source.image_id = images.id
+ WHERE projects.name = ? AND images.fingerprint != ? AND (
+       (images_source.server = ? AND images_source.alias = ?) OR EXISTS (
+       SELECT 1
+         FROM images_source AS target_source
+         JOIN images AS target ON target.id = target_source.image_id
+        WHERE target.fingerprint = ? AND target.project_id = images.project_id
+          AND target_source.server = images_source.server AND target_source.alias = images_source.alias))
+ ORDER BY images.upload_date DESC
+`
+
+	enabled, err := cluster.ProjectHasImages(ctx, c.tx, projectName)
+	if err != nil {
+		return nil, fmt.Errorf("Check if project has images: %w", err)
+	}
+
+	if !enabled {
+		projectName = "default"
+	}
+
+	return query.SelectStrings(ctx, c.tx, q, projectName, fingerprint, server, alias, fingerprint)
+}
+
 // CreateImageSource inserts a new image source.
 func (c *ClusterTx) CreateImageSource(ctx context.Context, id int, server string, protocol string, certificate string, alias string) error {
 	protocolInt := -1
@@ -628,7 +790,7 @@ SELECT images_aliases.name
 func (c *ClusterTx) GetImageAlias(ctx context.Context, projectName string, imageName string, isTrustedClient bool) (int, api.ImageAliasesEntry, error) {
 	id := -1
 	entry := api.ImageAliasesEntry{}
-	q := `SELECT images_aliases.id, images.fingerprint, images.type, images_aliases.description
+	q := `SELECT images_aliases.id, images.fingerprint, images.type, images_aliases.description, images_aliases.auto_update, images_aliases.pinned_fingerprint
 			 FROM images_aliases
 			 INNER JOIN images
 			 ON images_aliases.image_id=images.id
@@ -648,11 +810,12 @@ func (c *ClusterTx) GetImageAlias(ctx context.Context, projectName string, image
 		projectName = "default"
 	}
 
-	var fingerprint, description string
+	var fingerprint, description, pinnedFingerprint string
 	var imageType int
+	var autoUpdate sql.NullBool
 
 	arg1 := []any{projectName, imageName}
-	arg2 := []any{&id, &fingerprint, &imageType, &description}
+	arg2 := []any{&id, &fingerprint, &imageType, &description, &autoUpdate, &pinnedFingerprint}
 	err = c.tx.QueryRowContext(ctx, q, arg1...).Scan(arg2...)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -666,10 +829,54 @@ func (c *ClusterTx) GetImageAlias(ctx context.Context, projectName string, image
 	entry.Target = fingerprint
 	entry.Description = description
 	entry.Type = instancetype.Type(imageType).String()
+	entry.PinnedFingerprint = pinnedFingerprint
+	if autoUpdate.Valid {
+		entry.AutoUpdate = &autoUpdate.Bool
+	}
 
 	return id, entry, nil
 }
 
+// GetSharedImageAlias returns the alias with the given name of an image of another project that is shared with the
+// given project, along with the name of the project that owns it.
+func (c *ClusterTx) GetSharedImageAlias(ctx context.Context, projectName string, aliasName string) (string, api.ImageAliasesEntry, error) {
+	q := `
+SELECT projects.name
+  FROM images_aliases
+  JOIN images ON images.id = images_aliases.image_id
+  JOIN projects ON projects.id = images_aliases.project_id
+  JOIN images_shared_projects ON images_shared_projects.image_id = images.id
+ WHERE images_shared_projects.project_id = (SELECT id FROM projects WHERE name = ?) AND images_aliases.name = ?
+ ORDER BY projects.id
+`
+	enabled, err := cluster.ProjectHasImages(ctx, c.tx, projectName)
+	if err != nil {
+		return "", api.ImageAliasesEntry{}, fmt.Errorf("Check if project has images: %w", err)
+	}
+
+	if !enabled {
+		projectName = "default"
+	}
+
+	owners, err := query.SelectStrings(ctx, c.tx, q, projectName, aliasName)
+	if err != nil {
+		return "", api.ImageAliasesEntry{}, err
+	}
+
+	if len(owners) == 0 {
+		return "", api.ImageAliasesEntry{}, api.StatusErrorf(http.StatusNotFound, "Image alias not found")
+	} else if len(owners) > 1 {
+		return "", api.ImageAliasesEntry{}, api.StatusErrorf(http.StatusConflict, "Image alias %q is shared from more than one project", aliasName)
+	}
+
+	_, entry, err := c.GetImageAlias(ctx, owners[0], aliasName, true)
+	if err != nil {
+		return "", api.ImageAliasesEntry{}, err
+	}
+
+	return owners[0], entry, nil
+}
+
 // RenameImageAlias renames the alias with the given ID.
 func (c *ClusterTx) RenameImageAlias(ctx context.Context, id int, name string) error {
 	q := "UPDATE images_aliases SET name=? WHERE id=?"
@@ -702,11 +909,62 @@ DELETE
 	return nil
 }
 
-// MoveImageAlias changes the image ID associated with an alias.
-func (c *ClusterTx) MoveImageAlias(ctx context.Context, source int, destination int) error {
-	q := "UPDATE images_aliases SET image_id=? WHERE image_id=?"
-	_, err := c.tx.ExecContext(ctx, q, destination, source)
+// imageAliasFollowsUpdates is the SQL condition matching the aliases that follow the updates of their image: aliases
+// that aren't pinned, and whose auto_update setting is enabled or unset while the image is auto-updated (given by the
+// single placeholder of the condition).
+const imageAliasFollowsUpdates = "pinned_fingerprint = '' AND IFNULL(auto_update, ?) = 1"
+
+// MoveImageAlias changes the image ID associated with the aliases of the source image that follow its updates. The
+// imageAutoUpdate argument is the auto_update setting of the source image.
+func (c *ClusterTx) MoveImageAlias(ctx context.Context, source int, destination int, imageAutoUpdate bool) error {
+	q := "UPDATE images_aliases SET image_id=? WHERE image_id=? AND " + imageAliasFollowsUpdates
+	_, err := c.tx.ExecContext(ctx, q, destination, source, imageAutoUpdate)
+
+	return err
+}
+
+// GetHeldImageAliases returns the aliases of the image that don't follow its updates, because they are pinned or
+// have auto_update disabled. The imageAutoUpdate argument is the auto_update setting of the image.
+func (c *ClusterTx) GetHeldImageAliases(ctx context.Context, imageID int, imageAutoUpdate bool) ([]api.ImageAlias, error) {
+	q := "SELECT name, description, auto_update, pinned_fingerprint FROM images_aliases WHERE image_id=? AND NOT (" + imageAliasFollowsUpdates + ")"
+
+	var aliases []api.ImageAlias
+	err := query.Scan(ctx, c.tx, q, func(scan func(dest ...any) error) error {
+		alias := api.ImageAlias{}
+		var autoUpdate sql.NullBool
+
+		err := scan(&alias.Name, &alias.Description, &autoUpdate, &alias.PinnedFingerprint)
+		if err != nil {
+			return err
+		}
+
+		if autoUpdate.Valid {
+			alias.AutoUpdate = &autoUpdate.Bool
+		}
+
+		aliases = append(aliases, alias)
+		return nil
+	}, imageID, imageAutoUpdate)
+	if err != nil {
+		return nil, err
+	}
+
+	return aliases, nil
+}
+
+// GetImageIDsWithAutoUpdateAliases returns the IDs of the images that have aliases with auto_update enabled, and
+// which aren't pinned.
+func (c *ClusterTx) GetImageIDsWithAutoUpdateAliases(ctx context.Context) ([]int, error) {
+	q := "SELECT DISTINCT image_id FROM images_aliases WHERE auto_update = 1 AND pinned_fingerprint = ''"
+
+	return query.SelectIntegers(ctx, c.tx, q)
+}
 
+// UpdateImageAliasAutoUpdate sets the auto_update override and pinned fingerprint of the alias with the given ID. A nil
+// autoUpdate makes the alias follow the auto_update setting of its image.
+func (c *ClusterTx) UpdateImageAliasAutoUpdate(ctx context.Context, aliasID int, autoUpdate *bool, pinnedFingerprint string) error {
+	stmt := `UPDATE images_aliases SET auto_update=?, pinned_fingerprint=? WHERE id=?`
+	_, err := c.tx.ExecContext(ctx, stmt, autoUpdate, pinnedFingerprint, aliasID)
 	return err
 }
 
@@ -4,6 +4,7 @@ package db_test
 
 import (
 	"context"
+	"net/http"
 	"testing"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 
 	"github.com/canonical/lxd/lxd/db"
 	"github.com/canonical/lxd/lxd/db/cluster"
+	"github.com/canonical/lxd/shared/api"
 )
 
 func TestLocateImage(t *testing.T) {
@@ -106,3 +108,110 @@ func TestGetImage(t *testing.T) {
 		return nil
 	})
 }
+
+func TestGetImageDeltaSourcesFingerprints(t *testing.T) {
+	dbCluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+	project := "default"
+
+	_ = dbCluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		for i, fingerprint := range []string{"old", "new", "other"} {
+			err := tx.CreateImage(ctx, project, fingerprint, "x.gz", 16, false, false, "amd64", time.Now(), time.Now(), map[string]string{}, "container", nil)
+			require.NoError(t, err)
+
+			id, _, err := tx.GetImage(ctx, fingerprint, cluster.ImageFilter{Project: &project})
+			require.NoError(t, err)
+
+			alias := "jammy"
+			if i == 2 {
+				alias = "noble"
+			}
+
+			err = tx.CreateImageSource(ctx, id, "https://images.example.com", "simplestreams", "", alias)
+			require.NoError(t, err)
+		}
+
+		// Images from the same source as the given image.
+		fingerprints, err := tx.GetImageDeltaSourcesFingerprints(ctx, project, "new", "", "")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"old"}, fingerprints)
+
+		// Images from the given source.
+		fingerprints, err = tx.GetImageDeltaSourcesFingerprints(ctx, project, "missing", "https://images.example.com", "noble")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"other"}, fingerprints)
+
+		fingerprints, err = tx.GetImageDeltaSourcesFingerprints(ctx, project, "missing", "https://other.example.com", "jammy")
+		require.NoError(t, err)
+		assert.Empty(t, fingerprints)
+
+		return nil
+	})
+}
+
+func TestGetSharedImageByFingerprintPrefix(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	for _, name := range []string{"base", "consumer", "other"} {
+		project := cluster.Project{}
+		project.Name = name
+		id, err := cluster.CreateProject(ctx, tx.Tx(), project)
+		require.NoError(t, err)
+
+		err = cluster.CreateProjectConfig(ctx, tx.Tx(), id, map[string]string{"features.images": "true"})
+		require.NoError(t, err)
+	}
+
+	err := tx.CreateImage(ctx, "base", "abcd1", "x.gz", 16, false, false, "amd64", time.Now(), time.Now(), map[string]string{}, "container", nil)
+	require.NoError(t, err)
+
+	base := "base"
+	id, _, err := tx.GetImage(ctx, "abcd1", cluster.ImageFilter{Project: &base})
+	require.NoError(t, err)
+
+	err = tx.CreateImageAlias(ctx, "base", "jammy", id, "")
+	require.NoError(t, err)
+
+	// The image isn't shared yet.
+	_, _, err = tx.GetProjectUsableImage(ctx, "consumer", "abcd")
+	assert.True(t, api.StatusErrorCheck(err, http.StatusNotFound))
+
+	err = tx.UpdateImageSharedProjects(ctx, id, []string{"consumer"})
+	require.NoError(t, err)
+
+	err = tx.UpdateImageSharedProjects(ctx, id, []string{"missing"})
+	assert.True(t, api.StatusErrorCheck(err, http.StatusNotFound))
+
+	err = tx.UpdateImageSharedProjects(ctx, id, []string{"consumer"})
+	require.NoError(t, err)
+
+	_, img, err := tx.GetImage(ctx, "abcd1", cluster.ImageFilter{Project: &base})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"consumer"}, img.SharedWith)
+	assert.Empty(t, img.Origin)
+
+	fingerprints, err := tx.GetSharedImagesFingerprints(ctx, "consumer", "")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"abcd1": "base"}, fingerprints)
+
+	sharedID, img, err := tx.GetProjectUsableImage(ctx, "consumer", "abcd")
+	require.NoError(t, err)
+	assert.Equal(t, id, sharedID)
+	assert.Equal(t, api.ImageOriginShared, img.Origin)
+	assert.Equal(t, "base", img.Project)
+
+	owner, alias, err := tx.GetSharedImageAlias(ctx, "consumer", "jammy")
+	require.NoError(t, err)
+	assert.Equal(t, "base", owner)
+	assert.Equal(t, "abcd1", alias.Target)
+
+	// The image isn't shared with other projects.
+	_, _, err = tx.GetProjectUsableImage(ctx, "other", "abcd")
+	assert.True(t, api.StatusErrorCheck(err, http.StatusNotFound))
+
+	_, _, err = tx.GetSharedImageAlias(ctx, "other", "jammy")
+	assert.True(t, api.StatusErrorCheck(err, http.StatusNotFound))
+}
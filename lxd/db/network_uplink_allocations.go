@@ -0,0 +1,109 @@
+//go:build linux && cgo && !agent
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/canonical/lxd/lxd/db/query"
+	"github.com/canonical/lxd/shared/api"
+)
+
+// CreateNetworkUplinkAllocation records that an address of the uplink network has been automatically allocated to
+// an entity of the network with the given ID. It fails if the address is already allocated and not released.
+func (c *ClusterTx) CreateNetworkUplinkAllocation(ctx context.Context, uplinkNetworkID int64, networkID int64, allocation *api.NetworkUplinkAllocation) (int64, error) {
+	result, err := c.tx.ExecContext(ctx, `
+		INSERT INTO networks_uplink_allocations
+		(uplink_network_id, network_id, address, type, used_by, allocated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		`, uplinkNetworkID, networkID, allocation.Address, allocation.Type, allocation.UsedBy, time.Now().UTC())
+	if err != nil {
+		return -1, err
+	}
+
+	allocationID, err := result.LastInsertId()
+	if err != nil {
+		return -1, err
+	}
+
+	return allocationID, nil
+}
+
+// DeleteNetworkUplinkAllocation deletes the uplink network allocation with the given ID.
+// It is used when the entity that the address was allocated to could not be created.
+func (c *ClusterTx) DeleteNetworkUplinkAllocation(ctx context.Context, allocationID int64) error {
+	_, err := c.tx.ExecContext(ctx, "DELETE FROM networks_uplink_allocations WHERE id = ?", allocationID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ReleaseNetworkUplinkAllocation marks the allocation of the given address of the uplink network as released, so
+// that the address can be allocated again. The allocation is kept as history.
+func (c *ClusterTx) ReleaseNetworkUplinkAllocation(ctx context.Context, uplinkNetworkID int64, address string) error {
+	_, err := c.tx.ExecContext(ctx, `
+		UPDATE networks_uplink_allocations SET released_at = ?
+		WHERE uplink_network_id = ? AND address = ? AND released_at IS NULL
+		`, time.Now().UTC(), uplinkNetworkID, address)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ReleaseNetworkUplinkAllocations marks all allocations of uplink network addresses to entities of the network with
+// the given ID as released.
+func (c *ClusterTx) ReleaseNetworkUplinkAllocations(ctx context.Context, networkID int64) error {
+	_, err := c.tx.ExecContext(ctx, `
+		UPDATE networks_uplink_allocations SET released_at = ?
+		WHERE network_id = ? AND released_at IS NULL
+		`, time.Now().UTC(), networkID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetNetworkUplinkAllocations returns the automatic allocations of addresses of the uplink network.
+// If includeReleased is true, allocations that have been released are also returned.
+func (c *ClusterTx) GetNetworkUplinkAllocations(ctx context.Context, uplinkNetworkID int64, includeReleased bool) ([]api.NetworkUplinkAllocation, error) {
+	q := `
+		SELECT address, type, used_by, allocated_at, released_at
+		FROM networks_uplink_allocations
+		WHERE uplink_network_id = ?
+		`
+
+	if !includeReleased {
+		q += " AND released_at IS NULL"
+	}
+
+	q += " ORDER BY id"
+
+	var allocations []api.NetworkUplinkAllocation
+	err := query.Scan(ctx, c.tx, q, func(scan func(dest ...any) error) error {
+		allocation := api.NetworkUplinkAllocation{Auto: true}
+		var releasedAt sql.NullTime
+
+		err := scan(&allocation.Address, &allocation.Type, &allocation.UsedBy, &allocation.AllocatedAt, &releasedAt)
+		if err != nil {
+			return err
+		}
+
+		allocation.ReleasedAt = releasedAt.Time // Convert nulls to zero.
+		allocations = append(allocations, allocation)
+
+		return nil
+	}, uplinkNetworkID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed loading uplink network allocations: %w", err)
+	}
+
+	return allocations, nil
+}
@@ -76,6 +76,13 @@ const (
 	RenewServerCertificate
 	RemoveExpiredTokens
 	ClusterHeal
+	ClusterDatabaseMaintenance
+	InstanceFilesystemUnfreeze
+	InstanceScheduledStart
+	InstanceScheduledStop
+	InstanceFreezeTimeout
+	InstancePoolReplenish
+	AuthGroupsImport
 )
 
 // Description return a human-readable description of the operation type.
@@ -199,6 +206,20 @@ func (t Type) Description() string {
 		return "Remove expired tokens"
 	case ClusterHeal:
 		return "Healing cluster"
+	case ClusterDatabaseMaintenance:
+		return "Maintaining cluster database"
+	case InstanceFilesystemUnfreeze:
+		return "Unfreezing instance filesystem"
+	case InstanceScheduledStart:
+		return "Starting instance on schedule"
+	case InstanceScheduledStop:
+		return "Stopping instance on schedule"
+	case InstanceFreezeTimeout:
+		return "Unfreezing instance after freeze timeout"
+	case InstancePoolReplenish:
+		return "Replenishing instance pools"
+	case AuthGroupsImport:
+		return "Importing authorization groups"
 	default:
 		return "Executing operation"
 	}
@@ -221,12 +242,20 @@ func (t Type) Permission() (entity.Type, auth.Entitlement) {
 		return entity.TypeInstance, auth.EntitlementCanUpdateState
 	case InstanceUnfreeze:
 		return entity.TypeInstance, auth.EntitlementCanUpdateState
+	case InstanceFilesystemUnfreeze:
+		return entity.TypeInstance, auth.EntitlementCanUpdateState
 	case InstanceStart:
 		return entity.TypeInstance, auth.EntitlementCanUpdateState
 	case InstanceStop:
 		return entity.TypeInstance, auth.EntitlementCanUpdateState
 	case InstanceRestart:
 		return entity.TypeInstance, auth.EntitlementCanUpdateState
+	case InstanceScheduledStart:
+		return entity.TypeInstance, auth.EntitlementCanUpdateState
+	case InstanceScheduledStop:
+		return entity.TypeInstance, auth.EntitlementCanUpdateState
+	case InstanceFreezeTimeout:
+		return entity.TypeInstance, auth.EntitlementCanUpdateState
 	case CommandExec:
 		return entity.TypeInstance, auth.EntitlementCanExec
 	case SnapshotCreate:
@@ -18,6 +18,13 @@ import (
 
 const maxRetries = 250
 
+// Parameters of the exponential backoff used by RetryBackoff.
+const (
+	backoffMaxAttempts = 10
+	backoffInitial     = 50 * time.Millisecond
+	backoffMax         = 5 * time.Second
+)
+
 // Retry wraps a function that interacts with the database, and retries it in
 // case a transient error is hit.
 //
@@ -57,6 +64,104 @@ func Retry(ctx context.Context, f func(ctx context.Context) error) error {
 	return err
 }
 
+// retryContextKey is the key of the context value telling the function retried by RetryBackoff that a previous
+// attempt may have been applied.
+type retryContextKey struct{}
+
+// RetryBackoff wraps an idempotent function that interacts with the database, and retries it with exponential
+// backoff in case the dqlite leader changes. It gives up once the context is done.
+//
+// Other transient errors, like the database being locked, are already retried by Retry so they aren't retried
+// again. If an attempt fails because leadership was lost whilst committing, its changes may still have been applied.
+// The next attempts can find out with IsRetryAfterLeadershipLost.
+//
+// This should be typically used to wrap transactions of API handlers that are expected to survive a leader election.
+func RetryBackoff(ctx context.Context, f func(ctx context.Context) error) error {
+	delay := backoffInitial
+	attemptCtx := ctx
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = f(attemptCtx)
+		if err == nil {
+			if attempt > 1 {
+				logger.Debug("Database operation succeeded after retrying", logger.Ctx{"attempts": attempt})
+			}
+
+			return nil
+		}
+
+		if !IsLeadershipChangeError(err) {
+			return err
+		}
+
+		if isLeadershipLostError(err) {
+			attemptCtx = context.WithValue(ctx, retryContextKey{}, true)
+		}
+
+		if attempt == backoffMaxAttempts {
+			break
+		}
+
+		logger.Debug("Database operation failed, retrying", logger.Ctx{"attempt": attempt, "delay": delay, "err": err})
+
+		select {
+		case <-ctx.Done():
+			logger.Debug("Database operation failed, context done", logger.Ctx{"attempts": attempt, "err": err})
+			return err
+		case <-time.After(jitter.Deviation(nil, 0.2)(delay)):
+		}
+
+		delay = min(delay*2, backoffMax)
+	}
+
+	logger.Debug("Database operation failed, giving up", logger.Ctx{"attempts": backoffMaxAttempts, "err": err})
+
+	return err
+}
+
+// IsRetryAfterLeadershipLost returns true if the function wrapped by RetryBackoff is being retried after a previous
+// attempt failed because the dqlite leader lost leadership. In that case the changes of the previous attempt may have
+// been committed regardless of the error, so functions that aren't idempotent should check whether they were before
+// reporting a conflict.
+func IsRetryAfterLeadershipLost(ctx context.Context) bool {
+	retry, _ := ctx.Value(retryContextKey{}).(bool)
+	return retry
+}
+
+// isLeadershipLostError returns true if the given error was caused by the dqlite leader losing leadership while
+// running the interaction, in which case it isn't known whether a commit was applied.
+func isLeadershipLostError(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if strings.Contains(err.Error(), "leadership lost") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsLeadershipChangeError returns true if the given error was caused by the dqlite leader changing or being
+// unavailable, in which case the interaction can be retried once a new leader has been elected.
+func IsLeadershipChangeError(err error) bool {
+	if errors.Is(err, driver.ErrNoAvailableLeader) {
+		return true
+	}
+
+	// Unwrap errors one at a time.
+	for ; err != nil; err = errors.Unwrap(err) {
+		if strings.Contains(err.Error(), "not leader") {
+			return true
+		}
+
+		if strings.Contains(err.Error(), "leadership lost") {
+			return true
+		}
+	}
+
+	return false
+}
+
 // IsRetriableError returns true if the given error might be transient and the
 // interaction can be safely retried.
 func IsRetriableError(err error) bool {
@@ -0,0 +1,97 @@
+package query_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/canonical/go-dqlite/driver"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/canonical/lxd/lxd/db/query"
+)
+
+// Errors caused by a leadership change are detected, even when wrapped.
+func TestIsLeadershipChangeError(t *testing.T) {
+	assert.True(t, query.IsLeadershipChangeError(driver.ErrNoAvailableLeader))
+	assert.True(t, query.IsLeadershipChangeError(fmt.Errorf("Failed creating group: %w", fmt.Errorf("not leader"))))
+	assert.True(t, query.IsLeadershipChangeError(fmt.Errorf("leadership lost")))
+	assert.False(t, query.IsLeadershipChangeError(fmt.Errorf("UNIQUE constraint failed")))
+	assert.False(t, query.IsLeadershipChangeError(nil))
+}
+
+// Leadership change errors are retried until the function succeeds.
+func TestRetryBackoff_LeadershipChange(t *testing.T) {
+	attempts := 0
+	err := query.RetryBackoff(context.Background(), func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return fmt.Errorf("Failed to commit transaction: %w", driver.ErrNoAvailableLeader)
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+}
+
+// Other errors are returned straight away.
+func TestRetryBackoff_OtherError(t *testing.T) {
+	attempts := 0
+	err := query.RetryBackoff(context.Background(), func(ctx context.Context) error {
+		attempts++
+		return fmt.Errorf("UNIQUE constraint failed")
+	})
+
+	assert.EqualError(t, err, "UNIQUE constraint failed")
+	assert.Equal(t, 1, attempts)
+}
+
+// Errors already retried by Retry, like the database being locked, aren't retried again.
+func TestRetryBackoff_RetriableError(t *testing.T) {
+	attempts := 0
+	err := query.RetryBackoff(context.Background(), func(ctx context.Context) error {
+		attempts++
+		return fmt.Errorf("database is locked")
+	})
+
+	assert.EqualError(t, err, "database is locked")
+	assert.Equal(t, 1, attempts)
+}
+
+// Attempts following a loss of leadership are told that a previous attempt may have been applied.
+func TestRetryBackoff_LeadershipLost(t *testing.T) {
+	retries := []bool{}
+	err := query.RetryBackoff(context.Background(), func(ctx context.Context) error {
+		retries = append(retries, query.IsRetryAfterLeadershipLost(ctx))
+		switch len(retries) {
+		case 1:
+			return fmt.Errorf("not leader")
+		case 2:
+			return fmt.Errorf("Failed to commit transaction: %w", fmt.Errorf("leadership lost"))
+		case 3:
+			return driver.ErrNoAvailableLeader
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{false, false, true, true}, retries)
+}
+
+// Retries stop once the context is done, returning the last error.
+func TestRetryBackoff_ContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	attempts := 0
+	err := query.RetryBackoff(ctx, func(ctx context.Context) error {
+		attempts++
+		return fmt.Errorf("not leader")
+	})
+
+	assert.EqualError(t, err, "not leader")
+	assert.Equal(t, 1, attempts)
+}
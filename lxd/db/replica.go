@@ -0,0 +1,217 @@
+//go:build linux && cgo && !agent
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/canonical/go-dqlite/client"
+
+	"github.com/canonical/lxd/lxd/db/cluster"
+	"github.com/canonical/lxd/lxd/request"
+	"github.com/canonical/lxd/shared/logger"
+)
+
+// ErrReplicaTooStale is returned by Replica.Staleness if the replica is not known to be within the requested
+// staleness bound.
+var ErrReplicaTooStale = errors.New("Database replica is too stale")
+
+// Replica is a read-only copy of the global database taken from the dqlite node of this member. It's used to serve
+// reads that accept stale data without going through the leader.
+//
+// As dqlite doesn't expose the replication index of a node, the replication lag of the copy is measured with the
+// heartbeats that the leader records in the nodes table: since the raft log is applied in order, a copy containing a
+// heartbeat recorded at a given time contains all the changes committed before that time. This assumes that the
+// clocks of the cluster members are in sync, as the offline detection of cluster members already does.
+type Replica struct {
+	dir string // Directory holding the copies of the database.
+
+	mu          sync.RWMutex
+	cluster     *Cluster  // Read-only handle to the current copy, nil if there's none yet.
+	path        string    // Directory holding the current copy.
+	replicated  time.Time // Most recent heartbeat recorded by the leader found in the current copy.
+	refreshMu   sync.Mutex
+	refreshedAt time.Time
+}
+
+// NewReplica returns a Replica whose copies of the global database are stored in the given directory. Any copy left
+// over in the directory is removed.
+func NewReplica(dir string) (*Replica, error) {
+	err := os.RemoveAll(dir)
+	if err != nil {
+		return nil, fmt.Errorf("Failed removing database replica directory %q: %w", dir, err)
+	}
+
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, fmt.Errorf("Failed creating database replica directory %q: %w", dir, err)
+	}
+
+	return &Replica{dir: dir}, nil
+}
+
+// Staleness returns how stale the replica is at most. ErrReplicaTooStale is returned if that is more than the given
+// maximum staleness, or if the replica has no copy of the database yet.
+func (r *Replica) Staleness(maxStaleness time.Duration) (time.Duration, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.cluster == nil || r.replicated.IsZero() {
+		return 0, ErrReplicaTooStale
+	}
+
+	staleness := time.Since(r.replicated)
+	if staleness < 0 {
+		// The clock of the leader is ahead of ours.
+		staleness = 0
+	}
+
+	if staleness > maxStaleness {
+		return 0, ErrReplicaTooStale
+	}
+
+	return staleness, nil
+}
+
+// Refresh replaces the copy of the global database with the files returned by the given dump function, unless the
+// copy was refreshed less than the given interval ago or another refresh is in progress. The node ID is the ID of this
+// member in the global database.
+func (r *Replica) Refresh(interval time.Duration, nodeID int64, dump func() ([]client.File, error)) error {
+	if !r.refreshMu.TryLock() {
+		return nil
+	}
+
+	defer r.refreshMu.Unlock()
+
+	if time.Since(r.refreshedAt) < interval {
+		return nil
+	}
+
+	r.refreshedAt = time.Now()
+
+	files, err := dump()
+	if err != nil {
+		return fmt.Errorf("Failed dumping global database: %w", err)
+	}
+
+	path, err := os.MkdirTemp(r.dir, "db-")
+	if err != nil {
+		return fmt.Errorf("Failed creating database replica directory: %w", err)
+	}
+
+	clusterDB, replicated, err := openReplica(path, files)
+	if err != nil {
+		_ = os.RemoveAll(path)
+		return err
+	}
+
+	clusterDB.NodeID(nodeID)
+
+	// Wait for the transactions on the previous copy to complete before removing it.
+	r.mu.Lock()
+	oldCluster := r.cluster
+	oldPath := r.path
+	r.cluster = clusterDB
+	r.path = path
+	r.replicated = replicated
+	r.mu.Unlock()
+
+	if oldCluster != nil {
+		err = oldCluster.db.Close()
+		if err != nil {
+			logger.Warn("Failed closing previous database replica", logger.Ctx{"path": oldPath, "err": err})
+		}
+
+		err = os.RemoveAll(oldPath)
+		if err != nil {
+			logger.Warn("Failed removing previous database replica", logger.Ctx{"path": oldPath, "err": err})
+		}
+	}
+
+	return nil
+}
+
+// openReplica writes the given database files into the given directory and opens them read-only. It returns the time
+// of the most recent heartbeat recorded by the leader in the copy.
+func openReplica(path string, files []client.File) (*Cluster, time.Time, error) {
+	for _, file := range files {
+		err := os.WriteFile(filepath.Join(path, file.Name), file.Data, 0600)
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("Failed writing database replica file %q: %w", file.Name, err)
+		}
+	}
+
+	sqldb, err := sql.Open("sqlite3", "file:"+filepath.Join(path, "db.bin")+"?mode=ro")
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("Failed opening database replica: %w", err)
+	}
+
+	replicated, err := replicaHeartbeat(sqldb)
+	if err != nil {
+		_ = sqldb.Close()
+		return nil, time.Time{}, err
+	}
+
+	return ForLocalInspection(sqldb), replicated, nil
+}
+
+// replicaHeartbeat returns the most recent heartbeat recorded in the nodes table of the given database. The maximum is
+// computed here rather than in SQL, as the heartbeats recorded by different leaders may not be in the same time zone.
+func replicaHeartbeat(sqldb *sql.DB) (time.Time, error) {
+	rows, err := sqldb.Query("SELECT heartbeat FROM nodes")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Failed loading heartbeats from database replica: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	var replicated time.Time
+	for rows.Next() {
+		var heartbeat time.Time
+		err := rows.Scan(&heartbeat)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("Failed loading heartbeats from database replica: %w", err)
+		}
+
+		if heartbeat.After(replicated) {
+			replicated = heartbeat
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Failed loading heartbeats from database replica: %w", err)
+	}
+
+	return replicated, nil
+}
+
+// transaction runs the given function in a read-only transaction on the current copy of the global database.
+func (r *Replica) transaction(ctx context.Context, f func(context.Context, *ClusterTx) error) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.cluster == nil {
+		return ErrReplicaTooStale
+	}
+
+	return r.cluster.transaction(ctx, func(ctx context.Context, tx *ClusterTx) error {
+		defer cluster.TrackReplicaTx(tx.tx)()
+
+		return f(ctx, tx)
+	})
+}
+
+// replicaFromContext returns the Replica that the cluster database transactions made with the given context should
+// use, or nil if they must use the global database.
+func replicaFromContext(ctx context.Context) *Replica {
+	replica, _ := ctx.Value(request.CtxDatabaseReplica).(*Replica)
+	return replica
+}
@@ -0,0 +1,95 @@
+//go:build linux && cgo && !agent
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/canonical/go-dqlite/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/lxd/lxd/request"
+)
+
+// newTestReplicaDump returns a dump function returning a database whose nodes table has the given heartbeats.
+func newTestReplicaDump(t *testing.T, heartbeats ...time.Time) func() ([]client.File, error) {
+	path := filepath.Join(t.TempDir(), "db.bin")
+	sqldb, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+
+	_, err = sqldb.Exec("CREATE TABLE nodes (id INTEGER PRIMARY KEY, name TEXT, heartbeat DATETIME)")
+	require.NoError(t, err)
+
+	for _, heartbeat := range heartbeats {
+		_, err = sqldb.Exec("INSERT INTO nodes (name, heartbeat) VALUES ('node', ?)", heartbeat)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, sqldb.Close())
+
+	return func() ([]client.File, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		return []client.File{{Name: "db.bin", Data: data}}, nil
+	}
+}
+
+func TestReplica(t *testing.T) {
+	replica, err := NewReplica(filepath.Join(t.TempDir(), "replica"))
+	require.NoError(t, err)
+
+	// There's no copy of the database before the first refresh.
+	_, err = replica.Staleness(time.Hour)
+	assert.ErrorIs(t, err, ErrReplicaTooStale)
+
+	// The staleness is measured from the most recent heartbeat.
+	now := time.Now().UTC()
+	err = replica.Refresh(0, 1, newTestReplicaDump(t, now.Add(-time.Hour), now.Add(-time.Minute)))
+	require.NoError(t, err)
+
+	staleness, err := replica.Staleness(time.Hour)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, staleness, time.Minute)
+	assert.Less(t, staleness, 2*time.Minute)
+
+	_, err = replica.Staleness(time.Second)
+	assert.ErrorIs(t, err, ErrReplicaTooStale)
+
+	// The copy isn't refreshed again within the given interval.
+	err = replica.Refresh(time.Hour, 1, newTestReplicaDump(t, now))
+	require.NoError(t, err)
+	_, err = replica.Staleness(time.Second)
+	assert.ErrorIs(t, err, ErrReplicaTooStale)
+
+	err = replica.Refresh(0, 1, newTestReplicaDump(t, now))
+	require.NoError(t, err)
+	_, err = replica.Staleness(time.Minute)
+	assert.NoError(t, err)
+
+	// Transactions made with a context carrying the replica read from the copy, which is read-only.
+	cluster := ForLocalInspection(nil)
+	ctx := context.WithValue(context.Background(), request.CtxDatabaseReplica, replica)
+	err = cluster.Transaction(ctx, func(ctx context.Context, tx *ClusterTx) error {
+		var count int
+		err := tx.Tx().QueryRowContext(ctx, "SELECT count(*) FROM nodes").Scan(&count)
+		if err != nil {
+			return err
+		}
+
+		assert.Equal(t, 1, count)
+		assert.Equal(t, int64(1), tx.GetNodeID())
+
+		_, err = tx.Tx().ExecContext(ctx, "DELETE FROM nodes")
+		return err
+	})
+	assert.ErrorContains(t, err, "readonly")
+}
@@ -214,6 +214,54 @@ func (c *ClusterTx) GetExpiredStorageVolumeSnapshots(ctx context.Context, member
 	return snapshots, nil
 }
 
+// GetStorageVolumeSnapshotsByVolumeID returns the snapshots of the storage volume with the given ID, ordered by
+// creation date.
+func (c *ClusterTx) GetStorageVolumeSnapshotsByVolumeID(ctx context.Context, volumeID int64) ([]StorageVolumeArgs, error) {
+	q := `
+	SELECT
+		storage_volumes_snapshots.id,
+		storage_volumes.name,
+		storage_volumes_snapshots.name,
+		storage_volumes_snapshots.creation_date,
+		storage_volumes_snapshots.expiry_date,
+		storage_pools.name,
+		projects.name,
+		IFNULL(storage_volumes.node_id, -1)
+	FROM storage_volumes_snapshots
+	JOIN storage_volumes ON storage_volumes_snapshots.storage_volume_id = storage_volumes.id
+	JOIN storage_pools ON storage_volumes.storage_pool_id = storage_pools.id
+	JOIN projects ON storage_volumes.project_id = projects.id
+	WHERE storage_volumes.id = ?
+	ORDER BY storage_volumes_snapshots.creation_date, storage_volumes_snapshots.id
+	`
+
+	var snapshots []StorageVolumeArgs
+
+	err := query.Scan(ctx, c.Tx(), q, func(scan func(dest ...any) error) error {
+		var snap StorageVolumeArgs
+		var snapName string
+		var volName string
+		var expiryTime sql.NullTime
+
+		err := scan(&snap.ID, &volName, &snapName, &snap.CreationDate, &expiryTime, &snap.PoolName, &snap.ProjectName, &snap.NodeID)
+		if err != nil {
+			return err
+		}
+
+		snap.Name = volName + shared.SnapshotDelimiter + snapName
+		snap.ExpiryDate = expiryTime.Time // Convert nulls to zero.
+		snap.Snapshot = true
+		snapshots = append(snapshots, snap)
+
+		return nil
+	}, volumeID)
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
 // Updates the expiry date of a storage volume snapshot.
 func storageVolumeSnapshotExpiryDateUpdate(tx *sql.Tx, volumeID int64, expiryDate time.Time) error {
 	stmt := "UPDATE storage_volumes_snapshots SET expiry_date=? WHERE id=?"
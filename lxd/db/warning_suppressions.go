@@ -0,0 +1,198 @@
+//go:build linux && cgo && !agent
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/canonical/lxd/lxd/db/cluster"
+	"github.com/canonical/lxd/lxd/db/query"
+	"github.com/canonical/lxd/lxd/db/warningtype"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
+)
+
+// WarningSuppression is a rule matching warnings on their type, entity and message. Warnings that match an active
+// rule when they are created are acknowledged and tagged as suppressed.
+type WarningSuppression struct {
+	ID int
+
+	// TypeCode is the type of the matching warnings. Warnings of any type match if it is nil.
+	TypeCode *warningtype.Type
+
+	// EntityType and EntityID are the entity of the matching warnings. Warnings of any entity match if EntityType
+	// is empty.
+	EntityType cluster.EntityType
+	EntityID   int
+
+	// MessagePattern is a regular expression matched against the message of the warnings. Warnings with any
+	// message match if it is empty.
+	MessagePattern string
+
+	Description string
+	CreatedAt   time.Time
+
+	// ExpiresAt is the time after which the rule no longer applies. The rule never expires if it is zero.
+	ExpiresAt time.Time
+}
+
+// Matches returns whether a warning with the given type, entity and message matches the rule.
+func (w WarningSuppression) Matches(typeCode warningtype.Type, entityType entity.Type, entityID int, message string) (bool, error) {
+	if w.TypeCode != nil && *w.TypeCode != typeCode {
+		return false, nil
+	}
+
+	if w.EntityType != "" && (w.EntityType != cluster.EntityType(entityType) || w.EntityID != entityID) {
+		return false, nil
+	}
+
+	if w.MessagePattern != "" {
+		match, err := regexp.MatchString(w.MessagePattern, message)
+		if err != nil {
+			return false, fmt.Errorf("Invalid message pattern of warning suppression `%d`: %w", w.ID, err)
+		}
+
+		if !match {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// Expired returns whether the rule has expired.
+func (w WarningSuppression) Expired() bool {
+	return !w.ExpiresAt.IsZero() && time.Now().After(w.ExpiresAt)
+}
+
+// CreateWarningSuppression adds a new warning suppression rule and returns its ID.
+func (c *ClusterTx) CreateWarningSuppression(ctx context.Context, suppression WarningSuppression) (int64, error) {
+	var typeCode any
+	if suppression.TypeCode != nil {
+		typeCode = *suppression.TypeCode
+	}
+
+	var expiresAt any
+	if !suppression.ExpiresAt.IsZero() {
+		expiresAt = suppression.ExpiresAt.UTC()
+	}
+
+	result, err := c.tx.ExecContext(ctx, `
+		INSERT INTO warnings_suppressions
+		(type_code, entity_type_code, entity_id, message_pattern, description, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, typeCode, suppression.EntityType, suppression.EntityID, suppression.MessagePattern, suppression.Description, time.Now().UTC(), expiresAt)
+	if err != nil {
+		return -1, fmt.Errorf("Failed to create warning suppression: %w", err)
+	}
+
+	suppressionID, err := result.LastInsertId()
+	if err != nil {
+		return -1, fmt.Errorf("Failed to fetch warning suppression ID: %w", err)
+	}
+
+	return suppressionID, nil
+}
+
+// GetWarningSuppressions returns all warning suppression rules, including the ones that have expired.
+func (c *ClusterTx) GetWarningSuppressions(ctx context.Context) ([]WarningSuppression, error) {
+	return c.getWarningSuppressions(ctx, "")
+}
+
+// GetWarningSuppression returns the warning suppression rule with the given ID.
+func (c *ClusterTx) GetWarningSuppression(ctx context.Context, suppressionID int) (*WarningSuppression, error) {
+	suppressions, err := c.getWarningSuppressions(ctx, "WHERE id = ?", suppressionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(suppressions) == 0 {
+		return nil, api.StatusErrorf(http.StatusNotFound, "Warning suppression not found")
+	}
+
+	return &suppressions[0], nil
+}
+
+// DeleteWarningSuppression deletes the warning suppression rule with the given ID. Warnings that were suppressed by
+// the rule are left as they are.
+func (c *ClusterTx) DeleteWarningSuppression(ctx context.Context, suppressionID int) error {
+	result, err := c.tx.ExecContext(ctx, "DELETE FROM warnings_suppressions WHERE id = ?", suppressionID)
+	if err != nil {
+		return fmt.Errorf("Failed to delete warning suppression: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("Failed to get affected rows to delete warning suppression: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return api.StatusErrorf(http.StatusNotFound, "Warning suppression not found")
+	}
+
+	return nil
+}
+
+// warningSuppressed returns whether a warning with the given type, entity and message matches an active warning
+// suppression rule.
+func (c *ClusterTx) warningSuppressed(ctx context.Context, typeCode warningtype.Type, entityType entity.Type, entityID int, message string) (bool, error) {
+	suppressions, err := c.getWarningSuppressions(ctx, "WHERE expires_at IS NULL OR expires_at > ?", time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+
+	for _, suppression := range suppressions {
+		match, err := suppression.Matches(typeCode, entityType, entityID, message)
+		if err != nil {
+			return false, err
+		}
+
+		if match {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// getWarningSuppressions returns the warning suppression rules matching the given WHERE clause.
+func (c *ClusterTx) getWarningSuppressions(ctx context.Context, where string, args ...any) ([]WarningSuppression, error) {
+	q := `
+		SELECT id, type_code, coalesce(entity_type_code, -1), coalesce(entity_id, -1), message_pattern, description, created_at, expires_at
+		FROM warnings_suppressions
+		` + where + `
+		ORDER BY id
+		`
+
+	var suppressions []WarningSuppression
+	err := query.Scan(ctx, c.tx, q, func(scan func(dest ...any) error) error {
+		suppression := WarningSuppression{}
+		var typeCode sql.NullInt64
+		var expiresAt sql.NullTime
+
+		err := scan(&suppression.ID, &typeCode, &suppression.EntityType, &suppression.EntityID, &suppression.MessagePattern, &suppression.Description, &suppression.CreatedAt, &expiresAt)
+		if err != nil {
+			return err
+		}
+
+		if typeCode.Valid {
+			code := warningtype.Type(typeCode.Int64)
+			suppression.TypeCode = &code
+		}
+
+		suppression.ExpiresAt = expiresAt.Time // Convert nulls to zero.
+		suppressions = append(suppressions, suppression)
+
+		return nil
+	}, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed loading warning suppressions: %w", err)
+	}
+
+	return suppressions, nil
+}
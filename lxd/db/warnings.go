@@ -18,8 +18,8 @@ import (
 )
 
 var warningCreate = cluster.RegisterStmt(`
-INSERT INTO warnings (node_id, project_id, entity_type_code, entity_id, uuid, type_code, status, first_seen_date, last_seen_date, updated_date, last_message, count)
-  VALUES ((SELECT nodes.id FROM nodes WHERE nodes.name = ?), (SELECT projects.id FROM projects WHERE projects.name = ?), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+INSERT INTO warnings (node_id, project_id, entity_type_code, entity_id, uuid, type_code, status, first_seen_date, last_seen_date, updated_date, last_message, count, suppressed)
+  VALUES ((SELECT nodes.id FROM nodes WHERE nodes.name = ?), (SELECT projects.id FROM projects WHERE projects.name = ?), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 `)
 
 // UpsertWarningLocalNode creates or updates a warning for the local member. Returns error if no local member name.
@@ -73,14 +73,29 @@ func (c *ClusterTx) UpsertWarning(ctx context.Context, nodeName string, projectN
 	} else if len(warnings) == 1 {
 		// If there is a historical warning that was previously automatically resolved and the same
 		// warning has now reoccurred then set the status back to warningtype.StatusNew so it shows as
-		// a current active warning.
+		// a current active warning. Suppressed warnings go back to being acknowledged instead.
 		newStatus := warnings[0].Status
 		if newStatus == warningtype.StatusResolved {
 			newStatus = warningtype.StatusNew
+			if warnings[0].Suppressed {
+				newStatus = warningtype.StatusAcknowledged
+			}
 		}
 
 		err = c.UpdateWarningState(warnings[0].UUID, message, newStatus)
 	} else {
+		// Warnings matching a suppression rule are acknowledged as they are created.
+		var suppressed bool
+		suppressed, err = c.warningSuppressed(ctx, typeCode, entityType, entityID, message)
+		if err != nil {
+			return err
+		}
+
+		status := warningtype.StatusNew
+		if suppressed {
+			status = warningtype.StatusAcknowledged
+		}
+
 		warning := cluster.Warning{
 			Node:          nodeName,
 			Project:       projectName,
@@ -88,12 +103,13 @@ func (c *ClusterTx) UpsertWarning(ctx context.Context, nodeName string, projectN
 			EntityID:      entityID,
 			UUID:          uuid.New().String(),
 			TypeCode:      typeCode,
-			Status:        warningtype.StatusNew,
+			Status:        status,
 			FirstSeenDate: now,
 			LastSeenDate:  now,
 			UpdatedDate:   time.Time{}.UTC(),
 			LastMessage:   message,
 			Count:         1,
+			Suppressed:    suppressed,
 		}
 
 		_, err = c.createWarning(ctx, warning)
@@ -160,7 +176,7 @@ func (c *ClusterTx) createWarning(ctx context.Context, object cluster.Warning) (
 		return -1, fmt.Errorf("This warning already exists")
 	}
 
-	args := make([]any, 12)
+	args := make([]any, 13)
 
 	// Populate the statement arguments.
 	if object.Node != "" {
@@ -203,6 +219,7 @@ func (c *ClusterTx) createWarning(ctx context.Context, object cluster.Warning) (
 	args[9] = object.UpdatedDate
 	args[10] = object.LastMessage
 	args[11] = object.Count
+	args[12] = object.Suppressed
 
 	// Prepared statement to use.
 	stmt, err := cluster.Stmt(c.tx, warningCreate)
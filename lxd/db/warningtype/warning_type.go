@@ -58,6 +58,30 @@ const (
 	StoragePoolUnvailable
 	// UnableToUpdateClusterCertificate represents the unable to update cluster certificate warning.
 	UnableToUpdateClusterCertificate
+	// InstanceScheduledSnapshotFailure represents repeated failures of the scheduled snapshots of an instance.
+	InstanceScheduledSnapshotFailure
+	// AuthGroupBroadAdminAccess represents a group granting administrative access on the server to many identities.
+	AuthGroupBroadAdminAccess
+	// IdentityCacheRefreshFailed represents the failure to notify other cluster members to refresh their identity cache.
+	IdentityCacheRefreshFailed
+	// IdentitiesWithoutGroups represents identities that are not a member of any group while group membership is required.
+	IdentitiesWithoutGroups
+	// FilesystemFreezeTimeout represents a file system frozen by LXD that was thawed after the freeze timeout.
+	FilesystemFreezeTimeout
+	// InstanceScheduledPowerFailure represents the failure to start or stop an instance on its boot schedule.
+	InstanceScheduledPowerFailure
+	// InstanceFreezeTimeout represents an instance that was automatically unfrozen after its freeze timeout.
+	InstanceFreezeTimeout
+	// InstancePoolReplenishFailure represents the failure to create the instances of an instance pool.
+	InstancePoolReplenishFailure
+	// PinnedImageAliasOutdated represents a pinned image alias whose image has been updated at its source.
+	PinnedImageAliasOutdated
+	// AuthGroupOutsideConfinedProjects represents a group granting permissions outside of the projects of the
+	// restricted identities that are a member of it.
+	AuthGroupOutsideConfinedProjects
+	// ExternalDHCPRegistrationFailure represents the failure to register an instance NIC with the external DHCP
+	// system of its network.
+	ExternalDHCPRegistrationFailure
 )
 
 // TypeNames associates a warning code to its name.
@@ -88,6 +112,17 @@ var TypeNames = map[Type]string{
 	InstanceTypeNotOperational:             "Instance type not operational",
 	StoragePoolUnvailable:                  "Storage pool unavailable",
 	UnableToUpdateClusterCertificate:       "Unable to update cluster certificate",
+	InstanceScheduledSnapshotFailure:       "Failed to create scheduled instance snapshots",
+	AuthGroupBroadAdminAccess:              "Authorization group grants administrative access broadly",
+	IdentityCacheRefreshFailed:             "Failed to refresh the identity cache of cluster members",
+	IdentitiesWithoutGroups:                "Identities are not a member of any group",
+	FilesystemFreezeTimeout:                "Frozen file system thawed after timeout",
+	InstanceScheduledPowerFailure:          "Failed to start or stop instance on schedule",
+	InstanceFreezeTimeout:                  "Frozen instance unfrozen after timeout",
+	InstancePoolReplenishFailure:           "Failed to replenish instance pool",
+	PinnedImageAliasOutdated:               "Pinned image alias has an update available",
+	AuthGroupOutsideConfinedProjects:       "Authorization group grants permissions outside of the projects of restricted identities",
+	ExternalDHCPRegistrationFailure:        "Failed to register instance with external DHCP",
 }
 
 // Severity returns the severity of the warning type.
@@ -145,6 +180,28 @@ func (t Type) Severity() Severity {
 		return SeverityHigh
 	case UnableToUpdateClusterCertificate:
 		return SeverityLow
+	case InstanceScheduledSnapshotFailure:
+		return SeverityLow
+	case AuthGroupBroadAdminAccess:
+		return SeverityModerate
+	case IdentityCacheRefreshFailed:
+		return SeverityModerate
+	case IdentitiesWithoutGroups:
+		return SeverityLow
+	case FilesystemFreezeTimeout:
+		return SeverityModerate
+	case InstanceScheduledPowerFailure:
+		return SeverityLow
+	case InstanceFreezeTimeout:
+		return SeverityModerate
+	case InstancePoolReplenishFailure:
+		return SeverityModerate
+	case PinnedImageAliasOutdated:
+		return SeverityLow
+	case AuthGroupOutsideConfinedProjects:
+		return SeverityModerate
+	case ExternalDHCPRegistrationFailure:
+		return SeverityModerate
 	}
 
 	return SeverityLow
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"net/http"
 	"sort"
 	"strings"
 
@@ -23,20 +24,30 @@ func (device Device) Clone() Device {
 }
 
 // Validate accepts a map of field/validation functions to run against the device's config.
+// All invalid values and unknown fields are reported together in a single bad request error, with suggestions of
+// the closest valid field names for unknown fields.
 func (device Device) Validate(rules map[string]func(value string) error) error {
-	checkedFields := map[string]struct{}{}
+	var problems []string
 
-	for k, validator := range rules {
-		checkedFields[k] = struct{}{} // Mark field as checked.
-		err := validator(device[k])
+	// Run the validators in a stable order so the reported problems are too.
+	fields := make([]string, 0, len(rules))
+	for k := range rules {
+		fields = append(fields, k)
+	}
+
+	sort.Strings(fields)
+
+	for _, k := range fields {
+		err := rules[k](device[k])
 		if err != nil {
-			return fmt.Errorf("Invalid value for device option %q: %w", k, err)
+			problems = append(problems, fmt.Sprintf("Invalid value for device option %q: %v", k, err))
 		}
 	}
 
 	// Look for any unchecked fields, as these are unknown fields and validation should fail.
+	unknownFields := make([]string, 0)
 	for k := range device {
-		_, checked := checkedFields[k]
+		_, checked := rules[k]
 		if checked {
 			continue
 		}
@@ -64,7 +75,23 @@ func (device Device) Validate(rules map[string]func(value string) error) error {
 			continue
 		}
 
-		return fmt.Errorf("Invalid device option %q", k)
+		unknownFields = append(unknownFields, k)
+	}
+
+	sort.Strings(unknownFields)
+
+	for _, k := range unknownFields {
+		suggestions := closestFields(k, fields)
+		if len(suggestions) == 0 {
+			problems = append(problems, fmt.Sprintf("Invalid device option %q", k))
+			continue
+		}
+
+		problems = append(problems, fmt.Sprintf("Invalid device option %q (did you mean %s?)", k, quotedList(suggestions)))
+	}
+
+	if len(problems) > 0 {
+		return api.StatusErrorf(http.StatusBadRequest, "%s", strings.Join(problems, "; "))
 	}
 
 	return nil
@@ -1,9 +1,13 @@
 package config
 
 import (
+	"fmt"
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/canonical/lxd/shared/api"
 )
 
 func TestSortableDevices(t *testing.T) {
@@ -49,3 +53,36 @@ func TestSortableDevices(t *testing.T) {
 	result = devices.Reversed()
 	assert.Equal(t, expectedReversed, result)
 }
+
+func TestDeviceValidate(t *testing.T) {
+	rules := map[string]func(value string) error{
+		"ipv4.address": func(value string) error { return nil },
+		"ipv6.address": func(value string) error { return nil },
+		"mtu": func(value string) error {
+			if value == "bad" {
+				return fmt.Errorf("Invalid MTU")
+			}
+
+			return nil
+		},
+	}
+
+	// Known, user and type fields are accepted.
+	err := Device{"type": "nic", "nictype": "bridged", "ipv4.address": "10.0.0.2", "user.foo": "bar"}.Validate(rules)
+	assert.NoError(t, err)
+
+	// Unknown fields are reported with the closest valid field names.
+	err = Device{"type": "nic", "ipv4.addres": "10.0.0.2"}.Validate(rules)
+	assert.EqualError(t, err, `Invalid device option "ipv4.addres" (did you mean "ipv4.address"?)`)
+	assert.True(t, api.StatusErrorCheck(err, http.StatusBadRequest))
+
+	err = Device{"type": "nic", "ipvX.address": "10.0.0.2"}.Validate(rules)
+	assert.EqualError(t, err, `Invalid device option "ipvX.address" (did you mean "ipv4.address" or "ipv6.address"?)`)
+
+	err = Device{"type": "nic", "foo": "bar"}.Validate(rules)
+	assert.EqualError(t, err, `Invalid device option "foo"`)
+
+	// All problems are reported together.
+	err = Device{"type": "nic", "mtu": "bad", "ipv4.addres": "10.0.0.2", "mut": "1500"}.Validate(rules)
+	assert.EqualError(t, err, `Invalid value for device option "mtu": Invalid MTU; Invalid device option "ipv4.addres" (did you mean "ipv4.address"?); Invalid device option "mut" (did you mean "mtu"?)`)
+}
@@ -1,5 +1,10 @@
 package config
 
+import (
+	"fmt"
+	"strings"
+)
+
 // deviceEquals checks for any difference and addition/removal of properties.
 func deviceEquals(old Device, d Device) bool {
 	for k := range d {
@@ -35,3 +40,70 @@ func deviceEqualsDiffKeys(old Device, d Device) []string {
 
 	return keys
 }
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
+
+// closestFields returns the fields closest to the unknown field by edit distance, if close enough to be a likely
+// typo. The fields must be sorted, and so are the returned ones.
+func closestFields(unknown string, fields []string) []string {
+	// Allow roughly one edit for every three characters, with a minimum of two.
+	maxDistance := max(2, len(unknown)/3)
+
+	var closest []string
+	bestDistance := maxDistance
+
+	for _, field := range fields {
+		distance := editDistance(unknown, field)
+		if distance > maxDistance {
+			continue
+		}
+
+		if distance < bestDistance {
+			bestDistance = distance
+			closest = []string{field}
+		} else if distance == bestDistance {
+			closest = append(closest, field)
+		}
+	}
+
+	return closest
+}
+
+// quotedList returns the values quoted and joined as a human readable list of alternatives.
+func quotedList(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		quoted = append(quoted, fmt.Sprintf("%q", value))
+	}
+
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " or " + quoted[len(quoted)-1]
+}
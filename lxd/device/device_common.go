@@ -3,12 +3,14 @@ package device
 import (
 	"fmt"
 	"net"
+	"strings"
 
 	deviceConfig "github.com/canonical/lxd/lxd/device/config"
 	"github.com/canonical/lxd/lxd/instance"
 	"github.com/canonical/lxd/lxd/instance/instancetype"
 	"github.com/canonical/lxd/lxd/network"
 	"github.com/canonical/lxd/lxd/state"
+	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/logger"
 )
 
@@ -101,6 +103,11 @@ func (d *deviceCommon) Remove() error {
 // Accepts optional hwaddr MAC address to use for generating the interface name in mac mode.
 // In mac mode the interface prefix is always "lxd".
 func (d *deviceCommon) generateHostName(prefix string, hwaddr string) (string, error) {
+	// Handle host_name.persistent, which takes precedence over instances.nic.host_name.
+	if shared.IsTrue(d.config["host_name.persistent"]) {
+		return d.persistentHostName(prefix)
+	}
+
 	hostNameMode := d.state.GlobalConfig.InstancesNICHostname()
 
 	// Handle instances.nic.host_name mac mode if a MAC address has been supplied.
@@ -116,3 +123,55 @@ func (d *deviceCommon) generateHostName(prefix string, hwaddr string) (string, e
 	// Handle instances.nic.host_name random mode or where no MAC address supplied.
 	return network.RandomDevName(prefix), nil
 }
+
+// persistentHostName returns the stable name to use for the host side NIC interface when host_name.persistent is
+// enabled. The name is derived from the project, instance and device names and recorded in the device's volatile
+// config, so that it is kept across restarts and when moving the instance to another cluster member. A new name is
+// only derived if the recorded name is already used by another interface or NIC on this cluster member.
+func (d *deviceCommon) persistentHostName(prefix string) (string, error) {
+	hostNames, err := nicHostNamesInUse(d.state, d.inst, d.name)
+	if err != nil {
+		return "", err
+	}
+
+	inUse := func(hostName string) bool {
+		_, ok := hostNames[hostName]
+		return ok || network.InterfaceExists(hostName)
+	}
+
+	recorded := d.volatileGet()["persistent_host_name"]
+	if recorded != "" && strings.HasPrefix(recorded, prefix) && !inUse(recorded) {
+		return recorded, nil
+	}
+
+	seed := fmt.Sprintf("%s/%s/%s", d.inst.Project().Name, d.inst.Name(), d.name)
+	for i := 0; i < 100; i++ {
+		// Add the attempt number to the seed on collisions to derive a different name.
+		attemptSeed := seed
+		if i > 0 {
+			attemptSeed = fmt.Sprintf("%s/%d", seed, i)
+		}
+
+		hostName := network.StableDevName(prefix, attemptSeed)
+		if hostName == "" {
+			return "", fmt.Errorf("Failed generating a persistent host name with prefix %q", prefix)
+		}
+
+		if inUse(hostName) {
+			continue
+		}
+
+		if recorded != "" && recorded != hostName {
+			d.logger.Warn("Regenerated persistent host name", logger.Ctx{"old": recorded, "new": hostName})
+		}
+
+		err = d.volatileSet(map[string]string{"persistent_host_name": hostName})
+		if err != nil {
+			return "", err
+		}
+
+		return hostName, nil
+	}
+
+	return "", fmt.Errorf("Failed generating a persistent host name with prefix %q: All candidate names are in use", prefix)
+}
@@ -15,6 +15,7 @@ import (
 
 	"github.com/canonical/lxd/lxd/idmap"
 	"github.com/canonical/lxd/lxd/instance"
+	"github.com/canonical/lxd/lxd/resources"
 	storageDrivers "github.com/canonical/lxd/lxd/storage/drivers"
 	"github.com/canonical/lxd/lxd/storage/filesystem"
 	"github.com/canonical/lxd/lxd/subprocess"
@@ -421,10 +422,13 @@ func DiskVMVirtfsProxyStop(pidPath string) error {
 
 // DiskVMVirtiofsdStart starts a new virtiofsd process.
 // If the idmaps slice is supplied then the proxy process is run inside a user namespace using the supplied maps.
+// If translateOwner is true then virtiofsd translates the UIDs and GIDs of the guest so that files created by the
+// guest are owned by the owner of the share path on the host, and files owned by that owner appear as owned by root
+// in the guest.
 // Returns UnsupportedError error if the host system or instance does not support virtiosfd, returns normal error
 // type if process cannot be started for other reasons.
 // Returns revert function and listener file handle on success.
-func DiskVMVirtiofsdStart(execPath string, inst instance.Instance, socketPath string, pidPath string, logPath string, sharePath string, idmaps []idmap.IdmapEntry) (func(), net.Listener, error) {
+func DiskVMVirtiofsdStart(execPath string, inst instance.Instance, socketPath string, pidPath string, logPath string, sharePath string, idmaps []idmap.IdmapEntry, translateOwner bool) (func(), net.Listener, error) {
 	revert := revert.New()
 	defer revert.Fail()
 
@@ -436,17 +440,7 @@ func DiskVMVirtiofsdStart(execPath string, inst instance.Instance, socketPath st
 	_ = os.Remove(socketPath)
 
 	// Locate virtiofsd.
-	cmd, err := exec.LookPath("virtiofsd")
-	if err != nil {
-		if shared.PathExists("/usr/lib/qemu/virtiofsd") {
-			cmd = "/usr/lib/qemu/virtiofsd"
-		} else if shared.PathExists("/usr/libexec/virtiofsd") {
-			cmd = "/usr/libexec/virtiofsd"
-		} else if shared.PathExists("/usr/lib/virtiofsd") {
-			cmd = "/usr/lib/virtiofsd"
-		}
-	}
-
+	cmd := resources.GetVirtiofsdPath()
 	if cmd == "" {
 		return nil, nil, ErrMissingVirtiofsd
 	}
@@ -465,6 +459,30 @@ func DiskVMVirtiofsdStart(execPath string, inst instance.Instance, socketPath st
 		return nil, nil, UnsupportedError{"SEV unsupported"}
 	}
 
+	var translateArgs []string
+	if translateOwner {
+		// The IDs of the share owner are only known on the host, so translation can't be combined with running
+		// virtiofsd inside a user namespace.
+		if len(idmaps) > 0 || !resources.VirtiofsdSupportsIdmap(cmd) {
+			return nil, nil, ErrVirtiofsdIdmapUnsupported
+		}
+
+		fInfo, err := os.Stat(sharePath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed getting owner of share path %q: %w", sharePath, err)
+		}
+
+		_, uid, gid := shared.GetOwnerMode(fInfo)
+
+		// Squash all guest IDs to the share owner and present the share owner as root in the guest.
+		translateArgs = []string{
+			fmt.Sprintf("--translate-uid=squash-guest:0:%d:4294967295", uid),
+			fmt.Sprintf("--translate-uid=host:%d:0:1", uid),
+			fmt.Sprintf("--translate-gid=squash-guest:0:%d:4294967295", gid),
+			fmt.Sprintf("--translate-gid=host:%d:0:1", gid),
+		}
+	}
+
 	// Trickery to handle paths > 107 chars.
 	socketFileDir, err := os.Open(filepath.Dir(socketPath))
 	if err != nil {
@@ -499,6 +517,7 @@ func DiskVMVirtiofsdStart(execPath string, inst instance.Instance, socketPath st
 
 	// Start the virtiofsd process in non-daemon mode.
 	args := []string{"--fd=3", "-o", fmt.Sprintf("source=%s", sharePath)}
+	args = append(args, translateArgs...)
 	proc, err := subprocess.NewProcess(cmd, args, logPath, logPath)
 	if err != nil {
 		return nil, nil, err
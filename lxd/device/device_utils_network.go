@@ -21,8 +21,10 @@ import (
 	"github.com/canonical/lxd/lxd/instance/instancetype"
 	"github.com/canonical/lxd/lxd/ip"
 	"github.com/canonical/lxd/lxd/network"
+	"github.com/canonical/lxd/lxd/resources"
 	"github.com/canonical/lxd/lxd/state"
 	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/revert"
 	"github.com/canonical/lxd/shared/units"
@@ -209,8 +211,9 @@ func networkRestorePhysicalNIC(hostName string, volatile map[string]string) erro
 // networkCreateVethPair creates and configures a veth pair. It will set the hwaddr and mtu settings
 // in the supplied config to the newly created peer interface. If mtu is not specified, but parent
 // is supplied in config, then the MTU of the new peer interface will inherit the parent MTU.
-// Accepts the name of the host side interface as a parameter and returns the peer interface name and MTU used.
-func networkCreateVethPair(hostName string, m deviceConfig.Device) (string, uint32, error) {
+// Accepts the name of the host side interface and the number of queues of both ends (0 for the kernel default) as
+// parameters and returns the peer interface name and MTU used.
+func networkCreateVethPair(hostName string, m deviceConfig.Device, queues uint32) (string, uint32, error) {
 	var err error
 
 	veth := &ip.Veth{
@@ -290,6 +293,14 @@ func networkCreateVethPair(hostName string, m deviceConfig.Device) (string, uint
 
 	veth.Peer.TXQueueLength = veth.TXQueueLength
 
+	// Set the number of queues on both ends.
+	if queues > 0 {
+		veth.NumTXQueues = queues
+		veth.NumRXQueues = queues
+		veth.Peer.NumTXQueues = queues
+		veth.Peer.NumRXQueues = queues
+	}
+
 	// Add and configure the interface in one operation to reduce the number of executions and to avoid
 	// systemd-udevd from applying the default MACAddressPolicy=persistent policy.
 	err = veth.Add()
@@ -1149,3 +1160,36 @@ func networkVLANListExpand(rawVLANValues []string) ([]int, error) {
 
 	return networkVLANList, nil
 }
+
+// networkHostInterfaceState returns the state of a NIC from its host side interface, for use when the instance can't
+// report it. As the state is reported from the instance's point of view, the host side counters are reversed.
+func networkHostInterfaceState(hostName string, hwaddr string, addresses []api.InstanceStateNetworkAddress) (*api.InstanceStateNetwork, error) {
+	iface, err := net.InterfaceByName(hostName)
+	if err != nil {
+		return nil, fmt.Errorf("Failed getting host interface %q: %w", hostName, err)
+	}
+
+	hostCounters, err := resources.GetNetworkCounters(hostName)
+	if err != nil {
+		return nil, fmt.Errorf("Failed getting network interface counters: %w", err)
+	}
+
+	if addresses == nil {
+		addresses = []api.InstanceStateNetworkAddress{}
+	}
+
+	return &api.InstanceStateNetwork{
+		Addresses: addresses,
+		Counters: api.InstanceStateNetworkCounters{
+			BytesReceived:   hostCounters.BytesSent,
+			BytesSent:       hostCounters.BytesReceived,
+			PacketsReceived: hostCounters.PacketsSent,
+			PacketsSent:     hostCounters.PacketsReceived,
+		},
+		Hwaddr:   hwaddr,
+		HostName: hostName,
+		Mtu:      iface.MTU,
+		State:    "up",
+		Type:     "broadcast",
+	}, nil
+}
@@ -39,6 +39,24 @@ import (
 // Special disk "source" value used for generating a VM cloud-init config ISO.
 const diskSourceCloudInit = "cloud-init:config"
 
+// Transports used by disk devices sharing a directory with a VM.
+const (
+	diskShareTransportVirtiofs = "virtiofs"
+	diskShareTransport9p       = "9p"
+)
+
+// Ownership mappings used by disk devices sharing a directory with a VM.
+const (
+	// diskShareIdmapNone indicates that the ownership of shared files isn't mapped.
+	diskShareIdmapNone = "none"
+
+	// diskShareIdmapTranslate indicates that virtiofsd translates the ownership of shared files.
+	diskShareIdmapTranslate = "translate"
+
+	// diskShareIdmapUserns indicates that the share is served from within a user namespace using raw.idmap.
+	diskShareIdmapUserns = "userns"
+)
+
 // DiskVirtiofsdSockMountOpt indicates the mount option prefix used to provide the virtiofsd socket path to
 // the QEMU driver.
 const DiskVirtiofsdSockMountOpt = "virtiofsdSock"
@@ -197,6 +215,8 @@ func (d *disk) validateConfig(instConf instance.ConfigReader) error {
 		"path":              validate.IsAny,
 		"io.cache":          validate.Optional(validate.IsOneOf("none", "writeback", "unsafe")),
 		"io.bus":            validate.Optional(validate.IsOneOf("virtio-scsi", "nvme")),
+		"idmap.translate":   validate.Optional(validate.IsBool),
+		"idmap.required":    validate.Optional(validate.IsBool),
 	}
 
 	err := d.config.Validate(rules)
@@ -212,6 +232,14 @@ func (d *disk) validateConfig(instConf instance.ConfigReader) error {
 		return fmt.Errorf("IO cache configuration cannot be applied to containers")
 	}
 
+	if instConf.Type() == instancetype.Container && (d.config["idmap.translate"] != "" || d.config["idmap.required"] != "") {
+		return fmt.Errorf("Ownership translation configuration cannot be applied to containers (use shift instead)")
+	}
+
+	if shared.IsTrue(d.config["idmap.required"]) && !shared.IsTrue(d.config["idmap.translate"]) {
+		return fmt.Errorf(`The "idmap.required" property requires "idmap.translate" to be enabled`)
+	}
+
 	if d.config["required"] != "" && d.config["optional"] != "" {
 		return fmt.Errorf(`Cannot use both "required" and deprecated "optional" properties at the same time`)
 	}
@@ -950,15 +978,25 @@ func (d *disk) startVM() (*deviceConfig.RunConfig, error) {
 
 				// Start virtiofsd for virtio-fs share. The lxd-agent prefers to use this over the
 				// virtfs-proxy-helper 9p share. The 9p share will only be used as a fallback.
+				translateOwner := shared.IsTrue(d.config["idmap.translate"])
+				idmapRequired := shared.IsTrue(d.config["idmap.required"])
+				virtiofsStarted := false
 				err = func() error {
 					sockPath, pidPath := d.vmVirtiofsdPaths()
 					logPath := filepath.Join(d.inst.LogPath(), fmt.Sprintf("disk.%s.log", d.name))
 					_ = os.Remove(logPath) // Remove old log if needed.
 
-					revertFunc, unixListener, err := DiskVMVirtiofsdStart(d.state.OS.ExecPath, d.inst, sockPath, pidPath, logPath, mount.DevPath, rawIDMaps)
+					revertFunc, unixListener, err := DiskVMVirtiofsdStart(d.state.OS.ExecPath, d.inst, sockPath, pidPath, logPath, mount.DevPath, rawIDMaps, translateOwner)
+					if errors.Is(err, ErrVirtiofsdIdmapUnsupported) && !idmapRequired {
+						d.logger.Warn("Unable to translate ownership for virtio-fs share, sharing without translation", logger.Ctx{"err": err})
+
+						translateOwner = false
+						revertFunc, unixListener, err = DiskVMVirtiofsdStart(d.state.OS.ExecPath, d.inst, sockPath, pidPath, logPath, mount.DevPath, rawIDMaps, translateOwner)
+					}
+
 					if err != nil {
 						var errUnsupported UnsupportedError
-						if errors.As(err, &errUnsupported) {
+						if errors.As(err, &errUnsupported) && !idmapRequired {
 							d.logger.Warn("Unable to use virtio-fs for device, using 9p as a fallback", logger.Ctx{"err": errUnsupported})
 
 							if errUnsupported == ErrMissingVirtiofsd {
@@ -977,6 +1015,7 @@ func (d *disk) startVM() (*deviceConfig.RunConfig, error) {
 					}
 
 					revert.Add(revertFunc)
+					virtiofsStarted = true
 
 					// Request the unix listener is closed after QEMU has connected on startup.
 					runConf.PostHooks = append(runConf.PostHooks, unixListener.Close)
@@ -999,6 +1038,26 @@ func (d *disk) startVM() (*deviceConfig.RunConfig, error) {
 					return nil, fmt.Errorf("Failed to setup virtiofsd for device %q: %w", d.name, err)
 				}
 
+				// Record the transport and ownership mapping that the share is actually using.
+				shareTransport := ""
+				shareIdmap := diskShareIdmapNone
+				if virtiofsStarted {
+					shareTransport = diskShareTransportVirtiofs
+				} else if !d.inst.IsRunning() {
+					shareTransport = diskShareTransport9p
+				}
+
+				if translateOwner && virtiofsStarted {
+					shareIdmap = diskShareIdmapTranslate
+				} else if len(rawIDMaps) > 0 {
+					shareIdmap = diskShareIdmapUserns
+				}
+
+				err = d.volatileSet(map[string]string{"share.transport": shareTransport, "share.idmap": shareIdmap})
+				if err != nil {
+					return nil, err
+				}
+
 				// We can't hotplug 9p shares, so only do 9p for stopped instances.
 				if !d.inst.IsRunning() {
 					// Start virtfs-proxy-helper for 9p share (this will rewrite mount.DevPath with
@@ -1791,6 +1850,14 @@ func (d *disk) stopVM() (*deviceConfig.RunConfig, error) {
 		return &deviceConfig.RunConfig{}, fmt.Errorf("Failed cleaning up virtiofsd: %w", err)
 	}
 
+	// Clear the share state.
+	if d.volatileGet()["share.transport"] != "" {
+		err = d.volatileSet(map[string]string{"share.transport": "", "share.idmap": ""})
+		if err != nil {
+			return &deviceConfig.RunConfig{}, err
+		}
+	}
+
 	runConf := deviceConfig.RunConfig{
 		PostHooks: []func() error{d.postStop},
 	}
@@ -2221,10 +2288,10 @@ func (d *disk) generateVMConfigDrive() (string, error) {
 		return "", err
 	}
 
-	// Include a network-config file if the user configured it.
-	networkConfig, ok := instanceConfig["cloud-init.network-config"]
-	if !ok {
-		networkConfig = instanceConfig["user.network-config"]
+	// Include a network-config file if the user configured it or asked for it to be generated.
+	networkConfig, err := CloudInitNetworkConfig(d.state, d.inst)
+	if err != nil {
+		return "", err
 	}
 
 	if networkConfig != "" {
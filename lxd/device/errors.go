@@ -21,3 +21,6 @@ var ErrCannotUpdate = fmt.Errorf("Device does not support updates")
 
 // ErrMissingVirtiofsd is the error that occurs if virtiofsd is missing.
 var ErrMissingVirtiofsd = UnsupportedError{msg: "Virtiofsd missing"}
+
+// ErrVirtiofsdIdmapUnsupported is the error that occurs if virtiofsd cannot translate the ownership of shared files.
+var ErrVirtiofsdIdmapUnsupported = UnsupportedError{msg: "Virtiofsd ownership translation unsupported"}
@@ -1,15 +1,29 @@
 package device
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"runtime"
+	"strconv"
 	"strings"
 
+	"github.com/canonical/lxd/lxd/db"
+	"github.com/canonical/lxd/lxd/db/cluster"
+	deviceConfig "github.com/canonical/lxd/lxd/device/config"
 	"github.com/canonical/lxd/lxd/instance"
+	"github.com/canonical/lxd/lxd/instance/instancetype"
 	"github.com/canonical/lxd/lxd/network/acl"
+	"github.com/canonical/lxd/lxd/resources"
+	"github.com/canonical/lxd/lxd/state"
 	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/validate"
 )
 
+// nicMaxQueues is the maximum number of queues of a NIC, which is limited by the number of queues of a TAP device.
+const nicMaxQueues = 256
+
 // nicValidationRules returns config validation rules for nic devices.
 func nicValidationRules(requiredFields []string, optionalFields []string, instConf instance.ConfigReader) map[string]func(value string) error {
 	// Define a set of default validators for each field name.
@@ -23,6 +37,7 @@ func nicValidationRules(requiredFields []string, optionalFields []string, instCo
 		"gvrp":                                 validate.Optional(validate.IsBool),
 		"hwaddr":                               validate.IsNetworkMAC,
 		"host_name":                            validate.IsAny,
+		"host_name.persistent":                 validate.Optional(validate.IsBool),
 		"limits.ingress":                       validate.IsAny,
 		"limits.egress":                        validate.IsAny,
 		"limits.max":                           validate.IsAny,
@@ -31,6 +46,7 @@ func nicValidationRules(requiredFields []string, optionalFields []string, instCo
 		"security.ipv4_filtering":              validate.IsAny,
 		"security.ipv6_filtering":              validate.IsAny,
 		"security.port_isolation":              validate.Optional(validate.IsBool),
+		"allow_duplicate_address":              validate.Optional(validate.IsBool),
 		"maas.subnet.ipv4":                     validate.IsAny,
 		"maas.subnet.ipv6":                     validate.IsAny,
 		"ipv4.address":                         validate.Optional(validate.IsNetworkAddressV4),
@@ -45,6 +61,9 @@ func nicValidationRules(requiredFields []string, optionalFields []string, instCo
 		"ipv4.host_table":                      validate.Optional(validate.IsUint32),
 		"ipv6.host_table":                      validate.Optional(validate.IsUint32),
 		"queue.tx.length":                      validate.Optional(validate.IsUint32),
+		"queue.rx.ring_size":                   nicValidRingSize(instConf),
+		"queue.tx.ring_size":                   nicValidRingSize(instConf),
+		"queues":                               nicValidQueues,
 		"ipv4.routes.external":                 validate.Optional(validate.IsListOf(validate.IsNetworkV4)),
 		"ipv6.routes.external":                 validate.Optional(validate.IsListOf(validate.IsNetworkV6)),
 		"nested":                               validate.IsAny,
@@ -132,3 +151,148 @@ func nicCheckNamesUnique(instConf instance.ConfigReader) error {
 func nicCheckDNSNameConflict(instNameA string, instNameB string) bool {
 	return strings.EqualFold(instNameA, instNameB)
 }
+
+// nicHostNamesInUse returns the host side interface names used by the NICs of the instances on the same cluster
+// member as inst, other than the devName NIC of inst itself. This includes both the host_name settings of the NICs
+// and the names recorded for host_name.persistent. The names are mapped to a description of the NIC using them.
+func nicHostNamesInUse(s *state.State, inst instance.Instance, devName string) (map[string]string, error) {
+	node := inst.Location()
+
+	var instances []db.InstanceArgs
+	err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		return tx.InstanceList(ctx, func(dbInst db.InstanceArgs, p api.Project) error {
+			instances = append(instances, dbInst)
+
+			return nil
+		}, cluster.InstanceFilter{Node: &node})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hostNames := make(map[string]string)
+	for _, dbInst := range instances {
+		// Skip our own device. This avoids triggering conflicts when making temporary copies of our
+		// instance during migrations.
+		sameLogicalInstance := instance.IsSameLogicalInstance(inst, &dbInst)
+
+		devices := instancetype.ExpandInstanceDevices(dbInst.Devices.Clone(), dbInst.Profiles)
+		for nicName, nicConfig := range devices {
+			if nicConfig["type"] != "nic" || (sameLogicalInstance && nicName == devName) {
+				continue
+			}
+
+			description := fmt.Sprintf("NIC %q of instance %q in project %q", nicName, dbInst.Name, dbInst.Project)
+
+			if nicConfig["host_name"] != "" {
+				hostNames[nicConfig["host_name"]] = description
+			}
+
+			persistentHostName := dbInst.Config[fmt.Sprintf("volatile.%s.persistent_host_name", nicName)]
+			if persistentHostName != "" {
+				hostNames[persistentHostName] = description
+			}
+		}
+	}
+
+	return hostNames, nil
+}
+
+// nicCheckHostName checks that the host_name and host_name.persistent settings of the devName NIC aren't used together,
+// and that the host_name isn't used by another NIC on the same cluster member.
+// Returns api.StatusError with status code set to http.StatusConflict if the host_name is already used.
+func nicCheckHostName(s *state.State, inst instance.Instance, devName string, config deviceConfig.Device) error {
+	if config["host_name"] == "" {
+		return nil
+	}
+
+	if shared.IsTrue(config["host_name.persistent"]) {
+		return fmt.Errorf(`Cannot use "host_name" and "host_name.persistent" together`)
+	}
+
+	// Can only validate this when the instance is supplied (and not doing profile validation). Snapshots share the
+	// NICs of their instance, so they aren't checked.
+	if inst == nil || inst.IsSnapshot() {
+		return nil
+	}
+
+	hostNames, err := nicHostNamesInUse(s, inst, devName)
+	if err != nil {
+		return err
+	}
+
+	usedBy, ok := hostNames[config["host_name"]]
+	if ok {
+		return api.StatusErrorf(http.StatusConflict, "Host interface name %q already used by %s", config["host_name"], usedBy)
+	}
+
+	return nil
+}
+
+// nicValidQueues validates the "queues" setting, which is either "auto" or a number of queues.
+func nicValidQueues(value string) error {
+	if value == "auto" {
+		return nil
+	}
+
+	return validate.IsInRange(1, nicMaxQueues)(value)
+}
+
+// nicValidRingSize returns a validator for the "queue.rx.ring_size" and "queue.tx.ring_size" settings.
+// The ring sizes are those of the virtio-net device, and so are only supported by virtual machines.
+func nicValidRingSize(instConf instance.ConfigReader) func(value string) error {
+	return func(value string) error {
+		if instConf.Type() != instancetype.VM {
+			return fmt.Errorf("Ring sizes are only supported by virtual machines")
+		}
+
+		size, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("Invalid ring size %q", value)
+		}
+
+		// QEMU only accepts powers of two between 256 and 1024.
+		if size < 256 || size > 1024 || size&(size-1) != 0 {
+			return fmt.Errorf("Invalid ring size %q (must be a power of two between 256 and 1024)", value)
+		}
+
+		return nil
+	}
+}
+
+// nicContainerQueues returns the number of queues to create the veth pair of a container NIC with. If "queues"
+// is "auto" then there is one queue per CPU that the container may use. Returns 0 if "queues" isn't set, in which
+// case the kernel default of one queue is used.
+func nicContainerQueues(instConf instance.ConfigReader, m deviceConfig.Device) (uint32, error) {
+	if m["queues"] == "" {
+		return 0, nil
+	}
+
+	if m["queues"] != "auto" {
+		queues, err := strconv.ParseUint(m["queues"], 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid queues %q: %w", m["queues"], err)
+		}
+
+		return uint32(queues), nil
+	}
+
+	cpus := runtime.NumCPU()
+
+	limit := instConf.ExpandedConfig()["limits.cpu"]
+	if limit != "" {
+		count, err := strconv.Atoi(limit)
+		if err == nil {
+			cpus = count
+		} else {
+			cpuset, err := resources.ParseCpuset(limit)
+			if err != nil {
+				return 0, fmt.Errorf("Failed parsing limits.cpu: %w", err)
+			}
+
+			cpus = len(cpuset)
+		}
+	}
+
+	return uint32(min(cpus, nicMaxQueues)), nil
+}
@@ -20,6 +20,7 @@ import (
 
 	"github.com/canonical/lxd/lxd/db"
 	"github.com/canonical/lxd/lxd/db/cluster"
+	"github.com/canonical/lxd/lxd/db/warningtype"
 	deviceConfig "github.com/canonical/lxd/lxd/device/config"
 	"github.com/canonical/lxd/lxd/dnsmasq"
 	"github.com/canonical/lxd/lxd/dnsmasq/dhcpalloc"
@@ -27,11 +28,15 @@ import (
 	"github.com/canonical/lxd/lxd/instance/instancetype"
 	"github.com/canonical/lxd/lxd/ip"
 	"github.com/canonical/lxd/lxd/network"
+	"github.com/canonical/lxd/lxd/network/dhcpext"
 	"github.com/canonical/lxd/lxd/network/openvswitch"
+	"github.com/canonical/lxd/lxd/project"
 	"github.com/canonical/lxd/lxd/resources"
 	"github.com/canonical/lxd/lxd/util"
+	"github.com/canonical/lxd/lxd/warnings"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/revert"
 	"github.com/canonical/lxd/shared/validate"
@@ -70,8 +75,12 @@ func (d *nicBridged) validateConfig(instConf instance.ConfigReader) error {
 		"parent",
 		"mtu",
 		"queue.tx.length",
+		"queue.rx.ring_size",
+		"queue.tx.ring_size",
+		"queues",
 		"hwaddr",
 		"host_name",
+		"host_name.persistent",
 		"limits.ingress",
 		"limits.egress",
 		"limits.max",
@@ -86,6 +95,7 @@ func (d *nicBridged) validateConfig(instConf instance.ConfigReader) error {
 		"security.ipv4_filtering",
 		"security.ipv6_filtering",
 		"security.port_isolation",
+		"allow_duplicate_address",
 		"maas.subnet.ipv4",
 		"maas.subnet.ipv6",
 		"boot.priority",
@@ -353,6 +363,12 @@ func (d *nicBridged) validateConfig(instConf instance.ConfigReader) error {
 		return err
 	}
 
+	// Check the host_name isn't used by another NIC on the same cluster member.
+	err = nicCheckHostName(d.state, d.inst, d.name, d.config)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -372,8 +388,14 @@ func (d *nicBridged) checkAddressConflict() error {
 	}
 
 	// Check if any instance devices use this network.
-	// Managed bridge networks have a per-server DHCP daemon so perform a node level search.
-	filter := cluster.InstanceFilter{Node: &node}
+	// Managed bridge networks have a per-server DHCP daemon so perform a node level search, except for static IPs
+	// which are checked across the cluster as managed networks use the same subnet on all cluster members.
+	filters := []cluster.InstanceFilter{{Node: &node}}
+	if d.network != nil {
+		filters = nil
+	}
+
+	allowDuplicate := shared.IsTrue(d.config["allow_duplicate_address"])
 
 	// Set network name for comparison (needs to support connecting to unmanaged networks).
 	networkName := d.config["parent"]
@@ -398,9 +420,12 @@ func (d *nicBridged) checkAddressConflict() error {
 			return nil
 		}
 
+		// Only static IPs are checked on other cluster members.
+		sameNode := inst.Node == node
+
 		// Check there isn't another instance with the same DNS name connected to a managed network
 		// that has DNS enabled and is connected to the same untagged VLAN.
-		if d.network != nil && d.network.Config()["dns.mode"] != "none" && nicCheckDNSNameConflict(d.inst.Name(), inst.Name) {
+		if sameNode && d.network != nil && d.network.Config()["dns.mode"] != "none" && nicCheckDNSNameConflict(d.inst.Name(), inst.Name) {
 			if sameLogicalInstance {
 				return api.StatusErrorf(http.StatusConflict, "Instance DNS name %q conflict between %q and %q because both are connected to same network", strings.ToLower(inst.Name), d.name, nicName)
 			}
@@ -414,11 +439,15 @@ func (d *nicBridged) checkAddressConflict() error {
 			devNICMAC, _ = net.ParseMAC(inst.Config[fmt.Sprintf("volatile.%s.hwaddr", nicName)])
 		}
 
-		if ourNICMAC != nil && devNICMAC != nil && bytes.Equal(ourNICMAC, devNICMAC) {
+		if sameNode && ourNICMAC != nil && devNICMAC != nil && bytes.Equal(ourNICMAC, devNICMAC) {
 			return api.StatusErrorf(http.StatusConflict, "MAC address %q already defined on another NIC", devNICMAC.String())
 		}
 
-		// Check NIC's static IPs don't match this NIC's static IPs.
+		// Check NIC's static IPs don't match this NIC's static IPs, unless either NIC allows duplicates.
+		if allowDuplicate || shared.IsTrue(nicConfig["allow_duplicate_address"]) {
+			return nil
+		}
+
 		for _, key := range []string{"ipv4.address", "ipv6.address"} {
 			if d.config[key] == "" {
 				continue // No static IP specified on this NIC.
@@ -433,7 +462,45 @@ func (d *nicBridged) checkAddressConflict() error {
 		}
 
 		return nil
-	}, filter)
+	}, filters...)
+}
+
+// checkLeaseConflict checks that the static IPv4 address of the NIC isn't leased to another NIC by the DHCP server
+// of the managed network on any cluster member.
+// Returns api.StatusError with status code set to http.StatusConflict if a conflicting lease is found.
+func (d *nicBridged) checkLeaseConflict() error {
+	if d.network == nil || shared.IsTrue(d.config["allow_duplicate_address"]) {
+		return nil
+	}
+
+	ourNICIP := net.ParseIP(d.config["ipv4.address"])
+	if ourNICIP == nil {
+		return nil
+	}
+
+	ourNICMAC, _ := net.ParseMAC(d.config["hwaddr"])
+	if ourNICMAC == nil {
+		ourNICMAC, _ = net.ParseMAC(d.volatileGet()["hwaddr"])
+	}
+
+	leases, err := network.BridgeDynamicLeases(d.state, d.network)
+	if err != nil {
+		return err
+	}
+
+	for _, lease := range leases {
+		leaseIP := net.ParseIP(lease.Address)
+		leaseMAC, _ := net.ParseMAC(lease.Hwaddr)
+		if leaseIP == nil || leaseMAC == nil || !leaseIP.Equal(ourNICIP) {
+			continue
+		}
+
+		if ourNICMAC == nil || !bytes.Equal(ourNICMAC, leaseMAC) {
+			return api.StatusErrorf(http.StatusConflict, "IP address %q is leased to MAC address %q on %q", ourNICIP.String(), leaseMAC.String(), lease.Location)
+		}
+	}
+
+	return nil
 }
 
 // validateEnvironment checks the runtime environment for correctness.
@@ -457,15 +524,20 @@ func (d *nicBridged) UpdatableFields(oldDevice Type) []string {
 		return []string{}
 	}
 
-	return []string{"limits.ingress", "limits.egress", "limits.max", "limits.priority", "ipv4.routes", "ipv6.routes", "ipv4.routes.external", "ipv6.routes.external", "ipv4.address", "ipv6.address", "security.mac_filtering", "security.ipv4_filtering", "security.ipv6_filtering"}
+	return []string{"limits.ingress", "limits.egress", "limits.max", "limits.priority", "ipv4.routes", "ipv6.routes", "ipv4.routes.external", "ipv6.routes.external", "ipv4.address", "ipv6.address", "security.mac_filtering", "security.ipv4_filtering", "security.ipv6_filtering", "allow_duplicate_address"}
 }
 
 // Add is run when a device is added to a non-snapshot instance whether or not the instance is running.
 func (d *nicBridged) Add() error {
 	networkVethFillFromVolatile(d.config, d.volatileGet())
 
+	err := d.checkLeaseConflict()
+	if err != nil {
+		return err
+	}
+
 	// Rebuild dnsmasq entry if needed and reload.
-	err := d.rebuildDnsmasqEntry()
+	err = d.rebuildDnsmasqEntry()
 	if err != nil {
 		return err
 	}
@@ -512,7 +584,13 @@ func (d *nicBridged) Start() (*deviceConfig.RunConfig, error) {
 				return nil, err
 			}
 		}
-		peerName, mtu, err = networkCreateVethPair(saveData["host_name"], d.config)
+		var queues uint32
+		queues, err = nicContainerQueues(d.inst, d.config)
+		if err != nil {
+			return nil, err
+		}
+
+		peerName, mtu, err = networkCreateVethPair(saveData["host_name"], d.config, queues)
 	} else if d.inst.Type() == instancetype.VM {
 		if saveData["host_name"] == "" {
 			saveData["host_name"], err = d.generateHostName("tap", d.config["hwaddr"])
@@ -653,6 +731,14 @@ func (d *nicBridged) Start() (*deviceConfig.RunConfig, error) {
 		}
 	}
 
+	// Register the NIC with the external DHCP system of the network.
+	err = d.registerExternalDHCP(saveData)
+	if err != nil {
+		return nil, err
+	}
+
+	revert.Add(func() { d.unregisterExternalDHCP(saveData) })
+
 	err = d.volatileSet(saveData)
 	if err != nil {
 		return nil, err
@@ -674,6 +760,9 @@ func (d *nicBridged) Start() (*deviceConfig.RunConfig, error) {
 			[]deviceConfig.RunConfigItem{
 				{Key: "devName", Value: d.name},
 				{Key: "mtu", Value: fmt.Sprintf("%d", mtu)},
+				{Key: "queues", Value: d.config["queues"]},
+				{Key: "rxRingSize", Value: d.config["queue.rx.ring_size"]},
+				{Key: "txRingSize", Value: d.config["queue.tx.ring_size"]},
 			}...)
 	}
 
@@ -700,6 +789,13 @@ func (d *nicBridged) Update(oldDevices deviceConfig.Devices, isRunning bool) err
 	networkVethFillFromVolatile(d.config, v)
 	networkVethFillFromVolatile(oldConfig, v)
 
+	if d.config["ipv4.address"] != oldConfig["ipv4.address"] {
+		err := d.checkLeaseConflict()
+		if err != nil {
+			return err
+		}
+	}
+
 	// If an IPv6 address has changed, flush all existing IPv6 leases for instance so instance
 	// isn't allocated old IP. This is important with IPv6 because DHCPv6 supports multiple IP
 	// address allocation and would result in instance having leases for both old and new IPs.
@@ -827,7 +923,9 @@ func (d *nicBridged) Stop() (*deviceConfig.RunConfig, error) {
 func (d *nicBridged) postStop() error {
 	defer func() {
 		_ = d.volatileSet(map[string]string{
-			"host_name": "",
+			"host_name":               "",
+			"external_dhcp.addresses": "",
+			"external_dhcp.reference": "",
 		})
 	}()
 
@@ -835,6 +933,9 @@ func (d *nicBridged) postStop() error {
 
 	networkVethFillFromVolatile(d.config, v)
 
+	// Remove the reservation of the NIC from the external DHCP system of the network.
+	d.unregisterExternalDHCP(v)
+
 	if d.config["host_name"] != "" && network.InterfaceExists(d.config["host_name"]) {
 		// Detach host-side end of veth pair from bridge (required for openvswitch particularly).
 		err := network.DetachInterface(d.config["parent"], d.config["host_name"])
@@ -866,6 +967,11 @@ func (d *nicBridged) postStop() error {
 
 // Remove is run when the device is removed from the instance or the instance is deleted.
 func (d *nicBridged) Remove() error {
+	err := warnings.DeleteWarningsByLocalNodeAndProjectAndTypeAndEntity(d.state.DB.Cluster, d.inst.Project().Name, warningtype.ExternalDHCPRegistrationFailure, entity.TypeInstance, d.inst.ID())
+	if err != nil {
+		d.logger.Warn("Failed to delete warning", logger.Ctx{"err": err})
+	}
+
 	if d.config["parent"] != "" {
 		dnsmasq.ConfigMutex.Lock()
 		defer dnsmasq.ConfigMutex.Unlock()
@@ -893,6 +999,109 @@ func (d *nicBridged) Remove() error {
 	return nil
 }
 
+// externalDHCPReservation returns the reservation of the NIC for the external DHCP system of its network, or nil if
+// the NIC isn't connected to a managed network using external DHCP.
+func (d *nicBridged) externalDHCPReservation() *dhcpext.Reservation {
+	if d.network == nil || !d.network.IsManaged() || !shared.IsTrue(d.network.Config()["dhcp.external"]) {
+		return nil
+	}
+
+	dnsDomain := d.network.Config()["dns.domain"]
+	if dnsDomain == "" {
+		dnsDomain = "lxd"
+	}
+
+	hostname := project.DNS(d.inst.Project().Name, d.inst.Name())
+
+	r := &dhcpext.Reservation{
+		Project:  d.inst.Project().Name,
+		Instance: d.inst.Name(),
+		Device:   d.name,
+		Network:  d.network.Name(),
+		Hostname: hostname,
+		FQDN:     fmt.Sprintf("%s.%s", hostname, dnsDomain),
+		HWAddr:   d.config["hwaddr"],
+	}
+
+	// If address is set to none treat it the same as not being specified.
+	if d.config["ipv4.address"] != "none" {
+		r.IPv4Address = d.config["ipv4.address"]
+	}
+
+	if d.config["ipv6.address"] != "none" {
+		r.IPv6Address = d.config["ipv6.address"]
+	}
+
+	return r
+}
+
+// registerExternalDHCP registers the NIC with the external DHCP system of its network and records the allocation in
+// the volatile data. Failures are surfaced as a warning, and only returned if the network is configured with
+// dhcp.external.strict.
+func (d *nicBridged) registerExternalDHCP(saveData map[string]string) error {
+	r := d.externalDHCPReservation()
+	if r == nil {
+		return nil
+	}
+
+	var allocation *dhcpext.Allocation
+	backend, err := dhcpext.Load(d.network.Config(), d.state.Proxy)
+	if err == nil {
+		allocation, err = backend.Register(context.TODO(), *r)
+	}
+
+	if err != nil {
+		if shared.IsTrue(d.network.Config()["dhcp.external.strict"]) {
+			return fmt.Errorf("Failed registering with external DHCP of network %q: %w", d.network.Name(), err)
+		}
+
+		d.logger.Warn("Failed registering with external DHCP", logger.Ctx{"network": d.network.Name(), "err": err})
+
+		msg := fmt.Sprintf("Failed registering device %q with external DHCP of network %q: %v", d.name, d.network.Name(), err)
+		err = d.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+			return tx.UpsertWarningLocalNode(ctx, d.inst.Project().Name, entity.TypeInstance, d.inst.ID(), warningtype.ExternalDHCPRegistrationFailure, msg)
+		})
+		if err != nil {
+			d.logger.Warn("Failed to create warning", logger.Ctx{"err": err})
+		}
+
+		return nil
+	}
+
+	saveData["external_dhcp.addresses"] = strings.Join(allocation.Addresses, ",")
+	saveData["external_dhcp.reference"] = allocation.Reference
+
+	err = warnings.ResolveWarningsByLocalNodeAndProjectAndTypeAndEntity(d.state.DB.Cluster, d.inst.Project().Name, warningtype.ExternalDHCPRegistrationFailure, entity.TypeInstance, d.inst.ID())
+	if err != nil {
+		d.logger.Warn("Failed to resolve warning", logger.Ctx{"err": err})
+	}
+
+	return nil
+}
+
+// unregisterExternalDHCP removes the reservation of the NIC from the external DHCP system of its network, using the
+// allocation recorded in the volatile data. Failures are only logged so that they don't prevent the NIC from stopping.
+func (d *nicBridged) unregisterExternalDHCP(v map[string]string) {
+	r := d.externalDHCPReservation()
+	if r == nil {
+		return
+	}
+
+	allocation := dhcpext.Allocation{
+		Addresses: shared.SplitNTrimSpace(v["external_dhcp.addresses"], ",", -1, true),
+		Reference: v["external_dhcp.reference"],
+	}
+
+	backend, err := dhcpext.Load(d.network.Config(), d.state.Proxy)
+	if err == nil {
+		err = backend.Unregister(context.TODO(), *r, allocation)
+	}
+
+	if err != nil {
+		d.logger.Warn("Failed unregistering from external DHCP", logger.Ctx{"network": d.network.Name(), "err": err})
+	}
+}
+
 // rebuildDnsmasqEntry rebuilds the dnsmasq host entry if connected to a LXD managed network and reloads dnsmasq.
 func (d *nicBridged) rebuildDnsmasqEntry() error {
 	// Rebuild dnsmasq config if parent is a managed bridge network using dnsmasq.
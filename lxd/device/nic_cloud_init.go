@@ -0,0 +1,199 @@
+package device
+
+import (
+	"fmt"
+	"net"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/canonical/lxd/lxd/instance"
+	"github.com/canonical/lxd/lxd/network"
+	"github.com/canonical/lxd/lxd/project"
+	"github.com/canonical/lxd/lxd/state"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+)
+
+// CloudInitNetworkConfigAuto is the value of cloud-init.network-config that makes LXD generate the network
+// configuration from the NIC devices of the instance.
+const CloudInitNetworkConfigAuto = "auto"
+
+// cloudInitNetworkConfig is a version 2 (netplan style) cloud-init network configuration.
+type cloudInitNetworkConfig struct {
+	Version   int                          `yaml:"version"`
+	Ethernets map[string]cloudInitEthernet `yaml:"ethernets"`
+}
+
+// cloudInitEthernet is the configuration of a single interface in a version 2 cloud-init network configuration.
+type cloudInitEthernet struct {
+	Match       map[string]string     `yaml:"match"`
+	SetName     string                `yaml:"set-name,omitempty"`
+	DHCP4       bool                  `yaml:"dhcp4,omitempty"`
+	Addresses   []string              `yaml:"addresses,omitempty"`
+	Routes      []cloudInitRoute      `yaml:"routes,omitempty"`
+	Nameservers *cloudInitNameservers `yaml:"nameservers,omitempty"`
+}
+
+// cloudInitRoute is a route in a version 2 cloud-init network configuration.
+type cloudInitRoute struct {
+	To  string `yaml:"to"`
+	Via string `yaml:"via"`
+}
+
+// cloudInitNameservers is the DNS configuration in a version 2 cloud-init network configuration.
+type cloudInitNameservers struct {
+	Addresses []string `yaml:"addresses,omitempty"`
+	Search    []string `yaml:"search,omitempty"`
+}
+
+// CloudInitNetworkConfig returns the cloud-init network configuration to provide to the instance.
+// If cloud-init.network-config is set to auto, the configuration is generated from the NIC devices of the instance
+// unless user.network-config is set, as configuration provided by the user always takes precedence.
+func CloudInitNetworkConfig(s *state.State, inst instance.ConfigReader) (string, error) {
+	config := inst.ExpandedConfig()
+
+	networkConfig, ok := config["cloud-init.network-config"]
+	if !ok {
+		return config["user.network-config"], nil
+	}
+
+	if networkConfig != CloudInitNetworkConfigAuto {
+		return networkConfig, nil
+	}
+
+	if config["user.network-config"] != "" {
+		return config["user.network-config"], nil
+	}
+
+	return GenerateCloudInitNetworkConfig(s, inst)
+}
+
+// GenerateCloudInitNetworkConfig generates a version 2 cloud-init network configuration from the NIC devices of the
+// instance. Interfaces are matched by MAC address. NICs with a static IP address on a managed network get that
+// address along with a default route and DNS pointing to the network, all other NICs use DHCP.
+func GenerateCloudInitNetworkConfig(s *state.State, inst instance.ConfigReader) (string, error) {
+	config := inst.ExpandedConfig()
+	devices := inst.ExpandedDevices()
+
+	networkProjectName, _, err := project.NetworkProject(s.DB.Cluster, inst.Project().Name)
+	if err != nil {
+		return "", fmt.Errorf("Failed loading network project name: %w", err)
+	}
+
+	networkConfig := cloudInitNetworkConfig{
+		Version:   2,
+		Ethernets: map[string]cloudInitEthernet{},
+	}
+
+	// Sort the NICs so that the generated configuration is stable.
+	devNames := make([]string, 0, len(devices))
+	for devName, dev := range devices {
+		if dev["type"] == "nic" {
+			devNames = append(devNames, devName)
+		}
+	}
+
+	sort.Strings(devNames)
+
+	for _, devName := range devNames {
+		dev := devices[devName]
+
+		ethernet := cloudInitEthernet{Match: map[string]string{}}
+
+		hwaddr := dev["hwaddr"]
+		if hwaddr == "" {
+			hwaddr = config[fmt.Sprintf("volatile.%s.hwaddr", devName)]
+		}
+
+		if hwaddr != "" {
+			ethernet.Match["macaddress"] = hwaddr
+		}
+
+		ifaceName := devName
+		if dev["name"] != "" {
+			ifaceName = dev["name"]
+			ethernet.SetName = dev["name"]
+		}
+
+		if len(ethernet.Match) == 0 {
+			// Without a MAC address, the interface can only be found if its name is known.
+			if dev["name"] == "" {
+				continue
+			}
+
+			ethernet.Match["name"] = dev["name"]
+		}
+
+		// Look for the managed network the NIC is connected to.
+		var netConfig map[string]string
+		if dev["network"] != "" {
+			n, err := network.LoadByName(s, networkProjectName, dev["network"])
+			if err != nil {
+				return "", fmt.Errorf("Failed loading network %q for device %q: %w", dev["network"], devName, err)
+			}
+
+			netConfig = n.Config()
+		} else if dev["nictype"] == "bridged" && dev["parent"] != "" {
+			n, err := network.LoadByName(s, api.ProjectDefaultName, dev["parent"])
+			if err == nil && n.IsManaged() {
+				netConfig = n.Config()
+			}
+		}
+
+		ethernet.DHCP4 = true
+
+		if netConfig != nil {
+			if cloudInitStaticAddress(&ethernet, dev["ipv4.address"], netConfig["ipv4.address"], "0.0.0.0/0") {
+				ethernet.DHCP4 = false
+			}
+
+			cloudInitStaticAddress(&ethernet, dev["ipv6.address"], netConfig["ipv6.address"], "::/0")
+
+			if ethernet.Nameservers != nil && netConfig["dns.mode"] != "none" {
+				dnsDomain := netConfig["dns.domain"]
+				if dnsDomain == "" {
+					dnsDomain = "lxd"
+				}
+
+				ethernet.Nameservers.Search = []string{dnsDomain}
+			}
+		}
+
+		networkConfig.Ethernets[ifaceName] = ethernet
+	}
+
+	out, err := yaml.Marshal(networkConfig)
+	if err != nil {
+		return "", fmt.Errorf("Failed generating cloud-init network configuration: %w", err)
+	}
+
+	return string(out), nil
+}
+
+// cloudInitStaticAddress adds the static address of a NIC to the interface, using the subnet of the network it's on
+// and adding a default route and name server pointing to the network's address.
+// Returns false if the address couldn't be configured statically.
+func cloudInitStaticAddress(ethernet *cloudInitEthernet, address string, networkAddress string, defaultRoute string) bool {
+	if address == "" || shared.ValueInSlice(networkAddress, []string{"", "none"}) {
+		return false
+	}
+
+	ip := net.ParseIP(address)
+	gateway, subnet, err := net.ParseCIDR(networkAddress)
+	if ip == nil || err != nil || !subnet.Contains(ip) {
+		return false
+	}
+
+	prefix, _ := subnet.Mask.Size()
+	ethernet.Addresses = append(ethernet.Addresses, fmt.Sprintf("%s/%d", ip.String(), prefix))
+	ethernet.Routes = append(ethernet.Routes, cloudInitRoute{To: defaultRoute, Via: gateway.String()})
+
+	if ethernet.Nameservers == nil {
+		ethernet.Nameservers = &cloudInitNameservers{}
+	}
+
+	ethernet.Nameservers.Addresses = append(ethernet.Nameservers.Addresses, gateway.String())
+
+	return true
+}
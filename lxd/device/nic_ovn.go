@@ -84,6 +84,7 @@ func (d *nicOVN) validateConfig(instConf instance.ConfigReader) error {
 		"name",
 		"hwaddr",
 		"host_name",
+		"host_name.persistent",
 		"mtu",
 		"ipv4.address",
 		"ipv6.address",
@@ -251,6 +252,12 @@ func (d *nicOVN) validateConfig(instConf instance.ConfigReader) error {
 		return err
 	}
 
+	// Check the host_name isn't used by another NIC on the same cluster member.
+	err = nicCheckHostName(d.state, d.inst, d.name, d.config)
+	if err != nil {
+		return err
+	}
+
 	// Check IP external routes are within the network's external routes.
 	var externalRoutes []*net.IPNet
 	for _, k := range []string{"ipv4.routes.external", "ipv6.routes.external"} {
@@ -528,7 +535,7 @@ func (d *nicOVN) Start() (*deviceConfig.RunConfig, error) {
 				}
 
 				integrationBridgeNICName = saveData["host_name"]
-				peerName, mtu, err = networkCreateVethPair(saveData["host_name"], d.config)
+				peerName, mtu, err = networkCreateVethPair(saveData["host_name"], d.config, 0)
 				if err != nil {
 					return nil, err
 				}
@@ -10,6 +10,7 @@ import (
 	"github.com/canonical/lxd/lxd/network"
 	"github.com/canonical/lxd/lxd/util"
 	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/revert"
 )
 
@@ -32,8 +33,12 @@ func (d *nicP2P) validateConfig(instConf instance.ConfigReader) error {
 		"name",
 		"mtu",
 		"queue.tx.length",
+		"queue.rx.ring_size",
+		"queue.tx.ring_size",
+		"queues",
 		"hwaddr",
 		"host_name",
+		"host_name.persistent",
 		"limits.ingress",
 		"limits.egress",
 		"limits.max",
@@ -48,6 +53,12 @@ func (d *nicP2P) validateConfig(instConf instance.ConfigReader) error {
 		return err
 	}
 
+	// Check the host_name isn't used by another NIC on the same cluster member.
+	err = nicCheckHostName(d.state, d.inst, d.name, d.config)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -96,7 +107,13 @@ func (d *nicP2P) Start() (*deviceConfig.RunConfig, error) {
 			}
 		}
 
-		peerName, mtu, err = networkCreateVethPair(saveData["host_name"], d.config)
+		var queues uint32
+		queues, err = nicContainerQueues(d.inst, d.config)
+		if err != nil {
+			return nil, err
+		}
+
+		peerName, mtu, err = networkCreateVethPair(saveData["host_name"], d.config, queues)
 	} else if d.inst.Type() == instancetype.VM {
 		if saveData["host_name"] == "" {
 			saveData["host_name"], err = d.generateHostName("tap", d.config["hwaddr"])
@@ -155,6 +172,9 @@ func (d *nicP2P) Start() (*deviceConfig.RunConfig, error) {
 			[]deviceConfig.RunConfigItem{
 				{Key: "devName", Value: d.name},
 				{Key: "mtu", Value: fmt.Sprintf("%d", mtu)},
+				{Key: "queues", Value: d.config["queues"]},
+				{Key: "rxRingSize", Value: d.config["queue.rx.ring_size"]},
+				{Key: "txRingSize", Value: d.config["queue.tx.ring_size"]},
 			}...)
 	}
 
@@ -237,3 +257,15 @@ func (d *nicP2P) postStop() error {
 
 	return nil
 }
+
+// State gets the state of a P2P NIC by reading the counters of its host side interface.
+func (d *nicP2P) State() (*api.InstanceStateNetwork, error) {
+	// Populate device config with volatile fields (hwaddr and host_name) if needed.
+	networkVethFillFromVolatile(d.config, d.volatileGet())
+
+	if d.config["host_name"] == "" {
+		return nil, nil
+	}
+
+	return networkHostInterfaceState(d.config["host_name"], d.config["hwaddr"], nil)
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/canonical/lxd/lxd/network"
 	"github.com/canonical/lxd/lxd/util"
 	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/revert"
 	"github.com/canonical/lxd/shared/validate"
@@ -63,8 +64,12 @@ func (d *nicRouted) validateConfig(instConf instance.ConfigReader) error {
 		"parent",
 		"mtu",
 		"queue.tx.length",
+		"queue.rx.ring_size",
+		"queue.tx.ring_size",
+		"queues",
 		"hwaddr",
 		"host_name",
+		"host_name.persistent",
 		"vlan",
 		"limits.ingress",
 		"limits.egress",
@@ -93,6 +98,12 @@ func (d *nicRouted) validateConfig(instConf instance.ConfigReader) error {
 		return err
 	}
 
+	// Check the host_name isn't used by another NIC on the same cluster member.
+	err = nicCheckHostName(d.state, d.inst, d.name, d.config)
+	if err != nil {
+		return err
+	}
+
 	// Detect duplicate IPs in config.
 	for _, key := range []string{"ipv4.address", "ipv6.address"} {
 		ips := make(map[string]struct{})
@@ -324,7 +335,13 @@ func (d *nicRouted) Start() (*deviceConfig.RunConfig, error) {
 			}
 		}
 
-		peerName, mtu, err = networkCreateVethPair(saveData["host_name"], d.config)
+		var queues uint32
+		queues, err = nicContainerQueues(d.inst, d.config)
+		if err != nil {
+			return nil, err
+		}
+
+		peerName, mtu, err = networkCreateVethPair(saveData["host_name"], d.config, queues)
 	} else if d.inst.Type() == instancetype.VM {
 		if saveData["host_name"] == "" {
 			saveData["host_name"], err = d.generateHostName("tap", d.config["hwaddr"])
@@ -525,6 +542,9 @@ func (d *nicRouted) Start() (*deviceConfig.RunConfig, error) {
 		runConf.NetworkInterface = append(runConf.NetworkInterface, []deviceConfig.RunConfigItem{
 			{Key: "devName", Value: d.name},
 			{Key: "mtu", Value: fmt.Sprintf("%d", mtu)},
+			{Key: "queues", Value: d.config["queues"]},
+			{Key: "rxRingSize", Value: d.config["queue.rx.ring_size"]},
+			{Key: "txRingSize", Value: d.config["queue.tx.ring_size"]},
 		}...)
 	}
 
@@ -667,6 +687,38 @@ func (d *nicRouted) postStop() error {
 	return nil
 }
 
+// State gets the state of a routed NIC from its statically configured addresses and the counters of its host side
+// interface.
+func (d *nicRouted) State() (*api.InstanceStateNetwork, error) {
+	// Populate device config with volatile fields (hwaddr and host_name) if needed.
+	networkVethFillFromVolatile(d.config, d.volatileGet())
+
+	if d.config["host_name"] == "" {
+		return nil, nil
+	}
+
+	addresses := []api.InstanceStateNetworkAddress{}
+	for _, address := range shared.SplitNTrimSpace(d.config["ipv4.address"], ",", -1, true) {
+		addresses = append(addresses, api.InstanceStateNetworkAddress{
+			Family:  "inet",
+			Address: address,
+			Netmask: "32",
+			Scope:   "global",
+		})
+	}
+
+	for _, address := range shared.SplitNTrimSpace(d.config["ipv6.address"], ",", -1, true) {
+		addresses = append(addresses, api.InstanceStateNetworkAddress{
+			Family:  "inet6",
+			Address: address,
+			Netmask: "128",
+			Scope:   "global",
+		})
+	}
+
+	return networkHostInterfaceState(d.config["host_name"], d.config["hwaddr"], addresses)
+}
+
 func (d *nicRouted) ipHostAddress(ipFamily string) string {
 	key := fmt.Sprintf("%s.host_address", ipFamily)
 	if d.config[key] != "" {
@@ -158,12 +158,13 @@ func Up(config *Config) (*Endpoints, error) {
 // the relevant HTTP handlers to them. When LXD shuts down they close all
 // sockets.
 type Endpoints struct {
-	tomb      *tomb.Tomb            // Controls the HTTP servers shutdown.
-	mu        sync.RWMutex          // Serialize access to internal state.
-	listeners map[kind]net.Listener // Activer listeners by endpoint type.
-	servers   map[kind]*http.Server // HTTP servers by endpoint type.
-	cert      *shared.CertInfo      // Keypair and CA to use for TLS.
-	inherited map[kind]bool         // Store whether the listener came through socket activation
+	tomb           *tomb.Tomb            // Controls the HTTP servers shutdown.
+	mu             sync.RWMutex          // Serialize access to internal state.
+	listeners      map[kind]net.Listener // Activer listeners by endpoint type.
+	servers        map[kind]*http.Server // HTTP servers by endpoint type.
+	cert           *shared.CertInfo      // Keypair and CA to use for TLS.
+	inherited      map[kind]bool         // Store whether the listener came through socket activation
+	trustedProxies []*net.IPNet          // Trusted proxies of the network endpoint.
 
 	systemdListenFDsStart int // First socket activation FD, for tests.
 }
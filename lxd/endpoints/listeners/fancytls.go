@@ -18,7 +18,7 @@ type FancyTLSListener struct {
 	net.Listener
 	mu           sync.RWMutex
 	config       *tls.Config
-	trustedProxy []net.IP
+	trustedProxy []*net.IPNet
 }
 
 // NewFancyTLSListener creates a new FancyTLSListener.
@@ -42,7 +42,7 @@ func (l *FancyTLSListener) Accept() (net.Conn, error) {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 	config := l.config
-	if isProxy(c.RemoteAddr().String(), l.trustedProxy) {
+	if util.IsTrustedProxy(c.RemoteAddr().String(), l.trustedProxy) {
 		c = proxyproto.NewConn(c, 0)
 	}
 
@@ -60,25 +60,9 @@ func (l *FancyTLSListener) Config(cert *shared.CertInfo) {
 }
 
 // TrustedProxy sets new the https trusted proxy configuration.
-func (l *FancyTLSListener) TrustedProxy(trustedProxy []net.IP) {
+func (l *FancyTLSListener) TrustedProxy(trustedProxy []*net.IPNet) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
 	l.trustedProxy = trustedProxy
 }
-
-func isProxy(addr string, proxies []net.IP) bool {
-	host, _, err := net.SplitHostPort(addr)
-	if err != nil {
-		return false
-	}
-
-	hostIP := net.ParseIP(host)
-
-	for _, p := range proxies {
-		if hostIP.Equal(p) {
-			return true
-		}
-	}
-	return false
-}
@@ -146,21 +146,23 @@ func (e *Endpoints) NetworkUpdateCert(cert *shared.CertInfo) {
 	}
 }
 
+// NetworkTrustedProxies returns the trusted proxies of the network endpoint.
+func (e *Endpoints) NetworkTrustedProxies() []*net.IPNet {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.trustedProxies
+}
+
 // NetworkUpdateTrustedProxy updates the https trusted proxy used by the network endpoint.
 func (e *Endpoints) NetworkUpdateTrustedProxy(trustedProxy string) {
-	var proxies []net.IP
-	for _, p := range shared.SplitNTrimSpace(trustedProxy, ",", -1, true) {
-		proxyIP := net.ParseIP(p)
-		if proxyIP == nil {
-			continue
-		}
-
-		proxies = append(proxies, proxyIP)
-	}
+	proxies := util.ParseTrustedProxies(trustedProxy)
 
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
+	e.trustedProxies = proxies
+
 	for _, kind := range []kind{network, cluster} {
 		listener, ok := e.listeners[kind]
 		if !ok || listener == nil {
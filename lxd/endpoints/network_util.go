@@ -5,11 +5,12 @@ import (
 	"net"
 	"regexp"
 
+	"github.com/canonical/lxd/lxd/util"
 	"github.com/canonical/lxd/shared/logger"
 )
 
 type networkServerErrorLogWriter struct {
-	proxies []net.IP
+	proxies []*net.IPNet
 }
 
 // Regex for the log we want to ignore.
@@ -51,12 +52,8 @@ func (d networkServerErrorLogWriter) stripLog(p []byte) string {
 	}
 
 	// Discard the log if the source is in our list of trusted proxies.
-	if sourceIP != "" {
-		for _, ip := range d.proxies {
-			if ip.String() == sourceIP {
-				return ""
-			}
-		}
+	if sourceIP != "" && util.IsTrustedProxy(sourceIP, d.proxies) {
+		return ""
 	}
 
 	return string(p)
@@ -26,7 +26,7 @@ var privilegedEventTypes = []string{api.EventTypeLogging}
 var eventsCmd = APIEndpoint{
 	Path: "events",
 
-	Get: APIEndpointAction{Handler: eventsGet, AccessHandler: allowAuthenticated},
+	Get: APIEndpointAction{Handler: eventsGet, AccessHandler: allowAuthenticated, NoStaleReads: true},
 }
 
 type eventsServe struct {
@@ -99,6 +99,15 @@ func eventsSocket(s *state.State, r *http.Request, w http.ResponseWriter) error
 		return api.StatusErrorf(http.StatusForbidden, "Forbidden")
 	}
 
+	// Only deliver the lifecycle events of an authorization group if requested.
+	groupName := request.QueryParam(r, "group")
+	if groupName != "" {
+		err := s.Authorizer.CheckPermission(r.Context(), r, entity.AuthGroupURL(groupName), auth.EntitlementCanView)
+		if err != nil {
+			return err
+		}
+	}
+
 	l := logger.AddContext(logger.Ctx{"remote": r.RemoteAddr})
 
 	var excludeLocations []string
@@ -155,7 +164,7 @@ func eventsSocket(s *state.State, r *http.Request, w http.ResponseWriter) error
 	defer func() { _ = conn.Close() }() // Ensure listener below ends when this function ends.
 
 	listenerConnection := events.NewWebsocketListenerConnection(conn)
-	listener, err := s.Events.AddListener(projectName, allProjects, projectPermissionFunc, listenerConnection, types, excludeSources, recvFunc, excludeLocations)
+	listener, err := s.Events.AddListener(projectName, allProjects, projectPermissionFunc, listenerConnection, types, excludeSources, recvFunc, excludeLocations, groupName)
 	if err != nil {
 		l.Warn("Failed to add event listener", logger.Ctx{"err": err})
 		return nil
@@ -190,6 +199,11 @@ func eventsSocket(s *state.State, r *http.Request, w http.ResponseWriter) error
 //	    name: all-projects
 //	    description: Retrieve instances from all projects
 //	    type: boolean
+//	  - in: query
+//	    name: group
+//	    description: Only return the lifecycle events of this authorization group
+//	    type: string
+//	    example: operators
 //	responses:
 //	  "200":
 //	    description: Websocket message (JSON)
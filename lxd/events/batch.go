@@ -0,0 +1,150 @@
+package events
+
+import (
+	"net/url"
+	"path"
+	"sync"
+
+	"github.com/canonical/lxd/shared/api"
+)
+
+// lifecycleBatchKey identifies the lifecycle events of a batch that are aggregated together.
+type lifecycleBatchKey struct {
+	project string
+	action  string
+}
+
+// LifecycleBatch batches the lifecycle events of the entities of a bulk operation.
+// The events of each action are sent individually until the threshold is reached, and the following ones are
+// aggregated into a single event per action that is sent when the batch is closed.
+type LifecycleBatch struct {
+	server    *Server
+	operation string
+	threshold int
+	sources   map[string]bool
+
+	lock       sync.Mutex
+	sent       map[lifecycleBatchKey]int
+	aggregates map[lifecycleBatchKey]*api.EventLifecycle
+	order      []lifecycleBatchKey
+}
+
+// NewLifecycleBatch starts batching the lifecycle events of the entities with the given URLs, on behalf of the
+// operation with the given URL. Events are never aggregated if the threshold is zero.
+// The batch must be closed once the operation is done to send the aggregated events.
+func (s *Server) NewLifecycleBatch(operationURL string, threshold int, sources []*api.URL) *LifecycleBatch {
+	batch := &LifecycleBatch{
+		server:     s,
+		operation:  operationURL,
+		threshold:  threshold,
+		sources:    make(map[string]bool, len(sources)),
+		sent:       map[lifecycleBatchKey]int{},
+		aggregates: map[lifecycleBatchKey]*api.EventLifecycle{},
+	}
+
+	for _, source := range sources {
+		batch.sources[source.String()] = true
+	}
+
+	if threshold > 0 {
+		s.lock.Lock()
+		s.batches[batch] = struct{}{}
+		s.lock.Unlock()
+	}
+
+	return batch
+}
+
+// Close stops batching and sends the aggregated events.
+func (b *LifecycleBatch) Close() {
+	b.server.lock.Lock()
+	delete(b.server.batches, b)
+	b.server.lock.Unlock()
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for _, key := range b.order {
+		_ = b.server.Send(key.project, api.EventTypeLifecycle, b.aggregates[key])
+	}
+
+	b.order = nil
+	b.aggregates = map[lifecycleBatchKey]*api.EventLifecycle{}
+}
+
+// add returns false if the event must be sent individually, or aggregates it and returns true.
+func (b *LifecycleBatch) add(event api.EventLifecycle) bool {
+	if !b.sources[event.Source] {
+		return false
+	}
+
+	key := lifecycleBatchKey{project: event.Project, action: event.Action}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.sent[key] < b.threshold {
+		b.sent[key]++
+		return false
+	}
+
+	name := event.Name
+	if name == "" {
+		name = path.Base(event.Source)
+	}
+
+	aggregate, ok := b.aggregates[key]
+	if !ok {
+		aggregate = &api.EventLifecycle{
+			Action:    event.Action,
+			Source:    lifecycleCollectionURL(event.Source),
+			Requestor: event.Requestor,
+			Project:   event.Project,
+			Aggregate: &api.EventLifecycleAggregate{First: name, Operation: b.operation},
+		}
+
+		b.aggregates[key] = aggregate
+		b.order = append(b.order, key)
+	}
+
+	aggregate.Aggregate.Count++
+	aggregate.Aggregate.Last = name
+
+	return true
+}
+
+// batchLifecycle returns true if the lifecycle event was aggregated by one of the active batches.
+func (s *Server) batchLifecycle(event api.EventLifecycle) bool {
+	s.lock.Lock()
+	if len(s.batches) == 0 {
+		s.lock.Unlock()
+		return false
+	}
+
+	batches := make([]*LifecycleBatch, 0, len(s.batches))
+	for batch := range s.batches {
+		batches = append(batches, batch)
+	}
+
+	s.lock.Unlock()
+
+	for _, batch := range batches {
+		if batch.add(event) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lifecycleCollectionURL returns the URL of the collection that contains the entity with the given URL.
+func lifecycleCollectionURL(source string) string {
+	u, err := url.Parse(source)
+	if err != nil {
+		return source
+	}
+
+	u.Path = path.Dir(u.Path)
+
+	return u.String()
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -34,13 +36,19 @@ type InjectFunc func(event api.Event, eventSource EventSource)
 // NotifyFunc is called when an event is dispatched.
 type NotifyFunc func(event api.Event)
 
+// listenerQueueSize is the maximum number of events waiting to be delivered to a listener. Once the queue of a
+// listener is full, the events are dropped so that a slow listener can't cause unbounded memory growth.
+const listenerQueueSize = 1024
+
 // Server represents an instance of an event server.
 type Server struct {
 	serverCommon
 
 	listeners map[string]*Listener
+	batches   map[*LifecycleBatch]struct{}
 	notify    NotifyFunc
 	location  string
+	dropped   atomic.Uint64
 }
 
 // NewServer returns a new event server.
@@ -51,6 +59,7 @@ func NewServer(debug bool, verbose bool, notify NotifyFunc) *Server {
 			verbose: verbose,
 		},
 		listeners: map[string]*Listener{},
+		batches:   map[*LifecycleBatch]struct{}{},
 		notify:    notify,
 	}
 
@@ -67,7 +76,8 @@ func (s *Server) SetLocalLocation(location string) {
 }
 
 // AddListener creates and returns a new event listener.
-func (s *Server) AddListener(projectName string, allProjects bool, projectPermissionFunc auth.PermissionChecker, connection EventListenerConnection, messageTypes []string, excludeSources []EventSource, recvFunc EventHandler, excludeLocations []string) (*Listener, error) {
+// If groupName is set, only the lifecycle events of that authorization group are delivered to the listener.
+func (s *Server) AddListener(projectName string, allProjects bool, projectPermissionFunc auth.PermissionChecker, connection EventListenerConnection, messageTypes []string, excludeSources []EventSource, recvFunc EventHandler, excludeLocations []string, groupName string) (*Listener, error) {
 	if allProjects && projectName != "" {
 		return nil, fmt.Errorf("Cannot specify project name when listening for events on all projects")
 	}
@@ -92,6 +102,8 @@ func (s *Server) AddListener(projectName string, allProjects bool, projectPermis
 		projectPermissionFunc: projectPermissionFunc,
 		excludeSources:        excludeSources,
 		excludeLocations:      excludeLocations,
+		groupName:             groupName,
+		queue:                 make(chan api.Event, listenerQueueSize),
 	}
 
 	s.lock.Lock()
@@ -104,12 +116,23 @@ func (s *Server) AddListener(projectName string, allProjects bool, projectPermis
 	s.listeners[listener.id] = listener
 
 	go listener.start()
+	go s.deliver(listener)
 
 	return listener, nil
 }
 
+// Dropped returns the number of events that weren't delivered to a listener because its queue was full.
+func (s *Server) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
 // SendLifecycle broadcasts a lifecycle event.
+// If the event belongs to a bulk operation whose events are batched, it may be aggregated instead.
 func (s *Server) SendLifecycle(projectName string, event api.EventLifecycle) {
+	if s.batchLifecycle(event) {
+		return
+	}
+
 	_ = s.Send(projectName, api.EventTypeLifecycle, event)
 }
 
@@ -181,6 +204,10 @@ func (s *Server) broadcast(event api.Event, eventSource EventSource) error {
 		s.notify(event)
 	}
 
+	// Only work out the authorization groups of the event if a listener filters on them.
+	var groups []string
+	groupsLoaded := false
+
 	listeners := s.listeners
 	for _, listener := range listeners {
 		// If the event is project specific, check if the listener is requesting events from that project.
@@ -206,21 +233,54 @@ func (s *Server) broadcast(event api.Event, eventSource EventSource) error {
 			continue
 		}
 
-		go func(listener *Listener, event api.Event) {
-			// Check that the listener still exists
-			if listener == nil {
-				return
+		// If the listener only wants the events of an authorization group, don't deliver the others.
+		if listener.groupName != "" {
+			if !groupsLoaded {
+				groups = lifecycleEventGroups(event)
+				groupsLoaded = true
 			}
 
-			// Make sure we're not done already
-			if listener.IsClosed() {
-				// Remove the listener from the list
-				s.lock.Lock()
-				delete(s.listeners, listener.id)
-				s.lock.Unlock()
-				return
+			if !shared.ValueInSlice(listener.groupName, groups) {
+				continue
 			}
+		}
+
+		// Make sure we're not done already
+		if listener.IsClosed() {
+			// Remove the listener from the list
+			delete(s.listeners, listener.id)
+			continue
+		}
+
+		select {
+		case listener.queue <- event:
+		default:
+			s.dropped.Add(1)
+			if listener.dropped.Add(1) == 1 {
+				logger.Warn("Event listener is too slow, dropping events", logger.Ctx{"listener": listener.id, "remote": listener.RemoteAddr()})
+			}
+		}
+	}
+
+	s.lock.Unlock()
+
+	return nil
+}
 
+// deliver writes the events queued for the listener to it until the listener is closed.
+func (s *Server) deliver(listener *Listener) {
+	defer func() {
+		dropped := listener.dropped.Load()
+		if dropped > 0 {
+			logger.Warn("Event listener dropped events", logger.Ctx{"listener": listener.id, "remote": listener.RemoteAddr(), "dropped": dropped})
+		}
+	}()
+
+	for {
+		select {
+		case <-listener.done.Done():
+			return
+		case event := <-listener.queue:
 			err := listener.WriteJSON(event)
 			if err != nil {
 				// Remove the listener from the list
@@ -229,13 +289,10 @@ func (s *Server) broadcast(event api.Event, eventSource EventSource) error {
 				s.lock.Unlock()
 
 				listener.Close()
+				return
 			}
-		}(listener, event)
+		}
 	}
-
-	s.lock.Unlock()
-
-	return nil
 }
 
 // Listener describes an event listener.
@@ -247,4 +304,40 @@ type Listener struct {
 	projectPermissionFunc auth.PermissionChecker
 	excludeSources        []EventSource
 	excludeLocations      []string
+	groupName             string
+	queue                 chan api.Event
+	dropped               atomic.Uint64
+}
+
+// lifecycleEventGroups returns the names of the authorization groups that a lifecycle event relates to.
+// A renamed group relates to both its old and new names.
+func lifecycleEventGroups(event api.Event) []string {
+	if event.Type != api.EventTypeLifecycle {
+		return nil
+	}
+
+	lifecycleEvent := api.EventLifecycle{}
+	err := json.Unmarshal(event.Metadata, &lifecycleEvent)
+	if err != nil {
+		return nil
+	}
+
+	u, err := url.Parse(lifecycleEvent.Source)
+	if err != nil {
+		return nil
+	}
+
+	entityType, _, _, pathArguments, err := entity.ParseURL(*u)
+	if err != nil || entityType != entity.TypeAuthGroup || len(pathArguments) == 0 {
+		return nil
+	}
+
+	groups := []string{pathArguments[0]}
+
+	oldName, ok := lifecycleEvent.Context["old_name"].(string)
+	if ok {
+		groups = append(groups, oldName)
+	}
+
+	return groups
 }
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/canonical/lxd/lxd/db"
+	storagePools "github.com/canonical/lxd/lxd/storage"
+	"github.com/canonical/lxd/lxd/util"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+)
+
+// healthCheckDatabaseTimeout is the maximum amount of time that the database check of the health endpoints may take.
+const healthCheckDatabaseTimeout = 2 * time.Second
+
+// healthHandler returns the handler of the unauthenticated health endpoints. They are meant to be used by load
+// balancers, and so respond with "200 OK" only if all checks pass and "503 Service Unavailable" otherwise. The body
+// only lists the outcome of each check, without any details about the failures, which are logged instead.
+//
+// The `/healthz` endpoint checks that the daemon has started, that the global database can be queried and, if
+// `core.health_check_storage_pools` is enabled, that the storage pools are mounted. The `/readyz` endpoint also
+// fails once the daemon has started shutting down, so that load balancers stop sending it new requests.
+func healthHandler(d *Daemon, checkShutdown bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		health := api.ServerHealth{Status: api.ServerHealthStatusPass}
+		runCheck := func(name string, check func() error) {
+			start := time.Now()
+			err := check()
+			result := api.ServerHealthCheck{
+				Name:      name,
+				Status:    api.ServerHealthStatusPass,
+				LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+			}
+
+			if err != nil {
+				logger.Debug("Health check failed", logger.Ctx{"check": name, "err": err})
+				result.Status = api.ServerHealthStatusFail
+				health.Status = api.ServerHealthStatusFail
+			}
+
+			health.Checks = append(health.Checks, result)
+		}
+
+		runCheck("startup", func() error {
+			if d.waitReady.Err() == nil {
+				return errors.New("Daemon is still starting")
+			}
+
+			return nil
+		})
+
+		// The database and storage pools aren't set up until the daemon has started.
+		if health.Status == api.ServerHealthStatusPass {
+			s := d.State()
+
+			runCheck("database", func() error {
+				ctx, cancel := context.WithTimeout(r.Context(), healthCheckDatabaseTimeout)
+				defer cancel()
+
+				return s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+					var one int
+					return tx.Tx().QueryRowContext(ctx, "SELECT 1").Scan(&one)
+				})
+			})
+
+			if s.GlobalConfig.HealthCheckStoragePools() {
+				runCheck("storage", func() error {
+					if !storagePools.AllAvailable() {
+						return errors.New("Storage pools are unavailable")
+					}
+
+					return nil
+				})
+			}
+		}
+
+		if checkShutdown {
+			runCheck("shutdown", func() error {
+				return d.shutdownCtx.Err()
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if health.Status == api.ServerHealthStatusPass {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		if r.Method == http.MethodHead {
+			return
+		}
+
+		_ = util.WriteJSON(w, health, nil)
+	}
+}
@@ -3,11 +3,18 @@ package main
 import (
 	"context"
 	"crypto/x509"
+	"database/sql"
 	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
 
@@ -16,14 +23,19 @@ import (
 	"github.com/canonical/lxd/lxd/cluster"
 	"github.com/canonical/lxd/lxd/db"
 	dbCluster "github.com/canonical/lxd/lxd/db/cluster"
+	"github.com/canonical/lxd/lxd/db/warningtype"
 	"github.com/canonical/lxd/lxd/identity"
 	"github.com/canonical/lxd/lxd/lifecycle"
 	"github.com/canonical/lxd/lxd/request"
 	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/lxd/state"
+	"github.com/canonical/lxd/lxd/task"
 	"github.com/canonical/lxd/lxd/util"
+	"github.com/canonical/lxd/lxd/warnings"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/entity"
+	"github.com/canonical/lxd/shared/filter"
 	"github.com/canonical/lxd/shared/logger"
 )
 
@@ -45,6 +57,15 @@ var identitiesByAuthenticationMethodCmd = APIEndpoint{
 	},
 }
 
+var identitySelfPermissionsCmd = APIEndpoint{
+	Name: "identitySelfPermissions",
+	Path: "auth/self/permissions",
+	Get: APIEndpointAction{
+		Handler:       getIdentitySelfPermissions,
+		AccessHandler: allowAuthenticated,
+	},
+}
+
 var identityCmd = APIEndpoint{
 	Name: "identity",
 	Path: "auth/identities/{authenticationMethod}/{nameOrIdentifier}",
@@ -109,6 +130,78 @@ func identityAccessHandler(entitlement auth.Entitlement) func(d *Daemon, r *http
 	}
 }
 
+// swagger:operation GET /1.0/auth/self/permissions identities identity_self_permissions_get
+//
+//	Get the permissions of the caller
+//
+//	Returns the effective permissions of the calling identity. These are the union of the permissions of its groups,
+//	including the permissions inherited from roles and ancestor groups. Callers that aren't restricted are reported
+//	with the admin entitlement on the server.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: entity
+//	    description: Only return the permissions that apply to the entity with this URL
+//	    type: string
+//	    example: /1.0/instances/c1?project=default
+//	responses:
+//	  "200":
+//	    description: Permissions
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of permissions
+//	          items:
+//	            $ref: "#/definitions/Permission"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func getIdentitySelfPermissions(d *Daemon, r *http.Request) response.Response {
+	var entityURL *api.URL
+	entityParam := request.QueryParam(r, "entity")
+	if entityParam != "" {
+		u, err := url.Parse(entityParam)
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Invalid entity URL %q: %w", entityParam, err))
+		}
+
+		_, _, _, _, err = entity.ParseURL(*u)
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Invalid entity URL %q: %w", entityParam, err))
+		}
+
+		entityURL = &api.URL{URL: *u}
+	}
+
+	permissions, err := d.State().Authorizer.GetPermissions(r.Context(), r, entityURL)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, permissions)
+}
+
 // swagger:operation GET /1.0/auth/identities identities identities_get
 //
 //	Get the identities
@@ -118,6 +211,22 @@ func identityAccessHandler(entitlement auth.Entitlement) func(d *Daemon, r *http
 //	---
 //	produces:
 //	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: filter
+//	    description: Collection filter
+//	    type: string
+//	    example: type eq client-certificate
+//	  - in: query
+//	    name: limit
+//	    description: Maximum number of identities to return
+//	    type: integer
+//	    example: 100
+//	  - in: query
+//	    name: offset
+//	    description: Number of identities to skip
+//	    type: integer
+//	    example: 0
 //	responses:
 //	  "200":
 //	    description: API endpoints
@@ -161,6 +270,22 @@ func identityAccessHandler(entitlement auth.Entitlement) func(d *Daemon, r *http
 //	---
 //	produces:
 //	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: filter
+//	    description: Collection filter
+//	    type: string
+//	    example: type eq client-certificate
+//	  - in: query
+//	    name: limit
+//	    description: Maximum number of identities to return
+//	    type: integer
+//	    example: 100
+//	  - in: query
+//	    name: offset
+//	    description: Number of identities to skip
+//	    type: integer
+//	    example: 0
 //	responses:
 //	  "200":
 //	    description: API endpoints
@@ -199,6 +324,22 @@ func identityAccessHandler(entitlement auth.Entitlement) func(d *Daemon, r *http
 //	---
 //	produces:
 //	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: filter
+//	    description: Collection filter
+//	    type: string
+//	    example: type eq client-certificate
+//	  - in: query
+//	    name: limit
+//	    description: Maximum number of identities to return
+//	    type: integer
+//	    example: 100
+//	  - in: query
+//	    name: offset
+//	    description: Number of identities to skip
+//	    type: integer
+//	    example: 0
 //	responses:
 //	  "200":
 //	    description: API endpoints
@@ -237,6 +378,22 @@ func identityAccessHandler(entitlement auth.Entitlement) func(d *Daemon, r *http
 //	---
 //	produces:
 //	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: filter
+//	    description: Collection filter
+//	    type: string
+//	    example: type eq client-certificate
+//	  - in: query
+//	    name: limit
+//	    description: Maximum number of identities to return
+//	    type: integer
+//	    example: 100
+//	  - in: query
+//	    name: offset
+//	    description: Number of identities to skip
+//	    type: integer
+//	    example: 0
 //	responses:
 //	  "200":
 //	    description: API endpoints
@@ -280,6 +437,22 @@ func identityAccessHandler(entitlement auth.Entitlement) func(d *Daemon, r *http
 //	---
 //	produces:
 //	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: filter
+//	    description: Collection filter
+//	    type: string
+//	    example: type eq client-certificate
+//	  - in: query
+//	    name: limit
+//	    description: Maximum number of identities to return
+//	    type: integer
+//	    example: 100
+//	  - in: query
+//	    name: offset
+//	    description: Number of identities to skip
+//	    type: integer
+//	    example: 0
 //	responses:
 //	  "200":
 //	    description: API endpoints
@@ -318,6 +491,22 @@ func identityAccessHandler(entitlement auth.Entitlement) func(d *Daemon, r *http
 //	---
 //	produces:
 //	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: filter
+//	    description: Collection filter
+//	    type: string
+//	    example: type eq client-certificate
+//	  - in: query
+//	    name: limit
+//	    description: Maximum number of identities to return
+//	    type: integer
+//	    example: 100
+//	  - in: query
+//	    name: offset
+//	    description: Number of identities to skip
+//	    type: integer
+//	    example: 0
 //	responses:
 //	  "200":
 //	    description: API endpoints
@@ -360,6 +549,22 @@ func getIdentities(d *Daemon, r *http.Request) response.Response {
 	}
 
 	recursion := r.URL.Query().Get("recursion")
+
+	clauses, err := filter.Parse(request.QueryParam(r, "filter"), filter.QueryOperatorSet())
+	if err != nil {
+		return response.BadRequest(fmt.Errorf("Invalid filter: %w", err))
+	}
+
+	limit, err := permissionsQueryInt(r, "limit")
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	offset, err := permissionsQueryInt(r, "offset")
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	s := d.State()
 	hasPermission, err := s.Authorizer.GetPermissionChecker(r.Context(), r, auth.EntitlementCanView, entity.TypeIdentity)
 	if err != nil {
@@ -368,6 +573,7 @@ func getIdentities(d *Daemon, r *http.Request) response.Response {
 
 	var identities []dbCluster.Identity
 	var groupsByIdentityID map[int][]dbCluster.AuthGroup
+	var disabledByIdentityID map[int]dbCluster.IdentityDisabled
 	var apiIdentityInfo *api.IdentityInfo
 	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
 		// Get all identities, filter by authentication method if present.
@@ -382,11 +588,43 @@ func getIdentities(d *Daemon, r *http.Request) response.Response {
 			return err
 		}
 
-		// Filter results by what the user is allowed to view.
+		disabledByIdentityID, err = dbCluster.GetAllIdentitiesDisabled(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		// Filter results by what the user is allowed to view and by the filter clauses.
 		for _, id := range allIdentities {
-			if hasPermission(entity.IdentityURL(string(id.AuthMethod), id.Identifier)) {
-				identities = append(identities, id)
+			if !hasPermission(entity.IdentityURL(string(id.AuthMethod), id.Identifier)) {
+				continue
+			}
+
+			if len(clauses.Clauses) > 0 {
+				match, err := filter.Match(identityToAPI(id, disabledByIdentityID), *clauses)
+				if err != nil {
+					return api.StatusErrorf(http.StatusBadRequest, "Failed to filter identities: %v", err)
+				}
+
+				if !match {
+					continue
+				}
 			}
+
+			identities = append(identities, id)
+		}
+
+		// Apply pagination, ordering by ID so that pages are stable.
+		sort.Slice(identities, func(i, j int) bool {
+			return identities[i].ID < identities[j].ID
+		})
+
+		if offset > len(identities) {
+			offset = len(identities)
+		}
+
+		identities = identities[offset:]
+		if limit > 0 && limit < len(identities) {
+			identities = identities[:limit]
 		}
 
 		if len(identities) == 0 {
@@ -416,18 +654,14 @@ func getIdentities(d *Daemon, r *http.Request) response.Response {
 
 	// Optimisation for user that can only view themselves.
 	if apiIdentityInfo != nil {
+		apiIdentityInfo.EffectiveGroups = d.identityCache.GetEffectiveGroups(apiIdentityInfo.AuthenticationMethod, apiIdentityInfo.Identifier, apiIdentityInfo.Groups)
 		return response.SyncResponse(true, []api.IdentityInfo{*apiIdentityInfo})
 	}
 
 	if recursion == "1" {
 		apiIdentities := make([]api.Identity, 0, len(identities))
 		for _, id := range identities {
-			apiIdentities = append(apiIdentities, api.Identity{
-				AuthenticationMethod: string(id.AuthMethod),
-				Type:                 string(id.Type),
-				Identifier:           id.Identifier,
-				Name:                 id.Name,
-			})
+			apiIdentities = append(apiIdentities, identityToAPI(id, disabledByIdentityID))
 		}
 
 		return response.SyncResponse(true, apiIdentities)
@@ -444,16 +678,15 @@ func getIdentities(d *Daemon, r *http.Request) response.Response {
 
 		apiIdentityInfos := make([]api.IdentityInfo, 0, len(identities))
 		for _, id := range identities {
+			_, disabled := disabledByIdentityID[id.ID]
+			enabled := !disabled
 			apiIdentityInfos = append(apiIdentityInfos, api.IdentityInfo{
-				Identity: api.Identity{
-					AuthenticationMethod: string(id.AuthMethod),
-					Type:                 string(id.Type),
-					Identifier:           id.Identifier,
-					Name:                 id.Name,
-				},
+				Identity: identityToAPI(id, disabledByIdentityID),
 				IdentityPut: api.IdentityPut{
-					Groups: groupNamesByIdentityID[id.ID],
+					Groups:  groupNamesByIdentityID[id.ID],
+					Enabled: &enabled,
 				},
+				EffectiveGroups: d.identityCache.GetEffectiveGroups(string(id.AuthMethod), id.Identifier, groupNamesByIdentityID[id.ID]),
 			})
 		}
 
@@ -468,6 +701,25 @@ func getIdentities(d *Daemon, r *http.Request) response.Response {
 	return response.SyncResponse(true, urls)
 }
 
+// identityToAPI converts an identity to an api.Identity, including when and by whom it was disabled if it is in the
+// given map of disabled identities.
+func identityToAPI(id dbCluster.Identity, disabledByIdentityID map[int]dbCluster.IdentityDisabled) api.Identity {
+	apiIdentity := api.Identity{
+		AuthenticationMethod: string(id.AuthMethod),
+		Type:                 string(id.Type),
+		Identifier:           id.Identifier,
+		Name:                 id.Name,
+	}
+
+	disabled, ok := disabledByIdentityID[id.ID]
+	if ok {
+		apiIdentity.DisabledAt = &disabled.At
+		apiIdentity.DisabledBy = disabled.By
+	}
+
+	return apiIdentity
+}
+
 // swagger:operation GET /1.0/auth/identities/{authenticationMethod}/{nameOrIdentifier} identities identity_get
 //
 //	Get the identity
@@ -522,7 +774,11 @@ func getIdentity(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
-	return response.SyncResponseETag(true, apiIdentityInfo, apiIdentityInfo)
+	// The ETag is computed without the effective groups, as these cannot be updated.
+	etag := *apiIdentityInfo
+	apiIdentityInfo.EffectiveGroups = d.identityCache.GetEffectiveGroups(apiIdentityInfo.AuthenticationMethod, apiIdentityInfo.Identifier, apiIdentityInfo.Groups)
+
+	return response.SyncResponseETag(true, apiIdentityInfo, etag)
 }
 
 // swagger:operation PUT /1.0/auth/identities/{authenticationMethod}/{nameOrIdentifier} identities identity_put
@@ -537,6 +793,11 @@ func getIdentity(d *Daemon, r *http.Request) response.Response {
 //	produces:
 //	  - application/json
 //	parameters:
+//	  - in: query
+//	    name: force
+//	    description: Disable the identity even if it is the last server administrator
+//	    type: integer
+//	    example: 1
 //	  - in: body
 //	    name: identity
 //	    description: Update request
@@ -567,8 +828,10 @@ func updateIdentity(d *Daemon, r *http.Request) response.Response {
 		return response.NotImplemented(fmt.Errorf("Adding TLS identities to groups is currently not supported"))
 	}
 
+	force := request.QueryParam(r, "force") == "1"
+
 	s := d.State()
-	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+	err = s.DB.Cluster.TransactionRetry(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
 		apiIdentityInfo, err := id.ToAPIInfo(ctx, tx.Tx())
 		if err != nil {
 			return err
@@ -584,32 +847,24 @@ func updateIdentity(d *Daemon, r *http.Request) response.Response {
 			return err
 		}
 
-		return nil
-	})
-	if err != nil {
-		return response.SmartError(err)
-	}
-
-	// Notify other cluster members to update their identity cache.
-	notifier, err := cluster.NewNotifier(s, s.Endpoints.NetworkCert(), s.ServerCert(), cluster.NotifyAlive)
-	if err != nil {
-		return response.SmartError(err)
-	}
+		err = identitySetEnabled(ctx, tx.Tx(), s, r, id, *apiIdentityInfo.Enabled, identityPut.Enabled, force)
+		if err != nil {
+			return err
+		}
 
-	err = notifier(func(client lxd.InstanceServer) error {
-		_, _, err := client.RawQuery(http.MethodPost, "/internal/identity-cache-refresh", nil, "")
-		return err
+		return authAuditIdentityUpdated(ctx, tx.Tx(), request.CreateRequestor(r), id, apiIdentityInfo)
 	})
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	// Update the identity cache of all cluster members.
+	notifyIdentityCacheRefresh(s)
+
 	// Send a lifecycle event for the identity update.
 	lc := lifecycle.IdentityUpdated.Event(string(id.AuthMethod), id.Identifier, request.CreateRequestor(r), nil)
 	s.Events.SendLifecycle(api.ProjectDefaultName, lc)
 
-	s.UpdateIdentityCache()
-
 	return response.EmptySyncResponse
 }
 
@@ -625,6 +880,11 @@ func updateIdentity(d *Daemon, r *http.Request) response.Response {
 //	produces:
 //	  - application/json
 //	parameters:
+//	  - in: query
+//	    name: force
+//	    description: Disable the identity even if it is the last server administrator
+//	    type: integer
+//	    example: 1
 //	  - in: body
 //	    name: identity
 //	    description: Update request
@@ -656,9 +916,11 @@ func patchIdentity(d *Daemon, r *http.Request) response.Response {
 		return response.NotImplemented(fmt.Errorf("Adding TLS identities to groups is currently not supported"))
 	}
 
+	force := request.QueryParam(r, "force") == "1"
+
 	s := d.State()
 	var apiIdentityInfo *api.IdentityInfo
-	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+	err = s.DB.Cluster.TransactionRetry(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
 		apiIdentityInfo, err = id.ToAPIInfo(ctx, tx.Tx())
 		if err != nil {
 			return err
@@ -669,44 +931,219 @@ func patchIdentity(d *Daemon, r *http.Request) response.Response {
 			return err
 		}
 
+		before := *apiIdentityInfo
 		for _, groupName := range identityPut.Groups {
 			if !shared.ValueInSlice(groupName, apiIdentityInfo.Groups) {
 				apiIdentityInfo.Groups = append(apiIdentityInfo.Groups, groupName)
 			}
 		}
 
-		err = dbCluster.SetIdentityAuthGroups(ctx, tx.Tx(), id.ID, identityPut.Groups)
+		err = dbCluster.SetIdentityAuthGroups(ctx, tx.Tx(), id.ID, apiIdentityInfo.Groups)
 		if err != nil {
 			return err
 		}
 
-		return nil
+		err = identitySetEnabled(ctx, tx.Tx(), s, r, id, *apiIdentityInfo.Enabled, identityPut.Enabled, force)
+		if err != nil {
+			return err
+		}
+
+		return authAuditIdentityUpdated(ctx, tx.Tx(), request.CreateRequestor(r), id, &before)
 	})
 	if err != nil {
 		return response.SmartError(err)
 	}
 
-	// Notify other cluster members to update their identity cache.
-	notifier, err := cluster.NewNotifier(s, s.Endpoints.NetworkCert(), s.ServerCert(), cluster.NotifyAlive)
+	// Update the identity cache of all cluster members.
+	notifyIdentityCacheRefresh(s)
+
+	// Send a lifecycle event for the identity update.
+	lc := lifecycle.IdentityUpdated.Event(string(id.AuthMethod), id.Identifier, request.CreateRequestor(r), nil)
+	s.Events.SendLifecycle(api.ProjectDefaultName, lc)
+
+	return response.EmptySyncResponse
+}
+
+// identitySetEnabled enables or disables the identity if the requested enabled state differs from the current one.
+// This requires the can_disable entitlement on the identity. Cluster member identities can't be disabled. Unless
+// force is set, disabling the identity fails if it would leave no remote identity with the admin entitlement on the
+// server.
+func identitySetEnabled(ctx context.Context, tx *sql.Tx, s *state.State, r *http.Request, id *dbCluster.Identity, current bool, enabled *bool, force bool) error {
+	if enabled == nil || *enabled == current {
+		return nil
+	}
+
+	err := s.Authorizer.CheckPermission(r.Context(), r, entity.IdentityURL(string(id.AuthMethod), id.Identifier), auth.EntitlementCanDisable)
 	if err != nil {
-		return response.SmartError(err)
+		return err
+	}
+
+	if id.Type == api.IdentityTypeCertificateServer {
+		return api.StatusErrorf(http.StatusBadRequest, "Identities of cluster members cannot be disabled")
 	}
 
-	err = notifier(func(client lxd.InstanceServer) error {
-		_, _, err := client.RawQuery(http.MethodPost, "/internal/identity-cache-refresh", nil, "")
+	adminBefore := false
+	if !*enabled && !force {
+		adminBefore, err = authServerAdminExists(ctx, tx)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = dbCluster.SetIdentityEnabled(ctx, tx, id.ID, *enabled, request.CreateRequestor(r).Username)
+	if err != nil {
 		return err
+	}
+
+	return authGroupLockoutCheck(ctx, tx, adminBefore)
+}
+
+// Parameters of the retries of identity cache refresh notifications.
+const (
+	identityCacheNotifyAttempts    = 3
+	identityCacheNotifyDelay       = 500 * time.Millisecond
+	identityCacheReconcileInterval = time.Minute
+)
+
+// identityCacheReconcile tracks the background retries of identity cache refresh notifications.
+var identityCacheReconcile struct {
+	mu      sync.Mutex
+	running bool
+	pending bool
+}
+
+// notifyIdentityCacheRefresh updates the identity cache of all cluster members, including this one.
+//
+// The database is authoritative, so notifying other members is best-effort: it is retried a few times with backoff,
+// after which a warning is raised and the notification is retried in the background until it succeeds. Until then,
+// members that could not be notified may keep granting access based on the previous state.
+func notifyIdentityCacheRefresh(s *state.State) {
+	s.UpdateIdentityCache()
+
+	// Changes to identities and groups may change which groups grant administrative access broadly.
+	err := checkAuthGroupsBroadAdminAccess(s.ShutdownCtx, s)
+	if err != nil {
+		logger.Warn("Failed checking authorization groups for broad administrative access", logger.Ctx{"err": err})
+	}
+
+	// Changes to identities and groups may also change which groups grant permissions outside of the projects of
+	// their restricted identities.
+	err = checkAuthGroupsConfinedProjects(s.ShutdownCtx, s)
+	if err != nil {
+		logger.Warn("Failed checking authorization groups for permissions outside of the projects of restricted identities", logger.Ctx{"err": err})
+	}
+
+	delay := identityCacheNotifyDelay
+	for attempt := 1; ; attempt++ {
+		err = notifyIdentityCacheRefreshMembers(s)
+		if err == nil {
+			return
+		}
+
+		if attempt == identityCacheNotifyAttempts {
+			break
+		}
+
+		logger.Debug("Failed notifying cluster members to refresh their identity cache, retrying", logger.Ctx{"attempt": attempt, "delay": delay, "err": err})
+
+		select {
+		case <-s.ShutdownCtx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+	}
+
+	logger.Warn("Failed notifying cluster members to refresh their identity cache, retrying in the background", logger.Ctx{"err": err})
+	identityCacheRefreshFailed(s, err)
+	identityCacheReconcileStart(s)
+}
+
+// notifyIdentityCacheRefreshMembers notifies all other alive cluster members to update their identity cache.
+func notifyIdentityCacheRefreshMembers(s *state.State) error {
+	notifier, err := cluster.NewNotifier(s, s.Endpoints.NetworkCert(), s.ServerCert(), cluster.NotifyAlive)
+	if err != nil {
+		return err
+	}
+
+	return notifier(identityCacheRefreshMember)
+}
+
+// identityCacheRefreshMember asks the cluster member that the client is connected to to refresh its identity cache.
+// The endpoint is internal to the cluster, and like the other internal endpoints it isn't part of the client package,
+// so it is queried directly.
+func identityCacheRefreshMember(client lxd.InstanceServer) error {
+	_, _, err := client.RawQuery(http.MethodPost, "/internal/identity-cache-refresh", nil, "")
+	return err
+}
+
+// identityCacheRefreshFailed raises a warning on this cluster member that other members could not be notified to
+// refresh their identity cache.
+func identityCacheRefreshFailed(s *state.State, notifyErr error) {
+	err := s.DB.Cluster.Transaction(s.ShutdownCtx, func(ctx context.Context, tx *db.ClusterTx) error {
+		return tx.UpsertWarningLocalNode(ctx, "", "", -1, warningtype.IdentityCacheRefreshFailed, notifyErr.Error())
 	})
 	if err != nil {
-		return response.SmartError(err)
+		logger.Warn("Failed to create warning", logger.Ctx{"err": err})
 	}
+}
 
-	// Send a lifecycle event for the identity update.
-	lc := lifecycle.IdentityUpdated.Event(string(id.AuthMethod), id.Identifier, request.CreateRequestor(r), nil)
-	s.Events.SendLifecycle(api.ProjectDefaultName, lc)
+// identityCacheReconcileStart starts retrying to notify cluster members to refresh their identity cache in the
+// background, unless this is already happening. Retries continue until a notification succeeds after the most recent
+// failure, at which point the warning raised by identityCacheRefreshFailed is resolved.
+func identityCacheReconcileStart(s *state.State) {
+	identityCacheReconcile.mu.Lock()
+	defer identityCacheReconcile.mu.Unlock()
 
-	s.UpdateIdentityCache()
+	identityCacheReconcile.pending = true
+	if identityCacheReconcile.running {
+		return
+	}
 
-	return response.EmptySyncResponse
+	identityCacheReconcile.running = true
+
+	go func() {
+		for {
+			select {
+			case <-s.ShutdownCtx.Done():
+				identityCacheReconcile.mu.Lock()
+				identityCacheReconcile.running = false
+				identityCacheReconcile.mu.Unlock()
+				return
+			case <-time.After(identityCacheReconcileInterval):
+			}
+
+			identityCacheReconcile.mu.Lock()
+			identityCacheReconcile.pending = false
+			identityCacheReconcile.mu.Unlock()
+
+			err := notifyIdentityCacheRefreshMembers(s)
+			if err != nil {
+				logger.Warn("Failed notifying cluster members to refresh their identity cache, retrying in the background", logger.Ctx{"err": err})
+				identityCacheRefreshFailed(s, err)
+				continue
+			}
+
+			err = warnings.ResolveWarningsByLocalNodeAndType(s.DB.Cluster, warningtype.IdentityCacheRefreshFailed)
+			if err != nil {
+				logger.Warn("Failed to resolve warning", logger.Ctx{"err": err})
+			}
+
+			identityCacheReconcile.mu.Lock()
+			if identityCacheReconcile.pending {
+				// Another notification failed while this one was in progress.
+				identityCacheReconcile.mu.Unlock()
+				continue
+			}
+
+			identityCacheReconcile.running = false
+			identityCacheReconcile.mu.Unlock()
+
+			logger.Info("Notified cluster members to refresh their identity cache")
+			return
+		}
+	}()
 }
 
 // updateIdentityCache reads all identities from the database and sets them in the identity.Cache.
@@ -722,6 +1159,8 @@ func updateIdentityCache(d *Daemon) {
 	projects := make(map[int][]string)
 	groups := make(map[int][]string)
 	idpGroupMapping := make(map[string][]string)
+	var groupPermissions map[string][]api.Permission
+	var disabled map[int]dbCluster.IdentityDisabled
 	var err error
 	err = s.DB.Cluster.Transaction(d.shutdownCtx, func(ctx context.Context, tx *db.ClusterTx) error {
 		identities, err = dbCluster.GetIdentitys(ctx, tx.Tx())
@@ -729,6 +1168,11 @@ func updateIdentityCache(d *Daemon) {
 			return err
 		}
 
+		disabled, err = dbCluster.GetAllIdentitiesDisabled(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
 		for _, identity := range identities {
 			identityProjects, err := dbCluster.GetIdentityProjects(ctx, tx.Tx(), identity.ID)
 			if err != nil {
@@ -763,6 +1207,11 @@ func updateIdentityCache(d *Daemon) {
 			idpGroupMapping[apiIDPGroup.Name] = apiIDPGroup.Groups
 		}
 
+		groupPermissions, err = getAuthGroupsPermissions(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -782,6 +1231,8 @@ func updateIdentityCache(d *Daemon) {
 			Groups:               groups[id.ID],
 		}
 
+		_, cacheEntry.Disabled = disabled[id.ID]
+
 		if cacheEntry.AuthenticationMethod == api.AuthenticationMethodTLS {
 			cert, err := id.X509()
 			if err != nil {
@@ -798,6 +1249,12 @@ func updateIdentityCache(d *Daemon) {
 			}
 
 			cacheEntry.Subject = subject
+
+			cacheEntry.IdentityProviderGroups, err = id.IdentityProviderGroups()
+			if err != nil {
+				logger.Warn("Failed to extract identity provider groups from OIDC identity metadata", logger.Ctx{"error": err})
+				continue
+			}
 		}
 
 		identityCacheEntries = append(identityCacheEntries, cacheEntry)
@@ -823,12 +1280,171 @@ func updateIdentityCache(d *Daemon) {
 		// continue functioning, and hopefully the write will succeed on next update.
 	}
 
-	err = d.identityCache.ReplaceAll(identityCacheEntries, idpGroupMapping)
+	err = d.identityCache.ReplaceAll(identityCacheEntries, idpGroupMapping, groupPermissions)
 	if err != nil {
 		logger.Warn("Failed to update identity cache", logger.Ctx{"error": err})
 	}
 }
 
+// getAuthGroupsPermissions returns a map of group names to all the permissions that the group grants. These include
+// the permissions granted by the roles of the group and the permissions inherited from its ancestors. Permissions on
+// the instances of a cluster member are expanded to a permission on each instance currently located on the member.
+// Disabled groups grant no permissions, and their permissions aren't inherited by their descendants.
+func getAuthGroupsPermissions(ctx context.Context, tx *sql.Tx) (map[string][]api.Permission, error) {
+	groupPermissions := make(map[string][]api.Permission)
+
+	authGroups, err := dbCluster.GetAuthGroups(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	permissionsByGroupID, err := dbCluster.GetAllPermissionsByAuthGroupIDs(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var allPermissions []dbCluster.Permission
+	for _, permissions := range permissionsByGroupID {
+		allPermissions = append(allPermissions, permissions...)
+	}
+
+	entityURLs, err := dbCluster.GetPermissionEntityURLs(ctx, tx, allPermissions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Instance URLs by cluster member name, for resolving permissions on the instances of a cluster member.
+	memberInstanceURLs := make(map[string][]*api.URL)
+
+	for _, group := range authGroups {
+		for _, permission := range permissionsByGroupID[group.ID] {
+			u, ok := entityURLs[entity.Type(permission.EntityType)][permission.EntityID]
+			if !ok {
+				continue
+			}
+
+			groupPermissions[group.Name] = append(groupPermissions[group.Name], permission.ToAPI(u))
+
+			if permission.EntityType != dbCluster.EntityType(entity.TypeNode) || permission.SubtreeEntityType != entity.TypeInstance {
+				continue
+			}
+
+			// Grant the entitlement on each instance that is currently located on the cluster member.
+			memberName := path.Base(u.URL.Path)
+			instanceURLs, ok := memberInstanceURLs[memberName]
+			if !ok {
+				instances, err := dbCluster.GetInstances(ctx, tx, dbCluster.InstanceFilter{Node: &memberName})
+				if err != nil {
+					return nil, fmt.Errorf("Failed to get instances of cluster member %q: %w", memberName, err)
+				}
+
+				instanceURLs = make([]*api.URL, 0, len(instances))
+				for _, inst := range instances {
+					instanceURLs = append(instanceURLs, entity.InstanceURL(inst.Project, inst.Name))
+				}
+
+				memberInstanceURLs[memberName] = instanceURLs
+			}
+
+			for _, instanceURL := range instanceURLs {
+				groupPermissions[group.Name] = append(groupPermissions[group.Name], api.Permission{
+					EntityType:      string(entity.TypeInstance),
+					EntityReference: instanceURL.String(),
+					Entitlement:     string(permission.Entitlement),
+				})
+			}
+		}
+	}
+
+	// Roles expand to a permission for each of their entitlements on the entity that they are granted on.
+	roles, err := dbCluster.GetAuthRoles(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	roleEntitlements := make(map[int][]auth.Entitlement, len(roles))
+	for _, role := range roles {
+		roleEntitlements[role.ID] = role.Entitlements
+	}
+
+	rolesByGroupID, err := dbCluster.GetAllAuthGroupRolesByGroupIDs(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var allGroupRoles []dbCluster.AuthGroupRole
+	for _, groupRoles := range rolesByGroupID {
+		allGroupRoles = append(allGroupRoles, groupRoles...)
+	}
+
+	roleEntityURLs, err := dbCluster.GetAuthGroupRoleEntityURLs(ctx, tx, allGroupRoles)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range authGroups {
+		for _, role := range rolesByGroupID[group.ID] {
+			u, ok := roleEntityURLs[entity.Type(role.EntityType)][role.EntityID]
+			if !ok {
+				continue
+			}
+
+			for _, entitlement := range roleEntitlements[role.RoleID] {
+				groupPermissions[group.Name] = append(groupPermissions[group.Name], api.Permission{
+					EntityType:      string(role.EntityType),
+					EntityReference: u.String(),
+					Entitlement:     string(entitlement),
+				})
+			}
+		}
+	}
+
+	// Groups inherit the permissions of their ancestors, so add those to the permissions of each group.
+	parentIDsByGroupID, err := dbCluster.GetAllAuthGroupParentIDsByGroupIDs(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	groupsEnabled, err := dbCluster.GetAllAuthGroupsEnabled(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	groupsTemplate, err := dbCluster.GetAllAuthGroupsTemplate(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Disabled and template groups grant nothing, neither to their members nor to their child groups.
+	groupNames := make(map[int]string, len(authGroups))
+	directGroupPermissions := make(map[string][]api.Permission, len(groupPermissions))
+	for _, group := range authGroups {
+		groupNames[group.ID] = group.Name
+		if !groupsEnabled[group.ID] || groupsTemplate[group.ID] {
+			delete(groupPermissions, group.Name)
+			continue
+		}
+
+		directGroupPermissions[group.Name] = groupPermissions[group.Name]
+	}
+
+	for _, group := range authGroups {
+		if !groupsEnabled[group.ID] || groupsTemplate[group.ID] {
+			continue
+		}
+
+		for _, ancestorID := range dbCluster.AuthGroupAncestorIDs(parentIDsByGroupID, group.ID) {
+			if ancestorID == group.ID {
+				continue
+			}
+
+			groupPermissions[group.Name] = append(groupPermissions[group.Name], directGroupPermissions[groupNames[ancestorID]]...)
+		}
+	}
+
+	return groupPermissions, nil
+}
+
 // updateIdentityCacheFromLocal loads trusted server certificates from local database into the identity cache.
 func updateIdentityCacheFromLocal(d *Daemon) error {
 	logger.Debug("Refreshing identity cache with local trusted certificates")
@@ -872,10 +1488,152 @@ func updateIdentityCacheFromLocal(d *Daemon) error {
 		})
 	}
 
-	err = d.identityCache.ReplaceAll(identityCacheEntries, nil)
+	err = d.identityCache.ReplaceAll(identityCacheEntries, nil, nil)
 	if err != nil {
 		return fmt.Errorf("Failed to update identity cache from local trust store: %w", err)
 	}
 
 	return nil
 }
+
+// identitiesWithoutGroupsMaxListed is the maximum number of identities listed in the warning raised by
+// checkIdentitiesWithoutGroups.
+const identitiesWithoutGroupsMaxListed = 20
+
+// checkIdentitiesWithoutGroupsTask raises a warning listing the identities that are not a member of any group while
+// auth.require_group_membership is enabled. It only runs on the leader of a cluster.
+func checkIdentitiesWithoutGroupsTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		leader, err := d.gateway.LeaderAddress()
+		if err != nil && !errors.Is(err, cluster.ErrNodeIsNotClustered) {
+			logger.Error("Failed to get leader cluster member address", logger.Ctx{"err": err})
+			return
+		}
+
+		if err == nil && d.State().LocalConfig.ClusterAddress() != leader {
+			return
+		}
+
+		err = checkIdentitiesWithoutGroups(ctx, d.State(), d.identityCache)
+		if err != nil {
+			logger.Warn("Failed checking for identities that are not a member of any group", logger.Ctx{"err": err})
+		}
+	}
+
+	return f, task.Daily()
+}
+
+// checkIdentitiesWithoutGroups raises a warning listing the OIDC identities that are not a member of any group, either
+// directly or through their identity provider groups. The warning is resolved if there are none, or if group
+// membership is not required.
+func checkIdentitiesWithoutGroups(ctx context.Context, s *state.State, identityCache *identity.Cache) error {
+	warningType := warningtype.IdentitiesWithoutGroups
+
+	var identifiers []string
+	if s.GlobalConfig.AuthRequireGroupMembership() {
+		for identifier, entry := range identityCache.GetByAuthenticationMethod(api.AuthenticationMethodOIDC) {
+			if len(identityCache.GetEffectiveGroups(api.AuthenticationMethodOIDC, identifier, entry.Groups)) == 0 {
+				identifiers = append(identifiers, identifier)
+			}
+		}
+	}
+
+	if len(identifiers) == 0 {
+		return warnings.ResolveWarningsByNodeAndType(s.DB.Cluster, "", warningType)
+	}
+
+	sort.Strings(identifiers)
+
+	message := fmt.Sprintf("%d identities are not a member of any group: %s", len(identifiers), strings.Join(identifiers[:min(len(identifiers), identitiesWithoutGroupsMaxListed)], ", "))
+	if len(identifiers) > identitiesWithoutGroupsMaxListed {
+		message += fmt.Sprintf(" and %d more", len(identifiers)-identitiesWithoutGroupsMaxListed)
+	}
+
+	return s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+		return tx.UpsertWarning(ctx, "", "", "", -1, warningType, message)
+	})
+}
+
+// checkAuthGroupsConfinedProjects raises a warning for each group that grants permissions outside of the projects of
+// the restricted identities that are a member of it. Restricted identities are confined to their projects, so these
+// permissions are ignored by the authorizer. The warnings of groups that no longer do so are resolved.
+func checkAuthGroupsConfinedProjects(ctx context.Context, s *state.State) error {
+	warningType := warningtype.AuthGroupOutsideConfinedProjects
+
+	return s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+		groups, err := dbCluster.GetAuthGroups(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		identitiesByGroupID, err := dbCluster.GetAllIdentitiesByAuthGroupIDs(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		groupPermissions, err := getAuthGroupsPermissions(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		outsideGroupIDs := make(map[int]bool)
+		for _, group := range groups {
+			var identifiers []string
+			for _, id := range identitiesByGroupID[group.ID] {
+				isRestricted, err := identity.IsRestrictedIdentityType(string(id.Type))
+				if err != nil || !isRestricted {
+					continue
+				}
+
+				identityProjects, err := dbCluster.GetIdentityProjects(ctx, tx.Tx(), id.ID)
+				if err != nil {
+					return err
+				}
+
+				projectNames := make([]string, 0, len(identityProjects))
+				for _, p := range identityProjects {
+					projectNames = append(projectNames, p.Name)
+				}
+
+				for _, permission := range groupPermissions[group.Name] {
+					if !auth.PermissionInProjects(permission, projectNames) {
+						identifiers = append(identifiers, id.Identifier)
+						break
+					}
+				}
+			}
+
+			if len(identifiers) == 0 {
+				continue
+			}
+
+			sort.Strings(identifiers)
+
+			outsideGroupIDs[group.ID] = true
+			err = tx.UpsertWarning(ctx, "", "", entity.TypeAuthGroup, group.ID, warningType, fmt.Sprintf("Group %q grants permissions outside of the projects of restricted identities, which are ignored for: %s", group.Name, strings.Join(identifiers, ", ")))
+			if err != nil {
+				return err
+			}
+		}
+
+		// Resolve the warnings of groups that no longer grant permissions outside of the projects of their
+		// restricted identities, or no longer exist.
+		groupWarnings, err := dbCluster.GetWarnings(ctx, tx.Tx(), dbCluster.WarningFilter{TypeCode: &warningType})
+		if err != nil {
+			return err
+		}
+
+		for _, w := range groupWarnings {
+			if outsideGroupIDs[w.EntityID] || w.Status == warningtype.StatusResolved {
+				continue
+			}
+
+			err = tx.UpdateWarningStatus(w.UUID, warningtype.StatusResolved)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
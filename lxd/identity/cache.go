@@ -4,7 +4,9 @@ import (
 	"crypto/x509"
 	"fmt"
 	"net/http"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
@@ -18,7 +20,16 @@ type Cache struct {
 
 	// identityProviderGroups is a map of identity provider group name to slice of LXD group names.
 	identityProviderGroups map[string]*[]string
-	mu                     sync.RWMutex
+
+	// groupPermissions is a map of LXD group name to the permissions of the group, including the permissions that
+	// it inherits from its ancestors. The entity reference of each permission is the canonical URL of the entity.
+	groupPermissions map[string][]api.Permission
+	mu               sync.RWMutex
+
+	// groupsUsed is a map of LXD group name to the last time that a permission of the group granted access to a
+	// request, for groups whose usage has not been taken with TakeGroupsUsed yet. It is not replaced by ReplaceAll.
+	groupsUsed   map[string]time.Time
+	groupsUsedMu sync.Mutex
 }
 
 // CacheEntry represents an identity.
@@ -35,6 +46,13 @@ type CacheEntry struct {
 
 	// Subject is optional. It is only set when AuthenticationMethod is api.AuthenticationMethodOIDC.
 	Subject string
+
+	// IdentityProviderGroups is optional. It is only set when AuthenticationMethod is api.AuthenticationMethodOIDC
+	// and contains the identity provider groups that the identity last authenticated with.
+	IdentityProviderGroups []string
+
+	// Disabled is true if the identity has been disabled. Disabled identities must not be authenticated.
+	Disabled bool
 }
 
 // Get returns a single CacheEntry by its authentication method and identifier.
@@ -64,6 +82,20 @@ func (c *Cache) Get(authenticationMethod string, identifier string) (*CacheEntry
 	return &entryCopy, nil
 }
 
+// CheckEnabled returns an api.StatusError with http.StatusForbidden if the identity with the given authentication
+// method and identifier is disabled. Identities that are not in the cache are not considered disabled.
+func (c *Cache) CheckEnabled(authenticationMethod string, identifier string) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry := c.entries[authenticationMethod][identifier]
+	if entry != nil && entry.Disabled {
+		return api.StatusErrorf(http.StatusForbidden, "Identity %q (%s) is disabled", identifier, authenticationMethod)
+	}
+
+	return nil
+}
+
 // GetByType returns a map of identifier to CacheEntry, where all entries have the given identity type.
 func (c *Cache) GetByType(identityType string) map[string]CacheEntry {
 	c.mu.RLock()
@@ -108,8 +140,91 @@ func (c *Cache) GetByAuthenticationMethod(authenticationMethod string) map[strin
 	return entriesOfAuthMethodCopy
 }
 
-// ReplaceAll deletes all entries and identity provider groups from the cache and replaces them with the given values.
-func (c *Cache) ReplaceAll(entries []CacheEntry, idpGroups map[string][]string) error {
+// GetGroupPermissions returns a map of group name to the permissions of each of the groups with the given names.
+func (c *Cache) GetGroupPermissions(groupNames []string) map[string][]api.Permission {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	permissions := make(map[string][]api.Permission, len(groupNames))
+	for _, groupName := range groupNames {
+		permissions[groupName] = c.groupPermissions[groupName]
+	}
+
+	return permissions
+}
+
+// GetEffectiveGroups returns the effective groups of the identity with the given authentication method and
+// identifier, given the groups that it is a direct member of. The identity provider groups that the identity last
+// authenticated with are mapped to groups using the cached identity provider group mappings. The result is sorted by
+// group name.
+func (c *Cache) GetEffectiveGroups(authenticationMethod string, identifier string, groupNames []string) []api.IdentityEffectiveGroup {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	effectiveGroups := make(map[string]*api.IdentityEffectiveGroup, len(groupNames))
+	for _, groupName := range groupNames {
+		effectiveGroups[groupName] = &api.IdentityEffectiveGroup{Group: groupName, Direct: true, IdentityProviderGroups: []string{}}
+	}
+
+	entry := c.entries[authenticationMethod][identifier]
+	if entry != nil {
+		for _, idpGroupName := range entry.IdentityProviderGroups {
+			authGroupNames := c.identityProviderGroups[idpGroupName]
+			if authGroupNames == nil {
+				continue
+			}
+
+			for _, groupName := range *authGroupNames {
+				effectiveGroup, ok := effectiveGroups[groupName]
+				if !ok {
+					effectiveGroup = &api.IdentityEffectiveGroup{Group: groupName, IdentityProviderGroups: []string{}}
+					effectiveGroups[groupName] = effectiveGroup
+				}
+
+				effectiveGroup.IdentityProviderGroups = append(effectiveGroup.IdentityProviderGroups, idpGroupName)
+			}
+		}
+	}
+
+	result := make([]api.IdentityEffectiveGroup, 0, len(effectiveGroups))
+	for _, effectiveGroup := range effectiveGroups {
+		result = append(result, *effectiveGroup)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Group < result[j].Group
+	})
+
+	return result
+}
+
+// MarkGroupUsed records that a permission of the group with the given name has granted access to a request.
+func (c *Cache) MarkGroupUsed(groupName string) {
+	c.groupsUsedMu.Lock()
+	defer c.groupsUsedMu.Unlock()
+
+	if c.groupsUsed == nil {
+		c.groupsUsed = make(map[string]time.Time)
+	}
+
+	c.groupsUsed[groupName] = time.Now().UTC()
+}
+
+// TakeGroupsUsed returns a map of group name to the last time that the group was marked as used, for all groups that
+// have been marked as used since the previous call.
+func (c *Cache) TakeGroupsUsed() map[string]time.Time {
+	c.groupsUsedMu.Lock()
+	defer c.groupsUsedMu.Unlock()
+
+	groupsUsed := c.groupsUsed
+	c.groupsUsed = nil
+
+	return groupsUsed
+}
+
+// ReplaceAll deletes all entries, identity provider groups, and group permissions from the cache and replaces them
+// with the given values.
+func (c *Cache) ReplaceAll(entries []CacheEntry, idpGroups map[string][]string, groupPermissions map[string][]api.Permission) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -135,6 +250,13 @@ func (c *Cache) ReplaceAll(entries []CacheEntry, idpGroups map[string][]string)
 		c.identityProviderGroups[idpGroupName] = &authGroupNamesCopy
 	}
 
+	c.groupPermissions = make(map[string][]api.Permission, len(groupPermissions))
+	for groupName, permissions := range groupPermissions {
+		permissionsCopy := make([]api.Permission, 0, len(permissions))
+		permissionsCopy = append(permissionsCopy, permissions...)
+		c.groupPermissions[groupName] = permissionsCopy
+	}
+
 	return nil
 }
 
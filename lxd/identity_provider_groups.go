@@ -6,12 +6,12 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
+	"unicode"
 
 	"github.com/gorilla/mux"
 
-	"github.com/canonical/lxd/client"
 	"github.com/canonical/lxd/lxd/auth"
-	"github.com/canonical/lxd/lxd/cluster"
 	"github.com/canonical/lxd/lxd/db"
 	dbCluster "github.com/canonical/lxd/lxd/db/cluster"
 	"github.com/canonical/lxd/lxd/lifecycle"
@@ -61,6 +61,30 @@ var identityProviderGroupCmd = APIEndpoint{
 	},
 }
 
+// validateIdentityProviderGroupName checks that the name of an identity provider group can be emitted in the groups
+// claim of an identity provider, so that mappings that can never match are rejected when they are created.
+func validateIdentityProviderGroupName(name string) error {
+	if name == "" {
+		return api.StatusErrorf(http.StatusBadRequest, "Identity provider group name cannot be empty")
+	}
+
+	if strings.TrimSpace(name) != name {
+		return api.StatusErrorf(http.StatusBadRequest, "Identity provider group name cannot start or end with whitespace")
+	}
+
+	if strings.Contains(name, "/") {
+		return api.StatusErrorf(http.StatusBadRequest, "Identity provider group name cannot contain a forward slash")
+	}
+
+	for _, r := range name {
+		if r == unicode.ReplacementChar || !unicode.IsPrint(r) {
+			return api.StatusErrorf(http.StatusBadRequest, "Identity provider group name cannot contain non-printable characters")
+		}
+	}
+
+	return nil
+}
+
 // swagger:operation GET /1.0/auth/identity-provider-groups identity_provider_groups identity_provider_groups_get
 //
 //	Get the identity provider groups
@@ -278,8 +302,13 @@ func createIdentityProviderGroup(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(fmt.Errorf("Failed to unmarshal request body: %w", err))
 	}
 
+	err = validateIdentityProviderGroupName(idpGroup.Name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	s := d.State()
-	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+	err = s.DB.Cluster.TransactionRetry(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
 		id, err := dbCluster.CreateIdentityProviderGroup(ctx, tx.Tx(), dbCluster.IdentityProviderGroup{Name: idpGroup.Name})
 		if err != nil {
 			return err
@@ -290,27 +319,19 @@ func createIdentityProviderGroup(d *Daemon, r *http.Request) response.Response {
 			return err
 		}
 
-		return nil
-	})
-	if err != nil {
-		return response.SmartError(err)
-	}
-
-	// Notify other cluster members to update their identity cache.
-	notifier, err := cluster.NewNotifier(s, s.Endpoints.NetworkCert(), s.ServerCert(), cluster.NotifyAlive)
-	if err != nil {
-		return response.SmartError(err)
-	}
+		after, err := authAuditIdentityProviderGroupState(ctx, tx.Tx(), idpGroup.Name)
+		if err != nil {
+			return err
+		}
 
-	err = notifier(func(client lxd.InstanceServer) error {
-		_, _, err := client.RawQuery(http.MethodPost, "/internal/identity-cache-refresh", nil, "")
-		return err
+		return authAuditRecord(ctx, tx.Tx(), request.CreateRequestor(r), api.AuthAuditObjectTypeIdentityProviderGroup, idpGroup.Name, api.AuthAuditActionCreated, nil, after)
 	})
 	if err != nil {
 		return response.SmartError(err)
 	}
 
-	s.UpdateIdentityCache()
+	// Update the identity cache of all cluster members.
+	notifyIdentityCacheRefresh(s)
 
 	// Send a lifecycle event for the IDP group creation.
 	lc := lifecycle.IdentityProviderGroupCreated.Event(idpGroup.Name, request.CreateRequestor(r), nil)
@@ -357,34 +378,41 @@ func renameIdentityProviderGroup(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(fmt.Errorf("Failed to unmarshal request body: %w", err))
 	}
 
-	s := d.State()
-	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
-		return dbCluster.RenameIdentityProviderGroup(ctx, tx.Tx(), idpGroupName, idpGroupPost.Name)
-	})
+	err = validateIdentityProviderGroupName(idpGroupPost.Name)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
-	// Notify other cluster members to update their identity cache.
-	notifier, err := cluster.NewNotifier(s, s.Endpoints.NetworkCert(), s.ServerCert(), cluster.NotifyAlive)
-	if err != nil {
-		return response.SmartError(err)
-	}
+	s := d.State()
+	err = s.DB.Cluster.TransactionRetry(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		before, err := authAuditIdentityProviderGroupState(ctx, tx.Tx(), idpGroupName)
+		if err != nil {
+			return err
+		}
+
+		err = dbCluster.RenameIdentityProviderGroup(ctx, tx.Tx(), idpGroupName, idpGroupPost.Name)
+		if err != nil {
+			return err
+		}
 
-	err = notifier(func(client lxd.InstanceServer) error {
-		_, _, err := client.RawQuery(http.MethodPost, "/internal/identity-cache-refresh", nil, "")
-		return err
+		after, err := authAuditIdentityProviderGroupState(ctx, tx.Tx(), idpGroupPost.Name)
+		if err != nil {
+			return err
+		}
+
+		return authAuditRecord(ctx, tx.Tx(), request.CreateRequestor(r), api.AuthAuditObjectTypeIdentityProviderGroup, idpGroupPost.Name, api.AuthAuditActionRenamed, before, after)
 	})
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	// Update the identity cache of all cluster members.
+	notifyIdentityCacheRefresh(s)
+
 	// Send a lifecycle event for the IDP group rename.
 	lc := lifecycle.IdentityProviderGroupRenamed.Event(idpGroupPost.Name, request.CreateRequestor(r), map[string]any{"old_name": idpGroupName})
 	s.Events.SendLifecycle(api.ProjectDefaultName, lc)
 
-	s.UpdateIdentityCache()
-
 	return response.SyncResponseLocation(true, nil, entity.IdentityProviderGroupURL(idpGroupPost.Name).String())
 }
 
@@ -427,7 +455,7 @@ func updateIdentityProviderGroup(d *Daemon, r *http.Request) response.Response {
 	}
 
 	s := d.State()
-	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+	err = s.DB.Cluster.TransactionRetry(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
 		idpGroup, err := dbCluster.GetIdentityProviderGroup(ctx, tx.Tx(), idpGroupName)
 		if err != nil {
 			return err
@@ -443,32 +471,29 @@ func updateIdentityProviderGroup(d *Daemon, r *http.Request) response.Response {
 			return err
 		}
 
-		return dbCluster.SetIdentityProviderGroupMapping(ctx, tx.Tx(), idpGroup.ID, idpGroupPut.Groups)
-	})
-	if err != nil {
-		return response.SmartError(err)
-	}
+		err = dbCluster.SetIdentityProviderGroupMapping(ctx, tx.Tx(), idpGroup.ID, idpGroupPut.Groups)
+		if err != nil {
+			return err
+		}
 
-	// Notify other cluster members to update their identity cache.
-	notifier, err := cluster.NewNotifier(s, s.Endpoints.NetworkCert(), s.ServerCert(), cluster.NotifyAlive)
-	if err != nil {
-		return response.SmartError(err)
-	}
+		after, err := authAuditIdentityProviderGroupState(ctx, tx.Tx(), idpGroupName)
+		if err != nil {
+			return err
+		}
 
-	err = notifier(func(client lxd.InstanceServer) error {
-		_, _, err := client.RawQuery(http.MethodPost, "/internal/identity-cache-refresh", nil, "")
-		return err
+		return authAuditRecord(ctx, tx.Tx(), request.CreateRequestor(r), api.AuthAuditObjectTypeIdentityProviderGroup, idpGroupName, api.AuthAuditActionUpdated, apiIDPGroup, after)
 	})
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	// Update the identity cache of all cluster members.
+	notifyIdentityCacheRefresh(s)
+
 	// Send a lifecycle event for the IDP group update.
 	lc := lifecycle.IdentityProviderGroupUpdated.Event(idpGroupName, request.CreateRequestor(r), nil)
 	s.Events.SendLifecycle(api.ProjectDefaultName, lc)
 
-	s.UpdateIdentityCache()
-
 	return response.EmptySyncResponse
 }
 
@@ -512,7 +537,7 @@ func patchIdentityProviderGroup(d *Daemon, r *http.Request) response.Response {
 
 	s := d.State()
 	var apiIDPGroup *api.IdentityProviderGroup
-	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+	err = s.DB.Cluster.TransactionRetry(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
 		idpGroup, err := dbCluster.GetIdentityProviderGroup(ctx, tx.Tx(), idpGroupName)
 		if err != nil {
 			return err
@@ -528,38 +553,36 @@ func patchIdentityProviderGroup(d *Daemon, r *http.Request) response.Response {
 			return err
 		}
 
+		before := *apiIDPGroup
 		for _, newGroup := range idpGroupPut.Groups {
 			if !shared.ValueInSlice(newGroup, apiIDPGroup.Groups) {
 				apiIDPGroup.Groups = append(apiIDPGroup.Groups, newGroup)
 			}
 		}
 
-		return dbCluster.SetIdentityProviderGroupMapping(ctx, tx.Tx(), idpGroup.ID, apiIDPGroup.Groups)
-	})
-	if err != nil {
-		return response.SmartError(err)
-	}
+		err = dbCluster.SetIdentityProviderGroupMapping(ctx, tx.Tx(), idpGroup.ID, apiIDPGroup.Groups)
+		if err != nil {
+			return err
+		}
 
-	// Notify other cluster members to update their identity cache.
-	notifier, err := cluster.NewNotifier(s, s.Endpoints.NetworkCert(), s.ServerCert(), cluster.NotifyAlive)
-	if err != nil {
-		return response.SmartError(err)
-	}
+		after, err := authAuditIdentityProviderGroupState(ctx, tx.Tx(), idpGroupName)
+		if err != nil {
+			return err
+		}
 
-	err = notifier(func(client lxd.InstanceServer) error {
-		_, _, err := client.RawQuery(http.MethodPost, "/internal/identity-cache-refresh", nil, "")
-		return err
+		return authAuditRecord(ctx, tx.Tx(), request.CreateRequestor(r), api.AuthAuditObjectTypeIdentityProviderGroup, idpGroupName, api.AuthAuditActionUpdated, &before, after)
 	})
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	// Update the identity cache of all cluster members.
+	notifyIdentityCacheRefresh(s)
+
 	// Send a lifecycle event for the IDP group update.
 	lc := lifecycle.IdentityProviderGroupUpdated.Event(idpGroupName, request.CreateRequestor(r), nil)
 	s.Events.SendLifecycle(api.ProjectDefaultName, lc)
 
-	s.UpdateIdentityCache()
-
 	return response.EmptySyncResponse
 }
 
@@ -588,32 +611,29 @@ func deleteIdentityProviderGroup(d *Daemon, r *http.Request) response.Response {
 	}
 
 	s := d.State()
-	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
-		return dbCluster.DeleteIdentityProviderGroup(ctx, tx.Tx(), idpGroupName)
-	})
-	if err != nil {
-		return response.SmartError(err)
-	}
+	err = s.DB.Cluster.TransactionRetry(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		before, err := authAuditIdentityProviderGroupState(ctx, tx.Tx(), idpGroupName)
+		if err != nil {
+			return err
+		}
 
-	// Notify other cluster members to update their identity cache.
-	notifier, err := cluster.NewNotifier(s, s.Endpoints.NetworkCert(), s.ServerCert(), cluster.NotifyAlive)
-	if err != nil {
-		return response.SmartError(err)
-	}
+		err = dbCluster.DeleteIdentityProviderGroup(ctx, tx.Tx(), idpGroupName)
+		if err != nil {
+			return err
+		}
 
-	err = notifier(func(client lxd.InstanceServer) error {
-		_, _, err := client.RawQuery(http.MethodPost, "/internal/identity-cache-refresh", nil, "")
-		return err
+		return authAuditRecord(ctx, tx.Tx(), request.CreateRequestor(r), api.AuthAuditObjectTypeIdentityProviderGroup, idpGroupName, api.AuthAuditActionDeleted, before, nil)
 	})
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	// Update the identity cache of all cluster members.
+	notifyIdentityCacheRefresh(s)
+
 	// Send a lifecycle event for the IDP group deletion.
 	lc := lifecycle.IdentityProviderGroupDeleted.Event(idpGroupName, request.CreateRequestor(r), nil)
 	s.Events.SendLifecycle(api.ProjectDefaultName, lc)
 
-	s.UpdateIdentityCache()
-
 	return response.EmptySyncResponse
 }
@@ -18,6 +18,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -33,10 +34,12 @@ import (
 	"github.com/canonical/lxd/lxd/db"
 	dbCluster "github.com/canonical/lxd/lxd/db/cluster"
 	"github.com/canonical/lxd/lxd/db/operationtype"
+	"github.com/canonical/lxd/lxd/db/warningtype"
 	"github.com/canonical/lxd/lxd/instance"
 	"github.com/canonical/lxd/lxd/instance/instancetype"
 	"github.com/canonical/lxd/lxd/lifecycle"
 	"github.com/canonical/lxd/lxd/node"
+	"github.com/canonical/lxd/lxd/oci"
 	"github.com/canonical/lxd/lxd/operations"
 	projectutils "github.com/canonical/lxd/lxd/project"
 	"github.com/canonical/lxd/lxd/request"
@@ -47,6 +50,7 @@ import (
 	"github.com/canonical/lxd/lxd/util"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/cancel"
 	"github.com/canonical/lxd/shared/entity"
 	"github.com/canonical/lxd/shared/filter"
 	"github.com/canonical/lxd/shared/ioprogress"
@@ -191,6 +195,197 @@ func compressFile(compress string, infile io.Reader, outfile io.Writer) error {
 	return nil
 }
 
+// imageCompressionExtensions are the compression algorithms that images can be exported with, along with the file
+// extension of the tarballs they produce.
+var imageCompressionExtensions = map[string]string{
+	"none": ".tar",
+	"gzip": ".tar.gz",
+	"xz":   ".tar.xz",
+	"zstd": ".tar.zst",
+}
+
+// imageCompressionLevels are the ranges of the compression levels of the compression algorithms that support them.
+var imageCompressionLevels = map[string][2]int{
+	"gzip": {1, 9},
+	"xz":   {0, 9},
+	"zstd": {1, 19},
+}
+
+// imageCompressionCommand returns the compressFile command for the compression algorithm at the given level. A nil
+// level uses the default level of the algorithm.
+func imageCompressionCommand(algorithm string, level *int) (string, error) {
+	if level == nil {
+		return algorithm, nil
+	}
+
+	levels, ok := imageCompressionLevels[algorithm]
+	if !ok {
+		return "", api.StatusErrorf(http.StatusBadRequest, "Compression algorithm %q doesn't support compression levels", algorithm)
+	}
+
+	if *level < levels[0] || *level > levels[1] {
+		return "", api.StatusErrorf(http.StatusBadRequest, "Compression level of %q must be between %d and %d", algorithm, levels[0], levels[1])
+	}
+
+	return fmt.Sprintf("%s -%d", algorithm, *level), nil
+}
+
+// imageExportFile returns the file response entry of the image file at the given path, named after the given filename
+// and the extension of its format. If a compression algorithm is given and the file is a tarball, it is recompressed
+// with the given compressFile command while being sent, unless it already uses the algorithm and no level is given.
+func imageExportFile(ctx context.Context, path string, filename string, compression string, compress string, level *int) response.FileResponseEntry {
+	entry := response.FileResponseEntry{Path: path}
+
+	_, ext, decompress, err := shared.DetectCompression(path)
+	if err != nil {
+		ext = ""
+	}
+
+	if compression != "" && strings.HasPrefix(ext, ".tar") && (ext != imageCompressionExtensions[compression] || level != nil) {
+		ext = imageCompressionExtensions[compression]
+		entry.Path = ""
+		entry.Reader = imageExportTranscode(ctx, path, decompress, compress)
+	}
+
+	entry.Filename = filename + ext
+
+	return entry
+}
+
+// imageExportTranscode returns a reader streaming the tarball at the given path recompressed with the given
+// compressFile command, or uncompressed if it is "none". The tarball is first decompressed with the given command if it
+// is compressed. Nothing is written to disk, and the transcoding is stopped once the context is done.
+func imageExportTranscode(ctx context.Context, path string, decompress []string, compress string) io.Reader {
+	reader, writer := io.Pipe()
+
+	go func() {
+		<-ctx.Done()
+		_ = reader.CloseWithError(ctx.Err())
+	}()
+
+	go func() {
+		_ = writer.CloseWithError(imageExportTranscodeTo(path, decompress, compress, writer))
+	}()
+
+	return reader
+}
+
+// imageExportTranscodeTo writes the tarball at the given path to the writer, as described in imageExportTranscode.
+func imageExportTranscodeTo(path string, decompress []string, compress string, w io.Writer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = f.Close() }()
+
+	var tarball io.Reader = f
+	var cmd *exec.Cmd
+	if len(decompress) > 0 {
+		cmd = exec.Command(decompress[0], append(decompress[1:], "-c")...)
+		cmd.Stdin = f
+
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return err
+		}
+
+		err = cmd.Start()
+		if err != nil {
+			return fmt.Errorf("Failed decompressing %q: %w", path, err)
+		}
+
+		tarball = stdout
+	}
+
+	if compress == "none" {
+		_, err = io.Copy(w, tarball)
+	} else {
+		err = compressFile(compress, tarball, w)
+	}
+
+	if cmd != nil {
+		if err != nil {
+			_ = cmd.Process.Kill()
+		}
+
+		waitErr := cmd.Wait()
+		if err == nil && waitErr != nil {
+			err = fmt.Errorf("Failed decompressing %q: %w", path, waitErr)
+		}
+	}
+
+	return err
+}
+
+// imageExportDeltaSource returns the first of the given fingerprints that the image can be exported as a delta
+// against, or an empty string if none of them can be used. The source image must be available locally in the same
+// project with the same file layout, and be public if the requester isn't trusted.
+func imageExportDeltaSource(ctx context.Context, s *state.State, projectName string, imgInfo *api.Image, public bool, fingerprints []string) (string, error) {
+	_, err := exec.LookPath("xdelta3")
+	if err != nil {
+		return "", nil
+	}
+
+	split := shared.PathExists(shared.VarPath("images", imgInfo.Fingerprint+".rootfs"))
+
+	for _, fingerprint := range fingerprints {
+		// Only accept full fingerprints.
+		if len(fingerprint) != len(imgInfo.Fingerprint) || fingerprint == imgInfo.Fingerprint {
+			continue
+		}
+
+		var source *api.Image
+		err = s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+			_, source, err = tx.GetImage(ctx, fingerprint, dbCluster.ImageFilter{Project: &projectName})
+
+			return err
+		})
+		if err != nil {
+			if response.IsNotFoundError(err) {
+				continue
+			}
+
+			return "", err
+		}
+
+		if public && !source.Public {
+			continue
+		}
+
+		if !shared.PathExists(shared.VarPath("images", fingerprint)) || shared.PathExists(shared.VarPath("images", fingerprint+".rootfs")) != split {
+			continue
+		}
+
+		return fingerprint, nil
+	}
+
+	return "", nil
+}
+
+// imageExportDeltaFile replaces the content of the export entry of an image file by a binary delta against the file at
+// the given source path, computed while it is sent.
+func imageExportDeltaFile(ctx context.Context, entry *response.FileResponseEntry, srcPath string) {
+	path := entry.Path
+	reader, writer := io.Pipe()
+
+	go func() {
+		// External decompression is disabled so that the files are reconstructed byte for byte.
+		cmd := exec.CommandContext(ctx, "xdelta3", "-e", "-D", "-c", "-s", srcPath, path)
+		cmd.Stdout = writer
+
+		err := cmd.Run()
+		if err != nil {
+			err = fmt.Errorf("Failed computing delta of %q: %w", path, err)
+		}
+
+		_ = writer.CloseWithError(err)
+	}()
+
+	entry.Path = ""
+	entry.Reader = reader
+}
+
 /*
  * This function takes a container or snapshot from the local image server and
  * exports it as an image.
@@ -235,6 +430,31 @@ func imgPostInstanceInfo(s *state.State, r *http.Request, req api.ImagesPost, op
 
 	info.Type = c.Type().String()
 
+	// Embed a sanitized copy of the instance configuration if requested.
+	if req.IncludeManifest {
+		manifest, err := json.Marshal(instancetype.NewImageManifest(c.LocalConfig(), c.LocalDevices(), req.ManifestExcludeUser))
+		if err != nil {
+			return nil, fmt.Errorf("Failed encoding instance manifest: %w", err)
+		}
+
+		// Without explicit properties, keep the ones of the instance's current image metadata.
+		properties := req.Properties
+		if properties == nil {
+			properties, err = instanceMetadataProperties(s, c)
+			if err != nil {
+				return nil, fmt.Errorf("Failed loading instance image metadata: %w", err)
+			}
+		} else {
+			properties = make(map[string]string, len(req.Properties)+1)
+			for key, value := range req.Properties {
+				properties[key] = value
+			}
+		}
+
+		properties[instancetype.ImageManifestProperty] = string(manifest)
+		req.Properties = properties
+	}
+
 	// Build the actual image file
 	imageFile, err := os.CreateTemp(builddir, "lxd_build_image_")
 	if err != nil {
@@ -287,26 +507,15 @@ func imgPostInstanceInfo(s *state.State, r *http.Request, req api.ImagesPost, op
 	if req.CompressionAlgorithm != "" {
 		compress = req.CompressionAlgorithm
 	} else {
-		var p *api.Project
-		err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
-			project, err := dbCluster.GetProject(ctx, tx.Tx(), projectName)
-			if err != nil {
-				return err
-			}
-
-			p, err = project.ToAPI(ctx, tx.Tx())
-
-			return err
-		})
+		compress, err = projectImageCompressionAlgorithm(s, projectName)
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		if p.Config["images.compression_algorithm"] != "" {
-			compress = p.Config["images.compression_algorithm"]
-		} else {
-			compress = s.GlobalConfig.ImagesCompressionAlgorithm()
-		}
+	compress, err = imageCompressionCommand(compress, req.CompressionLevel)
+	if err != nil {
+		return nil, err
 	}
 
 	// Setup tar, optional compress and sha256 to happen in one pass.
@@ -570,6 +779,279 @@ func imgPostURLInfo(s *state.State, r *http.Request, req api.ImagesPost, op *ope
 	return info, nil
 }
 
+// imgPostOCIInfo imports an image from an OCI registry by flattening its layers into a unified container image.
+func imgPostOCIInfo(s *state.State, req api.ImagesPost, op *operations.Operation, builddir string, projectName string, budget int64) (*api.Image, error) {
+	if req.Source.Alias == "" {
+		return nil, fmt.Errorf("Missing OCI image reference")
+	}
+
+	if req.AutoUpdate {
+		return nil, fmt.Errorf("Auto-update isn't supported for OCI images")
+	}
+
+	ref, err := oci.ParseReference(req.Source.Alias)
+	if err != nil {
+		return nil, err
+	}
+
+	registry, err := oci.RegistryURL(req.Source.Server)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use the credentials of the request, or those stored for the registry.
+	credentials := req.Source.Secret
+	if credentials == "" {
+		credentials = s.GlobalConfig.ImagesOCICredentials(registry.Host)
+	}
+
+	httpClient, err := util.HTTPClient("", s.Proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := oci.NewClient(httpClient, registry.String(), credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Canceler = cancel.NewHTTPRequestCanceller()
+	op.SetCanceler(client.Canceler)
+
+	architectures := []string{}
+	for _, architecture := range s.OS.Architectures {
+		architectureName, err := osarch.ArchitectureName(architecture)
+		if err != nil {
+			return nil, err
+		}
+
+		architectures = append(architectures, architectureName)
+	}
+
+	// Download the layers, reporting the progress of each layer.
+	metadata := make(map[string]any)
+	img, err := client.Pull(context.TODO(), ref, architectures, builddir, func(layer int, layers int, percent int64, speed int64) {
+		shared.SetProgressMetadata(metadata, "download", fmt.Sprintf("Layer %d/%d", layer, layers), percent, 0, speed)
+		_ = op.UpdateMetadata(metadata)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed pulling OCI image %q from %q: %w", ref, registry.Host, err)
+	}
+
+	info := api.Image{}
+	info.Filename = req.Filename
+	info.Public = req.Public
+	info.Type = instancetype.Container.String()
+	info.Architecture = img.Architecture
+	info.ExpiresAt = req.ExpiresAt
+
+	info.CreatedAt = img.Created.UTC()
+	if img.Created.IsZero() {
+		info.CreatedAt = time.Now().UTC()
+	}
+
+	// Record the source and the runtime configuration of the image in its properties.
+	info.Properties = map[string]string{
+		"architecture":  img.Architecture,
+		"description":   fmt.Sprintf("%s/%s", registry.Host, ref),
+		"oci.reference": fmt.Sprintf("%s/%s", registry.Host, ref),
+		"oci.digest":    img.Digest,
+	}
+
+	for key, value := range map[string][]string{"oci.entrypoint": img.Entrypoint, "oci.cmd": img.Cmd, "oci.env": img.Env} {
+		if len(value) == 0 {
+			continue
+		}
+
+		buf, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+
+		info.Properties[key] = string(buf)
+	}
+
+	if img.WorkingDir != "" {
+		info.Properties["oci.working_dir"] = img.WorkingDir
+	}
+
+	if img.User != "" {
+		info.Properties["oci.user"] = img.User
+	}
+
+	for key, value := range req.Properties {
+		info.Properties[key] = value
+	}
+
+	meta := api.ImageMetadata{
+		Architecture: info.Architecture,
+		CreationDate: info.CreatedAt.Unix(),
+		Properties:   info.Properties,
+	}
+
+	if !info.ExpiresAt.IsZero() {
+		meta.ExpiryDate = info.ExpiresAt.Unix()
+	}
+
+	metaYAML, err := yaml.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build the unified image file.
+	imageFile, err := os.CreateTemp(builddir, "lxd_build_image_")
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = os.Remove(imageFile.Name()) }()
+
+	compress := req.CompressionAlgorithm
+	if compress == "" {
+		compress, err = projectImageCompressionAlgorithm(s, projectName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	compress, err = imageCompressionCommand(compress, req.CompressionLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	// Setup tar, optional compress and sha256 to happen in one pass.
+	sha256 := sha256.New()
+	var writer io.WriteCloser = imageFile
+	var writerHash io.Writer
+
+	wg := sync.WaitGroup{}
+	var compressErr error
+	if compress != "none" {
+		wg.Add(1)
+		tarReader, tarWriter := io.Pipe()
+		writer = tarWriter
+		writerHash = tarWriter
+		go func() {
+			defer wg.Done()
+			compressErr = compressFile(compress, tarReader, io.MultiWriter(imageFile, sha256))
+
+			// If a compression error occurred, close the reader to end the flattening.
+			if compressErr != nil {
+				_ = tarReader.CloseWithError(compressErr)
+			}
+		}()
+	} else {
+		writerHash = io.MultiWriter(imageFile, sha256)
+	}
+
+	tw := tar.NewWriter(shared.NewQuotaWriter(writerHash, budget))
+	err = tw.WriteHeader(&tar.Header{Name: "metadata.yaml", Mode: 0644, Size: int64(len(metaYAML)), ModTime: info.CreatedAt, Typeflag: tar.TypeReg})
+	if err == nil {
+		_, err = tw.Write(metaYAML)
+	}
+
+	if err == nil {
+		err = tw.WriteHeader(&tar.Header{Name: "rootfs/", Mode: 0755, ModTime: info.CreatedAt, Typeflag: tar.TypeDir})
+	}
+
+	if err == nil {
+		err = oci.Flatten(tw, img.Layers, "rootfs")
+	}
+
+	if err == nil {
+		err = tw.Close()
+	}
+
+	// When compression is used, closing the pipe is required for compressFile to know it is finished.
+	_ = writer.Close()
+	wg.Wait()
+	_ = imageFile.Close()
+
+	if compressErr != nil {
+		return nil, compressErr
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed creating image from OCI image %q: %w", ref, err)
+	}
+
+	// The downloaded layers are no longer needed.
+	for _, layer := range img.Layers {
+		_ = os.Remove(layer)
+	}
+
+	fi, err := os.Stat(imageFile.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	info.Size = fi.Size()
+	info.Fingerprint = fmt.Sprintf("%x", sha256.Sum(nil))
+
+	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		_, _, err = tx.GetImage(ctx, info.Fingerprint, dbCluster.ImageFilter{Project: &projectName})
+
+		return err
+	})
+	if !response.IsNotFoundError(err) {
+		if err != nil {
+			return nil, err
+		}
+
+		return &info, fmt.Errorf("The image already exists: %s", info.Fingerprint)
+	}
+
+	err = shared.FileMove(imageFile.Name(), shared.VarPath("images", info.Fingerprint))
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		var profileIDs []int64
+		for _, profile := range req.Profiles {
+			profileID, _, err := tx.GetProfile(ctx, projectName, profile)
+			if response.IsNotFoundError(err) {
+				return fmt.Errorf("Profile '%s' doesn't exist", profile)
+			} else if err != nil {
+				return err
+			}
+
+			profileIDs = append(profileIDs, profileID)
+		}
+
+		return tx.CreateImage(ctx, projectName, info.Fingerprint, info.Filename, info.Size, info.Public, false, info.Architecture, info.CreatedAt, info.ExpiresAt, info.Properties, info.Type, profileIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+// projectImageCompressionAlgorithm returns the compression algorithm to use for new images of the project.
+func projectImageCompressionAlgorithm(s *state.State, projectName string) (string, error) {
+	var p *api.Project
+	err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		project, err := dbCluster.GetProject(ctx, tx.Tx(), projectName)
+		if err != nil {
+			return err
+		}
+
+		p, err = project.ToAPI(ctx, tx.Tx())
+
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if p.Config["images.compression_algorithm"] != "" {
+		return p.Config["images.compression_algorithm"], nil
+	}
+
+	return s.GlobalConfig.ImagesCompressionAlgorithm(), nil
+}
+
 func getImgPostInfo(s *state.State, r *http.Request, builddir string, project string, post *os.File, metadata map[string]any) (*api.Image, error) {
 	info := api.Image{}
 	var imageMeta *api.ImageMetadata
@@ -1082,7 +1564,7 @@ func imagesPost(d *Daemon, r *http.Request) response.Response {
 		return createTokenResponse(s, r, projectName, req.Source.Fingerprint, metadata)
 	}
 
-	if !imageUpload && !shared.ValueInSlice(req.Source.Type, []string{"container", "instance", "virtual-machine", "snapshot", "image", "url"}) {
+	if !imageUpload && !shared.ValueInSlice(req.Source.Type, []string{"container", "instance", "virtual-machine", "snapshot", "image", "url", "oci"}) {
 		cleanup(builddir, post)
 		return response.InternalError(fmt.Errorf("Invalid images JSON"))
 	}
@@ -1128,6 +1610,9 @@ func imagesPost(d *Daemon, r *http.Request) response.Response {
 			} else if req.Source.Type == "url" {
 				/* Processing image copy from URL */
 				info, err = imgPostURLInfo(s, r, req, op, projectName, budget)
+			} else if req.Source.Type == "oci" {
+				/* Processing image import from OCI registry */
+				info, err = imgPostOCIInfo(s, req, op, builddir, projectName, budget)
 			} else {
 				/* Processing image creation from container */
 				imagePublishLock.Lock()
@@ -1192,6 +1677,18 @@ func imagesPost(d *Daemon, r *http.Request) response.Response {
 				if err != nil {
 					return fmt.Errorf("Add new image alias to the database: %w", err)
 				}
+
+				err = imageAliasSetAutoUpdate(ctx, tx, projectName, alias.Name, info.Fingerprint, alias.AutoUpdate, alias.PinnedFingerprint)
+				if err != nil {
+					return err
+				}
+			}
+
+			if req.SharedWith != nil {
+				err = imageSetSharedWith(ctx, tx, projectName, imgID, req.SharedWith)
+				if err != nil {
+					return err
+				}
 			}
 
 			return nil
@@ -1324,87 +1821,187 @@ func getImageMetadata(fname string) (*api.ImageMetadata, string, error) {
 			imageType = instancetype.Container.String()
 		}
 
-		if hdr.Name == "rootfs.img" || hdr.Name == "./rootfs.img" {
-			hasRoot = true
-			imageType = instancetype.VM.String()
+		if hdr.Name == "rootfs.img" || hdr.Name == "./rootfs.img" {
+			hasRoot = true
+			imageType = instancetype.VM.String()
+		}
+
+		if hasMeta && hasRoot {
+			// Done with the bits we want, no need to keep reading
+			break
+		}
+	}
+
+	if !hasMeta {
+		return nil, "unknown", fmt.Errorf("Metadata tarball is missing metadata.yaml")
+	}
+
+	_, err = osarch.ArchitectureId(result.Architecture)
+	if err != nil {
+		return nil, "unknown", err
+	}
+
+	if result.CreationDate == 0 {
+		return nil, "unknown", fmt.Errorf("Missing creation date")
+	}
+
+	return &result, imageType, nil
+}
+
+func doImagesGet(ctx context.Context, tx *db.ClusterTx, recursion bool, projectName string, public bool, clauses *filter.ClauseSet, hasPermission auth.PermissionChecker) (any, error) {
+	mustLoadObjects := recursion || (clauses != nil && len(clauses.Clauses) > 0)
+
+	fingerprints, err := tx.GetImagesFingerprints(ctx, projectName, public)
+	if err != nil {
+		return err, err
+	}
+
+	// Images shared by other projects are only listed to trusted clients, after the images of the project.
+	sharedFingerprints := map[string]string{}
+	if !public {
+		sharedFingerprints, err = tx.GetSharedImagesFingerprints(ctx, projectName, "")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, fingerprint := range fingerprints {
+			delete(sharedFingerprints, fingerprint)
+		}
+	}
+
+	var resultString []string
+	var resultMap []*api.Image
+
+	if recursion {
+		resultMap = make([]*api.Image, 0, len(fingerprints)+len(sharedFingerprints))
+	} else {
+		resultString = make([]string, 0, len(fingerprints)+len(sharedFingerprints))
+	}
+
+	addImage := func(image *api.Image) error {
+		if !mustLoadObjects {
+			resultString = append(resultString, api.NewURL().Path(version.APIVersion, "images", image.Fingerprint).String())
+			return nil
+		}
+
+		if clauses != nil && len(clauses.Clauses) > 0 {
+			match, err := filter.Match(*image, *clauses)
+			if err != nil {
+				return err
+			}
+
+			if !match {
+				return nil
+			}
+		}
+
+		if recursion {
+			resultMap = append(resultMap, image)
+		} else {
+			resultString = append(resultString, api.NewURL().Path(version.APIVersion, "images", image.Fingerprint).String())
+		}
+
+		return nil
+	}
+
+	for _, fingerprint := range fingerprints {
+		image, err := doImageGet(ctx, tx, projectName, fingerprint, public)
+		if err != nil {
+			continue
+		}
+
+		if !image.Public && !hasPermission(entity.ImageURL(projectName, fingerprint)) {
+			continue
+		}
+
+		err = addImage(image)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sortedSharedFingerprints := make([]string, 0, len(sharedFingerprints))
+	for fingerprint := range sharedFingerprints {
+		sortedSharedFingerprints = append(sortedSharedFingerprints, fingerprint)
+	}
+
+	sort.Strings(sortedSharedFingerprints)
+
+	for _, fingerprint := range sortedSharedFingerprints {
+		_, image, err := tx.GetSharedImageByFingerprintPrefix(ctx, projectName, fingerprint)
+		if err != nil {
+			continue
+		}
+
+		if !imageSharedCanView(projectName, image, hasPermission) {
+			continue
 		}
 
-		if hasMeta && hasRoot {
-			// Done with the bits we want, no need to keep reading
-			break
+		err = addImage(image)
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	if !hasMeta {
-		return nil, "unknown", fmt.Errorf("Metadata tarball is missing metadata.yaml")
+	if recursion {
+		return resultMap, nil
 	}
 
-	_, err = osarch.ArchitectureId(result.Architecture)
-	if err != nil {
-		return nil, "unknown", err
-	}
+	return resultString, nil
+}
 
-	if result.CreationDate == 0 {
-		return nil, "unknown", fmt.Errorf("Missing creation date")
+// imageSharedCanView returns whether the image shared with the project can be viewed, which is the case if images of
+// the project can be viewed or if the image can be viewed in the project that owns it.
+func imageSharedCanView(projectName string, image *api.Image, hasPermission auth.PermissionChecker) bool {
+	return hasPermission(entity.ImageURL(projectName, image.Fingerprint)) || hasPermission(entity.ImageURL(image.Project, image.Fingerprint))
+}
+
+// imageProjectName returns the name of the project that owns the image, which is the given project unless the image is
+// shared with it by another project.
+func imageProjectName(image *api.Image, projectName string) string {
+	if image.Origin == api.ImageOriginShared {
+		return image.Project
 	}
 
-	return &result, imageType, nil
+	return projectName
 }
 
-func doImagesGet(ctx context.Context, tx *db.ClusterTx, recursion bool, projectName string, public bool, clauses *filter.ClauseSet, hasPermission auth.PermissionChecker) (any, error) {
-	mustLoadObjects := recursion || (clauses != nil && len(clauses.Clauses) > 0)
-
-	fingerprints, err := tx.GetImagesFingerprints(ctx, projectName, public)
+// imageSetSharedWith sets the projects that the image with the given ID, in the given project, is shared with. Images
+// can only be shared with other projects that have their own images.
+func imageSetSharedWith(ctx context.Context, tx *db.ClusterTx, projectName string, id int, sharedWith []string) error {
+	hasImages, err := dbCluster.ProjectHasImages(ctx, tx.Tx(), projectName)
 	if err != nil {
-		return err, err
+		return err
 	}
 
-	var resultString []string
-	var resultMap []*api.Image
-
-	if recursion {
-		resultMap = make([]*api.Image, 0, len(fingerprints))
-	} else {
-		resultString = make([]string, 0, len(fingerprints))
+	if !hasImages {
+		projectName = api.ProjectDefaultName
 	}
 
-	for _, fingerprint := range fingerprints {
-		image, err := doImageGet(ctx, tx, projectName, fingerprint, public)
-		if err != nil {
-			continue
+	for i, name := range sharedWith {
+		if name == projectName {
+			return api.StatusErrorf(http.StatusBadRequest, "Image can't be shared with its own project %q", name)
 		}
 
-		if !image.Public && !hasPermission(entity.ImageURL(projectName, fingerprint)) {
-			continue
+		if shared.ValueInSlice(name, sharedWith[:i]) {
+			return api.StatusErrorf(http.StatusBadRequest, "Project %q is listed more than once", name)
 		}
 
-		if !mustLoadObjects {
-			resultString = append(resultString, api.NewURL().Path(version.APIVersion, "images", fingerprint).String())
-		} else {
-			if clauses != nil && len(clauses.Clauses) > 0 {
-				match, err := filter.Match(*image, *clauses)
-				if err != nil {
-					return nil, err
-				}
-
-				if !match {
-					continue
-				}
+		hasImages, err := dbCluster.ProjectHasImages(ctx, tx.Tx(), name)
+		if err != nil {
+			if api.StatusErrorCheck(err, http.StatusNotFound) {
+				return api.StatusErrorf(http.StatusBadRequest, "Project %q not found", name)
 			}
 
-			if recursion {
-				resultMap = append(resultMap, image)
-			} else {
-				resultString = append(resultString, api.NewURL().Path(version.APIVersion, "images", image.Fingerprint).String())
-			}
+			return err
 		}
-	}
 
-	if recursion {
-		return resultMap, nil
+		if !hasImages {
+			return api.StatusErrorf(http.StatusBadRequest, "Project %q doesn't have its own images", name)
+		}
 	}
 
-	return resultString, nil
+	return tx.UpdateImageSharedProjects(ctx, id, sharedWith)
 }
 
 // swagger:operation GET /1.0/images?public images images_get_untrusted
@@ -1708,7 +2305,20 @@ func autoUpdateImages(ctx context.Context, s *state.State) error {
 		var err error
 
 		autoUpdate := true
-		images, err := dbCluster.GetImages(ctx, tx.Tx(), dbCluster.ImageFilter{AutoUpdate: &autoUpdate})
+		filters := []dbCluster.ImageFilter{{AutoUpdate: &autoUpdate}}
+
+		// Include the images that have aliases following updates even if the images themselves don't.
+		aliasImageIDs, err := tx.GetImageIDsWithAutoUpdateAliases(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, id := range aliasImageIDs {
+			imageID := id
+			filters = append(filters, dbCluster.ImageFilter{ID: &imageID})
+		}
+
+		images, err := dbCluster.GetImages(ctx, tx.Tx(), filters...)
 		if err != nil {
 			return err
 		}
@@ -1783,6 +2393,7 @@ func autoUpdateImages(ctx context.Context, s *state.State) error {
 
 		var deleteIDs []int
 		var newImage *api.Image
+		var newImageProject string
 
 		for _, image := range images {
 			imgProject := image.Project
@@ -1804,27 +2415,28 @@ func autoUpdateImages(ctx context.Context, s *state.State) error {
 				continue
 			}
 
-			newInfo, err := autoUpdateImage(ctx, s, nil, image.ID, imageInfo, image.Project, false)
+			newInfo, keepOld, err := autoUpdateImage(ctx, s, nil, image.ID, imageInfo, image.Project, false)
 			if err != nil {
 				logger.Error("Failed to update image", logger.Ctx{"err": err, "project": image.Project, "fingerprint": image.Fingerprint})
 
 				if err == context.Canceled {
 					return nil
 				}
-			} else {
+			} else if !keepOld {
 				deleteIDs = append(deleteIDs, image.ID)
 			}
 
 			// newInfo will have the same content for each image in the list.
 			// Therefore, we just pick the first.
-			if newImage == nil {
+			if newImage == nil && newInfo != nil {
 				newImage = newInfo
+				newImageProject = image.Project
 			}
 		}
 
 		if newImage != nil {
 			if len(nodes) > 1 {
-				err := distributeImage(ctx, s, nodes, fingerprint, newImage)
+				err := distributeImage(ctx, s, nodes, newImageProject, fingerprint, newImage)
 				if err != nil {
 					logger.Error("Failed to distribute new image", logger.Ctx{"err": err, "fingerprint": newImage.Fingerprint})
 
@@ -1834,6 +2446,10 @@ func autoUpdateImages(ctx context.Context, s *state.State) error {
 				}
 			}
 
+			if len(deleteIDs) > 0 {
+				autoUpdateImageDeleteFiles(s, fingerprint)
+			}
+
 			_ = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
 				for _, ID := range deleteIDs {
 					// Remove the database entry for the image after distributing to cluster members.
@@ -1851,7 +2467,7 @@ func autoUpdateImages(ctx context.Context, s *state.State) error {
 	return nil
 }
 
-func distributeImage(ctx context.Context, s *state.State, nodes []string, oldFingerprint string, newImage *api.Image) error {
+func distributeImage(ctx context.Context, s *state.State, nodes []string, projectName string, oldFingerprint string, newImage *api.Image) error {
 	// Get config of all nodes (incl. own) and check for storage.images_volume.
 	// If the setting is missing, distribute the image to the node.
 	// If the option is set, only distribute the image once to nodes with this
@@ -1996,56 +2612,77 @@ func distributeImage(ctx context.Context, s *state.State, nodes []string, oldFin
 			}
 		}
 
-		createArgs := &lxd.ImageCreateArgs{}
-		imageMetaPath := shared.VarPath("images", newImage.Fingerprint)
-		imageRootfsPath := shared.VarPath("images", newImage.Fingerprint+".rootfs")
+		// Let the member transfer the image from this member, as a delta against the old image if possible.
+		transferred := false
+		if s.GlobalConfig.ImagesDeltaTransfer() {
+			req := internalImageTransferPost{
+				Fingerprint: newImage.Fingerprint,
+				Project:     projectName,
+				Address:     localClusterAddress,
+			}
 
-		metaFile, err := os.Open(imageMetaPath)
-		if err != nil {
-			return err
+			_, _, err = client.RawQuery("POST", "/internal/image-transfer", req, "")
+			if err != nil {
+				logger.Warn("Failed transferring new image to member, pushing it instead", logger.Ctx{"err": err, "remote": nodeAddress, "fingerprint": newImage.Fingerprint})
+			} else {
+				transferred = true
+			}
 		}
 
-		reverter.Add(func() {
-			_ = metaFile.Close()
-		})
+		var metaFile *os.File
+		var rootfsFile *os.File
 
-		createArgs.MetaFile = metaFile
-		createArgs.MetaName = filepath.Base(imageMetaPath)
-		createArgs.Type = newImage.Type
+		if !transferred {
+			createArgs := &lxd.ImageCreateArgs{}
+			imageMetaPath := shared.VarPath("images", newImage.Fingerprint)
+			imageRootfsPath := shared.VarPath("images", newImage.Fingerprint+".rootfs")
 
-		var rootfsFile *os.File
-		if shared.PathExists(imageRootfsPath) {
-			rootfsFile, err = os.Open(imageRootfsPath)
+			metaFile, err = os.Open(imageMetaPath)
 			if err != nil {
 				return err
 			}
 
 			reverter.Add(func() {
-				_ = rootfsFile.Close()
+				_ = metaFile.Close()
 			})
 
-			createArgs.RootfsFile = rootfsFile
-			createArgs.RootfsName = filepath.Base(imageRootfsPath)
-		}
+			createArgs.MetaFile = metaFile
+			createArgs.MetaName = filepath.Base(imageMetaPath)
+			createArgs.Type = newImage.Type
 
-		image := api.ImagesPost{}
-		image.Filename = createArgs.MetaName
+			if shared.PathExists(imageRootfsPath) {
+				rootfsFile, err = os.Open(imageRootfsPath)
+				if err != nil {
+					return err
+				}
 
-		op, err := client.CreateImage(image, createArgs)
-		if err != nil {
-			return err
-		}
+				reverter.Add(func() {
+					_ = rootfsFile.Close()
+				})
 
-		select {
-		case <-ctx.Done():
-			_ = op.Cancel()
-			return ctx.Err()
-		default:
-		}
+				createArgs.RootfsFile = rootfsFile
+				createArgs.RootfsName = filepath.Base(imageRootfsPath)
+			}
 
-		err = op.Wait()
-		if err != nil {
-			return err
+			image := api.ImagesPost{}
+			image.Filename = createArgs.MetaName
+
+			op, err := client.CreateImage(image, createArgs)
+			if err != nil {
+				return err
+			}
+
+			select {
+			case <-ctx.Done():
+				_ = op.Cancel()
+				return ctx.Err()
+			default:
+			}
+
+			err = op.Wait()
+			if err != nil {
+				return err
+			}
 		}
 
 		for _, poolName := range poolNames {
@@ -2069,9 +2706,11 @@ func distributeImage(ctx context.Context, s *state.State, nodes []string, oldFin
 			}
 		}
 
-		err = metaFile.Close()
-		if err != nil {
-			return err
+		if metaFile != nil {
+			err = metaFile.Close()
+			if err != nil {
+				return err
+			}
 		}
 
 		if rootfsFile != nil {
@@ -2088,8 +2727,9 @@ func distributeImage(ctx context.Context, s *state.State, nodes []string, oldFin
 }
 
 // Update a single image.  The operation can be nil, if no progress tracking is needed.
-// Returns whether the image has been updated.
-func autoUpdateImage(ctx context.Context, s *state.State, op *operations.Operation, id int, info *api.Image, projectName string, manual bool) (*api.Image, error) {
+// Returns the new image if the image has been updated, and whether the old image must be kept because some of its
+// aliases don't follow the update (as they are pinned or have auto_update disabled).
+func autoUpdateImage(ctx context.Context, s *state.State, op *operations.Operation, id int, info *api.Image, projectName string, manual bool) (*api.Image, bool, error) {
 	fingerprint := info.Fingerprint
 	var source api.ImageSource
 
@@ -2107,13 +2747,13 @@ func autoUpdateImage(ctx context.Context, s *state.State, op *operations.Operati
 			return err
 		})
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 
 		if project.Config["images.auto_update_interval"] != "" {
 			interval, err = strconv.ParseInt(project.Config["images.auto_update_interval"], 10, 64)
 			if err != nil {
-				return nil, fmt.Errorf("Unable to fetch project configuration: %w", err)
+				return nil, false, fmt.Errorf("Unable to fetch project configuration: %w", err)
 			}
 		} else {
 			interval = s.GlobalConfig.ImagesAutoUpdateIntervalHours()
@@ -2121,17 +2761,21 @@ func autoUpdateImage(ctx context.Context, s *state.State, op *operations.Operati
 
 		// Check if we're supposed to auto update at all (0 disables it)
 		if interval <= 0 {
-			return nil, nil
+			return nil, false, nil
 		}
 
 		now := time.Now()
 		elapsedHours := int64(math.Round(now.Sub(s.StartTime).Hours()))
 		if elapsedHours%interval != 0 {
-			return nil, nil
+			return nil, false, nil
 		}
 	}
 
+	// Aliases that don't set auto_update follow manual refreshes, and the auto_update setting of the image otherwise.
+	aliasesAutoUpdate := info.AutoUpdate || manual
+
 	var poolNames []string
+	var heldAliases []api.ImageAlias
 
 	err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
 		var err error
@@ -2156,10 +2800,17 @@ func autoUpdateImage(ctx context.Context, s *state.State, op *operations.Operati
 			return err
 		}
 
+		// Get the aliases that stay on the current image if it is updated.
+		heldAliases, err = tx.GetHeldImageAliases(ctx, id, aliasesAutoUpdate)
+		if err != nil {
+			logger.Error("Error getting image aliases", logger.Ctx{"err": err, "fingerprint": fingerprint})
+			return err
+		}
+
 		return nil
 	})
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	// If no optimized pools at least update the base store
@@ -2191,7 +2842,7 @@ func autoUpdateImage(ctx context.Context, s *state.State, op *operations.Operati
 	for _, poolName := range poolNames {
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return nil, false, ctx.Err()
 		default:
 		}
 
@@ -2254,7 +2905,7 @@ func autoUpdateImage(ctx context.Context, s *state.State, op *operations.Operati
 		}
 
 		err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
-			return tx.MoveImageAlias(ctx, id, newID)
+			return tx.MoveImageAlias(ctx, id, newID, aliasesAutoUpdate)
 		})
 		if err != nil {
 			logger.Error("Error moving aliases", logger.Ctx{"err": err, "fingerprint": hash})
@@ -2268,8 +2919,9 @@ func autoUpdateImage(ctx context.Context, s *state.State, op *operations.Operati
 			logger.Error("Copying default profiles", logger.Ctx{"err": err, "fingerprint": hash})
 		}
 
-		// If we do have optimized pools, make sure we remove the volumes associated with the image.
-		if poolName != "" {
+		// If we do have optimized pools, make sure we remove the volumes associated with the image, unless it is
+		// kept for its held aliases.
+		if poolName != "" && len(heldAliases) == 0 {
 			pool, err := storagePools.LoadByName(s, poolName)
 			if err != nil {
 				logger.Error("Error loading storage pool to delete image", logger.Ctx{"err": err, "pool": poolName, "fingerprint": fingerprint})
@@ -2287,13 +2939,51 @@ func autoUpdateImage(ctx context.Context, s *state.State, op *operations.Operati
 	// Image didn't change, nothing to do.
 	if hash == fingerprint {
 		setRefreshResult(false)
-		return nil, nil
+		return nil, false, nil
+	}
+
+	// Keep the current image for the aliases that don't follow the update. Its auto_update setting is disabled so
+	// that it isn't updated again, and a warning is recorded for the pinned aliases.
+	if len(heldAliases) > 0 {
+		err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+			err := tx.UpdateImage(ctx, id, info.Filename, info.Size, info.Public, false, info.Architecture, info.CreatedAt, info.ExpiresAt, info.Properties, "", nil)
+			if err != nil {
+				return err
+			}
+
+			for _, alias := range heldAliases {
+				if alias.PinnedFingerprint == "" {
+					continue
+				}
+
+				err = tx.UpsertWarning(ctx, "", projectName, entity.TypeImage, id, warningtype.PinnedImageAliasOutdated, fmt.Sprintf("Image alias %q is pinned to %q, but its source has been updated to %q", alias.Name, fingerprint, hash))
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			logger.Error("Error keeping image for held aliases", logger.Ctx{"err": err, "fingerprint": fingerprint})
+		}
+
+		setRefreshResult(true)
+		return newInfo, true, nil
 	}
 
+	// The files of the old image are removed by the caller once the new image has been distributed to the other
+	// cluster members, which may transfer it as a delta against the old image.
+	setRefreshResult(true)
+	return newInfo, false, nil
+}
+
+// autoUpdateImageDeleteFiles removes the files of an image that has been replaced by its update.
+func autoUpdateImageDeleteFiles(s *state.State, fingerprint string) {
 	// Remove main image file.
 	fname := filepath.Join(s.OS.VarDir, "images", fingerprint)
 	if shared.PathExists(fname) {
-		err = os.Remove(fname)
+		err := os.Remove(fname)
 		if err != nil {
 			logger.Error("Error deleting image file", logger.Ctx{"fingerprint": fingerprint, "file": fname, "err": err})
 		}
@@ -2302,14 +2992,11 @@ func autoUpdateImage(ctx context.Context, s *state.State, op *operations.Operati
 	// Remove the rootfs file for the image.
 	fname = filepath.Join(s.OS.VarDir, "images", fingerprint) + ".rootfs"
 	if shared.PathExists(fname) {
-		err = os.Remove(fname)
+		err := os.Remove(fname)
 		if err != nil {
 			logger.Error("Error deleting image rootfs file", logger.Ctx{"fingerprint": fingerprint, "file": fname, "err": err})
 		}
 	}
-
-	setRefreshResult(true)
-	return newInfo, nil
 }
 
 func pruneExpiredImagesTask(d *Daemon) (task.Func, task.Schedule) {
@@ -2922,6 +3609,16 @@ func imageValidSecret(s *state.State, r *http.Request, projectName string, finge
 //      description: Secret token to retrieve a private image
 //      type: string
 //      example: RANDOM-STRING
+//    - in: query
+//      name: compression
+//      description: Compression algorithm of the image tarballs (none, gzip, zstd or xz)
+//      type: string
+//      example: zstd
+//    - in: query
+//      name: compression_level
+//      description: Compression level of the compression algorithm
+//      type: integer
+//      example: 19
 //  responses:
 //    "200":
 //      description: Image
@@ -2997,12 +3694,19 @@ func imageGet(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
-	// Get the image (expand partial fingerprints).
+	// Get the image (expand partial fingerprints), falling back to the images shared with the project.
 	var info *api.Image
 	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
 		info, err = doImageGet(ctx, tx, projectName, fingerprint, false)
 		if err != nil {
-			return err
+			if !api.StatusErrorCheck(err, http.StatusNotFound) {
+				return err
+			}
+
+			_, info, err = tx.GetSharedImageByFingerprintPrefix(ctx, projectName, fingerprint)
+			if err != nil {
+				return err
+			}
 		}
 
 		return nil
@@ -3019,6 +3723,21 @@ func imageGet(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	// Images shared with the project can also be viewed by those who can view them in the project that owns them.
+	if !userCanViewImage && info.Origin == api.ImageOriginShared {
+		err = s.Authorizer.CheckPermission(r.Context(), r, entity.ImageURL(info.Project, info.Fingerprint), auth.EntitlementCanView)
+		if err == nil {
+			userCanViewImage = true
+		} else if !api.StatusErrorCheck(err, http.StatusForbidden) {
+			return response.SmartError(err)
+		}
+	}
+
+	// Shared images are never public in the projects they're shared with.
+	if info.Origin == api.ImageOriginShared && !userCanViewImage {
+		return response.NotFound(fmt.Errorf("Image %q not found", info.Fingerprint))
+	}
+
 	public := d.checkTrustedClient(r) != nil || !userCanViewImage
 	secret := r.FormValue("secret")
 
@@ -3128,6 +3847,13 @@ func imagePut(d *Daemon, r *http.Request) response.Response {
 			profileIDs[i] = profileID
 		}
 
+		if req.SharedWith != nil {
+			err = imageSetSharedWith(ctx, tx, projectName, id, req.SharedWith)
+			if err != nil {
+				return err
+			}
+		}
+
 		return tx.UpdateImage(ctx, id, info.Filename, info.Size, req.Public, req.AutoUpdate, info.Architecture, info.CreatedAt, info.ExpiresAt, req.Properties, projectName, profileIDs)
 	})
 	if err != nil {
@@ -3253,6 +3979,15 @@ func imagePatch(d *Daemon, r *http.Request) response.Response {
 	}
 
 	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		// Get SharedWith
+		_, ok := reqRaw["shared_with"]
+		if ok {
+			err := imageSetSharedWith(ctx, tx, projectName, id, req.SharedWith)
+			if err != nil {
+				return err
+			}
+		}
+
 		return tx.UpdateImage(ctx, id, info.Filename, info.Size, info.Public, info.AutoUpdate, info.Architecture, info.CreatedAt, info.ExpiresAt, info.Properties, "", nil)
 	})
 	if err != nil {
@@ -3265,6 +4000,26 @@ func imagePatch(d *Daemon, r *http.Request) response.Response {
 	return response.EmptySyncResponse
 }
 
+// imageAliasSetAutoUpdate sets the auto_update override and the pinned fingerprint of the alias, which targets the
+// image with the given fingerprint. An alias can only be pinned to the image it targets, and the pinned fingerprint
+// can be given as a prefix of the fingerprint of the image.
+func imageAliasSetAutoUpdate(ctx context.Context, tx *db.ClusterTx, projectName string, aliasName string, fingerprint string, autoUpdate *bool, pinnedFingerprint string) error {
+	if pinnedFingerprint != "" {
+		if !strings.HasPrefix(fingerprint, pinnedFingerprint) {
+			return api.StatusErrorf(http.StatusBadRequest, "Image alias %q can only be pinned to its target image %q", aliasName, fingerprint)
+		}
+
+		pinnedFingerprint = fingerprint
+	}
+
+	aliasID, _, err := tx.GetImageAlias(ctx, projectName, aliasName, true)
+	if err != nil {
+		return err
+	}
+
+	return tx.UpdateImageAliasAutoUpdate(ctx, aliasID, autoUpdate, pinnedFingerprint)
+}
+
 // swagger:operation POST /1.0/images/aliases images images_aliases_post
 //
 //	Add an image alias
@@ -3322,7 +4077,7 @@ func imageAliasesPost(d *Daemon, r *http.Request) response.Response {
 			return api.StatusErrorf(http.StatusConflict, "Alias %q already exists", req.Name)
 		}
 
-		imgID, _, err := tx.GetImageByFingerprintPrefix(ctx, req.Target, dbCluster.ImageFilter{Project: &projectName})
+		imgID, img, err := tx.GetImageByFingerprintPrefix(ctx, req.Target, dbCluster.ImageFilter{Project: &projectName})
 		if err != nil {
 			return err
 		}
@@ -3332,7 +4087,7 @@ func imageAliasesPost(d *Daemon, r *http.Request) response.Response {
 			return err
 		}
 
-		return err
+		return imageAliasSetAutoUpdate(ctx, tx, projectName, req.Name, img.Fingerprint, req.AutoUpdate, req.PinnedFingerprint)
 	})
 	if err != nil {
 		return response.SmartError(err)
@@ -3620,6 +4375,10 @@ func imageAliasGet(d *Daemon, r *http.Request) response.Response {
 	var alias api.ImageAliasesEntry
 	err = d.State().DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
 		_, alias, err = tx.GetImageAlias(ctx, projectName, name, !public)
+		if err != nil && !public && api.StatusErrorCheck(err, http.StatusNotFound) {
+			// Fall back to the aliases of the images shared with the project.
+			_, alias, err = tx.GetSharedImageAlias(ctx, projectName, name)
+		}
 
 		return err
 	})
@@ -3761,7 +4520,7 @@ func imageAliasPut(d *Daemon, r *http.Request) response.Response {
 			return err
 		}
 
-		imageID, _, err := tx.GetImageByFingerprintPrefix(ctx, req.Target, dbCluster.ImageFilter{Project: &projectName})
+		imageID, img, err := tx.GetImageByFingerprintPrefix(ctx, req.Target, dbCluster.ImageFilter{Project: &projectName})
 		if err != nil {
 			return err
 		}
@@ -3771,7 +4530,7 @@ func imageAliasPut(d *Daemon, r *http.Request) response.Response {
 			return err
 		}
 
-		return err
+		return imageAliasSetAutoUpdate(ctx, tx, projectName, name, img.Fingerprint, req.AutoUpdate, req.PinnedFingerprint)
 	})
 	if err != nil {
 		return response.SmartError(err)
@@ -3867,7 +4626,36 @@ func imageAliasPatch(d *Daemon, r *http.Request) response.Response {
 			imgAlias.Description = description
 		}
 
-		imageID, _, err := tx.GetImage(ctx, imgAlias.Target, dbCluster.ImageFilter{Project: &projectName})
+		autoUpdate, ok := req["auto_update"]
+		if ok {
+			imgAlias.AutoUpdate = nil
+			if autoUpdate != nil {
+				autoUpdate, err := req.GetBool("auto_update")
+				if err != nil {
+					return api.StatusErrorf(http.StatusBadRequest, "%v", err)
+				}
+
+				imgAlias.AutoUpdate = &autoUpdate
+			}
+		}
+
+		_, ok = req["pinned_fingerprint"]
+		if ok {
+			pinnedFingerprint, err := req.GetString("pinned_fingerprint")
+			if err != nil {
+				return api.StatusErrorf(http.StatusBadRequest, "%v", err)
+			}
+
+			// Pinning an alias without a new target pins it to the given image.
+			_, ok = req["target"]
+			if !ok && pinnedFingerprint != "" {
+				imgAlias.Target = pinnedFingerprint
+			}
+
+			imgAlias.PinnedFingerprint = pinnedFingerprint
+		}
+
+		imageID, img, err := tx.GetImageByFingerprintPrefix(ctx, imgAlias.Target, dbCluster.ImageFilter{Project: &projectName})
 		if err != nil {
 			return err
 		}
@@ -3877,7 +4665,7 @@ func imageAliasPatch(d *Daemon, r *http.Request) response.Response {
 			return err
 		}
 
-		return nil
+		return imageAliasSetAutoUpdate(ctx, tx, projectName, name, img.Fingerprint, imgAlias.AutoUpdate, imgAlias.PinnedFingerprint)
 	})
 	if err != nil {
 		return response.SmartError(err)
@@ -4008,6 +4796,14 @@ func imageAliasPost(d *Daemon, r *http.Request) response.Response {
 //	Download the raw image file(s) from the server.
 //	If the image is in split format, a multipart http transfer occurs.
 //
+//	If a compression algorithm is given, the image tarballs that don't already use it are recompressed while they
+//	are sent, and the extension of the file names matches the algorithm. Other files, like squashfs or qcow2 root
+//	filesystems, are sent as they are. Recompressed files don't match the image fingerprint.
+//
+//	If previous versions of the image are given as delta sources and no compression algorithm is given, binary deltas
+//	of the image files against the first of them that is available on the server are sent instead, in which case the
+//	`X-LXD-Delta-Source` header is set to its fingerprint. The full image is sent otherwise.
+//
 //	---
 //	produces:
 //	  - application/octet-stream
@@ -4018,9 +4814,26 @@ func imageAliasPost(d *Daemon, r *http.Request) response.Response {
 //	    description: Project name
 //	    type: string
 //	    example: default
+//	  - in: query
+//	    name: compression
+//	    description: Compression algorithm of the image tarballs (none, gzip, zstd or xz)
+//	    type: string
+//	    example: zstd
+//	  - in: query
+//	    name: compression_level
+//	    description: Compression level of the compression algorithm
+//	    type: integer
+//	    example: 19
+//	  - in: query
+//	    name: delta_sources
+//	    description: Comma separated fingerprints of previous versions of the image that the client has
+//	    type: string
+//	    example: 06b86454720d36b20f94e31c6812e05ec51c1b568cf3a8abd273769d213394bb
 //	responses:
 //	  "200":
 //	    description: Raw image data
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
 //	  "403":
 //	    $ref: "#/responses/Forbidden"
 //	  "500":
@@ -4034,6 +4847,31 @@ func imageExport(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	compression := request.QueryParam(r, "compression")
+	if compression != "" && imageCompressionExtensions[compression] == "" {
+		return response.BadRequest(fmt.Errorf("Invalid `compression` query parameter %q", compression))
+	}
+
+	var compressionLevel *int
+	level := request.QueryParam(r, "compression_level")
+	if level != "" {
+		if compression == "" {
+			return response.BadRequest(fmt.Errorf("The `compression_level` query parameter requires `compression`"))
+		}
+
+		n, err := strconv.Atoi(level)
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Invalid `compression_level` query parameter %q", level))
+		}
+
+		compressionLevel = &n
+	}
+
+	compress, err := imageCompressionCommand(compression, compressionLevel)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	// Get the image (expand the fingerprint).
 	var imgInfo *api.Image
 	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
@@ -4095,53 +4933,58 @@ func imageExport(d *Daemon, r *http.Request) response.Response {
 		return response.ForwardedResponse(client, r)
 	}
 
-	imagePath := shared.VarPath("images", imgInfo.Fingerprint)
-	rootfsPath := imagePath + ".rootfs"
+	var deltaSource string
+	var headers map[string]string
 
-	_, ext, _, err := shared.DetectCompression(imagePath)
-	if err != nil {
-		ext = ""
+	deltaSources := request.QueryParam(r, "delta_sources")
+	if deltaSources != "" && compression == "" && s.GlobalConfig.ImagesDeltaTransfer() {
+		deltaSource, err = imageExportDeltaSource(r.Context(), s, projectName, imgInfo, public, strings.Split(deltaSources, ","))
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		if deltaSource != "" {
+			headers = map[string]string{"X-LXD-Delta-Source": deltaSource}
+		}
 	}
 
-	filename := fmt.Sprintf("%s%s", imgInfo.Fingerprint, ext)
+	imagePath := shared.VarPath("images", imgInfo.Fingerprint)
+	rootfsPath := imagePath + ".rootfs"
 
 	if shared.PathExists(rootfsPath) {
 		files := make([]response.FileResponseEntry, 2)
 
+		files[0] = imageExportFile(r.Context(), imagePath, "meta-"+imgInfo.Fingerprint, compression, compress, compressionLevel)
 		files[0].Identifier = "metadata"
-		files[0].Path = imagePath
-		files[0].Filename = "meta-" + filename
-
-		// Recompute the extension for the root filesystem, it may use a different
-		// compression algorithm than the metadata.
-		_, ext, _, err = shared.DetectCompression(rootfsPath)
-		if err != nil {
-			ext = ""
-		}
-
-		filename = fmt.Sprintf("%s%s", imgInfo.Fingerprint, ext)
 
+		// The root filesystem may use a different format than the metadata.
+		files[1] = imageExportFile(r.Context(), rootfsPath, imgInfo.Fingerprint, compression, compress, compressionLevel)
 		if imgInfo.Type == "virtual-machine" {
 			files[1].Identifier = "rootfs.img"
 		} else {
 			files[1].Identifier = "rootfs"
 		}
 
-		files[1].Path = rootfsPath
-		files[1].Filename = filename
+		if deltaSource != "" {
+			imageExportDeltaFile(r.Context(), &files[0], shared.VarPath("images", deltaSource))
+			imageExportDeltaFile(r.Context(), &files[1], shared.VarPath("images", deltaSource+".rootfs"))
+		}
 
-		return response.FileResponse(r, files, nil)
+		return response.FileResponse(r, files, headers)
 	}
 
 	files := make([]response.FileResponseEntry, 1)
-	files[0].Identifier = filename
-	files[0].Path = imagePath
-	files[0].Filename = filename
+	files[0] = imageExportFile(r.Context(), imagePath, imgInfo.Fingerprint, compression, compress, compressionLevel)
+	files[0].Identifier = files[0].Filename
+
+	if deltaSource != "" {
+		imageExportDeltaFile(r.Context(), &files[0], shared.VarPath("images", deltaSource))
+	}
 
 	requestor := request.CreateRequestor(r)
 	s.Events.SendLifecycle(projectName, lifecycle.ImageRetrieved.Event(imgInfo.Fingerprint, projectName, requestor, nil))
 
-	return response.FileResponse(r, files, nil)
+	return response.FileResponse(r, files, headers)
 }
 
 // swagger:operation POST /1.0/images/{fingerprint}/export images images_export_post
@@ -4344,7 +5187,7 @@ func imageSecret(d *Daemon, r *http.Request) response.Response {
 	return createTokenResponse(s, r, projectName, imgInfo.Fingerprint, nil)
 }
 
-func imageImportFromNode(imagesDir string, client lxd.InstanceServer, fingerprint string) error {
+func imageImportFromNode(imagesDir string, client lxd.InstanceServer, fingerprint string, deltaSources []string) error {
 	// Prepare the temp files
 	buildDir, err := os.MkdirTemp(imagesDir, "lxd_build_")
 	if err != nil {
@@ -4368,8 +5211,10 @@ func imageImportFromNode(imagesDir string, client lxd.InstanceServer, fingerprin
 	defer func() { _ = rootfsFile.Close() }()
 
 	getReq := lxd.ImageFileRequest{
-		MetaFile:   io.WriteSeeker(metaFile),
-		RootfsFile: io.WriteSeeker(rootfsFile),
+		MetaFile:                io.WriteSeeker(metaFile),
+		RootfsFile:              io.WriteSeeker(rootfsFile),
+		DeltaSourceRetriever:    imageDeltaSourcePath,
+		DeltaSourceFingerprints: deltaSources,
 	}
 
 	getResp, err := client.GetImageFile(fingerprint, getReq)
@@ -4377,6 +5222,10 @@ func imageImportFromNode(imagesDir string, client lxd.InstanceServer, fingerprin
 		return err
 	}
 
+	if len(deltaSources) > 0 {
+		imageDeltaRecord(getResp)
+	}
+
 	// Truncate down to size
 	if getResp.RootfsSize > 0 {
 		err = rootfsFile.Truncate(getResp.RootfsSize)
@@ -4474,19 +5323,26 @@ func imageRefresh(d *Daemon, r *http.Request) response.Response {
 			return fmt.Errorf("Error getting cluster members for refreshing image %q in project %q: %w", fingerprint, projectName, err)
 		}
 
-		newImage, err := autoUpdateImage(s.ShutdownCtx, s, op, imageID, imageInfo, projectName, true)
+		newImage, keepOld, err := autoUpdateImage(s.ShutdownCtx, s, op, imageID, imageInfo, projectName, true)
 		if err != nil {
 			return fmt.Errorf("Failed to update image %q in project %q: %w", fingerprint, projectName, err)
 		}
 
 		if newImage != nil {
 			if len(nodes) > 1 {
-				err := distributeImage(s.ShutdownCtx, s, nodes, fingerprint, newImage)
+				err := distributeImage(s.ShutdownCtx, s, nodes, projectName, fingerprint, newImage)
 				if err != nil {
 					return fmt.Errorf("Failed to distribute new image %q: %w", newImage.Fingerprint, err)
 				}
 			}
 
+			// The old image is kept for the aliases that don't follow the update.
+			if keepOld {
+				return nil
+			}
+
+			autoUpdateImageDeleteFiles(s, fingerprint)
+
 			err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
 				// Remove the database entry for the image after distributing to cluster members.
 				return tx.DeleteImage(ctx, imageID)
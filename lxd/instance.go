@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -14,6 +15,7 @@ import (
 	"github.com/canonical/lxd/lxd/db"
 	dbCluster "github.com/canonical/lxd/lxd/db/cluster"
 	"github.com/canonical/lxd/lxd/db/operationtype"
+	"github.com/canonical/lxd/lxd/db/warningtype"
 	"github.com/canonical/lxd/lxd/instance"
 	"github.com/canonical/lxd/lxd/instance/instancetype"
 	"github.com/canonical/lxd/lxd/instance/operationlock"
@@ -23,8 +25,10 @@ import (
 	"github.com/canonical/lxd/lxd/state"
 	storagePools "github.com/canonical/lxd/lxd/storage"
 	"github.com/canonical/lxd/lxd/task"
+	"github.com/canonical/lxd/lxd/warnings"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/revert"
 )
@@ -76,7 +80,10 @@ func instanceImageTransfer(s *state.State, r *http.Request, projectName string,
 
 	client = client.UseProject(projectName)
 
-	err = imageImportFromNode(filepath.Join(s.OS.VarDir, "images"), client, hash)
+	// Previous versions of the image available on this member can be used as delta sources.
+	deltaSources := imageDeltaSources(s, projectName, hash, "", "")
+
+	err = imageImportFromNode(filepath.Join(s.OS.VarDir, "images"), client, hash, deltaSources)
 	if err != nil {
 		return err
 	}
@@ -97,6 +104,9 @@ func ensureImageIsLocallyAvailable(s *state.State, r *http.Request, img *api.Ima
 
 	defer unlock()
 
+	// Images shared by another project are transferred and recorded in the project that owns them.
+	projectName = imageProjectName(img, projectName)
+
 	var memberAddress string
 
 	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
@@ -151,6 +161,7 @@ func instanceCreateFromImage(s *state.State, r *http.Request, img *api.Image, ar
 
 	// Set the BaseImage field (regardless of previous value).
 	args.BaseImage = img.Fingerprint
+	args.Config["volatile.base_image.architecture"] = img.Architecture
 
 	// Create the instance.
 	inst, instOp, cleanup, err := instance.CreateInternal(s, args, true)
@@ -162,7 +173,7 @@ func instanceCreateFromImage(s *state.State, r *http.Request, img *api.Image, ar
 	defer instOp.Done(nil)
 
 	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
-		err = tx.UpdateImageLastUseDate(ctx, args.Project, img.Fingerprint, time.Now().UTC())
+		err = tx.UpdateImageLastUseDate(ctx, imageProjectName(img, args.Project), img.Fingerprint, time.Now().UTC())
 		if err != nil {
 			return fmt.Errorf("Error updating image last use date: %w", err)
 		}
@@ -235,6 +246,88 @@ type instanceCreateAsCopyOpts struct {
 	refresh              bool              // Refresh an existing target instance.
 	applyTemplateTrigger bool              // Apply deferred TemplateTriggerCopy.
 	allowInconsistent    bool              // Ignore some copy errors
+	snapshotsLimit       int               // Only copy the most recent snapshots (0 for all).
+	skipDevices          []string          // Devices not to copy to the snapshots.
+	deviceVolumes        map[string]string // Custom volumes to use for the disk devices of the snapshots.
+}
+
+// instanceCopyDevices removes the skipped devices from the devices of an instance being copied and points the
+// remapped disk devices at the given custom volumes. It then checks that the custom volumes used by the resulting
+// disk devices exist in the target project, so that the copy fails before any data is transferred.
+func instanceCopyDevices(s *state.State, targetProject string, devices map[string]map[string]string, skipDevices []string, deviceVolumes map[string]string) error {
+	for _, devName := range skipDevices {
+		dev, ok := devices[devName]
+		if !ok {
+			return api.StatusErrorf(http.StatusBadRequest, "Device %q to skip doesn't exist", devName)
+		}
+
+		if instancetype.IsRootDiskDevice(dev) {
+			return api.StatusErrorf(http.StatusBadRequest, "Root disk device %q cannot be skipped", devName)
+		}
+
+		delete(devices, devName)
+	}
+
+	for devName, volName := range deviceVolumes {
+		dev, ok := devices[devName]
+		if !ok {
+			return api.StatusErrorf(http.StatusBadRequest, "Device %q to remap doesn't exist", devName)
+		}
+
+		if !instanceCopyIsCustomVolumeDisk(dev) {
+			return api.StatusErrorf(http.StatusBadRequest, "Device %q isn't a custom volume disk", devName)
+		}
+
+		if volName == "" || shared.IsSnapshot(volName) {
+			return api.StatusErrorf(http.StatusBadRequest, "Invalid custom volume %q for device %q", volName, devName)
+		}
+
+		newDev := make(map[string]string, len(dev))
+		for k, v := range dev {
+			newDev[k] = v
+		}
+
+		newDev["source"] = volName
+		devices[devName] = newDev
+	}
+
+	storageProject, err := project.StorageVolumeProject(s.DB.Cluster, targetProject, dbCluster.StoragePoolVolumeTypeCustom)
+	if err != nil {
+		return err
+	}
+
+	return s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		for devName, dev := range devices {
+			if !instanceCopyIsCustomVolumeDisk(dev) {
+				continue
+			}
+
+			poolID, err := tx.GetStoragePoolID(ctx, dev["pool"])
+			if err != nil {
+				if api.StatusErrorCheck(err, http.StatusNotFound) {
+					return api.StatusErrorf(http.StatusBadRequest, "Storage pool %q of device %q not found", dev["pool"], devName)
+				}
+
+				return err
+			}
+
+			_, err = tx.GetStoragePoolVolume(ctx, poolID, storageProject, dbCluster.StoragePoolVolumeTypeCustom, dev["source"], true)
+			if err != nil {
+				if api.StatusErrorCheck(err, http.StatusNotFound) {
+					return api.StatusErrorf(http.StatusBadRequest, "Custom volume %q of device %q not found in project %q", dev["source"], devName, storageProject)
+				}
+
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// instanceCopyIsCustomVolumeDisk returns true if the device is a disk device backed by a custom volume.
+func instanceCopyIsCustomVolumeDisk(dev map[string]string) bool {
+	return dev["type"] == "disk" && dev["pool"] != "" && dev["source"] != "" && dev["path"] != "/"
 }
 
 // instanceCreateAsCopy create a new instance by copying from an existing instance.
@@ -291,6 +384,11 @@ func instanceCreateAsCopy(s *state.State, opts instanceCreateAsCopyOpts, op *ope
 				return nil, err
 			}
 
+			// Only keep the most recent snapshots if requested.
+			if opts.snapshotsLimit > 0 && len(sourceSnaps) > opts.snapshotsLimit {
+				sourceSnaps = sourceSnaps[len(sourceSnaps)-opts.snapshotsLimit:]
+			}
+
 			sourceSnapshotComparable := make([]storagePools.ComparableSnapshot, 0, len(sourceSnaps))
 			for _, sourceSnap := range sourceSnaps {
 				_, sourceSnapName, _ := api.GetParentAndSnapshotName(sourceSnap.Name())
@@ -337,11 +435,28 @@ func instanceCreateAsCopy(s *state.State, opts instanceCreateAsCopyOpts, op *ope
 			if err != nil {
 				return nil, err
 			}
+
+			// Only keep the most recent snapshots if requested.
+			if opts.snapshotsLimit > 0 && len(snapshots) > opts.snapshotsLimit {
+				snapshots = snapshots[len(snapshots)-opts.snapshotsLimit:]
+			}
 		}
 
 		for _, srcSnap := range snapshots {
 			snapLocalDevices := srcSnap.LocalDevices().Clone()
 
+			// Apply the same device exclusions and volume remapping as for the instance.
+			for _, devName := range opts.skipDevices {
+				delete(snapLocalDevices, devName)
+			}
+
+			for devName, volName := range opts.deviceVolumes {
+				dev, ok := snapLocalDevices[devName]
+				if ok && instanceCopyIsCustomVolumeDisk(dev) {
+					dev["source"] = volName
+				}
+			}
+
 			// Load snap root disk from expanded devices (in case it doesn't have its own root disk).
 			snapExpandedRootDiskDevKey, snapExpandedRootDiskDev, err := instancetype.GetRootDiskDevice(srcSnap.ExpandedDevices().CloneNative())
 			if err == nil {
@@ -420,7 +535,7 @@ func instanceCreateAsCopy(s *state.State, opts instanceCreateAsCopyOpts, op *ope
 			return nil, fmt.Errorf("Refresh instance: %w", err)
 		}
 	} else {
-		err = pool.CreateInstanceFromCopy(inst, opts.sourceInstance, !opts.instanceOnly, opts.allowInconsistent, op)
+		err = pool.CreateInstanceFromCopy(inst, opts.sourceInstance, snapshots, opts.allowInconsistent, op)
 		if err != nil {
 			return nil, fmt.Errorf("Create instance from copy: %w", err)
 		}
@@ -478,26 +593,102 @@ func instanceLoadNodeProjectAll(ctx context.Context, s *state.State, project str
 	return instances, nil
 }
 
-func autoCreateInstanceSnapshots(ctx context.Context, s *state.State, instances []instance.Instance) error {
+// scheduledInstanceSnapshots are the snapshot schedules of an instance that are due.
+type scheduledInstanceSnapshots struct {
+	inst instance.Instance
+
+	// schedules are the names of the due schedules, where an empty name is the schedule of snapshots.schedule.
+	schedules []string
+}
+
+// instanceSnapshotSchedulesDue returns the names of the snapshot schedules in the given expanded instance config that
+// are due now. The schedule of snapshots.schedule is returned as an empty name, followed by the due named schedules
+// sorted by name. Schedules may overlap, in which case a snapshot is taken for each of them.
+func instanceSnapshotSchedulesDue(config map[string]string, instanceID int64) []string {
+	var schedules []string
+
+	schedule := config["snapshots.schedule"]
+	if schedule != "" && snapshotIsScheduledNow(schedule, instanceID) {
+		schedules = append(schedules, "")
+	}
+
+	for _, name := range instancetype.SnapshotScheduleNames(config) {
+		if snapshotIsScheduledNow(config["snapshots.schedule."+name], instanceID) {
+			schedules = append(schedules, name)
+		}
+	}
+
+	return schedules
+}
+
+func autoCreateInstanceSnapshots(ctx context.Context, s *state.State, instances []scheduledInstanceSnapshots) error {
 	// Make the snapshots.
-	for _, inst := range instances {
-		err := ctx.Err()
-		if err != nil {
-			return err
+	var failures int
+	for _, scheduled := range instances {
+		failed := false
+		for _, schedule := range scheduled.schedules {
+			err := ctx.Err()
+			if err != nil {
+				return err
+			}
+
+			// Carry on with the other schedules and instances, the failure is recorded against the instance.
+			err = autoCreateInstanceSnapshot(s, scheduled.inst, schedule)
+			if err != nil {
+				failed = true
+			}
 		}
 
-		l := logger.AddContext(logger.Ctx{"project": inst.Project().Name, "instance": inst.Name()})
+		if failed {
+			failures++
+		}
+	}
 
-		snapshotName, err := instance.NextSnapshotName(s, inst, "snap%d")
+	if failures > 0 {
+		return fmt.Errorf("Failed creating scheduled snapshots of %d instances", failures)
+	}
+
+	return nil
+}
+
+// autoCreateInstanceSnapshot creates a scheduled snapshot of the instance and records its outcome. The snapshot is
+// named using the snapshots.pattern of the instance and expires according to its snapshots.expiry.
+//
+// If a schedule name is given, the snapshot is taken for the named schedule instead. It is then named using the
+// snapshots.pattern.<name> of the instance, expires according to its snapshots.schedule.<name>.expiry, both falling
+// back to the keys of snapshots.schedule, and is tagged with the name of the schedule.
+func autoCreateInstanceSnapshot(s *state.State, inst instance.Instance, schedule string) error {
+	l := logger.AddContext(logger.Ctx{"project": inst.Project().Name, "instance": inst.Name(), "schedule": schedule})
+
+	snapshot := func() error {
+		config := inst.ExpandedConfig()
+		patternKey := "snapshots.pattern"
+		expiryKey := "snapshots.expiry"
+		if schedule != "" {
+			if config["snapshots.pattern."+schedule] != "" {
+				patternKey = "snapshots.pattern." + schedule
+			}
+
+			if config["snapshots.schedule."+schedule+".expiry"] != "" {
+				expiryKey = "snapshots.schedule." + schedule + ".expiry"
+			}
+		}
+
+		pattern := config[patternKey]
+		if pattern == "" {
+			pattern = "snap%d"
+		}
+
+		snapshotName, err := instance.NextSnapshotNameFromPattern(s, inst, pattern)
 		if err != nil {
 			l.Error("Error retrieving next snapshot name", logger.Ctx{"err": err})
-			return err
+			return fmt.Errorf("Failed retrieving next snapshot name: %w", err)
 		}
 
-		expiry, err := shared.GetExpiry(time.Now(), inst.ExpandedConfig()["snapshots.expiry"])
+		expiry, err := shared.GetExpiry(time.Now(), config[expiryKey])
 		if err != nil {
-			l.Error("Error getting snapshots.expiry date")
-			return err
+			l.Error("Error getting " + expiryKey + " date")
+			return fmt.Errorf("Failed getting %s date: %w", expiryKey, err)
 		}
 
 		err = inst.Snapshot(snapshotName, expiry, false)
@@ -505,9 +696,87 @@ func autoCreateInstanceSnapshots(ctx context.Context, s *state.State, instances
 			l.Error("Error creating snapshot", logger.Ctx{"snapshot": snapshotName, "err": err})
 			return err
 		}
+
+		if schedule == "" {
+			return nil
+		}
+
+		// Tag the snapshot with its schedule.
+		snap, err := instance.LoadByProjectAndName(s, inst.Project().Name, inst.Name()+shared.SnapshotDelimiter+snapshotName)
+		if err != nil {
+			return fmt.Errorf("Failed loading snapshot %q: %w", snapshotName, err)
+		}
+
+		err = snap.VolatileSet(map[string]string{"volatile.snapshot.schedule": schedule})
+		if err != nil {
+			return fmt.Errorf("Failed tagging snapshot %q with its schedule: %w", snapshotName, err)
+		}
+
+		return nil
 	}
 
-	return nil
+	err := snapshot()
+	autoCreateInstanceSnapshotOutcome(s, inst, err)
+
+	return err
+}
+
+// autoCreateInstanceSnapshotOutcome records the outcome of a scheduled snapshot of the instance in its volatile
+// config. A warning is raised once the number of consecutive failures reaches snapshots.schedule.failure_threshold,
+// and resolved by the next successful scheduled snapshot.
+func autoCreateInstanceSnapshotOutcome(s *state.State, inst instance.Instance, snapshotErr error) {
+	l := logger.AddContext(logger.Ctx{"project": inst.Project().Name, "instance": inst.Name()})
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	if snapshotErr == nil {
+		err := inst.VolatileSet(map[string]string{
+			"volatile.snapshots.schedule.last_success": now,
+			"volatile.snapshots.schedule.failures":     "",
+		})
+		if err != nil {
+			l.Warn("Failed recording scheduled snapshot success", logger.Ctx{"err": err})
+		}
+
+		err = warnings.ResolveWarningsByLocalNodeAndProjectAndTypeAndEntity(s.DB.Cluster, inst.Project().Name, warningtype.InstanceScheduledSnapshotFailure, entity.TypeInstance, inst.ID())
+		if err != nil {
+			l.Warn("Failed to resolve scheduled snapshot failure warning", logger.Ctx{"err": err})
+		}
+
+		return
+	}
+
+	failures, _ := strconv.Atoi(inst.LocalConfig()["volatile.snapshots.schedule.failures"])
+	failures++
+
+	err := inst.VolatileSet(map[string]string{
+		"volatile.snapshots.schedule.last_failure": now,
+		"volatile.snapshots.schedule.last_error":   snapshotErr.Error(),
+		"volatile.snapshots.schedule.failures":     strconv.Itoa(failures),
+	})
+	if err != nil {
+		l.Warn("Failed recording scheduled snapshot failure", logger.Ctx{"err": err})
+	}
+
+	threshold := 3
+	thresholdStr := inst.ExpandedConfig()["snapshots.schedule.failure_threshold"]
+	if thresholdStr != "" {
+		threshold, err = strconv.Atoi(thresholdStr)
+		if err != nil {
+			l.Warn("Invalid snapshots.schedule.failure_threshold", logger.Ctx{"err": err})
+			return
+		}
+	}
+
+	if threshold == 0 || failures < threshold {
+		return
+	}
+
+	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		return tx.UpsertWarningLocalNode(ctx, inst.Project().Name, entity.TypeInstance, inst.ID(), warningtype.InstanceScheduledSnapshotFailure, fmt.Sprintf("%d consecutive scheduled snapshots failed: %v", failures, snapshotErr))
+	})
+	if err != nil {
+		l.Warn("Failed to create scheduled snapshot failure warning", logger.Ctx{"err": err})
+	}
 }
 
 var instSnapshotsPruneRunning = sync.Map{}
@@ -541,7 +810,16 @@ func pruneExpiredAndAutoCreateInstanceSnapshotsTask(d *Daemon) (task.Func, task.
 	// `f` creates new scheduled instance snapshots and then, prune the expired ones
 	f := func(ctx context.Context) {
 		s := d.State()
-		var instances, expiredSnapshotInstances []instance.Instance
+		var instances []scheduledInstanceSnapshots
+		var expiredSnapshotInstances []instance.Instance
+
+		// Instances using a snapshot retention policy, with the rules of the policy.
+		type retentionInstance struct {
+			inst  instance.Instance
+			rules api.SnapshotRetentionRules
+		}
+
+		var retentionInstances []retentionInstance
 
 		// Get list of expired instance snapshots for this local member.
 		err := s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
@@ -619,6 +897,21 @@ func pruneExpiredAndAutoCreateInstanceSnapshotsTask(d *Daemon) (task.Func, task.
 
 		err = s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
 			return tx.InstanceList(ctx, func(dbInst db.InstanceArgs, p api.Project) error {
+				// Snapshots that the retention policy of the instance doesn't keep are expired too.
+				retentionRules, err := snapshotRetentionPolicyRules(ctx, tx, dbInst.Project, instancetype.ExpandInstanceConfig(nil, dbInst.Config, dbInst.Profiles))
+				if err != nil {
+					return fmt.Errorf("Failed loading snapshot retention policy of instance %q (project %q): %w", dbInst.Name, dbInst.Project, err)
+				}
+
+				if retentionRules != nil {
+					inst, err := instance.Load(s, dbInst, p)
+					if err != nil {
+						return fmt.Errorf("Failed loading instance %q (project %q) for snapshot task: %w", dbInst.Name, dbInst.Project, err)
+					}
+
+					retentionInstances = append(retentionInstances, retentionInstance{inst: inst, rules: *retentionRules})
+				}
+
 				err = project.AllowSnapshotCreation(&p)
 				if err != nil {
 					return nil
@@ -629,14 +922,9 @@ func pruneExpiredAndAutoCreateInstanceSnapshotsTask(d *Daemon) (task.Func, task.
 					return fmt.Errorf("Failed loading instance %q (project %q) for snapshot task: %w", dbInst.Name, dbInst.Project, err)
 				}
 
-				// Check if instance has snapshot schedule enabled.
-				schedule, ok := inst.ExpandedConfig()["snapshots.schedule"]
-				if !ok || schedule == "" {
-					return nil
-				}
-
-				// Check if snapshot is scheduled.
-				if !snapshotIsScheduledNow(schedule, int64(inst.ID())) {
+				// Check if a snapshot schedule of the instance is due.
+				schedules := instanceSnapshotSchedulesDue(inst.ExpandedConfig(), int64(inst.ID()))
+				if len(schedules) == 0 {
 					return nil
 				}
 
@@ -645,8 +933,8 @@ func pruneExpiredAndAutoCreateInstanceSnapshotsTask(d *Daemon) (task.Func, task.
 					return nil
 				}
 
-				logger.Debug("Scheduling auto instance snapshot", logger.Ctx{"instance": inst.Name(), "project": inst.Project().Name})
-				instances = append(instances, inst)
+				logger.Debug("Scheduling auto instance snapshot", logger.Ctx{"instance": inst.Name(), "project": inst.Project().Name, "schedules": schedules})
+				instances = append(instances, scheduledInstanceSnapshots{inst: inst, schedules: schedules})
 
 				return nil
 			}, filter)
@@ -656,6 +944,29 @@ func pruneExpiredAndAutoCreateInstanceSnapshotsTask(d *Daemon) (task.Func, task.
 			return
 		}
 
+		expiredSnapshotIDs := make(map[int]bool, len(expiredSnapshotInstances))
+		for _, snapshot := range expiredSnapshotInstances {
+			expiredSnapshotIDs[snapshot.ID()] = true
+		}
+
+		for _, retention := range retentionInstances {
+			snapshots, err := snapshotRetentionPolicyExpiredInstanceSnapshots(retention.inst, retention.rules)
+			if err != nil {
+				logger.Error("Failed evaluating snapshot retention policy", logger.Ctx{"instance": retention.inst.Name(), "project": retention.inst.Project().Name, "err": err})
+				continue
+			}
+
+			for _, snapshot := range snapshots {
+				if expiredSnapshotIDs[snapshot.ID()] {
+					continue
+				}
+
+				logger.Debug("Scheduling instance snapshot expiry by retention policy", logger.Ctx{"instance": snapshot.Name(), "project": snapshot.Project().Name})
+				expiredSnapshotIDs[snapshot.ID()] = true
+				expiredSnapshotInstances = append(expiredSnapshotInstances, snapshot)
+			}
+		}
+
 		// Handle snapshot expiry first before creating new ones to reduce the chances of running out of
 		// disk space.
 		if len(expiredSnapshotInstances) > 0 {
@@ -749,8 +1060,8 @@ func getSourceImageFromInstanceSource(ctx context.Context, s *state.State, tx *d
 		}
 	}
 
-	// Check if image has an entry in the database.
-	_, sourceImage, err := tx.GetImageByFingerprintPrefix(ctx, sourceImageHash, dbCluster.ImageFilter{Project: &project})
+	// Check if image has an entry in the database, either in the project or shared with it by another project.
+	_, sourceImage, err := tx.GetProjectUsableImage(ctx, project, sourceImageHash)
 	if err != nil {
 		return nil, err
 	}
@@ -29,6 +29,7 @@ import (
 	"github.com/canonical/lxd/lxd/project"
 	"github.com/canonical/lxd/lxd/state"
 	storagePools "github.com/canonical/lxd/lxd/storage"
+	storageDrivers "github.com/canonical/lxd/lxd/storage/drivers"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/entity"
@@ -322,6 +323,74 @@ func (d *common) Snapshots() ([]instance.Instance, error) {
 	return snapshots, nil
 }
 
+// scheduledSnapshotsState returns the outcome of the scheduled snapshots of the instance, or nil if no scheduled
+// snapshot has been attempted yet.
+func (d *common) scheduledSnapshotsState() *api.InstanceStateScheduledSnapshots {
+	lastSuccess := d.localConfig["volatile.snapshots.schedule.last_success"]
+	lastFailure := d.localConfig["volatile.snapshots.schedule.last_failure"]
+	if lastSuccess == "" && lastFailure == "" {
+		return nil
+	}
+
+	state := &api.InstanceStateScheduledSnapshots{
+		LastError: d.localConfig["volatile.snapshots.schedule.last_error"],
+	}
+
+	// Times are stored in RFC3339 format, invalid values are reported as the zero time.
+	state.LastSuccessAt, _ = time.Parse(time.RFC3339, lastSuccess)
+	state.LastFailureAt, _ = time.Parse(time.RFC3339, lastFailure)
+	state.ConsecutiveFailures, _ = strconv.ParseInt(d.localConfig["volatile.snapshots.schedule.failures"], 10, 64)
+
+	return state
+}
+
+// rootFilesystemPath returns the mount path of the root volume of the instance.
+func (d *common) rootFilesystemPath() (string, error) {
+	pool, err := d.getStoragePool()
+	if err != nil {
+		return "", err
+	}
+
+	volType, err := storagePools.InstanceTypeToVolumeType(d.dbType)
+	if err != nil {
+		return "", err
+	}
+
+	return storageDrivers.GetVolumeMountPath(pool.Name(), volType, project.Instance(d.project.Name, d.name)), nil
+}
+
+// filesystemFreezeState returns the freeze of the root filesystem of the instance by LXD, or nil if it isn't
+// frozen by LXD.
+func (d *common) filesystemFreezeState() *api.InstanceStateFilesystemFreeze {
+	path, err := d.rootFilesystemPath()
+	if err != nil {
+		return nil
+	}
+
+	freeze := storageDrivers.FilesystemFrozen(path)
+	if freeze == nil {
+		return nil
+	}
+
+	return &api.InstanceStateFilesystemFreeze{
+		Operation:    freeze.Operation,
+		OperationURL: freeze.OperationURL,
+		FrozenAt:     freeze.FrozenAt,
+		ThawAt:       freeze.ThawAt,
+	}
+}
+
+// UnfreezeFilesystem thaws the root filesystem of the instance, whether or not LXD still tracks it as frozen.
+// This is distinct from Unfreeze which resumes the processes of the instance.
+func (d *common) UnfreezeFilesystem() error {
+	path, err := d.rootFilesystemPath()
+	if err != nil {
+		return err
+	}
+
+	return storageDrivers.FilesystemUnfreeze(path)
+}
+
 // VolatileSet sets one or more volatile config keys.
 func (d *common) VolatileSet(changes map[string]string) error {
 	// Quick check.
@@ -373,6 +442,11 @@ func (d *common) ConsoleBufferLogPath() string {
 	return filepath.Join(d.LogPath(), "console.log")
 }
 
+// ConsoleHistoryPath returns the instance's console history log path.
+func (d *common) ConsoleHistoryPath() string {
+	return filepath.Join(d.Path(), "console-history.log")
+}
+
 // DevicesPath returns the instance's devices path.
 func (d *common) DevicesPath() string {
 	name := project.Instance(d.project.Name, d.name)
@@ -515,11 +589,31 @@ func (d *common) expandConfig() error {
 	}
 
 	d.expandedConfig = instancetype.ExpandInstanceConfig(globalConfigDump, d.localConfig, d.profiles)
-	d.expandedDevices = instancetype.ExpandInstanceDevices(d.localDevices, d.profiles)
+	d.expandedDevices = instancetype.ExpandProjectDeviceDefaults(instancetype.ExpandInstanceDevices(d.localDevices, d.profiles), d.project.Config)
 
 	return nil
 }
 
+// refreshProject reloads the project of the instance from the database, so that changes to the project configuration
+// made since the instance was loaded (such as device defaults) are taken into account by the next expansion.
+func (d *common) refreshProject() error {
+	return d.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		dbProject, err := dbCluster.GetProject(ctx, tx.Tx(), d.project.Name)
+		if err != nil {
+			return err
+		}
+
+		p, err := dbProject.ToAPI(ctx, tx.Tx())
+		if err != nil {
+			return err
+		}
+
+		d.project = *p
+
+		return nil
+	})
+}
+
 // restartCommon handles the common part of instance restarts.
 func (d *common) restartCommon(inst instance.Instance, timeout time.Duration) error {
 	// Setup a new operation for the stop/shutdown phase.
@@ -616,12 +710,14 @@ func (d *common) rebuildCommon(inst instance.Instance, img *api.Image, op *opera
 	}
 
 	delete(instLocalConfig, "volatile.base_image")
+	delete(instLocalConfig, "volatile.base_image.architecture")
 	if img != nil {
 		for k, v := range img.Properties {
 			instLocalConfig[fmt.Sprintf("image.%s", k)] = v
 		}
 
 		instLocalConfig["volatile.base_image"] = img.Fingerprint
+		instLocalConfig["volatile.base_image.architecture"] = img.Architecture
 		instLocalConfig["volatile.uuid.generation"] = instLocalConfig["volatile.uuid"]
 	}
 
@@ -659,7 +755,13 @@ func (d *common) rebuildCommon(inst instance.Instance, img *api.Image, op *opera
 		}
 
 		if img != nil {
-			err = tx.UpdateImageLastUseDate(ctx, inst.Project().Name, img.Fingerprint, time.Now().UTC())
+			// Images shared by another project are recorded in the project that owns them.
+			imageProjectName := inst.Project().Name
+			if img.Origin == api.ImageOriginShared {
+				imageProjectName = img.Project
+			}
+
+			err = tx.UpdateImageLastUseDate(ctx, imageProjectName, img.Fingerprint, time.Now().UTC())
 			if err != nil {
 				return err
 			}
@@ -672,6 +774,14 @@ func (d *common) rebuildCommon(inst instance.Instance, img *api.Image, op *opera
 	}
 
 	d.localConfig = instLocalConfig
+
+	ctxMap := map[string]any{}
+	if img != nil {
+		ctxMap["image"] = img.Fingerprint
+	}
+
+	d.state.Events.SendLifecycle(d.project.Name, lifecycle.InstanceRebuilt.Event(d, ctxMap))
+
 	return nil
 }
 
@@ -697,7 +807,7 @@ func (d *common) snapshotCommon(inst instance.Instance, name string, expiry time
 	args := db.InstanceArgs{
 		Project:      inst.Project().Name,
 		Architecture: inst.Architecture(),
-		Config:       inst.LocalConfig(),
+		Config:       withoutSnapshotScheduleTag(inst.LocalConfig()),
 		Type:         inst.Type(),
 		Snapshot:     true,
 		Devices:      inst.LocalDevices(),
@@ -812,6 +922,22 @@ func (d *common) isStartableStatusCode(statusCode api.StatusCode) error {
 	return nil
 }
 
+// withoutSnapshotScheduleTag returns a copy of the given config without the volatile.snapshot.schedule key. The key
+// tags the snapshots created by a named snapshot schedule, so it must not be copied from an instance to its snapshots
+// or from a snapshot to the instance that it is restored to.
+func withoutSnapshotScheduleTag(config map[string]string) map[string]string {
+	result := make(map[string]string, len(config))
+	for key, value := range config {
+		if key == "volatile.snapshot.schedule" {
+			continue
+		}
+
+		result[key] = value
+	}
+
+	return result
+}
+
 // getStartupSnapNameAndExpiry returns the name and expiry for a snapshot to be taken at startup.
 func (d *common) getStartupSnapNameAndExpiry(inst instance.Instance) (string, *time.Time, error) {
 	schedule := strings.ToLower(d.expandedConfig["snapshots.schedule"])
@@ -1259,6 +1385,21 @@ func (d *common) needsNewInstanceID(changedConfig []string, oldExpandedDevices d
 		}
 	}
 
+	// When the network configuration is generated, any change to the NICs changes it.
+	if d.expandedConfig["cloud-init.network-config"] == device.CloudInitNetworkConfigAuto {
+		for devName, dev := range d.expandedDevices {
+			if dev["type"] == "nic" && !oldExpandedDevices.Contains(devName, dev) {
+				return true
+			}
+		}
+
+		for devName, dev := range oldExpandedDevices {
+			if dev["type"] == "nic" && !d.expandedDevices.Contains(devName, dev) {
+				return true
+			}
+		}
+	}
+
 	return false
 }
 
@@ -1328,7 +1469,7 @@ func (d *common) deviceAdd(dev device.Device, instanceRunning bool) error {
 	l.Debug("Adding device")
 
 	if instanceRunning && !dev.CanHotPlug() {
-		return fmt.Errorf("Device cannot be added when instance is running")
+		return api.StatusErrorf(http.StatusConflict, "Device cannot be added when instance is running")
 	}
 
 	return dev.Add()
@@ -1340,7 +1481,7 @@ func (d *common) deviceRemove(dev device.Device, instanceRunning bool) error {
 	l.Debug("Removing device")
 
 	if instanceRunning && !dev.CanHotPlug() {
-		return fmt.Errorf("Device cannot be removed when instance is running")
+		return api.StatusErrorf(http.StatusConflict, "Device cannot be removed when instance is running")
 	}
 
 	return dev.Remove()
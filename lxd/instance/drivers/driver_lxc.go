@@ -217,6 +217,13 @@ func lxcCreate(s *state.State, args db.InstanceArgs, p api.Project) (instance.In
 		return nil, nil, fmt.Errorf("Invalid config: %w", err)
 	}
 
+	if !d.IsSnapshot() {
+		err = instance.ValidBootAfter(s, d.project.Name, d.name, d.expandedConfig)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Invalid config: %w", err)
+		}
+	}
+
 	err = instance.ValidDevices(s, d.project, d.Type(), d.localDevices, d.expandedDevices)
 	if err != nil {
 		return nil, nil, fmt.Errorf("Invalid devices: %w", err)
@@ -704,17 +711,40 @@ func (d *lxc) initLXC(config bool) (*liblxc.Container, error) {
 			return nil, err
 		}
 
-		err = lxcSetConfigItem(cc, "lxc.console.size", "auto")
+		consoleHistorySize, err := instance.ConsoleHistorySize(d.expandedConfig)
 		if err != nil {
 			return nil, err
 		}
 
-		// File to dump ringbuffer contents to when requested or
-		// container shutdown.
-		consoleBufferLogFile := d.ConsoleBufferLogPath()
-		err = lxcSetConfigItem(cc, "lxc.console.logfile", consoleBufferLogFile)
-		if err != nil {
-			return nil, err
+		if consoleHistorySize > 0 {
+			// Persist the console output to the history log, which liblxc rotates once it reaches its size.
+			err = lxcSetConfigItem(cc, "lxc.console.size", strconv.FormatInt(consoleHistorySize, 10))
+			if err != nil {
+				return nil, err
+			}
+
+			err = lxcSetConfigItem(cc, "lxc.console.rotate", "1")
+			if err != nil {
+				return nil, err
+			}
+
+			err = lxcSetConfigItem(cc, "lxc.console.logfile", d.ConsoleHistoryPath())
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			err = lxcSetConfigItem(cc, "lxc.console.size", "auto")
+			if err != nil {
+				return nil, err
+			}
+
+			// File to dump ringbuffer contents to when requested or
+			// container shutdown.
+			consoleBufferLogFile := d.ConsoleBufferLogPath()
+			err = lxcSetConfigItem(cc, "lxc.console.logfile", consoleBufferLogFile)
+			if err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -1416,7 +1446,7 @@ func (d *lxc) deviceStart(dev device.Device, instanceRunning bool) (*deviceConfi
 	defer revert.Fail()
 
 	if instanceRunning && !dev.CanHotPlug() {
-		return nil, fmt.Errorf("Device cannot be started when instance is running")
+		return nil, api.StatusErrorf(http.StatusConflict, "Device cannot be started when instance is running")
 	}
 
 	runConf, err := dev.Start()
@@ -1575,7 +1605,7 @@ func (d *lxc) deviceStop(dev device.Device, instanceRunning bool, stopHookNetnsP
 	l.Debug("Stopping device")
 
 	if instanceRunning && !dev.CanHotPlug() {
-		return fmt.Errorf("Device cannot be stopped when instance is running")
+		return api.StatusErrorf(http.StatusConflict, "Device cannot be stopped when instance is running")
 	}
 
 	runConf, err := dev.Stop()
@@ -3244,6 +3274,8 @@ func (d *lxc) Render(options ...func(response any) error) (state any, etag any,
 	instState.Profiles = profileNames
 	instState.Stateful = d.stateful
 	instState.Project = d.project.Name
+	instState.ImageArchitecture = d.localConfig["volatile.base_image.architecture"]
+	instState.Personality, _ = osarch.ArchitecturePersonality(d.architecture)
 
 	for _, option := range options {
 		err := option(&instState)
@@ -3333,6 +3365,8 @@ func (d *lxc) renderState(statusCode api.StatusCode, hostInterfaces []net.Interf
 	}
 
 	status.Disk = d.diskState()
+	status.ScheduledSnapshots = d.scheduledSnapshotsState()
+	status.FilesystemFreeze = d.filesystemFreezeState()
 
 	d.release()
 
@@ -3557,7 +3591,7 @@ func (d *lxc) Restore(sourceContainer instance.Instance, stateful bool) error {
 	// Restore the configuration.
 	args := db.InstanceArgs{
 		Architecture: sourceContainer.Architecture(),
-		Config:       sourceContainer.LocalConfig(),
+		Config:       withoutSnapshotScheduleTag(sourceContainer.LocalConfig()),
 		Description:  sourceContainer.Description(),
 		Devices:      sourceContainer.LocalDevices(),
 		Ephemeral:    sourceContainer.IsEphemeral(),
@@ -4163,6 +4197,7 @@ func (d *lxc) Update(args db.InstanceArgs, userRequested bool) error {
 	}
 
 	oldExpiryDate := d.expiryDate
+	oldProject := d.project
 
 	// Define a function which reverts everything.  Defer this function
 	// so that it doesn't need to be explicitly called in every failing
@@ -4180,6 +4215,7 @@ func (d *lxc) Update(args db.InstanceArgs, userRequested bool) error {
 			d.localDevices = oldLocalDevices
 			d.profiles = oldProfiles
 			d.expiryDate = oldExpiryDate
+			d.project = oldProject
 			d.release()
 			d.cConfig = false
 			_, _ = d.initLXC(true)
@@ -4196,6 +4232,12 @@ func (d *lxc) Update(args db.InstanceArgs, userRequested bool) error {
 	d.profiles = args.Profiles
 	d.expiryDate = args.ExpiryDate
 
+	// Reload the project as its device defaults may have changed since the instance was loaded.
+	err = d.refreshProject()
+	if err != nil {
+		return err
+	}
+
 	// Expand the config and refresh the LXC config
 	err = d.expandConfig()
 	if err != nil {
@@ -4292,6 +4334,11 @@ func (d *lxc) Update(args db.InstanceArgs, userRequested bool) error {
 			return fmt.Errorf("Invalid expanded config: %w", err)
 		}
 
+		err = instance.ValidBootAfter(d.state, d.project.Name, d.name, d.expandedConfig)
+		if err != nil {
+			return fmt.Errorf("Invalid expanded config: %w", err)
+		}
+
 		// Do full expanded validation of the devices diff.
 		err = instance.ValidDevices(d.state, d.project, d.Type(), d.localDevices, d.expandedDevices)
 		if err != nil {
@@ -6611,6 +6658,22 @@ func (d *lxc) templateApplyNow(trigger instance.TemplateTrigger) error {
 		containerMeta["privileged"] = "false"
 	}
 
+	// Provide the generated network configuration to the templates if requested.
+	templateConfig := d.expandedConfig
+	if templateConfig["cloud-init.network-config"] == device.CloudInitNetworkConfigAuto {
+		networkConfig, err := device.CloudInitNetworkConfig(d.state, d)
+		if err != nil {
+			return err
+		}
+
+		templateConfig = make(map[string]string, len(d.expandedConfig))
+		for k, v := range d.expandedConfig {
+			templateConfig[k] = v
+		}
+
+		templateConfig["cloud-init.network-config"] = networkConfig
+	}
+
 	// Go through the templates
 	for tplPath, tpl := range metadata.Templates {
 		err = func(tplPath string, tpl *api.ImageMetadataTemplate) error {
@@ -6682,7 +6745,7 @@ func (d *lxc) templateApplyNow(trigger instance.TemplateTrigger) error {
 			}
 
 			configGet := func(confKey, confDefault *pongo2.Value) *pongo2.Value {
-				val, ok := d.expandedConfig[confKey.String()]
+				val, ok := templateConfig[confKey.String()]
 				if !ok {
 					return confDefault
 				}
@@ -6695,7 +6758,7 @@ func (d *lxc) templateApplyNow(trigger instance.TemplateTrigger) error {
 				"path":       tplPath,
 				"container":  containerMeta,
 				"instance":   containerMeta,
-				"config":     d.expandedConfig,
+				"config":     templateConfig,
 				"devices":    d.expandedDevices,
 				"properties": tpl.Properties,
 				"config_get": configGet}, w)
@@ -7396,8 +7459,17 @@ func (d *lxc) networkState(hostInterfaces []net.Interface) map[string]api.Instan
 	for name, dev := range result {
 		if dev.HostName == "" {
 			dev.HostName = d.localConfig[fmt.Sprintf("volatile.%s.host_name", name)]
-			result[name] = dev
 		}
+
+		// Get the number of queues from the host side interface.
+		if dev.HostName != "" {
+			queues, err := network.GetDevQueues(dev.HostName)
+			if err == nil {
+				dev.Queues = int(queues)
+			}
+		}
+
+		result[name] = dev
 	}
 
 	return result
@@ -281,6 +281,13 @@ func qemuCreate(s *state.State, args db.InstanceArgs, p api.Project) (instance.I
 		return nil, nil, fmt.Errorf("Invalid config: %w", err)
 	}
 
+	if !d.IsSnapshot() {
+		err = instance.ValidBootAfter(s, d.project.Name, d.name, d.expandedConfig)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Invalid config: %w", err)
+		}
+	}
+
 	err = instance.ValidDevices(s, d.project, d.Type(), d.localDevices, d.expandedDevices)
 	if err != nil {
 		return nil, nil, fmt.Errorf("Invalid devices: %w", err)
@@ -648,6 +655,7 @@ func (d *qemu) onStop(target string) error {
 	d.cleanupDevices() // Must be called before unmount.
 	_ = os.Remove(d.pidFilePath())
 	_ = os.Remove(d.monitorPath())
+	qemuDiskIOSampleForget(d.id)
 
 	// Stop the storage for the instance.
 	err = d.unmount()
@@ -1391,7 +1399,7 @@ func (d *qemu) start(stateful bool, op *operationlock.InstanceOperation) error {
 	// This is used by the lxd-agent in preference to 9p (due to its improved performance) and in scenarios
 	// where 9p isn't available in the VM guest OS.
 	configSockPath, configPIDPath := d.configVirtiofsdPaths()
-	revertFunc, unixListener, err := device.DiskVMVirtiofsdStart(d.state.OS.ExecPath, d, configSockPath, configPIDPath, "", configMntPath, nil)
+	revertFunc, unixListener, err := device.DiskVMVirtiofsdStart(d.state.OS.ExecPath, d, configSockPath, configPIDPath, "", configMntPath, nil, false)
 	if err != nil {
 		var errUnsupported device.UnsupportedError
 		if !errors.As(err, &errUnsupported) {
@@ -2121,7 +2129,7 @@ func (d *qemu) deviceStart(dev device.Device, instanceRunning bool) (*deviceConf
 	defer revert.Fail()
 
 	if instanceRunning && !dev.CanHotPlug() {
-		return nil, fmt.Errorf("Device cannot be started when instance is running")
+		return nil, api.StatusErrorf(http.StatusConflict, "Device cannot be started when instance is running")
 	}
 
 	runConf, err := dev.Start()
@@ -2449,7 +2457,7 @@ func (d *qemu) deviceStop(dev device.Device, instanceRunning bool, _ string) err
 	l.Debug("Stopping device")
 
 	if instanceRunning && !dev.CanHotPlug() {
-		return fmt.Errorf("Device cannot be stopped when instance is running")
+		return api.StatusErrorf(http.StatusConflict, "Device cannot be stopped when instance is running")
 	}
 
 	runConf, err := dev.Stop()
@@ -3201,8 +3209,23 @@ func (d *qemu) generateQemuConfigFile(cpuInfo *cpuTopology, mountInfo *storagePo
 	// QMP socket.
 	cfg = append(cfg, qemuControlSocket(&qemuControlSocketOpts{d.monitorPath()})...)
 
-	// Console output.
-	cfg = append(cfg, qemuConsole(&qemuConsoleOpts{d.consolePath()})...)
+	// Console output, logged to the console history if enabled.
+	consoleOpts := qemuConsoleOpts{path: d.consolePath()}
+	consoleHistorySize, err := instance.ConsoleHistorySize(d.expandedConfig)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if consoleHistorySize > 0 {
+		err = instance.ConsoleHistoryRotate(d.ConsoleHistoryPath(), consoleHistorySize)
+		if err != nil {
+			return "", nil, fmt.Errorf("Failed rotating console history: %w", err)
+		}
+
+		consoleOpts.logPath = d.ConsoleHistoryPath()
+	}
+
+	cfg = append(cfg, qemuConsole(&consoleOpts)...)
 
 	// Setup the bus allocator.
 	bus := qemuNewBus(busName, &cfg)
@@ -4169,7 +4192,7 @@ func (d *qemu) addNetDevConfig(busName string, qemuDev map[string]string, bootIn
 	reverter := revert.New()
 	defer reverter.Fail()
 
-	var devName, nicName, devHwaddr, pciSlotName, pciIOMMUGroup, vDPADevName, vhostVDPAPath, maxVQP, mtu, name string
+	var devName, nicName, devHwaddr, pciSlotName, pciIOMMUGroup, vDPADevName, vhostVDPAPath, maxVQP, mtu, name, queues, rxRingSize, txRingSize string
 	for _, nicItem := range nicConfig {
 		if nicItem.Key == "devName" {
 			devName = nicItem.Value
@@ -4191,6 +4214,12 @@ func (d *qemu) addNetDevConfig(busName string, qemuDev map[string]string, bootIn
 			mtu = nicItem.Value
 		} else if nicItem.Key == "name" {
 			name = nicItem.Value
+		} else if nicItem.Key == "queues" {
+			queues = nicItem.Value
+		} else if nicItem.Key == "rxRingSize" {
+			rxRingSize = nicItem.Value
+		} else if nicItem.Key == "txRingSize" {
+			txRingSize = nicItem.Value
 		}
 	}
 
@@ -4213,15 +4242,24 @@ func (d *qemu) addNetDevConfig(busName string, qemuDev map[string]string, bootIn
 
 	var monHook func(m *qmp.Monitor) error
 
-	// configureQueues modifies qemuDev with the queue configuration based on vCPUs.
+	// configureQueues modifies qemuDev with the queue configuration based on the NIC's queues setting or, if it
+	// isn't set or is "auto", on vCPUs.
 	// Returns the number of queues to use with NIC.
-	configureQueues := func(cpuCount int) int {
+	configureQueues := func(cpuCount int) (int, error) {
 		// Number of queues is the same as number of vCPUs. Run with a minimum of two queues.
 		queueCount := cpuCount
 		if queueCount < 2 {
 			queueCount = 2
 		}
 
+		if queues != "" && queues != "auto" {
+			var err error
+			queueCount, err = strconv.Atoi(queues)
+			if err != nil {
+				return -1, fmt.Errorf("Invalid queues %q: %w", queues, err)
+			}
+		}
+
 		// Number of vectors is number of vCPUs * 2 (RX/TX) + 2 (config/control MSI-X).
 		vectors := 2*queueCount + 2
 		if vectors > 0 {
@@ -4231,7 +4269,15 @@ func (d *qemu) addNetDevConfig(busName string, qemuDev map[string]string, bootIn
 			}
 		}
 
-		return queueCount
+		if rxRingSize != "" {
+			qemuDev["rx_queue_size"] = rxRingSize
+		}
+
+		if txRingSize != "" {
+			qemuDev["tx_queue_size"] = txRingSize
+		}
+
+		return queueCount, nil
 	}
 
 	// tapMonHook is a helper function used as the monitor hook for macvtap and tap interfaces to open
@@ -4246,7 +4292,10 @@ func (d *qemu) addNetDevConfig(busName string, qemuDev map[string]string, bootIn
 				return fmt.Errorf("Failed getting CPU list for NIC queues")
 			}
 
-			queueCount := configureQueues(len(cpus))
+			queueCount, err := configureQueues(len(cpus))
+			if err != nil {
+				return err
+			}
 
 			// Enable vhost_net offloading if available.
 			info := DriverStatuses()[instancetype.VM].Info
@@ -5101,7 +5150,7 @@ func (d *qemu) Restore(source instance.Instance, stateful bool) error {
 	// Restore the configuration.
 	args := db.InstanceArgs{
 		Architecture: source.Architecture(),
-		Config:       source.LocalConfig(),
+		Config:       withoutSnapshotScheduleTag(source.LocalConfig()),
 		Description:  source.Description(),
 		Devices:      source.LocalDevices(),
 		Ephemeral:    source.IsEphemeral(),
@@ -5456,6 +5505,7 @@ func (d *qemu) Update(args db.InstanceArgs, userRequested bool) error {
 	}
 
 	oldExpiryDate := d.expiryDate
+	oldProject := d.project
 
 	// Revert local changes if update fails.
 	revert.Add(func() {
@@ -5468,6 +5518,7 @@ func (d *qemu) Update(args db.InstanceArgs, userRequested bool) error {
 		d.localDevices = oldLocalDevices
 		d.profiles = oldProfiles
 		d.expiryDate = oldExpiryDate
+		d.project = oldProject
 	})
 
 	// Apply the various changes to local vars.
@@ -5479,6 +5530,12 @@ func (d *qemu) Update(args db.InstanceArgs, userRequested bool) error {
 	d.profiles = args.Profiles
 	d.expiryDate = args.ExpiryDate
 
+	// Reload the project as its device defaults may have changed since the instance was loaded.
+	err = d.refreshProject()
+	if err != nil {
+		return err
+	}
+
 	// Expand the config.
 	err = d.expandConfig()
 	if err != nil {
@@ -5556,6 +5613,11 @@ func (d *qemu) Update(args db.InstanceArgs, userRequested bool) error {
 			return fmt.Errorf("Invalid expanded config: %w", err)
 		}
 
+		err = instance.ValidBootAfter(d.state, d.project.Name, d.name, d.expandedConfig)
+		if err != nil {
+			return fmt.Errorf("Invalid expanded config: %w", err)
+		}
+
 		// Do full expanded validation of the devices diff.
 		err = instance.ValidDevices(d.state, d.project, d.Type(), d.localDevices, d.expandedDevices)
 		if err != nil {
@@ -6741,6 +6803,13 @@ func (d *qemu) migrateSendLive(pool storagePools.Pool, clusterMoveSourceName str
 	// shared storage and avoid needing to sync the root disk.
 	sharedStorage := clusterMoveSourceName != "" && pool.Driver().Info().Remote
 
+	// Report the progress of each phase of the migration from the bytes sent on the migration connections.
+	// This replaces the progress reported by the storage driver, which only covers the initial disk transfer.
+	progress := migration.NewProgress(d.op)
+	filesystemConn = progress.Conn(filesystemConn)
+	stateConn = progress.Conn(stateConn)
+	volSourceArgs.TrackProgress = false
+
 	revert := revert.New()
 
 	// Non-shared storage snapshot setup.
@@ -6858,6 +6927,14 @@ func (d *qemu) migrateSendLive(pool storagePools.Pool, clusterMoveSourceName str
 	// We enable AllowInconsistent mode as this allows for transferring the VM storage whilst it is running
 	// and the snapshot we took earlier is designed to provide consistency anyway.
 	volSourceArgs.AllowInconsistent = true
+
+	var diskSize int64
+	if !sharedStorage {
+		diskSize = rootDiskSize
+	}
+
+	progress.Phase(migration.ProgressPhaseDiskSync, diskSize)
+
 	err = pool.MigrateInstance(d, filesystemConn, volSourceArgs, d.op)
 	if err != nil {
 		return err
@@ -6938,6 +7015,24 @@ func (d *qemu) migrateSendLive(pool storagePools.Pool, clusterMoveSourceName str
 	}
 
 	d.logger.Debug("Stateful migration checkpoint send starting")
+	progress.Phase(migration.ProgressPhaseMemoryPrecopy, 0)
+
+	// Report the memory left to transfer while the guest is running, until QEMU pauses it to transfer the
+	// remaining memory and device state.
+	finalSync := false
+	memoryProgress := func(info qmp.MigrationInfo) {
+		if finalSync {
+			return
+		}
+
+		switch info.Status {
+		case "pre-switchover", "device", "completed":
+			finalSync = true
+			progress.Phase(migration.ProgressPhaseFinalSync, 0)
+		default:
+			progress.SetRemaining(info.RAM.Remaining)
+		}
+	}
 
 	// Send checkpoint to QEMU process on target. This will pause the guest OS (if not already paused).
 	pipeRead, pipeWrite, err := os.Pipe()
@@ -6960,7 +7055,7 @@ func (d *qemu) migrateSendLive(pool storagePools.Pool, clusterMoveSourceName str
 	// Non-shared storage snapshot transfer finalization.
 	if !sharedStorage {
 		// Wait until state transfer has reached pre-switchover state (the guest OS will remain paused).
-		err = monitor.MigrateWait("pre-switchover")
+		err = monitor.MigrateWaitProgress("pre-switchover", memoryProgress)
 		if err != nil {
 			return fmt.Errorf("Failed waiting for state transfer to reach pre-switchover stage: %w", err)
 		}
@@ -6986,7 +7081,7 @@ func (d *qemu) migrateSendLive(pool storagePools.Pool, clusterMoveSourceName str
 	}
 
 	// Wait until the migration state transfer has completed (the guest OS will remain paused).
-	err = monitor.MigrateWait("completed")
+	err = monitor.MigrateWaitProgress("completed", memoryProgress)
 	if err != nil {
 		return fmt.Errorf("Failed waiting for state transfer to reach completed stage: %w", err)
 	}
@@ -7718,6 +7813,7 @@ func (d *qemu) Render(options ...func(response any) error) (state any, etag any,
 	instState.Profiles = profileNames
 	instState.Stateful = d.stateful
 	instState.Project = d.project.Name
+	instState.ImageArchitecture = d.localConfig["volatile.base_image.architecture"]
 
 	for _, option := range options {
 		err := option(&instState)
@@ -7841,8 +7937,17 @@ func (d *qemu) renderState(statusCode api.StatusCode) (*api.InstanceState, error
 				if netStatus.Hwaddr == hwaddr {
 					if netStatus.HostName == "" {
 						netStatus.HostName = d.localConfig[fmt.Sprintf("volatile.%s.host_name", k)]
-						status.Network[netName] = netStatus
 					}
+
+					// Get the number of queues from the host side interface.
+					if netStatus.HostName != "" {
+						queues, err := network.GetDevQueues(netStatus.HostName)
+						if err == nil {
+							netStatus.Queues = int(queues)
+						}
+					}
+
+					status.Network[netName] = netStatus
 				}
 			}
 		}
@@ -7856,6 +7961,38 @@ func (d *qemu) renderState(statusCode api.StatusCode) (*api.InstanceState, error
 		d.logger.Warn("Error getting disk usage", logger.Ctx{"err": err})
 	}
 
+	status.ScheduledSnapshots = d.scheduledSnapshotsState()
+	status.FilesystemFreeze = d.filesystemFreezeState()
+
+	if d.isRunningStatusCode(statusCode) {
+		if status.Disk == nil {
+			status.Disk = map[string]api.InstanceStateDisk{}
+		}
+
+		// Populate the I/O counters of the disks from the block device statistics of QEMU.
+		err = d.diskIOState(status.Disk)
+		if err != nil {
+			d.logger.Warn("Error getting disk I/O counters", logger.Ctx{"err": err})
+		}
+
+		// Populate the transport and ownership mapping of directory shares.
+		for k, m := range d.ExpandedDevices() {
+			if m["type"] != "disk" {
+				continue
+			}
+
+			transport := d.localConfig[fmt.Sprintf("volatile.%s.share.transport", k)]
+			if transport == "" {
+				continue
+			}
+
+			diskState := status.Disk[k]
+			diskState.ShareTransport = transport
+			diskState.ShareIdmap = d.localConfig[fmt.Sprintf("volatile.%s.share.idmap", k)]
+			status.Disk[k] = diskState
+		}
+	}
+
 	return status, nil
 }
 
@@ -7864,7 +8001,7 @@ func (d *qemu) RenderState(hostInterfaces []net.Interface) (*api.InstanceState,
 	return d.renderState(d.statusCode())
 }
 
-// diskState gets disk usage info.
+// diskState gets disk usage info of the root disk and of the attached custom volumes.
 func (d *qemu) diskState() (map[string]api.InstanceStateDisk, error) {
 	pool, err := d.getStoragePool()
 	if err != nil {
@@ -7877,15 +8014,44 @@ func (d *qemu) diskState() (map[string]api.InstanceStateDisk, error) {
 		return nil, err
 	}
 
+	disk := map[string]api.InstanceStateDisk{}
+
 	usage, err := pool.GetInstanceUsage(d)
 	if err != nil {
-		return nil, err
+		if !errors.Is(err, storageDrivers.ErrNotSupported) {
+			return nil, err
+		}
+	} else {
+		disk[rootDiskName] = api.InstanceStateDisk{
+			Usage: usage.Used,
+			Total: usage.Total,
+		}
 	}
 
-	disk := map[string]api.InstanceStateDisk{}
-	disk[rootDiskName] = api.InstanceStateDisk{
-		Usage: usage.Used,
-		Total: usage.Total,
+	for _, dev := range d.expandedDevices.Sorted() {
+		if dev.Name == rootDiskName || dev.Config["type"] != "disk" || dev.Config["pool"] == "" {
+			continue
+		}
+
+		volPool, err := storagePools.LoadByName(d.state, dev.Config["pool"])
+		if err != nil {
+			d.logger.Error("Error loading storage pool", logger.Ctx{"poolName": dev.Config["pool"], "err": err})
+			continue
+		}
+
+		usage, err := volPool.GetCustomVolumeUsage(d.Project().Name, dev.Config["source"])
+		if err != nil {
+			if !errors.Is(err, storageDrivers.ErrNotSupported) {
+				d.logger.Error("Error getting volume usage", logger.Ctx{"volume": dev.Config["source"], "err": err})
+			}
+
+			continue
+		}
+
+		disk[dev.Name] = api.InstanceStateDisk{
+			Usage: usage.Used,
+			Total: usage.Total,
+		}
 	}
 
 	return disk, nil
@@ -3,20 +3,137 @@ package drivers
 import (
 	"bufio"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/canonical/lxd/lxd/instance"
 	"github.com/canonical/lxd/lxd/instance/drivers/qmp"
 	"github.com/canonical/lxd/lxd/instance/instancetype"
 	"github.com/canonical/lxd/lxd/metrics"
+	"github.com/canonical/lxd/lxd/storage/filesystem"
 	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/units"
 )
 
+// qemuDiskIOSampleInterval is the interval between the two samples of block device statistics that are taken to
+// compute the rate of I/O operations when no recent sample is available.
+const qemuDiskIOSampleInterval = 250 * time.Millisecond
+
+// qemuDiskIOSampleWindow is the maximum age of a sample of block device statistics to compute the rate of I/O
+// operations from.
+const qemuDiskIOSampleWindow = time.Minute
+
+// qemuDiskIOSample is a sample of the block device statistics of a VM, keyed by disk device name.
+type qemuDiskIOSample struct {
+	time  time.Time
+	stats map[string]qmp.BlockStats
+}
+
+// qemuDiskIOSamples holds the latest sample of block device statistics of each running VM, keyed by instance ID.
+var qemuDiskIOSamples = map[int]qemuDiskIOSample{}
+var qemuDiskIOSamplesMu sync.Mutex
+
+// qemuDiskIOSampleForget forgets the latest sample of block device statistics of the VM with the given ID.
+func qemuDiskIOSampleForget(instanceID int) {
+	qemuDiskIOSamplesMu.Lock()
+	delete(qemuDiskIOSamples, instanceID)
+	qemuDiskIOSamplesMu.Unlock()
+}
+
+// qemuBlockStatsDeviceName returns the name of the disk device of the given QEMU device path, as reported by
+// query-blockstats, or an empty string if the block device doesn't belong to a disk device.
+func qemuBlockStatsDeviceName(qdev string) string {
+	deviceID, _, _ := strings.Cut(strings.TrimPrefix(qdev, "/machine/peripheral/"), "/")
+	escapedDeviceName, found := strings.CutPrefix(deviceID, qemuDeviceIDPrefix)
+	if !found || escapedDeviceName == "" {
+		return ""
+	}
+
+	return filesystem.PathNameDecode(escapedDeviceName)
+}
+
+// diskIOState populates the I/O counters of the disk devices of the running VM in the given disk state, along with
+// the rate of I/O operations since the previous sample. If no recent sample is available, a second sample is taken
+// after qemuDiskIOSampleInterval.
+func (d *qemu) diskIOState(disk map[string]api.InstanceStateDisk) error {
+	monitor, err := qmp.Connect(d.monitorPath(), qemuSerialChardevName, d.getMonitorEventHandler())
+	if err != nil {
+		return err
+	}
+
+	sample := func() (qemuDiskIOSample, error) {
+		stats, err := monitor.GetBlockStats()
+		if err != nil {
+			return qemuDiskIOSample{}, err
+		}
+
+		sample := qemuDiskIOSample{time: time.Now(), stats: make(map[string]qmp.BlockStats, len(stats))}
+		for qdev, stat := range stats {
+			devName := qemuBlockStatsDeviceName(qdev)
+			if devName != "" {
+				sample.stats[devName] = stat
+			}
+		}
+
+		return sample, nil
+	}
+
+	current, err := sample()
+	if err != nil {
+		return err
+	}
+
+	qemuDiskIOSamplesMu.Lock()
+	previous, ok := qemuDiskIOSamples[d.id]
+	qemuDiskIOSamplesMu.Unlock()
+
+	age := current.time.Sub(previous.time)
+	if !ok || age < qemuDiskIOSampleInterval || age > qemuDiskIOSampleWindow {
+		previous = current
+		time.Sleep(qemuDiskIOSampleInterval)
+
+		current, err = sample()
+		if err != nil {
+			return err
+		}
+	}
+
+	qemuDiskIOSamplesMu.Lock()
+	qemuDiskIOSamples[d.id] = current
+	qemuDiskIOSamplesMu.Unlock()
+
+	elapsed := current.time.Sub(previous.time).Seconds()
+	for devName, stat := range current.stats {
+		if d.expandedDevices[devName]["type"] != "disk" {
+			continue
+		}
+
+		state := disk[devName]
+		state.ReadBytes = int64(stat.BytesRead)
+		state.WrittenBytes = int64(stat.BytesWritten)
+		state.ReadOperations = int64(stat.ReadsCompleted)
+		state.WriteOperations = int64(stat.WritesCompleted)
+
+		previousStat, ok := previous.stats[devName]
+		// Counters can go backwards if the disk was detached and attached again in between the samples.
+		if ok && elapsed > 0 && stat.ReadsCompleted >= previousStat.ReadsCompleted && stat.WritesCompleted >= previousStat.WritesCompleted {
+			state.ReadOperationsPerSecond = int64(math.Round(float64(stat.ReadsCompleted-previousStat.ReadsCompleted) / elapsed))
+			state.WriteOperationsPerSecond = int64(math.Round(float64(stat.WritesCompleted-previousStat.WritesCompleted) / elapsed))
+		}
+
+		disk[devName] = state
+	}
+
+	return nil
+}
+
 func (d *qemu) getQemuMetrics() (*metrics.MetricSet, error) {
 	// Connect to the monitor.
 	monitor, err := qmp.Connect(d.monitorPath(), qemuSerialChardevName, d.getMonitorEventHandler())
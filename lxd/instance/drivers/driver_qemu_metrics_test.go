@@ -0,0 +1,26 @@
+package drivers
+
+import (
+	"testing"
+)
+
+func TestQemuBlockStatsDeviceName(t *testing.T) {
+	tests := []struct {
+		qdev     string
+		expected string
+	}{
+		{qdev: "/machine/peripheral/dev-lxd_root/virtio-backend", expected: "root"},
+		{qdev: "dev-lxd_data", expected: "data"},
+		{qdev: "/machine/peripheral/dev-lxd_my--disk/virtio-backend", expected: "my-disk"},
+		{qdev: "/machine/peripheral/qemu_cdrom", expected: ""},
+		{qdev: "dev-lxd_", expected: ""},
+		{qdev: "", expected: ""},
+	}
+
+	for _, test := range tests {
+		actual := qemuBlockStatsDeviceName(test.qdev)
+		if actual != test.expected {
+			t.Errorf("Expected %q for %q, got %q", test.expected, test.qdev, actual)
+		}
+	}
+}
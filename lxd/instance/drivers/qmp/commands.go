@@ -291,17 +291,33 @@ func (m *Monitor) Migrate(uri string) error {
 	return nil
 }
 
+// MigrationInfo contains the status and memory statistics of a migration job.
+type MigrationInfo struct {
+	Status string `json:"status"`
+	RAM    struct {
+		Transferred int64 `json:"transferred"`
+		Remaining   int64 `json:"remaining"`
+		Total       int64 `json:"total"`
+	} `json:"ram"`
+}
+
 // MigrateWait waits until migration job reaches the specified status.
 // Returns nil if the migraton job reaches the specified status or an error if the migration job is in the failed
 // status.
 func (m *Monitor) MigrateWait(state string) error {
+	return m.MigrateWaitProgress(state, nil)
+}
+
+// MigrateWaitProgress waits until migration job reaches the specified status, calling the progress function (if
+// not nil) with the information of the migration job each time it is queried.
+// Returns nil if the migraton job reaches the specified status or an error if the migration job is in the failed
+// status.
+func (m *Monitor) MigrateWaitProgress(state string, progress func(info MigrationInfo)) error {
 	// Wait until it completes or fails.
 	for {
 		// Prepare the response.
 		var resp struct {
-			Return struct {
-				Status string `json:"status"`
-			} `json:"return"`
+			Return MigrationInfo `json:"return"`
 		}
 
 		err := m.run("query-migrate", nil, &resp)
@@ -313,6 +329,10 @@ func (m *Monitor) MigrateWait(state string) error {
 			return fmt.Errorf("Migrate call failed")
 		}
 
+		if progress != nil {
+			progress(resp.Return)
+		}
+
 		if resp.Return.Status == state {
 			return nil
 		}
@@ -80,6 +80,7 @@ type Instance interface {
 	Restart(timeout time.Duration) error
 	Rebuild(img *api.Image, op *operations.Operation) error
 	Unfreeze() error
+	UnfreezeFilesystem() error
 	RegisterDevices()
 
 	Info() Info
@@ -149,6 +150,7 @@ type Instance interface {
 	StatePath() string
 	LogFilePath() string
 	ConsoleBufferLogPath() string
+	ConsoleHistoryPath() string
 	LogPath() string
 	DevicesPath() string
 
@@ -5,7 +5,9 @@ import (
 	"context"
 	"crypto/rand"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io/fs"
 	"math/big"
 	"net/http"
 	"os"
@@ -36,6 +38,7 @@ import (
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/osarch"
 	"github.com/canonical/lxd/shared/revert"
+	"github.com/canonical/lxd/shared/units"
 	"github.com/canonical/lxd/shared/validate"
 	"github.com/canonical/lxd/shared/version"
 )
@@ -491,7 +494,15 @@ func ResolveImage(ctx context.Context, tx *db.ClusterTx, projectName string, sou
 
 		_, alias, err := tx.GetImageAlias(ctx, projectName, source.Alias, true)
 		if err != nil {
-			return "", err
+			if !api.StatusErrorCheck(err, http.StatusNotFound) {
+				return "", err
+			}
+
+			// Fall back to the aliases of the images shared with the project.
+			_, alias, err = tx.GetSharedImageAlias(ctx, projectName, source.Alias)
+			if err != nil {
+				return "", err
+			}
 		}
 
 		return alias.Target, nil
@@ -543,6 +554,33 @@ func ResolveImage(ctx context.Context, tx *db.ClusterTx, projectName string, sou
 	return "", fmt.Errorf("Must specify one of alias, fingerprint or properties for init from image")
 }
 
+// ValidArchitecture checks that an instance of the given type and architecture can run on this host.
+// Containers can use the host architecture or any of its personalities (such as 32-bit on a 64-bit host) whereas
+// virtual machines can only use the host architecture.
+func ValidArchitecture(s *state.State, instanceType instancetype.Type, architecture int) error {
+	architectureName, err := osarch.ArchitectureName(architecture)
+	if err != nil {
+		return api.StatusErrorf(http.StatusBadRequest, "%w", err)
+	}
+
+	supportedArchitectures := s.OS.Architectures
+	if instanceType == instancetype.VM && len(supportedArchitectures) > 0 {
+		supportedArchitectures = supportedArchitectures[:1]
+	}
+
+	if !shared.ValueInSlice(architecture, supportedArchitectures) {
+		supportedNames := make([]string, 0, len(supportedArchitectures))
+		for _, supportedArchitecture := range supportedArchitectures {
+			supportedName, _ := osarch.ArchitectureName(supportedArchitecture)
+			supportedNames = append(supportedNames, supportedName)
+		}
+
+		return api.StatusErrorf(http.StatusBadRequest, "Requested architecture %q isn't supported by this host for %s instances (supported: %s)", architectureName, instanceType, strings.Join(supportedNames, ", "))
+	}
+
+	return nil
+}
+
 // SuitableArchitectures returns a slice of architecture ids based on an instance create request.
 //
 // An empty list indicates that the request may be handled by any architecture.
@@ -577,7 +615,7 @@ func SuitableArchitectures(ctx context.Context, s *state.State, tx *db.ClusterTx
 	if req.Source.Type == "image" {
 		// Handle local images.
 		if req.Source.Server == "" {
-			_, img, err := tx.GetImageByFingerprintPrefix(ctx, sourceImageRef, cluster.ImageFilter{Project: &projectName})
+			_, img, err := tx.GetProjectUsableImage(ctx, projectName, sourceImageRef)
 			if err != nil {
 				return nil, err
 			}
@@ -773,15 +811,11 @@ func CreateInternal(s *state.State, args db.InstanceArgs, clearLogDir bool) (Ins
 	// Leave validating devices to Create function call below.
 
 	// Validate architecture.
-	_, err = osarch.ArchitectureName(args.Architecture)
+	err = ValidArchitecture(s, args.Type, args.Architecture)
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
-	if !shared.ValueInSlice(args.Architecture, s.OS.Architectures) {
-		return nil, nil, nil, fmt.Errorf("Requested architecture isn't supported by this host")
-	}
-
 	var profiles []string
 
 	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
@@ -1000,14 +1034,17 @@ func CreateInternal(s *state.State, args db.InstanceArgs, clearLogDir bool) (Ins
 
 // NextSnapshotName finds the next snapshot for an instance.
 func NextSnapshotName(s *state.State, inst Instance, defaultPattern string) (string, error) {
-	var err error
-
 	pattern := inst.ExpandedConfig()["snapshots.pattern"]
 	if pattern == "" {
 		pattern = defaultPattern
 	}
 
-	pattern, err = shared.RenderTemplate(pattern, pongo2.Context{
+	return NextSnapshotNameFromPattern(s, inst, pattern)
+}
+
+// NextSnapshotNameFromPattern finds the next snapshot for an instance using the given pattern.
+func NextSnapshotNameFromPattern(s *state.State, inst Instance, pattern string) (string, error) {
+	pattern, err := shared.RenderTemplate(pattern, pongo2.Context{
 		"creation_date": time.Now(),
 	})
 	if err != nil {
@@ -1237,3 +1274,185 @@ func SnapshotProtobufToInstanceArgs(s *state.State, inst Instance, snap *migrati
 
 	return &args, nil
 }
+
+// ConsoleHistorySize returns the size at which the console history log of an instance with the given expanded config
+// is rotated, or 0 if the instance doesn't keep a console history.
+func ConsoleHistorySize(expandedConfig map[string]string) (int64, error) {
+	size, err := units.ParseByteSizeString(expandedConfig["console.history.size"])
+	if err != nil {
+		return 0, fmt.Errorf("Invalid console.history.size: %w", err)
+	}
+
+	return size, nil
+}
+
+// ConsoleHistoryRotate rotates the console history log at the given path if it has reached the given size, by
+// moving its content to the previous log (the path with a ".1" suffix) and truncating it. The log is truncated rather
+// than renamed, as it is kept open by the process writing to it.
+func ConsoleHistoryRotate(path string, size int64) error {
+	st, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	if st.Size() < size {
+		return nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(path+".1.tmp", content, 0600)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(path+".1.tmp", path+".1")
+	if err != nil {
+		return err
+	}
+
+	return os.Truncate(path, 0)
+}
+
+// ConsoleHistoryRead returns the most recent maxBytes of the console history log at the given path, including its
+// previous log, or all of it if maxBytes is negative.
+func ConsoleHistoryRead(path string, maxBytes int64) ([]byte, error) {
+	var history []byte
+	for _, logPath := range []string{path + ".1", path} {
+		content, err := os.ReadFile(logPath)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		history = append(history, content...)
+	}
+
+	if maxBytes >= 0 && int64(len(history)) > maxBytes {
+		history = history[int64(len(history))-maxBytes:]
+	}
+
+	return history, nil
+}
+
+// BootAfter returns the names of the instances that must be started before the instance with the given expanded
+// config when LXD starts.
+func BootAfter(expandedConfig map[string]string) []string {
+	return shared.SplitNTrimSpace(expandedConfig["boot.after"], ",", -1, true)
+}
+
+// SortBootAfter orders the instance names so that each instance comes after the instances it depends on.
+// The after map holds the dependencies of each instance, dependencies that aren't part of names are ignored.
+// Instances otherwise keep their relative order. Returns an error if the dependencies form a cycle.
+func SortBootAfter(names []string, after map[string][]string) ([]string, error) {
+	pending := make(map[string]bool, len(names))
+	for _, name := range names {
+		pending[name] = true
+	}
+
+	sorted := make([]string, 0, len(names))
+	for len(sorted) < len(names) {
+		progress := false
+
+		for _, name := range names {
+			if !pending[name] {
+				continue
+			}
+
+			ready := true
+			for _, dep := range after[name] {
+				if pending[dep] {
+					ready = false
+					break
+				}
+			}
+
+			if ready {
+				sorted = append(sorted, name)
+				pending[name] = false
+				progress = true
+				break
+			}
+		}
+
+		if !progress {
+			cycle := []string{}
+			for _, name := range names {
+				if pending[name] {
+					cycle = append(cycle, name)
+				}
+			}
+
+			return nil, fmt.Errorf("Boot dependency cycle detected among instances %s", strings.Join(cycle, ", "))
+		}
+	}
+
+	return sorted, nil
+}
+
+// ValidBootAfter checks that the boot dependencies of an instance don't form a cycle with those of the other
+// instances of its project.
+func ValidBootAfter(s *state.State, projectName string, instanceName string, expandedConfig map[string]string) error {
+	deps := BootAfter(expandedConfig)
+	if len(deps) == 0 {
+		return nil
+	}
+
+	if shared.ValueInSlice(instanceName, deps) {
+		return fmt.Errorf("Instance %q can't be started after itself", instanceName)
+	}
+
+	names := []string{instanceName}
+	after := map[string][]string{instanceName: deps}
+
+	err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		return tx.InstanceList(ctx, func(dbInst db.InstanceArgs, p api.Project) error {
+			if dbInst.Name == instanceName {
+				return nil
+			}
+
+			names = append(names, dbInst.Name)
+			after[dbInst.Name] = BootAfter(instancetype.ExpandInstanceConfig(nil, dbInst.Config, dbInst.Profiles))
+
+			return nil
+		}, cluster.InstanceFilter{Project: &projectName})
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = SortBootAfter(names, after)
+	return err
+}
+
+// ImmutableKeys returns the config keys that were made immutable when the instance with the given local config was
+// created.
+func ImmutableKeys(localConfig map[string]string) []string {
+	return shared.SplitNTrimSpace(localConfig["volatile.immutable_keys"], ",", -1, true)
+}
+
+// ValidImmutableKeys checks that the keys can be made immutable.
+func ValidImmutableKeys(keys []string) error {
+	for _, key := range keys {
+		if strings.HasPrefix(key, instancetype.ConfigVolatilePrefix) {
+			return fmt.Errorf("Volatile key %q can't be made immutable", key)
+		}
+
+		_, err := instancetype.ConfigKeyChecker(key, instancetype.Any)
+		if err != nil {
+			return fmt.Errorf("Invalid immutable key %q: %w", key, err)
+		}
+	}
+
+	return nil
+}
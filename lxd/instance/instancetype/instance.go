@@ -3,6 +3,7 @@ package instancetype
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -17,11 +18,12 @@ type InstanceAction string
 
 // InstanceAction types.
 const (
-	Stop     InstanceAction = "stop"
-	Start    InstanceAction = "start"
-	Restart  InstanceAction = "restart"
-	Freeze   InstanceAction = "freeze"
-	Unfreeze InstanceAction = "unfreeze"
+	Stop               InstanceAction = "stop"
+	Start              InstanceAction = "start"
+	Restart            InstanceAction = "restart"
+	Freeze             InstanceAction = "freeze"
+	Unfreeze           InstanceAction = "unfreeze"
+	UnfreezeFilesystem InstanceAction = "unfreeze-fs"
 )
 
 // ConfigVolatilePrefix indicates the prefix used for volatile config keys.
@@ -102,6 +104,48 @@ var InstanceConfigKeysAny = map[string]func(value string) error{
 	//  shortdesc: What order to start the instances in
 	"boot.autostart.priority": validate.Optional(validate.IsInt64),
 
+	// lxdmeta:generate(entities=instance; group=boot; key=boot.after)
+	// Comma-separated list of instances in the same project that must be started before this instance when LXD
+	// starts. This takes precedence over {config:option}`instance-boot:boot.autostart.priority`.
+	// Instances located on other cluster members are ignored with a warning.
+	// ---
+	//  type: string
+	//  liveupdate: no
+	//  shortdesc: Instances to start before this instance
+	"boot.after": validate.Optional(validate.IsListOf(validate.IsHostname)),
+
+	// lxdmeta:generate(entities=instance; group=boot; key=boot.after.ready)
+	// What to wait for before starting this instance once the instances in {config:option}`instance-boot:boot.after`
+	// have been started. Can be `running`, `agent` (the instance reports being ready or its `lxd-agent` is reachable)
+	// or `network:<port>` (a TCP port is listening on one of the instance addresses).
+	// ---
+	//  type: string
+	//  defaultdesc: `running`
+	//  liveupdate: no
+	//  shortdesc: Readiness of the instances to start this instance after
+	"boot.after.ready": validate.Optional(func(value string) error {
+		if value == "running" || value == "agent" {
+			return nil
+		}
+
+		port, found := strings.CutPrefix(value, "network:")
+		if !found {
+			return fmt.Errorf("Invalid boot dependency readiness %q, must be running, agent or network:<port>", value)
+		}
+
+		return validate.IsNetworkPort(port)
+	}),
+
+	// lxdmeta:generate(entities=instance; group=boot; key=boot.after.timeout)
+	// The number of seconds to wait for the instances in {config:option}`instance-boot:boot.after` to become ready
+	// before starting this instance anyway.
+	// ---
+	//  type: integer
+	//  defaultdesc: "60"
+	//  liveupdate: no
+	//  shortdesc: How long to wait for the instances to start this instance after
+	"boot.after.timeout": validate.Optional(validate.IsUint32),
+
 	// lxdmeta:generate(entities=instance; group=boot; key=boot.stop.priority)
 	// The instance with the highest value is shut down first.
 	// ---
@@ -120,8 +164,29 @@ var InstanceConfigKeysAny = map[string]func(value string) error{
 	//  shortdesc: How long to wait for the instance to shut down
 	"boot.host_shutdown_timeout": validate.Optional(validate.IsInt64),
 
+	// lxdmeta:generate(entities=instance; group=boot; key=boot.schedule.start)
+	// Specify either a cron expression (`<minute> <hour> <dom> <month> <dow>`), a comma-separated list of schedule aliases (`@hourly`, `@daily`, `@midnight`, `@weekly`, `@monthly`, `@annually`, `@yearly`), or leave empty to disable scheduled starts.
+	// The instance is only started if it isn't already running.
+	// ---
+	//  type: string
+	//  defaultdesc: empty
+	//  liveupdate: yes
+	//  shortdesc: Schedule for starting the instance
+	"boot.schedule.start": validate.Optional(validate.IsCron([]string{"@hourly", "@daily", "@midnight", "@weekly", "@monthly", "@annually", "@yearly", "@never"})),
+
+	// lxdmeta:generate(entities=instance; group=boot; key=boot.schedule.stop)
+	// Specify either a cron expression (`<minute> <hour> <dom> <month> <dow>`), a comma-separated list of schedule aliases (`@hourly`, `@daily`, `@midnight`, `@weekly`, `@monthly`, `@annually`, `@yearly`), or leave empty to disable scheduled stops.
+	// The instance is shut down cleanly, and force-stopped if it doesn't shut down within {config:option}`instance-boot:boot.host_shutdown_timeout`.
+	// ---
+	//  type: string
+	//  defaultdesc: empty
+	//  liveupdate: yes
+	//  shortdesc: Schedule for stopping the instance
+	"boot.schedule.stop": validate.Optional(validate.IsCron([]string{"@hourly", "@daily", "@midnight", "@weekly", "@monthly", "@annually", "@yearly", "@never"})),
+
 	// lxdmeta:generate(entities=instance; group=cloud-init; key=cloud-init.network-config)
 	// The content is used as seed value for `cloud-init`.
+	// Set it to `auto` to have LXD generate the network configuration from the instance's NIC devices.
 	// ---
 	//  type: string
 	//  defaultdesc: `DHCP on eth0`
@@ -202,6 +267,30 @@ var InstanceConfigKeysAny = map[string]func(value string) error{
 	//  shortdesc: What to do when evacuating the instance
 	"cluster.evacuate": validate.Optional(validate.IsOneOf("auto", "migrate", "live-migrate", "stop")),
 
+	// lxdmeta:generate(entities=instance; group=miscellaneous; key=console.history.size)
+	// When set, the console output of the instance is written to a log in the instance directory, which is rotated
+	// once it reaches this size. The previous log is kept, so up to twice this size of console history is stored.
+	//
+	// See {ref}`instances-console-history` for more information.
+	// ---
+	//  type: string
+	//  liveupdate: no
+	//  shortdesc: Size of the persistent console history
+	"console.history.size": validate.Optional(validate.IsSize),
+
+	// lxdmeta:generate(entities=instance; group=miscellaneous; key=exec.record)
+	// When enabled, both directions of every exec session into the instance are recorded in the asciicast format
+	// and stored in the instance log directory. Recording can also be enabled for all instances of a project
+	// with {config:option}`project-specific:exec.record`.
+	//
+	// See {ref}`instances-exec-recording` for more information.
+	// ---
+	//  type: bool
+	//  defaultdesc: `false`
+	//  liveupdate: yes
+	//  shortdesc: Whether to record exec sessions
+	"exec.record": validate.Optional(validate.IsBool),
+
 	// lxdmeta:generate(entities=instance; group=resource-limits; key=limits.cpu)
 	// A number or a specific range of CPUs to expose to the instance.
 	//
@@ -311,6 +400,26 @@ var InstanceConfigKeysAny = map[string]func(value string) error{
 	//  shortdesc: Prevents the instance from being deleted
 	"security.protection.delete": validate.Optional(validate.IsBool),
 
+	// lxdmeta:generate(entities=instance; group=security; key=security.protection.stop)
+	// Stopping or restarting the instance through the API is refused, whether forced or not.
+	// Shutting the instance down from inside isn't prevented.
+	// ---
+	//  type: bool
+	//  defaultdesc: `false`
+	//  liveupdate: yes
+	//  shortdesc: Prevents the instance from being stopped or restarted
+	"security.protection.stop": validate.Optional(validate.IsBool),
+
+	// lxdmeta:generate(entities=instance; group=security; key=security.protection.force)
+	// Forced stops and restarts, rebuilds and snapshot restores are refused.
+	// A snapshot restore can still be done by setting `override_protection` in the restore request.
+	// ---
+	//  type: bool
+	//  defaultdesc: `false`
+	//  liveupdate: yes
+	//  shortdesc: Prevents forced and destructive changes to the instance
+	"security.protection.force": validate.Optional(validate.IsBool),
+
 	// lxdmeta:generate(entities=instance; group=snapshots; key=snapshots.schedule)
 	// Specify either a cron expression (`<minute> <hour> <dom> <month> <dow>`), a comma-separated list of schedule aliases (`@hourly`, `@daily`, `@midnight`, `@weekly`, `@monthly`, `@annually`, `@yearly`), or leave empty to disable automatic snapshots.
 	//
@@ -330,6 +439,16 @@ var InstanceConfigKeysAny = map[string]func(value string) error{
 	//  shortdesc: Whether to automatically snapshot stopped instances
 	"snapshots.schedule.stopped": validate.Optional(validate.IsBool),
 
+	// lxdmeta:generate(entities=instance; group=snapshots; key=snapshots.schedule.failure_threshold)
+	// A warning is raised when this number of consecutive scheduled snapshots of the instance have failed.
+	// Set to `0` to never raise a warning.
+	// ---
+	//  type: integer
+	//  defaultdesc: `3`
+	//  liveupdate: yes
+	//  shortdesc: Number of failed scheduled snapshots before raising a warning
+	"snapshots.schedule.failure_threshold": validate.Optional(validate.IsUint32),
+
 	// lxdmeta:generate(entities=instance; group=snapshots; key=snapshots.pattern)
 	// Specify a Pongo2 template string that represents the snapshot name.
 	// This template is used for scheduled snapshots and for unnamed snapshots.
@@ -342,6 +461,17 @@ var InstanceConfigKeysAny = map[string]func(value string) error{
 	//  shortdesc: Template for the snapshot name
 	"snapshots.pattern": validate.IsAny,
 
+	// lxdmeta:generate(entities=instance; group=snapshots; key=snapshots.retention.policy)
+	// Specify the name of a snapshot retention policy of the project, or of a server-wide policy if the project has
+	// no policy with that name. Snapshots that the rules of the policy don't keep are deleted.
+	//
+	// See {ref}`snapshot-retention-policies` for more information.
+	// ---
+	//  type: string
+	//  liveupdate: no
+	//  shortdesc: Snapshot retention policy
+	"snapshots.retention.policy": validate.IsAny,
+
 	// lxdmeta:generate(entities=instance; group=snapshots; key=snapshots.expiry)
 	// Specify an expression like `1M 2H 3d 4w 5m 6y`.
 	// ---
@@ -370,6 +500,13 @@ var InstanceConfigKeysAny = map[string]func(value string) error{
 	//  shortdesc: Hash of the base image
 	"volatile.base_image": validate.IsAny,
 
+	// lxdmeta:generate(entities=instance; group=volatile; key=volatile.base_image.architecture)
+	// The architecture of the image that the instance was created from (empty if the instance was not created from an image).
+	// ---
+	//  type: string
+	//  shortdesc: Architecture of the base image
+	"volatile.base_image.architecture": validate.IsAny,
+
 	// lxdmeta:generate(entities=instance; group=volatile; key=volatile.cloud_init.instance-id)
 	//
 	// ---
@@ -384,6 +521,13 @@ var InstanceConfigKeysAny = map[string]func(value string) error{
 	//  shortdesc: The origin of the evacuated instance
 	"volatile.evacuate.origin": validate.IsAny,
 
+	// lxdmeta:generate(entities=instance; group=volatile; key=volatile.immutable_keys)
+	// Comma-separated list of configuration keys that were made immutable when the instance was created.
+	// ---
+	//  type: string
+	//  shortdesc: Immutable configuration keys
+	"volatile.immutable_keys": validate.IsAny,
+
 	// lxdmeta:generate(entities=instance; group=volatile; key=volatile.last_state.power)
 	//
 	// ---
@@ -392,6 +536,20 @@ var InstanceConfigKeysAny = map[string]func(value string) error{
 	"volatile.last_state.power": validate.IsAny,
 	"volatile.last_state.ready": validate.IsBool,
 	"volatile.apply_quota":      validate.IsAny,
+
+	// lxdmeta:generate(entities=instance; group=volatile; key=volatile.snapshot.schedule)
+	// Set on the snapshots created by a named snapshot schedule (see `snapshots.schedule.<name>`).
+	// ---
+	//  type: string
+	//  shortdesc: Named schedule that created the snapshot
+	"volatile.snapshot.schedule": validate.IsAny,
+
+	// Outcome of the scheduled snapshots of the instance.
+	"volatile.snapshots.schedule.last_success": validate.IsAny,
+	"volatile.snapshots.schedule.last_failure": validate.IsAny,
+	"volatile.snapshots.schedule.last_error":   validate.IsAny,
+	"volatile.snapshots.schedule.failures":     validate.Optional(validate.IsUint32),
+
 	// lxdmeta:generate(entities=instance; group=volatile; key=volatile.uuid)
 	// The instance UUID is globally unique across all servers and projects.
 	// ---
@@ -1109,6 +1267,15 @@ func ConfigKeyChecker(key string, instanceType Type) (func(value string) error,
 			return validate.IsAny, nil
 		}
 
+		// lxdmeta:generate(entities=instance; group=volatile; key=volatile.<name>.persistent_host_name)
+		// The stable name of the network device on the host that is used when `host_name.persistent` is enabled on the device.
+		// ---
+		//  type: string
+		//  shortdesc: Persistent network device name on the host
+		if strings.HasSuffix(key, ".persistent_host_name") {
+			return validate.IsAny, nil
+		}
+
 		// lxdmeta:generate(entities=instance; group=volatile; key=volatile.<name>.last_state.mtu)
 		// The original MTU that was used when moving a physical device into an instance.
 		// ---
@@ -1185,6 +1352,43 @@ func ConfigKeyChecker(key string, instanceType Type) (func(value string) error,
 			return validate.IsAny, nil
 		}
 
+		// lxdmeta:generate(entities=instance; group=volatile; key=volatile.<name>.external_dhcp.addresses)
+		// The addresses allocated to a network device by the external DHCP system of its network, when known.
+		// ---
+		//  type: string
+		//  shortdesc: Addresses allocated by external DHCP
+		if strings.HasSuffix(key, ".external_dhcp.addresses") {
+			return validate.Optional(validate.IsListOf(validate.IsNetworkAddress)), nil
+		}
+
+		// lxdmeta:generate(entities=instance; group=volatile; key=volatile.<name>.external_dhcp.reference)
+		// The reference of the reservation of a network device in the external DHCP system of its network.
+		// ---
+		//  type: string
+		//  shortdesc: External DHCP reservation reference
+		if strings.HasSuffix(key, ".external_dhcp.reference") {
+			return validate.IsAny, nil
+		}
+
+		// lxdmeta:generate(entities=instance; group=volatile; key=volatile.<name>.share.transport)
+		// The transport (`virtiofs` or `9p`) that a disk device uses to share a directory with a virtual machine.
+		// ---
+		//  type: string
+		//  shortdesc: Directory share transport
+		if strings.HasSuffix(key, ".share.transport") {
+			return validate.Optional(validate.IsOneOf("virtiofs", "9p")), nil
+		}
+
+		// lxdmeta:generate(entities=instance; group=volatile; key=volatile.<name>.share.idmap)
+		// How a disk device sharing a directory with a virtual machine maps the ownership of files
+		// (`none`, `translate` or `userns`).
+		// ---
+		//  type: string
+		//  shortdesc: Directory share ownership mapping
+		if strings.HasSuffix(key, ".share.idmap") {
+			return validate.Optional(validate.IsOneOf("none", "translate", "userns")), nil
+		}
+
 		if strings.HasSuffix(key, ".driver") {
 			return validate.IsAny, nil
 		}
@@ -1198,6 +1402,43 @@ func ConfigKeyChecker(key string, instanceType Type) (func(value string) error,
 		}
 	}
 
+	// lxdmeta:generate(entities=instance; group=snapshots; key=snapshots.schedule.<name>)
+	// Specify a cron expression or a comma-separated list of schedule aliases, like for `snapshots.schedule`
+	// (except `@startup`). Each named schedule creates its own snapshots, which are tagged with the name of the
+	// schedule in their `volatile.snapshot.schedule` key and expire according to `snapshots.schedule.<name>.expiry`.
+	// The names `stopped` and `failure_threshold` are reserved.
+	// ---
+	//  type: string
+	//  liveupdate: no
+	//  shortdesc: Named schedule for automatic instance snapshots
+
+	// lxdmeta:generate(entities=instance; group=snapshots; key=snapshots.schedule.<name>.expiry)
+	// Specify an expression like `1M 2H 3d 4w 5m 6y`.
+	// ---
+	//  type: string
+	//  defaultdesc: value of `snapshots.expiry`
+	//  liveupdate: no
+	//  shortdesc: When snapshots of the named schedule are to be deleted
+
+	// lxdmeta:generate(entities=instance; group=snapshots; key=snapshots.pattern.<name>)
+	// Specify a Pongo2 template string that represents the name of the snapshots of the named schedule.
+	// ---
+	//  type: string
+	//  defaultdesc: value of `snapshots.pattern`
+	//  liveupdate: no
+	//  shortdesc: Template for the snapshot name of the named schedule
+	_, setting, ok := ParseSnapshotScheduleKey(key)
+	if ok {
+		switch setting {
+		case "expiry":
+			return InstanceConfigKeysAny["snapshots.expiry"], nil
+		case "pattern":
+			return InstanceConfigKeysAny["snapshots.pattern"], nil
+		default:
+			return validate.Optional(validate.IsCron([]string{"@hourly", "@daily", "@midnight", "@weekly", "@monthly", "@annually", "@yearly", "@never"})), nil
+		}
+	}
+
 	if strings.HasPrefix(key, "environment.") {
 		return validate.IsAny, nil
 	}
@@ -1223,6 +1464,49 @@ func ConfigKeyChecker(key string, instanceType Type) (func(value string) error,
 	return nil, fmt.Errorf("Unknown configuration key: %s", key)
 }
 
+// ParseSnapshotScheduleKey returns the name of the named snapshot schedule that the given configuration key belongs
+// to, and the setting of the schedule that it configures. The setting is empty for `snapshots.schedule.<name>`,
+// "expiry" for `snapshots.schedule.<name>.expiry` and "pattern" for `snapshots.pattern.<name>`. It returns false if
+// the key does not belong to a named snapshot schedule.
+func ParseSnapshotScheduleKey(key string) (name string, setting string, ok bool) {
+	if strings.HasPrefix(key, "snapshots.pattern.") {
+		name = strings.TrimPrefix(key, "snapshots.pattern.")
+		setting = "pattern"
+	} else if strings.HasPrefix(key, "snapshots.schedule.") {
+		name = strings.TrimPrefix(key, "snapshots.schedule.")
+		if strings.HasSuffix(name, ".expiry") {
+			name = strings.TrimSuffix(name, ".expiry")
+			setting = "expiry"
+		}
+	} else {
+		return "", "", false
+	}
+
+	// The keys under snapshots.schedule that configure all schedules cannot be used as schedule names.
+	if name == "" || strings.Contains(name, ".") || name == "stopped" || name == "failure_threshold" {
+		return "", "", false
+	}
+
+	return name, setting, true
+}
+
+// SnapshotScheduleNames returns the sorted names of the named snapshot schedules that are set in the given config.
+func SnapshotScheduleNames(config map[string]string) []string {
+	var names []string
+	for key, value := range config {
+		name, setting, ok := ParseSnapshotScheduleKey(key)
+		if !ok || setting != "" || value == "" {
+			continue
+		}
+
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
 // InstanceIncludeWhenCopying is used to decide whether to include a config item or not when copying an instance.
 // The remoteCopy argument indicates if the copy is remote (i.e between LXD nodes) as this affects the keys kept.
 func InstanceIncludeWhenCopying(configKey string, remoteCopy bool) bool {
@@ -1230,6 +1514,14 @@ func InstanceIncludeWhenCopying(configKey string, remoteCopy bool) bool {
 		return true // Include volatile.base_image always as it can help optimize copies.
 	}
 
+	if configKey == "volatile.base_image.architecture" {
+		return true // Include volatile.base_image.architecture as it describes the same image as volatile.base_image.
+	}
+
+	if configKey == "volatile.immutable_keys" {
+		return true // Include volatile.immutable_keys always so copies keep the same immutable keys.
+	}
+
 	if configKey == "volatile.last_state.idmap" && !remoteCopy {
 		return true // Include volatile.last_state.idmap when doing local copy to avoid needless remapping.
 	}
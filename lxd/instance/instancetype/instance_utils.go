@@ -1,9 +1,12 @@
 package instancetype
 
 import (
+	"fmt"
 	"strconv"
+	"strings"
 
 	deviceConfig "github.com/canonical/lxd/lxd/device/config"
+	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 )
 
@@ -65,3 +68,127 @@ func ExpandInstanceDevices(devices deviceConfig.Devices, profiles []api.Profile)
 
 	return expandedDevices
 }
+
+// ProjectDeviceDefaultPrefix is the prefix of the project configuration keys that set device configuration keys on
+// the instances of the project.
+const ProjectDeviceDefaultPrefix = "default.device."
+
+// ParseProjectDeviceDefaultKey splits a project configuration key of the form `default.device.<device>.<key>` into
+// the device name or type it applies to and the device configuration key to set.
+func ParseProjectDeviceDefaultKey(key string) (device string, deviceKey string, err error) {
+	device, deviceKey, found := strings.Cut(strings.TrimPrefix(key, ProjectDeviceDefaultPrefix), ".")
+	if !strings.HasPrefix(key, ProjectDeviceDefaultPrefix) || !found || device == "" || deviceKey == "" {
+		return "", "", fmt.Errorf("Project device default keys must be of the form %q", ProjectDeviceDefaultPrefix+"<device>.<key>")
+	}
+
+	return device, deviceKey, nil
+}
+
+// ExpandProjectDeviceDefaults applies the device defaults of the given project configuration to the given expanded
+// instance devices. A `default.device.<device>.<key>` key sets `<key>` on each device whose type or name is
+// `<device>`, with defaults for a device name taking precedence over defaults for a device type. The value set by
+// profiles or by the instance is overridden, unless `default.device.<device>.overridable` is true in which case the
+// default only applies to devices that do not set the key.
+func ExpandProjectDeviceDefaults(devices deviceConfig.Devices, projectConfig map[string]string) deviceConfig.Devices {
+	defaults := map[string]map[string]string{}
+	for key, value := range projectConfig {
+		device, deviceKey, err := ParseProjectDeviceDefaultKey(key)
+		if err != nil || deviceKey == "overridable" {
+			continue
+		}
+
+		if defaults[device] == nil {
+			defaults[device] = map[string]string{}
+		}
+
+		defaults[device][deviceKey] = value
+	}
+
+	if len(defaults) == 0 {
+		return devices
+	}
+
+	expandedDevices := make(deviceConfig.Devices, len(devices))
+	for name, dev := range devices {
+		expandedDevices[name] = dev
+
+		// Apply the defaults for the device type first so that those for the device name take precedence.
+		for _, match := range []string{dev["type"], name} {
+			deviceDefaults, ok := defaults[match]
+			if !ok {
+				continue
+			}
+
+			overridable := shared.IsTrue(projectConfig[ProjectDeviceDefaultPrefix+match+".overridable"])
+
+			// Copy the device before modifying it as it may be shared with a profile or the instance.
+			expandedDevice := expandedDevices[name].Clone()
+			for deviceKey, value := range deviceDefaults {
+				_, isSet := dev[deviceKey]
+				if isSet && overridable {
+					continue
+				}
+
+				expandedDevice[deviceKey] = value
+			}
+
+			expandedDevices[name] = expandedDevice
+		}
+	}
+
+	return expandedDevices
+}
+
+// ImageManifestProperty is the image property holding the instance configuration embedded when publishing an
+// instance with its manifest.
+const ImageManifestProperty = "instance_manifest"
+
+// ImageManifestExcludedKeys lists the instance configuration keys that are never embedded in an image manifest.
+// Entries ending with a dot match all the keys with that prefix.
+var ImageManifestExcludedKeys = []string{"volatile.", "image.", "security.sev.session."}
+
+// ImageManifestExcludedDeviceKeys lists the device configuration keys that are never embedded in an image manifest
+// as they must be unique to each instance.
+var ImageManifestExcludedDeviceKeys = []string{"hwaddr"}
+
+// ImageManifestKeyExcluded returns whether the instance configuration key is stripped from image manifests.
+// The user.* keys are stripped as well if excludeUser is set.
+func ImageManifestKeyExcluded(key string, excludeUser bool) bool {
+	if excludeUser && strings.HasPrefix(key, "user.") {
+		return true
+	}
+
+	for _, excluded := range ImageManifestExcludedKeys {
+		if key == excluded || (strings.HasSuffix(excluded, ".") && strings.HasPrefix(key, excluded)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewImageManifest returns a copy of the given local configuration and devices for embedding in an image, stripped
+// of the keys that must not be carried over to other instances.
+func NewImageManifest(config map[string]string, devices deviceConfig.Devices, excludeUser bool) api.ImageInstanceManifest {
+	manifest := api.ImageInstanceManifest{
+		Config:  map[string]string{},
+		Devices: map[string]map[string]string{},
+	}
+
+	for key, value := range config {
+		if !ImageManifestKeyExcluded(key, excludeUser) {
+			manifest.Config[key] = value
+		}
+	}
+
+	for name, device := range devices {
+		manifest.Devices[name] = map[string]string{}
+		for key, value := range device {
+			if !shared.ValueInSlice(key, ImageManifestExcludedDeviceKeys) {
+				manifest.Devices[name][key] = value
+			}
+		}
+	}
+
+	return manifest
+}
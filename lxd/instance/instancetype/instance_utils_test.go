@@ -0,0 +1,114 @@
+package instancetype
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	deviceConfig "github.com/canonical/lxd/lxd/device/config"
+)
+
+func TestExpandProjectDeviceDefaults(t *testing.T) {
+	devices := deviceConfig.Devices{
+		"eth0": {"type": "nic", "network": "lxdbr0", "limits.ingress": "1Gbit"},
+		"eth1": {"type": "nic", "network": "lxdbr1"},
+		"root": {"type": "disk", "pool": "default", "path": "/"},
+	}
+
+	tests := []struct {
+		name          string
+		projectConfig map[string]string
+		expected      deviceConfig.Devices
+	}{
+		{
+			name:          "No defaults",
+			projectConfig: map[string]string{"limits.instances": "10"},
+			expected:      devices,
+		},
+		{
+			name:          "Forced by type",
+			projectConfig: map[string]string{"default.device.nic.limits.ingress": "10Mbit"},
+			expected: deviceConfig.Devices{
+				"eth0": {"type": "nic", "network": "lxdbr0", "limits.ingress": "10Mbit"},
+				"eth1": {"type": "nic", "network": "lxdbr1", "limits.ingress": "10Mbit"},
+				"root": {"type": "disk", "pool": "default", "path": "/"},
+			},
+		},
+		{
+			name:          "Overridable by type",
+			projectConfig: map[string]string{"default.device.nic.limits.ingress": "10Mbit", "default.device.nic.overridable": "true"},
+			expected: deviceConfig.Devices{
+				"eth0": {"type": "nic", "network": "lxdbr0", "limits.ingress": "1Gbit"},
+				"eth1": {"type": "nic", "network": "lxdbr1", "limits.ingress": "10Mbit"},
+				"root": {"type": "disk", "pool": "default", "path": "/"},
+			},
+		},
+		{
+			name:          "Name takes precedence over type",
+			projectConfig: map[string]string{"default.device.nic.limits.ingress": "10Mbit", "default.device.eth1.limits.ingress": "20Mbit", "default.device.root.size": "10GiB"},
+			expected: deviceConfig.Devices{
+				"eth0": {"type": "nic", "network": "lxdbr0", "limits.ingress": "10Mbit"},
+				"eth1": {"type": "nic", "network": "lxdbr1", "limits.ingress": "20Mbit"},
+				"root": {"type": "disk", "pool": "default", "path": "/", "size": "10GiB"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := devices.Clone()
+			assert.Equal(t, tt.expected, ExpandProjectDeviceDefaults(devices, tt.projectConfig))
+			assert.Equal(t, original, devices, "The given devices must not be modified")
+		})
+	}
+}
+
+func TestParseProjectDeviceDefaultKey(t *testing.T) {
+	device, deviceKey, err := ParseProjectDeviceDefaultKey("default.device.eth0.limits.ingress")
+	assert.NoError(t, err)
+	assert.Equal(t, "eth0", device)
+	assert.Equal(t, "limits.ingress", deviceKey)
+
+	for _, key := range []string{"default.device.eth0", "default.device..size", "default.device.root.", "default.size"} {
+		_, _, err := ParseProjectDeviceDefaultKey(key)
+		assert.Error(t, err, key)
+	}
+}
+
+func TestImageManifestKeyExcluded(t *testing.T) {
+	for _, key := range []string{"volatile.base_image", "volatile.eth0.hwaddr", "volatile.immutable_keys", "image.os", "security.sev.session.dh", "security.sev.session.data"} {
+		assert.True(t, ImageManifestKeyExcluded(key, false), key)
+	}
+
+	for _, key := range []string{"limits.cpu", "security.nesting", "security.sev", "cloud-init.user-data", "user.foo", "volatile", "imagefoo"} {
+		assert.False(t, ImageManifestKeyExcluded(key, false), key)
+	}
+
+	assert.True(t, ImageManifestKeyExcluded("user.foo", true))
+	assert.False(t, ImageManifestKeyExcluded("cloud-init.user-data", true))
+}
+
+func TestNewImageManifest(t *testing.T) {
+	config := map[string]string{
+		"limits.cpu":          "2",
+		"user.foo":            "bar",
+		"volatile.base_image": "abc",
+		"image.os":            "Ubuntu",
+	}
+
+	devices := deviceConfig.Devices{
+		"eth0": {"type": "nic", "network": "lxdbr0", "hwaddr": "00:16:3e:00:00:01"},
+		"root": {"type": "disk", "pool": "default", "path": "/"},
+	}
+
+	manifest := NewImageManifest(config, devices, false)
+	assert.Equal(t, map[string]string{"limits.cpu": "2", "user.foo": "bar"}, manifest.Config)
+	assert.Equal(t, map[string]map[string]string{
+		"eth0": {"type": "nic", "network": "lxdbr0"},
+		"root": {"type": "disk", "pool": "default", "path": "/"},
+	}, manifest.Devices)
+	assert.Equal(t, "00:16:3e:00:00:01", devices["eth0"]["hwaddr"], "The given devices must not be modified")
+
+	manifest = NewImageManifest(config, devices, true)
+	assert.Equal(t, map[string]string{"limits.cpu": "2"}, manifest.Config)
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/canonical/lxd/lxd/db"
+	dbCluster "github.com/canonical/lxd/lxd/db/cluster"
+	"github.com/canonical/lxd/lxd/db/operationtype"
+	"github.com/canonical/lxd/lxd/db/warningtype"
+	"github.com/canonical/lxd/lxd/instance"
+	instanceDrivers "github.com/canonical/lxd/lxd/instance/drivers"
+	"github.com/canonical/lxd/lxd/instance/instancetype"
+	"github.com/canonical/lxd/lxd/operations"
+	"github.com/canonical/lxd/lxd/state"
+	"github.com/canonical/lxd/lxd/task"
+	"github.com/canonical/lxd/lxd/warnings"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/version"
+)
+
+// scheduledInstanceAction is an instance together with the power action that its boot schedule requires.
+type scheduledInstanceAction struct {
+	inst   instance.Instance
+	action instancetype.InstanceAction
+}
+
+// instanceBootScheduleAction returns the power action due now according to the boot.schedule.start and
+// boot.schedule.stop settings of the instance. An empty action is returned if nothing is due, if the instance is
+// already in the desired state, or if both schedules are due at the same time.
+func instanceBootScheduleAction(inst instance.Instance) instancetype.InstanceAction {
+	config := inst.ExpandedConfig()
+
+	start := config["boot.schedule.start"] != "" && snapshotIsScheduledNow(config["boot.schedule.start"], int64(inst.ID()))
+	stop := config["boot.schedule.stop"] != "" && snapshotIsScheduledNow(config["boot.schedule.stop"], int64(inst.ID()))
+
+	// Conflicting schedules cancel each other out.
+	if start == stop {
+		return ""
+	}
+
+	if start && !inst.IsRunning() {
+		return instancetype.Start
+	}
+
+	if stop && inst.IsRunning() {
+		return instancetype.Stop
+	}
+
+	return ""
+}
+
+func autoStartStopInstancesTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		s := d.State()
+
+		// Instances on an evacuated member are not started again until the member is restored.
+		evacuated := s.DB.Cluster.LocalNodeIsEvacuated()
+
+		// Only the cluster member hosting an instance acts on its boot schedule.
+		filter := dbCluster.InstanceFilter{Node: &s.ServerName}
+
+		var scheduled []scheduledInstanceAction
+
+		err := s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+			return tx.InstanceList(ctx, func(dbInst db.InstanceArgs, p api.Project) error {
+				expandedConfig := instancetype.ExpandInstanceConfig(nil, dbInst.Config, dbInst.Profiles)
+				if expandedConfig["boot.schedule.start"] == "" && expandedConfig["boot.schedule.stop"] == "" {
+					return nil
+				}
+
+				inst, err := instance.Load(s, dbInst, p)
+				if err != nil {
+					return fmt.Errorf("Failed loading instance %q (project %q) for boot schedule task: %w", dbInst.Name, dbInst.Project, err)
+				}
+
+				action := instanceBootScheduleAction(inst)
+				if action == "" || (action == instancetype.Start && evacuated) {
+					return nil
+				}
+
+				logger.Debug("Scheduling instance power action", logger.Ctx{"instance": inst.Name(), "project": inst.Project().Name, "action": action})
+				scheduled = append(scheduled, scheduledInstanceAction{inst: inst, action: action})
+
+				return nil
+			}, filter)
+		})
+		if err != nil {
+			logger.Error("Failed getting instance boot schedule info", logger.Ctx{"err": err})
+			return
+		}
+
+		for _, entry := range scheduled {
+			if ctx.Err() != nil {
+				return
+			}
+
+			err := autoStartStopInstance(ctx, s, entry.inst, entry.action)
+			autoStartStopInstanceOutcome(s, entry.inst, entry.action, err)
+		}
+	}
+
+	first := true
+	schedule := func() (time.Duration, error) {
+		interval := time.Minute
+
+		if first {
+			first = false
+			return interval, task.ErrSkip
+		}
+
+		return interval, nil
+	}
+
+	return f, schedule
+}
+
+// autoStartStopInstance starts or stops the instance in a task operation, so that the resulting lifecycle events
+// can be told apart from user initiated ones.
+func autoStartStopInstance(ctx context.Context, s *state.State, inst instance.Instance, action instancetype.InstanceAction) error {
+	l := logger.AddContext(logger.Ctx{"project": inst.Project().Name, "instance": inst.Name()})
+
+	opType := operationtype.InstanceScheduledStart
+	if action == instancetype.Stop {
+		opType = operationtype.InstanceScheduledStop
+	}
+
+	opRun := func(op *operations.Operation) error {
+		inst.SetOperation(op)
+
+		if action == instancetype.Start {
+			return inst.Start(false)
+		}
+
+		// Start with a clean shutdown.
+		timeoutSeconds := 30
+		value, ok := inst.ExpandedConfig()["boot.host_shutdown_timeout"]
+		if ok {
+			timeoutSeconds, _ = strconv.Atoi(value)
+		}
+
+		err := inst.Shutdown(time.Duration(timeoutSeconds) * time.Second)
+		if err != nil {
+			l.Warn("Failed shutting down instance, forcing stop", logger.Ctx{"err": err})
+
+			// Fallback to forced stop.
+			err = inst.Stop(false)
+			if err != nil && !errors.Is(err, instanceDrivers.ErrInstanceIsStopped) {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	resources := map[string][]api.URL{}
+	resources["instances"] = []api.URL{*api.NewURL().Path(version.APIVersion, "instances", inst.Name()).Project(inst.Project().Name)}
+
+	op, err := operations.OperationCreate(s, inst.Project().Name, operations.OperationClassTask, opType, resources, nil, opRun, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("Failed creating operation: %w", err)
+	}
+
+	l.Info("Running scheduled instance power action", logger.Ctx{"action": action})
+
+	err = op.Start()
+	if err != nil {
+		return fmt.Errorf("Failed starting operation: %w", err)
+	}
+
+	return op.Wait(ctx)
+}
+
+// autoStartStopInstanceOutcome raises a warning when a scheduled start or stop of the instance failed, and resolves
+// it once a scheduled action succeeds. Failed actions aren't retried until the schedule is due again.
+func autoStartStopInstanceOutcome(s *state.State, inst instance.Instance, action instancetype.InstanceAction, actionErr error) {
+	l := logger.AddContext(logger.Ctx{"project": inst.Project().Name, "instance": inst.Name()})
+
+	if actionErr == nil {
+		err := warnings.ResolveWarningsByLocalNodeAndProjectAndTypeAndEntity(s.DB.Cluster, inst.Project().Name, warningtype.InstanceScheduledPowerFailure, entity.TypeInstance, inst.ID())
+		if err != nil {
+			l.Warn("Failed to resolve scheduled instance power action failure warning", logger.Ctx{"err": err})
+		}
+
+		return
+	}
+
+	l.Error("Failed scheduled instance power action", logger.Ctx{"action": action, "err": actionErr})
+
+	err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		return tx.UpsertWarningLocalNode(ctx, inst.Project().Name, entity.TypeInstance, inst.ID(), warningtype.InstanceScheduledPowerFailure, fmt.Sprintf("Failed scheduled %s: %v", action, actionErr))
+	})
+	if err != nil {
+		l.Warn("Failed to create scheduled instance power action failure warning", logger.Ctx{"err": err})
+	}
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/sftp"
+
+	"github.com/canonical/lxd/lxd/device"
+	"github.com/canonical/lxd/lxd/instance"
+	"github.com/canonical/lxd/lxd/request"
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+)
+
+// cloudInitRunDir is where cloud-init keeps its status for the current boot.
+const cloudInitRunDir = "/run/cloud-init"
+
+// cloudInitStages lists the cloud-init stages in the order they run.
+var cloudInitStages = []string{"init-local", "init", "modules-config", "modules-final"}
+
+// cloudInitStage is a stage entry of cloud-init's status.json.
+type cloudInitStage struct {
+	Errors []string `json:"errors"`
+}
+
+// cloudInitStatusFile is the content of cloud-init's status.json.
+type cloudInitStatusFile struct {
+	V1 map[string]json.RawMessage `json:"v1"`
+}
+
+// cloudInitResultFile is the content of cloud-init's result.json, written once cloud-init has finished.
+type cloudInitResultFile struct {
+	V1 struct {
+		Datasource string   `json:"datasource"`
+		Errors     []string `json:"errors"`
+	} `json:"v1"`
+}
+
+// swagger:operation GET /1.0/instances/{name}/cloud-init instances instance_cloud_init_get
+//
+//	Get the cloud-init status
+//
+//	Gets the cloud-init status of the instance, as reported by the files cloud-init writes in the guest.
+//	If `wait` is set, the request only returns once cloud-init has finished, the timeout is reached
+//	or the instance stops.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	  - in: query
+//	    name: wait
+//	    description: Wait for cloud-init to finish
+//	    type: boolean
+//	  - in: query
+//	    name: timeout
+//	    description: How long to wait (in s) when waiting, -1 to wait indefinitely
+//	    type: integer
+//	    example: 300
+//	responses:
+//	  "200":
+//	    description: Cloud-init status
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          $ref: "#/definitions/InstanceCloudInit"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func instanceCloudInitGet(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	instanceType, err := urlInstanceTypeDetect(r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	projectName := request.ProjectParam(r)
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if shared.IsSnapshot(name) {
+		return response.BadRequest(fmt.Errorf("Invalid instance name"))
+	}
+
+	wait := shared.IsTrue(request.QueryParam(r, "wait"))
+	timeoutSecs, err := shared.AtoiEmptyDefault(request.QueryParam(r, "timeout"), -1)
+	if err != nil {
+		return response.BadRequest(fmt.Errorf("Invalid timeout: %w", err))
+	}
+
+	// Handle requests targeted to an instance on a different node.
+	resp, err := forwardedResponseIfInstanceIsRemote(s, r, projectName, name, instanceType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if resp != nil {
+		return resp
+	}
+
+	inst, err := instance.LoadByProjectAndName(s, projectName, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !wait {
+		if !inst.IsRunning() {
+			return response.BadRequest(fmt.Errorf("Instance is not running"))
+		}
+
+		status, err := instanceCloudInitStatus(inst)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.SyncResponse(true, status)
+	}
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+
+	// If timeout is -1, it will wait indefinitely otherwise it will timeout after timeoutSecs.
+	if timeoutSecs > -1 {
+		ctx, cancel = context.WithDeadline(r.Context(), time.Now().Add(time.Second*time.Duration(timeoutSecs)))
+	} else {
+		ctx, cancel = context.WithCancel(r.Context())
+	}
+
+	waitResponse := func(w http.ResponseWriter) error {
+		defer cancel()
+
+		// Write header to avoid client side timeouts.
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusOK)
+		f, ok := w.(http.Flusher)
+		if ok {
+			f.Flush()
+		}
+
+		status, err := instanceCloudInitWait(ctx, inst)
+		if err != nil {
+			_ = response.SmartError(err).Render(w)
+			return nil
+		}
+
+		_ = response.SyncResponse(true, status).Render(w)
+		return nil
+	}
+
+	return response.ManualResponse(waitResponse)
+}
+
+// instanceCloudInitWait polls the cloud-init status of the instance until cloud-init has finished or the context is done.
+// The last known status is returned once the context is done, so that callers can tell where cloud-init got to.
+func instanceCloudInitWait(ctx context.Context, inst instance.Instance) (*api.InstanceCloudInit, error) {
+	var status *api.InstanceCloudInit
+	var err error
+
+	for {
+		if !inst.IsRunning() {
+			return nil, api.StatusErrorf(http.StatusBadRequest, "Instance is not running")
+		}
+
+		// The guest may not be reachable yet while the instance boots, so keep trying until the context is done.
+		status, err = instanceCloudInitStatus(inst)
+		if err == nil && !shared.ValueInSlice(status.Status, []string{api.InstanceCloudInitStatusNotStarted, api.InstanceCloudInitStatusRunning}) {
+			return status, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return nil, err
+			}
+
+			return status, nil
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// instanceCloudInitStatus reads the cloud-init status files of the running instance.
+func instanceCloudInitStatus(inst instance.Instance) (*api.InstanceCloudInit, error) {
+	client, err := inst.FileSFTP()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = client.Close() }()
+
+	status := &api.InstanceCloudInit{
+		Status: api.InstanceCloudInitStatusNotAvailable,
+		Errors: []string{},
+	}
+
+	// Check whether cloud-init is installed and enabled.
+	for _, path := range []string{"/etc/cloud/cloud-init.disabled", cloudInitRunDir + "/disabled"} {
+		_, err = client.Stat(path)
+		if err == nil {
+			return status, nil
+		}
+	}
+
+	_, err = client.Stat("/etc/cloud")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return status, nil
+		}
+
+		return nil, fmt.Errorf("Failed checking for cloud-init: %w", err)
+	}
+
+	// Once cloud-init is done, result.json holds the outcome of the whole run.
+	result := cloudInitResultFile{}
+	found, err := instanceCloudInitReadFile(client, cloudInitRunDir+"/result.json", &result)
+	if err != nil {
+		return nil, err
+	}
+
+	if found {
+		status.Status = api.InstanceCloudInitStatusDone
+		status.Datasource = result.V1.Datasource
+		if len(result.V1.Errors) > 0 {
+			status.Status = api.InstanceCloudInitStatusError
+			status.Errors = result.V1.Errors
+		}
+
+		return status, nil
+	}
+
+	// Otherwise, status.json records the progress of each stage.
+	statusFile := cloudInitStatusFile{}
+	found, err = instanceCloudInitReadFile(client, cloudInitRunDir+"/status.json", &statusFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		status.Status = api.InstanceCloudInitStatusNotStarted
+		return status, nil
+	}
+
+	status.Status = api.InstanceCloudInitStatusRunning
+	_ = json.Unmarshal(statusFile.V1["datasource"], &status.Datasource)
+	_ = json.Unmarshal(statusFile.V1["stage"], &status.Stage)
+
+	for _, stageName := range cloudInitStages {
+		value, ok := statusFile.V1[stageName]
+		if !ok {
+			continue
+		}
+
+		stage := cloudInitStage{}
+		err := json.Unmarshal(value, &stage)
+		if err != nil {
+			continue
+		}
+
+		status.Errors = append(status.Errors, stage.Errors...)
+	}
+
+	if len(status.Errors) > 0 {
+		status.Status = api.InstanceCloudInitStatusError
+	}
+
+	return status, nil
+}
+
+// instanceCloudInitReadFile decodes a JSON file from the instance into target.
+// Returns false if the file doesn't exist.
+func instanceCloudInitReadFile(client *sftp.Client, path string, target any) (bool, error) {
+	file, err := client.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("Failed opening %q: %w", path, err)
+	}
+
+	defer func() { _ = file.Close() }()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return false, fmt.Errorf("Failed reading %q: %w", path, err)
+	}
+
+	err = json.Unmarshal(content, target)
+	if err != nil {
+		return false, fmt.Errorf("Failed parsing %q: %w", path, err)
+	}
+
+	return true, nil
+}
+
+// swagger:operation GET /1.0/instances/{name}/cloud-init/network-config instances instance_cloud_init_network_config_get
+//
+//	Get the generated cloud-init network configuration
+//
+//	Gets the cloud-init network configuration generated from the NIC devices of the instance.
+//	This is what the instance is provided with when `cloud-init.network-config` is set to `auto`.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	responses:
+//	  "200":
+//	    description: Generated network configuration
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          $ref: "#/definitions/InstanceCloudInitNetworkConfig"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func instanceCloudInitNetworkConfigGet(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	projectName := request.ProjectParam(r)
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if shared.IsSnapshot(name) {
+		return response.BadRequest(fmt.Errorf("Invalid instance name"))
+	}
+
+	inst, err := instance.LoadByProjectAndName(s, projectName, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	networkConfig, err := device.GenerateCloudInitNetworkConfig(s, inst)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	config := inst.ExpandedConfig()
+
+	resp := api.InstanceCloudInitNetworkConfig{
+		NetworkConfig: networkConfig,
+		Active:        config["cloud-init.network-config"] == device.CloudInitNetworkConfigAuto && config["user.network-config"] == "",
+	}
+
+	return response.SyncResponse(true, resp)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -17,6 +18,7 @@ import (
 	liblxc "github.com/lxc/go-lxc"
 	"golang.org/x/sys/unix"
 
+	"github.com/canonical/lxd/lxd/auth"
 	"github.com/canonical/lxd/lxd/cluster"
 	"github.com/canonical/lxd/lxd/db/operationtype"
 	"github.com/canonical/lxd/lxd/instance"
@@ -24,9 +26,14 @@ import (
 	"github.com/canonical/lxd/lxd/operations"
 	"github.com/canonical/lxd/lxd/request"
 	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/lxd/state"
+	storagePools "github.com/canonical/lxd/lxd/storage"
+	"github.com/canonical/lxd/lxd/task"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
 	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/units"
 	"github.com/canonical/lxd/shared/version"
 	"github.com/canonical/lxd/shared/ws"
 )
@@ -533,6 +540,8 @@ func instanceConsolePost(d *Daemon, r *http.Request) response.Response {
 //	Get console log
 //
 //	Gets the console log for the instance.
+//	If the history query parameter is set, the console history of the instance is returned instead, which requires
+//	the instance to have `console.history.size` set and the `can_access_console` entitlement on it.
 //
 //	---
 //	produces:
@@ -543,6 +552,11 @@ func instanceConsolePost(d *Daemon, r *http.Request) response.Response {
 //	    description: Project name
 //	    type: string
 //	    example: default
+//	  - in: query
+//	    name: history
+//	    description: Number of bytes of the console history to return, or `all`, instead of the console buffer
+//	    type: string
+//	    example: all
 //	responses:
 //	  "200":
 //	     description: Raw console log
@@ -587,6 +601,11 @@ func instanceConsoleLogGet(d *Daemon, r *http.Request) response.Response {
 		return resp
 	}
 
+	history := request.QueryParam(r, "history")
+	if history != "" {
+		return instanceConsoleHistoryGet(s, r, projectName, name, history)
+	}
+
 	if !liblxc.RuntimeLiblxcVersionAtLeast(liblxc.Version(), 3, 0, 0) {
 		return response.BadRequest(fmt.Errorf("Querying the console buffer requires liblxc >= 3.0"))
 	}
@@ -614,12 +633,13 @@ func instanceConsoleLogGet(d *Daemon, r *http.Request) response.Response {
 		return response.FileResponse(r, []response.FileResponseEntry{ent}, nil)
 	}
 
-	// Query the container's console ringbuffer.
+	// Query the container's console ringbuffer. Its contents aren't written to the log file if it is the console
+	// history, as the console output is already written to it.
 	console := liblxc.ConsoleLogOptions{
 		ClearLog:       false,
 		ReadLog:        true,
 		ReadMax:        0,
-		WriteToLogFile: true,
+		WriteToLogFile: c.ExpandedConfig()["console.history.size"] == "",
 	}
 
 	// Send a ringbuffer request to the container.
@@ -644,6 +664,96 @@ func instanceConsoleLogGet(d *Daemon, r *http.Request) response.Response {
 	return response.FileResponse(r, []response.FileResponseEntry{ent}, nil)
 }
 
+// instanceConsoleHistoryGet returns the most recent bytes of the console history of the instance, or all of it if
+// history is "all". Unlike the console buffer, the console history requires the can_access_console entitlement.
+func instanceConsoleHistoryGet(s *state.State, r *http.Request, projectName string, name string, history string) response.Response {
+	err := s.Authorizer.CheckPermission(r.Context(), r, entity.InstanceURL(projectName, name), auth.EntitlementCanAccessConsole)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	maxBytes := int64(-1)
+	if history != "all" {
+		maxBytes, err = units.ParseByteSizeString(history)
+		if err != nil || maxBytes <= 0 {
+			return response.BadRequest(fmt.Errorf("Invalid console history size %q", history))
+		}
+	}
+
+	inst, err := instance.LoadByProjectAndName(s, projectName, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	size, err := instance.ConsoleHistorySize(inst.ExpandedConfig())
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if size == 0 {
+		return response.BadRequest(fmt.Errorf("Instance %q does not keep a console history", name))
+	}
+
+	// The console history is in the instance directory, which is only mounted while the instance is running.
+	pool, err := storagePools.LoadByInstance(s, inst)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	_, err = storagePools.InstanceMount(pool, inst, nil)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	defer func() { _ = storagePools.InstanceUnmount(pool, inst, nil) }()
+
+	content, err := instance.ConsoleHistoryRead(inst.ConsoleHistoryPath(), maxBytes)
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Failed reading console history: %w", err))
+	}
+
+	ent := response.FileResponseEntry{
+		File:         bytes.NewReader(content),
+		FileModified: time.Now(),
+		FileSize:     int64(len(content)),
+		Filename:     "console-history.log",
+	}
+
+	return response.FileResponse(r, []response.FileResponseEntry{ent}, nil)
+}
+
+// rotateConsoleHistoryTask rotates the console history of the running virtual machines on this member once it has
+// reached its size. The console history of containers is rotated by liblxc.
+func rotateConsoleHistoryTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		s := d.State()
+
+		instances, err := instance.LoadNodeAll(s, instancetype.VM)
+		if err != nil {
+			logger.Warn("Failed loading instances to rotate their console history", logger.Ctx{"err": err})
+			return
+		}
+
+		for _, inst := range instances {
+			if !inst.IsRunning() {
+				continue
+			}
+
+			size, err := instance.ConsoleHistorySize(inst.ExpandedConfig())
+			if err != nil || size == 0 {
+				continue
+			}
+
+			err = instance.ConsoleHistoryRotate(inst.ConsoleHistoryPath(), size)
+			if err != nil {
+				logger.Warn("Failed rotating console history", logger.Ctx{"project": inst.Project().Name, "instance": inst.Name(), "err": err})
+			}
+		}
+	}
+
+	return f, task.Every(time.Minute)
+}
+
 // swagger:operation DELETE /1.0/instances/{name}/console instances instance_console_delete
 //
 //	Clear the console log
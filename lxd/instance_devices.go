@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+
+	"github.com/canonical/lxd/lxd/db"
+	deviceConfig "github.com/canonical/lxd/lxd/device/config"
+	"github.com/canonical/lxd/lxd/instance"
+	projecthelpers "github.com/canonical/lxd/lxd/project"
+	"github.com/canonical/lxd/lxd/request"
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/lxd/state"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/osarch"
+)
+
+// swagger:operation POST /1.0/instances/{name}/devices/{deviceName} instances instance_device_post
+//
+//	Add a device
+//
+//	Adds a single device to the instance's local devices, without replacing the rest of its configuration.
+//	If the instance is running, the device is hotplugged. If the device doesn't support hotplug, the
+//	request fails with a conflict error and the instance is left unchanged.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	  - in: body
+//	    name: device
+//	    description: Device configuration
+//	    required: true
+//	    schema:
+//	      $ref: "#/definitions/InstanceDevicePost"
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "409":
+//	    $ref: "#/responses/Conflict"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func instanceDevicePost(d *Daemon, r *http.Request) response.Response {
+	// Don't mess with instance while in setup mode.
+	<-d.waitReady.Done()
+
+	s := d.State()
+
+	req := api.InstanceDevicePost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if len(req.Config) == 0 {
+		return response.BadRequest(fmt.Errorf("No device configuration provided"))
+	}
+
+	return instanceDeviceUpdate(s, r, func(inst instance.Instance, deviceName string, devices deviceConfig.Devices) error {
+		_, found := devices[deviceName]
+		if found {
+			return api.StatusErrorf(http.StatusConflict, "Device %q already exists on instance %q", deviceName, inst.Name())
+		}
+
+		devices[deviceName] = req.Config
+
+		return nil
+	})
+}
+
+// swagger:operation DELETE /1.0/instances/{name}/devices/{deviceName} instances instance_device_delete
+//
+//	Remove a device
+//
+//	Removes a single device from the instance's local devices, without replacing the rest of its configuration.
+//	If the instance is running, the device is hot-unplugged. If the device doesn't support hotplug, the
+//	request fails with a conflict error and the instance is left unchanged.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "409":
+//	    $ref: "#/responses/Conflict"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func instanceDeviceDelete(d *Daemon, r *http.Request) response.Response {
+	// Don't mess with instance while in setup mode.
+	<-d.waitReady.Done()
+
+	s := d.State()
+
+	return instanceDeviceUpdate(s, r, func(inst instance.Instance, deviceName string, devices deviceConfig.Devices) error {
+		_, found := devices[deviceName]
+		if !found {
+			_, found = inst.ExpandedDevices()[deviceName]
+			if found {
+				return api.StatusErrorf(http.StatusBadRequest, "Device %q is inherited from a profile and cannot be removed from instance %q", deviceName, inst.Name())
+			}
+
+			return api.StatusErrorf(http.StatusNotFound, "Device %q not found on instance %q", deviceName, inst.Name())
+		}
+
+		delete(devices, deviceName)
+
+		return nil
+	})
+}
+
+// instanceDeviceUpdate applies a change to the local devices of the instance targeted by the request.
+// The change is made under the instance operation lock and goes through the regular instance update, which
+// validates the devices, hotplugs them on running instances and records them in the database and the backup file.
+func instanceDeviceUpdate(s *state.State, r *http.Request, change func(inst instance.Instance, deviceName string, devices deviceConfig.Devices) error) response.Response {
+	instanceType, err := urlInstanceTypeDetect(r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	projectName := request.ProjectParam(r)
+
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if shared.IsSnapshot(name) {
+		return response.BadRequest(fmt.Errorf("Invalid instance name"))
+	}
+
+	deviceName, err := url.PathUnescape(mux.Vars(r)["deviceName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Handle requests targeted to an instance on a different node.
+	resp, err := forwardedResponseIfInstanceIsRemote(s, r, projectName, name, instanceType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if resp != nil {
+		return resp
+	}
+
+	unlock, err := instanceOperationLock(s.ShutdownCtx, projectName, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	defer unlock()
+
+	inst, err := instance.LoadByProjectAndName(s, projectName, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	devices := inst.LocalDevices().Clone()
+	err = change(inst, deviceName, devices)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Check project limits.
+	profileNames := make([]string, 0, len(inst.Profiles()))
+	for _, profile := range inst.Profiles() {
+		profileNames = append(profileNames, profile.Name)
+	}
+
+	architectureName, err := osarch.ArchitectureName(inst.Architecture())
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	req := api.InstancePut{
+		Architecture: architectureName,
+		Config:       inst.LocalConfig(),
+		Devices:      devices.CloneNative(),
+		Ephemeral:    inst.IsEphemeral(),
+		Profiles:     profileNames,
+		Description:  inst.Description(),
+	}
+
+	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		return projecthelpers.AllowInstanceUpdate(s.GlobalConfig, tx, projectName, name, req, inst.LocalConfig())
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	args := db.InstanceArgs{
+		Architecture: inst.Architecture(),
+		Config:       inst.LocalConfig(),
+		Description:  inst.Description(),
+		Devices:      devices,
+		Ephemeral:    inst.IsEphemeral(),
+		Profiles:     inst.Profiles(),
+		Project:      projectName,
+	}
+
+	err = inst.Update(args, true)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
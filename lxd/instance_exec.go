@@ -24,6 +24,7 @@ import (
 	"github.com/canonical/lxd/lxd/db/operationtype"
 	"github.com/canonical/lxd/lxd/instance"
 	"github.com/canonical/lxd/lxd/instance/instancetype"
+	"github.com/canonical/lxd/lxd/lifecycle"
 	"github.com/canonical/lxd/lxd/operations"
 	"github.com/canonical/lxd/lxd/request"
 	"github.com/canonical/lxd/lxd/response"
@@ -185,6 +186,18 @@ func (s *execWs) Do(op *operations.Operation) error {
 	var stdout *os.File
 	var stderr *os.File
 
+	// Start recording the session if required, refusing to run the command unrecorded.
+	var rec *execRecording
+	var recFile string
+	if execRecordingEnabled(s.instance) {
+		rec, recFile, err = execRecordingCreate(execRecordingsPath(s.instance.Project().Name, s.instance.Name()), op.ID(), s.req)
+		if err != nil {
+			return fmt.Errorf("Failed creating exec recording: %w", err)
+		}
+
+		s.s.Events.SendLifecycle(s.instance.Project().Name, lifecycle.InstanceExecRecordingStarted.Event(recFile, s.instance, op.Requestor(), logger.Ctx{"command": s.req.Command, "interactive": s.req.Interactive}))
+	}
+
 	if s.req.Interactive {
 		if s.instance.Type() == instancetype.Container {
 			// For containers, we setup a PTY on the LXD server.
@@ -289,6 +302,15 @@ func (s *execWs) Do(op *operations.Operation) error {
 			_ = pty.Close()
 		}
 
+		if rec != nil {
+			err = rec.Close()
+			if err != nil {
+				logger.Warn("Failed closing exec recording", logger.Ctx{"project": s.instance.Project().Name, "instance": s.instance.Name(), "file": recFile, "err": err})
+			}
+
+			s.s.Events.SendLifecycle(s.instance.Project().Name, lifecycle.InstanceExecRecordingFinished.Event(recFile, s.instance, op.Requestor(), logger.Ctx{"command": s.req.Command, "return": cmdResult}))
+		}
+
 		metadata := shared.Jmap{"return": cmdResult}
 		err = op.ExtendMetadata(metadata)
 		if err != nil {
@@ -394,6 +416,10 @@ func (s *execWs) Do(op *operations.Operation) error {
 					l.Debug("Failed to set window size", logger.Ctx{"err": err, "width": winchWidth, "height": winchHeight})
 					continue
 				}
+
+				if rec != nil {
+					rec.event(execRecordingResize, fmt.Sprintf("%dx%d", winchWidth, winchHeight))
+				}
 			} else if command.Command == "signal" {
 				err := cmd.Signal(unix.Signal(command.Signal))
 				if err != nil {
@@ -424,10 +450,22 @@ func (s *execWs) Do(op *operations.Operation) error {
 			if s.instance.Type() == instancetype.Container {
 				// For containers, we are running the command via the local LXD managed PTY and so
 				// need to use the same PTY handle for both read and write.
-				readDone, writeDone = ws.Mirror(conn, shared.NewExecWrapper(waitAttachedChildIsDead, ptys[0]))
+				var pty io.ReadWriteCloser = shared.NewExecWrapper(waitAttachedChildIsDead, ptys[0])
+				if rec != nil {
+					pty = rec.recordTerminal(pty)
+				}
+
+				readDone, writeDone = ws.Mirror(conn, pty)
 			} else {
-				readDone = ws.MirrorRead(conn, ptys[execWSStdout])
-				writeDone = ws.MirrorWrite(conn, ttys[execWSStdin])
+				var output io.Reader = ptys[execWSStdout]
+				var input io.Writer = ttys[execWSStdin]
+				if rec != nil {
+					output = rec.recordReader(output, execRecordingOutput)
+					input = rec.recordWriter(input, execRecordingInput)
+				}
+
+				readDone = ws.MirrorRead(conn, output)
+				writeDone = ws.MirrorWrite(conn, input)
 			}
 
 			readErr = <-readDone
@@ -472,10 +510,20 @@ func (s *execWs) Do(op *operations.Operation) error {
 				}
 
 				if i == execWSStdin {
-					err = <-ws.MirrorWrite(conn, ttys[i])
+					var input io.Writer = ttys[i]
+					if rec != nil {
+						input = rec.recordWriter(input, execRecordingInput)
+					}
+
+					err = <-ws.MirrorWrite(conn, input)
 					_ = ttys[i].Close()
 				} else {
-					err = <-ws.MirrorRead(conn, shared.NewExecWrapper(waitAttachedChildIsDead, ptys[i]))
+					var output io.Reader = shared.NewExecWrapper(waitAttachedChildIsDead, ptys[i])
+					if rec != nil {
+						output = rec.recordReader(output, execRecordingOutput)
+					}
+
+					err = <-ws.MirrorRead(conn, output)
 					_ = ptys[i].Close()
 					wgEOF.Done()
 				}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/canonical/lxd/lxd/instance"
+	"github.com/canonical/lxd/lxd/project"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+)
+
+// Asciicast event codes used in exec recordings.
+const (
+	execRecordingOutput = "o"
+	execRecordingInput  = "i"
+	execRecordingResize = "r"
+)
+
+// execRecordingEnabled returns whether exec sessions into the instance should be recorded.
+func execRecordingEnabled(inst instance.Instance) bool {
+	return shared.IsTrue(inst.ExpandedConfig()["exec.record"]) || shared.IsTrue(inst.Project().Config["exec.record"])
+}
+
+// execRecordingsPath returns the directory holding the exec recordings of an instance.
+func execRecordingsPath(projectName string, instanceName string) string {
+	return shared.LogPath(project.Instance(projectName, instanceName), "exec")
+}
+
+// validExecRecordingFileName returns whether the name is one of an exec recording.
+func validExecRecordingFileName(fName string) bool {
+	return strings.HasPrefix(fName, "exec_") && strings.HasSuffix(fName, ".cast") && !strings.Contains(fName, "/")
+}
+
+// execRecordingHeader is the header line of an asciicast v2 file.
+type execRecordingHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Command   string            `json:"command,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// execRecording writes an exec session to a file in the asciicast v2 format.
+type execRecording struct {
+	mu     sync.Mutex
+	w      io.WriteCloser
+	start  time.Time
+	closed bool
+	failed bool
+}
+
+// execRecordingCreate creates a new recording of the exec request in the given directory.
+func execRecordingCreate(dir string, id string, req api.InstanceExecPost) (*execRecording, string, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, "", err
+	}
+
+	start := time.Now()
+	fileName := fmt.Sprintf("exec_%s_%s.cast", start.UTC().Format("20060102T150405Z"), id)
+
+	f, err := os.OpenFile(filepath.Join(dir, fileName), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rec, err := newExecRecording(f, start, req)
+	if err != nil {
+		_ = f.Close()
+		return nil, "", err
+	}
+
+	return rec, fileName, nil
+}
+
+// newExecRecording writes the asciicast header for the exec request and returns the recording.
+func newExecRecording(w io.WriteCloser, start time.Time, req api.InstanceExecPost) (*execRecording, error) {
+	header := execRecordingHeader{
+		Version:   2,
+		Width:     req.Width,
+		Height:    req.Height,
+		Timestamp: start.Unix(),
+		Command:   strings.Join(req.Command, " "),
+	}
+
+	// Non-interactive sessions have no terminal, use the usual default size.
+	if header.Width <= 0 || header.Height <= 0 {
+		header.Width = 80
+		header.Height = 24
+	}
+
+	term, ok := req.Environment["TERM"]
+	if ok {
+		header.Env = map[string]string{"TERM": term}
+	}
+
+	buf, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = w.Write(append(buf, '\n'))
+	if err != nil {
+		return nil, err
+	}
+
+	return &execRecording{w: w, start: start}, nil
+}
+
+// event appends an event to the recording.
+// Failures are logged once and don't interrupt the session, events after the recording is closed are dropped.
+func (r *execRecording) event(code string, data string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed || r.failed {
+		return
+	}
+
+	elapsed := float64(time.Since(r.start).Microseconds()) / 1e6
+
+	buf, err := json.Marshal([]any{elapsed, code, data})
+	if err == nil {
+		_, err = r.w.Write(append(buf, '\n'))
+	}
+
+	if err != nil {
+		r.failed = true
+		logger.Warn("Failed writing exec recording", logger.Ctx{"err": err})
+	}
+}
+
+// Close closes the recording file.
+func (r *execRecording) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+
+	return r.w.Close()
+}
+
+// stream returns a writer recording its data as events of the given code.
+func (r *execRecording) stream(code string) *execRecordingStream {
+	return &execRecordingStream{rec: r, code: code}
+}
+
+// execRecordingStream turns data written to it into events of a recording.
+// Incomplete UTF-8 sequences are held back until the rest of the sequence is written.
+type execRecordingStream struct {
+	rec     *execRecording
+	code    string
+	pending []byte
+}
+
+// Write records the data.
+func (s *execRecordingStream) Write(p []byte) (int, error) {
+	data := append(s.pending, p...)
+
+	cut := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				cut = i
+			}
+
+			break
+		}
+	}
+
+	s.pending = append([]byte(nil), data[cut:]...)
+	if cut > 0 {
+		s.rec.event(s.code, string(data[:cut]))
+	}
+
+	return len(p), nil
+}
+
+// execRecordingReader records the data read from a reader.
+type execRecordingReader struct {
+	r      io.Reader
+	stream *execRecordingStream
+}
+
+// Read reads from the underlying reader and records the data.
+func (e *execRecordingReader) Read(p []byte) (int, error) {
+	n, err := e.r.Read(p)
+	if n > 0 {
+		_, _ = e.stream.Write(p[:n])
+	}
+
+	return n, err
+}
+
+// execRecordingWriter records the data written to a writer.
+type execRecordingWriter struct {
+	w      io.Writer
+	stream *execRecordingStream
+}
+
+// Write records the data and writes it to the underlying writer.
+func (e *execRecordingWriter) Write(p []byte) (int, error) {
+	_, _ = e.stream.Write(p)
+
+	return e.w.Write(p)
+}
+
+// execRecordingReadWriteCloser records the output read from and the input written to a terminal.
+type execRecordingReadWriteCloser struct {
+	execRecordingReader
+	execRecordingWriter
+	io.Closer
+}
+
+// recordReader returns a reader recording the data read from r as events of the given code.
+func (r *execRecording) recordReader(rd io.Reader, code string) io.Reader {
+	return &execRecordingReader{r: rd, stream: r.stream(code)}
+}
+
+// recordWriter returns a writer recording the data written to w as events of the given code.
+func (r *execRecording) recordWriter(w io.Writer, code string) io.Writer {
+	return &execRecordingWriter{w: w, stream: r.stream(code)}
+}
+
+// recordTerminal returns a ReadWriteCloser recording the output read from and the input written to rwc.
+func (r *execRecording) recordTerminal(rwc io.ReadWriteCloser) io.ReadWriteCloser {
+	return &execRecordingReadWriteCloser{
+		execRecordingReader: execRecordingReader{r: rwc, stream: r.stream(execRecordingOutput)},
+		execRecordingWriter: execRecordingWriter{w: rwc, stream: r.stream(execRecordingInput)},
+		Closer:              rwc,
+	}
+}
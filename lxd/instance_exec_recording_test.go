@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/lxd/shared/api"
+)
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func TestExecRecording(t *testing.T) {
+	buf := &bytes.Buffer{}
+	req := api.InstanceExecPost{
+		Command:     []string{"cat", "-"},
+		Environment: map[string]string{"TERM": "xterm", "HOME": "/root"},
+	}
+
+	rec, err := newExecRecording(nopWriteCloser{buf}, time.Now(), req)
+	require.NoError(t, err)
+
+	// "é" is split across two writes and must only be recorded once complete.
+	in := rec.recordWriter(io.Discard, execRecordingInput)
+	_, err = in.Write([]byte("caf\xc3"))
+	require.NoError(t, err)
+	_, err = in.Write([]byte("\xa9\n"))
+	require.NoError(t, err)
+
+	out := rec.recordReader(bytes.NewReader([]byte("café\n")), execRecordingOutput)
+	_, err = io.ReadAll(out)
+	require.NoError(t, err)
+
+	rec.event(execRecordingResize, "100x50")
+	require.NoError(t, rec.Close())
+
+	// Events after the recording is closed are dropped.
+	rec.event(execRecordingOutput, "dropped")
+
+	scanner := bufio.NewScanner(buf)
+	require.True(t, scanner.Scan())
+
+	header := execRecordingHeader{}
+	require.NoError(t, json.Unmarshal(scanner.Bytes(), &header))
+	assert.Equal(t, execRecordingHeader{Version: 2, Width: 80, Height: 24, Timestamp: header.Timestamp, Command: "cat -", Env: map[string]string{"TERM": "xterm"}}, header)
+
+	var events [][]any
+	for scanner.Scan() {
+		var event []any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		require.Len(t, event, 3)
+		events = append(events, event[1:])
+	}
+
+	assert.Equal(t, [][]any{{"i", "caf"}, {"i", "é\n"}, {"o", "café\n"}, {"r", "100x50"}}, events)
+}
+
+func TestValidExecRecordingFileName(t *testing.T) {
+	assert.True(t, validExecRecordingFileName("exec_20240304T120000Z_d0a89537-0617-4ed6-a79b-c2e88a970965.cast"))
+	assert.False(t, validExecRecordingFileName("lxc.log"))
+	assert.False(t, validExecRecordingFileName("exec_foo.stdout"))
+	assert.False(t, validExecRecordingFileName("exec_/../../lxc.cast"))
+}
@@ -15,16 +15,29 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/pkg/sftp"
 
+	"github.com/canonical/lxd/lxd/auth"
 	"github.com/canonical/lxd/lxd/instance"
 	"github.com/canonical/lxd/lxd/lifecycle"
 	"github.com/canonical/lxd/lxd/request"
 	"github.com/canonical/lxd/lxd/response"
 	"github.com/canonical/lxd/lxd/state"
 	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/entity"
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/revert"
 )
 
+// instanceFileReadAccessHandler allows reading the files of an instance if the caller can either read and write them
+// or only read them.
+func instanceFileReadAccessHandler(d *Daemon, r *http.Request) response.Response {
+	resp := allowPermission(entity.TypeInstance, auth.EntitlementCanEditFiles, "name")(d, r)
+	if resp == response.EmptySyncResponse {
+		return resp
+	}
+
+	return allowPermission(entity.TypeInstance, auth.EntitlementCanAccessFiles, "name")(d, r)
+}
+
 func instanceFileHandler(d *Daemon, r *http.Request) response.Response {
 	s := d.State()
 
@@ -88,11 +101,13 @@ func instanceFileHandler(d *Daemon, r *http.Request) response.Response {
 //	Get a file
 //
 //	Gets the file content. If it's a directory, a json list of files will be returned instead.
+//	With `format=tar` and `recursive=true`, the directory is returned as a tarball instead.
 //
 //	---
 //	produces:
 //	  - application/json
 //	  - application/octet-stream
+//	  - application/x-tar
 //	parameters:
 //	  - in: query
 //	    name: path
@@ -100,6 +115,16 @@ func instanceFileHandler(d *Daemon, r *http.Request) response.Response {
 //	    type: string
 //	    example: default
 //	  - in: query
+//	    name: format
+//	    description: Set to `tar` to retrieve a directory as a tarball
+//	    type: string
+//	    example: tar
+//	  - in: query
+//	    name: recursive
+//	    description: Whether to include the content of the directory recursively (required by the tar format)
+//	    type: boolean
+//	    example: true
+//	  - in: query
 //	    name: project
 //	    description: Project name
 //	    type: string
@@ -152,6 +177,10 @@ func instanceFileHandler(d *Daemon, r *http.Request) response.Response {
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
 func instanceFileGet(s *state.State, inst instance.Instance, path string, r *http.Request) response.Response {
+	if r.FormValue("format") == "tar" {
+		return instanceFileGetTar(s, inst, path, r)
+	}
+
 	revert := revert.New()
 	defer revert.Fail()
 
@@ -408,7 +437,7 @@ func instanceFileHead(s *state.State, inst instance.Instance, path string, r *ht
 //	    example: 0644
 //	  - in: header
 //	    name: X-LXD-type
-//	    description: Type of file (file, symlink or directory)
+//	    description: Type of file (file, symlink, directory or tar to unpack a tarball into the directory)
 //	    schema:
 //	      type: string
 //	    example: file
@@ -449,6 +478,42 @@ func instanceFilePost(s *state.State, inst instance.Instance, path string, r *ht
 	_, err = client.Stat(path)
 	exists := err == nil
 
+	if type_ == "tar" {
+		// Create the target directory.
+		if !exists {
+			err = client.Mkdir(path)
+			if err != nil {
+				return response.SmartError(err)
+			}
+
+			if mode < 0 {
+				// Default mode for directories (sftp doesn't know about umask).
+				mode = 0750
+			}
+
+			err = client.Chmod(path, fs.FileMode(mode))
+			if err != nil {
+				return response.SmartError(err)
+			}
+
+			if uid >= 0 || gid >= 0 {
+				err = client.Chown(path, int(uid), int(gid))
+				if err != nil {
+					return response.SmartError(err)
+				}
+			}
+		}
+
+		// Unpack the tarball into the target directory.
+		err = instanceFileTarUnpack(client, path, r.Body, instanceFileTarMaxSize)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		s.Events.SendLifecycle(inst.Project().Name, lifecycle.InstanceFilePushed.Event(inst, logger.Ctx{"path": path}))
+		return response.EmptySyncResponse
+	}
+
 	if type_ == "file" {
 		fileMode := os.O_RDWR
 
@@ -555,6 +620,43 @@ func instanceFilePost(s *state.State, inst instance.Instance, path string, r *ht
 	}
 }
 
+// instanceFileGetTar streams the directory at the given path of the instance as a tarball.
+func instanceFileGetTar(s *state.State, inst instance.Instance, path string, r *http.Request) response.Response {
+	if !shared.IsTrue(r.FormValue("recursive")) {
+		return response.BadRequest(fmt.Errorf("The tar format requires the recursive argument"))
+	}
+
+	// Get a SFTP client.
+	client, err := inst.FileSFTP()
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	// Check that the path is a directory before starting to stream.
+	stat, err := client.Lstat(path)
+	if err != nil {
+		_ = client.Close()
+		return response.SmartError(err)
+	}
+
+	if !stat.IsDir() {
+		_ = client.Close()
+		return response.BadRequest(fmt.Errorf("Path %q isn't a directory", path))
+	}
+
+	s.Events.SendLifecycle(inst.Project().Name, lifecycle.InstanceFileRetrieved.Event(inst, logger.Ctx{"path": path}))
+
+	return response.ManualResponse(func(w http.ResponseWriter) error {
+		defer func() { _ = client.Close() }()
+
+		w.Header().Set("Content-Type", "application/x-tar")
+		w.Header().Set("X-LXD-type", "tar")
+		w.WriteHeader(http.StatusOK)
+
+		return instanceFileTarPack(client, path, w, instanceFileTarMaxSize)
+	})
+}
+
 // swagger:operation DELETE /1.0/instances/{name}/files instances instance_files_delete
 //
 //	Delete a file
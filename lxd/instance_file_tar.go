@@ -0,0 +1,326 @@
+package main
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/sftp"
+
+	"github.com/canonical/lxd/shared/api"
+)
+
+// instanceFileTarMaxSize is the maximum total size of the regular files in a tarball pushed to or pulled from an
+// instance.
+const instanceFileTarMaxSize = 16 * 1024 * 1024 * 1024
+
+// instanceFileTarPath returns the path in the instance of the tarball entry with the given name, relative to root.
+// Absolute names and names escaping root are refused. The second value is the cleaned name relative to root.
+func instanceFileTarPath(root string, name string) (string, string, error) {
+	cleanName := filepath.Clean(name)
+	if cleanName != "." && !filepath.IsLocal(cleanName) {
+		return "", "", api.StatusErrorf(http.StatusBadRequest, "Tarball entry %q escapes the target path", name)
+	}
+
+	return filepath.Join(root, cleanName), cleanName, nil
+}
+
+// instanceFileTarUnpacker unpacks a tarball into a directory of an instance over SFTP.
+type instanceFileTarUnpacker struct {
+	client  *sftp.Client
+	root    string
+	maxSize int64
+
+	// dirs records the directories below root, relative to root, that are known not to be symlinks.
+	dirs map[string]bool
+	size int64
+}
+
+// instanceFileTarUnpack unpacks the tarball read from reader into the root directory of the instance, preserving the
+// ownership, mode and modification time of its entries. Only directories, regular files and symlinks are supported.
+// Entries can't escape root, neither by their name nor through a symlink, and the unpacking fails once the regular
+// files exceed maxSize bytes.
+func instanceFileTarUnpack(client *sftp.Client, root string, reader io.Reader, maxSize int64) error {
+	u := &instanceFileTarUnpacker{
+		client:  client,
+		root:    root,
+		maxSize: maxSize,
+		dirs:    map[string]bool{".": true},
+	}
+
+	tr := tar.NewReader(reader)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return api.StatusErrorf(http.StatusBadRequest, "Invalid tarball: %v", err)
+		}
+
+		err = u.unpackEntry(hdr, tr)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// unpackEntry unpacks a single tarball entry.
+func (u *instanceFileTarUnpacker) unpackEntry(hdr *tar.Header, tr *tar.Reader) error {
+	if hdr.Typeflag == tar.TypeXGlobalHeader {
+		return nil
+	}
+
+	path, name, err := instanceFileTarPath(u.root, hdr.Name)
+	if err != nil {
+		return err
+	}
+
+	err = u.checkParents(name)
+	if err != nil {
+		return err
+	}
+
+	mode := fs.FileMode(hdr.Mode).Perm()
+
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		if name != "." {
+			err = u.mkdir(name, path)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = u.setAttributes(path, hdr, mode)
+		if err != nil {
+			return err
+		}
+	case tar.TypeReg:
+		u.size += hdr.Size
+		if u.size > u.maxSize {
+			return api.StatusErrorf(http.StatusRequestEntityTooLarge, "Tarball exceeds the maximum size of %d bytes", u.maxSize)
+		}
+
+		err = u.removeNonDir(name, path)
+		if err != nil {
+			return err
+		}
+
+		file, err := u.client.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+		if err != nil {
+			return fmt.Errorf("Failed creating %q: %w", path, err)
+		}
+
+		_, err = io.Copy(file, tr)
+		if err != nil {
+			_ = file.Close()
+			return fmt.Errorf("Failed writing %q: %w", path, err)
+		}
+
+		err = file.Close()
+		if err != nil {
+			return fmt.Errorf("Failed writing %q: %w", path, err)
+		}
+
+		err = u.setAttributes(path, hdr, mode)
+		if err != nil {
+			return err
+		}
+	case tar.TypeSymlink:
+		err = u.removeNonDir(name, path)
+		if err != nil {
+			return err
+		}
+
+		err = u.client.Symlink(hdr.Linkname, path)
+		if err != nil {
+			return fmt.Errorf("Failed creating symlink %q: %w", path, err)
+		}
+	default:
+		return api.StatusErrorf(http.StatusBadRequest, "Tarball entry %q has an unsupported type", hdr.Name)
+	}
+
+	return nil
+}
+
+// checkParents checks that the parent directories of the entry with the given name are directories below root,
+// creating them if missing. This prevents entries from escaping root through a symlink, whether it comes from the
+// tarball or already existed in the instance.
+func (u *instanceFileTarUnpacker) checkParents(name string) error {
+	parent := filepath.Dir(name)
+	if u.dirs[parent] {
+		return nil
+	}
+
+	err := u.checkParents(parent)
+	if err != nil {
+		return err
+	}
+
+	return u.mkdir(parent, filepath.Join(u.root, parent))
+}
+
+// mkdir creates the directory with the given name and path unless it already exists. An existing file or symlink
+// is refused rather than replaced so that the ownership of existing content is never changed implicitly.
+func (u *instanceFileTarUnpacker) mkdir(name string, path string) error {
+	stat, err := u.client.Lstat(path)
+	if err == nil {
+		if !stat.IsDir() {
+			return api.StatusErrorf(http.StatusBadRequest, "Tarball entry %q conflicts with an existing non-directory", name)
+		}
+
+		u.dirs[name] = true
+		return nil
+	}
+
+	if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("Failed checking %q: %w", path, err)
+	}
+
+	err = u.client.Mkdir(path)
+	if err != nil {
+		return fmt.Errorf("Failed creating directory %q: %w", path, err)
+	}
+
+	u.dirs[name] = true
+	return nil
+}
+
+// removeNonDir removes the file or symlink with the given name and path, if any, so that it can be replaced without
+// following an existing symlink. Existing directories are refused.
+func (u *instanceFileTarUnpacker) removeNonDir(name string, path string) error {
+	if name == "." {
+		return api.StatusErrorf(http.StatusBadRequest, "Tarball entry %q must be a directory", name)
+	}
+
+	stat, err := u.client.Lstat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return fmt.Errorf("Failed checking %q: %w", path, err)
+	}
+
+	if stat.IsDir() {
+		return api.StatusErrorf(http.StatusBadRequest, "Tarball entry %q conflicts with an existing directory", name)
+	}
+
+	err = u.client.Remove(path)
+	if err != nil {
+		return fmt.Errorf("Failed replacing %q: %w", path, err)
+	}
+
+	return nil
+}
+
+// setAttributes sets the mode, ownership and modification time of a directory or regular file from its header.
+func (u *instanceFileTarUnpacker) setAttributes(path string, hdr *tar.Header, mode fs.FileMode) error {
+	err := u.client.Chmod(path, mode)
+	if err != nil {
+		return fmt.Errorf("Failed setting mode of %q: %w", path, err)
+	}
+
+	err = u.client.Chown(path, hdr.Uid, hdr.Gid)
+	if err != nil {
+		return fmt.Errorf("Failed setting ownership of %q: %w", path, err)
+	}
+
+	err = u.client.Chtimes(path, hdr.ModTime, hdr.ModTime)
+	if err != nil {
+		return fmt.Errorf("Failed setting modification time of %q: %w", path, err)
+	}
+
+	return nil
+}
+
+// instanceFileTarPack writes a tarball of the root directory of the instance to writer, preserving the ownership,
+// mode and modification time of its entries. Entries are named relative to root, symlinks are not followed and
+// other special files are skipped. Writing fails once the regular files exceed maxSize bytes.
+func instanceFileTarPack(client *sftp.Client, root string, writer io.Writer, maxSize int64) error {
+	tw := tar.NewWriter(writer)
+	size := int64(0)
+
+	walker := client.Walk(root)
+	for walker.Step() {
+		err := walker.Err()
+		if err != nil {
+			return err
+		}
+
+		path := walker.Path()
+		stat := walker.Stat()
+
+		name, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		if name == "." && !stat.IsDir() {
+			return api.StatusErrorf(http.StatusBadRequest, "Path %q isn't a directory", root)
+		}
+
+		linkTarget := ""
+		if stat.Mode()&fs.ModeSymlink != 0 {
+			linkTarget, err = client.ReadLink(path)
+			if err != nil {
+				return fmt.Errorf("Failed reading symlink %q: %w", path, err)
+			}
+		} else if !stat.IsDir() && !stat.Mode().IsRegular() {
+			continue
+		}
+
+		hdr, err := tar.FileInfoHeader(stat, linkTarget)
+		if err != nil {
+			return err
+		}
+
+		hdr.Name = name
+		if stat.IsDir() {
+			hdr.Name = strings.TrimSuffix(name, "/") + "/"
+		}
+
+		fileStat, ok := stat.Sys().(*sftp.FileStat)
+		if ok {
+			hdr.Uid = int(fileStat.UID)
+			hdr.Gid = int(fileStat.GID)
+		}
+
+		if hdr.Typeflag == tar.TypeReg {
+			size += hdr.Size
+			if size > maxSize {
+				return fmt.Errorf("Directory %q exceeds the maximum tarball size of %d bytes", root, maxSize)
+			}
+		}
+
+		err = tw.WriteHeader(hdr)
+		if err != nil {
+			return err
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		file, err := client.Open(path)
+		if err != nil {
+			return fmt.Errorf("Failed opening %q: %w", path, err)
+		}
+
+		_, err = io.CopyN(tw, file, hdr.Size)
+		_ = file.Close()
+		if err != nil {
+			return fmt.Errorf("Failed reading %q: %w", path, err)
+		}
+	}
+
+	return tw.Close()
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// instanceFileTarTestClient returns an SFTP client connected to a server serving the local filesystem.
+func instanceFileTarTestClient(t *testing.T) *sftp.Client {
+	serverConn, clientConn := net.Pipe()
+
+	server, err := sftp.NewServer(serverConn)
+	require.NoError(t, err)
+
+	go func() { _ = server.Serve() }()
+
+	client, err := sftp.NewClientPipe(clientConn, clientConn)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+
+	return client
+}
+
+// instanceFileTarTestEntry is an entry of a tarball built by instanceFileTarTestBuild.
+type instanceFileTarTestEntry struct {
+	name     string
+	typeflag byte
+	content  string
+	linkname string
+	mode     int64
+}
+
+// instanceFileTarTestBuild returns a tarball with the given entries.
+func instanceFileTarTestBuild(t *testing.T, entries []instanceFileTarTestEntry) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+
+	for _, entry := range entries {
+		mode := entry.mode
+		if mode == 0 {
+			mode = 0644
+		}
+
+		hdr := &tar.Header{
+			Name:     entry.name,
+			Typeflag: entry.typeflag,
+			Linkname: entry.linkname,
+			Mode:     mode,
+			Size:     int64(len(entry.content)),
+			Uid:      os.Getuid(),
+			Gid:      os.Getgid(),
+			ModTime:  time.Unix(1700000000, 0),
+		}
+
+		require.NoError(t, tw.WriteHeader(hdr))
+
+		_, err := io.WriteString(tw, entry.content)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+
+	return buf
+}
+
+func TestInstanceFileTarPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		wantPath string
+		wantErr  bool
+	}{
+		{name: "file", wantPath: "/root/file"},
+		{name: "./dir/file", wantPath: "/root/dir/file"},
+		{name: "dir/../file", wantPath: "/root/file"},
+		{name: "./", wantPath: "/root"},
+		{name: "/etc/passwd", wantErr: true},
+		{name: "../file", wantErr: true},
+		{name: "dir/../../file", wantErr: true},
+		{name: "..", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path, _, err := instanceFileTarPath("/root", test.name)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.wantPath, path)
+		})
+	}
+}
+
+func TestInstanceFileTarUnpack(t *testing.T) {
+	client := instanceFileTarTestClient(t)
+	root := t.TempDir()
+
+	tarball := instanceFileTarTestBuild(t, []instanceFileTarTestEntry{
+		{name: "./", typeflag: tar.TypeDir, mode: 0750},
+		{name: "dir/", typeflag: tar.TypeDir, mode: 0700},
+		{name: "dir/file", typeflag: tar.TypeReg, content: "hello", mode: 0600},
+		{name: "missing-parent/file", typeflag: tar.TypeReg, content: "world"},
+		{name: "link", typeflag: tar.TypeSymlink, linkname: "dir/file"},
+	})
+
+	err := instanceFileTarUnpack(client, root, tarball, instanceFileTarMaxSize)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(root, "dir", "file"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(content))
+
+	stat, err := os.Stat(filepath.Join(root, "dir", "file"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+	assert.Equal(t, time.Unix(1700000000, 0), stat.ModTime())
+
+	stat, err = os.Stat(filepath.Join(root, "dir"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), stat.Mode().Perm())
+
+	stat, err = os.Stat(root)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0750), stat.Mode().Perm())
+
+	content, err = os.ReadFile(filepath.Join(root, "missing-parent", "file"))
+	require.NoError(t, err)
+	assert.Equal(t, "world", string(content))
+
+	target, err := os.Readlink(filepath.Join(root, "link"))
+	require.NoError(t, err)
+	assert.Equal(t, "dir/file", target)
+}
+
+func TestInstanceFileTarUnpack_Escape(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []instanceFileTarTestEntry
+	}{
+		{
+			name:    "Parent directory",
+			entries: []instanceFileTarTestEntry{{name: "../file", typeflag: tar.TypeReg, content: "escaped"}},
+		},
+		{
+			name:    "Absolute path",
+			entries: []instanceFileTarTestEntry{{name: "/file", typeflag: tar.TypeReg, content: "escaped"}},
+		},
+		{
+			name: "Symlink in tarball",
+			entries: []instanceFileTarTestEntry{
+				{name: "link", typeflag: tar.TypeSymlink, linkname: ".."},
+				{name: "link/file", typeflag: tar.TypeReg, content: "escaped"},
+			},
+		},
+		{
+			name: "Symlink replaced by file",
+			entries: []instanceFileTarTestEntry{
+				{name: "link", typeflag: tar.TypeSymlink, linkname: "../file"},
+				{name: "link", typeflag: tar.TypeReg, content: "replaced"},
+				{name: "link/file", typeflag: tar.TypeReg, content: "escaped"},
+			},
+		},
+		{
+			name:    "Hard link",
+			entries: []instanceFileTarTestEntry{{name: "file", typeflag: tar.TypeLink, linkname: "../file"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := instanceFileTarTestClient(t)
+			parent := t.TempDir()
+			root := filepath.Join(parent, "root")
+			require.NoError(t, os.Mkdir(root, 0755))
+
+			err := instanceFileTarUnpack(client, root, instanceFileTarTestBuild(t, test.entries), instanceFileTarMaxSize)
+			assert.Error(t, err)
+
+			_, err = os.Lstat(filepath.Join(parent, "file"))
+			assert.ErrorIs(t, err, os.ErrNotExist)
+		})
+	}
+}
+
+func TestInstanceFileTarUnpack_ExistingSymlink(t *testing.T) {
+	client := instanceFileTarTestClient(t)
+	parent := t.TempDir()
+	root := filepath.Join(parent, "root")
+	require.NoError(t, os.Mkdir(root, 0755))
+	require.NoError(t, os.Symlink(parent, filepath.Join(root, "link")))
+	require.NoError(t, os.WriteFile(filepath.Join(parent, "target"), []byte("original"), 0644))
+	require.NoError(t, os.Symlink(filepath.Join(parent, "target"), filepath.Join(root, "file")))
+
+	// Entries below an existing symlink are refused.
+	tarball := instanceFileTarTestBuild(t, []instanceFileTarTestEntry{{name: "link/file", typeflag: tar.TypeReg, content: "escaped"}})
+	err := instanceFileTarUnpack(client, root, tarball, instanceFileTarMaxSize)
+	assert.Error(t, err)
+
+	_, err = os.Lstat(filepath.Join(parent, "file"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// An existing symlink is replaced rather than followed.
+	tarball = instanceFileTarTestBuild(t, []instanceFileTarTestEntry{{name: "file", typeflag: tar.TypeReg, content: "replaced"}})
+	err = instanceFileTarUnpack(client, root, tarball, instanceFileTarMaxSize)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(parent, "target"))
+	require.NoError(t, err)
+	assert.Equal(t, "original", string(content))
+
+	content, err = os.ReadFile(filepath.Join(root, "file"))
+	require.NoError(t, err)
+	assert.Equal(t, "replaced", string(content))
+}
+
+func TestInstanceFileTarUnpack_MaxSize(t *testing.T) {
+	client := instanceFileTarTestClient(t)
+	root := t.TempDir()
+
+	tarball := instanceFileTarTestBuild(t, []instanceFileTarTestEntry{
+		{name: "file1", typeflag: tar.TypeReg, content: "12345"},
+		{name: "file2", typeflag: tar.TypeReg, content: "67890"},
+	})
+
+	err := instanceFileTarUnpack(client, root, tarball, 8)
+	assert.Error(t, err)
+
+	_, err = os.Lstat(filepath.Join(root, "file1"))
+	assert.NoError(t, err)
+
+	_, err = os.Lstat(filepath.Join(root, "file2"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestInstanceFileTarPack(t *testing.T) {
+	client := instanceFileTarTestClient(t)
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, "dir"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "dir", "file"), []byte("hello"), 0600))
+	require.NoError(t, os.Symlink("/etc/passwd", filepath.Join(root, "link")))
+
+	buf := &bytes.Buffer{}
+	err := instanceFileTarPack(client, root, buf, instanceFileTarMaxSize)
+	require.NoError(t, err)
+
+	entries := map[string]*tar.Header{}
+	tr := tar.NewReader(buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		require.NoError(t, err)
+		entries[hdr.Name] = hdr
+
+		if hdr.Name == "dir/file" {
+			content, err := io.ReadAll(tr)
+			require.NoError(t, err)
+			assert.Equal(t, "hello", string(content))
+		}
+	}
+
+	require.Contains(t, entries, "./")
+	require.Contains(t, entries, "dir/")
+	assert.Equal(t, int64(0700), entries["dir/"].Mode&0777)
+	require.Contains(t, entries, "dir/file")
+	assert.Equal(t, int64(0600), entries["dir/file"].Mode&0777)
+	assert.Equal(t, os.Getuid(), entries["dir/file"].Uid)
+	require.Contains(t, entries, "link")
+	assert.Equal(t, byte(tar.TypeSymlink), entries["link"].Typeflag)
+	assert.Equal(t, "/etc/passwd", entries["link"].Linkname)
+
+	// A tarball of a directory can be unpacked somewhere else.
+	buf.Reset()
+	require.NoError(t, instanceFileTarPack(client, root, buf, instanceFileTarMaxSize))
+
+	target := t.TempDir()
+	require.NoError(t, instanceFileTarUnpack(client, target, buf, instanceFileTarMaxSize))
+
+	content, err := os.ReadFile(filepath.Join(target, "dir", "file"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(content))
+
+	// Directories exceeding the maximum size are refused.
+	err = instanceFileTarPack(client, root, io.Discard, 4)
+	assert.Error(t, err)
+}
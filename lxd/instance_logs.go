@@ -1,7 +1,9 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"net/http"
 	"net/url"
 	"os"
@@ -69,6 +71,28 @@ var instanceExecOutputsCmd = APIEndpoint{
 	Get: APIEndpointAction{Handler: instanceExecOutputsGet, AccessHandler: allowPermission(entity.TypeInstance, auth.EntitlementCanExec, "name")},
 }
 
+var instanceExecRecordingCmd = APIEndpoint{
+	Name: "instanceExecRecording",
+	Path: "instances/{name}/logs/exec/{file}",
+	Aliases: []APIEndpointAlias{
+		{Name: "containerExecRecording", Path: "containers/{name}/logs/exec/{file}"},
+		{Name: "vmExecRecording", Path: "virtual-machines/{name}/logs/exec/{file}"},
+	},
+
+	Get: APIEndpointAction{Handler: instanceExecRecordingGet, AccessHandler: allowPermission(entity.TypeInstance, auth.EntitlementCanExec, "name")},
+}
+
+var instanceExecRecordingsCmd = APIEndpoint{
+	Name: "instanceExecRecordings",
+	Path: "instances/{name}/logs/exec",
+	Aliases: []APIEndpointAlias{
+		{Name: "containerExecRecordings", Path: "containers/{name}/logs/exec"},
+		{Name: "vmExecRecordings", Path: "virtual-machines/{name}/logs/exec"},
+	},
+
+	Get: APIEndpointAction{Handler: instanceExecRecordingsGet, AccessHandler: allowPermission(entity.TypeInstance, auth.EntitlementCanExec, "name")},
+}
+
 // swagger:operation GET /1.0/instances/{name}/logs instances instance_logs_get
 //
 //	Get the log files
@@ -681,6 +705,193 @@ func instanceExecOutputDelete(d *Daemon, r *http.Request) response.Response {
 	return response.EmptySyncResponse
 }
 
+// swagger:operation GET /1.0/instances/{name}/logs/exec instances instance_exec-recordings_get
+//
+//	Get the exec session recordings
+//
+//	Returns a list of exec session recordings (URLs).
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	responses:
+//	  "200":
+//	    description: API endpoints
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of endpoints
+//	          items:
+//	            type: string
+//	          example: |-
+//	            [
+//	              "/1.0/instances/foo/logs/exec/exec_20240304T120000Z_d0a89537-0617-4ed6-a79b-c2e88a970965.cast"
+//	            ]
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func instanceExecRecordingsGet(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	instanceType, err := urlInstanceTypeDetect(r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	projectName := request.ProjectParam(r)
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if shared.IsSnapshot(name) {
+		return response.BadRequest(fmt.Errorf("Invalid instance name"))
+	}
+
+	// Ensure instance exists.
+	_, err = instance.LoadByProjectAndName(s, projectName, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Handle requests targeted to a container on a different node
+	resp, err := forwardedResponseIfInstanceIsRemote(s, r, projectName, name, instanceType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if resp != nil {
+		return resp
+	}
+
+	result := []string{}
+
+	// No recordings directory means no session was recorded yet.
+	dents, err := os.ReadDir(execRecordingsPath(projectName, name))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return response.SmartError(err)
+	}
+
+	for _, f := range dents {
+		if !validExecRecordingFileName(f.Name()) {
+			continue
+		}
+
+		result = append(result, fmt.Sprintf("/%s/instances/%s/logs/exec/%s", version.APIVersion, name, f.Name()))
+	}
+
+	return response.SyncResponse(true, result)
+}
+
+// swagger:operation GET /1.0/instances/{name}/logs/exec/{filename} instances instance_exec-recording_get
+//
+//	Get the exec session recording
+//
+//	Gets the exec session recording in the asciicast v2 format.
+//
+//	---
+//	produces:
+//	  - application/json
+//	  - application/octet-stream
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	responses:
+//	  "200":
+//	     description: Raw file
+//	     content:
+//	       application/octet-stream:
+//	         schema:
+//	           type: string
+//	           example: some-text
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func instanceExecRecordingGet(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	instanceType, err := urlInstanceTypeDetect(r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	projectName := request.ProjectParam(r)
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if shared.IsSnapshot(name) {
+		return response.BadRequest(fmt.Errorf("Invalid instance name"))
+	}
+
+	// Ensure instance exists.
+	inst, err := instance.LoadByProjectAndName(s, projectName, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Handle requests targeted to a container on a different node
+	resp, err := forwardedResponseIfInstanceIsRemote(s, r, projectName, name, instanceType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if resp != nil {
+		return resp
+	}
+
+	file, err := url.PathUnescape(mux.Vars(r)["file"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !validExecRecordingFileName(file) {
+		return response.BadRequest(fmt.Errorf("Exec recording file name %q not valid", file))
+	}
+
+	ent := response.FileResponseEntry{
+		Path:     filepath.Join(execRecordingsPath(projectName, name), file),
+		Filename: file,
+	}
+
+	s.Events.SendLifecycle(projectName, lifecycle.InstanceLogRetrieved.Event(file, inst, request.CreateRequestor(r), nil))
+
+	return response.FileResponse(r, []response.FileResponseEntry{ent}, nil)
+}
+
 func validLogFileName(fname string) bool {
 	/* Let's just require that the paths be relative, so that we don't have
 	 * to deal with any escaping or whatever.
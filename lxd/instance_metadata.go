@@ -143,6 +143,43 @@ func instanceMetadataGet(d *Daemon, r *http.Request) response.Response {
 	return response.SyncResponseETag(true, metadata, metadata)
 }
 
+// instanceMetadataProperties returns the image properties from the metadata.yaml of the instance.
+func instanceMetadataProperties(s *state.State, inst instance.Instance) (map[string]string, error) {
+	pool, err := storagePools.LoadByInstance(s, inst)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = storagePools.InstanceMount(pool, inst, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = storagePools.InstanceUnmount(pool, inst, nil) }()
+
+	metadataPath := filepath.Join(inst.Path(), "metadata.yaml")
+	if !shared.PathExists(metadataPath) {
+		return map[string]string{}, nil
+	}
+
+	data, err := os.ReadFile(metadataPath)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := api.ImageMetadata{}
+	err = yaml.Unmarshal(data, &metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if metadata.Properties == nil {
+		return map[string]string{}, nil
+	}
+
+	return metadata.Properties, nil
+}
+
 // swagger:operation PATCH /1.0/instances/{name}/metadata instances instance_metadata_patch
 //
 //	Partially update the image metadata
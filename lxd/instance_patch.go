@@ -171,6 +171,11 @@ func instancePatch(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
+	err = instanceImmutableKeysCheck(s, r, c, req.Config)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	// Check if devices was passed
 	if req.Devices == nil {
 		req.Devices = c.LocalDevices().CloneNative()
@@ -0,0 +1,1409 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/canonical/lxd/lxd/auth"
+	"github.com/canonical/lxd/lxd/cluster"
+	"github.com/canonical/lxd/lxd/db"
+	dbCluster "github.com/canonical/lxd/lxd/db/cluster"
+	"github.com/canonical/lxd/lxd/db/operationtype"
+	"github.com/canonical/lxd/lxd/db/warningtype"
+	deviceConfig "github.com/canonical/lxd/lxd/device/config"
+	"github.com/canonical/lxd/lxd/instance"
+	"github.com/canonical/lxd/lxd/instance/instancetype"
+	"github.com/canonical/lxd/lxd/lifecycle"
+	"github.com/canonical/lxd/lxd/metrics"
+	"github.com/canonical/lxd/lxd/operations"
+	"github.com/canonical/lxd/lxd/project"
+	"github.com/canonical/lxd/lxd/request"
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/lxd/state"
+	"github.com/canonical/lxd/lxd/task"
+	"github.com/canonical/lxd/lxd/util"
+	"github.com/canonical/lxd/lxd/warnings"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/osarch"
+	"github.com/canonical/lxd/shared/revert"
+	"github.com/canonical/lxd/shared/version"
+)
+
+var instancePoolsCmd = APIEndpoint{
+	Path: "instance-pools",
+
+	Get:  APIEndpointAction{Handler: instancePoolsGet, AccessHandler: allowPermission(entity.TypeProject, auth.EntitlementCanView)},
+	Post: APIEndpointAction{Handler: instancePoolsPost, AccessHandler: allowPermission(entity.TypeProject, auth.EntitlementCanEdit)},
+}
+
+var instancePoolCmd = APIEndpoint{
+	Path: "instance-pools/{name}",
+
+	Get:    APIEndpointAction{Handler: instancePoolGet, AccessHandler: allowPermission(entity.TypeProject, auth.EntitlementCanView)},
+	Put:    APIEndpointAction{Handler: instancePoolPut, AccessHandler: allowPermission(entity.TypeProject, auth.EntitlementCanEdit)},
+	Patch:  APIEndpointAction{Handler: instancePoolPut, AccessHandler: allowPermission(entity.TypeProject, auth.EntitlementCanEdit)},
+	Delete: APIEndpointAction{Handler: instancePoolDelete, AccessHandler: allowPermission(entity.TypeProject, auth.EntitlementCanEdit)},
+}
+
+var instancePoolAcquireCmd = APIEndpoint{
+	Path: "instance-pools/{name}/acquire",
+
+	Post: APIEndpointAction{Handler: instancePoolAcquirePost, AccessHandler: allowPermission(entity.TypeProject, auth.EntitlementCanCreateInstances)},
+}
+
+// instancePoolAcquireStats records the instances acquired from an instance pool on this member, and the total time
+// spent acquiring them.
+type instancePoolAcquireStats struct {
+	count   int
+	seconds float64
+}
+
+var instancePoolAcquireStatsMu sync.Mutex
+
+// instancePoolAcquireNameMu serializes picking the names of the instances acquired on this member and renaming them,
+// so that concurrent acquisitions don't pick the same name.
+var instancePoolAcquireNameMu sync.Mutex
+
+// instancePoolAcquireStatsByPool holds the acquisition stats of the instance pools, keyed by project and pool name.
+var instancePoolAcquireStatsByPool = map[string]map[string]*instancePoolAcquireStats{}
+
+// instancePoolAcquireObserve records an instance acquired from the given pool in the given time.
+func instancePoolAcquireObserve(projectName string, poolName string, duration time.Duration) {
+	instancePoolAcquireStatsMu.Lock()
+	defer instancePoolAcquireStatsMu.Unlock()
+
+	if instancePoolAcquireStatsByPool[projectName] == nil {
+		instancePoolAcquireStatsByPool[projectName] = map[string]*instancePoolAcquireStats{}
+	}
+
+	stats := instancePoolAcquireStatsByPool[projectName][poolName]
+	if stats == nil {
+		stats = &instancePoolAcquireStats{}
+		instancePoolAcquireStatsByPool[projectName][poolName] = stats
+	}
+
+	stats.count++
+	stats.seconds += duration.Seconds()
+}
+
+// instancePoolMetrics returns the size of the instance pools of the given projects, the number of instances
+// available in them on the given member and the acquisitions of instances from them on this member.
+func instancePoolMetrics(ctx context.Context, tx *db.ClusterTx, memberName string, projectNames []string) (*metrics.MetricSet, error) {
+	out := metrics.NewMetricSet(nil)
+
+	instancePoolAcquireStatsMu.Lock()
+	defer instancePoolAcquireStatsMu.Unlock()
+
+	for _, projectName := range projectNames {
+		pools, err := dbCluster.GetInstancePools(ctx, tx.Tx(), projectName)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pool := range pools {
+			instances, err := dbCluster.GetInstancePoolInstances(ctx, tx.Tx(), pool.ID)
+			if err != nil {
+				return nil, err
+			}
+
+			available := 0
+			for _, inst := range instances {
+				if inst.Node == memberName {
+					available++
+				}
+			}
+
+			labels := map[string]string{"project": projectName, "pool": pool.Name}
+			out.AddSamples(metrics.InstancePoolSize, metrics.Sample{Labels: labels, Value: float64(pool.Size)})
+			out.AddSamples(metrics.InstancePoolInstances, metrics.Sample{Labels: labels, Value: float64(available)})
+
+			stats := instancePoolAcquireStatsByPool[projectName][pool.Name]
+			if stats == nil {
+				stats = &instancePoolAcquireStats{}
+			}
+
+			out.AddSamples(metrics.InstancePoolAcquiresTotal, metrics.Sample{Labels: labels, Value: float64(stats.count)})
+			out.AddSamples(metrics.InstancePoolAcquireSeconds, metrics.Sample{Labels: labels, Value: stats.seconds})
+		}
+	}
+
+	return out, nil
+}
+
+// instancePoolURL returns the URL of the instance pool with the given name in the given project.
+func instancePoolURL(projectName string, name string) *api.URL {
+	return api.NewURL().Path(version.APIVersion, "instance-pools", name).Project(projectName)
+}
+
+// validateInstancePool checks the name and the fields of an instance pool, and sets the defaults of unset fields.
+func validateInstancePool(name string, pool *api.InstancePoolPut) error {
+	if name == "" {
+		return api.StatusErrorf(http.StatusBadRequest, "Instance pool name cannot be empty")
+	}
+
+	if strings.Contains(name, "/") {
+		return api.StatusErrorf(http.StatusBadRequest, "Instance pool name cannot contain a forward slash")
+	}
+
+	if pool.Size < 0 {
+		return api.StatusErrorf(http.StatusBadRequest, "The size of instance pool %q cannot be negative", name)
+	}
+
+	if pool.NamePattern == "" {
+		pool.NamePattern = name + "-%d"
+	}
+
+	if strings.Count(strings.ReplaceAll(pool.NamePattern, "%d", ""), "%") > 0 || strings.Count(pool.NamePattern, "%d") > 1 {
+		return api.StatusErrorf(http.StatusBadRequest, "The name pattern of instance pool %q can only contain %%d once", name)
+	}
+
+	err := instance.ValidName(strings.Replace(pool.NamePattern, "%d", "0", 1), false)
+	if err != nil {
+		return api.StatusErrorf(http.StatusBadRequest, "Invalid name pattern of instance pool %q: %v", name, err)
+	}
+
+	if pool.Template.Type == "" {
+		pool.Template.Type = api.InstanceTypeContainer
+	}
+
+	_, err = instancetype.New(string(pool.Template.Type))
+	if err != nil {
+		return api.StatusErrorf(http.StatusBadRequest, "Invalid instance type of instance pool %q: %v", name, err)
+	}
+
+	if pool.Template.Source.Type == "" {
+		pool.Template.Source.Type = "image"
+	}
+
+	if pool.Template.Source.Type != "image" || pool.Template.Source.Server != "" {
+		return api.StatusErrorf(http.StatusBadRequest, "Instance pools only support images from the image store of the project")
+	}
+
+	return nil
+}
+
+// instancePoolToAPI converts the given pool to an api.InstancePool, with the URLs of the given available instances
+// as its UsedBy field.
+func instancePoolToAPI(pool dbCluster.InstancePool, instances []dbCluster.InstancePoolInstance) api.InstancePool {
+	apiPool := pool.ToAPI()
+	apiPool.UsedBy = make([]string, 0, len(instances))
+	for _, inst := range instances {
+		apiPool.UsedBy = append(apiPool.UsedBy, entity.InstanceURL(pool.Project, inst.Name).String())
+	}
+
+	return apiPool
+}
+
+// instancePoolAcquireName returns the name of an instance acquired from a pool with the given name pattern. The %d
+// in the pattern is replaced by the lowest number that doesn't give the name of one of the existing instances.
+func instancePoolAcquireName(pattern string, existingNames []string) (string, error) {
+	if !strings.Contains(pattern, "%d") {
+		if shared.ValueInSlice(pattern, existingNames) {
+			return "", api.StatusErrorf(http.StatusConflict, "Instance %q already exists", pattern)
+		}
+
+		return pattern, nil
+	}
+
+	existing := make(map[string]bool, len(existingNames))
+	for _, name := range existingNames {
+		existing[name] = true
+	}
+
+	for i := 0; ; i++ {
+		name := strings.Replace(pattern, "%d", strconv.Itoa(i), 1)
+		if !existing[name] {
+			return name, nil
+		}
+	}
+}
+
+// swagger:operation GET /1.0/instance-pools instance-pools instance_pools_get
+//
+//	Get the instance pools
+//
+//	Returns a list of instance pools (URLs).
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	responses:
+//	  "200":
+//	    description: API endpoints
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of endpoints
+//	          items:
+//	            type: string
+//	          example: |-
+//	            [
+//	              "/1.0/instance-pools/ci",
+//	              "/1.0/instance-pools/builders"
+//	            ]
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+
+// swagger:operation GET /1.0/instance-pools?recursion=1 instance-pools instance_pools_get_recursion1
+//
+//	Get the instance pools
+//
+//	Returns a list of instance pools (structs).
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	responses:
+//	  "200":
+//	    description: API endpoints
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of instance pools
+//	          items:
+//	            $ref: "#/definitions/InstancePool"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func instancePoolsGet(d *Daemon, r *http.Request) response.Response {
+	recursion := util.IsRecursionRequest(r)
+	projectName := request.ProjectParam(r)
+
+	s := d.State()
+	var apiPools []api.InstancePool
+	err := s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		pools, err := dbCluster.GetInstancePools(ctx, tx.Tx(), projectName)
+		if err != nil {
+			return err
+		}
+
+		apiPools = make([]api.InstancePool, 0, len(pools))
+		for _, pool := range pools {
+			var instances []dbCluster.InstancePoolInstance
+			if recursion {
+				instances, err = dbCluster.GetInstancePoolInstances(ctx, tx.Tx(), pool.ID)
+				if err != nil {
+					return err
+				}
+			}
+
+			apiPools = append(apiPools, instancePoolToAPI(pool, instances))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !recursion {
+		urls := make([]string, 0, len(apiPools))
+		for _, pool := range apiPools {
+			urls = append(urls, instancePoolURL(pool.Project, pool.Name).String())
+		}
+
+		return response.SyncResponse(true, urls)
+	}
+
+	for i := range apiPools {
+		apiPools[i].UsedBy = project.FilterUsedBy(s.Authorizer, r, apiPools[i].UsedBy)
+	}
+
+	return response.SyncResponse(true, apiPools)
+}
+
+// swagger:operation POST /1.0/instance-pools instance-pools instance_pools_post
+//
+//	Add an instance pool
+//
+//	Creates a new instance pool. The instances of the pool are created in the background.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	  - in: body
+//	    name: pool
+//	    description: Instance pool
+//	    required: true
+//	    schema:
+//	      $ref: "#/definitions/InstancePoolsPost"
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func instancePoolsPost(d *Daemon, r *http.Request) response.Response {
+	projectName := request.ProjectParam(r)
+
+	req := api.InstancePoolsPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(fmt.Errorf("Invalid request body: %w", err))
+	}
+
+	err = validateInstancePool(req.Name, &req.InstancePoolPut)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	s := d.State()
+	err = s.DB.Cluster.TransactionRetry(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		var imageRef string
+		_, err := getSourceImageFromInstanceSource(ctx, s, tx, projectName, req.Template.Source, &imageRef, string(req.Template.Type))
+		if err != nil {
+			return api.StatusErrorf(http.StatusBadRequest, "Failed loading image of instance pool %q: %v", req.Name, err)
+		}
+
+		pool := dbCluster.InstancePool{
+			Name:        req.Name,
+			Project:     projectName,
+			Description: req.Description,
+			Size:        req.Size,
+			NamePattern: req.NamePattern,
+			Template:    req.Template,
+		}
+
+		_, err = dbCluster.CreateInstancePool(ctx, tx.Tx(), pool)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	d.instancePoolsReplenishSoon()
+
+	lc := lifecycle.InstancePoolCreated.Event(projectName, req.Name, request.CreateRequestor(r), nil)
+	s.Events.SendLifecycle(projectName, lc)
+
+	return response.SyncResponseLocation(true, nil, instancePoolURL(projectName, req.Name).String())
+}
+
+// swagger:operation GET /1.0/instance-pools/{name} instance-pools instance_pool_get
+//
+//	Get the instance pool
+//
+//	Gets a specific instance pool.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	responses:
+//	  "200":
+//	    description: Instance pool
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          $ref: "#/definitions/InstancePool"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func instancePoolGet(d *Daemon, r *http.Request) response.Response {
+	projectName := request.ProjectParam(r)
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	s := d.State()
+	var apiPool api.InstancePool
+	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		pool, err := dbCluster.GetInstancePool(ctx, tx.Tx(), projectName, name)
+		if err != nil {
+			return err
+		}
+
+		instances, err := dbCluster.GetInstancePoolInstances(ctx, tx.Tx(), pool.ID)
+		if err != nil {
+			return err
+		}
+
+		apiPool = instancePoolToAPI(*pool, instances)
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	apiPool.UsedBy = project.FilterUsedBy(s.Authorizer, r, apiPool.UsedBy)
+
+	return response.SyncResponseETag(true, apiPool, apiPool.Writable())
+}
+
+// swagger:operation PUT /1.0/instance-pools/{name} instance-pools instance_pool_put
+//
+//	Update the instance pool
+//
+//	Updates the instance pool. A new template only applies to the instances created afterwards. If the size is
+//	reduced, the surplus instances are deleted in the background.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	  - in: body
+//	    name: pool
+//	    description: Instance pool
+//	    required: true
+//	    schema:
+//	      $ref: "#/definitions/InstancePoolPut"
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+
+// swagger:operation PATCH /1.0/instance-pools/{name} instance-pools instance_pool_patch
+//
+//	Partially update the instance pool
+//
+//	Updates a subset of the fields of the instance pool. A new template only applies to the instances created
+//	afterwards. If the size is reduced, the surplus instances are deleted in the background.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	  - in: body
+//	    name: pool
+//	    description: Instance pool
+//	    required: true
+//	    schema:
+//	      $ref: "#/definitions/InstancePoolPut"
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func instancePoolPut(d *Daemon, r *http.Request) response.Response {
+	projectName := request.ProjectParam(r)
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	s := d.State()
+	err = s.DB.Cluster.TransactionRetry(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		pool, err := dbCluster.GetInstancePool(ctx, tx.Tx(), projectName, name)
+		if err != nil {
+			return err
+		}
+
+		current := pool.ToAPI()
+		err = util.EtagCheck(r, current.Writable())
+		if err != nil {
+			return err
+		}
+
+		req := current.Writable()
+		if r.Method == http.MethodPut {
+			req = api.InstancePoolPut{}
+		}
+
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			return api.StatusErrorf(http.StatusBadRequest, "Invalid request body: %v", err)
+		}
+
+		err = validateInstancePool(name, &req)
+		if err != nil {
+			return err
+		}
+
+		var imageRef string
+		_, err = getSourceImageFromInstanceSource(ctx, s, tx, projectName, req.Template.Source, &imageRef, string(req.Template.Type))
+		if err != nil {
+			return api.StatusErrorf(http.StatusBadRequest, "Failed loading image of instance pool %q: %v", name, err)
+		}
+
+		pool.Description = req.Description
+		pool.Size = req.Size
+		pool.NamePattern = req.NamePattern
+		pool.Template = req.Template
+
+		return dbCluster.UpdateInstancePool(ctx, tx.Tx(), pool.ID, *pool)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	d.instancePoolsReplenishSoon()
+
+	lc := lifecycle.InstancePoolUpdated.Event(projectName, name, request.CreateRequestor(r), nil)
+	s.Events.SendLifecycle(projectName, lc)
+
+	return response.EmptySyncResponse
+}
+
+// swagger:operation DELETE /1.0/instance-pools/{name} instance-pools instance_pool_delete
+//
+//	Delete the instance pool
+//
+//	Deletes the instance pool and the instances that are available in it. Instances that were acquired from the
+//	pool are kept.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func instancePoolDelete(d *Daemon, r *http.Request) response.Response {
+	projectName := request.ProjectParam(r)
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	s := d.State()
+	var instances []dbCluster.InstancePoolInstance
+	memberAddresses := make(map[string]string)
+	err = s.DB.Cluster.TransactionRetry(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		pool, err := dbCluster.GetInstancePool(ctx, tx.Tx(), projectName, name)
+		if err != nil {
+			return err
+		}
+
+		instances, err = dbCluster.GetInstancePoolInstances(ctx, tx.Tx(), pool.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, inst := range instances {
+			if inst.Node == s.ServerName || memberAddresses[inst.Node] != "" {
+				continue
+			}
+
+			member, err := tx.GetNodeByName(ctx, inst.Node)
+			if err != nil {
+				return fmt.Errorf("Failed loading cluster member %q: %w", inst.Node, err)
+			}
+
+			memberAddresses[inst.Node] = member.Address
+		}
+
+		// The instances are no longer available once the pool is deleted, so they can't be acquired anymore.
+		return dbCluster.DeleteInstancePool(ctx, tx.Tx(), pool.ID)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	for _, inst := range instances {
+		err := instancePoolDeleteInstance(s, r, projectName, inst.Name, memberAddresses[inst.Node])
+		if err != nil {
+			logger.Warn("Failed deleting instance of deleted instance pool", logger.Ctx{"project": projectName, "pool": name, "instance": inst.Name, "err": err})
+		}
+	}
+
+	lc := lifecycle.InstancePoolDeleted.Event(projectName, name, request.CreateRequestor(r), nil)
+	s.Events.SendLifecycle(projectName, lc)
+
+	return response.EmptySyncResponse
+}
+
+// instancePoolDeleteInstance deletes an instance that was available in an instance pool. The instance is deleted
+// through the cluster member at the given address if there is one, and locally otherwise.
+func instancePoolDeleteInstance(s *state.State, r *http.Request, projectName string, name string, memberAddress string) error {
+	if memberAddress != "" {
+		client, err := cluster.Connect(memberAddress, s.Endpoints.NetworkCert(), s.ServerCert(), r, true)
+		if err != nil {
+			return err
+		}
+
+		op, err := client.UseProject(projectName).DeleteInstance(name)
+		if err != nil {
+			return err
+		}
+
+		return op.Wait()
+	}
+
+	unlock, err := instanceOperationLock(s.ShutdownCtx, projectName, name)
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	inst, err := instance.LoadByProjectAndName(s, projectName, name)
+	if err != nil {
+		return err
+	}
+
+	return inst.Delete(true)
+}
+
+// swagger:operation POST /1.0/instance-pools/{name}/acquire instance-pools instance_pool_acquire_post
+//
+//	Acquire an instance from the instance pool
+//
+//	Claims one of the stopped instances that are available in the pool, renames it after the name pattern of the
+//	pool (or to the requested name), applies the requested configuration and starts it.
+//	The acquired instance no longer belongs to the pool, and the pool creates a new instance in the background.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	  - in: query
+//	    name: target
+//	    description: Cluster member name
+//	    type: string
+//	    example: lxd01
+//	  - in: body
+//	    name: acquire
+//	    description: Acquisition request
+//	    required: true
+//	    schema:
+//	      $ref: "#/definitions/InstancePoolAcquirePost"
+//	responses:
+//	  "200":
+//	    description: Acquired instance
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          $ref: "#/definitions/Instance"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "409":
+//	    $ref: "#/responses/Conflict"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+//	  "503":
+//	    description: The pool has no available instances
+func instancePoolAcquirePost(d *Daemon, r *http.Request) response.Response {
+	start := time.Now()
+
+	s := d.State()
+	projectName := request.ProjectParam(r)
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	resp := forwardedResponseIfTargetIsRemote(s, r)
+	if resp != nil {
+		return resp
+	}
+
+	// Keep the body around in case the request is forwarded to the member that has an available instance.
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	req := api.InstancePoolAcquirePost{}
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		return response.BadRequest(fmt.Errorf("Invalid request body: %w", err))
+	}
+
+	if req.Name != "" {
+		err = instance.ValidName(req.Name, false)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+	}
+
+	instancePoolAcquireNameMu.Lock()
+	unlockName := sync.OnceFunc(instancePoolAcquireNameMu.Unlock)
+	defer unlockName()
+
+	var claimed *dbCluster.InstancePoolInstance
+	var newName string
+	var remoteMember string
+	err = s.DB.Cluster.TransactionRetry(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		claimed = nil
+		remoteMember = ""
+
+		pool, err := dbCluster.GetInstancePool(ctx, tx.Tx(), projectName, name)
+		if err != nil {
+			return err
+		}
+
+		instances, err := dbCluster.GetInstancePoolInstances(ctx, tx.Tx(), pool.ID)
+		if err != nil {
+			return err
+		}
+
+		// Prefer instances of this member, which can be started without forwarding the request.
+		for i := range instances {
+			if instances[i].Node == s.ServerName {
+				claimed = &instances[i]
+				break
+			}
+		}
+
+		if claimed == nil {
+			if len(instances) > 0 && request.QueryParam(r, "target") == "" {
+				remoteMember = instances[0].Node
+				return nil
+			}
+
+			return api.StatusErrorf(http.StatusServiceUnavailable, "Instance pool %q has no available instances", name)
+		}
+
+		names, err := tx.GetInstanceNames(ctx, projectName)
+		if err != nil {
+			return err
+		}
+
+		newName = req.Name
+		if newName == "" {
+			newName, err = instancePoolAcquireName(pool.NamePattern, names)
+			if err != nil {
+				return err
+			}
+		} else if shared.ValueInSlice(newName, names) {
+			return api.StatusErrorf(http.StatusConflict, "Instance %q already exists", newName)
+		}
+
+		// Removing the instance from the pool claims it, as it can't be removed by concurrent requests anymore.
+		return dbCluster.RemoveInstancePoolInstance(ctx, tx.Tx(), claimed.InstanceID)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if remoteMember != "" {
+		unlockName()
+
+		query := r.URL.Query()
+		query.Set("target", remoteMember)
+		r.URL.RawQuery = query.Encode()
+		r.Body = io.NopCloser(bytes.NewBuffer(body))
+
+		return forwardedResponseToNode(s, r, remoteMember)
+	}
+
+	l := logger.AddContext(logger.Ctx{"project": projectName, "pool": name, "instance": claimed.Name, "newName": newName})
+
+	inst, err := instancePoolAcquireInstance(s, projectName, name, *claimed, newName, req.Config, unlockName)
+	if err != nil {
+		l.Warn("Failed acquiring instance from instance pool", logger.Ctx{"err": err})
+		return response.SmartError(err)
+	}
+
+	instancePoolAcquireObserve(projectName, name, time.Since(start))
+	l.Debug("Acquired instance from instance pool", logger.Ctx{"duration": time.Since(start)})
+
+	d.instancePoolsReplenishSoon()
+
+	lc := lifecycle.InstancePoolAcquired.Event(projectName, name, request.CreateRequestor(r), map[string]any{"instance": newName})
+	s.Events.SendLifecycle(projectName, lc)
+
+	render, _, err := inst.Render()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseLocation(true, render, entity.InstanceURL(projectName, newName).String())
+}
+
+// instancePoolAcquireInstance renames the claimed instance, applies the given configuration and starts it. The
+// instance is returned to the pool if it can't be renamed, and deleted if it can't be configured or started. The
+// renamed function is called once the instance has been renamed, or has failed to be.
+func instancePoolAcquireInstance(s *state.State, projectName string, poolName string, claimed dbCluster.InstancePoolInstance, newName string, config map[string]string, renamed func()) (instance.Instance, error) {
+	reverter := revert.New()
+	defer reverter.Fail()
+
+	reverter.Add(func() {
+		_ = s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+			pool, err := dbCluster.GetInstancePool(ctx, tx.Tx(), projectName, poolName)
+			if err != nil {
+				return err
+			}
+
+			return dbCluster.AddInstancePoolInstance(ctx, tx.Tx(), pool.ID, claimed.InstanceID)
+		})
+	})
+
+	unlock, err := instanceOperationLock(s.ShutdownCtx, projectName, claimed.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	inst, err := instance.LoadByProjectAndName(s, projectName, claimed.Name)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+
+	err = inst.Rename(newName, true)
+	unlock()
+	renamed()
+	if err != nil {
+		return nil, fmt.Errorf("Failed renaming instance %q to %q: %w", claimed.Name, newName, err)
+	}
+
+	reverter.Success()
+
+	// From here on the instance has left the pool, so it is deleted rather than left behind if it can't be used.
+	reverter = revert.New()
+	defer reverter.Fail()
+
+	unlock, err = instanceOperationLock(s.ShutdownCtx, projectName, newName)
+	if err != nil {
+		return nil, err
+	}
+
+	defer unlock()
+
+	inst, err = instance.LoadByProjectAndName(s, projectName, newName)
+	if err != nil {
+		return nil, err
+	}
+
+	reverter.Add(func() { _ = inst.Delete(true) })
+
+	if len(config) > 0 {
+		localConfig := inst.LocalConfig()
+		for key, value := range config {
+			localConfig[key] = value
+		}
+
+		profileNames := make([]string, 0, len(inst.Profiles()))
+		for _, profile := range inst.Profiles() {
+			profileNames = append(profileNames, profile.Name)
+		}
+
+		architectureName, err := osarch.ArchitectureName(inst.Architecture())
+		if err != nil {
+			return nil, err
+		}
+
+		req := api.InstancePut{
+			Architecture: architectureName,
+			Config:       localConfig,
+			Devices:      inst.LocalDevices().CloneNative(),
+			Ephemeral:    inst.IsEphemeral(),
+			Profiles:     profileNames,
+			Description:  inst.Description(),
+		}
+
+		// Check project limits.
+		err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+			return project.AllowInstanceUpdate(s.GlobalConfig, tx, projectName, newName, req, inst.LocalConfig())
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		args := db.InstanceArgs{
+			Architecture: inst.Architecture(),
+			Config:       localConfig,
+			Description:  inst.Description(),
+			Devices:      inst.LocalDevices(),
+			Ephemeral:    inst.IsEphemeral(),
+			Profiles:     inst.Profiles(),
+			Project:      projectName,
+		}
+
+		err = inst.Update(args, true)
+		if err != nil {
+			return nil, fmt.Errorf("Failed configuring instance %q: %w", newName, err)
+		}
+	}
+
+	err = inst.Start(false)
+	if err != nil {
+		return nil, fmt.Errorf("Failed starting instance %q: %w", newName, err)
+	}
+
+	reverter.Success()
+
+	return inst, nil
+}
+
+// instancePoolsReplenishSoon runs the instance pool replenishment task now rather than on its next scheduled run.
+func (d *Daemon) instancePoolsReplenishSoon() {
+	if d.taskInstancePools == nil {
+		return
+	}
+
+	// Don't block the caller while the task is running.
+	go d.taskInstancePools.Reset()
+}
+
+func autoReplenishInstancePoolsTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		s := d.State()
+
+		// In order to not create more instances than needed, only the leader replenishes the pools.
+		leader, err := d.gateway.LeaderAddress()
+		if err != nil && !errors.Is(err, cluster.ErrNodeIsNotClustered) {
+			logger.Error("Failed to get leader cluster member address", logger.Ctx{"err": err})
+			return
+		}
+
+		if err == nil && s.LocalConfig.ClusterAddress() != leader {
+			logger.Debug("Skipping instance pool replenishment task since we're not leader")
+			return
+		}
+
+		if s.DB.Cluster.LocalNodeIsEvacuated() {
+			return
+		}
+
+		var pools []dbCluster.InstancePool
+		available := make(map[int][]dbCluster.InstancePoolInstance)
+		err = s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+			allPools, err := dbCluster.GetAllInstancePools(ctx, tx.Tx())
+			if err != nil {
+				return err
+			}
+
+			for _, pool := range allPools {
+				instances, err := dbCluster.GetInstancePoolInstances(ctx, tx.Tx(), pool.ID)
+				if err != nil {
+					return err
+				}
+
+				// Surplus instances are only deleted on this member, and all members count towards the size.
+				localInstances := 0
+				for _, inst := range instances {
+					if inst.Node == s.ServerName {
+						localInstances++
+					}
+				}
+
+				if len(instances) < pool.Size || (len(instances) > pool.Size && localInstances > 0) {
+					pools = append(pools, pool)
+					available[pool.ID] = instances
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			logger.Error("Failed getting instance pools", logger.Ctx{"err": err})
+			return
+		}
+
+		if len(pools) == 0 {
+			return
+		}
+
+		opRun := func(op *operations.Operation) error {
+			failedProjects := make(map[string]bool)
+			for _, pool := range pools {
+				err := instancePoolReplenish(ctx, s, pool, available[pool.ID], op)
+				if err != nil {
+					failedProjects[pool.Project] = true
+				}
+
+				instancePoolReplenishOutcome(s, pool, err)
+			}
+
+			for _, pool := range pools {
+				if failedProjects[pool.Project] {
+					continue
+				}
+
+				err := warnings.ResolveWarningsByLocalNodeAndProjectAndType(s.DB.Cluster, pool.Project, warningtype.InstancePoolReplenishFailure)
+				if err != nil {
+					logger.Warn("Failed to resolve instance pool replenishment failure warning", logger.Ctx{"project": pool.Project, "err": err})
+				}
+			}
+
+			return nil
+		}
+
+		op, err := operations.OperationCreate(s, "", operations.OperationClassTask, operationtype.InstancePoolReplenish, nil, nil, opRun, nil, nil, nil)
+		if err != nil {
+			logger.Error("Failed creating instance pool replenishment operation", logger.Ctx{"err": err})
+			return
+		}
+
+		logger.Debug("Replenishing instance pools")
+		err = op.Start()
+		if err != nil {
+			logger.Error("Failed starting instance pool replenishment operation", logger.Ctx{"err": err})
+			return
+		}
+
+		err = op.Wait(ctx)
+		if err != nil {
+			logger.Error("Failed replenishing instance pools", logger.Ctx{"err": err})
+			return
+		}
+
+		logger.Debug("Done replenishing instance pools")
+	}
+
+	first := true
+	schedule := func() (time.Duration, error) {
+		interval := time.Minute
+
+		if first {
+			first = false
+			return interval, task.ErrSkip
+		}
+
+		return interval, nil
+	}
+
+	return f, schedule
+}
+
+// instancePoolReplenish creates instances until the given pool has as many available instances as its size, or
+// deletes the surplus instances of this member if it has more.
+func instancePoolReplenish(ctx context.Context, s *state.State, pool dbCluster.InstancePool, instances []dbCluster.InstancePoolInstance, op *operations.Operation) error {
+	l := logger.AddContext(logger.Ctx{"project": pool.Project, "pool": pool.Name})
+
+	for i := len(instances); i < pool.Size; i++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		name, err := instancePoolCreateInstance(ctx, s, pool, op)
+		if err != nil {
+			return err
+		}
+
+		l.Debug("Created instance of instance pool", logger.Ctx{"instance": name})
+	}
+
+	// Delete the most recently created surplus instances first.
+	surplus := len(instances) - pool.Size
+	for i := len(instances) - 1; i >= 0 && surplus > 0; i-- {
+		inst := instances[i]
+		if inst.Node != s.ServerName {
+			continue
+		}
+
+		// Claim the instance first so that it can't be acquired while it is being deleted.
+		err := s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+			return dbCluster.RemoveInstancePoolInstance(ctx, tx.Tx(), inst.InstanceID)
+		})
+		if err != nil {
+			if api.StatusErrorCheck(err, http.StatusNotFound) {
+				continue // Acquired in the meantime.
+			}
+
+			return err
+		}
+
+		err = instancePoolDeleteInstance(s, nil, pool.Project, inst.Name, "")
+		if err != nil {
+			return fmt.Errorf("Failed deleting surplus instance %q: %w", inst.Name, err)
+		}
+
+		l.Debug("Deleted surplus instance of instance pool", logger.Ctx{"instance": inst.Name})
+		surplus--
+	}
+
+	return nil
+}
+
+// instancePoolCreateInstance creates a stopped instance from the template of the given pool and makes it available
+// in the pool. The instance counts against the limits of the project like any other instance.
+func instancePoolCreateInstance(ctx context.Context, s *state.State, pool dbCluster.InstancePool, op *operations.Operation) (string, error) {
+	req := api.InstancesPost{
+		InstancePut: api.InstancePut{
+			Config:   util.CopyConfig(pool.Template.Config),
+			Devices:  map[string]map[string]string{},
+			Profiles: pool.Template.Profiles,
+		},
+		Source: pool.Template.Source,
+		Type:   pool.Template.Type,
+	}
+
+	for name, device := range pool.Template.Devices {
+		req.Devices[name] = util.CopyConfig(device)
+	}
+
+	dbType, err := instancetype.New(string(req.Type))
+	if err != nil {
+		return "", err
+	}
+
+	var img *api.Image
+	var profiles []api.Profile
+	err = s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+		var imageRef string
+		img, err = getSourceImageFromInstanceSource(ctx, s, tx, pool.Project, req.Source, &imageRef, string(req.Type))
+		if err != nil {
+			return fmt.Errorf("Failed loading image: %w", err)
+		}
+
+		if req.Profiles == nil {
+			req.Profiles = img.Profiles
+		}
+
+		dbProfiles, err := dbCluster.GetProfilesIfEnabled(ctx, tx.Tx(), pool.Project, req.Profiles)
+		if err != nil {
+			return fmt.Errorf("Failed loading profiles: %w", err)
+		}
+
+		profiles = make([]api.Profile, 0, len(dbProfiles))
+		for _, dbProfile := range dbProfiles {
+			profile, err := dbProfile.ToAPI(ctx, tx.Tx())
+			if err != nil {
+				return err
+			}
+
+			profiles = append(profiles, *profile)
+		}
+
+		names, err := tx.GetInstanceNames(ctx, pool.Project)
+		if err != nil {
+			return err
+		}
+
+		for i := 0; req.Name == "" || shared.ValueInSlice(req.Name, names); i++ {
+			if i > 100 {
+				return fmt.Errorf("Couldn't generate a new unique name after 100 tries")
+			}
+
+			suffix, err := shared.RandomCryptoString()
+			if err != nil {
+				return err
+			}
+
+			req.Name = fmt.Sprintf("%s-pool-%s", pool.Name, suffix[:8])
+		}
+
+		return project.AllowInstanceCreation(s.GlobalConfig, tx, pool.Project, req)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	err = instance.ValidName(req.Name, false)
+	if err != nil {
+		return "", err
+	}
+
+	architecture, err := osarch.ArchitectureId(img.Architecture)
+	if err != nil {
+		return "", err
+	}
+
+	err = instance.ValidArchitecture(s, dbType, architecture)
+	if err != nil {
+		return "", err
+	}
+
+	err = ensureImageIsLocallyAvailable(s, nil, img, pool.Project, dbType)
+	if err != nil {
+		return "", err
+	}
+
+	args := db.InstanceArgs{
+		Project:      pool.Project,
+		Architecture: architecture,
+		Config:       req.Config,
+		Type:         dbType,
+		Description:  fmt.Sprintf("Instance of instance pool %q", pool.Name),
+		Devices:      deviceConfig.ApplyDeviceInitialValues(deviceConfig.NewDevices(req.Devices), profiles),
+		Name:         req.Name,
+		Profiles:     profiles,
+	}
+
+	err = instanceCreateFromImage(s, nil, img, args, op)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+		instanceID, err := dbCluster.GetInstanceID(ctx, tx.Tx(), pool.Project, req.Name)
+		if err != nil {
+			return err
+		}
+
+		// The pool may have been deleted in the meantime.
+		current, err := dbCluster.GetInstancePool(ctx, tx.Tx(), pool.Project, pool.Name)
+		if err != nil {
+			return err
+		}
+
+		return dbCluster.AddInstancePoolInstance(ctx, tx.Tx(), current.ID, int(instanceID))
+	})
+	if err != nil {
+		deleteErr := instancePoolDeleteInstance(s, nil, pool.Project, req.Name, "")
+		if deleteErr != nil {
+			logger.Warn("Failed deleting instance that couldn't be added to instance pool", logger.Ctx{"project": pool.Project, "pool": pool.Name, "instance": req.Name, "err": deleteErr})
+		}
+
+		return "", fmt.Errorf("Failed adding instance %q to instance pool: %w", req.Name, err)
+	}
+
+	return req.Name, nil
+}
+
+// instancePoolReplenishOutcome raises a warning when the given pool couldn't be replenished.
+func instancePoolReplenishOutcome(s *state.State, pool dbCluster.InstancePool, replenishErr error) {
+	if replenishErr == nil {
+		return
+	}
+
+	l := logger.AddContext(logger.Ctx{"project": pool.Project, "pool": pool.Name})
+	l.Error("Failed replenishing instance pool", logger.Ctx{"err": replenishErr})
+
+	err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		return tx.UpsertWarningLocalNode(ctx, pool.Project, "", -1, warningtype.InstancePoolReplenishFailure, fmt.Sprintf("Failed replenishing instance pool %q: %v", pool.Name, replenishErr))
+	})
+	if err != nil {
+		l.Warn("Failed to create instance pool replenishment failure warning", logger.Ctx{"err": err})
+	}
+}
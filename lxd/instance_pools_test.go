@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/canonical/lxd/shared/api"
+)
+
+func TestInstancePoolAcquireName(t *testing.T) {
+	tests := []struct {
+		name          string
+		pattern       string
+		existingNames []string
+		want          string
+		wantStatus    int
+	}{
+		{
+			name:    "First number",
+			pattern: "ci-%d",
+			want:    "ci-0",
+		},
+		{
+			name:          "Lowest unused number",
+			pattern:       "ci-%d",
+			existingNames: []string{"ci-0", "ci-1", "ci-3", "other-2"},
+			want:          "ci-2",
+		},
+		{
+			name:    "Pattern without number",
+			pattern: "runner",
+			want:    "runner",
+		},
+		{
+			name:          "Pattern without number already in use",
+			pattern:       "runner",
+			existingNames: []string{"runner"},
+			wantStatus:    http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := instancePoolAcquireName(tt.pattern, tt.existingNames)
+			if tt.wantStatus != 0 {
+				assert.True(t, api.StatusErrorCheck(err, tt.wantStatus))
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -9,6 +9,9 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
 
@@ -17,8 +20,10 @@ import (
 	"github.com/canonical/lxd/lxd/db"
 	dbCluster "github.com/canonical/lxd/lxd/db/cluster"
 	"github.com/canonical/lxd/lxd/db/operationtype"
+	deviceConfig "github.com/canonical/lxd/lxd/device/config"
 	"github.com/canonical/lxd/lxd/instance"
 	"github.com/canonical/lxd/lxd/instance/instancetype"
+	"github.com/canonical/lxd/lxd/migration"
 	"github.com/canonical/lxd/lxd/operations"
 	"github.com/canonical/lxd/lxd/project"
 	"github.com/canonical/lxd/lxd/request"
@@ -330,9 +335,31 @@ func instancePost(d *Daemon, r *http.Request) response.Response {
 				}
 			}
 
+			if req.SnapshotsLimit < 0 {
+				return response.BadRequest(fmt.Errorf("Invalid snapshots limit %d", req.SnapshotsLimit))
+			}
+
+			// Check the skipped devices and the device volumes before moving any data.
+			if len(req.SkipDevices) > 0 || len(req.DeviceVolumes) > 0 {
+				targetProject := req.Project
+				if targetProject == "" {
+					targetProject = projectName
+				}
+
+				devices := inst.LocalDevices().CloneNative()
+				for devName, dev := range req.Devices {
+					devices[devName] = dev
+				}
+
+				err := instanceCopyDevices(s, targetProject, devices, req.SkipDevices, req.DeviceVolumes)
+				if err != nil {
+					return response.SmartError(err)
+				}
+			}
+
 			// Setup the instance move operation.
 			run := func(op *operations.Operation) error {
-				return instancePostMigration(s, inst, req.Name, req.Pool, req.Project, req.Config, req.Devices, req.Profiles, req.InstanceOnly, req.Live, req.AllowInconsistent, op)
+				return instancePostMigration(s, inst, req.Name, req.Pool, req.Project, req.Config, req.Devices, req.Profiles, req.InstanceOnly, req.Live, req.AllowInconsistent, req.SnapshotsLimit, req.SkipDevices, req.DeviceVolumes, op)
 			}
 
 			resources := map[string][]api.URL{}
@@ -345,6 +372,10 @@ func instancePost(d *Daemon, r *http.Request) response.Response {
 			return operations.OperationResponse(op)
 		}
 
+		if req.SnapshotsLimit != 0 || len(req.SkipDevices) > 0 || len(req.DeviceVolumes) > 0 {
+			return response.BadRequest(fmt.Errorf("Snapshot limits, skipped devices and device volumes are only supported when moving between pools or projects"))
+		}
+
 		if targetMemberInfo != nil {
 			var backups []string
 
@@ -454,7 +485,7 @@ func instancePost(d *Daemon, r *http.Request) response.Response {
 }
 
 // Move an instance.
-func instancePostMigration(s *state.State, inst instance.Instance, newName string, newPool string, newProject string, config map[string]string, devices map[string]map[string]string, profiles []string, instanceOnly bool, stateful bool, allowInconsistent bool, op *operations.Operation) error {
+func instancePostMigration(s *state.State, inst instance.Instance, newName string, newPool string, newProject string, config map[string]string, devices map[string]map[string]string, profiles []string, instanceOnly bool, stateful bool, allowInconsistent bool, snapshotsLimit int, skipDevices []string, deviceVolumes map[string]string, op *operations.Operation) error {
 	if inst.IsSnapshot() {
 		return fmt.Errorf("Instance snapshots cannot be moved between pools")
 	}
@@ -493,6 +524,17 @@ func instancePostMigration(s *state.State, inst instance.Instance, newName strin
 		localDevices[devName] = dev
 	}
 
+	// Remove the skipped devices and remap the device volumes.
+	if len(skipDevices) > 0 || len(deviceVolumes) > 0 {
+		nativeDevices := localDevices.CloneNative()
+		err := instanceCopyDevices(s, newProject, nativeDevices, skipDevices, deviceVolumes)
+		if err != nil {
+			return err
+		}
+
+		localDevices = deviceConfig.NewDevices(nativeDevices)
+	}
+
 	// Apply previous profiles, if provided profiles are nil.
 	if profiles == nil {
 		profiles = make([]string, 0, len(inst.Profiles()))
@@ -601,6 +643,9 @@ func instancePostMigration(s *state.State, inst instance.Instance, newName strin
 		instanceOnly:         instanceOnly,
 		applyTemplateTrigger: false, // Don't apply templates when moving.
 		allowInconsistent:    allowInconsistent,
+		snapshotsLimit:       snapshotsLimit,
+		skipDevices:          skipDevices,
+		deviceVolumes:        deviceVolumes,
 	}, op)
 	if err != nil {
 		return err
@@ -771,8 +816,23 @@ func instancePostClusteringMigrate(s *state.State, r *http.Request, srcPool stor
 			return fmt.Errorf("Failed requesting instance create on destination: %w", err)
 		}
 
+		// The progress of a live migration is reported by the source, so relay it along with the metadata of
+		// the destination operation.
+		var metadataMu sync.Mutex
+		var destMetadata map[string]any
+		updateMetadata := func() {
+			metadataMu.Lock()
+			defer metadataMu.Unlock()
+
+			_ = op.UpdateMetadata(clusterMoveMetadata(destMetadata, srcOp.Metadata()))
+		}
+
 		handler := func(newOp api.Operation) {
-			_ = op.UpdateMetadata(newOp.Metadata)
+			metadataMu.Lock()
+			destMetadata = newOp.Metadata
+			metadataMu.Unlock()
+
+			updateMetadata()
 		}
 
 		_, err = destOp.AddHandler(handler)
@@ -780,6 +840,25 @@ func instancePostClusteringMigrate(s *state.State, r *http.Request, srcPool stor
 			return err
 		}
 
+		if live {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			go func() {
+				ticker := time.NewTicker(time.Second)
+				defer ticker.Stop()
+
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case <-ticker.C:
+						updateMetadata()
+					}
+				}
+			}()
+		}
+
 		err = destOp.Wait()
 		if err != nil {
 			return fmt.Errorf("Instance move to destination failed: %w", err)
@@ -874,6 +953,32 @@ func instancePostClusteringMigrate(s *state.State, r *http.Request, srcPool stor
 	return run, nil
 }
 
+// clusterMoveMetadata returns the metadata of a cluster member move operation from the metadata of the destination
+// and source operations. The live migration progress reported by the source is added to the metadata of the
+// destination. It then replaces the other progress of the destination, so that the CLI renders it.
+func clusterMoveMetadata(destMetadata map[string]any, srcMetadata map[string]any) map[string]any {
+	metadata := make(map[string]any, len(destMetadata)+2)
+	for k, v := range destMetadata {
+		metadata[k] = v
+	}
+
+	progress, ok := srcMetadata[migration.ProgressMetadataKey]
+	if !ok {
+		return metadata
+	}
+
+	for k := range metadata {
+		if strings.HasSuffix(k, "_progress") {
+			delete(metadata, k)
+		}
+	}
+
+	metadata[migration.ProgressMetadataKey] = progress
+	metadata[migration.ProgressTextMetadataKey] = srcMetadata[migration.ProgressTextMetadataKey]
+
+	return metadata
+}
+
 // instancePostClusteringMigrateWithCeph handles moving a ceph instance from a source member that is offline.
 // This function must be run on the target cluster member to move the instance to.
 func instancePostClusteringMigrateWithCeph(s *state.State, r *http.Request, srcPool storagePools.Pool, srcInst instance.Instance, newInstName string, newMember db.NodeInfo, stateful bool) (func(op *operations.Operation) error, error) {
@@ -990,13 +1095,25 @@ func migrateInstance(s *state.State, r *http.Request, inst instance.Instance, ta
 			return err
 		}
 
-		return f(op)
-	}
+		err = f(op)
+		if err != nil {
+			return err
+		}
+	} else {
+		f, err := instancePostClusteringMigrate(s, r, srcPool, inst, req.Name, srcMember, newMember, req.Live, req.AllowInconsistent)
+		if err != nil {
+			return err
+		}
 
-	f, err := instancePostClusteringMigrate(s, r, srcPool, inst, req.Name, srcMember, newMember, req.Live, req.AllowInconsistent)
-	if err != nil {
-		return err
+		err = f(op)
+		if err != nil {
+			return err
+		}
 	}
 
-	return f(op)
+	// Permissions on the instances of a cluster member are resolved when loading the identity cache, so it must be
+	// refreshed on all members now that the instance is located on another member.
+	notifyIdentityCacheRefresh(s)
+
+	return nil
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 
+	"github.com/canonical/lxd/lxd/auth"
 	"github.com/canonical/lxd/lxd/db"
 	"github.com/canonical/lxd/lxd/db/cluster"
 	"github.com/canonical/lxd/lxd/db/operationtype"
@@ -24,6 +25,7 @@ import (
 	"github.com/canonical/lxd/lxd/util"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
 	"github.com/canonical/lxd/shared/osarch"
 	"github.com/canonical/lxd/shared/revert"
 	"github.com/canonical/lxd/shared/version"
@@ -131,6 +133,17 @@ func instancePut(d *Daemon, r *http.Request) response.Response {
 	var do func(*operations.Operation) error
 	var opType operationtype.Type
 	if configRaw.Restore == "" {
+		err = instanceImmutableKeysCheck(s, r, inst, configRaw.Config)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		// Validate the new devices upfront so that invalid ones are rejected before the operation is created.
+		err = instance.ValidDevices(s, inst.Project(), inst.Type(), deviceConfig.NewDevices(configRaw.Devices), nil)
+		if err != nil {
+			return response.SmartError(fmt.Errorf("Invalid devices: %w", err))
+		}
+
 		// Check project limits.
 		apiProfiles := make([]api.Profile, 0, len(configRaw.Profiles))
 		err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
@@ -178,6 +191,10 @@ func instancePut(d *Daemon, r *http.Request) response.Response {
 
 		opType = operationtype.InstanceUpdate
 	} else {
+		if shared.IsTrue(inst.ExpandedConfig()["security.protection.force"]) && !configRaw.OverrideProtection {
+			return response.Forbidden(fmt.Errorf("Instance is protected against snapshot restores (security.protection.force), set override_protection to restore anyway"))
+		}
+
 		// Snapshot Restore
 		do = func(op *operations.Operation) error {
 			defer unlock()
@@ -235,3 +252,25 @@ func instanceSnapRestore(s *state.State, projectName string, name string, snap s
 
 	return nil
 }
+
+// instanceImmutableKeysCheck refuses changes to the immutable config keys of the instance, including the list of
+// immutable keys itself, unless the requestor is allowed to override them.
+func instanceImmutableKeysCheck(s *state.State, r *http.Request, inst instance.Instance, newConfig map[string]string) error {
+	oldConfig := inst.LocalConfig()
+
+	keys := append(instance.ImmutableKeys(oldConfig), "volatile.immutable_keys")
+	for _, key := range keys {
+		if newConfig[key] == oldConfig[key] {
+			continue
+		}
+
+		err := s.Authorizer.CheckPermission(r.Context(), r, entity.ServerURL(), auth.EntitlementCanOverrideImmutable)
+		if err != nil && api.StatusErrorCheck(err, http.StatusForbidden) {
+			return api.StatusErrorf(http.StatusForbidden, "Config key %q is immutable", key)
+		}
+
+		return err
+	}
+
+	return nil
+}
@@ -131,27 +131,63 @@ func instanceRebuildPost(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
-	if inst.IsRunning() {
-		return response.BadRequest(fmt.Errorf("Instance must be stopped to be rebuilt"))
+	if shared.IsTrue(inst.ExpandedConfig()["security.protection.force"]) {
+		return response.Forbidden(fmt.Errorf("Instance is protected against being rebuilt (security.protection.force)"))
+	}
+
+	if inst.IsRunning() && !req.Force {
+		return response.BadRequest(fmt.Errorf("Instance must be stopped to be rebuilt, or the rebuild must be forced"))
 	}
 
 	run := func(op *operations.Operation) error {
-		if req.Source.Type == "none" {
-			return instanceRebuildFromEmpty(s, inst, op)
+		metadata := make(map[string]any)
+		setProgress := func(progress string) {
+			metadata["rebuild_progress"] = progress
+			_ = op.UpdateMetadata(metadata)
 		}
 
-		if req.Source.Server != "" {
+		if req.Source.Type != "none" && req.Source.Server != "" {
+			setProgress("Downloading image")
 			sourceImage, err = ensureDownloadedImageFitWithinBudget(s, r, op, *targetProject, sourceImage, sourceImageRef, req.Source, inst.Type().String())
 			if err != nil {
 				return err
 			}
 		}
 
-		if sourceImage == nil {
+		if req.Source.Type != "none" && sourceImage == nil {
 			return fmt.Errorf("Image not provided for instance rebuild")
 		}
 
-		return instanceRebuildFromImage(s, r, inst, sourceImage, op)
+		// Stop the instance only once the image is available, to keep the downtime short.
+		wasRunning := inst.IsRunning()
+		if wasRunning {
+			setProgress("Stopping instance")
+			err := inst.Stop(false)
+			if err != nil {
+				return fmt.Errorf("Failed stopping instance: %w", err)
+			}
+		}
+
+		setProgress("Rebuilding root volume")
+		if req.Source.Type == "none" {
+			err = instanceRebuildFromEmpty(s, inst, op)
+		} else {
+			err = instanceRebuildFromImage(s, r, inst, sourceImage, op)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if wasRunning {
+			setProgress("Starting instance")
+			err := inst.Start(false)
+			if err != nil {
+				return fmt.Errorf("Failed starting rebuilt instance: %w", err)
+			}
+		}
+
+		return nil
 	}
 
 	resources := map[string][]api.URL{}
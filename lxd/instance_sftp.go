@@ -7,25 +7,44 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"os"
 	"sync"
 
 	"github.com/gorilla/mux"
+	"github.com/pkg/sftp"
 
+	"github.com/canonical/lxd/lxd/auth"
 	"github.com/canonical/lxd/lxd/cluster"
 	"github.com/canonical/lxd/lxd/instance"
 	"github.com/canonical/lxd/lxd/request"
 	"github.com/canonical/lxd/lxd/response"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/tcp"
 )
 
+// instanceSFTPAccessHandler allows access to the instance SFTP endpoint if the caller can either connect over SFTP,
+// read and write the files of the instance, or only read them. The handler itself decides whether the connection is
+// read-only.
+func instanceSFTPAccessHandler(d *Daemon, r *http.Request) response.Response {
+	for _, entitlement := range []auth.Entitlement{auth.EntitlementCanConnectSFTP, auth.EntitlementCanEditFiles} {
+		resp := allowPermission(entity.TypeInstance, entitlement, "name")(d, r)
+		if resp == response.EmptySyncResponse {
+			return resp
+		}
+	}
+
+	return allowPermission(entity.TypeInstance, auth.EntitlementCanAccessFiles, "name")(d, r)
+}
+
 // swagger:operation GET /1.0/instances/{name}/sftp instances instance_sftp
 //
 //	Get the instance SFTP connection
 //
 //	Upgrades the request to an SFTP connection of the instance's filesystem.
+//	Callers which can only read the files of the instance get a read-only connection.
 //
 //	---
 //	produces:
@@ -66,9 +85,8 @@ func instanceSFTPHandler(d *Daemon, r *http.Request) response.Response {
 	}
 
 	resp := &sftpServeResponse{
-		req:         r,
-		projectName: projectName,
-		instName:    instName,
+		req:    r,
+		logCtx: logger.Ctx{"project": projectName, "instance": instName},
 	}
 
 	// Forward the request if the instance is remote.
@@ -78,7 +96,7 @@ func instanceSFTPHandler(d *Daemon, r *http.Request) response.Response {
 	}
 
 	if client != nil {
-		resp.instConn, err = client.GetInstanceFileSFTPConn(instName)
+		resp.conn, err = client.GetInstanceFileSFTPConn(instName)
 		if err != nil {
 			return response.SmartError(err)
 		}
@@ -88,20 +106,179 @@ func instanceSFTPHandler(d *Daemon, r *http.Request) response.Response {
 			return response.SmartError(err)
 		}
 
-		resp.instConn, err = inst.FileSFTPConn()
+		// Callers that can neither connect over SFTP nor edit the files of the instance were let through by the
+		// access handler because they can read them.
+		instURL := entity.InstanceURL(projectName, instName)
+		readOnly := true
+		for _, entitlement := range []auth.Entitlement{auth.EntitlementCanConnectSFTP, auth.EntitlementCanEditFiles} {
+			if s.Authorizer.CheckPermission(r.Context(), r, instURL, entitlement) == nil {
+				readOnly = false
+				break
+			}
+		}
+
+		resp.conn, err = inst.FileSFTPConn()
 		if err != nil {
 			return response.SmartError(api.StatusErrorf(http.StatusInternalServerError, "Failed getting instance SFTP connection: %v", err))
 		}
+
+		if readOnly {
+			resp.conn, err = instanceSFTPReadOnlyConn(resp.conn)
+			if err != nil {
+				return response.SmartError(api.StatusErrorf(http.StatusInternalServerError, "Failed getting read-only instance SFTP connection: %v", err))
+			}
+		}
+
+		resp.logCtx["readOnly"] = readOnly
 	}
 
 	return resp
 }
 
+// instanceSFTPReadOnlyConn returns a connection to an SFTP server that serves the files of the instance SFTP server
+// behind conn without allowing any change to them. The instance SFTP server is shared by all the sessions of the
+// instance, so it can't be made read-only itself. The connection to it is closed once the returned connection has
+// been closed.
+func instanceSFTPReadOnlyConn(conn net.Conn) (net.Conn, error) {
+	client, err := sftp.NewClientPipe(conn, conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	serverConn, clientConn := net.Pipe()
+	handler := &instanceSFTPReadOnlyHandler{client: client}
+	server := sftp.NewRequestServer(serverConn, sftp.Handlers{
+		FileGet:  handler,
+		FilePut:  handler,
+		FileCmd:  handler,
+		FileList: handler,
+	})
+
+	go func() {
+		_ = server.Serve()
+		_ = server.Close()
+		_ = client.Close()
+	}()
+
+	return clientConn, nil
+}
+
+// instanceSFTPReadOnlyHandler handles the requests of a read-only SFTP session by forwarding those that only read
+// files to the instance SFTP server and refusing the others.
+type instanceSFTPReadOnlyHandler struct {
+	client *sftp.Client
+}
+
+// Fileread opens the requested file for reading.
+func (h *instanceSFTPReadOnlyHandler) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	return h.client.Open(r.Filepath)
+}
+
+// Filewrite refuses to open files for writing.
+func (h *instanceSFTPReadOnlyHandler) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	return nil, sftp.ErrSSHFxPermissionDenied
+}
+
+// Filecmd refuses all the requests changing the filesystem.
+func (h *instanceSFTPReadOnlyHandler) Filecmd(r *sftp.Request) error {
+	return sftp.ErrSSHFxPermissionDenied
+}
+
+// Filelist lists the entries of the requested directory or returns the information of the requested file.
+func (h *instanceSFTPReadOnlyHandler) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	switch r.Method {
+	case "List":
+		entries, err := h.client.ReadDir(r.Filepath)
+		if err != nil {
+			return nil, err
+		}
+
+		return instanceSFTPListerAt(entries), nil
+	case "Stat":
+		info, err := h.client.Stat(r.Filepath)
+		if err != nil {
+			return nil, err
+		}
+
+		return instanceSFTPListerAt([]os.FileInfo{info}), nil
+	}
+
+	return nil, sftp.ErrSSHFxOpUnsupported
+}
+
+// Lstat returns the information of the requested file without following symlinks.
+func (h *instanceSFTPReadOnlyHandler) Lstat(r *sftp.Request) (sftp.ListerAt, error) {
+	info, err := h.client.Lstat(r.Filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	return instanceSFTPListerAt([]os.FileInfo{info}), nil
+}
+
+// Readlink returns the target of the requested symlink.
+func (h *instanceSFTPReadOnlyHandler) Readlink(path string) (string, error) {
+	return h.client.ReadLink(path)
+}
+
+// instanceSFTPFileInfo exposes the ownership of a file returned by the instance SFTP server, which is otherwise lost
+// when the file information is sent back to the client.
+type instanceSFTPFileInfo struct {
+	os.FileInfo
+}
+
+// Uid returns the user ID of the file owner.
+func (fi instanceSFTPFileInfo) Uid() uint32 {
+	stat, ok := fi.Sys().(*sftp.FileStat)
+	if !ok {
+		return 0
+	}
+
+	return stat.UID
+}
+
+// Gid returns the group ID of the file owner.
+func (fi instanceSFTPFileInfo) Gid() uint32 {
+	stat, ok := fi.Sys().(*sftp.FileStat)
+	if !ok {
+		return 0
+	}
+
+	return stat.GID
+}
+
+// instanceSFTPListerAt implements sftp.ListerAt for a list of files.
+type instanceSFTPListerAt []os.FileInfo
+
+// ListAt copies the files starting at the given offset into the given slice.
+func (l instanceSFTPListerAt) ListAt(entries []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+
+	n := 0
+	for _, info := range l[offset:] {
+		if n >= len(entries) {
+			break
+		}
+
+		entries[n] = instanceSFTPFileInfo{FileInfo: info}
+		n++
+	}
+
+	if n < len(entries) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
 type sftpServeResponse struct {
-	req         *http.Request
-	projectName string
-	instName    string
-	instConn    net.Conn
+	req     *http.Request
+	logCtx  logger.Ctx // Identifies the entity being served in log messages.
+	conn    net.Conn   // Connection to the SFTP server.
+	cleanup func()     // Optional function called once the SFTP session has ended.
 }
 
 func (r *sftpServeResponse) String() string {
@@ -110,7 +287,11 @@ func (r *sftpServeResponse) String() string {
 
 // Render renders the server response.
 func (r *sftpServeResponse) Render(w http.ResponseWriter) error {
-	defer func() { _ = r.instConn.Close() }()
+	if r.cleanup != nil {
+		defer r.cleanup()
+	}
+
+	defer func() { _ = r.conn.Close() }()
 
 	hijacker, ok := w.(http.Hijacker)
 	if !ok {
@@ -139,37 +320,34 @@ func (r *sftpServeResponse) Render(w http.ResponseWriter) error {
 	}
 
 	ctx, cancel := context.WithCancel(r.req.Context())
-	l := logger.AddContext(logger.Ctx{
-		"project":  r.projectName,
-		"instance": r.instName,
-		"local":    remoteConn.LocalAddr(),
-		"remote":   remoteConn.RemoteAddr(),
-		"err":      err,
+	l := logger.AddContext(r.logCtx).AddContext(logger.Ctx{
+		"local":  remoteConn.LocalAddr(),
+		"remote": remoteConn.RemoteAddr(),
 	})
 
 	wg := sync.WaitGroup{}
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		_, err := io.Copy(remoteConn, r.instConn)
+		_, err := io.Copy(remoteConn, r.conn)
 		if err != nil {
 			if ctx.Err() == nil {
-				l.Warn("Failed copying SFTP instance connection to remote connection", logger.Ctx{"err": err})
+				l.Warn("Failed copying SFTP server connection to remote connection", logger.Ctx{"err": err})
 			}
 		}
 		cancel()               // Cancel context first so when remoteConn is closed it doesn't cause a warning.
 		_ = remoteConn.Close() // Trigger the cancellation of the io.Copy reading from remoteConn.
 	}()
 
-	_, err = io.Copy(r.instConn, remoteConn)
+	_, err = io.Copy(r.conn, remoteConn)
 	if err != nil {
 		if ctx.Err() == nil {
-			l.Warn("Failed copying SFTP remote connection to instance connection", logger.Ctx{"err": err})
+			l.Warn("Failed copying SFTP remote connection to server connection", logger.Ctx{"err": err})
 		}
 	}
-	cancel() // Cancel context first so when instConn is closed it doesn't cause a warning.
+	cancel() // Cancel context first so when conn is closed it doesn't cause a warning.
 
-	err = r.instConn.Close() // Trigger the cancellation of the io.Copy reading from instConn.
+	err = r.conn.Close() // Trigger the cancellation of the io.Copy reading from conn.
 	if err != nil {
 		return fmt.Errorf("Failed closing connection to remote server: %w", err)
 	}
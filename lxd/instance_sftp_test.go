@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstanceSFTPReadOnlyConn(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "file"), []byte("hello"), 0600))
+	require.NoError(t, os.Symlink("file", filepath.Join(root, "link")))
+
+	serverConn, instanceConn := net.Pipe()
+	server, err := sftp.NewServer(serverConn)
+	require.NoError(t, err)
+
+	go func() { _ = server.Serve() }()
+
+	conn, err := instanceSFTPReadOnlyConn(instanceConn)
+	require.NoError(t, err)
+
+	client, err := sftp.NewClientPipe(conn, conn)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+
+	// Files can be read.
+	file, err := client.Open(filepath.Join(root, "file"))
+	require.NoError(t, err)
+
+	content := make([]byte, 5)
+	_, err = file.Read(content)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(content))
+	require.NoError(t, file.Close())
+
+	entries, err := client.ReadDir(root)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	stat, err := client.Stat(filepath.Join(root, "link"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+	assert.Equal(t, uint32(os.Getuid()), stat.Sys().(*sftp.FileStat).UID)
+
+	stat, err = client.Lstat(filepath.Join(root, "link"))
+	require.NoError(t, err)
+	assert.NotZero(t, stat.Mode()&os.ModeSymlink)
+
+	target, err := client.ReadLink(filepath.Join(root, "link"))
+	require.NoError(t, err)
+	assert.Equal(t, "file", target)
+
+	// Nothing can be changed.
+	_, err = client.OpenFile(filepath.Join(root, "file"), os.O_WRONLY|os.O_TRUNC)
+	assert.ErrorIs(t, err, os.ErrPermission)
+
+	_, err = client.Create(filepath.Join(root, "new"))
+	assert.ErrorIs(t, err, os.ErrPermission)
+
+	assert.ErrorIs(t, client.Mkdir(filepath.Join(root, "dir")), os.ErrPermission)
+	assert.ErrorIs(t, client.Remove(filepath.Join(root, "file")), os.ErrPermission)
+	assert.ErrorIs(t, client.Rename(filepath.Join(root, "file"), filepath.Join(root, "renamed")), os.ErrPermission)
+	assert.ErrorIs(t, client.Chmod(filepath.Join(root, "file"), 0777), os.ErrPermission)
+	assert.ErrorIs(t, client.Symlink("file", filepath.Join(root, "link2")), os.ErrPermission)
+
+	content, err = os.ReadFile(filepath.Join(root, "file"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(content))
+
+	_, err = os.Lstat(filepath.Join(root, "new"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
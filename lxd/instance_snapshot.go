@@ -212,6 +212,10 @@ func instanceSnapshotsGet(d *Daemon, r *http.Request) response.Response {
 //
 //	Creates a new snapshot.
 //
+//	If the scheduled parameter is set to "retry", the scheduled snapshot of the instance is taken immediately, using
+//	the name pattern and expiry of scheduled snapshots, and its outcome is recorded in the instance state.
+//	The schedule parameter selects a named snapshot schedule instead of the one of `snapshots.schedule`.
+//
 //	---
 //	consumes:
 //	  - application/json
@@ -223,6 +227,16 @@ func instanceSnapshotsGet(d *Daemon, r *http.Request) response.Response {
 //	    description: Project name
 //	    type: string
 //	    example: default
+//	  - in: query
+//	    name: scheduled
+//	    description: Set to "retry" to immediately take the scheduled snapshot of the instance (the request body is ignored)
+//	    type: string
+//	    example: retry
+//	  - in: query
+//	    name: schedule
+//	    description: Name of the snapshot schedule to retry
+//	    type: string
+//	    example: hourly
 //	  - in: body
 //	    name: snapshot
 //	    description: Snapshot request
@@ -299,6 +313,11 @@ func instanceSnapshotsPost(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	scheduled := request.QueryParam(r, "scheduled")
+	if scheduled != "" {
+		return instanceScheduledSnapshotRetry(s, r, inst, scheduled)
+	}
+
 	req := api.InstanceSnapshotsPost{}
 	err = json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
@@ -349,6 +368,41 @@ func instanceSnapshotsPost(d *Daemon, r *http.Request) response.Response {
 	return operations.OperationResponse(op)
 }
 
+// instanceScheduledSnapshotRetry takes the scheduled snapshot of the instance outside of its schedule.
+func instanceScheduledSnapshotRetry(s *state.State, r *http.Request, inst instance.Instance, scheduled string) response.Response {
+	if scheduled != "retry" {
+		return response.BadRequest(fmt.Errorf("Invalid value %q for the scheduled parameter", scheduled))
+	}
+
+	schedule := request.QueryParam(r, "schedule")
+	if schedule == "" && inst.ExpandedConfig()["snapshots.schedule"] == "" {
+		return response.BadRequest(fmt.Errorf("Instance %q does not have a snapshot schedule", inst.Name()))
+	}
+
+	if schedule != "" && !shared.ValueInSlice(schedule, instancetype.SnapshotScheduleNames(inst.ExpandedConfig())) {
+		return response.BadRequest(fmt.Errorf("Instance %q does not have a snapshot schedule named %q", inst.Name(), schedule))
+	}
+
+	snapshot := func(op *operations.Operation) error {
+		inst.SetOperation(op)
+		return autoCreateInstanceSnapshot(s, inst, schedule)
+	}
+
+	resources := map[string][]api.URL{}
+	resources["instances"] = []api.URL{*api.NewURL().Path(version.APIVersion, "instances", inst.Name())}
+
+	if inst.Type() == instancetype.Container {
+		resources["containers"] = resources["instances"]
+	}
+
+	op, err := operations.OperationCreate(s, inst.Project().Name, operations.OperationClassTask, operationtype.SnapshotCreate, resources, nil, snapshot, nil, nil, r)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
 func instanceSnapshotHandler(d *Daemon, r *http.Request) response.Response {
 	s := d.State()
 
@@ -1,23 +1,31 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
 
+	"github.com/canonical/lxd/lxd/db"
 	"github.com/canonical/lxd/lxd/db/operationtype"
+	"github.com/canonical/lxd/lxd/db/warningtype"
 	"github.com/canonical/lxd/lxd/instance"
 	"github.com/canonical/lxd/lxd/instance/instancetype"
 	"github.com/canonical/lxd/lxd/operations"
+	"github.com/canonical/lxd/lxd/project"
 	"github.com/canonical/lxd/lxd/request"
 	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/lxd/state"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
+	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/version"
 )
 
@@ -190,6 +198,11 @@ func instanceStatePut(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	err = instanceStateProtectionCheck(inst, req)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	// Actually perform the change.
 	opType, err := instanceActionToOptype(req.Action)
 	if err != nil {
@@ -199,7 +212,7 @@ func instanceStatePut(d *Daemon, r *http.Request) response.Response {
 	do := func(op *operations.Operation) error {
 		inst.SetOperation(op)
 
-		return doInstanceStatePut(inst, req)
+		return doInstanceStatePut(s, inst, req)
 	}
 
 	resources := map[string][]api.URL{}
@@ -224,12 +237,42 @@ func instanceActionToOptype(action string) (operationtype.Type, error) {
 		return operationtype.InstanceFreeze, nil
 	case instancetype.Unfreeze:
 		return operationtype.InstanceUnfreeze, nil
+	case instancetype.UnfreezeFilesystem:
+		return operationtype.InstanceFilesystemUnfreeze, nil
 	}
 
 	return operationtype.Unknown, fmt.Errorf("Unknown action: '%s'", action)
 }
 
-func doInstanceStatePut(inst instance.Instance, req api.InstanceStatePut) error {
+// instanceStateProtectionCheck returns a forbidden error if the state change is prevented by the
+// security.protection.stop or security.protection.force settings of the instance.
+func instanceStateProtectionCheck(inst instance.Instance, req api.InstanceStatePut) error {
+	action := instancetype.InstanceAction(req.Action)
+	if action != instancetype.Stop && action != instancetype.Restart {
+		return nil
+	}
+
+	if shared.IsTrue(inst.ExpandedConfig()["security.protection.stop"]) {
+		return api.StatusErrorf(http.StatusForbidden, "Instance is protected against being stopped or restarted (security.protection.stop)")
+	}
+
+	if req.Force && shared.IsTrue(inst.ExpandedConfig()["security.protection.force"]) {
+		return api.StatusErrorf(http.StatusForbidden, "Instance is protected against forced stops and restarts (security.protection.force)")
+	}
+
+	return nil
+}
+
+func doInstanceStatePut(s *state.State, inst instance.Instance, req api.InstanceStatePut) error {
+	// Checked again here as bulk state changes only check protection at this point.
+	err := instanceStateProtectionCheck(inst, req)
+	if err != nil {
+		return err
+	}
+
+	// For freeze, the timeout is how long the instance may stay frozen before being unfrozen automatically.
+	freezeTimeout := time.Duration(req.Timeout) * time.Second
+
 	if req.Force {
 		// A zero timeout indicates to do a forced stop/restart.
 		req.Timeout = 0
@@ -256,10 +299,115 @@ func doInstanceStatePut(inst instance.Instance, req api.InstanceStatePut) error
 	case instancetype.Restart:
 		return inst.Restart(timeout)
 	case instancetype.Freeze:
-		return inst.Freeze()
+		err := inst.Freeze()
+		if err != nil {
+			return err
+		}
+
+		instanceFreezeTimerSet(s, inst.Project().Name, inst.Name(), freezeTimeout)
+
+		return nil
 	case instancetype.Unfreeze:
-		return inst.Unfreeze()
+		err := inst.Unfreeze()
+		if err != nil {
+			return err
+		}
+
+		instanceFreezeTimerSet(s, inst.Project().Name, inst.Name(), 0)
+
+		return nil
+	case instancetype.UnfreezeFilesystem:
+		return inst.UnfreezeFilesystem()
 	}
 
 	return fmt.Errorf("Unknown action: '%s'", req.Action)
 }
+
+// instanceFreezeTimers holds the timers unfreezing instances that were frozen with a timeout, keyed by instance.
+// The timers only live in memory, so a freeze timeout doesn't survive a restart of LXD.
+var instanceFreezeTimers = map[string]*time.Timer{}
+var instanceFreezeTimersMu sync.Mutex
+
+// instanceFreezeTimerSet starts the timer unfreezing the instance once the timeout is reached, replacing any
+// existing one. A zero or negative timeout only cancels the existing timer.
+func instanceFreezeTimerSet(s *state.State, projectName string, instanceName string, timeout time.Duration) {
+	key := project.Instance(projectName, instanceName)
+
+	instanceFreezeTimersMu.Lock()
+	defer instanceFreezeTimersMu.Unlock()
+
+	existing, ok := instanceFreezeTimers[key]
+	if ok {
+		existing.Stop()
+		delete(instanceFreezeTimers, key)
+	}
+
+	if timeout <= 0 {
+		return
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(timeout, func() {
+		instanceFreezeTimersMu.Lock()
+		if instanceFreezeTimers[key] != timer {
+			instanceFreezeTimersMu.Unlock()
+			return // Replaced or cancelled in the meantime.
+		}
+
+		delete(instanceFreezeTimers, key)
+		instanceFreezeTimersMu.Unlock()
+
+		instanceFreezeTimeoutUnfreeze(s, projectName, instanceName, timeout)
+	})
+
+	instanceFreezeTimers[key] = timer
+}
+
+// instanceFreezeTimeoutUnfreeze unfreezes an instance that stayed frozen for longer than its freeze timeout and
+// raises a warning so that operators know it happened.
+func instanceFreezeTimeoutUnfreeze(s *state.State, projectName string, instanceName string, timeout time.Duration) {
+	l := logger.AddContext(logger.Ctx{"project": projectName, "instance": instanceName, "timeout": timeout})
+
+	inst, err := instance.LoadByProjectAndName(s, projectName, instanceName)
+	if err != nil {
+		l.Warn("Failed loading instance to unfreeze after freeze timeout", logger.Ctx{"err": err})
+		return
+	}
+
+	if !inst.IsFrozen() {
+		return // Already unfrozen, stopped or restarted.
+	}
+
+	l.Warn("Unfreezing instance frozen for too long")
+
+	opRun := func(op *operations.Operation) error {
+		inst.SetOperation(op)
+
+		return inst.Unfreeze()
+	}
+
+	resources := map[string][]api.URL{}
+	resources["instances"] = []api.URL{*api.NewURL().Path(version.APIVersion, "instances", instanceName).Project(projectName)}
+
+	msg := fmt.Sprintf("Instance frozen for more than %s was unfrozen", timeout)
+
+	op, err := operations.OperationCreate(s, projectName, operations.OperationClassTask, operationtype.InstanceFreezeTimeout, resources, nil, opRun, nil, nil, nil)
+	if err == nil {
+		err = op.Start()
+		if err == nil {
+			err = op.Wait(s.ShutdownCtx)
+		}
+	}
+
+	if err != nil {
+		l.Error("Failed unfreezing instance after freeze timeout", logger.Ctx{"err": err})
+		msg = fmt.Sprintf("Failed unfreezing instance frozen for more than %s: %v", timeout, err)
+	}
+
+	err = s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+		return tx.UpsertWarningLocalNode(ctx, projectName, entity.TypeInstance, inst.ID(), warningtype.InstanceFreezeTimeout, msg)
+	})
+	if err != nil {
+		l.Warn("Failed to create instance freeze timeout warning", logger.Ctx{"err": err})
+	}
+}
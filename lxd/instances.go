@@ -3,12 +3,14 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -88,6 +90,28 @@ var instanceStateCmd = APIEndpoint{
 	Put: APIEndpointAction{Handler: instanceStatePut, AccessHandler: allowPermission(entity.TypeInstance, auth.EntitlementCanUpdateState, "name")},
 }
 
+var instanceCloudInitCmd = APIEndpoint{
+	Name: "instanceCloudInit",
+	Path: "instances/{name}/cloud-init",
+	Aliases: []APIEndpointAlias{
+		{Name: "containerCloudInit", Path: "containers/{name}/cloud-init"},
+		{Name: "vmCloudInit", Path: "virtual-machines/{name}/cloud-init"},
+	},
+
+	Get: APIEndpointAction{Handler: instanceCloudInitGet, AccessHandler: allowPermission(entity.TypeInstance, auth.EntitlementCanView, "name")},
+}
+
+var instanceCloudInitNetworkConfigCmd = APIEndpoint{
+	Name: "instanceCloudInitNetworkConfig",
+	Path: "instances/{name}/cloud-init/network-config",
+	Aliases: []APIEndpointAlias{
+		{Name: "containerCloudInitNetworkConfig", Path: "containers/{name}/cloud-init/network-config"},
+		{Name: "vmCloudInitNetworkConfig", Path: "virtual-machines/{name}/cloud-init/network-config"},
+	},
+
+	Get: APIEndpointAction{Handler: instanceCloudInitNetworkConfigGet, AccessHandler: allowPermission(entity.TypeInstance, auth.EntitlementCanView, "name")},
+}
+
 var instanceSFTPCmd = APIEndpoint{
 	Name: "instanceFile",
 	Path: "instances/{name}/sftp",
@@ -96,7 +120,7 @@ var instanceSFTPCmd = APIEndpoint{
 		{Name: "vmFile", Path: "virtual-machines/{name}/sftp"},
 	},
 
-	Get: APIEndpointAction{Handler: instanceSFTPHandler, AccessHandler: allowPermission(entity.TypeInstance, auth.EntitlementCanConnectSFTP, "name")},
+	Get: APIEndpointAction{Handler: instanceSFTPHandler, AccessHandler: instanceSFTPAccessHandler, NoStaleReads: true},
 }
 
 var instanceFileCmd = APIEndpoint{
@@ -107,10 +131,10 @@ var instanceFileCmd = APIEndpoint{
 		{Name: "vmFile", Path: "virtual-machines/{name}/files"},
 	},
 
-	Get:    APIEndpointAction{Handler: instanceFileHandler, AccessHandler: allowPermission(entity.TypeInstance, auth.EntitlementCanAccessFiles, "name")},
-	Head:   APIEndpointAction{Handler: instanceFileHandler, AccessHandler: allowPermission(entity.TypeInstance, auth.EntitlementCanAccessFiles, "name")},
-	Post:   APIEndpointAction{Handler: instanceFileHandler, AccessHandler: allowPermission(entity.TypeInstance, auth.EntitlementCanAccessFiles, "name")},
-	Delete: APIEndpointAction{Handler: instanceFileHandler, AccessHandler: allowPermission(entity.TypeInstance, auth.EntitlementCanAccessFiles, "name")},
+	Get:    APIEndpointAction{Handler: instanceFileHandler, AccessHandler: instanceFileReadAccessHandler},
+	Head:   APIEndpointAction{Handler: instanceFileHandler, AccessHandler: instanceFileReadAccessHandler},
+	Post:   APIEndpointAction{Handler: instanceFileHandler, AccessHandler: allowPermission(entity.TypeInstance, auth.EntitlementCanEditFiles, "name")},
+	Delete: APIEndpointAction{Handler: instanceFileHandler, AccessHandler: allowPermission(entity.TypeInstance, auth.EntitlementCanEditFiles, "name")},
 }
 
 var instanceSnapshotsCmd = APIEndpoint{
@@ -164,6 +188,18 @@ var instanceExecCmd = APIEndpoint{
 	Post: APIEndpointAction{Handler: instanceExecPost, AccessHandler: allowPermission(entity.TypeInstance, auth.EntitlementCanExec, "name")},
 }
 
+var instanceDeviceCmd = APIEndpoint{
+	Name: "instanceDevice",
+	Path: "instances/{name}/devices/{deviceName}",
+	Aliases: []APIEndpointAlias{
+		{Name: "containerDevice", Path: "containers/{name}/devices/{deviceName}"},
+		{Name: "vmDevice", Path: "virtual-machines/{name}/devices/{deviceName}"},
+	},
+
+	Post:   APIEndpointAction{Handler: instanceDevicePost, AccessHandler: allowPermission(entity.TypeInstance, auth.EntitlementCanEdit, "name")},
+	Delete: APIEndpointAction{Handler: instanceDeviceDelete, AccessHandler: allowPermission(entity.TypeInstance, auth.EntitlementCanEdit, "name")},
+}
+
 var instanceMetadataCmd = APIEndpoint{
 	Name: "instanceMetadata",
 	Path: "instances/{name}/metadata",
@@ -266,6 +302,13 @@ func instancesStart(s *state.State, instances []instance.Instance) {
 	defer instancesStartMu.Unlock()
 
 	sort.Sort(instanceAutostartList(instances))
+	instances = instancesSortBootAfter(instances)
+
+	// Index the instances by project and name to find the boot dependencies located on this member.
+	local := make(map[string]instance.Instance, len(instances))
+	for _, inst := range instances {
+		local[inst.Project().Name+"/"+inst.Name()] = inst
+	}
 
 	maxAttempts := 3
 
@@ -286,6 +329,8 @@ func instancesStart(s *state.State, instances []instance.Instance) {
 
 		instLogger := logger.AddContext(logger.Ctx{"project": inst.Project().Name, "instance": inst.Name()})
 
+		instanceWaitBootAfter(inst, local, instLogger)
+
 		// Try to start the instance.
 		var attempt = 0
 		for {
@@ -334,6 +379,132 @@ func instancesStart(s *state.State, instances []instance.Instance) {
 	}
 }
 
+// instancesSortBootAfter orders the instances so that each instance comes after the instances of its project listed
+// in its boot.after config key. The original order is kept if the boot dependencies form a cycle.
+func instancesSortBootAfter(instances []instance.Instance) []instance.Instance {
+	names := make([]string, 0, len(instances))
+	byName := make(map[string]instance.Instance, len(instances))
+	after := make(map[string][]string, len(instances))
+
+	for _, inst := range instances {
+		name := inst.Project().Name + "/" + inst.Name()
+		names = append(names, name)
+		byName[name] = inst
+
+		for _, dep := range instance.BootAfter(inst.ExpandedConfig()) {
+			after[name] = append(after[name], inst.Project().Name+"/"+dep)
+		}
+	}
+
+	sorted, err := instance.SortBootAfter(names, after)
+	if err != nil {
+		logger.Warn("Ignoring instance boot dependencies", logger.Ctx{"err": err})
+		return instances
+	}
+
+	result := make([]instance.Instance, 0, len(sorted))
+	for _, name := range sorted {
+		result = append(result, byName[name])
+	}
+
+	return result
+}
+
+// instanceWaitBootAfter waits for the boot dependencies of the instance to be ready according to its
+// boot.after.ready config key. Dependencies that aren't located on this member are skipped with a warning.
+func instanceWaitBootAfter(inst instance.Instance, local map[string]instance.Instance, instLogger logger.Logger) {
+	config := inst.ExpandedConfig()
+
+	deps := instance.BootAfter(config)
+	if len(deps) == 0 {
+		return
+	}
+
+	ready := config["boot.after.ready"]
+	if ready == "" {
+		ready = "running"
+	}
+
+	timeout := 60 * time.Second
+	if config["boot.after.timeout"] != "" {
+		timeoutInt, err := strconv.Atoi(config["boot.after.timeout"])
+		if err == nil {
+			timeout = time.Duration(timeoutInt) * time.Second
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	for _, depName := range deps {
+		dep, ok := local[inst.Project().Name+"/"+depName]
+		if !ok {
+			instLogger.Warn("Not waiting for boot dependency that isn't located on this member", logger.Ctx{"dependency": depName})
+			continue
+		}
+
+		for {
+			if !dep.IsRunning() {
+				instLogger.Warn("Not waiting for boot dependency that isn't running", logger.Ctx{"dependency": depName})
+				break
+			}
+
+			if instanceBootReady(dep, ready) {
+				break
+			}
+
+			if time.Now().After(deadline) {
+				instLogger.Warn("Timed out waiting for boot dependency", logger.Ctx{"dependency": depName, "ready": ready})
+				break
+			}
+
+			time.Sleep(time.Second)
+		}
+	}
+}
+
+// instanceBootReady returns whether the running instance is ready according to a boot.after.ready value.
+func instanceBootReady(inst instance.Instance, ready string) bool {
+	if ready == "running" {
+		return true
+	}
+
+	hostInterfaces, _ := net.Interfaces()
+	state, err := inst.RenderState(hostInterfaces)
+	if err != nil {
+		return false
+	}
+
+	// The instance reported being ready, or the process count is available (from the lxd-agent for VMs).
+	if ready == "agent" {
+		return state.StatusCode == api.Ready || state.Processes >= 0
+	}
+
+	port, found := strings.CutPrefix(ready, "network:")
+	if !found {
+		return false
+	}
+
+	for netName, network := range state.Network {
+		if netName == "lo" {
+			continue
+		}
+
+		for _, addr := range network.Addresses {
+			if addr.Scope != "global" {
+				continue
+			}
+
+			conn, err := net.DialTimeout("tcp", net.JoinHostPort(addr.Address, port), time.Second)
+			if err == nil {
+				_ = conn.Close()
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 type instanceStopList []instance.Instance
 
 func (slice instanceStopList) Len() int {
@@ -217,6 +217,22 @@ func createFromMigration(s *state.State, r *http.Request, projectName string, pr
 		return response.BadRequest(fmt.Errorf("Instance type not supported %q", req.Type))
 	}
 
+	if req.Source.SnapshotsLimit != 0 {
+		return response.BadRequest(fmt.Errorf("Limiting the number of snapshots is only supported when copying on the same server"))
+	}
+
+	// Remove the skipped devices and remap the device volumes before transferring any data.
+	if len(req.Source.SkipDevices) > 0 || len(req.Source.DeviceVolumes) > 0 {
+		if req.Devices == nil {
+			req.Devices = map[string]map[string]string{}
+		}
+
+		err = instanceCopyDevices(s, projectName, req.Devices, req.Source.SkipDevices, req.Source.DeviceVolumes)
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
 	// Prepare the instance creation request.
 	args := db.InstanceArgs{
 		Project:      projectName,
@@ -420,6 +436,10 @@ func createFromCopy(s *state.State, r *http.Request, projectName string, profile
 
 	targetProject := projectName
 
+	if req.Source.SnapshotsLimit < 0 {
+		return response.BadRequest(fmt.Errorf("Invalid snapshots limit %d", req.Source.SnapshotsLimit))
+	}
+
 	source, err := instance.LoadByProjectAndName(s, sourceProject, req.Source.Source)
 	if err != nil {
 		return response.SmartError(err)
@@ -477,6 +497,18 @@ func createFromCopy(s *state.State, r *http.Request, projectName string, profile
 
 		_, exists := req.Config[key]
 		if exists {
+			// Keep the immutable keys of the source on top of the requested ones.
+			if key == "volatile.immutable_keys" {
+				keys := instance.ImmutableKeys(req.Config)
+				for _, sourceKey := range instance.ImmutableKeys(sourceConfig) {
+					if !shared.ValueInSlice(sourceKey, keys) {
+						keys = append(keys, sourceKey)
+					}
+				}
+
+				req.Config[key] = strings.Join(keys, ",")
+			}
+
 			continue
 		}
 
@@ -499,6 +531,14 @@ func createFromCopy(s *state.State, r *http.Request, projectName string, profile
 		req.Devices[key] = value
 	}
 
+	// Remove the skipped devices and remap the device volumes before copying any data.
+	if len(req.Source.SkipDevices) > 0 || len(req.Source.DeviceVolumes) > 0 {
+		err = instanceCopyDevices(s, targetProject, req.Devices, req.Source.SkipDevices, req.Source.DeviceVolumes)
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
 	if req.Stateful {
 		sourceName, _, _ := api.GetParentAndSnapshotName(source.Name())
 		if sourceName != req.Name {
@@ -542,6 +582,9 @@ func createFromCopy(s *state.State, r *http.Request, projectName string, profile
 			refresh:              req.Source.Refresh,
 			applyTemplateTrigger: true,
 			allowInconsistent:    req.Source.AllowInconsistent,
+			snapshotsLimit:       req.Source.SnapshotsLimit,
+			skipDevices:          req.Source.SkipDevices,
+			deviceVolumes:        req.Source.DeviceVolumes,
 		}, op)
 		if err != nil {
 			return err
@@ -907,6 +950,15 @@ func instancesPost(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
+	if len(req.ImmutableKeys) > 0 {
+		req.Config["volatile.immutable_keys"] = strings.Join(req.ImmutableKeys, ",")
+	}
+
+	err = instance.ValidImmutableKeys(instance.ImmutableKeys(req.Config))
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	var targetProject *api.Project
 	var profiles []api.Profile
 	var sourceInst *dbCluster.Instance
@@ -992,6 +1044,13 @@ func instancesPost(d *Daemon, r *http.Request) response.Response {
 				req.Architecture = sourceImage.Architecture
 				req.Profiles = sourceImage.Profiles
 			}
+
+			if req.Source.ApplyManifest {
+				err = instanceApplyImageManifest(sourceImage, &req)
+				if err != nil {
+					return err
+				}
+			}
 		}
 
 		// Use default profile if no profile list specified (not even an empty list).
@@ -1096,6 +1155,18 @@ func instancesPost(d *Daemon, r *http.Request) response.Response {
 				return err
 			}
 
+			// Virtual machines can't make use of personalities so only keep members with a matching architecture.
+			if req.Type == api.InstanceTypeVM && architectures != nil {
+				vmCandidateMembers := make([]db.NodeInfo, 0, len(candidateMembers))
+				for _, member := range candidateMembers {
+					if shared.ValueInSlice(member.Architecture, architectures) {
+						vmCandidateMembers = append(vmCandidateMembers, member)
+					}
+				}
+
+				candidateMembers = vmCandidateMembers
+			}
+
 			return nil
 		}
 
@@ -1179,6 +1250,31 @@ func instancesPost(d *Daemon, r *http.Request) response.Response {
 		return operations.ForwardedOperationResponse(targetProjectName, &opAPI)
 	}
 
+	// Check that the architecture can be used on this member before starting to create the instance.
+	if req.Source.Type != "copy" {
+		architectureName := req.Architecture
+		if sourceImage != nil {
+			architectureName = sourceImage.Architecture
+		}
+
+		if architectureName != "" {
+			architecture, err := osarch.ArchitectureId(architectureName)
+			if err != nil {
+				return response.BadRequest(err)
+			}
+
+			dbType, err := instancetype.New(string(req.Type))
+			if err != nil {
+				return response.BadRequest(err)
+			}
+
+			err = instance.ValidArchitecture(s, dbType, architecture)
+			if err != nil {
+				return response.SmartError(err)
+			}
+		}
+	}
+
 	switch req.Source.Type {
 	case "image":
 		return createFromImage(s, r, *targetProject, profiles, sourceImage, sourceImageRef, &req)
@@ -1271,6 +1367,24 @@ func instanceFindStoragePool(s *state.State, projectName string, req *api.Instan
 func clusterCopyContainerInternal(s *state.State, r *http.Request, source instance.Instance, projectName string, profiles []api.Profile, req *api.InstancesPost) response.Response {
 	name := req.Source.Source
 
+	if req.Source.SnapshotsLimit != 0 {
+		return response.BadRequest(fmt.Errorf("Limiting the number of snapshots isn't supported when copying between cluster members"))
+	}
+
+	// Include the source devices so that they can be skipped or remapped by the migration.
+	if len(req.Source.SkipDevices) > 0 || len(req.Source.DeviceVolumes) > 0 {
+		if req.Devices == nil {
+			req.Devices = map[string]map[string]string{}
+		}
+
+		for devName, dev := range source.LocalDevices().CloneNative() {
+			_, exists := req.Devices[devName]
+			if !exists {
+				req.Devices[devName] = dev
+			}
+		}
+	}
+
 	// Locate the source of the container
 	var nodeAddress string
 	err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
@@ -1357,3 +1471,49 @@ func clusterCopyContainerInternal(s *state.State, r *http.Request, source instan
 	// Run the migration
 	return createFromMigration(s, nil, projectName, profiles, req)
 }
+
+// instanceApplyImageManifest adds the instance configuration embedded in the image to the request.
+// Configuration keys and devices set in the request take precedence over the ones from the image.
+func instanceApplyImageManifest(img *api.Image, req *api.InstancesPost) error {
+	if img == nil {
+		return api.StatusErrorf(http.StatusBadRequest, "The image must be available locally to apply its instance manifest")
+	}
+
+	value, ok := img.Properties[instancetype.ImageManifestProperty]
+	if !ok {
+		return api.StatusErrorf(http.StatusBadRequest, "Image %q has no instance manifest", img.Fingerprint)
+	}
+
+	manifest := api.ImageInstanceManifest{}
+	err := json.Unmarshal([]byte(value), &manifest)
+	if err != nil {
+		return api.StatusErrorf(http.StatusBadRequest, "Invalid instance manifest in image %q: %w", img.Fingerprint, err)
+	}
+
+	// Strip the manifest again in case the image wasn't published by LXD.
+	manifest = instancetype.NewImageManifest(manifest.Config, deviceConfig.NewDevices(manifest.Devices), false)
+
+	if req.Config == nil {
+		req.Config = map[string]string{}
+	}
+
+	for key, value := range manifest.Config {
+		_, ok := req.Config[key]
+		if !ok {
+			req.Config[key] = value
+		}
+	}
+
+	if req.Devices == nil {
+		req.Devices = map[string]map[string]string{}
+	}
+
+	for name, device := range manifest.Devices {
+		_, ok := req.Devices[name]
+		if !ok {
+			req.Devices[name] = device
+		}
+	}
+
+	return nil
+}
@@ -97,6 +97,11 @@ func instancesPut(d *Daemon, r *http.Request) response.Response {
 
 	action := instancetype.InstanceAction(req.State.Action)
 
+	// Thawing filesystems is a recovery action that only applies to individual instances.
+	if action == instancetype.UnfreezeFilesystem {
+		return response.BadRequest(fmt.Errorf("The %q action can only be used on a single instance", action))
+	}
+
 	userHasPermission, err := s.Authorizer.GetPermissionChecker(r.Context(), r, auth.EntitlementCanUpdateState, entity.TypeInstance)
 	if err != nil {
 		return response.SmartError(err)
@@ -158,13 +163,22 @@ func instancesPut(d *Daemon, r *http.Request) response.Response {
 			failuresLock := sync.Mutex{}
 			wgAction := sync.WaitGroup{}
 
+			// Aggregate the lifecycle events of the instances if there are many of them.
+			sources := make([]*api.URL, 0, len(instances))
+			for _, inst := range instances {
+				sources = append(sources, api.NewURL().Path(version.APIVersion, "instances", inst.Name()).Project(inst.Project().Name))
+			}
+
+			batch := s.Events.NewLifecycleBatch(op.URL(), int(s.GlobalConfig.EventsAggregationThreshold()), sources)
+			defer batch.Close()
+
 			for _, inst := range instances {
 				wgAction.Add(1)
 				go func(inst instance.Instance) {
 					defer wgAction.Done()
 
 					inst.SetOperation(op)
-					err := doInstanceStatePut(inst, *req.State)
+					err := doInstanceStatePut(s, inst, *req.State)
 					if err != nil {
 						failuresLock.Lock()
 						failures[inst.Name()] = err
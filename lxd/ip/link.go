@@ -20,6 +20,8 @@ type Link struct {
 	Parent        string
 	Address       net.HardwareAddr
 	TXQueueLength uint32
+	NumTXQueues   uint32
+	NumRXQueues   uint32
 	AllMutlicast  bool
 	Master        string
 	Up            bool
@@ -49,6 +51,14 @@ func (l *Link) args() []string {
 		result = append(result, "txqueuelen", fmt.Sprintf("%d", l.TXQueueLength))
 	}
 
+	if l.NumTXQueues > 0 {
+		result = append(result, "numtxqueues", fmt.Sprintf("%d", l.NumTXQueues))
+	}
+
+	if l.NumRXQueues > 0 {
+		result = append(result, "numrxqueues", fmt.Sprintf("%d", l.NumRXQueues))
+	}
+
 	if l.AllMutlicast {
 		result = append(result, "allmulticast", "on")
 	}
@@ -23,6 +23,7 @@ func (a AuthGroupAction) Event(groupName string, requestor *api.EventLifecycleRe
 	return api.EventLifecycle{
 		Action:    string(a),
 		Source:    u.String(),
+		Name:      groupName,
 		Context:   ctx,
 		Requestor: requestor,
 	}
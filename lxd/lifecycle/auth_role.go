@@ -0,0 +1,28 @@
+package lifecycle
+
+import (
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/version"
+)
+
+// AuthRoleAction represents a lifecycle event action for auth roles.
+type AuthRoleAction string
+
+// All supported lifecycle events for auth roles.
+const (
+	AuthRoleCreated = AuthRoleAction(api.EventLifecycleAuthRoleCreated)
+	AuthRoleUpdated = AuthRoleAction(api.EventLifecycleAuthRoleUpdated)
+	AuthRoleDeleted = AuthRoleAction(api.EventLifecycleAuthRoleDeleted)
+)
+
+// Event creates the lifecycle event for an action on an auth role.
+func (a AuthRoleAction) Event(roleName string, requestor *api.EventLifecycleRequestor, ctx map[string]any) api.EventLifecycle {
+	u := api.NewURL().Path(version.APIVersion, "auth", "roles", roleName)
+
+	return api.EventLifecycle{
+		Action:    string(a),
+		Source:    u.String(),
+		Context:   ctx,
+		Requestor: requestor,
+	}
+}
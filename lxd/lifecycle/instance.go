@@ -1,6 +1,7 @@
 package lifecycle
 
 import (
+	"github.com/canonical/lxd/lxd/db/operationtype"
 	"github.com/canonical/lxd/lxd/operations"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/version"
@@ -27,6 +28,7 @@ const (
 	InstanceReady            = InstanceAction(api.EventLifecycleInstanceReady)
 	InstanceResumed          = InstanceAction(api.EventLifecycleInstanceResumed)
 	InstanceRestored         = InstanceAction(api.EventLifecycleInstanceRestored)
+	InstanceRebuilt          = InstanceAction(api.EventLifecycleInstanceRebuilt)
 	InstanceDeleted          = InstanceAction(api.EventLifecycleInstanceDeleted)
 	InstanceRenamed          = InstanceAction(api.EventLifecycleInstanceRenamed)
 	InstanceUpdated          = InstanceAction(api.EventLifecycleInstanceUpdated)
@@ -44,8 +46,25 @@ func (a InstanceAction) Event(inst instance, ctx map[string]any) api.EventLifecy
 	url := api.NewURL().Path(version.APIVersion, "instances", inst.Name()).Project(inst.Project().Name)
 
 	var requestor *api.EventLifecycleRequestor
-	if inst.Operation() != nil {
-		requestor = inst.Operation().Requestor()
+	op := inst.Operation()
+	if op != nil {
+		requestor = op.Requestor()
+
+		// Distinguish actions triggered by LXD itself from user initiated ones.
+		switch op.Type() {
+		case operationtype.InstanceScheduledStart, operationtype.InstanceScheduledStop:
+			if ctx == nil {
+				ctx = map[string]any{}
+			}
+
+			ctx["scheduled"] = true
+		case operationtype.InstanceFreezeTimeout:
+			if ctx == nil {
+				ctx = map[string]any{}
+			}
+
+			ctx["freeze_timeout"] = true
+		}
 	}
 
 	return api.EventLifecycle{
@@ -0,0 +1,29 @@
+package lifecycle
+
+import (
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/version"
+)
+
+// InstanceExecRecordingAction represents a lifecycle event action for instance exec recordings.
+type InstanceExecRecordingAction string
+
+// All supported lifecycle events for instance exec recordings.
+const (
+	InstanceExecRecordingStarted  = InstanceExecRecordingAction(api.EventLifecycleInstanceExecRecordingStarted)
+	InstanceExecRecordingFinished = InstanceExecRecordingAction(api.EventLifecycleInstanceExecRecordingFinished)
+)
+
+// Event creates the lifecycle event for an action on an instance exec recording.
+func (a InstanceExecRecordingAction) Event(file string, inst instance, requestor *api.EventLifecycleRequestor, ctx map[string]any) api.EventLifecycle {
+	u := api.NewURL().Path(version.APIVersion, "instances", inst.Name(), "logs", "exec", file).Project(inst.Project().Name)
+
+	return api.EventLifecycle{
+		Action:    string(a),
+		Source:    u.String(),
+		Context:   ctx,
+		Requestor: requestor,
+		Name:      inst.Name(),
+		Project:   inst.Project().Name,
+	}
+}
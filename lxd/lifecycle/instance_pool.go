@@ -0,0 +1,29 @@
+package lifecycle
+
+import (
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/version"
+)
+
+// InstancePoolAction represents a lifecycle event action for instance pools.
+type InstancePoolAction string
+
+// All supported lifecycle events for instance pools.
+const (
+	InstancePoolCreated  = InstancePoolAction(api.EventLifecycleInstancePoolCreated)
+	InstancePoolUpdated  = InstancePoolAction(api.EventLifecycleInstancePoolUpdated)
+	InstancePoolDeleted  = InstancePoolAction(api.EventLifecycleInstancePoolDeleted)
+	InstancePoolAcquired = InstancePoolAction(api.EventLifecycleInstancePoolAcquired)
+)
+
+// Event creates the lifecycle event for an action on an instance pool.
+func (a InstancePoolAction) Event(projectName string, poolName string, requestor *api.EventLifecycleRequestor, ctx map[string]any) api.EventLifecycle {
+	u := api.NewURL().Path(version.APIVersion, "instance-pools", poolName).Project(projectName)
+
+	return api.EventLifecycle{
+		Action:    string(a),
+		Source:    u.String(),
+		Context:   ctx,
+		Requestor: requestor,
+	}
+}
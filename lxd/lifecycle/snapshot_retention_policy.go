@@ -0,0 +1,33 @@
+package lifecycle
+
+import (
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/version"
+)
+
+// SnapshotRetentionPolicyAction represents a lifecycle event action for snapshot retention policies.
+type SnapshotRetentionPolicyAction string
+
+// All supported lifecycle events for snapshot retention policies.
+const (
+	SnapshotRetentionPolicyCreated = SnapshotRetentionPolicyAction(api.EventLifecycleSnapshotRetentionPolicyCreated)
+	SnapshotRetentionPolicyUpdated = SnapshotRetentionPolicyAction(api.EventLifecycleSnapshotRetentionPolicyUpdated)
+	SnapshotRetentionPolicyApplied = SnapshotRetentionPolicyAction(api.EventLifecycleSnapshotRetentionPolicyApplied)
+	SnapshotRetentionPolicyDeleted = SnapshotRetentionPolicyAction(api.EventLifecycleSnapshotRetentionPolicyDeleted)
+)
+
+// Event creates the lifecycle event for an action on a snapshot retention policy. The project is empty for a
+// server-wide policy.
+func (a SnapshotRetentionPolicyAction) Event(projectName string, policyName string, requestor *api.EventLifecycleRequestor, ctx map[string]any) api.EventLifecycle {
+	u := api.NewURL().Path(version.APIVersion, "snapshot-retention-policies", policyName)
+	if projectName != "" {
+		u = u.WithQuery("project", projectName)
+	}
+
+	return api.EventLifecycle{
+		Action:    string(a),
+		Source:    u.String(),
+		Context:   ctx,
+		Requestor: requestor,
+	}
+}
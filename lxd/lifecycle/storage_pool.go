@@ -10,9 +10,11 @@ type StoragePoolAction string
 
 // All supported lifecycle events for storage pools.
 const (
-	StoragePoolCreated = StoragePoolAction(api.EventLifecycleStoragePoolCreated)
-	StoragePoolDeleted = StoragePoolAction(api.EventLifecycleStoragePoolDeleted)
-	StoragePoolUpdated = StoragePoolAction(api.EventLifecycleStoragePoolUpdated)
+	StoragePoolCreated     = StoragePoolAction(api.EventLifecycleStoragePoolCreated)
+	StoragePoolDeleted     = StoragePoolAction(api.EventLifecycleStoragePoolDeleted)
+	StoragePoolUpdated     = StoragePoolAction(api.EventLifecycleStoragePoolUpdated)
+	StoragePoolUnavailable = StoragePoolAction(api.EventLifecycleStoragePoolUnavailable)
+	StoragePoolAvailable   = StoragePoolAction(api.EventLifecycleStoragePoolAvailable)
 )
 
 // Event creates the lifecycle event for an action on an storage pool.
@@ -136,11 +136,18 @@ func (c *cmdActivateifneeded) Run(cmd *cobra.Command, args []string) error {
 
 		// Check for scheduled instance snapshots
 		config := inst.ExpandedConfig()
-		if config["snapshots.schedule"] != "" {
+		if config["snapshots.schedule"] != "" || len(instancetype.SnapshotScheduleNames(config)) > 0 {
 			logger.Debugf("Daemon has scheduled instance snapshots, activating...")
 			_, err := lxd.ConnectLXDUnix("", nil)
 			return err
 		}
+
+		// Check for scheduled instance starts
+		if config["boot.schedule.start"] != "" {
+			logger.Debugf("Daemon has scheduled instance starts, activating...")
+			_, err := lxd.ConnectLXDUnix("", nil)
+			return err
+		}
 	}
 
 	// Check for scheduled volume snapshots
@@ -85,6 +85,7 @@ void forkfile(void)
 import "C"
 
 import (
+	"fmt"
 	"net"
 	"os"
 	"os/signal"
@@ -104,7 +105,7 @@ type cmdForkfile struct {
 func (c *cmdForkfile) Command() *cobra.Command {
 	// Main subcommand
 	cmd := &cobra.Command{}
-	cmd.Use = "forkfile <listen fd> <rootfs fd> <PIDFd> <PID>"
+	cmd.Use = "forkfile <listen fd> <rootfs fd> <PIDFd> <PID> [read-only]"
 	cmd.Short = "Perform container file operations"
 	cmd.Long = `Description:
   Perform container file operations
@@ -115,9 +116,11 @@ func (c *cmdForkfile) Command() *cobra.Command {
 
   The command can be called with PID and PIDFd set to 0 to just operate on the rootfs fd.
   In such cases, it's the responsibility of the caller to handle any kind of userns shifting.
+
+  If the optional read-only argument is passed, the SFTP server rejects any write operation.
 `
 	cmd.Hidden = true
-	cmd.Args = cobra.ExactArgs(4)
+	cmd.Args = cobra.RangeArgs(4, 5)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -149,6 +152,16 @@ func (c *cmdForkfile) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// Check for read-only mode.
+	serverOptions := []sftp.ServerOption{}
+	if len(args) > 4 {
+		if args[4] != "read-only" {
+			return fmt.Errorf("Invalid mode %q", args[4])
+		}
+
+		serverOptions = append(serverOptions, sftp.ReadOnly())
+	}
+
 	// Automatically shutdown after inactivity.
 	go func() {
 		for {
@@ -229,7 +242,7 @@ func (c *cmdForkfile) Run(cmd *cobra.Command, args []string) error {
 			mu.Unlock()
 
 			// Spawn the server.
-			server, err := sftp.NewServer(conn)
+			server, err := sftp.NewServer(conn, serverOptions...)
 			if err != nil {
 				return
 			}
@@ -110,6 +110,9 @@ func (m *MetricSet) String() string {
 		GoHeapObjects,
 		Containers,
 		VMs,
+		InstancePoolInstances,
+		InstancePoolSize,
+		StoragePoolCreateQueued,
 	}
 
 	for _, metricType := range metricTypes {
@@ -148,6 +148,26 @@ const (
 	Containers
 	// VMs represents the VM count.
 	VMs
+	// DatabaseSizeBytes represents the size of the global database on disk.
+	DatabaseSizeBytes
+	// InstancePoolInstances represents the number of instances available in an instance pool.
+	InstancePoolInstances
+	// InstancePoolSize represents the number of instances that an instance pool keeps available.
+	InstancePoolSize
+	// InstancePoolAcquiresTotal represents the number of instances acquired from an instance pool.
+	InstancePoolAcquiresTotal
+	// InstancePoolAcquireSeconds represents the total time spent acquiring instances from an instance pool.
+	InstancePoolAcquireSeconds
+	// StoragePoolCreateQueued represents the number of instance volume creations waiting for a slot on a storage pool.
+	StoragePoolCreateQueued
+	// EventsDroppedTotal represents the number of events that weren't delivered to a slow event listener.
+	EventsDroppedTotal
+	// ImageDeltaHitsTotal represents the number of image transfers that used a binary delta.
+	ImageDeltaHitsTotal
+	// ImageDeltaMissesTotal represents the number of image transfers that offered delta sources but transferred the full image.
+	ImageDeltaMissesTotal
+	// ImageDeltaSavedBytesTotal represents the number of bytes that image transfers didn't transfer thanks to binary deltas.
+	ImageDeltaSavedBytesTotal
 )
 
 // MetricNames associates a metric type to its name.
@@ -218,6 +238,16 @@ var MetricNames = map[MetricType]string{
 	WarningsTotal:               "lxd_warnings_total",
 	Containers:                  "lxd_containers",
 	VMs:                         "lxd_vms",
+	DatabaseSizeBytes:           "lxd_database_size_bytes",
+	InstancePoolInstances:       "lxd_instance_pool_instances",
+	InstancePoolSize:            "lxd_instance_pool_size",
+	InstancePoolAcquiresTotal:   "lxd_instance_pool_acquires_total",
+	InstancePoolAcquireSeconds:  "lxd_instance_pool_acquire_seconds_total",
+	StoragePoolCreateQueued:     "lxd_storage_pool_create_queued",
+	EventsDroppedTotal:          "lxd_events_dropped_total",
+	ImageDeltaHitsTotal:         "lxd_image_delta_hits_total",
+	ImageDeltaMissesTotal:       "lxd_image_delta_misses_total",
+	ImageDeltaSavedBytesTotal:   "lxd_image_delta_saved_bytes_total",
 }
 
 // MetricHeaders represents the metric headers which contain help messages as specified by OpenMetrics.
@@ -288,4 +318,14 @@ var MetricHeaders = map[MetricType]string{
 	WarningsTotal:               "# HELP lxd_warnings_total The number of active warnings.",
 	Containers:                  "# HELP lxd_containers The number of containers.",
 	VMs:                         "# HELP lxd_vms The number of virtual machines.",
+	DatabaseSizeBytes:           "# HELP lxd_database_size_bytes The size of the global database on disk in bytes.",
+	InstancePoolInstances:       "# HELP lxd_instance_pool_instances The number of instances available in the instance pool on the member.",
+	InstancePoolSize:            "# HELP lxd_instance_pool_size The number of instances that the instance pool keeps available.",
+	InstancePoolAcquiresTotal:   "# HELP lxd_instance_pool_acquires_total The number of instances acquired from the instance pool on the member.",
+	InstancePoolAcquireSeconds:  "# HELP lxd_instance_pool_acquire_seconds_total The total time spent acquiring instances from the instance pool on the member in seconds.",
+	StoragePoolCreateQueued:     "# HELP lxd_storage_pool_create_queued The number of instance volume creations queued on the storage pool on the member.",
+	EventsDroppedTotal:          "# HELP lxd_events_dropped_total The number of events that weren't delivered to an event listener because its queue was full.",
+	ImageDeltaHitsTotal:         "# HELP lxd_image_delta_hits_total The number of image transfers to the member that used a binary delta against a previous version of the image.",
+	ImageDeltaMissesTotal:       "# HELP lxd_image_delta_misses_total The number of image transfers to the member that offered previous versions of the image but transferred the full image.",
+	ImageDeltaSavedBytesTotal:   "# HELP lxd_image_delta_saved_bytes_total The number of bytes that image transfers to the member didn't transfer thanks to binary deltas.",
 }
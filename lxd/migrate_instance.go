@@ -119,12 +119,13 @@ func (s *migrationSourceWs) Do(state *state.State, migrateOp *operations.Operati
 			return nil, fmt.Errorf("Migration source filesystem connection not initialized")
 		}
 
-		wsConn, err := conn.WebsocketIO(ctx)
+		// Allow the filesystem transfer to be resumed over a new connection if the transport fails.
+		resumableConn, err := newMigrationResumableConn(ctx, conn, int(state.GlobalConfig.InstancesMigrationTransferRetries()))
 		if err != nil {
 			return nil, fmt.Errorf("Failed getting migration source filesystem connection: %w", err)
 		}
 
-		return wsConn, nil
+		return resumableConn, nil
 	}
 
 	s.instance.SetOperation(migrateOp)
@@ -247,12 +248,13 @@ func (c *migrationSink) Do(state *state.State, instOp *operationlock.InstanceOpe
 			return nil, fmt.Errorf("Migration target filesystem connection not initialized")
 		}
 
-		wsConn, err := conn.WebsocketIO(ctx)
+		// Allow the filesystem transfer to be resumed over a new connection if the transport fails.
+		resumableConn, err := newMigrationResumableConn(ctx, conn, int(state.GlobalConfig.InstancesMigrationTransferRetries()))
 		if err != nil {
 			return nil, fmt.Errorf("Failed getting migration target filesystem connection: %w", err)
 		}
 
-		return wsConn, nil
+		return resumableConn, nil
 	}
 
 	err = c.instance.MigrateReceive(instance.MigrateReceiveArgs{
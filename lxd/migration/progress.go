@@ -0,0 +1,218 @@
+package migration
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/canonical/lxd/lxd/operations"
+	"github.com/canonical/lxd/shared/units"
+)
+
+// Phases of a live migration, as reported in the progress of the migration operation.
+const (
+	// ProgressPhaseDiskSync is the transfer of the root disk while the instance is running.
+	ProgressPhaseDiskSync = "disk_sync"
+
+	// ProgressPhaseMemoryPrecopy is the transfer of the memory while the instance is running.
+	ProgressPhaseMemoryPrecopy = "memory_precopy"
+
+	// ProgressPhaseFinalSync is the transfer of the remaining disk writes, memory and device state while the
+	// instance is paused.
+	ProgressPhaseFinalSync = "final_sync"
+)
+
+// ProgressMetadataKey is the key of the operation metadata holding the structured progress of a live migration.
+const ProgressMetadataKey = "migration"
+
+// ProgressTextMetadataKey is the key of the operation metadata holding the progress of a live migration as text.
+const ProgressTextMetadataKey = "migration_progress"
+
+// progressInterval is the minimum interval between two updates of the operation metadata.
+const progressInterval = time.Second
+
+var progressPhaseDescriptions = map[string]string{
+	ProgressPhaseDiskSync:      "Syncing disk",
+	ProgressPhaseMemoryPrecopy: "Copying memory",
+	ProgressPhaseFinalSync:     "Final sync",
+}
+
+// Progress reports the progress of a live migration in the metadata of an operation.
+type Progress struct {
+	op *operations.Operation
+
+	mu         sync.Mutex
+	phase      string
+	phaseStart time.Time
+	phaseBytes int64 // Bytes transferred since the start of the phase.
+	expected   int64 // Bytes expected to be transferred in the phase, zero if unknown.
+	remaining  int64 // Bytes left to transfer in the phase, -1 if unknown.
+	bytes      int64 // Bytes transferred since the start of the migration.
+	lastUpdate time.Time
+}
+
+// NewProgress returns a new Progress reporting in the metadata of the operation. The operation may be nil, in
+// which case the progress is tracked but not reported.
+func NewProgress(op *operations.Operation) *Progress {
+	return &Progress{op: op, remaining: -1}
+}
+
+// Phase starts a new phase of the migration. The expected number of bytes to transfer in the phase is used to
+// estimate the remaining time, and can be zero if unknown.
+func (p *Progress) Phase(phase string, expected int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.phase = phase
+	p.phaseStart = time.Now()
+	p.phaseBytes = 0
+	p.expected = expected
+	p.remaining = -1
+	if expected > 0 {
+		p.remaining = expected
+	}
+
+	p.update(true)
+}
+
+// Add records that n bytes were transferred.
+func (p *Progress) Add(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.bytes += n
+	p.phaseBytes += n
+	if p.expected > 0 {
+		p.remaining = p.expected - p.phaseBytes
+		if p.remaining < 0 {
+			p.remaining = 0
+		}
+	}
+
+	p.update(false)
+}
+
+// SetRemaining records the number of bytes left to transfer in the current phase, as reported by the transfer
+// itself rather than estimated from the expected size of the phase.
+func (p *Progress) SetRemaining(remaining int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.expected = 0
+	p.remaining = remaining
+
+	p.update(false)
+}
+
+// Conn returns a connection wrapping conn that records the bytes read from and written to it as transferred.
+func (p *Progress) Conn(conn io.ReadWriteCloser) io.ReadWriteCloser {
+	return &progressConn{ReadWriteCloser: conn, progress: p}
+}
+
+// Metadata returns the structured progress of the migration, as stored in the operation metadata.
+// The transfer rate is the average rate of the current phase in bytes per second. The remaining time is an
+// estimate for the current phase in seconds, or -1 if unknown.
+func (p *Progress) Metadata() map[string]any {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.metadata()
+}
+
+func (p *Progress) metadata() map[string]any {
+	return map[string]any{
+		"phase":             p.phase,
+		"bytes_transferred": p.bytes,
+		"transfer_rate":     p.rate(),
+		"remaining_seconds": p.remainingSeconds(),
+	}
+}
+
+// rate returns the average transfer rate of the current phase in bytes per second.
+func (p *Progress) rate() int64 {
+	duration := time.Since(p.phaseStart).Seconds()
+	if duration <= 0 {
+		return 0
+	}
+
+	return int64(float64(p.phaseBytes) / duration)
+}
+
+// remainingSeconds returns the estimated remaining time of the current phase in seconds, or -1 if unknown.
+func (p *Progress) remainingSeconds() int64 {
+	rate := p.rate()
+	if p.remaining < 0 || rate <= 0 {
+		return -1
+	}
+
+	return p.remaining / rate
+}
+
+// text returns the progress of the migration as text, as rendered by the CLI.
+func (p *Progress) text() string {
+	description, ok := progressPhaseDescriptions[p.phase]
+	if !ok {
+		description = p.phase
+	}
+
+	text := fmt.Sprintf("%s: %s (%s/s", description, units.GetByteSizeString(p.bytes, 2), units.GetByteSizeString(p.rate(), 2))
+
+	remaining := p.remainingSeconds()
+	if remaining >= 0 {
+		text = fmt.Sprintf("%s, %s remaining", text, time.Duration(remaining)*time.Second)
+	}
+
+	return text + ")"
+}
+
+// update writes the progress to the operation metadata, at most once per progressInterval unless forced.
+// Must be called with the lock held.
+func (p *Progress) update(force bool) {
+	if p.op == nil || p.phase == "" {
+		return
+	}
+
+	if !force && time.Since(p.lastUpdate) < progressInterval {
+		return
+	}
+
+	p.lastUpdate = time.Now()
+
+	// Copy the metadata, as it may be read concurrently to relay the progress to another operation.
+	meta := make(map[string]any, len(p.op.Metadata())+2)
+	for k, v := range p.op.Metadata() {
+		meta[k] = v
+	}
+
+	meta[ProgressMetadataKey] = p.metadata()
+	meta[ProgressTextMetadataKey] = p.text()
+	_ = p.op.UpdateMetadata(meta)
+}
+
+// progressConn records the bytes read from and written to a connection in a Progress.
+type progressConn struct {
+	io.ReadWriteCloser
+
+	progress *Progress
+}
+
+// Read reads from the connection and records the bytes read.
+func (c *progressConn) Read(b []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Read(b)
+	if n > 0 {
+		c.progress.Add(int64(n))
+	}
+
+	return n, err
+}
+
+// Write writes to the connection and records the bytes written.
+func (c *progressConn) Write(b []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Write(b)
+	if n > 0 {
+		c.progress.Add(int64(n))
+	}
+
+	return n, err
+}
@@ -0,0 +1,72 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/canonical/lxd/lxd/operations"
+	"github.com/canonical/lxd/shared/logger"
+)
+
+// ResumeTimeout is the maximum amount of time to wait for the transport of a ResumableConn to be re-established.
+const ResumeTimeout = 30 * time.Second
+
+// ResumableConn is a migration connection whose transport can be re-established after a failure, so that a transfer
+// can be resumed rather than restarted from scratch.
+type ResumableConn interface {
+	io.ReadWriteCloser
+
+	// Reconnect closes the current transport and waits until a new one is established.
+	Reconnect(ctx context.Context) error
+
+	// Retries returns the number of times that a failed transfer may be retried.
+	Retries() int
+
+	// Transferred returns the number of bytes read from and written to the connection over all its transports.
+	Transferred() int64
+}
+
+// RetryTransfer runs the given transfer over conn. If the transfer fails and conn is a ResumableConn, its transport is
+// re-established and the transfer is run again, up to the number of retries allowed by the connection.
+//
+// The transfer must resume from the data that is already present on the target when it is run again. For example,
+// rsync keeps partially transferred files, and each snapshot is transferred by its own transfer so that snapshots
+// received before the failure are not transferred again. Both sides of the migration retry the same transfer.
+//
+// The attempt number and the number of bytes that were transferred before resuming are recorded in the operation
+// metadata under "fs_transfer_attempt" and "fs_resumed_bytes".
+func RetryTransfer(op *operations.Operation, conn io.ReadWriteCloser, name string, transfer func() error) error {
+	resumableConn, ok := conn.(ResumableConn)
+	if !ok {
+		return transfer()
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := transfer()
+		if err == nil || attempt > resumableConn.Retries() {
+			return err
+		}
+
+		logger.Warn("Migration transfer failed, resuming over a new connection", logger.Ctx{"name": name, "attempt": attempt, "err": err})
+
+		ctx, cancel := context.WithTimeout(context.Background(), ResumeTimeout)
+		reconnectErr := resumableConn.Reconnect(ctx)
+		cancel()
+		if reconnectErr != nil {
+			return fmt.Errorf("%w (failed re-establishing migration connection: %v)", err, reconnectErr)
+		}
+
+		if op != nil {
+			meta := op.Metadata()
+			if meta == nil {
+				meta = make(map[string]any)
+			}
+
+			meta["fs_transfer_attempt"] = attempt + 1
+			meta["fs_resumed_bytes"] = resumableConn.Transferred()
+			_ = op.UpdateMetadata(meta)
+		}
+	}
+}
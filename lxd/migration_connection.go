@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -162,6 +163,24 @@ func (c *migrationConn) WebsocketIO(ctx context.Context) (io.ReadWriteCloser, er
 	return ws.NewWrapper(wsConn), nil
 }
 
+// Reset closes the connection (if established) so that a new one can be established by WebSocket or AcceptIncoming.
+func (c *migrationConn) Reset() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.disconnected {
+		return fmt.Errorf("Connection already disconnected")
+	}
+
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+		c.connected = make(chan struct{})
+	}
+
+	return nil
+}
+
 // Close closes the connection (if established) and marks it as disconnected so that it cannot be used again.
 func (c *migrationConn) Close() {
 	c.mu.Lock()
@@ -174,3 +193,88 @@ func (c *migrationConn) Close() {
 		c.conn = nil
 	}
 }
+
+// newMigrationResumableConn returns a migration.ResumableConn for the given migration connection, which must be
+// established already. A failed transfer over the connection may be retried the given number of times.
+func newMigrationResumableConn(ctx context.Context, conn *migrationConn, retries int) (*migrationResumableConn, error) {
+	rwc, err := conn.WebsocketIO(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &migrationResumableConn{conn: conn, rwc: rwc, retries: retries}, nil
+}
+
+// migrationResumableConn wraps a migration connection so that its transport can be re-established after a failure.
+type migrationResumableConn struct {
+	conn        *migrationConn
+	retries     int
+	transferred atomic.Int64
+
+	mu  sync.Mutex
+	rwc io.ReadWriteCloser
+}
+
+func (c *migrationResumableConn) current() io.ReadWriteCloser {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.rwc
+}
+
+// Read reads from the current transport.
+func (c *migrationResumableConn) Read(p []byte) (int, error) {
+	n, err := c.current().Read(p)
+	c.transferred.Add(int64(n))
+
+	return n, err
+}
+
+// Write writes to the current transport.
+func (c *migrationResumableConn) Write(p []byte) (int, error) {
+	n, err := c.current().Write(p)
+	c.transferred.Add(int64(n))
+
+	return n, err
+}
+
+// Close closes the current transport.
+func (c *migrationResumableConn) Close() error {
+	return c.current().Close()
+}
+
+// Reconnect closes the current transport and waits until a new one is established. Outgoing connections are retried
+// until the context is done, as the other side may not have released its previous connection yet.
+func (c *migrationResumableConn) Reconnect(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for {
+		err := c.conn.Reset()
+		if err != nil {
+			return err
+		}
+
+		rwc, err := c.conn.WebsocketIO(ctx)
+		if err == nil {
+			c.rwc = rwc
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("Failed re-establishing migration connection: %w", err)
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// Retries returns the number of times that a failed transfer may be retried.
+func (c *migrationResumableConn) Retries() int {
+	return c.retries
+}
+
+// Transferred returns the number of bytes read from and written to the connection over all its transports.
+func (c *migrationResumableConn) Transferred() int64 {
+	return c.transferred.Load()
+}
@@ -0,0 +1,77 @@
+package dhcpext
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/canonical/lxd/lxd/util"
+)
+
+// Reservation is the DHCP reservation and DNS records of an instance NIC.
+type Reservation struct {
+	Project  string `json:"project"`
+	Instance string `json:"instance"`
+	Device   string `json:"device"`
+	Network  string `json:"network"`
+
+	// Hostname of the instance, and fully qualified domain name using the domain of the network.
+	Hostname string `json:"hostname"`
+	FQDN     string `json:"fqdn"`
+
+	// MAC address of the NIC.
+	HWAddr string `json:"hwaddr"`
+
+	// Static addresses of the NIC, if any. The external system allocates addresses for those that aren't set.
+	IPv4Address string `json:"ipv4_address,omitempty"`
+	IPv6Address string `json:"ipv6_address,omitempty"`
+}
+
+// Allocation is what the external system recorded for a reservation.
+type Allocation struct {
+	// Addresses allocated to the NIC, if known.
+	Addresses []string `json:"addresses,omitempty"`
+
+	// Reference of the reservation in the external system, passed back when unregistering it.
+	Reference string `json:"reference,omitempty"`
+}
+
+// Backend registers the reservations of instance NICs with an external DHCP and DNS system.
+type Backend interface {
+	// Register registers the DHCP reservation and the DNS records of the NIC.
+	Register(ctx context.Context, r Reservation) (*Allocation, error)
+
+	// Unregister removes the DHCP reservation and the DNS records of the NIC.
+	Unregister(ctx context.Context, r Reservation, a Allocation) error
+}
+
+// requestTimeout is the time allowed for each request to the external system.
+const requestTimeout = 30 * time.Second
+
+// Backends lists the supported backends.
+var Backends = []string{"webhook", "kea"}
+
+// Load returns the backend configured by the dhcp.external.* keys of the network config.
+func Load(config map[string]string, proxy func(req *http.Request) (*url.URL, error)) (Backend, error) {
+	if config["dhcp.external.url"] == "" {
+		return nil, fmt.Errorf(`No "dhcp.external.url" set for external DHCP`)
+	}
+
+	client, err := util.HTTPClient("", proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Timeout = requestTimeout
+
+	switch config["dhcp.external.backend"] {
+	case "", "webhook":
+		return &webhook{client: client, url: config["dhcp.external.url"]}, nil
+	case "kea":
+		return newKea(client, config)
+	}
+
+	return nil, fmt.Errorf("Unknown external DHCP backend %q", config["dhcp.external.backend"])
+}
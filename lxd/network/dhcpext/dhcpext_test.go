@@ -0,0 +1,129 @@
+package dhcpext
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	cases := []struct {
+		name   string
+		config map[string]string
+		valid  bool
+	}{
+		{"No URL", map[string]string{}, false},
+		{"Default backend", map[string]string{"dhcp.external.url": "http://localhost"}, true},
+		{"Unknown backend", map[string]string{"dhcp.external.url": "http://localhost", "dhcp.external.backend": "foo"}, false},
+		{"Kea without subnet", map[string]string{"dhcp.external.url": "http://localhost", "dhcp.external.backend": "kea"}, false},
+		{"Kea", map[string]string{"dhcp.external.url": "http://localhost", "dhcp.external.backend": "kea", "dhcp.external.kea.ipv4.subnet_id": "1"}, true},
+		{"Kea DNS without zone", map[string]string{"dhcp.external.url": "http://localhost", "dhcp.external.backend": "kea", "dhcp.external.kea.ipv4.subnet_id": "1", "dhcp.external.rfc2136.server": "127.0.0.1:53"}, false},
+		{"Kea invalid TSIG key", map[string]string{"dhcp.external.url": "http://localhost", "dhcp.external.backend": "kea", "dhcp.external.kea.ipv4.subnet_id": "1", "dhcp.external.rfc2136.server": "127.0.0.1:53", "dhcp.external.rfc2136.zone": "lxd.example.net", "dhcp.external.rfc2136.tsig_key": "foo"}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := Load(c.config, nil)
+			if c.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var requests []webhookRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req webhookRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		require.NoError(t, err)
+
+		requests = append(requests, req)
+
+		if req.Reservation.Instance == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if req.Action == "register" {
+			_ = json.NewEncoder(w).Encode(Allocation{Addresses: []string{"10.0.0.2"}, Reference: "ref1"})
+		}
+	}))
+	defer server.Close()
+
+	backend, err := Load(map[string]string{"dhcp.external.url": server.URL}, nil)
+	require.NoError(t, err)
+
+	r := Reservation{Project: "default", Instance: "c1", Device: "eth0", Network: "lxdbr0", Hostname: "c1", FQDN: "c1.lxd", HWAddr: "00:16:3e:00:00:01"}
+
+	allocation, err := backend.Register(context.Background(), r)
+	require.NoError(t, err)
+	assert.Equal(t, &Allocation{Addresses: []string{"10.0.0.2"}, Reference: "ref1"}, allocation)
+
+	err = backend.Unregister(context.Background(), r, *allocation)
+	require.NoError(t, err)
+
+	require.Len(t, requests, 2)
+	assert.Equal(t, webhookRequest{Action: "register", Reservation: r}, requests[0])
+	assert.Equal(t, webhookRequest{Action: "unregister", Reservation: r, Allocation: allocation}, requests[1])
+
+	r.Instance = "fail"
+	_, err = backend.Register(context.Background(), r)
+	assert.Error(t, err)
+}
+
+func TestKea(t *testing.T) {
+	var commands []keaCommand
+	result := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var command keaCommand
+		err := json.NewDecoder(r.Body).Decode(&command)
+		require.NoError(t, err)
+
+		commands = append(commands, command)
+		_ = json.NewEncoder(w).Encode([]keaResult{{Result: result, Text: "result"}})
+	}))
+	defer server.Close()
+
+	backend, err := Load(map[string]string{
+		"dhcp.external.url":                server.URL,
+		"dhcp.external.backend":            "kea",
+		"dhcp.external.kea.ipv4.subnet_id": "1",
+		"dhcp.external.kea.ipv6.subnet_id": "2",
+	}, nil)
+	require.NoError(t, err)
+
+	r := Reservation{Hostname: "c1", HWAddr: "00:16:3e:00:00:01", IPv4Address: "10.0.0.2"}
+
+	allocation, err := backend.Register(context.Background(), r)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.2"}, allocation.Addresses)
+
+	require.Len(t, commands, 2)
+	assert.Equal(t, "reservation-add", commands[0].Command)
+	assert.Equal(t, []string{"dhcp4"}, commands[0].Service)
+	assert.Equal(t, "10.0.0.2", commands[0].Arguments["reservation"].(map[string]any)["ip-address"])
+	assert.Equal(t, []string{"dhcp6"}, commands[1].Service)
+	assert.NotContains(t, commands[1].Arguments["reservation"], "ip-addresses")
+
+	// Removing a reservation that doesn't exist succeeds.
+	result = keaResultEmpty
+	err = backend.Unregister(context.Background(), r, *allocation)
+	require.NoError(t, err)
+
+	require.Len(t, commands, 4)
+	assert.Equal(t, "reservation-del", commands[2].Command)
+	assert.Equal(t, r.HWAddr, commands[2].Arguments["identifier"])
+
+	// But adding one fails on errors.
+	result = 1
+	_, err = backend.Register(context.Background(), r)
+	assert.Error(t, err)
+}
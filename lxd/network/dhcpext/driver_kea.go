@@ -0,0 +1,272 @@
+package dhcpext
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// keaResultEmpty is the result returned by the Kea control agent when the object of a command doesn't exist.
+const keaResultEmpty = 3
+
+// keaCommand is a command sent to the Kea control agent.
+type keaCommand struct {
+	Command   string         `json:"command"`
+	Service   []string       `json:"service"`
+	Arguments map[string]any `json:"arguments"`
+}
+
+// keaResult is the result of a command for one service of the Kea control agent.
+type keaResult struct {
+	Result int    `json:"result"`
+	Text   string `json:"text"`
+}
+
+// kea registers DHCP reservations through the host_cmds hook of the Kea control agent, and DNS records through
+// RFC2136 dynamic updates if a DNS server is configured.
+type kea struct {
+	client *http.Client
+	url    string
+
+	// Kea subnet IDs of the network, reservations are only registered for the configured address families.
+	subnetIDv4 int64
+	subnetIDv6 int64
+
+	// RFC2136 DNS server (host:port), zone and TSIG key.
+	dnsServer     string
+	dnsZone       string
+	dnsTSIGName   string
+	dnsTSIGSecret string
+}
+
+func newKea(client *http.Client, config map[string]string) (*kea, error) {
+	k := &kea{
+		client:    client,
+		url:       config["dhcp.external.url"],
+		dnsServer: config["dhcp.external.rfc2136.server"],
+		dnsZone:   dns.Fqdn(config["dhcp.external.rfc2136.zone"]),
+	}
+
+	for key, subnetID := range map[string]*int64{"dhcp.external.kea.ipv4.subnet_id": &k.subnetIDv4, "dhcp.external.kea.ipv6.subnet_id": &k.subnetIDv6} {
+		if config[key] == "" {
+			continue
+		}
+
+		id, err := strconv.ParseInt(config[key], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %q: %w", key, err)
+		}
+
+		*subnetID = id
+	}
+
+	if k.subnetIDv4 == 0 && k.subnetIDv6 == 0 {
+		return nil, fmt.Errorf(`One of "dhcp.external.kea.ipv4.subnet_id" or "dhcp.external.kea.ipv6.subnet_id" must be set for the Kea backend`)
+	}
+
+	if k.dnsServer != "" {
+		if config["dhcp.external.rfc2136.zone"] == "" {
+			return nil, fmt.Errorf(`"dhcp.external.rfc2136.zone" must be set with "dhcp.external.rfc2136.server"`)
+		}
+
+		tsigKey := config["dhcp.external.rfc2136.tsig_key"]
+		if tsigKey != "" {
+			name, secret, ok := strings.Cut(tsigKey, ":")
+			if !ok || name == "" || secret == "" {
+				return nil, fmt.Errorf(`Invalid "dhcp.external.rfc2136.tsig_key", expected "<name>:<secret>"`)
+			}
+
+			k.dnsTSIGName = dns.Fqdn(name)
+			k.dnsTSIGSecret = secret
+		}
+	}
+
+	return k, nil
+}
+
+// Register adds the DHCP reservations of the NIC to Kea and its DNS records to the DNS server.
+func (k *kea) Register(ctx context.Context, r Reservation) (*Allocation, error) {
+	allocation := &Allocation{}
+
+	if k.subnetIDv4 != 0 {
+		reservation := map[string]any{
+			"subnet-id":  k.subnetIDv4,
+			"hw-address": r.HWAddr,
+			"hostname":   r.Hostname,
+		}
+
+		if r.IPv4Address != "" {
+			reservation["ip-address"] = r.IPv4Address
+			allocation.Addresses = append(allocation.Addresses, r.IPv4Address)
+		}
+
+		err := k.send(ctx, "reservation-add", "dhcp4", map[string]any{"reservation": reservation}, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if k.subnetIDv6 != 0 {
+		reservation := map[string]any{
+			"subnet-id":  k.subnetIDv6,
+			"hw-address": r.HWAddr,
+			"hostname":   r.Hostname,
+		}
+
+		if r.IPv6Address != "" {
+			reservation["ip-addresses"] = []string{r.IPv6Address}
+			allocation.Addresses = append(allocation.Addresses, r.IPv6Address)
+		}
+
+		err := k.send(ctx, "reservation-add", "dhcp6", map[string]any{"reservation": reservation}, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := k.updateDNS(r, allocation.Addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	return allocation, nil
+}
+
+// Unregister removes the DHCP reservations of the NIC from Kea and its DNS records from the DNS server.
+func (k *kea) Unregister(ctx context.Context, r Reservation, a Allocation) error {
+	services := []struct {
+		name     string
+		subnetID int64
+	}{{"dhcp4", k.subnetIDv4}, {"dhcp6", k.subnetIDv6}}
+
+	for _, service := range services {
+		if service.subnetID == 0 {
+			continue
+		}
+
+		args := map[string]any{
+			"subnet-id":       service.subnetID,
+			"identifier-type": "hw-address",
+			"identifier":      r.HWAddr,
+		}
+
+		err := k.send(ctx, "reservation-del", service.name, args, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	return k.updateDNS(r, nil)
+}
+
+// send sends a command for a service to the Kea control agent. If allowEmpty is true, commands whose object doesn't
+// exist are considered successful.
+func (k *kea) send(ctx context.Context, command string, service string, args map[string]any, allowEmpty bool) error {
+	buf, err := json.Marshal(keaCommand{Command: command, Service: []string{service}, Arguments: args})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, k.url, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Failed sending %q command to Kea: %w", command, err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Kea %q command failed with status %q: %s", command, resp.Status, bytes.TrimSpace(body))
+	}
+
+	var results []keaResult
+	err = json.Unmarshal(body, &results)
+	if err != nil {
+		return fmt.Errorf("Invalid response to Kea %q command: %w", command, err)
+	}
+
+	for _, result := range results {
+		if result.Result == 0 || (allowEmpty && result.Result == keaResultEmpty) {
+			continue
+		}
+
+		return fmt.Errorf("Kea %q command failed for %q: %s", command, service, result.Text)
+	}
+
+	return nil
+}
+
+// updateDNS replaces the address records of the NIC in the DNS zone with records for the given addresses, or removes
+// them if there are none. It does nothing if no DNS server is configured.
+func (k *kea) updateDNS(r Reservation, addresses []string) error {
+	if k.dnsServer == "" {
+		return nil
+	}
+
+	name := dns.Fqdn(r.Hostname + "." + strings.TrimSuffix(k.dnsZone, "."))
+
+	msg := &dns.Msg{}
+	msg.SetUpdate(k.dnsZone)
+	msg.RemoveRRset([]dns.RR{
+		&dns.A{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Class: dns.ClassINET}},
+		&dns.AAAA{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeAAAA, Class: dns.ClassINET}},
+	})
+
+	var records []dns.RR
+	for _, address := range addresses {
+		ip := net.ParseIP(address)
+		if ip == nil {
+			continue
+		}
+
+		header := dns.RR_Header{Name: name, Class: dns.ClassINET, Ttl: 300}
+		if ip.To4() != nil {
+			header.Rrtype = dns.TypeA
+			records = append(records, &dns.A{Hdr: header, A: ip})
+		} else {
+			header.Rrtype = dns.TypeAAAA
+			records = append(records, &dns.AAAA{Hdr: header, AAAA: ip})
+		}
+	}
+
+	if len(records) > 0 {
+		msg.Insert(records)
+	}
+
+	client := &dns.Client{Timeout: requestTimeout}
+	if k.dnsTSIGName != "" {
+		client.TsigSecret = map[string]string{k.dnsTSIGName: k.dnsTSIGSecret}
+		msg.SetTsig(k.dnsTSIGName, dns.HmacSHA256, 300, time.Now().Unix())
+	}
+
+	resp, _, err := client.Exchange(msg, k.dnsServer)
+	if err != nil {
+		return fmt.Errorf("Failed sending DNS update for %q: %w", name, err)
+	}
+
+	if resp.Rcode != dns.RcodeSuccess {
+		return fmt.Errorf("DNS update for %q failed: %s", name, dns.RcodeToString[resp.Rcode])
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package dhcpext
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// webhookRequest is the body of the requests sent to the webhook.
+type webhookRequest struct {
+	// Action is either "register" or "unregister".
+	Action      string      `json:"action"`
+	Reservation Reservation `json:"reservation"`
+
+	// Allocation returned by the webhook when the reservation was registered, only set when unregistering.
+	Allocation *Allocation `json:"allocation,omitempty"`
+}
+
+// webhook sends the reservations to a generic webhook, which is responsible for both DHCP and DNS.
+//
+// The webhook is sent a POST request with a JSON body for each action. It must reply with a 2xx status code and can
+// reply to registrations with a JSON allocation.
+type webhook struct {
+	client *http.Client
+	url    string
+}
+
+// Register registers the reservation with the webhook.
+func (w *webhook) Register(ctx context.Context, r Reservation) (*Allocation, error) {
+	body, err := w.send(ctx, webhookRequest{Action: "register", Reservation: r})
+	if err != nil {
+		return nil, err
+	}
+
+	allocation := &Allocation{}
+	if len(bytes.TrimSpace(body)) > 0 {
+		err = json.Unmarshal(body, allocation)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid allocation returned by webhook: %w", err)
+		}
+	}
+
+	return allocation, nil
+}
+
+// Unregister removes the reservation from the webhook.
+func (w *webhook) Unregister(ctx context.Context, r Reservation, a Allocation) error {
+	_, err := w.send(ctx, webhookRequest{Action: "unregister", Reservation: r, Allocation: &a})
+	return err
+}
+
+// send sends the request to the webhook and returns the body of the response.
+func (w *webhook) send(ctx context.Context, req webhookRequest) ([]byte, error) {
+	buf, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("Failed sending %s request to webhook: %w", req.Action, err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("Webhook %s request failed with status %q: %s", req.Action, resp.Status, bytes.TrimSpace(body))
+	}
+
+	return body, nil
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/canonical/lxd/lxd/instance/instancetype"
 	"github.com/canonical/lxd/lxd/ip"
 	"github.com/canonical/lxd/lxd/network/acl"
+	"github.com/canonical/lxd/lxd/network/dhcpext"
 	"github.com/canonical/lxd/lxd/network/openvswitch"
 	"github.com/canonical/lxd/lxd/project"
 	"github.com/canonical/lxd/lxd/subprocess"
@@ -216,6 +217,16 @@ func (n *bridge) Validate(config map[string]string) error {
 		"bridge.mtu":    validate.Optional(validate.IsNetworkMTU),
 		"bridge.mode":   validate.Optional(validate.IsOneOf("standard", "fan")),
 
+		"dhcp.external":                    validate.Optional(validate.IsBool),
+		"dhcp.external.backend":            validate.Optional(validate.IsOneOf(dhcpext.Backends...)),
+		"dhcp.external.url":                validate.Optional(validate.IsRequestURL),
+		"dhcp.external.strict":             validate.Optional(validate.IsBool),
+		"dhcp.external.kea.ipv4.subnet_id": validate.Optional(validate.IsUint32),
+		"dhcp.external.kea.ipv6.subnet_id": validate.Optional(validate.IsUint32),
+		"dhcp.external.rfc2136.server":     validate.Optional(validate.IsListenAddress(true, false, true)),
+		"dhcp.external.rfc2136.zone":       validate.Optional(validate.IsHostname),
+		"dhcp.external.rfc2136.tsig_key":   validate.IsAny,
+
 		"fan.overlay_subnet": validate.Optional(validate.IsNetworkV4),
 		"fan.underlay_subnet": validate.Optional(func(value string) error {
 			if value == "auto" {
@@ -393,6 +404,18 @@ func (n *bridge) Validate(config map[string]string) error {
 		}
 	}
 
+	// Check the external DHCP backend can be loaded.
+	if shared.IsTrue(config["dhcp.external"]) {
+		if bridgeMode == "fan" {
+			return fmt.Errorf("External DHCP can't be used in 'fan' mode")
+		}
+
+		_, err = dhcpext.Load(config, n.state.Proxy)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Check using same MAC address on every cluster node is safe.
 	if config["bridge.hwaddr"] != "" {
 		err = n.checkClusterWideMACSafe(config)
@@ -963,7 +986,7 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 
 		// Update the dnsmasq config.
 		dnsmasqCmd = append(dnsmasqCmd, fmt.Sprintf("--listen-address=%s", ipAddress.String()))
-		if n.DHCPv4Subnet() != nil {
+		if n.DHCPv4Subnet() != nil && !n.hasExternalDHCP() {
 			if !shared.ValueInSlice("--dhcp-no-override", dnsmasqCmd) {
 				dnsmasqCmd = append(dnsmasqCmd, []string{"--dhcp-no-override", "--dhcp-authoritative", fmt.Sprintf("--dhcp-leasefile=%s", shared.VarPath("networks", n.name, "dnsmasq.leases")), fmt.Sprintf("--dhcp-hostsfile=%s", shared.VarPath("networks", n.name, "dnsmasq.hosts"))}...)
 			}
@@ -1111,7 +1134,7 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 
 		// Update the dnsmasq config.
 		dnsmasqCmd = append(dnsmasqCmd, []string{fmt.Sprintf("--listen-address=%s", ipAddress.String()), "--enable-ra"}...)
-		if n.DHCPv6Subnet() != nil {
+		if n.DHCPv6Subnet() != nil && !n.hasExternalDHCP() {
 			if n.hasIPv6Firewall() {
 				fwOpts.FeaturesV6.ICMPDHCPDNSAccess = true
 			}
@@ -2296,6 +2319,12 @@ func (n *bridge) hasDHCPv6() bool {
 	return shared.IsTrueOrEmpty(n.config["ipv6.dhcp"])
 }
 
+// hasExternalDHCP indicates whether DHCP is delegated to an external server, in which case dnsmasq doesn't serve
+// DHCP for the network and the reservations of the instances are registered with the external system instead.
+func (n *bridge) hasExternalDHCP() bool {
+	return shared.IsTrue(n.config["dhcp.external"])
+}
+
 // DHCPv4Subnet returns the DHCPv4 subnet (if DHCP is enabled on network).
 func (n *bridge) DHCPv4Subnet() *net.IPNet {
 	// DHCP is disabled on this network.
@@ -2643,9 +2672,13 @@ func (n *bridge) getExternalSubnetInUse() ([]externalSubnetUsage, error) {
 }
 
 // ForwardCreate creates a network forward.
-func (n *bridge) ForwardCreate(forward api.NetworkForwardsPost, clientType request.ClientType) error {
+func (n *bridge) ForwardCreate(forward api.NetworkForwardsPost, clientType request.ClientType) (net.IP, error) {
 	memberSpecific := true // bridge supports per-member forwards.
 
+	if forward.Allocate != "" {
+		return nil, api.StatusErrorf(http.StatusBadRequest, "Automatic listen address allocation is only supported for OVN networks")
+	}
+
 	err := n.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
 		// Check if there is an existing forward using the same listen address.
 		_, _, err := tx.GetNetworkForward(ctx, n.ID(), memberSpecific, forward.ListenAddress)
@@ -2653,23 +2686,23 @@ func (n *bridge) ForwardCreate(forward api.NetworkForwardsPost, clientType reque
 		return err
 	})
 	if err == nil {
-		return api.StatusErrorf(http.StatusConflict, "A forward for that listen address already exists")
+		return nil, api.StatusErrorf(http.StatusConflict, "A forward for that listen address already exists")
 	}
 
 	// Convert listen address to subnet so we can check its valid and can be used.
 	listenAddressNet, err := ParseIPToNet(forward.ListenAddress)
 	if err != nil {
-		return fmt.Errorf("Failed parsing address forward listen address %q: %w", forward.ListenAddress, err)
+		return nil, fmt.Errorf("Failed parsing address forward listen address %q: %w", forward.ListenAddress, err)
 	}
 
 	_, err = n.forwardValidate(listenAddressNet.IP, &forward.NetworkForwardPut)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	externalSubnetsInUse, err := n.getExternalSubnetInUse()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Check the listen address subnet doesn't fall within any existing network external subnets.
@@ -2686,7 +2719,7 @@ func (n *bridge) ForwardCreate(forward api.NetworkForwardsPost, clientType reque
 		if SubnetContains(&externalSubnetUser.subnet, listenAddressNet) || SubnetContains(listenAddressNet, &externalSubnetUser.subnet) {
 			// This error is purposefully vague so that it doesn't reveal any names of
 			// resources potentially outside of the network.
-			return fmt.Errorf("Forward listen address %q overlaps with another network or NIC", listenAddressNet.String())
+			return nil, fmt.Errorf("Forward listen address %q overlaps with another network or NIC", listenAddressNet.String())
 		}
 	}
 
@@ -2702,7 +2735,7 @@ func (n *bridge) ForwardCreate(forward api.NetworkForwardsPost, clientType reque
 		return err
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	revert.Add(func() {
@@ -2715,7 +2748,7 @@ func (n *bridge) ForwardCreate(forward api.NetworkForwardsPost, clientType reque
 
 	err = n.forwardSetupFirewall()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Check if hairpin mode needs to be enabled on active NIC bridge ports.
@@ -2741,7 +2774,7 @@ func (n *bridge) ForwardCreate(forward api.NetworkForwardsPost, clientType reque
 				return err
 			})
 			if err != nil {
-				return fmt.Errorf("Failed loading network forwards: %w", err)
+				return nil, fmt.Errorf("Failed loading network forwards: %w", err)
 			}
 
 			// If we are the first forward on this bridge, enable hairpin mode on active NIC ports.
@@ -2787,7 +2820,7 @@ func (n *bridge) ForwardCreate(forward api.NetworkForwardsPost, clientType reque
 					}, filter)
 				})
 				if err != nil {
-					return err
+					return nil, err
 				}
 			}
 		}
@@ -2796,11 +2829,11 @@ func (n *bridge) ForwardCreate(forward api.NetworkForwardsPost, clientType reque
 	// Refresh exported BGP prefixes on local member.
 	err = n.forwardBGPSetupPrefixes()
 	if err != nil {
-		return fmt.Errorf("Failed applying BGP prefixes for address forwards: %w", err)
+		return nil, fmt.Errorf("Failed applying BGP prefixes for address forwards: %w", err)
 	}
 
 	revert.Success()
-	return nil
+	return net.ParseIP(forward.ListenAddress), nil
 }
 
 // ForwardUpdate updates a network forward.
@@ -33,6 +33,7 @@ import (
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/revert"
 	"github.com/canonical/lxd/shared/validate"
+	"github.com/canonical/lxd/shared/version"
 )
 
 const ovnChassisPriorityMax = 32767
@@ -1079,6 +1080,19 @@ func (n *ovn) uplinkAllAllocatedIPs(ctx context.Context, tx *db.ClusterTx, uplin
 	v4IPs := make([]net.IP, 0)
 	v6IPs := make([]net.IP, 0)
 
+	appendIP := func(address string) {
+		ip := net.ParseIP(address)
+		if ip == nil {
+			return
+		}
+
+		if ip.To4() != nil {
+			v4IPs = append(v4IPs, ip)
+		} else {
+			v6IPs = append(v6IPs, ip)
+		}
+	}
+
 	for _, networks := range projectNetworks {
 		for _, netInfo := range networks {
 			if netInfo.Type != "ovn" || netInfo.Config["network"] != uplinkNetName {
@@ -1087,20 +1101,140 @@ func (n *ovn) uplinkAllAllocatedIPs(ctx context.Context, tx *db.ClusterTx, uplin
 
 			for _, k := range []string{ovnVolatileUplinkIPv4, ovnVolatileUplinkIPv6} {
 				if netInfo.Config[k] != "" {
-					ip := net.ParseIP(netInfo.Config[k])
-					if ip != nil {
-						if ip.To4() != nil {
-							v4IPs = append(v4IPs, ip)
-						} else {
-							v6IPs = append(v6IPs, ip)
+					appendIP(netInfo.Config[k])
+				}
+			}
+		}
+	}
+
+	// Addresses automatically allocated to network forwards and load balancers are also taken from the OVN ranges.
+	uplinkNetID, _, _, err := tx.GetNetworkInAnyState(ctx, api.ProjectDefaultName, uplinkNetName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to load uplink network %q: %w", uplinkNetName, err)
+	}
+
+	allocations, err := tx.GetNetworkUplinkAllocations(ctx, uplinkNetID, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, allocation := range allocations {
+		appendIP(allocation.Address)
+	}
+
+	return v4IPs, v6IPs, nil
+}
+
+// uplinkAllocateListenAddress allocates a free address from the uplink network's OVN ranges for a network forward
+// or load balancer (depending on usageType) of this network, and records the allocation. IPv4 ranges are used if
+// configured, otherwise IPv6 ranges. Returns the allocated address and the ID of the allocation.
+func (n *ovn) uplinkAllocateListenAddress(uplinkNet Network, usageType subnetUsageType) (net.IP, int64, error) {
+	uplinkNetConf := uplinkNet.Config()
+
+	var ipRanges []*shared.IPRange
+	var err error
+	if uplinkNetConf["ipv4.ovn.ranges"] != "" {
+		ipRanges, err = parseIPRanges(uplinkNetConf["ipv4.ovn.ranges"], uplinkNet.DHCPv4Subnet())
+		if err != nil {
+			return nil, -1, fmt.Errorf("Failed to parse uplink IPv4 OVN ranges: %w", err)
+		}
+	} else if uplinkNetConf["ipv6.ovn.ranges"] != "" {
+		ipRanges, err = parseIPRanges(uplinkNetConf["ipv6.ovn.ranges"], uplinkNet.DHCPv6Subnet())
+		if err != nil {
+			return nil, -1, fmt.Errorf("Failed to parse uplink IPv6 OVN ranges: %w", err)
+		}
+	} else {
+		return nil, -1, api.StatusErrorf(http.StatusBadRequest, `Uplink network %q has no "ipv4.ovn.ranges" or "ipv6.ovn.ranges" to allocate from`, uplinkNet.Name())
+	}
+
+	var listenAddress net.IP
+	var allocationID int64
+
+	// The allocation is performed in a single cluster transaction, so that concurrent allocations on other
+	// members cannot pick the same address.
+	err = n.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		allAllocatedIPv4, allAllocatedIPv6, err := n.uplinkAllAllocatedIPs(ctx, tx, uplinkNet.Name())
+		if err != nil {
+			return fmt.Errorf("Failed to get all allocated IPs for uplink: %w", err)
+		}
+
+		allAllocated := append(allAllocatedIPv4, allAllocatedIPv6...)
+
+		// Listen addresses that were specified manually may also fall within the OVN ranges.
+		forwardListenAddresses, err := tx.GetProjectNetworkForwardListenAddressesByUplink(ctx, uplinkNet.Name(), false)
+		if err != nil {
+			return fmt.Errorf("Failed loading network forward listen addresses: %w", err)
+		}
+
+		loadBalancerListenAddresses, err := tx.GetProjectNetworkLoadBalancerListenAddressesByUplink(ctx, uplinkNet.Name(), false)
+		if err != nil {
+			return fmt.Errorf("Failed loading network load balancer listen addresses: %w", err)
+		}
+
+		for _, listenAddressesByProject := range []map[string]map[string][]string{forwardListenAddresses, loadBalancerListenAddresses} {
+			for _, listenAddressesByNetwork := range listenAddressesByProject {
+				for _, listenAddresses := range listenAddressesByNetwork {
+					for _, address := range listenAddresses {
+						ip := net.ParseIP(address)
+						if ip != nil {
+							allAllocated = append(allAllocated, ip)
 						}
 					}
 				}
 			}
 		}
+
+		listenAddress, err = n.uplinkAllocateIP(ipRanges, allAllocated)
+		if err != nil {
+			return fmt.Errorf("Failed to allocate uplink address: %w", err)
+		}
+
+		uplinkNetID, _, _, err := tx.GetNetworkInAnyState(ctx, api.ProjectDefaultName, uplinkNet.Name())
+		if err != nil {
+			return fmt.Errorf("Failed to load uplink network %q: %w", uplinkNet.Name(), err)
+		}
+
+		allocation := api.NetworkUplinkAllocation{
+			Address: listenAddress.String(),
+			Type:    "network-forward",
+			UsedBy:  api.NewURL().Path(version.APIVersion, "networks", n.name, "forwards", listenAddress.String()).Project(n.project).String(),
+		}
+
+		if usageType == subnetUsageNetworkLoadBalancer {
+			allocation.Type = "network-load-balancer"
+			allocation.UsedBy = api.NewURL().Path(version.APIVersion, "networks", n.name, "load-balancers", listenAddress.String()).Project(n.project).String()
+		}
+
+		allocationID, err = tx.CreateNetworkUplinkAllocation(ctx, uplinkNetID, n.ID(), &allocation)
+		if err != nil {
+			return fmt.Errorf("Failed recording uplink network allocation: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, -1, err
 	}
 
-	return v4IPs, v6IPs, nil
+	return listenAddress, allocationID, nil
+}
+
+// uplinkReleaseListenAddress returns an automatically allocated listen address to the uplink network's OVN ranges.
+// It does nothing if the listen address was not allocated automatically.
+func (n *ovn) uplinkReleaseListenAddress(listenAddress string) error {
+	return n.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		uplinkNetID, _, _, err := tx.GetNetworkInAnyState(ctx, api.ProjectDefaultName, n.config["network"])
+		if err != nil {
+			return fmt.Errorf("Failed to load uplink network %q: %w", n.config["network"], err)
+		}
+
+		err = tx.ReleaseNetworkUplinkAllocation(ctx, uplinkNetID, listenAddress)
+		if err != nil {
+			return fmt.Errorf("Failed releasing uplink network allocation: %w", err)
+		}
+
+		return nil
+	})
 }
 
 // uplinkAllocateIP allocates a free IP from one of the IP ranges.
@@ -2732,6 +2866,14 @@ func (n *ovn) Delete(clientType request.ClientType) error {
 		if err != nil {
 			return fmt.Errorf("Failed deleting network forwards and load balancers: %w", err)
 		}
+
+		// Return any automatically allocated uplink addresses to the uplink network's OVN ranges.
+		err = n.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+			return tx.ReleaseNetworkUplinkAllocations(ctx, n.ID())
+		})
+		if err != nil {
+			return fmt.Errorf("Failed releasing uplink network allocations: %w", err)
+		}
 	}
 
 	return n.common.delete(clientType)
@@ -4531,13 +4673,36 @@ func (n *ovn) forwardFlattenVIPs(listenAddress net.IP, defaultTargetAddress net.
 }
 
 // ForwardCreate creates a network forward.
-func (n *ovn) ForwardCreate(forward api.NetworkForwardsPost, clientType request.ClientType) error {
+func (n *ovn) ForwardCreate(forward api.NetworkForwardsPost, clientType request.ClientType) (net.IP, error) {
 	revert := revert.New()
 	defer revert.Fail()
 
 	if clientType == request.ClientTypeNormal {
 		memberSpecific := false // OVN doesn't support per-member forwards.
 
+		// Allocate the listen address from the uplink network's OVN ranges if requested.
+		allocated := forward.Allocate != ""
+		if allocated {
+			uplinkNet, err := LoadByName(n.state, api.ProjectDefaultName, n.config["network"])
+			if err != nil {
+				return nil, fmt.Errorf("Failed loading uplink network %q: %w", n.config["network"], err)
+			}
+
+			listenAddress, allocationID, err := n.uplinkAllocateListenAddress(uplinkNet, subnetUsageNetworkForward)
+			if err != nil {
+				return nil, err
+			}
+
+			revert.Add(func() {
+				_ = n.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+					return tx.DeleteNetworkUplinkAllocation(ctx, allocationID)
+				})
+			})
+
+			forward.ListenAddress = listenAddress.String()
+			forward.Allocate = ""
+		}
+
 		err := n.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
 			// Check if there is an existing forward using the same listen address.
 			_, _, err := tx.GetNetworkForward(ctx, n.ID(), memberSpecific, forward.ListenAddress)
@@ -4545,18 +4710,18 @@ func (n *ovn) ForwardCreate(forward api.NetworkForwardsPost, clientType request.
 			return err
 		})
 		if err == nil {
-			return api.StatusErrorf(http.StatusConflict, "A forward for that listen address already exists")
+			return nil, api.StatusErrorf(http.StatusConflict, "A forward for that listen address already exists")
 		}
 
 		// Convert listen address to subnet so we can check its valid and can be used.
 		listenAddressNet, err := ParseIPToNet(forward.ListenAddress)
 		if err != nil {
-			return fmt.Errorf("Failed parsing %q: %w", forward.ListenAddress, err)
+			return nil, fmt.Errorf("Failed parsing %q: %w", forward.ListenAddress, err)
 		}
 
 		portMaps, err := n.forwardValidate(listenAddressNet.IP, &forward.NetworkForwardPut)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		// Load the project to get uplink network restrictions.
@@ -4583,30 +4748,35 @@ func (n *ovn) ForwardCreate(forward api.NetworkForwardsPost, clientType request.
 			return nil
 		})
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		uplinkRoutes, err := n.uplinkRoutes(uplink)
 		if err != nil {
-			return err
+			return nil, err
+		}
+
+		// Allocated listen addresses come from the uplink network's OVN ranges rather than its routes.
+		if allocated {
+			uplinkRoutes = []*net.IPNet{listenAddressNet}
 		}
 
 		// Get project restricted routes.
 		projectRestrictedSubnets, err := n.projectRestrictedSubnets(p, n.config["network"])
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		externalSubnetsInUse, err := n.getExternalSubnetInUse(n.config["network"])
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		// Check the listen address subnet is allowed within both the uplink's external routes and any
 		// project restricted subnets.
 		err = n.validateExternalSubnet(uplinkRoutes, projectRestrictedSubnets, listenAddressNet)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		// Check the listen address subnet doesn't fall within any existing OVN network external subnets.
@@ -4623,13 +4793,13 @@ func (n *ovn) ForwardCreate(forward api.NetworkForwardsPost, clientType request.
 			if SubnetContains(&externalSubnetUser.subnet, listenAddressNet) || SubnetContains(listenAddressNet, &externalSubnetUser.subnet) {
 				// This error is purposefully vague so that it doesn't reveal any names of
 				// resources potentially outside of the network's project.
-				return fmt.Errorf("Forward listen address %q overlaps with another network or NIC", listenAddressNet.String())
+				return nil, fmt.Errorf("Forward listen address %q overlaps with another network or NIC", listenAddressNet.String())
 			}
 		}
 
 		client, err := openvswitch.NewOVN(n.state)
 		if err != nil {
-			return fmt.Errorf("Failed to get OVN client: %w", err)
+			return nil, fmt.Errorf("Failed to get OVN client: %w", err)
 		}
 
 		var forwardID int64
@@ -4641,7 +4811,7 @@ func (n *ovn) ForwardCreate(forward api.NetworkForwardsPost, clientType request.
 			return err
 		})
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		revert.Add(func() {
@@ -4657,31 +4827,31 @@ func (n *ovn) ForwardCreate(forward api.NetworkForwardsPost, clientType request.
 
 		err = client.LoadBalancerApply(n.getLoadBalancerName(forward.ListenAddress), []openvswitch.OVNRouter{n.getRouterName()}, []openvswitch.OVNSwitch{n.getIntSwitchName()}, vips...)
 		if err != nil {
-			return fmt.Errorf("Failed applying OVN load balancer: %w", err)
+			return nil, fmt.Errorf("Failed applying OVN load balancer: %w", err)
 		}
 
 		// Notify all other members to refresh their BGP prefixes.
 		notifier, err := cluster.NewNotifier(n.state, n.state.Endpoints.NetworkCert(), n.state.ServerCert(), cluster.NotifyAll)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		err = notifier(func(client lxd.InstanceServer) error {
 			return client.UseProject(n.project).CreateNetworkForward(n.name, forward)
 		})
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	// Refresh exported BGP prefixes on local member.
 	err := n.forwardBGPSetupPrefixes()
 	if err != nil {
-		return fmt.Errorf("Failed applying BGP prefixes for address forwards: %w", err)
+		return nil, fmt.Errorf("Failed applying BGP prefixes for address forwards: %w", err)
 	}
 
 	revert.Success()
-	return nil
+	return net.ParseIP(forward.ListenAddress), nil
 }
 
 // ForwardUpdate updates a network forward.
@@ -4824,6 +4994,12 @@ func (n *ovn) ForwardDelete(listenAddress string, clientType request.ClientType)
 			return err
 		}
 
+		// Return the listen address to the uplink network's OVN ranges if it was allocated automatically.
+		err = n.uplinkReleaseListenAddress(forward.ListenAddress)
+		if err != nil {
+			return err
+		}
+
 		// Notify all other members to refresh their BGP prefixes.
 		notifier, err := cluster.NewNotifier(n.state, n.state.Endpoints.NetworkCert(), n.state.ServerCert(), cluster.NotifyAll)
 		if err != nil {
@@ -4886,13 +5062,36 @@ func (n *ovn) loadBalancerFlattenVIPs(listenAddress net.IP, portMaps []*loadBala
 }
 
 // LoadBalancerCreate creates a network load balancer.
-func (n *ovn) LoadBalancerCreate(loadBalancer api.NetworkLoadBalancersPost, clientType request.ClientType) error {
+func (n *ovn) LoadBalancerCreate(loadBalancer api.NetworkLoadBalancersPost, clientType request.ClientType) (net.IP, error) {
 	revert := revert.New()
 	defer revert.Fail()
 
 	if clientType == request.ClientTypeNormal {
 		memberSpecific := false // OVN doesn't support per-member load balancers.
 
+		// Allocate the listen address from the uplink network's OVN ranges if requested.
+		allocated := loadBalancer.Allocate != ""
+		if allocated {
+			uplinkNet, err := LoadByName(n.state, api.ProjectDefaultName, n.config["network"])
+			if err != nil {
+				return nil, fmt.Errorf("Failed loading uplink network %q: %w", n.config["network"], err)
+			}
+
+			listenAddress, allocationID, err := n.uplinkAllocateListenAddress(uplinkNet, subnetUsageNetworkLoadBalancer)
+			if err != nil {
+				return nil, err
+			}
+
+			revert.Add(func() {
+				_ = n.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+					return tx.DeleteNetworkUplinkAllocation(ctx, allocationID)
+				})
+			})
+
+			loadBalancer.ListenAddress = listenAddress.String()
+			loadBalancer.Allocate = ""
+		}
+
 		err := n.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
 			// Check if there is an existing load balancer using the same listen address.
 			_, _, err := tx.GetNetworkLoadBalancer(ctx, n.ID(), memberSpecific, loadBalancer.ListenAddress)
@@ -4900,18 +5099,18 @@ func (n *ovn) LoadBalancerCreate(loadBalancer api.NetworkLoadBalancersPost, clie
 			return err
 		})
 		if err == nil {
-			return api.StatusErrorf(http.StatusConflict, "A load balancer for that listen address already exists")
+			return nil, api.StatusErrorf(http.StatusConflict, "A load balancer for that listen address already exists")
 		}
 
 		// Convert listen address to subnet so we can check its valid and can be used.
 		listenAddressNet, err := ParseIPToNet(loadBalancer.ListenAddress)
 		if err != nil {
-			return fmt.Errorf("Failed parsing %q: %w", loadBalancer.ListenAddress, err)
+			return nil, fmt.Errorf("Failed parsing %q: %w", loadBalancer.ListenAddress, err)
 		}
 
 		portMaps, err := n.loadBalancerValidate(listenAddressNet.IP, &loadBalancer.NetworkLoadBalancerPut)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		// Load the project to get uplink network restrictions.
@@ -4938,30 +5137,35 @@ func (n *ovn) LoadBalancerCreate(loadBalancer api.NetworkLoadBalancersPost, clie
 			return nil
 		})
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		uplinkRoutes, err := n.uplinkRoutes(uplink)
 		if err != nil {
-			return err
+			return nil, err
+		}
+
+		// Allocated listen addresses come from the uplink network's OVN ranges rather than its routes.
+		if allocated {
+			uplinkRoutes = []*net.IPNet{listenAddressNet}
 		}
 
 		// Get project restricted routes.
 		projectRestrictedSubnets, err := n.projectRestrictedSubnets(p, n.config["network"])
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		externalSubnetsInUse, err := n.getExternalSubnetInUse(n.config["network"])
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		// Check the listen address subnet is allowed within both the uplink's external routes and any
 		// project restricted subnets.
 		err = n.validateExternalSubnet(uplinkRoutes, projectRestrictedSubnets, listenAddressNet)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		// Check the listen address subnet doesn't fall within any existing OVN network external subnets.
@@ -4978,13 +5182,13 @@ func (n *ovn) LoadBalancerCreate(loadBalancer api.NetworkLoadBalancersPost, clie
 			if SubnetContains(&externalSubnetUser.subnet, listenAddressNet) || SubnetContains(listenAddressNet, &externalSubnetUser.subnet) {
 				// This error is purposefully vague so that it doesn't reveal any names of
 				// resources potentially outside of the network's project.
-				return fmt.Errorf("Load balancer listen address %q overlaps with another network or NIC", listenAddressNet.String())
+				return nil, fmt.Errorf("Load balancer listen address %q overlaps with another network or NIC", listenAddressNet.String())
 			}
 		}
 
 		client, err := openvswitch.NewOVN(n.state)
 		if err != nil {
-			return fmt.Errorf("Failed to get OVN client: %w", err)
+			return nil, fmt.Errorf("Failed to get OVN client: %w", err)
 		}
 
 		var loadBalancerID int64
@@ -4996,7 +5200,7 @@ func (n *ovn) LoadBalancerCreate(loadBalancer api.NetworkLoadBalancersPost, clie
 			return err
 		})
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		revert.Add(func() {
@@ -5012,31 +5216,31 @@ func (n *ovn) LoadBalancerCreate(loadBalancer api.NetworkLoadBalancersPost, clie
 
 		err = client.LoadBalancerApply(n.getLoadBalancerName(loadBalancer.ListenAddress), []openvswitch.OVNRouter{n.getRouterName()}, []openvswitch.OVNSwitch{n.getIntSwitchName()}, vips...)
 		if err != nil {
-			return fmt.Errorf("Failed applying OVN load balancer: %w", err)
+			return nil, fmt.Errorf("Failed applying OVN load balancer: %w", err)
 		}
 
 		// Notify all other members to refresh their BGP prefixes.
 		notifier, err := cluster.NewNotifier(n.state, n.state.Endpoints.NetworkCert(), n.state.ServerCert(), cluster.NotifyAll)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		err = notifier(func(client lxd.InstanceServer) error {
 			return client.UseProject(n.project).CreateNetworkLoadBalancer(n.name, loadBalancer)
 		})
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	// Refresh exported BGP prefixes on local member.
 	err := n.loadBalancerBGPSetupPrefixes()
 	if err != nil {
-		return fmt.Errorf("Failed applying BGP prefixes for load balancers: %w", err)
+		return nil, fmt.Errorf("Failed applying BGP prefixes for load balancers: %w", err)
 	}
 
 	revert.Success()
-	return nil
+	return net.ParseIP(loadBalancer.ListenAddress), nil
 }
 
 // LoadBalancerUpdate updates a network load balancer.
@@ -5180,6 +5384,12 @@ func (n *ovn) LoadBalancerDelete(listenAddress string, clientType request.Client
 			return err
 		}
 
+		// Return the listen address to the uplink network's OVN ranges if it was allocated automatically.
+		err = n.uplinkReleaseListenAddress(forward.ListenAddress)
+		if err != nil {
+			return err
+		}
+
 		// Notify all other members to refresh their BGP prefixes.
 		notifier, err := cluster.NewNotifier(n.state, n.state.Endpoints.NetworkCert(), n.state.ServerCert(), cluster.NotifyAll)
 		if err != nil {
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	cryptorand "crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"math/big"
@@ -71,6 +72,21 @@ func RandomDevName(prefix string) string {
 	return iface
 }
 
+// StableDevName returns a device name with prefix derived from a hash of the seed, so that the same seed always
+// gives the same name. The hash is truncated to give names of the same length as RandomDevName, which keeps them
+// within the 15 characters limit of interface names (and the 13 characters supported by buggy dhclient applications).
+// If the hash combined with the prefix exceeds 13 characters then empty string is returned.
+func StableDevName(prefix string, seed string) string {
+	hash := sha256.Sum256([]byte(seed))
+
+	iface := prefix + hex.EncodeToString(hash[:4])
+	if len(iface) > 13 {
+		return ""
+	}
+
+	return iface
+}
+
 // MACDevName returns interface name with prefix 'lxd' and MAC without leading 2 digits.
 func MACDevName(mac net.HardwareAddr) string {
 	devName := strings.Join(strings.Split(mac.String(), ":"), "")
@@ -326,6 +342,23 @@ func GetTXQueueLength(devName string) (uint32, error) {
 	return uint32(txqlen), nil
 }
 
+// GetDevQueues retrieves the number of active transmit queues of a named network device.
+func GetDevQueues(devName string) (uint32, error) {
+	entries, err := os.ReadDir(fmt.Sprintf("/sys/class/net/%s/queues", devName))
+	if err != nil {
+		return 0, err
+	}
+
+	var queues uint32
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "tx-") {
+			queues++
+		}
+	}
+
+	return queues, nil
+}
+
 // DefaultGatewaySubnetV4 returns subnet of default gateway interface.
 func DefaultGatewaySubnetV4() (*net.IPNet, string, error) {
 	file, err := os.Open("/proc/net/route")
@@ -2,12 +2,21 @@ package network
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"sort"
 	"strings"
+	"sync"
 
+	"github.com/canonical/lxd/client"
+	"github.com/canonical/lxd/lxd/cluster"
+	"github.com/canonical/lxd/lxd/cluster/request"
+	"github.com/canonical/lxd/lxd/db"
 	"github.com/canonical/lxd/lxd/ip"
 	"github.com/canonical/lxd/lxd/network/openvswitch"
+	"github.com/canonical/lxd/lxd/state"
 	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
 )
 
 // BridgeVLANFilteringStatus returns whether VLAN filtering is enabled on a bridge interface.
@@ -92,3 +101,141 @@ func DetachInterface(bridgeName string, devName string) error {
 
 	return nil
 }
+
+// BridgeDynamicLeases returns the DHCP leases handed out by the given bridge network on all online cluster members.
+// Leases without a MAC address, such as DHCPv6 leases, are skipped as they can't be attributed to a NIC.
+func BridgeDynamicLeases(s *state.State, n Network) ([]api.NetworkLease, error) {
+	// Getting the leases as a notifier only returns the leases of the local member, without filtering them.
+	leases, err := n.Leases("", request.ClientTypeNotifier)
+	if err != nil {
+		return nil, fmt.Errorf("Failed getting leases of network %q: %w", n.Name(), err)
+	}
+
+	notifier, err := cluster.NewNotifier(s, s.Endpoints.NetworkCert(), s.ServerCert(), cluster.NotifyAlive)
+	if err != nil {
+		return nil, err
+	}
+
+	var leasesMu sync.Mutex
+	err = notifier(func(client lxd.InstanceServer) error {
+		memberLeases, err := client.GetNetworkLeases(n.Name())
+		if err != nil {
+			return err
+		}
+
+		leasesMu.Lock()
+		leases = append(leases, memberLeases...)
+		leasesMu.Unlock()
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed getting leases of network %q from cluster members: %w", n.Name(), err)
+	}
+
+	dynamicLeases := make([]api.NetworkLease, 0, len(leases))
+	for _, lease := range leases {
+		if lease.Type == "dynamic" && lease.Hwaddr != "" {
+			dynamicLeases = append(dynamicLeases, lease)
+		}
+	}
+
+	return dynamicLeases, nil
+}
+
+// BridgeStaticAddresses returns the static IP addresses of the instance NICs connected to the given bridge network
+// on all cluster members along with their conflicts: other NICs on the same VLAN with the same address, DHCP leases
+// of the address to another NIC and addresses outside of the subnet of the network.
+func BridgeStaticAddresses(s *state.State, n Network) ([]api.NetworkStaticAddress, error) {
+	subnets := make(map[string]*net.IPNet, 2)
+	for _, key := range []string{"ipv4.address", "ipv6.address"} {
+		_, subnet, err := net.ParseCIDR(n.Config()[key])
+		if err == nil {
+			subnets[key] = subnet
+		}
+	}
+
+	addresses := []api.NetworkStaticAddress{}
+	vlans := []string{}
+	err := UsedByInstanceDevices(s, n.Project(), n.Name(), n.Type(), func(inst db.InstanceArgs, nicName string, nicConfig map[string]string) error {
+		hwaddr := nicConfig["hwaddr"]
+		if hwaddr == "" {
+			hwaddr = inst.Config[fmt.Sprintf("volatile.%s.hwaddr", nicName)]
+		}
+
+		mac, _ := net.ParseMAC(hwaddr)
+
+		for _, key := range []string{"ipv4.address", "ipv6.address"} {
+			ip := net.ParseIP(nicConfig[key])
+			if ip == nil {
+				continue // No static IP specified on this NIC.
+			}
+
+			address := api.NetworkStaticAddress{
+				Address:        ip.String(),
+				Project:        inst.Project,
+				Instance:       inst.Name,
+				Device:         nicName,
+				Hwaddr:         mac.String(),
+				Location:       inst.Node,
+				AllowDuplicate: shared.IsTrue(nicConfig["allow_duplicate_address"]),
+				Conflicts:      []string{},
+			}
+
+			if subnets[key] != nil && !subnets[key].Contains(ip) {
+				address.Conflicts = append(address.Conflicts, fmt.Sprintf("Address is outside of the network subnet %q", subnets[key].String()))
+			}
+
+			addresses = append(addresses, address)
+			vlans = append(vlans, nicConfig["vlan"])
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the addresses against each other. Duplicates are allowed if either NIC allows them.
+	for i := range addresses {
+		for j := range addresses {
+			if i == j || addresses[i].Address != addresses[j].Address || vlans[i] != vlans[j] {
+				continue
+			}
+
+			if addresses[i].AllowDuplicate || addresses[j].AllowDuplicate {
+				continue
+			}
+
+			addresses[i].Conflicts = append(addresses[i].Conflicts, fmt.Sprintf("Address is also assigned to NIC %q of instance %q in project %q", addresses[j].Device, addresses[j].Instance, addresses[j].Project))
+		}
+	}
+
+	// Check the addresses against the DHCP leases of the network.
+	leases, err := BridgeDynamicLeases(s, n)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range addresses {
+		if addresses[i].AllowDuplicate {
+			continue
+		}
+
+		for _, lease := range leases {
+			leaseIP := net.ParseIP(lease.Address)
+			leaseMAC, _ := net.ParseMAC(lease.Hwaddr)
+			if leaseIP == nil || leaseMAC == nil || leaseIP.String() != addresses[i].Address || leaseMAC.String() == addresses[i].Hwaddr {
+				continue
+			}
+
+			addresses[i].Conflicts = append(addresses[i].Conflicts, fmt.Sprintf("Address is leased to MAC address %q on %q", leaseMAC.String(), lease.Location))
+		}
+	}
+
+	sort.SliceStable(addresses, func(i int, j int) bool {
+		return addresses[i].Address < addresses[j].Address
+	})
+
+	return addresses, nil
+}
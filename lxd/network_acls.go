@@ -155,7 +155,7 @@ func networkACLsGet(d *Daemon, r *http.Request) response.Response {
 
 	var aclNames []string
 
-	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
 		var err error
 
 		// Get list of Network ACLs.
@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
 
 	"github.com/canonical/lxd/lxd/auth"
 	clusterRequest "github.com/canonical/lxd/lxd/cluster/request"
@@ -27,6 +30,13 @@ var networkAllocationsCmd = APIEndpoint{
 	Get: APIEndpointAction{Handler: networkAllocationsGet, AccessHandler: allowAuthenticated},
 }
 
+// The uplink allocations reveal networks of all projects, so only those who can edit the uplink network can view them.
+var networkUplinkAllocationsCmd = APIEndpoint{
+	Path: "networks/{networkName}/allocations",
+
+	Get: APIEndpointAction{Handler: networkUplinkAllocationsGet, AccessHandler: allowPermission(entity.TypeNetwork, auth.EntitlementCanEdit, "networkName")},
+}
+
 // swagger:operation GET /1.0/network-allocations network-allocations network_allocations_get
 //
 //	Get the network allocations in use (`network`, `network-forward` and `load-balancer` and `instance`)
@@ -248,3 +258,173 @@ func networkAllocationsGet(d *Daemon, r *http.Request) response.Response {
 
 	return response.SyncResponse(true, result)
 }
+
+// swagger:operation GET /1.0/networks/{networkName}/allocations networks networks_allocations_get
+//
+//	Get the uplink network allocations
+//
+//	Returns the addresses of an uplink network that are used by OVN networks, and by their network forwards and
+//	load balancers. Addresses that were allocated automatically and have since been released are also returned.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	responses:
+//	  "200":
+//	    description: API endpoints
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of uplink network allocations
+//	          items:
+//	            $ref: "#/definitions/NetworkUplinkAllocation"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func networkUplinkAllocationsGet(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	projectName, _, err := project.NetworkProject(s.DB.Cluster, request.ProjectParam(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	networkName, err := url.PathUnescape(mux.Vars(r)["networkName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Uplink networks are always in the default project.
+	if projectName != api.ProjectDefaultName {
+		return response.BadRequest(fmt.Errorf("Uplink networks must be in the %q project", api.ProjectDefaultName))
+	}
+
+	result := make([]api.NetworkUplinkAllocation, 0)
+
+	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		uplinkNetID, uplinkNet, _, err := tx.GetNetworkInAnyState(ctx, projectName, networkName)
+		if err != nil {
+			return err
+		}
+
+		if !shared.ValueInSlice(uplinkNet.Type, []string{"bridge", "physical"}) {
+			return api.StatusErrorf(http.StatusBadRequest, "Network %q of type %q cannot be used as an uplink network", networkName, uplinkNet.Type)
+		}
+
+		allocations, err := tx.GetNetworkUplinkAllocations(ctx, uplinkNetID, true)
+		if err != nil {
+			return err
+		}
+
+		// Index the active automatic allocations by address, so that they can be matched with their users.
+		activeAllocations := make(map[string]api.NetworkUplinkAllocation, len(allocations))
+		for _, allocation := range allocations {
+			if allocation.ReleasedAt.IsZero() {
+				activeAllocations[allocation.Address] = allocation
+			}
+		}
+
+		// Addresses used by OVN networks as their external router address.
+		projectNetworks, err := tx.GetCreatedNetworks(ctx)
+		if err != nil {
+			return fmt.Errorf("Failed loading networks: %w", err)
+		}
+
+		for networkProjectName, networks := range projectNetworks {
+			for _, netInfo := range networks {
+				if netInfo.Type != "ovn" || netInfo.Config["network"] != networkName {
+					continue
+				}
+
+				for _, k := range []string{"volatile.network.ipv4.address", "volatile.network.ipv6.address"} {
+					if netInfo.Config[k] == "" {
+						continue
+					}
+
+					result = append(result, api.NetworkUplinkAllocation{
+						Address: netInfo.Config[k],
+						UsedBy:  api.NewURL().Path(version.APIVersion, "networks", netInfo.Name).Project(networkProjectName).String(),
+						Type:    "network",
+					})
+				}
+			}
+		}
+
+		// Addresses used by network forwards and load balancers of OVN networks.
+		forwardListenAddresses, err := tx.GetProjectNetworkForwardListenAddressesByUplink(ctx, networkName, false)
+		if err != nil {
+			return fmt.Errorf("Failed loading network forward listen addresses: %w", err)
+		}
+
+		loadBalancerListenAddresses, err := tx.GetProjectNetworkLoadBalancerListenAddressesByUplink(ctx, networkName, false)
+		if err != nil {
+			return fmt.Errorf("Failed loading network load balancer listen addresses: %w", err)
+		}
+
+		for _, usage := range []struct {
+			allocationType    string
+			pathName          string
+			listenAddressesBy map[string]map[string][]string
+		}{
+			{allocationType: "network-forward", pathName: "forwards", listenAddressesBy: forwardListenAddresses},
+			{allocationType: "network-load-balancer", pathName: "load-balancers", listenAddressesBy: loadBalancerListenAddresses},
+		} {
+			for networkProjectName, listenAddressesByNetwork := range usage.listenAddressesBy {
+				for ovnNetworkName, listenAddresses := range listenAddressesByNetwork {
+					for _, listenAddress := range listenAddresses {
+						allocation, ok := activeAllocations[listenAddress]
+						if !ok {
+							allocation = api.NetworkUplinkAllocation{
+								Address: listenAddress,
+								UsedBy:  api.NewURL().Path(version.APIVersion, "networks", ovnNetworkName, usage.pathName, listenAddress).Project(networkProjectName).String(),
+								Type:    usage.allocationType,
+							}
+						}
+
+						result = append(result, allocation)
+					}
+				}
+			}
+		}
+
+		// Historical automatic allocations that have been released.
+		for _, allocation := range allocations {
+			if !allocation.ReleasedAt.IsZero() {
+				result = append(result, allocation)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, result)
+}
@@ -256,6 +256,16 @@ func networkLoadBalancersPost(d *Daemon, r *http.Request) response.Response {
 
 	req.Normalise() // So we handle the request in normalised/canonical form.
 
+	if req.Allocate != "" {
+		if req.Allocate != "auto" {
+			return response.BadRequest(fmt.Errorf(`Invalid listen address allocation mode %q, must be "auto"`, req.Allocate))
+		}
+
+		if req.ListenAddress != "" {
+			return response.BadRequest(fmt.Errorf("Listen address cannot be specified when it is allocated automatically"))
+		}
+	}
+
 	networkName, err := url.PathUnescape(mux.Vars(r)["networkName"])
 	if err != nil {
 		return response.SmartError(err)
@@ -277,12 +287,12 @@ func networkLoadBalancersPost(d *Daemon, r *http.Request) response.Response {
 
 	clientType := clusterRequest.UserAgentClientType(r.Header.Get("User-Agent"))
 
-	err = n.LoadBalancerCreate(req, clientType)
+	listenAddress, err := n.LoadBalancerCreate(req, clientType)
 	if err != nil {
 		return response.SmartError(fmt.Errorf("Failed creating load balancer: %w", err))
 	}
 
-	lc := lifecycle.NetworkLoadBalancerCreated.Event(n, req.ListenAddress, request.CreateRequestor(r), nil)
+	lc := lifecycle.NetworkLoadBalancerCreated.Event(n, listenAddress.String(), request.CreateRequestor(r), nil)
 	s.Events.SendLifecycle(projectName, lc)
 
 	return response.SyncResponseLocation(true, nil, lc.Source)
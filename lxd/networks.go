@@ -68,6 +68,12 @@ var networkLeasesCmd = APIEndpoint{
 	Get: APIEndpointAction{Handler: networkLeasesGet, AccessHandler: allowPermission(entity.TypeNetwork, auth.EntitlementCanView, "networkName")},
 }
 
+var networkStaticAddressesCmd = APIEndpoint{
+	Path: "networks/{networkName}/static-addresses",
+
+	Get: APIEndpointAction{Handler: networkStaticAddressesGet, AccessHandler: allowPermission(entity.TypeNetwork, auth.EntitlementCanView, "networkName")},
+}
+
 var networkStateCmd = APIEndpoint{
 	Path: "networks/{networkName}/state",
 
@@ -1477,6 +1483,88 @@ func networkLeasesGet(d *Daemon, r *http.Request) response.Response {
 	return response.SyncResponse(true, leases)
 }
 
+// swagger:operation GET /1.0/networks/{name}/static-addresses networks networks_static_addresses_get
+//
+//	Get the static addresses
+//
+//	Returns a list of the static IP addresses of the instance NICs connected to the network on all cluster
+//	members, along with their conflicts with other NICs, DHCP leases and the subnet of the network.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	responses:
+//	  "200":
+//	    description: API endpoints
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of static addresses
+//	          items:
+//	            $ref: "#/definitions/NetworkStaticAddress"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func networkStaticAddressesGet(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	projectName, reqProject, err := project.NetworkProject(s.DB.Cluster, request.ProjectParam(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	networkName, err := url.PathUnescape(mux.Vars(r)["networkName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Attempt to load the network.
+	n, err := network.LoadByName(s, projectName, networkName)
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Failed loading network: %w", err))
+	}
+
+	// Check if project allows access to network.
+	if !project.NetworkAllowed(reqProject.Config, networkName, n.IsManaged()) {
+		return response.SmartError(api.StatusErrorf(http.StatusNotFound, "Network not found"))
+	}
+
+	if n.Type() != "bridge" {
+		return response.BadRequest(fmt.Errorf("Static addresses can only be listed for bridge networks"))
+	}
+
+	addresses, err := network.BridgeStaticAddresses(s, n)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, addresses)
+}
+
 func networkStartup(s *state.State) error {
 	var err error
 
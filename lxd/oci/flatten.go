@@ -0,0 +1,173 @@
+package oci
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Prefixes of the whiteout files that hide the files of the lower layers.
+const (
+	whiteoutPrefix = ".wh."
+	whiteoutOpaque = ".wh..wh..opq"
+)
+
+// Flatten writes the files of the layers to a tar archive, with the whiteouts of each layer applied to the layers
+// below it. The layers are given starting with the base layer, and the paths of the files are prefixed with prefix.
+//
+// The layers are read from the top layer down, so that only the topmost version of each file is written.
+func Flatten(tw *tar.Writer, layers []string, prefix string) error {
+	// Paths that have been written or deleted by an upper layer.
+	seen := map[string]bool{}
+
+	// Directories whose content from the lower layers is hidden, either because they were deleted or made opaque.
+	hidden := map[string]bool{}
+
+	for i := len(layers) - 1; i >= 0; i-- {
+		opaque, err := flattenLayer(tw, layers[i], prefix, seen, hidden)
+		if err != nil {
+			return fmt.Errorf("Failed flattening layer %d: %w", i, err)
+		}
+
+		// Opaque directories only hide the content of the layers below the one that they are in.
+		for _, dir := range opaque {
+			hidden[dir] = true
+		}
+	}
+
+	return nil
+}
+
+// flattenLayer writes the files of a layer that aren't hidden by the upper layers, and returns the opaque directories
+// of the layer.
+func flattenLayer(tw *tar.Writer, layer string, prefix string, seen map[string]bool, hidden map[string]bool) ([]string, error) {
+	f, err := os.Open(layer)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = f.Close() }()
+
+	r, err := decompress(f)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = r.Close() }()
+
+	var opaque []string
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		name := cleanPath(hdr.Name)
+		if name == "" {
+			continue
+		}
+
+		dir, base := path.Split(name)
+		dir = strings.TrimSuffix(dir, "/")
+
+		if base == whiteoutOpaque {
+			opaque = append(opaque, dir)
+			continue
+		}
+
+		if strings.HasPrefix(base, whiteoutPrefix) {
+			name = path.Join(dir, strings.TrimPrefix(base, whiteoutPrefix))
+			if !seen[name] && !isHidden(name, hidden) {
+				seen[name] = true
+				hidden[name] = true
+			}
+
+			continue
+		}
+
+		if seen[name] || isHidden(name, hidden) {
+			continue
+		}
+
+		seen[name] = true
+
+		// A file replacing a directory of a lower layer hides its content.
+		if hdr.Typeflag != tar.TypeDir {
+			hidden[name] = true
+		}
+
+		hdr.Name = path.Join(prefix, name)
+		if hdr.Typeflag == tar.TypeDir {
+			hdr.Name += "/"
+		}
+
+		if hdr.Typeflag == tar.TypeLink {
+			hdr.Linkname = path.Join(prefix, cleanPath(hdr.Linkname))
+		}
+
+		err = tw.WriteHeader(hdr)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = io.Copy(tw, tr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return opaque, nil
+}
+
+// isHidden returns whether one of the parent directories of the path is hidden.
+func isHidden(name string, hidden map[string]bool) bool {
+	for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if hidden[dir] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cleanPath returns the path relative to the root of the layer, or an empty string for the root itself.
+func cleanPath(name string) string {
+	name = path.Clean("/" + name)
+	return strings.TrimPrefix(name, "/")
+}
+
+// decompress returns a reader of the uncompressed content of a layer, which can be compressed with gzip or zstd.
+func decompress(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+
+		return zr.IOReadCloser(), nil
+	}
+
+	return io.NopCloser(br), nil
+}
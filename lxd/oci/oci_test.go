@@ -0,0 +1,245 @@
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReference(t *testing.T) {
+	cases := []struct {
+		ref      string
+		expected *Reference
+	}{
+		{"alpine", &Reference{Repository: "alpine", Tag: "latest"}},
+		{"library/alpine:3.19", &Reference{Repository: "library/alpine", Tag: "3.19"}},
+		{"org/app@sha256:abcd", &Reference{Repository: "org/app", Digest: "sha256:abcd"}},
+		{"org/app:1.0@sha256:abcd", &Reference{Repository: "org/app", Tag: "1.0", Digest: "sha256:abcd"}},
+		{"org/app@md5:abcd", nil},
+		{"", nil},
+		{"org/", nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.ref, func(t *testing.T) {
+			ref, err := ParseReference(c.ref)
+			if c.expected == nil {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, ref)
+		})
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:org/app:pull,push"`)
+	assert.Equal(t, "Bearer", scheme)
+	assert.Equal(t, map[string]string{
+		"realm":   "https://auth.example.com/token",
+		"service": "registry.example.com",
+		"scope":   "repository:org/app:pull,push",
+	}, params)
+}
+
+// testLayer returns a gzipped tar layer with the given files. Files with an empty content are directories.
+func testLayer(t *testing.T, files map[string]string) []byte {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for _, name := range names {
+		hdr := &tar.Header{Name: name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(files[name]))}
+		if files[name] == "" {
+			hdr.Typeflag = tar.TypeDir
+			hdr.Mode = 0755
+		}
+
+		require.NoError(t, tw.WriteHeader(hdr))
+		_, err := tw.Write([]byte(files[name]))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	return buf.Bytes()
+}
+
+// testRegistry serves an index with an amd64 and an arm64 image of the "org/app:1.0" repository, requiring a bearer
+// token for the given credentials.
+func testRegistry(t *testing.T, credentials string) *httptest.Server {
+	blobs := map[string][]byte{}
+	manifests := map[string][]byte{}
+	add := func(store map[string][]byte, content []byte) descriptor {
+		digest := fmt.Sprintf("sha256:%x", sha256.Sum256(content))
+		store[digest] = content
+		return descriptor{Digest: digest, Size: int64(len(content))}
+	}
+
+	image := func(architecture string, layers ...[]byte) descriptor {
+		config, err := json.Marshal(map[string]any{
+			"architecture": architecture,
+			"os":           "linux",
+			"config":       map[string]any{"Entrypoint": []string{"/app"}, "Cmd": []string{"--serve"}, "Env": []string{"PATH=/bin"}, "WorkingDir": "/srv"},
+		})
+		require.NoError(t, err)
+
+		m := manifest{MediaType: mediaTypeOCIManifest, Config: add(blobs, config)}
+		for _, layer := range layers {
+			d := add(blobs, layer)
+			d.MediaType = "application/vnd.oci.image.layer.v1.tar+gzip"
+			m.Layers = append(m.Layers, d)
+		}
+
+		buf, err := json.Marshal(m)
+		require.NoError(t, err)
+
+		d := add(manifests, buf)
+		d.MediaType = mediaTypeOCIManifest
+		d.Platform = &platform{Architecture: architecture, OS: "linux"}
+		return d
+	}
+
+	base := testLayer(t, map[string]string{"etc/": "", "etc/hostname": "base", "etc/old": "old", "var/": "", "var/cache/": "", "var/cache/a": "a", "opt/": "", "opt/b": "b"})
+	top := testLayer(t, map[string]string{"etc/": "", "etc/hostname": "top", "etc/.wh.old": "x", "var/.wh.cache": "x", "opt/": "", "opt/.wh..wh..opq": "x", "opt/c": "c", "app": "app"})
+
+	index, err := json.Marshal(manifest{MediaType: mediaTypeOCIIndex, Manifests: []descriptor{image("amd64", base, top), image("arm64", base)}})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			username, password, _ := r.BasicAuth()
+			if credentials != "" && username+":"+password != credentials {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			_ = json.NewEncoder(w).Encode(map[string]string{"token": "secret-token"})
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="http://%s/token",service="test"`, r.Host))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		ref, ok := strings.CutPrefix(r.URL.Path, "/v2/org/app/manifests/")
+		if ok {
+			content := manifests[ref]
+			if ref == "1.0" {
+				content = index
+			}
+
+			if content == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			_, _ = w.Write(content)
+			return
+		}
+
+		digest, ok := strings.CutPrefix(r.URL.Path, "/v2/org/app/blobs/")
+		if ok && blobs[digest] != nil {
+			_, _ = w.Write(blobs[digest])
+			return
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPull(t *testing.T) {
+	server := testRegistry(t, "user:pass")
+
+	client, err := NewClient(server.Client(), server.URL, "user:pass")
+	require.NoError(t, err)
+
+	ref, err := ParseReference("org/app:1.0")
+	require.NoError(t, err)
+
+	var progress []string
+	img, err := client.Pull(context.Background(), ref, []string{"x86_64", "aarch64"}, t.TempDir(), func(layer int, layers int, percent int64, speed int64) {
+		progress = append(progress, fmt.Sprintf("%d/%d", layer, layers))
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "x86_64", img.Architecture)
+	assert.Equal(t, []string{"/app"}, img.Entrypoint)
+	assert.Equal(t, []string{"--serve"}, img.Cmd)
+	assert.Equal(t, "/srv", img.WorkingDir)
+	assert.Len(t, img.Layers, 2)
+	assert.Contains(t, progress, "1/2")
+	assert.Contains(t, progress, "2/2")
+
+	// The layers are flattened with their whiteouts applied.
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	require.NoError(t, Flatten(tw, img.Layers, "rootfs"))
+	require.NoError(t, tw.Close())
+
+	files := map[string]string{}
+	tr := tar.NewReader(&buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		require.NoError(t, err)
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[hdr.Name] = string(content)
+	}
+
+	assert.Equal(t, map[string]string{
+		"rootfs/app":          "app",
+		"rootfs/etc/":         "",
+		"rootfs/etc/hostname": "top",
+		"rootfs/opt/":         "",
+		"rootfs/opt/c":        "c",
+		"rootfs/var/":         "",
+	}, files)
+
+	// The image of another architecture is selected from the index.
+	img, err = client.Pull(context.Background(), ref, []string{"aarch64"}, t.TempDir(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "aarch64", img.Architecture)
+	assert.Len(t, img.Layers, 1)
+
+	// Unsupported architectures are refused.
+	_, err = client.Pull(context.Background(), ref, []string{"s390x"}, t.TempDir(), nil)
+	assert.Error(t, err)
+
+	// The registry refuses the wrong credentials.
+	client, err = NewClient(server.Client(), server.URL, "user:wrong")
+	require.NoError(t, err)
+
+	_, err = client.Pull(context.Background(), ref, []string{"x86_64"}, t.TempDir(), nil)
+	assert.Error(t, err)
+}
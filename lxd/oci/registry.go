@@ -0,0 +1,683 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/canonical/lxd/shared/cancel"
+	"github.com/canonical/lxd/shared/ioprogress"
+	"github.com/canonical/lxd/shared/osarch"
+	"github.com/canonical/lxd/shared/version"
+)
+
+// DefaultRegistry is the registry used when none is given, which is Docker Hub.
+const DefaultRegistry = "https://registry-1.docker.io"
+
+// Media types of the manifests that can be pulled.
+const (
+	mediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+	mediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+)
+
+// maxManifestSize is the maximum size of the manifests and image configs that are read from the registry.
+const maxManifestSize = 4 * 1024 * 1024
+
+// descriptor describes a content addressable blob or manifest.
+type descriptor struct {
+	MediaType string    `json:"mediaType"`
+	Digest    string    `json:"digest"`
+	Size      int64     `json:"size"`
+	Platform  *platform `json:"platform,omitempty"`
+}
+
+// platform is the platform that a manifest of an index applies to.
+type platform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// manifest is either an image manifest or an index of image manifests for different platforms.
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Manifests []descriptor `json:"manifests"`
+	Config    descriptor   `json:"config"`
+	Layers    []descriptor `json:"layers"`
+}
+
+// imageConfig is the configuration blob of an image.
+type imageConfig struct {
+	Architecture string    `json:"architecture"`
+	OS           string    `json:"os"`
+	Variant      string    `json:"variant"`
+	Created      time.Time `json:"created"`
+	Config       struct {
+		Entrypoint []string          `json:"Entrypoint"`
+		Cmd        []string          `json:"Cmd"`
+		Env        []string          `json:"Env"`
+		WorkingDir string            `json:"WorkingDir"`
+		User       string            `json:"User"`
+		Labels     map[string]string `json:"Labels"`
+	} `json:"config"`
+}
+
+// Reference is a reference to an image in a registry repository, by tag or by digest.
+type Reference struct {
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference parses an image reference of the form "repository[:tag][@digest]". The tag defaults to "latest".
+func ParseReference(ref string) (*Reference, error) {
+	r := &Reference{}
+
+	ref, r.Digest, _ = strings.Cut(ref, "@")
+	if r.Digest != "" && !strings.HasPrefix(r.Digest, "sha256:") {
+		return nil, fmt.Errorf("Unsupported digest %q", r.Digest)
+	}
+
+	// The tag follows the last colon, unless it is part of a registry port in the repository.
+	i := strings.LastIndex(ref, ":")
+	if i >= 0 && !strings.Contains(ref[i:], "/") {
+		r.Tag = ref[i+1:]
+		ref = ref[:i]
+	}
+
+	r.Repository = ref
+	if r.Repository == "" || strings.HasPrefix(r.Repository, "/") || strings.HasSuffix(r.Repository, "/") {
+		return nil, fmt.Errorf("Invalid image reference %q", ref)
+	}
+
+	if r.Tag == "" && r.Digest == "" {
+		r.Tag = "latest"
+	}
+
+	return r, nil
+}
+
+// String returns the reference in the form "repository[:tag][@digest]".
+func (r Reference) String() string {
+	s := r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+
+	return s
+}
+
+// Image is an image pulled from a registry.
+type Image struct {
+	// Digest of the image manifest.
+	Digest string
+
+	// Architecture of the image, as a LXD architecture name.
+	Architecture string
+
+	Created    time.Time
+	Entrypoint []string
+	Cmd        []string
+	Env        []string
+	WorkingDir string
+	User       string
+	Labels     map[string]string
+
+	// Paths of the downloaded layers, starting with the base layer.
+	Layers []string
+}
+
+// Progress is called while downloading the layers of an image, with the number of the layer being downloaded
+// (starting at 1), the total number of layers, and the percentage and speed of the download of the layer.
+type Progress func(layer int, layers int, percent int64, speed int64)
+
+// Client pulls images from a registry implementing the OCI distribution specification.
+type Client struct {
+	http     *http.Client
+	url      *url.URL
+	username string
+	password string
+	token    string
+	basic    bool
+
+	// Canceler is used to cancel the downloads of the client, if set.
+	Canceler *cancel.HTTPRequestCanceller
+}
+
+// NewClient returns a client for the registry at the given URL, which defaults to Docker Hub. The credentials are
+// either empty or of the form "username:password".
+func NewClient(httpClient *http.Client, server string, credentials string) (*Client, error) {
+	u, err := RegistryURL(server)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{http: httpClient, url: u}
+	if credentials != "" {
+		var ok bool
+		c.username, c.password, ok = strings.Cut(credentials, ":")
+		if !ok {
+			return nil, fmt.Errorf(`Invalid registry credentials, expected "<username>:<password>"`)
+		}
+	}
+
+	return c, nil
+}
+
+// RegistryURL returns the URL of a registry. The scheme defaults to HTTPS, and Docker Hub is used when the registry is
+// empty or "docker.io".
+func RegistryURL(server string) (*url.URL, error) {
+	if server == "" || server == "docker.io" {
+		server = DefaultRegistry
+	}
+
+	if !strings.Contains(server, "://") {
+		server = "https://" + server
+	}
+
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid registry URL %q: %w", server, err)
+	}
+
+	if u.Scheme != "https" && u.Scheme != "http" || u.Host == "" {
+		return nil, fmt.Errorf("Invalid registry URL %q", server)
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	return u, nil
+}
+
+// Host returns the host of the registry, as used to look up its credentials.
+func (c *Client) Host() string {
+	return c.url.Host
+}
+
+// Pull downloads the manifest, config and layers of the image to the given directory. If the reference is to an
+// index, the manifest of the first of the given architectures that it contains is used.
+func (c *Client) Pull(ctx context.Context, ref *Reference, architectures []string, dir string, progress Progress) (*Image, error) {
+	repository := ref.Repository
+
+	// Images of Docker Hub without a namespace are in the library namespace.
+	if c.url.Host == "registry-1.docker.io" && !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+
+	manifestRef := ref.Digest
+	if manifestRef == "" {
+		manifestRef = ref.Tag
+	}
+
+	m, digest, err := c.getManifest(ctx, repository, manifestRef)
+	if err != nil {
+		return nil, err
+	}
+
+	// Select the manifest of the architecture from the index.
+	if m.MediaType == mediaTypeOCIIndex || m.MediaType == mediaTypeDockerManifestList {
+		d, err := selectManifest(m.Manifests, architectures)
+		if err != nil {
+			return nil, err
+		}
+
+		m, digest, err = c.getManifest(ctx, repository, d.Digest)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if m.MediaType != mediaTypeOCIManifest && m.MediaType != mediaTypeDockerManifest {
+		return nil, fmt.Errorf("Unsupported manifest media type %q", m.MediaType)
+	}
+
+	var buf bytes.Buffer
+	err = c.getBlob(ctx, repository, m.Config, &buf, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Failed getting image config: %w", err)
+	}
+
+	var config imageConfig
+	err = json.Unmarshal(buf.Bytes(), &config)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid image config: %w", err)
+	}
+
+	if config.OS != "" && config.OS != "linux" {
+		return nil, fmt.Errorf("Unsupported image OS %q", config.OS)
+	}
+
+	architecture, err := architectureName(platform{Architecture: config.Architecture, Variant: config.Variant})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(architectures) > 0 && !contains(architectures, architecture) {
+		return nil, fmt.Errorf("Image architecture %q isn't supported by the server", architecture)
+	}
+
+	img := &Image{
+		Digest:       digest,
+		Architecture: architecture,
+		Created:      config.Created,
+		Entrypoint:   config.Config.Entrypoint,
+		Cmd:          config.Config.Cmd,
+		Env:          config.Config.Env,
+		WorkingDir:   config.Config.WorkingDir,
+		User:         config.Config.User,
+		Labels:       config.Config.Labels,
+	}
+
+	for i, layer := range m.Layers {
+		if strings.Contains(layer.MediaType, "nondistributable") || strings.Contains(layer.MediaType, "foreign") {
+			return nil, fmt.Errorf("Unsupported non-distributable layer %q", layer.Digest)
+		}
+
+		path := filepath.Join(dir, fmt.Sprintf("layer%d", i))
+		err = c.downloadLayer(ctx, repository, layer, path, func(percent int64, speed int64) {
+			if progress != nil {
+				progress(i+1, len(m.Layers), percent, speed)
+			}
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Failed downloading layer %q: %w", layer.Digest, err)
+		}
+
+		img.Layers = append(img.Layers, path)
+	}
+
+	return img, nil
+}
+
+// downloadLayer downloads a layer to the given path.
+func (c *Client) downloadLayer(ctx context.Context, repository string, layer descriptor, path string, handler func(int64, int64)) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = f.Close() }()
+
+	err = c.getBlob(ctx, repository, layer, f, &ioprogress.ProgressTracker{Length: layer.Size, Handler: handler})
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// getManifest gets a manifest by tag or digest and returns it with its digest.
+func (c *Client) getManifest(ctx context.Context, repository string, ref string) (*manifest, string, error) {
+	accept := []string{mediaTypeOCIIndex, mediaTypeOCIManifest, mediaTypeDockerManifestList, mediaTypeDockerManifest}
+	resp, err := c.do(ctx, repository, "/v2/"+repository+"/manifests/"+ref, accept)
+	if err != nil {
+		return nil, "", err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", responseError(resp, fmt.Sprintf("Failed getting manifest %q of %q", ref, repository))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize))
+	if err != nil {
+		return nil, "", err
+	}
+
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(body))
+	if strings.HasPrefix(ref, "sha256:") && ref != digest {
+		return nil, "", fmt.Errorf("Digest mismatch for manifest %q, got %q", ref, digest)
+	}
+
+	m := &manifest{}
+	err = json.Unmarshal(body, m)
+	if err != nil {
+		return nil, "", fmt.Errorf("Invalid manifest %q of %q: %w", ref, repository, err)
+	}
+
+	// Older manifests only have their media type in the response headers.
+	if m.MediaType == "" {
+		m.MediaType, _, _ = strings.Cut(resp.Header.Get("Content-Type"), ";")
+	}
+
+	return m, digest, nil
+}
+
+// getBlob writes a blob to the given writer after checking its digest, and tracks the progress of the download if
+// a tracker is given.
+func (c *Client) getBlob(ctx context.Context, repository string, d descriptor, w io.Writer, tracker *ioprogress.ProgressTracker) error {
+	if !strings.HasPrefix(d.Digest, "sha256:") {
+		return fmt.Errorf("Unsupported digest %q", d.Digest)
+	}
+
+	resp, err := c.do(ctx, repository, "/v2/"+repository+"/blobs/"+d.Digest, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp, fmt.Sprintf("Failed getting blob %q of %q", d.Digest, repository))
+	}
+
+	var body io.ReadCloser = resp.Body
+	if tracker != nil {
+		body = &ioprogress.ProgressReader{ReadCloser: resp.Body, Tracker: tracker}
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(w, hash), body)
+	if err != nil {
+		return err
+	}
+
+	if d.Size > 0 && size != d.Size {
+		return fmt.Errorf("Size mismatch for blob %q, got %d bytes instead of %d", d.Digest, size, d.Size)
+	}
+
+	digest := fmt.Sprintf("sha256:%x", hash.Sum(nil))
+	if digest != d.Digest {
+		return fmt.Errorf("Digest mismatch for blob %q, got %q", d.Digest, digest)
+	}
+
+	return nil
+}
+
+// do sends a GET request to the registry, authenticating with the challenge of the registry if needed.
+func (c *Client) do(ctx context.Context, repository string, path string, accept []string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url.String()+path, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("User-Agent", version.UserAgent)
+		if len(accept) > 0 {
+			req.Header.Set("Accept", strings.Join(accept, ", "))
+		}
+
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		} else if c.basic {
+			req.SetBasicAuth(c.username, c.password)
+		}
+
+		resp, doneCh, err := cancel.CancelableDownload(c.Canceler, c.http.Do, req)
+		if err != nil {
+			return nil, fmt.Errorf("Failed sending request to registry %q: %w", c.url.Host, err)
+		}
+
+		// Signal the canceler once the response has been read.
+		resp.Body = &doneReadCloser{ReadCloser: resp.Body, done: doneCh}
+
+		if resp.StatusCode != http.StatusUnauthorized || attempt > 0 {
+			return resp, nil
+		}
+
+		challenge := resp.Header.Get("WWW-Authenticate")
+		_ = resp.Body.Close()
+
+		err = c.authenticate(ctx, challenge, repository)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// authenticate handles the authentication challenge of the registry, getting a bearer token if needed.
+func (c *Client) authenticate(ctx context.Context, challenge string, repository string) error {
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if c.username == "" {
+			return fmt.Errorf("Registry %q requires credentials", c.url.Host)
+		}
+
+		c.basic = true
+		return nil
+	case "bearer":
+	default:
+		return fmt.Errorf("Unsupported authentication challenge from registry %q: %q", c.url.Host, challenge)
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Scheme == "" {
+		return fmt.Errorf("Invalid authentication realm %q from registry %q", params["realm"], c.url.Host)
+	}
+
+	scope := params["scope"]
+	if scope == "" {
+		scope = "repository:" + repository + ":pull"
+	}
+
+	query := realm.Query()
+	query.Set("scope", scope)
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("User-Agent", version.UserAgent)
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("Failed getting token for registry %q: %w", c.url.Host, err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp, fmt.Sprintf("Failed getting token for registry %q", c.url.Host))
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+
+	err = json.NewDecoder(io.LimitReader(resp.Body, maxManifestSize)).Decode(&token)
+	if err != nil {
+		return fmt.Errorf("Invalid token from registry %q: %w", c.url.Host, err)
+	}
+
+	c.token = token.Token
+	if c.token == "" {
+		c.token = token.AccessToken
+	}
+
+	if c.token == "" {
+		return fmt.Errorf("No token returned for registry %q", c.url.Host)
+	}
+
+	return nil
+}
+
+// parseChallenge parses a WWW-Authenticate header into its scheme and parameters.
+func parseChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := map[string]string{}
+
+	for rest != "" {
+		var key string
+		key, rest, _ = strings.Cut(rest, "=")
+		key = strings.ToLower(strings.Trim(key, " ,"))
+
+		var value strings.Builder
+		rest = strings.TrimLeft(rest, " ")
+		if strings.HasPrefix(rest, `"`) {
+			// Quoted values can contain commas and escaped characters.
+			i := 1
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+
+				value.WriteByte(rest[i])
+			}
+
+			rest = rest[min(i+1, len(rest)):]
+		} else {
+			var v string
+			v, rest, _ = strings.Cut(rest, ",")
+			value.WriteString(strings.TrimSpace(v))
+		}
+
+		_, rest, _ = strings.Cut(rest, ",")
+		if key != "" {
+			params[key] = value.String()
+		}
+	}
+
+	return scheme, params
+}
+
+// selectManifest returns the Linux manifest of the index for the first of the given architectures that it contains,
+// or for any supported architecture if none are given.
+func selectManifest(manifests []descriptor, architectures []string) (*descriptor, error) {
+	byArchitecture := map[string]*descriptor{}
+	for i, d := range manifests {
+		if d.Platform == nil || d.Platform.OS != "linux" {
+			continue
+		}
+
+		architecture, err := architectureName(*d.Platform)
+		if err != nil {
+			continue
+		}
+
+		_, ok := byArchitecture[architecture]
+		if !ok {
+			byArchitecture[architecture] = &manifests[i]
+		}
+
+		if len(architectures) == 0 {
+			return &manifests[i], nil
+		}
+	}
+
+	for _, architecture := range architectures {
+		d, ok := byArchitecture[architecture]
+		if ok {
+			return d, nil
+		}
+	}
+
+	return nil, fmt.Errorf("No image found for the architectures of the server (%s)", strings.Join(architectures, ", "))
+}
+
+// architectureName returns the LXD architecture name of an OCI platform.
+func architectureName(p platform) (string, error) {
+	name := p.Architecture
+	switch {
+	case p.Architecture == "arm" && p.Variant == "v6":
+		name = "armv6l"
+	case p.Architecture == "arm" && (p.Variant == "v7" || p.Variant == ""):
+		name = "armv7l"
+	case p.Architecture == "arm" && p.Variant == "v8":
+		name = "armv8l"
+	}
+
+	id, err := osarch.ArchitectureId(name)
+	if err != nil {
+		return "", err
+	}
+
+	return osarch.ArchitectureName(id)
+}
+
+// responseError returns an error for a failed response, including the error message of the registry if any.
+func responseError(resp *http.Response, msg string) error {
+	var registryErr struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize))
+	err := json.Unmarshal(body, &registryErr)
+	if err == nil && len(registryErr.Errors) > 0 {
+		return fmt.Errorf("%s: %s (%s)", msg, registryErr.Errors[0].Message, registryErr.Errors[0].Code)
+	}
+
+	return fmt.Errorf("%s: %s", msg, resp.Status)
+}
+
+// doneReadCloser signals a channel when closed.
+type doneReadCloser struct {
+	io.ReadCloser
+	done chan bool
+}
+
+// Close closes the reader and signals the channel.
+func (r *doneReadCloser) Close() error {
+	err := r.ReadCloser.Close()
+	if r.done != nil {
+		close(r.done)
+		r.done = nil
+	}
+
+	return err
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseCredentials parses a comma-separated list of registry credentials of the form
+// "<registry>=<username>:<password>" into a map of registry host to credentials.
+func ParseCredentials(value string) (map[string]string, error) {
+	credentials := map[string]string{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		registry, userPassword, ok := strings.Cut(entry, "=")
+		username, _, hasPassword := strings.Cut(userPassword, ":")
+		if !ok || !hasPassword || username == "" {
+			return nil, fmt.Errorf(`Invalid registry credentials for %q, expected "<registry>=<username>:<password>"`, registry)
+		}
+
+		u, err := RegistryURL(registry)
+		if err != nil {
+			return nil, err
+		}
+
+		credentials[u.Host] = userPassword
+	}
+
+	return credentials, nil
+}
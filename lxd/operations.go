@@ -33,7 +33,7 @@ var operationCmd = APIEndpoint{
 	Path: "operations/{id}",
 
 	Delete: APIEndpointAction{Handler: operationDelete, AccessHandler: allowAuthenticated},
-	Get:    APIEndpointAction{Handler: operationGet, AccessHandler: allowAuthenticated},
+	Get:    APIEndpointAction{Handler: operationGet, AccessHandler: allowAuthenticated, NoStaleReads: true},
 }
 
 var operationsCmd = APIEndpoint{
@@ -45,13 +45,13 @@ var operationsCmd = APIEndpoint{
 var operationWait = APIEndpoint{
 	Path: "operations/{id}/wait",
 
-	Get: APIEndpointAction{Handler: operationWaitGet, AllowUntrusted: true},
+	Get: APIEndpointAction{Handler: operationWaitGet, AllowUntrusted: true, NoStaleReads: true},
 }
 
 var operationWebsocket = APIEndpoint{
 	Path: "operations/{id}/websocket",
 
-	Get: APIEndpointAction{Handler: operationWebsocketGet, AllowUntrusted: true},
+	Get: APIEndpointAction{Handler: operationWebsocketGet, AllowUntrusted: true, NoStaleReads: true},
 }
 
 // waitForOperations waits for operations to finish.
@@ -599,7 +599,7 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 	// Get all nodes with running operations in this project.
 	var membersWithOps []string
 	var members []db.NodeInfo
-	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
 		var err error
 
 		if allProjects {
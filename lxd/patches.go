@@ -15,6 +15,7 @@ import (
 	"github.com/canonical/lxd/lxd/backup"
 	"github.com/canonical/lxd/lxd/certificate"
 	"github.com/canonical/lxd/lxd/cluster"
+	clusterConfig "github.com/canonical/lxd/lxd/cluster/config"
 	"github.com/canonical/lxd/lxd/db"
 	dbCluster "github.com/canonical/lxd/lxd/db/cluster"
 	"github.com/canonical/lxd/lxd/db/query"
@@ -85,6 +86,7 @@ var patches = []patch{
 	{name: "storage_unset_invalid_block_settings", stage: patchPostDaemonStorage, run: patchStorageUnsetInvalidBlockSettings},
 	{name: "candid_rbac_remove_config_keys", stage: patchPreDaemonStorage, run: patchRemoveCandidRBACConfigKeys},
 	{name: "storage_set_volume_uuid", stage: patchPostDaemonStorage, run: patchStorageSetVolumeUUID},
+	{name: "core_https_trusted_proxy_remove_invalid", stage: patchPreDaemonStorage, run: patchTrustedProxyRemoveInvalid},
 }
 
 type patch struct {
@@ -1550,4 +1552,44 @@ func patchStorageSetVolumeUUID(_ string, d *Daemon) error {
 	return nil
 }
 
+// patchTrustedProxyRemoveInvalid removes the entries of core.https_trusted_proxy that aren't IP addresses or subnets.
+// They used to be ignored, but are now rejected, which would cause the whole key to be dropped when loading the config.
+func patchTrustedProxyRemoveInvalid(_ string, d *Daemon) error {
+	s := d.State()
+	err := s.DB.Cluster.Transaction(s.ShutdownCtx, func(ctx context.Context, tx *db.ClusterTx) error {
+		config, err := tx.Config(ctx)
+		if err != nil {
+			return err
+		}
+
+		value, ok := config["core.https_trusted_proxy"]
+		if !ok {
+			return nil
+		}
+
+		valid := []string{}
+		for _, proxy := range shared.SplitNTrimSpace(value, ",", -1, true) {
+			err := clusterConfig.TrustedProxyValidator(proxy)
+			if err != nil {
+				logger.Warn("Removing invalid trusted proxy", logger.Ctx{"proxy": proxy, "err": err})
+				continue
+			}
+
+			valid = append(valid, proxy)
+		}
+
+		newValue := strings.Join(valid, ",")
+		if newValue == value {
+			return nil
+		}
+
+		return tx.UpdateClusterConfig(map[string]string{"core.https_trusted_proxy": newValue})
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to remove invalid trusted proxies: %w", err)
+	}
+
+	return nil
+}
+
 // Patches end here
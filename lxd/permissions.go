@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/canonical/lxd/lxd/auth"
 	"github.com/canonical/lxd/lxd/db"
@@ -42,6 +43,21 @@ var permissionsCmd = APIEndpoint{
 //	    description: Type of entity
 //	    type: string
 //	    example: instance
+//	  - in: query
+//	    name: search
+//	    description: Only return permissions on entities whose name, or project or parent entity name, contains the given string (case insensitive)
+//	    type: string
+//	    example: c1
+//	  - in: query
+//	    name: limit
+//	    description: Maximum number of permissions to return
+//	    type: integer
+//	    example: 100
+//	  - in: query
+//	    name: offset
+//	    description: Number of permissions to skip
+//	    type: integer
+//	    example: 0
 //	responses:
 //	  "200":
 //	    description: API endpoints
@@ -91,6 +107,21 @@ var permissionsCmd = APIEndpoint{
 //	    description: Type of entity
 //	    type: string
 //	    example: instance
+//	  - in: query
+//	    name: search
+//	    description: Only return permissions on entities whose name, or project or parent entity name, contains the given string (case insensitive)
+//	    type: string
+//	    example: c1
+//	  - in: query
+//	    name: limit
+//	    description: Maximum number of permissions to return
+//	    type: integer
+//	    example: 100
+//	  - in: query
+//	    name: offset
+//	    description: Number of permissions to skip
+//	    type: integer
+//	    example: 0
 //	responses:
 //	  "200":
 //	    description: API endpoints
@@ -122,6 +153,7 @@ var permissionsCmd = APIEndpoint{
 func getPermissions(d *Daemon, r *http.Request) response.Response {
 	projectNameFilter := r.URL.Query().Get("project")
 	entityTypeFilter := r.URL.Query().Get("entity-type")
+	search := r.URL.Query().Get("search")
 	recursion := r.URL.Query().Get("recursion")
 	var entityTypes []entity.Type
 	if entityTypeFilter != "" {
@@ -132,110 +164,308 @@ func getPermissions(d *Daemon, r *http.Request) response.Response {
 		}
 
 		entityTypes = append(entityTypes, entityType)
+	} else {
+		entityTypes = cluster.ListableEntityTypes(projectNameFilter)
+	}
+
+	limit, err := permissionsQueryInt(r, "limit")
+	if err != nil {
+		return response.BadRequest(err)
 	}
 
-	var entityURLs map[entity.Type]map[int]*api.URL
-	var permissions []cluster.Permission
-	var groupsByPermissionID map[int][]cluster.AuthGroup
-	err := d.State().DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
-		var err error
+	offset, err := permissionsQueryInt(r, "offset")
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Permissions are listed by entity type, then by entity. Each entity has a permission for each entitlement of its
+	// type, so the page of permissions is mapped to a page of entities of each type. Only the entities in the page
+	// (and their assigned permissions) are loaded.
+	var apiPermissionInfos []api.PermissionInfo
+	err = d.State().DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
 		if projectNameFilter != "" {
 			// Validate that the project exists first.
-			_, err = cluster.GetProject(ctx, tx.Tx(), projectNameFilter)
+			_, err := cluster.GetProject(ctx, tx.Tx(), projectNameFilter)
 			if err != nil {
 				return err
 			}
 		}
 
-		if recursion == "1" {
-			permissions, err = cluster.GetPermissions(ctx, tx.Tx())
-			if err != nil {
-				return fmt.Errorf("Failed to get currently assigned permissions: %w", err)
+		apiPermissionInfos = []api.PermissionInfo{}
+		skip := offset
+		remaining := -1
+		if limit > 0 {
+			remaining = limit
+		}
+
+		for _, entityType := range entityTypes {
+			if remaining == 0 {
+				break
 			}
 
-			groupsByPermissionID, err = cluster.GetAllAuthGroupsByPermissionID(ctx, tx.Tx())
+			entitlements, err := auth.EntitlementsByEntityType(entityType)
 			if err != nil {
-				return fmt.Errorf("Failed to get groups by permission mapping: %w", err)
+				return fmt.Errorf("Failed to list available entitlements for entity type %q: %w", entityType, err)
 			}
-		}
 
-		entityURLs, err = cluster.GetEntityURLs(ctx, tx.Tx(), projectNameFilter, entityTypes...)
-		if err != nil {
-			return err
-		}
+			if len(entitlements) == 0 {
+				continue
+			}
 
-		return nil
-	})
-	if err != nil {
-		return response.SmartError(err)
-	}
+			// Skip all entities of this type if they are before the offset.
+			entityOffset := 0
+			if skip > 0 {
+				count, err := cluster.CountEntities(ctx, tx.Tx(), entityType, projectNameFilter, search)
+				if err != nil {
+					return err
+				}
 
-	// If we're recursing, convert the groupsByPermissionID map into a map of cluster.Permission to list of group names.
-	assignedPermissions := make(map[cluster.Permission][]string, len(groupsByPermissionID))
-	if recursion == "1" {
-		for permissionID, groups := range groupsByPermissionID {
-			var perm cluster.Permission
-			for _, p := range permissions {
-				if permissionID == p.ID {
-					perm = p
-
-					// A permission is unique via its entity ID, entity type, and entitlement. Set the ID to zero
-					// so we can create a map key from the entityURL map below.
-					perm.ID = 0
-					break
+				if skip >= count*len(entitlements) {
+					skip -= count * len(entitlements)
+					continue
 				}
-			}
 
-			groupNames := make([]string, 0, len(groups))
-			for _, g := range groups {
-				groupNames = append(groupNames, g.Name)
+				entityOffset = skip / len(entitlements)
+				skip = skip % len(entitlements)
 			}
 
-			assignedPermissions[perm] = groupNames
-		}
-	}
+			entityLimit := -1
+			if remaining > 0 {
+				entityLimit = (skip + remaining + len(entitlements) - 1) / len(entitlements)
+			}
 
-	var apiPermissions []api.Permission
-	var apiPermissionInfos []api.PermissionInfo
-	for entityType, entities := range entityURLs {
-		for entityID, entityURL := range entities {
-			entitlements, err := auth.EntitlementsByEntityType(entityType)
+			entityIDs, entityURLs, err := cluster.GetEntityURLsPage(ctx, tx.Tx(), entityType, projectNameFilter, search, entityLimit, entityOffset)
 			if err != nil {
-				return response.InternalError(fmt.Errorf("Failed to list available entitlements for entity type %q: %w", entityType, err))
+				return err
+			}
+
+			var groupNames map[int]map[auth.Entitlement][]string
+			if recursion == "1" {
+				groupNames, err = cluster.GetAuthGroupNamesByEntityIDs(ctx, tx.Tx(), entityType, entityIDs)
+				if err != nil {
+					return err
+				}
 			}
 
-			for _, entitlement := range entitlements {
-				if recursion == "1" {
-					permissionInfo := api.PermissionInfo{
+			for _, entityID := range entityIDs {
+				for _, entitlement := range entitlements {
+					if skip > 0 {
+						skip--
+						continue
+					}
+
+					if remaining == 0 {
+						break
+					}
+
+					apiPermissionInfos = append(apiPermissionInfos, api.PermissionInfo{
 						Permission: api.Permission{
 							EntityType:      string(entityType),
-							EntityReference: entityURL.String(),
+							EntityReference: entityURLs[entityID].String(),
 							Entitlement:     string(entitlement),
 						},
-						// Get the groups from the assigned permissions map. We don't have the permission ID in scope
-						// here. Thats why we set it to zero above.
-						Groups: assignedPermissions[cluster.Permission{
-							Entitlement: entitlement,
-							EntityType:  cluster.EntityType(entityType),
-							EntityID:    entityID,
-						}],
-					}
-
-					apiPermissionInfos = append(apiPermissionInfos, permissionInfo)
-				} else {
-					apiPermissions = append(apiPermissions, api.Permission{
-						EntityType:      string(entityType),
-						EntityReference: entityURL.String(),
-						Entitlement:     string(entitlement),
+						Groups: groupNames[entityID][entitlement],
 					})
+
+					if remaining > 0 {
+						remaining--
+					}
 				}
 			}
 		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
 	}
 
 	if recursion == "1" {
 		return response.SyncResponse(true, apiPermissionInfos)
 	}
 
+	apiPermissions := make([]api.Permission, 0, len(apiPermissionInfos))
+	for _, permissionInfo := range apiPermissionInfos {
+		apiPermissions = append(apiPermissions, permissionInfo.Permission)
+	}
+
 	return response.SyncResponse(true, apiPermissions)
 }
+
+// permissionsQueryInt returns the value of the given pagination query parameter, or zero if it is not set.
+func permissionsQueryInt(r *http.Request, key string) (int, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("Invalid `%s` query parameter %q: Must be a non-negative integer", key, value)
+	}
+
+	return n, nil
+}
+
+var entitlementsCmd = APIEndpoint{
+	Name: "entitlements",
+	Path: "auth/entitlements",
+	Get: APIEndpointAction{
+		Handler:       getEntitlements,
+		AccessHandler: allowAuthenticated,
+	},
+}
+
+// swagger:operation GET /1.0/auth/entitlements?recursion=1 permissions entitlements_get_recursion1
+//
+//	Get the entitlements with their descriptions
+//
+//	Returns the entitlements that can be granted on each entity type, with a description and category of each
+//	entitlement and the broader entitlements that imply it.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    description: Entitlements
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of entitlements by entity type
+//	          items:
+//	            $ref: "#/definitions/EntityTypeEntitlementsFull"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+
+// swagger:operation GET /1.0/auth/entitlements permissions entitlements_get
+//
+//	Get the entitlements
+//
+//	Returns the entitlements that can be granted on each entity type, including those that can be granted on all
+//	entities of the type within a parent entity.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    description: Entitlements
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of entitlements by entity type
+//	          items:
+//	            $ref: "#/definitions/EntityTypeEntitlements"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func getEntitlements(d *Daemon, r *http.Request) response.Response {
+	if r.URL.Query().Get("recursion") == "1" {
+		return getEntitlementsFull()
+	}
+
+	entityTypes := entity.Types()
+	result := make([]api.EntityTypeEntitlements, 0, len(entityTypes))
+	for _, entityType := range entityTypes {
+		entitlements, err := auth.EntitlementsByEntityType(entityType)
+		if err != nil {
+			return response.InternalError(err)
+		}
+
+		entityTypeEntitlements := api.EntityTypeEntitlements{
+			EntityType:          string(entityType),
+			Entitlements:        make([]string, 0, len(entitlements)),
+			SubtreeEntitlements: make(map[string][]string),
+		}
+
+		for _, entitlement := range entitlements {
+			entityTypeEntitlements.Entitlements = append(entityTypeEntitlements.Entitlements, string(entitlement))
+		}
+
+		for parentEntityType, subtreeEntitlements := range auth.SubtreeEntitlementsByEntityType(entityType) {
+			for _, entitlement := range subtreeEntitlements {
+				entityTypeEntitlements.SubtreeEntitlements[string(parentEntityType)] = append(entityTypeEntitlements.SubtreeEntitlements[string(parentEntityType)], string(entitlement))
+			}
+		}
+
+		result = append(result, entityTypeEntitlements)
+	}
+
+	return response.SyncResponse(true, result)
+}
+
+// getEntitlementsFull returns the entitlements that can be granted on each entity type along with their definitions.
+func getEntitlementsFull() response.Response {
+	entityTypes := entity.Types()
+	result := make([]api.EntityTypeEntitlementsFull, 0, len(entityTypes))
+	for _, entityType := range entityTypes {
+		definitions, err := auth.EntitlementDefinitionsByEntityType(entityType)
+		if err != nil {
+			return response.InternalError(err)
+		}
+
+		entityTypeEntitlements := api.EntityTypeEntitlementsFull{
+			EntityType:          string(entityType),
+			Entitlements:        make([]api.EntitlementInfo, 0, len(definitions)),
+			SubtreeEntitlements: make(map[string][]string),
+		}
+
+		for _, definition := range definitions {
+			impliedBy := make([]string, 0, len(definition.ImpliedBy))
+			for _, entitlement := range definition.ImpliedBy {
+				impliedBy = append(impliedBy, string(entitlement))
+			}
+
+			entityTypeEntitlements.Entitlements = append(entityTypeEntitlements.Entitlements, api.EntitlementInfo{
+				Name:        string(definition.Entitlement),
+				Description: definition.Description,
+				Category:    string(definition.Category),
+				ImpliedBy:   impliedBy,
+			})
+		}
+
+		for parentEntityType, subtreeEntitlements := range auth.SubtreeEntitlementsByEntityType(entityType) {
+			for _, entitlement := range subtreeEntitlements {
+				entityTypeEntitlements.SubtreeEntitlements[string(parentEntityType)] = append(entityTypeEntitlements.SubtreeEntitlements[string(parentEntityType)], string(entitlement))
+			}
+		}
+
+		result = append(result, entityTypeEntitlements)
+	}
+
+	return response.SyncResponse(true, result)
+}
@@ -302,7 +302,7 @@ func GetImageSpaceBudget(globalConfig *clusterConfig.Config, tx *db.ClusterTx, p
 		return -1, err
 	}
 
-	instances, err := expandInstancesConfigAndDevices(globalConfigDump, info.Instances, info.Profiles)
+	instances, err := expandInstancesConfigAndDevices(globalConfigDump, info.Project.Config, info.Instances, info.Profiles)
 	if err != nil {
 		return -1, err
 	}
@@ -349,7 +349,7 @@ func checkRestrictionsAndAggregateLimits(globalConfig *clusterConfig.Config, tx
 		globalConfigDump = globalConfig.Dump()
 	}
 
-	instances, err := expandInstancesConfigAndDevices(globalConfigDump, info.Instances, info.Profiles)
+	instances, err := expandInstancesConfigAndDevices(globalConfigDump, info.Project.Config, info.Instances, info.Profiles)
 	if err != nil {
 		return err
 	}
@@ -1014,7 +1014,7 @@ func AllowProjectUpdate(globalConfig *clusterConfig.Config, tx *db.ClusterTx, pr
 		return err
 	}
 
-	info.Instances, err = expandInstancesConfigAndDevices(globalConfigDump, info.Instances, info.Profiles)
+	info.Instances, err = expandInstancesConfigAndDevices(globalConfigDump, config, info.Instances, info.Profiles)
 	if err != nil {
 		return err
 	}
@@ -1267,8 +1267,8 @@ func fetchProject(globalConfig map[string]any, tx *db.ClusterTx, projectName str
 }
 
 // Expand the configuration and devices of the given instances, taking the give
-// project profiles into account.
-func expandInstancesConfigAndDevices(globalConfig map[string]any, instances []api.Instance, profiles []api.Profile) ([]api.Instance, error) {
+// project profiles and the device defaults of the given project configuration into account.
+func expandInstancesConfigAndDevices(globalConfig map[string]any, projectConfig map[string]string, instances []api.Instance, profiles []api.Profile) ([]api.Instance, error) {
 	expandedInstances := make([]api.Instance, len(instances))
 
 	// Index of all profiles by name.
@@ -1287,7 +1287,7 @@ func expandInstancesConfigAndDevices(globalConfig map[string]any, instances []ap
 
 		expandedInstances[i] = instance
 		expandedInstances[i].Config = instancetype.ExpandInstanceConfig(globalConfig, instance.Config, apiProfiles)
-		expandedInstances[i].Devices = instancetype.ExpandInstanceDevices(deviceconfig.NewDevices(instance.Devices), apiProfiles).CloneNative()
+		expandedInstances[i].Devices = instancetype.ExpandProjectDeviceDefaults(instancetype.ExpandInstanceDevices(deviceconfig.NewDevices(instance.Devices), apiProfiles), projectConfig).CloneNative()
 	}
 
 	return expandedInstances, nil
@@ -24,7 +24,7 @@ func GetCurrentAllocations(globalConfig map[string]any, ctx context.Context, tx
 		return nil, fmt.Errorf("Project %q returned empty info struct", projectName)
 	}
 
-	info.Instances, err = expandInstancesConfigAndDevices(globalConfig, info.Instances, info.Profiles)
+	info.Instances, err = expandInstancesConfigAndDevices(globalConfig, info.Project.Config, info.Instances, info.Profiles)
 	if err != nil {
 		return nil, err
 	}
@@ -42,6 +42,10 @@ const (
 	// specified in the URL. (For example, if a project has `features.networks=false`, any networks in this project actually
 	// belong to the default project).
 	CtxEffectiveProjectName CtxKey = "effective_project_name"
+
+	// CtxDatabaseReplica is the database replica field in the request context. If set, the cluster database
+	// transactions made with the request context read from the local copy of the global database.
+	CtxDatabaseReplica CtxKey = "database_replica"
 )
 
 // Headers.
@@ -58,4 +62,12 @@ const (
 	// HeaderForwardedIdentityProviderGroups is the forwarded identity provider groups field in request header.
 	// This will be a JSON marshalled []string.
 	HeaderForwardedIdentityProviderGroups = "X-LXD-forwarded-identity-provider-groups"
+
+	// HeaderAllowStale is the request header with the maximum staleness of the global database data that the client
+	// accepts for a GET request, as a duration (e.g. "5s").
+	HeaderAllowStale = "X-LXD-allow-stale"
+
+	// HeaderStaleness is the response header with the maximum staleness of the global database data that a GET
+	// request was served from, if it was served from the local copy of the global database.
+	HeaderStaleness = "X-LXD-staleness"
 )
@@ -3,6 +3,7 @@ package request
 import (
 	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
@@ -38,3 +39,30 @@ func QueryParam(request *http.Request, key string) string {
 
 	return values.Get(key)
 }
+
+// PreferRepresentation returns whether the client asked for the created or modified resource to be returned in the
+// response body, by sending the "Prefer: return=representation" header (RFC 7240).
+func PreferRepresentation(request *http.Request) bool {
+	return prefers(request, "return=representation")
+}
+
+// PreferLenientHandling returns whether the client asked for the request to be handled leniently, by sending the
+// "Prefer: handling=lenient" header (RFC 7240).
+func PreferLenientHandling(request *http.Request) bool {
+	return prefers(request, "handling=lenient")
+}
+
+// prefers returns whether the request has a "Prefer" header containing the given preference.
+func prefers(request *http.Request, want string) bool {
+	for _, header := range request.Header.Values("Prefer") {
+		for _, preference := range strings.Split(header, ",") {
+			// Ignore any preference parameters.
+			preference, _, _ = strings.Cut(preference, ";")
+			if strings.EqualFold(strings.TrimSpace(preference), want) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
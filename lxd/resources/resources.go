@@ -56,16 +56,23 @@ func GetResources() (*api.Resources, error) {
 		return nil, fmt.Errorf("Failed to retrieve system information: %w", err)
 	}
 
+	// Get virtiofs information
+	virtiofs, err := GetVirtiofs()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to retrieve virtiofs information: %w", err)
+	}
+
 	// Build the final struct
 	resources := api.Resources{
-		CPU:     *cpu,
-		Memory:  *memory,
-		GPU:     *gpu,
-		Network: *network,
-		Storage: *storage,
-		USB:     *usb,
-		PCI:     *pci,
-		System:  *system,
+		CPU:      *cpu,
+		Memory:   *memory,
+		GPU:      *gpu,
+		Network:  *network,
+		Storage:  *storage,
+		USB:      *usb,
+		PCI:      *pci,
+		System:   *system,
+		Virtiofs: *virtiofs,
 	}
 
 	return &resources, nil
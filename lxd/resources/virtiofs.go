@@ -0,0 +1,51 @@
+package resources
+
+import (
+	"os/exec"
+	"strings"
+
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+)
+
+// GetVirtiofsdPath returns the path of the virtiofsd binary, or an empty string if it cannot be found.
+func GetVirtiofsdPath() string {
+	cmd, err := exec.LookPath("virtiofsd")
+	if err == nil {
+		return cmd
+	}
+
+	for _, path := range []string{"/usr/lib/qemu/virtiofsd", "/usr/libexec/virtiofsd", "/usr/lib/virtiofsd"} {
+		if shared.PathExists(path) {
+			return path
+		}
+	}
+
+	return ""
+}
+
+// VirtiofsdSupportsIdmap returns whether the given virtiofsd binary supports translating the UIDs and GIDs of the
+// guest to those of the host.
+func VirtiofsdSupportsIdmap(cmd string) bool {
+	out, err := shared.RunCommand(cmd, "--help")
+	if err != nil {
+		return false
+	}
+
+	return strings.Contains(out, "--translate-uid") && strings.Contains(out, "--translate-gid")
+}
+
+// GetVirtiofs returns a filled api.ResourcesVirtiofs struct ready for use by LXD.
+func GetVirtiofs() (*api.ResourcesVirtiofs, error) {
+	virtiofs := api.ResourcesVirtiofs{}
+
+	cmd := GetVirtiofsdPath()
+	if cmd == "" {
+		return &virtiofs, nil
+	}
+
+	virtiofs.Available = true
+	virtiofs.Idmap = VirtiofsdSupportsIdmap(cmd)
+
+	return &virtiofs, nil
+}
@@ -6,9 +6,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"mime/multipart"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/canonical/lxd/client"
@@ -241,18 +243,27 @@ func (r *syncResponse) String() string {
 
 // Error response.
 type errorResponse struct {
-	code int    // Code to return in both the HTTP header and Code field of the response body.
-	msg  string // Message to return in the Error field of the response body.
+	code     int    // Code to return in both the HTTP header and Code field of the response body.
+	msg      string // Message to return in the Error field of the response body.
+	metadata any    // Optional metadata to return in the Metadata field of the response body.
+
+	headers map[string]string // Optional headers to return alongside the error.
 }
 
 // ErrorResponse returns an error response with the given code and msg.
 func ErrorResponse(code int, msg string) Response {
-	return &errorResponse{code, msg}
+	return &errorResponse{code: code, msg: msg}
+}
+
+// ErrorResponseMetadata returns an error response with the given code, msg and metadata.
+// The metadata is returned to the client alongside the error message so that it can act upon the failure.
+func ErrorResponseMetadata(code int, msg string, metadata any) Response {
+	return &errorResponse{code: code, msg: msg, metadata: metadata}
 }
 
 // BadRequest returns a bad request response (400) with the given error.
 func BadRequest(err error) Response {
-	return &errorResponse{http.StatusBadRequest, err.Error()}
+	return &errorResponse{code: http.StatusBadRequest, msg: err.Error()}
 }
 
 // Conflict returns a conflict response (409) with the given error.
@@ -262,7 +273,7 @@ func Conflict(err error) Response {
 		message = err.Error()
 	}
 
-	return &errorResponse{http.StatusConflict, message}
+	return &errorResponse{code: http.StatusConflict, msg: message}
 }
 
 // Forbidden returns a forbidden response (403) with the given error.
@@ -272,12 +283,12 @@ func Forbidden(err error) Response {
 		message = err.Error()
 	}
 
-	return &errorResponse{http.StatusForbidden, message}
+	return &errorResponse{code: http.StatusForbidden, msg: message}
 }
 
 // InternalError returns an internal error response (500) with the given error.
 func InternalError(err error) Response {
-	return &errorResponse{http.StatusInternalServerError, err.Error()}
+	return &errorResponse{code: http.StatusInternalServerError, msg: err.Error()}
 }
 
 // NotFound returns a not found response (404) with the given error.
@@ -287,7 +298,7 @@ func NotFound(err error) Response {
 		message = err.Error()
 	}
 
-	return &errorResponse{http.StatusNotFound, message}
+	return &errorResponse{code: http.StatusNotFound, msg: message}
 }
 
 // NotImplemented returns a not implemented response (501) with the given error.
@@ -297,13 +308,13 @@ func NotImplemented(err error) Response {
 		message = err.Error()
 	}
 
-	return &errorResponse{http.StatusNotImplemented, message}
+	return &errorResponse{code: http.StatusNotImplemented, msg: message}
 }
 
 // PreconditionFailed returns a precondition failed response (412) with the
 // given error.
 func PreconditionFailed(err error) Response {
-	return &errorResponse{http.StatusPreconditionFailed, err.Error()}
+	return &errorResponse{code: http.StatusPreconditionFailed, msg: err.Error()}
 }
 
 // Unavailable return an unavailable response (503) with the given error.
@@ -313,7 +324,21 @@ func Unavailable(err error) Response {
 		message = err.Error()
 	}
 
-	return &errorResponse{http.StatusServiceUnavailable, message}
+	return &errorResponse{code: http.StatusServiceUnavailable, msg: message}
+}
+
+// UnavailableRetryAfter returns an unavailable response (503) with the given error and a Retry-After header,
+// so that clients wait for the given duration before retrying the request.
+func UnavailableRetryAfter(err error, retryAfter time.Duration) Response {
+	message := "unavailable"
+	if err != nil {
+		message = err.Error()
+	}
+
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	headers := map[string]string{"Retry-After": strconv.FormatInt(seconds, 10)}
+
+	return &errorResponse{code: http.StatusServiceUnavailable, msg: message, headers: headers}
 }
 
 func (r *errorResponse) String() string {
@@ -332,9 +357,10 @@ func (r *errorResponse) Render(w http.ResponseWriter) error {
 	}
 
 	resp := api.ResponseRaw{
-		Type:  api.ErrorResponse,
-		Error: r.msg,
-		Code:  r.code, // Set the error code in the Code field of the response body.
+		Type:     api.ErrorResponse,
+		Error:    r.msg,
+		Code:     r.code, // Set the error code in the Code field of the response body.
+		Metadata: r.metadata,
 	}
 
 	err := json.NewEncoder(output).Encode(resp)
@@ -351,6 +377,10 @@ func (r *errorResponse) Render(w http.ResponseWriter) error {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("X-Content-Type-Options", "nosniff")
 
+	for h, v := range r.headers {
+		w.Header().Set(h, v)
+	}
+
 	if w.Header().Get("Connection") != "keep-alive" {
 		w.WriteHeader(r.code) // Set the error code in the HTTP header response.
 	}
@@ -374,6 +404,9 @@ type FileResponseEntry struct {
 	FileSize     int64
 	FileModified time.Time
 
+	// Read from a stream of unknown size.
+	Reader io.Reader
+
 	// Optional.
 	Cleanup func()
 }
@@ -421,6 +454,15 @@ func (r *fileResponse) Render(w http.ResponseWriter) error {
 			defer r.files[0].Cleanup()
 		}
 
+		if r.files[0].Reader != nil {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("inline;filename=%s", r.files[0].Filename))
+			w.WriteHeader(http.StatusOK)
+
+			_, err := io.Copy(w, r.files[0].Reader)
+			return err
+		}
+
 		if r.files[0].File != nil {
 			rs = r.files[0].File
 			mt = r.files[0].FileModified
@@ -461,7 +503,9 @@ func (r *fileResponse) Render(w http.ResponseWriter) error {
 
 	for _, entry := range r.files {
 		var rd io.Reader
-		if entry.File != nil {
+		if entry.Reader != nil {
+			rd = entry.Reader
+		} else if entry.File != nil {
 			rd = entry.File
 		} else {
 			fd, err := os.Open(entry.Path)
@@ -576,5 +620,5 @@ func Unauthorized(err error) Response {
 		message = err.Error()
 	}
 
-	return &errorResponse{http.StatusUnauthorized, message}
+	return &errorResponse{code: http.StatusUnauthorized, msg: message}
 }
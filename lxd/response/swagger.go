@@ -102,6 +102,24 @@ type swaggerPreconditionFailed struct {
 	}
 }
 
+// Conflict
+//
+// swagger:response Conflict
+type swaggerConflict struct {
+	// Conflict
+	// in: body
+	Body struct {
+		// Example: error
+		Type string `json:"type"`
+
+		// Example: conflict
+		Error string `json:"error"`
+
+		// Example: 409
+		ErrorCode int `json:"error_code"`
+	}
+}
+
 // Internal Server Error
 //
 // swagger:response InternalServerError
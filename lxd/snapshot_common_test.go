@@ -35,6 +35,25 @@ func (suite *containerTestSuite) TestSnapshotScheduling() {
 	op.Done(nil)
 }
 
+func (suite *containerTestSuite) TestSnapshotSchedulesDue() {
+	config := map[string]string{
+		"snapshots.schedule":                   "* * * * *",
+		"snapshots.schedule.hourly":            "* * * * *",
+		"snapshots.schedule.hourly.expiry":     "1d",
+		"snapshots.schedule.never":             "@never",
+		"snapshots.schedule.stopped":           "true",
+		"snapshots.schedule.failure_threshold": "3",
+		"snapshots.pattern.daily":              "daily-%d",
+		"snapshots.schedule.daily":             "* * * * *",
+	}
+
+	// Overlapping schedules each get their own snapshot, starting with the unnamed schedule.
+	suite.Equal([]string{"", "daily", "hourly"}, instanceSnapshotSchedulesDue(config, 1))
+
+	delete(config, "snapshots.schedule")
+	suite.Equal([]string{"daily", "hourly"}, instanceSnapshotSchedulesDue(config, 1))
+}
+
 func TestSnapshotCommon(t *testing.T) {
 	suite.Run(t, new(containerTestSuite))
 }
@@ -0,0 +1,1098 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/canonical/lxd/lxd/auth"
+	"github.com/canonical/lxd/lxd/db"
+	dbCluster "github.com/canonical/lxd/lxd/db/cluster"
+	"github.com/canonical/lxd/lxd/instance"
+	"github.com/canonical/lxd/lxd/instance/instancetype"
+	"github.com/canonical/lxd/lxd/lifecycle"
+	"github.com/canonical/lxd/lxd/project"
+	"github.com/canonical/lxd/lxd/request"
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/lxd/util"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
+	"github.com/canonical/lxd/shared/version"
+)
+
+var snapshotRetentionPoliciesCmd = APIEndpoint{
+	Path: "snapshot-retention-policies",
+
+	Get:  APIEndpointAction{Handler: snapshotRetentionPoliciesGet, AccessHandler: allowSnapshotRetentionPolicyPermission(auth.EntitlementCanView)},
+	Post: APIEndpointAction{Handler: snapshotRetentionPoliciesPost, AccessHandler: allowSnapshotRetentionPolicyPermission(auth.EntitlementCanEdit)},
+}
+
+var snapshotRetentionPolicyCmd = APIEndpoint{
+	Path: "snapshot-retention-policies/{name}",
+
+	Get:    APIEndpointAction{Handler: snapshotRetentionPolicyGet, AccessHandler: allowSnapshotRetentionPolicyPermission(auth.EntitlementCanView)},
+	Put:    APIEndpointAction{Handler: snapshotRetentionPolicyPut, AccessHandler: allowSnapshotRetentionPolicyPermission(auth.EntitlementCanEdit)},
+	Patch:  APIEndpointAction{Handler: snapshotRetentionPolicyPut, AccessHandler: allowSnapshotRetentionPolicyPermission(auth.EntitlementCanEdit)},
+	Delete: APIEndpointAction{Handler: snapshotRetentionPolicyDelete, AccessHandler: allowSnapshotRetentionPolicyPermission(auth.EntitlementCanEdit)},
+}
+
+var snapshotRetentionPolicySimulateCmd = APIEndpoint{
+	Path: "snapshot-retention-policies/{name}/simulate",
+
+	Post: APIEndpointAction{Handler: snapshotRetentionPolicySimulatePost, AccessHandler: allowSnapshotRetentionPolicyPermission(auth.EntitlementCanEdit)},
+}
+
+var snapshotRetentionPolicyApplyCmd = APIEndpoint{
+	Path: "snapshot-retention-policies/{name}/apply",
+
+	Post: APIEndpointAction{Handler: snapshotRetentionPolicyApplyPost, AccessHandler: allowSnapshotRetentionPolicyPermission(auth.EntitlementCanEdit)},
+}
+
+// allowSnapshotRetentionPolicyPermission returns an access handler checking the given entitlement on the project of
+// the request if it has a project query parameter, as it is then about the policies of that project. Otherwise the
+// request is about the server-wide policies, and the entitlement is checked on the server.
+func allowSnapshotRetentionPolicyPermission(entitlement auth.Entitlement) func(d *Daemon, r *http.Request) response.Response {
+	return func(d *Daemon, r *http.Request) response.Response {
+		if request.QueryParam(r, "project") == "" {
+			return allowPermission(entity.TypeServer, entitlement)(d, r)
+		}
+
+		return allowPermission(entity.TypeProject, entitlement)(d, r)
+	}
+}
+
+// snapshotRetentionPolicyURL returns the URL of the policy with the given name in the given project, or of the
+// server-wide policy with the given name if the project is empty. Unlike for other entities, the project query
+// parameter is set for the default project, as its absence denotes a server-wide policy.
+func snapshotRetentionPolicyURL(projectName string, name string) *api.URL {
+	u := api.NewURL().Path(version.APIVersion, "snapshot-retention-policies", name)
+	if projectName != "" {
+		u = u.WithQuery("project", projectName)
+	}
+
+	return u
+}
+
+// validateSnapshotRetentionPolicy checks the name and the rules of a policy.
+func validateSnapshotRetentionPolicy(name string, policy api.SnapshotRetentionPolicyPut) error {
+	if name == "" {
+		return api.StatusErrorf(http.StatusBadRequest, "Snapshot retention policy name cannot be empty")
+	}
+
+	if strings.Contains(name, "/") {
+		return api.StatusErrorf(http.StatusBadRequest, "Snapshot retention policy name cannot contain a forward slash")
+	}
+
+	for _, count := range []int{policy.Rules.KeepLast, policy.Rules.KeepHourly, policy.Rules.KeepDaily, policy.Rules.KeepWeekly, policy.Rules.KeepMonthly, policy.Rules.KeepYearly} {
+		if count < 0 {
+			return api.StatusErrorf(http.StatusBadRequest, "The rules of snapshot retention policy %q cannot keep a negative number of snapshots", name)
+		}
+	}
+
+	return nil
+}
+
+// snapshotRetentionSnapshot is a snapshot that is subject to a snapshot retention policy.
+type snapshotRetentionSnapshot struct {
+	name      string // Name of the snapshot, without the name of its parent.
+	createdAt time.Time
+}
+
+// snapshotRetentionTarget is an instance or custom volume that uses a snapshot retention policy.
+type snapshotRetentionTarget struct {
+	entityURL *api.URL
+	snapshots []snapshotRetentionSnapshot
+}
+
+// snapshotRetentionEvaluate returns the names of the given snapshots that are kept and deleted under the given
+// rules, from the most recent to the oldest. A snapshot is kept if it is one of the `keep_last` most recent snapshots,
+// or if it is the most recent snapshot of one of the most recent hours, days, weeks, months or years (in UTC) that
+// have snapshots, up to the number of periods of the rules. All snapshots are kept if the rules are empty.
+func snapshotRetentionEvaluate(rules api.SnapshotRetentionRules, snapshots []snapshotRetentionSnapshot) (kept []string, deleted []string) {
+	sorted := make([]snapshotRetentionSnapshot, len(snapshots))
+	copy(sorted, snapshots)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].createdAt.After(sorted[j].createdAt)
+	})
+
+	keep := make([]bool, len(sorted))
+	if rules == (api.SnapshotRetentionRules{}) {
+		for i := range keep {
+			keep[i] = true
+		}
+	}
+
+	for i := 0; i < len(sorted) && i < rules.KeepLast; i++ {
+		keep[i] = true
+	}
+
+	periods := []struct {
+		count  int
+		period func(t time.Time) string
+	}{
+		{count: rules.KeepHourly, period: func(t time.Time) string { return t.Format("2006-01-02 15") }},
+		{count: rules.KeepDaily, period: func(t time.Time) string { return t.Format("2006-01-02") }},
+		{count: rules.KeepWeekly, period: func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-%d", year, week)
+		}},
+		{count: rules.KeepMonthly, period: func(t time.Time) string { return t.Format("2006-01") }},
+		{count: rules.KeepYearly, period: func(t time.Time) string { return t.Format("2006") }},
+	}
+
+	for _, p := range periods {
+		if p.count <= 0 {
+			continue
+		}
+
+		// The snapshots are sorted from the most recent, so the first snapshot of each period is its most recent.
+		var lastPeriod string
+		var periodCount int
+		for i, snapshot := range sorted {
+			period := p.period(snapshot.createdAt.UTC())
+			if period == lastPeriod {
+				continue
+			}
+
+			lastPeriod = period
+			keep[i] = true
+			periodCount++
+			if periodCount >= p.count {
+				break
+			}
+		}
+	}
+
+	kept = []string{}
+	deleted = []string{}
+	for i, snapshot := range sorted {
+		if keep[i] {
+			kept = append(kept, snapshot.name)
+		} else {
+			deleted = append(deleted, snapshot.name)
+		}
+	}
+
+	return kept, deleted
+}
+
+// snapshotRetentionPolicyTargets returns the instances and custom volumes that use the given policy, ordered by URL.
+// An instance or custom volume uses the policy if its `snapshots.retention.policy` is set to the name of the policy
+// and, for a server-wide policy, if its project doesn't have a policy with the same name. Their snapshots are only
+// loaded if withSnapshots is true.
+func snapshotRetentionPolicyTargets(ctx context.Context, tx *db.ClusterTx, policy dbCluster.SnapshotRetentionPolicy, withSnapshots bool) ([]snapshotRetentionTarget, error) {
+	// Projects that have their own policy with the same name don't use the server-wide policy.
+	overridingProjects := make(map[string]bool)
+	if policy.Project == "" {
+		policies, err := dbCluster.GetAllSnapshotRetentionPolicies(ctx, tx.Tx())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range policies {
+			if p.Project != "" && p.Name == policy.Name {
+				overridingProjects[p.Project] = true
+			}
+		}
+	}
+
+	usesPolicy := func(projectName string, config map[string]string) bool {
+		if config["snapshots.retention.policy"] != policy.Name {
+			return false
+		}
+
+		if policy.Project != "" {
+			return projectName == policy.Project
+		}
+
+		return !overridingProjects[projectName]
+	}
+
+	var filters []dbCluster.InstanceFilter
+	if policy.Project != "" {
+		filters = append(filters, dbCluster.InstanceFilter{Project: &policy.Project})
+	}
+
+	var instances []db.InstanceArgs
+	err := tx.InstanceList(ctx, func(inst db.InstanceArgs, p api.Project) error {
+		if usesPolicy(inst.Project, instancetype.ExpandInstanceConfig(nil, inst.Config, inst.Profiles)) {
+			instances = append(instances, inst)
+		}
+
+		return nil
+	}, filters...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed loading instances: %w", err)
+	}
+
+	var targets []snapshotRetentionTarget
+	for _, inst := range instances {
+		target := snapshotRetentionTarget{entityURL: entity.InstanceURL(inst.Project, inst.Name)}
+		if withSnapshots {
+			snapshots, err := dbCluster.GetInstanceSnapshots(ctx, tx.Tx(), dbCluster.InstanceSnapshotFilter{Project: &inst.Project, Instance: &inst.Name})
+			if err != nil {
+				return nil, fmt.Errorf("Failed loading snapshots of instance %q in project %q: %w", inst.Name, inst.Project, err)
+			}
+
+			for _, snapshot := range snapshots {
+				target.snapshots = append(target.snapshots, snapshotRetentionSnapshot{name: snapshot.Name, createdAt: snapshot.CreationDate})
+			}
+		}
+
+		targets = append(targets, target)
+	}
+
+	volumes, err := tx.GetStoragePoolVolumesWithType(ctx, dbCluster.StoragePoolVolumeTypeCustom, false)
+	if err != nil {
+		return nil, fmt.Errorf("Failed loading custom volumes: %w", err)
+	}
+
+	for _, vol := range volumes {
+		if !usesPolicy(vol.ProjectName, vol.Config) {
+			continue
+		}
+
+		target := snapshotRetentionTarget{entityURL: api.NewURL().Path(version.APIVersion, "storage-pools", vol.PoolName, "volumes", dbCluster.StoragePoolVolumeTypeNameCustom, vol.Name).Project(vol.ProjectName)}
+		if withSnapshots {
+			snapshots, err := tx.GetStorageVolumeSnapshotsByVolumeID(ctx, vol.ID)
+			if err != nil {
+				return nil, fmt.Errorf("Failed loading snapshots of custom volume %q in project %q: %w", vol.Name, vol.ProjectName, err)
+			}
+
+			for _, snapshot := range snapshots {
+				_, snapshotName, _ := api.GetParentAndSnapshotName(snapshot.Name)
+				target.snapshots = append(target.snapshots, snapshotRetentionSnapshot{name: snapshotName, createdAt: snapshot.CreationDate})
+			}
+		}
+
+		targets = append(targets, target)
+	}
+
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].entityURL.String() < targets[j].entityURL.String()
+	})
+
+	return targets, nil
+}
+
+// snapshotRetentionPolicySimulate returns the snapshots of the given targets that are kept and deleted under the given
+// rules. The token of the simulation is derived from the rules and from the deleted snapshots, so that it no longer
+// matches if the outcome of applying the rules changes.
+func snapshotRetentionPolicySimulate(targets []snapshotRetentionTarget, rules api.SnapshotRetentionRules) (*api.SnapshotRetentionPolicySimulation, error) {
+	rulesJSON, err := json.Marshal(rules)
+	if err != nil {
+		return nil, fmt.Errorf("Failed encoding snapshot retention rules: %w", err)
+	}
+
+	hash := sha256.New()
+	_, _ = hash.Write(rulesJSON)
+
+	simulation := api.SnapshotRetentionPolicySimulation{
+		Rules:    rules,
+		Entities: make([]api.SnapshotRetentionPolicySimulationEntity, 0, len(targets)),
+	}
+
+	for _, target := range targets {
+		kept, deleted := snapshotRetentionEvaluate(rules, target.snapshots)
+		simulation.Entities = append(simulation.Entities, api.SnapshotRetentionPolicySimulationEntity{
+			EntityURL: target.entityURL.String(),
+			Kept:      kept,
+			Deleted:   deleted,
+		})
+
+		for _, snapshotName := range deleted {
+			_, _ = fmt.Fprintf(hash, "\n%s %s", target.entityURL.String(), snapshotName)
+		}
+	}
+
+	simulation.Token = hex.EncodeToString(hash.Sum(nil))
+
+	return &simulation, nil
+}
+
+// snapshotRetentionPolicyToAPI converts the given policy to an api.SnapshotRetentionPolicy, with the URLs of the
+// given targets as its UsedBy field.
+func snapshotRetentionPolicyToAPI(policy dbCluster.SnapshotRetentionPolicy, targets []snapshotRetentionTarget) api.SnapshotRetentionPolicy {
+	apiPolicy := policy.ToAPI()
+	apiPolicy.UsedBy = make([]string, 0, len(targets))
+	for _, target := range targets {
+		apiPolicy.UsedBy = append(apiPolicy.UsedBy, target.entityURL.String())
+	}
+
+	return apiPolicy
+}
+
+// swagger:operation GET /1.0/snapshot-retention-policies snapshot-retention-policies snapshot_retention_policies_get
+//
+//	Get the snapshot retention policies
+//
+//	Returns a list of snapshot retention policies (URLs).
+//	The policies of the project are returned if the project query parameter is set, and the server-wide policies
+//	otherwise.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	responses:
+//	  "200":
+//	    description: API endpoints
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of endpoints
+//	          items:
+//	            type: string
+//	          example: |-
+//	            [
+//	              "/1.0/snapshot-retention-policies/gfs",
+//	              "/1.0/snapshot-retention-policies/daily"
+//	            ]
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+
+// swagger:operation GET /1.0/snapshot-retention-policies?recursion=1 snapshot-retention-policies snapshot_retention_policies_get_recursion1
+//
+//	Get the snapshot retention policies
+//
+//	Returns a list of snapshot retention policies (structs).
+//	The policies of the project are returned if the project query parameter is set, and the server-wide policies
+//	otherwise.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	responses:
+//	  "200":
+//	    description: API endpoints
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of snapshot retention policies
+//	          items:
+//	            $ref: "#/definitions/SnapshotRetentionPolicy"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func snapshotRetentionPoliciesGet(d *Daemon, r *http.Request) response.Response {
+	recursion := util.IsRecursionRequest(r)
+	projectName := request.QueryParam(r, "project")
+
+	s := d.State()
+	var apiPolicies []api.SnapshotRetentionPolicy
+	err := s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		policies, err := dbCluster.GetSnapshotRetentionPolicies(ctx, tx.Tx(), projectName)
+		if err != nil {
+			return err
+		}
+
+		apiPolicies = make([]api.SnapshotRetentionPolicy, 0, len(policies))
+		for _, policy := range policies {
+			var targets []snapshotRetentionTarget
+			if recursion {
+				targets, err = snapshotRetentionPolicyTargets(ctx, tx, policy, false)
+				if err != nil {
+					return err
+				}
+			}
+
+			apiPolicies = append(apiPolicies, snapshotRetentionPolicyToAPI(policy, targets))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !recursion {
+		urls := make([]string, 0, len(apiPolicies))
+		for _, policy := range apiPolicies {
+			urls = append(urls, snapshotRetentionPolicyURL(policy.Project, policy.Name).String())
+		}
+
+		return response.SyncResponse(true, urls)
+	}
+
+	for i := range apiPolicies {
+		apiPolicies[i].UsedBy = project.FilterUsedBy(s.Authorizer, r, apiPolicies[i].UsedBy)
+	}
+
+	return response.SyncResponse(true, apiPolicies)
+}
+
+// swagger:operation POST /1.0/snapshot-retention-policies snapshot-retention-policies snapshot_retention_policies_post
+//
+//	Add a snapshot retention policy
+//
+//	Creates a new snapshot retention policy in the project if the project query parameter is set, and a server-wide
+//	policy otherwise.
+//	If instances or custom volumes already use a policy with that name, its rules are only pending until they are
+//	applied (see the simulation of the policy).
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	  - in: body
+//	    name: policy
+//	    description: Snapshot retention policy
+//	    required: true
+//	    schema:
+//	      $ref: "#/definitions/SnapshotRetentionPoliciesPost"
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func snapshotRetentionPoliciesPost(d *Daemon, r *http.Request) response.Response {
+	projectName := request.QueryParam(r, "project")
+
+	req := api.SnapshotRetentionPoliciesPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(fmt.Errorf("Invalid request body: %w", err))
+	}
+
+	err = validateSnapshotRetentionPolicy(req.Name, req.SnapshotRetentionPolicyPut)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	s := d.State()
+	err = s.DB.Cluster.TransactionRetry(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		policy := dbCluster.SnapshotRetentionPolicy{
+			Name:        req.Name,
+			Project:     projectName,
+			Description: req.Description,
+			Rules:       req.Rules,
+		}
+
+		// Instances and custom volumes can use a policy before it is created. Creating it must not delete their
+		// snapshots unless the outcome has been simulated.
+		targets, err := snapshotRetentionPolicyTargets(ctx, tx, policy, false)
+		if err != nil {
+			return err
+		}
+
+		if len(targets) > 0 {
+			policy.Rules = api.SnapshotRetentionRules{}
+			policy.PendingRules = &req.Rules
+		}
+
+		_, err = dbCluster.CreateSnapshotRetentionPolicy(ctx, tx.Tx(), policy)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	lc := lifecycle.SnapshotRetentionPolicyCreated.Event(projectName, req.Name, request.CreateRequestor(r), nil)
+	s.Events.SendLifecycle(projectName, lc)
+
+	return response.SyncResponseLocation(true, nil, snapshotRetentionPolicyURL(projectName, req.Name).String())
+}
+
+// swagger:operation GET /1.0/snapshot-retention-policies/{name} snapshot-retention-policies snapshot_retention_policy_get
+//
+//	Get the snapshot retention policy
+//
+//	Gets a specific snapshot retention policy of the project if the project query parameter is set, and a
+//	server-wide policy otherwise.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	responses:
+//	  "200":
+//	    description: Snapshot retention policy
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          $ref: "#/definitions/SnapshotRetentionPolicy"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func snapshotRetentionPolicyGet(d *Daemon, r *http.Request) response.Response {
+	projectName := request.QueryParam(r, "project")
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	s := d.State()
+	var apiPolicy api.SnapshotRetentionPolicy
+	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		policy, err := dbCluster.GetSnapshotRetentionPolicy(ctx, tx.Tx(), projectName, name)
+		if err != nil {
+			return err
+		}
+
+		targets, err := snapshotRetentionPolicyTargets(ctx, tx, *policy, false)
+		if err != nil {
+			return err
+		}
+
+		apiPolicy = snapshotRetentionPolicyToAPI(*policy, targets)
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	apiPolicy.UsedBy = project.FilterUsedBy(s.Authorizer, r, apiPolicy.UsedBy)
+
+	return response.SyncResponseETag(true, apiPolicy, apiPolicy.Writable())
+}
+
+// swagger:operation PUT /1.0/snapshot-retention-policies/{name} snapshot-retention-policies snapshot_retention_policy_put
+//
+//	Update the snapshot retention policy
+//
+//	Updates the description of the policy. If the rules change, the new rules are pending until they are applied,
+//	so that changing a policy doesn't delete snapshots before the outcome has been simulated.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	  - in: body
+//	    name: policy
+//	    description: Snapshot retention policy
+//	    required: true
+//	    schema:
+//	      $ref: "#/definitions/SnapshotRetentionPolicyPut"
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+
+// swagger:operation PATCH /1.0/snapshot-retention-policies/{name} snapshot-retention-policies snapshot_retention_policy_patch
+//
+//	Partially update the snapshot retention policy
+//
+//	Updates a subset of the fields of the policy. If the rules change, the new rules are pending until they are
+//	applied, so that changing a policy doesn't delete snapshots before the outcome has been simulated.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	  - in: body
+//	    name: policy
+//	    description: Snapshot retention policy
+//	    required: true
+//	    schema:
+//	      $ref: "#/definitions/SnapshotRetentionPolicyPut"
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func snapshotRetentionPolicyPut(d *Daemon, r *http.Request) response.Response {
+	projectName := request.QueryParam(r, "project")
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	s := d.State()
+	err = s.DB.Cluster.TransactionRetry(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		policy, err := dbCluster.GetSnapshotRetentionPolicy(ctx, tx.Tx(), projectName, name)
+		if err != nil {
+			return err
+		}
+
+		current := policy.ToAPI()
+		err = util.EtagCheck(r, current.Writable())
+		if err != nil {
+			return err
+		}
+
+		req := current.Writable()
+
+		// Pending rules are the base of partial updates, as they are the most recent rules that were set.
+		if r.Method == http.MethodPatch && policy.PendingRules != nil {
+			req.Rules = *policy.PendingRules
+		} else if r.Method == http.MethodPut {
+			req = api.SnapshotRetentionPolicyPut{}
+		}
+
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			return api.StatusErrorf(http.StatusBadRequest, "Invalid request body: %v", err)
+		}
+
+		err = validateSnapshotRetentionPolicy(name, req)
+		if err != nil {
+			return err
+		}
+
+		policy.Description = req.Description
+		if req.Rules == policy.Rules {
+			policy.PendingRules = nil
+		} else {
+			policy.PendingRules = &req.Rules
+		}
+
+		return dbCluster.UpdateSnapshotRetentionPolicy(ctx, tx.Tx(), policy.ID, *policy)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	lc := lifecycle.SnapshotRetentionPolicyUpdated.Event(projectName, name, request.CreateRequestor(r), nil)
+	s.Events.SendLifecycle(projectName, lc)
+
+	return response.EmptySyncResponse
+}
+
+// swagger:operation DELETE /1.0/snapshot-retention-policies/{name} snapshot-retention-policies snapshot_retention_policy_delete
+//
+//	Delete the snapshot retention policy
+//
+//	Deletes the policy. A policy cannot be deleted while instances or custom volumes use it.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func snapshotRetentionPolicyDelete(d *Daemon, r *http.Request) response.Response {
+	projectName := request.QueryParam(r, "project")
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	s := d.State()
+	err = s.DB.Cluster.TransactionRetry(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		policy, err := dbCluster.GetSnapshotRetentionPolicy(ctx, tx.Tx(), projectName, name)
+		if err != nil {
+			return err
+		}
+
+		targets, err := snapshotRetentionPolicyTargets(ctx, tx, *policy, false)
+		if err != nil {
+			return err
+		}
+
+		if len(targets) > 0 {
+			return api.StatusErrorf(http.StatusBadRequest, "Snapshot retention policy %q is currently in use", name)
+		}
+
+		return dbCluster.DeleteSnapshotRetentionPolicy(ctx, tx.Tx(), policy.ID)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	lc := lifecycle.SnapshotRetentionPolicyDeleted.Event(projectName, name, request.CreateRequestor(r), nil)
+	s.Events.SendLifecycle(projectName, lc)
+
+	return response.EmptySyncResponse
+}
+
+// swagger:operation POST /1.0/snapshot-retention-policies/{name}/simulate snapshot-retention-policies snapshot_retention_policy_simulate_post
+//
+//	Simulate the snapshot retention policy
+//
+//	Returns the snapshots of each instance and custom volume using the policy that are kept and deleted under the
+//	given rules, or under the pending rules of the policy (or its rules if there are none) if no rules are given.
+//	Nothing is deleted. The token of the simulation of the pending rules confirms them when applying them.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	  - in: body
+//	    name: simulation
+//	    description: Simulation request
+//	    required: false
+//	    schema:
+//	      $ref: "#/definitions/SnapshotRetentionPolicySimulatePost"
+//	responses:
+//	  "200":
+//	    description: Simulation
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          $ref: "#/definitions/SnapshotRetentionPolicySimulation"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func snapshotRetentionPolicySimulatePost(d *Daemon, r *http.Request) response.Response {
+	projectName := request.QueryParam(r, "project")
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	req := api.SnapshotRetentionPolicySimulatePost{}
+	if r.ContentLength != 0 {
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Invalid request body: %w", err))
+		}
+	}
+
+	if req.Rules != nil {
+		err = validateSnapshotRetentionPolicy(name, api.SnapshotRetentionPolicyPut{Rules: *req.Rules})
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
+	var simulation *api.SnapshotRetentionPolicySimulation
+	err = d.State().DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		policy, err := dbCluster.GetSnapshotRetentionPolicy(ctx, tx.Tx(), projectName, name)
+		if err != nil {
+			return err
+		}
+
+		rules := policy.Rules
+		if req.Rules != nil {
+			rules = *req.Rules
+		} else if policy.PendingRules != nil {
+			rules = *policy.PendingRules
+		}
+
+		targets, err := snapshotRetentionPolicyTargets(ctx, tx, *policy, true)
+		if err != nil {
+			return err
+		}
+
+		simulation, err = snapshotRetentionPolicySimulate(targets, rules)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, simulation)
+}
+
+// swagger:operation POST /1.0/snapshot-retention-policies/{name}/apply snapshot-retention-policies snapshot_retention_policy_apply_post
+//
+//	Apply the pending rules of the snapshot retention policy
+//
+//	Replaces the rules of the policy with its pending rules. The request must include the token of a simulation of
+//	the pending rules, which no longer matches if the snapshots that the pending rules delete have changed since.
+//	The snapshots are deleted by the next run of the snapshot expiry task.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	  - in: body
+//	    name: apply
+//	    description: Apply request
+//	    required: true
+//	    schema:
+//	      $ref: "#/definitions/SnapshotRetentionPolicyApplyPost"
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func snapshotRetentionPolicyApplyPost(d *Daemon, r *http.Request) response.Response {
+	projectName := request.QueryParam(r, "project")
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	req := api.SnapshotRetentionPolicyApplyPost{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(fmt.Errorf("Invalid request body: %w", err))
+	}
+
+	if req.Token == "" {
+		return response.BadRequest(fmt.Errorf("The token of a simulation of the pending rules is required"))
+	}
+
+	s := d.State()
+	err = s.DB.Cluster.TransactionRetry(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		policy, err := dbCluster.GetSnapshotRetentionPolicy(ctx, tx.Tx(), projectName, name)
+		if err != nil {
+			return err
+		}
+
+		if policy.PendingRules == nil {
+			return api.StatusErrorf(http.StatusBadRequest, "Snapshot retention policy %q has no pending rules", name)
+		}
+
+		targets, err := snapshotRetentionPolicyTargets(ctx, tx, *policy, true)
+		if err != nil {
+			return err
+		}
+
+		simulation, err := snapshotRetentionPolicySimulate(targets, *policy.PendingRules)
+		if err != nil {
+			return err
+		}
+
+		if simulation.Token != req.Token {
+			return api.StatusErrorf(http.StatusPreconditionFailed, "The simulation of snapshot retention policy %q is out of date, simulate the pending rules again", name)
+		}
+
+		policy.Rules = *policy.PendingRules
+		policy.PendingRules = nil
+
+		return dbCluster.UpdateSnapshotRetentionPolicy(ctx, tx.Tx(), policy.ID, *policy)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	lc := lifecycle.SnapshotRetentionPolicyApplied.Event(projectName, name, request.CreateRequestor(r), nil)
+	s.Events.SendLifecycle(projectName, lc)
+
+	return response.EmptySyncResponse
+}
+
+// snapshotRetentionPolicyRules returns the rules of the policy that an instance or custom volume in the given project
+// uses given its config. It returns nil if it doesn't use a policy, or if the policy doesn't exist.
+func snapshotRetentionPolicyRules(ctx context.Context, tx *db.ClusterTx, projectName string, config map[string]string) (*api.SnapshotRetentionRules, error) {
+	name := config["snapshots.retention.policy"]
+	if name == "" {
+		return nil, nil
+	}
+
+	policy, err := dbCluster.GetEffectiveSnapshotRetentionPolicy(ctx, tx.Tx(), projectName, name)
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &policy.Rules, nil
+}
+
+// snapshotRetentionPolicyExpiredVolumeSnapshots returns the snapshots of the given custom volume that are deleted
+// under the rules of the snapshot retention policy it uses, if any.
+func snapshotRetentionPolicyExpiredVolumeSnapshots(ctx context.Context, tx *db.ClusterTx, vol db.StorageVolumeArgs) ([]db.StorageVolumeArgs, error) {
+	rules, err := snapshotRetentionPolicyRules(ctx, tx, vol.ProjectName, vol.Config)
+	if err != nil || rules == nil {
+		return nil, err
+	}
+
+	snapshots, err := tx.GetStorageVolumeSnapshotsByVolumeID(ctx, vol.ID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed loading snapshots of custom volume %q in project %q: %w", vol.Name, vol.ProjectName, err)
+	}
+
+	retentionSnapshots := make([]snapshotRetentionSnapshot, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		_, snapshotName, _ := api.GetParentAndSnapshotName(snapshot.Name)
+		retentionSnapshots = append(retentionSnapshots, snapshotRetentionSnapshot{name: snapshotName, createdAt: snapshot.CreationDate})
+	}
+
+	_, deleted := snapshotRetentionEvaluate(*rules, retentionSnapshots)
+
+	expired := make([]db.StorageVolumeArgs, 0, len(deleted))
+	for _, snapshot := range snapshots {
+		_, snapshotName, _ := api.GetParentAndSnapshotName(snapshot.Name)
+		if shared.ValueInSlice(snapshotName, deleted) {
+			expired = append(expired, snapshot)
+		}
+	}
+
+	return expired, nil
+}
+
+// snapshotRetentionPolicyExpiredInstanceSnapshots returns the snapshots of the given instance that are deleted under
+// the given rules.
+func snapshotRetentionPolicyExpiredInstanceSnapshots(inst instance.Instance, rules api.SnapshotRetentionRules) ([]instance.Instance, error) {
+	snapshots, err := inst.Snapshots()
+	if err != nil {
+		return nil, fmt.Errorf("Failed loading snapshots of instance %q in project %q: %w", inst.Name(), inst.Project().Name, err)
+	}
+
+	retentionSnapshots := make([]snapshotRetentionSnapshot, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		_, snapshotName, _ := api.GetParentAndSnapshotName(snapshot.Name())
+		retentionSnapshots = append(retentionSnapshots, snapshotRetentionSnapshot{name: snapshotName, createdAt: snapshot.CreationDate()})
+	}
+
+	_, deleted := snapshotRetentionEvaluate(rules, retentionSnapshots)
+
+	expired := make([]instance.Instance, 0, len(deleted))
+	for _, snapshot := range snapshots {
+		_, snapshotName, _ := api.GetParentAndSnapshotName(snapshot.Name())
+		if shared.ValueInSlice(snapshotName, deleted) {
+			expired = append(expired, snapshot)
+		}
+	}
+
+	return expired, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/canonical/lxd/shared/api"
+)
+
+func TestSnapshotRetentionEvaluate(t *testing.T) {
+	base := time.Date(2024, time.March, 4, 12, 0, 0, 0, time.UTC) // A Monday.
+
+	// Two snapshots a day over twenty days, snap0 being the oldest.
+	var snapshots []snapshotRetentionSnapshot
+	for i := 0; i < 40; i++ {
+		snapshots = append(snapshots, snapshotRetentionSnapshot{
+			name:      fmt.Sprintf("snap%d", i),
+			createdAt: base.Add(time.Duration(i) * 12 * time.Hour),
+		})
+	}
+
+	tests := []struct {
+		name     string
+		rules    api.SnapshotRetentionRules
+		wantKept []string
+	}{
+		{
+			name:  "Empty rules keep everything",
+			rules: api.SnapshotRetentionRules{},
+		},
+		{
+			name:     "Keep last",
+			rules:    api.SnapshotRetentionRules{KeepLast: 3},
+			wantKept: []string{"snap39", "snap38", "snap37"},
+		},
+		{
+			name:     "Keep daily",
+			rules:    api.SnapshotRetentionRules{KeepDaily: 2},
+			wantKept: []string{"snap39", "snap38"},
+		},
+		{
+			name:     "Keep weekly",
+			rules:    api.SnapshotRetentionRules{KeepWeekly: 3},
+			wantKept: []string{"snap39", "snap26", "snap12"},
+		},
+		{
+			name:     "Rules are combined",
+			rules:    api.SnapshotRetentionRules{KeepLast: 1, KeepDaily: 2, KeepMonthly: 5},
+			wantKept: []string{"snap39", "snap38"},
+		},
+		{
+			name:     "More periods than snapshots",
+			rules:    api.SnapshotRetentionRules{KeepYearly: 5},
+			wantKept: []string{"snap39"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, deleted := snapshotRetentionEvaluate(tt.rules, snapshots)
+			assert.Len(t, append(kept, deleted...), len(snapshots))
+
+			if tt.wantKept == nil {
+				assert.Len(t, kept, len(snapshots))
+				assert.Empty(t, deleted)
+				return
+			}
+
+			assert.Equal(t, tt.wantKept, kept)
+		})
+	}
+}
@@ -652,6 +652,13 @@ func (b *lxdBackend) CreateInstance(inst instance.Instance, op *operations.Opera
 		return err
 	}
 
+	release, err := b.instanceCreateAcquire(op)
+	if err != nil {
+		return err
+	}
+
+	defer release()
+
 	volType, err := InstanceTypeToVolumeType(inst.Type())
 	if err != nil {
 		return err
@@ -968,8 +975,11 @@ func (b *lxdBackend) CreateInstanceFromBackup(srcBackup backup.Info, srcData io.
 }
 
 // CreateInstanceFromCopy copies an instance volume and optionally its snapshots to new volume(s).
-func (b *lxdBackend) CreateInstanceFromCopy(inst instance.Instance, src instance.Instance, snapshots bool, allowInconsistent bool, op *operations.Operation) error {
-	l := b.logger.AddContext(logger.Ctx{"project": inst.Project().Name, "instance": inst.Name(), "src": src.Name(), "snapshots": snapshots})
+// Only the snapshots of the source instance in srcSnapshots are copied.
+func (b *lxdBackend) CreateInstanceFromCopy(inst instance.Instance, src instance.Instance, srcSnapshots []instance.Instance, allowInconsistent bool, op *operations.Operation) error {
+	snapshots := len(srcSnapshots) > 0
+
+	l := b.logger.AddContext(logger.Ctx{"project": inst.Project().Name, "instance": inst.Name(), "src": src.Name(), "snapshots": len(srcSnapshots)})
 	l.Debug("CreateInstanceFromCopy started")
 	defer l.Debug("CreateInstanceFromCopy finished")
 
@@ -978,6 +988,13 @@ func (b *lxdBackend) CreateInstanceFromCopy(inst instance.Instance, src instance
 		return err
 	}
 
+	release, err := b.instanceCreateAcquire(op)
+	if err != nil {
+		return err
+	}
+
+	defer release()
+
 	if inst.Type() != src.Type() {
 		return fmt.Errorf("Instance types must match")
 	}
@@ -1006,6 +1023,32 @@ func (b *lxdBackend) CreateInstanceFromCopy(inst instance.Instance, src instance
 		return fmt.Errorf("Failed generating instance copy config: %w", err)
 	}
 
+	// Only keep the requested snapshots in the backup config.
+	if snapshots {
+		snapshotNames := make([]string, 0, len(srcSnapshots))
+		for _, srcSnapshot := range srcSnapshots {
+			_, snapshotName, _ := api.GetParentAndSnapshotName(srcSnapshot.Name())
+			snapshotNames = append(snapshotNames, snapshotName)
+		}
+
+		volumeSnapshots := make([]*api.StorageVolumeSnapshot, 0, len(srcSnapshots))
+		for _, volumeSnapshot := range srcConfig.VolumeSnapshots {
+			if shared.ValueInSlice(volumeSnapshot.Name, snapshotNames) {
+				volumeSnapshots = append(volumeSnapshots, volumeSnapshot)
+			}
+		}
+
+		instanceSnapshots := make([]*api.InstanceSnapshot, 0, len(srcSnapshots))
+		for _, instanceSnapshot := range srcConfig.Snapshots {
+			if shared.ValueInSlice(instanceSnapshot.Name, snapshotNames) {
+				instanceSnapshots = append(instanceSnapshots, instanceSnapshot)
+			}
+		}
+
+		srcConfig.VolumeSnapshots = volumeSnapshots
+		srcConfig.Snapshots = instanceSnapshots
+	}
+
 	// Use the information from the backup config to create a list of all the source volume's snapshots.
 	// This way we don't have to retrieve them separately from the database.
 	sourceSnapshots := make([]drivers.Volume, 0, len(srcConfig.VolumeSnapshots))
@@ -1782,6 +1825,13 @@ func (b *lxdBackend) CreateInstanceFromImage(inst instance.Instance, fingerprint
 		return err
 	}
 
+	release, err := b.instanceCreateAcquire(op)
+	if err != nil {
+		return err
+	}
+
+	defer release()
+
 	volType, err := InstanceTypeToVolumeType(inst.Type())
 	if err != nil {
 		return err
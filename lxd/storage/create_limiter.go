@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/canonical/lxd/lxd/operations"
+	"github.com/canonical/lxd/shared/logger"
+)
+
+// createLimiter limits the number of concurrent instance root volume creations on a storage pool.
+// Slots are handed over to waiters in the order they arrived.
+type createLimiter struct {
+	mu      sync.Mutex
+	running int
+	waiters []chan struct{}
+}
+
+// createLimiters holds the limiter of each storage pool by pool name.
+var createLimiters = map[string]*createLimiter{}
+
+// createLimitersMu protects createLimiters.
+var createLimitersMu sync.Mutex
+
+// CreateQueueDepths returns the number of instance root volume creations waiting for a slot on each storage pool.
+func CreateQueueDepths() map[string]int {
+	createLimitersMu.Lock()
+	defer createLimitersMu.Unlock()
+
+	depths := make(map[string]int, len(createLimiters))
+	for poolName, limiter := range createLimiters {
+		limiter.mu.Lock()
+		depths[poolName] = len(limiter.waiters)
+		limiter.mu.Unlock()
+	}
+
+	return depths
+}
+
+// instanceCreateAcquire waits until an instance root volume can be created on the pool without exceeding
+// instances.create_concurrency. While waiting, the operation is marked as queued so that clients can tell a queued
+// creation apart from a stuck one. Returns a function that must be called to release the slot once done.
+func (b *lxdBackend) instanceCreateAcquire(op *operations.Operation) (func(), error) {
+	limit, _ := strconv.Atoi(b.db.Config["instances.create_concurrency"])
+	if limit <= 0 {
+		return func() {}, nil
+	}
+
+	createLimitersMu.Lock()
+	limiter := createLimiters[b.name]
+	if limiter == nil {
+		limiter = &createLimiter{}
+		createLimiters[b.name] = limiter
+	}
+
+	createLimitersMu.Unlock()
+
+	limiter.mu.Lock()
+	if limiter.running < limit {
+		limiter.running++
+		limiter.mu.Unlock()
+
+		return limiter.release, nil
+	}
+
+	wait := make(chan struct{})
+	limiter.waiters = append(limiter.waiters, wait)
+	position := len(limiter.waiters)
+	limiter.mu.Unlock()
+
+	b.logger.Debug("Queued instance volume creation", logger.Ctx{"position": position, "limit": limit})
+	if op != nil {
+		_ = op.ExtendMetadata(map[string]any{"create_instance_queued": true, "create_instance_queue_position": position})
+	}
+
+	select {
+	case <-wait:
+	case <-b.state.ShutdownCtx.Done():
+		limiter.mu.Lock()
+		for i, waiter := range limiter.waiters {
+			if waiter == wait {
+				limiter.waiters = append(limiter.waiters[:i], limiter.waiters[i+1:]...)
+				limiter.mu.Unlock()
+
+				return nil, b.state.ShutdownCtx.Err()
+			}
+		}
+
+		limiter.mu.Unlock()
+
+		// The slot was handed over in the meantime, so pass it on.
+		limiter.release()
+
+		return nil, b.state.ShutdownCtx.Err()
+	}
+
+	if op != nil {
+		_ = op.ExtendMetadata(map[string]any{"create_instance_queued": false, "create_instance_queue_position": 0})
+	}
+
+	return limiter.release, nil
+}
+
+// release hands the slot over to the next waiter, or frees it if none are waiting.
+func (l *createLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.waiters) > 0 {
+		wait := l.waiters[0]
+		l.waiters = l.waiters[1:]
+		close(wait)
+
+		return
+	}
+
+	l.running--
+}
@@ -1506,9 +1506,12 @@ func (d *ceph) CreateVolumeSnapshot(snapVol Volume, op *operations.Operation) er
 		// could still be busy), as we do not guarantee the consistency of a snapshot. This is costly but
 		// try to ensure that all cached data has been committed to disk. If we don't then the rbd snapshot
 		// of the underlying filesystem can be inconsistent or, in the worst case, empty.
-		unfreezeFS, err := d.filesystemFreeze(sourcePath)
+		unfreezeFS, err := d.filesystemFreeze(sourcePath, op)
 		if err == nil {
 			defer func() { _ = unfreezeFS() }()
+		} else if _, frozen := err.(ErrFilesystemFrozen); frozen {
+			// Refuse to snapshot a filesystem left frozen by another operation.
+			return err
 		}
 	}
 
@@ -573,7 +573,14 @@ func (d *common) isBlockBacked(vol Volume) bool {
 }
 
 // filesystemFreeze syncs and freezes a filesystem and returns an unfreeze function on success.
-func (d *common) filesystemFreeze(path string) (func() error, error) {
+// The freeze is tracked until the filesystem is unfrozen, and an ErrFilesystemFrozen error is returned if the
+// filesystem is already frozen by LXD, as syncing a frozen filesystem would block.
+func (d *common) filesystemFreeze(path string, op *operations.Operation) (func() error, error) {
+	freeze := FilesystemFrozen(path)
+	if freeze != nil {
+		return nil, ErrFilesystemFrozen{Freeze: *freeze}
+	}
+
 	err := filesystem.SyncFS(path)
 	if err != nil {
 		return nil, fmt.Errorf("Failed syncing filesystem %q: %w", path, err)
@@ -584,9 +591,16 @@ func (d *common) filesystemFreeze(path string) (func() error, error) {
 		return nil, fmt.Errorf("Failed freezing filesystem %q: %w", path, err)
 	}
 
+	freeze = filesystemFreezeTrack(d.state, path, op, d.logger)
+
 	d.logger.Info("Filesystem frozen", logger.Ctx{"path": path})
 
 	unfreezeFS := func() error {
+		// Skip filesystems already thawed on request or by the freeze timeout.
+		if filesystemFreezeUntrack(path, freeze) == nil {
+			return nil
+		}
+
 		_, err := shared.RunCommand("fsfreeze", "--unfreeze", path)
 		if err != nil {
 			return fmt.Errorf("Failed unfreezing filesystem %q: %w", path, err)
@@ -11,6 +11,7 @@ import (
 	"github.com/canonical/lxd/lxd/operations"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/validate"
 )
 
 type dir struct {
@@ -112,7 +113,30 @@ func (d *dir) Delete(op *operations.Operation) error {
 
 // Validate checks that all provide keys are supported and that no conflicting or missing configuration is present.
 func (d *dir) Validate(config map[string]string) error {
-	return d.validatePool(config, nil, nil)
+	rules := map[string]func(value string) error{
+		// lxdmeta:generate(entities=storage-dir; group=pool-conf; key=dir.quota.strict)
+		// When enabled, the backing file system of the pool must support project quotas, and setting a size that
+		// can't be enforced fails. Otherwise, sizes that can't be enforced are ignored.
+		// ---
+		//  type: bool
+		//  defaultdesc: `false`
+		//  shortdesc: Whether to refuse size limits that can't be enforced
+		"dir.quota.strict": validate.Optional(validate.IsBool),
+	}
+
+	err := d.validatePool(config, rules, nil)
+	if err != nil {
+		return err
+	}
+
+	if shared.IsTrue(config["dir.quota.strict"]) && config["source"] != "" {
+		err = d.validateQuotaSupported(shared.HostPath(config["source"]))
+		if err != nil {
+			return fmt.Errorf("Invalid value for option %q: %w", "dir.quota.strict", err)
+		}
+	}
+
+	return nil
 }
 
 // Update applies any driver changes required from a configuration change.
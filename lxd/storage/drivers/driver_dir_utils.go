@@ -4,11 +4,16 @@ import (
 	"fmt"
 
 	"github.com/canonical/lxd/lxd/storage/quota"
+	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/revert"
 	"github.com/canonical/lxd/shared/units"
 )
 
+// quotaSupported checks whether the filesystem of a path supports project quotas. It is a variable so that tests can
+// replace it.
+var quotaSupported = quota.Supported
+
 // withoutGetVolID returns a copy of this struct but with a volIDFunc which will cause quotas to be skipped.
 func (d *dir) withoutGetVolID() Driver {
 	newDriver := &dir{}
@@ -47,6 +52,14 @@ func (d *dir) setupInitialQuota(vol Volume) (revert.Hook, error) {
 		return nil, err
 	}
 
+	// Refuse a size limit that can't be enforced if the pool requires it.
+	if sizeBytes > 0 && volID != volIDQuotaSkip && vol.volType != VolumeTypeVM && d.quotaStrict() {
+		err = d.validateQuotaSupported(volPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	err = d.setQuota(volPath, volID, sizeBytes)
 	if err != nil {
 		return nil, err
@@ -135,3 +148,27 @@ func (d *dir) setQuota(path string, volID int64, sizeBytes int64) error {
 	// Set the project quota size.
 	return quota.SetProjectQuota(path, projectID, sizeBytes)
 }
+
+// quotaStrict returns whether the pool refuses size limits that can't be enforced.
+func (d *dir) quotaStrict() bool {
+	return shared.IsTrue(d.config["dir.quota.strict"])
+}
+
+// validateQuotaSupported returns an error if the filesystem of the path doesn't support the project quotas that are
+// needed to enforce a size limit. Paths that don't exist yet are skipped as their filesystem isn't known.
+func (d *dir) validateQuotaSupported(path string) error {
+	if !shared.PathExists(path) {
+		return nil
+	}
+
+	ok, err := quotaSupported(path)
+	if err != nil {
+		return fmt.Errorf("Failed checking project quota support of %q: %w", path, err)
+	}
+
+	if !ok {
+		return fmt.Errorf("Size limits require the backing filesystem of the pool to support project quotas (XFS, or ext4 with the project quota feature enabled)")
+	}
+
+	return nil
+}
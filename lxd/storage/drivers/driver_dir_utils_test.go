@@ -0,0 +1,117 @@
+package drivers
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/lxd/shared/validate"
+)
+
+// newTestDir returns a dir driver for a pool whose mount path exists, with the given pool config and quota support
+// of its backing filesystem.
+func newTestDir(t *testing.T, config map[string]string, supported bool, supportedErr error) *dir {
+	t.Setenv("LXD_DIR", t.TempDir())
+	require.NoError(t, os.MkdirAll(GetPoolMountPath("pool"), 0711))
+
+	previous := quotaSupported
+	quotaSupported = func(path string) (bool, error) { return supported, supportedErr }
+	t.Cleanup(func() { quotaSupported = previous })
+
+	d := &dir{}
+	d.init(nil, "pool", config, nil, nil, &Validators{
+		PoolRules: func() map[string]func(string) error {
+			return map[string]func(string) error{
+				"source":      validate.IsAny,
+				"volume.size": validate.Optional(validate.IsSize),
+			}
+		},
+		VolumeRules: func(vol Volume) map[string]func(string) error {
+			return map[string]func(string) error{
+				"size": validate.Optional(validate.IsSize),
+			}
+		},
+	})
+
+	return d
+}
+
+func Test_dir_validateQuotaSupported(t *testing.T) {
+	d := newTestDir(t, map[string]string{}, true, nil)
+	assert.NoError(t, d.validateQuotaSupported(GetPoolMountPath("pool")))
+
+	// Paths that don't exist yet are skipped.
+	d = newTestDir(t, map[string]string{}, false, nil)
+	assert.NoError(t, d.validateQuotaSupported(filepath.Join(t.TempDir(), "missing")))
+	assert.ErrorContains(t, d.validateQuotaSupported(GetPoolMountPath("pool")), "project quotas")
+
+	// The error of the check is returned.
+	d = newTestDir(t, map[string]string{}, false, errors.New("No backing device"))
+	assert.ErrorContains(t, d.validateQuotaSupported(GetPoolMountPath("pool")), "No backing device")
+}
+
+func Test_dir_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    map[string]string
+		supported bool
+		valid     bool
+	}{
+		{"Size on unsupported filesystem", map[string]string{"volume.size": "1GiB"}, false, true},
+		{"Strict on supported filesystem", map[string]string{"dir.quota.strict": "true"}, true, true},
+		{"Strict on unsupported filesystem", map[string]string{"dir.quota.strict": "true"}, false, false},
+		{"Invalid strict", map[string]string{"dir.quota.strict": "foo"}, true, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := newTestDir(t, nil, test.supported, nil)
+			test.config["source"] = GetPoolMountPath("pool")
+
+			err := d.Validate(test.config)
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func Test_dir_ValidateVolume(t *testing.T) {
+	tests := []struct {
+		name      string
+		strict    string
+		supported bool
+		size      string
+		valid     bool
+	}{
+		{"Size on unsupported filesystem", "", false, "1MiB", true},
+		{"Strict size on supported filesystem", "true", true, "1MiB", true},
+		{"Strict size on unsupported filesystem", "true", false, "1MiB", false},
+		{"Strict without size on unsupported filesystem", "true", false, "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := newTestDir(t, map[string]string{"dir.quota.strict": test.strict}, test.supported, nil)
+
+			volConfig := map[string]string{}
+			if test.size != "" {
+				volConfig["size"] = test.size
+			}
+
+			vol := NewVolume(d, "pool", VolumeTypeCustom, ContentTypeFS, "vol", volConfig, d.config)
+			err := d.ValidateVolume(vol, false)
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
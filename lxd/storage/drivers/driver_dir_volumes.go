@@ -250,6 +250,22 @@ func (d *dir) ValidateVolume(vol Volume, removeUnknownKeys bool) error {
 		return fmt.Errorf("Size cannot be specified for buckets")
 	}
 
+	// Filesystem volumes are limited with project quotas, so refuse a size that can't be enforced if the pool
+	// requires it.
+	if d.quotaStrict() && vol.contentType == ContentTypeFS && vol.volType != VolumeTypeBucket && vol.config["size"] != "" {
+		sizeBytes, err := units.ParseByteSizeString(vol.config["size"])
+		if err != nil {
+			return err
+		}
+
+		if sizeBytes > 0 {
+			err = d.validateQuotaSupported(GetPoolMountPath(d.name))
+			if err != nil {
+				return fmt.Errorf("Invalid value for %q: %w", "size", err)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -340,8 +356,17 @@ func (d *dir) SetVolumeQuota(vol Volume, size string, allowUnsafeResize bool, op
 			return err
 		}
 
-		// Custom handling for filesystem volume associated with a VM.
+		// Refuse a size limit that can't be enforced if the pool requires it. The filesystem volume of a VM is
+		// only limited on a best effort basis as its size is derived from the size of the VM.
 		volPath := vol.MountPath()
+		if sizeBytes > 0 && volID != volIDQuotaSkip && vol.volType != VolumeTypeVM && d.quotaStrict() {
+			err = d.validateQuotaSupported(volPath)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Custom handling for filesystem volume associated with a VM.
 		if sizeBytes > 0 && vol.volType == VolumeTypeVM && shared.PathExists(filepath.Join(volPath, genericVolumeDiskFile)) {
 			// Get the size of the VM image.
 			blockSize, err := BlockDiskSizeBytes(filepath.Join(volPath, genericVolumeDiskFile))
@@ -600,6 +625,14 @@ func (d *dir) RestoreVolume(vol Volume, snapVol Volume, op *operations.Operation
 		if err != nil {
 			return fmt.Errorf("Failed to rsync volume: %w", err)
 		}
+
+		// Re-apply the quota so that the restored files are accounted to the volume's project.
+		if vol.contentType == ContentTypeFS && vol.volType != VolumeTypeBucket {
+			err = d.SetVolumeQuota(vol, vol.ConfigSize(), false, op)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	// Restore block volume.
@@ -0,0 +1,283 @@
+package drivers
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/canonical/lxd/lxd/migration"
+	"github.com/canonical/lxd/lxd/operations"
+	"github.com/canonical/lxd/lxd/util"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/validate"
+)
+
+// iscsiDefaultPort represents the default port of iSCSI portals.
+const iscsiDefaultPort = 3260
+
+var iscsiLoaded bool
+var iscsiVersion string
+
+type iscsi struct {
+	common
+}
+
+// load is used to run one-time action per-driver rather than per-pool.
+func (d *iscsi) load() error {
+	// Done if previously loaded.
+	if iscsiLoaded {
+		return nil
+	}
+
+	// Validate the required binaries.
+	for _, tool := range []string{"iscsiadm"} {
+		_, err := exec.LookPath(tool)
+		if err != nil {
+			return fmt.Errorf("Required tool %q is missing", tool)
+		}
+	}
+
+	// Detect and record the version.
+	out, err := shared.RunCommand("iscsiadm", "--version")
+	if err != nil {
+		return fmt.Errorf("Failed to get iscsiadm version: %w", err)
+	}
+
+	fields := strings.Fields(out)
+	if strings.HasPrefix(out, "iscsiadm version ") && len(fields) > 2 {
+		iscsiVersion = fields[2]
+	}
+
+	// Load the iSCSI/TCP kernel module.
+	// Ignore if the module cannot be loaded, logging in to the portals fails in that case.
+	_ = util.LoadModule("iscsi_tcp")
+
+	iscsiLoaded = true
+	return nil
+}
+
+// isRemote returns true indicating this driver uses remote storage.
+func (d *iscsi) isRemote() bool {
+	return true
+}
+
+// Info returns info about the driver and its environment.
+func (d *iscsi) Info() Info {
+	return Info{
+		Name:              "iscsi",
+		Version:           iscsiVersion,
+		OptimizedImages:   false,
+		PreservesInodes:   false,
+		Remote:            d.isRemote(),
+		VolumeTypes:       []VolumeType{VolumeTypeCustom},
+		BlockBacking:      true,
+		RunningCopyFreeze: false,
+		DirectIO:          true,
+		IOUring:           true,
+		MountedRoot:       false,
+	}
+}
+
+// FillConfig populates the storage pool's configuration file with the default values.
+func (d *iscsi) FillConfig() error {
+	return nil
+}
+
+// Create is called during pool creation and is effectively using an empty driver struct.
+// WARNING: The Create() function cannot rely on any of the struct attributes being set.
+func (d *iscsi) Create() error {
+	err := d.FillConfig()
+	if err != nil {
+		return err
+	}
+
+	// Validate the keys that aren't cluster member specific here and return an error if they are not set.
+	// The general validation rules allow empty strings in order to create the pending storage pools.
+	for _, key := range []string{"iscsi.portals", "iscsi.target", "iscsi.provisioner"} {
+		if d.config[key] == "" {
+			return fmt.Errorf("The %s cannot be empty", key)
+		}
+	}
+
+	if !shared.PathExists(d.config["iscsi.provisioner"]) {
+		return fmt.Errorf("The iSCSI provisioner %q doesn't exist", d.config["iscsi.provisioner"])
+	}
+
+	return nil
+}
+
+// Delete removes the storage pool from the storage device.
+func (d *iscsi) Delete(op *operations.Operation) error {
+	// Log out from the target on this host.
+	err := d.logout()
+	if err != nil {
+		return err
+	}
+
+	// If the user completely destroyed it, call it done.
+	if !shared.PathExists(GetPoolMountPath(d.name)) {
+		return nil
+	}
+
+	// On delete, wipe everything in the directory.
+	return wipeDirectory(GetPoolMountPath(d.name))
+}
+
+// Validate checks that all provided keys are supported and that no conflicting or missing configuration is present.
+func (d *iscsi) Validate(config map[string]string) error {
+	rules := map[string]func(value string) error{
+		// lxdmeta:generate(entities=storage-iscsi; group=pool-conf; key=iscsi.portals)
+		// Specify a comma-separated list of IP addresses, optionally with a port.
+		// LXD logs in to the target through each of the portals.
+		// ---
+		//  type: string
+		//  shortdesc: Portals of the iSCSI target
+		"iscsi.portals": validate.Optional(validate.IsListOf(validate.IsListenAddress(false, false, false))),
+		// lxdmeta:generate(entities=storage-iscsi; group=pool-conf; key=iscsi.target)
+		//
+		// ---
+		//  type: string
+		//  shortdesc: IQN of the iSCSI target
+		"iscsi.target": validate.IsAny,
+		// lxdmeta:generate(entities=storage-iscsi; group=pool-conf; key=iscsi.chap.username)
+		// If set, LXD uses CHAP to authenticate with the target.
+		// ---
+		//  type: string
+		//  shortdesc: User name for CHAP authentication
+		"iscsi.chap.username": validate.IsAny,
+		// lxdmeta:generate(entities=storage-iscsi; group=pool-conf; key=iscsi.chap.password)
+		//
+		// ---
+		//  type: string
+		//  shortdesc: Password for CHAP authentication
+		"iscsi.chap.password": validate.IsAny,
+		// lxdmeta:generate(entities=storage-iscsi; group=pool-conf; key=iscsi.provisioner)
+		// The provisioner creates, resizes and deletes the LUNs of the volumes on the storage array.
+		// See {ref}`storage-iscsi-provisioner` for more information.
+		// ---
+		//  type: string
+		//  shortdesc: Path to the executable that provisions LUNs
+		"iscsi.provisioner": validate.Optional(validate.IsAbsFilePath),
+		// lxdmeta:generate(entities=storage-iscsi; group=pool-conf; key=iscsi.multipath)
+		// If enabled, the paths to a LUN through all portals are assembled into a single multipath device.
+		// ---
+		//  type: bool
+		//  defaultdesc: `true`
+		//  shortdesc: Whether to use multipath devices
+		"iscsi.multipath": validate.Optional(validate.IsBool),
+	}
+
+	err := d.validatePool(config, rules, nil)
+	if err != nil {
+		return err
+	}
+
+	// Check if multipath is available on this member.
+	// When forming the storage pool on a LXD cluster, this gets checked on every cluster member too
+	// since Validate gets executed when receiving the cluster notification to finally create the pool.
+	if !shared.IsFalse(config["iscsi.multipath"]) {
+		_, err := exec.LookPath("multipath")
+		if err != nil {
+			return fmt.Errorf("Required tool %q is missing", "multipath")
+		}
+	}
+
+	return nil
+}
+
+// Update applies any driver changes required from a configuration change.
+func (d *iscsi) Update(changedConfig map[string]string) error {
+	_, changed := changedConfig["iscsi.target"]
+	if changed {
+		return fmt.Errorf("The iscsi.target cannot be changed")
+	}
+
+	// Log in through any newly added portal or using the new credentials.
+	for _, key := range []string{"iscsi.portals", "iscsi.chap.username", "iscsi.chap.password"} {
+		_, changed := changedConfig[key]
+		if changed {
+			return d.login()
+		}
+	}
+
+	return nil
+}
+
+// Mount mounts the storage pool.
+func (d *iscsi) Mount() (bool, error) {
+	err := d.login()
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Unmount unmounts the storage pool.
+func (d *iscsi) Unmount() (bool, error) {
+	// Nothing to do here.
+	// The sessions are kept so that the volumes remain reachable, they are only closed when deleting the pool.
+	return true, nil
+}
+
+// GetResources returns the pool resource usage information.
+// The space of the pool isn't known to LXD, instead the state of the sessions with each portal is returned.
+func (d *iscsi) GetResources() (*api.ResourcesStoragePool, error) {
+	sessions, err := d.targetSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	res := &api.ResourcesStoragePool{}
+	loggedIn := false
+	states := []string{}
+
+	for _, portal := range d.portals() {
+		session := api.ResourcesStoragePoolSession{
+			Portal: portal,
+			Target: d.config["iscsi.target"],
+			State:  "FREE",
+		}
+
+		for _, s := range sessions {
+			if s.portal == portal {
+				session.State = s.state
+				break
+			}
+		}
+
+		if session.State == "LOGGED_IN" {
+			loggedIn = true
+		}
+
+		res.Sessions = append(res.Sessions, session)
+		states = append(states, fmt.Sprintf("%s: %s", portal, session.State))
+	}
+
+	if !loggedIn {
+		return nil, fmt.Errorf("No session with iSCSI target %q is logged in (%s)", d.config["iscsi.target"], strings.Join(states, ", "))
+	}
+
+	return res, nil
+}
+
+// MigrationTypes returns the type of transfer methods to be used when doing migrations between pools in preference order.
+func (d *iscsi) MigrationTypes(contentType ContentType, refresh bool, copySnapshots bool) []migration.Type {
+	var rsyncFeatures []string
+
+	// Do not pass compression argument to rsync if the associated
+	// config key, that is rsync.compression, is set to false.
+	if shared.IsFalse(d.Config()["rsync.compression"]) {
+		rsyncFeatures = []string{"xattrs", "delete", "bidirectional"}
+	} else {
+		rsyncFeatures = []string{"xattrs", "delete", "compress", "bidirectional"}
+	}
+
+	return []migration.Type{
+		{
+			FSType:   migration.MigrationFSType_BLOCK_AND_RSYNC,
+			Features: rsyncFeatures,
+		},
+	}
+}
@@ -0,0 +1,567 @@
+package drivers
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/canonical/lxd/lxd/locking"
+	"github.com/canonical/lxd/lxd/util"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/revert"
+)
+
+// iscsiSession represents an iSCSI session of this host.
+type iscsiSession struct {
+	id     string
+	target string
+	portal string
+	state  string
+}
+
+// iscsiSessions returns the iSCSI sessions of this host as reported by the kernel.
+func iscsiSessions() ([]iscsiSession, error) {
+	paths, err := filepath.Glob("/sys/class/iscsi_session/session*")
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]iscsiSession, 0, len(paths))
+	for _, path := range paths {
+		id := strings.TrimPrefix(filepath.Base(path), "session")
+		connPath := fmt.Sprintf("/sys/class/iscsi_connection/connection%s:0", id)
+
+		values := map[string]string{}
+		for _, file := range []string{filepath.Join(path, "targetname"), filepath.Join(path, "state"), filepath.Join(connPath, "persistent_address"), filepath.Join(connPath, "persistent_port")} {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				// The session might have been closed in the meantime.
+				if os.IsNotExist(err) {
+					break
+				}
+
+				return nil, fmt.Errorf("Failed reading iSCSI session %s: %w", id, err)
+			}
+
+			values[filepath.Base(file)] = strings.TrimSpace(string(content))
+		}
+
+		if len(values) < 4 {
+			continue
+		}
+
+		sessions = append(sessions, iscsiSession{
+			id:     id,
+			target: values["targetname"],
+			portal: iscsiCanonicalPortal(net.JoinHostPort(values["persistent_address"], values["persistent_port"])),
+			state:  values["state"],
+		})
+	}
+
+	return sessions, nil
+}
+
+// iscsiCanonicalPortal returns the portal address including the port in the same form as reported by the kernel.
+func iscsiCanonicalPortal(address string) string {
+	address = util.CanonicalNetworkAddress(strings.TrimSpace(address), iscsiDefaultPort)
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return address
+	}
+
+	return net.JoinHostPort(ip.String(), port)
+}
+
+// iscsiScanLUN asks the SCSI host of the given iSCSI session to scan for a single LUN.
+// The portals are logged in to with manual scanning, so only the LUNs of the volumes used on this host get a device.
+func iscsiScanLUN(sessionID string, lun uint64) error {
+	sessionPath, err := filepath.EvalSymlinks(fmt.Sprintf("/sys/class/iscsi_session/session%s/device", sessionID))
+	if err != nil {
+		return fmt.Errorf("Failed resolving iSCSI session %s: %w", sessionID, err)
+	}
+
+	// The session device is a child of the SCSI host that got created for it.
+	host := filepath.Base(filepath.Dir(sessionPath))
+	if !strings.HasPrefix(host, "host") {
+		return fmt.Errorf("Failed finding the SCSI host of iSCSI session %s", sessionID)
+	}
+
+	// As each session has its own SCSI host, any channel and target ID can be scanned.
+	err = os.WriteFile(filepath.Join("/sys/class/scsi_host", host, "scan"), []byte(fmt.Sprintf("- - %d", lun)), 0)
+	if err != nil {
+		return fmt.Errorf("Failed scanning for LUN %d on iSCSI session %s: %w", lun, sessionID, err)
+	}
+
+	return nil
+}
+
+// iscsiLUNDisks returns the SCSI disks (for example sdb) of the given LUN through each of the sessions.
+func iscsiLUNDisks(sessions []iscsiSession, lun uint64) ([]string, error) {
+	var disks []string
+
+	for _, session := range sessions {
+		paths, err := filepath.Glob(fmt.Sprintf("/sys/class/iscsi_session/session%s/device/target*/*:*:*:%d/block/*", session.id, lun))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, path := range paths {
+			disks = append(disks, filepath.Base(path))
+		}
+	}
+
+	return disks, nil
+}
+
+// multipathDevice returns the name of the multipath device holding the given SCSI disk.
+// Returns an empty string if the disk isn't part of a multipath device.
+func multipathDevice(disk string) (string, error) {
+	holders, err := os.ReadDir(filepath.Join("/sys/block", disk, "holders"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	for _, holder := range holders {
+		dmUUID, err := os.ReadFile(filepath.Join("/sys/block", holder.Name(), "dm", "uuid"))
+		if err != nil || !strings.HasPrefix(string(dmUUID), "mpath-") {
+			continue
+		}
+
+		name, err := os.ReadFile(filepath.Join("/sys/block", holder.Name(), "dm", "name"))
+		if err != nil {
+			return "", err
+		}
+
+		return strings.TrimSpace(string(name)), nil
+	}
+
+	return "", nil
+}
+
+// portals returns the canonical addresses of the portals of the pool.
+func (d *iscsi) portals() []string {
+	var portals []string
+
+	for _, portal := range shared.SplitNTrimSpace(d.config["iscsi.portals"], ",", -1, true) {
+		portals = append(portals, iscsiCanonicalPortal(portal))
+	}
+
+	return portals
+}
+
+// multipath returns true if the paths to a LUN are assembled into a multipath device.
+func (d *iscsi) multipath() bool {
+	return !shared.IsFalse(d.config["iscsi.multipath"])
+}
+
+// targetSessions returns the sessions of this host with the target of the pool.
+func (d *iscsi) targetSessions() ([]iscsiSession, error) {
+	sessions, err := iscsiSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	var targetSessions []iscsiSession
+	for _, session := range sessions {
+		if session.target == d.config["iscsi.target"] {
+			targetSessions = append(targetSessions, session)
+		}
+	}
+
+	return targetSessions, nil
+}
+
+// iscsiadm runs iscsiadm for the node record of the pool's target on the given portal.
+func (d *iscsi) iscsiadm(portal string, args ...string) (string, error) {
+	return shared.RunCommandContext(d.state.ShutdownCtx, "iscsiadm", append([]string{"--mode", "node", "--targetname", d.config["iscsi.target"], "--portal", portal}, args...)...)
+}
+
+// login logs this host in to the pool's target through each of the portals it doesn't have a session with yet.
+// Failing to log in through some of the portals is tolerated as long as there is a session through another one.
+// The operation is locked using lock name iscsi.
+func (d *iscsi) login() error {
+	unlock, err := locking.Lock(d.state.ShutdownCtx, "iscsi")
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	sessions, err := d.targetSessions()
+	if err != nil {
+		return err
+	}
+
+	var loginErr error
+	loggedIn := false
+
+	for _, portal := range d.portals() {
+		found := false
+		for _, session := range sessions {
+			if session.portal == portal {
+				found = true
+				break
+			}
+		}
+
+		if found {
+			loggedIn = true
+			continue
+		}
+
+		err := d.loginPortal(portal)
+		if err != nil {
+			d.logger.Warn("Failed logging in to iSCSI portal", logger.Ctx{"portal": portal, "err": err})
+			loginErr = err
+			continue
+		}
+
+		loggedIn = true
+	}
+
+	if !loggedIn {
+		if loginErr != nil {
+			return loginErr
+		}
+
+		return fmt.Errorf("No iSCSI portal configured")
+	}
+
+	return nil
+}
+
+// loginPortal creates the node record for the given portal and logs in to the pool's target through it.
+func (d *iscsi) loginPortal(portal string) error {
+	_, err := d.iscsiadm(portal, "--op", "new")
+	if err != nil {
+		return fmt.Errorf("Failed creating iSCSI node record for portal %q: %w", portal, err)
+	}
+
+	// LXD logs in when the pool is mounted, and scans for the LUN of each volume when it's needed.
+	settings := [][2]string{
+		{"node.startup", "manual"},
+		{"node.session.scan", "manual"},
+	}
+
+	if d.config["iscsi.chap.username"] != "" {
+		settings = append(settings,
+			[2]string{"node.session.auth.authmethod", "CHAP"},
+			[2]string{"node.session.auth.username", d.config["iscsi.chap.username"]},
+			[2]string{"node.session.auth.password", d.config["iscsi.chap.password"]},
+		)
+	} else {
+		settings = append(settings, [2]string{"node.session.auth.authmethod", "None"})
+	}
+
+	for _, setting := range settings {
+		_, err := d.iscsiadm(portal, "--op", "update", "--name", setting[0], "--value", setting[1])
+		if err != nil {
+			return fmt.Errorf("Failed setting %q of iSCSI node record for portal %q: %w", setting[0], portal, err)
+		}
+	}
+
+	_, err = d.iscsiadm(portal, "--login")
+	if err != nil {
+		return fmt.Errorf("Failed logging in to iSCSI portal %q: %w", portal, err)
+	}
+
+	return nil
+}
+
+// logout logs this host out of all sessions with the pool's target and removes the node records of its portals.
+// The operation is locked using lock name iscsi.
+func (d *iscsi) logout() error {
+	unlock, err := locking.Lock(d.state.ShutdownCtx, "iscsi")
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	sessions, err := d.targetSessions()
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		_, err := d.iscsiadm(session.portal, "--logout")
+		if err != nil {
+			return fmt.Errorf("Failed logging out of iSCSI portal %q: %w", session.portal, err)
+		}
+	}
+
+	// The node record doesn't exist for portals that were never logged in to.
+	for _, portal := range d.portals() {
+		_, _ = d.iscsiadm(portal, "--op", "delete")
+	}
+
+	return nil
+}
+
+// provision runs the pool's provisioner with the given action for the volume and returns its output.
+func (d *iscsi) provision(action string, volName string, args ...string) (string, error) {
+	env := append(os.Environ(), fmt.Sprintf("LXD_STORAGE_POOL=%s", d.name), fmt.Sprintf("LXD_ISCSI_TARGET=%s", d.config["iscsi.target"]))
+
+	stdout, _, err := shared.RunCommandSplit(d.state.ShutdownCtx, env, nil, d.config["iscsi.provisioner"], append([]string{action, volName}, args...)...)
+	if err != nil {
+		return "", fmt.Errorf("Failed running iSCSI provisioner action %q for volume %q: %w", action, volName, err)
+	}
+
+	return strings.TrimSpace(stdout), nil
+}
+
+// getVolumeName returns the name of the volume on the storage array.
+// It's derived from the volume's UUID so that it doesn't change when the volume gets renamed.
+func (d *iscsi) getVolumeName(vol Volume) (string, error) {
+	volUUID, err := uuid.Parse(vol.config["volatile.uuid"])
+	if err != nil {
+		return "", fmt.Errorf(`Failed parsing "volatile.uuid" from volume %q: %w`, vol.name, err)
+	}
+
+	return fmt.Sprintf("lxd-%s", volUUID.String()), nil
+}
+
+// getVolumeLUN returns the LUN of the volume as reported by the provisioner.
+// Returns a not found error if the volume doesn't exist on the storage array.
+func (d *iscsi) getVolumeLUN(vol Volume) (uint64, error) {
+	volName, err := d.getVolumeName(vol)
+	if err != nil {
+		return 0, err
+	}
+
+	out, err := d.provision("lun", volName)
+	if err != nil {
+		return 0, err
+	}
+
+	if out == "" {
+		return 0, api.StatusErrorf(http.StatusNotFound, "Volume %q not found on iSCSI target %q", volName, d.config["iscsi.target"])
+	}
+
+	lun, err := strconv.ParseUint(out, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Failed parsing LUN %q of volume %q: %w", out, volName, err)
+	}
+
+	return lun, nil
+}
+
+// getMappedDevPath returns the local device path of the volume's LUN.
+// Set mapVolume to true to scan for the LUN on each session if it isn't already mapped to this host.
+// When multipath is enabled, the paths through all sessions are assembled into a multipath device.
+func (d *iscsi) getMappedDevPath(vol Volume, mapVolume bool) (string, revert.Hook, error) {
+	revert := revert.New()
+	defer revert.Fail()
+
+	lun, err := d.getVolumeLUN(vol)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if mapVolume {
+		err = d.login()
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	sessions, err := d.targetSessions()
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Only unmap the LUN on failure if it wasn't already mapped before.
+	if mapVolume {
+		disks, err := iscsiLUNDisks(sessions, lun)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if len(disks) == 0 {
+			revert.Add(func() { _ = d.unmapLUN(lun) })
+		}
+	}
+
+	loggedIn := 0
+	for _, session := range sessions {
+		if session.state != "LOGGED_IN" {
+			continue
+		}
+
+		loggedIn++
+
+		if mapVolume {
+			err := iscsiScanLUN(session.id, lun)
+			if err != nil {
+				return "", nil, err
+			}
+		}
+	}
+
+	// Give the paths through all sessions a chance to show up before assembling the multipath device.
+	// Otherwise the multipath device is assembled with the paths found so far.
+	pathTimeout := time.Now().Add(5 * time.Second)
+	timeout := time.Now().Add(10 * time.Second)
+	assembled := false
+
+	for {
+		disks, err := iscsiLUNDisks(sessions, lun)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if len(disks) > 0 {
+			if !d.multipath() {
+				cleanup := revert.Clone().Fail
+				revert.Success()
+				return filepath.Join("/dev", disks[0]), cleanup, nil
+			}
+
+			for _, disk := range disks {
+				name, err := multipathDevice(disk)
+				if err != nil {
+					return "", nil, err
+				}
+
+				if name != "" {
+					cleanup := revert.Clone().Fail
+					revert.Success()
+					return filepath.Join("/dev/mapper", name), cleanup, nil
+				}
+			}
+
+			if mapVolume && !assembled && (len(disks) >= loggedIn || time.Now().After(pathTimeout)) {
+				devPath := filepath.Join("/dev", disks[0])
+
+				// Allow the device to be used by multipath regardless of the find_multipaths setting.
+				_, err := shared.RunCommand("multipath", "-a", devPath)
+				if err != nil {
+					return "", nil, fmt.Errorf("Failed adding %q to the multipath devices: %w", devPath, err)
+				}
+
+				_, err = shared.RunCommand("multipath", devPath)
+				if err != nil {
+					return "", nil, fmt.Errorf("Failed creating multipath device for %q: %w", devPath, err)
+				}
+
+				assembled = true
+			}
+		}
+
+		// Exit if the volume wasn't explicitly mapped.
+		// Doing a retry would run into the timeout when the LUN isn't mapped.
+		if !mapVolume {
+			break
+		}
+
+		if time.Now().After(timeout) {
+			return "", nil, fmt.Errorf("Timeout exceeded for iSCSI LUN %d discovery of volume %q", lun, vol.name)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return "", nil, fmt.Errorf("LUN %d of volume %q isn't mapped", lun, vol.name)
+}
+
+// unmapLUN removes the devices of the given LUN from this host.
+// The multipath device is flushed before the SCSI disks of each path get deleted, so that no stale
+// devices are left behind when a volume is detached or moved to another cluster member.
+func (d *iscsi) unmapLUN(lun uint64) error {
+	sessions, err := d.targetSessions()
+	if err != nil {
+		return err
+	}
+
+	disks, err := iscsiLUNDisks(sessions, lun)
+	if err != nil {
+		return err
+	}
+
+	// Check for the multipath device regardless of the pool's config, in case it changed since mapping the LUN.
+	for _, disk := range disks {
+		name, err := multipathDevice(disk)
+		if err != nil {
+			return err
+		}
+
+		if name == "" {
+			continue
+		}
+
+		_, err = shared.RunCommand("multipath", "-f", name)
+		if err != nil {
+			return fmt.Errorf("Failed flushing multipath device %q: %w", name, err)
+		}
+
+		break
+	}
+
+	for _, disk := range disks {
+		// Flushing fails for paths that are down, which doesn't prevent deleting the disk.
+		_, _ = shared.RunCommand("blockdev", "--flushbufs", filepath.Join("/dev", disk))
+
+		err := os.WriteFile(filepath.Join("/sys/block", disk, "device", "delete"), []byte("1"), 0)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("Failed deleting SCSI disk %q: %w", disk, err)
+		}
+	}
+
+	return nil
+}
+
+// rescanLUN makes the kernel pick up the new size of the given LUN on each path and in the multipath device.
+func (d *iscsi) rescanLUN(lun uint64) error {
+	sessions, err := d.targetSessions()
+	if err != nil {
+		return err
+	}
+
+	disks, err := iscsiLUNDisks(sessions, lun)
+	if err != nil {
+		return err
+	}
+
+	multipathName := ""
+	for _, disk := range disks {
+		err := os.WriteFile(filepath.Join("/sys/block", disk, "device", "rescan"), []byte("1"), 0)
+		if err != nil {
+			return fmt.Errorf("Failed rescanning SCSI disk %q: %w", disk, err)
+		}
+
+		if multipathName == "" {
+			multipathName, err = multipathDevice(disk)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if multipathName != "" {
+		_, err := shared.RunCommand("multipathd", "resize", "map", multipathName)
+		if err != nil {
+			return fmt.Errorf("Failed resizing multipath device %q: %w", multipathName, err)
+		}
+	}
+
+	return nil
+}
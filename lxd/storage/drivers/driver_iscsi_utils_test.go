@@ -0,0 +1,25 @@
+package drivers
+
+import (
+	"fmt"
+)
+
+func Example_iscsiCanonicalPortal() {
+	portals := []string{
+		"10.0.0.10",
+		"10.0.0.10:3261",
+		" 10.0.0.11 ",
+		"fd00::0010",
+		"[fd00:0::10]:3261",
+	}
+
+	for _, portal := range portals {
+		fmt.Println(iscsiCanonicalPortal(portal))
+	}
+
+	// Output: 10.0.0.10:3260
+	// 10.0.0.10:3261
+	// 10.0.0.11:3260
+	// [fd00::10]:3260
+	// [fd00::10]:3261
+}
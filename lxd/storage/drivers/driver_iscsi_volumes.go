@@ -0,0 +1,315 @@
+package drivers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/canonical/lxd/lxd/backup"
+	"github.com/canonical/lxd/lxd/instancewriter"
+	"github.com/canonical/lxd/lxd/migration"
+	"github.com/canonical/lxd/lxd/operations"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/revert"
+	"github.com/canonical/lxd/shared/units"
+)
+
+// CreateVolume creates an empty volume and can optionally fill it by executing the supplied filler function.
+func (d *iscsi) CreateVolume(vol Volume, filler *VolumeFiller, op *operations.Operation) error {
+	if vol.volType != VolumeTypeCustom || vol.contentType != ContentTypeBlock {
+		return ErrNotSupported
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	sizeBytes, err := units.ParseByteSizeString(vol.ConfigSize())
+	if err != nil {
+		return err
+	}
+
+	volName, err := d.getVolumeName(vol)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.provision("create", volName, strconv.FormatInt(sizeBytes, 10))
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() { _, _ = d.provision("delete", volName) })
+
+	// Run the volume filler function if supplied.
+	if filler != nil && filler.Fill != nil {
+		err = vol.MountTask(func(mountPath string, op *operations.Operation) error {
+			devPath, err := d.GetVolumeDiskPath(vol)
+			if err != nil {
+				return err
+			}
+
+			return d.runFiller(vol, devPath, filler, false)
+		}, op)
+		if err != nil {
+			return err
+		}
+	}
+
+	revert.Success()
+	return nil
+}
+
+// CreateVolumeFromBackup re-creates a volume from its exported state.
+func (d *iscsi) CreateVolumeFromBackup(vol VolumeCopy, srcBackup backup.Info, srcData io.ReadSeeker, op *operations.Operation) (VolumePostHook, revert.Hook, error) {
+	return genericVFSBackupUnpack(d, d.state.OS, vol, srcBackup.Snapshots, srcData, op)
+}
+
+// CreateVolumeFromCopy provides same-pool volume copying functionality.
+func (d *iscsi) CreateVolumeFromCopy(vol VolumeCopy, srcVol VolumeCopy, allowInconsistent bool, op *operations.Operation) error {
+	return genericVFSCopyVolume(d, nil, vol, srcVol, nil, false, allowInconsistent, op)
+}
+
+// CreateVolumeFromMigration creates a volume being sent via a migration.
+func (d *iscsi) CreateVolumeFromMigration(vol VolumeCopy, conn io.ReadWriteCloser, volTargetArgs migration.VolumeTargetArgs, preFiller *VolumeFiller, op *operations.Operation) error {
+	// When performing a cluster member move the volume is already on the target.
+	if volTargetArgs.ClusterMoveSourceName != "" {
+		return nil
+	}
+
+	return genericVFSCreateVolumeFromMigration(d, nil, vol, conn, volTargetArgs, preFiller, op)
+}
+
+// RefreshVolume updates an existing volume to match the state of another.
+func (d *iscsi) RefreshVolume(vol VolumeCopy, srcVol VolumeCopy, refreshSnapshots []string, allowInconsistent bool, op *operations.Operation) error {
+	return genericVFSCopyVolume(d, nil, vol, srcVol, refreshSnapshots, true, allowInconsistent, op)
+}
+
+// DeleteVolume deletes a volume of the storage device.
+func (d *iscsi) DeleteVolume(vol Volume, op *operations.Operation) error {
+	lun, err := d.getVolumeLUN(vol)
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return nil
+		}
+
+		return err
+	}
+
+	// Remove any device of the LUN left on this host before the LUN gets deleted.
+	err = d.unmapLUN(lun)
+	if err != nil {
+		return err
+	}
+
+	volName, err := d.getVolumeName(vol)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.provision("delete", volName)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// HasVolume indicates whether a specific volume exists on the storage pool.
+func (d *iscsi) HasVolume(vol Volume) (bool, error) {
+	_, err := d.getVolumeLUN(vol)
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ValidateVolume validates the supplied volume config.
+func (d *iscsi) ValidateVolume(vol Volume, removeUnknownKeys bool) error {
+	return d.validateVolume(vol, nil, removeUnknownKeys)
+}
+
+// UpdateVolume applies config changes to the volume.
+func (d *iscsi) UpdateVolume(vol Volume, changedConfig map[string]string) error {
+	newSize, sizeChanged := changedConfig["size"]
+	if sizeChanged {
+		err := d.SetVolumeQuota(vol, newSize, false, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetVolumeQuota applies a size limit on volume.
+// Does nothing if supplied with an empty/zero size.
+func (d *iscsi) SetVolumeQuota(vol Volume, size string, allowUnsafeResize bool, op *operations.Operation) error {
+	// Convert to bytes.
+	sizeBytes, err := units.ParseByteSizeString(size)
+	if err != nil {
+		return err
+	}
+
+	// Do nothing if size isn't specified.
+	if sizeBytes <= 0 {
+		return nil
+	}
+
+	devPath, cleanup, err := d.getMappedDevPath(vol, true)
+	if err != nil {
+		return err
+	}
+
+	if cleanup != nil {
+		defer func() { cleanup() }()
+	}
+
+	oldSizeBytes, err := BlockDiskSizeBytes(devPath)
+	if err != nil {
+		return fmt.Errorf("Error getting current size: %w", err)
+	}
+
+	// Do nothing if volume is already specified size (+/- 512 bytes).
+	if oldSizeBytes+512 > sizeBytes && oldSizeBytes-512 < sizeBytes {
+		return nil
+	}
+
+	// Shrinking the LUN would truncate the data of the block volume.
+	if sizeBytes < oldSizeBytes {
+		return fmt.Errorf("Volume capacity can only be increased")
+	}
+
+	// We don't allow online resizing of block volumes.
+	if !allowUnsafeResize && vol.MountInUse() {
+		return ErrInUse
+	}
+
+	volName, err := d.getVolumeName(vol)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.provision("resize", volName, strconv.FormatInt(sizeBytes, 10))
+	if err != nil {
+		return err
+	}
+
+	lun, err := d.getVolumeLUN(vol)
+	if err != nil {
+		return err
+	}
+
+	return d.rescanLUN(lun)
+}
+
+// GetVolumeDiskPath returns the location of a root disk block device.
+func (d *iscsi) GetVolumeDiskPath(vol Volume) (string, error) {
+	if vol.volType == VolumeTypeCustom && IsContentBlock(vol.contentType) {
+		devPath, _, err := d.getMappedDevPath(vol, false)
+		return devPath, err
+	}
+
+	return "", ErrNotSupported
+}
+
+// ListVolumes returns a list of LXD volumes in storage pool.
+func (d *iscsi) ListVolumes() ([]Volume, error) {
+	return []Volume{}, nil
+}
+
+// MountVolume mounts a volume and increments ref counter. Please call UnmountVolume() when done with the volume.
+func (d *iscsi) MountVolume(vol Volume, op *operations.Operation) error {
+	unlock, err := vol.MountLock()
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	// Map the LUN if needed.
+	devPath, cleanup, err := d.getMappedDevPath(vol, true)
+	if err != nil {
+		return err
+	}
+
+	revert.Add(cleanup)
+
+	d.logger.Debug("Mapped iSCSI volume", logger.Ctx{"volName": vol.name, "dev": devPath})
+
+	vol.MountRefCountIncrement() // From here on it is up to caller to call UnmountVolume() when done.
+	revert.Success()
+	return nil
+}
+
+// UnmountVolume simulates unmounting a volume.
+// keepBlockDev indicates if backing block device should not be unmapped if volume is unmounted.
+func (d *iscsi) UnmountVolume(vol Volume, keepBlockDev bool, op *operations.Operation) (bool, error) {
+	unlock, err := vol.MountLock()
+	if err != nil {
+		return false, err
+	}
+
+	defer unlock()
+
+	refCount := vol.MountRefCountDecrement()
+
+	if keepBlockDev {
+		return false, nil
+	}
+
+	// Check if the LUN is currently mapped (but don't map if not).
+	devPath, _, _ := d.getMappedDevPath(vol, false)
+	if devPath == "" {
+		return false, nil
+	}
+
+	if refCount > 0 {
+		d.logger.Debug("Skipping unmount as in use", logger.Ctx{"volName": vol.name, "refCount": refCount})
+		return false, ErrInUse
+	}
+
+	lun, err := d.getVolumeLUN(vol)
+	if err != nil {
+		return false, err
+	}
+
+	err = d.unmapLUN(lun)
+	if err != nil {
+		return false, err
+	}
+
+	d.logger.Debug("Unmapped iSCSI volume", logger.Ctx{"volName": vol.name, "dev": devPath})
+
+	return true, nil
+}
+
+// RenameVolume renames a volume and its snapshots.
+func (d *iscsi) RenameVolume(vol Volume, newVolName string, op *operations.Operation) error {
+	// The name of the volume on the storage array is derived from its UUID.
+	return nil
+}
+
+// MigrateVolume sends a volume for migration.
+func (d *iscsi) MigrateVolume(vol VolumeCopy, conn io.ReadWriteCloser, volSrcArgs *migration.VolumeSourceArgs, op *operations.Operation) error {
+	// When performing a cluster member move don't do anything on the source member.
+	if volSrcArgs.ClusterMove {
+		return nil
+	}
+
+	return genericVFSMigrateVolume(d, d.state, vol, conn, volSrcArgs, op)
+}
+
+// BackupVolume creates an exported version of a volume.
+func (d *iscsi) BackupVolume(vol VolumeCopy, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots []string, op *operations.Operation) error {
+	return genericVFSBackupVolume(d, vol, tarWriter, snapshots, op)
+}
@@ -820,9 +820,12 @@ func (d *powerflex) CreateVolumeSnapshot(snapVol Volume, op *operations.Operatio
 		// could still be busy), as we do not guarantee the consistency of a snapshot. This is costly but
 		// try to ensure that all cached data has been committed to disk. If we don't then the snapshot
 		// of the underlying filesystem can be inconsistent or, in the worst case, empty.
-		unfreezeFS, err := d.filesystemFreeze(sourcePath)
+		unfreezeFS, err := d.filesystemFreeze(sourcePath, op)
 		if err == nil {
 			defer func() { _ = unfreezeFS() }()
+		} else if _, frozen := err.(ErrFilesystemFrozen); frozen {
+			// Refuse to snapshot a filesystem left frozen by another operation.
+			return err
 		}
 	}
 
@@ -595,7 +595,7 @@ func (d *zfs) CreateVolumeFromCopy(vol VolumeCopy, srcVol VolumeCopy, allowIncon
 	var unfreezeFS func() error
 	sourcePath := srcVol.MountPath()
 	if !allowInconsistent && srcVol.contentType == ContentTypeFS && srcVol.IsBlockBacked() && filesystem.IsMountPoint(sourcePath) {
-		unfreezeFS, err = d.filesystemFreeze(sourcePath)
+		unfreezeFS, err = d.filesystemFreeze(sourcePath, op)
 		if err != nil {
 			return err
 		}
@@ -1074,7 +1074,10 @@ func (d *zfs) createVolumeFromMigrationOptimized(vol Volume, conn io.ReadWriteCl
 
 			wrapper := migration.ProgressWriter(op, "fs_progress", snapVol.Name())
 
-			err = d.receiveDataset(snapVol, conn, wrapper)
+			// Snapshots received before a failed transfer are kept, so only the failed snapshot is retried.
+			err = migration.RetryTransfer(op, conn, snapVol.Name(), func() error {
+				return d.receiveDataset(snapVol, conn, wrapper)
+			})
 			if err != nil {
 				_ = d.DeleteVolume(snapVol, op)
 				return fmt.Errorf("Failed receiving snapshot volume %q: %w", snapVol.Name(), err)
@@ -1094,7 +1097,9 @@ func (d *zfs) createVolumeFromMigrationOptimized(vol Volume, conn io.ReadWriteCl
 
 	// Transfer the main volume.
 	wrapper := migration.ProgressWriter(op, "fs_progress", vol.name)
-	err = d.receiveDataset(vol, conn, wrapper)
+	err = migration.RetryTransfer(op, conn, vol.name, func() error {
+		return d.receiveDataset(vol, conn, wrapper)
+	})
 	if err != nil {
 		return fmt.Errorf("Failed receiving volume %q: %w", vol.Name(), err)
 	}
@@ -2413,7 +2418,7 @@ func (d *zfs) MigrateVolume(vol VolumeCopy, conn io.ReadWriteCloser, volSrcArgs
 		// otherwise the source and target volumes may differ. Tests have shown that only calling
 		// os.SyncFS() doesn't suffice. A freeze and unfreeze is needed.
 		err := vol.MountTask(func(mountPath string, op *operations.Operation) error {
-			unfreezeFS, err := d.filesystemFreeze(mountPath)
+			unfreezeFS, err := d.filesystemFreeze(mountPath, op)
 			if err != nil {
 				return err
 			}
@@ -2580,7 +2585,10 @@ func (d *zfs) migrateVolumeOptimized(vol Volume, conn io.ReadWriteCloser, volSrc
 		}
 
 		// Send snapshot to recipient (ensure local snapshot volume is mounted if needed).
-		err := d.sendDataset(d.dataset(snapshot, false), parent, volSrcArgs, conn, wrapper)
+		// If the transfer fails, only this snapshot is sent again.
+		err := migration.RetryTransfer(op, conn, snapshot.name, func() error {
+			return d.sendDataset(d.dataset(snapshot, false), parent, volSrcArgs, conn, wrapper)
+		})
 		if err != nil {
 			return err
 		}
@@ -2625,7 +2633,9 @@ func (d *zfs) migrateVolumeOptimized(vol Volume, conn io.ReadWriteCloser, volSrc
 	}
 
 	// Send the volume itself.
-	err := d.sendDataset(srcSnapshot, finalParent, volSrcArgs, conn, wrapper)
+	err := migration.RetryTransfer(op, conn, vol.name, func() error {
+		return d.sendDataset(srcSnapshot, finalParent, volSrcArgs, conn, wrapper)
+	})
 	if err != nil {
 		return err
 	}
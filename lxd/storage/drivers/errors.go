@@ -2,6 +2,7 @@ package drivers
 
 import (
 	"fmt"
+	"time"
 )
 
 // ErrUnknownDriver is the "Unknown driver" error.
@@ -27,3 +28,12 @@ type ErrDeleteSnapshots struct {
 func (e ErrDeleteSnapshots) Error() string {
 	return fmt.Sprintf("More recent snapshots must be deleted: %+v", e.Snapshots)
 }
+
+// ErrFilesystemFrozen is returned when an operation needs to freeze a filesystem that is already frozen by LXD.
+type ErrFilesystemFrozen struct {
+	Freeze FilesystemFreeze
+}
+
+func (e ErrFilesystemFrozen) Error() string {
+	return fmt.Sprintf("Filesystem %q is frozen by %s since %s", e.Freeze.Path, filesystemFreezeOperation(&e.Freeze), e.Freeze.FrozenAt.Format(time.RFC3339))
+}
@@ -0,0 +1,169 @@
+package drivers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/canonical/lxd/lxd/db"
+	"github.com/canonical/lxd/lxd/db/warningtype"
+	"github.com/canonical/lxd/lxd/operations"
+	"github.com/canonical/lxd/lxd/state"
+	"github.com/canonical/lxd/lxd/storage/filesystem"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/logger"
+)
+
+// FilesystemFreeze represents a filesystem frozen by LXD.
+type FilesystemFreeze struct {
+	// Path of the frozen filesystem.
+	Path string
+
+	// Description of the operation that froze the filesystem.
+	Operation string
+
+	// URL of the operation that froze the filesystem, if any.
+	OperationURL string
+
+	// When the filesystem was frozen.
+	FrozenAt time.Time
+
+	// When the filesystem is thawed automatically, zero if never.
+	ThawAt time.Time
+
+	timer *time.Timer
+}
+
+// filesystemFreezes tracks the filesystems frozen by LXD by path.
+var filesystemFreezes = map[string]*FilesystemFreeze{}
+var filesystemFreezesMu sync.Mutex
+
+// FilesystemFrozen returns the freeze of the filesystem at path by LXD, or nil if LXD didn't freeze it.
+func FilesystemFrozen(path string) *FilesystemFreeze {
+	filesystemFreezesMu.Lock()
+	defer filesystemFreezesMu.Unlock()
+
+	freeze, ok := filesystemFreezes[path]
+	if !ok {
+		return nil
+	}
+
+	freezeCopy := *freeze
+	freezeCopy.timer = nil
+
+	return &freezeCopy
+}
+
+// FilesystemUnfreeze thaws the filesystem mounted at path, whether or not LXD still tracks it as frozen.
+// This allows recovering filesystems left frozen by an operation that didn't complete.
+func FilesystemUnfreeze(path string) error {
+	if !filesystem.IsMountPoint(path) {
+		return fmt.Errorf("Filesystem %q isn't mounted", path)
+	}
+
+	freeze := filesystemFreezeUntrack(path, nil)
+
+	_, err := shared.RunCommand("fsfreeze", "--unfreeze", path)
+	if err != nil {
+		if freeze == nil {
+			return fmt.Errorf("Failed unfreezing filesystem %q (it may not be frozen): %w", path, err)
+		}
+
+		return fmt.Errorf("Failed unfreezing filesystem %q: %w", path, err)
+	}
+
+	logger.Warn("Filesystem unfrozen on request", logger.Ctx{"path": path, "frozenBy": filesystemFreezeOperation(freeze)})
+
+	return nil
+}
+
+// filesystemFreezeTrack records the freeze of the filesystem at path by the operation and starts the watchdog
+// thawing it once the freeze timeout of the server is reached.
+func filesystemFreezeTrack(s *state.State, path string, op *operations.Operation, l logger.Logger) *FilesystemFreeze {
+	freeze := &FilesystemFreeze{
+		Path:      path,
+		Operation: "Unknown operation",
+		FrozenAt:  time.Now(),
+	}
+
+	if op != nil {
+		freeze.Operation = op.Type().Description()
+		freeze.OperationURL = op.URL()
+	}
+
+	var timeout time.Duration
+	if s != nil && s.GlobalConfig != nil {
+		timeout = s.GlobalConfig.InstancesFilesystemFreezeTimeout()
+	}
+
+	filesystemFreezesMu.Lock()
+	defer filesystemFreezesMu.Unlock()
+
+	if timeout > 0 {
+		freeze.ThawAt = freeze.FrozenAt.Add(timeout)
+		freeze.timer = time.AfterFunc(timeout, func() {
+			if filesystemFreezeUntrack(path, freeze) == nil {
+				return // Already thawed.
+			}
+
+			msg := fmt.Sprintf("Filesystem %q frozen by %s for more than %s was thawed", path, filesystemFreezeOperation(freeze), timeout)
+			l.Warn("Thawing filesystem frozen for too long", logger.Ctx{"path": path, "frozenBy": filesystemFreezeOperation(freeze), "timeout": timeout})
+
+			_, err := shared.RunCommand("fsfreeze", "--unfreeze", path)
+			if err != nil {
+				l.Error("Failed unfreezing filesystem", logger.Ctx{"path": path, "err": err})
+				msg = fmt.Sprintf("%s: %v", msg, err)
+			}
+
+			if s == nil || s.DB == nil || s.DB.Cluster == nil {
+				return
+			}
+
+			err = s.DB.Cluster.Transaction(context.Background(), func(ctx context.Context, tx *db.ClusterTx) error {
+				return tx.UpsertWarningLocalNode(ctx, "", "", -1, warningtype.FilesystemFreezeTimeout, msg)
+			})
+			if err != nil {
+				l.Warn("Failed to create warning", logger.Ctx{"err": err})
+			}
+		})
+	}
+
+	filesystemFreezes[path] = freeze
+
+	return freeze
+}
+
+// filesystemFreezeUntrack stops tracking the freeze of the filesystem at path and returns it.
+// If freeze isn't nil, the tracked freeze is only removed if it is this one.
+// Returns nil if the filesystem isn't tracked as frozen (anymore).
+func filesystemFreezeUntrack(path string, freeze *FilesystemFreeze) *FilesystemFreeze {
+	filesystemFreezesMu.Lock()
+	defer filesystemFreezesMu.Unlock()
+
+	tracked, ok := filesystemFreezes[path]
+	if !ok || (freeze != nil && tracked != freeze) {
+		return nil
+	}
+
+	if tracked.timer != nil {
+		tracked.timer.Stop()
+	}
+
+	delete(filesystemFreezes, path)
+
+	return tracked
+}
+
+// filesystemFreezeOperation returns a description of the operation behind a freeze.
+func filesystemFreezeOperation(freeze *FilesystemFreeze) string {
+	if freeze == nil {
+		return "an untracked operation"
+	}
+
+	if freeze.OperationURL == "" {
+		return fmt.Sprintf("%q", freeze.Operation)
+	}
+
+	return fmt.Sprintf("%q (%s)", freeze.Operation, freeze.OperationURL)
+}
@@ -0,0 +1,39 @@
+package drivers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/lxd/shared/logger"
+)
+
+func TestFilesystemFreezeTracking(t *testing.T) {
+	path := "/var/lib/lxd/storage-pools/default/containers/c1"
+
+	assert.Nil(t, FilesystemFrozen(path))
+
+	freeze := filesystemFreezeTrack(nil, path, nil, logger.Log)
+	defer filesystemFreezeUntrack(path, nil)
+
+	tracked := FilesystemFrozen(path)
+	require.NotNil(t, tracked)
+	assert.Equal(t, "Unknown operation", tracked.Operation)
+	assert.True(t, tracked.ThawAt.IsZero())
+
+	// Freezing an already frozen filesystem is refused, naming the operation that froze it.
+	d := &common{}
+	_, err := d.filesystemFreeze(path, nil)
+	require.Error(t, err)
+	assert.IsType(t, ErrFilesystemFrozen{}, err)
+	assert.Contains(t, err.Error(), `frozen by "Unknown operation"`)
+
+	// A stale freeze doesn't untrack a newer one.
+	newFreeze := filesystemFreezeTrack(nil, path, nil, logger.Log)
+	assert.Nil(t, filesystemFreezeUntrack(path, freeze))
+	assert.NotNil(t, FilesystemFrozen(path))
+
+	assert.Equal(t, newFreeze, filesystemFreezeUntrack(path, newFreeze))
+	assert.Nil(t, FilesystemFrozen(path))
+}
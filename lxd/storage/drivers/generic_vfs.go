@@ -173,14 +173,18 @@ func genericVFSMigrateVolume(d Driver, s *state.State, vol VolumeCopy, conn io.R
 		path := shared.AddSlash(mountPath)
 
 		d.Logger().Debug("Sending filesystem volume", logger.Ctx{"volName": vol.name, "path": path, "bwlimit": bwlimit, "rsyncArgs": rsyncArgs})
-		err := rsync.Send(vol.name, path, conn, wrapper, volSrcArgs.MigrationType.Features, bwlimit, s.OS.ExecPath, rsyncArgs...)
 
-		status, _ := shared.ExitStatus(err)
-		if volSrcArgs.AllowInconsistent && status == 24 {
-			return nil
-		}
+		// If the transfer is retried, rsync only sends what the target is still missing.
+		return migration.RetryTransfer(op, conn, vol.name, func() error {
+			err := rsync.Send(vol.name, path, conn, wrapper, volSrcArgs.MigrationType.Features, bwlimit, s.OS.ExecPath, rsyncArgs...)
 
-		return err
+			status, _ := shared.ExitStatus(err)
+			if volSrcArgs.AllowInconsistent && status == 24 {
+				return nil
+			}
+
+			return err
+		})
 	}
 
 	// Define function to send a block volume.
@@ -322,7 +326,10 @@ func genericVFSCreateVolumeFromMigration(d Driver, initVolume func(vol Volume) (
 		d.Logger().Debug("Receiving filesystem volume started", logger.Ctx{"volName": volName, "path": path, "features": volTargetArgs.MigrationType.Features})
 		defer d.Logger().Debug("Receiving filesystem volume stopped", logger.Ctx{"volName": volName, "path": path})
 
-		return rsync.Recv(path, conn, wrapper, volTargetArgs.MigrationType.Features)
+		// Partially transferred files are kept by rsync if the transfer is retried.
+		return migration.RetryTransfer(op, conn, volName, func() error {
+			return rsync.Recv(path, conn, wrapper, volTargetArgs.MigrationType.Features)
+		})
 	}
 
 	recvBlockVol := func(volName string, conn io.ReadWriteCloser, path string) error {
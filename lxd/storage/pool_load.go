@@ -239,6 +239,14 @@ func IsAvailable(poolName string) bool {
 	return !found
 }
 
+// AllAvailable checks if all pools that were mounted on this server are available.
+func AllAvailable() bool {
+	unavailablePoolsMu.Lock()
+	defer unavailablePoolsMu.Unlock()
+
+	return len(unavailablePools) == 0
+}
+
 // Patch applies specified patch to all storage pools.
 // All storage pools must be available locally before any storage pools are patched.
 func Patch(s *state.State, patchName string) error {
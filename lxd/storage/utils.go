@@ -462,7 +462,7 @@ func poolAndVolumeCommonRules(vol *drivers.Volume) map[string]func(string) error
 		//  defaultdesc: auto (20% of free disk space, >= 5 GiB and <= 30 GiB)
 		//  shortdesc: Size of the storage pool (for loop-based pools)
 
-		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-lvm,storage-zfs; group=volume-conf; key=size)
+		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-lvm,storage-zfs,storage-iscsi; group=volume-conf; key=size)
 		//
 		// ---
 		//  type: string
@@ -514,6 +514,14 @@ func poolAndVolumeCommonRules(vol *drivers.Volume) map[string]func(string) error
 		//  defaultdesc: same as `volume.snapshots.pattern` or `snap%d`
 		//  shortdesc: Template for the snapshot name
 		"snapshots.pattern": validate.IsAny,
+		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-lvm,storage-zfs,storage-powerflex; group=volume-conf; key=snapshots.retention.policy)
+		// Specify the name of a snapshot retention policy of the project, or of a server-wide policy if the project
+		// has no policy with that name. Snapshots that the rules of the policy don't keep are deleted.
+		// ---
+		//  type: string
+		//  condition: custom volume
+		//  shortdesc: Snapshot retention policy
+		"snapshots.retention.policy": validate.IsAny,
 	}
 
 	// security.shifted and security.unmapped are only relevant for custom filesystem volumes.
@@ -538,7 +546,7 @@ func poolAndVolumeCommonRules(vol *drivers.Volume) map[string]func(string) error
 
 	// Those keys are only valid for volumes.
 	if vol != nil {
-		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-lvm,storage-zfs,storage-powerflex; group=volume-conf; key=volatile.uuid)
+		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-lvm,storage-zfs,storage-powerflex,storage-iscsi; group=volume-conf; key=volatile.uuid)
 		//
 		// ---
 		//  type: string
@@ -613,6 +621,31 @@ func validatePoolCommonRules() map[string]func(string) error {
 		//  defaultdesc: `true`
 		//  shortdesc: Whether to use compression while migrating storage pools
 		"rsync.compression": validate.Optional(validate.IsBool),
+		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-lvm,storage-zfs,storage-powerflex,storage-iscsi; group=pool-conf; key=unavailable.action)
+		// When set to `pause`, LXD regularly checks that the storage pool is reachable on each cluster member.
+		// If it isn't, the running instances using the pool on that member are frozen (containers) or paused
+		// (virtual machines) until the pool is reachable again.
+		// ---
+		//  type: string
+		//  defaultdesc: `none`
+		//  shortdesc: What to do with instances when the pool becomes unreachable (`pause` or `none`)
+		"unavailable.action": validate.Optional(validate.IsOneOf("none", "pause")),
+		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-lvm,storage-zfs,storage-powerflex,storage-iscsi; group=pool-conf; key=unavailable.timeout)
+		// Instances that stay paused for longer than this because the pool is unreachable are stopped.
+		// ---
+		//  type: integer
+		//  condition: `unavailable.action` set to `pause`
+		//  defaultdesc: `0` (no limit)
+		//  shortdesc: How long (in seconds) instances can stay paused before being stopped
+		"unavailable.timeout": validate.Optional(validate.IsUint32),
+		// lxdmeta:generate(entities=storage-btrfs,storage-cephfs,storage-ceph,storage-dir,storage-lvm,storage-zfs,storage-powerflex; group=pool-conf; key=instances.create_concurrency)
+		// Instance root volumes created while the limit is reached are queued until earlier creations finish.
+		// The limit applies to each cluster member separately.
+		// ---
+		//  type: integer
+		//  defaultdesc: `0` (no limit)
+		//  shortdesc: Maximum number of instance root volumes created concurrently on the pool
+		"instances.create_concurrency": validate.Optional(validate.IsUint32),
 	}
 
 	// Add to pool config rules (prefixed with volume.*) which are common for pool and volume.
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/canonical/lxd/lxd/db"
+	"github.com/canonical/lxd/lxd/db/warningtype"
+	"github.com/canonical/lxd/lxd/instance"
+	"github.com/canonical/lxd/lxd/instance/instancetype"
+	"github.com/canonical/lxd/lxd/lifecycle"
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/lxd/state"
+	storagePools "github.com/canonical/lxd/lxd/storage"
+	"github.com/canonical/lxd/lxd/task"
+	"github.com/canonical/lxd/lxd/warnings"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/version"
+)
+
+// storagePoolHealthCheckTimeout is how long a storage pool has to answer a health check before being considered unreachable.
+const storagePoolHealthCheckTimeout = 30 * time.Second
+
+// storagePoolOutage records the instances paused on this member while a storage pool is unreachable.
+type storagePoolOutage struct {
+	since     time.Time
+	instances []instance.Instance
+}
+
+// storagePoolOutages holds the ongoing outages by pool name. It is only accessed by the storage pool health task.
+var storagePoolOutages = map[string]*storagePoolOutage{}
+
+// storagePoolHealthTask checks the storage pools with unavailable.action set to pause, pausing the instances that use
+// them while they are unreachable and resuming them once they recover.
+func storagePoolHealthTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		s := d.State()
+
+		var poolNames []string
+
+		err := s.DB.Cluster.Transaction(ctx, func(ctx context.Context, tx *db.ClusterTx) error {
+			var err error
+
+			poolNames, err = tx.GetCreatedStoragePoolNames(ctx)
+
+			return err
+		})
+		if err != nil && !response.IsNotFoundError(err) {
+			logger.Error("Failed loading storage pools for health check", logger.Ctx{"err": err})
+			return
+		}
+
+		// Forget about outages of pools that have since been deleted.
+		for poolName := range storagePoolOutages {
+			if !shared.ValueInSlice(poolName, poolNames) {
+				delete(storagePoolOutages, poolName)
+			}
+		}
+
+		for _, poolName := range poolNames {
+			if ctx.Err() != nil {
+				return
+			}
+
+			outage := storagePoolOutages[poolName]
+
+			// Pools that were never initialized on this member have no running instances to pause.
+			if outage == nil && !storagePools.IsAvailable(poolName) {
+				continue
+			}
+
+			pool, err := storagePools.LoadByName(s, poolName)
+			if err != nil {
+				logger.Error("Failed loading storage pool for health check", logger.Ctx{"pool": poolName, "err": err})
+				continue
+			}
+
+			config := pool.Driver().Config()
+			if config["unavailable.action"] != "pause" {
+				// Don't leave instances paused if the action was unset during an outage.
+				if outage != nil {
+					storagePoolResumeInstances(s, pool, outage)
+					delete(storagePoolOutages, poolName)
+				}
+
+				continue
+			}
+
+			checkErr := storagePoolHealthCheck(ctx, pool)
+			if checkErr == nil {
+				if outage != nil {
+					storagePoolResumeInstances(s, pool, outage)
+					delete(storagePoolOutages, poolName)
+				}
+
+				continue
+			}
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			if outage == nil {
+				storagePoolOutages[poolName] = storagePoolPauseInstances(s, pool, checkErr)
+				continue
+			}
+
+			// Convert long outages into a stop of the paused instances.
+			timeout, _ := strconv.Atoi(config["unavailable.timeout"])
+			if timeout > 0 && len(outage.instances) > 0 && time.Since(outage.since) > time.Duration(timeout)*time.Second {
+				storagePoolStopInstances(s, pool, outage, timeout)
+			}
+		}
+	}
+
+	return f, task.Every(10 * time.Second)
+}
+
+// storagePoolHealthCheck checks that the backing store of the pool can be reached.
+func storagePoolHealthCheck(ctx context.Context, pool storagePools.Pool) error {
+	ctx, cancel := context.WithTimeout(ctx, storagePoolHealthCheckTimeout)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := pool.GetResources()
+		errCh <- err
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("Timed out checking storage pool after %s", storagePoolHealthCheckTimeout)
+	}
+}
+
+// storagePoolUsedByInstance returns whether any of the disks of the instance are on the pool.
+func storagePoolUsedByInstance(poolName string, inst instance.Instance) bool {
+	for _, dev := range inst.ExpandedDevices() {
+		if dev["type"] == "disk" && dev["pool"] == poolName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// storagePoolInstanceURLs returns the URLs of the instances for use in lifecycle events.
+func storagePoolInstanceURLs(instances []instance.Instance) []string {
+	urls := make([]string, 0, len(instances))
+	for _, inst := range instances {
+		urls = append(urls, api.NewURL().Path(version.APIVersion, "instances", inst.Name()).Project(inst.Project().Name).String())
+	}
+
+	return urls
+}
+
+// storagePoolPauseInstances freezes the running instances on this member that use the unreachable pool.
+// Instances that were already frozen are left alone so that they aren't resumed once the pool recovers.
+func storagePoolPauseInstances(s *state.State, pool storagePools.Pool, checkErr error) *storagePoolOutage {
+	l := logger.AddContext(logger.Ctx{"pool": pool.Name()})
+	l.Warn("Storage pool is unreachable, pausing instances", logger.Ctx{"err": checkErr})
+
+	outage := &storagePoolOutage{since: time.Now()}
+
+	instances, err := instance.LoadNodeAll(s, instancetype.Any)
+	if err != nil {
+		l.Error("Failed loading instances to pause", logger.Ctx{"err": err})
+	}
+
+	for _, inst := range instances {
+		if !inst.IsRunning() || inst.IsFrozen() || !storagePoolUsedByInstance(pool.Name(), inst) {
+			continue
+		}
+
+		err := inst.Freeze()
+		if err != nil {
+			l.Error("Failed pausing instance", logger.Ctx{"project": inst.Project().Name, "instance": inst.Name(), "err": err})
+			continue
+		}
+
+		outage.instances = append(outage.instances, inst)
+	}
+
+	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		return tx.UpsertWarningLocalNode(ctx, "", entity.TypeStoragePool, int(pool.ID()), warningtype.StoragePoolUnvailable, fmt.Sprintf("Storage pool unreachable, paused %d instance(s): %v", len(outage.instances), checkErr))
+	})
+	if err != nil {
+		l.Warn("Failed to create storage pool unavailable warning", logger.Ctx{"err": err})
+	}
+
+	ctx := logger.Ctx{"target": s.ServerName, "instances": storagePoolInstanceURLs(outage.instances)}
+	s.Events.SendLifecycle(api.ProjectDefaultName, lifecycle.StoragePoolUnavailable.Event(pool.Name(), nil, ctx))
+
+	return outage
+}
+
+// storagePoolResumeInstances unfreezes the instances that were paused because of the outage of the pool.
+func storagePoolResumeInstances(s *state.State, pool storagePools.Pool, outage *storagePoolOutage) {
+	l := logger.AddContext(logger.Ctx{"pool": pool.Name()})
+	l.Info("Storage pool is reachable again, resuming instances", logger.Ctx{"since": outage.since})
+
+	resumed := make([]instance.Instance, 0, len(outage.instances))
+	for _, inst := range outage.instances {
+		// Skip instances that were stopped or resumed in the meantime.
+		if !inst.IsFrozen() {
+			continue
+		}
+
+		err := inst.Unfreeze()
+		if err != nil {
+			l.Error("Failed resuming instance", logger.Ctx{"project": inst.Project().Name, "instance": inst.Name(), "err": err})
+			continue
+		}
+
+		resumed = append(resumed, inst)
+	}
+
+	err := warnings.ResolveWarningsByLocalNodeAndProjectAndTypeAndEntity(s.DB.Cluster, "", warningtype.StoragePoolUnvailable, entity.TypeStoragePool, int(pool.ID()))
+	if err != nil {
+		l.Warn("Failed to resolve storage pool unavailable warning", logger.Ctx{"err": err})
+	}
+
+	ctx := logger.Ctx{"target": s.ServerName, "instances": storagePoolInstanceURLs(resumed)}
+	s.Events.SendLifecycle(api.ProjectDefaultName, lifecycle.StoragePoolAvailable.Event(pool.Name(), nil, ctx))
+}
+
+// storagePoolStopInstances stops the instances that stayed paused for longer than unavailable.timeout.
+func storagePoolStopInstances(s *state.State, pool storagePools.Pool, outage *storagePoolOutage, timeout int) {
+	l := logger.AddContext(logger.Ctx{"pool": pool.Name()})
+	l.Warn("Storage pool unreachable for too long, stopping paused instances", logger.Ctx{"since": outage.since})
+
+	stopped := 0
+	for _, inst := range outage.instances {
+		if !inst.IsRunning() {
+			continue
+		}
+
+		err := inst.Stop(false)
+		if err != nil {
+			l.Error("Failed stopping instance", logger.Ctx{"project": inst.Project().Name, "instance": inst.Name(), "err": err})
+			continue
+		}
+
+		stopped++
+	}
+
+	// Stopped instances are not started again when the pool recovers.
+	outage.instances = nil
+
+	err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		return tx.UpsertWarningLocalNode(ctx, "", entity.TypeStoragePool, int(pool.ID()), warningtype.StoragePoolUnvailable, fmt.Sprintf("Storage pool unreachable for more than %ds, stopped %d instance(s)", timeout, stopped))
+	})
+	if err != nil {
+		l.Warn("Failed to update storage pool unavailable warning", logger.Ctx{"err": err})
+	}
+}
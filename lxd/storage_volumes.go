@@ -8,6 +8,7 @@ import (
 	"encoding/pem"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
 	"os"
@@ -324,7 +325,7 @@ func storagePoolVolumesGet(d *Daemon, r *http.Request) response.Response {
 
 	var poolID int64
 
-	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
 		poolID, err = tx.GetStoragePoolID(ctx, poolName)
 
 		return err
@@ -546,9 +547,14 @@ func filterVolumes(volumes []*db.StorageVolume, clauses *filter.ClauseSet, allPr
 //	Creates a new storage volume.
 //	Will return an empty sync response on simple volume creation but an operation on copy or migration.
 //
+//	Filesystem volumes can be created with initial content, either from a tarball at the source URL
+//	(`url` source type) or from a tarball uploaded as the `content` part of a multipart request whose
+//	`request` part holds the volume definition (`upload` source type).
+//
 //	---
 //	consumes:
 //	  - application/json
+//	  - multipart/form-data
 //	produces:
 //	  - application/json
 //	parameters:
@@ -587,9 +593,14 @@ func filterVolumes(volumes []*db.StorageVolume, clauses *filter.ClauseSet, allPr
 //	Creates a new storage volume (type specific endpoint).
 //	Will return an empty sync response on simple volume creation but an operation on copy or migration.
 //
+//	Filesystem volumes can be created with initial content, either from a tarball at the source URL
+//	(`url` source type) or from a tarball uploaded as the `content` part of a multipart request whose
+//	`request` part holds the volume definition (`upload` source type).
+//
 //	---
 //	consumes:
 //	  - application/json
+//	  - multipart/form-data
 //	produces:
 //	  - application/json
 //	parameters:
@@ -649,10 +660,23 @@ func storagePoolVolumesPost(d *Daemon, r *http.Request) response.Response {
 
 	req := api.StorageVolumesPost{}
 
-	// Parse the request.
-	err = json.NewDecoder(r.Body).Decode(&req)
-	if err != nil {
-		return response.BadRequest(err)
+	// Parse the request, multipart requests also carry the initial content of the volume.
+	var content io.Reader
+	mediaType, ctypeParams, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		content, err = storagePoolVolumesPostMultipart(r, ctypeParams["boundary"], &req)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		if req.Source.Type == "" {
+			req.Source.Type = "upload"
+		}
+	} else {
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			return response.BadRequest(err)
+		}
 	}
 
 	// Quick checks.
@@ -745,6 +769,10 @@ func storagePoolVolumesPost(d *Daemon, r *http.Request) response.Response {
 		return clusterCopyCustomVolumeInternal(s, r, nodeAddress, projectName, poolName, &req)
 	}
 
+	if content != nil && req.Source.Type != "upload" {
+		return response.BadRequest(fmt.Errorf("Content can't be uploaded with the %q source type", req.Source.Type))
+	}
+
 	switch req.Source.Type {
 	case "":
 		return doVolumeCreateOrCopy(s, r, request.ProjectParam(r), projectName, poolName, &req)
@@ -756,6 +784,14 @@ func storagePoolVolumesPost(d *Daemon, r *http.Request) response.Response {
 		return doVolumeCreateOrCopy(s, r, request.ProjectParam(r), projectName, poolName, &req)
 	case "migration":
 		return doVolumeMigration(s, r, request.ProjectParam(r), projectName, poolName, &req)
+	case "upload":
+		if content == nil {
+			return response.BadRequest(fmt.Errorf("The %q source type requires a multipart request", req.Source.Type))
+		}
+
+		return createStoragePoolVolumeFromContent(s, r, request.ProjectParam(r), projectName, poolName, &req, content)
+	case "url":
+		return createStoragePoolVolumeFromContent(s, r, request.ProjectParam(r), projectName, poolName, &req, nil)
 	default:
 		return response.BadRequest(fmt.Errorf("Unknown source type %q", req.Source.Type))
 	}
@@ -1720,7 +1756,7 @@ func storagePoolVolumeGet(d *Daemon, r *http.Request) response.Response {
 
 	var dbVolume *db.StorageVolume
 
-	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
 		// Get the ID of the storage pool the storage volume is supposed to be attached to.
 		poolID, err := tx.GetStoragePoolID(ctx, poolName)
 		if err != nil {
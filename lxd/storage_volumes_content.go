@@ -0,0 +1,419 @@
+package main
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/canonical/lxd/lxd/archive"
+	"github.com/canonical/lxd/lxd/backup"
+	"github.com/canonical/lxd/lxd/db"
+	"github.com/canonical/lxd/lxd/db/cluster"
+	"github.com/canonical/lxd/lxd/db/operationtype"
+	"github.com/canonical/lxd/lxd/operations"
+	"github.com/canonical/lxd/lxd/project"
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/lxd/state"
+	storagePools "github.com/canonical/lxd/lxd/storage"
+	storageDrivers "github.com/canonical/lxd/lxd/storage/drivers"
+	"github.com/canonical/lxd/lxd/sys"
+	"github.com/canonical/lxd/lxd/util"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/cancel"
+	"github.com/canonical/lxd/shared/ioprogress"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/revert"
+	"github.com/canonical/lxd/shared/units"
+	"github.com/canonical/lxd/shared/version"
+)
+
+// storageVolumeContentDevices are the supported ways of handling device nodes found in an initial content tarball.
+var storageVolumeContentDevices = []string{"skip", "create", "reject"}
+
+// storagePoolVolumesPostMultipart parses a multipart storage volume creation request.
+// The "request" part holds the JSON encoded volume definition and the following "content" part holds the tarball to
+// unpack into the new volume. The returned reader is only valid until the request body is closed.
+func storagePoolVolumesPostMultipart(r *http.Request, boundary string, req *api.StorageVolumesPost) (io.Reader, error) {
+	mr := multipart.NewReader(r.Body, boundary)
+
+	part, err := mr.NextPart()
+	if err != nil {
+		return nil, err
+	}
+
+	if part.FormName() != "request" {
+		return nil, fmt.Errorf("Invalid multipart request, expected %q part but got %q", "request", part.FormName())
+	}
+
+	err = json.NewDecoder(part).Decode(req)
+	if err != nil {
+		return nil, err
+	}
+
+	part, err = mr.NextPart()
+	if err != nil {
+		return nil, err
+	}
+
+	if part.FormName() != "content" {
+		return nil, fmt.Errorf("Invalid multipart request, expected %q part but got %q", "content", part.FormName())
+	}
+
+	return part, nil
+}
+
+// createStoragePoolVolumeFromContent creates a custom filesystem volume and unpacks a tarball into it.
+// The tarball is either the uploaded data (for the "upload" source type) or is fetched from the source URL.
+func createStoragePoolVolumeFromContent(s *state.State, r *http.Request, requestProjectName string, projectName string, poolName string, req *api.StorageVolumesPost, data io.Reader) response.Response {
+	revert := revert.New()
+	defer revert.Fail()
+
+	if req.ContentType != cluster.StoragePoolVolumeContentTypeNameFS {
+		return response.BadRequest(fmt.Errorf("Initial content can only be provided for %q volumes", cluster.StoragePoolVolumeContentTypeNameFS))
+	}
+
+	if req.Source.Type == "url" {
+		u, err := url.Parse(req.Source.URL)
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Invalid source URL: %w", err))
+		}
+
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return response.BadRequest(fmt.Errorf("Unsupported source URL scheme %q", u.Scheme))
+		}
+	} else if req.Source.URL != "" {
+		return response.BadRequest(fmt.Errorf("A source URL can't be used with the %q source type", req.Source.Type))
+	}
+
+	if req.Source.Checksum != "" {
+		checksum, err := hex.DecodeString(req.Source.Checksum)
+		if err != nil || len(checksum) != sha256.Size {
+			return response.BadRequest(fmt.Errorf("Invalid SHA256 checksum %q", req.Source.Checksum))
+		}
+	}
+
+	if req.Source.Devices == "" {
+		req.Source.Devices = "skip"
+	}
+
+	if !shared.ValueInSlice(req.Source.Devices, storageVolumeContentDevices) {
+		return response.BadRequest(fmt.Errorf("Invalid device handling %q, must be one of %s", req.Source.Devices, strings.Join(storageVolumeContentDevices, ", ")))
+	}
+
+	if req.Source.Devices == "create" && s.OS.RunningInUserNS {
+		return response.BadRequest(fmt.Errorf("Device nodes can't be created when running in a user namespace"))
+	}
+
+	pool, err := storagePools.LoadByName(s, poolName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Create temporary file to store the tarball.
+	contentFile, err := os.CreateTemp(shared.VarPath("backups"), fmt.Sprintf("%s_content_", backup.WorkingDirPrefix))
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	defer func() { _ = os.Remove(contentFile.Name()) }()
+	revert.Add(func() { _ = contentFile.Close() })
+
+	// Stream uploaded content into temporary file, hashing it on the way.
+	contentHash := sha256.New()
+	if data != nil {
+		_, err = io.Copy(io.MultiWriter(contentFile, contentHash), data)
+		if err != nil {
+			return response.InternalError(err)
+		}
+	}
+
+	// Copy reverter so far so we can use it inside run after this function has finished.
+	runRevert := revert.Clone()
+
+	run := func(op *operations.Operation) error {
+		defer func() { _ = contentFile.Close() }()
+		defer runRevert.Fail()
+
+		setProgress := func(key string, value string) {
+			meta := op.Metadata()
+			if meta == nil {
+				meta = make(map[string]any)
+			}
+
+			if meta[key] != value {
+				meta[key] = value
+				_ = op.UpdateMetadata(meta)
+			}
+		}
+
+		if req.Source.Type == "url" {
+			err := storageVolumeContentDownload(s, op, req.Source.URL, contentFile, contentHash, func(progress ioprogress.ProgressData) {
+				setProgress("download_progress", progress.Text)
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		checksum := hex.EncodeToString(contentHash.Sum(nil))
+		if req.Source.Checksum != "" && !strings.EqualFold(checksum, req.Source.Checksum) {
+			return fmt.Errorf("Checksum mismatch for volume content: %s != %s", checksum, req.Source.Checksum)
+		}
+
+		setProgress("checksum", checksum)
+
+		// Check that the content can be safely unpacked and fits in the volume.
+		_, err := contentFile.Seek(0, io.SeekStart)
+		if err != nil {
+			return err
+		}
+
+		tarArgs, extension, unpacker, err := shared.DetectCompressionFile(contentFile)
+		if err != nil {
+			return err
+		}
+
+		if !strings.HasPrefix(extension, ".tar") {
+			return fmt.Errorf("Volume content must be a tarball, got %q", extension)
+		}
+
+		contentSize, devices, err := storageVolumeContentScan(s.OS, contentFile, unpacker, req.Source.Devices)
+		if err != nil {
+			return fmt.Errorf("Invalid volume content: %w", err)
+		}
+
+		volSize := req.Config["size"]
+		if volSize == "" {
+			volSize = pool.Driver().Config()["volume.size"]
+		}
+
+		if volSize != "" {
+			sizeBytes, err := units.ParseByteSizeString(volSize)
+			if err != nil {
+				return err
+			}
+
+			if sizeBytes > 0 && contentSize > sizeBytes {
+				return fmt.Errorf("Volume content (%s) doesn't fit in a volume of size %s", units.GetByteSizeString(contentSize, 2), units.GetByteSizeString(sizeBytes, 2))
+			}
+		}
+
+		// Without an explicit size, account for the content against the project limits.
+		if req.Config["size"] == "" {
+			projectReq := *req
+			projectReq.Config = make(map[string]string, len(req.Config)+1)
+			for k, v := range req.Config {
+				projectReq.Config[k] = v
+			}
+
+			projectReq.Config["size"] = fmt.Sprintf("%d", contentSize)
+
+			err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+				return project.AllowVolumeCreation(s.GlobalConfig, tx, projectName, projectReq)
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		err = pool.CreateCustomVolume(projectName, req.Name, req.Description, req.Config, storageDrivers.ContentTypeFS, op)
+		if err != nil {
+			return err
+		}
+
+		runRevert.Add(func() { _ = pool.DeleteCustomVolume(projectName, req.Name, op) })
+
+		_, err = pool.MountCustomVolume(projectName, req.Name, op)
+		if err != nil {
+			return err
+		}
+
+		defer func() { _, _ = pool.UnmountCustomVolume(projectName, req.Name, op) }()
+
+		mountPath := storageDrivers.GetVolumeMountPath(pool.Name(), storageDrivers.VolumeTypeCustom, project.StorageVolume(projectName, req.Name))
+
+		tracker := &ioprogress.ProgressTracker{
+			Handler: func(percent int64, speed int64) {
+				setProgress("unpack_progress", fmt.Sprintf("%d%% (%s/s)", percent, units.GetByteSizeString(speed, 2)))
+			},
+		}
+
+		err = storageVolumeContentUnpack(s.OS, contentFile, mountPath, tarArgs, unpacker, devices, req.Source.Xattrs, tracker)
+		if err != nil {
+			return fmt.Errorf("Failed unpacking volume content: %w", err)
+		}
+
+		runRevert.Success()
+		return nil
+	}
+
+	resources := map[string][]api.URL{}
+	resources["storage_volumes"] = []api.URL{*api.NewURL().Path(version.APIVersion, "storage-pools", poolName, "volumes", "custom", req.Name)}
+
+	op, err := operations.OperationCreate(s, requestProjectName, operations.OperationClassTask, operationtype.VolumeCreate, resources, nil, run, nil, nil, r)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	revert.Success()
+	return operations.OperationResponse(op)
+}
+
+// storageVolumeContentDownload fetches the volume content from the given URL into the target file.
+func storageVolumeContentDownload(s *state.State, op *operations.Operation, sourceURL string, target *os.File, hashFunc hash.Hash, progress func(progress ioprogress.ProgressData)) error {
+	httpClient, err := util.HTTPClient("", s.Proxy)
+	if err != nil {
+		return err
+	}
+
+	canceler := cancel.NewHTTPRequestCanceller()
+	op.SetCanceler(canceler)
+
+	logger.Debug("Downloading volume content", logger.Ctx{"url": sourceURL, "operation": op.ID()})
+
+	_, err = shared.DownloadFileHash(context.TODO(), httpClient, version.UserAgent, progress, canceler, "", sourceURL, "", nil, target)
+	if err != nil {
+		return err
+	}
+
+	_, err = target.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(hashFunc, target)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// storageVolumeContentScan walks through the tarball and checks that all of its entries stay within the directory
+// it is unpacked into. It returns the total size of the regular files and the names of the device nodes that
+// should be skipped during extraction.
+func storageVolumeContentScan(sysOS *sys.OS, f *os.File, unpacker []string, devices string) (int64, []string, error) {
+	tr, cancelFunc, err := archive.CompressedTarReader(context.Background(), f, unpacker, sysOS, f.Name())
+	if err != nil {
+		return -1, nil, err
+	}
+
+	defer cancelFunc()
+
+	var size int64
+	var skipped []string
+	symlinks := map[string]bool{}
+
+	isUnsafe := func(name string) bool {
+		name = path.Clean(name)
+		return path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../")
+	}
+
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return -1, nil, err
+		}
+
+		if isUnsafe(hdr.Name) {
+			return -1, nil, fmt.Errorf("Entry %q is outside of the volume", hdr.Name)
+		}
+
+		// Entries must not be written through a symlink from the same tarball.
+		name := path.Clean(hdr.Name)
+		for parent := path.Dir(name); parent != "."; parent = path.Dir(parent) {
+			if symlinks[parent] {
+				return -1, nil, fmt.Errorf("Entry %q is below symlink %q", hdr.Name, parent)
+			}
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeReg:
+			size += hdr.Size
+		case tar.TypeSymlink:
+			symlinks[name] = true
+		case tar.TypeLink:
+			if isUnsafe(hdr.Linkname) {
+				return -1, nil, fmt.Errorf("Hard link %q points outside of the volume", hdr.Name)
+			}
+
+		case tar.TypeChar, tar.TypeBlock:
+			switch devices {
+			case "reject":
+				return -1, nil, fmt.Errorf("Device node %q isn't allowed", hdr.Name)
+			case "skip":
+				skipped = append(skipped, hdr.Name)
+			}
+		}
+	}
+
+	return size, skipped, nil
+}
+
+// storageVolumeContentUnpack extracts the tarball into the mounted volume, leaving out the skipped entries.
+func storageVolumeContentUnpack(sysOS *sys.OS, f *os.File, mountPath string, tarArgs []string, unpacker []string, skipped []string, xattrs bool, tracker *ioprogress.ProgressTracker) error {
+	args := []string{"--restrict", "--force-local", "-C", mountPath, "--numeric-owner"}
+	if xattrs {
+		args = append(args, "--xattrs", "--xattrs-include=*")
+	} else {
+		args = append(args, "--no-xattrs")
+	}
+
+	if len(skipped) > 0 {
+		args = append(args, "--anchored", "--no-wildcards")
+		for _, name := range skipped {
+			args = append(args, "--exclude="+name)
+		}
+	}
+
+	args = append(args, tarArgs...)
+	args = append(args, "-")
+
+	_, err := f.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	fsinfo, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	tracker.Length = fsinfo.Size()
+
+	outputDir, err := os.OpenFile(mountPath, os.O_RDONLY, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening directory: %w", err)
+	}
+
+	defer func() { _ = outputDir.Close() }()
+
+	allowedCmds := []string{}
+	if len(unpacker) > 0 {
+		allowedCmds = append(allowedCmds, unpacker[0])
+	}
+
+	reader := &ioprogress.ProgressReader{
+		ReadCloser: io.NopCloser(f),
+		Tracker:    tracker,
+	}
+
+	return archive.ExtractWithFds("tar", args, allowedCmds, reader, sysOS, outputDir)
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/sys/unix"
+
+	"github.com/canonical/lxd/client"
+	"github.com/canonical/lxd/lxd/auth"
+	"github.com/canonical/lxd/lxd/cluster"
+	"github.com/canonical/lxd/lxd/db"
+	dbCluster "github.com/canonical/lxd/lxd/db/cluster"
+	"github.com/canonical/lxd/lxd/project"
+	"github.com/canonical/lxd/lxd/request"
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/lxd/state"
+	storagePools "github.com/canonical/lxd/lxd/storage"
+	storageDrivers "github.com/canonical/lxd/lxd/storage/drivers"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/entity"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/revert"
+)
+
+var storagePoolVolumeTypeSFTPCmd = APIEndpoint{
+	Path: "storage-pools/{poolName}/volumes/{type}/{volumeName}/sftp",
+
+	Get: APIEndpointAction{Handler: storagePoolVolumeTypeSFTPHandler, AccessHandler: storagePoolVolumeTypeSFTPAccessHandler, NoStaleReads: true},
+}
+
+// storagePoolVolumeTypeSFTPAccessHandler allows access to the volume SFTP endpoint if the caller has either read-write
+// or read-only SFTP access to the volume. The handler itself decides which of the two is granted.
+func storagePoolVolumeTypeSFTPAccessHandler(d *Daemon, r *http.Request) response.Response {
+	resp := allowPermission(entity.TypeStorageVolume, auth.EntitlementCanConnectSFTP, "poolName", "type", "volumeName")(d, r)
+	if resp == response.EmptySyncResponse {
+		return resp
+	}
+
+	return allowPermission(entity.TypeStorageVolume, auth.EntitlementCanConnectSFTPReadOnly, "poolName", "type", "volumeName")(d, r)
+}
+
+// swagger:operation GET /1.0/storage-pools/{poolName}/volumes/{type}/{volumeName}/sftp storage storage_pool_volume_type_sftp
+//
+//	Get the storage volume SFTP connection
+//
+//	Upgrades the request to an SFTP connection of the custom storage volume's filesystem.
+//	Callers which only have read-only SFTP access to the volume get a read-only connection.
+//
+//	---
+//	produces:
+//	  - application/json
+//	  - application/octet-stream
+//	parameters:
+//	  - in: query
+//	    name: project
+//	    description: Project name
+//	    type: string
+//	    example: default
+//	  - in: query
+//	    name: target
+//	    description: Cluster member name
+//	    type: string
+//	    example: lxd01
+//	responses:
+//	  "101":
+//	    description: Switching protocols to SFTP
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func storagePoolVolumeTypeSFTPHandler(d *Daemon, r *http.Request) response.Response {
+	s := d.State()
+
+	poolName, err := url.PathUnescape(mux.Vars(r)["poolName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	volumeTypeName, err := url.PathUnescape(mux.Vars(r)["type"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	volumeName, err := url.PathUnescape(mux.Vars(r)["volumeName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if shared.IsSnapshot(volumeName) {
+		return response.BadRequest(fmt.Errorf("Invalid volume name"))
+	}
+
+	// Convert the volume type name to our internal integer representation.
+	volumeType, err := storagePools.VolumeTypeNameToDBType(volumeTypeName)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Only custom volumes can be accessed over SFTP, instance volumes are reached through the instance.
+	if volumeType != dbCluster.StoragePoolVolumeTypeCustom {
+		return response.BadRequest(fmt.Errorf("SFTP access is only supported on custom storage volumes"))
+	}
+
+	if r.Header.Get("Upgrade") != "sftp" {
+		return response.SmartError(api.StatusErrorf(http.StatusBadRequest, "Missing or invalid upgrade header"))
+	}
+
+	requestProjectName := request.ProjectParam(r)
+	projectName, err := project.StorageVolumeProject(s.DB.Cluster, requestProjectName, volumeType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	resp := &sftpServeResponse{
+		req:    r,
+		logCtx: logger.Ctx{"project": projectName, "pool": poolName, "volume": volumeName},
+	}
+
+	// Connect to the cluster member where the volume lives if it isn't this one. The SFTP connection is then
+	// proxied from there, as upgraded connections cannot be forwarded.
+	var client lxd.InstanceServer
+	target := request.QueryParam(r, "target")
+	if target != "" {
+		address, err := cluster.ResolveTarget(r.Context(), s, target)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		if address != "" {
+			client, err = cluster.Connect(address, s.Endpoints.NetworkCert(), s.ServerCert(), r, false)
+			if err != nil {
+				return response.SmartError(err)
+			}
+		}
+	} else {
+		client, err = cluster.ConnectIfVolumeIsRemote(s, poolName, projectName, volumeName, volumeType, s.Endpoints.NetworkCert(), s.ServerCert(), r)
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
+	if client != nil {
+		resp.conn, err = client.UseProject(requestProjectName).GetStoragePoolVolumeFileSFTPConn(poolName, volumeTypeName, volumeName)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return resp
+	}
+
+	// Callers that cannot connect with read-write access were let through by the access handler because they
+	// have read-only access.
+	volumeURL := entity.StorageVolumeURL(requestProjectName, target, poolName, volumeTypeName, volumeName)
+	readOnly := s.Authorizer.CheckPermission(r.Context(), r, volumeURL, auth.EntitlementCanConnectSFTP) != nil
+
+	pool, err := storagePools.LoadByName(s, poolName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	resp.conn, resp.cleanup, err = storagePoolVolumeSFTPConn(s, pool, projectName, volumeName, readOnly)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	resp.logCtx["readOnly"] = readOnly
+
+	return resp
+}
+
+// storagePoolVolumeSFTPConn mounts the custom volume and spawns an SFTP server confined to its filesystem.
+// It returns a connection to the server along with a function that stops the server and unmounts the volume once
+// the connection has been closed. The volume mount is reference counted, so concurrent sessions on the same volume
+// keep it mounted until the last of them has ended.
+func storagePoolVolumeSFTPConn(s *state.State, pool storagePools.Pool, projectName string, volumeName string, readOnly bool) (net.Conn, func(), error) {
+	var dbVolume *db.StorageVolume
+	err := s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		var err error
+		dbVolume, err = tx.GetStoragePoolVolume(ctx, pool.ID(), projectName, dbCluster.StoragePoolVolumeTypeCustom, volumeName, true)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if dbVolume.ContentType != dbCluster.StoragePoolVolumeContentTypeNameFS {
+		return nil, nil, api.StatusErrorf(http.StatusBadRequest, "SFTP access is only supported on filesystem volumes")
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	_, err = pool.MountCustomVolume(projectName, volumeName, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed mounting storage volume %q: %w", volumeName, err)
+	}
+
+	revert.Add(func() { _, _ = pool.UnmountCustomVolume(projectName, volumeName, nil) })
+
+	mountPath := storageDrivers.GetVolumeMountPath(pool.Name(), storageDrivers.VolumeTypeCustom, project.StorageVolume(projectName, volumeName))
+
+	// The listener is only used for a single connection and lives in a private directory so that nobody else
+	// can connect to it.
+	socketDir, err := os.MkdirTemp("", "lxd_sftp_")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer func() { _ = os.RemoveAll(socketDir) }()
+
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: filepath.Join(socketDir, "sftp.sock"), Net: "unix"})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer func() { _ = listener.Close() }()
+
+	listenerFile, err := listener.File()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer func() { _ = listenerFile.Close() }()
+
+	rootfsFile, err := os.Open(mountPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer func() { _ = rootfsFile.Close() }()
+
+	// Spawn forkfile without a PID so that it chroots into the volume.
+	args := []string{s.OS.ExecPath, "forkfile", "--", "3", "4", "-1", "0"}
+	if readOnly {
+		args = append(args, "read-only")
+	}
+
+	var stderr bytes.Buffer
+	forkfile := &exec.Cmd{
+		Path:       s.OS.ExecPath,
+		Args:       args,
+		ExtraFiles: []*os.File{listenerFile, rootfsFile},
+		Stderr:     &stderr,
+	}
+
+	err = forkfile.Start()
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to run forkfile: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	revert.Add(func() {
+		_ = forkfile.Process.Kill()
+		_ = forkfile.Wait()
+	})
+
+	conn, err := net.DialUnix("unix", nil, listener.Addr().(*net.UnixAddr))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cleanup := func() {
+		// Ask forkfile to exit once the connection has gone and wait for it before unmounting the volume.
+		_ = forkfile.Process.Signal(unix.SIGINT)
+		_ = forkfile.Wait()
+
+		_, err := pool.UnmountCustomVolume(projectName, volumeName, nil)
+		if err != nil {
+			logger.Warn("Failed unmounting storage volume after SFTP session", logger.Ctx{"project": projectName, "pool": pool.Name(), "volume": volumeName, "err": err})
+		}
+	}
+
+	revert.Success()
+	return conn, cleanup, nil
+}
@@ -408,7 +408,7 @@ func storagePoolVolumeSnapshotsTypeGet(d *Daemon, r *http.Request) response.Resp
 	var poolID int64
 	var volumes []db.StorageVolumeArgs
 
-	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
 		var err error
 
 		// Retrieve ID of the storage pool (and check if the storage pool exists).
@@ -717,7 +717,7 @@ func storagePoolVolumeSnapshotTypeGet(d *Daemon, r *http.Request) response.Respo
 	var dbVolume *db.StorageVolume
 	var expiry time.Time
 
-	err = s.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+	err = s.DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
 		// Get the snapshot.
 		poolID, _, _, err = tx.GetStoragePool(ctx, poolName)
 		if err != nil {
@@ -1212,7 +1212,32 @@ func pruneExpiredAndAutoCreateCustomVolumeSnapshotsTask(d *Daemon) (task.Func, t
 				return fmt.Errorf("Failed getting volumes for auto custom volume snapshot task: %w", err)
 			}
 
+			expiredSnapshotIDs := make(map[int64]bool, len(allExpiredSnapshots))
+			for _, v := range allExpiredSnapshots {
+				expiredSnapshotIDs[v.ID] = true
+			}
+
 			for _, v := range allVolumes {
+				// Snapshots that the retention policy of the volume doesn't keep are expired too.
+				retentionExpiredSnapshots, err := snapshotRetentionPolicyExpiredVolumeSnapshots(ctx, tx, v)
+				if err != nil {
+					return fmt.Errorf("Failed evaluating snapshot retention policy of custom volume %q (project %q): %w", v.Name, v.ProjectName, err)
+				}
+
+				for _, snapshot := range retentionExpiredSnapshots {
+					if expiredSnapshotIDs[snapshot.ID] {
+						continue
+					}
+
+					expiredSnapshotIDs[snapshot.ID] = true
+					if snapshot.NodeID < 0 {
+						expiredRemoteSnapshots = append(expiredRemoteSnapshots, snapshot)
+					} else {
+						logger.Debug("Scheduling local custom volume snapshot expiry by retention policy", logger.Ctx{"volName": snapshot.Name, "project": snapshot.ProjectName, "pool": snapshot.PoolName})
+						expiredSnapshots = append(expiredSnapshots, snapshot)
+					}
+				}
+
 				err = project.AllowSnapshotCreation(projects[v.ProjectName])
 				if err != nil {
 					continue
@@ -18,6 +18,12 @@ func (s *OS) GlobalDatabaseDir() string {
 	return filepath.Join(s.VarDir, "database", "global")
 }
 
+// GlobalDatabaseReplicaDir returns the path of the directory holding the local copies of the global database used to
+// serve stale reads.
+func (s *OS) GlobalDatabaseReplicaDir() string {
+	return filepath.Join(s.VarDir, "database", "replica")
+}
+
 // GlobalDatabasePath returns the path of the global database SQLite file
 // managed by dqlite.
 func (s *OS) GlobalDatabasePath() string {
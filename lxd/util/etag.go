@@ -0,0 +1,424 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// etagPrefix is the prefix of the ETags computed from the canonical encoding of the data. ETags without it are in the
+// legacy format, which was the SHA256 of the JSON encoding of the data.
+const etagPrefix = "c1-"
+
+// etagWriter streams the canonical encoding of a value into a hash.
+//
+// The canonical encoding follows the JSON data model of the value, so that the same value gives the same encoding
+// whether it is a struct or the result of decoding its JSON into an interface, on any member. Object keys are sorted,
+// numbers are formatted canonically and struct fields are named and omitted as in their JSON encoding.
+type etagWriter struct {
+	h       hash.Hash
+	scratch []byte
+}
+
+// etagField is a field of a struct as it appears in the JSON encoding of the struct.
+type etagField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+// etagFieldsCache caches the sorted fields of the struct types that have been encoded.
+var etagFieldsCache sync.Map
+
+var (
+	etagJSONMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	etagTextMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	etagJSONNumberType    = reflect.TypeOf(json.Number(""))
+)
+
+// etagCanonicalHash returns the SHA256 of the canonical encoding of the data.
+func etagCanonicalHash(data any) (string, error) {
+	w := &etagWriter{h: sha256.New(), scratch: make([]byte, 0, 64)}
+	err := w.encode(reflect.ValueOf(data))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", w.h.Sum(nil)), nil
+}
+
+// etagLegacyHash returns the SHA256 of the JSON encoding of the data, as used for ETags before they were computed from
+// the canonical encoding.
+func etagLegacyHash(data any) (string, error) {
+	etag := sha256.New()
+	err := json.NewEncoder(etag).Encode(data)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", etag.Sum(nil)), nil
+}
+
+func (w *etagWriter) writeString(s string) {
+	_, _ = io.WriteString(w.h, s)
+}
+
+func (w *etagWriter) writeQuoted(s string) {
+	w.scratch = strconv.AppendQuote(w.scratch[:0], s)
+	_, _ = w.h.Write(w.scratch)
+}
+
+func (w *etagWriter) writeNumber(s string) {
+	w.writeString("#")
+	w.writeString(s)
+	w.writeString(";")
+}
+
+// etagFormatFloat formats a float so that integral values are formatted like integers.
+func etagFormatFloat(f float64) string {
+	if f == math.Trunc(f) && math.Abs(f) < 1e21 {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// etagFormatJSONNumber formats a decoded JSON number like the number it was encoded from.
+func etagFormatJSONNumber(n json.Number) (string, error) {
+	i, err := strconv.ParseInt(string(n), 10, 64)
+	if err == nil {
+		return strconv.FormatInt(i, 10), nil
+	}
+
+	f, err := strconv.ParseFloat(string(n), 64)
+	if err != nil {
+		return "", fmt.Errorf("Invalid number %q: %w", n, err)
+	}
+
+	return etagFormatFloat(f), nil
+}
+
+func (w *etagWriter) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		w.writeString("n")
+		return nil
+	}
+
+	// Values with a custom JSON encoding are encoded through their JSON.
+	if v.Type() != etagJSONNumberType && (v.Type().Implements(etagJSONMarshalerType) || v.Type().Implements(etagTextMarshalerType)) {
+		if (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && v.IsNil() {
+			w.writeString("n")
+			return nil
+		}
+
+		return w.encodeJSON(v.Interface())
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			w.writeString("n")
+			return nil
+		}
+
+		return w.encode(v.Elem())
+	case reflect.Bool:
+		if v.Bool() {
+			w.writeString("t")
+		} else {
+			w.writeString("f")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		w.writeNumber(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		w.writeNumber(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		w.writeNumber(etagFormatFloat(v.Float()))
+	case reflect.String:
+		if v.Type() == etagJSONNumberType {
+			n, err := etagFormatJSONNumber(json.Number(v.String()))
+			if err != nil {
+				return err
+			}
+
+			w.writeNumber(n)
+			return nil
+		}
+
+		w.writeQuoted(v.String())
+	case reflect.Slice:
+		if v.IsNil() {
+			w.writeString("n")
+			return nil
+		}
+
+		// Byte slices are encoded as base64 strings like in JSON.
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			w.writeQuoted(base64.StdEncoding.EncodeToString(v.Bytes()))
+			return nil
+		}
+
+		return w.encodeArray(v)
+	case reflect.Array:
+		return w.encodeArray(v)
+	case reflect.Map:
+		if v.IsNil() {
+			w.writeString("n")
+			return nil
+		}
+
+		return w.encodeMap(v)
+	case reflect.Struct:
+		return w.encodeStruct(v)
+	default:
+		return fmt.Errorf("Unsupported type %q", v.Type())
+	}
+
+	return nil
+}
+
+// encodeJSON encodes a value through its JSON encoding, so that its JSON objects are encoded canonically.
+func (w *etagWriter) encodeJSON(data any) error {
+	buf, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(string(buf)))
+	decoder.UseNumber()
+
+	var decoded any
+	err = decoder.Decode(&decoded)
+	if err != nil {
+		return err
+	}
+
+	return w.encode(reflect.ValueOf(decoded))
+}
+
+func (w *etagWriter) encodeArray(v reflect.Value) error {
+	w.writeString("[")
+	for i := 0; i < v.Len(); i++ {
+		if i > 0 {
+			w.writeString(",")
+		}
+
+		err := w.encode(v.Index(i))
+		if err != nil {
+			return err
+		}
+	}
+
+	w.writeString("]")
+	return nil
+}
+
+func (w *etagWriter) encodeMap(v reflect.Value) error {
+	keys := make([]string, 0, v.Len())
+	values := make(map[string]reflect.Value, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key, err := etagMapKey(iter.Key())
+		if err != nil {
+			return err
+		}
+
+		keys = append(keys, key)
+		values[key] = iter.Value()
+	}
+
+	sort.Strings(keys)
+
+	w.writeString("{")
+	for i, key := range keys {
+		if i > 0 {
+			w.writeString(",")
+		}
+
+		w.writeQuoted(key)
+		w.writeString(":")
+		err := w.encode(values[key])
+		if err != nil {
+			return err
+		}
+	}
+
+	w.writeString("}")
+	return nil
+}
+
+// etagMapKey returns the key of a map entry as it appears in the JSON encoding of the map.
+func etagMapKey(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+
+	if k.Type().Implements(etagTextMarshalerType) {
+		text, err := k.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return "", err
+		}
+
+		return string(text), nil
+	}
+
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+
+	return "", fmt.Errorf("Unsupported map key type %q", k.Type())
+}
+
+func (w *etagWriter) encodeStruct(v reflect.Value) error {
+	w.writeString("{")
+	first := true
+	for _, field := range etagStructFields(v.Type()) {
+		fieldValue, ok := etagFieldByIndex(v, field.index)
+		if !ok || (field.omitEmpty && etagIsEmpty(fieldValue)) {
+			continue
+		}
+
+		if !first {
+			w.writeString(",")
+		}
+
+		first = false
+		w.writeQuoted(field.name)
+		w.writeString(":")
+		err := w.encode(fieldValue)
+		if err != nil {
+			return err
+		}
+	}
+
+	w.writeString("}")
+	return nil
+}
+
+// etagFieldByIndex returns the field with the given index, or false if it is in an embedded struct pointer that is nil.
+func etagFieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, fieldIndex := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+
+			v = v.Elem()
+		}
+
+		v = v.Field(fieldIndex)
+	}
+
+	return v, true
+}
+
+// etagIsEmpty returns whether a value is omitted from the JSON encoding of its struct by the omitempty option.
+func etagIsEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+
+	return false
+}
+
+// etagStructFields returns the fields of a struct type as they appear in its JSON encoding, sorted by name. The fields
+// of embedded structs without a JSON name are promoted, unless a shallower field has the same name.
+func etagStructFields(t reflect.Type) []etagField {
+	cached, ok := etagFieldsCache.Load(t)
+	if ok {
+		return cached.([]etagField)
+	}
+
+	var fields []etagField
+	names := map[string]bool{}
+
+	type embedded struct {
+		t     reflect.Type
+		index []int
+	}
+
+	current := []embedded{{t: t}}
+	for len(current) > 0 {
+		var next []embedded
+		var levelFields []etagField
+		for _, e := range current {
+			for i := 0; i < e.t.NumField(); i++ {
+				sf := e.t.Field(i)
+				index := append(append([]int{}, e.index...), i)
+
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+
+				name, opts, _ := strings.Cut(tag, ",")
+
+				if sf.Anonymous && name == "" {
+					ft := sf.Type
+					if ft.Kind() == reflect.Pointer {
+						ft = ft.Elem()
+					}
+
+					if ft.Kind() == reflect.Struct {
+						next = append(next, embedded{t: ft, index: index})
+						continue
+					}
+				}
+
+				if !sf.IsExported() {
+					continue
+				}
+
+				if name == "" {
+					name = sf.Name
+				}
+
+				levelFields = append(levelFields, etagField{
+					name:      name,
+					index:     index,
+					omitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+				})
+			}
+		}
+
+		for _, field := range levelFields {
+			if names[field.name] {
+				continue
+			}
+
+			names[field.name] = true
+			fields = append(fields, field)
+		}
+
+		current = next
+	}
+
+	sort.Slice(fields, func(i, j int) bool { return fields[i].name < fields[j].name })
+
+	etagFieldsCache.Store(t, fields)
+	return fields
+}
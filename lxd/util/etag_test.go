@@ -0,0 +1,165 @@
+package util_test
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/lxd/lxd/util"
+	"github.com/canonical/lxd/shared/api"
+)
+
+// etagTestInstance returns an instance with a large expanded config whose maps are filled in the given key order.
+func etagTestInstance(keys []string) api.Instance {
+	inst := api.Instance{
+		Name:       "c1",
+		Status:     "Running",
+		StatusCode: api.Running,
+		CreatedAt:  time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC),
+		Location:   "member1",
+		Project:    "default",
+		Type:       "container",
+		ExpandedDevices: map[string]map[string]string{
+			"root": {"type": "disk", "path": "/", "pool": "default"},
+			"eth0": {"type": "nic", "network": "lxdbr0"},
+		},
+		InstancePut: api.InstancePut{
+			Architecture: "x86_64",
+			Config:       map[string]string{},
+			Devices:      map[string]map[string]string{},
+			Profiles:     []string{"default"},
+			Stateful:     true,
+		},
+	}
+
+	inst.ExpandedConfig = map[string]string{}
+	for _, key := range keys {
+		inst.ExpandedConfig[key] = strings.Repeat(key, 10)
+		inst.Config[key] = key
+	}
+
+	return inst
+}
+
+func TestEtagHashStableAcrossMembers(t *testing.T) {
+	var keys []string
+	for i := 0; i < 1000; i++ {
+		keys = append(keys, fmt.Sprintf("user.key%d", i))
+	}
+
+	reversed := make([]string, len(keys))
+	for i, key := range keys {
+		reversed[len(keys)-1-i] = key
+	}
+
+	// Two members building the same instance with their maps filled in a different order.
+	member1, err := util.EtagHash(etagTestInstance(keys))
+	require.NoError(t, err)
+
+	member2, err := util.EtagHash(etagTestInstance(reversed))
+	require.NoError(t, err)
+
+	assert.Equal(t, member1, member2)
+
+	// A member that received the instance from another member as JSON.
+	buf, err := json.Marshal(etagTestInstance(keys))
+	require.NoError(t, err)
+
+	var forwarded api.Instance
+	err = json.Unmarshal(buf, &forwarded)
+	require.NoError(t, err)
+
+	member3, err := util.EtagHash(forwarded)
+	require.NoError(t, err)
+
+	assert.Equal(t, member1, member3)
+
+	// A member that only decoded the JSON into generic values.
+	decoder := json.NewDecoder(strings.NewReader(string(buf)))
+	decoder.UseNumber()
+
+	var generic any
+	err = decoder.Decode(&generic)
+	require.NoError(t, err)
+
+	member4, err := util.EtagHash(generic)
+	require.NoError(t, err)
+
+	assert.Equal(t, member1, member4)
+
+	// A change gives a different ETag.
+	changed := etagTestInstance(keys)
+	changed.ExpandedConfig["user.key1"] = "changed"
+	other, err := util.EtagHash(changed)
+	require.NoError(t, err)
+
+	assert.NotEqual(t, member1, other)
+}
+
+func TestEtagHashValues(t *testing.T) {
+	cases := []struct {
+		name  string
+		a     any
+		b     any
+		equal bool
+	}{
+		{"Integer and integral float", []any{1, int64(100000000)}, []any{1.0, 1e8}, true},
+		{"Float and number", 0.5, json.Number("0.5"), true},
+		{"Omitted field and missing key", api.InstancePut{Profiles: []string{}}, map[string]any{"architecture": "", "config": nil, "devices": nil, "ephemeral": false, "profiles": []any{}, "stateful": false, "description": ""}, true},
+		{"Nil and empty slice", []string(nil), []string{}, false},
+		{"String and number", "1", 1, false},
+		{"Different order", []string{"a", "b"}, []string{"b", "a"}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a, err := util.EtagHash(c.a)
+			require.NoError(t, err)
+
+			b, err := util.EtagHash(c.b)
+			require.NoError(t, err)
+
+			if c.equal {
+				assert.Equal(t, a, b)
+			} else {
+				assert.NotEqual(t, a, b)
+			}
+		})
+	}
+}
+
+func TestEtagCheck(t *testing.T) {
+	data := []any{"description", map[string]string{"user.foo": "bar"}}
+
+	etag, err := util.EtagHash(data)
+	require.NoError(t, err)
+
+	// ETags in the legacy format are the SHA256 of the JSON encoding of the data.
+	buf, err := json.Marshal(data)
+	require.NoError(t, err)
+	legacy := fmt.Sprintf("%x", sha256.Sum256(append(buf, '\n')))
+
+	for _, match := range []string{"", "*", `"` + etag + `"`, `"` + legacy + `"`} {
+		r, err := http.NewRequest(http.MethodPut, "/", nil)
+		require.NoError(t, err)
+
+		r.Header.Set("If-Match", match)
+		assert.NoError(t, util.EtagCheck(r, data), match)
+	}
+
+	for _, match := range []string{`"c1-0000"`, `"0000"`} {
+		r, err := http.NewRequest(http.MethodPut, "/", nil)
+		require.NoError(t, err)
+
+		r.Header.Set("If-Match", match)
+		err = util.EtagCheck(r, data)
+		assert.True(t, api.StatusErrorCheck(err, http.StatusPreconditionFailed), match)
+	}
+}
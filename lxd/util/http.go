@@ -3,7 +3,6 @@ package util
 import (
 	"bytes"
 	"context"
-	"crypto/sha256"
 	"crypto/x509"
 	"encoding/json"
 	"encoding/pem"
@@ -63,28 +62,39 @@ func WriteJSON(w http.ResponseWriter, body any, debugLogger logger.Logger) error
 	return err
 }
 
-// EtagHash hashes the provided data and returns the sha256.
+// EtagHash hashes the provided data and returns the ETag. The hash is computed from a canonical encoding of the data
+// that doesn't depend on the order of map keys or on how the data was built, so that all members return the same ETag
+// for the same data.
 func EtagHash(data any) (string, error) {
-	etag := sha256.New()
-	err := json.NewEncoder(etag).Encode(data)
+	hash, err := etagCanonicalHash(data)
 	if err != nil {
 		return "", err
 	}
 
-	return fmt.Sprintf("%x", etag.Sum(nil)), nil
+	return etagPrefix + hash, nil
 }
 
 // EtagCheck validates the hash of the current state with the hash
-// provided by the client.
+// provided by the client. A wildcard If-Match header matches any state, as the
+// caller has already loaded the current state of the resource.
+// ETags in the legacy format are still accepted so that clients holding one
+// don't fail during upgrades.
 func EtagCheck(r *http.Request, data any) error {
 	match := r.Header.Get("If-Match")
-	if match == "" {
+	if match == "" || match == "*" {
 		return nil
 	}
 
 	match = strings.Trim(match, "\"")
 
-	hash, err := EtagHash(data)
+	var hash string
+	var err error
+	if strings.HasPrefix(match, etagPrefix) {
+		hash, err = EtagHash(data)
+	} else {
+		hash, err = etagLegacyHash(data)
+	}
+
 	if err != nil {
 		return err
 	}
@@ -338,3 +348,36 @@ func IsJSONRequest(r *http.Request) bool {
 
 	return false
 }
+
+// ProxyClientAddress returns the address of the client of a request relayed by a trusted proxy, as reported in the
+// X-Forwarded-For or X-Real-IP header. The remote address of the request is returned unchanged if it doesn't belong
+// to a trusted proxy or if neither header holds a valid address, so that untrusted clients can't spoof it.
+// As proxies only report the IP address of the client, the returned address uses port 0.
+func ProxyClientAddress(r *http.Request, proxies []*net.IPNet) string {
+	if len(proxies) == 0 || !IsTrustedProxy(r.RemoteAddr, proxies) {
+		return r.RemoteAddr
+	}
+
+	// Each proxy appends the address it received the request from, so the client is the rightmost address that
+	// doesn't belong to a trusted proxy.
+	forwardedFor := shared.SplitNTrimSpace(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",", -1, true)
+	for i := len(forwardedFor) - 1; i >= 0; i-- {
+		ip := net.ParseIP(forwardedFor[i])
+		if ip == nil {
+			break
+		}
+
+		if i > 0 && IsTrustedProxy(ip.String(), proxies) {
+			continue
+		}
+
+		return net.JoinHostPort(ip.String(), "0")
+	}
+
+	ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP")))
+	if ip != nil {
+		return net.JoinHostPort(ip.String(), "0")
+	}
+
+	return r.RemoteAddr
+}
@@ -2,6 +2,7 @@ package util
 
 import (
 	"fmt"
+	"net/http"
 )
 
 func ExampleListenAddresses() {
@@ -38,3 +39,45 @@ func ExampleListenAddresses() {
 	// "foo:8000:9000": [] address foo:8000:9000: too many colons in address
 	// ":::8000": [] address :::8000: too many colons in address
 }
+
+func ExampleProxyClientAddress() {
+	proxies := ParseTrustedProxies("10.0.0.1, 192.0.2.0/24, 2001:db8::/64")
+
+	requests := []struct {
+		remoteAddr string
+		headers    map[string]string
+	}{
+		{"198.51.100.1:1234", map[string]string{"X-Forwarded-For": "203.0.113.1"}}, // Untrusted source.
+		{"10.0.0.1:1234", nil}, // No header.
+		{"10.0.0.1:1234", map[string]string{"X-Forwarded-For": "203.0.113.1"}},                     // Trusted IP address.
+		{"192.0.2.10:1234", map[string]string{"X-Forwarded-For": "203.0.113.1"}},                   // Trusted subnet.
+		{"[2001:db8::1]:1234", map[string]string{"X-Forwarded-For": "2001:db8:1::1"}},              // Trusted IPv6 subnet.
+		{"10.0.0.1:1234", map[string]string{"X-Forwarded-For": "203.0.113.1, 198.51.100.2"}},       // Spoofed first hop.
+		{"10.0.0.1:1234", map[string]string{"X-Forwarded-For": "203.0.113.1, 192.0.2.20"}},         // Chained proxies.
+		{"10.0.0.1:1234", map[string]string{"X-Real-IP": "203.0.113.1"}},                           // Real IP header.
+		{"10.0.0.1:1234", map[string]string{"X-Forwarded-For": "foo", "X-Real-IP": "203.0.113.1"}}, // Invalid forwarded address.
+		{"10.0.0.1:1234", map[string]string{"X-Forwarded-For": "foo"}},                             // Invalid header.
+		{"@", map[string]string{"X-Forwarded-For": "203.0.113.1"}},                                 // Unix socket.
+	}
+
+	for _, req := range requests {
+		r := &http.Request{RemoteAddr: req.remoteAddr, Header: http.Header{}}
+		for k, v := range req.headers {
+			r.Header.Set(k, v)
+		}
+
+		fmt.Println(ProxyClientAddress(r, proxies))
+	}
+
+	// Output: 198.51.100.1:1234
+	// 10.0.0.1:1234
+	// 203.0.113.1:0
+	// 203.0.113.1:0
+	// [2001:db8:1::1]:0
+	// 198.51.100.2:0
+	// 203.0.113.1:0
+	// 203.0.113.1:0
+	// 203.0.113.1:0
+	// 10.0.0.1:1234
+	// @
+}
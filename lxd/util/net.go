@@ -306,3 +306,52 @@ func SysctlSet(parts ...string) error {
 
 	return nil
 }
+
+// ParseTrustedProxies parses a comma-separated list of IP addresses and subnets in CIDR notation of trusted
+// proxies. IP addresses are returned as single address subnets. Invalid entries are skipped.
+func ParseTrustedProxies(value string) []*net.IPNet {
+	var proxies []*net.IPNet
+	for _, p := range shared.SplitNTrimSpace(value, ",", -1, true) {
+		_, subnet, err := net.ParseCIDR(p)
+		if err == nil {
+			proxies = append(proxies, subnet)
+			continue
+		}
+
+		ip := net.ParseIP(p)
+		if ip == nil {
+			continue
+		}
+
+		bits := 8 * net.IPv6len
+		if ip.To4() != nil {
+			ip = ip.To4()
+			bits = 8 * net.IPv4len
+		}
+
+		proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+
+	return proxies
+}
+
+// IsTrustedProxy returns whether the given address, with or without a port, belongs to one of the trusted proxies.
+func IsTrustedProxy(address string, proxies []*net.IPNet) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, proxy := range proxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strconv"
 	"time"
 
@@ -23,6 +24,8 @@ import (
 	"github.com/canonical/lxd/lxd/response"
 	"github.com/canonical/lxd/lxd/state"
 	"github.com/canonical/lxd/lxd/task"
+	"github.com/canonical/lxd/lxd/util"
+	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/entity"
 	"github.com/canonical/lxd/shared/filter"
@@ -36,6 +39,20 @@ var warningsCmd = APIEndpoint{
 	Get: APIEndpointAction{Handler: warningsGet, AccessHandler: allowPermission(entity.TypeServer, auth.EntitlementCanEdit)},
 }
 
+var warningSuppressionsCmd = APIEndpoint{
+	Path: "warnings/suppressions",
+
+	Get:  APIEndpointAction{Handler: warningSuppressionsGet, AccessHandler: allowPermission(entity.TypeServer, auth.EntitlementCanManageWarningSuppressions)},
+	Post: APIEndpointAction{Handler: warningSuppressionsPost, AccessHandler: allowPermission(entity.TypeServer, auth.EntitlementCanManageWarningSuppressions)},
+}
+
+var warningSuppressionCmd = APIEndpoint{
+	Path: "warnings/suppressions/{id}",
+
+	Get:    APIEndpointAction{Handler: warningSuppressionGet, AccessHandler: allowPermission(entity.TypeServer, auth.EntitlementCanManageWarningSuppressions)},
+	Delete: APIEndpointAction{Handler: warningSuppressionDelete, AccessHandler: allowPermission(entity.TypeServer, auth.EntitlementCanManageWarningSuppressions)},
+}
+
 var warningCmd = APIEndpoint{
 	Path: "warnings/{id}",
 
@@ -79,6 +96,11 @@ func filterWarnings(warnings []api.Warning, clauses *filter.ClauseSet) ([]api.Wa
 //      description: Project name
 //      type: string
 //      example: default
+//    - in: query
+//      name: include-suppressed
+//      description: Whether to include the warnings that were suppressed by a suppression rule
+//      type: boolean
+//      example: true
 //  responses:
 //    "200":
 //      description: Sync response
@@ -126,6 +148,11 @@ func filterWarnings(warnings []api.Warning, clauses *filter.ClauseSet) ([]api.Wa
 //	    description: Project name
 //	    type: string
 //	    example: default
+//	  - in: query
+//	    name: include-suppressed
+//	    description: Whether to include the warnings that were suppressed by a suppression rule
+//	    type: boolean
+//	    example: true
 //	responses:
 //	  "200":
 //	    description: API endpoints
@@ -171,8 +198,11 @@ func warningsGet(d *Daemon, r *http.Request) response.Response {
 	// Parse the project field
 	projectName := request.QueryParam(r, "project")
 
+	// Suppressed warnings are only listed when requested, for example to audit what has been suppressed.
+	includeSuppressed := shared.IsTrue(request.QueryParam(r, "include-suppressed"))
+
 	var warnings []api.Warning
-	err = d.State().DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+	err = d.State().DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
 		filters := []cluster.WarningFilter{}
 		if projectName != "" {
 			filter := cluster.WarningFilter{Project: &projectName}
@@ -184,15 +214,19 @@ func warningsGet(d *Daemon, r *http.Request) response.Response {
 			return fmt.Errorf("Failed to get warnings: %w", err)
 		}
 
-		warnings = make([]api.Warning, len(dbWarnings))
-		for i, w := range dbWarnings {
+		warnings = make([]api.Warning, 0, len(dbWarnings))
+		for _, w := range dbWarnings {
+			if w.Suppressed && !includeSuppressed {
+				continue
+			}
+
 			warning := w.ToAPI()
 			warning.EntityURL, err = getWarningEntityURL(ctx, tx.Tx(), &w)
 			if err != nil {
 				return err
 			}
 
-			warnings[i] = warning
+			warnings = append(warnings, warning)
 		}
 
 		return nil
@@ -270,7 +304,7 @@ func warningGet(d *Daemon, r *http.Request) response.Response {
 	}
 
 	var resp api.Warning
-	err = d.State().DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+	err = d.State().DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
 		dbWarning, err := cluster.GetWarning(ctx, tx.Tx(), id)
 		if err != nil {
 			return err
@@ -436,6 +470,361 @@ func warningDelete(d *Daemon, r *http.Request) response.Response {
 	return response.EmptySyncResponse
 }
 
+// swagger:operation GET /1.0/warnings/suppressions warnings warning_suppressions_get
+//
+//	Get the warning suppression rules
+//
+//	Returns a list of warning suppression rules (URLs).
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    description: API endpoints
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of endpoints
+//	          items:
+//	            type: string
+//	          example: |-
+//	            [
+//	              "/1.0/warnings/suppressions/1",
+//	              "/1.0/warnings/suppressions/2"
+//	            ]
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+
+// swagger:operation GET /1.0/warnings/suppressions?recursion=1 warnings warning_suppressions_get_recursion1
+//
+//	Get the warning suppression rules
+//
+//	Returns a list of warning suppression rules (structs), including the rules that have expired.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    description: API endpoints
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of warning suppression rules
+//	          items:
+//	            $ref: "#/definitions/WarningSuppression"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func warningSuppressionsGet(d *Daemon, r *http.Request) response.Response {
+	recursion := util.IsRecursionRequest(r)
+
+	var apiSuppressions []api.WarningSuppression
+	err := d.State().DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		suppressions, err := tx.GetWarningSuppressions(ctx)
+		if err != nil {
+			return err
+		}
+
+		apiSuppressions = make([]api.WarningSuppression, 0, len(suppressions))
+		for _, suppression := range suppressions {
+			apiSuppression, err := warningSuppressionToAPI(ctx, tx.Tx(), suppression)
+			if err != nil {
+				// Rules of deleted entities no longer match any warnings.
+				if api.StatusErrorCheck(err, http.StatusNotFound) {
+					continue
+				}
+
+				return err
+			}
+
+			apiSuppressions = append(apiSuppressions, *apiSuppression)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !recursion {
+		urls := make([]string, 0, len(apiSuppressions))
+		for _, suppression := range apiSuppressions {
+			urls = append(urls, api.NewURL().Path(version.APIVersion, "warnings", "suppressions", strconv.Itoa(suppression.ID)).String())
+		}
+
+		return response.SyncResponse(true, urls)
+	}
+
+	return response.SyncResponse(true, apiSuppressions)
+}
+
+// swagger:operation POST /1.0/warnings/suppressions warnings warning_suppressions_post
+//
+//	Add a warning suppression rule
+//
+//	Creates a rule matching warnings on their type, entity and/or message. Warnings that match an active rule when
+//	they are created are acknowledged and tagged as suppressed. Suppressed warnings are only listed when requested.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: body
+//	    name: suppression
+//	    description: Warning suppression rule
+//	    required: true
+//	    schema:
+//	      $ref: "#/definitions/WarningSuppressionsPost"
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func warningSuppressionsPost(d *Daemon, r *http.Request) response.Response {
+	req := api.WarningSuppressionsPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if req.Type == "" && req.EntityURL == "" && req.MessagePattern == "" {
+		return response.BadRequest(fmt.Errorf("A warning suppression must match on at least one of the warning type, entity or message"))
+	}
+
+	suppression := db.WarningSuppression{
+		MessagePattern: req.MessagePattern,
+		Description:    req.Description,
+		ExpiresAt:      req.ExpiresAt,
+	}
+
+	if req.Type != "" {
+		for typeCode, typeName := range warningtype.TypeNames {
+			if typeName == req.Type {
+				suppression.TypeCode = &typeCode
+				break
+			}
+		}
+
+		if suppression.TypeCode == nil {
+			return response.BadRequest(fmt.Errorf("Unknown warning type %q", req.Type))
+		}
+	}
+
+	if req.MessagePattern != "" {
+		_, err = regexp.Compile(req.MessagePattern)
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Invalid message pattern: %w", err))
+		}
+	}
+
+	if suppression.Expired() {
+		return response.BadRequest(fmt.Errorf("The expiry of a warning suppression must be in the future"))
+	}
+
+	var entityURL *api.URL
+	if req.EntityURL != "" {
+		u, err := url.Parse(req.EntityURL)
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Failed to parse entity URL: %w", err))
+		}
+
+		entityURL = &api.URL{URL: *u}
+	}
+
+	var suppressionID int64
+	err = d.State().DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		if entityURL != nil {
+			entityRefs := map[*api.URL]*cluster.EntityRef{entityURL: {}}
+			err := cluster.PopulateEntityReferencesFromURLs(ctx, tx.Tx(), entityRefs)
+			if err != nil {
+				return api.StatusErrorf(http.StatusBadRequest, "Failed to resolve entity URL: %v", err)
+			}
+
+			suppression.EntityType = entityRefs[entityURL].EntityType
+			suppression.EntityID = entityRefs[entityURL].EntityID
+		}
+
+		suppressionID, err = tx.CreateWarningSuppression(ctx, suppression)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseLocation(true, nil, api.NewURL().Path(version.APIVersion, "warnings", "suppressions", strconv.FormatInt(suppressionID, 10)).String())
+}
+
+// swagger:operation GET /1.0/warnings/suppressions/{id} warnings warning_suppression_get
+//
+//	Get the warning suppression rule
+//
+//	Gets a specific warning suppression rule.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    description: Warning suppression rule
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          type: string
+//	          description: Response type
+//	          example: sync
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          $ref: "#/definitions/WarningSuppression"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func warningSuppressionGet(d *Daemon, r *http.Request) response.Response {
+	suppressionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return response.BadRequest(fmt.Errorf("Invalid warning suppression ID: %w", err))
+	}
+
+	var apiSuppression *api.WarningSuppression
+	err = d.State().DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		suppression, err := tx.GetWarningSuppression(ctx, suppressionID)
+		if err != nil {
+			return err
+		}
+
+		apiSuppression, err = warningSuppressionToAPI(ctx, tx.Tx(), *suppression)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, apiSuppression)
+}
+
+// swagger:operation DELETE /1.0/warnings/suppressions/{id} warnings warning_suppression_delete
+//
+//	Delete the warning suppression rule
+//
+//	Removes the warning suppression rule. Warnings that were suppressed by the rule stay suppressed.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "403":
+//	    $ref: "#/responses/Forbidden"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func warningSuppressionDelete(d *Daemon, r *http.Request) response.Response {
+	suppressionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return response.BadRequest(fmt.Errorf("Invalid warning suppression ID: %w", err))
+	}
+
+	err = d.State().DB.Cluster.Transaction(r.Context(), func(ctx context.Context, tx *db.ClusterTx) error {
+		return tx.DeleteWarningSuppression(ctx, suppressionID)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// warningSuppressionToAPI converts the given warning suppression rule to its API representation. It returns a not
+// found error if the entity of the rule has been deleted.
+func warningSuppressionToAPI(ctx context.Context, tx *sql.Tx, suppression db.WarningSuppression) (*api.WarningSuppression, error) {
+	apiSuppression := api.WarningSuppression{
+		WarningSuppressionsPost: api.WarningSuppressionsPost{
+			MessagePattern: suppression.MessagePattern,
+			Description:    suppression.Description,
+			ExpiresAt:      suppression.ExpiresAt,
+		},
+		ID:        suppression.ID,
+		CreatedAt: suppression.CreatedAt,
+	}
+
+	if suppression.TypeCode != nil {
+		apiSuppression.Type = warningtype.TypeNames[*suppression.TypeCode]
+	}
+
+	if suppression.EntityType != "" {
+		u, err := cluster.GetEntityURL(ctx, tx, entity.Type(suppression.EntityType), suppression.EntityID)
+		if err != nil {
+			return nil, err
+		}
+
+		apiSuppression.EntityURL = u.String()
+	}
+
+	return &apiSuppression, nil
+}
+
 func pruneResolvedWarningsTask(d *Daemon) (task.Func, task.Schedule) {
 	f := func(ctx context.Context) {
 		s := d.State()
@@ -1,5 +1,10 @@
 package api
 
+import (
+	"encoding/json"
+	"time"
+)
+
 const (
 	// AuthenticationMethodTLS is the default authentication method for interacting with LXD remotely.
 	AuthenticationMethodTLS = "tls"
@@ -48,6 +53,18 @@ type Identity struct {
 	// of the certificate if authenticated with TLS.
 	// Example: Jane Doe
 	Name string `json:"name" yaml:"name"`
+
+	// DisabledAt is when the identity was disabled. It is only set for disabled identities.
+	// Example: 2026-10-15T12:00:00Z
+	//
+	// API extension: identity_enabled.
+	DisabledAt *time.Time `json:"disabled_at,omitempty" yaml:"disabled_at,omitempty"`
+
+	// DisabledBy is the username of the requestor that disabled the identity. It is only set for disabled identities.
+	// Example: jane.doe@example.com
+	//
+	// API extension: identity_enabled.
+	DisabledBy string `json:"disabled_by,omitempty" yaml:"disabled_by,omitempty"`
 }
 
 // IdentityInfo expands an Identity to include group membership.
@@ -58,6 +75,32 @@ type Identity struct {
 type IdentityInfo struct {
 	IdentityPut `yaml:",inline"`
 	Identity    `yaml:",inline"`
+
+	// EffectiveGroups are the groups that the identity is a member of, either directly or through the mappings of
+	// the identity provider groups that the identity last authenticated with.
+	//
+	// API extension: identity_effective_groups.
+	EffectiveGroups []IdentityEffectiveGroup `json:"effective_groups" yaml:"effective_groups"`
+}
+
+// IdentityEffectiveGroup is a group that an identity is a member of and the sources of its membership.
+//
+// swagger:model
+//
+// API extension: identity_effective_groups.
+type IdentityEffectiveGroup struct {
+	// Group is the name of the group.
+	// Example: operators
+	Group string `json:"group" yaml:"group"`
+
+	// Direct is true if the identity is a member of the group itself. Only direct memberships are kept when the
+	// identity next authenticates.
+	// Example: true
+	Direct bool `json:"direct" yaml:"direct"`
+
+	// IdentityProviderGroups are the identity provider groups of the identity whose mapping includes the group.
+	// Example: ["sales"]
+	IdentityProviderGroups []string `json:"identity_provider_groups" yaml:"identity_provider_groups"`
 }
 
 // IdentityPut contains the editable fields of an IdentityInfo.
@@ -69,6 +112,13 @@ type IdentityPut struct {
 	// Groups is the list of groups for which the identity is a member.
 	// Example: ["foo", "bar"]
 	Groups []string `json:"groups" yaml:"groups"`
+
+	// Enabled is whether the identity can authenticate. A disabled identity keeps its group memberships and can be
+	// enabled again. If unset, the enabled state of the identity is left unchanged.
+	// Example: true
+	//
+	// API extension: identity_enabled.
+	Enabled *bool `json:"enabled,omitempty" yaml:"enabled,omitempty"`
 }
 
 // AuthGroup is the type for a LXD group.
@@ -86,6 +136,82 @@ type AuthGroup struct {
 	// includes this group.
 	// Example: ["sales", "operations"]
 	IdentityProviderGroups []string `json:"identity_provider_groups" yaml:"identity_provider_groups"`
+
+	// LastUsedAt is the last time that a permission of the group granted access to a request.
+	// It is updated at most once per minute.
+	// Example: 2024-03-23T20:00:00-04:00
+	//
+	// API extension: auth_group_last_used.
+	LastUsedAt time.Time `json:"last_used_at" yaml:"last_used_at"`
+
+	// InheritedPermissions are the permissions that the group inherits from its parents and their ancestors.
+	//
+	// API extension: auth_group_parents.
+	InheritedPermissions []AuthGroupInheritedPermission `json:"inherited_permissions" yaml:"inherited_permissions"`
+}
+
+// AuthGroupSummary is a summary of a LXD group, as returned by the group listing in summary mode.
+//
+// swagger:model
+//
+// API extension: auth_groups_summary.
+type AuthGroupSummary struct {
+	// Name is the name of the group.
+	// Example: default-c1-viewers
+	Name string `json:"name" yaml:"name"`
+
+	// Description is a short description of the group.
+	// Example: Viewers of instance c1 in the default project.
+	Description string `json:"description" yaml:"description"`
+
+	// PermissionCount is the number of permissions of the group, excluding inherited permissions and the
+	// permissions of its roles.
+	// Example: 2
+	PermissionCount int `json:"permission_count" yaml:"permission_count"`
+
+	// Scope is the broadest scope of the permissions of the group. It is "server" if the group has a permission on
+	// the server, otherwise "project" if it has a permission on a project, otherwise "entity" if it has any
+	// permission, and "none" otherwise.
+	// Example: project
+	Scope string `json:"scope" yaml:"scope"`
+}
+
+// AuthGroupInheritedPermission is a permission that a group inherits from one of its ancestors.
+//
+// swagger:model
+//
+// API extension: auth_group_parents.
+type AuthGroupInheritedPermission struct {
+	Permission `yaml:",inline"`
+
+	// Group is the name of the ancestor group that the permission is inherited from.
+	// Example: viewers
+	Group string `json:"group" yaml:"group"`
+}
+
+// AuthGroupsDeleted lists the groups deleted by a bulk deletion of groups matching a filter.
+//
+// swagger:model
+//
+// API extension: auth_groups_bulk_delete.
+type AuthGroupsDeleted struct {
+	// Names of the groups that were deleted (or that would be deleted in a dry run)
+	// Example: ["project-foo-operators", "project-foo-viewers"]
+	Deleted []string `json:"deleted" yaml:"deleted"`
+
+	// Names of the groups that matched the filter but that the caller isn't allowed to delete
+	// Example: ["project-foo-admins"]
+	Skipped []string `json:"skipped" yaml:"skipped"`
+}
+
+// AuthGroupsImport is used for creating many groups at once.
+//
+// swagger:model
+//
+// API extension: auth_groups_import.
+type AuthGroupsImport struct {
+	// Groups to create, in order. The parents of a group must exist or come before it.
+	Groups []AuthGroupsPost `json:"groups" yaml:"groups"`
 }
 
 // AuthGroupsPost is used for creating a new group.
@@ -121,6 +247,226 @@ type AuthGroupPut struct {
 
 	// Permissions are a list of permissions.
 	Permissions []Permission `json:"permissions" yaml:"permissions"`
+
+	// PermissionsBase is the list of permissions that the client based its update on.
+	// If set on a PUT or PATCH request, the update is rejected with a conflict if the current permissions of the group differ.
+	// An empty list is a base of no permissions, whereas a nil list (sent as null) skips the check.
+	//
+	// API extension: auth_group_permissions_base.
+	PermissionsBase []Permission `json:"permissions_base" yaml:"permissions_base,omitempty"`
+
+	// Parents are the names of the groups whose permissions are inherited by the group.
+	// Permissions are inherited transitively, so the group also inherits the permissions of the parents of its parents.
+	// Example: ["viewers"]
+	//
+	// API extension: auth_group_parents.
+	Parents []string `json:"parents" yaml:"parents"`
+
+	// Roles are the roles granted to the group. Each role grants all of its entitlements on the referenced entity.
+	//
+	// API extension: auth_roles.
+	Roles []AuthGroupRole `json:"roles" yaml:"roles"`
+
+	// Enabled is whether the permissions of the group are granted to its members. A disabled group keeps its
+	// permissions and members, and can be enabled again. If unset, new groups are enabled and the enabled state of
+	// existing groups is left unchanged.
+	// Example: true
+	//
+	// API extension: auth_group_enabled.
+	Enabled *bool `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+
+	// Template is whether the group is only meant to be copied. Template groups can't have members and their
+	// permissions aren't granted, including to their child groups. If unset, new groups aren't templates and the
+	// template state of existing groups is left unchanged.
+	// Example: false
+	//
+	// API extension: auth_group_template.
+	Template *bool `json:"template,omitempty" yaml:"template,omitempty"`
+}
+
+// AuthGroupRole is a role that is granted to a group on a specific entity.
+//
+// swagger:model
+//
+// API extension: auth_roles.
+type AuthGroupRole struct {
+	// Role is the name of the role.
+	// Example: project-operator
+	Role string `json:"role" yaml:"role"`
+
+	// EntityReference is the URL of the entity that the role is granted on.
+	// The entity must have the entity type of the role.
+	// Example: /1.0/projects/foo
+	EntityReference string `json:"url" yaml:"url"`
+}
+
+// AuthRole is a named bundle of entitlements on entities of a single entity type.
+//
+// swagger:model
+//
+// API extension: auth_roles.
+type AuthRole struct {
+	AuthRolesPost `yaml:",inline"`
+
+	// UsedBy is a list of the groups that the role is granted to.
+	// Example: ["/1.0/auth/groups/foo-operators"]
+	UsedBy []string `json:"used_by" yaml:"used_by"`
+}
+
+// AuthRolesPost is used for creating a new role.
+//
+// swagger:model
+//
+// API extension: auth_roles.
+type AuthRolesPost struct {
+	AuthRolePut `yaml:",inline"`
+
+	// Name is the name of the role.
+	// Example: project-operator
+	Name string `json:"name" yaml:"name"`
+}
+
+// AuthRolePut contains the editable fields of a role.
+//
+// swagger:model
+//
+// API extension: auth_roles.
+type AuthRolePut struct {
+	// Description is a short description of the role.
+	// Example: Operators of a project.
+	Description string `json:"description" yaml:"description"`
+
+	// EntityType is the entity type that the entitlements of the role apply to.
+	// Example: project
+	EntityType string `json:"entity_type" yaml:"entity_type"`
+
+	// Entitlements are the entitlements that the role grants on an entity of the entity type.
+	// Example: ["can_view", "can_operate_instances", "can_view_events"]
+	Entitlements []string `json:"entitlements" yaml:"entitlements"`
+}
+
+// AuthGroupPermissionsConflict is returned as the metadata of a conflict response when the permissions of a group
+// have diverged from the permissions that the client based its update on.
+//
+// swagger:model
+//
+// API extension: auth_group_permissions_base.
+type AuthGroupPermissionsConflict struct {
+	// Base is the list of permissions that the client based its update on.
+	Base []Permission `json:"base" yaml:"base"`
+
+	// Current is the list of permissions currently granted to the group.
+	Current []Permission `json:"current" yaml:"current"`
+
+	// Requested is the list of permissions requested by the client.
+	Requested []Permission `json:"requested" yaml:"requested"`
+
+	// CurrentAdded are the permissions that were granted to the group since the base.
+	CurrentAdded []Permission `json:"current_added" yaml:"current_added"`
+
+	// CurrentRemoved are the permissions that were revoked from the group since the base.
+	CurrentRemoved []Permission `json:"current_removed" yaml:"current_removed"`
+
+	// RequestedAdded are the permissions that the client requested to grant relative to the base.
+	RequestedAdded []Permission `json:"requested_added" yaml:"requested_added"`
+
+	// RequestedRemoved are the permissions that the client requested to revoke relative to the base.
+	RequestedRemoved []Permission `json:"requested_removed" yaml:"requested_removed"`
+}
+
+// AuthGroupDiff is the difference between two groups.
+//
+// swagger:model
+//
+// API extension: auth_group_diff.
+type AuthGroupDiff struct {
+	// Group is the name of the group that is compared.
+	// Example: operators
+	Group string `json:"group" yaml:"group"`
+
+	// Against is the name of the group that it is compared against.
+	// Example: operators-old
+	Against string `json:"against" yaml:"against"`
+
+	// Description contains the descriptions of both groups if they differ, and is nil otherwise.
+	Description *AuthGroupDiffDescription `json:"description" yaml:"description"`
+
+	// Permissions is the difference between the permissions of the groups, excluding inherited permissions and the
+	// permissions of their roles.
+	Permissions AuthGroupDiffPermissions `json:"permissions" yaml:"permissions"`
+
+	// Identities is the difference between the identities that are members of the groups.
+	Identities AuthGroupDiffIdentities `json:"identities" yaml:"identities"`
+
+	// IdentityProviderGroups is the difference between the identity provider groups that are mapped to the groups.
+	IdentityProviderGroups AuthGroupDiffIdentityProviderGroups `json:"identity_provider_groups" yaml:"identity_provider_groups"`
+}
+
+// AuthGroupDiffDescription contains the descriptions of two groups that differ.
+//
+// swagger:model
+//
+// API extension: auth_group_diff.
+type AuthGroupDiffDescription struct {
+	// Group is the description of the group that is compared.
+	// Example: Operators of the default project.
+	Group string `json:"group" yaml:"group"`
+
+	// Against is the description of the group that it is compared against.
+	// Example: Operators of the default project (old).
+	Against string `json:"against" yaml:"against"`
+}
+
+// AuthGroupDiffPermissions is the difference between the permissions of two groups.
+//
+// swagger:model
+//
+// API extension: auth_group_diff.
+type AuthGroupDiffPermissions struct {
+	// OnlyInGroup are the permissions that only the group that is compared has.
+	OnlyInGroup []Permission `json:"only_in_group" yaml:"only_in_group"`
+
+	// OnlyInAgainst are the permissions that only the group that it is compared against has.
+	OnlyInAgainst []Permission `json:"only_in_against" yaml:"only_in_against"`
+
+	// InBoth are the permissions that both groups have.
+	InBoth []Permission `json:"in_both" yaml:"in_both"`
+}
+
+// AuthGroupDiffIdentities is the difference between the identities that are members of two groups.
+//
+// swagger:model
+//
+// API extension: auth_group_diff.
+type AuthGroupDiffIdentities struct {
+	// OnlyInGroup are the identities that are only members of the group that is compared.
+	OnlyInGroup []Identity `json:"only_in_group" yaml:"only_in_group"`
+
+	// OnlyInAgainst are the identities that are only members of the group that it is compared against.
+	OnlyInAgainst []Identity `json:"only_in_against" yaml:"only_in_against"`
+
+	// InBoth are the identities that are members of both groups.
+	InBoth []Identity `json:"in_both" yaml:"in_both"`
+}
+
+// AuthGroupDiffIdentityProviderGroups is the difference between the identity provider groups that are mapped to two
+// groups.
+//
+// swagger:model
+//
+// API extension: auth_group_diff.
+type AuthGroupDiffIdentityProviderGroups struct {
+	// OnlyInGroup are the identity provider groups that are only mapped to the group that is compared.
+	// Example: ["sales"]
+	OnlyInGroup []string `json:"only_in_group" yaml:"only_in_group"`
+
+	// OnlyInAgainst are the identity provider groups that are only mapped to the group that it is compared against.
+	// Example: ["operations"]
+	OnlyInAgainst []string `json:"only_in_against" yaml:"only_in_against"`
+
+	// InBoth are the identity provider groups that are mapped to both groups.
+	// Example: ["admins"]
+	InBoth []string `json:"in_both" yaml:"in_both"`
 }
 
 // IdentityProviderGroup represents a mapping between LXD groups and groups defined by an identity provider.
@@ -165,12 +511,21 @@ type Permission struct {
 	EntityType string `json:"entity_type" yaml:"entity_type"`
 
 	// EntityReference is the URL of the entity that the permission applies to.
+	// If it refers to an entity of another type than EntityType (e.g. a storage pool for the storage_volume
+	// entity type), the permission applies to all child entities of EntityType within that entity.
 	// Example: /1.0/instances/c1?project=default
 	EntityReference string `json:"url" yaml:"url"`
 
 	// Entitlement is the entitlement define for the entity type.
 	// Example: can_view
 	Entitlement string `json:"entitlement" yaml:"entitlement"`
+
+	// Error is set when listing groups if the entity of the permission can't be resolved (e.g. a dangling permission
+	// whose entity no longer exists). The EntityReference of the permission is then empty.
+	// Example: Entity URL missing for permission with entity type "instance" and entity ID `42`
+	//
+	// API extension: auth_group_permission_errors.
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
 }
 
 // PermissionInfo expands a Permission to include any groups that may have the specified Permission.
@@ -185,3 +540,268 @@ type PermissionInfo struct {
 	// Example: ["foo", "bar"]
 	Groups []string `json:"groups" yaml:"groups"`
 }
+
+// EntityTypeEntitlements lists the entitlements that can be granted on entities of an entity type.
+//
+// swagger:model
+//
+// API extension: auth_entitlements.
+type EntityTypeEntitlements struct {
+	// EntityType is the string representation of the entity type.
+	// Example: instance
+	EntityType string `json:"entity_type" yaml:"entity_type"`
+
+	// Entitlements are the entitlements that can be granted on an entity of the entity type.
+	// Example: ["can_view", "can_edit", "can_delete"]
+	Entitlements []string `json:"entitlements" yaml:"entitlements"`
+
+	// SubtreeEntitlements is a map of parent entity type to the entitlements that can be granted on all entities of
+	// the entity type within an entity of the parent entity type.
+	// Example: {"storage_pool": ["can_view", "can_edit"]}
+	SubtreeEntitlements map[string][]string `json:"subtree_entitlements" yaml:"subtree_entitlements"`
+}
+
+// EntitlementInfo describes an entitlement that can be granted on entities of an entity type.
+//
+// swagger:model
+//
+// API extension: auth_entitlements_info.
+type EntitlementInfo struct {
+	// Name is the name of the entitlement.
+	// Example: can_manage_backups
+	Name string `json:"name" yaml:"name"`
+
+	// Description is a human readable description of what the entitlement grants.
+	// Example: Grants permission to create, view, edit, and delete backups of the instance.
+	Description string `json:"description" yaml:"description"`
+
+	// Category is the kind of access the entitlement grants (view, edit, admin, or delegate).
+	// Example: edit
+	Category string `json:"category" yaml:"category"`
+
+	// ImpliedBy is the list of broader entitlements on the same entity that include this entitlement.
+	// Example: ["operator"]
+	ImpliedBy []string `json:"implied_by" yaml:"implied_by"`
+}
+
+// EntityTypeEntitlementsFull lists the entitlements that can be granted on entities of an entity type, with a
+// description of each entitlement.
+//
+// swagger:model
+//
+// API extension: auth_entitlements_info.
+type EntityTypeEntitlementsFull struct {
+	// EntityType is the string representation of the entity type.
+	// Example: instance
+	EntityType string `json:"entity_type" yaml:"entity_type"`
+
+	// Entitlements are the entitlements that can be granted on an entity of the entity type.
+	Entitlements []EntitlementInfo `json:"entitlements" yaml:"entitlements"`
+
+	// SubtreeEntitlements is a map of parent entity type to the entitlements that can be granted on all entities of
+	// the entity type within an entity of the parent entity type.
+	// Example: {"storage_pool": ["can_view", "can_edit"]}
+	SubtreeEntitlements map[string][]string `json:"subtree_entitlements" yaml:"subtree_entitlements"`
+}
+
+const (
+	// AuthAuditObjectTypeGroup is the object type of audit entries recording changes to authorization groups.
+	AuthAuditObjectTypeGroup = "group"
+
+	// AuthAuditObjectTypeIdentity is the object type of audit entries recording changes to identities.
+	AuthAuditObjectTypeIdentity = "identity"
+
+	// AuthAuditObjectTypeIdentityProviderGroup is the object type of audit entries recording changes to identity
+	// provider groups.
+	AuthAuditObjectTypeIdentityProviderGroup = "identity_provider_group"
+
+	// AuthAuditObjectTypeRole is the object type of audit entries recording changes to authorization roles.
+	AuthAuditObjectTypeRole = "role"
+)
+
+const (
+	// AuthAuditActionCreated is the action of audit entries recording the creation of an object.
+	AuthAuditActionCreated = "created"
+
+	// AuthAuditActionUpdated is the action of audit entries recording the update of an object.
+	AuthAuditActionUpdated = "updated"
+
+	// AuthAuditActionRenamed is the action of audit entries recording the renaming of an object.
+	AuthAuditActionRenamed = "renamed"
+
+	// AuthAuditActionDeleted is the action of audit entries recording the deletion of an object.
+	AuthAuditActionDeleted = "deleted"
+)
+
+// AuthAuditEntry is an entry of the audit trail of changes to the authorization configuration.
+//
+// Entries are chained: the hash of each entry covers the hash of the previous entry, so that modified or removed
+// entries can be detected.
+//
+// swagger:model
+//
+// API extension: auth_audit.
+type AuthAuditEntry struct {
+	// ID is the sequence number of the entry.
+	// Example: 42
+	ID int64 `json:"id" yaml:"id"`
+
+	// Date is when the change was made.
+	// Example: 2024-03-23T17:38:37Z
+	Date time.Time `json:"date" yaml:"date"`
+
+	// Actor is the username of the identity that made the change.
+	// Example: jane@example.com
+	Actor string `json:"actor" yaml:"actor"`
+
+	// ActorProtocol is the protocol that the actor authenticated with.
+	// Example: oidc
+	ActorProtocol string `json:"actor_protocol" yaml:"actor_protocol"`
+
+	// ObjectType is the type of the changed object (group, identity, identity_provider_group, or role).
+	// Example: group
+	ObjectType string `json:"object_type" yaml:"object_type"`
+
+	// ObjectName is the name of the changed object. Identities are named by their authentication method and
+	// identifier. Renamed objects are named by their new name.
+	// Example: operators
+	ObjectName string `json:"object_name" yaml:"object_name"`
+
+	// Action is the kind of change (created, updated, renamed, or deleted).
+	// Example: updated
+	Action string `json:"action" yaml:"action"`
+
+	// Before is the object before the change. It is not set for created objects.
+	Before json.RawMessage `json:"before,omitempty" yaml:"before,omitempty"`
+
+	// After is the object after the change. It is not set for deleted objects.
+	After json.RawMessage `json:"after,omitempty" yaml:"after,omitempty"`
+
+	// PreviousHash is the hash of the previous entry, or empty for the first entry.
+	// Example: 5ebc3f1a0bd7bd7c4ea1ba0e3b6f0a4a4c0b4d8e1cde7de2e0b2b8be6c2f5f0d
+	PreviousHash string `json:"previous_hash" yaml:"previous_hash"`
+
+	// Hash is the SHA-256 hash of the entry. It covers the previous hash, date (RFC 3339 in UTC), actor, actor
+	// protocol, object type, object name, action, before and after fields, each followed by a null byte.
+	// Example: 0d8f4c7e3fa2c1f6c1e3d3c7b9a0d2b5e7f1c9a8b6d4e2f0a1c3e5d7f9b1a3c5
+	Hash string `json:"hash" yaml:"hash"`
+}
+
+const (
+	// AuthModelFormatOpenFGA is the format of the authorization model export for OpenFGA.
+	AuthModelFormatOpenFGA = "openfga"
+
+	// AuthModelFormatRego is the format of the authorization model export for Open Policy Agent (Rego).
+	AuthModelFormatRego = "rego"
+)
+
+// AuthModelOpenFGA is the authorization model of the server as an OpenFGA authorization model and relationship tuples.
+//
+// swagger:model
+//
+// API extension: auth_model_export.
+type AuthModelOpenFGA struct {
+	// Model is the OpenFGA authorization model in the OpenFGA DSL. There is a type for each entity type with a
+	// relation for each of its entitlements.
+	Model string `json:"model" yaml:"model"`
+
+	// Tuples are the relationship tuples granting the permissions of the groups, along with the membership of the
+	// groups.
+	Tuples []AuthModelOpenFGATuple `json:"tuples" yaml:"tuples"`
+}
+
+// AuthModelOpenFGATuple is an OpenFGA relationship tuple.
+//
+// swagger:model
+//
+// API extension: auth_model_export.
+type AuthModelOpenFGATuple struct {
+	// User is the subject of the tuple.
+	// Example: group:/1.0/auth/groups/operators#member
+	User string `json:"user" yaml:"user"`
+
+	// Relation is the relation of the tuple.
+	// Example: can_exec
+	Relation string `json:"relation" yaml:"relation"`
+
+	// Object is the object of the tuple.
+	// Example: instance:/1.0/instances/c1?project=default
+	Object string `json:"object" yaml:"object"`
+}
+
+// AuthModelRego is the authorization model of the server as an Open Policy Agent policy and data document.
+//
+// swagger:model
+//
+// API extension: auth_model_export.
+type AuthModelRego struct {
+	// Policy is a Rego module deciding whether a request is allowed, given the data document.
+	Policy string `json:"policy" yaml:"policy"`
+
+	// Data is the data document that the policy is evaluated against.
+	Data AuthModelRegoDocument `json:"data" yaml:"data"`
+}
+
+// AuthModelRegoDocument is the data document of an AuthModelRego.
+//
+// swagger:model
+//
+// API extension: auth_model_export.
+type AuthModelRegoDocument struct {
+	// LXD is the authorization data of the server.
+	LXD AuthModelRegoData `json:"lxd" yaml:"lxd"`
+}
+
+// AuthModelRegoData is the authorization data of the server in an AuthModelRego.
+//
+// swagger:model
+//
+// API extension: auth_model_export.
+type AuthModelRegoData struct {
+	// Groups is a map of group name to the members and permissions of the group.
+	Groups map[string]AuthModelRegoGroup `json:"groups" yaml:"groups"`
+
+	// Parents is a map of group name to the names of its parent groups.
+	// Example: {"operators": ["viewers"]}
+	Parents map[string][]string `json:"parents" yaml:"parents"`
+}
+
+// AuthModelRegoGroup is a group in an AuthModelRegoData.
+//
+// swagger:model
+//
+// API extension: auth_model_export.
+type AuthModelRegoGroup struct {
+	// Enabled is whether the group grants its permissions to its members.
+	// Example: true
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Identities are the identities that are members of the group, as their authentication method and identifier.
+	// Example: ["oidc/jane@example.com"]
+	Identities []string `json:"identities" yaml:"identities"`
+
+	// IdentityProviderGroups are the names of the identity provider groups that are mapped to the group.
+	// Example: ["operators"]
+	IdentityProviderGroups []string `json:"identity_provider_groups" yaml:"identity_provider_groups"`
+
+	// Permissions are the permissions granted by the group, including those granted by its roles.
+	Permissions []AuthModelRegoPermission `json:"permissions" yaml:"permissions"`
+}
+
+// AuthModelRegoPermission is a permission in an AuthModelRegoGroup.
+//
+// swagger:model
+//
+// API extension: auth_model_export.
+type AuthModelRegoPermission struct {
+	Permission `yaml:",inline"`
+
+	// Subtree is whether the permission applies to the child entities of the referenced entity.
+	// Example: false
+	Subtree bool `json:"subtree" yaml:"subtree"`
+
+	// Location is the name of the cluster member that a subtree permission on a cluster member applies to. Such
+	// permissions apply to the instances located on that member.
+	// Example: member01
+	Location string `json:"location,omitempty" yaml:"location,omitempty"`
+}
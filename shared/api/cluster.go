@@ -137,6 +137,70 @@ func (t *ClusterMemberJoinToken) String() string {
 	return base64.StdEncoding.EncodeToString(joinTokenJSON)
 }
 
+// ClusterMemberJoinValidationPost represents the fields required to check whether a server can join the cluster.
+//
+// swagger:model
+//
+// API extension: cluster_join_validation.
+type ClusterMemberJoinValidationPost struct {
+	// The encoded join token of the new cluster member
+	// Example: eyJzZXJ2ZXJfbmFtZSI6Imx4ZDAyIiwiZmluZ2VycHJpbnQiOiI1N2Ji...
+	JoinToken string `json:"join_token" yaml:"join_token"`
+
+	// The name of the new cluster member
+	// Example: lxd02
+	ServerName string `json:"server_name" yaml:"server_name"`
+
+	// The address the new cluster member will use for cluster communication
+	// Example: 10.0.0.2:8443
+	ServerAddress string `json:"server_address" yaml:"server_address"`
+
+	// The database schema version of the new cluster member
+	// Example: 71
+	Schema int `json:"schema" yaml:"schema"`
+
+	// The number of API extensions supported by the new cluster member
+	// Example: 380
+	APIExtensions int `json:"api_extensions" yaml:"api_extensions"`
+
+	// The current time on the new cluster member (the clocks aren't compared if not set)
+	// Example: 2021-03-23T17:38:37.753398689-04:00
+	Time time.Time `json:"time" yaml:"time"`
+
+	// The member configuration keys the new cluster member will provide when joining
+	// Example: []
+	MemberConfig []ClusterMemberConfigKey `json:"member_config" yaml:"member_config"`
+}
+
+// ClusterMemberJoinValidation represents the outcome of checking whether a server can join the cluster.
+//
+// swagger:model
+//
+// API extension: cluster_join_validation.
+type ClusterMemberJoinValidation struct {
+	// List of checks that failed (empty if the server can join)
+	// Example: []
+	Failures []ClusterMemberJoinValidationFailure `json:"failures" yaml:"failures"`
+}
+
+// ClusterMemberJoinValidationFailure represents a single failed check of a cluster join validation.
+//
+// swagger:model
+//
+// API extension: cluster_join_validation.
+type ClusterMemberJoinValidationFailure struct {
+	// The name of the failed check (version, time, name, address, member_config or connectivity)
+	// Example: member_config
+	Check string `json:"check" yaml:"check"`
+
+	// A human friendly description of the failure
+	// Example: Missing member config "source" for storage pool "local"
+	Message string `json:"message" yaml:"message"`
+
+	// The member configuration key that needs to be provided (only set for member_config failures)
+	MemberConfig *ClusterMemberConfigKey `json:"member_config,omitempty" yaml:"member_config,omitempty"`
+}
+
 // ClusterMemberPost represents the fields required to rename a LXD node.
 //
 // swagger:model
@@ -310,3 +374,49 @@ type ClusterGroupPut struct {
 func (c *ClusterGroup) Writable() ClusterGroupPut {
 	return c.ClusterGroupPut
 }
+
+// ClusterDatabase represents the state of the global database.
+//
+// swagger:model
+//
+// API extension: cluster_database.
+type ClusterDatabase struct {
+	// Total size of the database files on disk of the answering member (in bytes)
+	// Example: 10485760
+	DiskSize int64 `json:"disk_size" yaml:"disk_size"`
+
+	// Size of the raft log segments of the answering member, which act as the database write-ahead log (in bytes)
+	// Example: 8388608
+	WALSize int64 `json:"wal_size" yaml:"wal_size"`
+
+	// Size of a database page (in bytes)
+	// Example: 4096
+	PageSize int64 `json:"page_size" yaml:"page_size"`
+
+	// Total number of database pages
+	// Example: 512
+	PageCount int64 `json:"page_count" yaml:"page_count"`
+
+	// Number of unused database pages
+	// Example: 12
+	FreePageCount int64 `json:"free_page_count" yaml:"free_page_count"`
+
+	// Fraction of database pages in use
+	// Example: 0.98
+	PageUtilization float64 `json:"page_utilization" yaml:"page_utilization"`
+
+	// Number of rows of each table
+	// Example: {"instances": 10, "projects": 1}
+	Tables map[string]int `json:"tables" yaml:"tables"`
+}
+
+// ClusterDatabasePost represents an action on the global database.
+//
+// swagger:model
+//
+// API extension: cluster_database.
+type ClusterDatabasePost struct {
+	// The action to run (either "vacuum" or "checkpoint")
+	// Example: vacuum
+	Action string `json:"action" yaml:"action"`
+}
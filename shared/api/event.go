@@ -152,6 +152,30 @@ type EventLifecycle struct {
 	// API extension: event_lifecycle_name_and_project
 	Name    string `yaml:"name,omitempty" json:"name,omitempty"`
 	Project string `yaml:"project,omitempty" json:"project,omitempty"`
+
+	// API extension: event_lifecycle_aggregation
+	Aggregate *EventLifecycleAggregate `yaml:"aggregate,omitempty" json:"aggregate,omitempty"`
+}
+
+// EventLifecycleAggregate represents the lifecycle events of a bulk operation that were aggregated into a single event
+//
+// API extension: event_lifecycle_aggregation.
+type EventLifecycleAggregate struct {
+	// Number of aggregated events
+	// Example: 1900
+	Count int `yaml:"count" json:"count"`
+
+	// Name of the entity of the first aggregated event
+	// Example: c101
+	First string `yaml:"first" json:"first"`
+
+	// Name of the entity of the last aggregated event
+	// Example: c2000
+	Last string `yaml:"last" json:"last"`
+
+	// URL of the bulk operation
+	// Example: /1.0/operations/fcbe3a4c-4a1b-4d9e-8e0c-0c2f6b3f3f6e
+	Operation string `yaml:"operation" json:"operation"`
 }
 
 // EventLifecycleRequestor represents the initial requestor for an event
@@ -38,6 +38,8 @@ const (
 	EventLifecycleInstanceCreated                   = "instance-created"
 	EventLifecycleInstanceDeleted                   = "instance-deleted"
 	EventLifecycleInstanceExec                      = "instance-exec"
+	EventLifecycleInstanceExecRecordingFinished     = "instance-exec-recording-finished"
+	EventLifecycleInstanceExecRecordingStarted      = "instance-exec-recording-started"
 	EventLifecycleInstanceFileDeleted               = "instance-file-deleted"
 	EventLifecycleInstanceFilePushed                = "instance-file-pushed"
 	EventLifecycleInstanceFileRetrieved             = "instance-file-retrieved"
@@ -50,6 +52,7 @@ const (
 	EventLifecycleInstanceMetadataUpdated           = "instance-metadata-updated"
 	EventLifecycleInstancePaused                    = "instance-paused"
 	EventLifecycleInstanceReady                     = "instance-ready"
+	EventLifecycleInstanceRebuilt                   = "instance-rebuilt"
 	EventLifecycleInstanceRenamed                   = "instance-renamed"
 	EventLifecycleInstanceRestarted                 = "instance-restarted"
 	EventLifecycleInstanceRestored                  = "instance-restored"
@@ -97,6 +100,8 @@ const (
 	EventLifecycleStoragePoolCreated                = "storage-pool-created"
 	EventLifecycleStoragePoolDeleted                = "storage-pool-deleted"
 	EventLifecycleStoragePoolUpdated                = "storage-pool-updated"
+	EventLifecycleStoragePoolUnavailable            = "storage-pool-unavailable"
+	EventLifecycleStoragePoolAvailable              = "storage-pool-available"
 	EventLifecycleStorageBucketCreated              = "storage-bucket-created"
 	EventLifecycleStorageBucketUpdated              = "storage-bucket-updated"
 	EventLifecycleStorageBucketDeleted              = "storage-bucket-deleted"
@@ -129,4 +134,15 @@ const (
 	EventLifecycleIdentityProviderGroupUpdated      = "identity-provider-group-updated"
 	EventLifecycleIdentityProviderGroupRenamed      = "identity-provider-group-renamed"
 	EventLifecycleIdentityProviderGroupDeleted      = "identity-provider-group-deleted"
+	EventLifecycleAuthRoleCreated                   = "auth-role-created"
+	EventLifecycleAuthRoleUpdated                   = "auth-role-updated"
+	EventLifecycleAuthRoleDeleted                   = "auth-role-deleted"
+	EventLifecycleSnapshotRetentionPolicyCreated    = "snapshot-retention-policy-created"
+	EventLifecycleSnapshotRetentionPolicyUpdated    = "snapshot-retention-policy-updated"
+	EventLifecycleSnapshotRetentionPolicyApplied    = "snapshot-retention-policy-applied"
+	EventLifecycleSnapshotRetentionPolicyDeleted    = "snapshot-retention-policy-deleted"
+	EventLifecycleInstancePoolCreated               = "instance-pool-created"
+	EventLifecycleInstancePoolUpdated               = "instance-pool-updated"
+	EventLifecycleInstancePoolDeleted               = "instance-pool-deleted"
+	EventLifecycleInstancePoolAcquired              = "instance-pool-acquired"
 )
@@ -57,11 +57,44 @@ type ImagesPost struct {
 	// API extension: image_compression_algorithm
 	CompressionAlgorithm string `json:"compression_algorithm" yaml:"compression_algorithm"`
 
+	// Compression level to use with the compression algorithm (gzip, zstd or xz only)
+	// Example: 9
+	//
+	// API extension: image_export_compression
+	CompressionLevel *int `json:"compression_level,omitempty" yaml:"compression_level,omitempty"`
+
 	// Aliases to add to the image
 	// Example: [{"name": "foo"}, {"name": "bar"}]
 	//
 	// API extension: image_create_aliases
 	Aliases []ImageAlias `json:"aliases" yaml:"aliases"`
+
+	// Whether to embed a sanitized copy of the source instance's local configuration and devices
+	// Example: true
+	//
+	// API extension: image_instance_manifest
+	IncludeManifest bool `json:"include_manifest" yaml:"include_manifest"`
+
+	// Whether to also strip user.* keys from the embedded instance configuration
+	// Example: false
+	//
+	// API extension: image_instance_manifest
+	ManifestExcludeUser bool `json:"manifest_exclude_user" yaml:"manifest_exclude_user"`
+}
+
+// ImageInstanceManifest represents the instance configuration embedded into an image published from an instance.
+//
+// swagger:model
+//
+// API extension: image_instance_manifest.
+type ImageInstanceManifest struct {
+	// Instance configuration
+	// Example: {"limits.cpu": "2"}
+	Config map[string]string `json:"config" yaml:"config"`
+
+	// Instance devices
+	// Example: {"eth1": {"type": "nic", "network": "lxdbr1"}}
+	Devices map[string]map[string]string `json:"devices" yaml:"devices"`
 }
 
 // ImagesPostSource represents the source of a new LXD image
@@ -74,7 +107,9 @@ type ImagesPostSource struct {
 	// Example: pull
 	Mode string `json:"mode" yaml:"mode"`
 
-	// Type of image source (instance, snapshot, image or url)
+	// Type of image source (instance, snapshot, image, url or oci)
+	// For "oci", the registry is set in Server, the image reference in Alias and the optional registry
+	// credentials ("<username>:<password>") in Secret.
 	// Example: instance
 	Type string `json:"type" yaml:"type"`
 
@@ -128,8 +163,19 @@ type ImagePut struct {
 	//
 	// API extension: image_profiles
 	Profiles []string `json:"profiles" yaml:"profiles"`
+
+	// List of other projects that can use the image (left unchanged by PUT if omitted)
+	// Example: ["project1"]
+	//
+	// API extension: image_shared_with
+	SharedWith []string `json:"shared_with" yaml:"shared_with"`
 }
 
+// ImageOriginShared is the origin of the images listed in a project that are shared with it by another project.
+//
+// API extension: image_shared_with.
+const ImageOriginShared = "shared"
+
 // Image represents a LXD image
 //
 // swagger:model
@@ -179,6 +225,18 @@ type Image struct {
 	// When the image was added to this LXD server
 	// Example: 2021-03-24T14:18:15.115036787-04:00
 	UploadedAt time.Time `json:"uploaded_at" yaml:"uploaded_at"`
+
+	// Where the image comes from, set to "shared" for images shared by another project
+	// Example: shared
+	//
+	// API extension: image_shared_with
+	Origin string `json:"origin,omitempty" yaml:"origin,omitempty"`
+
+	// Project that owns the image, set for images shared by another project
+	// Example: base
+	//
+	// API extension: image_shared_with
+	Project string `json:"project,omitempty" yaml:"project,omitempty"`
 }
 
 // Writable converts a full Image struct into a ImagePut struct (filters read-only fields).
@@ -202,6 +260,18 @@ type ImageAlias struct {
 	// Description of the alias
 	// Example: Our preferred Ubuntu image
 	Description string `json:"description" yaml:"description"`
+
+	// Whether the alias follows updates of its image (unset to follow the auto_update setting of the image)
+	// Example: false
+	//
+	// API extension: image_alias_pinning
+	AutoUpdate *bool `json:"auto_update,omitempty" yaml:"auto_update,omitempty"`
+
+	// Fingerprint of the image that the alias is pinned to (the alias doesn't follow updates of a pinned image)
+	// Example: 06b86454720d36b20f94e31c6812e05ec51c1b568cf3a8abd273769d213394bb
+	//
+	// API extension: image_alias_pinning
+	PinnedFingerprint string `json:"pinned_fingerprint,omitempty" yaml:"pinned_fingerprint,omitempty"`
 }
 
 // ImageSource represents the source of a LXD image
@@ -258,6 +328,18 @@ type ImageAliasesEntryPut struct {
 	// Target fingerprint for the alias
 	// Example: 06b86454720d36b20f94e31c6812e05ec51c1b568cf3a8abd273769d213394bb
 	Target string `json:"target" yaml:"target"`
+
+	// Whether the alias follows updates of its image (unset to follow the auto_update setting of the image)
+	// Example: false
+	//
+	// API extension: image_alias_pinning
+	AutoUpdate *bool `json:"auto_update,omitempty" yaml:"auto_update,omitempty"`
+
+	// Fingerprint of the image that the alias is pinned to (the alias doesn't follow updates of a pinned image)
+	// Example: 06b86454720d36b20f94e31c6812e05ec51c1b568cf3a8abd273769d213394bb
+	//
+	// API extension: image_alias_pinning
+	PinnedFingerprint string `json:"pinned_fingerprint,omitempty" yaml:"pinned_fingerprint,omitempty"`
 }
 
 // ImageAliasesEntry represents a LXD image alias
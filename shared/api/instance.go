@@ -49,6 +49,12 @@ type InstancesPost struct {
 	// Type (container or virtual-machine)
 	// Example: container
 	Type InstanceType `json:"type" yaml:"type"`
+
+	// Config keys that can't be changed after creation
+	// Example: ["security.secureboot", "user.cost_center"]
+	//
+	// API extension: instance_immutable_keys
+	ImmutableKeys []string `json:"immutable_keys,omitempty" yaml:"immutable_keys,omitempty"`
 }
 
 // InstancesPut represents the fields available for a mass update.
@@ -125,6 +131,24 @@ type InstancePost struct {
 	//
 	// API extension: instance_move_config
 	Profiles []string
+
+	// Number of most recent snapshots to keep (local pool or project move only, 0 for all)
+	// Example: 3
+	//
+	// API extension: instance_copy_exclusions
+	SnapshotsLimit int `json:"snapshots_limit,omitempty" yaml:"snapshots_limit,omitempty"`
+
+	// Devices that should be removed from the instance (local pool or project move only)
+	// Example: ["data"]
+	//
+	// API extension: instance_copy_exclusions
+	SkipDevices []string `json:"skip_devices,omitempty" yaml:"skip_devices,omitempty"`
+
+	// Custom volumes in the target project to use for disk devices (local pool or project move only)
+	// Example: {"data": "data-copy"}
+	//
+	// API extension: instance_copy_exclusions
+	DeviceVolumes map[string]string `json:"device_volumes,omitempty" yaml:"device_volumes,omitempty"`
 }
 
 // InstancePostTarget represents the migration target host and operation.
@@ -176,6 +200,12 @@ type InstancePut struct {
 	// Example: snap0
 	Restore string `json:"restore,omitempty" yaml:"restore,omitempty"`
 
+	// Whether to restore the snapshot even if the instance has security.protection.force set
+	// Example: false
+	//
+	// API extension: instance_protection_stop_force
+	OverrideProtection bool `json:"override_protection,omitempty" yaml:"override_protection,omitempty"`
+
 	// Whether the instance currently has saved state on disk
 	// Example: false
 	Stateful bool `json:"stateful" yaml:"stateful"`
@@ -193,6 +223,23 @@ type InstancePut struct {
 type InstanceRebuildPost struct {
 	// Rebuild source
 	Source InstanceSource `json:"source" yaml:"source"`
+
+	// Whether to stop the instance if it is running, and start it again once rebuilt
+	// Example: false
+	//
+	// API extension: instances_rebuild_force
+	Force bool `json:"force" yaml:"force"`
+}
+
+// InstanceDevicePost represents the fields required to add a device to an instance.
+//
+// swagger:model
+//
+// API extension: instance_device_hotplug.
+type InstanceDevicePost struct {
+	// Device configuration
+	// Example: {"type": "disk", "source": "/srv/data", "path": "/data"}
+	Config map[string]string `json:"config" yaml:"config"`
 }
 
 // Instance represents a LXD instance.
@@ -244,6 +291,18 @@ type Instance struct {
 	//
 	// API extension: instance_all_projects
 	Project string `json:"project" yaml:"project"`
+
+	// Architecture of the image the instance was created from
+	// Example: i686
+	//
+	// API extension: instance_architecture_personality
+	ImageArchitecture string `json:"image_architecture,omitempty" yaml:"image_architecture,omitempty"`
+
+	// Personality the instance runs with (containers only)
+	// Example: linux32
+	//
+	// API extension: instance_architecture_personality
+	Personality string `json:"personality,omitempty" yaml:"personality,omitempty"`
 }
 
 // InstanceFull is a combination of Instance, InstanceBackup, InstanceState and InstanceSnapshot.
@@ -373,6 +432,30 @@ type InstanceSource struct {
 	//
 	// API extension: instance_allow_inconsistent_copy
 	AllowInconsistent bool `json:"allow_inconsistent" yaml:"allow_inconsistent"`
+
+	// Whether to apply the instance configuration embedded in the image (for image source)
+	// Example: false
+	//
+	// API extension: image_instance_manifest
+	ApplyManifest bool `json:"apply_manifest,omitempty" yaml:"apply_manifest,omitempty"`
+
+	// Number of most recent snapshots to copy (for local copy, 0 for all)
+	// Example: 3
+	//
+	// API extension: instance_copy_exclusions
+	SnapshotsLimit int `json:"snapshots_limit,omitempty" yaml:"snapshots_limit,omitempty"`
+
+	// Devices of the source instance that shouldn't be copied (for copy and migration)
+	// Example: ["data"]
+	//
+	// API extension: instance_copy_exclusions
+	SkipDevices []string `json:"skip_devices,omitempty" yaml:"skip_devices,omitempty"`
+
+	// Custom volumes in the target project to use for disk devices (for copy and migration)
+	// Example: {"data": "data-copy"}
+	//
+	// API extension: instance_copy_exclusions
+	DeviceVolumes map[string]string `json:"device_volumes,omitempty" yaml:"device_volumes,omitempty"`
 }
 
 // InstanceUEFIVars represents the UEFI variables of a LXD virtual machine.
@@ -0,0 +1,54 @@
+package api
+
+// InstanceCloudInitStatusNotAvailable is returned when cloud-init isn't installed or is disabled in the instance.
+const InstanceCloudInitStatusNotAvailable = "not-available"
+
+// InstanceCloudInitStatusNotStarted is returned when cloud-init is installed but hasn't started yet.
+const InstanceCloudInitStatusNotStarted = "not-started"
+
+// InstanceCloudInitStatusRunning is returned while cloud-init is running.
+const InstanceCloudInitStatusRunning = "running"
+
+// InstanceCloudInitStatusDone is returned when cloud-init finished successfully.
+const InstanceCloudInitStatusDone = "done"
+
+// InstanceCloudInitStatusError is returned when cloud-init finished or is running with errors.
+const InstanceCloudInitStatusError = "error"
+
+// InstanceCloudInit represents the cloud-init status of a LXD instance.
+//
+// swagger:model
+//
+// API extension: instance_cloud_init_status.
+type InstanceCloudInit struct {
+	// Current status (not-available, not-started, running, done or error)
+	// Example: done
+	Status string `json:"status" yaml:"status"`
+
+	// Data source used by cloud-init
+	// Example: DataSourceNoCloud [seed=/dev/sr0][dsmode=net]
+	Datasource string `json:"datasource" yaml:"datasource"`
+
+	// Current cloud-init stage (empty when not running)
+	// Example: modules-final
+	Stage string `json:"stage" yaml:"stage"`
+
+	// Errors reported by cloud-init
+	// Example: ["('scripts_user', RuntimeError('Runparts: 1 failures in 1 attempted commands'))"]
+	Errors []string `json:"errors" yaml:"errors"`
+}
+
+// InstanceCloudInitNetworkConfig represents the cloud-init network configuration LXD generates for a LXD instance.
+//
+// swagger:model
+//
+// API extension: cloud_init_network_config_auto.
+type InstanceCloudInitNetworkConfig struct {
+	// Network configuration generated from the NIC devices of the instance
+	// Example: version: 2\nethernets:\n  eth0:\n    match:\n      macaddress: 00:16:3e:2c:47:9b\n    set-name: eth0\n    dhcp4: true\n
+	NetworkConfig string `json:"network_config" yaml:"network_config"`
+
+	// Whether the generated configuration is provided to the instance
+	// Example: true
+	Active bool `json:"active" yaml:"active"`
+}
@@ -0,0 +1,121 @@
+package api
+
+// InstancePoolTemplate represents the instances that an instance pool pre-creates.
+//
+// swagger:model
+//
+// API extension: instance_pools.
+type InstancePoolTemplate struct {
+	// Type of the instances (container or virtual-machine)
+	// Example: container
+	Type InstanceType `json:"type" yaml:"type"`
+
+	// Image of the instances (an image source from the image store of the project)
+	Source InstanceSource `json:"source" yaml:"source"`
+
+	// List of profiles applied to the instances (the profiles of the image if unset)
+	// Example: ["default"]
+	Profiles []string `json:"profiles" yaml:"profiles"`
+
+	// Instance configuration (see doc/instances.md)
+	// Example: {"limits.cpu": "2"}
+	Config map[string]string `json:"config" yaml:"config"`
+
+	// Instance devices (see doc/instances.md)
+	// Example: {"root": {"type": "disk", "pool": "default", "path": "/"}}
+	Devices map[string]map[string]string `json:"devices" yaml:"devices"`
+}
+
+// InstancePoolPut represents the modifiable fields of an instance pool.
+//
+// swagger:model
+//
+// API extension: instance_pools.
+type InstancePoolPut struct {
+	// Description of the pool
+	// Example: Runners for the CI
+	Description string `json:"description" yaml:"description"`
+
+	// Number of stopped instances that the pool keeps available
+	// Example: 5
+	Size int `json:"size" yaml:"size"`
+
+	// Pattern of the names given to acquired instances (%d is replaced by the lowest unused number)
+	// Example: ci-%d
+	NamePattern string `json:"name_pattern" yaml:"name_pattern"`
+
+	// Template of the instances of the pool
+	Template InstancePoolTemplate `json:"template" yaml:"template"`
+}
+
+// InstancePoolsPost represents the fields of a new instance pool.
+//
+// swagger:model
+//
+// API extension: instance_pools.
+type InstancePoolsPost struct {
+	InstancePoolPut `yaml:",inline"`
+
+	// Name of the pool
+	// Example: ci
+	Name string `json:"name" yaml:"name"`
+}
+
+// InstancePool represents a pool of pre-created stopped instances that are acquired on demand.
+//
+// swagger:model
+//
+// API extension: instance_pools.
+type InstancePool struct {
+	// Name of the pool
+	// Example: ci
+	Name string `json:"name" yaml:"name"`
+
+	// Project of the pool
+	// Example: default
+	Project string `json:"project" yaml:"project"`
+
+	// Description of the pool
+	// Example: Runners for the CI
+	Description string `json:"description" yaml:"description"`
+
+	// Number of stopped instances that the pool keeps available
+	// Example: 5
+	Size int `json:"size" yaml:"size"`
+
+	// Pattern of the names given to acquired instances (%d is replaced by the lowest unused number)
+	// Example: ci-%d
+	NamePattern string `json:"name_pattern" yaml:"name_pattern"`
+
+	// Template of the instances of the pool
+	Template InstancePoolTemplate `json:"template" yaml:"template"`
+
+	// List of URLs of the instances that are available in the pool
+	// Example: ["/1.0/instances/pool-ci-3f2a9c1e?project=default"]
+	UsedBy []string `json:"used_by" yaml:"used_by"`
+}
+
+// Writable converts a full InstancePool struct into a InstancePoolPut struct (filters read-only fields).
+func (pool *InstancePool) Writable() InstancePoolPut {
+	return InstancePoolPut{
+		Description: pool.Description,
+		Size:        pool.Size,
+		NamePattern: pool.NamePattern,
+		Template:    pool.Template,
+	}
+}
+
+// InstancePoolAcquirePost represents the fields of a request to acquire an instance from an instance pool.
+//
+// swagger:model
+//
+// API extension: instance_pools.
+type InstancePoolAcquirePost struct {
+	// Name of the acquired instance (generated from the name pattern of the pool if unset)
+	// Example: ci-42
+	Name string `json:"name" yaml:"name"`
+
+	// Configuration set on the acquired instance before it is started
+	// Example: {"user.job": "1234"}
+	Config map[string]string `json:"config" yaml:"config"`
+}
@@ -1,16 +1,21 @@
 package api
 
+import (
+	"time"
+)
+
 // InstanceStatePut represents the modifiable fields of a LXD instance's state.
 //
 // swagger:model
 //
 // API extension: instances.
 type InstanceStatePut struct {
-	// State change action (start, stop, restart, freeze, unfreeze)
+	// State change action (start, stop, restart, freeze, unfreeze or unfreeze-fs)
 	// Example: start
 	Action string `json:"action" yaml:"action"`
 
 	// How long to wait (in s) before giving up (when force isn't set)
+	// For freeze, how long (in s) before the instance is automatically unfrozen (API extension: instance_freeze_timeout)
 	// Example: 30
 	Timeout int `json:"timeout" yaml:"timeout"`
 
@@ -56,6 +61,62 @@ type InstanceState struct {
 
 	// CPU usage information
 	CPU InstanceStateCPU `json:"cpu" yaml:"cpu"`
+
+	// Outcome of the scheduled snapshots of the instance
+	//
+	// API extension: instance_scheduled_snapshots_retry
+	ScheduledSnapshots *InstanceStateScheduledSnapshots `json:"scheduled_snapshots,omitempty" yaml:"scheduled_snapshots,omitempty"`
+
+	// Freeze of the root filesystem of the instance by LXD
+	//
+	// API extension: instance_filesystem_freeze
+	FilesystemFreeze *InstanceStateFilesystemFreeze `json:"filesystem_freeze,omitempty" yaml:"filesystem_freeze,omitempty"`
+}
+
+// InstanceStateFilesystemFreeze represents a freeze of the root filesystem of a LXD instance by LXD.
+//
+// swagger:model
+//
+// API extension: instance_filesystem_freeze.
+type InstanceStateFilesystemFreeze struct {
+	// Description of the operation that froze the filesystem
+	// Example: Snapshotting instance
+	Operation string `json:"operation" yaml:"operation"`
+
+	// URL of the operation that froze the filesystem
+	// Example: /1.0/operations/66e83638-9dd7-4a26-aef2-5462814869a1
+	OperationURL string `json:"operation_url" yaml:"operation_url"`
+
+	// When the filesystem was frozen
+	// Example: 2024-03-23T20:00:00-04:00
+	FrozenAt time.Time `json:"frozen_at" yaml:"frozen_at"`
+
+	// When the filesystem is automatically thawed (zero if never)
+	// Example: 2024-03-23T20:05:00-04:00
+	ThawAt time.Time `json:"thaw_at" yaml:"thaw_at"`
+}
+
+// InstanceStateScheduledSnapshots represents the outcome of the scheduled snapshots of a LXD instance.
+//
+// swagger:model
+//
+// API extension: instance_scheduled_snapshots_retry.
+type InstanceStateScheduledSnapshots struct {
+	// When the last successful scheduled snapshot was taken
+	// Example: 2024-03-23T20:00:00-04:00
+	LastSuccessAt time.Time `json:"last_success_at" yaml:"last_success_at"`
+
+	// When the last scheduled snapshot failed
+	// Example: 2024-03-23T21:00:00-04:00
+	LastFailureAt time.Time `json:"last_failure_at" yaml:"last_failure_at"`
+
+	// Error of the last failed scheduled snapshot
+	// Example: No space left on device
+	LastError string `json:"last_error" yaml:"last_error"`
+
+	// Number of scheduled snapshots that have failed since the last successful one
+	// Example: 2
+	ConsecutiveFailures int64 `json:"consecutive_failures" yaml:"consecutive_failures"`
 }
 
 // InstanceStateDisk represents the disk information section of a LXD instance's state.
@@ -73,6 +134,54 @@ type InstanceStateDisk struct {
 	//
 	// API extension: instances_state_total
 	Total int64 `json:"total" yaml:"total"`
+
+	// Transport used to share a directory with a virtual machine (virtiofs or 9p)
+	// Example: virtiofs
+	//
+	// API extension: disk_virtiofs_idmap
+	ShareTransport string `json:"share_transport,omitempty" yaml:"share_transport,omitempty"`
+
+	// Ownership mapping used by a directory shared with a virtual machine (none, translate or userns)
+	// Example: translate
+	//
+	// API extension: disk_virtiofs_idmap
+	ShareIdmap string `json:"share_idmap,omitempty" yaml:"share_idmap,omitempty"`
+
+	// Number of bytes read from the disk
+	// Example: 1073741824
+	//
+	// API extension: instances_state_disk_io
+	ReadBytes int64 `json:"read_bytes,omitempty" yaml:"read_bytes,omitempty"`
+
+	// Number of bytes written to the disk
+	// Example: 536870912
+	//
+	// API extension: instances_state_disk_io
+	WrittenBytes int64 `json:"written_bytes,omitempty" yaml:"written_bytes,omitempty"`
+
+	// Number of completed read operations
+	// Example: 24680
+	//
+	// API extension: instances_state_disk_io
+	ReadOperations int64 `json:"read_operations,omitempty" yaml:"read_operations,omitempty"`
+
+	// Number of completed write operations
+	// Example: 13579
+	//
+	// API extension: instances_state_disk_io
+	WriteOperations int64 `json:"write_operations,omitempty" yaml:"write_operations,omitempty"`
+
+	// Read operations per second, sampled over a short window
+	// Example: 120
+	//
+	// API extension: instances_state_disk_io
+	ReadOperationsPerSecond int64 `json:"read_operations_per_second,omitempty" yaml:"read_operations_per_second,omitempty"`
+
+	// Write operations per second, sampled over a short window
+	// Example: 45
+	//
+	// API extension: instances_state_disk_io
+	WriteOperationsPerSecond int64 `json:"write_operations_per_second,omitempty" yaml:"write_operations_per_second,omitempty"`
 }
 
 // InstanceStateCPU represents the cpu information section of a LXD instance's state.
@@ -146,6 +255,12 @@ type InstanceStateNetwork struct {
 	// Type of interface (broadcast, loopback, point-to-point, ...)
 	// Example: broadcast
 	Type string `json:"type" yaml:"type"`
+
+	// Number of active queues of the interface on the host
+	// Example: 4
+	//
+	// API extension: instance_nic_queues
+	Queues int `json:"queues,omitempty" yaml:"queues,omitempty"`
 }
 
 // InstanceStateNetworkAddress represents a network address as part of the network section of a LXD
@@ -137,6 +137,45 @@ type NetworkLease struct {
 	Location string `json:"location" yaml:"location"`
 }
 
+// NetworkStaticAddress represents a static IP address of an instance NIC connected to a network
+//
+// swagger:model
+//
+// API extension: network_static_addresses.
+type NetworkStaticAddress struct {
+	// The IP address
+	// Example: 10.0.0.98
+	Address string `json:"address" yaml:"address"`
+
+	// Project of the instance
+	// Example: default
+	Project string `json:"project" yaml:"project"`
+
+	// Name of the instance
+	// Example: c1
+	Instance string `json:"instance" yaml:"instance"`
+
+	// Name of the NIC device
+	// Example: eth0
+	Device string `json:"device" yaml:"device"`
+
+	// The MAC address of the NIC
+	// Example: 00:16:3e:2c:89:d9
+	Hwaddr string `json:"hwaddr" yaml:"hwaddr"`
+
+	// What cluster member the instance is located on
+	// Example: lxd01
+	Location string `json:"location" yaml:"location"`
+
+	// Whether the NIC allows other NICs to use the same address
+	// Example: false
+	AllowDuplicate bool `json:"allow_duplicate" yaml:"allow_duplicate"`
+
+	// List of conflicts of the address
+	// Example: ["Address is also assigned to NIC \"eth0\" of instance \"c2\" in project \"default\""]
+	Conflicts []string `json:"conflicts" yaml:"conflicts"`
+}
+
 // NetworkState represents the network state
 //
 // swagger:model
@@ -1,5 +1,9 @@
 package api
 
+import (
+	"time"
+)
+
 // NetworkAllocations used for displaying network addresses used by a consuming entity
 // e.g, instance, network forward, load-balancer, network...
 //
@@ -19,3 +23,35 @@ type NetworkAllocations struct {
 	// Hwaddr is the MAC address of the entity consuming the network address
 	Hwaddr string `json:"hwaddr" yaml:"hwaddr"`
 }
+
+// NetworkUplinkAllocation represents an address of an uplink network that is used by an OVN network, or by one of its
+// network forwards or load balancers.
+//
+// swagger:model
+//
+// API extension: network_uplink_allocations.
+type NetworkUplinkAllocation struct {
+	// The uplink network address
+	// Example: 192.0.2.10
+	Address string `json:"address" yaml:"address"`
+
+	// URL of the entity using the address
+	// Example: /1.0/networks/ovn1/forwards/192.0.2.10?project=default
+	UsedBy string `json:"used_by" yaml:"used_by"`
+
+	// Type of the entity using the address (network, network-forward or network-load-balancer)
+	// Example: network-forward
+	Type string `json:"type" yaml:"type"`
+
+	// Whether the address was allocated automatically from the uplink network's OVN ranges
+	// Example: true
+	Auto bool `json:"auto" yaml:"auto"`
+
+	// When the address was allocated (only set for automatically allocated addresses)
+	// Example: 2024-07-01T12:00:00Z
+	AllocatedAt time.Time `json:"allocated_at" yaml:"allocated_at"`
+
+	// When the address was released (only set for automatically allocated addresses that are no longer in use)
+	// Example: 2024-07-02T12:00:00Z
+	ReleasedAt time.Time `json:"released_at" yaml:"released_at"`
+}
@@ -95,6 +95,13 @@ type NetworkLoadBalancersPost struct {
 	// The listen address of the load balancer
 	// Example: 192.0.2.1
 	ListenAddress string `json:"listen_address" yaml:"listen_address"`
+
+	// How to allocate the listen address if it isn't specified ("auto" to allocate a free address from the
+	// uplink network's OVN ranges)
+	// Example: auto
+	//
+	// API extension: network_uplink_allocations
+	Allocate string `json:"allocate,omitempty" yaml:"allocate,omitempty"`
 }
 
 // NetworkLoadBalancerPut represents the modifiable fields of a LXD network load balancer
@@ -41,6 +41,11 @@ type Resources struct {
 	//
 	// API extension: resources_system
 	System ResourcesSystem `json:"system" yaml:"system"`
+
+	// Virtiofs support
+	//
+	// API extension: disk_virtiofs_idmap
+	Virtiofs ResourcesVirtiofs `json:"virtiofs" yaml:"virtiofs"`
 }
 
 // ResourcesCPU represents the cpu resources available on the system
@@ -783,6 +788,30 @@ type ResourcesStoragePool struct {
 
 	// DIsk inode usage
 	Inodes ResourcesStoragePoolInodes `json:"inodes,omitempty" yaml:"inodes,omitempty"`
+
+	// Sessions to the storage targets of the pool on this member
+	//
+	// API extension: storage_driver_iscsi
+	Sessions []ResourcesStoragePoolSession `json:"sessions,omitempty" yaml:"sessions,omitempty"`
+}
+
+// ResourcesStoragePoolSession represents the state of a session between a member and a storage target
+//
+// swagger:model
+//
+// API extension: storage_driver_iscsi.
+type ResourcesStoragePoolSession struct {
+	// Address of the storage target portal
+	// Example: 10.0.0.10:3260
+	Portal string `json:"portal" yaml:"portal"`
+
+	// Name of the storage target
+	// Example: iqn.2003-01.org.example:storage
+	Target string `json:"target" yaml:"target"`
+
+	// State of the session
+	// Example: LOGGED_IN
+	State string `json:"state" yaml:"state"`
 }
 
 // ResourcesStoragePoolSpace represents the space available to a given storage pool
@@ -1094,3 +1123,18 @@ type ResourcesSystemMotherboard struct {
 	// Example: None
 	Version string `json:"version" yaml:"version"`
 }
+
+// ResourcesVirtiofs represents the support for sharing directories with virtual machines using virtiofs
+//
+// swagger:model
+//
+// API extension: disk_virtiofs_idmap.
+type ResourcesVirtiofs struct {
+	// Whether virtiofsd is available
+	// Example: true
+	Available bool `json:"available" yaml:"available"`
+
+	// Whether virtiofsd supports translating the ownership of shared files
+	// Example: true
+	Idmap bool `json:"idmap" yaml:"idmap"`
+}
@@ -228,3 +228,42 @@ type Server struct {
 func (srv *Server) Writable() ServerPut {
 	return srv.ServerPut
 }
+
+// ServerHealthStatusPass is the status of a passed health check.
+const ServerHealthStatusPass = "pass"
+
+// ServerHealthStatusFail is the status of a failed health check.
+const ServerHealthStatusFail = "fail"
+
+// ServerHealth represents the outcome of the health checks of the server
+//
+// swagger:model
+//
+// API extension: server_health_endpoints.
+type ServerHealth struct {
+	// Overall status, "pass" if all checks passed and "fail" otherwise
+	// Example: pass
+	Status string `json:"status" yaml:"status"`
+
+	// Outcome of each check
+	Checks []ServerHealthCheck `json:"checks" yaml:"checks"`
+}
+
+// ServerHealthCheck represents the outcome of a single health check of the server
+//
+// swagger:model
+//
+// API extension: server_health_endpoints.
+type ServerHealthCheck struct {
+	// Name of the check
+	// Example: database
+	Name string `json:"name" yaml:"name"`
+
+	// Status of the check, either "pass" or "fail"
+	// Example: pass
+	Status string `json:"status" yaml:"status"`
+
+	// Time taken by the check in milliseconds
+	// Example: 1.5
+	LatencyMS float64 `json:"latency_ms" yaml:"latency_ms"`
+}
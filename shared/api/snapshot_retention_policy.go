@@ -0,0 +1,159 @@
+package api
+
+// SnapshotRetentionRules represents the rules of a snapshot retention policy. A snapshot is kept if any of the rules
+// keeps it, and snapshots that are kept by none of the rules are deleted. The periods are in UTC.
+//
+// swagger:model
+//
+// API extension: snapshot_retention_policies.
+type SnapshotRetentionRules struct {
+	// Number of most recent snapshots to keep
+	// Example: 3
+	KeepLast int `json:"keep_last" yaml:"keep_last"`
+
+	// Number of hours for which to keep the most recent snapshot of the hour
+	// Example: 24
+	KeepHourly int `json:"keep_hourly" yaml:"keep_hourly"`
+
+	// Number of days for which to keep the most recent snapshot of the day
+	// Example: 7
+	KeepDaily int `json:"keep_daily" yaml:"keep_daily"`
+
+	// Number of weeks for which to keep the most recent snapshot of the week
+	// Example: 4
+	KeepWeekly int `json:"keep_weekly" yaml:"keep_weekly"`
+
+	// Number of months for which to keep the most recent snapshot of the month
+	// Example: 12
+	KeepMonthly int `json:"keep_monthly" yaml:"keep_monthly"`
+
+	// Number of years for which to keep the most recent snapshot of the year
+	// Example: 2
+	KeepYearly int `json:"keep_yearly" yaml:"keep_yearly"`
+}
+
+// SnapshotRetentionPolicyPut represents the modifiable fields of a snapshot retention policy.
+//
+// swagger:model
+//
+// API extension: snapshot_retention_policies.
+type SnapshotRetentionPolicyPut struct {
+	// Description of the policy
+	// Example: Keep 7 daily, 4 weekly and 12 monthly snapshots
+	Description string `json:"description" yaml:"description"`
+
+	// Retention rules of the policy
+	Rules SnapshotRetentionRules `json:"rules" yaml:"rules"`
+}
+
+// SnapshotRetentionPoliciesPost represents the fields of a new snapshot retention policy.
+//
+// swagger:model
+//
+// API extension: snapshot_retention_policies.
+type SnapshotRetentionPoliciesPost struct {
+	SnapshotRetentionPolicyPut `yaml:",inline"`
+
+	// Name of the policy
+	// Example: gfs
+	Name string `json:"name" yaml:"name"`
+}
+
+// SnapshotRetentionPolicy represents a named snapshot retention policy. Instances and custom storage volumes use a
+// policy by setting `snapshots.retention.policy` to its name.
+//
+// swagger:model
+//
+// API extension: snapshot_retention_policies.
+type SnapshotRetentionPolicy struct {
+	// Name of the policy
+	// Example: gfs
+	Name string `json:"name" yaml:"name"`
+
+	// Description of the policy
+	// Example: Keep 7 daily, 4 weekly and 12 monthly snapshots
+	Description string `json:"description" yaml:"description"`
+
+	// Project of the policy (empty for a server-wide policy)
+	// Example: default
+	Project string `json:"project" yaml:"project"`
+
+	// Retention rules that are in effect
+	Rules SnapshotRetentionRules `json:"rules" yaml:"rules"`
+
+	// Retention rules that are only in effect once applied (see the simulation of the policy)
+	PendingRules *SnapshotRetentionRules `json:"pending_rules" yaml:"pending_rules"`
+
+	// List of URLs of instances and custom storage volumes using the policy
+	// Example: ["/1.0/instances/c1?project=default"]
+	UsedBy []string `json:"used_by" yaml:"used_by"`
+}
+
+// Writable converts a full SnapshotRetentionPolicy struct into a SnapshotRetentionPolicyPut struct (filters
+// read-only fields).
+func (policy *SnapshotRetentionPolicy) Writable() SnapshotRetentionPolicyPut {
+	return SnapshotRetentionPolicyPut{
+		Description: policy.Description,
+		Rules:       policy.Rules,
+	}
+}
+
+// SnapshotRetentionPolicySimulatePost represents the fields of a snapshot retention policy simulation request.
+//
+// swagger:model
+//
+// API extension: snapshot_retention_policies.
+type SnapshotRetentionPolicySimulatePost struct {
+	// Retention rules to simulate (the pending rules of the policy, or its rules if there are none, if unset)
+	Rules *SnapshotRetentionRules `json:"rules" yaml:"rules"`
+}
+
+// SnapshotRetentionPolicySimulation represents the snapshots that are kept and deleted under a snapshot retention
+// policy.
+//
+// swagger:model
+//
+// API extension: snapshot_retention_policies.
+type SnapshotRetentionPolicySimulation struct {
+	// Retention rules that were simulated
+	Rules SnapshotRetentionRules `json:"rules" yaml:"rules"`
+
+	// Snapshots kept and deleted for each instance and custom storage volume using the policy
+	Entities []SnapshotRetentionPolicySimulationEntity `json:"entities" yaml:"entities"`
+
+	// Token confirming the simulation when applying the pending rules of the policy
+	// Example: 2bb7a8a4f2c7e6cf0d9d2b1b2d06e6d4c0b0ab5c1f0b1a8f4b9e0c9d3a2e1f00
+	Token string `json:"token" yaml:"token"`
+}
+
+// SnapshotRetentionPolicySimulationEntity represents the snapshots of an instance or custom storage volume that are
+// kept and deleted under a snapshot retention policy.
+//
+// swagger:model
+//
+// API extension: snapshot_retention_policies.
+type SnapshotRetentionPolicySimulationEntity struct {
+	// URL of the instance or custom storage volume
+	// Example: /1.0/instances/c1?project=default
+	EntityURL string `json:"entity_url" yaml:"entity_url"`
+
+	// Names of the snapshots that are kept
+	// Example: ["snap3", "snap2"]
+	Kept []string `json:"kept" yaml:"kept"`
+
+	// Names of the snapshots that are deleted
+	// Example: ["snap1"]
+	Deleted []string `json:"deleted" yaml:"deleted"`
+}
+
+// SnapshotRetentionPolicyApplyPost represents the fields of a request to apply the pending rules of a snapshot
+// retention policy.
+//
+// swagger:model
+//
+// API extension: snapshot_retention_policies.
+type SnapshotRetentionPolicyApplyPost struct {
+	// Token of the simulation of the pending rules
+	// Example: 2bb7a8a4f2c7e6cf0d9d2b1b2d06e6d4c0b0ab5c1f0b1a8f4b9e0c9d3a2e1f00
+	Token string `json:"token" yaml:"token"`
+}
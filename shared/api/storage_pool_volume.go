@@ -187,7 +187,7 @@ type StorageVolumeSource struct {
 	// Example: foo
 	Name string `json:"name" yaml:"name"`
 
-	// Source type (copy or migration)
+	// Source type (copy, migration, url or upload)
 	// Example: copy
 	Type string `json:"type" yaml:"type"`
 
@@ -242,6 +242,30 @@ type StorageVolumeSource struct {
 	//
 	// API extension: cluster_internal_custom_volume_copy
 	Location string `json:"location" yaml:"location"`
+
+	// URL of a tarball to unpack into the new volume (for url)
+	// Example: https://example.com/content.tar.gz
+	//
+	// API extension: storage_volume_initial_content
+	URL string `json:"url,omitempty" yaml:"url,omitempty"`
+
+	// Expected SHA256 checksum of the tarball (for url or upload)
+	// Example: 0c1a2d1d8d6e1b8ce9fbb7b2f1e1c6a1fbb6a8e7f3a6c1e0cbe1f2f5a6d7e8f9
+	//
+	// API extension: storage_volume_initial_content
+	Checksum string `json:"checksum,omitempty" yaml:"checksum,omitempty"`
+
+	// Whether to restore extended attributes from the tarball (for url or upload)
+	// Example: false
+	//
+	// API extension: storage_volume_initial_content
+	Xattrs bool `json:"xattrs,omitempty" yaml:"xattrs,omitempty"`
+
+	// How to handle device nodes in the tarball, one of "skip" (default), "create" or "reject" (for url or upload)
+	// Example: skip
+	//
+	// API extension: storage_volume_initial_content
+	Devices string `json:"devices,omitempty" yaml:"devices,omitempty"`
 }
 
 // Writable converts a full StorageVolume struct into a StorageVolumePut struct (filters read-only fields).
@@ -51,6 +51,12 @@ type Warning struct {
 	// The entity affected by this warning
 	// Example: /1.0/instances/c1?project=default
 	EntityURL string `json:"entity_url" yaml:"entity_url"`
+
+	// Whether the warning was acknowledged on creation because it matched a suppression rule
+	// Example: false
+	//
+	// API extension: warnings_suppressions.
+	Suppressed bool `json:"suppressed" yaml:"suppressed"`
 }
 
 // WarningPut represents the modifiable fields of a warning.
@@ -63,3 +69,48 @@ type WarningPut struct {
 	// Example: new
 	Status string `json:"status" yaml:"status"`
 }
+
+// WarningSuppressionsPost represents the fields of a new warning suppression rule.
+//
+// swagger:model
+//
+// API extension: warnings_suppressions.
+type WarningSuppressionsPost struct {
+	// Type of the warnings to suppress (warnings of any type if empty)
+	// Example: Couldn't find the CGroup blkio.weight
+	Type string `json:"type" yaml:"type"`
+
+	// URL of the entity whose warnings to suppress (warnings of any entity if empty)
+	// Example: /1.0/instances/c1?project=default
+	EntityURL string `json:"entity_url" yaml:"entity_url"`
+
+	// Regular expression matched against the message of the warnings to suppress (warnings with any message if empty)
+	// Example: ^Couldn't find the CGroup
+	MessagePattern string `json:"message_pattern" yaml:"message_pattern"`
+
+	// Description of the rule
+	// Example: Known to be missing on this platform
+	Description string `json:"description" yaml:"description"`
+
+	// When the rule expires (never if unset)
+	// Example: 2021-03-23T17:38:37.753398689-04:00
+	ExpiresAt time.Time `json:"expires_at" yaml:"expires_at"`
+}
+
+// WarningSuppression represents a warning suppression rule. Warnings that match an active rule when they are created
+// are acknowledged and tagged as suppressed.
+//
+// swagger:model
+//
+// API extension: warnings_suppressions.
+type WarningSuppression struct {
+	WarningSuppressionsPost `yaml:",inline"`
+
+	// ID of the rule
+	// Example: 1
+	ID int `json:"id" yaml:"id"`
+
+	// When the rule was created
+	// Example: 2021-03-23T17:38:37.753398689-04:00
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+}
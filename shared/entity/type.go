@@ -125,6 +125,11 @@ var entityTypes = []Type{
 	TypeIdentityProviderGroup,
 }
 
+// Types returns all entity types.
+func Types() []Type {
+	return append([]Type{}, entityTypes...)
+}
+
 // String implements fmt.Stringer for Type.
 func (t Type) String() string {
 	return string(t)
@@ -359,6 +364,21 @@ entityTypeLoop:
 	return entityType, projectName, u.Query().Get("target"), pathArguments, nil
 }
 
+// CanonicalURL returns the canonical form of the given entity URL, so that URLs referencing the same entity are equal.
+// Trailing slashes and query parameters that are irrelevant to the entity type are removed, the default project is
+// made explicit and the remaining query parameters are sorted.
+func CanonicalURL(u url.URL) (*api.URL, error) {
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawPath = strings.TrimSuffix(u.RawPath, "/")
+
+	entityType, projectName, location, pathArguments, err := ParseURL(u)
+	if err != nil {
+		return nil, err
+	}
+
+	return entityType.URL(projectName, location, pathArguments...)
+}
+
 // urlMust is used internally when we know that creation of an *api.URL ought to succeed. If an error does occur an
 // empty string is return and the error is logged with as much context as possible, including the file and line number
 // of the caller.
@@ -202,3 +202,58 @@ func TestURL(t *testing.T) {
 		})
 	}
 }
+
+func TestCanonicalURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		rawURLs  []string
+		expected string
+	}{
+		{
+			name:     "server",
+			rawURLs:  []string{"/1.0", "/1.0/", "https://example.com/1.0", "/1.0?project=foo"},
+			expected: "/1.0",
+		},
+		{
+			name:     "instance in default project",
+			rawURLs:  []string{"/1.0/instances/c1", "/1.0/instances/c1/", "/1.0/instances/c1?project=default", "/1.0/instances/c1/?project=default&foo=bar"},
+			expected: "/1.0/instances/c1?project=default",
+		},
+		{
+			name:     "storage volume with query parameters in any order",
+			rawURLs:  []string{"/1.0/storage-pools/default/volumes/custom/vol1?project=p1&target=node1", "/1.0/storage-pools/default/volumes/custom/vol1?target=node1&project=p1", "/1.0/storage-pools/default/volumes/custom/vol1/?target=node1&project=p1"},
+			expected: "/1.0/storage-pools/default/volumes/custom/vol1?project=p1&target=node1",
+		},
+		{
+			name:     "project without project parameter",
+			rawURLs:  []string{"/1.0/projects/p1", "/1.0/projects/p1?project=p2", "/1.0/projects/p1/"},
+			expected: "/1.0/projects/p1",
+		},
+		{
+			name:     "escaped path arguments",
+			rawURLs:  []string{"/1.0/auth/groups/my%20group", "/1.0/auth/groups/my%20group/"},
+			expected: "/1.0/auth/groups/my%20group",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, rawURL := range tt.rawURLs {
+				u, err := url.Parse(rawURL)
+				require.NoError(t, err)
+
+				actual, err := CanonicalURL(*u)
+				require.NoError(t, err, rawURL)
+				assert.Equal(t, tt.expected, actual.String(), rawURL)
+			}
+		})
+	}
+
+	for _, rawURL := range []string{"/1.0/instances", "/1.0/instances/c1/state", "/2.0/instances/c1"} {
+		u, err := url.Parse(rawURL)
+		require.NoError(t, err)
+
+		_, err = CanonicalURL(*u)
+		assert.Error(t, err, rawURL)
+	}
+}
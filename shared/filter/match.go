@@ -113,8 +113,19 @@ func (s ClauseSet) match(c Clause, objValue any) (bool, error) {
 	var valueSlice []string
 	var err error
 
-	// If 'value' is type of string try to test value as a regexp.
+	// Pointers are matched on the value they point to, or on the zero value of that type if nil.
 	valInfo := reflect.ValueOf(objValue)
+	if valInfo.Kind() == reflect.Pointer {
+		if valInfo.IsNil() {
+			valInfo = reflect.Zero(valInfo.Type().Elem())
+		} else {
+			valInfo = valInfo.Elem()
+		}
+
+		objValue = valInfo.Interface()
+	}
+
+	// If 'value' is type of string try to test value as a regexp.
 	kind := valInfo.Kind()
 	switch kind {
 	case reflect.String:
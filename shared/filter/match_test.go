@@ -81,3 +81,37 @@ func TestMatch_Image(t *testing.T) {
 		})
 	}
 }
+
+func TestMatch_AuthGroup(t *testing.T) {
+	enabled := false
+	group := api.AuthGroup{
+		AuthGroupsPost: api.AuthGroupsPost{
+			AuthGroupPost: api.AuthGroupPost{Name: "viewers"},
+			AuthGroupPut:  api.AuthGroupPut{Enabled: &enabled},
+		},
+	}
+
+	cases := map[string]any{
+		"enabled eq false":                    true,
+		"enabled eq true":                     false,
+		"name eq viewers and enabled ne true": true,
+	}
+
+	for s := range cases {
+		t.Run(s, func(t *testing.T) {
+			f, err := filter.Parse(s, filter.QueryOperatorSet())
+			require.NoError(t, err)
+			match, err := filter.Match(group, *f)
+			require.NoError(t, err)
+			assert.Equal(t, cases[s], match)
+		})
+	}
+
+	// An unset pointer is matched as its zero value.
+	group.Enabled = nil
+	f, err := filter.Parse("enabled eq false", filter.QueryOperatorSet())
+	require.NoError(t, err)
+	match, err := filter.Match(group, *f)
+	require.NoError(t, err)
+	assert.True(t, match)
+}
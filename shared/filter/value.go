@@ -14,7 +14,7 @@ func ValueOf(obj any, field string) any {
 	key := parts[0]
 	rest := strings.Join(parts[1:], ".")
 
-	var parent any
+	var parents []any
 
 	if value.Kind() == reflect.Map {
 		switch reflect.TypeOf(obj).Elem().Kind() {
@@ -40,7 +40,7 @@ func ValueOf(obj any, field string) any {
 		yaml := fieldType.Tag.Get("yaml")
 
 		if yaml == ",inline" {
-			parent = fieldValue.Interface()
+			parents = append(parents, fieldValue.Interface())
 		}
 
 		yamlKey, _, _ := strings.Cut(yaml, ",")
@@ -54,8 +54,12 @@ func ValueOf(obj any, field string) any {
 		}
 	}
 
-	if parent != nil {
-		return ValueOf(parent, field)
+	// Structs can embed several inline structs, so look for the field in each of them.
+	for _, parent := range parents {
+		v := ValueOf(parent, field)
+		if v != nil {
+			return v
+		}
 	}
 
 	return nil
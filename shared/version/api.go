@@ -400,6 +400,92 @@ var APIExtensions = []string{
 	"instances_migration_stateful",
 	"container_syscall_filtering_allow_deny_syntax",
 	"access_management",
+	"auth_group_permissions_base",
+	"custom_volume_sftp",
+	"cluster_join_validation",
+	"auth_groups_count",
+	"oidc_claims",
+	"auth_permission_subtree",
+	"disk_virtiofs_idmap",
+	"auth_group_create_representation",
+	"auth_permissions_pagination",
+	"auth_permission_cluster_member_instances",
+	"network_uplink_allocations",
+	"auth_group_last_used",
+	"instance_scheduled_snapshots_retry",
+	"cluster_database",
+	"auth_group_parents",
+	"auth_group_admin_access_warning",
+	"auth_entitlements",
+	"instance_architecture_personality",
+	"auth_group_etag_required",
+	"projects_default_device",
+	"auth_group_identity_filter",
+	"identity_effective_groups",
+	"instances_migration_transfer_retries",
+	"warnings_suppressions",
+	"auth_require_group_membership",
+	"auth_roles",
+	"instance_snapshot_schedules",
+	"server_health_endpoints",
+	"auth_groups_preview",
+	"instances_rebuild_force",
+	"instance_nic_queues",
+	"snapshot_retention_policies",
+	"instance_console_history",
+	"auth_identities_filter",
+	"auth_groups_summary",
+	"instance_exec_recording",
+	"instance_filesystem_freeze",
+	"auth_group_enabled",
+	"instance_migration_progress",
+	"auth_group_lockout_check",
+	"https_trusted_proxy_forwarded_headers",
+	"instance_device_hotplug",
+	"auth_self_permissions",
+	"instance_boot_schedule",
+	"device_config_strict_validation",
+	"instance_freeze_timeout",
+	"instance_pools",
+	"auth_entitlements_info",
+	"instances_state_disk_io",
+	"auth_groups_if_not_exists",
+	"auth_groups_bulk_delete",
+	"storage_volume_initial_content",
+	"instance_boot_after",
+	"instance_immutable_keys",
+	"events_auth_group_filter",
+	"instance_cloud_init_status",
+	"storage_pool_unavailable_action",
+	"image_instance_manifest",
+	"cloud_init_network_config_auto",
+	"storage_pool_create_concurrency",
+	"instance_protection_stop_force",
+	"identity_enabled",
+	"auth_group_delegation",
+	"storage_driver_iscsi",
+	"instance_copy_exclusions",
+	"auth_groups_max_permissions",
+	"instance_file_tar",
+	"network_static_addresses",
+	"instance_files_edit_entitlement",
+	"auth_audit",
+	"auth_model_export",
+	"instance_nic_persistent_host_name",
+	"image_alias_pinning",
+	"auth_group_template",
+	"image_export_compression",
+	"event_lifecycle_aggregation",
+	"image_export_delta",
+	"auth_group_diff",
+	"auth_case_insensitive_group_names",
+	"image_shared_with",
+	"network_dhcp_external",
+	"auth_group_permission_errors",
+	"image_import_oci",
+	"auth_groups_import",
+	"storage_dir_quota_strict",
+	"api_stale_reads",
 }
 
 // APIExtensionsCount returns the number of available API extensions.
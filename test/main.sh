@@ -204,12 +204,16 @@ if [ "${1:-"all"}" != "cluster" ]; then
     run_test test_check_deps "checking dependencies"
     run_test test_database_restore "database restore"
     run_test test_database_no_disk_space "database out of disk space"
+    run_test test_database_maintenance "database maintenance"
     run_test test_sql "lxd sql"
     run_test test_tls_restrictions "TLS restrictions"
     run_test test_oidc "OpenID Connect"
     run_test test_authorization "Authorization"
     run_test test_certificate_edit "Certificate edit"
     run_test test_basic_usage "basic usage"
+    run_test test_basic_freeze_timeout "instance freeze timeout"
+    run_test test_basic_events_aggregation "lifecycle events aggregation"
+    run_test test_instance_pools "instance pools"
     run_test test_server_info "server info"
     run_test test_remote_url "remote url handling"
     run_test test_remote_admin "remote administration"
@@ -261,10 +265,12 @@ if [ "${1:-"all"}" != "cluster" ]; then
     run_test test_projects_profiles_default "profiles from the global default project"
     run_test test_projects_images "images inside projects"
     run_test test_projects_images_default "images from the global default project"
+    run_test test_projects_images_shared "images shared with other projects"
     run_test test_projects_storage "projects and storage pools"
     run_test test_projects_network "projects and networks"
     run_test test_projects_limits "projects limits"
     run_test test_projects_usage "projects usage"
+    run_test test_projects_default_devices "projects device defaults"
     run_test test_projects_restrictions "projects restrictions"
     run_test test_container_devices_disk "container devices - disk"
     run_test test_container_devices_disk_restricted "container devices - disk - restricted"
@@ -295,6 +301,7 @@ if [ "${1:-"all"}" != "cluster" ]; then
     run_test test_image_prefer_cached "image prefer cached"
     run_test test_image_import_dir "import image from directory"
     run_test test_image_refresh "image refresh"
+    run_test test_image_instance_manifest "image instance manifest"
     run_test test_image_acl "image acl"
     run_test test_cloud_init "cloud-init"
     run_test test_exec "exec"
@@ -304,7 +311,9 @@ if [ "${1:-"all"}" != "cluster" ]; then
     run_test test_snapshots "container snapshots"
     run_test test_snap_restore "snapshot restores"
     run_test test_snap_expiry "snapshot expiry"
+    run_test test_snap_retention_policies "snapshot retention policies"
     run_test test_snap_schedule "snapshot scheduling"
+    run_test test_snap_filesystem_freeze "snapshot filesystem freeze"
     run_test test_snap_volume_db_recovery "snapshot volume database record recovery"
     run_test test_config_profiles "profiles and configuration"
     run_test test_config_edit "container configuration edit"
@@ -339,6 +348,7 @@ if [ "${1:-"all"}" != "cluster" ]; then
     run_test test_storage_driver_btrfs "btrfs storage driver"
     run_test test_storage_driver_ceph "ceph storage driver"
     run_test test_storage_driver_cephfs "cephfs storage driver"
+    run_test test_storage_driver_dir "dir storage driver"
     run_test test_storage_driver_zfs "zfs storage driver"
     run_test test_storage_buckets "storage buckets"
     run_test test_storage_volume_import "storage volume import"
@@ -359,6 +369,7 @@ if [ "${1:-"all"}" != "cluster" ]; then
     run_test test_backup_export_import_recover "backup export, import, and recovery"
     run_test test_container_local_cross_pool_handling "container local cross pool handling"
     run_test test_incremental_copy "incremental container copy"
+    run_test test_copy_exclusions "instance copy exclusions"
     run_test test_profiles_project_default "profiles in default project"
     run_test test_profiles_project_images_profiles "profiles in project with images and profiles enabled"
     run_test test_profiles_project_images "profiles in project with images enabled and profiles disabled"
@@ -6,6 +6,143 @@ test_authorization() {
   ### GROUP MANAGEMENT ###
   lxc auth group create test-group
 
+  # Group count honours the filter.
+  [ "$(lxc query "/1.0/auth/groups?count=1")" = "1" ]
+  [ "$(lxc query "/1.0/auth/groups?count=1&filter=name%20eq%20test-group")" = "1" ]
+  [ "$(lxc query "/1.0/auth/groups?count=1&filter=name%20eq%20not-a-group")" = "0" ]
+  [ "$(lxc query "/1.0/auth/groups?filter=name%20eq%20test-group" | jq -r '.[0]')" = "/1.0/auth/groups/test-group" ]
+
+  # Updates without an If-Match header are only refused if core.etag_required_for_auth is enabled.
+  # An If-Match header of "*" only requires the group to exist.
+  [ "$(curl -s -o /dev/null -w "%{http_code}" --unix-socket "${LXD_DIR}/unix.socket" -X PATCH "lxd/1.0/auth/groups/test-group" --data '{"description": "Test"}')" = "200" ]
+  [ "$(curl -s -o /dev/null -w "%{http_code}" --unix-socket "${LXD_DIR}/unix.socket" -X PATCH -H "If-Match: *" "lxd/1.0/auth/groups/test-group" --data '{"description": "Test"}')" = "200" ]
+  lxc config set core.etag_required_for_auth true
+  [ "$(curl -s -o /dev/null -w "%{http_code}" --unix-socket "${LXD_DIR}/unix.socket" -X PATCH "lxd/1.0/auth/groups/test-group" --data '{"description": "Test"}')" = "428" ]
+  [ "$(curl -s -o /dev/null -w "%{http_code}" --unix-socket "${LXD_DIR}/unix.socket" -X PUT "lxd/1.0/auth/groups/test-group" --data '{"description": "Test"}')" = "428" ]
+  [ "$(curl -s -o /dev/null -w "%{http_code}" --unix-socket "${LXD_DIR}/unix.socket" -X PATCH -H "If-Match: *" "lxd/1.0/auth/groups/test-group" --data '{"description": "Test"}')" = "200" ]
+  [ "$(curl -s -o /dev/null -w "%{http_code}" --unix-socket "${LXD_DIR}/unix.socket" -X PUT -H "If-Match: *" "lxd/1.0/auth/groups/test-group" --data '{"description": ""}')" = "200" ]
+  [ "$(curl -s -o /dev/null -w "%{http_code}" --unix-socket "${LXD_DIR}/unix.socket" -X PATCH -H "If-Match: *" "lxd/1.0/auth/groups/not-a-group" --data '{"description": "Test"}')" = "404" ]
+  [ "$(curl -s -o /dev/null -w "%{http_code}" --unix-socket "${LXD_DIR}/unix.socket" -X PATCH -H "If-Match: not-the-etag" "lxd/1.0/auth/groups/test-group" --data '{"description": "Test"}')" = "412" ]
+  lxc config unset core.etag_required_for_auth
+
+  # Groups can't have more permissions than core.auth_groups_max_permissions.
+  lxc config set core.auth_groups_max_permissions 1
+  [ "$(curl -s -o /dev/null -w "%{http_code}" --unix-socket "${LXD_DIR}/unix.socket" -X POST "lxd/1.0/auth/groups" --data '{"name": "test-group-quota", "permissions": [{"entity_type": "server", "url": "/1.0", "entitlement": "viewer"}, {"entity_type": "server", "url": "/1.0", "entitlement": "can_view_resources"}]}')" = "400" ]
+  [ "$(curl -s -o /dev/null -w "%{http_code}" --unix-socket "${LXD_DIR}/unix.socket" -X POST "lxd/1.0/auth/groups/preview" --data '{"name": "test-group-quota", "permissions": [{"entity_type": "server", "url": "/1.0", "entitlement": "viewer"}, {"entity_type": "server", "url": "/1.0", "entitlement": "can_view_resources"}]}')" = "400" ]
+  [ "$(curl -s -o /dev/null -w "%{http_code}" --unix-socket "${LXD_DIR}/unix.socket" -X PUT "lxd/1.0/auth/groups/test-group" --data '{"permissions": [{"entity_type": "server", "url": "/1.0", "entitlement": "viewer"}, {"entity_type": "server", "url": "/1.0", "entitlement": "can_view_resources"}]}')" = "400" ]
+  [ "$(curl -s -o /dev/null -w "%{http_code}" --unix-socket "${LXD_DIR}/unix.socket" -X PUT "lxd/1.0/auth/groups/test-group" --data '{"permissions": [{"entity_type": "server", "url": "/1.0", "entitlement": "viewer"}]}')" = "200" ]
+  [ "$(curl -s -o /dev/null -w "%{http_code}" --unix-socket "${LXD_DIR}/unix.socket" -X PUT "lxd/1.0/auth/groups/test-group" --data '{"description": ""}')" = "200" ]
+  lxc config unset core.auth_groups_max_permissions
+
+  # The created group is returned when requested.
+  [ "$(curl -s --unix-socket "${LXD_DIR}/unix.socket" -X POST -H "Prefer: return=representation" "lxd/1.0/auth/groups" --data '{"name": "test-group-2", "permissions": [{"entity_type": "server", "url": "/1.0", "entitlement": "viewer"}]}' | jq -r '.metadata.permissions[0].entitlement')" = "viewer" ]
+  [ "$(curl -s --unix-socket "${LXD_DIR}/unix.socket" -X POST "lxd/1.0/auth/groups" --data '{"name": "test-group-3"}' | jq -r '.metadata')" = "null" ]
+
+  # The group summary reports the number of permissions and their broadest scope.
+  [ "$(lxc query "/1.0/auth/groups?summary=1&filter=name%20eq%20test-group-2" | jq -r '.[0].permission_count')" = "1" ]
+  [ "$(lxc query "/1.0/auth/groups?summary=1&filter=name%20eq%20test-group-2" | jq -r '.[0].scope')" = "server" ]
+  [ "$(lxc query "/1.0/auth/groups?summary=1&filter=name%20eq%20test-group-3" | jq -r '.[0].scope')" = "none" ]
+  lxc auth group permission add test-group-3 project default viewer
+  [ "$(lxc query "/1.0/auth/groups?summary=1&filter=name%20eq%20test-group-3" | jq -r '.[0].scope')" = "project" ]
+  ! lxc query "/1.0/auth/groups?summary=1&recursion=1" || false
+  lxc auth group delete test-group-2
+  lxc auth group delete test-group-3
+
+  # Creating a group that already exists succeeds only if requested and if its definition is the same.
+  lxc query -X POST /1.0/auth/groups --data '{"name": "test-group-4", "description": "Test", "permissions": [{"entity_type": "project", "url": "/1.0/projects/default", "entitlement": "viewer"}]}'
+  [ "$(curl -s -o /dev/null -w "%{http_code}" --unix-socket "${LXD_DIR}/unix.socket" -X POST "lxd/1.0/auth/groups" --data '{"name": "test-group-4", "description": "Test", "permissions": [{"entity_type": "project", "url": "/1.0/projects/default", "entitlement": "viewer"}]}')" = "409" ]
+  [ "$(curl -s --unix-socket "${LXD_DIR}/unix.socket" -X POST -H "Prefer: handling=lenient" "lxd/1.0/auth/groups" --data '{"name": "test-group-4", "description": "Test", "permissions": [{"entity_type": "project", "url": "/1.0/projects/default", "entitlement": "viewer"}]}' | jq -r '.metadata.name')" = "test-group-4" ]
+  [ "$(curl -s -o /dev/null -w "%{http_code}" --unix-socket "${LXD_DIR}/unix.socket" -X POST "lxd/1.0/auth/groups?if_not_exists=1" --data '{"name": "test-group-4", "description": "Test", "permissions": [{"entity_type": "project", "url": "/1.0/projects/default", "entitlement": "viewer"}]}')" = "200" ]
+  [ "$(curl -s -o /dev/null -w "%{http_code}" --unix-socket "${LXD_DIR}/unix.socket" -X POST "lxd/1.0/auth/groups?if_not_exists=1" --data '{"name": "test-group-4", "description": "Other"}')" = "409" ]
+  [ "$(curl -s -o /dev/null -w "%{http_code}" --unix-socket "${LXD_DIR}/unix.socket" -X POST "lxd/1.0/auth/groups?if_not_exists=1" --data '{"name": "test-group-5"}')" = "200" ]
+  [ "$(lxc query /1.0/auth/groups/test-group-4 | jq -r '.description')" = "Test" ]
+  ! lxc query /1.0/auth/groups/test-group-4%2Frequested || false
+  lxc auth group delete test-group-4
+  lxc auth group delete test-group-5
+
+  # Groups matching a filter can be deleted in bulk, with a dry run listing them first.
+  lxc auth group create bulk-1
+  lxc auth group create bulk-2
+  ! lxc query -X DELETE "/1.0/auth/groups?filter=name%20eq%20bulk-.*" || false # Requires confirmation
+  ! lxc query -X DELETE "/1.0/auth/groups?confirm=1" || false # Requires a filter
+  [ "$(lxc query -X DELETE "/1.0/auth/groups?filter=name%20eq%20bulk-.*&dry_run=1" | jq -c '.deleted')" = '["bulk-1","bulk-2"]' ]
+  lxc auth group show bulk-1
+  [ "$(lxc query -X DELETE "/1.0/auth/groups?filter=name%20eq%20bulk-.*&confirm=1" | jq -c '.deleted')" = '["bulk-1","bulk-2"]' ]
+  ! lxc auth group show bulk-1 || false
+  ! lxc auth group show bulk-2 || false
+
+  # Groups can be imported in bulk in a background operation reporting its progress.
+  ! lxc query -X POST /1.0/auth/groups/import --data '{"groups": [{"name": "import-1"}, {"name": "import"}]}' || false # Reserved name
+  op="$(lxc query -X POST --wait /1.0/auth/groups/import --data '{"groups": [{"name": "import-1"}, {"name": "import-2", "parents": ["import-1"]}]}')"
+  [ "$(echo "${op}" | jq -r '.status')" = "Success" ]
+  [ "$(echo "${op}" | jq -r '.metadata.groups_created')" = "2" ]
+  [ "$(echo "${op}" | jq -r '.metadata.groups_total')" = "2" ]
+  [ "$(lxc query /1.0/auth/groups/import-2 | jq -c '.parents')" = '["import-1"]' ]
+
+  # A failed import keeps the groups created before the failure.
+  ! lxc query -X POST --wait /1.0/auth/groups/import --data '{"groups": [{"name": "import-3"}, {"name": "import-1"}]}' || false
+  lxc auth group show import-3
+  lxc auth group delete import-2
+  lxc auth group delete import-1
+  lxc auth group delete import-3
+  [ "$(lxc query -X DELETE "/1.0/auth/groups?filter=name%20eq%20bulk-.*&confirm=1" | jq -c '.deleted')" = '[]' ]
+
+  # Changes to groups are recorded in the auth audit trail, which can be filtered, paginated and exported as JSON lines.
+  lxc auth group create audit-group
+  lxc auth group permission add audit-group server viewer
+  lxc auth group rename audit-group audit-group-renamed
+  lxc auth group delete audit-group-renamed
+  [ "$(lxc query "/1.0/auth/audit?object-type=group" | jq -c '[.[] | select(.object_name | startswith("audit-group")) | .action]')" = '["created","updated","renamed","deleted"]' ]
+  [ "$(lxc query "/1.0/auth/audit?object-type=group" | jq -r '[.[] | select(.object_name == "audit-group")][1].after.permissions[0].entitlement')" = "viewer" ]
+  [ "$(lxc query "/1.0/auth/audit?object-type=group" | jq -r '[.[] | select(.object_name == "audit-group-renamed")][1].after')" = "null" ]
+  lxc query /1.0/auth/audit | jq -e '[range(1; length) as $i | .[$i].previous_hash == .[$i - 1].hash] | all'
+  [ "$(lxc query "/1.0/auth/audit?limit=1" | jq 'length')" = "1" ]
+  [ "$(lxc query "/1.0/auth/audit?since=2100-01-01T00:00:00Z" | jq 'length')" = "0" ]
+  ! lxc query "/1.0/auth/audit?object-type=not-a-type" || false
+  ! lxc query "/1.0/auth/audit?since=yesterday" || false
+  curl -s --unix-socket "${LXD_DIR}/unix.socket" "lxd/1.0/auth/audit?format=jsonl&object-type=group" | tail -n1 | jq -e '.object_name == "audit-group-renamed" and .action == "deleted"'
+
+  # The authorization model can be exported for OpenFGA and Open Policy Agent.
+  lxc auth group create model-parent
+  lxc auth group permission add model-parent server viewer
+  lxc auth group create model-child
+  lxc query -X PATCH /1.0/auth/groups/model-child --data '{"parents": ["model-parent"]}'
+  lxc query /1.0/auth/model | jq -e '.model | startswith("model\n  schema 1.1\n")'
+  lxc query "/1.0/auth/model?format=openfga" | jq -e '.tuples | any(.user == "group:/1.0/auth/groups/model-parent#member" and .relation == "viewer" and .object == "server:/1.0")'
+  lxc query "/1.0/auth/model?format=openfga" | jq -e '.tuples | any(.user == "group:/1.0/auth/groups/model-child#member" and .relation == "member" and .object == "group:/1.0/auth/groups/model-parent")'
+  lxc query "/1.0/auth/model?format=rego" | jq -e '.policy | startswith("package lxd.authz\n")'
+  [ "$(lxc query "/1.0/auth/model?format=rego" | jq -c '.data.lxd.parents["model-child"]')" = '["model-parent"]' ]
+  ! lxc query "/1.0/auth/model?format=xacml" || false
+  lxc auth group delete model-child
+  lxc auth group delete model-parent
+
+  # Equivalent entity references are normalized and result in a single permission.
+  lxc query -X POST /1.0/auth/groups --data '{"name": "canonical", "permissions": [{"entity_type": "project", "url": "/1.0/projects/default/", "entitlement": "viewer"}, {"entity_type": "project", "url": "/1.0/projects/default?project=foo", "entitlement": "viewer"}, {"entity_type": "server", "url": "/1.0/", "entitlement": "viewer"}, {"entity_type": "server", "url": "/1.0", "entitlement": "viewer"}]}'
+  [ "$(lxc query /1.0/auth/groups/canonical | jq -c '[.permissions[].url] | sort')" = '["/1.0","/1.0/projects/default"]' ]
+  lxc query -X PATCH /1.0/auth/groups/canonical --data '{"permissions": [{"entity_type": "project", "url": "/1.0/projects/default", "entitlement": "viewer"}]}'
+  [ "$(lxc query /1.0/auth/groups/canonical | jq '.permissions | length')" = "2" ]
+  lxc auth group delete canonical
+
+  # A group that has never granted access to a request has a zero last used time.
+  [ "$(lxc query /1.0/auth/groups/test-group | jq -r '.last_used_at')" = "0001-01-01T00:00:00Z" ]
+
+  # Groups are enabled by default and can be disabled and enabled again without losing their definition.
+  [ "$(lxc query /1.0/auth/groups/test-group | jq -r '.enabled')" = "true" ]
+  lxc auth group permission add test-group project default viewer
+  lxc query -X PATCH /1.0/auth/groups/test-group --data '{"enabled": false}'
+  [ "$(lxc query /1.0/auth/groups/test-group | jq -r '.enabled')" = "false" ]
+  [ "$(lxc query /1.0/auth/groups/test-group | jq '.permissions | length')" = "1" ]
+  [ "$(lxc query "/1.0/auth/groups?count=1&filter=enabled%20eq%20false")" = "1" ]
+  [ "$(lxc query "/1.0/auth/groups?recursion=1&filter=enabled%20eq%20true" | jq 'length')" = "0" ]
+  lxc query -X PATCH /1.0/auth/groups/test-group --data '{"description": "Disabled"}'
+  [ "$(lxc query /1.0/auth/groups/test-group | jq -r '.enabled')" = "false" ]
+  lxc query -X PATCH /1.0/auth/groups/test-group --data '{"enabled": true, "description": ""}'
+  [ "$(lxc query "/1.0/auth/groups?recursion=1&filter=enabled%20eq%20true" | jq -r '.[0].name')" = "test-group" ]
+  lxc auth group permission remove test-group project default viewer
+  lxc query -X POST /1.0/auth/groups --data '{"name": "test-group-disabled", "enabled": false}'
+  [ "$(lxc query /1.0/auth/groups/test-group-disabled | jq -r '.enabled')" = "false" ]
+  lxc auth group delete test-group-disabled
+
   # Invalid entity types
   ! lxc auth group permission add test-group not_an_entity_type admin || false
   ! lxc auth group permission add test-group not_an_entity_type not_an_entity_name admin || false
@@ -22,6 +159,12 @@ test_authorization() {
   ! lxc auth group permission add test-group warning fake_name can_view || false # No entitlements defined for warnings (may contain sensitive data, use server level entitlements).
   ! lxc auth group permission add test-group cluster_group fake_name can_view || false # No entitlements defined for cluster groups (use server entitlements).
 
+  # Entitlement descriptions
+  lxc query /1.0/auth/entitlements | jq -e '.[] | select(.entity_type == "instance") | .entitlements | index("can_manage_backups")'
+  lxc query "/1.0/auth/entitlements?recursion=1" | jq -e '.[] | select(.entity_type == "instance") | .entitlements[] | select(.name == "can_manage_backups") | .description != "" and .category == "edit" and .implied_by == ["operator"]'
+  lxc query "/1.0/auth/entitlements?recursion=1" | jq -e '.[] | select(.entity_type == "server") | .entitlements[] | select(.name == "permission_manager") | .category == "delegate"'
+  [ "$(lxc query "/1.0/auth/entitlements?recursion=1" | jq '[.[] | select(.entity_type == "warning") | .entitlements[]] | length')" = "0" ]
+
   # Server permissions
   lxc auth group permission add test-group server admin # Valid
   lxc auth group permission remove test-group server admin # Valid
@@ -48,6 +191,14 @@ test_authorization() {
   lxc auth group permission add test-group instance c1 can_exec project=default # Valid
   lxc auth group permission remove test-group instance c1 can_exec project=default # Valid
   ! lxc auth group permission remove test-group instance c1 can_exec project=default || false # Already removed
+  lxc auth group permission add test-group instance c1 can_exec --project default # Valid (project from flag)
+
+  # Check that the entity references that don't resolve are named in the error, and nothing is written.
+  ! lxc query -X PATCH /1.0/auth/groups/test-group --data '{"permissions": [{"entity_type": "instance", "entity_reference": "/1.0/instances/c1?project=default", "entitlement": "can_view"}, {"entity_type": "instance", "entity_reference": "/1.0/instances/c1?project=foo", "entitlement": "can_view"}]}' 2> "${TEST_DIR}/auth.err" || false
+  grep -F 'No entity found for "/1.0/instances/c1?project=foo" (project "foo")' "${TEST_DIR}/auth.err"
+  ! lxc auth group show test-group | grep -F can_view || false
+  lxc auth group permission remove test-group instance c1 can_exec --project default # Valid (project from flag)
+  ! lxc auth group permission add test-group instance c1 can_exec project=default --project not-found || false # Conflicting projects
   ! lxc auth group permission add test-group instance c1 not_an_instance_entitlement project=default || false # Invalid entitlement
   lxc rm c1
 
@@ -61,6 +212,75 @@ test_authorization() {
   ! lxc auth group permission add test-group network n1 not_a_network_entitlement project=default || false # Invalid entitlement
   lxc network rm n1
 
+  # Permission updates based on a stale permission set are rejected.
+  lxc auth group permission add test-group server viewer
+  ! lxc query -X PUT /1.0/auth/groups/test-group --data '{"permissions": [], "permissions_base": []}' || false
+  curl -s --unix-socket "${LXD_DIR}/unix.socket" -X PATCH "lxd/1.0/auth/groups/test-group" --data '{"permissions": [{"entity_type": "server", "url": "/1.0", "entitlement": "can_view"}], "permissions_base": []}' > "${TEST_DIR}/conflict.json"
+  [ "$(jq -r '.error_code' "${TEST_DIR}/conflict.json")" = "409" ]
+  [ "$(jq -r '.metadata.current_added[0].entitlement' "${TEST_DIR}/conflict.json")" = "viewer" ]
+  [ "$(jq -r '.metadata.requested_added[0].entitlement' "${TEST_DIR}/conflict.json")" = "can_view" ]
+  rm "${TEST_DIR}/conflict.json"
+  [ "$(lxc query /1.0/auth/groups/test-group | jq -r '.permissions | map(.entitlement) | join(",")')" = "viewer" ]
+  lxc query -X PUT /1.0/auth/groups/test-group --data '{"permissions": [], "permissions_base": [{"entity_type": "server", "url": "/1.0", "entitlement": "viewer"}]}'
+  [ "$(lxc query /1.0/auth/groups/test-group | jq '.permissions | length')" = "0" ]
+
+  # Groups inherit the permissions of their ancestors.
+  lxc auth group create test-viewers
+  lxc auth group permission add test-viewers server viewer
+  ! lxc query -X POST /1.0/auth/groups --data '{"name": "test-editors", "parents": ["not-a-group"]}' || false
+  curl -s --unix-socket "${LXD_DIR}/unix.socket" -X POST "lxd/1.0/auth/groups" --data '{"name": "test-editors", "parents": ["test-viewers"]}'
+  lxc query -X PUT /1.0/auth/groups/test-group --data '{"permissions": [], "parents": ["test-editors"]}'
+  [ "$(lxc query /1.0/auth/groups/test-group | jq -r '.parents[0]')" = "test-editors" ]
+  [ "$(lxc query /1.0/auth/groups/test-group | jq '.permissions | length')" = "0" ]
+  [ "$(lxc query /1.0/auth/groups/test-group | jq -r '.inherited_permissions[0].entitlement')" = "viewer" ]
+  [ "$(lxc query /1.0/auth/groups/test-group | jq -r '.inherited_permissions[0].group')" = "test-viewers" ]
+  [ "$(lxc query "/1.0/auth/groups?recursion=1" | jq -r '.[] | select(.name == "test-group") | .inherited_permissions[0].group')" = "test-viewers" ]
+  ! lxc query -X PATCH /1.0/auth/groups/test-viewers --data '{"parents": ["test-group"]}' || false # Cycle
+  ! lxc query -X PATCH /1.0/auth/groups/test-viewers --data '{"parents": ["test-viewers"]}' || false # Self
+  ! lxc query -X PATCH /1.0/auth/groups/test-viewers --data '{"parents": ["not-a-group"]}' || false
+  lxc auth group delete test-editors
+  [ "$(lxc query /1.0/auth/groups/test-group | jq '.parents | length')" = "0" ]
+  [ "$(lxc query /1.0/auth/groups/test-group | jq '.inherited_permissions | length')" = "0" ]
+  lxc auth group delete test-viewers
+
+  # Subtree permissions apply to all storage volumes in a storage pool.
+  pool="$(lxc profile device get default root pool)"
+  lxc query -X PUT /1.0/auth/groups/test-group --data "{\"permissions\": [{\"entity_type\": \"storage_volume\", \"url\": \"/1.0/storage-pools/${pool}\", \"entitlement\": \"can_edit\"}]}"
+  [ "$(lxc query /1.0/auth/groups/test-group | jq -r '.permissions[0].entity_type')" = "storage_volume" ]
+  [ "$(lxc query /1.0/auth/groups/test-group | jq -r '.permissions[0].url')" = "/1.0/storage-pools/${pool}" ]
+  ! lxc query -X PUT /1.0/auth/groups/test-group --data "{\"permissions\": [{\"entity_type\": \"storage_volume\", \"url\": \"/1.0/storage-pools/${pool}\", \"entitlement\": \"can_delete\"}]}" || false # Not valid as a subtree permission
+  ! lxc query -X PUT /1.0/auth/groups/test-group --data "{\"permissions\": [{\"entity_type\": \"instance\", \"url\": \"/1.0/storage-pools/${pool}\", \"entitlement\": \"can_view\"}]}" || false # Instances are not children of storage pools
+
+  # Subtree permissions apply to all instances located on a cluster member.
+  member="none" # The cluster member of a standalone server.
+  lxc query -X PUT /1.0/auth/groups/test-group --data "{\"permissions\": [{\"entity_type\": \"instance\", \"url\": \"/1.0/cluster/members/${member}\", \"entitlement\": \"operator\"}]}"
+  [ "$(lxc query /1.0/auth/groups/test-group | jq -r '.permissions[0].url')" = "/1.0/cluster/members/${member}" ]
+  ! lxc query -X PUT /1.0/auth/groups/test-group --data "{\"permissions\": [{\"entity_type\": \"instance\", \"url\": \"/1.0/cluster/members/${member}\", \"entitlement\": \"can_delete\"}]}" || false # Not valid as a subtree permission
+  ! lxc query -X PUT /1.0/auth/groups/test-group --data "{\"permissions\": [{\"entity_type\": \"instance\", \"url\": \"/1.0/instances/c1?project=default&target=${member}\", \"entitlement\": \"can_view\"}]}" || false # Instance references cannot have a target
+  lxc query -X PUT /1.0/auth/groups/test-group --data '{"permissions": []}'
+
+  # Roles grant a bundle of entitlements on an entity to the groups that reference them.
+  ! lxc query -X POST /1.0/auth/roles --data '{"name": "test-role", "entity_type": "project", "entitlements": ["not_a_project_entitlement"]}' || false
+  ! lxc query -X POST /1.0/auth/roles --data '{"name": "test-role", "entity_type": "not_an_entity_type", "entitlements": ["can_view"]}' || false
+  lxc query -X POST /1.0/auth/roles --data '{"name": "test-role", "entity_type": "project", "entitlements": ["can_view", "can_operate_instances"]}'
+  ! lxc query -X POST /1.0/auth/roles --data '{"name": "test-role", "entity_type": "project", "entitlements": ["can_view"]}' || false # Already exists
+  [ "$(lxc query /1.0/auth/roles | jq -r '.[0]')" = "/1.0/auth/roles/test-role" ]
+  ! lxc query -X PUT /1.0/auth/groups/test-group --data '{"roles": [{"role": "not-a-role", "url": "/1.0/projects/default"}]}' || false
+  ! lxc query -X PUT /1.0/auth/groups/test-group --data '{"roles": [{"role": "test-role", "url": "/1.0"}]}' || false # Wrong entity type
+  lxc query -X PUT /1.0/auth/groups/test-group --data '{"roles": [{"role": "test-role", "url": "/1.0/projects/default"}]}'
+  [ "$(lxc query /1.0/auth/groups/test-group | jq -r '.roles[0].role')" = "test-role" ]
+  [ "$(lxc query /1.0/auth/groups/test-group | jq -r '.roles[0].url')" = "/1.0/projects/default" ]
+  [ "$(lxc query "/1.0/auth/groups?recursion=1" | jq -r '.[] | select(.name == "test-group") | .roles[0].role')" = "test-role" ]
+  [ "$(lxc query /1.0/auth/roles/test-role | jq -r '.used_by[0]')" = "/1.0/auth/groups/test-group" ]
+  lxc query -X PUT /1.0/auth/roles/test-role --data '{"entity_type": "project", "entitlements": ["can_view", "can_view_events", "can_operate_instances"]}'
+  [ "$(lxc query /1.0/auth/roles/test-role | jq '.entitlements | length')" = "3" ]
+  ! lxc query -X PUT /1.0/auth/roles/test-role --data '{"entity_type": "server", "entitlements": ["viewer"]}' || false # Granted to a group
+  ! lxc query -X DELETE /1.0/auth/roles/test-role || false # Granted to a group
+  lxc query -X PATCH /1.0/auth/groups/test-group --data '{"roles": []}'
+  [ "$(lxc query /1.0/auth/groups/test-group | jq '.roles | length')" = "0" ]
+  lxc query -X DELETE /1.0/auth/roles/test-role
+  [ "$(lxc query /1.0/auth/roles | jq 'length')" = "0" ]
+
   ### IDENTITY MANAGEMENT ###
   lxc config trust show "${tls_user_fingerprint}"
   ! lxc auth identity group add "tls/${tls_user_fingerprint}" test-group || false # TLS identities cannot be added to groups (yet).
@@ -75,21 +295,99 @@ test_authorization() {
   ! lxc auth identity group add oidc/test-user@example.com not-found || false # Group not found
   lxc auth identity group add oidc/test-user@example.com test-group # Valid
 
+  # Template groups can't have members, and groups with members can't become templates.
+  lxc query -X POST /1.0/auth/groups --data '{"name": "test-group-template", "template": true}'
+  [ "$(lxc query /1.0/auth/groups/test-group-template | jq -r '.template')" = "true" ]
+  ! lxc auth identity group add oidc/test-user@example.com test-group-template || false
+  ! lxc query -X PATCH /1.0/auth/groups/test-group --data '{"template": true}' || false
+  [ "$(lxc query /1.0/auth/groups/test-group | jq -r '.template')" = "false" ]
+  lxc query -X PATCH /1.0/auth/groups/test-group-template --data '{"description": "Reference group"}'
+  [ "$(lxc query /1.0/auth/groups/test-group-template | jq -r '.template')" = "true" ]
+
   # Check user has been added to the group.
   lxc auth identity list --format csv | grep -Fq 'oidc,OIDC client," ",test-user@example.com,test-group'
 
+  # Check the identities of the group can be filtered.
+  [ "$(lxc query "/1.0/auth/groups/test-group?identity_filter=authentication_method%20eq%20oidc" | jq '.identities | length')" = "1" ]
+  [ "$(lxc query "/1.0/auth/groups/test-group?identity_filter=id%20eq%20test-user.*" | jq -r '.identities[0].id')" = "test-user@example.com" ]
+  [ "$(lxc query "/1.0/auth/groups/test-group?identity_filter=authentication_method%20eq%20tls" | jq '.identities | length')" = "0" ]
+  [ "$(lxc query "/1.0/auth/groups?recursion=1&identity_filter=authentication_method%20eq%20tls" | jq '.[] | select(.name == "test-group") | .identities | length')" = "0" ]
+  ! lxc query "/1.0/auth/groups/test-group?identity_filter=id%20eq" || false
+
+  # Check the effective groups of the identity.
+  [ "$(lxc query /1.0/auth/identities/oidc/test-user@example.com | jq -r '.effective_groups[0].group')" = "test-group" ]
+  [ "$(lxc query /1.0/auth/identities/oidc/test-user@example.com | jq -r '.effective_groups[0].direct')" = "true" ]
+  [ "$(lxc query /1.0/auth/identities/oidc/test-user@example.com | jq '.effective_groups[0].identity_provider_groups | length')" = "0" ]
+  [ "$(lxc query "/1.0/auth/identities?recursion=2" | jq -r '.[] | select(.id == "test-user@example.com") | .effective_groups[0].group')" = "test-group" ]
+
+  # Check identities can be filtered and paginated.
+  [ "$(lxc query "/1.0/auth/identities?recursion=1&filter=authentication_method%20eq%20oidc" | jq -r '.[0].id')" = "test-user@example.com" ]
+  [ "$(lxc query "/1.0/auth/identities?filter=name%20eq%20test-user.*" | jq -r '.[0]')" = "/1.0/auth/identities/oidc/test-user@example.com" ]
+  [ "$(lxc query "/1.0/auth/identities/tls?recursion=1&filter=authentication_method%20eq%20oidc" | jq 'length')" = "0" ]
+  identity_count="$(lxc query "/1.0/auth/identities" | jq 'length')"
+  [ "$(lxc query "/1.0/auth/identities?limit=1" | jq 'length')" = "1" ]
+  [ "$(lxc query "/1.0/auth/identities?offset=1" | jq 'length')" = "$((identity_count - 1))" ]
+  [ "$(lxc query "/1.0/auth/identities?limit=1&offset=1" | jq -r '.[0]')" = "$(lxc query "/1.0/auth/identities" | jq -r '.[1]')" ]
+  ! lxc query "/1.0/auth/identities?limit=-1" || false
+  ! lxc query "/1.0/auth/identities?filter=name%20eq" || false
+
+  # Identities can be disabled without losing their group memberships. They are refused until enabled again.
+  [ "$(lxc query /1.0/auth/identities/oidc/test-user@example.com | jq -r '.enabled')" = "true" ]
+  lxc query oidc:/1.0/instances
+  lxc query -X PATCH /1.0/auth/identities/oidc/test-user@example.com --data '{"enabled": false}'
+  [ "$(lxc query /1.0/auth/identities/oidc/test-user@example.com | jq -r '.enabled')" = "false" ]
+  [ "$(lxc query /1.0/auth/identities/oidc/test-user@example.com | jq -r '.groups[0]')" = "test-group" ]
+  [ "$(lxc query "/1.0/auth/identities?recursion=1&filter=id%20eq%20test-user.*" | jq -r '.[0].disabled_at')" != "null" ]
+  [ "$(lxc query "/1.0/auth/identities?recursion=1&filter=id%20eq%20test-user.*" | jq -r '.[0].disabled_by')" != "" ]
+  [ "$(lxc query "/1.0/auth/identities?recursion=2&filter=id%20eq%20test-user.*" | jq -r '.[0].enabled')" = "false" ]
+  lxc query oidc:/1.0/instances 2>&1 | grep -F "is disabled"
+  lxc query -X PATCH /1.0/auth/identities/oidc/test-user@example.com --data '{"enabled": true}'
+  [ "$(lxc query /1.0/auth/identities/oidc/test-user@example.com | jq -r '.disabled_at')" = "null" ]
+  lxc query oidc:/1.0/instances
+
+  # Disabling the last server administrator (the unrestricted client certificate used by the test suite) is refused.
+  ! lxc query -X PATCH "/1.0/auth/identities/tls/${tls_user_fingerprint}" --data '{"enabled": false}' || false
+  [ "$(lxc query "/1.0/auth/identities/tls/${tls_user_fingerprint}" | jq -r '.enabled')" = "true" ]
+
   ### IDENTITY PROVIDER GROUP MANAGEMENT ###
+  ! lxc auth identity-provider-group create " test-idp-group" || false # Leading whitespace
+  ! lxc query -X POST /1.0/auth/identity-provider-groups -d '{"name": "test\tidp-group"}' || false # Non-printable character
   lxc auth identity-provider-group create test-idp-group
+  ! lxc auth identity-provider-group rename test-idp-group "test-idp-group " || false # Trailing whitespace
   ! lxc auth identity-provider-group group add test-idp-group not-found || false # Group not found
   lxc auth identity-provider-group group add test-idp-group test-group
   lxc auth identity-provider-group group remove test-idp-group test-group
+  ! lxc auth identity-provider-group group add test-idp-group test-group-template || false
+  lxc query -X PATCH /1.0/auth/groups/test-group-template --data '{"template": false}'
+  lxc auth identity-provider-group group add test-idp-group test-group-template
+  lxc auth identity-provider-group group remove test-idp-group test-group-template
+  lxc auth group delete test-group-template
+
+  # A warning is raised while a group granting administrative access is mapped to an identity provider group.
+  lxc auth group create test-admins
+  lxc auth group permission add test-admins server admin
+  lxc auth identity-provider-group group add test-idp-group test-admins
+  [ "$(lxc query "/1.0/warnings?recursion=1" | jq -r '.[] | select(.last_message | contains("\"test-admins\"")) | .status')" = "new" ]
+  lxc query -X PATCH /1.0/auth/groups/test-admins --data '{"enabled": false}' # Disabled groups grant nothing
+  [ "$(lxc query "/1.0/warnings?recursion=1" | jq -r '.[] | select(.last_message | contains("\"test-admins\"")) | .status')" = "resolved" ]
+  lxc query -X PATCH /1.0/auth/groups/test-admins --data '{"enabled": true}'
+  lxc auth identity-provider-group group remove test-idp-group test-admins
+  [ "$(lxc query "/1.0/warnings?recursion=1" | jq -r '.[] | select(.last_message | contains("\"test-admins\"")) | .status')" = "resolved" ]
+
+  # Changes to administrative groups are allowed while another server administrator remains (the unrestricted client
+  # certificate used by the test suite). The lockout check can always be bypassed with force=1.
+  lxc auth identity-provider-group group add test-idp-group test-admins
+  lxc query -X PATCH /1.0/auth/groups/test-admins --data '{"enabled": false}'
+  lxc query -X PATCH "/1.0/auth/groups/test-admins?force=1" --data '{"enabled": true}'
+  lxc auth identity-provider-group group remove test-idp-group test-admins
+  lxc query -X DELETE "/1.0/auth/groups/test-admins?force=1"
   ! lxc auth identity-provider-group group remove test-idp-group test-group || false # Group not mapped
 
   ### PERMISSION INSPECTION ###
   list_output="$(lxc auth permission list --format csv)"
 
   # grep for some easily grepable things.
-  echo "${list_output}" | grep -Fq 'identity,/1.0/auth/identities/oidc/test-user@example.com,"can_delete,can_edit,can_view"'
+  echo "${list_output}" | grep -Fq 'identity,/1.0/auth/identities/oidc/test-user@example.com,"can_delete,can_disable,can_edit,can_view"'
   echo "${list_output}" | grep -Fq 'group,/1.0/auth/groups/test-group,"can_delete,can_edit,can_view"'
   echo "${list_output}" | grep -Fq 'identity_provider_group,/1.0/auth/identity-provider-groups/test-idp-group,"can_delete,can_edit,can_view"'
   echo "${list_output}" | grep -Fq 'image_alias,/1.0/images/aliases/testimage?project=default,"can_delete,can_edit,can_view"'
@@ -97,17 +395,198 @@ test_authorization() {
   echo "${list_output}" | grep -Fq 'project,/1.0/projects/default,"can_create_image_aliases,can_create_images,can_create_instances,..."'
 
   list_output="$(lxc auth permission list entity_type=server --format csv --max-entitlements 0)"
-  echo "${list_output}" | grep -Fq 'server,/1.0,"admin,can_create_groups,can_create_identities,can_create_projects,can_create_storage_pools,can_delete_groups,can_delete_identities,can_delete_projects,can_delete_storage_pools,can_edit,can_edit_groups,can_edit_identities,can_edit_projects,can_edit_storage_pools,can_override_cluster_target_restriction,can_view,can_view_configuration,can_view_groups,can_view_identities,can_view_metrics,can_view_permissions,can_view_privileged_events,can_view_projects,can_view_resources,can_view_warnings,permission_manager,project_manager,storage_pool_manager,viewer"'
+  echo "${list_output}" | grep -Fq 'server,/1.0,"admin,can_create_groups,can_create_identities,can_create_projects,can_create_storage_pools,can_delete_groups,can_delete_identities,can_delete_projects,can_delete_storage_pools,can_edit,can_edit_groups,can_edit_identities,can_edit_projects,can_edit_storage_pools,can_manage_warning_suppressions,can_override_cluster_target_restriction,can_override_immutable,can_view,can_view_configuration,can_view_groups,can_view_identities,can_view_metrics,can_view_permissions,can_view_privileged_events,can_view_projects,can_view_resources,can_view_warnings,permission_manager,project_manager,storage_pool_manager,viewer"'
 
   list_output="$(lxc auth permission list entity_type=project --format csv --max-entitlements 0)"
   echo "${list_output}" | grep -Fq 'project,/1.0/projects/default,"can_create_image_aliases,can_create_images,can_create_instances,can_create_network_acls,can_create_network_zones,can_create_networks,can_create_profiles,can_create_storage_buckets,can_create_storage_volumes,can_delete,can_delete_image_aliases,can_delete_images,can_delete_instances,can_delete_network_acls,can_delete_network_zones,can_delete_networks,can_delete_profiles,can_delete_storage_buckets,can_delete_storage_volumes,can_edit,can_edit_image_aliases,can_edit_images,can_edit_instances,can_edit_network_acls,can_edit_network_zones,can_edit_networks,can_edit_profiles,can_edit_storage_buckets,can_edit_storage_volumes,can_operate_instances,can_view,can_view_events,can_view_image_aliases,can_view_images,can_view_instances,can_view_network_acls,can_view_network_zones,can_view_networks,can_view_operations,can_view_profiles,can_view_storage_buckets,can_view_storage_volumes,image_alias_manager,image_manager,instance_manager,network_acl_manager,network_manager,network_zone_manager,operator,profile_manager,storage_bucket_manager,storage_volume_manager,viewer"'
 
+  # Permissions can be searched and paginated.
+  [ "$(lxc query "/1.0/auth/permissions?entity-type=server&limit=2" | jq 'length')" = "2" ]
+  [ "$(lxc query "/1.0/auth/permissions?entity-type=server&limit=2&offset=1" | jq -r '.[0].entitlement')" = "$(lxc query "/1.0/auth/permissions?entity-type=server&limit=2" | jq -r '.[1].entitlement')" ]
+  [ "$(lxc query "/1.0/auth/permissions?entity-type=profile&search=DEFAULT" | jq -r '[.[].url] | unique | .[]')" = "/1.0/profiles/default?project=default" ]
+  [ "$(lxc query "/1.0/auth/permissions?entity-type=profile&search=not-a-profile" | jq 'length')" = "0" ]
+  ! lxc query "/1.0/auth/permissions?limit=-1" || false
+
+  # Pages can span multiple entity types.
+  [ "$(lxc query "/1.0/auth/permissions?project=default&limit=50&offset=40" | jq -c '.')" = "$(lxc query "/1.0/auth/permissions?project=default" | jq -c '.[40:90]')" ]
+
   # Test max entitlements flag doesn't apply to entitlements that are assigned.
   lxc auth group permission add test-group server viewer
   lxc auth group permission add test-group server project_manager
   list_output="$(lxc auth permission list entity_type=server --format csv)"
   echo "${list_output}" | grep -Fq 'server,/1.0,"project_manager:(test-group),viewer:(test-group),admin,can_create_groups,can_create_identities,..."'
 
+  # The entitlements that can be granted on each entity type are listed.
+  [ "$(lxc query /1.0/auth/entitlements | jq -r '.[] | select(.entity_type == "server") | .entitlements | index("admin") != null')" = "true" ]
+  [ "$(lxc query /1.0/auth/entitlements | jq -r '.[] | select(.entity_type == "instance") | .entitlements | index("admin") != null')" = "false" ]
+  [ "$(lxc query /1.0/auth/entitlements | jq -r '.[] | select(.entity_type == "storage_volume") | .subtree_entitlements.storage_pool | index("can_edit") != null')" = "true" ]
+  [ "$(lxc query /1.0/auth/entitlements | jq -r '.[] | select(.entity_type == "warning") | .entitlements | length')" = "0" ]
+
+  # The groups that have each permission are returned with recursion, also when paginating.
+  [ "$(lxc query "/1.0/auth/permissions?entity-type=server&recursion=1" | jq -r '.[] | select(.entitlement == "viewer") | .groups[0]')" = "test-group" ]
+  [ "$(lxc query "/1.0/auth/permissions?entity-type=server&recursion=1&limit=100" | jq -r '.[] | select(.entitlement == "project_manager") | .groups[0]')" = "test-group" ]
+
+  # Previewing a group returns the permissions that it would grant, including inherited ones, without creating it.
+  preview="$(lxc query -X POST /1.0/auth/groups/preview --data '{"name": "test-preview", "parents": ["test-group"], "permissions": [{"entity_type": "project", "url": "/1.0/projects/default", "entitlement": "can_view"}]}')"
+  [ "$(echo "${preview}" | jq -r '.[] | select(.url == "/1.0/projects/default") | .entitlement')" = "can_view" ]
+  [ "$(echo "${preview}" | jq -r '.[] | select(.url == "/1.0" and .entitlement == "viewer") | .entity_type')" = "server" ]
+  ! lxc auth group show test-preview || false
+  ! lxc query -X POST /1.0/auth/groups/preview --data '{"name": "test-preview", "parents": ["not-found"]}' || false
+  ! lxc query -X POST /1.0/auth/groups/preview --data '{"name": "test-group"}' || false # Already exists
+  ! lxc auth group create preview || false # Reserved name
+
+  # Group names can be made case-insensitive.
+  lxc auth group create Case-Group
+  lxc auth group create case-group # Distinct by default
+  lxc auth group delete case-group
+  lxc config set auth.case_insensitive_group_names=true
+  ! lxc auth group create case-group || false
+  [ "$(curl -s -o /dev/null -w "%{http_code}" --unix-socket "${LXD_DIR}/unix.socket" -X POST "lxd/1.0/auth/groups/preview" --data '{"name": "case-group"}')" = "409" ]
+  [ "$(lxc query /1.0/auth/groups/CASE-GROUP | jq -r '.name')" = "Case-Group" ]
+  lxc auth group create other-group
+  ! lxc auth group rename other-group CASE-group || false
+  lxc auth group rename Case-Group case-group # A group can change the case of its own name
+  lxc auth group delete case-group
+  lxc auth group delete other-group
+  lxc config unset auth.case_insensitive_group_names
+
+  # Diffing two groups splits their permissions into the ones only in either group and the ones in both.
+  lxc auth group create test-diff --description "Diff group"
+  lxc auth group permission add test-diff server viewer
+  lxc auth group permission add test-diff project default can_view
+  diff="$(lxc query "/1.0/auth/groups/test-group/diff?against=test-diff")"
+  [ "$(echo "${diff}" | jq -r '.permissions.in_both[] | select(.entity_type == "server") | .entitlement')" = "viewer" ]
+  [ "$(echo "${diff}" | jq -r '.permissions.only_in_against[] | select(.entity_type == "project") | .entitlement')" = "can_view" ]
+  [ "$(echo "${diff}" | jq -r '.permissions.only_in_group[] | select(.entitlement == "project_manager") | .entity_type')" = "server" ]
+  [ "$(echo "${diff}" | jq -r '.description.against')" = "Diff group" ]
+  [ "$(lxc query "/1.0/auth/groups/test-diff/diff?against=test-diff" | jq -r '.permissions.only_in_group | length')" = "0" ]
+  ! lxc query "/1.0/auth/groups/test-group/diff" || false
+  ! lxc query "/1.0/auth/groups/test-group/diff?against=not-found" || false
+  lxc auth group delete test-diff
+
+  # Immutable instance config keys can only be changed with the can_override_immutable entitlement.
+  lxc query -X POST /1.0/instances --wait --data '{"name": "immutable", "source": {"type": "image", "alias": "testimage"}, "config": {"user.cost_center": "1"}, "immutable_keys": ["user.cost_center"]}'
+  [ "$(lxc config get immutable volatile.immutable_keys)" = "user.cost_center" ]
+  lxc config show immutable | grep -xF '  user.cost_center: "1" # immutable'
+  ! lxc query -X POST /1.0/instances --data '{"name": "immutable2", "source": {"type": "none"}, "immutable_keys": ["volatile.uuid"]}' || false
+  lxc auth group permission add test-group project default can_view_instances
+  lxc auth group permission add test-group project default can_edit_instances
+  ! lxc config set oidc:immutable user.cost_center=2 || false
+  ! lxc config unset oidc:immutable volatile.immutable_keys || false
+  lxc config set oidc:immutable user.other=2
+  lxc auth group permission add test-group server can_override_immutable
+  lxc config set oidc:immutable user.cost_center=2
+  lxc auth group permission remove test-group server can_override_immutable
+  lxc auth group permission remove test-group project default can_edit_instances
+  lxc auth group permission remove test-group project default can_view_instances
+  lxc copy immutable immutable-copy
+  [ "$(lxc config get immutable-copy volatile.immutable_keys)" = "user.cost_center" ]
+  lxc delete immutable immutable-copy
+
+  # Instance files can be read with can_access_files, but can only be changed with can_edit_files.
+  lxc init testimage files
+  echo "hello" > "${TEST_DIR}/files.txt"
+  lxc file push "${TEST_DIR}/files.txt" files/root/files.txt
+  lxc auth group permission add test-group instance files can_view project=default
+  lxc auth group permission add test-group instance files can_access_files project=default
+  lxc file pull oidc:files/root/files.txt - | grep -xF hello
+  ! lxc file push "${TEST_DIR}/files.txt" oidc:files/root/other.txt || false
+  ! lxc file delete oidc:files/root/files.txt || false
+  lxc auth group permission remove test-group instance files can_access_files project=default
+  lxc auth group permission add test-group instance files can_edit_files project=default
+  lxc file push "${TEST_DIR}/files.txt" oidc:files/root/other.txt
+  lxc file pull oidc:files/root/other.txt - | grep -xF hello
+  lxc file delete oidc:files/root/other.txt
+  lxc auth group permission remove test-group instance files can_edit_files project=default
+  lxc auth group permission remove test-group instance files can_view project=default
+  lxc delete files
+  rm "${TEST_DIR}/files.txt"
+
+  # Custom volumes can be accessed over SFTP with can_connect_sftp, and read-only with can_connect_sftp_read_only.
+  lxc storage volume create "${pool}" sftp-vol
+  sftp_path="/1.0/storage-pools/${pool}/volumes/custom/sftp-vol/sftp"
+  lxc auth group permission add test-group storage_volume sftp-vol can_view project=default pool="${pool}" type=custom
+  ! lxc query "oidc:${sftp_path}" 2>&1 | grep -F "upgrade header" || false # Refused by the permission check
+  lxc auth group permission add test-group storage_volume sftp-vol can_connect_sftp_read_only project=default pool="${pool}" type=custom
+  lxc query "oidc:${sftp_path}" 2>&1 | grep -F "upgrade header" # Allowed by the permission check
+
+  if command -v sftp >/dev/null; then
+    sftp_unix="$(pwd)/deps/sftp-upgrade unix:${LXD_DIR}/unix.socket ${sftp_path}"
+    sftp_oidc="$(pwd)/deps/sftp-upgrade https://${LXD_ADDR} ${sftp_path} ${LXD_CONF}/oidctokens/oidc.json"
+    echo "hello" > "${TEST_DIR}/sftp.txt"
+    echo "put ${TEST_DIR}/sftp.txt /sftp.txt" | sftp -b - -D "${sftp_unix}"
+
+    # Read-only connections can read files but not change them.
+    lxc query oidc:/1.0 >/dev/null # Refresh the access token if needed.
+    echo "get /sftp.txt ${TEST_DIR}/sftp-read-only.txt" | sftp -b - -D "${sftp_oidc}"
+    grep -xF hello "${TEST_DIR}/sftp-read-only.txt"
+    ! echo "put ${TEST_DIR}/sftp.txt /other.txt" | sftp -b - -D "${sftp_oidc}" || false
+    ! echo "rm /sftp.txt" | sftp -b - -D "${sftp_oidc}" || false
+
+    # Read-write connections can change files.
+    lxc auth group permission add test-group storage_volume sftp-vol can_connect_sftp project=default pool="${pool}" type=custom
+    echo "put ${TEST_DIR}/sftp.txt /other.txt" | sftp -b - -D "${sftp_oidc}"
+    echo "rm /other.txt" | sftp -b - -D "${sftp_oidc}"
+    lxc auth group permission remove test-group storage_volume sftp-vol can_connect_sftp project=default pool="${pool}" type=custom
+
+    # Concurrent sessions keep the volume mounted until the last of them has ended.
+    mkfifo "${TEST_DIR}/sftp.fifo"
+    sftp -b "${TEST_DIR}/sftp.fifo" -D "${sftp_unix}" > "${TEST_DIR}/sftp.out" &
+    sftp_pid=$!
+    exec 9>"${TEST_DIR}/sftp.fifo"
+    echo "ls /" >&9
+    for _ in $(seq 50); do
+      grep -qF sftp.txt "${TEST_DIR}/sftp.out" && break
+      sleep 0.1
+    done
+
+    grep -qF sftp.txt "${TEST_DIR}/sftp.out"
+    echo "ls /" | sftp -b - -D "${sftp_unix}" | grep -F sftp.txt
+    echo "get /sftp.txt ${TEST_DIR}/sftp-concurrent.txt" >&9
+    exec 9>&-
+    wait "${sftp_pid}"
+    grep -xF hello "${TEST_DIR}/sftp-concurrent.txt"
+
+    rm "${TEST_DIR}/sftp.fifo" "${TEST_DIR}/sftp.out" "${TEST_DIR}/sftp.txt" "${TEST_DIR}/sftp-read-only.txt" "${TEST_DIR}/sftp-concurrent.txt"
+  else
+    echo "==> SKIP: SFTP sessions of storage volumes (missing sftp client)"
+  fi
+
+  lxc auth group permission remove test-group storage_volume sftp-vol can_connect_sftp_read_only project=default pool="${pool}" type=custom
+  lxc auth group permission remove test-group storage_volume sftp-vol can_view project=default pool="${pool}" type=custom
+
+  # A subtree permission on a storage pool lets group members list the custom volumes of the pool.
+  [ "$(lxc query "oidc:/1.0/storage-pools/${pool}/volumes/custom" | jq -r '.[]')" = "" ]
+  ! lxc query "oidc:/1.0/storage-pools/${pool}/volumes/custom/sftp-vol" || false
+  lxc query -X PATCH /1.0/auth/groups/test-group --data "{\"permissions\": [{\"entity_type\": \"storage_volume\", \"url\": \"/1.0/storage-pools/${pool}\", \"entitlement\": \"can_view\"}]}"
+  [ "$(lxc query "oidc:/1.0/storage-pools/${pool}/volumes/custom" | jq -r '.[]')" = "/1.0/storage-pools/${pool}/volumes/custom/sftp-vol" ]
+  [ "$(lxc query "oidc:/1.0/storage-pools/${pool}/volumes/custom/sftp-vol" | jq -r '.name')" = "sftp-vol" ]
+  ! lxc query -X PATCH "oidc:/1.0/storage-pools/${pool}/volumes/custom/sftp-vol" --data '{"config": {"user.foo": "bar"}}' || false
+  lxc query -X PUT /1.0/auth/groups/test-group --data "$(lxc query /1.0/auth/groups/test-group | jq -c --arg url "/1.0/storage-pools/${pool}" '{description, permissions: [.permissions[] | select(.url != $url)]}')"
+  [ "$(lxc query "oidc:/1.0/storage-pools/${pool}/volumes/custom" | jq -r '.[]')" = "" ]
+  lxc storage volume delete "${pool}" sftp-vol
+
+  # Rebuilding the entity URLs of permissions removes those of entities that no longer exist and reports unfixable ones.
+  lxd sql global "INSERT INTO permissions (entitlement, entity_type, entity_id) VALUES ('can_view', 3, 1000000), ('can_view', 9999, 1)"
+  lxd sql global "INSERT INTO auth_groups_permissions (auth_group_id, permission_id) SELECT auth_groups.id, permissions.id FROM auth_groups, permissions WHERE auth_groups.name = 'test-group' AND permissions.entity_id = 1000000"
+  ! lxc auth group show test-group || false
+
+  # The dangling permission is reported when listing groups rather than failing the whole listing.
+  lxc query "/1.0/auth/groups?recursion=1" > "${TEST_DIR}/groups.json"
+  [ "$(jq -r '.[] | select(.name == "test-group") | .permissions[] | select(.error != null) | .url' "${TEST_DIR}/groups.json")" = "" ]
+  [ "$(jq -r '[.[] | select(.name == "test-group") | .permissions[] | select(.error != null)] | length' "${TEST_DIR}/groups.json")" = "1" ]
+  rm "${TEST_DIR}/groups.json"
+
+  lxc query -X POST /internal/auth/rebuild-entity-urls > "${TEST_DIR}/rebuild.json"
+  [ "$(jq -r '.removed | length' "${TEST_DIR}/rebuild.json")" = "1" ]
+  [ "$(jq -r '.removed[0].entity_id' "${TEST_DIR}/rebuild.json")" = "1000000" ]
+  [ "$(jq -r '.unfixable | length' "${TEST_DIR}/rebuild.json")" = "1" ]
+  [ "$(jq -r '.unfixable[0].entity_type' "${TEST_DIR}/rebuild.json")" = "9999" ]
+  lxc auth group show test-group
+  ! lxc query -X POST oidc:/internal/auth/rebuild-entity-urls || false
+  lxd sql global "DELETE FROM permissions WHERE entity_type = 9999"
+  rm "${TEST_DIR}/rebuild.json"
+
   # Cleanup
   lxc auth group delete test-group
   lxc auth identity-provider-group delete test-idp-group
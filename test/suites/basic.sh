@@ -32,6 +32,23 @@ test_basic_usage() {
   lxc image alias list | grep -qv foo  # the old name is gone
   lxc image alias delete bar
 
+  # Test alias pinning
+  lxc image alias create foo "${sum}" --pin --auto-update=false
+  [ "$(lxc query /1.0/images/aliases/foo | jq -r '.pinned_fingerprint')" = "${sum}" ]
+  [ "$(lxc query /1.0/images/aliases/foo | jq -r '.auto_update')" = "false" ]
+  lxc image alias list local: foo --format csv | grep -q ",yes,"
+  lxc image list --format json | jq -e --arg sum "${sum}" '.[]|select(.fingerprint==$sum)|.aliases[]|select(.name=="foo" and .pinned_fingerprint==$sum)'
+  lxc image alias unpin foo --auto-update=true
+  [ "$(lxc query /1.0/images/aliases/foo | jq -r '.pinned_fingerprint')" = "" ]
+  [ "$(lxc query /1.0/images/aliases/foo | jq -r '.auto_update')" = "true" ]
+  lxc image alias pin foo
+  [ "$(lxc query /1.0/images/aliases/foo | jq -r '.pinned_fingerprint')" = "${sum}" ]
+  lxc query -X PATCH /1.0/images/aliases/foo -d '{"pinned_fingerprint": "", "auto_update": null}'
+  [ "$(lxc query /1.0/images/aliases/foo | jq -r '.auto_update')" = "null" ]
+  ! lxc query -X PATCH /1.0/images/aliases/foo -d '{"target": "'"${sum}"'", "pinned_fingerprint": "0000"}' || false
+  ! lxc image alias create bar "${sum}" --auto-update=maybe || false
+  lxc image alias delete foo
+
   # Test image list output formats (table & json)
   lxc image list --format table | grep -q testimage
   lxc image list --format json \
@@ -64,6 +81,49 @@ test_basic_usage() {
   [ "${sum}" = "$(sha256sum "${LXD_DIR}/foo.tar.xz" | cut -d' ' -f1)" ]
   rm "${LXD_DIR}/foo.tar.xz"
 
+  # Test image export with another compression algorithm
+  lxc image export testimage "${LXD_DIR}/" --compression=gzip --compression-level=9
+  lxc image export testimage "${LXD_DIR}/" --compression=none
+  [ "$(gzip -dc "${LXD_DIR}/${sum}.tar.gz" | sha256sum)" = "$(sha256sum < "${LXD_DIR}/${sum}.tar")" ]
+  lxc image export testimage "${LXD_DIR}/"
+  [ "$(xz -dc "${LXD_DIR}/${sum}.tar.xz" | sha256sum)" = "$(sha256sum < "${LXD_DIR}/${sum}.tar")" ]
+  rm "${LXD_DIR}/${sum}.tar.gz" "${LXD_DIR}/${sum}.tar" "${LXD_DIR}/${sum}.tar.xz"
+
+  # Tarballs that already use the algorithm are sent as they are
+  lxc image export testimage "${LXD_DIR}/foo" --compression=xz
+  [ "${sum}" = "$(sha256sum "${LXD_DIR}/foo.tar.xz" | cut -d' ' -f1)" ]
+  rm "${LXD_DIR}/foo.tar.xz"
+
+  ! lxc image export testimage "${LXD_DIR}/" --compression=bzip2 || false
+  ! lxc image export testimage "${LXD_DIR}/" --compression=none --compression-level=1 || false
+  ! lxc image export testimage "${LXD_DIR}/" --compression=gzip --compression-level=42 || false
+
+  # Test image export as a delta against a previous version of the image
+  if command -v xdelta3 >/dev/null; then
+    deps/import-busybox --alias deltaimage --template start
+    delta_sum="$(lxc image info deltaimage | awk '/^Fingerprint/ {print $2}')"
+
+    curl -sf --unix-socket "${LXD_DIR}/unix.socket" -D "${LXD_DIR}/headers" -o "${LXD_DIR}/delta" "lxd/1.0/images/${sum}/export?delta_sources=${delta_sum}"
+    grep -qi "^X-LXD-Delta-Source: ${delta_sum}" "${LXD_DIR}/headers"
+    xdelta3 -d -s "${LXD_DIR}/images/${delta_sum}" "${LXD_DIR}/delta" "${LXD_DIR}/patched"
+    [ "${sum}" = "$(sha256sum "${LXD_DIR}/patched" | cut -d' ' -f1)" ]
+    rm "${LXD_DIR}/delta" "${LXD_DIR}/patched"
+
+    # The full image is sent when delta transfers are disabled or the delta source is unknown
+    lxc config set images.delta_transfer false
+    curl -sf --unix-socket "${LXD_DIR}/unix.socket" -D "${LXD_DIR}/headers" -o "${LXD_DIR}/full" "lxd/1.0/images/${sum}/export?delta_sources=${delta_sum}"
+    ! grep -qi "^X-LXD-Delta-Source" "${LXD_DIR}/headers" || false
+    [ "${sum}" = "$(sha256sum "${LXD_DIR}/full" | cut -d' ' -f1)" ]
+    lxc config unset images.delta_transfer
+
+    curl -sf --unix-socket "${LXD_DIR}/unix.socket" -D "${LXD_DIR}/headers" -o "${LXD_DIR}/full" "lxd/1.0/images/${sum}/export?delta_sources=$(printf '0%.0s' $(seq 64))"
+    ! grep -qi "^X-LXD-Delta-Source" "${LXD_DIR}/headers" || false
+    [ "${sum}" = "$(sha256sum "${LXD_DIR}/full" | cut -d' ' -f1)" ]
+
+    rm "${LXD_DIR}/headers" "${LXD_DIR}/full"
+    lxc image delete deltaimage
+  fi
+
 
   # Test image export with a split image.
   deps/import-busybox --split --alias splitimage
@@ -197,6 +257,12 @@ test_basic_usage() {
   lxc publish bar --alias=foo-image-compressed --compression="gzip --rsyncable" prop=val1
   lxc image delete foo-image-compressed
 
+  # Test compression levels
+  lxc publish bar --alias=foo-image-compressed --compression=zstd --compression-level=19 prop=val1
+  lxc image delete foo-image-compressed
+  ! lxc publish bar --alias=foo-image-compressed --compression=bzip2 --compression-level=9 prop=val1 || false
+  ! lxc publish bar --alias=foo-image-compressed --compression=gzip --compression-level=42 prop=val1 || false
+
   # Test privileged container publish
   lxc profile create priv
   lxc profile set priv security.privileged true
@@ -295,6 +361,15 @@ test_basic_usage() {
   [ "$(my_curl "https://${LXD_ADDR}/1.0/containers/configtest" | jq -r .metadata.config[\"raw.lxc\"])" = "lxc.hook.clone=/bin/true" ]
   lxc delete configtest
 
+  # Test boot dependency validation
+  lxc init testimage bootafter1
+  lxc init testimage bootafter2 -c boot.after=bootafter1 -c boot.after.ready=network:5432
+  ! lxc config set bootafter1 boot.after=bootafter2 || false
+  ! lxc config set bootafter1 boot.after=bootafter1 || false
+  ! lxc config set bootafter2 boot.after.ready=foo || false
+  lxc config set bootafter2 boot.after.ready=agent
+  lxc delete bootafter1 bootafter2
+
   # Test activateifneeded/shutdown
   LXD_ACTIVATION_DIR=$(mktemp -d -p "${TEST_DIR}" XXX)
   chmod +x "${LXD_ACTIVATION_DIR}"
@@ -344,6 +419,16 @@ test_basic_usage() {
 
     lxc config unset autostart snapshots.schedule --force-local
 
+    # Check for scheduled instance starts
+    lxc config set autostart boot.schedule.start "0 6 * * *" --force-local
+    shutdown_lxd "${LXD_DIR}"
+    lxd activateifneeded --debug 2>&1 | grep -qF "Daemon has scheduled instance starts, activating..."
+
+    # shellcheck disable=SC2031
+    respawn_lxd "${LXD_DIR}" true
+
+    lxc config unset autostart boot.schedule.start --force-local
+
     # Check for scheduled volume snapshots
     storage_pool="lxdtest-$(basename "${LXD_DIR}")"
 
@@ -401,6 +486,19 @@ test_basic_usage() {
   lxc list last-used-at-test  --format json | jq -r '.[].last_used_at' | grep -v '1970-01-01T00:00:00Z'
   lxc delete last-used-at-test --force
 
+  # Test image architecture and personality are exposed and architectures are checked on create
+  lxc init testimage arch-test
+  [ "$(lxc query /1.0/instances/arch-test | jq -r .image_architecture)" = "$(lxc query /1.0/instances/arch-test | jq -r .architecture)" ]
+  [ "$(lxc config get arch-test volatile.base_image.architecture)" = "$(lxc query /1.0/instances/arch-test | jq -r .architecture)" ]
+  lxc query /1.0/instances/arch-test | jq -r .personality | grep -xE 'linux(32|64)'
+  lxc delete arch-test
+
+  if [ "$(uname -m)" = "x86_64" ]; then
+    lxc query -X POST /1.0/instances --data '{"name": "arch-test", "source": {"type": "none"}, "architecture": "aarch64"}' 2>&1 | grep -qF "Requested architecture \"aarch64\" isn't supported by this host for container instances"
+    lxc query -X POST /1.0/instances --data '{"name": "arch-test", "type": "virtual-machine", "source": {"type": "none"}, "architecture": "i686"}' 2>&1 | grep -qF "Requested architecture \"i686\" isn't supported by this host for virtual-machine instances (supported: x86_64)"
+    ! lxc info arch-test || false
+  fi
+
   # Test user, group and cwd
   lxc exec foo -- mkdir /blah
   [ "$(lxc exec foo --user 1000 -- id -u)" = "1000" ] || false
@@ -521,6 +619,16 @@ test_basic_usage() {
   [ "$(lxc config get configtest boot.host_shutdown_timeout)" -eq 45 ]
   lxc config set configtest boot.host_shutdown_timeout 15
   [ "$(lxc config get configtest boot.host_shutdown_timeout)" -eq 15 ]
+
+  # Test boot.schedule.start and boot.schedule.stop config settings
+  lxc config set configtest boot.schedule.start "0 8 * * 1-5" boot.schedule.stop "0 18 * * 1-5"
+  [ "$(lxc config get configtest boot.schedule.start)" = "0 8 * * 1-5" ]
+  [ "$(lxc config get configtest boot.schedule.stop)" = "0 18 * * 1-5" ]
+  lxc config set configtest boot.schedule.start "@daily, @weekly"
+  ! lxc config set configtest boot.schedule.start "not a cron" || false
+  ! lxc config set configtest boot.schedule.stop "@startup" || false
+  lxc config unset configtest boot.schedule.start
+  lxc config unset configtest boot.schedule.stop
   lxc delete configtest
 
   # Test deleting multiple images
@@ -636,7 +744,17 @@ test_basic_usage() {
   # Test a forced rebuild
   lxc launch testimage c1
   ! lxc rebuild testimage c1 || false
+  hwaddr="$(lxc config get c1 volatile.eth0.hwaddr)"
+  uuid="$(lxc config get c1 volatile.uuid)"
   lxc rebuild testimage c1 --force
+  [ "$(lxc list -f csv -c s c1)" = "RUNNING" ]
+  [ "$(lxc config get c1 volatile.eth0.hwaddr)" = "${hwaddr}" ]
+  [ "$(lxc config get c1 volatile.uuid)" = "${uuid}" ]
+
+  # Test a forced rebuild through the API, which stops and starts the instance itself.
+  fingerprint="$(lxc config get c1 volatile.base_image)"
+  lxc query --wait -X POST /1.0/instances/c1/rebuild --data "{\"source\": {\"type\": \"image\", \"fingerprint\": \"${fingerprint}\"}, \"force\": true}"
+  [ "$(lxc list -f csv -c s c1)" = "RUNNING" ]
   lxc delete c1 -f
 
   # Test rebuilding an instance with a new image.
@@ -674,3 +792,71 @@ test_server_info() {
   # Ensure server always reports support for containers.
   lxc query /1.0 | jq -e '.environment.instance_types | contains(["container"])'
 }
+
+test_basic_freeze_timeout() {
+  ensure_import_testimage
+
+  lxc launch testimage c1
+
+  stdbuf -oL lxc monitor --type=lifecycle > "${TEST_DIR}/freeze-timeout.log" &
+  monitorPID=$!
+
+  # Check an instance frozen without a timeout stays frozen.
+  lxc pause c1
+  sleep 2
+  [ "$(lxc list -c s --format csv c1)" = "FROZEN" ]
+  lxc start c1
+  [ "$(lxc list -c s --format csv c1)" = "RUNNING" ]
+
+  # Check an instance frozen with a timeout is unfrozen automatically, with a lifecycle event and a warning.
+  lxc pause c1 --timeout 2
+  [ "$(lxc list -c s --format csv c1)" = "FROZEN" ]
+  sleep 4
+  [ "$(lxc list -c s --format csv c1)" = "RUNNING" ]
+  grep -F "instance-resumed" "${TEST_DIR}/freeze-timeout.log"
+  grep -F "freeze_timeout: true" "${TEST_DIR}/freeze-timeout.log"
+  lxc warning list | grep -F "Frozen instance unfrozen after timeout"
+
+  # Check unfreezing the instance cancels the timeout, and so does freezing it again without one.
+  lxc query -X PUT --wait /1.0/instances/c1/state -d '{"action": "freeze", "timeout": 2}'
+  lxc start c1
+  lxc query -X PUT --wait /1.0/instances/c1/state -d '{"action": "freeze", "timeout": 2}'
+  lxc start c1
+  lxc pause c1
+  sleep 4
+  [ "$(lxc list -c s --format csv c1)" = "FROZEN" ]
+
+  kill -9 "${monitorPID}" || true
+  lxc delete -f c1
+  lxc query --wait /1.0/warnings\?recursion=1 | jq -r '.[] | select(.type == "Frozen instance unfrozen after timeout") | .uuid' | xargs -rn1 lxc warning delete
+}
+
+test_basic_events_aggregation() {
+  ensure_import_testimage
+
+  lxc init testimage c1
+  lxc init testimage c2
+  lxc init testimage c3
+
+  stdbuf -oL lxc monitor --type=lifecycle --format=json > "${TEST_DIR}/events-aggregation.log" &
+  monitorPID=$!
+
+  # Check the events of a bulk operation beyond the threshold are aggregated into a single event.
+  lxc config set core.events_aggregation_threshold 1
+  lxc start --all
+  sleep 1
+  [ "$(jq -c 'select(.metadata.action == "instance-started" and .metadata.aggregate == null)' "${TEST_DIR}/events-aggregation.log" | wc -l)" = "1" ]
+  [ "$(jq -r 'select(.metadata.action == "instance-started" and .metadata.aggregate != null) | .metadata.aggregate.count' "${TEST_DIR}/events-aggregation.log")" = "2" ]
+  jq -r 'select(.metadata.aggregate != null) | .metadata.aggregate.operation' "${TEST_DIR}/events-aggregation.log" | grep -F "/1.0/operations/"
+
+  # Check all the events are sent when the aggregation is disabled.
+  lxc config set core.events_aggregation_threshold 0
+  lxc stop --all --force
+  sleep 1
+  [ "$(jq -c 'select(.metadata.action == "instance-stopped" and .metadata.aggregate == null)' "${TEST_DIR}/events-aggregation.log" | wc -l)" = "3" ]
+  [ "$(jq -c 'select(.metadata.action == "instance-stopped" and .metadata.aggregate != null)' "${TEST_DIR}/events-aggregation.log" | wc -l)" = "0" ]
+
+  kill -9 "${monitorPID}" || true
+  lxc config unset core.events_aggregation_threshold
+  lxc delete -f c1 c2 c3
+}
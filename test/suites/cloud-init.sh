@@ -27,4 +27,38 @@ test_cloud_init() {
   [ -n "${ID6}" ] && [ "${ID6}" != "${ID5}" ]
 
   lxc delete -f c1 c2
+
+  # The status of instances without cloud-init is reported as not available.
+  lxc init testimage c1
+  ! lxc cloud-init status c1 || false
+  lxc start c1
+  lxc cloud-init status c1 | grep -xF "status: not-available"
+  lxc cloud-init status c1 --wait --timeout=30 | grep -xF "status: not-available"
+  [ "$(lxc query "/1.0/instances/c1/cloud-init" | jq -r .status)" = "not-available" ]
+  lxc delete -f c1
+
+  # The network configuration can be generated from the NICs.
+  lxc network create "lxdt$$" ipv4.address=192.0.2.1/24 ipv6.address=none dns.domain=example.net
+  lxc init testimage c1 -c cloud-init.network-config=auto
+  lxc config device add c1 eth0 nic network="lxdt$$" name=eth0
+  HWADDR="$(lxc config get c1 volatile.eth0.hwaddr)"
+  lxc cloud-init network-config c1 | grep -xF "      macaddress: ${HWADDR}"
+  lxc cloud-init network-config c1 | grep -xF "    dhcp4: true"
+  [ "$(lxc query "/1.0/instances/c1/cloud-init/network-config" | jq -r .active)" = "true" ]
+
+  # Static addresses come with the network's gateway and DNS, and changing the NICs resets the instance ID.
+  ID1=$(lxc config get c1 volatile.cloud-init.instance-id)
+  lxc config device set c1 eth0 ipv4.address=192.0.2.10
+  ID2=$(lxc config get c1 volatile.cloud-init.instance-id)
+  [ -n "${ID2}" ] && [ "${ID2}" != "${ID1}" ]
+  lxc cloud-init network-config c1 | grep -xF -- "    - 192.0.2.10/24"
+  lxc cloud-init network-config c1 | grep -xF "      via: 192.0.2.1"
+  lxc cloud-init network-config c1 | grep -xF -- "      - example.net"
+  ! lxc cloud-init network-config c1 | grep -F "dhcp4" || false
+
+  # User provided configuration takes precedence.
+  lxc config set c1 user.network-config="version: 2"
+  [ "$(lxc query "/1.0/instances/c1/cloud-init/network-config" | jq -r .active)" = "false" ]
+  lxc delete -f c1
+  lxc network delete "lxdt$$"
 }
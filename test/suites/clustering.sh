@@ -263,6 +263,24 @@ test_clustering_membership() {
   # Disable image replication
   LXD_DIR="${LXD_ONE_DIR}" lxc config set cluster.images_minimal_replica 1
 
+  # Database maintenance can be requested from any member while all members are online.
+  [ "$(LXD_DIR="${LXD_TWO_DIR}" lxc query --wait -X POST -d '{"action":"checkpoint"}' /1.0/cluster/database | jq '.metadata.disk_size_after > 0')" = "true" ]
+
+  # GET requests accepting stale data are served from the local copy of the global database of non-leader members.
+  [ "$(curl -s -o /dev/null -w "%{http_code}" --unix-socket "${LXD_TWO_DIR}/unix.socket" -H "X-LXD-allow-stale: foo" "lxd/1.0/profiles")" = "400" ]
+  ! curl -s -o /dev/null -D - --unix-socket "${LXD_TWO_DIR}/unix.socket" "lxd/1.0/profiles" | grep -i "^X-LXD-staleness:" || false
+  for _ in $(seq 10); do
+    # The local copy is refreshed in the background once requests accept stale data.
+    curl -s -o /dev/null -D - --unix-socket "${LXD_TWO_DIR}/unix.socket" -H "X-LXD-allow-stale: 1h" "lxd/1.0/profiles" | grep -i "^X-LXD-staleness:" && break
+    sleep 1
+  done
+
+  curl -s -o /dev/null -D - --unix-socket "${LXD_TWO_DIR}/unix.socket" -H "X-LXD-allow-stale: 1h" "lxd/1.0/profiles" | grep -i "^X-LXD-staleness:"
+  [ "$(curl -s --unix-socket "${LXD_TWO_DIR}/unix.socket" -H "X-LXD-allow-stale: 1h" "lxd/1.0/profiles" | jq -c '.metadata')" = "$(LXD_DIR="${LXD_TWO_DIR}" lxc query /1.0/profiles | jq -c '.')" ]
+  ! curl -s -o /dev/null -D - --unix-socket "${LXD_TWO_DIR}/unix.socket" -H "X-LXD-allow-stale: 0s" "lxd/1.0/profiles" | grep -i "^X-LXD-staleness:" || false
+  ! curl -s -o /dev/null -D - --unix-socket "${LXD_TWO_DIR}/unix.socket" -H "X-LXD-allow-stale: 1h" "lxd/1.0/operations/foo" | grep -i "^X-LXD-staleness:" || false # Excluded endpoint
+  ! curl -s -o /dev/null -D - --unix-socket "${LXD_ONE_DIR}/unix.socket" -H "X-LXD-allow-stale: 1h" "lxd/1.0/profiles" | grep -i "^X-LXD-staleness:" || false # Leader
+
   # Shutdown a database node, and wait a few seconds so it will be
   # detected as down.
   LXD_DIR="${LXD_ONE_DIR}" lxc config set cluster.offline_threshold 11
@@ -271,6 +289,11 @@ test_clustering_membership() {
   LXD_DIR="${LXD_TWO_DIR}" lxc cluster list
   LXD_DIR="${LXD_TWO_DIR}" lxc cluster show node3 | grep -q "status: Offline"
 
+  # Database maintenance is refused while a member is offline.
+  ! LXD_DIR="${LXD_TWO_DIR}" lxc query --wait -X POST -d '{"action":"vacuum"}' /1.0/cluster/database 2> "${TEST_DIR}/maintenance.err" || false
+  grep -F "while cluster members are offline:" "${TEST_DIR}/maintenance.err" | grep -wF node3
+  rm "${TEST_DIR}/maintenance.err"
+
   # Gracefully remove a node and check trust certificate is removed.
   LXD_DIR="${LXD_ONE_DIR}" lxc cluster list | grep node4
   LXD_DIR="${LXD_ONE_DIR}" lxd sql global 'SELECT name FROM identities WHERE type = 3' | grep node4
@@ -1203,11 +1226,20 @@ test_clustering_network() {
   ! LXD_DIR="${LXD_ONE_DIR}" lxc config device set c2 eth0 ipv4.address=192.0.2.2 || false
   ! LXD_DIR="${LXD_ONE_DIR}" lxc config device set c2 eth0 ipv6.address=2001:db8::2 || false
 
-  # Check duplicate static DHCP allocation is allowed for instance on a different server.
+  # Check duplicate static DHCP allocation detection is working for instance on a different server.
   LXD_DIR="${LXD_ONE_DIR}" lxc init --target node2 -n "${net}" testimage c3
+  ! LXD_DIR="${LXD_ONE_DIR}" lxc config device set c3 eth0 ipv4.address=192.0.2.2 || false
+  ! LXD_DIR="${LXD_ONE_DIR}" lxc config device set c3 eth0 ipv6.address=2001:db8::2 || false
+
+  # Check duplicate static DHCP allocation is allowed when requested.
+  LXD_DIR="${LXD_ONE_DIR}" lxc config device set c3 eth0 allow_duplicate_address=true
   LXD_DIR="${LXD_ONE_DIR}" lxc config device set c3 eth0 ipv4.address=192.0.2.2
   LXD_DIR="${LXD_ONE_DIR}" lxc config device set c3 eth0 ipv6.address=2001:db8::2
 
+  # Check the static addresses are reported without conflicts as duplicates are allowed.
+  [ "$(LXD_DIR="${LXD_ONE_DIR}" lxc query "/1.0/networks/${net}/static-addresses" | jq -r '[.[] | select(.address == "192.0.2.2")] | length')" = "2" ]
+  [ "$(LXD_DIR="${LXD_ONE_DIR}" lxc query "/1.0/networks/${net}/static-addresses" | jq -r '[.[].conflicts[]] | length')" = "0" ]
+
   # Check duplicate MAC address assignment detection is working using both network and parent keys.
   c1MAC=$(LXD_DIR="${LXD_ONE_DIR}" lxc config get c1 volatile.eth0.hwaddr)
   ! LXD_DIR="${LXD_ONE_DIR}" lxc config device set c2 eth0 hwaddr="${c1MAC}" || false
@@ -1720,6 +1752,18 @@ test_clustering_join_api() {
   ns2="${prefix}2"
   LXD_NETNS="${ns2}" spawn_lxd "${LXD_TWO_DIR}" false
 
+  # Validating a join reports all failed checks and doesn't consume the join token.
+  token="$(LXD_DIR="${LXD_ONE_DIR}" lxc cluster add node2 --quiet)"
+  failures=$(LXD_DIR="${LXD_ONE_DIR}" lxc query -X POST "/1.0/cluster/members?validate-only=true" --data "{\"join_token\":\"${token}\",\"server_address\":\"10.1.1.101:8443\",\"schema\":0,\"api_extensions\":0,\"time\":\"2000-01-01T00:00:00Z\"}")
+  echo "${failures}" | jq -e '.failures | map(.check) | index("address") != null'
+  echo "${failures}" | jq -e '.failures | map(.check) | index("version") != null'
+  echo "${failures}" | jq -e '.failures | map(.check) | index("time") != null'
+  LXD_DIR="${LXD_ONE_DIR}" lxc query -X POST "/1.0/cluster/members?validate-only=true" --data "{\"join_token\":\"${token}\",\"server_address\":\"10.1.1.102:8443\"}" | jq -e '.failures | map(.check) | index("time") == null'
+  echo "${failures}" | jq -e '.failures[] | select(.check == "member_config") | .member_config.name == "data" and .member_config.key == "source"'
+  LXD_DIR="${LXD_ONE_DIR}" lxc cluster list-tokens | grep -wF node2
+  ! LXD_DIR="${LXD_ONE_DIR}" lxc query -X POST "/1.0/cluster/members?validate-only=true" --data "{\"join_token\":\"${token}\",\"server_name\":\"node3\"}" || false
+  LXD_DIR="${LXD_ONE_DIR}" lxc cluster revoke-token node2
+
   op=$(curl --unix-socket "${LXD_TWO_DIR}/unix.socket" -X PUT "lxd/1.0/cluster" -d "{\"server_name\":\"node2\",\"enabled\":true,\"member_config\":[{\"entity\": \"storage-pool\",\"name\":\"data\",\"key\":\"source\",\"value\":\"\"}],\"server_address\":\"10.1.1.102:8443\",\"cluster_address\":\"10.1.1.101:8443\",\"cluster_certificate\":\"${cert}\",\"cluster_password\":\"sekret\"}" | jq -r .operation)
   curl --unix-socket "${LXD_TWO_DIR}/unix.socket" "lxd${op}/wait"
 
@@ -20,4 +20,25 @@ test_console() {
   lxc console cons1 --show-log | grep 'some more content'
 
   lxc delete --force cons1
+
+  echo "==> API extension instance_console_history"
+
+  lxc init testimage cons1 -c console.history.size=64KiB
+
+  # Check the console history is only available to instances keeping one.
+  lxc launch testimage cons2
+  ! lxc query "/1.0/instances/cons2/console?history=all" || false
+  lxc delete --force cons2
+
+  lxc start cons1
+  echo 'some history' | lxc exec cons1 -- tee /dev/console
+  lxc query "/1.0/instances/cons1/console?history=all" | grep 'some history'
+  [ "$(lxc query "/1.0/instances/cons1/console?history=5" | wc -c)" -le 5 ]
+  ! lxc query "/1.0/instances/cons1/console?history=invalid" || false
+
+  # Check the console history is kept once the instance is stopped.
+  lxc stop --force cons1
+  lxc query "/1.0/instances/cons1/console?history=all" | grep 'some history'
+
+  lxc delete --force cons1
 }
@@ -10,6 +10,7 @@ test_container_devices_disk() {
   test_container_devices_disk_cephfs
   test_container_devices_disk_socket
   test_container_devices_disk_char
+  test_container_devices_disk_hotplug
 
   lxc delete -f foo
 }
@@ -182,3 +183,32 @@ test_container_devices_disk_char() {
   lxc config device remove foo char
   lxc stop foo -f
 }
+
+test_container_devices_disk_hotplug() {
+  mkdir -p "${TEST_DIR}/hotplug"
+  touch "${TEST_DIR}/hotplug/foo"
+  lxc start foo
+
+  # Add a disk to the running container without a full config update.
+  lxc query -X POST /1.0/instances/foo/devices/hotplug --data "{\"config\": {\"type\": \"disk\", \"source\": \"${TEST_DIR}/hotplug\", \"path\": \"/mnt/hotplug\"}}"
+  lxc exec foo -- test -e /mnt/hotplug/foo
+  [ "$(lxc config device get foo hotplug path)" = "/mnt/hotplug" ]
+  grep -F "/mnt/hotplug" "${LXD_DIR}/containers/foo/backup.yaml"
+
+  # Adding an existing device or an invalid one fails.
+  ! lxc query -X POST /1.0/instances/foo/devices/hotplug --data "{\"config\": {\"type\": \"disk\", \"source\": \"${TEST_DIR}/hotplug\", \"path\": \"/mnt/other\"}}" || false
+  ! lxc query -X POST /1.0/instances/foo/devices/invalid --data '{"config": {"type": "disk", "path": "/mnt/invalid"}}' || false
+  ! lxc config device get foo invalid type || false
+
+  # Remove it again.
+  lxc query -X DELETE /1.0/instances/foo/devices/hotplug
+  ! lxc exec foo -- test -e /mnt/hotplug/foo || false
+  ! grep -F "/mnt/hotplug" "${LXD_DIR}/containers/foo/backup.yaml" || false
+  ! lxc query -X DELETE /1.0/instances/foo/devices/hotplug || false
+
+  # Devices inherited from profiles can't be removed.
+  ! lxc query -X DELETE /1.0/instances/foo/devices/root || false
+
+  lxc stop -f foo
+  rm -rf "${TEST_DIR}/hotplug"
+}
@@ -68,6 +68,12 @@ test_container_devices_nic_bridged() {
   ! lxc config device add "${ctName}" ./invalid nic network=${brName} vlan=1 || false
   ! lxc config device add "${ctName}" ../invalid nic network=${brName} vlan=1 || false
 
+  # Test unknown device options are rejected with suggestions of the closest valid options.
+  ! lxc config device add "${ctName}" eth1 nic network=${brName} vlan=1 ipv4.addres=192.0.2.2 || false
+  lxc config device add "${ctName}" eth1 nic network=${brName} vlan=1 ipv4.addres=192.0.2.2 mut=1400 2>&1 | grep -F 'Invalid device option "ipv4.addres" (did you mean "ipv4.address"?); Invalid device option "mut" (did you mean "mtu"?)'
+  lxc config device add "${ctName}" eth1 nic network=${brName} vlan=1 user.foo=bar
+  lxc config device remove "${ctName}" eth1
+
   # Start instance.
   lxc start "${ctName}"
 
@@ -623,12 +629,26 @@ test_container_devices_nic_bridged() {
   lxc stop -f test-naming
 
   lxc config unset instances.nic.host_name
+
+  # Test persistent host interface names are kept across restarts and can't be reused by other NICs.
+  lxc config device override test-naming eth0 host_name.persistent=true
+  lxc start test-naming
+  persistentHostName="$(lxc query "/1.0/instances/test-naming/state" | jq -r .network.eth0.host_name)"
+  echo "${persistentHostName}" | grep ^veth
+  [ "$(lxc config get test-naming volatile.eth0.persistent_host_name)" = "${persistentHostName}" ]
+  lxc restart -f test-naming
+  [ "$(lxc query "/1.0/instances/test-naming/state" | jq -r .network.eth0.host_name)" = "${persistentHostName}" ]
+  ! lxc config device set test-naming eth0 host_name=foo || false
+  lxc init testimage test-naming2
+  ! lxc config device override test-naming2 eth0 host_name="${persistentHostName}" || false
+  lxc delete -f test-naming2
   lxc delete -f test-naming
 
   # Test new container with conflicting addresses can be created as a copy.
   lxc config device set "${ctName}" eth0 \
     ipv4.address=192.0.2.232 \
-    hwaddr="" # Remove static MAC so that copies use new MAC (as changing MAC triggers device remove/add on snapshot restore).
+    host_name="" \
+    hwaddr="" # Remove static MAC and host name so that copies use new ones (as changing MAC triggers device remove/add on snapshot restore).
   grep -F "192.0.2.232" "${LXD_DIR}/networks/${brName}/dnsmasq.hosts/${ctName}.eth0"
   lxc copy "${ctName}" foo # Gets new MAC address but IPs still conflict.
   ! stat "${LXD_DIR}/networks/${brName}/dnsmasq.hosts/foo.eth0" || false
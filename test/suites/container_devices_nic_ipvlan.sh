@@ -31,6 +31,11 @@ test_container_devices_nic_ipvlan() {
     mtu=1400
   lxc start "${ctName}"
 
+  # IPVLAN NICs can't be hotplugged through the device API either.
+  lxc query -X POST "/1.0/instances/${ctName}/devices/eth1" --data "{\"config\": {\"type\": \"nic\", \"nictype\": \"ipvlan\", \"parent\": \"${ctName}\"}}" 2>&1 | grep -F "Device cannot be added when instance is running"
+  lxc query -X DELETE "/1.0/instances/${ctName}/devices/eth0" 2>&1 | grep -F "Device cannot be stopped when instance is running"
+  [ "$(lxc config device list "${ctName}")" = "eth0" ]
+
   # Check custom MTU is applied.
   if ! lxc exec "${ctName}" -- ip link show eth0 | grep "mtu 1400" ; then
     echo "mtu invalid"
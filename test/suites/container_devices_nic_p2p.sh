@@ -207,6 +207,24 @@ test_container_devices_nic_p2p() {
   lxc delete "${ctName}" -f
   lxc profile delete "${ctName}"
 
+  # Test multi-queue p2p devices.
+  lxc launch testimage "${ctName}"
+  lxc config device add "${ctName}" eth0 nic nictype=p2p name=eth0 queues=2
+  [ "$(lxc exec "${ctName}" -- find /sys/class/net/eth0/queues/ -name 'tx-*' | wc -l)" = "2" ]
+  [ "$(lxc query "/1.0/instances/${ctName}/state" | jq '.network.eth0.queues')" = "2" ]
+
+  # Check the queues are changed by re-attaching the device.
+  lxc config device set "${ctName}" eth0 queues=4
+  [ "$(lxc exec "${ctName}" -- find /sys/class/net/eth0/queues/ -name 'rx-*' | wc -l)" = "4" ]
+  [ "$(lxc query "/1.0/instances/${ctName}/state" | jq '.network.eth0.queues')" = "4" ]
+
+  # Check invalid queue settings are rejected.
+  ! lxc config device set "${ctName}" eth0 queues=0 || false
+  ! lxc config device set "${ctName}" eth0 queues=257 || false
+  ! lxc config device set "${ctName}" eth0 queue.rx.ring_size=512 || false
+  lxc config device set "${ctName}" eth0 queues=auto
+  lxc delete "${ctName}" -f
+
   # Test adding a p2p device to a running container without host_name and no limits/routes.
   lxc launch testimage "${ctName}"
   lxc config device add "${ctName}" eth0 nic \
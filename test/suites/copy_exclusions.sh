@@ -0,0 +1,51 @@
+test_copy_exclusions() {
+  ensure_import_testimage
+
+  # shellcheck disable=2039,3043
+  local pool
+  pool="$(lxc profile device get default root pool)"
+
+  lxc init testimage c1
+  lxc storage volume create "${pool}" vol1
+  lxc storage volume create "${pool}" vol2
+  lxc config device add c1 vol disk pool="${pool}" source=vol1 path=/mnt
+  lxc config device add c1 eth0 none
+
+  for i in 0 1 2 3; do
+    lxc snapshot c1 "snap${i}"
+  done
+
+  # Only copy the most recent snapshots.
+  lxc copy c1 c2 --snapshots-limit 2
+  [ "$(lxc query /1.0/instances/c2/snapshots | jq -r 'length')" = "2" ]
+  lxc query /1.0/instances/c2/snapshots | jq -r '.[]' | grep -Fx "/1.0/instances/c2/snapshots/snap3"
+  ! lxc query /1.0/instances/c2/snapshots | jq -r '.[]' | grep -Fx "/1.0/instances/c2/snapshots/snap1" || false
+  ! lxc copy c1 c3 --snapshots-limit -1 || false
+  lxc delete c2
+
+  # Skip a device.
+  lxc copy c1 c2 --skip-device eth0
+  ! lxc config device show c2 | grep -F "eth0:" || false
+  [ "$(lxc query /1.0/instances/c2/snapshots/snap0 | jq -r '.devices | has("eth0")')" = "false" ]
+  lxc config device get c2 vol source | grep -Fx "vol1"
+  lxc delete c2
+
+  # Remap a device to another custom volume.
+  lxc copy c1 c2 --device-volume vol=vol2
+  lxc config device get c2 vol source | grep -Fx "vol2"
+  lxc config device get c1 vol source | grep -Fx "vol1"
+  lxc delete c2
+
+  # Invalid requests fail before copying anything and name the offending device.
+  ! lxc copy c1 c2 --skip-device missing || false
+  lxc copy c1 c2 --skip-device missing 2>&1 | grep -F 'Device "missing" to skip doesn'"'"'t exist'
+  lxc copy c1 c2 --device-volume eth0=vol2 2>&1 | grep -F 'Device "eth0" isn'"'"'t a custom volume disk'
+  lxc copy c1 c2 --device-volume vol=missing 2>&1 | grep -F 'Custom volume "missing" of device "vol" not found'
+  lxc config device add c1 root disk pool="${pool}" path=/
+  lxc copy c1 c2 --skip-device root 2>&1 | grep -F 'Root disk device "root" cannot be skipped'
+  ! lxc info c2 || false
+
+  lxc delete c1
+  lxc storage volume delete "${pool}" vol1
+  lxc storage volume delete "${pool}" vol2
+}
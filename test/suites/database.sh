@@ -91,3 +91,22 @@ test_database_no_disk_space(){
   umount "${GLOBAL_DB_DIR}"
   kill_lxd "${LXD_NOSPACE_DIR}"
 }
+
+test_database_maintenance(){
+  # Database state
+  [ "$(lxc query /1.0/cluster/database | jq '.disk_size > 0')" = "true" ]
+  [ "$(lxc query /1.0/cluster/database | jq '.page_size > 0')" = "true" ]
+  [ "$(lxc query /1.0/cluster/database | jq '.tables.projects')" = "1" ]
+
+  # Database maintenance
+  ! lxc query -X POST -d '{"action":"foo"}' /1.0/cluster/database || false
+  [ "$(lxc query --wait -X POST -d '{"action":"checkpoint"}' /1.0/cluster/database | jq '.metadata.disk_size_after > 0')" = "true" ]
+  lxc query --wait -X POST -d '{"action":"vacuum"}' /1.0/cluster/database > "${TEST_DIR}/vacuum.json"
+  [ "$(jq -r '.status' "${TEST_DIR}/vacuum.json")" = "Success" ]
+  [ "$(jq '.metadata.disk_size_after > 0' "${TEST_DIR}/vacuum.json")" = "true" ]
+  [ "$(jq '.metadata.disk_size_after <= .metadata.disk_size_before' "${TEST_DIR}/vacuum.json")" = "true" ]
+  rm "${TEST_DIR}/vacuum.json"
+
+  # Database size metric
+  curl -s --unix-socket "${LXD_DIR}/unix.socket" "lxd/1.0/metrics" | grep -q "^lxd_database_size_bytes "
+}
@@ -37,6 +37,32 @@ test_exec() {
   stdOutURL=$(lxc query  /1.0/operations/"${opID}" | jq '.metadata.output["1"]')
   lxc query "${stdOutURL}" | grep -F "hello"
 
+  # Check exec sessions are only recorded when enabled.
+  [ "$(lxc query /1.0/instances/x1/logs/exec | jq 'length')" = "0" ]
+  ! lxc config set x1 exec.record=invalid || false
+  lxc config set x1 exec.record=true
+  echo "recorded-interactive" | lxc exec x1 --force-interactive -- cat
+  echo "recorded-noninteractive" | lxc exec x1 --force-noninteractive -- cat
+  [ "$(lxc query /1.0/instances/x1/logs/exec | jq 'length')" = "2" ]
+  for url in $(lxc query /1.0/instances/x1/logs/exec | jq -r '.[]'); do
+    lxc query "${url}" | head -n1 | jq -e '.version == 2'
+  done
+
+  lxc query /1.0/instances/x1/logs/exec | jq -r '.[]' | while read -r url; do lxc query "${url}"; done > "${LXD_DIR}/exec-recordings"
+  grep -F '"i","recorded-interactive' "${LXD_DIR}/exec-recordings"
+  grep -F '"o","recorded-noninteractive' "${LXD_DIR}/exec-recordings"
+  ! lxc query /1.0/instances/x1/logs/exec/lxc.log || false
+  rm "${LXD_DIR}/exec-recordings"
+
+  # Check the project option also enables recording.
+  lxc config unset x1 exec.record
+  lxc project set default exec.record=true
+  lxc exec x1 -- true
+  [ "$(lxc query /1.0/instances/x1/logs/exec | jq 'length')" = "3" ]
+  lxc project unset default exec.record
+  lxc exec x1 -- true
+  [ "$(lxc query /1.0/instances/x1/logs/exec | jq 'length')" = "3" ]
+
   lxc stop "${name}" --force
   lxc delete "${name}"
 }
@@ -111,6 +111,16 @@ test_filemanip() {
   lxc file push -p "${TEST_DIR}"/source/foo filemanip/A/B/C/D/
   [ "$(lxc exec filemanip --project=test -- cat /A/B/C/D/foo)" = "foo" ]
 
+  # Test pushing and pulling directories as tarballs.
+  tar -C "${TEST_DIR}"/source -cf "${TEST_DIR}"/source.tar .
+  curl -s --fail --unix-socket "${LXD_DIR}/unix.socket" -X POST -H "X-LXD-type: tar" --data-binary @"${TEST_DIR}"/source.tar "lxd/1.0/instances/filemanip/files?project=test&path=/tmp/tartest"
+  [ "$(lxc exec filemanip --project=test -- cat /tmp/tartest/foo)" = "foo" ]
+  curl -s --fail --unix-socket "${LXD_DIR}/unix.socket" "lxd/1.0/instances/filemanip/files?project=test&path=/tmp/tartest&format=tar&recursive=true" | tar -tf - | grep -qxF "./foo"
+  tar -C "${TEST_DIR}" -cf "${TEST_DIR}"/escape.tar --transform 's|^|../|' source.tar
+  [ "$(curl -s -o /dev/null -w "%{http_code}" --unix-socket "${LXD_DIR}/unix.socket" -X POST -H "X-LXD-type: tar" --data-binary @"${TEST_DIR}"/escape.tar "lxd/1.0/instances/filemanip/files?project=test&path=/tmp/tartest")" = "400" ]
+  ! lxc exec filemanip --project=test -- test -e /tmp/source.tar || false
+  rm "${TEST_DIR}"/source.tar "${TEST_DIR}"/escape.tar
+
   if [ "$(storage_backend "$LXD_DIR")" != "lvm" ]; then
     lxc launch testimage idmap -c "raw.idmap=both 0 0"
     [ "$(stat -c %u "${LXD_DIR}/containers/test_idmap/rootfs")" = "0" ]
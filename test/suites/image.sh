@@ -180,3 +180,43 @@ test_image_refresh() {
   lxc remote rm l2
   kill_lxd "${LXD2_DIR}"
 }
+
+test_image_instance_manifest() {
+  ensure_import_testimage
+
+  lxc init testimage c1 -c limits.memory=128MiB -c user.foo=bar
+  lxc config device add c1 eth1 nic nictype=p2p hwaddr=00:16:3e:00:00:01
+
+  # Sensitive keys are stripped from the manifest.
+  lxc publish c1 --alias with-manifest --include-manifest
+  manifest="$(lxc image get-property with-manifest instance_manifest)"
+  [ "$(echo "${manifest}" | jq -r '.config["limits.memory"]')" = "128MiB" ]
+  [ "$(echo "${manifest}" | jq -r '.config["user.foo"]')" = "bar" ]
+  [ "$(echo "${manifest}" | jq -r '.config | keys[] | select(startswith("volatile.") or startswith("image.") or startswith("security.sev.session."))')" = "" ]
+  [ "$(echo "${manifest}" | jq -r '.devices.eth1.hwaddr')" = "null" ]
+
+  # The original image properties are kept.
+  [ "$(lxc image get-property with-manifest os)" = "$(lxc image get-property testimage os)" ]
+
+  # user.* keys are only stripped if requested.
+  lxc publish c1 --alias without-user --include-manifest --manifest-exclude-user
+  manifest="$(lxc image get-property without-user instance_manifest)"
+  [ "$(echo "${manifest}" | jq -r '.config["user.foo"]')" = "null" ]
+
+  # The manifest is applied on request, with the request taking precedence.
+  lxc init with-manifest c2 --apply-manifest -c user.foo=override
+  [ "$(lxc config get c2 limits.memory)" = "128MiB" ]
+  [ "$(lxc config get c2 user.foo)" = "override" ]
+  [ "$(lxc config device get c2 eth1 nictype)" = "p2p" ]
+  [ "$(lxc config get c2 volatile.eth1.hwaddr)" != "00:16:3e:00:00:01" ]
+
+  # Without the flag, the manifest is ignored.
+  lxc init with-manifest c3
+  [ "$(lxc config get c3 limits.memory)" = "" ]
+
+  # Images without a manifest can't be used with the flag.
+  ! lxc init testimage c4 --apply-manifest || false
+
+  lxc delete c1 c2 c3
+  lxc image delete with-manifest without-user
+}
@@ -0,0 +1,77 @@
+test_instance_pools() {
+  ensure_import_testimage
+
+  # Check invalid pools are rejected.
+  ! lxc query -X POST /1.0/instance-pools --data '{"name": "invalid", "size": -1, "template": {"source": {"alias": "testimage"}}}' || false
+  ! lxc query -X POST /1.0/instance-pools --data '{"name": "invalid", "name_pattern": "ci-%d-%d", "template": {"source": {"alias": "testimage"}}}' || false
+  ! lxc query -X POST /1.0/instance-pools --data '{"name": "invalid", "template": {"source": {"alias": "missing"}}}' || false
+
+  # Check the pool is filled up to its size in the background.
+  lxc query -X POST /1.0/instance-pools --data '{"name": "ci", "size": 2, "name_pattern": "ci-%d", "template": {"source": {"alias": "testimage"}, "config": {"user.pool": "ci"}}}'
+  [ "$(lxc query /1.0/instance-pools/ci | jq -r '.name_pattern')" = "ci-%d" ]
+  wait_instance_pool_filled ci 2
+  [ "$(lxc list -c s --format csv ci-pool-)" = "$(printf 'STOPPED\nSTOPPED')" ]
+
+  # Check acquiring an instance renames, configures and starts it, and removes it from the pool.
+  [ "$(lxc query -X POST /1.0/instance-pools/ci/acquire --data '{"config": {"user.job": "1"}}' | jq -r '.name')" = "ci-0" ]
+  [ "$(lxc list -c s --format csv ci-0)" = "RUNNING" ]
+  [ "$(lxc config get ci-0 user.job)" = "1" ]
+  [ "$(lxc config get ci-0 user.pool)" = "ci" ]
+  ! lxc query /1.0/instance-pools/ci | jq -r '.used_by[]' | grep -xF /1.0/instances/ci-0 || false
+  [ "$(lxc query -X POST /1.0/instance-pools/ci/acquire --data '{"name": "job-2"}' | jq -r '.name')" = "job-2" ]
+  ! lxc query -X POST /1.0/instance-pools/ci/acquire --data '{"name": "job-2"}' || false
+
+  # Check the pool is replenished, and that metrics report its fill level and acquisitions.
+  wait_instance_pool_filled ci 2
+  lxc query /1.0/metrics | grep -F 'lxd_instance_pool_instances{pool="ci",project="default"} 2'
+  lxc query /1.0/metrics | grep -F 'lxd_instance_pool_acquires_total{pool="ci",project="default"} 2'
+
+  # Check concurrent acquisitions get distinct instances with distinct names.
+  lxc query -X POST /1.0/instance-pools/ci/acquire --data '{}' > "${TEST_DIR}/acquire1.json" &
+  acquire1=$!
+  lxc query -X POST /1.0/instance-pools/ci/acquire --data '{}' > "${TEST_DIR}/acquire2.json" &
+  acquire2=$!
+  wait "${acquire1}"
+  wait "${acquire2}"
+  name1="$(jq -r '.name' "${TEST_DIR}/acquire1.json")"
+  name2="$(jq -r '.name' "${TEST_DIR}/acquire2.json")"
+  [ "$(printf '%s\n%s\n' "${name1}" "${name2}" | sort)" = "$(printf 'ci-1\nci-2')" ]
+  [ "$(lxc query "/1.0/instances/${name1}" | jq -r '.status')" = "Running" ]
+  [ "$(lxc query "/1.0/instances/${name2}" | jq -r '.status')" = "Running" ]
+  rm "${TEST_DIR}/acquire1.json" "${TEST_DIR}/acquire2.json"
+
+  # Check the instance is returned to the pool if it can't be renamed, here because its new path is in the way.
+  wait_instance_pool_filled ci 2
+  available="$(lxc query /1.0/instance-pools/ci | jq -r '.used_by | sort | join(",")')"
+  mkdir -p "${LXD_DIR}/containers/job-3/blocker"
+  ! lxc query -X POST /1.0/instance-pools/ci/acquire --data '{"name": "job-3"}' || false
+  rm -rf "${LXD_DIR}/containers/job-3"
+  [ "$(lxc query /1.0/instance-pools/ci | jq -r '.used_by | sort | join(",")')" = "${available}" ]
+  ! lxc query /1.0/instances/job-3 || false
+  [ "$(lxc query -X POST /1.0/instance-pools/ci/acquire --data '{"name": "job-3"}' | jq -r '.name')" = "job-3" ]
+  wait_instance_pool_filled ci 2
+
+  # Check pooled instances count against the project limits.
+  lxc project set default limits.instances=7
+  lxc query -X PATCH /1.0/instance-pools/ci --data '{"size": 3}'
+  sleep 2
+  [ "$(lxc query /1.0/instance-pools/ci | jq -r '.used_by | length')" = "2" ]
+  lxc warning list | grep -F "Failed to replenish instance pool"
+  lxc project unset default limits.instances
+
+  # Check deleting the pool deletes its available instances but keeps the acquired ones.
+  lxc query -X DELETE /1.0/instance-pools/ci
+  [ "$(lxc list --format csv -c n ci-pool- | wc -l)" = "0" ]
+  lxc delete -f ci-0 ci-1 ci-2 job-2 job-3
+  lxc query --wait /1.0/warnings\?recursion=1 | jq -r '.[] | select(.type == "Failed to replenish instance pool") | .uuid' | xargs -rn1 lxc warning delete
+}
+
+# wait_instance_pool_filled waits for the given instance pool to hold the given number of available instances.
+wait_instance_pool_filled() {
+  for _ in $(seq 30); do
+    [ "$(lxc query "/1.0/instance-pools/${1}" | jq -r '.used_by | length')" = "${2}" ] && break
+    sleep 1
+  done
+
+  [ "$(lxc query "/1.0/instance-pools/${1}" | jq -r '.used_by | length')" = "${2}" ]
+}
@@ -496,6 +496,18 @@ migration() {
   lxc_remote storage volume delete l2:"$remote_pool2" bar
   lxc_remote storage unset l1:"$remote_pool1" rsync.compression
 
+  # Test migration with filesystem transfer retries enabled
+  ! lxc_remote config set l1: instances.migration.transfer_retries -1 || false
+  lxc_remote config set l1: instances.migration.transfer_retries 3
+  lxc_remote config set l2: instances.migration.transfer_retries 3
+  lxc_remote init l1:testimage l1:retries
+  lxc_remote snapshot l1:retries
+  lxc_remote copy l1:retries l2:retries
+  [ "$(lxc_remote query l2:/1.0/instances/retries/snapshots | jq length)" = "1" ]
+  lxc_remote delete l1:retries l2:retries
+  lxc_remote config unset l1: instances.migration.transfer_retries
+  lxc_remote config unset l2: instances.migration.transfer_retries
+
   # Test some migration between projects
   lxc_remote project create l1:proj -c features.images=false -c features.profiles=false
   lxc_remote project switch l1:proj
@@ -132,6 +132,12 @@ test_network_forward() {
     [ "$(nft -nn list chain inet lxd "fwdpstrt.${netName}" | wc -l)" -eq 7 ]
   fi
 
+  # Check automatic listen address allocation is only supported for OVN networks.
+  ! lxc query -X POST "/1.0/networks/${netName}/forwards" -d '{"allocate": "auto"}' || false
+
+  # Check the uplink allocations of a bridge network can be listed.
+  [ "$(lxc query "/1.0/networks/${netName}/allocations" | jq 'length')" = "0" ]
+
   # Check forward is exported via BGP prefixes before network delete.
   lxc query /internal/testing/bgp | grep "198.51.100.1/32"
 
@@ -31,6 +31,29 @@ test_oidc() {
   # OIDC user should be added to identities table.
   [ "$(lxd sql global "SELECT identifier, name, auth_method, type FROM identities WHERE type = 5 AND identifier = 'test-user@example.com' AND auth_method = 2" | wc -l)" = 5 ]
 
+  # Claim paths are validated.
+  lxc config set oidc.groups.claim=resource_access.lxd.roles
+  lxc config set oidc.name.claim=preferred_username
+  ! lxc config set oidc.groups.claim=resource_access..roles || false
+  ! lxc config set oidc.email.claim=.email || false
+  ! lxc config set "oidc.name.claim=user name" || false
+  lxc config unset oidc.groups.claim
+  lxc config unset oidc.name.claim
+
+  # Identities that are not a member of any group are refused when group membership is required, but are still added
+  # so that they can be added to a group.
+  lxc config set auth.require_group_membership=true
+  set_oidc other-user other-user@example.com
+  ! BROWSER=curl lxc remote add --accept-certificate oidc-other "${LXD_ADDR}" --auth-type oidc || false
+  [ "$(lxc query /1.0/auth/identities/oidc/other-user@example.com | jq -r '.groups | length')" = "0" ]
+  lxc auth group create other-group
+  lxc auth identity group add oidc/other-user@example.com other-group
+  BROWSER=curl lxc remote add --accept-certificate oidc-other "${LXD_ADDR}" --auth-type oidc
+  lxc remote remove oidc-other
+  lxc auth group delete other-group
+  lxc config unset auth.require_group_membership
+  set_oidc test-user test-user@example.com
+
   # Cleanup OIDC
   lxc remote remove oidc
   kill_oidc
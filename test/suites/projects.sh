@@ -466,6 +466,63 @@ test_projects_images_default() {
   lxc project delete foo
 }
 
+# Images shared with other projects.
+test_projects_images_shared() {
+  lxc project create base
+  lxc project create consumer
+  lxc project create other
+  lxc project create noimages -c features.images=false
+
+  # Import an image into the base project and grab its fingerprint
+  deps/import-busybox --project base --alias base-image
+  fingerprint="$(lxc query "/1.0/images/aliases/base-image?project=base" | jq -r .target)"
+
+  # The image isn't visible or usable from the consumer project yet
+  ! lxc image list --project consumer | grep -q "${fingerprint:0:12}" || false
+  ! lxc query "/1.0/images/${fingerprint}?project=consumer" || false
+
+  # Images can only be shared with other projects that have their own images
+  ! lxc query -X PATCH -d '{"shared_with": ["base"]}' "/1.0/images/${fingerprint}?project=base" || false
+  ! lxc query -X PATCH -d '{"shared_with": ["missing"]}' "/1.0/images/${fingerprint}?project=base" || false
+  ! lxc query -X PATCH -d '{"shared_with": ["noimages"]}' "/1.0/images/${fingerprint}?project=base" || false
+
+  # Share the image with the consumer project
+  lxc query -X PATCH -d '{"shared_with": ["consumer"]}' "/1.0/images/${fingerprint}?project=base"
+  [ "$(lxc query "/1.0/images/${fingerprint}?project=base" | jq -r '.shared_with | join(",")')" = "consumer" ]
+  [ "$(lxc query "/1.0/images/${fingerprint}?project=base" | jq -r '.origin // ""')" = "" ]
+
+  # The image is listed in the consumer project with a shared origin, but not in the other project
+  [ "$(lxc query "/1.0/images?project=consumer&recursion=1" | jq -r '.[0].origin')" = "shared" ]
+  [ "$(lxc query "/1.0/images?project=consumer&recursion=1" | jq -r '.[0].project')" = "base" ]
+  [ "$(lxc query "/1.0/images/${fingerprint}?project=consumer" | jq -r .origin)" = "shared" ]
+  ! lxc query "/1.0/images/${fingerprint}?project=other" || false
+
+  # Instances can be created in the consumer project from the fingerprint or the alias of the image
+  lxc profile device add default root disk path="/" pool="lxdtest-$(basename "${LXD_DIR}")" --project consumer
+  lxc init "${fingerprint}" c1 --project consumer
+  lxc init base-image c2 --project consumer
+  [ "$(lxc config get c2 volatile.base_image --project consumer)" = "${fingerprint}" ]
+  lxc delete c1 c2 --project consumer
+
+  # But not in projects the image isn't shared with
+  lxc profile device add default root disk path="/" pool="lxdtest-$(basename "${LXD_DIR}")" --project other
+  ! lxc init "${fingerprint}" c1 --project other || false
+  ! lxc init base-image c1 --project other || false
+
+  # Stop sharing the image
+  lxc query -X PATCH -d '{"shared_with": []}' "/1.0/images/${fingerprint}?project=base"
+  ! lxc query "/1.0/images/${fingerprint}?project=consumer" || false
+  ! lxc init base-image c1 --project consumer || false
+
+  lxc image delete base-image --project base
+  lxc profile device remove default root --project consumer
+  lxc profile device remove default root --project other
+  lxc project delete noimages
+  lxc project delete other
+  lxc project delete consumer
+  lxc project delete base
+}
+
 # Interaction between projects and storage pools.
 test_projects_storage() {
   pool="lxdtest-$(basename "${LXD_DIR}")"
@@ -1049,3 +1106,33 @@ test_projects_usage() {
   lxc image delete testimage --project test-usage
   lxc project delete test-usage
 }
+
+# Use project device defaults.
+test_projects_default_devices() {
+  lxc project create test-defaults -c features.images=false
+  lxc profile show default --project default | lxc profile edit default --project test-defaults
+
+  # Device defaults apply to new instances and are taken into account by project limits.
+  lxc project set test-defaults default.device.root.size=2GiB limits.disk=10GiB
+  lxc init c1 --empty --project test-defaults
+  [ "$(lxc query "/1.0/instances/c1?project=test-defaults" | jq -r '.expanded_devices.root.size')" = "2GiB" ]
+  lxc project info test-defaults --format csv | grep -q "DISK,10.00GiB,2.00GiB"
+
+  # Device defaults override the devices of the instance unless they are overridable.
+  lxc config device add c1 data disk source="${TEST_DIR}" path=/mnt --project test-defaults
+  lxc project set test-defaults default.device.data.readonly=true
+  [ "$(lxc query "/1.0/instances/c1?project=test-defaults" | jq -r '.expanded_devices.data.readonly')" = "true" ]
+  lxc config device set c1 data readonly=false --project test-defaults
+  [ "$(lxc query "/1.0/instances/c1?project=test-defaults" | jq -r '.expanded_devices.data.readonly')" = "true" ]
+  lxc project set test-defaults default.device.data.overridable=true
+  [ "$(lxc query "/1.0/instances/c1?project=test-defaults" | jq -r '.expanded_devices.data.readonly')" = "false" ]
+  lxc project unset test-defaults default.device.data.readonly
+  lxc project unset test-defaults default.device.data.overridable
+
+  # Invalid device defaults.
+  ! lxc project set test-defaults default.device.root=2GiB || false
+  ! lxc project set test-defaults default.device.data.overridable=maybe || false
+
+  lxc delete c1 --project test-defaults
+  lxc project delete test-defaults
+}
@@ -162,7 +162,24 @@ test_security_protection() {
 
   lxc profile unset default security.protection.delete
 
-  # Test shifting protection
+  # Test stop and force protection
+  lxc launch testimage c1 -c security.protection.stop=true
+  ! lxc stop c1 || false
+  ! lxc restart c1 --force || false
+  lxc stop c1 --force 2>&1 | grep -F "security.protection.stop"
+  lxc config set c1 security.protection.stop=false security.protection.force=true
+  ! lxc stop c1 --force || false
+  ! lxc restart c1 --force || false
+  lxc snapshot c1 snap0
+  ! lxc restore c1 snap0 || false
+  lxc restore c1 snap0 --override-protection
+  [ "$(lxc config get c1 security.protection.force)" = "true" ]
+  lxc stop c1
+  lxc delete c1/snap0
+  ! lxc rebuild testimage c1 || false
+  lxc config unset c1 security.protection.force
+  lxc rebuild testimage c1
+  lxc delete c1
 
   # Respawn LXD with kernel ID shifting support disabled to force manual shifting.
   shutdown_lxd "${LXD_DIR}"
@@ -5,6 +5,7 @@ test_server_config() {
 
   test_server_config_password
   test_server_config_access
+  test_server_config_trusted_proxy
   test_server_config_storage
 
   kill_lxd "${LXD_SERVERCONFIG_DIR}"
@@ -30,6 +31,59 @@ test_server_config_access() {
 
   # only tls is enabled by default
   ! curl --unix-socket "$LXD_DIR/unix.socket" "lxd/1.0" | jq .metadata.auth_methods | grep oidc || false
+
+  # test the unauthenticated health endpoints
+  addr="$(cat "${LXD_SERVERCONFIG_DIR}/lxd.addr")"
+  [ "$(curl -k -s -o /dev/null -w "%{http_code}" "https://${addr}/healthz")" = "200" ]
+  [ "$(curl -k -s -o /dev/null -w "%{http_code}" "https://${addr}/readyz")" = "200" ]
+  [ "$(curl -k -s "https://${addr}/healthz" | jq -r '.status')" = "pass" ]
+  [ "$(curl -k -s "https://${addr}/healthz" | jq -r '[.checks[].name] | join(",")')" = "startup,database" ]
+  [ "$(curl -k -s "https://${addr}/readyz" | jq -r '[.checks[].name] | join(",")')" = "startup,database,shutdown" ]
+  LXD_DIR="${LXD_SERVERCONFIG_DIR}" lxc config set core.health_check_storage_pools true
+  [ "$(curl -k -s "https://${addr}/healthz" | jq -r '.checks[] | select(.name == "storage") | .status')" = "pass" ]
+  LXD_DIR="${LXD_SERVERCONFIG_DIR}" lxc config unset core.health_check_storage_pools
+}
+
+test_server_config_trusted_proxy() {
+  # shellcheck disable=2039,3043
+  local LXD_DIR
+  LXD_DIR="${LXD_SERVERCONFIG_DIR}"
+  addr="$(cat "${LXD_SERVERCONFIG_DIR}/lxd.addr")"
+  lxc config trust add "${LXD_CONF}/client.crt"
+
+  # Trusted proxies are IP addresses or subnets.
+  ! lxc config set core.https_trusted_proxy foo || false
+  lxc config set core.https_trusted_proxy "192.0.2.1, 2001:db8::/64"
+
+  lxc monitor --type=lifecycle > "${TEST_DIR}/trusted-proxy.log" &
+  monitorTrustedProxyPID=$!
+
+  # The forwarded client address is ignored for untrusted sources.
+  my_curl -f -X POST "https://${addr}/1.0/projects" -H "X-Forwarded-For: 203.0.113.1" --data '{"name": "proxy-untrusted"}'
+
+  # And used for trusted proxies.
+  lxc config set core.https_trusted_proxy 127.0.0.0/8
+  my_curl -f -X POST "https://${addr}/1.0/projects" -H "X-Forwarded-For: 203.0.113.1" --data '{"name": "proxy-forwarded"}'
+  my_curl -f -X POST "https://${addr}/1.0/projects" -H "X-Real-IP: 203.0.113.2" --data '{"name": "proxy-real-ip"}'
+  sleep 1
+
+  kill -9 "${monitorTrustedProxyPID}" || true
+  grep -F -A8 "proxy-untrusted" "${TEST_DIR}/trusted-proxy.log" | grep -F "address: 127.0.0.1:"
+  grep -F -A8 "proxy-forwarded" "${TEST_DIR}/trusted-proxy.log" | grep -F "address: 203.0.113.1:0"
+  grep -F -A8 "proxy-real-ip" "${TEST_DIR}/trusted-proxy.log" | grep -F "address: 203.0.113.2:0"
+
+  lxc project delete proxy-untrusted
+  lxc project delete proxy-forwarded
+  lxc project delete proxy-real-ip
+
+  # Invalid entries set by earlier versions are removed on upgrade, keeping the valid ones.
+  lxd sql global "UPDATE config SET value = '127.0.0.0/8,foo' WHERE key = 'core.https_trusted_proxy'"
+  lxd sql local "DELETE FROM patches WHERE name = 'core_https_trusted_proxy_remove_invalid'"
+  shutdown_lxd "${LXD_DIR}"
+  respawn_lxd "${LXD_DIR}" true
+  [ "$(lxc config get core.https_trusted_proxy)" = "127.0.0.0/8" ]
+
+  lxc config unset core.https_trusted_proxy
 }
 
 test_server_config_storage() {
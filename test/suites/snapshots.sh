@@ -368,6 +368,55 @@ test_snap_expiry() {
   lxc rm -f c2
 }
 
+test_snap_retention_policies() {
+  ensure_import_testimage
+  ensure_has_localhost_remote "${LXD_ADDR}"
+
+  lxc init testimage c1 -c snapshots.retention.policy=keep-one
+  lxc snapshot c1
+  lxc snapshot c1
+  lxc snapshot c1
+
+  # Check rules of a policy that is already in use are pending.
+  lxc query -X POST "/1.0/snapshot-retention-policies?project=default" --data '{"name": "keep-one", "rules": {"keep_last": 1}}'
+  [ "$(lxc query "/1.0/snapshot-retention-policies/keep-one?project=default" | jq -r '.rules.keep_last')" = "0" ]
+  [ "$(lxc query "/1.0/snapshot-retention-policies/keep-one?project=default" | jq -r '.pending_rules.keep_last')" = "1" ]
+  [ "$(lxc query "/1.0/snapshot-retention-policies/keep-one?project=default" | jq -r '.used_by[0]')" = "/1.0/instances/c1" ]
+  ! lxc query -X POST "/1.0/snapshot-retention-policies?project=default" --data '{"name": "keep-one"}' || false
+  ! lxc query -X POST "/1.0/snapshot-retention-policies?project=default" --data '{"name": "negative", "rules": {"keep_daily": -1}}' || false
+
+  # Check the project policy takes precedence over a server-wide policy with the same name.
+  lxc query -X POST /1.0/snapshot-retention-policies --data '{"name": "keep-one", "rules": {"keep_last": 2}}'
+  [ "$(lxc query "/1.0/snapshot-retention-policies?recursion=1" | jq -r '.[0].used_by | length')" = "0" ]
+  [ "$(lxc query "/1.0/snapshot-retention-policies?recursion=1" | jq -r '.[0].pending_rules')" = "null" ]
+
+  # Check the simulation reports the snapshots that would be deleted, and that applying requires its token.
+  token="$(lxc query -X POST "/1.0/snapshot-retention-policies/keep-one/simulate?project=default" | jq -r '.token')"
+  [ "$(lxc query -X POST "/1.0/snapshot-retention-policies/keep-one/simulate?project=default" | jq -r '.entities[0].kept | join(",")')" = "snap2" ]
+  [ "$(lxc query -X POST "/1.0/snapshot-retention-policies/keep-one/simulate?project=default" | jq -r '.entities[0].deleted | join(",")')" = "snap1,snap0" ]
+  [ "$(lxc query -X POST "/1.0/snapshot-retention-policies/keep-one/simulate?project=default" --data '{"rules": {"keep_last": 2}}' | jq -r '.entities[0].deleted | join(",")')" = "snap0" ]
+  ! lxc query -X POST "/1.0/snapshot-retention-policies/keep-one/apply?project=default" --data '{"token": "invalid"}' || false
+
+  # Check a simulation is out of date once the snapshots change.
+  lxc snapshot c1
+  ! lxc query -X POST "/1.0/snapshot-retention-policies/keep-one/apply?project=default" --data "{\"token\": \"${token}\"}" || false
+  [ "$(lxc query "/1.0/snapshot-retention-policies/keep-one?project=default" | jq -r '.rules.keep_last')" = "0" ]
+
+  token="$(lxc query -X POST "/1.0/snapshot-retention-policies/keep-one/simulate?project=default" | jq -r '.token')"
+  lxc query -X POST "/1.0/snapshot-retention-policies/keep-one/apply?project=default" --data "{\"token\": \"${token}\"}"
+  [ "$(lxc query "/1.0/snapshot-retention-policies/keep-one?project=default" | jq -r '.rules.keep_last')" = "1" ]
+  [ "$(lxc query "/1.0/snapshot-retention-policies/keep-one?project=default" | jq -r '.pending_rules')" = "null" ]
+  ! lxc query -X POST "/1.0/snapshot-retention-policies/keep-one/apply?project=default" --data "{\"token\": \"${token}\"}" || false
+
+  # Check a policy cannot be deleted while it is in use.
+  ! lxc query -X DELETE "/1.0/snapshot-retention-policies/keep-one?project=default" || false
+  lxc config unset c1 snapshots.retention.policy
+  lxc query -X DELETE "/1.0/snapshot-retention-policies/keep-one?project=default"
+  lxc query -X DELETE /1.0/snapshot-retention-policies/keep-one
+
+  lxc rm -f c1
+}
+
 test_snap_schedule() {
   # shellcheck disable=2039,3043
   local lxd_backend
@@ -392,9 +441,55 @@ test_snap_schedule() {
   lxc restart c1 -f
   lxc info c1 | grep -q snap1
 
+  # Check the scheduled snapshot can be taken outside of the schedule and its outcome is recorded.
+  lxc query -X POST "/1.0/instances/c1/snapshots?scheduled=retry"
+  lxc info c1 | grep -q snap2
+  [ "$(lxc query /1.0/instances/c1/state | jq -r '.scheduled_snapshots.consecutive_failures')" = "0" ]
+  [ "$(lxc query /1.0/instances/c1/state | jq -r '.scheduled_snapshots.last_success_at')" != "0001-01-01T00:00:00Z" ]
+  ! lxc query -X POST "/1.0/instances/c1/snapshots?scheduled=invalid" || false
+
+  # Check named schedules have their own pattern and expiry and tag their snapshots.
+  lxc config set c1 snapshots.schedule.hourly='@hourly' snapshots.schedule.hourly.expiry=1d snapshots.pattern.hourly='hourly%d'
+  lxc query -X POST "/1.0/instances/c1/snapshots?scheduled=retry&schedule=hourly"
+  [ "$(lxc query /1.0/instances/c1/snapshots/hourly0 | jq -r '.config["volatile.snapshot.schedule"]')" = "hourly" ]
+  [ "$(lxc query /1.0/instances/c1/snapshots/hourly0 | jq -r '.expires_at')" != "0001-01-01T00:00:00Z" ]
+  [ "$(lxc config get c1 volatile.snapshot.schedule)" = "" ]
+  ! lxc query -X POST "/1.0/instances/c1/snapshots?scheduled=retry&schedule=missing" || false
+  ! lxc config set c1 snapshots.schedule.hourly='@startup' || false
+  ! lxc config set c1 snapshots.schedule.stopped='@daily' || false
+  ! lxc config set c1 snapshots.schedule.foo.bar='@daily' || false
+  lxc config unset c1 snapshots.schedule.hourly
+
+  # Check instances without a snapshot schedule cannot have their scheduled snapshot retried.
+  lxc config unset c2 snapshots.schedule
+  ! lxc query -X POST "/1.0/instances/c2/snapshots?scheduled=retry" || false
+
   lxc rm -f c1 c2 c3 c4 c5
 }
 
+test_snap_filesystem_freeze() {
+  ensure_import_testimage
+
+  lxc launch testimage c1
+
+  # Check snapshots don't leave the root filesystem frozen.
+  [ "$(lxc query /1.0/instances/c1/state | jq -r '.filesystem_freeze')" = "null" ]
+  lxc snapshot c1
+  [ "$(lxc query /1.0/instances/c1/state | jq -r '.filesystem_freeze')" = "null" ]
+
+  # Check thawing a filesystem that isn't frozen fails and can't be done in bulk.
+  ! lxc query -X PUT --wait /1.0/instances/c1/state -d '{"action": "unfreeze-fs"}' || false
+  ! lxc query -X PUT --wait /1.0/instances -d '{"state": {"action": "unfreeze-fs"}}' || false
+
+  # Check the freeze timeout option.
+  [ "$(lxc config get instances.filesystem_freeze.timeout)" = "" ]
+  lxc config set instances.filesystem_freeze.timeout 60
+  ! lxc config set instances.filesystem_freeze.timeout -1 || false
+  lxc config unset instances.filesystem_freeze.timeout
+
+  lxc delete -f c1
+}
+
 test_snap_volume_db_recovery() {
   # shellcheck disable=2039,3043
   local lxd_backend
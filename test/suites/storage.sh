@@ -28,6 +28,25 @@ test_storage() {
   lxc storage set "$storage_pool" user.abc def
   [ "$(lxc storage get "$storage_pool" user.abc)" = "def" ]
 
+  # Validate the unavailable pool handling keys
+  lxc storage set "$storage_pool" unavailable.action pause
+  lxc storage set "$storage_pool" unavailable.timeout 600
+  ! lxc storage set "$storage_pool" unavailable.action stop || false
+  ! lxc storage set "$storage_pool" unavailable.timeout -1 || false
+  lxc storage unset "$storage_pool" unavailable.action
+  lxc storage unset "$storage_pool" unavailable.timeout
+
+  # Limit the number of instance volumes created concurrently, queuing the others
+  ! lxc storage set "$storage_pool" instances.create_concurrency -1 || false
+  lxc storage set "$storage_pool" instances.create_concurrency 1
+  lxc init testimage c1 -s "$storage_pool" &
+  C1_PID=$!
+  lxc init testimage c2 -s "$storage_pool"
+  wait "${C1_PID}"
+  lxc query "/1.0/metrics" | grep -xF "lxd_storage_pool_create_queued{pool=\"${storage_pool}\"} 0"
+  lxc delete c1 c2
+  lxc storage unset "$storage_pool" instances.create_concurrency
+
   lxc storage volume set "$storage_pool" "$storage_volume" user.abc def
   [ "$(lxc storage volume get "$storage_pool" "$storage_volume" user.abc)" = "def" ]
 
@@ -0,0 +1,29 @@
+test_storage_driver_dir() {
+  # shellcheck disable=2039,3043
+  local pool source
+
+  pool="lxdtest-$(basename "${LXD_DIR}")-dir"
+  source="$(mktemp -d -p "${TEST_DIR}" XXXXXXXXX)"
+
+  # Size limits that can't be enforced are ignored by default.
+  lxc storage create "${pool}" dir source="${source}" volume.size=1GiB
+  lxc storage volume create "${pool}" vol1 size=1MiB
+  lxc storage volume set "${pool}" vol1 size=2MiB
+  [ "$(lxc storage volume get "${pool}" vol1 size)" = "2MiB" ]
+  lxc storage volume delete "${pool}" vol1
+
+  # Strict pools refuse them, which requires the backing filesystem to support project quotas.
+  if lxc storage set "${pool}" dir.quota.strict=true; then
+    lxc storage volume create "${pool}" vol1 size=1MiB
+    lxc storage volume set "${pool}" vol1 size=2MiB
+    lxc storage volume delete "${pool}" vol1
+  else
+    [ "$(lxc storage get "${pool}" dir.quota.strict)" = "" ]
+    echo "==> SKIP: The backing filesystem doesn't support project quotas"
+  fi
+
+  ! lxc storage set "${pool}" dir.quota.strict=foo || false
+
+  lxc storage delete "${pool}"
+  rmdir "${source}"
+}
@@ -51,11 +51,40 @@ test_storage_volume_import() {
   lxc storage volume show "lxdtest-$(basename "${LXD_DIR}")" foo | sed 's/^description:.*/description: foo/' | lxc storage volume edit "lxdtest-$(basename "${LXD_DIR}")" foo
   lxc storage volume show "lxdtest-$(basename "${LXD_DIR}")" foo | grep -q 'description: foo'
 
+  # create a filesystem volume from an uploaded tarball
+  mkdir -p content/dir
+  echo foo > content/dir/foo
+  tar -C content -czf content.tar.gz .
+  sum="$(sha256sum content.tar.gz | cut -d' ' -f1)"
+  op="$(curl -s --unix-socket "${LXD_DIR}/unix.socket" -X POST -F "request={\"name\": \"content\", \"source\": {\"checksum\": \"${sum}\"}};type=application/json" -F "content=@content.tar.gz" "lxd/1.0/storage-pools/lxdtest-$(basename "${LXD_DIR}")/volumes/custom" | jq -r .operation)"
+  curl -s --unix-socket "${LXD_DIR}/unix.socket" "lxd${op}/wait" | jq -e --arg sum "${sum}" '.metadata.status == "Success" and .metadata.metadata.checksum == $sum'
+  lxc storage volume show "lxdtest-$(basename "${LXD_DIR}")" content | grep -q 'content_type: filesystem'
+
+  lxc storage volume attach "lxdtest-$(basename "${LXD_DIR}")" content c1 /mnt
+  lxc start c1
+  [ "$(lxc exec c1 -- cat /mnt/dir/foo)" = "foo" ]
+  lxc stop -f c1
+
+  # a checksum mismatch fails the creation and leaves no volume behind
+  op="$(curl -s --unix-socket "${LXD_DIR}/unix.socket" -X POST -F "request={\"name\": \"content2\", \"source\": {\"checksum\": \"$(printf '0%.0s' $(seq 64))\"}};type=application/json" -F "content=@content.tar.gz" "lxd/1.0/storage-pools/lxdtest-$(basename "${LXD_DIR}")/volumes/custom" | jq -r .operation)"
+  curl -s --unix-socket "${LXD_DIR}/unix.socket" "lxd${op}/wait" | jq -e '.metadata.status == "Failure"'
+  ! lxc storage volume show "lxdtest-$(basename "${LXD_DIR}")" content2 || false
+
+  # tarballs escaping the volume are rejected
+  tar -C content -czf escape.tar.gz --absolute-names --transform 's|^\./dir|../dir|' ./dir
+  op="$(curl -s --unix-socket "${LXD_DIR}/unix.socket" -X POST -F "request={\"name\": \"content2\"};type=application/json" -F "content=@escape.tar.gz" "lxd/1.0/storage-pools/lxdtest-$(basename "${LXD_DIR}")/volumes/custom" | jq -r .operation)"
+  curl -s --unix-socket "${LXD_DIR}/unix.socket" "lxd${op}/wait" | jq -e '.metadata.status == "Failure"'
+  ! lxc storage volume show "lxdtest-$(basename "${LXD_DIR}")" content2 || false
+
+  # only filesystem volumes can be given initial content
+  [ "$(curl -s -o /dev/null -w "%{http_code}" --unix-socket "${LXD_DIR}/unix.socket" -X POST -F "request={\"name\": \"content2\", \"content_type\": \"block\"};type=application/json" -F "content=@content.tar.gz" "lxd/1.0/storage-pools/lxdtest-$(basename "${LXD_DIR}")/volumes/custom")" = "400" ]
+
   # cleanup
   lxc delete -f c1
   lxc storage volume delete "lxdtest-$(basename "${LXD_DIR}")" foo
   lxc storage volume delete "lxdtest-$(basename "${LXD_DIR}")" bar
   lxc storage volume delete "lxdtest-$(basename "${LXD_DIR}")" foobar
+  lxc storage volume delete "lxdtest-$(basename "${LXD_DIR}")" content
 
-  rm -f foo.iso foo.img
+  rm -rf foo.iso foo.img content content.tar.gz escape.tar.gz
 }
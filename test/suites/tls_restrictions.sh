@@ -20,6 +20,11 @@ test_tls_restrictions() {
   # Validate admin rights with no restrictions
   lxc_remote project create localhost:blah
 
+  # Unrestricted clients are reported as server administrators, regardless of the entity.
+  [ "$(lxc_remote query localhost:/1.0/auth/self/permissions | jq -r '.[] | .entity_type + " " + .url + " " + .entitlement')" = "server /1.0 admin" ]
+  [ "$(lxc_remote query "localhost:/1.0/auth/self/permissions?entity=/1.0/projects/blah" | jq -r '.[0].entitlement')" = "admin" ]
+  ! lxc_remote query "localhost:/1.0/auth/self/permissions?entity=/1.0/not-an-entity" || false
+
   # Validate normal view with no restrictions
   lxc_remote project list localhost: | grep -q default
   lxc_remote project list localhost: | grep -q blah
@@ -30,6 +35,38 @@ test_tls_restrictions() {
   # Confirm no project visible when none listed
   [ "$(lxc_remote project list localhost: --format csv | wc -l)" = 0 ]
 
+  # Restricted clients only have the permissions of their groups.
+  [ "$(lxc_remote query localhost:/1.0/auth/self/permissions | jq 'length')" = "0" ]
+
+  # The management of specific groups can be delegated with the can_view, can_edit and can_delete entitlements on
+  # those groups. TLS identities can't be added to groups through the API yet, so the membership is added directly.
+  lxc auth group create team-lead
+  lxc auth group create team-a
+  lxc auth group create team-b
+  lxc auth group permission add team-lead group team-a can_view
+  lxc auth group permission add team-lead group team-a can_edit
+  lxc auth group permission add team-lead group team-a can_delete
+  lxd sql global "INSERT INTO identities_auth_groups (identity_id, auth_group_id) SELECT identities.id, auth_groups.id FROM identities, auth_groups WHERE identities.identifier = '${FINGERPRINT}' AND auth_groups.name = 'team-lead'"
+  lxc query -X POST /internal/identity-cache-refresh
+  [ "$(lxc_remote query localhost:/1.0/auth/groups | jq -r '.[]')" = "/1.0/auth/groups/team-a" ]
+  lxc_remote auth group show localhost:team-a
+  ! lxc_remote auth group show localhost:team-b || false
+  lxc_remote query -X PATCH localhost:/1.0/auth/groups/team-a --data '{"description": "Team A"}'
+  ! lxc_remote query -X PATCH localhost:/1.0/auth/groups/team-b --data '{"description": "Team B"}' || false
+
+  # Delegates can only grant the entitlements that they have, and can't inherit from groups that they can't edit.
+  lxc_remote auth group permission add localhost:team-a group team-a can_view
+  ! lxc_remote auth group permission add localhost:team-a server admin || false
+  ! lxc_remote auth group permission add localhost:team-a group team-b can_edit || false
+  ! lxc_remote query -X PATCH localhost:/1.0/auth/groups/team-a --data '{"parents": ["team-b"]}' || false
+  [ "$(lxc auth group show team-a | grep -c 'entitlement:')" = "1" ]
+
+  ! lxc_remote auth group delete localhost:team-b || false
+  lxc_remote auth group delete localhost:team-a
+  lxc auth group delete team-b
+  lxc auth group delete team-lead
+  [ "$(lxc_remote query localhost:/1.0/auth/self/permissions | jq 'length')" = "0" ]
+
   # Confirm we can still view storage pools
   [ "$(lxc_remote storage list localhost: --format csv | wc -l)" = 1 ]
 
@@ -64,4 +64,31 @@ test_warnings() {
     lxc warning rm "${uuid}"
     ! lxc warning list | grep -q "${uuid}" || false
     ! lxc warning show "${uuid}" || false
+
+    # Warning suppressions must match on something and have a valid message pattern.
+    ! lxc query --wait -X POST -d '{\"description\": \"everything\"}' /1.0/warnings/suppressions || false
+    ! lxc query --wait -X POST -d '{\"message_pattern\": \"(\"}' /1.0/warnings/suppressions || false
+    ! lxc query --wait -X POST -d '{\"type\": \"Unknown warning type\"}' /1.0/warnings/suppressions || false
+
+    # Suppress the warnings of the image with a matching message.
+    lxc query --wait -X POST -d "{\\\"type\\\": \\\"Undefined warning\\\", \\\"entity_url\\\": \\\"/1.0/images/$(lxc image info testimage | awk '/^Fingerprint/ {print $2}')\\\", \\\"message_pattern\\\": \\\"^suppressed\\\"}" /1.0/warnings/suppressions
+    [ "$(lxc query --wait /1.0/warnings/suppressions | jq 'length')" = "1" ]
+    suppression_url=$(lxc query --wait /1.0/warnings/suppressions | jq -r '.[0]')
+    lxc query --wait "${suppression_url}" | jq -e '.type == "Undefined warning" and (.entity_url | startswith("/1.0/images/"))'
+
+    # A matching warning is acknowledged and hidden from the listing unless suppressed warnings are included.
+    lxc warning rm "$(lxc warning list --all --format json | jq -r '.[] | select(.entity_url | startswith("/1.0/images/")) | .uuid')"
+    lxc query --wait -X POST -d "{\\\"type_code\\\": 0, \\\"message\\\": \\\"suppressed warning\\\", \\\"entity_type\\\": \\\"image\\\", \\\"entity_id\\\": ${image_id}}" /internal/testing/warnings
+    [ "$(lxc query --wait /1.0/warnings\?recursion=1 | jq '[.[] | select(.last_message == "suppressed warning")] | length')" = "0" ]
+    lxc query --wait "/1.0/warnings?recursion=1&include-suppressed=true" | jq -e '.[] | select(.last_message == "suppressed warning") | .suppressed and .status == "acknowledged"'
+
+    # Warnings that don't match the rule are not suppressed.
+    lxc query --wait -X POST -d '{\"type_code\": 0, \"message\": \"another warning\"}' /internal/testing/warnings
+    lxc query --wait /1.0/warnings\?recursion=1 | jq -e '.[] | select(.last_message == "another warning") | (.suppressed | not) and .status == "new"'
+
+    # Deleting the rule leaves the suppressed warnings as they are.
+    lxc query --wait -X DELETE "${suppression_url}"
+    ! lxc query --wait "${suppression_url}" || false
+    [ "$(lxc query --wait /1.0/warnings/suppressions | jq 'length')" = "0" ]
+    lxc query --wait "/1.0/warnings?recursion=1&include-suppressed=true" | jq -e '.[] | select(.last_message == "suppressed warning") | .suppressed'
 }